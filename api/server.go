@@ -21,8 +21,15 @@
 package api
 
 import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
@@ -39,11 +46,158 @@ func newHandler(logger logrus.FieldLogger) http.Handler {
 	return mux
 }
 
+// Config is the configuration of the REST API server.
+type Config struct {
+	Address string
+	// Token is the bearer token of the requests with the full access to the API, and ReadToken
+	// is the one of the requests that can only get the status of the test. The requests don't
+	// have to be authenticated if both are empty.
+	Token     string
+	ReadToken string
+	// TLSCertFile and TLSKeyFile are the certificate and the private key of the server, it
+	// serves HTTPS if they are set.
+	TLSCertFile string
+	TLSKeyFile  string
+	// AllowedNetworks are the IP addresses and the CIDR networks of the clients that can use the
+	// API, like 10.0.0.0/8 for the other containers of a cluster. All of the clients can use it
+	// if it's empty.
+	AllowedNetworks []string
+}
+
 // ListenAndServe is analogous to the stdlib one but also takes a core.Engine and logrus.FieldLogger
-func ListenAndServe(addr string, engine *core.Engine, logger logrus.FieldLogger) error {
+func ListenAndServe(conf Config, engine *core.Engine, logger logrus.FieldLogger) error {
+	if (conf.TLSCertFile == "") != (conf.TLSKeyFile == "") {
+		return errors.New("the REST API server needs both the TLS certificate and the TLS key")
+	}
+	networks, err := parseNetworks(conf.AllowedNetworks)
+	if err != nil {
+		return err
+	}
+	if conf.Token == "" && conf.ReadToken == "" && len(networks) == 0 && !isLoopbackAddress(conf.Address) {
+		logger.Warnf("The REST API server on %s is reachable from other hosts without authentication, "+
+			"set the K6_API_TOKEN environment variable to require a token", conf.Address)
+	}
+
 	mux := newHandler(logger)
+	srv := &http.Server{
+		Addr:    conf.Address,
+		Handler: withEngine(engine, newLogger(logger, withAllowedNetworks(networks, withAuth(conf, mux)))),
+	}
+	if conf.TLSCertFile != "" {
+		return srv.ListenAndServeTLS(conf.TLSCertFile, conf.TLSKeyFile)
+	}
+	return srv.ListenAndServe()
+}
+
+// isLoopbackAddress returns whether the host of the address is only reachable from this host.
+func isLoopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// parseNetworks parses the allowed networks, the single IP addresses are networks of their own.
+func parseNetworks(networks []string) ([]*net.IPNet, error) {
+	parsed := make([]*net.IPNet, 0, len(networks))
+	for _, network := range networks {
+		if !strings.Contains(network, "/") {
+			ip := net.ParseIP(network)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid allowed network of the REST API server '%s'", network)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			parsed = append(parsed, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed network of the REST API server '%s': %w", network, err)
+		}
+		parsed = append(parsed, ipNet)
+	}
+	return parsed, nil
+}
+
+// withAllowedNetworks returns the middleware that only allows the requests of the clients in the
+// networks, if there are any.
+func withAllowedNetworks(networks []*net.IPNet, next http.Handler) http.Handler {
+	if len(networks) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			for _, network := range networks {
+				if network.Contains(ip) {
+					next.ServeHTTP(rw, r)
+					return
+				}
+			}
+		}
+		authError(rw, "Forbidden", "the address of the client isn't allowed", http.StatusForbidden)
+	})
+}
+
+// withAuth returns the middleware that authenticates the requests with the bearer tokens of the
+// config, the read token only allows the GET requests. The /ping endpoint doesn't require a
+// token, so it can be used for the health checks.
+func withAuth(conf Config, next http.Handler) http.Handler {
+	if conf.Token == "" && conf.ReadToken == "" {
+		return next
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ping" {
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		var token string
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+		switch {
+		case token != "" && tokenEqual(token, conf.Token):
+			next.ServeHTTP(rw, r)
+		case token != "" && tokenEqual(token, conf.ReadToken):
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				authError(rw, "Forbidden", "the token only allows reading the status of the test", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(rw, r)
+		default:
+			rw.Header().Set("WWW-Authenticate", `Bearer realm="k6"`)
+			authError(rw, "Unauthorized", "a valid bearer token is required", http.StatusUnauthorized)
+		}
+	})
+}
+
+func tokenEqual(token, expected string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
 
-	return http.ListenAndServe(addr, withEngine(engine, newLogger(logger, mux)))
+// authError writes the error in the format of the errors of the v1 API, which the clients parse.
+func authError(rw http.ResponseWriter, title, detail string, status int) {
+	data, err := json.Marshal(v1.ErrorResponse{Errors: []v1.Error{
+		{Status: strconv.Itoa(status), Title: title, Detail: detail},
+	}})
+	if err != nil {
+		panic(err)
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_, _ = rw.Write(data)
 }
 
 type wrappedResponseWriter struct {
@@ -56,6 +210,16 @@ func (w wrappedResponseWriter) WriteHeader(status int) {
 	w.ResponseWriter.WriteHeader(status)
 }
 
+// Hijack implements http.Hijacker, so WebSocket endpoints can work through
+// the logger middleware.
+func (w wrappedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer doesn't support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
 // newLogger returns the middleware which logs response status for request.
 func newLogger(l logrus.FieldLogger, next http.Handler) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
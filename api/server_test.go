@@ -104,3 +104,91 @@ func TestPing(t *testing.T) {
 	assert.Equal(t, http.StatusOK, res.StatusCode)
 	assert.Equal(t, []byte{'o', 'k'}, rw.Body.Bytes())
 }
+
+func TestWithAuth(t *testing.T) {
+	t.Parallel()
+	handler := withAuth(Config{Token: "secret", ReadToken: "reader"}, http.HandlerFunc(testHTTPHandler))
+	testCases := []struct {
+		method, path, token string
+		status              int
+	}{
+		{http.MethodGet, "/v1/status", "", http.StatusUnauthorized},
+		{http.MethodGet, "/v1/status", "wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/v1/status", "secret", http.StatusOK},
+		{http.MethodPatch, "/v1/status", "secret", http.StatusOK},
+		{http.MethodGet, "/v1/status", "reader", http.StatusOK},
+		{http.MethodPatch, "/v1/status", "reader", http.StatusForbidden},
+		{http.MethodGet, "/ping", "", http.StatusOK},
+	}
+	// the tokens have to be bearer tokens
+	for _, auth := range []string{"secret", "Basic secret"} {
+		rw := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/v1/status", nil)
+		r.Header.Set("Authorization", auth)
+		handler.ServeHTTP(rw, r)
+		assert.Equal(t, http.StatusUnauthorized, rw.Code, auth)
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.method+" "+tc.path+" "+tc.token, func(t *testing.T) {
+			t.Parallel()
+			rw := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, "http://example.com"+tc.path, nil)
+			if tc.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			handler.ServeHTTP(rw, r)
+			res := rw.Result()
+			defer func() { _ = res.Body.Close() }()
+			assert.Equal(t, tc.status, res.StatusCode)
+			if tc.status == http.StatusUnauthorized {
+				assert.Equal(t, `Bearer realm="k6"`, res.Header.Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	rw := httptest.NewRecorder()
+	withAuth(Config{}, http.HandlerFunc(testHTTPHandler)).ServeHTTP(rw, httptest.NewRequest("PATCH", "/v1/status", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+}
+
+func TestWithAllowedNetworks(t *testing.T) {
+	t.Parallel()
+	networks, err := parseNetworks([]string{"10.0.0.0/8", "192.168.1.10", "fd00::/8"})
+	require.NoError(t, err)
+	handler := withAllowedNetworks(networks, http.HandlerFunc(testHTTPHandler))
+	for remoteAddr, status := range map[string]int{
+		"10.1.2.3:1234":       http.StatusOK,
+		"192.168.1.10:1234":   http.StatusOK,
+		"[fd00::1]:1234":      http.StatusOK,
+		"192.168.1.11:1234":   http.StatusForbidden,
+		"127.0.0.1:1234":      http.StatusForbidden,
+		"[2001:db8::1]:1234":  http.StatusForbidden,
+		"invalid remote addr": http.StatusForbidden,
+	} {
+		rw := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/v1/status", nil)
+		r.RemoteAddr = remoteAddr
+		handler.ServeHTTP(rw, r)
+		assert.Equal(t, status, rw.Code, remoteAddr)
+	}
+
+	_, err = parseNetworks([]string{"10.0.0.0/33"})
+	assert.EqualError(t, err, "invalid allowed network of the REST API server '10.0.0.0/33': invalid CIDR address: 10.0.0.0/33")
+	_, err = parseNetworks([]string{"example.com"})
+	assert.EqualError(t, err, "invalid allowed network of the REST API server 'example.com'")
+}
+
+func TestIsLoopbackAddress(t *testing.T) {
+	t.Parallel()
+	for addr, expected := range map[string]bool{
+		"localhost:6565": true,
+		"127.0.0.1:6565": true,
+		"[::1]:6565":     true,
+		":6565":          false,
+		"0.0.0.0:6565":   false,
+		"10.0.0.1:6565":  false,
+	} {
+		assert.Equal(t, expected, isLoopbackAddress(addr), addr)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/manyminds/api2go/jsonapi"
 	"github.com/sirupsen/logrus"
@@ -40,15 +41,20 @@ type Client struct {
 	BaseURL    *url.URL
 	httpClient *http.Client
 	logger     *logrus.Entry
+	token      string
 }
 
 // Option function are helpers that enable the flexible configuration of the
 // REST API client.
 type Option func(*Client)
 
-// New returns a newly configured REST API Client.
+// New returns a newly configured REST API Client. The base is the address of the server, with
+// the https:// prefix if it serves HTTPS.
 func New(base string, options ...Option) (*Client, error) {
-	baseURL, err := url.Parse("http://" + base)
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		base = "http://" + base
+	}
+	baseURL, err := url.Parse(base)
 	if err != nil {
 		return nil, err
 	}
@@ -72,6 +78,13 @@ func WithHTTPClient(httpClient *http.Client) Option {
 	})
 }
 
+// WithToken sets the bearer token of the requests, for the servers that require one.
+func WithToken(token string) Option {
+	return Option(func(c *Client) {
+		c.token = token
+	})
+}
+
 // WithLogger sets the specifield logger to the client.
 func WithLogger(logger *logrus.Entry) Option {
 	return Option(func(c *Client) {
@@ -110,8 +123,12 @@ func (c *Client) Call(ctx context.Context, method string, rel *url.URL, body, ou
 	req := &http.Request{
 		Method: method,
 		URL:    c.BaseURL.ResolveReference(rel),
+		Header: make(http.Header),
 		Body:   bodyReader,
 	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
 	req = req.WithContext(ctx)
 
 	res, err := c.httpClient.Do(req)
@@ -0,0 +1,39 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"context"
+	"net/url"
+
+	v1 "go.k6.io/k6/api/v1"
+)
+
+// Tags returns the runtime tags of the samples.
+func (c *Client) Tags(ctx context.Context) (ret v1.Tags, err error) {
+	return ret, c.Call(ctx, "GET", &url.URL{Path: "/v1/tags"}, nil, &ret)
+}
+
+// SetTags sets the runtime tags of the patch, and removes the ones with null values, and returns
+// the new runtime tags.
+func (c *Client) SetTags(ctx context.Context, patch v1.Tags) (ret v1.Tags, err error) {
+	return ret, c.Call(ctx, "PATCH", &url.URL{Path: "/v1/tags"}, patch, &ret)
+}
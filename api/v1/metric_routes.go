@@ -21,33 +21,117 @@
 package v1
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/manyminds/api2go/jsonapi"
 
 	"go.k6.io/k6/api/common"
+	"go.k6.io/k6/core"
+	"go.k6.io/k6/lib/types"
 )
 
+const (
+	defaultMetricsStreamInterval = time.Second
+	minMetricsStreamInterval     = 100 * time.Millisecond
+)
+
+//nolint:gochecknoglobals
+var metricsStreamUpgrader = websocket.Upgrader{
+	// A nil CheckOrigin only accepts requests without an Origin header, or with
+	// one that has the same host as the Host header, so that scripts on other
+	// websites that are opened in a browser on the same machine can't read the
+	// metrics stream.
+	CheckOrigin: nil,
+}
+
 func handleGetMetrics(rw http.ResponseWriter, r *http.Request) {
 	engine := common.GetEngine(r.Context())
 
+	data, err := jsonapi.Marshal(getMetricsSnapshot(engine))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
+
+func getMetricsSnapshot(engine *core.Engine) []Metric {
 	var t time.Duration
 	if engine.ExecutionScheduler != nil {
 		t = engine.ExecutionScheduler.GetState().GetCurrentTestRunDuration()
 	}
 
-	metrics := make([]Metric, 0)
+	engine.MetricsLock.Lock()
+	defer engine.MetricsLock.Unlock()
+
+	metrics := make([]Metric, 0, len(engine.Metrics))
 	for _, m := range engine.Metrics {
 		metrics = append(metrics, NewMetric(m, t))
 	}
+	return metrics
+}
+
+// handleMetricsStream upgrades the connection to a WebSocket and sends a
+// snapshot of all metrics, in the same format as the /v1/metrics endpoint,
+// every interval until the client disconnects.
+func handleMetricsStream(rw http.ResponseWriter, r *http.Request) {
+	interval := defaultMetricsStreamInterval
+	if v := r.URL.Query().Get("interval"); v != "" {
+		var err error
+		interval, err = types.ParseExtendedDuration(v)
+		if err != nil {
+			apiError(rw, "Invalid interval", err.Error(), http.StatusBadRequest)
+			return
+		}
+		if interval < minMetricsStreamInterval {
+			apiError(rw, "Invalid interval",
+				fmt.Sprintf("the interval should be at least %s", minMetricsStreamInterval), http.StatusBadRequest)
+			return
+		}
+	}
 
-	data, err := jsonapi.Marshal(metrics)
+	engine := common.GetEngine(r.Context())
+	conn, err := metricsStreamUpgrader.Upgrade(rw, r, nil)
 	if err != nil {
-		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		// The upgrader has already responded with an error
 		return
 	}
-	_, _ = rw.Write(data)
+	defer func() { _ = conn.Close() }()
+
+	// We don't expect any messages from the client, but we need to read them
+	// to process control frames and to know when the connection is closed.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		data, err := jsonapi.Marshal(getMetricsSnapshot(engine))
+		if err != nil {
+			_ = conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseInternalServerErr, err.Error()))
+			return
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-closed:
+			return
+		}
+	}
 }
 
 func handleGetMetric(rw http.ResponseWriter, r *http.Request, id string) {
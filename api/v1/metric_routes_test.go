@@ -24,14 +24,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/manyminds/api2go/jsonapi"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/guregu/null.v3"
 
+	"go.k6.io/k6/api/common"
 	"go.k6.io/k6/core"
 	"go.k6.io/k6/core/local"
 	"go.k6.io/k6/lib"
@@ -131,3 +135,72 @@ func TestGetMetric(t *testing.T) {
 		})
 	})
 }
+
+func TestMetricsStream(t *testing.T) {
+	t.Parallel()
+	logger := logrus.New()
+	logger.SetOutput(testutils.NewTestOutput(t))
+	execScheduler, err := local.NewExecutionScheduler(&minirunner.MiniRunner{}, logger)
+	require.NoError(t, err)
+	engine, err := core.NewEngine(execScheduler, lib.Options{}, lib.RuntimeOptions{}, nil, logger)
+	require.NoError(t, err)
+
+	engine.Metrics = map[string]*stats.Metric{
+		"my_metric": stats.New("my_metric", stats.Trend),
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		NewHandler().ServeHTTP(rw, r.WithContext(common.WithEngine(r.Context(), engine)))
+	}))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/metrics/stream"
+
+	t.Run("invalid interval", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		NewHandler().ServeHTTP(rw, newRequestWithEngine(engine, "GET", "/v1/metrics/stream?interval=10ms", nil))
+		assert.Equal(t, http.StatusBadRequest, rw.Result().StatusCode)
+	})
+
+	t.Run("cross origin", func(t *testing.T) {
+		_, res, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": []string{"http://example.com"}})
+		require.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": []string{srv.URL}})
+		require.NoError(t, err)
+		assert.NoError(t, conn.Close())
+	})
+
+	t.Run("snapshots", func(t *testing.T) {
+		addValue := func(v float64) {
+			engine.MetricsLock.Lock()
+			engine.Metrics["my_metric"].Sink.Add(stats.Sample{Value: v})
+			engine.MetricsLock.Unlock()
+		}
+		readMax := func(conn *websocket.Conn) float64 {
+			_, data, err := conn.ReadMessage()
+			require.NoError(t, err)
+			var metrics []Metric
+			require.NoError(t, jsonapi.Unmarshal(data, &metrics))
+			require.Len(t, metrics, 1)
+			assert.Equal(t, "my_metric", metrics[0].Name)
+			assert.Equal(t, stats.Trend, metrics[0].Type.Type)
+			return metrics[0].Sample["max"]
+		}
+
+		addValue(1)
+		conn, res, err := websocket.DefaultDialer.Dial(wsURL+"?interval=100ms", nil)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+		assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+		// The first snapshot is sent right away
+		assert.Equal(t, 1.0, readMax(conn))
+
+		addValue(2)
+		for max := readMax(conn); max != 2.0; max = readMax(conn) {
+			assert.Equal(t, 1.0, max)
+		}
+	})
+}
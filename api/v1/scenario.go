@@ -0,0 +1,117 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package v1
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/executor"
+	"go.k6.io/k6/lib/types"
+)
+
+// The possible values of Scenario.Status
+const (
+	ScenarioWaiting  = "waiting"
+	ScenarioRunning  = "running"
+	ScenarioStopped  = "stopped"
+	ScenarioFinished = "finished"
+)
+
+// Scenario contains the live execution details of a single scenario. When
+// used for updates, only Stopped, VUs and VUsMax are taken into account.
+type Scenario struct {
+	Name     string `json:"-" yaml:"name"`
+	Executor string `json:"executor" yaml:"executor"`
+	Status   string `json:"status" yaml:"status"`
+
+	StartTime             null.Time          `json:"start-time" yaml:"start-time"`
+	Progress              float64            `json:"progress" yaml:"progress"`
+	ETA                   types.NullDuration `json:"eta" yaml:"eta"`
+	Iterations            uint64             `json:"iterations" yaml:"iterations"`
+	InterruptedIterations uint64             `json:"interrupted-iterations" yaml:"interrupted-iterations"`
+
+	VUs     null.Int `json:"vus" yaml:"vus"`
+	VUsMax  null.Int `json:"vus-max" yaml:"vus-max"`
+	Stopped bool     `json:"stopped" yaml:"stopped"`
+}
+
+// NewScenario returns the current state of the scenario run by the given
+// executor.
+func NewScenario(exec lib.Executor) Scenario {
+	conf := exec.GetConfig()
+	progress, _ := exec.GetProgress().Progress()
+	scenario := Scenario{
+		Name:     conf.GetName(),
+		Executor: conf.GetType(),
+		Status:   ScenarioWaiting,
+		Progress: progress,
+	}
+
+	if observable, ok := exec.(lib.ObservableExecutor); ok {
+		stats := observable.GetStats()
+		scenario.Stopped = stats.Stopped
+		scenario.VUs = null.IntFrom(stats.ActiveVUs)
+		scenario.Iterations = stats.Iterations
+		scenario.InterruptedIterations = stats.InterruptedIterations
+		if !stats.StartTime.IsZero() {
+			scenario.StartTime = null.TimeFrom(stats.StartTime)
+		}
+
+		switch {
+		case stats.Running:
+			scenario.Status = ScenarioRunning
+			if progress > 0 && progress < 1 {
+				spent := time.Since(stats.StartTime)
+				eta := time.Duration(float64(spent) * (1 - progress) / progress)
+				scenario.ETA = types.NullDurationFrom(eta.Round(time.Second))
+			}
+		case stats.Stopped:
+			scenario.Status = ScenarioStopped
+		case !stats.StartTime.IsZero():
+			scenario.Status = ScenarioFinished
+		}
+	}
+
+	if mex, ok := exec.(*executor.ExternallyControlled); ok {
+		scenario.VUsMax = mex.GetCurrentConfig().MaxVUs
+	}
+
+	return scenario
+}
+
+// GetName returns the JSON API type of the scenarios.
+func (s Scenario) GetName() string {
+	return "scenarios"
+}
+
+// GetID returns the scenario name.
+func (s Scenario) GetID() string {
+	return s.Name
+}
+
+// SetID sets the scenario name.
+func (s *Scenario) SetID(id string) error {
+	s.Name = id
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package v1
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/manyminds/api2go/jsonapi"
+
+	"go.k6.io/k6/api/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/executor"
+)
+
+func findExecutor(execScheduler lib.ExecutionScheduler, name string) lib.Executor {
+	for _, exec := range execScheduler.GetExecutors() {
+		if exec.GetConfig().GetName() == name {
+			return exec
+		}
+	}
+	return nil
+}
+
+func handleGetScenarios(rw http.ResponseWriter, r *http.Request) {
+	engine := common.GetEngine(r.Context())
+
+	executors := engine.ExecutionScheduler.GetExecutors()
+	scenarios := make([]Scenario, 0, len(executors))
+	for _, exec := range executors {
+		scenarios = append(scenarios, NewScenario(exec))
+	}
+
+	data, err := jsonapi.Marshal(scenarios)
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
+
+func handleGetScenario(rw http.ResponseWriter, r *http.Request, id string) {
+	engine := common.GetEngine(r.Context())
+
+	exec := findExecutor(engine.ExecutionScheduler, id)
+	if exec == nil {
+		apiError(rw, "Not Found", "No scenario with that name was found", http.StatusNotFound)
+		return
+	}
+
+	data, err := jsonapi.Marshal(NewScenario(exec))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
+
+func handlePatchScenario(rw http.ResponseWriter, r *http.Request, id string) {
+	engine := common.GetEngine(r.Context())
+
+	exec := findExecutor(engine.ExecutionScheduler, id)
+	if exec == nil {
+		apiError(rw, "Not Found", "No scenario with that name was found", http.StatusNotFound)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		apiError(rw, "Couldn't read request", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var scenario Scenario
+	if err = jsonapi.Unmarshal(body, &scenario); err != nil {
+		apiError(rw, "Invalid data", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if scenario.Stopped { //nolint:nestif
+		stoppable, ok := exec.(lib.StoppableExecutor)
+		if !ok {
+			apiError(rw, "Stop error", fmt.Sprintf("scenario '%s' can't be stopped", id), http.StatusBadRequest)
+			return
+		}
+		stoppable.Stop()
+	} else if scenario.VUsMax.Valid || scenario.VUs.Valid {
+		mex, ok := exec.(*executor.ExternallyControlled)
+		if !ok {
+			apiError(rw, "Execution config error", fmt.Sprintf(
+				"scenario '%s' can't be scaled, only externally-controlled scenarios support live VU updates",
+				id,
+			), http.StatusBadRequest)
+			return
+		}
+		newConfig := mex.GetCurrentConfig().ExternallyControlledConfigParams
+		if scenario.VUsMax.Valid {
+			newConfig.MaxVUs = scenario.VUsMax
+		}
+		if scenario.VUs.Valid {
+			newConfig.VUs = scenario.VUs
+		}
+		if updateErr := mex.UpdateConfig(r.Context(), newConfig); updateErr != nil {
+			apiError(rw, "Config update error", updateErr.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	data, err := jsonapi.Marshal(NewScenario(exec))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
@@ -0,0 +1,163 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/manyminds/api2go/jsonapi"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/core"
+	"go.k6.io/k6/core/local"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/lib/testutils/minirunner"
+	"go.k6.io/k6/stats"
+)
+
+func TestScenarios(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(testutils.NewTestOutput(t))
+
+	scenarios := lib.ScenarioConfigs{}
+	err := json.Unmarshal([]byte(`{
+		"external": {"executor": "externally-controlled", "vus": 0, "maxVUs": 10, "duration": "3s"},
+		"constant": {"executor": "constant-vus", "vus": 2, "duration": "3s", "gracefulStop": "0s"}
+	}`), &scenarios)
+	require.NoError(t, err)
+	options := lib.Options{Scenarios: scenarios}
+
+	runner := &minirunner.MiniRunner{
+		Options: options,
+		Fn: func(ctx context.Context, _ chan<- stats.SampleContainer) error {
+			select {
+			case <-ctx.Done():
+			case <-time.After(10 * time.Millisecond):
+			}
+			return nil
+		},
+	}
+	execScheduler, err := local.NewExecutionScheduler(runner, logger)
+	require.NoError(t, err)
+	engine, err := core.NewEngine(execScheduler, options, lib.RuntimeOptions{}, nil, logger)
+	require.NoError(t, err)
+
+	getScenario := func(t *testing.T, name string) Scenario {
+		rw := httptest.NewRecorder()
+		NewHandler().ServeHTTP(rw, newRequestWithEngine(engine, "GET", "/v1/scenarios/"+name, nil))
+		require.Equal(t, http.StatusOK, rw.Result().StatusCode)
+		var scenario Scenario
+		require.NoError(t, jsonapi.Unmarshal(rw.Body.Bytes(), &scenario))
+		return scenario
+	}
+	patchScenario := func(t *testing.T, name string, scenario Scenario) *httptest.ResponseRecorder {
+		body, err := jsonapi.Marshal(scenario)
+		require.NoError(t, err)
+		rw := httptest.NewRecorder()
+		NewHandler().ServeHTTP(rw, newRequestWithEngine(engine, "PATCH", "/v1/scenarios/"+name, bytes.NewReader(body)))
+		return rw
+	}
+
+	t.Run("before start", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		NewHandler().ServeHTTP(rw, newRequestWithEngine(engine, "GET", "/v1/scenarios", nil))
+		res := rw.Result()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var doc jsonapi.Document
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &doc))
+		require.Len(t, doc.Data.DataArray, 2)
+		assert.Equal(t, "scenarios", doc.Data.DataArray[0].Type)
+
+		var list []Scenario
+		require.NoError(t, jsonapi.Unmarshal(rw.Body.Bytes(), &list))
+		names := []string{list[0].Name, list[1].Name}
+		assert.ElementsMatch(t, []string{"external", "constant"}, names)
+		for _, s := range list {
+			assert.Equal(t, ScenarioWaiting, s.Status)
+			assert.False(t, s.StartTime.Valid)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		NewHandler().ServeHTTP(rw, newRequestWithEngine(engine, "GET", "/v1/scenarios/nope", nil))
+		assert.Equal(t, http.StatusNotFound, rw.Result().StatusCode)
+
+		rw = patchScenario(t, "nope", Scenario{Stopped: true})
+		assert.Equal(t, http.StatusNotFound, rw.Result().StatusCode)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	run, wait, err := engine.Init(ctx, ctx)
+	require.NoError(t, err)
+	errC := make(chan error)
+	go func() { errC <- run() }()
+	time.Sleep(500 * time.Millisecond)
+
+	t.Run("running", func(t *testing.T) {
+		scenario := getScenario(t, "constant")
+		assert.Equal(t, "constant-vus", scenario.Executor)
+		assert.Equal(t, ScenarioRunning, scenario.Status)
+		assert.True(t, scenario.StartTime.Valid)
+		assert.True(t, scenario.ETA.Valid)
+		assert.Greater(t, scenario.Iterations, uint64(0))
+		assert.False(t, scenario.VUsMax.Valid)
+
+		external := getScenario(t, "external")
+		assert.Equal(t, null.IntFrom(10), external.VUsMax)
+	})
+
+	t.Run("scale", func(t *testing.T) {
+		rw := patchScenario(t, "constant", Scenario{VUs: null.IntFrom(5)})
+		assert.Equal(t, http.StatusBadRequest, rw.Result().StatusCode)
+
+		rw = patchScenario(t, "external", Scenario{VUsMax: null.IntFrom(20)})
+		require.Equal(t, http.StatusOK, rw.Result().StatusCode)
+		assert.Equal(t, null.IntFrom(20), getScenario(t, "external").VUsMax)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		rw := patchScenario(t, "constant", Scenario{Stopped: true})
+		require.Equal(t, http.StatusOK, rw.Result().StatusCode)
+
+		time.Sleep(100 * time.Millisecond)
+		scenario := getScenario(t, "constant")
+		assert.Equal(t, ScenarioStopped, scenario.Status)
+		assert.True(t, scenario.Stopped)
+		assert.Equal(t, ScenarioRunning, getScenario(t, "external").Status)
+	})
+
+	engine.Stop()
+	require.NoError(t, <-errC)
+	wait()
+}
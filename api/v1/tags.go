@@ -0,0 +1,58 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package v1
+
+import (
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/core"
+)
+
+// Tags are the runtime tags of the samples sent to the outputs, which are set during the test
+// run and override the static tags. The tags with null values are removed by a PATCH.
+type Tags struct {
+	Tags map[string]null.String `json:"tags" yaml:"tags"`
+}
+
+// NewTags returns the runtime tags of the engine.
+func NewTags(engine *core.Engine) Tags {
+	runtimeTags := engine.RuntimeTags()
+	tags := make(map[string]null.String, len(runtimeTags))
+	for k, v := range runtimeTags {
+		tags[k] = null.StringFrom(v)
+	}
+	return Tags{Tags: tags}
+}
+
+// GetName gets the name of the resource.
+func (t Tags) GetName() string {
+	return "tags"
+}
+
+// GetID gets the ID of the resource.
+func (t Tags) GetID() string {
+	return "default"
+}
+
+// SetID sets the ID of the resource.
+func (t Tags) SetID(id string) error {
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package v1
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/manyminds/api2go/jsonapi"
+
+	"go.k6.io/k6/api/common"
+)
+
+func handleGetTags(rw http.ResponseWriter, r *http.Request) {
+	engine := common.GetEngine(r.Context())
+
+	data, err := jsonapi.Marshal(NewTags(engine))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
+
+func handlePatchTags(rw http.ResponseWriter, r *http.Request) {
+	engine := common.GetEngine(r.Context())
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		apiError(rw, "Couldn't read request", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var tags Tags
+	if err := jsonapi.Unmarshal(body, &tags); err != nil {
+		apiError(rw, "Invalid data", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	set := make(map[string]string, len(tags.Tags))
+	var unset []string
+	for k, v := range tags.Tags {
+		if v.Valid {
+			set[k] = v.String
+		} else {
+			unset = append(unset, k)
+		}
+	}
+	if err := engine.UpdateTags(set, unset); err != nil {
+		apiError(rw, "Invalid tags", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := jsonapi.Marshal(NewTags(engine))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
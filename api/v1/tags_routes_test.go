@@ -0,0 +1,82 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package v1
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/manyminds/api2go/jsonapi"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/core"
+	"go.k6.io/k6/core/local"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/lib/testutils/minirunner"
+)
+
+func TestTags(t *testing.T) {
+	t.Parallel()
+	logger := logrus.New()
+	logger.SetOutput(testutils.NewTestOutput(t))
+	execScheduler, err := local.NewExecutionScheduler(&minirunner.MiniRunner{}, logger)
+	require.NoError(t, err)
+	engine, err := core.NewEngine(execScheduler, lib.Options{}, lib.RuntimeOptions{}, nil, logger)
+	require.NoError(t, err)
+
+	do := func(method, body string) (int, Tags) {
+		rw := httptest.NewRecorder()
+		NewHandler().ServeHTTP(rw, newRequestWithEngine(engine, method, "/v1/tags", bytes.NewBufferString(body)))
+		res := rw.Result()
+		defer func() { _ = res.Body.Close() }()
+		var tags Tags
+		if res.StatusCode == http.StatusOK {
+			require.NoError(t, jsonapi.Unmarshal(rw.Body.Bytes(), &tags))
+		}
+		return res.StatusCode, tags
+	}
+
+	status, tags := do(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, tags.Tags)
+
+	status, tags = do(http.MethodPatch,
+		`{"data":{"type":"tags","id":"default","attributes":{"tags":{"phase":"deployment","canary":"v2"}}}}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]string{"phase": "deployment", "canary": "v2"}, engine.RuntimeTags())
+	assert.Equal(t, "deployment", tags.Tags["phase"].String)
+
+	status, _ = do(http.MethodPatch, `{"data":{"type":"tags","id":"default","attributes":{"tags":{"canary":null}}}}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]string{"phase": "deployment"}, engine.RuntimeTags())
+
+	status, _ = do(http.MethodPatch, `{"data":{"type":"tags","id":"default","attributes":{"tags":{"":"x"}}}}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = do(http.MethodPatch, `not json`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = do(http.MethodPut, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+}
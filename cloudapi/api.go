@@ -22,10 +22,12 @@ package cloudapi
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"mime/multipart"
 	"net/http"
 	"strconv"
+	"time"
 
 	"go.k6.io/k6/lib"
 )
@@ -46,6 +48,8 @@ type TestRun struct {
 	Thresholds map[string][]string `json:"thresholds"`
 	// Duration of test in seconds. -1 for unknown length, 0 for continuous running.
 	Duration int64 `json:"duration"`
+
+	Metadata *lib.TestRunMetadata `json:"metadata,omitempty"`
 }
 
 type CreateTestRunResponse struct {
@@ -60,6 +64,14 @@ type TestProgressResponse struct {
 	Progress      float64       `json:"progress"`
 }
 
+// TestRunResults are the results of a test run, besides its status, the results of its
+// thresholds and the values of the summary of its metrics, by metric name and by stat.
+type TestRunResults struct {
+	TestProgressResponse
+	Thresholds ThresholdResult               `json:"thresholds"`
+	Metrics    map[string]map[string]float64 `json:"metrics"`
+}
+
 type LoginResponse struct {
 	Token string `json:"token"`
 }
@@ -169,6 +181,50 @@ func (c *Client) GetTestProgress(referenceID string) (*TestProgressResponse, err
 	return &ctrr, nil
 }
 
+// PollTestProgress gets the progress of a test run every interval, until done returns true for
+// it or the context is done. The errors of getting the progress are logged and the polling goes
+// on, it returns the last progress and the error of the context if it was done before.
+func (c *Client) PollTestProgress(
+	ctx context.Context, referenceID string, interval time.Duration, done func(*TestProgressResponse) bool,
+) (*TestProgressResponse, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *TestProgressResponse
+	for {
+		select {
+		case <-ctx.Done():
+			return last, ctx.Err()
+		case <-ticker.C:
+		}
+		progress, err := c.GetTestProgress(referenceID)
+		if err != nil {
+			c.logger.WithError(err).Error("Test progress error")
+			continue
+		}
+		last = progress
+		if done(progress) {
+			return progress, nil
+		}
+	}
+}
+
+// GetTestRunResults returns the status, the results of the thresholds and the summary metrics of
+// a test run, which are partial while it's still running.
+func (c *Client) GetTestRunResults(referenceID string) (*TestRunResults, error) {
+	url := fmt.Sprintf("%s/tests/%s/results", c.baseURL, referenceID)
+	req, err := c.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	results := TestRunResults{}
+	if err := c.Do(req, &results); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
 func (c *Client) StopCloudTestRun(referenceID string) error {
 	url := fmt.Sprintf("%s/tests/%s/stop", c.baseURL, referenceID)
 
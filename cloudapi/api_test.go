@@ -21,17 +21,22 @@
 package cloudapi
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
 
+	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/testutils"
 	"go.k6.io/k6/lib/types"
 )
@@ -86,6 +91,53 @@ func TestFinished(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestPollTestProgress(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/test-progress/1", r.URL.Path)
+		requests++
+		status := lib.RunStatusRunning
+		if requests == 3 {
+			status = lib.RunStatusFinished
+		}
+		fprintf(t, w, `{"run_status": %d, "progress": %g}`, status, float64(requests)/3)
+	}))
+	defer server.Close()
+
+	client := NewClient(testutils.NewLogger(t), "token", server.URL, "1.0")
+	done := func(p *TestProgressResponse) bool { return p.RunStatus > lib.RunStatusRunning }
+	progress, err := client.PollTestProgress(context.Background(), "1", time.Millisecond, done)
+	require.NoError(t, err)
+	assert.Equal(t, lib.RunStatusFinished, progress.RunStatus)
+	assert.Equal(t, 1.0, progress.Progress)
+	assert.Equal(t, 3, requests)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	progress, err = client.PollTestProgress(ctx, "1", time.Millisecond, done)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NotNil(t, progress)
+	assert.Equal(t, lib.RunStatusRunning, progress.RunStatus)
+}
+
+func TestGetTestRunResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/tests/1/results", r.URL.Path)
+		fprintf(t, w, `{"run_status": 3, "run_status_text": "Finished", "result_status": 1, "progress": 1,
+			"thresholds": {"http_req_duration": {"p(95)<500": true}},
+			"metrics": {"http_req_duration": {"avg": 120.5, "p(95)": 640}}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(testutils.NewLogger(t), "token", server.URL, "1.0")
+	results, err := client.GetTestRunResults("1")
+	require.NoError(t, err)
+	assert.Equal(t, lib.RunStatusFinished, results.RunStatus)
+	assert.Equal(t, ResultStatusFailed, results.ResultStatus)
+	assert.Equal(t, ThresholdResult{"http_req_duration": {"p(95)<500": true}}, results.Thresholds)
+	assert.Equal(t, 640.0, results.Metrics["http_req_duration"]["p(95)"])
+}
+
 func TestAuthorizedError(t *testing.T) {
 	called := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -104,6 +156,40 @@ func TestAuthorizedError(t *testing.T) {
 	assert.EqualError(t, err, "(403/E5) Not allowed")
 }
 
+func TestTokenRefresh(t *testing.T) {
+	var called int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called++
+		if r.Header.Get("Authorization") != "Token new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fprintf(t, w, `{"error": {"code": 4, "message": "Invalid token"}}`)
+			return
+		}
+		fprintf(t, w, `{"reference_id": "1"}`)
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("old\n"), 0o600))
+	conf := Config{Host: null.StringFrom(server.URL), TokenFile: null.StringFrom(tokenFile)}
+	token, err := conf.ReadTokenFile()
+	require.NoError(t, err)
+	conf.Token = null.StringFrom(token)
+	client, err := NewClientFromConfig(testutils.NewLogger(t), conf, "1.0")
+	require.NoError(t, err)
+
+	_, err = client.CreateTestRun(&TestRun{Name: "test"})
+	assert.ErrorIs(t, err, ErrTokenRejected)
+	assert.Contains(t, err.Error(), "(401/E4) Invalid token")
+	assert.Equal(t, 1, called)
+
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("new\n"), 0o600))
+	resp, err := client.CreateTestRun(&TestRun{Name: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, "1", resp.ReferenceID)
+	assert.Equal(t, 3, called)
+}
+
 func TestDetailsError(t *testing.T) {
 	called := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
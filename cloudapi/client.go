@@ -29,6 +29,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -48,10 +49,13 @@ const (
 // Client handles communication with Load Impact cloud API.
 type Client struct {
 	client  *http.Client
-	token   string
 	baseURL string
 	version string
 
+	tokenMu      sync.RWMutex
+	token        string
+	refreshToken func() (string, error)
+
 	logger logrus.FieldLogger
 
 	retries       int
@@ -72,6 +76,21 @@ func NewClient(logger logrus.FieldLogger, token, host, version string) *Client {
 	return c
 }
 
+// NewClientFromConfig returns a new client for the cloud API with the token, the host and the
+// transport of the config.
+func NewClientFromConfig(logger logrus.FieldLogger, conf Config, version string) (*Client, error) {
+	transport, err := conf.HTTPTransport()
+	if err != nil {
+		return nil, err
+	}
+	c := NewClient(logger, conf.Token.String, conf.Host.String, version)
+	c.client.Transport = transport
+	if conf.TokenFile.Valid && conf.TokenFile.String != "" {
+		c.SetTokenRefresher(conf.ReadTokenFile)
+	}
+	return c, nil
+}
+
 // NewRequest creates new HTTP request.
 //
 // This is the same as http.NewRequest, except that data if not nil
@@ -112,6 +131,29 @@ func (c *Client) Do(req *http.Request, v interface{}) error {
 		req.Body, _ = req.GetBody()
 	}
 
+	err := c.doWithRetries(req, v)
+	if !isUnauthenticated(err) {
+		return err
+	}
+	if c.refreshToken != nil {
+		refreshed, refreshErr := c.refresh()
+		if refreshErr != nil {
+			c.logger.WithError(refreshErr).Warn("Couldn't refresh the cloud token")
+		}
+		if refreshed {
+			c.logger.Debug("The cloud token was refreshed, sending the request again")
+			if req.GetBody != nil {
+				req.Body, _ = req.GetBody()
+			}
+			if err = c.doWithRetries(req, v); !isUnauthenticated(err) {
+				return err
+			}
+		}
+	}
+	return tokenRejectedError{err}
+}
+
+func (c *Client) doWithRetries(req *http.Request, v interface{}) error {
 	// TODO(cuonglm): finding away to move this back to NewRequest
 	c.prepareHeaders(req)
 
@@ -132,13 +174,36 @@ func (c *Client) Do(req *http.Request, v interface{}) error {
 	return nil
 }
 
+// SetTokenRefresher sets the function that returns the new token when the cloud rejects the current
+// one, the requests are sent again if the token changed.
+func (c *Client) SetTokenRefresher(refresh func() (string, error)) {
+	c.refreshToken = refresh
+}
+
+func (c *Client) refresh() (bool, error) {
+	token, err := c.refreshToken()
+	if err != nil {
+		return false, err
+	}
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if token == "" || token == c.token {
+		return false, nil
+	}
+	c.token = token
+	return true, nil
+}
+
 func (c *Client) prepareHeaders(req *http.Request) {
 	if req.Header.Get("Content-Type") == "" {
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	if c.token != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Token %s", c.token))
+	c.tokenMu.RLock()
+	token := c.token
+	c.tokenMu.RUnlock()
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Token %s", token))
 	}
 
 	if shouldAddIdempotencyKey(req) {
@@ -22,6 +22,9 @@ package cloudapi
 
 import (
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
 	"time"
 
 	"gopkg.in/guregu/null.v3"
@@ -38,6 +41,10 @@ type Config struct {
 	ProjectID null.Int    `json:"projectID" envconfig:"K6_CLOUD_PROJECT_ID"`
 	Name      null.String `json:"name" envconfig:"K6_CLOUD_NAME"`
 
+	// The file with the token, which takes precedence over Token. It's read again when the cloud
+	// rejects the token, so tokens that expire can be renewed in it during long tests.
+	TokenFile null.String `json:"tokenFile" envconfig:"K6_CLOUD_TOKEN_FILE"`
+
 	Host        null.String `json:"host" envconfig:"K6_CLOUD_HOST"`
 	LogsTailURL null.String `json:"-" envconfig:"K6_CLOUD_LOGS_TAIL_URL"`
 	PushRefID   null.String `json:"pushRefID" envconfig:"K6_CLOUD_PUSH_REF_ID"`
@@ -45,8 +52,17 @@ type Config struct {
 	NoCompress  null.Bool   `json:"noCompress" envconfig:"K6_CLOUD_NO_COMPRESS"`
 	StopOnError null.Bool   `json:"stopOnError" envconfig:"K6_CLOUD_STOP_ON_ERROR"`
 
+	// The PEM file with the certificates of the CAs that are trusted for the connections to the
+	// cloud, besides the ones of the system, like the CA of a proxy that intercepts them.
+	CACertFile            null.String `json:"caCertFile" envconfig:"K6_CLOUD_CA_CERT_FILE"`
+	InsecureSkipTLSVerify null.Bool   `json:"insecureSkipTLSVerify" envconfig:"K6_CLOUD_INSECURE_SKIP_TLS_VERIFY"`
+
 	MaxMetricSamplesPerPackage null.Int `json:"maxMetricSamplesPerPackage" envconfig:"K6_CLOUD_MAX_METRIC_SAMPLES_PER_PACKAGE"`
 
+	// The maximum size in bytes of the local file where the metric samples that couldn't be sent
+	// during network outages are kept, until they can be sent again. 0 disables it, so they are dropped.
+	MetricSpillMaxSize null.Int `json:"metricSpillMaxSize" envconfig:"K6_CLOUD_METRIC_SPILL_MAX_SIZE"`
+
 	// The time interval between periodic API calls for sending samples to the cloud ingest service.
 	MetricPushInterval types.NullDuration `json:"metricPushInterval" envconfig:"K6_CLOUD_METRIC_PUSH_INTERVAL"`
 
@@ -55,6 +71,11 @@ type Config struct {
 
 	// Aggregation docs:
 	//
+	// All the aggregation settings can be set in the cloud collector of the JSON config, in
+	// options.ext.loadimpact of the script and with the K6_CLOUD_AGGREGATION_* environment
+	// variables, in increasing order of precedence, like the token, the name and the project ID.
+	// The cloud service can override them when the test run is created.
+	//
 	// If AggregationPeriod is specified and if it is greater than 0, HTTP metric aggregation
 	// with that period will be enabled. The general algorithm is this:
 	// - HTTP trail samples will be collected separately and not
@@ -153,6 +174,10 @@ type Config struct {
 
 	// Connection or request times with how many IQRs above Q3 to consier as non-aggregatable outliers.
 	AggregationOutlierIqrCoefUpper null.Float `json:"aggregationOutlierIqrCoefUpper" envconfig:"K6_CLOUD_AGGREGATION_OUTLIER_IQR_COEF_UPPER"`
+
+	// The values of the name tag of the HTTP requests that are never aggregated, so that all of their
+	// trails are sent individually, like the ones of the requests whose outliers matter the most.
+	AggregationExcludedNames []string `json:"aggregationExcludedNames" envconfig:"K6_CLOUD_AGGREGATION_EXCLUDED_NAMES"`
 }
 
 // NewConfig creates a new Config instance with default values for some fields.
@@ -164,6 +189,7 @@ func NewConfig() Config {
 		MetricPushInterval:         types.NewNullDuration(1*time.Second, false),
 		MetricPushConcurrency:      null.NewInt(1, false),
 		MaxMetricSamplesPerPackage: null.NewInt(100000, false),
+		MetricSpillMaxSize:         null.NewInt(100*1024*1024, false),
 		// Aggregation is disabled by default, since AggregationPeriod has no default value
 		// but if it's enabled manually or from the cloud service, those are the default values it will use:
 		AggregationCalcInterval:         types.NewNullDuration(3*time.Second, false),
@@ -185,6 +211,9 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.Token.Valid {
 		c.Token = cfg.Token
 	}
+	if cfg.TokenFile.Valid && cfg.TokenFile.String != "" {
+		c.TokenFile = cfg.TokenFile
+	}
 	if cfg.ProjectID.Valid && cfg.ProjectID.Int64 > 0 {
 		c.ProjectID = cfg.ProjectID
 	}
@@ -209,16 +238,29 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.StopOnError.Valid {
 		c.StopOnError = cfg.StopOnError
 	}
+	if cfg.CACertFile.Valid && cfg.CACertFile.String != "" {
+		c.CACertFile = cfg.CACertFile
+	}
+	if cfg.InsecureSkipTLSVerify.Valid {
+		c.InsecureSkipTLSVerify = cfg.InsecureSkipTLSVerify
+	}
 	if cfg.MaxMetricSamplesPerPackage.Valid {
 		c.MaxMetricSamplesPerPackage = cfg.MaxMetricSamplesPerPackage
 	}
+	if cfg.MetricSpillMaxSize.Valid {
+		c.MetricSpillMaxSize = cfg.MetricSpillMaxSize
+	}
 	if cfg.MetricPushInterval.Valid {
 		c.MetricPushInterval = cfg.MetricPushInterval
 	}
 	if cfg.MetricPushConcurrency.Valid {
 		c.MetricPushConcurrency = cfg.MetricPushConcurrency
 	}
+	return c.applyAggregation(cfg)
+}
 
+// applyAggregation saves the non-zero aggregation settings from the passed config in the receiver.
+func (c Config) applyAggregation(cfg Config) Config {
 	if cfg.AggregationPeriod.Valid {
 		c.AggregationPeriod = cfg.AggregationPeriod
 	}
@@ -246,11 +288,14 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.AggregationOutlierIqrCoefUpper.Valid {
 		c.AggregationOutlierIqrCoefUpper = cfg.AggregationOutlierIqrCoefUpper
 	}
+	if len(cfg.AggregationExcludedNames) > 0 {
+		c.AggregationExcludedNames = cfg.AggregationExcludedNames
+	}
 	return c
 }
 
-// MergeFromExternal merges three fields from the JSON in a loadimpact key of
-// the provided external map. Used for options.ext.loadimpact settings.
+// MergeFromExternal merges three fields and the aggregation settings from the JSON in a loadimpact
+// key of the provided external map. Used for options.ext.loadimpact settings.
 func MergeFromExternal(external map[string]json.RawMessage, conf *Config) error {
 	if val, ok := external["loadimpact"]; ok {
 		// TODO: Important! Separate configs and fix the whole 2 configs mess!
@@ -258,7 +303,7 @@ func MergeFromExternal(external map[string]json.RawMessage, conf *Config) error
 		if err := json.Unmarshal(val, &tmpConfig); err != nil {
 			return err
 		}
-		// Only take out the ProjectID, Name and Token from the options.ext.loadimpact map:
+		// Only take out the ProjectID, Name, Token and the aggregation settings from the options.ext.loadimpact map:
 		if tmpConfig.ProjectID.Valid {
 			conf.ProjectID = tmpConfig.ProjectID
 		}
@@ -268,6 +313,7 @@ func MergeFromExternal(external map[string]json.RawMessage, conf *Config) error
 		if tmpConfig.Token.Valid {
 			conf.Token = tmpConfig.Token
 		}
+		*conf = conf.applyAggregation(tmpConfig)
 	}
 	return nil
 }
@@ -300,5 +346,39 @@ func GetConsolidatedConfig(
 		result.Name = null.StringFrom(configArg)
 	}
 
+	if result.TokenFile.Valid && result.TokenFile.String != "" {
+		token, err := result.ReadTokenFile()
+		if err != nil {
+			return result, err
+		}
+		result.Token = null.StringFrom(token)
+	}
+
 	return result, nil
 }
+
+// ReadTokenFile returns the token in the TokenFile.
+func (c Config) ReadTokenFile() (string, error) {
+	data, err := ioutil.ReadFile(c.TokenFile.String)
+	if err != nil {
+		return "", fmt.Errorf("couldn't read the cloud token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("the cloud token file %s is empty", c.TokenFile.String)
+	}
+	return token, nil
+}
+
+// reloadToken reads the TokenFile again, if it's set, and returns whether the token changed.
+func (c *Config) reloadToken() bool {
+	if !c.TokenFile.Valid || c.TokenFile.String == "" {
+		return false
+	}
+	token, err := c.ReadTokenFile()
+	if err != nil || token == c.Token.String {
+		return false
+	}
+	c.Token = null.StringFrom(token)
+	return true
+}
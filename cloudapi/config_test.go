@@ -44,6 +44,7 @@ func TestConfigApply(t *testing.T) {
 
 	full := Config{
 		Token:                           null.NewString("Token", true),
+		TokenFile:                       null.NewString("TokenFile", true),
 		ProjectID:                       null.NewInt(1, true),
 		Name:                            null.NewString("Name", true),
 		Host:                            null.NewString("Host", true),
@@ -52,7 +53,10 @@ func TestConfigApply(t *testing.T) {
 		WebAppURL:                       null.NewString("foo", true),
 		NoCompress:                      null.NewBool(true, true),
 		StopOnError:                     null.NewBool(true, true),
+		CACertFile:                      null.NewString("CACertFile", true),
+		InsecureSkipTLSVerify:           null.NewBool(true, true),
 		MaxMetricSamplesPerPackage:      null.NewInt(2, true),
+		MetricSpillMaxSize:              null.NewInt(1024, true),
 		MetricPushInterval:              types.NewNullDuration(1*time.Second, true),
 		MetricPushConcurrency:           null.NewInt(3, true),
 		AggregationPeriod:               types.NewNullDuration(2*time.Second, true),
@@ -64,6 +68,7 @@ func TestConfigApply(t *testing.T) {
 		AggregationOutlierIqrRadius:     null.NewFloat(6, true),
 		AggregationOutlierIqrCoefLower:  null.NewFloat(7, true),
 		AggregationOutlierIqrCoefUpper:  null.NewFloat(8, true),
+		AggregationExcludedNames:        []string{"https://example.com/"},
 	}
 
 	assert.Equal(t, full, full.Apply(empty))
@@ -83,9 +88,29 @@ func TestGetConsolidatedConfig(t *testing.T) { //nolint:paralleltest
 	require.NoError(t, err)
 	require.Equal(t, config.Token.String, "ext")
 
+	config, err = GetConsolidatedConfig(json.RawMessage(`{"aggregationPeriod":"5s"}`), nil, "",
+		map[string]json.RawMessage{"loadimpact": json.RawMessage(`{"aggregationPeriod":"3s"}`)})
+	require.NoError(t, err)
+	require.Equal(t, types.NewNullDuration(3*time.Second, true), config.AggregationPeriod)
+
 	require.NoError(t, os.Setenv("K6_CLOUD_TOKEN", "envvalue")) // TODO drop when we don't use envconfig
 	config, err = GetConsolidatedConfig(json.RawMessage(`{"token":"jsonraw"}`), nil, "",
 		map[string]json.RawMessage{"loadimpact": json.RawMessage(`{"token":"ext"}`)})
 	require.NoError(t, err)
 	require.Equal(t, config.Token.String, "envvalue")
 }
+
+func TestMergeFromExternalAggregation(t *testing.T) {
+	t.Parallel()
+	conf := NewConfig()
+	external := map[string]json.RawMessage{"loadimpact": json.RawMessage(`{
+		"name": "test", "host": "https://example.com", "aggregationPeriod": "3s",
+		"aggregationOutlierIqrCoefUpper": 2.5, "aggregationExcludedNames": ["https://example.com/login"]}`)}
+	require.NoError(t, MergeFromExternal(external, &conf))
+	assert.Equal(t, "test", conf.Name.String)
+	assert.False(t, conf.Host.Valid)
+	assert.Equal(t, types.NewNullDuration(3*time.Second, true), conf.AggregationPeriod)
+	assert.Equal(t, null.NewFloat(2.5, true), conf.AggregationOutlierIqrCoefUpper)
+	assert.Equal(t, null.NewFloat(1.5, false), conf.AggregationOutlierIqrCoefLower)
+	assert.Equal(t, []string{"https://example.com/login"}, conf.AggregationExcludedNames)
+}
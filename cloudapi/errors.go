@@ -31,8 +31,44 @@ var (
 	ErrNotAuthorized    = errors.New("Not allowed to upload result to Load Impact cloud")
 	ErrNotAuthenticated = errors.New("Failed to authenticate with Load Impact cloud")
 	ErrUnknown          = errors.New("An error occurred talking to Load Impact cloud")
+
+	// ErrTokenRejected is matched by the errors of the requests that the cloud rejected because
+	// of the token, after it was refreshed if possible.
+	ErrTokenRejected = errors.New("The token was rejected by the cloud, it may have expired, " +
+		"please use `k6 login cloud`, set a new K6_CLOUD_TOKEN or update the K6_CLOUD_TOKEN_FILE")
 )
 
+// tokenRejectedError is the error of a request rejected because of the token, it adds the hint of
+// ErrTokenRejected to the error of the request.
+type tokenRejectedError struct {
+	err error
+}
+
+func (e tokenRejectedError) Error() string {
+	return e.err.Error() + "\n " + ErrTokenRejected.Error()
+}
+
+func (e tokenRejectedError) Unwrap() error {
+	return e.err
+}
+
+func (e tokenRejectedError) Is(target error) bool {
+	return target == ErrTokenRejected //nolint:errorlint
+}
+
+// isUnauthenticated returns whether the error is of a request rejected with 401 Unauthorized.
+func isUnauthenticated(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrNotAuthenticated) {
+		return true
+	}
+	var errResp ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil &&
+		errResp.Response.StatusCode == http.StatusUnauthorized
+}
+
 // ErrorResponse represents an error cause by talking to the API
 type ErrorResponse struct {
 	Response *http.Response `json:"-"`
@@ -25,7 +25,10 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -82,6 +85,112 @@ func (m *msg) Log(logger logrus.FieldLogger) {
 	}
 }
 
+const (
+	// logsDropSummaryInterval is how often the number of the log lines dropped by the logs server is
+	// logged.
+	logsDropSummaryInterval = 30 * time.Second
+	// logsMaxRedials is how many times the connection to the logs server is retried, after it was
+	// lost and until a message is received again.
+	logsMaxRedials       = 5
+	logsRedialBackoff    = time.Second
+	logsMaxRedialBackoff = 30 * time.Second
+)
+
+// logsTail is the state of the streaming of the logs of a test run, kept between the connections
+// to the logs server.
+type logsTail struct {
+	redialBackoff   time.Duration
+	summaryInterval time.Duration
+
+	mu sync.Mutex
+	// start is the time the next connection streams the logs from, just after the last log line
+	start time.Time
+	// firstDropped is the time of the first log line dropped by the server since the last
+	// connection, the next one starts from it so that they aren't missed, even if the following
+	// log lines are streamed again
+	firstDropped time.Time
+	// dropped is the number of the dropped log lines since the last summary
+	dropped int64
+	// totalDropped is the number of all the dropped log lines
+	totalDropped int64
+}
+
+func newLogsTail(start time.Time) *logsTail {
+	return &logsTail{
+		redialBackoff:   logsRedialBackoff,
+		summaryInterval: logsDropSummaryInterval,
+		start:           start,
+	}
+}
+
+// track updates the state of the tail with the log lines and the dropped entries of a message.
+func (t *logsTail) track(m *msg) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, stream := range m.Streams {
+		for _, value := range stream.Values {
+			nsec, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			if next := time.Unix(0, nsec+1); next.After(t.start) {
+				t.start = next
+			}
+		}
+	}
+	for _, dropped := range m.DroppedEntries {
+		t.dropped++
+		t.totalDropped++
+		nsec, err := strconv.ParseInt(dropped.Timestamp, 10, 64)
+		if err != nil {
+			continue
+		}
+		if ts := time.Unix(0, nsec); t.firstDropped.IsZero() || ts.Before(t.firstDropped) {
+			t.firstDropped = ts
+		}
+	}
+}
+
+// next returns the time the next connection has to stream the logs from.
+func (t *logsTail) next() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	start := t.start
+	if !t.firstDropped.IsZero() && t.firstDropped.Before(start) {
+		start = t.firstDropped
+	}
+	t.firstDropped = time.Time{}
+	return start
+}
+
+// summarize logs how many log lines were dropped in each summary interval until ctx is done, and
+// then how many were dropped in total.
+func (t *logsTail) summarize(ctx context.Context, logger logrus.FieldLogger) {
+	ticker := time.NewTicker(t.summaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			t.mu.Lock()
+			total := t.totalDropped
+			t.mu.Unlock()
+			if total > 0 {
+				logger.Warnf("%d log lines were dropped by the cloud logs server in total", total)
+			}
+			return
+		case <-ticker.C:
+		}
+		t.mu.Lock()
+		dropped := t.dropped
+		t.dropped = 0
+		t.mu.Unlock()
+		if dropped > 0 {
+			logger.Warnf("%d log lines dropped in the last %s, consider raising the log level or filtering the logs",
+				dropped, t.summaryInterval)
+		}
+	}
+}
+
 func labelsToLogrusFields(labels map[string]string) logrus.Fields {
 	fields := make(logrus.Fields, len(labels))
 
@@ -92,16 +201,62 @@ func labelsToLogrusFields(labels map[string]string) logrus.Fields {
 	return fields
 }
 
-func (c *Config) getRequest(referenceID string, start time.Duration) (*url.URL, error) {
+// LogsFilter narrows the logs of a test run that are streamed, it's added to the query of the
+// logs server, so the other logs aren't even sent.
+type LogsFilter struct {
+	// Labels are matchers of the labels of the logs, like instance_id=1, scenario!=setup or
+	// lz=~amazon:.*, the values can also be quoted.
+	Labels []string
+	// Line is the text the log lines have to contain.
+	Line string
+}
+
+var logsLabelMatcherRegex = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*(.*)$`)
+
+// query returns the LogQL query of the logs of the test run that match the filter.
+func (f LogsFilter) query(referenceID string) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "{test_run_id=%s", strconv.Quote(referenceID))
+	for _, label := range f.Labels {
+		match := logsLabelMatcherRegex.FindStringSubmatch(label)
+		if match == nil {
+			return "", fmt.Errorf("invalid label matcher '%s', it has to be like name=value, name!=value, "+
+				"name=~regex or name!~regex", label)
+		}
+		name, op, value := match[1], match[2], strings.TrimSpace(match[3])
+		if name == "test_run_id" {
+			return "", fmt.Errorf("invalid label matcher '%s', the test run can't be changed", label)
+		}
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return "", fmt.Errorf("invalid value of the label matcher '%s': %w", label, err)
+			}
+			value = unquoted
+		}
+		fmt.Fprintf(&sb, ",%s%s%s", name, op, strconv.Quote(value))
+	}
+	sb.WriteString("}")
+	if f.Line != "" {
+		fmt.Fprintf(&sb, " |= %s", strconv.Quote(f.Line))
+	}
+	return sb.String(), nil
+}
+
+func (c *Config) getRequest(referenceID string, start time.Time, filter LogsFilter) (*url.URL, error) {
 	u, err := url.Parse(c.LogsTailURL.String)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't parse cloud logs host %w", err)
 	}
 
-	u.RawQuery = fmt.Sprintf(`query={test_run_id="%s"}&start=%d`,
-		referenceID,
-		time.Now().Add(-start).UnixNano(),
-	)
+	query, err := filter.query(referenceID)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = url.Values{
+		"query": []string{query},
+		"start": []string{strconv.FormatInt(start.UnixNano(), 10)},
+	}.Encode()
 
 	return u, nil
 }
@@ -111,23 +266,96 @@ func (c *Config) getRequest(referenceID string, start time.Duration) (*url.URL,
 func (c *Config) StreamLogsToLogger(
 	ctx context.Context, logger logrus.FieldLogger, referenceID string, start time.Duration,
 ) error {
-	u, err := c.getRequest(referenceID, start)
+	return c.StreamFilteredLogsToLogger(ctx, logger, referenceID, start, LogsFilter{})
+}
+
+// StreamFilteredLogsToLogger is like StreamLogsToLogger, but only the logs that match the filter
+// are streamed.
+func (c *Config) StreamFilteredLogsToLogger(
+	ctx context.Context, logger logrus.FieldLogger, referenceID string, start time.Duration, filter LogsFilter,
+) error {
+	tail := newLogsTail(time.Now().Add(-start))
+	return c.streamLogsToLogger(ctx, logger, referenceID, filter, tail)
+}
+
+// streamLogsToLogger streams the logs, and connects again when the connection is lost after it was
+// established, from the time of the first dropped log line or of the last streamed one.
+func (c *Config) streamLogsToLogger(
+	ctx context.Context, logger logrus.FieldLogger, referenceID string, filter LogsFilter, tail *logsTail,
+) error {
+	summaryCtx, summaryCancel := context.WithCancel(ctx)
+	defer summaryCancel()
+	go tail.summarize(summaryCtx, logger)
+
+	backoff := tail.redialBackoff
+	for attempt := 1; ; attempt++ {
+		connected, received, err := c.streamLogsConn(ctx, logger, referenceID, filter, tail)
+		if err == nil || ctx.Err() != nil {
+			return nil
+		}
+		if !connected && attempt == 1 {
+			return err
+		}
+		if received {
+			attempt, backoff = 1, tail.redialBackoff
+		}
+		if attempt > logsMaxRedials {
+			return err
+		}
+		logger.WithError(err).Warnf("The connection to the cloud logs was lost, reconnecting in %s...", backoff)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > logsMaxRedialBackoff {
+			backoff = logsMaxRedialBackoff
+		}
+	}
+}
+
+// streamLogsConn streams the logs with a single connection to the logs server, until ctx is done
+// or the connection fails. It returns whether the connection was established and whether any
+// message was received with it.
+func (c *Config) streamLogsConn(
+	ctx context.Context, logger logrus.FieldLogger, referenceID string, filter LogsFilter, tail *logsTail,
+) (connected bool, received bool, err error) {
+	u, err := c.getRequest(referenceID, tail.next(), filter)
 	if err != nil {
-		return err
+		return false, false, err
 	}
 
-	headers := make(http.Header)
-	headers.Add("Sec-WebSocket-Protocol", "token="+c.Token.String)
+	dialer, err := c.logsDialer()
+	if err != nil {
+		return false, false, err
+	}
 
-	// We don't need to close the http body or use it for anything until we want to actually log
-	// what the server returned as body when it errors out
-	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), headers) //nolint:bodyclose
+	dial := func() (*websocket.Conn, *http.Response, error) {
+		headers := make(http.Header)
+		headers.Add("Sec-WebSocket-Protocol", "token="+c.Token.String)
+
+		// We don't need to close the http body or use it for anything until we want to actually log
+		// what the server returned as body when it errors out
+		return dialer.DialContext(ctx, u.String(), headers) //nolint:bodyclose
+	}
+	conn, resp, err := dial()
+	if err != nil && resp != nil && resp.StatusCode == http.StatusUnauthorized {
+		if c.reloadToken() {
+			logger.Debug("The cloud token was refreshed, connecting to the cloud logs again")
+			conn, resp, err = dial()
+		}
+		if err != nil && resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			err = tokenRejectedError{err}
+		}
+	}
 	if err != nil {
-		return err
+		return false, false, err
 	}
 
+	connCtx, connCancel := context.WithCancel(ctx)
+	defer connCancel()
 	go func() {
-		<-ctx.Done()
+		<-connCtx.Done()
 
 		_ = conn.WriteControl(
 			websocket.CloseMessage,
@@ -138,10 +366,16 @@ func (c *Config) StreamLogsToLogger(
 	}()
 
 	msgBuffer := make(chan []byte, 10)
+	processed := make(chan struct{})
 
-	defer close(msgBuffer)
+	// the messages are all processed before returning, so the state of the tail is up to date
+	defer func() {
+		close(msgBuffer)
+		<-processed
+	}()
 
 	go func() {
+		defer close(processed)
 		for message := range msgBuffer {
 			var m msg
 			err := easyjson.Unmarshal(message, &m)
@@ -152,6 +386,7 @@ func (c *Config) StreamLogsToLogger(
 			}
 
 			m.Log(logger)
+			tail.track(&m)
 		}
 	}()
 
@@ -159,19 +394,20 @@ func (c *Config) StreamLogsToLogger(
 		_, message, err := conn.ReadMessage()
 		select { // check if we should stop before continuing
 		case <-ctx.Done():
-			return nil
+			return true, received, nil
 		default:
 		}
 
 		if err != nil {
 			logger.WithError(err).Warn("error reading a message from the cloud")
 
-			return err
+			return true, received, err
 		}
+		received = true
 
 		select {
 		case <-ctx.Done():
-			return nil
+			return true, received, nil
 		case msgBuffer <- message:
 		}
 	}
@@ -21,14 +21,21 @@
 package cloudapi
 
 import (
+	"context"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/mailru/easyjson"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
 
 	"go.k6.io/k6/lib/testutils"
 )
@@ -123,3 +130,96 @@ func TestMSGLog(t *testing.T) {
 		require.Equal(t, expectTime, entry.Time)
 	}
 }
+
+func TestLogsFilterQuery(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		filter   LogsFilter
+		expected string
+	}{
+		{LogsFilter{}, `{test_run_id="123"}`},
+		{
+			LogsFilter{Labels: []string{"instance_id=1", `scenario != "a b"`, "lz=~amazon:.*"}, Line: `status "503"`},
+			`{test_run_id="123",instance_id="1",scenario!="a b",lz=~"amazon:.*"} |= "status \"503\""`,
+		},
+		{LogsFilter{Labels: []string{"level!~debug|info"}}, `{test_run_id="123",level!~"debug|info"}`},
+	}
+	for _, tc := range testCases {
+		query, err := tc.filter.query("123")
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, query)
+	}
+
+	for _, label := range []string{"1abc=x", "scenario", "test_run_id=456", `scenario="a`} {
+		_, err := LogsFilter{Labels: []string{label}}.query("123")
+		assert.Error(t, err, label)
+	}
+
+	c := Config{LogsTailURL: null.StringFrom("wss://cloudlogs.k6.io/api/v1/tail")}
+	u, err := c.getRequest("123", time.Now(), LogsFilter{Labels: []string{"instance_id=1"}})
+	require.NoError(t, err)
+	assert.Equal(t, `{test_run_id="123",instance_id="1"}`, u.Query().Get("query"))
+	assert.NotEmpty(t, u.Query().Get("start"))
+}
+
+func TestStreamLogsRedial(t *testing.T) {
+	t.Parallel()
+	var (
+		mu     sync.Mutex
+		starts []string
+	)
+	upgrader := websocket.Upgrader{Subprotocols: []string{"token=token"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		starts = append(starts, r.URL.Query().Get("start"))
+		n := len(starts)
+		mu.Unlock()
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+		if n == 1 {
+			// a log line and an older dropped one, and then the connection is lost
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{
+				"streams": [{"stream": {"level": "info"}, "values": [["2000", "first"]]}],
+				"dropped_entries": [{"labels": {}, "timestamp": "1500"}]}`)))
+			return
+		}
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{
+			"streams": [{"stream": {"level": "info"}, "values": [["2500", "second"]]}]}`)))
+		_, _, _ = conn.ReadMessage()
+	}))
+	defer srv.Close()
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	hook := &testutils.SimpleLogrusHook{HookedLevels: logrus.AllLevels}
+	logger.AddHook(hook)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := Config{
+		LogsTailURL: null.StringFrom("ws" + strings.TrimPrefix(srv.URL, "http")),
+		Token:       null.StringFrom("token"),
+	}
+	tail := newLogsTail(time.Unix(0, 1000))
+	tail.redialBackoff, tail.summaryInterval = time.Millisecond, 10*time.Millisecond
+	done := make(chan error)
+	go func() { done <- c.streamLogsToLogger(ctx, logger, "123", LogsFilter{}, tail) }()
+
+	require.Eventually(t, func() bool {
+		for _, e := range hook.Drain() {
+			if e.Message == "second" {
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	mu.Lock()
+	assert.Equal(t, []string{"1000", "1500"}, starts)
+	mu.Unlock()
+	assert.Equal(t, int64(1), tail.totalDropped)
+	assert.Equal(t, time.Unix(0, 2501), tail.next())
+}
@@ -0,0 +1,108 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloudapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"go.k6.io/k6/lib"
+)
+
+// LoadZoneDistribution is the share of the VUs of a test, or of a scenario, in a load zone.
+type LoadZoneDistribution struct {
+	LoadZone string `json:"loadZone"`
+	Percent  int64  `json:"percent"`
+}
+
+// ScenarioConfig is the cloud config of a scenario, in the loadimpact key of its ext, which
+// overrides the one of the whole test in options.ext.loadimpact for the VUs of the scenario.
+type ScenarioConfig struct {
+	Distribution map[string]LoadZoneDistribution `json:"distribution"`
+}
+
+// testOnlyScenarioKeys are the keys of ext.loadimpact that can't be set for a scenario, since
+// they are the ones of the whole test run.
+var testOnlyScenarioKeys = []string{"token", "projectID", "name"} //nolint:gochecknoglobals
+
+// GetScenarioConfig returns the cloud config of a scenario, and false if it doesn't have one.
+func GetScenarioConfig(scenario lib.ExecutorConfig) (ScenarioConfig, bool, error) {
+	val, ok := scenario.GetExt()["loadimpact"]
+	if !ok {
+		return ScenarioConfig{}, false, nil
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(val, &keys); err != nil {
+		return ScenarioConfig{}, false, fmt.Errorf("invalid ext.loadimpact of the scenario %s: %w", scenario.GetName(), err)
+	}
+	for _, key := range testOnlyScenarioKeys {
+		if _, ok := keys[key]; ok {
+			return ScenarioConfig{}, false, fmt.Errorf("ext.loadimpact.%s of the scenario %s can only be set "+
+				"for the whole test, in options.ext.loadimpact", key, scenario.GetName())
+		}
+	}
+
+	var conf ScenarioConfig
+	if err := json.Unmarshal(val, &conf); err != nil {
+		return ScenarioConfig{}, false, fmt.Errorf("invalid ext.loadimpact of the scenario %s: %w", scenario.GetName(), err)
+	}
+	if err := validateDistribution(conf.Distribution); err != nil {
+		return ScenarioConfig{}, false, fmt.Errorf("invalid ext.loadimpact.distribution of the scenario %s: %w",
+			scenario.GetName(), err)
+	}
+	return conf, true, nil
+}
+
+// ValidateScenarioConfigs checks the cloud configs of all the scenarios that have one.
+func ValidateScenarioConfigs(scenarios lib.ScenarioConfigs) error {
+	names := make([]string, 0, len(scenarios))
+	for name := range scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, _, err := GetScenarioConfig(scenarios[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateDistribution(distribution map[string]LoadZoneDistribution) error {
+	if distribution == nil {
+		return nil
+	}
+	var total int64
+	for label, d := range distribution {
+		if d.LoadZone == "" {
+			return fmt.Errorf("the load zone of %s is empty", label)
+		}
+		if d.Percent <= 0 || d.Percent > 100 {
+			return fmt.Errorf("the percent of %s has to be between 1 and 100, but it's %d", label, d.Percent)
+		}
+		total += d.Percent
+	}
+	if total != 100 {
+		return fmt.Errorf("the percents of the load zones have to add up to 100, but they add up to %d", total)
+	}
+	return nil
+}
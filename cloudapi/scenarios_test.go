@@ -0,0 +1,72 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloudapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib"
+	_ "go.k6.io/k6/lib/executor"
+)
+
+func TestScenarioConfigs(t *testing.T) {
+	t.Parallel()
+	parse := func(ext string) lib.ScenarioConfigs {
+		var scenarios lib.ScenarioConfigs
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"default": {"executor": "shared-iterations"},
+			"eu": {"executor": "constant-vus", "duration": "1m", "ext": `+ext+`}}`), &scenarios))
+		return scenarios
+	}
+
+	scenarios := parse(`{"loadimpact": {"distribution": {
+		"dublin": {"loadZone": "amazon:ie:dublin", "percent": 60},
+		"frankfurt": {"loadZone": "amazon:de:frankfurt", "percent": 40}}}}`)
+	require.NoError(t, ValidateScenarioConfigs(scenarios))
+	conf, ok, err := GetScenarioConfig(scenarios["eu"])
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, LoadZoneDistribution{LoadZone: "amazon:ie:dublin", Percent: 60}, conf.Distribution["dublin"])
+	_, ok, err = GetScenarioConfig(scenarios["default"])
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	data, err := json.Marshal(scenarios)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"ext":{"loadimpact":{"distribution"`)
+
+	invalid := map[string]string{
+		`{"loadimpact": {"projectID": 1}}`: "can only be set for the whole test",
+		`{"loadimpact": []}`:               "invalid ext.loadimpact of the scenario eu",
+		`{"loadimpact": {"distribution": {"a": {"loadZone": "amazon:us:ashburn", "percent": 50}}}}`: "add up to 50",
+		`{"loadimpact": {"distribution": {"a": {"percent": 100}}}}`:                                 "the load zone of a is empty",
+		`{"loadimpact": {"distribution": {"a": {"loadZone": "amazon:us:ashburn", "percent": 0}}}}`:  "between 1 and 100",
+	}
+	for ext, expected := range invalid {
+		err := ValidateScenarioConfigs(parse(ext))
+		require.Error(t, err, ext)
+		assert.Contains(t, err.Error(), expected, ext)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloudapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// logsHandshakeTimeout is the timeout of the handshake of the websocket of the cloud logs.
+const logsHandshakeTimeout = 45 * time.Second
+
+// TLSConfig returns the TLS config of the connections to the cloud, with the CAs of
+// CACertFile and InsecureSkipTLSVerify, it's nil if they aren't set.
+func (c Config) TLSConfig() (*tls.Config, error) {
+	hasCACerts := c.CACertFile.Valid && c.CACertFile.String != ""
+	if !hasCACerts && !c.InsecureSkipTLSVerify.Bool {
+		return nil, nil //nolint:nilnil
+	}
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipTLSVerify.Bool, //nolint:gosec
+		MinVersion:         tls.VersionTLS12,
+	}
+	if hasCACerts {
+		pemCerts, err := ioutil.ReadFile(c.CACertFile.String)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the CA certificates of the cloud: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pemCerts) {
+			return nil, fmt.Errorf("the file %s doesn't have any valid PEM certificate", c.CACertFile.String)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// HTTPTransport returns the transport of the requests to the cloud API, it uses the proxy of the
+// HTTPS_PROXY and NO_PROXY environment variables and the TLS config of the config.
+func (c Config) HTTPTransport() (*http.Transport, error) {
+	tlsConfig, err := c.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// logsDialer returns the dialer of the websocket of the cloud logs, with the same proxy and TLS
+// config as the transport of the cloud API.
+func (c Config) logsDialer() (*websocket.Dialer, error) {
+	tlsConfig, err := c.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: logsHandshakeTimeout,
+		TLSClientConfig:  tlsConfig,
+	}, nil
+}
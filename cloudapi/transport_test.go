@@ -0,0 +1,71 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloudapi
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/testutils"
+)
+
+func TestClientTLSConfig(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fprintf(t, w, `{"run_status": 2}`)
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(caFile, caPEM, 0o600))
+	invalidFile := filepath.Join(t.TempDir(), "invalid.pem")
+	require.NoError(t, ioutil.WriteFile(invalidFile, []byte("invalid"), 0o600))
+
+	getProgress := func(conf Config) error {
+		conf.Host = null.StringFrom(server.URL)
+		client, err := NewClientFromConfig(testutils.NewLogger(t), conf, "1.0")
+		if err != nil {
+			return err
+		}
+		client.retries = 1
+		_, err = client.GetTestProgress("1")
+		return err
+	}
+
+	assert.Error(t, getProgress(Config{}))
+	assert.NoError(t, getProgress(Config{CACertFile: null.StringFrom(caFile)}))
+	assert.NoError(t, getProgress(Config{InsecureSkipTLSVerify: null.BoolFrom(true)}))
+	assert.Error(t, getProgress(Config{CACertFile: null.StringFrom(invalidFile)}))
+	assert.Error(t, getProgress(Config{CACertFile: null.StringFrom(filepath.Join(t.TempDir(), "missing.pem"))}))
+
+	tlsConfig, err := Config{}.TLSConfig()
+	require.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+}
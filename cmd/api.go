@@ -0,0 +1,71 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/spf13/afero"
+
+	"go.k6.io/k6/api"
+	"go.k6.io/k6/api/v1/client"
+)
+
+// getAPIConfig returns the configuration of the REST API server, the tokens are only taken from
+// the environment variables so they aren't in the process list.
+func getAPIConfig() api.Config {
+	return api.Config{
+		Address:         address,
+		Token:           os.Getenv("K6_API_TOKEN"),
+		ReadToken:       os.Getenv("K6_API_READ_TOKEN"),
+		TLSCertFile:     apiTLSCert,
+		TLSKeyFile:      apiTLSKey,
+		AllowedNetworks: apiAllowedNetworks,
+	}
+}
+
+// newAPIClient returns the client of the REST API server at the --address, with the token of
+// K6_API_TOKEN, or K6_API_READ_TOKEN for the commands that only get the status.
+func newAPIClient() (*client.Client, error) {
+	token := os.Getenv("K6_API_TOKEN")
+	if token == "" {
+		token = os.Getenv("K6_API_READ_TOKEN")
+	}
+	options := []client.Option{client.WithToken(token)}
+	if apiCACert != "" {
+		pem, err := afero.ReadFile(defaultFs, apiCACert)
+		if err != nil {
+			return nil, err
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("the REST API CA certificate %s doesn't have any PEM certificates", apiCACert)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+		options = append(options, client.WithHTTPClient(&http.Client{Transport: transport}))
+	}
+	return client.New(address, options...)
+}
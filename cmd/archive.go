@@ -21,17 +21,29 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
+	"go.k6.io/k6/lib"
 	"go.k6.io/k6/loader"
 )
 
-var archiveOut = "archive.tar"
+// archivePassphraseEnvVar is the environment variable with the passphrase of the encrypted archives,
+// it isn't a flag so the passphrase isn't in the shell history or in the processes list.
+const archivePassphraseEnvVar = "K6_ARCHIVE_PASSPHRASE"
+
+//nolint:gochecknoglobals
+var (
+	archiveOut       = "archive.tar"
+	archiveEncrypt   bool
+	archiveRedactEnv []string
+)
 
 func getArchiveCmd(logger *logrus.Logger) *cobra.Command {
 	// archiveCmd represents the pause command
@@ -40,11 +52,19 @@ func getArchiveCmd(logger *logrus.Logger) *cobra.Command {
 		Short: "Create an archive",
 		Long: `Create an archive.
 
-An archive is a fully self-contained test run, and can be executed identically elsewhere.`,
+An archive is a fully self-contained test run, and can be executed identically elsewhere.
+
+The files of the archive can be encrypted with --encrypt, with the passphrase of the
+K6_ARCHIVE_PASSPHRASE environment variable, which is also needed for running it. The
+environment variables with tokens can be left out of the archive with --redact-env, their
+values are also replaced in the options, so they have to be given again when it's run.`,
 		Example: `
   # Archive a test run.
   k6 archive -u 10 -d 10s -O myarchive.tar script.js
 
+  # Archive a test run with encrypted files and without the tokens of the environment.
+  K6_ARCHIVE_PASSPHRASE=... k6 archive --encrypt --redact-env '*_TOKEN' -e API_TOKEN=... script.js
+
   # Run the resulting archive.
   k6 run myarchive.tar`[1:],
 		Args: cobra.ExactArgs(1),
@@ -92,6 +112,25 @@ An archive is a fully self-contained test run, and can be executed identically e
 
 			// Archive.
 			arc := r.MakeArchive()
+			if len(archiveRedactEnv) > 0 {
+				names, err := arc.RedactEnv(archiveRedactEnv, buildEnvMap(os.Environ()))
+				if err != nil {
+					return err
+				}
+				if len(names) > 0 {
+					logger.Infof("The environment variables %s aren't in the archive", strings.Join(names, ", "))
+				}
+			}
+			if archiveEncrypt {
+				passphrase := os.Getenv(archivePassphraseEnvVar)
+				if passphrase == "" {
+					return fmt.Errorf("the passphrase of the encryption has to be in the %s environment variable",
+						archivePassphraseEnvVar)
+				}
+				if err := arc.Encrypt(passphrase); err != nil {
+					return err
+				}
+			}
 			f, err := os.Create(archiveOut)
 			if err != nil {
 				return err
@@ -113,5 +152,24 @@ func archiveCmdFlagSet() *pflag.FlagSet {
 	flags.AddFlagSet(runtimeOptionFlagSet(false))
 	// TODO: figure out a better way to handle the CLI flags - global variables are not very testable... :/
 	flags.StringVarP(&archiveOut, "archive-out", "O", archiveOut, "archive output filename")
+	flags.BoolVar(&archiveEncrypt, "encrypt", false,
+		"encrypt the files of the archive with the passphrase of K6_ARCHIVE_PASSPHRASE")
+	flags.StringArrayVar(&archiveRedactEnv, "redact-env", nil,
+		"leave the environment variables with names matching the `pattern` out of the archive, "+
+			"and replace their values in the options, can be repeated")
 	return flags
 }
+
+// decryptArchive decrypts the files of the archive with the passphrase of K6_ARCHIVE_PASSPHRASE, if
+// they are encrypted.
+func decryptArchive(arc *lib.Archive) error {
+	if arc.Encryption == nil {
+		return nil
+	}
+	passphrase := os.Getenv(archivePassphraseEnvVar)
+	if passphrase == "" {
+		return fmt.Errorf("the archive is encrypted, its passphrase has to be in the %s environment variable",
+			archivePassphraseEnvVar)
+	}
+	return arc.Decrypt(passphrase)
+}
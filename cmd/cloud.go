@@ -45,6 +45,7 @@ import (
 	"go.k6.io/k6/errext/exitcodes"
 	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/consts"
+	"go.k6.io/k6/lib/types"
 	"go.k6.io/k6/loader"
 	"go.k6.io/k6/ui/pb"
 )
@@ -53,8 +54,20 @@ import (
 var (
 	exitOnRunning = os.Getenv("K6_EXIT_ON_RUNNING") != ""
 	showCloudLogs = true
+	cloudAsync    = false
 )
 
+// cloudAsyncTestRun is the machine-readable output of k6 cloud --async, for checking the test run
+// later with k6 cloud status, results and logs.
+type cloudAsyncTestRun struct {
+	ReferenceID      string         `json:"referenceID"`
+	URL              string         `json:"url"`
+	Name             string         `json:"name"`
+	ProjectID        int64          `json:"projectID"`
+	Script           string         `json:"script"`
+	ExpectedDuration types.Duration `json:"expectedDuration"`
+}
+
 //nolint:funlen,gocognit,gocyclo
 func getCloudCmd(ctx context.Context, logger *logrus.Logger) *cobra.Command {
 	cloudCmd := &cobra.Command{
@@ -79,8 +92,13 @@ This will execute the test on the k6 cloud service. Use "k6 login cloud" to auth
 					showCloudLogs = showCloudLogsValue
 				}
 			}
-			// TODO: disable in quiet mode?
-			_, _ = fmt.Fprintf(stdout, "\n%s\n\n", getBanner(noColor || !stdoutTTY))
+			if cloudAsync {
+				// the only output on stdout is the one of the started test run
+				quiet = true
+			} else {
+				// TODO: disable in quiet mode?
+				_, _ = fmt.Fprintf(stdout, "\n%s\n\n", getBanner(noColor || !stdoutTTY))
+			}
 
 			progressBar := pb.New(
 				pb.WithConstLeft("Init"),
@@ -127,6 +145,9 @@ This will execute the test on the k6 cloud service. Use "k6 login cloud" to auth
 			if err != nil {
 				return err
 			}
+			if err = cloudapi.ValidateScenarioConfigs(derivedConf.Scenarios); err != nil {
+				return err
+			}
 
 			// TODO: validate for usage of execution segment
 			// TODO: validate for externally controlled executor (i.e. executors that aren't distributable)
@@ -196,7 +217,10 @@ This will execute the test on the k6 cloud service. Use "k6 login cloud" to auth
 
 			// Start cloud test run
 			modifyAndPrintBar(progressBar, pb.WithConstProgress(0, "Validating script options"))
-			client := cloudapi.NewClient(logger, cloudConfig.Token.String, cloudConfig.Host.String, consts.Version)
+			client, err := cloudapi.NewClientFromConfig(logger, cloudConfig, consts.Version)
+			if err != nil {
+				return err
+			}
 			if err = client.ValidateOptions(arc.Options); err != nil {
 				return err
 			}
@@ -207,6 +231,10 @@ This will execute the test on the k6 cloud service. Use "k6 login cloud" to auth
 				return err
 			}
 
+			if cloudAsync {
+				return writeCloudAsyncTestRun(refID, name, filename, cloudConfig, derivedConf)
+			}
+
 			// Trap Interrupts, SIGINTs and SIGTERMs.
 			sigC := make(chan os.Signal, 1)
 			signal.Notify(sigC, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -341,6 +369,7 @@ This will execute the test on the k6 cloud service. Use "k6 login cloud" to auth
 	}
 	cloudCmd.Flags().SortFlags = false
 	cloudCmd.Flags().AddFlagSet(cloudCmdFlagSet())
+	cloudCmd.AddCommand(getCloudLogsCmd(ctx, logger), getCloudStatusCmd(logger), getCloudResultsCmd(logger))
 	return cloudCmd
 }
 
@@ -362,6 +391,30 @@ func cloudCmdFlagSet() *pflag.FlagSet {
 	// read the comments above for explanation why this is done this way and what are the problems
 	flags.BoolVar(&showCloudLogs, "show-logs", showCloudLogs,
 		"enable showing of logs when a test is executed in the cloud")
+	flags.BoolVar(&cloudAsync, "async", cloudAsync,
+		"exit right after starting the test, printing its ID and details as JSON")
 
 	return flags
 }
+
+// writeCloudAsyncTestRun prints the details of a test run started by k6 cloud --async.
+func writeCloudAsyncTestRun(refID, name, filename string, cloudConfig cloudapi.Config, conf Config) error {
+	et, err := lib.NewExecutionTuple(conf.ExecutionSegment, conf.ExecutionSegmentSequence)
+	if err != nil {
+		return err
+	}
+	duration, _ := lib.GetEndOffset(conf.Scenarios.GetFullExecutionRequirements(et))
+	data, err := json.MarshalIndent(cloudAsyncTestRun{
+		ReferenceID:      refID,
+		URL:              cloudapi.URLForResults(refID, cloudConfig),
+		Name:             name,
+		ProjectID:        cloudConfig.ProjectID.Int64,
+		Script:           filename,
+		ExpectedDuration: types.Duration(duration),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,151 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"go.k6.io/k6/cloudapi"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/consts"
+)
+
+// cloudLogsFinishedGrace is how long the logs are still streamed after the test run finished, so
+// the last ones aren't lost.
+const cloudLogsFinishedGrace = 5 * time.Second
+
+// getCloudTestRunConfig returns the cloud config of the subcommands for the existing test runs,
+// from the config file of k6 login cloud and the environment variables.
+func getCloudTestRunConfig() (cloudapi.Config, error) {
+	diskConf, _, err := readDiskConfig(afero.NewOsFs())
+	if err != nil {
+		return cloudapi.Config{}, err
+	}
+	conf, err := cloudapi.GetConsolidatedConfig(diskConf.Collectors["cloud"], buildEnvMap(os.Environ()), "", nil)
+	if err != nil {
+		return cloudapi.Config{}, err
+	}
+	if !conf.Token.Valid {
+		return conf, errors.New("Not logged in, please use `k6 login cloud`.") //nolint:golint,revive,stylecheck
+	}
+	return conf, nil
+}
+
+func getCloudLogsCmd(ctx context.Context, logger *logrus.Logger) *cobra.Command {
+	var (
+		since  time.Duration
+		follow bool
+		filter cloudapi.LogsFilter
+	)
+
+	cloudLogsCmd := &cobra.Command{
+		Use:   "logs <test-run-id>",
+		Short: "Stream the logs of a cloud test run",
+		Long: `Stream the logs of a cloud test run.
+
+The logs of a running or recently finished test run are streamed until it finishes, or
+until Ctrl+C, so it can be attached to from any terminal, and again after disconnecting.`,
+		Example: `
+  # Stream the logs of the last hour of a test run.
+  k6 cloud logs 123456
+
+  # Stream the logs of the whole last day, until Ctrl+C even if the test run finished.
+  k6 cloud logs --since 24h --follow 123456
+
+  # Stream only the errors of the first instance of a distributed test run.
+  k6 cloud logs --label instance_id=0 --label level=error 123456
+
+  # Stream only the log lines with the given text.
+  k6 cloud logs --grep "status 503" 123456`[1:],
+		Args: exactArgsWithMsg(1, "arg should be the ID of the test run"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			refID := args[0]
+			conf, err := getCloudTestRunConfig()
+			if err != nil {
+				return err
+			}
+			client, err := cloudapi.NewClientFromConfig(logger, conf, consts.Version)
+			if err != nil {
+				return err
+			}
+			if _, err = client.GetTestProgress(refID); err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			sigC := make(chan os.Signal, 1)
+			signal.Notify(sigC, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+			defer signal.Stop(sigC)
+			go func() {
+				select {
+				case <-sigC:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			if !follow {
+				go waitForCloudTestRun(ctx, cancel, logger, client, refID, 2*time.Second, cloudLogsFinishedGrace)
+			}
+
+			logger.Debug("Connecting to cloud logs server...")
+			return conf.StreamFilteredLogsToLogger(ctx, logger, refID, since, filter)
+		},
+	}
+
+	flags := cloudLogsCmd.Flags()
+	flags.SortFlags = false
+	flags.DurationVar(&since, "since", time.Hour, "stream the logs since this long ago")
+	flags.BoolVarP(&follow, "follow", "f", false, "keep streaming the logs after the test run finished, until Ctrl+C")
+	flags.StringArrayVarP(&filter.Labels, "label", "l", nil,
+		"stream only the logs with the label matching `name=value`, != , =~ and !~ are also supported, can be repeated")
+	flags.StringVar(&filter.Line, "grep", "", "stream only the log lines containing the text")
+	return cloudLogsCmd
+}
+
+// waitForCloudTestRun polls the status of the test run, and calls cancel the grace period after
+// it's finished.
+func waitForCloudTestRun(
+	ctx context.Context, cancel func(), logger logrus.FieldLogger, client *cloudapi.Client, refID string,
+	interval, grace time.Duration,
+) {
+	progress, err := client.PollTestProgress(ctx, refID, interval, func(p *cloudapi.TestProgressResponse) bool {
+		return p.RunStatus > lib.RunStatusRunning
+	})
+	if err != nil {
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(grace):
+		logger.Debugf("The test run finished with the status %s", progress.RunStatusText)
+		cancel()
+	}
+}
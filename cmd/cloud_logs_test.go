@@ -0,0 +1,59 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.k6.io/k6/cloudapi"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/testutils"
+)
+
+func TestWaitForCloudTestRun(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/test-progress/123", r.URL.Path)
+		status := lib.RunStatusRunning
+		if atomic.AddInt32(&requests, 1) > 1 {
+			status = lib.RunStatusFinished
+		}
+		_, _ = fmt.Fprintf(w, `{"run_status": %d, "run_status_text": "Finished"}`, status)
+	}))
+	defer srv.Close()
+
+	logger := testutils.NewLogger(t)
+	client := cloudapi.NewClient(logger, "token", srv.URL, "1.0")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	waitForCloudTestRun(ctx, cancel, logger, client, "123", 10*time.Millisecond, 10*time.Millisecond)
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests))
+}
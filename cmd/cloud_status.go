@@ -0,0 +1,234 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"go.k6.io/k6/cloudapi"
+	"go.k6.io/k6/errext"
+	"go.k6.io/k6/errext/exitcodes"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/consts"
+)
+
+// cloudTestRunStatus is the machine-readable status of a cloud test run, of k6 cloud status and
+// of k6 cloud results with --format json.
+type cloudTestRunStatus struct {
+	ReferenceID string                        `json:"referenceID"`
+	URL         string                        `json:"url"`
+	RunStatus   lib.RunStatus                 `json:"runStatus"`
+	Status      string                        `json:"status"`
+	Finished    bool                          `json:"finished"`
+	Passed      bool                          `json:"passed"`
+	Progress    float64                       `json:"progress"`
+	Thresholds  cloudapi.ThresholdResult      `json:"thresholds,omitempty"`
+	Metrics     map[string]map[string]float64 `json:"metrics,omitempty"`
+}
+
+func newCloudTestRunStatus(refID string, conf cloudapi.Config, progress cloudapi.TestProgressResponse) cloudTestRunStatus {
+	return cloudTestRunStatus{
+		ReferenceID: refID,
+		URL:         cloudapi.URLForResults(refID, conf),
+		RunStatus:   progress.RunStatus,
+		Status:      progress.RunStatusText,
+		Finished:    progress.RunStatus > lib.RunStatusRunning,
+		Passed:      progress.ResultStatus == cloudapi.ResultStatusPassed,
+		Progress:    progress.Progress,
+	}
+}
+
+// err returns the error with the exit code of k6 cloud for a test run that finished and failed.
+func (s cloudTestRunStatus) err() error {
+	if s.Finished && !s.Passed {
+		//nolint:stylecheck,golint
+		return errext.WithExitCodeIfNone(errors.New("The test has failed"), exitcodes.CloudTestRunFailed)
+	}
+	return nil
+}
+
+func getCloudStatusCmd(logger *logrus.Logger) *cobra.Command {
+	var format string
+
+	cloudStatusCmd := &cobra.Command{
+		Use:   "status <test-run-id>",
+		Short: "Show the status of a cloud test run",
+		Long: `Show the status of a cloud test run.
+
+The exit code is the one of k6 cloud when the test run finished and failed, so CI jobs can
+start a test run and check it later.`,
+		Example: `
+  # Show the status of a test run.
+  k6 cloud status 123456
+
+  # Wait until a test run finishes.
+  until k6 cloud status --format json 123456 | grep -q '"finished": true'; do sleep 30; done`[1:],
+		Args: exactArgsWithMsg(1, "arg should be the ID of the test run"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := getCloudTestRunConfig()
+			if err != nil {
+				return err
+			}
+			client, err := cloudapi.NewClientFromConfig(logger, conf, consts.Version)
+			if err != nil {
+				return err
+			}
+			progress, err := client.GetTestProgress(args[0])
+			if err != nil {
+				return errext.WithExitCodeIfNone(err, exitcodes.CloudFailedToGetProgress)
+			}
+			status := newCloudTestRunStatus(args[0], conf, *progress)
+			if err := writeCloudTestRunStatus(stdout, status, format); err != nil {
+				return err
+			}
+			return status.err()
+		},
+	}
+
+	cloudStatusCmd.Flags().StringVar(&format, "format", "text", "the output format, text or json")
+	return cloudStatusCmd
+}
+
+func getCloudResultsCmd(logger *logrus.Logger) *cobra.Command {
+	var format string
+
+	cloudResultsCmd := &cobra.Command{
+		Use:   "results <test-run-id>",
+		Short: "Show the results of a cloud test run",
+		Long: `Show the results of a cloud test run.
+
+The results are the status of the test run, the results of its thresholds and the summary
+of its metrics, which are partial while it's still running.`,
+		Example: `
+  # Show the results of a test run.
+  k6 cloud results 123456
+
+  # Save the results of a test run for processing them.
+  k6 cloud results --format json 123456 > results.json`[1:],
+		Args: exactArgsWithMsg(1, "arg should be the ID of the test run"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := getCloudTestRunConfig()
+			if err != nil {
+				return err
+			}
+			client, err := cloudapi.NewClientFromConfig(logger, conf, consts.Version)
+			if err != nil {
+				return err
+			}
+			results, err := client.GetTestRunResults(args[0])
+			if err != nil {
+				return errext.WithExitCodeIfNone(err, exitcodes.CloudFailedToGetProgress)
+			}
+			status := newCloudTestRunStatus(args[0], conf, results.TestProgressResponse)
+			status.Thresholds, status.Metrics = results.Thresholds, results.Metrics
+			if err := writeCloudTestRunStatus(stdout, status, format); err != nil {
+				return err
+			}
+			return status.err()
+		},
+	}
+
+	cloudResultsCmd.Flags().StringVar(&format, "format", "text", "the output format, text or json")
+	return cloudResultsCmd
+}
+
+func writeCloudTestRunStatus(w io.Writer, status cloudTestRunStatus, format string) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case "text":
+	default:
+		return fmt.Errorf("unsupported format '%s', it has to be text or json", format)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "     test run: %s\n", status.ReferenceID)
+	fmt.Fprintf(&sb, "       output: %s\n", status.URL)
+	fmt.Fprintf(&sb, "  test status: %s (%.0f%%)\n", status.Status, status.Progress*100)
+	if status.Finished {
+		result := "passed"
+		if !status.Passed {
+			result = "failed"
+		}
+		fmt.Fprintf(&sb, "       result: %s\n", result)
+	}
+
+	if len(status.Thresholds) > 0 {
+		sb.WriteString("\nthresholds:\n")
+		names := make([]string, 0, len(status.Thresholds))
+		for name := range status.Thresholds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			thresholds := status.Thresholds[name]
+			sources := make([]string, 0, len(thresholds))
+			for source := range thresholds {
+				sources = append(sources, source)
+			}
+			sort.Strings(sources)
+			for _, source := range sources {
+				mark := "✓"
+				if thresholds[source] { // the value is whether the threshold failed
+					mark = "✗"
+				}
+				fmt.Fprintf(&sb, "  %s %s: %s\n", mark, name, source)
+			}
+		}
+	}
+
+	if len(status.Metrics) > 0 {
+		sb.WriteString("\nmetrics:\n")
+		names := make([]string, 0, len(status.Metrics))
+		for name := range status.Metrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			values := status.Metrics[name]
+			stats := make([]string, 0, len(values))
+			for stat := range values {
+				stats = append(stats, stat)
+			}
+			sort.Strings(stats)
+			parts := make([]string, len(stats))
+			for i, stat := range stats {
+				parts[i] = fmt.Sprintf("%s=%g", stat, values[stat])
+			}
+			fmt.Fprintf(&sb, "  %s: %s\n", name, strings.Join(parts, " "))
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
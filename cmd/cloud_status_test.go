@@ -0,0 +1,88 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/cloudapi"
+	"go.k6.io/k6/errext"
+	"go.k6.io/k6/errext/exitcodes"
+	"go.k6.io/k6/lib"
+)
+
+func TestWriteCloudTestRunStatus(t *testing.T) {
+	t.Parallel()
+	conf := cloudapi.Config{WebAppURL: null.StringFrom("https://app.k6.io")}
+	status := newCloudTestRunStatus("123", conf, cloudapi.TestProgressResponse{
+		RunStatus: lib.RunStatusFinished, RunStatusText: "Finished", ResultStatus: cloudapi.ResultStatusFailed, Progress: 1,
+	})
+	status.Thresholds = cloudapi.ThresholdResult{"http_req_duration": {"p(95)<500": true, "avg<200": false}}
+	status.Metrics = map[string]map[string]float64{"http_reqs": {"count": 100, "rate": 9.5}}
+
+	t.Run("text", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		require.NoError(t, writeCloudTestRunStatus(&buf, status, "text"))
+		assert.Equal(t, `     test run: 123
+       output: https://app.k6.io/runs/123
+  test status: Finished (100%)
+       result: failed
+
+thresholds:
+  ✓ http_req_duration: avg<200
+  ✗ http_req_duration: p(95)<500
+
+metrics:
+  http_reqs: count=100 rate=9.5
+`, buf.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		require.NoError(t, writeCloudTestRunStatus(&buf, status, "json"))
+		var decoded cloudTestRunStatus
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, status, decoded)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, writeCloudTestRunStatus(&bytes.Buffer{}, status, "xml"))
+	})
+
+	t.Run("exit code", func(t *testing.T) {
+		t.Parallel()
+		var errWithExitCode errext.HasExitCode
+		require.ErrorAs(t, status.err(), &errWithExitCode)
+		assert.Equal(t, exitcodes.CloudTestRunFailed, errWithExitCode.ExitCode())
+		running := status
+		running.Finished = false
+		assert.NoError(t, running.err())
+	})
+}
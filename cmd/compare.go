@@ -0,0 +1,474 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"go.k6.io/k6/errext"
+	"go.k6.io/k6/errext/exitcodes"
+	"go.k6.io/k6/stats"
+)
+
+// compareMetric are the aggregated values of a metric of a test run, from a summary export or
+// the JSON output. The sink is only set for the JSON output, for any percentile of the trends.
+type compareMetric struct {
+	Type   stats.MetricType
+	Values map[string]float64
+	sink   stats.Sink
+}
+
+func (m compareMetric) value(stat string) (float64, bool) {
+	if v, ok := m.Values[stat]; ok {
+		return v, true
+	}
+	if p, ok := m.sink.(interface{ P(float64) float64 }); ok &&
+		strings.HasPrefix(stat, "p(") && strings.HasSuffix(stat, ")") {
+		pct, err := strconv.ParseFloat(stat[2:len(stat)-1], 64)
+		if err == nil && pct >= 0 && pct <= 100 {
+			return p.P(pct / 100), true
+		}
+	}
+	return 0, false
+}
+
+// compareOptions are the options of k6 compare.
+type compareOptions struct {
+	trendStats       []string
+	metrics          []string
+	tolerance        float64
+	metricTolerances map[string]float64
+	higherIsBetter   map[string]bool
+}
+
+// compareResult is the comparison of a value of a metric in the baseline and the current run.
+// The delta is the change in percent, it's null if the baseline value is 0 and the current one
+// isn't.
+type compareResult struct {
+	Metric     string   `json:"metric"`
+	Stat       string   `json:"stat"`
+	Baseline   float64  `json:"baseline"`
+	Current    *float64 `json:"current"`
+	Delta      *float64 `json:"delta"`
+	Tolerance  float64  `json:"tolerance"`
+	Regression bool     `json:"regression"`
+}
+
+// defaultHigherIsBetter are the built-in metrics that regress when their values decrease, the
+// values of all the other metrics regress when they increase.
+//nolint:gochecknoglobals
+var defaultHigherIsBetter = []string{"checks", "data_received", "data_sent", "http_reqs", "iterations"}
+
+//nolint:funlen
+func getCompareCmd() *cobra.Command {
+	var (
+		format           string
+		trendStats       []string
+		metrics          []string
+		tolerance        float64
+		metricTolerances []string
+		higherIsBetter   []string
+	)
+
+	compareCmd := &cobra.Command{
+		Use:   "compare <baseline> <current>",
+		Short: "Compare the results of a test run with a baseline",
+		Long: `Compare the results of a test run with a baseline.
+
+The results are the files of the --summary-export option, the JSON of a handleSummary()
+function with the data of the summary, or the files of the JSON output, also gzipped. The
+values of the metrics are compared in percent of the baseline, the ones that got worse by
+more than the tolerance are regressions, and k6 compare then exits with a non-zero code.
+
+The values of the trends are their --stats, the values of the rates and the counters are
+their rates, and the gauges aren't compared. The values of the metrics increase when they
+get worse, except for the checks, data_received, data_sent, http_reqs, iterations and the
+metrics of --higher-is-better.`,
+		Example: `
+  # Compare a test run with the baseline, with a tolerance of 10%.
+  k6 compare baseline.json current.json
+
+  # Fail on any increase of the p(95) of http_req_duration, and on 5% for everything else.
+  k6 compare --tolerance 5 --metric-tolerance "http_req_duration:p(95)=0" baseline.json current.json
+
+  # Compare the JSON outputs of two test runs, with the p(99) of the trends.
+  k6 compare --stats "avg,p(95),p(99)" baseline.json.gz current.json.gz`[1:],
+		Args: exactArgsWithMsg(2, "the args should be the baseline and the current results"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := compareOptions{
+				trendStats:       trendStats,
+				metrics:          metrics,
+				tolerance:        tolerance,
+				metricTolerances: make(map[string]float64, len(metricTolerances)),
+				higherIsBetter:   make(map[string]bool),
+			}
+			for _, mt := range metricTolerances {
+				i := strings.LastIndexByte(mt, '=')
+				if i < 0 {
+					return fmt.Errorf("invalid metric tolerance '%s', it has to be metric=percent or metric:stat=percent", mt)
+				}
+				value, err := strconv.ParseFloat(mt[i+1:], 64)
+				if err != nil {
+					return fmt.Errorf("invalid metric tolerance '%s': %w", mt, err)
+				}
+				opts.metricTolerances[mt[:i]] = value
+			}
+			for _, name := range append(append([]string{}, defaultHigherIsBetter...), higherIsBetter...) {
+				opts.higherIsBetter[name] = true
+			}
+
+			baseline, err := loadCompareRun(defaultFs, args[0])
+			if err != nil {
+				return err
+			}
+			current, err := loadCompareRun(defaultFs, args[1])
+			if err != nil {
+				return err
+			}
+			results := compareRuns(baseline, current, opts)
+			if err := writeCompareResults(stdout, results, format); err != nil {
+				return err
+			}
+
+			regressions := 0
+			for _, r := range results {
+				if r.Regression {
+					regressions++
+				}
+			}
+			if regressions > 0 {
+				return errext.WithExitCodeIfNone(
+					fmt.Errorf("%d of the %d compared values regressed", regressions, len(results)),
+					exitcodes.PerformanceRegression)
+			}
+			return nil
+		},
+	}
+
+	flags := compareCmd.Flags()
+	flags.SortFlags = false
+	flags.Float64Var(&tolerance, "tolerance", 10, "the percent the values can get worse by without a regression")
+	flags.StringArrayVar(&metricTolerances, "metric-tolerance", nil,
+		"the tolerance of a metric or a value of a metric, as metric=percent or metric:stat=percent")
+	flags.StringSliceVar(&trendStats, "stats", []string{"avg", "med", "p(90)", "p(95)"},
+		"the values of the trends that are compared")
+	flags.StringSliceVar(&metrics, "metrics", nil, "compare only these metrics, all of them by default")
+	flags.StringSliceVar(&higherIsBetter, "higher-is-better", nil,
+		"the metrics that get worse when their values decrease, besides the built-in ones")
+	flags.StringVar(&format, "format", "text", "the output format, text or json")
+	return compareCmd
+}
+
+// loadCompareRun returns the metrics of the results of a test run, a summary export or a JSON output.
+func loadCompareRun(afs afero.Fs, path string) (map[string]compareMetric, error) {
+	data, err := afero.ReadFile(afs, path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the gzipped results '%s': %w", path, err)
+		}
+		if data, err = ioutil.ReadAll(gz); err != nil {
+			return nil, fmt.Errorf("couldn't read the gzipped results '%s': %w", path, err)
+		}
+	}
+
+	// the summaries are a single object with the metrics, the JSON output has a line for each sample
+	var summary struct {
+		Metrics map[string]map[string]json.RawMessage `json:"metrics"`
+	}
+	if err := json.Unmarshal(data, &summary); err == nil && summary.Metrics != nil {
+		return parseCompareSummary(summary.Metrics)
+	}
+	metrics, err := parseCompareJSONOutput(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the results '%s', they have to be a summary or a JSON output: %w",
+			path, err)
+	}
+	return metrics, nil
+}
+
+// parseCompareSummary returns the metrics of a summary export, or of the data of a handleSummary(),
+// where the values are in their own object with the type of the metric.
+func parseCompareSummary(summary map[string]map[string]json.RawMessage) (map[string]compareMetric, error) {
+	metrics := make(map[string]compareMetric, len(summary))
+	for name, fields := range summary {
+		m := compareMetric{Values: make(map[string]float64)}
+		if rawValues, ok := fields["values"]; ok {
+			if err := json.Unmarshal(rawValues, &m.Values); err != nil {
+				return nil, fmt.Errorf("invalid values of the metric '%s': %w", name, err)
+			}
+			var typ string
+			_ = json.Unmarshal(fields["type"], &typ)
+			if err := m.Type.UnmarshalText([]byte(typ)); err != nil {
+				return nil, fmt.Errorf("invalid type of the metric '%s': %w", name, err)
+			}
+			metrics[name] = m
+			continue
+		}
+
+		// the summary export doesn't have the types, they are recognized by the values
+		for k, raw := range fields {
+			var v float64
+			if json.Unmarshal(raw, &v) == nil {
+				m.Values[k] = v
+			}
+		}
+		_, hasPasses := m.Values["passes"]
+		_, hasAvg := m.Values["avg"]
+		_, hasCount := m.Values["count"]
+		switch {
+		case hasPasses:
+			m.Type, m.Values["rate"] = stats.Rate, m.Values["value"]
+		case hasAvg:
+			m.Type = stats.Trend
+		case hasCount:
+			m.Type = stats.Counter
+		default:
+			m.Type = stats.Gauge
+		}
+		metrics[name] = m
+	}
+	return metrics, nil
+}
+
+// parseCompareJSONOutput aggregates the samples of a JSON output, the rates of the counters are
+// per second of the time between the first and the last sample.
+func parseCompareJSONOutput(r io.Reader) (map[string]compareMetric, error) {
+	metrics := make(map[string]*stats.Metric)
+	var first, last time.Time
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var envelope struct {
+			Type   string          `json:"type"`
+			Metric string          `json:"metric"`
+			Data   json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(line, &envelope); err != nil {
+			return nil, err
+		}
+		switch envelope.Type {
+		case "Metric":
+			var metric struct {
+				Type stats.MetricType `json:"type"`
+			}
+			if err := json.Unmarshal(envelope.Data, &metric); err != nil {
+				return nil, err
+			}
+			if _, ok := metrics[envelope.Metric]; !ok {
+				metrics[envelope.Metric] = stats.New(envelope.Metric, metric.Type)
+			}
+		case "Point":
+			m, ok := metrics[envelope.Metric]
+			if !ok {
+				continue
+			}
+			var point struct {
+				Time  time.Time `json:"time"`
+				Value float64   `json:"value"`
+			}
+			if err := json.Unmarshal(envelope.Data, &point); err != nil {
+				return nil, err
+			}
+			m.Sink.Add(stats.Sample{Metric: m, Time: point.Time, Value: point.Value})
+			if first.IsZero() || point.Time.Before(first) {
+				first = point.Time
+			}
+			if point.Time.After(last) {
+				last = point.Time
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("no metrics")
+	}
+
+	duration := last.Sub(first)
+	if duration < time.Second {
+		duration = time.Second
+	}
+	result := make(map[string]compareMetric, len(metrics))
+	for name, m := range metrics {
+		result[name] = compareMetric{Type: m.Type, Values: m.Sink.Format(duration), sink: m.Sink}
+	}
+	return result, nil
+}
+
+// compareRuns compares the values of the metrics of the baseline with the current run, sorted by
+// the metrics and in the order of their stats.
+func compareRuns(baseline, current map[string]compareMetric, opts compareOptions) []compareResult {
+	names := make([]string, 0, len(baseline))
+	for name := range baseline {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	only := make(map[string]bool, len(opts.metrics))
+	for _, name := range opts.metrics {
+		only[name] = true
+	}
+
+	results := []compareResult{}
+	for _, name := range names {
+		if len(only) > 0 && !only[name] {
+			continue
+		}
+		base := baseline[name]
+		var compared []string
+		switch base.Type {
+		case stats.Trend, stats.Histogram:
+			compared = opts.trendStats
+		case stats.Rate, stats.Counter, stats.Throughput:
+			compared = []string{"rate"}
+		default:
+			continue
+		}
+		// the built-in and the custom metrics without tags are in the sub-metrics' list too
+		parent := name
+		if i := strings.IndexByte(name, '{'); i >= 0 {
+			parent = name[:i]
+		}
+
+		for _, stat := range compared {
+			b, ok := base.value(stat)
+			if !ok {
+				continue
+			}
+			result := compareResult{Metric: name, Stat: stat, Baseline: b, Tolerance: opts.tolerance}
+			if t, ok := opts.metricTolerances[name]; ok {
+				result.Tolerance = t
+			}
+			if t, ok := opts.metricTolerances[name+":"+stat]; ok {
+				result.Tolerance = t
+			}
+			cur, hasCurrent := current[name]
+			c, ok := cur.value(stat)
+			if !hasCurrent || !ok {
+				results = append(results, result)
+				continue
+			}
+			result.Current = &c
+
+			var delta float64
+			switch {
+			case b == c:
+				delta = 0
+			case b == 0:
+				delta = math.Inf(1)
+				if c < 0 {
+					delta = math.Inf(-1)
+				}
+			default:
+				delta = (c - b) / math.Abs(b) * 100
+			}
+			worse := delta
+			if opts.higherIsBetter[parent] {
+				worse = -delta
+			}
+			result.Regression = worse > result.Tolerance
+			if !math.IsInf(delta, 0) {
+				result.Delta = &delta
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func writeCompareResults(w io.Writer, results []compareResult, format string) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case "text":
+	default:
+		return fmt.Errorf("unsupported format '%s', it has to be text or json", format)
+	}
+
+	rows := [][]string{{"metric", "stat", "baseline", "current", "delta", ""}}
+	formatValue := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	for _, r := range results {
+		current, delta, mark := "-", "-", ""
+		if r.Current != nil {
+			current = formatValue(math.Round(*r.Current*1000) / 1000)
+			delta = "+inf%"
+			if r.Delta != nil {
+				delta = fmt.Sprintf("%+.2f%%", *r.Delta)
+			} else if *r.Current < 0 {
+				delta = "-inf%"
+			}
+		} else {
+			mark = "missing"
+		}
+		if r.Regression {
+			mark = fmt.Sprintf("✗ regression, tolerance %s%%", formatValue(r.Tolerance))
+		}
+		rows = append(rows, []string{r.Metric, r.Stat, formatValue(math.Round(r.Baseline*1000) / 1000), current, delta, mark})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+	var sb strings.Builder
+	for _, row := range rows {
+		line := ""
+		for i, cell := range row {
+			line += cell + strings.Repeat(" ", widths[i]-len(cell)+2)
+		}
+		sb.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
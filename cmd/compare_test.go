@@ -0,0 +1,201 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/stats"
+)
+
+const compareSummaryExport = `{
+	"metrics": {
+		"http_req_duration": {"avg": 100, "med": 90, "p(90)": 150, "p(95)": 200, "min": 10, "max": 300},
+		"http_req_failed": {"passes": 1, "fails": 99, "value": 0.01},
+		"http_reqs": {"count": 1000, "rate": 50},
+		"vus": {"value": 10, "min": 1, "max": 10}
+	}
+}`
+
+func testCompareOptions() compareOptions {
+	opts := compareOptions{
+		trendStats:       []string{"avg", "p(95)"},
+		tolerance:        10,
+		metricTolerances: map[string]float64{},
+		higherIsBetter:   map[string]bool{},
+	}
+	for _, name := range defaultHigherIsBetter {
+		opts.higherIsBetter[name] = true
+	}
+	return opts
+}
+
+func TestLoadCompareRun(t *testing.T) {
+	t.Parallel()
+	afs := afero.NewMemMapFs()
+
+	t.Run("SummaryExport", func(t *testing.T) {
+		t.Parallel()
+		require.NoError(t, afero.WriteFile(afs, "/export.json", []byte(compareSummaryExport), 0o644))
+		metrics, err := loadCompareRun(afs, "/export.json")
+		require.NoError(t, err)
+		assert.Equal(t, stats.Trend, metrics["http_req_duration"].Type)
+		assert.Equal(t, stats.Rate, metrics["http_req_failed"].Type)
+		assert.Equal(t, 0.01, metrics["http_req_failed"].Values["rate"])
+		assert.Equal(t, stats.Counter, metrics["http_reqs"].Type)
+		assert.Equal(t, stats.Gauge, metrics["vus"].Type)
+	})
+
+	t.Run("HandleSummary", func(t *testing.T) {
+		t.Parallel()
+		data := `{"metrics": {"checks": {"type": "rate", "contains": "default", "values": {"rate": 0.9}}}}`
+		require.NoError(t, afero.WriteFile(afs, "/summary.json", []byte(data), 0o644))
+		metrics, err := loadCompareRun(afs, "/summary.json")
+		require.NoError(t, err)
+		assert.Equal(t, compareMetric{Type: stats.Rate, Values: map[string]float64{"rate": 0.9}}, metrics["checks"])
+	})
+
+	t.Run("JSONOutput", func(t *testing.T) {
+		t.Parallel()
+		lines := `{"type":"Metric","data":{"name":"http_req_duration","type":"trend","contains":"time"},"metric":"http_req_duration"}
+{"type":"Point","data":{"time":"2021-06-01T10:00:00Z","value":10},"metric":"http_req_duration"}
+{"type":"Point","data":{"time":"2021-06-01T10:00:01Z","value":20},"metric":"http_req_duration"}
+{"type":"Metric","data":{"name":"http_reqs","type":"counter","contains":"default"},"metric":"http_reqs"}
+{"type":"Point","data":{"time":"2021-06-01T10:00:01Z","value":1},"metric":"http_reqs"}
+{"type":"Point","data":{"time":"2021-06-01T10:00:02Z","value":1},"metric":"http_reqs"}
+`
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, err := gz.Write([]byte(lines))
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+		require.NoError(t, afero.WriteFile(afs, "/output.json.gz", buf.Bytes(), 0o644))
+
+		metrics, err := loadCompareRun(afs, "/output.json.gz")
+		require.NoError(t, err)
+		assert.Equal(t, 15.0, metrics["http_req_duration"].Values["avg"])
+		p99, ok := metrics["http_req_duration"].value("p(99)")
+		require.True(t, ok)
+		assert.InDelta(t, 19.9, p99, 0.001)
+		assert.Equal(t, 1.0, metrics["http_reqs"].Values["rate"])
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+		require.NoError(t, afero.WriteFile(afs, "/invalid.json", []byte("not json"), 0o644))
+		_, err := loadCompareRun(afs, "/invalid.json")
+		assert.Error(t, err)
+		_, err = loadCompareRun(afs, "/missing.json")
+		assert.Error(t, err)
+	})
+}
+
+func TestCompareRuns(t *testing.T) {
+	t.Parallel()
+	baseline, err := parseCompareSummary(mustCompareSummary(t, compareSummaryExport))
+	require.NoError(t, err)
+	current, err := parseCompareSummary(mustCompareSummary(t, `{
+		"metrics": {
+			"http_req_duration": {"avg": 105, "med": 90, "p(90)": 150, "p(95)": 250},
+			"http_req_failed": {"passes": 5, "fails": 95, "value": 0.05},
+			"http_reqs": {"count": 800, "rate": 40},
+			"vus": {"value": 20}
+		}
+	}`))
+	require.NoError(t, err)
+
+	t.Run("Defaults", func(t *testing.T) {
+		t.Parallel()
+		results := compareRuns(baseline, current, testCompareOptions())
+		require.Len(t, results, 4)
+		regressions := map[string]bool{}
+		for _, r := range results {
+			regressions[r.Metric+":"+r.Stat] = r.Regression
+		}
+		assert.Equal(t, map[string]bool{
+			"http_req_duration:avg":   false,
+			"http_req_duration:p(95)": true,
+			"http_req_failed:rate":    true,
+			"http_reqs:rate":          true,
+		}, regressions)
+		assert.Equal(t, 25.0, *results[1].Delta)
+	})
+
+	t.Run("Tolerances", func(t *testing.T) {
+		t.Parallel()
+		opts := testCompareOptions()
+		opts.metrics = []string{"http_req_duration"}
+		opts.metricTolerances["http_req_duration"] = 1
+		opts.metricTolerances["http_req_duration:p(95)"] = 30
+		results := compareRuns(baseline, current, opts)
+		require.Len(t, results, 2)
+		assert.True(t, results[0].Regression)
+		assert.Equal(t, 1.0, results[0].Tolerance)
+		assert.False(t, results[1].Regression)
+		assert.Equal(t, 30.0, results[1].Tolerance)
+	})
+
+	t.Run("ZeroAndMissing", func(t *testing.T) {
+		t.Parallel()
+		base := map[string]compareMetric{
+			"errors":  {Type: stats.Rate, Values: map[string]float64{"rate": 0}},
+			"removed": {Type: stats.Counter, Values: map[string]float64{"rate": 1}},
+		}
+		cur := map[string]compareMetric{"errors": {Type: stats.Rate, Values: map[string]float64{"rate": 0.1}}}
+		results := compareRuns(base, cur, testCompareOptions())
+		require.Len(t, results, 2)
+		assert.True(t, results[0].Regression)
+		assert.Nil(t, results[0].Delta)
+		assert.False(t, results[1].Regression)
+		assert.Nil(t, results[1].Current)
+
+		var buf bytes.Buffer
+		require.NoError(t, writeCompareResults(&buf, results, "text"))
+		assert.Equal(t, ""+
+			"metric   stat  baseline  current  delta\n"+
+			"errors   rate  0         0.1      +inf%  ✗ regression, tolerance 10%\n"+
+			"removed  rate  1         -        -      missing\n", buf.String())
+
+		buf.Reset()
+		require.NoError(t, writeCompareResults(&buf, results, "json"))
+		var decoded []map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Nil(t, decoded[0]["delta"])
+		assert.Equal(t, true, decoded[0]["regression"])
+
+		assert.Error(t, writeCompareResults(&buf, results, "yaml"))
+	})
+}
+
+func mustCompareSummary(t *testing.T, data string) map[string]map[string]json.RawMessage {
+	var summary struct {
+		Metrics map[string]map[string]json.RawMessage `json:"metrics"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(data), &summary))
+	return summary.Metrics
+}
@@ -38,6 +38,7 @@ import (
 	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/executor"
 	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/output"
 	"go.k6.io/k6/stats"
 )
 
@@ -47,24 +48,60 @@ func configFlagSet() *pflag.FlagSet {
 	flags.SortFlags = false
 	flags.StringArrayP("out", "o", []string{}, "`uri` for an external metrics database")
 	flags.BoolP("linger", "l", false, "keep the API server alive past test end")
+	flags.Bool("dashboard", false, "show a live dashboard in the terminal instead of the progress bars")
 	flags.Bool("no-usage-report", false, "don't send anonymous stats to the developers")
+	flags.String("performance-profile", defaultPerformanceProfile,
+		"engine tuning `profile`, one of: "+strings.Join(performanceProfileNames(), ", "))
 	return flags
 }
 
 type Config struct {
 	lib.Options
 
-	Out           []string  `json:"out" envconfig:"K6_OUT"`
-	Linger        null.Bool `json:"linger" envconfig:"K6_LINGER"`
-	NoUsageReport null.Bool `json:"noUsageReport" envconfig:"K6_NO_USAGE_REPORT"`
+	Out                []string    `json:"out" envconfig:"K6_OUT"`
+	Linger             null.Bool   `json:"linger" envconfig:"K6_LINGER"`
+	Dashboard          null.Bool   `json:"dashboard" envconfig:"K6_DASHBOARD"`
+	NoUsageReport      null.Bool   `json:"noUsageReport" envconfig:"K6_NO_USAGE_REPORT"`
+	PerformanceProfile null.String `json:"performanceProfile" envconfig:"K6_PERFORMANCE_PROFILE"`
 
 	// TODO: deprecate
 	Collectors map[string]json.RawMessage `json:"collectors"`
+
+	// The filters of the samples of the outputs, by their types or by their full --out values
+	// if there are several outputs of the same type, like "csv=file.csv".
+	OutputFilters map[string]output.Filter `json:"outputFilters" ignored:"true"`
+
+	// The limit of the samples each output buffers in memory, and the on-disk queue of the
+	// samples over it, which are dropped without it.
+	OutputBufferMaxSamples   null.Int    `json:"outputBufferMaxSamples" envconfig:"K6_OUTPUT_BUFFER_MAX_SAMPLES"`
+	OutputBufferSpillDir     null.String `json:"outputBufferSpillDir" envconfig:"K6_OUTPUT_BUFFER_SPILL_DIR"`
+	OutputBufferSpillMaxSize null.Int    `json:"outputBufferSpillMaxSize" envconfig:"K6_OUTPUT_BUFFER_SPILL_MAX_SIZE"`
 }
 
 // Validate checks if all of the specified options make sense
 func (c Config) Validate() []error {
 	errors := c.Options.Validate()
+	if c.PerformanceProfile.Valid {
+		if _, ok := performanceProfiles[c.PerformanceProfile.String]; !ok {
+			errors = append(errors, fmt.Errorf(
+				"unknown performance profile '%s', valid values are: %s",
+				c.PerformanceProfile.String, strings.Join(performanceProfileNames(), ", "),
+			))
+		}
+	}
+	for name, filter := range c.OutputFilters {
+		if err := filter.Validate(); err != nil {
+			errors = append(errors, fmt.Errorf("invalid filter of the output '%s': %w", name, err))
+		}
+	}
+	if c.OutputBufferMaxSamples.Valid && c.OutputBufferMaxSamples.Int64 <= 0 {
+		errors = append(errors, fmt.Errorf(
+			"the outputBufferMaxSamples should be positive, but it's %d", c.OutputBufferMaxSamples.Int64))
+	}
+	if c.OutputBufferSpillMaxSize.Int64 < 0 {
+		errors = append(errors, fmt.Errorf(
+			"the outputBufferSpillMaxSize can't be negative, but it's %d", c.OutputBufferSpillMaxSize.Int64))
+	}
 	//TODO: validate all of the other options... that we should have already been validating...
 	//TODO: maybe integrate an external validation lib: https://github.com/avelino/awesome-go#validation
 
@@ -79,12 +116,30 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.Linger.Valid {
 		c.Linger = cfg.Linger
 	}
+	if cfg.Dashboard.Valid {
+		c.Dashboard = cfg.Dashboard
+	}
 	if cfg.NoUsageReport.Valid {
 		c.NoUsageReport = cfg.NoUsageReport
 	}
+	if cfg.PerformanceProfile.Valid {
+		c.PerformanceProfile = cfg.PerformanceProfile
+	}
 	if len(cfg.Collectors) > 0 {
 		c.Collectors = cfg.Collectors
 	}
+	if len(cfg.OutputFilters) > 0 {
+		c.OutputFilters = cfg.OutputFilters
+	}
+	if cfg.OutputBufferMaxSamples.Valid {
+		c.OutputBufferMaxSamples = cfg.OutputBufferMaxSamples
+	}
+	if cfg.OutputBufferSpillDir.Valid {
+		c.OutputBufferSpillDir = cfg.OutputBufferSpillDir
+	}
+	if cfg.OutputBufferSpillMaxSize.Valid {
+		c.OutputBufferSpillMaxSize = cfg.OutputBufferSpillMaxSize
+	}
 	return c
 }
 
@@ -99,10 +154,12 @@ func getConfig(flags *pflag.FlagSet) (Config, error) {
 		return Config{}, err
 	}
 	return Config{
-		Options:       opts,
-		Out:           out,
-		Linger:        getNullBool(flags, "linger"),
-		NoUsageReport: getNullBool(flags, "no-usage-report"),
+		Options:            opts,
+		Out:                out,
+		Linger:             getNullBool(flags, "linger"),
+		Dashboard:          getNullBool(flags, "dashboard"),
+		NoUsageReport:      getNullBool(flags, "no-usage-report"),
+		PerformanceProfile: getNullString(flags, "performance-profile"),
 	}, nil
 }
 
@@ -265,5 +322,10 @@ func validateScenarioConfig(conf lib.ExecutorConfig, isExecutable func(string) b
 	if !isExecutable(execFn) {
 		return fmt.Errorf("executor %s: function '%s' not found in exports", conf.GetName(), execFn)
 	}
+	for _, fn := range []string{conf.GetSetup(), conf.GetTeardown()} {
+		if fn != "" && !isExecutable(fn) {
+			return fmt.Errorf("executor %s: function '%s' not found in exports", conf.GetName(), fn)
+		}
+	}
 	return nil
 }
@@ -21,12 +21,14 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/guregu/null.v3"
 
 	"go.k6.io/k6/errext"
@@ -35,6 +37,7 @@ import (
 	"go.k6.io/k6/lib/executor"
 	"go.k6.io/k6/lib/testutils"
 	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
 )
 
 type testCmdData struct {
@@ -140,6 +143,25 @@ func TestConfigApply(t *testing.T) {
 		conf = Config{}.Apply(Config{Out: []string{"influxdb", "json"}})
 		assert.Equal(t, []string{"influxdb", "json"}, conf.Out)
 	})
+	t.Run("OutputBuffer", func(t *testing.T) {
+		conf := Config{}.Apply(Config{
+			OutputBufferMaxSamples: null.IntFrom(100000),
+			OutputBufferSpillDir:   null.StringFrom("/tmp"),
+		})
+		assert.Equal(t, null.IntFrom(100000), conf.OutputBufferMaxSamples)
+		assert.Equal(t, null.StringFrom("/tmp"), conf.OutputBufferSpillDir)
+		assert.Empty(t, conf.Validate())
+		assert.Len(t, Config{OutputBufferMaxSamples: null.IntFrom(0)}.Validate(), 1)
+	})
+	t.Run("OutputFilters", func(t *testing.T) {
+		var fromJSON Config
+		require.NoError(t, json.Unmarshal(
+			[]byte(`{"outputFilters": {"influxdb": {"types": ["trend"]}, "csv=raw.csv": {"excludeMetrics": ["["]}}}`),
+			&fromJSON))
+		conf := Config{}.Apply(fromJSON)
+		assert.Equal(t, []stats.MetricType{stats.Trend}, conf.OutputFilters["influxdb"].Types)
+		assert.Len(t, conf.Validate(), 1)
+	})
 }
 
 func TestDeriveAndValidateConfig(t *testing.T) {
@@ -21,15 +21,21 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"path/filepath"
 
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"gopkg.in/guregu/null.v3"
 
 	"go.k6.io/k6/converter/har"
+	"go.k6.io/k6/converter/jmx"
+	"go.k6.io/k6/converter/openapi"
+	"go.k6.io/k6/converter/postman"
 	"go.k6.io/k6/lib"
 )
 
@@ -37,6 +43,8 @@ import (
 //nolint: gochecknoglobals
 var (
 	convertOutput       string
+	convertInputFormat  string
+	postmanEnvironment  string
 	optionsFilePath     string
 	minSleep            uint
 	maxSleep            uint
@@ -53,8 +61,9 @@ var (
 func getConvertCmd() *cobra.Command {
 	convertCmd := &cobra.Command{
 		Use:   "convert",
-		Short: "Convert a HAR file to a k6 script",
-		Long:  "Convert a HAR (HTTP Archive) file to a k6 script",
+		Short: "Convert a HAR file, an OpenAPI document, a Postman collection or a JMeter test plan to a k6 script",
+		Long: "Convert a HAR (HTTP Archive) file, an OpenAPI 3 document, a Postman v2.1 collection " +
+			"or a JMeter .jmx test plan to a k6 script",
 		Example: `
   # Convert a HAR file to a k6 script.
   k6 convert -O har-session.js session.har
@@ -65,45 +74,46 @@ func getConvertCmd() *cobra.Command {
   # Convert a HAR file. Batching requests together as long as idle time between requests <800ms
   k6 convert --batch-threshold 800 session.har
 
+  # Convert an OpenAPI document to a k6 script with a request for each operation.
+  k6 convert -O api.js --enable-status-code-checks openapi.yaml
+
+  # Convert a Postman collection with the variables of a Postman environment.
+  k6 convert -O collection.js --postman-environment staging.postman_environment.json collection.json
+
+  # Convert a JMeter test plan, with a scenario for each thread group.
+  k6 convert -O plan.js plan.jmx
+
   # Run the k6 script.
   k6 run har-session.js`[1:],
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Parse the HAR file
 			filePath, err := filepath.Abs(args[0])
 			if err != nil {
 				return err
 			}
-			r, err := defaultFs.Open(filePath)
-			if err != nil {
-				return err
-			}
-			h, err := har.Decode(r)
+			data, err := afero.ReadFile(defaultFs, filePath)
 			if err != nil {
 				return err
 			}
-			if err = r.Close(); err != nil {
-				return err
-			}
-
-			// recordings include redirections as separate requests, and we dont want to trigger them twice
-			options := lib.Options{MaxRedirects: null.IntFrom(0)}
 
-			if optionsFilePath != "" {
-				optionsFileContents, err := ioutil.ReadFile(optionsFilePath) //nolint:gosec,govet
-				if err != nil {
-					return err
-				}
-				var injectedOptions lib.Options
-				if err := json.Unmarshal(optionsFileContents, &injectedOptions); err != nil {
-					return err
-				}
-				options = options.Apply(injectedOptions)
+			format := convertInputFormat
+			if format == "auto" {
+				format = detectConvertInputFormat(data)
 			}
 
-			// TODO: refactor...
-			script, err := har.Convert(h, options, minSleep, maxSleep, enableChecks,
-				returnOnFailedCheck, threshold, nobatch, correlate, only, skip)
+			var script string
+			switch format {
+			case "har":
+				script, err = convertHAR(data)
+			case "openapi":
+				script, err = convertOpenAPI(data)
+			case "postman":
+				script, err = convertPostman(data)
+			case "jmx":
+				script, err = convertJMX(data)
+			default:
+				return fmt.Errorf("unsupported input format '%s', it has to be auto, har, openapi, postman or jmx", format)
+			}
 			if err != nil {
 				return err
 			}
@@ -137,6 +147,14 @@ func getConvertCmd() *cobra.Command {
 		&convertOutput, "output", "O", convertOutput,
 		"k6 script output filename (stdout by default)",
 	)
+	convertCmd.Flags().StringVarP(
+		&convertInputFormat, "input-format", "", "auto",
+		"the format of the input file: har, openapi, postman, jmx, or auto to detect it",
+	)
+	convertCmd.Flags().StringVarP(
+		&postmanEnvironment, "postman-environment", "", postmanEnvironment,
+		"path to a Postman environment with the values of the variables of the collection",
+	)
 	convertCmd.Flags().StringVarP(
 		&optionsFilePath, "options", "", optionsFilePath,
 		"path to a JSON file with options that would be injected in the output script",
@@ -152,3 +170,95 @@ func getConvertCmd() *cobra.Command {
 	convertCmd.Flags().UintVarP(&maxSleep, "max-sleep", "", 40, "the maximum amount of seconds to sleep after each iteration")
 	return convertCmd
 }
+
+// detectConvertInputFormat returns the format of the file to convert, HAR files are the default.
+func detectConvertInputFormat(data []byte) string {
+	if jmx.IsTestPlan(data) {
+		return "jmx"
+	}
+	if postman.IsCollection(data) {
+		return "postman"
+	}
+	if openapi.IsDocument(data) {
+		return "openapi"
+	}
+	return "har"
+}
+
+func readConvertOptions(options lib.Options) (lib.Options, error) {
+	if optionsFilePath == "" {
+		return options, nil
+	}
+	optionsFileContents, err := ioutil.ReadFile(optionsFilePath) //nolint:gosec
+	if err != nil {
+		return options, err
+	}
+	var injectedOptions lib.Options
+	if err := json.Unmarshal(optionsFileContents, &injectedOptions); err != nil {
+		return options, err
+	}
+	return options.Apply(injectedOptions), nil
+}
+
+func convertHAR(data []byte) (string, error) {
+	h, err := har.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	// recordings include redirections as separate requests, and we dont want to trigger them twice
+	options, err := readConvertOptions(lib.Options{MaxRedirects: null.IntFrom(0)})
+	if err != nil {
+		return "", err
+	}
+
+	// TODO: refactor...
+	return har.Convert(h, options, minSleep, maxSleep, enableChecks,
+		returnOnFailedCheck, threshold, nobatch, correlate, only, skip)
+}
+
+func convertOpenAPI(data []byte) (string, error) {
+	doc, err := openapi.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	options, err := readConvertOptions(lib.Options{})
+	if err != nil {
+		return "", err
+	}
+	return openapi.Convert(doc, options, minSleep, maxSleep, enableChecks, returnOnFailedCheck)
+}
+
+func convertPostman(data []byte) (string, error) {
+	collection, err := postman.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	var env *postman.Environment
+	if postmanEnvironment != "" {
+		envData, err := afero.ReadFile(defaultFs, postmanEnvironment)
+		if err != nil {
+			return "", err
+		}
+		if env, err = postman.DecodeEnvironment(bytes.NewReader(envData)); err != nil {
+			return "", err
+		}
+	}
+	options, err := readConvertOptions(lib.Options{})
+	if err != nil {
+		return "", err
+	}
+	return postman.Convert(collection, env, options, minSleep, maxSleep, enableChecks, returnOnFailedCheck)
+}
+
+func convertJMX(data []byte) (string, error) {
+	plan, err := jmx.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	options, err := readConvertOptions(lib.Options{})
+	if err != nil {
+		return "", err
+	}
+	return jmx.Convert(plan, options, enableChecks, returnOnFailedCheck)
+}
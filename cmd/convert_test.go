@@ -208,6 +208,79 @@ func TestIntegrationConvertCmd(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, testHARConvertResult, string(output))
 	})
+	t.Run("OpenAPI", func(t *testing.T) {
+		docFile, err := filepath.Abs("openapi.yaml")
+		require.NoError(t, err)
+		doc, err := ioutil.ReadFile("../converter/openapi/testdata/petstore.yaml")
+		require.NoError(t, err)
+		expected, err := ioutil.ReadFile("../converter/openapi/testdata/petstore.js")
+		require.NoError(t, err)
+		defaultFs = afero.NewMemMapFs()
+		err = afero.WriteFile(defaultFs, docFile, doc, 0o644)
+		require.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		defaultWriter = buf
+
+		convertCmd := getConvertCmd()
+		assert.NoError(t, convertCmd.Flags().Set("enable-status-code-checks", "true"))
+		err = convertCmd.RunE(convertCmd, []string{docFile})
+		assert.NoError(t, convertCmd.Flags().Set("enable-status-code-checks", "false"))
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), buf.String())
+
+		assert.NoError(t, convertCmd.Flags().Set("input-format", "har"))
+		err = convertCmd.RunE(convertCmd, []string{docFile})
+		assert.NoError(t, convertCmd.Flags().Set("input-format", "auto"))
+		assert.Error(t, err)
+	})
+	t.Run("Postman", func(t *testing.T) {
+		collectionFile, err := filepath.Abs("collection.json")
+		require.NoError(t, err)
+		collection, err := ioutil.ReadFile("../converter/postman/testdata/collection.json")
+		require.NoError(t, err)
+		env, err := ioutil.ReadFile("../converter/postman/testdata/staging.postman_environment.json")
+		require.NoError(t, err)
+		expected, err := ioutil.ReadFile("../converter/postman/testdata/collection.js")
+		require.NoError(t, err)
+		defaultFs = afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(defaultFs, collectionFile, collection, 0o644))
+		require.NoError(t, afero.WriteFile(defaultFs, "/staging.json", env, 0o644))
+
+		buf := &bytes.Buffer{}
+		defaultWriter = buf
+
+		convertCmd := getConvertCmd()
+		assert.NoError(t, convertCmd.Flags().Set("postman-environment", "/staging.json"))
+		assert.NoError(t, convertCmd.Flags().Set("enable-status-code-checks", "true"))
+		assert.NoError(t, convertCmd.Flags().Set("return-on-failed-check", "true"))
+		err = convertCmd.RunE(convertCmd, []string{collectionFile})
+		assert.NoError(t, convertCmd.Flags().Set("postman-environment", ""))
+		assert.NoError(t, convertCmd.Flags().Set("enable-status-code-checks", "false"))
+		assert.NoError(t, convertCmd.Flags().Set("return-on-failed-check", "false"))
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), buf.String())
+	})
+	t.Run("JMX", func(t *testing.T) {
+		planFile, err := filepath.Abs("plan.jmx")
+		require.NoError(t, err)
+		plan, err := ioutil.ReadFile("../converter/jmx/testdata/plan.jmx")
+		require.NoError(t, err)
+		expected, err := ioutil.ReadFile("../converter/jmx/testdata/plan.js")
+		require.NoError(t, err)
+		defaultFs = afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(defaultFs, planFile, plan, 0o644))
+
+		buf := &bytes.Buffer{}
+		defaultWriter = buf
+
+		convertCmd := getConvertCmd()
+		assert.NoError(t, convertCmd.Flags().Set("enable-status-code-checks", "true"))
+		err = convertCmd.RunE(convertCmd, []string{planFile})
+		assert.NoError(t, convertCmd.Flags().Set("enable-status-code-checks", "false"))
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), buf.String())
+	})
 	// TODO: test options injection; right now that's difficult because when there are multiple
 	// options, they can be emitted in different order in the JSON
 }
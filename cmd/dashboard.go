@@ -0,0 +1,375 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/ssh/terminal"
+
+	"go.k6.io/k6/core"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+	"go.k6.io/k6/ui/pb"
+)
+
+const (
+	dashboardUpdateFreq  = 1 * time.Second
+	dashboardHistorySize = 30
+)
+
+//nolint:gochecknoglobals
+var sparklineChars = []rune("▁▂▃▄▅▆▇█")
+
+// dashboardSnapshot contains the cumulative values of the metrics shown in
+// the dashboard at a certain point in time.
+type dashboardSnapshot struct {
+	time          time.Time
+	vus           float64
+	iterations    float64
+	reqs          float64
+	failedReqs    int64
+	totalReqs     int64
+	durationCount uint64
+	durationSum   float64
+	durationMin   float64
+	durationMax   float64
+}
+
+// scenarioRequests counts the failed HTTP requests of a scenario.
+type scenarioRequests struct {
+	failed, total int64
+}
+
+// dashboard is a live overview of the test run, shown in the terminal instead
+// of the plain progress bars when k6 is executed with --dashboard. Besides the
+// progress and the error rate of every scenario, it shows the request rate,
+// the error rate and the latency of the HTTP requests, with sparkline charts
+// of their recent values.
+//
+// The dashboard is also an output of the engine, since the error rates of the
+// scenarios are counted from the scenario tag of the http_req_failed samples,
+// which the engine doesn't aggregate on its own.
+type dashboard struct {
+	pbs        []*pb.ProgressBar
+	executors  []lib.Executor
+	widthDelta int
+
+	mutex     sync.Mutex
+	engine    *core.Engine
+	last      dashboardSnapshot
+	rps       []float64
+	latency   []float64
+	scenarios map[string]*scenarioRequests
+}
+
+var _ output.Output = &dashboard{}
+
+func newDashboard(pbs []*pb.ProgressBar, executors []lib.Executor) *dashboard {
+	return &dashboard{pbs: pbs, executors: executors, scenarios: make(map[string]*scenarioRequests)}
+}
+
+// Description returns the description of the dashboard as an output.
+func (d *dashboard) Description() string {
+	return "dashboard"
+}
+
+// Start is a no-op, the dashboard is shown by showDashboard().
+func (d *dashboard) Start() error {
+	return nil
+}
+
+// Stop is a no-op, the dashboard is shown by showDashboard().
+func (d *dashboard) Stop() error {
+	return nil
+}
+
+// AddMetricSamples counts the failed HTTP requests of every scenario.
+func (d *dashboard) AddMetricSamples(containers []stats.SampleContainer) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, sc := range containers {
+		for _, sample := range sc.GetSamples() {
+			if sample.Metric.Name != metrics.HTTPReqFailed.Name {
+				continue
+			}
+			scenario, _ := sample.Tags.Get("scenario")
+			reqs, ok := d.scenarios[scenario]
+			if !ok {
+				reqs = &scenarioRequests{}
+				d.scenarios[scenario] = reqs
+			}
+			reqs.total++
+			if sample.Value != 0 {
+				reqs.failed++
+			}
+		}
+	}
+}
+
+// setEngine makes the dashboard start showing the metrics of the engine,
+// which is created only after the progress of the initialization is shown.
+func (d *dashboard) setEngine(engine *core.Engine) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.engine = engine
+	d.last = d.snapshot(time.Now())
+}
+
+// snapshot has to be called with the dashboard mutex held.
+func (d *dashboard) snapshot(now time.Time) dashboardSnapshot {
+	snap := dashboardSnapshot{time: now}
+	if d.engine == nil {
+		return snap
+	}
+
+	d.engine.MetricsLock.Lock()
+	defer d.engine.MetricsLock.Unlock()
+	if m, ok := d.engine.Metrics[metrics.VUs.Name]; ok {
+		if sink, ok := m.Sink.(*stats.GaugeSink); ok {
+			snap.vus = sink.Value
+		}
+	}
+	if m, ok := d.engine.Metrics[metrics.Iterations.Name]; ok {
+		if sink, ok := m.Sink.(*stats.CounterSink); ok {
+			snap.iterations = sink.Value
+		}
+	}
+	if m, ok := d.engine.Metrics[metrics.HTTPReqs.Name]; ok {
+		if sink, ok := m.Sink.(*stats.CounterSink); ok {
+			snap.reqs = sink.Value
+		}
+	}
+	if m, ok := d.engine.Metrics[metrics.HTTPReqFailed.Name]; ok {
+		if sink, ok := m.Sink.(*stats.RateSink); ok {
+			snap.failedReqs, snap.totalReqs = sink.Trues, sink.Total
+		}
+	}
+	if m, ok := d.engine.Metrics[metrics.HTTPReqDuration.Name]; ok {
+		if sink, ok := m.Sink.(*stats.TrendSink); ok {
+			snap.durationCount, snap.durationSum = sink.Count, sink.Sum
+			snap.durationMin, snap.durationMax = sink.Min, sink.Max
+		}
+	}
+	return snap
+}
+
+// update takes a new snapshot of the metrics and adds the request rate and the
+// average latency since the previous one to the chart histories.
+func (d *dashboard) update(now time.Time) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.engine == nil {
+		return
+	}
+
+	snap := d.snapshot(now)
+	if elapsed := snap.time.Sub(d.last.time).Seconds(); elapsed > 0 {
+		d.rps = appendHistory(d.rps, (snap.reqs-d.last.reqs)/elapsed)
+	}
+	if count := snap.durationCount - d.last.durationCount; count > 0 {
+		d.latency = appendHistory(d.latency, (snap.durationSum-d.last.durationSum)/float64(count))
+	} else {
+		d.latency = appendHistory(d.latency, 0)
+	}
+	d.last = snap
+}
+
+func appendHistory(history []float64, value float64) []float64 {
+	history = append(history, value)
+	if len(history) > dashboardHistorySize {
+		history = history[len(history)-dashboardHistorySize:]
+	}
+	return history
+}
+
+// sparkline returns a chart of the values, scaled between 0 and their maximum.
+func sparkline(values []float64) string {
+	var max float64
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	var sb strings.Builder
+	for _, v := range values {
+		i := 0
+		if max > 0 {
+			i = int(math.Round(v / max * float64(len(sparklineChars)-1)))
+		}
+		sb.WriteRune(sparklineChars[i])
+	}
+	return sb.String()
+}
+
+func formatDuration(ms float64) string {
+	return time.Duration(ms * float64(time.Millisecond)).Round(time.Microsecond).String()
+}
+
+// render returns the text of the dashboard. If goBack is true, it ends with
+// the terminal codes that move the cursor back to its start, so that the next
+// render overwrites it.
+func (d *dashboard) render(termWidth int, goBack bool) string {
+	var leftLen int64
+	for _, pb := range d.pbs {
+		leftLen = lib.Max(int64(len(pb.Left())), leftLen)
+	}
+	maxLeft := int(lib.Min(leftLen, maxLeftLength))
+
+	barText, longestLine := renderMultipleBars(true, false, maxLeft, termWidth, d.widthDelta, d.pbs)
+	d.widthDelta = termWidth - longestLine - termPadding
+	lines := strings.Split(strings.TrimSuffix(barText, "\x1b[K\n"), "\x1b[K\n")
+
+	lines = append(lines, "", fmt.Sprintf("  %-20s %-8s %6s %12s %12s %8s",
+		"scenario", "status", "vus", "iterations", "interrupted", "errors"))
+	d.mutex.Lock()
+	for _, exec := range d.executors {
+		observable, ok := exec.(lib.ObservableExecutor)
+		if !ok {
+			continue
+		}
+		st := observable.GetStats()
+		status := "waiting"
+		switch {
+		case st.Running:
+			status = "running"
+		case st.Stopped:
+			status = "stopped"
+		case !st.StartTime.IsZero():
+			status = "done"
+		}
+		name := exec.GetConfig().GetName()
+		errRate := "-"
+		if reqs, ok := d.scenarios[name]; ok && reqs.total > 0 {
+			errRate = fmt.Sprintf("%.2f%%", float64(reqs.failed)/float64(reqs.total)*100)
+		}
+		if len(name) > 20 {
+			name = name[:19] + "…"
+		}
+		lines = append(lines, fmt.Sprintf("  %-20s %-8s %6d %12d %12d %8s",
+			name, status, st.ActiveVUs, st.Iterations, st.InterruptedIterations, errRate))
+	}
+
+	if d.engine != nil {
+		last := d.last
+		var rps float64
+		if len(d.rps) > 0 {
+			rps = d.rps[len(d.rps)-1]
+		}
+		var failedRate float64
+		if last.totalReqs > 0 {
+			failedRate = float64(last.failedReqs) / float64(last.totalReqs) * 100
+		}
+		var avgDuration float64
+		if last.durationCount > 0 {
+			avgDuration = last.durationSum / float64(last.durationCount)
+		}
+		lines = append(lines,
+			"",
+			fmt.Sprintf("  vus........: %-10.0f iterations: %.0f", last.vus, last.iterations),
+			fmt.Sprintf("  requests...: %-10.0f %8.2f/s  %s", last.reqs, rps, sparkline(d.rps)),
+			fmt.Sprintf("  errors.....: %-10d %8.2f%%", last.failedReqs, failedRate),
+			fmt.Sprintf("  latency....: avg=%s min=%s max=%s  %s",
+				formatDuration(avgDuration), formatDuration(last.durationMin),
+				formatDuration(last.durationMax), sparkline(d.latency)),
+		)
+	}
+	d.mutex.Unlock()
+
+	var sb strings.Builder
+	for _, line := range lines {
+		// Trim the lines to the terminal width, so the amount of lines to
+		// go back is known. This won't work well with colored text
+		// (e.g. the progress bars), since it counts the escape codes.
+		if utf8.RuneCountInString(line) > termWidth-termPadding && !strings.Contains(line, "\x1b") {
+			line = string([]rune(line)[:termWidth-termPadding])
+		}
+		sb.WriteString(line)
+		sb.WriteString("\x1b[K\n")
+	}
+	if goBack {
+		sb.WriteString(fmt.Sprintf("\r\x1b[J\x1b[%dA", len(lines)))
+	}
+	return sb.String()
+}
+
+// showDashboard periodically renders the dashboard, until the context is done.
+// It should only be used when stdout is a TTY.
+func showDashboard(ctx context.Context, dash *dashboard) {
+	fd := int(os.Stdout.Fd())
+	termWidth := defaultTermWidth
+	updateTermWidth := func() {
+		if tw, _, err := terminal.GetSize(fd); tw > 0 && err == nil {
+			termWidth = tw
+		}
+	}
+	updateTermWidth()
+
+	var lastRenderLock sync.Mutex
+	var lastRender []byte
+	printDashboard := func() {
+		lastRenderLock.Lock()
+		_, _ = stdout.Writer.Write(lastRender)
+		lastRenderLock.Unlock()
+	}
+	renderDashboard := func(goBack bool) {
+		text := dash.render(termWidth, goBack)
+		lastRenderLock.Lock()
+		lastRender = []byte(text)
+		lastRenderLock.Unlock()
+		outMutex.Lock()
+		printDashboard()
+		outMutex.Unlock()
+	}
+
+	outMutex.Lock()
+	stdout.PersistentText = printDashboard
+	stderr.PersistentText = printDashboard
+	outMutex.Unlock()
+	defer func() {
+		outMutex.Lock()
+		stdout.PersistentText = nil
+		stderr.PersistentText = nil
+		outMutex.Unlock()
+	}()
+
+	ticker := time.NewTicker(dashboardUpdateFreq)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			dash.update(time.Now())
+			renderDashboard(false)
+			return
+		case now := <-ticker.C:
+			updateTermWidth()
+			dash.update(now)
+			renderDashboard(true)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/core"
+	"go.k6.io/k6/core/local"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/executor"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/lib/testutils/minirunner"
+	"go.k6.io/k6/stats"
+)
+
+func TestSparkline(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "", sparkline(nil))
+	assert.Equal(t, "▁▁▁", sparkline([]float64{0, 0, 0}))
+	assert.Equal(t, "▁▅█", sparkline([]float64{0, 5, 10}))
+}
+
+func TestDashboardRender(t *testing.T) {
+	t.Parallel()
+	logger := logrus.New()
+	logger.SetOutput(testutils.NewTestOutput(t))
+	options, err := executor.DeriveScenariosFromShortcuts(lib.Options{})
+	require.NoError(t, err)
+	execScheduler, err := local.NewExecutionScheduler(&minirunner.MiniRunner{Options: options}, logger)
+	require.NoError(t, err)
+	engine, err := core.NewEngine(execScheduler, options, lib.RuntimeOptions{}, nil, logger)
+	require.NoError(t, err)
+
+	dash := newDashboard(createTestProgressBars(2, 0, 1), execScheduler.GetExecutors())
+	text := dash.render(80, true)
+	assert.Contains(t, text, "left 0")
+	assert.Contains(t, text, "default              waiting       0            0            0        -")
+	assert.NotContains(t, text, "requests")
+
+	start := time.Now()
+	dash.setEngine(engine)
+	dash.last.time = start
+	engine.MetricsLock.Lock()
+	reqs := metrics.HTTPReqs.Derive(metrics.HTTPReqs.Name)
+	reqs.Sink.Add(stats.Sample{Value: 20})
+	duration := metrics.HTTPReqDuration.Derive(metrics.HTTPReqDuration.Name)
+	duration.Sink.Add(stats.Sample{Value: 100})
+	duration.Sink.Add(stats.Sample{Value: 300})
+	engine.Metrics[reqs.Name] = reqs
+	engine.Metrics[duration.Name] = duration
+	engine.MetricsLock.Unlock()
+	dash.update(start.Add(2 * time.Second))
+	failed := func(scenario string, value float64) stats.Sample {
+		return stats.Sample{
+			Metric: metrics.HTTPReqFailed, Value: value,
+			Tags: stats.IntoSampleTags(&map[string]string{"scenario": scenario}),
+		}
+	}
+	dash.AddMetricSamples([]stats.SampleContainer{
+		failed("default", 1), failed("default", 0), failed("default", 0), failed("default", 0), failed("other", 1),
+	})
+
+	text = dash.render(80, true)
+	assert.Contains(t, text, "default              waiting       0            0            0   25.00%")
+	assert.Contains(t, text, "requests...: 20            10.00/s  █")
+	assert.Contains(t, text, "latency....: avg=200ms min=100ms max=300ms  █")
+	lines := strings.Count(text, "\n")
+	assert.True(t, strings.HasSuffix(text, fmt.Sprintf("\x1b[J\x1b[%dA", lines)), text)
+}
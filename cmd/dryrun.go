@@ -0,0 +1,123 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/core"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/executor"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+// dryRunScenario is the result of the iteration of a scenario in a dry run.
+type dryRunScenario struct {
+	Name            string `json:"name"`
+	Exec            string `json:"exec"`
+	OK              bool   `json:"ok"`
+	Iterations      uint64 `json:"iterations"`
+	IterationErrors uint64 `json:"iterationErrors"`
+	Interrupted     uint64 `json:"interruptedIterations"`
+}
+
+// dryRunReport is the validation report that k6 run --dry-run prints instead of the end-of-test
+// summary.
+type dryRunReport struct {
+	OK                 bool             `json:"ok"`
+	Scenarios          []dryRunScenario `json:"scenarios"`
+	ChecksPassed       int64            `json:"checksPassed"`
+	ChecksFailed       int64            `json:"checksFailed"`
+	HTTPRequests       int64            `json:"httpRequests"`
+	FailedHTTPRequests int64            `json:"failedHTTPRequests"`
+}
+
+// getDryRunConfig replaces the scenarios of the config with a single iteration of a single VU for
+// each of them, all starting at the beginning of the test. The thresholds are removed, since they
+// aren't meaningful for a single iteration.
+func getDryRunConfig(conf Config) Config {
+	scenarios := make(lib.ScenarioConfigs, len(conf.Scenarios))
+	for name, sc := range conf.Scenarios {
+		dry := executor.NewPerVUIterationsConfig(name)
+		dry.VUs = null.IntFrom(1)
+		dry.Iterations = null.IntFrom(1)
+		dry.GracefulStop = types.NullDurationFrom(0)
+		dry.Exec = null.StringFrom(sc.GetExec())
+		dry.Env = sc.GetEnv()
+		dry.Tags = sc.GetTags()
+		scenarios[name] = dry
+	}
+	conf.Scenarios = scenarios
+	conf.VUs = null.Int{}
+	conf.Iterations = null.Int{}
+	conf.Duration = types.NullDuration{}
+	conf.Stages = nil
+	conf.Thresholds = nil
+	return conf
+}
+
+// getDryRunReport returns the results of the iterations of the scenarios of a dry run, which
+// is successful if each scenario finished its iteration without errors or failed checks.
+func getDryRunReport(engine *core.Engine, executors []lib.Executor) dryRunReport {
+	report := dryRunReport{OK: true, Scenarios: []dryRunScenario{}}
+	for _, exec := range executors {
+		config := exec.GetConfig()
+		scenario := dryRunScenario{Name: config.GetName(), Exec: config.GetExec()}
+		if observable, ok := exec.(lib.ObservableExecutor); ok {
+			st := observable.GetStats()
+			scenario.Iterations = st.Iterations
+			scenario.IterationErrors = st.IterationErrors
+			scenario.Interrupted = st.InterruptedIterations
+		}
+		scenario.OK = scenario.Iterations > 0 && scenario.IterationErrors == 0 && scenario.Interrupted == 0
+		report.OK = report.OK && scenario.OK
+		report.Scenarios = append(report.Scenarios, scenario)
+	}
+
+	engine.MetricsLock.Lock()
+	defer engine.MetricsLock.Unlock()
+	if m, ok := engine.Metrics[metrics.Checks.Name]; ok {
+		if sink, ok := m.Sink.(*stats.RateSink); ok {
+			report.ChecksPassed, report.ChecksFailed = sink.Trues, sink.Total-sink.Trues
+		}
+	}
+	if m, ok := engine.Metrics[metrics.HTTPReqFailed.Name]; ok {
+		if sink, ok := m.Sink.(*stats.RateSink); ok {
+			report.HTTPRequests, report.FailedHTTPRequests = sink.Total, sink.Trues
+		}
+	}
+	report.OK = report.OK && report.ChecksFailed == 0
+	return report
+}
+
+func writeDryRunReport(w io.Writer, report dryRunReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,78 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/executor"
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+func TestGetDryRunConfig(t *testing.T) {
+	t.Parallel()
+	arrivalRate := executor.NewConstantArrivalRateConfig("api")
+	arrivalRate.Rate = null.IntFrom(100)
+	arrivalRate.Duration = types.NullDurationFrom(time.Hour)
+	arrivalRate.PreAllocatedVUs = null.IntFrom(50)
+	arrivalRate.Exec = null.StringFrom("api")
+	arrivalRate.Env = map[string]string{"TARGET": "api"}
+	arrivalRate.Tags = map[string]string{"team": "backend"}
+	arrivalRate.StartTime = types.NullDurationFrom(time.Minute)
+	constantVUs := executor.NewConstantVUsConfig(lib.DefaultScenarioName)
+	constantVUs.VUs = null.IntFrom(100)
+
+	conf := Config{Options: lib.Options{
+		VUs:        null.IntFrom(100),
+		Duration:   types.NullDurationFrom(time.Hour),
+		Scenarios:  lib.ScenarioConfigs{"api": arrivalRate, lib.DefaultScenarioName: constantVUs},
+		Thresholds: map[string]stats.Thresholds{"checks": {}},
+	}}
+	dry := getDryRunConfig(conf)
+
+	assert.False(t, dry.VUs.Valid)
+	assert.False(t, dry.Duration.Valid)
+	assert.Nil(t, dry.Thresholds)
+	require.Len(t, dry.Scenarios, 2)
+	for name, sc := range dry.Scenarios {
+		perVU, ok := sc.(executor.PerVUIterationsConfig)
+		require.True(t, ok, name)
+		assert.Equal(t, null.IntFrom(1), perVU.VUs, name)
+		assert.Equal(t, null.IntFrom(1), perVU.Iterations, name)
+		assert.Equal(t, time.Duration(0), perVU.GetStartTime(), name)
+		assert.Empty(t, perVU.Validate(), name)
+	}
+	api := dry.Scenarios["api"]
+	assert.Equal(t, "api", api.GetExec())
+	assert.Equal(t, map[string]string{"TARGET": "api"}, api.GetEnv())
+	assert.Equal(t, map[string]string{"team": "backend"}, api.GetTags())
+	assert.Equal(t, "default", dry.Scenarios[lib.DefaultScenarioName].GetExec())
+	et, err := lib.NewExecutionTuple(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), lib.GetMaxPlannedVUs(dry.Scenarios.GetFullExecutionRequirements(et)))
+}
@@ -27,14 +27,56 @@ import (
 	"os"
 
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"go.k6.io/k6/js"
 	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/consts"
+	"go.k6.io/k6/lib/types"
 	"go.k6.io/k6/loader"
 )
 
-func getInspectCmd(logger logrus.FieldLogger) *cobra.Command {
+// inspectExecutionStep is a step of the execution requirements of a scenario or of the test.
+type inspectExecutionStep struct {
+	TimeOffset      types.Duration `json:"timeOffset"`
+	PlannedVUs      uint64         `json:"plannedVUs"`
+	MaxUnplannedVUs uint64         `json:"maxUnplannedVUs"`
+}
+
+// inspectScenario is the execution timeline of a scenario, the times are offsets from the start
+// of the test.
+type inspectScenario struct {
+	Name           string                 `json:"name"`
+	Executor       string                 `json:"executor"`
+	Exec           string                 `json:"exec"`
+	Description    string                 `json:"description"`
+	StartTime      types.Duration         `json:"startTime"`
+	EndTime        types.Duration         `json:"endTime"`
+	GracefulStop   types.Duration         `json:"gracefulStop"`
+	MaxVUs         uint64                 `json:"maxVUs"`
+	MaxPossibleVUs uint64                 `json:"maxPossibleVUs"`
+	Steps          []inspectExecutionStep `json:"steps"`
+}
+
+// inspectExecutionRequirements is the output of k6 inspect --execution-requirements.
+type inspectExecutionRequirements struct {
+	Options        lib.Options            `json:"options"`
+	Scenarios      []inspectScenario      `json:"scenarios"`
+	Timeline       []inspectExecutionStep `json:"timeline"`
+	MaxVUs         uint64                 `json:"maxVUs"`
+	MaxPossibleVUs uint64                 `json:"maxPossibleVUs"`
+	TotalDuration  types.Duration         `json:"totalDuration"`
+	// IsFinal is false when the test doesn't end by itself, like with the
+	// externally-controlled executor.
+	IsFinal bool `json:"isFinal"`
+}
+
+//nolint:funlen
+func getInspectCmd(logger *logrus.Logger) *cobra.Command {
+	var executionRequirements bool
+
 	// inspectCmd represents the inspect command
 	inspectCmd := &cobra.Command{
 		Use:   "inspect [file]",
@@ -62,6 +104,23 @@ func getInspectCmd(logger logrus.FieldLogger) *cobra.Command {
 				return err
 			}
 
+			if executionRequirements {
+				r, err := newRunner(logger, src, typ, filesystems, runtimeOptions)
+				if err != nil {
+					return err
+				}
+				requirements, err := getInspectExecutionRequirements(cmd.Flags(), r)
+				if err != nil {
+					return err
+				}
+				data, err := json.MarshalIndent(requirements, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
 			var (
 				opts lib.Options
 				b    *js.Bundle
@@ -73,6 +132,9 @@ func getInspectCmd(logger logrus.FieldLogger) *cobra.Command {
 				if err != nil {
 					return err
 				}
+				if err = decryptArchive(arc); err != nil {
+					return err
+				}
 				b, err = js.NewBundleFromArchive(logger, arc, runtimeOptions)
 				if err != nil {
 					return err
@@ -98,6 +160,74 @@ func getInspectCmd(logger logrus.FieldLogger) *cobra.Command {
 	inspectCmd.Flags().SortFlags = false
 	inspectCmd.Flags().AddFlagSet(runtimeOptionFlagSet(false))
 	inspectCmd.Flags().StringVarP(&runType, "type", "t", runType, "override file `type`, \"js\" or \"archive\"")
+	inspectCmd.Flags().BoolVar(&executionRequirements, "execution-requirements", false,
+		"print the consolidated options of the script, the environment, the config file and the CLI flags, "+
+			"with the execution timeline of the scenarios and the max VUs and duration of the test")
+	inspectCmd.Flags().AddFlagSet(optionFlagSet())
 
 	return inspectCmd
 }
+
+// getInspectExecutionRequirements consolidates the options like k6 run does and returns them with
+// the execution requirements of the scenarios and of the whole test.
+func getInspectExecutionRequirements(flags *pflag.FlagSet, r lib.Runner) (*inspectExecutionRequirements, error) {
+	cliOpts, err := getOptions(flags)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := getConsolidatedConfig(afero.NewOsFs(), Config{Options: cliOpts}, r)
+	if err != nil {
+		return nil, err
+	}
+	conf, err = deriveAndValidateConfig(conf, r.IsExecutable)
+	if err != nil {
+		return nil, err
+	}
+	et, err := lib.NewExecutionTuple(conf.ExecutionSegment, conf.ExecutionSegmentSequence)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &inspectExecutionRequirements{Options: conf.Options, Scenarios: []inspectScenario{}}
+	for _, sc := range conf.Scenarios.GetSortedConfigs() {
+		steps := sc.GetExecutionRequirements(et)
+		duration, _ := lib.GetEndOffset(steps)
+		scenario := inspectScenario{
+			Name:           sc.GetName(),
+			Executor:       sc.GetType(),
+			Exec:           sc.GetExec(),
+			Description:    sc.GetDescription(et),
+			StartTime:      types.Duration(sc.GetStartTime()),
+			EndTime:        types.Duration(sc.GetStartTime() + duration),
+			GracefulStop:   types.Duration(sc.GetGracefulStop()),
+			MaxVUs:         lib.GetMaxPlannedVUs(steps),
+			MaxPossibleVUs: lib.GetMaxPossibleVUs(steps),
+			Steps:          inspectExecutionSteps(steps),
+		}
+		if scenario.Exec == "" {
+			scenario.Exec = consts.DefaultFn
+		}
+		result.Scenarios = append(result.Scenarios, scenario)
+	}
+
+	plan := conf.Scenarios.GetFullExecutionRequirements(et)
+	totalDuration, isFinal := lib.GetEndOffset(plan)
+	result.Timeline = inspectExecutionSteps(plan)
+	result.MaxVUs = lib.GetMaxPlannedVUs(plan)
+	result.MaxPossibleVUs = lib.GetMaxPossibleVUs(plan)
+	result.TotalDuration = types.Duration(totalDuration)
+	result.IsFinal = isFinal
+	return result, nil
+}
+
+func inspectExecutionSteps(steps []lib.ExecutionStep) []inspectExecutionStep {
+	result := make([]inspectExecutionStep, len(steps))
+	for i, s := range steps {
+		result[i] = inspectExecutionStep{
+			TimeOffset:      types.Duration(s.TimeOffset),
+			PlannedVUs:      s.PlannedVUs,
+			MaxUnplannedVUs: s.MaxUnplannedVUs,
+		}
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/loader"
+)
+
+func TestInspectExecutionRequirements(t *testing.T) {
+	t.Parallel()
+	script := []byte(`
+		export let options = {
+			scenarios: {
+				warmup: { executor: 'constant-vus', vus: 2, duration: '10s', gracefulStop: '0s' },
+				main: {
+					executor: 'ramping-vus', startTime: '10s', exec: 'main', gracefulStop: '0s',
+					gracefulRampDown: '0s', stages: [{ duration: '20s', target: 10 }],
+				},
+			},
+		};
+		export default function() {}
+		export function main() {}
+	`)
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/script.js", script, 0o644))
+	runner, err := newRunner(
+		testutils.NewLogger(t),
+		&loader.SourceData{Data: script, URL: &url.URL{Path: "/script.js", Scheme: "file"}},
+		typeJS,
+		map[string]afero.Fs{"file": fs},
+		lib.RuntimeOptions{},
+	)
+	require.NoError(t, err)
+
+	flags := optionFlagSet()
+	require.NoError(t, flags.Parse([]string{"--tag", "team=qa"}))
+	requirements, err := getInspectExecutionRequirements(flags, runner)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"team": "qa"}, requirements.Options.RunTags.CloneTags())
+	require.Len(t, requirements.Scenarios, 2)
+	warmup, main := requirements.Scenarios[0], requirements.Scenarios[1]
+	assert.Equal(t, "warmup", warmup.Name)
+	assert.Equal(t, "default", warmup.Exec)
+	assert.Equal(t, types.Duration(0), warmup.StartTime)
+	assert.Equal(t, types.Duration(10*time.Second), warmup.EndTime)
+	assert.Equal(t, uint64(2), warmup.MaxVUs)
+	assert.Equal(t, "main", main.Name)
+	assert.Equal(t, "ramping-vus", main.Executor)
+	assert.Equal(t, "main", main.Exec)
+	assert.Equal(t, types.Duration(10*time.Second), main.StartTime)
+	assert.Equal(t, types.Duration(30*time.Second), main.EndTime)
+	assert.Equal(t, uint64(10), main.MaxVUs)
+
+	assert.Equal(t, uint64(10), requirements.MaxVUs)
+	assert.Equal(t, types.Duration(30*time.Second), requirements.TotalDuration)
+	assert.True(t, requirements.IsFinal)
+	last := requirements.Timeline[len(requirements.Timeline)-1]
+	assert.Equal(t, inspectExecutionStep{TimeOffset: types.Duration(30 * time.Second)}, last)
+}
@@ -0,0 +1,78 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+// jsonFormatter formats the logs as JSON objects, one per line. The entries of the VUs, like the
+// messages of the console, have the VU context, and have the vu, iter, scenario and line fields
+// of the VU and the script line that logged them. These entries have to be logged from the
+// goroutine of the VU, since the line is taken from the call stack of its runtime.
+type jsonFormatter struct {
+	logrus.JSONFormatter
+}
+
+func newJSONFormatter() *jsonFormatter {
+	return &jsonFormatter{logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}}
+}
+
+// Format renders a single log entry
+func (f *jsonFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Context == nil {
+		return f.JSONFormatter.Format(entry)
+	}
+
+	fields := make(logrus.Fields, 4)
+	if state := lib.GetState(entry.Context); state != nil {
+		fields["vu"] = state.VUIDGlobal
+		fields["iter"] = state.Iteration
+	}
+	if scenario := lib.GetScenarioState(entry.Context); scenario != nil {
+		fields["scenario"] = scenario.Name
+	}
+	if rt := common.GetRuntime(entry.Context); rt != nil {
+		for _, frame := range rt.CaptureCallStack(10, nil) {
+			if pos := frame.Position(); pos.Line > 0 {
+				fields["line"] = fmt.Sprintf("%s:%d:%d", frame.SrcName(), pos.Line, pos.Column)
+				break
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return f.JSONFormatter.Format(entry)
+	}
+
+	// the fields of the entry are kept, the entry is copied since it can be formatted by others
+	for k, v := range entry.Data {
+		fields[k] = v
+	}
+	withFields := *entry
+	withFields.Data = fields
+	return f.JSONFormatter.Format(&withFields)
+}
@@ -0,0 +1,77 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+func TestJSONFormatter(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(newJSONFormatter())
+
+	decode := func() map[string]interface{} {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		buf.Reset()
+		return entry
+	}
+
+	logger.WithField("source", "console").Info("plain")
+	entry := decode()
+	assert.Equal(t, "plain", entry["msg"])
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "console", entry["source"])
+	assert.NotContains(t, entry, "vu")
+
+	rt := goja.New()
+	ctx := lib.WithState(context.Background(), &lib.State{VUIDGlobal: 3, Iteration: 7})
+	ctx = lib.WithScenarioState(ctx, &lib.ScenarioState{Name: "checkout"})
+	ctx = common.WithRuntime(ctx, rt)
+	require.NoError(t, rt.Set("log", func(msg string) {
+		logger.WithField("source", "console").WithContext(ctx).Warn(msg)
+	}))
+	_, err := rt.RunScript("script.js", "\n  log('from the VU')")
+	require.NoError(t, err)
+
+	entry = decode()
+	assert.Equal(t, "from the VU", entry["msg"])
+	assert.Equal(t, "warning", entry["level"])
+	assert.Equal(t, "console", entry["source"])
+	assert.Equal(t, float64(3), entry["vu"])
+	assert.Equal(t, float64(7), entry["iter"])
+	assert.Equal(t, "checkout", entry["scenario"])
+	assert.Equal(t, "script.js:2:7", entry["line"])
+}
@@ -118,7 +118,12 @@ This will set the default token used when just "k6 run -o cloud" is passed.`,
 				email := vals["Email"].(string)
 				password := vals["Password"].(string)
 
-				client := cloudapi.NewClient(logger, "", consolidatedCurrentConfig.Host.String, consts.Version)
+				loginConfig := consolidatedCurrentConfig
+				loginConfig.Token = null.String{}
+				client, err := cloudapi.NewClientFromConfig(logger, loginConfig, consts.Version)
+				if err != nil {
+					return err
+				}
 				res, err := client.Login(email, password)
 				if err != nil {
 					return err
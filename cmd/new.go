@@ -0,0 +1,301 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"go.k6.io/k6/lib/consts"
+)
+
+// The built-in templates of k6 new, the files of common are in all of them. The files with the
+// .tmpl extension are rendered with the newTemplateData, and the dot- prefix of the names is
+// replaced with a dot, since these files can't be embedded.
+//go:embed templates
+var newTemplates embed.FS //nolint:gochecknoglobals
+
+// newTemplateData is the data the .tmpl files of the templates are rendered with.
+type newTemplateData struct {
+	Name     string
+	Template string
+	Version  string
+}
+
+//nolint:funlen
+func getNewCmd(logger logrus.FieldLogger) *cobra.Command {
+	var (
+		dir   string
+		force bool
+		list  bool
+	)
+
+	newCmd := &cobra.Command{
+		Use:   "new [template]",
+		Short: "Create a new test project from a template",
+		Long: `Create a new test project from a template.
+
+The project has a script, an options file with the load profile and the thresholds, the
+configuration of the environments, a handleSummary() function that writes the reports of
+each run, and a CI pipeline that runs the test.
+
+The template is one of the built-in templates, "basic" by default, or the URL of a git
+repository, which is cloned and copied without its .git directory. The files of a template
+with the .tmpl extension are rendered as Go templates, with the {{ .Name }}, {{ .Template }}
+and {{ .Version }} fields.`,
+		Example: `
+  # Create a project in the current directory.
+  k6 new
+
+  # Create a project for testing an API in the api-test directory.
+  k6 new api --dir api-test
+
+  # Create a project from the template of a git repository.
+  k6 new https://github.com/example/k6-template.git --dir my-test
+
+  # List the built-in templates.
+  k6 new --list`[1:],
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list {
+				names, err := builtinNewTemplates()
+				if err != nil {
+					return err
+				}
+				for _, name := range names {
+					fprintf(stdout, "%s\n", name)
+				}
+				return nil
+			}
+
+			name := "basic"
+			if len(args) > 0 {
+				name = args[0]
+			}
+			absDir, err := filepath.Abs(dir)
+			if err != nil {
+				return err
+			}
+			data := newTemplateData{Name: filepath.Base(absDir), Template: name, Version: consts.Version}
+
+			var files map[string][]byte
+			if isGitTemplate(name) {
+				files, err = gitNewTemplate(name)
+			} else {
+				files, err = builtinNewTemplate(name)
+			}
+			if err != nil {
+				return err
+			}
+			files, err = renderNewTemplate(files, data)
+			if err != nil {
+				return err
+			}
+			if err := writeNewProject(defaultFs, dir, files, force); err != nil {
+				return err
+			}
+			logger.Infof("Created the %s project in %s, run it with: k6 run --config options.json script.js",
+				name, dir)
+			return nil
+		},
+	}
+
+	flags := newCmd.Flags()
+	flags.SortFlags = false
+	flags.StringVarP(&dir, "dir", "d", ".", "the directory of the project, it's created if it doesn't exist")
+	flags.BoolVarP(&force, "force", "f", false, "overwrite the files that already exist")
+	flags.BoolVar(&list, "list", false, "list the built-in templates")
+	return newCmd
+}
+
+func builtinNewTemplates() ([]string, error) {
+	entries, err := newTemplates.ReadDir("templates")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != "common" {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// builtinNewTemplate returns the files of a built-in template, with their paths in the project.
+func builtinNewTemplate(name string) (map[string][]byte, error) {
+	names, err := builtinNewTemplates()
+	if err != nil {
+		return nil, err
+	}
+	i := sort.SearchStrings(names, name)
+	if i == len(names) || names[i] != name {
+		return nil, fmt.Errorf("unknown template '%s', it has to be one of %s or the URL of a git repository",
+			name, strings.Join(names, ", "))
+	}
+
+	files := make(map[string][]byte)
+	for _, dir := range []string{"templates/common", "templates/" + name} {
+		err := fs.WalkDir(newTemplates, dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := newTemplates.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			files[strings.TrimPrefix(p, dir+"/")] = data
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func isGitTemplate(name string) bool {
+	return strings.HasPrefix(name, "https://") || strings.HasPrefix(name, "http://") ||
+		strings.HasPrefix(name, "git@") || strings.HasPrefix(name, "ssh://") || strings.HasSuffix(name, ".git")
+}
+
+// gitNewTemplate returns the files of the template in a git repository, cloned with the git
+// command, without the files of its .git directory.
+func gitNewTemplate(repoURL string) (map[string][]byte, error) {
+	tmpDir, err := ioutil.TempDir("", "k6-new-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	clone := exec.Command("git", "clone", "--quiet", "--depth", "1", "--", repoURL, tmpDir) //nolint:gosec
+	var stderr bytes.Buffer
+	clone.Stderr = &stderr
+	if err := clone.Run(); err != nil {
+		return nil, fmt.Errorf("couldn't clone the template '%s': %w %s", repoURL, err, strings.TrimSpace(stderr.String()))
+	}
+
+	files := make(map[string][]byte)
+	err = filepath.Walk(tmpDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(tmpDir, p)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(p) //nolint:gosec
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("the template '%s' doesn't have any files", repoURL)
+	}
+	return files, nil
+}
+
+// renderNewTemplate renders the .tmpl files of the template and renames the dot- files.
+func renderNewTemplate(files map[string][]byte, data newTemplateData) (map[string][]byte, error) {
+	rendered := make(map[string][]byte, len(files))
+	for p, content := range files {
+		parts := strings.Split(p, "/")
+		for i, part := range parts {
+			if strings.HasPrefix(part, "dot-") {
+				parts[i] = "." + strings.TrimPrefix(part, "dot-")
+			}
+		}
+		p = strings.Join(parts, "/")
+
+		if strings.HasSuffix(p, ".tmpl") {
+			tmpl, err := template.New(p).Option("missingkey=error").Parse(string(content))
+			if err != nil {
+				return nil, fmt.Errorf("invalid template file '%s': %w", p, err)
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				return nil, fmt.Errorf("couldn't render the template file '%s': %w", p, err)
+			}
+			p, content = strings.TrimSuffix(p, ".tmpl"), buf.Bytes()
+		}
+		rendered[p] = content
+	}
+	return rendered, nil
+}
+
+// writeNewProject writes the files of the project in the directory, it doesn't overwrite any
+// existing file unless force is true.
+func writeNewProject(afs afero.Fs, dir string, files map[string][]byte, force bool) error {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	if !force {
+		var existing []string
+		for _, p := range paths {
+			if _, err := afs.Stat(filepath.Join(dir, filepath.FromSlash(p))); err == nil {
+				existing = append(existing, p)
+			}
+		}
+		if len(existing) > 0 {
+			return fmt.Errorf("the project files %s already exist in %s, use --force to overwrite them",
+				strings.Join(existing, ", "), dir)
+		}
+	}
+
+	for _, p := range paths {
+		target := filepath.Join(dir, filepath.FromSlash(p))
+		if err := afs.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := afero.WriteFile(afs, target, files[p], 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
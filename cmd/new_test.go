@@ -0,0 +1,81 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib/consts"
+)
+
+func TestNewProject(t *testing.T) {
+	t.Parallel()
+	names, err := builtinNewTemplates()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api", "basic"}, names)
+
+	for _, name := range names {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			files, err := builtinNewTemplate(name)
+			require.NoError(t, err)
+			files, err = renderNewTemplate(files, newTemplateData{Name: "my-test", Template: name, Version: consts.Version})
+			require.NoError(t, err)
+
+			fs := afero.NewMemMapFs()
+			require.NoError(t, writeNewProject(fs, "/my-test", files, false))
+			for _, p := range []string{
+				"script.js", "options.json", "env/local.json", "env/staging.json", "summary.js",
+				".github/workflows/k6.yml", "README.md",
+			} {
+				exists, err := afero.Exists(fs, "/my-test/"+p)
+				require.NoError(t, err)
+				assert.True(t, exists, p)
+			}
+			readme, err := afero.ReadFile(fs, "/my-test/README.md")
+			require.NoError(t, err)
+			assert.Contains(t, string(readme), "# my-test\n")
+			assert.Contains(t, string(readme), "`k6 new "+name+"`")
+			workflow, err := afero.ReadFile(fs, "/my-test/.github/workflows/k6.yml")
+			require.NoError(t, err)
+			assert.Contains(t, string(workflow), "/v"+consts.Version+"/k6-v"+consts.Version+"-linux-amd64.tar.gz")
+
+			err = writeNewProject(fs, "/my-test", files, false)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "already exist in /my-test, use --force to overwrite them")
+			assert.NoError(t, writeNewProject(fs, "/my-test", files, true))
+		})
+	}
+
+	_, err = builtinNewTemplate("nope")
+	assert.EqualError(t, err, "unknown template 'nope', it has to be one of api, basic or the URL of a git repository")
+	assert.True(t, isGitTemplate("https://github.com/example/template.git"))
+	assert.True(t, isGitTemplate("git@github.com:example/template.git"))
+	assert.False(t, isGitTemplate("api"))
+
+	_, err = renderNewTemplate(map[string][]byte{"a.tmpl": []byte("{{ .Missing }}")}, newTemplateData{})
+	assert.Error(t, err)
+}
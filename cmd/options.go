@@ -59,15 +59,30 @@ func optionFlagSet() *pflag.FlagSet {
 	flags.Bool("no-setup", false, "don't run setup()")
 	flags.Bool("no-teardown", false, "don't run teardown()")
 	flags.Int64("max-redirects", 10, "follow at most n redirects")
+	flags.Int64("max-response-header-bytes", 0, "fail HTTP responses with headers larger than n bytes, 0 means 10MB")
 	flags.Int64("batch", 20, "max parallel batch reqs")
 	flags.Int64("batch-per-host", 6, "max parallel batch reqs per host")
+	flags.Int64("async-requests", 20, "max in-flight async reqs per VU")
+	flags.Int64("async-requests-per-host", 6, "max in-flight async reqs per VU and host")
 	flags.Int64("rps", 0, "limit requests per second")
+	flags.String("files-dir", "", "allow the script to write files with k6/files in this `directory`")
+	flags.Int64("files-rate", 0, "limit the bytes per second written with k6/files")
+	flags.StringArray("secret-source", nil,
+		"get the secrets of k6/secrets from this `source`: env, file=path, vault=path or aws[=region], can be repeated")
 	flags.String("user-agent", fmt.Sprintf("k6/%s (https://k6.io/)", consts.Version), "user agent for http requests")
 	flags.String("http-debug", "", "log all HTTP requests and responses. Excludes body by default. To include body use '--http-debug=full'")
 	flags.Lookup("http-debug").NoOptDefVal = "headers"
+	flags.String("grpc-debug", "", "log all gRPC calls with their metadata and message sizes. "+
+		"To include the messages use '--grpc-debug=full', and also their encoded bytes '--grpc-debug=wire'")
+	flags.Lookup("grpc-debug").NoOptDefVal = "headers"
 	flags.Bool("insecure-skip-tls-verify", false, "skip verification of TLS certificates")
 	flags.Bool("no-connection-reuse", false, "disable keep-alive connections")
 	flags.Bool("no-vu-connection-reuse", false, "don't reuse connections between iterations")
+	flags.Bool("no-cookies-reset", false, "keep the cookies of the VUs between iterations")
+	flags.Int64("max-idle-conns-per-host", 0, "max idle connections kept per host and VU, the batch-per-host value by default")
+	flags.Duration("idle-conn-timeout", 0, "close the idle connections after this duration, they're kept by default")
+	flags.Bool("http-conn-pool-metrics", false, "emit the metrics of the open and idle connections and the waits for them")
+	flags.Bool("http-cache", false, "emulate the cache of a browser with conditional requests for the cached responses")
 	flags.Duration("min-iteration-duration", 0, "minimum amount of time k6 will take executing a single iteration")
 	flags.BoolP("throw", "w", false, "throw warnings (like failed http requests) as errors")
 	flags.StringSlice("blacklist-ip", nil, "blacklist an `ip range` from being called")
@@ -82,6 +97,8 @@ func optionFlagSet() *pflag.FlagSet {
 	)
 	flags.StringSlice("summary-trend-stats", nil, sumTrendStatsHelp)
 	flags.String("summary-time-unit", "", "define the time unit used to display the trend stats. Possible units are: 's', 'ms' and 'us'")
+	flags.Duration("summary-time-series-interval", 0, "aggregate the metrics in intervals of this `duration` "+
+		"and pass them to handleSummary() as time series; 0 disables them")
 	// system-tags must have a default value, but we can't specify it here, otherwiese, it will always override others.
 	// set it to nil here, and add the default in applyDefault() instead.
 	systemTagsCliHelpText := fmt.Sprintf(
@@ -90,10 +107,20 @@ func optionFlagSet() *pflag.FlagSet {
 	)
 	flags.StringSlice("system-tags", nil, systemTagsCliHelpText)
 	flags.StringSlice("tag", nil, "add a `tag` to be applied to all samples, as `[name]=[value]`")
-	flags.String("console-output", "", "redirects the console logging to the provided output file")
+	flags.StringSlice("static-tag", nil, "add a static `tag` to all samples sent to the outputs, "+
+		"including the ones of k6 itself, as `[name]=[value]`")
+	flags.StringSlice("static-metadata", nil, "add static `metadata` to all samples sent to the outputs, "+
+		"as `[key]=[value]`")
+	flags.Int64("max-tag-values", 0, "max distinct values for every tag of every metric, "+
+		"additional values will be replaced with '"+stats.TagValueOverflow+"'; 0 means unlimited")
+	flags.String("console-output", "", "redirects the console logging to the provided output file, or to "+
+		"'file=path,maxSize=10MB,maxBackups=3', 'loki=url', 'log,level=warn' or 'none'")
 	flags.Bool("discard-response-bodies", false, "Read but don't process or save HTTP response bodies")
 	flags.String("local-ips", "", "Client IP Ranges and/or CIDRs from which each VU will be making requests, "+
 		"e.g. '192.168.220.1,192.168.0.10-192.168.0.25', 'fd:1::0/120', etc.")
+	flags.Duration("tcp-keep-alive", 30*time.Second, "period of the TCP keep-alive probes, 0 disables them")
+	flags.Int64("dscp", 0, "DSCP marking of the IP packets, from 0 to 63, e.g. 46 for expedited forwarding")
+	flags.Bool("tcp-reuse-port", false, "set SO_REUSEPORT on the sockets of the connections")
 	flags.String("dns", types.DefaultDNSConfig().String(), "DNS resolver configuration. Possible ttl values are: 'inf' "+
 		"for a persistent cache, '0' to disable the cache,\nor a positive duration, e.g. '1s', '1m', etc. "+
 		"Milliseconds are assumed if no unit is provided.\n"+
@@ -104,24 +131,42 @@ func optionFlagSet() *pflag.FlagSet {
 
 func getOptions(flags *pflag.FlagSet) (lib.Options, error) {
 	opts := lib.Options{
-		VUs:                   getNullInt64(flags, "vus"),
-		Duration:              getNullDuration(flags, "duration"),
-		Iterations:            getNullInt64(flags, "iterations"),
-		Paused:                getNullBool(flags, "paused"),
-		NoSetup:               getNullBool(flags, "no-setup"),
-		NoTeardown:            getNullBool(flags, "no-teardown"),
-		MaxRedirects:          getNullInt64(flags, "max-redirects"),
-		Batch:                 getNullInt64(flags, "batch"),
-		BatchPerHost:          getNullInt64(flags, "batch-per-host"),
-		RPS:                   getNullInt64(flags, "rps"),
-		UserAgent:             getNullString(flags, "user-agent"),
-		HTTPDebug:             getNullString(flags, "http-debug"),
-		InsecureSkipTLSVerify: getNullBool(flags, "insecure-skip-tls-verify"),
-		NoConnectionReuse:     getNullBool(flags, "no-connection-reuse"),
-		NoVUConnectionReuse:   getNullBool(flags, "no-vu-connection-reuse"),
-		MinIterationDuration:  getNullDuration(flags, "min-iteration-duration"),
-		Throw:                 getNullBool(flags, "throw"),
-		DiscardResponseBodies: getNullBool(flags, "discard-response-bodies"),
+		VUs:                       getNullInt64(flags, "vus"),
+		Duration:                  getNullDuration(flags, "duration"),
+		Iterations:                getNullInt64(flags, "iterations"),
+		Paused:                    getNullBool(flags, "paused"),
+		NoSetup:                   getNullBool(flags, "no-setup"),
+		NoTeardown:                getNullBool(flags, "no-teardown"),
+		MaxRedirects:              getNullInt64(flags, "max-redirects"),
+		Batch:                     getNullInt64(flags, "batch"),
+		BatchPerHost:              getNullInt64(flags, "batch-per-host"),
+		AsyncRequests:             getNullInt64(flags, "async-requests"),
+		AsyncRequestsPerHost:      getNullInt64(flags, "async-requests-per-host"),
+		RPS:                       getNullInt64(flags, "rps"),
+		UserAgent:                 getNullString(flags, "user-agent"),
+		HTTPDebug:                 getNullString(flags, "http-debug"),
+		GRPCDebug:                 getNullString(flags, "grpc-debug"),
+		InsecureSkipTLSVerify:     getNullBool(flags, "insecure-skip-tls-verify"),
+		NoConnectionReuse:         getNullBool(flags, "no-connection-reuse"),
+		NoVUConnectionReuse:       getNullBool(flags, "no-vu-connection-reuse"),
+		NoCookiesReset:            getNullBool(flags, "no-cookies-reset"),
+		MaxIdleConnsPerHost:       getNullInt64(flags, "max-idle-conns-per-host"),
+		TCPKeepAlive:              getNullDuration(flags, "tcp-keep-alive"),
+		DSCP:                      getNullInt64(flags, "dscp"),
+		TCPReusePort:              getNullBool(flags, "tcp-reuse-port"),
+		IdleConnTimeout:           getNullDuration(flags, "idle-conn-timeout"),
+		HTTPConnPoolMetrics:       getNullBool(flags, "http-conn-pool-metrics"),
+		HTTPCache:                 getNullBool(flags, "http-cache"),
+		MinIterationDuration:      getNullDuration(flags, "min-iteration-duration"),
+		Throw:                     getNullBool(flags, "throw"),
+		DiscardResponseBodies:     getNullBool(flags, "discard-response-bodies"),
+		MaxTagValues:              getNullInt64(flags, "max-tag-values"),
+		MaxResponseHeaderBytes:    getNullInt64(flags, "max-response-header-bytes"),
+		SummaryTimeSeriesInterval: getNullDuration(flags, "summary-time-series-interval"),
+
+		FilesDir:  getNullString(flags, "files-dir"),
+		FilesRate: getNullInt64(flags, "files-rate"),
+
 		// Default values for options without CLI flags:
 		// TODO: find a saner and more dev-friendly and error-proof way to handle options
 		SetupTimeout:    types.NullDuration{Duration: types.Duration(60 * time.Second), Valid: false},
@@ -130,6 +175,14 @@ func getOptions(flags *pflag.FlagSet) (lib.Options, error) {
 		MetricSamplesBufferSize: null.NewInt(1000, false),
 	}
 
+	if flags.Changed("secret-source") {
+		secretSources, err := flags.GetStringArray("secret-source")
+		if err != nil {
+			return opts, err
+		}
+		opts.SecretSources = secretSources
+	}
+
 	// Using Changed() because GetStringSlice() doesn't differentiate between empty and no value
 	if flags.Changed("stage") {
 		stageStrings, err := flags.GetStringSlice("stage")
@@ -256,6 +309,13 @@ func getOptions(flags *pflag.FlagSet) (lib.Options, error) {
 		opts.RunTags = stats.IntoSampleTags(&parsedRunTags)
 	}
 
+	if opts.StaticTags, err = getStaticKeyValues(flags, "static-tag"); err != nil {
+		return opts, err
+	}
+	if opts.StaticMetadata, err = getStaticKeyValues(flags, "static-metadata"); err != nil {
+		return opts, err
+	}
+
 	redirectConFile, err := flags.GetString("console-output")
 	if err != nil {
 		return opts, err
@@ -276,6 +336,23 @@ func getOptions(flags *pflag.FlagSet) (lib.Options, error) {
 	return opts, nil
 }
 
+// getStaticKeyValues returns the [name]=[value] pairs of the flag, nil if there are none.
+func getStaticKeyValues(flags *pflag.FlagSet, name string) (map[string]string, error) {
+	pairs, err := flags.GetStringSlice(name)
+	if err != nil || len(pairs) == 0 {
+		return nil, err
+	}
+	result := make(map[string]string, len(pairs))
+	for _, s := range pairs {
+		key, value, err := parseTagNameValue(s)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s '%s': %w", name, s, err)
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
 func parseTagNameValue(nv string) (string, string, error) {
 	if nv == "" {
 		return "", "", ErrTagEmptyString
@@ -32,11 +32,22 @@ import (
 	"go.k6.io/k6/lib"
 	"go.k6.io/k6/loader"
 	"go.k6.io/k6/output"
+	"go.k6.io/k6/output/azuremonitor"
 	"go.k6.io/k6/output/cloud"
 	"go.k6.io/k6/output/csv"
+	"go.k6.io/k6/output/gcp"
+	"go.k6.io/k6/output/graphite"
 	"go.k6.io/k6/output/influxdb"
 	"go.k6.io/k6/output/json"
+	"go.k6.io/k6/output/mqtt"
+	"go.k6.io/k6/output/ndjson"
+	"go.k6.io/k6/output/newrelic"
+	"go.k6.io/k6/output/notify"
+	"go.k6.io/k6/output/otlplogs"
+	"go.k6.io/k6/output/prometheus"
+	"go.k6.io/k6/output/splunk"
 	"go.k6.io/k6/output/statsd"
+	"go.k6.io/k6/output/webdashboard"
 )
 
 // TODO: move this to an output sub-module after we get rid of the old collectors?
@@ -44,6 +55,7 @@ func getAllOutputConstructors() (map[string]func(output.Params) (output.Output,
 	// Start with the built-in outputs
 	result := map[string]func(output.Params) (output.Output, error){
 		"json":     json.New,
+		"ndjson":   ndjson.New,
 		"cloud":    cloud.New,
 		"influxdb": influxdb.New,
 		"kafka": func(params output.Params) (output.Output, error) {
@@ -55,7 +67,17 @@ func getAllOutputConstructors() (map[string]func(output.Params) (output.Output,
 			return nil, errors.New("the datadog output was deprecated in k6 v0.32.0 and removed in k6 v0.34.0, " +
 				"please use the statsd output with env. variable K6_STATSD_ENABLE_TAGS=true instead")
 		},
-		"csv": csv.New,
+		"csv":           csv.New,
+		"web-dashboard": webdashboard.New,
+		"graphite":      graphite.New,
+		"newrelic":      newrelic.New,
+		"azure-monitor": azuremonitor.New,
+		"gcp":           gcp.New,
+		"splunk":        splunk.New,
+		"mqtt":          mqtt.New,
+		"prometheus":    prometheus.New,
+		"otlp-logs":     otlplogs.New,
+		"notify":        notify.New,
 	}
 
 	exts := output.GetExtensions()
@@ -83,7 +105,8 @@ func getPossibleIDList(constrs map[string]func(output.Params) (output.Output, er
 
 func createOutputs(
 	outputFullArguments []string, src *loader.SourceData, conf Config, rtOpts lib.RuntimeOptions,
-	executionPlan []lib.ExecutionStep, osEnvironment map[string]string, logger logrus.FieldLogger,
+	executionPlan []lib.ExecutionStep, osEnvironment map[string]string, runMetadata *lib.TestRunMetadata,
+	logger logrus.FieldLogger,
 ) ([]output.Output, error) {
 	outputConstructors, err := getAllOutputConstructors()
 	if err != nil {
@@ -99,6 +122,7 @@ func createOutputs(
 		ScriptOptions:  conf.Options,
 		RuntimeOptions: rtOpts,
 		ExecutionPlan:  executionPlan,
+		RunMetadata:    runMetadata,
 	}
 	result := make([]output.Output, 0, len(outputFullArguments))
 
@@ -117,16 +141,43 @@ func createOutputs(
 		params.ConfigArgument = outputArg
 		params.JSONConfig = conf.Collectors[outputType]
 
-		output, err := outputConstructor(params)
+		out, err := outputConstructor(params)
 		if err != nil {
 			return nil, fmt.Errorf("could not create the '%s' output: %w", outputType, err)
 		}
-		result = append(result, output)
+		if conf.OutputBufferMaxSamples.Valid {
+			if err := setOutputBufferLimits(out, conf, logger); err != nil {
+				return nil, fmt.Errorf("could not limit the buffer of the '%s' output: %w", outputType, err)
+			}
+		}
+		if filter, ok := conf.OutputFilters[outputFullArg]; ok {
+			out = output.NewFilteredOutput(out, filter)
+		} else if filter, ok := conf.OutputFilters[outputType]; ok {
+			out = output.NewFilteredOutput(out, filter)
+		}
+		result = append(result, out)
 	}
 
 	return result, nil
 }
 
+// setOutputBufferLimits sets the limits of the buffer of an output with a SampleBuffer, the
+// other outputs have their own buffers.
+func setOutputBufferLimits(out output.Output, conf Config, logger logrus.FieldLogger) error {
+	limited, ok := out.(interface {
+		SetBufferLimits(output.SampleBufferLimits) error
+	})
+	if !ok {
+		logger.Warnf("The %s output doesn't support the outputBufferMaxSamples option", out.Description())
+		return nil
+	}
+	return limited.SetBufferLimits(output.SampleBufferLimits{
+		MaxSamples:    int(conf.OutputBufferMaxSamples.Int64),
+		SpillDir:      conf.OutputBufferSpillDir.String,
+		MaxSpillBytes: conf.OutputBufferSpillMaxSize.Int64,
+	})
+}
+
 func parseOutputArgument(s string) (t, arg string) {
 	parts := strings.SplitN(s, "=", 2)
 	switch len(parts) {
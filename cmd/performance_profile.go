@@ -0,0 +1,104 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/guregu/null.v3"
+)
+
+const (
+	defaultPerformanceProfile        = "default"
+	highThroughputPerformanceProfile = "high-throughput"
+)
+
+// performanceProfile is a bundle of engine tunings that can be enabled with
+// the --performance-profile flag. Zero values mean that the k6 defaults are
+// kept for the particular setting.
+type performanceProfile struct {
+	// metricSamplesBufferSize is only used if the user hasn't explicitly
+	// configured the metricSamplesBufferSize option.
+	metricSamplesBufferSize int64
+	// gcPercent is passed to debug.SetGCPercent(), unless the GOGC
+	// environment variable was set.
+	gcPercent int
+	// progressUpdateFreq is the refresh interval of the progress bars.
+	progressUpdateFreq time.Duration
+	// disableAutoSubmetrics stops the engine from tracking the submetrics it
+	// creates by default, like http_req_duration{expected_response:true}.
+	disableAutoSubmetrics bool
+}
+
+//nolint:gochecknoglobals
+var performanceProfiles = map[string]performanceProfile{
+	defaultPerformanceProfile: {},
+	highThroughputPerformanceProfile: {
+		metricSamplesBufferSize: 100 * 1000,
+		gcPercent:               400,
+		progressUpdateFreq:      2 * time.Second,
+		disableAutoSubmetrics:   true,
+	},
+}
+
+func performanceProfileNames() []string {
+	names := make([]string, 0, len(performanceProfiles))
+	for name := range performanceProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getPerformanceProfile returns the profile selected in the config. The name
+// should have already been validated by Config.Validate().
+func getPerformanceProfile(conf Config) performanceProfile {
+	if !conf.PerformanceProfile.Valid {
+		return performanceProfiles[defaultPerformanceProfile]
+	}
+	return performanceProfiles[conf.PerformanceProfile.String]
+}
+
+// applyToConfig returns a copy of the config with the options of the profile
+// that weren't explicitly configured by the user.
+func (p performanceProfile) applyToConfig(conf Config) Config {
+	if p.metricSamplesBufferSize > 0 && !conf.MetricSamplesBufferSize.Valid {
+		conf.MetricSamplesBufferSize = null.IntFrom(p.metricSamplesBufferSize)
+	}
+	return conf
+}
+
+// applyToRuntime changes the Go runtime settings of the profile, unless they
+// were explicitly configured through the environment.
+func (p performanceProfile) applyToRuntime(env map[string]string, logger logrus.FieldLogger) {
+	if p.gcPercent == 0 {
+		return
+	}
+	if _, ok := env["GOGC"]; ok {
+		logger.Debug("GOGC is set, the performance profile won't change the GC percent")
+		return
+	}
+	prev := debug.SetGCPercent(p.gcPercent)
+	logger.Debugf("GC percent changed from %d to %d by the performance profile", prev, p.gcPercent)
+}
@@ -0,0 +1,65 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib"
+)
+
+func TestPerformanceProfile(t *testing.T) {
+	t.Parallel()
+
+	t.Run("validation", func(t *testing.T) {
+		t.Parallel()
+		assert.Empty(t, Config{PerformanceProfile: null.StringFrom("high-throughput")}.Validate())
+		errs := Config{PerformanceProfile: null.StringFrom("turbo")}.Validate()
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0].Error(), "unknown performance profile 'turbo'")
+	})
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+		conf := Config{Options: lib.Options{MetricSamplesBufferSize: null.NewInt(1000, false)}}
+		profile := getPerformanceProfile(conf)
+		assert.Equal(t, performanceProfile{}, profile)
+		assert.Equal(t, conf, profile.applyToConfig(conf))
+	})
+
+	t.Run("high-throughput", func(t *testing.T) {
+		t.Parallel()
+		conf := Config{
+			Options:            lib.Options{MetricSamplesBufferSize: null.NewInt(1000, false)},
+			PerformanceProfile: null.StringFrom("high-throughput"),
+		}
+		profile := getPerformanceProfile(conf)
+		assert.True(t, profile.disableAutoSubmetrics)
+		assert.Equal(t, null.IntFrom(100000), profile.applyToConfig(conf).MetricSamplesBufferSize)
+
+		conf.MetricSamplesBufferSize = null.IntFrom(500)
+		assert.Equal(t, null.IntFrom(500), profile.applyToConfig(conf).MetricSamplesBufferSize)
+	})
+}
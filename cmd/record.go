@@ -0,0 +1,212 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/converter/har"
+	"go.k6.io/k6/converter/recorder"
+	"go.k6.io/k6/lib"
+)
+
+//nolint:funlen,gocognit
+func getRecordCmd(ctx context.Context, logger *logrus.Logger) *cobra.Command {
+	var (
+		address            string
+		output             string
+		harOutput          string
+		caCertPath         string
+		caKeyPath          string
+		idleGap            time.Duration
+		includeStatic      bool
+		insecure           bool
+		recordOnly         []string
+		recordSkip         []string
+		recordChecks       bool
+		recordNoBatch      bool
+		recordCorrelate    bool
+		recordMinSleep     uint
+		recordMaxSleep     uint
+		recordBatchTimeout uint
+	)
+
+	recordCmd := &cobra.Command{
+		Use:   "record",
+		Short: "Record the traffic of a browser or an API client as a k6 script",
+		Long: `Record the traffic of a browser or an API client as a k6 script.
+
+k6 record starts an HTTP proxy that records the requests that go through it, until it's
+stopped with Ctrl+C, and converts them to a script like k6 convert does with HAR files.
+The requests are grouped by the pages the browser navigates to, or by the pauses of the
+traffic, and the script sleeps as long as the recorded user did between them.
+
+The HTTPS connections are intercepted with the certificates of a CA that is created in the
+--ca-cert and --ca-key files when they don't exist, the recorded clients have to trust it.`,
+		Example: `
+  # Record the traffic of a browser configured with the proxy localhost:8888.
+  k6 record -O recording.js
+
+  # Record only the requests to the given domains, and keep the HAR file.
+  k6 record -O recording.js --only example.com --har recording.har
+
+  # Record with the proxy listening on another address, then run the script.
+  k6 record --address 0.0.0.0:9090 -O recording.js && k6 run recording.js`[1:],
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ca, err := loadRecordCA(caCertPath, caKeyPath, logger)
+			if err != nil {
+				return err
+			}
+
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.Proxy = nil
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: insecure} //nolint:gosec
+			rec := recorder.New(ca, transport, idleGap, logger)
+
+			listener, err := net.Listen("tcp", address)
+			if err != nil {
+				return err
+			}
+			srv := &http.Server{Handler: rec}
+			go func() {
+				if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.WithError(err).Error("The recording proxy failed")
+				}
+			}()
+			logger.Infof("Recording, configure the browser or the client to use the proxy %s "+
+				"and stop the recording with Ctrl+C", listener.Addr())
+
+			sigC := make(chan os.Signal, 2)
+			signal.Notify(sigC, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+			defer signal.Stop(sigC)
+			select {
+			case <-sigC:
+			case <-ctx.Done():
+			}
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+
+			h := rec.HAR(includeStatic)
+			logger.Infof("Recorded %d requests", len(h.Log.Entries))
+			if harOutput != "" {
+				data, err := json.MarshalIndent(h, "", "  ")
+				if err != nil {
+					return err
+				}
+				if err := afero.WriteFile(defaultFs, harOutput, data, 0o644); err != nil {
+					return err
+				}
+			}
+
+			// the recordings include the redirections as separate requests, so k6 doesn't follow them
+			options, err := readConvertOptions(lib.Options{MaxRedirects: null.IntFrom(0)})
+			if err != nil {
+				return err
+			}
+			script, err := har.Convert(h, options, recordMinSleep, recordMaxSleep, recordChecks, false,
+				recordBatchTimeout, recordNoBatch || recordCorrelate, recordCorrelate, recordOnly, recordSkip)
+			if err != nil {
+				return err
+			}
+			if output == "" || output == "-" {
+				_, err = defaultWriter.Write([]byte(script))
+				return err
+			}
+			if err := afero.WriteFile(defaultFs, output, []byte(script), 0o644); err != nil {
+				return err
+			}
+			logger.Infof("The script was written to %s", output)
+			return nil
+		},
+	}
+
+	flags := recordCmd.Flags()
+	flags.SortFlags = false
+	flags.StringVarP(&output, "output", "O", "", "k6 script output filename (stdout by default)")
+	flags.StringVar(&address, "address", "localhost:8888", "address of the recording proxy")
+	flags.StringVar(&harOutput, "har", "", "also save the recording as a HAR file")
+	flags.StringVar(&caCertPath, "ca-cert", "k6-record-ca.crt",
+		"path to the certificate of the CA for the HTTPS connections, it's created if it doesn't exist")
+	flags.StringVar(&caKeyPath, "ca-key", "k6-record-ca.key", "path to the private key of the CA")
+	flags.BoolVar(&insecure, "insecure-skip-tls-verify", false, "don't verify the certificates of the recorded servers")
+	flags.DurationVar(&idleGap, "group-gap", 3*time.Second,
+		"the pause of the traffic that starts a new group, besides the navigations of the browser")
+	flags.BoolVar(&includeStatic, "include-static", false,
+		"include the requests of the static assets, like images, stylesheets, scripts and fonts")
+	flags.StringSliceVar(&recordOnly, "only", []string{}, "include only requests from the given domains")
+	flags.StringSliceVar(&recordSkip, "skip", []string{}, "skip requests from the given domains")
+	flags.StringVar(&optionsFilePath, "options", optionsFilePath,
+		"path to a JSON file with options that would be injected in the output script")
+	flags.UintVar(&recordBatchTimeout, "batch-threshold", 500, "batch request idle time threshold")
+	flags.BoolVar(&recordNoBatch, "no-batch", false, "don't generate batch calls")
+	flags.BoolVar(&recordCorrelate, "correlate", false,
+		"detect values in responses being used in subsequent requests and extract them in the script")
+	flags.BoolVar(&recordChecks, "enable-status-code-checks", false, "add a status code check for each HTTP response")
+	flags.UintVar(&recordMinSleep, "min-sleep", 20, "the minimum amount of seconds to sleep after each iteration")
+	flags.UintVar(&recordMaxSleep, "max-sleep", 40, "the maximum amount of seconds to sleep after each iteration")
+	return recordCmd
+}
+
+// loadRecordCA loads the CA of the recording proxy, or creates it in the files when they don't
+// exist, so the clients have to trust it only once.
+func loadRecordCA(certPath, keyPath string, logger logrus.FieldLogger) (*recorder.CA, error) {
+	certPEM, certErr := afero.ReadFile(defaultFs, certPath)
+	keyPEM, keyErr := afero.ReadFile(defaultFs, keyPath)
+	if certErr == nil && keyErr == nil {
+		return recorder.LoadCA(certPEM, keyPEM)
+	}
+	if !os.IsNotExist(certErr) || !os.IsNotExist(keyErr) {
+		if certErr != nil {
+			return nil, certErr
+		}
+		return nil, keyErr
+	}
+
+	ca, err := recorder.NewCA()
+	if err != nil {
+		return nil, err
+	}
+	if err := afero.WriteFile(defaultFs, certPath, ca.CertificatePEM(), 0o644); err != nil {
+		return nil, err
+	}
+	if err := afero.WriteFile(defaultFs, keyPath, ca.KeyPEM(), 0o600); err != nil {
+		return nil, err
+	}
+	logger.Infof("Created the CA certificate %s, the recorded browser or client has to trust it "+
+		"for the HTTPS requests", certPath)
+	return ca, nil
+}
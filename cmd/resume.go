@@ -27,7 +27,6 @@ import (
 	"gopkg.in/guregu/null.v3"
 
 	v1 "go.k6.io/k6/api/v1"
-	"go.k6.io/k6/api/v1/client"
 )
 
 func getResumeCmd(ctx context.Context) *cobra.Command {
@@ -37,9 +36,10 @@ func getResumeCmd(ctx context.Context) *cobra.Command {
 		Short: "Resume a paused test",
 		Long: `Resume a paused test.
 
-  Use the global --address flag to specify the URL to the API server.`,
+  Use the global --address flag to specify the URL to the API server, with the https:// prefix
+  if it serves HTTPS, and the K6_API_TOKEN environment variable for its token.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			c, err := client.New(address)
+			c, err := newAPIClient()
 			if err != nil {
 				return err
 			}
@@ -29,6 +29,7 @@ import (
 	stdlog "log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -69,9 +70,13 @@ var configFilePath = os.Getenv("K6_CONFIG") // Overridden by `-c`/`--config` fla
 //nolint:gochecknoglobals
 var (
 	// TODO: have environment variables for configuring these? hopefully after we move away from global vars though...
-	quiet   bool
-	noColor bool
-	address string
+	quiet              bool
+	noColor            bool
+	address            string
+	apiTLSCert         string
+	apiTLSKey          string
+	apiCACert          string
+	apiAllowedNetworks []string
 )
 
 // This is to keep all fields needed for the main/root k6 command
@@ -83,6 +88,8 @@ type rootCommand struct {
 	loggerStopped  <-chan struct{}
 	logOutput      string
 	logFmt         string
+	logRateLimit   int
+	logThrottle    *log.Throttle
 	loggerIsRemote bool
 	verbose        bool
 }
@@ -126,6 +133,18 @@ func (c *rootCommand) persistentPreRunE(cmd *cobra.Command, args []string) error
 			c.logOutput = envLogOutput
 		}
 	}
+	if !cmd.Flags().Changed("log-format") && !cmd.Flags().Changed("logformat") {
+		if envLogFormat, ok := os.LookupEnv("K6_LOG_FORMAT"); ok {
+			c.logFmt = envLogFormat
+		}
+	}
+	if !cmd.Flags().Changed("log-rate-limit") {
+		if envLogRateLimit, ok := os.LookupEnv("K6_LOG_RATE_LIMIT"); ok {
+			if c.logRateLimit, err = strconv.Atoi(envLogRateLimit); err != nil {
+				return fmt.Errorf("invalid K6_LOG_RATE_LIMIT `%s`: %w", envLogRateLimit, err)
+			}
+		}
+	}
 	c.loggerStopped, err = c.setupLoggers()
 	if err != nil {
 		return err
@@ -167,15 +186,19 @@ func Execute() {
 	c.cmd.AddCommand(
 		getArchiveCmd(logger),
 		getCloudCmd(ctx, logger),
+		getCompareCmd(),
 		getConvertCmd(),
 		getInspectCmd(logger),
 		loginCmd,
+		getNewCmd(logger),
 		getPauseCmd(ctx),
+		getRecordCmd(ctx, logger),
 		getResumeCmd(ctx),
 		getScaleCmd(ctx),
 		getRunCmd(ctx, logger),
 		getStatsCmd(ctx),
 		getStatusCmd(ctx),
+		getVendorCmd(logger),
 		getVersionCmd(),
 	)
 
@@ -199,6 +222,7 @@ func Execute() {
 		}
 
 		logger.WithFields(fields).Error(errText)
+		c.flushLogThrottle()
 		if c.loggerIsRemote {
 			fallbackLogger.WithFields(fields).Error(errText)
 			cancel()
@@ -208,10 +232,18 @@ func Execute() {
 		os.Exit(exitCode) //nolint:gocritic
 	}
 
+	c.flushLogThrottle()
 	cancel()
 	c.waitRemoteLogger()
 }
 
+// flushLogThrottle logs the summaries of the messages suppressed since its last flush.
+func (c *rootCommand) flushLogThrottle() {
+	if c.logThrottle != nil {
+		c.logThrottle.Flush()
+	}
+}
+
 func (c *rootCommand) waitRemoteLogger() {
 	if c.loggerIsRemote {
 		select {
@@ -230,8 +262,19 @@ func (c *rootCommand) rootCmdPersistentFlagSet() *pflag.FlagSet {
 	flags.BoolVar(&noColor, "no-color", false, "disable colored output")
 	flags.StringVar(&c.logOutput, "log-output", "stderr",
 		"change the output for k6 logs, possible values are stderr,stdout,none,loki[=host:port]")
-	flags.StringVar(&c.logFmt, "logformat", "", "log output format") // TODO rename to log-format and warn on old usage
+	flags.StringVar(&c.logFmt, "log-format", "", "log output format, possible values are text,json,raw")
+	flags.StringVar(&c.logFmt, "logformat", "", "log output format")
+	must(flags.MarkDeprecated("logformat", "use --log-format instead"))
+	flags.IntVar(&c.logRateLimit, "log-rate-limit", 0, "the maximum of identical log messages per second, "+
+		"the others are suppressed with a summary of their count, 0 means unlimited")
 	flags.StringVarP(&address, "address", "a", "localhost:6565", "address for the api server")
+	flags.StringVar(&apiTLSCert, "api-tls-cert", "", "path to the TLS certificate of the api server, "+
+		"it serves HTTPS with it and --api-tls-key")
+	flags.StringVar(&apiTLSKey, "api-tls-key", "", "path to the TLS private key of the api server")
+	flags.StringVar(&apiCACert, "api-ca-cert", "", "path to the CA certificate that the commands like "+
+		"k6 pause verify the certificate of the api server with")
+	flags.StringSliceVar(&apiAllowedNetworks, "api-allowed-networks", nil, "the IP addresses and the CIDR "+
+		"networks of the clients that can use the api server, all of them by default")
 
 	// TODO: Fix... This default value needed, so both CLI flags and environment variables work
 	flags.StringVarP(&configFilePath, "config", "c", configFilePath, "JSON config file")
@@ -299,11 +342,21 @@ func (c *rootCommand) setupLoggers() (<-chan struct{}, error) {
 		c.logger.SetFormatter(&RawFormatter{})
 		c.logger.Debug("Logger format: RAW")
 	case "json":
-		c.logger.SetFormatter(&logrus.JSONFormatter{})
+		c.logger.SetFormatter(newJSONFormatter())
 		c.logger.Debug("Logger format: JSON")
-	default:
+	case "", "text":
 		c.logger.SetFormatter(&logrus.TextFormatter{ForceColors: loggerForceColors, DisableColors: noColor})
 		c.logger.Debug("Logger format: TEXT")
+	default:
+		return nil, fmt.Errorf("unsupported log format `%s`", c.logFmt)
+	}
+
+	if c.logRateLimit < 0 {
+		return nil, fmt.Errorf("the log rate limit can't be negative, is %d", c.logRateLimit)
+	}
+	if c.logRateLimit > 0 {
+		c.logThrottle = log.NewThrottle(c.logger, c.logRateLimit)
+		go c.logThrottle.Run(c.ctx)
 	}
 	return ch, nil
 }
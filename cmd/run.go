@@ -49,7 +49,9 @@ import (
 	"go.k6.io/k6/js"
 	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/consts"
+	"go.k6.io/k6/lib/events"
 	"go.k6.io/k6/loader"
+	"go.k6.io/k6/output"
 	"go.k6.io/k6/ui/pb"
 )
 
@@ -60,7 +62,14 @@ const (
 
 // TODO: fix this, global variables are not very testable...
 //nolint:gochecknoglobals
-var runType = os.Getenv("K6_TYPE")
+var (
+	runType       = os.Getenv("K6_TYPE")
+	dryRun        bool
+	watch         bool
+	runManifest   string
+	webhooks      []string
+	webhookEvents []string
+)
 
 //nolint:funlen,gocognit,gocyclo
 func getRunCmd(ctx context.Context, logger *logrus.Logger) *cobra.Command {
@@ -88,10 +97,19 @@ a commandline interface for interacting with it.`,
   # Ramp VUs from 0 to 100 over 10s, stay there for 60s, then 10s down to 0.
   k6 run -u 0 -s 10s:100 -s 60s -s 10s:0
 
+  # Run a TypeScript test, its types are erased when it's loaded.
+  k6 run script.ts
+
+  # Validate the script with a single iteration of each scenario.
+  k6 run --dry-run script.js
+
+  # Validate the script again on each change of its files.
+  k6 run --watch script.js
+
   # Send metrics to an influxdb server
   k6 run -o influxdb=http://1.2.3.4:8086/k6`[1:],
 		Args: exactArgsWithMsg(1, "arg should either be \"-\", if reading script from stdin, or a path to a script file"),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			// TODO: disable in quiet mode?
 			_, _ = fmt.Fprintf(stdout, "\n%s\n\n", getBanner(noColor || !stdoutTTY))
 
@@ -114,6 +132,12 @@ a commandline interface for interacting with it.`,
 			if err != nil {
 				return err
 			}
+			if watch {
+				if filename == "-" {
+					return errors.New("--watch can't be used with a script from the standard input")
+				}
+				return watchScript(ctx, logger, filename, runtimeOptions)
+			}
 
 			initRunner, err := newRunner(logger, src, runType, filesystems, runtimeOptions)
 			if err != nil {
@@ -135,11 +159,29 @@ a commandline interface for interacting with it.`,
 			if err != nil {
 				return err
 			}
+			if dryRun {
+				conf = getDryRunConfig(conf)
+			}
+
+			perfProfile := getPerformanceProfile(conf)
+			conf = perfProfile.applyToConfig(conf)
+			perfProfile.applyToRuntime(osEnvironment, logger)
+
+			// the run_id tag of the samples is the run ID of the test run metadata of the outputs
+			runMetadata, err := lib.NewTestRunMetadata(src.URL.String(), src.Data, conf.Options)
+			if err != nil {
+				return err
+			}
+			conf.Options = runMetadata.WithRunIDTag(conf.Options)
 
 			// Write options back to the runner too.
 			if err = initRunner.SetOptions(conf.Options); err != nil {
 				return err
 			}
+			// the console outputs like Loki push their last messages when the runner is closed
+			if closer, ok := initRunner.(io.Closer); ok {
+				defer func() { _ = closer.Close() }()
+			}
 
 			// We prepare a bunch of contexts:
 			//  - The runCtx is cancelled as soon as the Engine's run() lambda finishes,
@@ -174,35 +216,67 @@ a commandline interface for interacting with it.`,
 			initBar := execScheduler.GetInitProgressBar()
 			progressBarWG := &sync.WaitGroup{}
 			progressBarWG.Add(1)
+			pbs := []*pb.ProgressBar{execScheduler.GetInitProgressBar()}
+			for _, s := range execScheduler.GetExecutors() {
+				pbs = append(pbs, s.GetProgress())
+			}
+			var dash *dashboard
+			if conf.Dashboard.Bool && !quiet {
+				if stdoutTTY {
+					dash = newDashboard(pbs, execScheduler.GetExecutors())
+				} else {
+					logger.Warn("The dashboard can only be shown in an interactive terminal, showing the progress bars instead")
+				}
+			}
 			go func() {
-				pbs := []*pb.ProgressBar{execScheduler.GetInitProgressBar()}
-				for _, s := range execScheduler.GetExecutors() {
-					pbs = append(pbs, s.GetProgress())
+				if dash != nil {
+					showDashboard(progressCtx, dash)
+				} else {
+					showProgress(progressCtx, conf, pbs, logger)
 				}
-				showProgress(progressCtx, conf, pbs, logger)
 				progressBarWG.Done()
 			}()
 
 			// Create all outputs.
 			executionPlan := execScheduler.GetExecutionPlan()
-			outputs, err := createOutputs(conf.Out, src, conf, runtimeOptions, executionPlan, osEnvironment, logger)
+			outputs, err := createOutputs(
+				conf.Out, src, conf, runtimeOptions, executionPlan, osEnvironment, runMetadata, logger)
 			if err != nil {
 				return err
 			}
 
 			// Create the engine.
 			initBar.Modify(pb.WithConstProgress(0, "Init engine"))
-			engine, err := core.NewEngine(execScheduler, conf.Options, runtimeOptions, outputs, logger)
+			engineOutputs := outputs
+			if dash != nil {
+				// the dashboard gets the samples too, to count the failed requests of every scenario
+				engineOutputs = append(append([]output.Output{}, outputs...), dash)
+			}
+			engine, err := core.NewEngine(execScheduler, conf.Options, runtimeOptions, engineOutputs, logger)
 			if err != nil {
 				return err
 			}
+			if perfProfile.disableAutoSubmetrics {
+				engine.DisableAutoSubmetrics()
+			}
+			if dash != nil {
+				dash.setEngine(engine)
+			}
+			eventBus, closeWebhooks, err := newEventBus(runMetadata.RunID, webhooks, webhookEvents, outputs, logger)
+			if err != nil {
+				return err
+			}
+			// the deferred calls run in reverse, so the webhooks get the end of the test run
+			defer closeWebhooks()
+			engine.SetEvents(eventBus)
+			execScheduler.SetEvents(eventBus)
 
 			// Spin up the REST API server, if not disabled.
 			if address != "" {
 				initBar.Modify(pb.WithConstProgress(0, "Init API server"))
 				go func() {
 					logger.Debugf("Starting the REST API server on %s", address)
-					if aerr := api.ListenAndServe(address, engine, logger); aerr != nil {
+					if aerr := api.ListenAndServe(getAPIConfig(), engine, logger); aerr != nil {
 						// Only exit k6 if the user has explicitly set the REST API address
 						if cmd.Flags().Lookup("address").Changed {
 							logger.WithError(aerr).Error("Error from API server")
@@ -221,6 +295,10 @@ a commandline interface for interacting with it.`,
 				return err
 			}
 			defer engine.StopOutputs()
+			// the deferred calls run in reverse, so the outputs get the manifest before they're stopped
+			defer func() {
+				finishRunManifest(runManifest, runMetadata, conf.Options, outputs, err, logger)
+			}()
 
 			printExecutionDescription(
 				"local", filename, "", conf, execScheduler.GetState().ExecutionTuple,
@@ -233,6 +311,7 @@ a commandline interface for interacting with it.`,
 			go func() {
 				sig := <-sigC
 				logger.WithField("sig", sig).Debug("Stopping k6 in response to signal...")
+				execScheduler.GetState().SetAbortReason(lib.AbortReasonSignal)
 				lingerCancel() // stop the test run, metric processing is cancelled below
 
 				// If we get a second signal, we immediately exit, so something like
@@ -270,7 +349,19 @@ a commandline interface for interacting with it.`,
 
 			// Start the test run
 			initBar.Modify(pb.WithConstProgress(0, "Starting test..."))
-			if err := engineRun(); err != nil {
+			startRunMetadata(runMetadata, outputs)
+			scenarioNames := make([]string, 0, len(conf.Scenarios))
+			for _, ec := range execScheduler.GetExecutorConfigs() {
+				scenarioNames = append(scenarioNames, ec.GetName())
+			}
+			eventBus.Publish(events.TestStart, map[string]interface{}{
+				"script":    src.URL.String(),
+				"scenarios": scenarioNames,
+				"maxVUs":    lib.GetMaxPossibleVUs(executionPlan),
+			})
+			// A test aborted by the script still has its summary, then k6 exits with its exit code
+			var abortErr *errext.AbortTest
+			if err := engineRun(); err != nil && !errors.As(err, &abortErr) {
 				return errext.WithExitCodeIfNone(err, exitcodes.GenericEngine)
 			}
 			runCancel()
@@ -280,23 +371,43 @@ a commandline interface for interacting with it.`,
 			progressBarWG.Wait()
 
 			executionState := execScheduler.GetState()
+			if eventBus != nil {
+				eventBus.Publish(events.TestEnd, getTestEndEventData(engine, executionState.GetCurrentTestRunDuration()))
+			}
 			// Warn if no iterations could be completed.
 			if executionState.GetFullIterationCount() == 0 {
 				logger.Warn("No script iterations finished, consider making the test duration longer")
 			}
 
+			// In a dry run, the validation report is shown instead of the end-of-test summary.
+			var dryRunErr error
+			if dryRun {
+				report := getDryRunReport(engine, execScheduler.GetExecutors())
+				if err := writeDryRunReport(stdout, report); err != nil {
+					logger.WithError(err).Error("failed to write the dry run report")
+				}
+				if !report.OK {
+					dryRunErr = errext.WithExitCodeIfNone(errors.New("the dry run failed"), exitcodes.DryRunFailed)
+				}
+			}
+
 			// Handle the end-of-test summary.
-			if !runtimeOptions.NoSummary.Bool {
-				summaryResult, err := initRunner.HandleSummary(globalCtx, &lib.Summary{
+			if !runtimeOptions.NoSummary.Bool && !dryRun {
+				summary := &lib.Summary{
 					Metrics:         engine.Metrics,
 					RootGroup:       engine.ExecutionScheduler.GetRunner().GetDefaultGroup(),
 					TestRunDuration: executionState.GetCurrentTestRunDuration(),
 					NoColor:         noColor,
+					TimeSeries:      engine.TimeSeries,
 					UIState: lib.UIState{
 						IsStdOutTTY: stdoutTTY,
 						IsStdErrTTY: stderrTTY,
 					},
-				})
+				}
+				if conf.ExecutionSegment != nil {
+					summary.Instance = lib.NewInstanceExecutionReport(executionState, engine.Metrics)
+				}
+				summaryResult, err := initRunner.HandleSummary(globalCtx, summary)
 				if err == nil {
 					err = handleSummaryResult(afero.NewOsFs(), stdout, stderr, summaryResult)
 				}
@@ -320,6 +431,12 @@ a commandline interface for interacting with it.`,
 			logger.Debug("Waiting for engine processes to finish...")
 			engineWait()
 			logger.Debug("Everything has finished, exiting k6!")
+			if abortErr != nil {
+				return abortErr
+			}
+			if dryRunErr != nil {
+				return dryRunErr
+			}
 			if engine.IsTainted() {
 				return errext.WithExitCodeIfNone(errors.New("some thresholds have failed"), exitcodes.ThresholdsHaveFailed)
 			}
@@ -380,6 +497,20 @@ func runCmdFlagSet() *pflag.FlagSet {
 	// - and finally, global variables are not very testable... :/
 	flags.StringVarP(&runType, "type", "t", runType, "override file `type`, \"js\" or \"archive\"")
 	flags.Lookup("type").DefValue = ""
+	flags.BoolVar(&dryRun, "dry-run", false, "run a single iteration of a single VU for each scenario, "+
+		"regardless of the configured load, and print a JSON validation report instead of the summary")
+	flags.BoolVar(&watch, "watch", false, "run the script like with --dry-run, and again whenever it "+
+		"or its local modules and files change")
+	flags.StringVar(&runManifest, "run-manifest", os.Getenv("K6_RUN_MANIFEST"), "write the manifest of the "+
+		"test run, with its ID, its script hash, its options and its exit code, to this JSON `file`")
+	flags.Lookup("run-manifest").DefValue = ""
+	flags.StringArrayVar(&webhooks, "webhook", envWebhookValues("K6_WEBHOOKS"), "POST the JSON events of the "+
+		"test run, like its start, the failing thresholds and its end with the summary, to this `url`")
+	flags.Lookup("webhook").DefValue = "[]"
+	flags.StringSliceVar(&webhookEvents, "webhook-events", envWebhookValues("K6_WEBHOOK_EVENTS"), "the types "+
+		"of the events that the webhooks get, from test.start, threshold.crossed, scenario.end, test.abort "+
+		"and test.end, all of them by default")
+	flags.Lookup("webhook-events").DefValue = "[]"
 	return flags
 }
 
@@ -391,6 +522,9 @@ func newRunner(
 	case "":
 		runner, err = newRunner(logger, src, detectType(src.Data), filesystems, rtOpts)
 	case typeJS:
+		if err = loadLockfile(logger, src, filesystems); err != nil {
+			return nil, err
+		}
 		runner, err = js.New(logger, src, filesystems, rtOpts)
 	case typeArchive:
 		var arc *lib.Archive
@@ -398,6 +532,9 @@ func newRunner(
 		if err != nil {
 			return nil, err
 		}
+		if err = decryptArchive(arc); err != nil {
+			return nil, err
+		}
 		switch arc.Type {
 		case typeJS:
 			runner, err = js.NewFromArchive(logger, arc, rtOpts)
@@ -0,0 +1,84 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"go.k6.io/k6/errext"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/output"
+)
+
+// getRunExitCode returns the exit code k6 exits with because of the error of the run.
+func getRunExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var ecerr errext.HasExitCode
+	if errors.As(err, &ecerr) {
+		return int(ecerr.ExitCode())
+	}
+	return -1
+}
+
+// startRunMetadata marks the start of the test run in its metadata and passes it to the outputs
+// that emit it then.
+func startRunMetadata(md *lib.TestRunMetadata, outputs []output.Output) {
+	md.MarkStarted()
+	for _, out := range outputs {
+		if startOut, ok := out.(output.WithRunStart); ok {
+			startOut.SetRunStart(md)
+		}
+	}
+}
+
+// finishRunManifest passes the manifest of the finished test run to the outputs that support
+// it, and writes it to the file, if there is one.
+func finishRunManifest(
+	filename string, md *lib.TestRunMetadata, opts lib.Options, outputs []output.Output, runErr error,
+	logger logrus.FieldLogger,
+) {
+	manifest := lib.NewTestRunManifest(md, opts, getRunExitCode(runErr), runErr)
+	for _, out := range outputs {
+		if manifestOut, ok := out.(output.WithRunManifest); ok {
+			manifestOut.SetRunManifest(manifest)
+		}
+	}
+	if filename == "" {
+		return
+	}
+	if err := writeRunManifest(defaultFs, filename, manifest); err != nil {
+		logger.WithError(err).Error("Couldn't write the test run manifest")
+	}
+}
+
+func writeRunManifest(afs afero.Fs, filename string, manifest *lib.TestRunManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return afero.WriteFile(afs, filename, append(data, '\n'), 0o644)
+}
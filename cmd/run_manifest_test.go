@@ -0,0 +1,61 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/errext"
+	"go.k6.io/k6/errext/exitcodes"
+	"go.k6.io/k6/lib"
+)
+
+func TestGetRunExitCode(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, 0, getRunExitCode(nil))
+	assert.Equal(t, -1, getRunExitCode(errors.New("some error")))
+	thresholdsErr := errext.WithExitCodeIfNone(errors.New("some thresholds have failed"), exitcodes.ThresholdsHaveFailed)
+	assert.Equal(t, int(exitcodes.ThresholdsHaveFailed), getRunExitCode(thresholdsErr))
+}
+
+func TestWriteRunManifest(t *testing.T) {
+	t.Parallel()
+	afs := afero.NewMemMapFs()
+	md := &lib.TestRunMetadata{RunID: "some-id", ScriptHash: "abc"}
+	manifest := lib.NewTestRunManifest(md, lib.Options{}, 99, errors.New("some thresholds have failed"))
+	require.NoError(t, writeRunManifest(afs, "manifest.json", manifest))
+
+	data, err := afero.ReadFile(afs, "manifest.json")
+	require.NoError(t, err)
+	var fromFile map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &fromFile))
+	assert.Equal(t, "some-id", fromFile["run_id"])
+	assert.Equal(t, "abc", fromFile["script_hash"])
+	assert.Equal(t, float64(99), fromFile["exit_code"])
+	assert.Equal(t, "some thresholds have failed", fromFile["error"])
+	assert.Contains(t, fromFile, "options")
+}
@@ -30,6 +30,7 @@ import (
 	"gopkg.in/guregu/null.v3"
 
 	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/secrets"
 )
 
 // TODO: move this whole file out of the cmd package? maybe when fixing
@@ -132,6 +133,18 @@ func getRuntimeOptions(flags *pflag.FlagSet, environment map[string]string) (lib
 
 	if opts.IncludeSystemEnvVars.Bool { // If enabled, gather the actual system environment variables
 		opts.Env = environment
+		for key := range environment {
+			// the secrets are only available with k6/secrets, so they aren't in the archives
+			if strings.HasPrefix(key, secrets.EnvPrefix) {
+				opts.Env = make(map[string]string, len(environment))
+				for key, value := range environment {
+					if !strings.HasPrefix(key, secrets.EnvPrefix) {
+						opts.Env[key] = value
+					}
+				}
+				break
+			}
+		}
 	}
 
 	// Set/overwrite environment variables with custom user-supplied values
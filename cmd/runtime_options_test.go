@@ -157,6 +157,16 @@ var runtimeOptionsTestCases = map[string]runtimeOptionsTestCase{ //nolint:gochec
 			Env:                  map[string]string{"test1": "val1"},
 		},
 	},
+	"system env without secrets": {
+		useSysEnv: true,
+		systemEnv: map[string]string{"test1": "val1", "K6_SECRET_TOKEN": "s3cr3t"},
+		cliFlags:  []string{},
+		expRTOpts: lib.RuntimeOptions{
+			IncludeSystemEnvVars: null.NewBool(true, false),
+			CompatibilityMode:    defaultCompatMode,
+			Env:                  map[string]string{"test1": "val1"},
+		},
+	},
 	"mixed system and cli env": {
 		useSysEnv: true,
 		systemEnv: map[string]string{"test1": "val1", "test2": ""},
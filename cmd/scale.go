@@ -27,7 +27,6 @@ import (
 	"github.com/spf13/cobra"
 
 	v1 "go.k6.io/k6/api/v1"
-	"go.k6.io/k6/api/v1/client"
 )
 
 func getScaleCmd(ctx context.Context) *cobra.Command {
@@ -37,7 +36,8 @@ func getScaleCmd(ctx context.Context) *cobra.Command {
 		Short: "Scale a running test",
 		Long: `Scale a running test.
 
-  Use the global --address flag to specify the URL to the API server.`,
+  Use the global --address flag to specify the URL to the API server, with the https:// prefix
+  if it serves HTTPS, and the K6_API_TOKEN environment variable for its token.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			vus := getNullInt64(cmd.Flags(), "vus")
 			max := getNullInt64(cmd.Flags(), "max")
@@ -45,7 +45,7 @@ func getScaleCmd(ctx context.Context) *cobra.Command {
 				return errors.New("Specify either -u/--vus or -m/--max") //nolint:golint
 			}
 
-			c, err := client.New(address)
+			c, err := newAPIClient()
 			if err != nil {
 				return err
 			}
@@ -24,8 +24,6 @@ import (
 	"context"
 
 	"github.com/spf13/cobra"
-
-	"go.k6.io/k6/api/v1/client"
 )
 
 func getStatusCmd(ctx context.Context) *cobra.Command {
@@ -35,9 +33,10 @@ func getStatusCmd(ctx context.Context) *cobra.Command {
 		Short: "Show test status",
 		Long: `Show test status.
 
-  Use the global --address flag to specify the URL to the API server.`,
+  Use the global --address flag to specify the URL to the API server, with the https:// prefix
+  if it serves HTTPS, and the K6_API_TOKEN environment variable for its token.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			c, err := client.New(address)
+			c, err := newAPIClient()
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,41 @@
+import http from 'k6/http';
+import { check, group, sleep } from 'k6';
+
+export { handleSummary } from './summary.js';
+
+const env = JSON.parse(open(`./env/${__ENV.ENVIRONMENT || 'local'}.json`));
+
+const params = {
+    headers: { 'Content-Type': 'application/json' },
+};
+
+// setup runs once before the test, the data it returns is passed to each iteration.
+export function setup() {
+    const username = `user_${Date.now()}@example.com`;
+    const credentials = { username: username, password: 'superCroc2019' };
+    http.post(`${env.baseUrl}/user/register/`, JSON.stringify(
+        Object.assign({ first_name: 'k6', last_name: 'test', email: username }, credentials)), params);
+    const res = http.post(`${env.baseUrl}/auth/token/login/`, JSON.stringify(credentials), params);
+    check(res, { 'logged in': (r) => r.status === 200 && r.json('access') !== undefined });
+    return { token: res.json('access') };
+}
+
+export default function (data) {
+    const authParams = {
+        headers: Object.assign({ Authorization: `Bearer ${data.token}` }, params.headers),
+    };
+
+    group('crocodiles', function () {
+        const created = http.post(`${env.baseUrl}/my/crocodiles/`, JSON.stringify({
+            name: `croc ${__VU}-${__ITER}`, sex: 'M', date_of_birth: '2001-01-01',
+        }), authParams);
+        check(created, { 'created': (r) => r.status === 201 });
+
+        const id = created.json('id');
+        const res = http.get(`${env.baseUrl}/my/crocodiles/${id}/`, authParams);
+        check(res, { 'read': (r) => r.status === 200 && r.json('id') === id });
+
+        http.del(`${env.baseUrl}/my/crocodiles/${id}/`, null, authParams);
+    });
+    sleep(1);
+}
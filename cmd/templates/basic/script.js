@@ -0,0 +1,16 @@
+import http from 'k6/http';
+import { check, group, sleep } from 'k6';
+
+export { handleSummary } from './summary.js';
+
+const env = JSON.parse(open(`./env/${__ENV.ENVIRONMENT || 'local'}.json`));
+
+export default function () {
+    group('home page', function () {
+        const res = http.get(`${env.baseUrl}/`);
+        check(res, {
+            'status is 200': (r) => r.status === 200,
+        });
+    });
+    sleep(1);
+}
@@ -0,0 +1,32 @@
+// handleSummary is called by k6 at the end of the test, the files it returns are the reports of
+// the test run, besides the summary in the terminal.
+export function handleSummary(data) {
+    const report = markdownReport(data);
+    return {
+        'stdout': report,
+        'reports/summary.json': JSON.stringify(data, null, 2),
+        'reports/summary.md': report,
+    };
+}
+
+function markdownReport(data) {
+    const lines = ['# Test report', '', '| Metric | Avg | P(95) | Max | Thresholds |', '|---|---|---|---|---|'];
+    for (const [name, metric] of Object.entries(data.metrics)) {
+        const values = metric.values;
+        const thresholds = Object.entries(metric.thresholds || {})
+            .map(([source, result]) => `${result.ok ? '✓' : '✗'} ${source}`)
+            .join(', ');
+        if (metric.type === 'trend') {
+            lines.push(`| ${name} | ${format(values.avg)} | ${format(values['p(95)'])} | ${format(values.max)} | ${thresholds} |`);
+        } else if (metric.type === 'rate') {
+            lines.push(`| ${name} | ${(values.rate * 100).toFixed(2)}% | | | ${thresholds} |`);
+        } else {
+            lines.push(`| ${name} | ${format(values.value !== undefined ? values.value : values.count)} | | | ${thresholds} |`);
+        }
+    }
+    return lines.join('\n') + '\n';
+}
+
+function format(value) {
+    return value === undefined ? '' : value.toFixed(2);
+}
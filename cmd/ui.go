@@ -331,7 +331,6 @@ func showProgress(
 		}
 	}
 
-	//TODO: make configurable?
 	updateFreq := 1 * time.Second
 	if stdoutTTY {
 		updateFreq = 100 * time.Millisecond
@@ -347,6 +346,10 @@ func showProgress(
 		}()
 	}
 
+	if profileFreq := getPerformanceProfile(conf).progressUpdateFreq; profileFreq > 0 {
+		updateFreq = profileFreq
+	}
+
 	var (
 		fd     = int(os.Stdout.Fd())
 		ticker = time.NewTicker(updateFreq)
@@ -0,0 +1,136 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"go.k6.io/k6/js"
+	"go.k6.io/k6/loader"
+)
+
+var errVendorNotLocal = errors.New("only the remote modules of local scripts can be vendored")
+
+func getVendorCmd(logger *logrus.Logger) *cobra.Command {
+	var lockOnly bool
+
+	vendorCmd := &cobra.Command{
+		Use:   "vendor [file]",
+		Short: "Lock and vendor the remote modules of a script",
+		Long: `Lock and vendor the remote modules of a script.
+
+The remote modules that the script imports, like the ones of jslib.k6.io, are downloaded
+and their integrity hashes are written in the k6.lock file in the directory of the script.
+The modules are also copied in its vendor directory, unless --lock-only is used.
+
+When the k6.lock file exists, the runs of the script load the vendored modules instead of
+downloading them, so they work offline, and they fail if a downloaded module doesn't have
+the hash of the lockfile. Run k6 vendor again after changing the imports.`,
+		Example: `
+  # Lock and vendor the remote modules of the script.
+  k6 vendor script.js
+
+  # Only lock the remote modules, they are still downloaded in each run.
+  k6 vendor --lock-only script.js`[1:],
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			filesystems := loader.CreateFilesystems()
+			src, err := loader.ReadSource(logger, args[0], pwd, filesystems, os.Stdin)
+			if err != nil {
+				return err
+			}
+			dir := scriptDir(src)
+			if dir == "" {
+				return errVendorNotLocal
+			}
+			runtimeOptions, err := getRuntimeOptions(cmd.Flags(), buildEnvMap(os.Environ()))
+			if err != nil {
+				return err
+			}
+
+			// the modules are always downloaded, without the vendored ones of the current lockfile
+			if _, err = js.New(logger, src, filesystems, runtimeOptions); err != nil {
+				return err
+			}
+			lock, err := loader.NewLockfile(filesystems["https"])
+			if err != nil {
+				return err
+			}
+			if err := lock.Write(defaultFs, dir); err != nil {
+				return err
+			}
+			if !lockOnly {
+				if err := loader.Vendor(filesystems["https"], defaultFs, dir); err != nil {
+					return err
+				}
+			}
+			logger.Infof("Locked %d remote modules in %s", len(lock.Modules), filepath.Join(dir, loader.LockfileName))
+			return nil
+		},
+	}
+
+	flags := vendorCmd.Flags()
+	flags.SortFlags = false
+	flags.BoolVar(&lockOnly, "lock-only", false, "only write the lockfile, without vendoring the modules")
+	flags.AddFlagSet(runtimeOptionFlagSet(false))
+	return vendorCmd
+}
+
+// scriptDir returns the directory of the script, where its lockfile is, or an empty string if it
+// isn't a local file.
+func scriptDir(src *loader.SourceData) string {
+	if src.URL == nil || src.URL.Scheme != "file" || src.URL.Path == "/-" {
+		return ""
+	}
+	return filepath.Dir(filepath.FromSlash(src.URL.Path))
+}
+
+// loadLockfile loads the vendored modules of the lockfile of the script, if it has one, in the
+// https filesystem, and replaces it with the one that checks the downloaded modules against the
+// lockfile. The lockfile is read from the real filesystem, so it isn't in the archives.
+func loadLockfile(logger logrus.FieldLogger, src *loader.SourceData, filesystems map[string]afero.Fs) error {
+	dir := scriptDir(src)
+	if dir == "" {
+		return nil
+	}
+	lock, err := loader.ReadLockfile(defaultFs, dir)
+	if lock == nil || err != nil {
+		return err
+	}
+	vendored, err := lock.LoadVendored(defaultFs, dir, filesystems["https"])
+	if err != nil {
+		return err
+	}
+	logger.Debugf("Loaded %d of the %d remote modules of %s from the vendor directory",
+		vendored, len(lock.Modules), loader.LockfileName)
+	filesystems["https"] = lock.LockFs(filesystems["https"])
+	return nil
+}
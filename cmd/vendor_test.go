@@ -0,0 +1,73 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/loader"
+)
+
+func TestVendoredModules(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "k6-vendor-")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	script := filepath.Join(dir, "script.js")
+	vendored := filepath.Join(dir, "vendor", "lib.k6.invalid", "utils.js")
+	utils := []byte(`export const name = 'utils';`)
+	// the lockfile and the vendored modules are read with the defaultFs
+	require.NoError(t, defaultFs.MkdirAll(filepath.Dir(vendored), 0o755))
+	require.NoError(t, ioutil.WriteFile(script, []byte(`
+		import { name } from 'https://lib.k6.invalid/utils.js';
+		export default function() {}
+	`), 0o644))
+	require.NoError(t, afero.WriteFile(defaultFs, vendored, utils, 0o644))
+	lock := &loader.Lockfile{Version: 1, Modules: map[string]loader.LockedModule{
+		"https://lib.k6.invalid/utils.js": {Integrity: loader.Integrity(utils)},
+	}}
+	require.NoError(t, lock.Write(defaultFs, dir))
+
+	newScriptRunner := func() (lib.Runner, error) {
+		filesystems := loader.CreateFilesystems()
+		src, err := loader.ReadSource(testutils.NewLogger(t), script, dir, filesystems, nil)
+		require.NoError(t, err)
+		return newRunner(testutils.NewLogger(t), src, typeJS, filesystems, lib.RuntimeOptions{})
+	}
+	// the vendored module is loaded without downloading it
+	_, err = newScriptRunner()
+	require.NoError(t, err)
+
+	require.NoError(t, afero.WriteFile(defaultFs, vendored, []byte(`export const name = 'changed';`), 0o644))
+	_, err = newScriptRunner()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the vendored module https://lib.k6.invalid/utils.js has the integrity")
+}
@@ -0,0 +1,221 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/fsext"
+	"go.k6.io/k6/loader"
+)
+
+const (
+	// watchPollInterval is how often the watched files are checked for changes.
+	watchPollInterval = 500 * time.Millisecond
+	// watchDebounce is how long the files have to be unchanged before the run starts, so that
+	// the run doesn't start in the middle of the saving of several files.
+	watchDebounce = 300 * time.Millisecond
+)
+
+// watchedFiles are the modification times of the local files of a script.
+type watchedFiles map[string]time.Time
+
+// getWatchedFiles returns the local files that the script loads, the script itself and the
+// modules it imports and the files it opens, with their current modification times.
+func getWatchedFiles(logger *logrus.Logger, filename string, rtOpts lib.RuntimeOptions) watchedFiles {
+	files := make(watchedFiles)
+	if abs, err := filepath.Abs(filename); err == nil {
+		files.add(abs)
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return files
+	}
+	filesystems := loader.CreateFilesystems()
+	src, err := loader.ReadSource(logger, filename, pwd, filesystems, os.Stdin)
+	if err != nil {
+		return files
+	}
+	// the runner loads all the modules and the files of the init context, so they are in the cache
+	if _, err = newRunner(logger, src, runType, filesystems, rtOpts); err != nil {
+		logger.WithError(err).Debug("Couldn't load the script for finding the files to watch")
+	}
+	cache, ok := filesystems["file"].(fsext.CacheOnReadFs)
+	if !ok {
+		return files
+	}
+	_ = afero.Walk(cache.GetCachingFs(), "/", func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files.add(filepath.FromSlash(path))
+		}
+		return nil
+	})
+	return files
+}
+
+func (w watchedFiles) add(path string) {
+	if info, err := os.Stat(path); err == nil {
+		w[path] = info.ModTime()
+	} else {
+		w[path] = time.Time{}
+	}
+}
+
+// changed returns the files that were modified, created or removed since their times were taken.
+func (w watchedFiles) changed() []string {
+	var changed []string
+	for path, modTime := range w {
+		info, err := os.Stat(path)
+		switch {
+		case err != nil && !modTime.IsZero(), err == nil && !info.ModTime().Equal(modTime):
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// watchRunArgs returns the arguments of the k6 run of each change, the same arguments without
+// --watch and with --dry-run, so each run is a short validation of the script.
+func watchRunArgs(args []string) []string {
+	result := make([]string, 0, len(args)+1)
+	hasDryRun := false
+	for i, arg := range args {
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+		if arg == "--watch" || strings.HasPrefix(arg, "--watch=") {
+			continue
+		}
+		if arg == "--dry-run" || strings.HasPrefix(arg, "--dry-run=") {
+			hasDryRun = true
+		}
+		result = append(result, arg)
+	}
+	if !hasDryRun {
+		// the flag has to be after the run command
+		for i, arg := range result {
+			if arg == "run" {
+				result = append(result[:i+1], append([]string{"--dry-run"}, result[i+1:]...)...)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// watchScript runs the validation of the script, and runs it again on each change of its files,
+// until it's stopped with Ctrl+C.
+func watchScript(ctx context.Context, logger *logrus.Logger, filename string, rtOpts lib.RuntimeOptions) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := watchRunArgs(os.Args[1:])
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigC)
+	go func() {
+		select {
+		case <-sigC:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		files := getWatchedFiles(logger, filename, rtOpts)
+		run := exec.CommandContext(ctx, executable, args...) //nolint:gosec
+		run.Stdin, run.Stdout, run.Stderr = os.Stdin, os.Stdout, os.Stderr
+		err := run.Run()
+		var exitErr *exec.ExitError
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case errors.As(err, &exitErr):
+			logger.Warnf("The validation failed with the exit code %d, waiting for changes of the %d watched files...",
+				exitErr.ExitCode(), len(files))
+		case err != nil:
+			return err
+		default:
+			logger.Infof("The validation passed, waiting for changes of the %d watched files...", len(files))
+		}
+
+		if !waitForChanges(ctx, logger, files) {
+			return nil
+		}
+	}
+}
+
+// waitForChanges waits until the files change and then stop changing, it returns false if the
+// context is done before.
+func waitForChanges(ctx context.Context, logger logrus.FieldLogger, files watchedFiles) bool {
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+		changed := files.changed()
+		if len(changed) == 0 {
+			continue
+		}
+		all := make(map[string]bool)
+		for len(changed) > 0 {
+			for _, path := range changed {
+				all[path] = true
+				files.add(path)
+			}
+			select {
+			case <-ctx.Done():
+				return false
+			case <-time.After(watchDebounce):
+			}
+			changed = files.changed()
+		}
+		for path := range all {
+			changed = append(changed, path)
+		}
+		sort.Strings(changed)
+		logger.Infof("Changed %s, running the validation again...", strings.Join(changed, ", "))
+		return true
+	}
+}
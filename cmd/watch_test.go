@@ -0,0 +1,94 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/testutils"
+)
+
+func TestWatchRunArgs(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		args, expected []string
+	}{
+		{[]string{"run", "--watch", "script.js"}, []string{"run", "--dry-run", "script.js"}},
+		{[]string{"--verbose", "run", "--watch=true", "-e", "A=1", "script.js"}, []string{"--verbose", "run", "--dry-run", "-e", "A=1", "script.js"}},
+		{[]string{"run", "--dry-run", "--watch", "script.js"}, []string{"run", "--dry-run", "script.js"}},
+		{[]string{"run", "--watch", "--", "--watch"}, []string{"run", "--dry-run", "--", "--watch"}},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, watchRunArgs(tc.args), tc.args)
+	}
+}
+
+func TestWatchedFiles(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "k6-watch-")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	dir, err = filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+
+	script := filepath.Join(dir, "script.js")
+	module := filepath.Join(dir, "lib", "module.js")
+	data := filepath.Join(dir, "data.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(module), 0o755))
+	require.NoError(t, ioutil.WriteFile(script, []byte(`
+		import { name } from './lib/module.js';
+		const data = JSON.parse(open('./data.json'));
+		export default function() {}
+	`), 0o644))
+	require.NoError(t, ioutil.WriteFile(module, []byte(`export const name = 'module';`), 0o644))
+	require.NoError(t, ioutil.WriteFile(data, []byte(`{}`), 0o644))
+
+	files := getWatchedFiles(testutils.NewLogger(t), script, lib.RuntimeOptions{})
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	assert.Equal(t, []string{data, module, script}, paths)
+	assert.Empty(t, files.changed())
+
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(module, later, later))
+	assert.Equal(t, []string{module}, files.changed())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.True(t, waitForChanges(ctx, testutils.NewLogger(t), files))
+	assert.Empty(t, files.changed())
+
+	require.NoError(t, os.Remove(data))
+	assert.Equal(t, []string{data}, files.changed())
+}
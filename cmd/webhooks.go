@@ -0,0 +1,136 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/core"
+	"go.k6.io/k6/lib/events"
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+// webhooksCloseTimeout is how long k6 waits for the webhooks to send their queued events.
+const webhooksCloseTimeout = 15 * time.Second
+
+// envWebhookValues returns the comma-separated values of the environment variable.
+func envWebhookValues(key string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+// newEventBus returns the bus of the events of the test run with the webhooks of the URLs and
+// the outputs that support it subscribed to it, and the function that closes the webhooks. The
+// bus is nil if there are no URLs and no such outputs.
+func newEventBus(
+	runID string, urls, types []string, outputs []output.Output, logger logrus.FieldLogger,
+) (*events.Bus, func(), error) {
+	var eventOutputs []output.WithEvents
+	for _, out := range outputs {
+		if eventOut, ok := out.(output.WithEvents); ok {
+			eventOutputs = append(eventOutputs, eventOut)
+		}
+	}
+	if len(urls) == 0 && len(eventOutputs) == 0 {
+		return nil, func() {}, nil
+	}
+
+	eventTypes := make([]events.Type, 0, len(types))
+	for _, t := range types {
+		eventTypes = append(eventTypes, events.Type(strings.TrimSpace(t)))
+	}
+	bus := events.NewBus(runID)
+	webhooks := make([]*events.Webhook, 0, len(urls))
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, nil, fmt.Errorf("the webhook '%s' has to be an http or https URL", u)
+		}
+		w, err := events.NewWebhook(u, eventTypes, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		bus.Subscribe(w.Handle)
+		webhooks = append(webhooks, w)
+	}
+	for _, eventOut := range eventOutputs {
+		eventOut.SetEvents(bus)
+	}
+
+	closeFn := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), webhooksCloseTimeout)
+		defer cancel()
+		for _, w := range webhooks {
+			if err := w.Close(ctx); err != nil {
+				logger.WithError(err).Warn("Couldn't close the webhook")
+			}
+		}
+	}
+	return bus, closeFn, nil
+}
+
+// getTestEndEventData returns the data of the event of the end of the test run, the summary of
+// its metrics and whether their thresholds passed.
+func getTestEndEventData(engine *core.Engine, duration time.Duration) events.TestEndData {
+	engine.MetricsLock.Lock()
+	defer engine.MetricsLock.Unlock()
+
+	metrics := make(map[string]events.MetricSummary, len(engine.Metrics))
+	for name, m := range engine.Metrics {
+		// only the submetrics with thresholds are included, like the automatic ones aren't
+		if m.Sub.Parent != "" && len(m.Thresholds.Thresholds) == 0 {
+			continue
+		}
+		metric := events.MetricSummary{
+			Type:     m.Type.String(),
+			Contains: m.Contains.String(),
+			Values:   m.Sink.Format(duration),
+		}
+		if rate, ok := m.Sink.(*stats.RateSink); ok {
+			metric.Values["passes"] = float64(rate.Trues)
+			metric.Values["fails"] = float64(rate.Total - rate.Trues)
+		}
+		if len(m.Thresholds.Thresholds) > 0 {
+			metric.Thresholds = make(map[string]bool, len(m.Thresholds.Thresholds))
+			for _, th := range m.Thresholds.Thresholds {
+				metric.Thresholds[th.Source] = !th.LastFailed
+			}
+		}
+		metrics[name] = metric
+	}
+	return events.TestEndData{
+		Duration:         types.Duration(duration),
+		ThresholdsFailed: engine.IsTainted(),
+		Metrics:          metrics,
+	}
+}
@@ -26,38 +26,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/url"
 	"sort"
 	"strings"
 
 	"github.com/tidwall/pretty"
 
+	"go.k6.io/k6/converter/internal/jsgen"
 	"go.k6.io/k6/lib"
 )
 
-// fprint panics when where's an error writing to the supplied io.Writer
-// since this will be used on in-memory expandable buffers, that should
-// happen only when we run out of memory...
-func fprint(w io.Writer, a ...interface{}) int {
-	n, err := fmt.Fprint(w, a...)
-	if err != nil {
-		panic(err.Error())
-	}
-	return n
-}
-
-// fprintf panics when where's an error writing to the supplied io.Writer
-// since this will be used on in-memory expandable buffers, that should
-// happen only when we run out of memory...
-func fprintf(w io.Writer, format string, a ...interface{}) int {
-	n, err := fmt.Fprintf(w, format, a...)
-	if err != nil {
-		panic(err.Error())
-	}
-	return n
-}
-
 // TODO: refactor this to have fewer parameters... or just refactor in general...
 func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks bool, returnOnFailedCheck bool, batchTime uint, nobatch bool, correlate bool, only, skip []string) (result string, convertErr error) {
 	var b bytes.Buffer
@@ -76,35 +54,35 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 	}
 
 	if enableChecks {
-		fprint(w, "import { group, check, sleep } from 'k6';\n")
+		jsgen.Fprint(w, "import { group, check, sleep } from 'k6';\n")
 	} else {
-		fprint(w, "import { group, sleep } from 'k6';\n")
+		jsgen.Fprint(w, "import { group, sleep } from 'k6';\n")
 	}
-	fprint(w, "import http from 'k6/http';\n\n")
+	jsgen.Fprint(w, "import http from 'k6/http';\n\n")
 
-	fprintf(w, "// Version: %v\n", h.Log.Version)
-	fprintf(w, "// Creator: %v\n", h.Log.Creator.Name)
+	jsgen.Fprintf(w, "// Version: %v\n", h.Log.Version)
+	jsgen.Fprintf(w, "// Creator: %v\n", h.Log.Creator.Name)
 	if h.Log.Browser != nil {
-		fprintf(w, "// Browser: %v\n", h.Log.Browser.Name)
+		jsgen.Fprintf(w, "// Browser: %v\n", h.Log.Browser.Name)
 	}
 	if h.Log.Comment != "" {
-		fprintf(w, "// %v\n", h.Log.Comment)
+		jsgen.Fprintf(w, "// %v\n", h.Log.Comment)
 	}
 
-	fprint(w, "\nexport let options = {\n")
+	jsgen.Fprint(w, "\nexport let options = {\n")
 	options.ForEachSpecified("json", func(key string, val interface{}) {
 		if valJSON, err := json.MarshalIndent(val, "    ", "    "); err != nil {
 			convertErr = err
 		} else {
-			fprintf(w, "    %s: %s,\n", key, valJSON)
+			jsgen.Fprintf(w, "    %s: %s,\n", key, valJSON)
 		}
 	})
 	if convertErr != nil {
 		return "", convertErr
 	}
-	fprint(w, "};\n\n")
+	jsgen.Fprint(w, "};\n\n")
 
-	fprint(w, "export default function() {\n\n")
+	jsgen.Fprint(w, "export default function() {\n\n")
 
 	pages := h.Log.Pages
 	sort.Sort(PageByStarted(pages))
@@ -145,6 +123,21 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 		}
 	}
 
+	// the dynamic values are found in all the entries, in the order of their pages, since the
+	// responses of a page can have the values that the requests of the next ones use
+	var values *correlation
+	if correlate && nobatch {
+		var all []*Entry
+		for _, page := range pages {
+			sort.Sort(EntryByStarted(pageEntries[page.ID]))
+			all = append(all, pageEntries[page.ID]...)
+		}
+		values = findDynamicValues(all)
+		if len(values.names) > 0 {
+			jsgen.Fprintf(w, "\tlet %s;\n\n", strings.Join(values.names, ", "))
+		}
+	}
+
 	for i, page := range pages {
 
 		entries := pageEntries[page.ID]
@@ -155,7 +148,7 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 			// I can't just remove the group() call since all of the subsequent code indentation is hardcoded...
 			scriptGroupName = page.Title
 		}
-		fprintf(w, "\tgroup(%q, function() {\n", scriptGroupName)
+		jsgen.Fprintf(w, "\tgroup(%q, function() {\n", scriptGroupName)
 
 		sort.Sort(EntryByStarted(entries))
 
@@ -163,7 +156,7 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 			var recordedRedirectURL string
 			previousResponse := map[string]interface{}{}
 
-			fprint(w, "\t\tlet res, redirectUrl, json;\n")
+			jsgen.Fprint(w, "\t\tlet res, redirectUrl, json;\n")
 
 			for entryIndex, e := range entries {
 
@@ -171,24 +164,31 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 				var cookies []string
 				var body string
 
-				fprintf(w, "\t\t// Request #%d\n", entryIndex)
+				jsgen.Fprintf(w, "\t\t// Request #%d\n", entryIndex)
 
 				if e.Request.PostData != nil {
 					body = e.Request.PostData.Text
 				}
 
 				for _, c := range e.Request.Cookies {
-					cookies = append(cookies, fmt.Sprintf(`%q: %q`, c.Name, c.Value))
+					if correlate && values.jarCookies[e][c.Name] {
+						// the cookie jar sends the cookies that the previous responses set
+						continue
+					}
+					cookies = append(cookies, fmt.Sprintf(`%q: %s`, c.Name, values.literal(e, c.Value)))
 				}
 				if len(cookies) > 0 {
 					params = append(params, fmt.Sprintf("\"cookies\": {\n\t\t\t\t%s\n\t\t\t}", strings.Join(cookies, ",\n\t\t\t\t\t")))
 				}
 
-				if headers := buildK6Headers(e.Request.Headers); len(headers) > 0 {
+				headers := buildK6HeadersWith(e.Request.Headers, func(value string) string {
+					return values.literal(e, value)
+				})
+				if len(headers) > 0 {
 					params = append(params, fmt.Sprintf("\"headers\": {\n\t\t\t\t\t%s\n\t\t\t\t}", strings.Join(headers, ",\n\t\t\t\t\t")))
 				}
 
-				fprintf(w, "\t\tres = http.%s(", strings.ToLower(e.Request.Method))
+				jsgen.Fprintf(w, "\t\tres = http.%s(", strings.ToLower(e.Request.Method))
 
 				if correlate && recordedRedirectURL != "" {
 					if recordedRedirectURL != e.Request.URL {
@@ -197,10 +197,10 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 								"Possibly a misbehaving client or concurrent requests?",
 						)
 					}
-					fprintf(w, "redirectUrl")
+					jsgen.Fprintf(w, "redirectUrl")
 					recordedRedirectURL = ""
 				} else {
-					fprintf(w, "%q", e.Request.URL)
+					jsgen.Fprint(w, values.literal(e, e.Request.URL))
 				}
 
 				if e.Request.Method != "GET" {
@@ -219,30 +219,30 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 						requestText, err := json.Marshal(requestMap)
 						if err == nil {
 							prettyJSONString := string(pretty.PrettyOptions(requestText, &pretty.Options{Width: 999999, Prefix: "\t\t\t", Indent: "\t", SortKeys: true})[:])
-							fprintf(w, ",\n\t\t\t`%s`", strings.TrimSpace(prettyJSONString))
+							jsgen.Fprintf(w, ",\n\t\t\t`%s`", values.substitute(e, strings.TrimSpace(prettyJSONString)))
 						} else {
 							return "", err
 						}
 
 					} else {
-						fprintf(w, ",\n\t\t%q", body)
+						jsgen.Fprintf(w, ",\n\t\t%s", values.literal(e, body))
 					}
 				}
 
 				if len(params) > 0 {
-					fprintf(w, ",\n\t\t\t{\n\t\t\t\t%s\n\t\t\t}", strings.Join(params, ",\n\t\t\t"))
+					jsgen.Fprintf(w, ",\n\t\t\t{\n\t\t\t\t%s\n\t\t\t}", strings.Join(params, ",\n\t\t\t"))
 				}
 
-				fprintf(w, "\n\t\t)\n")
+				jsgen.Fprintf(w, "\n\t\t)\n")
 
 				if e.Response != nil {
 					// the response is nil if there is a failed request in the recording, or if responses were not recorded
 					if enableChecks {
 						if e.Response.Status > 0 {
 							if returnOnFailedCheck {
-								fprintf(w, "\t\tif (!check(res, {\"status is %v\": (r) => r.status === %v })) { return };\n", e.Response.Status, e.Response.Status)
+								jsgen.Fprintf(w, "\t\tif (!check(res, {\"status is %v\": (r) => r.status === %v })) { return };\n", e.Response.Status, e.Response.Status)
 							} else {
-								fprintf(w, "\t\tcheck(res, {\"status is %v\": (r) => r.status === %v });\n", e.Response.Status, e.Response.Status)
+								jsgen.Fprintf(w, "\t\tcheck(res, {\"status is %v\": (r) => r.status === %v });\n", e.Response.Status, e.Response.Status)
 							}
 						}
 					}
@@ -250,52 +250,55 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 					if e.Response.Headers != nil {
 						for _, header := range e.Response.Headers {
 							if header.Name == "Location" {
-								fprintf(w, "\t\tredirectUrl = res.headers.Location;\n")
+								jsgen.Fprintf(w, "\t\tredirectUrl = res.headers.Location;\n")
 								recordedRedirectURL = header.Value
 								break
 							}
 						}
 					}
 
-					responseMimeType := e.Response.Content.MimeType
-					if correlate &&
-						strings.Index(responseMimeType, "application/") == 0 &&
-						strings.Index(responseMimeType, "json") == len(responseMimeType)-4 {
+					if correlate {
+						for _, v := range values.extracted[e] {
+							jsgen.Fprintf(w, "\t\t%s = %s;\n", v.name, v.extraction)
+						}
+					}
+
+					if correlate && isJSONResponse(e.Response) {
 						if err := json.Unmarshal([]byte(e.Response.Content.Text), &previousResponse); err != nil {
 							return "", err
 						}
-						fprint(w, "\t\tjson = JSON.parse(res.body);\n")
+						jsgen.Fprint(w, "\t\tjson = JSON.parse(res.body);\n")
 					}
 				}
 			}
 		} else {
 			batches := SplitEntriesInBatches(entries, batchTime)
 
-			fprint(w, "\t\tlet req, res;\n")
+			jsgen.Fprint(w, "\t\tlet req, res;\n")
 
 			for j, batchEntries := range batches {
 
-				fprint(w, "\t\treq = [")
+				jsgen.Fprint(w, "\t\treq = [")
 				for k, e := range batchEntries {
 					r, err := buildK6RequestObject(e.Request)
 					if err != nil {
 						return "", err
 					}
-					fprintf(w, "%v", r)
+					jsgen.Fprintf(w, "%v", r)
 					if k != len(batchEntries)-1 {
-						fprint(w, ",")
+						jsgen.Fprint(w, ",")
 					}
 				}
-				fprint(w, "];\n")
-				fprint(w, "\t\tres = http.batch(req);\n")
+				jsgen.Fprint(w, "];\n")
+				jsgen.Fprint(w, "\t\tres = http.batch(req);\n")
 
 				if enableChecks {
 					for k, e := range batchEntries {
 						if e.Response.Status > 0 {
 							if returnOnFailedCheck {
-								fprintf(w, "\t\tif (!check(res, {\"status is %v\": (r) => r.status === %v })) { return };\n", e.Response.Status, e.Response.Status)
+								jsgen.Fprintf(w, "\t\tif (!check(res, {\"status is %v\": (r) => r.status === %v })) { return };\n", e.Response.Status, e.Response.Status)
 							} else {
-								fprintf(w, "\t\tcheck(res[%v], {\"status is %v\": (r) => r.status === %v });\n", k, e.Response.Status, e.Response.Status)
+								jsgen.Fprintf(w, "\t\tcheck(res[%v], {\"status is %v\": (r) => r.status === %v });\n", k, e.Response.Status, e.Response.Status)
 							}
 						}
 					}
@@ -305,14 +308,14 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 					lastBatchEntry := batchEntries[len(batchEntries)-1]
 					firstBatchEntry := batches[j+1][0]
 					t := firstBatchEntry.StartedDateTime.Sub(lastBatchEntry.StartedDateTime).Seconds()
-					fprintf(w, "\t\tsleep(%.2f);\n", t)
+					jsgen.Fprintf(w, "\t\tsleep(%.2f);\n", t)
 				}
 			}
 
 			if i == len(pages)-1 {
 				// Last page; add random sleep time at the group completion
-				fprintf(w, "\t\t// Random sleep between %ds and %ds\n", minSleep, maxSleep)
-				fprintf(w, "\t\tsleep(Math.floor(Math.random()*%d+%d));\n", maxSleep-minSleep, minSleep)
+				jsgen.Fprintf(w, "\t\t// Random sleep between %ds and %ds\n", minSleep, maxSleep)
+				jsgen.Fprintf(w, "\t\tsleep(Math.floor(Math.random()*%d+%d));\n", maxSleep-minSleep, minSleep)
 			} else {
 				// Add sleep time at the end of the group
 				nextPage := pages[i+1]
@@ -324,14 +327,14 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 						sleepTime = t
 					}
 				}
-				fprintf(w, "\t\tsleep(%.2f);\n", sleepTime)
+				jsgen.Fprintf(w, "\t\tsleep(%.2f);\n", sleepTime)
 			}
 		}
 
-		fprint(w, "\t});\n")
+		jsgen.Fprint(w, "\t});\n")
 	}
 
-	fprint(w, "\n}\n")
+	jsgen.Fprint(w, "\n}\n")
 	if err := w.Flush(); err != nil {
 		return "", err
 	}
@@ -342,22 +345,22 @@ func buildK6RequestObject(req *Request) (string, error) {
 	var b bytes.Buffer
 	w := bufio.NewWriter(&b)
 
-	fprint(w, "{\n")
+	jsgen.Fprint(w, "{\n")
 
 	method := strings.ToLower(req.Method)
 	if method == "delete" {
 		method = "del"
 	}
-	fprintf(w, `"method": %q, "url": %q`, method, req.URL)
+	jsgen.Fprintf(w, `"method": %q, "url": %q`, method, req.URL)
 
 	if req.PostData != nil && method != "get" {
 		postParams, plainText, err := buildK6Body(req)
 		if err != nil {
 			return "", err
 		} else if len(postParams) > 0 {
-			fprintf(w, `, "body": { %s }`, strings.Join(postParams, ", "))
+			jsgen.Fprintf(w, `, "body": { %s }`, strings.Join(postParams, ", "))
 		} else if plainText != "" {
-			fprintf(w, `, "body": %q`, plainText)
+			jsgen.Fprintf(w, `, "body": %q`, plainText)
 		}
 	}
 
@@ -375,10 +378,10 @@ func buildK6RequestObject(req *Request) (string, error) {
 	}
 
 	if len(params) > 0 {
-		fprintf(w, `, "params": { %s }`, strings.Join(params, ", "))
+		jsgen.Fprintf(w, `, "params": { %s }`, strings.Join(params, ", "))
 	}
 
-	fprint(w, "}")
+	jsgen.Fprint(w, "}")
 	if err := w.Flush(); err != nil {
 		return "", err
 	}
@@ -392,6 +395,12 @@ func buildK6RequestObject(req *Request) (string, error) {
 }
 
 func buildK6Headers(headers []Header) []string {
+	return buildK6HeadersWith(headers, func(value string) string { return fmt.Sprintf("%q", value) })
+}
+
+// buildK6HeadersWith returns the headers with their values as the JS expressions that the
+// function returns for them.
+func buildK6HeadersWith(headers []Header, valueJS func(string) string) []string {
 	var h []string
 	if len(headers) > 0 {
 		ignored := map[string]bool{"cookie": true, "content-length": true}
@@ -401,7 +410,7 @@ func buildK6Headers(headers []Header) []string {
 			// Avoid SPDY's, duplicated or ignored headers
 			if !isIgnored && name[0] != ':' {
 				ignored[name] = true
-				h = append(h, fmt.Sprintf("%q: %q", header.Name, header.Value))
+				h = append(h, fmt.Sprintf("%q: %s", header.Name, valueJS(header.Value)))
 			}
 		}
 	}
@@ -427,6 +436,12 @@ func buildK6Body(req *Request) ([]string, string, error) {
 	return postParams, req.PostData.Text, nil
 }
 
+// isJSONResponse returns whether the response has a JSON body that the later requests can use.
+func isJSONResponse(resp *Response) bool {
+	mimeType := resp.Content.MimeType
+	return strings.Index(mimeType, "application/") == 0 && strings.Index(mimeType, "json") == len(mimeType)-4
+}
+
 func traverseMaps(request map[string]interface{}, response map[string]interface{}, path []interface{}) {
 	if response == nil {
 		// previous call reached a leaf in the response map so there's no point continuing
@@ -0,0 +1,407 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package har
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"go.k6.io/k6/converter/internal/jsgen"
+)
+
+// dynamicValue is a value that a response returns and that later requests send back, like a
+// session ID or a CSRF token, so it has to be extracted from the response instead of being
+// hard-coded in the script.
+type dynamicValue struct {
+	value string
+	// the variable name is based on where the value comes from, like the name of the header
+	source string
+	name   string
+	// extraction is the JS expression that extracts the value from the response
+	extraction string
+}
+
+// correlation has the dynamic values of the entries of a recording.
+type correlation struct {
+	// the values that the response of each entry returns and later requests use
+	extracted map[*Entry][]*dynamicValue
+	// the values that the request of each entry uses, the longest ones first
+	substituted map[*Entry][]*dynamicValue
+	// the names of the cookies that previous responses set before each entry, the cookie jar of
+	// k6 sends them back so they don't have to be in the script
+	jarCookies map[*Entry]map[string]bool
+	// the names of the variables of the used values
+	names []string
+}
+
+//nolint:gochecknoglobals
+var (
+	nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]+`)
+	// the response headers with these in their names can have dynamic values
+	dynamicHeaderNames = []string{"token", "csrf", "xsrf", "session", "auth", "nonce", "key"}
+	// the names that the generated script already uses
+	reservedNames = map[string]bool{
+		"res": true, "redirectUrl": true, "json": true, "http": true, "check": true, "group": true, "sleep": true,
+		"default": true, "new": true, "class": true, "function": true, "var": true, "let": true, "const": true,
+		"return": true, "delete": true, "this": true, "in": true, "typeof": true, "value": true,
+	}
+)
+
+// findDynamicValues finds the dynamic values of the responses of the entries that later requests use.
+func findDynamicValues(entries []*Entry) *correlation {
+	c := &correlation{
+		extracted:   make(map[*Entry][]*dynamicValue),
+		substituted: make(map[*Entry][]*dynamicValue),
+		jarCookies:  make(map[*Entry]map[string]bool),
+	}
+	var known []*dynamicValue
+	seen := make(map[string]bool)
+	var sent strings.Builder
+	setCookies := make(map[string]bool)
+	used := make(map[*dynamicValue]bool)
+
+	var page string
+	previousResponse := map[string]interface{}{}
+
+	for _, e := range entries {
+		if e.Pageref != page {
+			page = e.Pageref
+			previousResponse = map[string]interface{}{}
+		}
+		requestText := requestText(e.Request, previousResponse)
+		for _, v := range known {
+			if strings.Contains(requestText, v.value) {
+				used[v] = true
+				c.substituted[e] = append(c.substituted[e], v)
+			}
+		}
+		sort.SliceStable(c.substituted[e], func(i, j int) bool {
+			return len(c.substituted[e][i].value) > len(c.substituted[e][j].value)
+		})
+		c.jarCookies[e] = make(map[string]bool, len(setCookies))
+		for name := range setCookies {
+			c.jarCookies[e][name] = true
+		}
+		sent.WriteString(requestText)
+
+		if e.Response == nil {
+			continue
+		}
+		for _, v := range responseValues(e.Response) {
+			// the values that the client sent first aren't dynamic values of the server
+			if seen[v.value] || strings.Contains(sent.String(), v.value) {
+				continue
+			}
+			seen[v.value] = true
+			known = append(known, v)
+			c.extracted[e] = append(c.extracted[e], v)
+		}
+		if isJSONResponse(e.Response) {
+			previousResponse = map[string]interface{}{}
+			_ = json.Unmarshal([]byte(e.Response.Content.Text), &previousResponse)
+		}
+		for _, cookie := range e.Response.Cookies {
+			setCookies[cookie.Name] = true
+		}
+		for _, h := range e.Response.Headers {
+			if strings.EqualFold(h.Name, "Set-Cookie") {
+				if i := strings.IndexByte(h.Value, '='); i > 0 {
+					setCookies[strings.TrimSpace(h.Value[:i])] = true
+				}
+			}
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, e := range entries {
+		values := c.extracted[e][:0]
+		for _, v := range c.extracted[e] {
+			if !used[v] {
+				continue
+			}
+			v.name = variableName(v.source, names)
+			c.names = append(c.names, v.name)
+			values = append(values, v)
+		}
+		c.extracted[e] = values
+	}
+	return c
+}
+
+// requestText returns the parts of the request that can have dynamic values. The values of the
+// JSON bodies that the JSON response before them has are left out, since the script gets them
+// from the json variable of that response.
+func requestText(req *Request, previousResponse map[string]interface{}) string {
+	parts := []string{req.URL}
+	for _, h := range req.Headers {
+		name := strings.ToLower(h.Name)
+		if name != "cookie" && name != "content-length" && !strings.HasPrefix(name, ":") {
+			parts = append(parts, h.Value)
+		}
+	}
+	if req.PostData != nil {
+		text := req.PostData.Text
+		requestMap := map[string]interface{}{}
+		if strings.Contains(req.PostData.MimeType, "json") && json.Unmarshal([]byte(text), &requestMap) == nil {
+			if len(previousResponse) != 0 {
+				traverseMaps(requestMap, previousResponse, nil)
+			}
+			if data, err := json.Marshal(requestMap); err == nil {
+				text = string(data)
+			}
+		}
+		parts = append(parts, text)
+		for _, p := range req.PostData.Params {
+			parts = append(parts, p.Value)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// responseValues returns the values of the response that look dynamic, from the headers with
+// names like tokens and sessions, the string values of JSON bodies, and the hidden inputs and
+// the CSRF meta tags of HTML bodies.
+//nolint:gocognit
+func responseValues(resp *Response) []*dynamicValue {
+	var values []*dynamicValue
+	for _, h := range resp.Headers {
+		name := strings.ToLower(h.Name)
+		if name == "set-cookie" || !isDynamic(h.Value) {
+			continue
+		}
+		for _, n := range dynamicHeaderNames {
+			if strings.Contains(name, n) {
+				values = append(values, &dynamicValue{
+					value:      h.Value,
+					source:     h.Name,
+					extraction: fmt.Sprintf("res.headers[%q]", http.CanonicalHeaderKey(h.Name)),
+				})
+				break
+			}
+		}
+	}
+
+	if resp.Content == nil || resp.Content.Text == "" {
+		return values
+	}
+	body := resp.Content.Text
+	if resp.Content.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return values
+		}
+		body = string(decoded)
+	}
+
+	switch mimeType := strings.ToLower(resp.Content.MimeType); {
+	case strings.Contains(mimeType, "json"):
+		var v interface{}
+		if json.Unmarshal([]byte(body), &v) == nil {
+			values = append(values, jsonValues(v, nil)...)
+		}
+	case strings.Contains(mimeType, "html"):
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+		if err != nil {
+			return values
+		}
+		doc.Find(`input[type="hidden"][name][value]`).Each(func(_ int, s *goquery.Selection) {
+			name, _ := s.Attr("name")
+			value, _ := s.Attr("value")
+			if isDynamic(value) {
+				values = append(values, &dynamicValue{
+					value:  value,
+					source: name,
+					extraction: fmt.Sprintf("res.html().find(%q).first().attr(\"value\")",
+						fmt.Sprintf("input[name=%q]", name)),
+				})
+			}
+		})
+		doc.Find(`meta[name][content]`).Each(func(_ int, s *goquery.Selection) {
+			name, _ := s.Attr("name")
+			content, _ := s.Attr("content")
+			lower := strings.ToLower(name)
+			if isDynamic(content) && (strings.Contains(lower, "csrf") || strings.Contains(lower, "token")) {
+				values = append(values, &dynamicValue{
+					value:  content,
+					source: name,
+					extraction: fmt.Sprintf("res.html().find(%q).first().attr(\"content\")",
+						fmt.Sprintf("meta[name=%q]", name)),
+				})
+			}
+		})
+	}
+	return values
+}
+
+// jsonValues returns the string values of a JSON document that look dynamic, with the res.json()
+// expressions that select them.
+func jsonValues(v interface{}, path []string) []*dynamicValue {
+	var values []*dynamicValue
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			// the keys with the special characters of the GJSON syntax are skipped
+			if strings.ContainsAny(k, `.*?|#@\`) || k == "" {
+				continue
+			}
+			values = append(values, jsonValues(v[k], append(path[:len(path):len(path)], k))...)
+		}
+	case []interface{}:
+		for i, item := range v {
+			values = append(values, jsonValues(item, append(path[:len(path):len(path)], strconv.Itoa(i)))...)
+		}
+	case string:
+		if isDynamic(v) && len(path) > 0 {
+			source := path[len(path)-1]
+			for i := len(path) - 1; i >= 0; i-- {
+				// the nearest key that isn't an array index
+				if _, err := strconv.Atoi(path[i]); err != nil {
+					source = path[i]
+					break
+				}
+			}
+			values = append(values, &dynamicValue{
+				value:      v,
+				source:     source,
+				extraction: fmt.Sprintf("res.json(%q)", strings.Join(path, ".")),
+			})
+		}
+	}
+	return values
+}
+
+// isDynamic returns whether the value looks like a generated one, like an ID or a token, instead
+// of a word, a URL or a date.
+func isDynamic(value string) bool {
+	if len(value) < 8 || len(value) > 4096 || strings.ContainsAny(value, " \t\r\n\"") ||
+		strings.Contains(value, "://") {
+		return false
+	}
+	if _, err := time.Parse(time.RFC3339, value); err == nil {
+		return false
+	}
+	if strings.ContainsAny(value, "0123456789") {
+		return true
+	}
+	// the long values without digits have to have mixed case letters, the rest are names like
+	// some_field_name or SOME_CONSTANT
+	return len(value) >= 16 && strings.ToLower(value) != value && strings.ToUpper(value) != value
+}
+
+// variableName returns a unique JS variable name in lower camel case for the source of a value.
+func variableName(source string, names map[string]bool) string {
+	words := strings.Fields(nonAlphanumeric.ReplaceAllString(source, " "))
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if i > 0 {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		words[i] = word
+	}
+	name := strings.Join(words, "")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "value" + strings.Title(name)
+	}
+	unique := name
+	for i := 2; names[unique] || reservedNames[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	names[unique] = true
+	return unique
+}
+
+// literal returns a JS string literal of the text of the request of the entry, or a template
+// literal with its dynamic values replaced with their variables.
+func (c *correlation) literal(e *Entry, s string) string {
+	if result, replaced := c.replace(e, s, jsgen.EscapeTemplate); replaced {
+		return "`" + result + "`"
+	}
+	return fmt.Sprintf("%q", s)
+}
+
+// substitute replaces the dynamic values of the request of the entry in the text of a template
+// literal with their variables.
+func (c *correlation) substitute(e *Entry, s string) string {
+	result, _ := c.replace(e, s, func(s string) string { return s })
+	return result
+}
+
+// replace returns the text with the dynamic values of the request of the entry replaced with
+// their ${variables}, and the rest of it escaped.
+func (c *correlation) replace(e *Entry, s string, escape func(string) string) (string, bool) {
+	if c == nil || len(c.substituted[e]) == 0 {
+		return escape(s), false
+	}
+	type span struct {
+		start, end int
+		name       string
+	}
+	var spans []span
+	overlaps := func(start, end int) bool {
+		for _, sp := range spans {
+			if start < sp.end && sp.start < end {
+				return true
+			}
+		}
+		return false
+	}
+	// the values are the longest ones first, so the values that are in others aren't replaced
+	for _, v := range c.substituted[e] {
+		for i := 0; ; {
+			j := strings.Index(s[i:], v.value)
+			if j < 0 {
+				break
+			}
+			start, end := i+j, i+j+len(v.value)
+			if !overlaps(start, end) {
+				spans = append(spans, span{start, end, v.name})
+			}
+			i = end
+		}
+	}
+	if len(spans) == 0 {
+		return escape(s), false
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var b strings.Builder
+	last := 0
+	for _, sp := range spans {
+		b.WriteString(escape(s[last:sp.start]))
+		b.WriteString("${" + sp.name + "}")
+		last = sp.end
+	}
+	b.WriteString(escape(s[last:]))
+	return b.String(), true
+}
@@ -0,0 +1,134 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package har
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib"
+)
+
+const correlationHAR = `{"log": {
+	"version": "1.2",
+	"creator": {"name": "test", "version": "1"},
+	"pages": [{"id": "page_1", "title": "Login", "startedDateTime": "2021-01-01T00:00:00Z"}],
+	"entries": [
+		{
+			"pageref": "page_1",
+			"startedDateTime": "2021-01-01T00:00:00Z",
+			"request": {"method": "GET", "url": "https://example.com/login", "headers": [], "cookies": []},
+			"response": {
+				"status": 200,
+				"headers": [
+					{"name": "Set-Cookie", "value": "sid=d41d8cd98f00b204; Path=/"},
+					{"name": "Content-Type", "value": "text/html"}
+				],
+				"cookies": [],
+				"content": {
+					"mimeType": "text/html",
+					"text": "<form><input type=\"hidden\" name=\"csrf_token\" value=\"a1b2c3d4e5f6\"><input type=\"hidden\" name=\"step\" value=\"login\"></form>"
+				}
+			}
+		},
+		{
+			"pageref": "page_1",
+			"startedDateTime": "2021-01-01T00:00:01Z",
+			"request": {
+				"method": "POST",
+				"url": "https://example.com/login",
+				"headers": [{"name": "Content-Type", "value": "application/x-www-form-urlencoded"}],
+				"cookies": [{"name": "sid", "value": "d41d8cd98f00b204"}],
+				"postData": {
+					"mimeType": "application/x-www-form-urlencoded",
+					"text": "csrf_token=a1b2c3d4e5f6&step=login&user=admin"
+				}
+			},
+			"response": {
+				"status": 200,
+				"headers": [{"name": "X-Auth-Token", "value": "9f86d081884c7d659a2feaa0"}],
+				"cookies": [],
+				"content": {"mimeType": "application/json", "text": "{\"user\": {\"id\": \"u-12345678\"}}"}
+			}
+		},
+		{
+			"pageref": "page_1",
+			"startedDateTime": "2021-01-01T00:00:02Z",
+			"request": {
+				"method": "GET",
+				"url": "https://example.com/users/u-12345678",
+				"headers": [{"name": "Authorization", "value": "Bearer 9f86d081884c7d659a2feaa0"}],
+				"cookies": [{"name": "sid", "value": "d41d8cd98f00b204"}]
+			},
+			"response": {"status": 200, "headers": [], "cookies": [], "content": {"mimeType": "text/plain", "text": ""}}
+		}
+	]
+}}`
+
+func TestCorrelate(t *testing.T) {
+	t.Parallel()
+	var h HAR
+	require.NoError(t, json.Unmarshal([]byte(correlationHAR), &h))
+
+	script, err := Convert(h, lib.Options{}, 1, 2, false, false, 500, true, true, nil, nil)
+	require.NoError(t, err)
+
+	assert.Contains(t, script, "\tlet csrfToken, xAuthToken, id;\n")
+	assert.Contains(t, script,
+		"\t\tcsrfToken = res.html().find(\"input[name=\\\"csrf_token\\\"]\").first().attr(\"value\");\n")
+	assert.Contains(t, script, "\t\txAuthToken = res.headers[\"X-Auth-Token\"];\n")
+	assert.Contains(t, script, "\t\tid = res.json(\"user.id\");\n")
+	assert.Contains(t, script, "\t\t`csrf_token=${csrfToken}&step=login&user=admin`")
+	assert.Contains(t, script, "res = http.get(`https://example.com/users/${id}`")
+	assert.Contains(t, script, "\"Authorization\": `Bearer ${xAuthToken}`")
+	// the session cookie is set by the first response, so the cookie jar sends it
+	assert.NotContains(t, script, "d41d8cd98f00b204")
+	// the word isn't a dynamic value
+	assert.NotContains(t, script, "step = ")
+
+	t.Run("Without", func(t *testing.T) {
+		t.Parallel()
+		script, err := Convert(h, lib.Options{}, 1, 2, false, false, 500, true, false, nil, nil)
+		require.NoError(t, err)
+		assert.NotContains(t, script, "csrfToken")
+		assert.Contains(t, script, "\"sid\": \"d41d8cd98f00b204\"")
+	})
+}
+
+func TestIsDynamic(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]bool{
+		"a1b2c3d4e5f6":                         true,
+		"570714bf-3c2b-452e-90cd-f7c5e552bb25": true,
+		"dGhpcyBpcyBhIHRva2Vu":                 true,
+		"short1":                               false,
+		"national_identification_number":       false,
+		"with spaces 12345678":                 false,
+		"https://example.com/12345678":         false,
+		"2021-01-01T00:00:00Z":                 false,
+	}
+	for value, expected := range testCases {
+		assert.Equal(t, expected, isDynamic(value), value)
+	}
+}
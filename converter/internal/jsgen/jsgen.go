@@ -0,0 +1,55 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package jsgen contains the helpers shared by the converters for writing the
+// generated k6 scripts.
+package jsgen
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Fprint panics when where's an error writing to the supplied io.Writer
+// since this will be used on in-memory expandable buffers, that should
+// happen only when we run out of memory...
+func Fprint(w io.Writer, a ...interface{}) {
+	if _, err := fmt.Fprint(w, a...); err != nil {
+		panic(err.Error())
+	}
+}
+
+// Fprintf panics when where's an error writing to the supplied io.Writer
+// since this will be used on in-memory expandable buffers, that should
+// happen only when we run out of memory...
+func Fprintf(w io.Writer, format string, a ...interface{}) {
+	if _, err := fmt.Fprintf(w, format, a...); err != nil {
+		panic(err.Error())
+	}
+}
+
+//nolint:gochecknoglobals
+var templateEscaper = strings.NewReplacer("\\", "\\\\", "`", "\\`", "${", "\\${")
+
+// EscapeTemplate escapes the text for a JS template literal.
+func EscapeTemplate(s string) string {
+	return templateEscaper.Replace(s)
+}
@@ -0,0 +1,915 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package jmx converts JMeter test plans to k6 scripts, with a scenario for each thread group,
+// the HTTP samplers as requests, the CSV data sets as shared arrays and the assertions as checks.
+package jmx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.k6.io/k6/converter/internal/jsgen"
+	"go.k6.io/k6/lib"
+)
+
+//nolint:gochecknoglobals
+var (
+	variableRef  = regexp.MustCompile(`\$\{([^{}]+)\}`)
+	functionCall = regexp.MustCompile(`^(__\w+)(?:\((.*)\))?$`)
+	identifier   = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+	nonWord      = regexp.MustCompile(`[^A-Za-z0-9]+`)
+	regexGroup   = regexp.MustCompile(`^\$(\d+)\$$`)
+
+	// the elements that k6 doesn't need, like the listeners and the cookie manager
+	ignoredElements = map[string]bool{
+		"ResultCollector": true, "Summariser": true, "BackendListener": true, "CookieManager": true,
+		"CacheManager": true, "DNSCacheManager": true, "CSVDataSet": true, "Arguments": true,
+	}
+
+	sizeOperators = map[string]string{"1": "===", "2": "!==", "3": ">", "4": "<", "5": ">=", "6": "<="}
+)
+
+// the bits of the Assertion.test_type of the response assertions
+const (
+	assertMatches   = 1
+	assertContains  = 2
+	assertNot       = 4
+	assertEquals    = 8
+	assertSubstring = 16
+	assertOr        = 32
+)
+
+type scenario struct {
+	Executor   string  `json:"executor"`
+	StartTime  string  `json:"startTime,omitempty"`
+	VUs        int64   `json:"vus,omitempty"`
+	Iterations int64   `json:"iterations,omitempty"`
+	StartVUs   *int64  `json:"startVUs,omitempty"`
+	Stages     []stage `json:"stages,omitempty"`
+	Duration   string  `json:"duration,omitempty"`
+	Exec       string  `json:"exec"`
+}
+
+type stage struct {
+	Duration string `json:"duration"`
+	Target   int64  `json:"target"`
+}
+
+type csvDataSet struct {
+	element  *Element
+	constant string
+}
+
+// scope has the elements that apply to all the samplers of a level of the test plan and the
+// levels below it.
+type scope struct {
+	defaults   map[string]string
+	headers    [][2]string
+	timers     []*Element
+	assertions []*Element
+	extractors []*Element
+}
+
+type converter struct {
+	enableChecks        bool
+	returnOnFailedCheck bool
+
+	csv         map[*Element]*csvDataSet
+	names       map[string]bool
+	unsupported map[string]bool
+
+	usesGroup, usesCheck, usesSleep, usesFaker bool
+}
+
+// Convert returns a k6 script with the thread groups of the test plan as scenarios.
+//nolint:funlen,gocognit,cyclop
+func Convert(plan *TestPlan, options lib.Options, enableChecks, returnOnFailedCheck bool) (string, error) {
+	if returnOnFailedCheck && !enableChecks {
+		return "", fmt.Errorf("return on failed check requires --enable-status-code-checks")
+	}
+
+	conv := &converter{
+		enableChecks:        enableChecks,
+		returnOnFailedCheck: returnOnFailedCheck,
+		csv:                 make(map[*Element]*csvDataSet),
+		names:               map[string]bool{"vars": true, "parseCSV": true, "setup": true, "teardown": true},
+		unsupported:         make(map[string]bool),
+	}
+
+	vars := [][2]string{}
+	for _, a := range plan.Arguments("TestPlan.user_defined_variables") {
+		vars = append(vars, [2]string{a.Prop("Argument.name"), a.Prop("Argument.value")})
+	}
+	var csvs []*csvDataSet
+	walk(plan.Element, func(e *Element) {
+		switch e.Type {
+		case "Arguments":
+			arguments, _ := e.CollectionProp("Arguments.arguments")
+			for _, a := range arguments {
+				vars = append(vars, [2]string{a.Prop("Argument.name"), a.Prop("Argument.value")})
+			}
+		case "CSVDataSet":
+			name := strings.TrimSuffix(path.Base(strings.ReplaceAll(e.Prop("filename"), "\\", "/")),
+				path.Ext(e.Prop("filename")))
+			d := &csvDataSet{element: e, constant: conv.identifier(name, "data")}
+			conv.csv[e] = d
+			csvs = append(csvs, d)
+		}
+	})
+
+	// the data sets outside of the thread groups apply to all of them
+	inThreadGroups := make(map[*Element]bool)
+	for _, tg := range plan.Children {
+		if strings.Contains(tg.Type, "ThreadGroup") {
+			walk(tg, func(e *Element) { inThreadGroups[e] = true })
+		}
+	}
+	var planCSV []*csvDataSet
+	for _, d := range csvs {
+		if !inThreadGroups[d.element] {
+			planCSV = append(planCSV, d)
+		}
+	}
+
+	planScope := conv.scope(scope{defaults: map[string]string{}}, plan.Children)
+	scenarioConfigs := make(map[string]scenario)
+	var functions, warnings bytes.Buffer
+	var setup, teardown []*Element
+	for _, tg := range plan.Children {
+		if !tg.Enabled {
+			continue
+		}
+		switch tg.Type {
+		case "ThreadGroup":
+			name := conv.identifier(tg.Name, "threadGroup")
+			jsgen.Fprintf(&functions, "// Thread Group %q\n", tg.Name)
+			scenarioConfigs[name] = threadGroupScenario(tg, name, &functions)
+			jsgen.Fprintf(&functions, "export function %s() {\n", name)
+			conv.writeFunctionBody(&functions, tg, planScope, planCSV)
+			jsgen.Fprint(&functions, "}\n\n")
+		case "SetupThreadGroup":
+			setup = append(setup, tg)
+		case "PostThreadGroup":
+			teardown = append(teardown, tg)
+		default:
+			if strings.Contains(tg.Type, "ThreadGroup") {
+				jsgen.Fprintf(&warnings, "// WARNING: the %s %q isn't converted, only the standard thread groups are.\n",
+					tg.Type, tg.Name)
+			}
+		}
+	}
+	if len(scenarioConfigs) == 0 {
+		return "", errors.New("the JMeter test plan doesn't have any enabled thread groups")
+	}
+	for _, g := range []struct {
+		function string
+		groups   []*Element
+	}{{"setup", setup}, {"teardown", teardown}} {
+		if len(g.groups) == 0 {
+			continue
+		}
+		// the samplers of all the setUp or tearDown thread groups run in a single function
+		combined := &Element{Enabled: true}
+		names := make([]string, len(g.groups))
+		for i, tg := range g.groups {
+			names[i] = tg.Name
+			combined.Children = append(combined.Children, tg.Children...)
+		}
+		jsgen.Fprintf(&functions, "// %s\nexport function %s() {\n", strings.Join(names, ", "), g.function)
+		conv.writeFunctionBody(&functions, combined, planScope, nil)
+		jsgen.Fprint(&functions, "}\n\n")
+	}
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+
+	var k6Imports []string
+	for _, i := range []struct {
+		name string
+		used bool
+	}{{"group", conv.usesGroup}, {"check", conv.usesCheck}, {"sleep", conv.usesSleep}} {
+		if i.used {
+			k6Imports = append(k6Imports, i.name)
+		}
+	}
+	if len(k6Imports) > 0 {
+		jsgen.Fprintf(w, "import { %s } from 'k6';\n", strings.Join(k6Imports, ", "))
+	}
+	jsgen.Fprint(w, "import http from 'k6/http';\n")
+	if len(csvs) > 0 {
+		jsgen.Fprint(w, "import { SharedArray } from 'k6/data';\n")
+	}
+	if conv.usesFaker {
+		jsgen.Fprint(w, "import faker from 'k6/faker';\n")
+	}
+	jsgen.Fprint(w, "\n")
+
+	if plan.Name != "" {
+		jsgen.Fprintf(w, "// %s\n", plan.Name)
+	}
+	jsgen.Fprint(w, "// Converted from a JMeter test plan.\n")
+	if plan.BoolProp("TestPlan.serialize_threadgroups") {
+		jsgen.Fprint(w, "// WARNING: the thread groups run consecutively in JMeter, set the startTime of the scenarios.\n")
+	}
+	jsgen.Fprint(w, warnings.String())
+	if len(conv.unsupported) > 0 {
+		jsgen.Fprintf(w, "// WARNING: the JMeter functions %s aren't supported, replace them by hand.\n",
+			strings.Join(sortedKeys(conv.unsupported), ", "))
+	}
+
+	jsgen.Fprint(w, "\nexport let options = {\n")
+	var convertErr error
+	options.ForEachSpecified("json", func(key string, val interface{}) {
+		if valJSON, err := json.MarshalIndent(val, "    ", "    "); err != nil {
+			convertErr = err
+		} else {
+			jsgen.Fprintf(w, "    %s: %s,\n", key, valJSON)
+		}
+	})
+	if convertErr != nil {
+		return "", convertErr
+	}
+	if options.Scenarios == nil {
+		scenariosJSON, err := json.MarshalIndent(scenarioConfigs, "    ", "    ")
+		if err != nil {
+			return "", err
+		}
+		jsgen.Fprintf(w, "    scenarios: %s,\n", scenariosJSON)
+	}
+	jsgen.Fprint(w, "};\n\n")
+
+	if len(vars) > 0 {
+		jsgen.Fprint(w, "// The user defined variables, they can be overridden with environment variables\n")
+		jsgen.Fprint(w, "const vars = {\n")
+		for _, v := range vars {
+			if identifier.MatchString(v[0]) {
+				jsgen.Fprintf(w, "\t%s: __ENV.%s || %q,\n", v[0], v[0], v[1])
+			} else {
+				jsgen.Fprintf(w, "\t%q: __ENV[%q] || %q,\n", v[0], v[0], v[1])
+			}
+		}
+		jsgen.Fprint(w, "};\n\n")
+	} else {
+		jsgen.Fprint(w, "const vars = {};\n\n")
+	}
+
+	for _, d := range csvs {
+		writeCSVDataSet(w, d)
+	}
+	if len(csvs) > 0 {
+		jsgen.Fprint(w, parseCSV)
+	}
+
+	jsgen.Fprint(w, strings.TrimSuffix(functions.String(), "\n"))
+
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+const parseCSV = `// parseCSV returns the rows of a CSV file as objects with the names as keys, or the values of
+// the first line when there aren't any names. Quoted values aren't supported.
+function parseCSV(text, delimiter, names, ignoreFirstLine) {
+	const lines = text.split(/\r?\n/).filter((line) => line !== "");
+	if (!names) {
+		names = lines.shift().split(delimiter);
+	} else if (ignoreFirstLine) {
+		lines.shift();
+	}
+	return lines.map((line) => {
+		const values = line.split(delimiter);
+		const row = {};
+		names.forEach((name, i) => { row[name.trim()] = values[i]; });
+		return row;
+	});
+}
+
+`
+
+func writeCSVDataSet(w io.Writer, d *csvDataSet) {
+	e := d.element
+	filename := e.Prop("filename")
+	delimiter := strings.ReplaceAll(e.Prop("delimiter"), `\t`, "\t")
+	if delimiter == "" {
+		delimiter = ","
+	}
+	names := "null"
+	if variableNames := strings.TrimSpace(e.Prop("variableNames")); variableNames != "" {
+		quoted := strings.Split(variableNames, ",")
+		for i, n := range quoted {
+			quoted[i] = fmt.Sprintf("%q", strings.TrimSpace(n))
+		}
+		names = "[" + strings.Join(quoted, ", ") + "]"
+	}
+	jsgen.Fprintf(w, "// CSV Data Set Config %q\n", e.Name)
+	jsgen.Fprintf(w, "const %s = new SharedArray(%q, function() {\n", d.constant, filename)
+	jsgen.Fprintf(w, "\treturn parseCSV(open(%q), %q, %s, %t);\n", filename, delimiter, names, e.BoolProp("ignoreFirstLine"))
+	jsgen.Fprint(w, "});\n\n")
+}
+
+// walk calls the function for each of the enabled elements below the element.
+func walk(e *Element, fn func(*Element)) {
+	for _, c := range e.Children {
+		if c.Enabled {
+			fn(c)
+			walk(c, fn)
+		}
+	}
+}
+
+// identifier returns a unique JS identifier in lower camel case for the name.
+func (conv *converter) identifier(name, fallback string) string {
+	words := strings.Fields(nonWord.ReplaceAllString(name, " "))
+	for i, word := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(word[:1]) + word[1:]
+		} else {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	result := strings.Join(words, "")
+	if result == "" || !identifier.MatchString(result) {
+		result = fallback + strings.Title(result)
+	}
+	unique := result
+	for i := 2; conv.names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", result, i)
+	}
+	conv.names[unique] = true
+	return unique
+}
+
+// threadGroupScenario returns the scenario of a thread group, the iterations of its loop controller
+// become per VU iterations and the threads of a scheduled group become VUs for its duration.
+func threadGroupScenario(tg *Element, exec string, w io.Writer) scenario {
+	threads := tg.IntProp("ThreadGroup.num_threads", 1)
+	rampUp := tg.IntProp("ThreadGroup.ramp_time", 0)
+	loops := int64(1)
+	if loop := tg.ElementProp("ThreadGroup.main_controller"); loop != nil {
+		loops = loop.IntProp("LoopController.loops", 1)
+		if loop.BoolProp("LoopController.continue_forever") && loops < 0 {
+			loops = -1
+		}
+	}
+	var duration, delay int64
+	if tg.BoolProp("ThreadGroup.scheduler") {
+		duration = tg.IntProp("ThreadGroup.duration", 0)
+		delay = tg.IntProp("ThreadGroup.delay", 0)
+	}
+
+	s := scenario{Exec: exec, StartTime: seconds(delay)}
+	if duration == 0 && loops >= 0 {
+		s.Executor = "per-vu-iterations"
+		s.VUs = threads
+		s.Iterations = loops
+		if rampUp > 0 {
+			jsgen.Fprintf(w, "// WARNING: the ramp-up of %ds isn't converted, the VUs start at once.\n", rampUp)
+		}
+		return s
+	}
+	if duration == 0 {
+		jsgen.Fprint(w, "// WARNING: the thread group loops forever, the duration of its scenario is one minute.\n")
+		duration = 60
+	}
+	if rampUp == 0 || rampUp >= duration {
+		s.Executor = "constant-vus"
+		s.VUs = threads
+		s.Duration = seconds(duration)
+		return s
+	}
+	startVUs := int64(0)
+	s.Executor = "ramping-vus"
+	s.StartVUs = &startVUs
+	s.Stages = []stage{
+		{Duration: seconds(rampUp), Target: threads},
+		{Duration: seconds(duration - rampUp), Target: threads},
+	}
+	return s
+}
+
+// seconds returns the duration of the seconds in the format of the k6 options, like 1m30s.
+func seconds(s int64) string {
+	if s <= 0 {
+		return ""
+	}
+	d := (time.Duration(s) * time.Second).String()
+	d = strings.Replace(d, "m0s", "m", 1)
+	return strings.Replace(d, "h0m", "h", 1)
+}
+
+// scope returns the scope with the elements of the level that apply to all its samplers.
+func (conv *converter) scope(parent scope, elements []*Element) scope {
+	s := scope{
+		defaults:   make(map[string]string, len(parent.defaults)),
+		headers:    append([][2]string{}, parent.headers...),
+		timers:     append([]*Element{}, parent.timers...),
+		assertions: append([]*Element{}, parent.assertions...),
+		extractors: append([]*Element{}, parent.extractors...),
+	}
+	for k, v := range parent.defaults {
+		s.defaults[k] = v
+	}
+	for _, e := range elements {
+		if !e.Enabled {
+			continue
+		}
+		switch {
+		case e.Type == "ConfigTestElement" && e.Class() == "HttpDefaultsGui":
+			for _, p := range []string{"domain", "port", "protocol", "path"} {
+				if v := e.Prop("HTTPSampler." + p); v != "" {
+					s.defaults[p] = v
+				}
+			}
+		case e.Type == "HeaderManager":
+			headers, _ := e.CollectionProp("HeaderManager.headers")
+			for _, h := range headers {
+				s.headers = setHeader(s.headers, h.Prop("Header.name"), h.Prop("Header.value"))
+			}
+		case strings.HasSuffix(e.Type, "Timer"):
+			s.timers = append(s.timers, e)
+		case strings.HasSuffix(e.Type, "Assertion"):
+			s.assertions = append(s.assertions, e)
+		case e.Type == "RegexExtractor" || e.Type == "JSONPostProcessor":
+			s.extractors = append(s.extractors, e)
+		}
+	}
+	return s
+}
+
+func setHeader(headers [][2]string, name, value string) [][2]string {
+	for i, h := range headers {
+		if strings.EqualFold(h[0], name) {
+			headers[i][1] = value
+			return headers
+		}
+	}
+	return append(headers, [2]string{name, value})
+}
+
+// isScoped returns whether the element is one of the ones that the scope has.
+func isScoped(e *Element) bool {
+	return e.Type == "ConfigTestElement" || e.Type == "HeaderManager" || strings.HasSuffix(e.Type, "Timer") ||
+		strings.HasSuffix(e.Type, "Assertion") || e.Type == "RegexExtractor" || e.Type == "JSONPostProcessor"
+}
+
+func (conv *converter) writeFunctionBody(w io.Writer, tg *Element, parent scope, planCSV []*csvDataSet) {
+	var rows []string
+	add := func(d *csvDataSet) {
+		rows = append(rows, fmt.Sprintf("%s[(__VU - 1 + __ITER) %% %s.length]", d.constant, d.constant))
+	}
+	for _, d := range planCSV {
+		add(d)
+	}
+	walk(tg, func(e *Element) {
+		if d, ok := conv.csv[e]; ok {
+			add(d)
+		}
+	})
+	if len(rows) > 0 {
+		jsgen.Fprintf(w, "\tconst v = Object.assign({}, vars, %s);\n", strings.Join(rows, ", "))
+	} else {
+		jsgen.Fprint(w, "\tconst v = Object.assign({}, vars);\n")
+	}
+	jsgen.Fprint(w, "\tlet res, body;\n\n")
+	var content bytes.Buffer
+	conv.writeElements(&content, tg.Children, 1, conv.scope(parent, tg.Children))
+	jsgen.Fprint(w, strings.TrimSuffix(content.String(), "\n"))
+}
+
+// writeElements writes the samplers and the controllers of a level of the test plan.
+//nolint:funlen
+func (conv *converter) writeElements(w io.Writer, elements []*Element, depth int, s scope) {
+	indent := strings.Repeat("\t", depth)
+	for _, e := range elements {
+		if !e.Enabled || isScoped(e) || ignoredElements[e.Type] {
+			continue
+		}
+		switch e.Type {
+		case "HTTPSamplerProxy", "HTTPSampler":
+			conv.writeSampler(w, e, depth, conv.scope(s, e.Children))
+			jsgen.Fprint(w, "\n")
+		case "TransactionController":
+			conv.usesGroup = true
+			jsgen.Fprintf(w, "%sgroup(%q, function() {\n", indent, e.Name)
+			conv.writeChildren(w, e, depth+1, s)
+			jsgen.Fprintf(w, "%s});\n\n", indent)
+		case "LoopController":
+			loops := e.IntProp("LoopController.loops", 1)
+			if loops < 0 {
+				jsgen.Fprintf(w, "%s// WARNING: the %q loop controller loops forever, it's converted to one loop.\n",
+					indent, e.Name)
+				loops = 1
+			}
+			jsgen.Fprintf(w, "%s// %s\n%sfor (let i = 0; i < %d; i++) {\n", indent, e.Name, indent, loops)
+			conv.writeChildren(w, e, depth+1, s)
+			jsgen.Fprintf(w, "%s}\n\n", indent)
+		case "GenericController":
+			jsgen.Fprintf(w, "%s// %s\n", indent, e.Name)
+			conv.writeChildren(w, e, depth, s)
+			jsgen.Fprint(w, "\n")
+		case "JSR223Sampler", "BeanShellSampler", "JSR223PreProcessor", "JSR223PostProcessor",
+			"BeanShellPreProcessor", "BeanShellPostProcessor":
+			jsgen.Fprintf(w, "%s// WARNING: the %s %q isn't converted, port its script by hand:\n", indent, e.Type, e.Name)
+			for _, line := range strings.Split(strings.TrimSpace(e.Prop("script")), "\n") {
+				jsgen.Fprintf(w, "%s//   %s\n", indent, strings.TrimRight(line, " \t\r"))
+			}
+			jsgen.Fprint(w, "\n")
+		default:
+			jsgen.Fprintf(w, "%s// WARNING: the %s %q isn't converted\n", indent, e.Type, e.Name)
+			if len(e.Children) > 0 {
+				conv.writeChildren(w, e, depth, s)
+			}
+			jsgen.Fprint(w, "\n")
+		}
+	}
+}
+
+func (conv *converter) writeChildren(w io.Writer, e *Element, depth int, parent scope) {
+	var content bytes.Buffer
+	conv.writeElements(&content, e.Children, depth, conv.scope(parent, e.Children))
+	// no blank line after the last element of the block
+	jsgen.Fprint(w, strings.TrimSuffix(content.String(), "\n"))
+}
+
+//nolint:funlen,gocognit,cyclop
+func (conv *converter) writeSampler(w io.Writer, e *Element, depth int, s scope) {
+	indent := strings.Repeat("\t", depth)
+	jsgen.Fprintf(w, "%s// %s\n", indent, e.Name)
+
+	for _, t := range s.timers {
+		delay := t.IntProp("ConstantTimer.delay", 0)
+		switch t.Type {
+		case "ConstantTimer":
+			conv.usesSleep = true
+			jsgen.Fprintf(w, "%ssleep(%s);\n", indent, milliseconds(delay))
+		case "UniformRandomTimer", "GaussianRandomTimer":
+			conv.usesSleep = true
+			jsgen.Fprintf(w, "%ssleep((%d + Math.random() * %d) / 1000);\n", indent,
+				delay, t.IntProp("RandomTimer.range", 0))
+		default:
+			jsgen.Fprintf(w, "%s// WARNING: the %s %q isn't converted\n", indent, t.Type, t.Name)
+		}
+	}
+
+	get := func(prop string) string {
+		if v := e.Prop("HTTPSampler." + prop); v != "" {
+			return v
+		}
+		return s.defaults[prop]
+	}
+	address := e.Prop("HTTPSampler.path")
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		protocol := get("protocol")
+		if protocol == "" {
+			protocol = "http"
+		}
+		host := get("domain")
+		if port := get("port"); port != "" && !(protocol == "http" && port == "80") &&
+			!(protocol == "https" && port == "443") {
+			host += ":" + port
+		}
+		if address != "" && !strings.HasPrefix(address, "/") {
+			address = "/" + address
+		}
+		address = protocol + "://" + host + address
+	}
+	method := strings.ToUpper(e.Prop("HTTPSampler.method"))
+	if method == "" {
+		method = "GET"
+	}
+
+	body := "null"
+	args := e.Arguments("HTTPsampler.Arguments")
+	switch {
+	case e.BoolProp("HTTPSampler.postBodyRaw") && len(args) > 0:
+		jsgen.Fprintf(w, "%sbody = %s;\n", indent, conv.template(args[0].Prop("Argument.value")))
+		body = "body"
+	case len(args) > 0 && (method == "POST" || method == "PUT" || method == "PATCH"):
+		var fields []string
+		for _, a := range args {
+			fields = append(fields, fmt.Sprintf("%s\t%s: %s,\n", indent,
+				conv.template(a.Prop("Argument.name")), conv.template(a.Prop("Argument.value"))))
+		}
+		// k6 encodes the objects as forms
+		jsgen.Fprintf(w, "%sbody = {\n%s%s};\n", indent, strings.Join(fields, ""), indent)
+		body = "body"
+	case len(args) > 0:
+		var query []string
+		for _, a := range args {
+			name, value := a.Prop("Argument.name"), a.Prop("Argument.value")
+			if a.BoolProp("HTTPArgument.always_encode") {
+				name, value = encodeStatic(name), encodeStatic(value)
+			}
+			query = append(query, name+"="+value)
+		}
+		separator := "?"
+		if strings.Contains(address, "?") {
+			separator = "&"
+		}
+		address += separator + strings.Join(query, "&")
+	}
+	if files := e.ElementProp("HTTPsampler.Files"); files != nil {
+		if elements, _ := files.CollectionProp("HTTPFileArgs.files"); len(elements) > 0 {
+			jsgen.Fprintf(w, "%s// WARNING: the files of the sampler aren't converted, add them with http.file() by hand\n", indent)
+		}
+	}
+
+	var params []string
+	if len(s.headers) > 0 {
+		headers := make([]string, len(s.headers))
+		for i, h := range s.headers {
+			headers[i] = conv.template(h[0]) + ": " + conv.template(h[1])
+		}
+		params = append(params, fmt.Sprintf("headers: {\n%s\t\t%s,\n%s\t}",
+			indent, strings.Join(headers, ",\n"+indent+"\t\t"), indent))
+	}
+	params = append(params, fmt.Sprintf("tags: { name: %q }", e.Name))
+	paramsJS := fmt.Sprintf("{\n%s\t%s,\n%s}", indent, strings.Join(params, ",\n"+indent+"\t"), indent)
+
+	target := conv.template(address)
+	switch {
+	case (method == "GET" || method == "HEAD") && body == "null":
+		jsgen.Fprintf(w, "%sres = http.%s(%s, %s);\n", indent, strings.ToLower(method), target, paramsJS)
+	case method == "POST" || method == "PUT" || method == "PATCH" || method == "OPTIONS":
+		jsgen.Fprintf(w, "%sres = http.%s(%s, %s, %s);\n", indent, strings.ToLower(method), target, body, paramsJS)
+	case method == "DELETE":
+		jsgen.Fprintf(w, "%sres = http.del(%s, %s, %s);\n", indent, target, body, paramsJS)
+	default:
+		jsgen.Fprintf(w, "%sres = http.request(%q, %s, %s, %s);\n", indent, method, target, body, paramsJS)
+	}
+
+	conv.writeChecks(w, s.assertions, indent)
+	for _, x := range s.extractors {
+		conv.writeExtractor(w, x, indent)
+	}
+}
+
+func milliseconds(ms int64) string {
+	if ms%1000 == 0 {
+		return fmt.Sprint(ms / 1000)
+	}
+	return fmt.Sprintf("%g", float64(ms)/1000)
+}
+
+// encodeStatic query encodes the text if it doesn't have any variables.
+func encodeStatic(s string) string {
+	if variableRef.MatchString(s) {
+		return s
+	}
+	return url.QueryEscape(s)
+}
+
+//nolint:funlen,gocognit,cyclop
+func (conv *converter) writeChecks(w io.Writer, assertions []*Element, indent string) {
+	var checks []string
+	checkedStatus := false
+	names := make(map[string]bool)
+	add := func(name, expression string) {
+		unique := name
+		for i := 2; names[unique]; i++ {
+			unique = fmt.Sprintf("%s (%d)", name, i)
+		}
+		names[unique] = true
+		checks = append(checks, fmt.Sprintf("%q: (r) => %s", unique, expression))
+	}
+
+	for _, a := range assertions {
+		switch a.Type {
+		case "ResponseAssertion":
+			var subject string
+			switch a.Prop("Assertion.test_field") {
+			case "Assertion.response_data", "":
+				subject = "r.body"
+			case "Assertion.response_code":
+				subject = "String(r.status)"
+				checkedStatus = true
+			case "Assertion.response_message":
+				subject = "r.status_text"
+			case "Assertion.response_headers":
+				subject = "JSON.stringify(r.headers)"
+			default:
+				jsgen.Fprintf(w, "%s// WARNING: the %q assertion of the %s isn't converted\n",
+					indent, a.Name, a.Prop("Assertion.test_field"))
+				continue
+			}
+			testType := a.IntProp("Assertion.test_type", assertContains)
+			_, patterns := a.CollectionProp("Asserion.test_strings")
+			if len(patterns) == 0 {
+				continue
+			}
+			var expressions []string
+			for _, p := range patterns {
+				var expression string
+				switch {
+				case testType&assertMatches != 0:
+					expression = fmt.Sprintf("new RegExp(%s).test(%s)",
+						conv.template("^(?:"+p+")$"), subject)
+				case testType&assertEquals != 0:
+					expression = fmt.Sprintf("%s === %s", subject, conv.template(p))
+				case testType&assertSubstring != 0:
+					expression = fmt.Sprintf("%s.includes(%s)", subject, conv.template(p))
+				default:
+					expression = fmt.Sprintf("new RegExp(%s).test(%s)", conv.template(p), subject)
+				}
+				if testType&assertNot != 0 {
+					expression = "!(" + expression + ")"
+				}
+				expressions = append(expressions, expression)
+			}
+			operator := " && "
+			if testType&assertOr != 0 {
+				operator = " || "
+			}
+			add(a.Name, strings.Join(expressions, operator))
+		case "DurationAssertion":
+			add(a.Name, fmt.Sprintf("r.timings.duration <= %d", a.IntProp("DurationAssertion.duration", 0)))
+		case "SizeAssertion":
+			operator, ok := sizeOperators[a.Prop("SizeAssertion.operator")]
+			if !ok {
+				operator = "==="
+			}
+			add(a.Name, fmt.Sprintf("r.body.length %s %d", operator, a.IntProp("SizeAssertion.size", 0)))
+		case "JSONPathAssertion":
+			selector := conv.jsonPath(a.Prop("JSON_PATH"), w, indent)
+			var expression string
+			switch {
+			case !a.BoolProp("JSONVALIDATION"):
+				expression = fmt.Sprintf("r.json(%q) !== undefined", selector)
+			case a.BoolProp("EXPECT_NULL"):
+				expression = fmt.Sprintf("r.json(%q) === null", selector)
+			case a.Prop("ISREGEX") != "false":
+				expression = fmt.Sprintf("new RegExp(%s).test(String(r.json(%q)))",
+					conv.template("^(?:"+a.Prop("EXPECTED_VALUE")+")$"), selector)
+			default:
+				expression = fmt.Sprintf("String(r.json(%q)) === %s", selector, conv.template(a.Prop("EXPECTED_VALUE")))
+			}
+			if a.BoolProp("INVERT") {
+				expression = "!(" + expression + ")"
+			}
+			add(a.Name, expression)
+		default:
+			jsgen.Fprintf(w, "%s// WARNING: the %s %q isn't converted\n", indent, a.Type, a.Name)
+		}
+	}
+	if conv.enableChecks && !checkedStatus {
+		add("status is not an error", "r.status < 400")
+	}
+	if len(checks) == 0 {
+		return
+	}
+	conv.usesCheck = true
+	checksJS := checks[0]
+	if len(checks) > 1 {
+		checksJS = "\n" + indent + "\t" + strings.Join(checks, ",\n"+indent+"\t") + ",\n" + indent
+	}
+	if conv.returnOnFailedCheck {
+		jsgen.Fprintf(w, "%sif (!check(res, {%s})) { return };\n", indent, checksJS)
+	} else {
+		jsgen.Fprintf(w, "%scheck(res, {%s});\n", indent, checksJS)
+	}
+}
+
+func (conv *converter) writeExtractor(w io.Writer, e *Element, indent string) {
+	switch e.Type {
+	case "RegexExtractor":
+		name := e.Prop("RegexExtractor.refname")
+		group := "0"
+		if m := regexGroup.FindStringSubmatch(e.Prop("RegexExtractor.template")); m != nil {
+			group = m[1]
+		} else {
+			jsgen.Fprintf(w, "%s// WARNING: the template of the %q extractor isn't converted, it extracts the match\n",
+				indent, e.Name)
+		}
+		subject := "res.body"
+		if e.Prop("RegexExtractor.useHeaders") == "true" {
+			subject = "JSON.stringify(res.headers)"
+		}
+		jsgen.Fprintf(w, "%s%s = (%s.match(new RegExp(%s)) || [])[%s] || %s;\n", indent, conv.variable(name),
+			subject, conv.template(e.Prop("RegexExtractor.regex")), group, conv.template(e.Prop("RegexExtractor.default")))
+	case "JSONPostProcessor":
+		names := strings.Split(e.Prop("JSONPostProcessor.referenceNames"), ";")
+		paths := strings.Split(e.Prop("JSONPostProcessor.jsonPathExprs"), ";")
+		defaults := strings.Split(e.Prop("JSONPostProcessor.defaultValues"), ";")
+		for i, name := range names {
+			if i >= len(paths) {
+				break
+			}
+			defaultValue := ""
+			if i < len(defaults) {
+				defaultValue = defaults[i]
+			}
+			variable := conv.variable(strings.TrimSpace(name))
+			jsgen.Fprintf(w, "%s%s = res.json(%q);\n", indent, variable, conv.jsonPath(paths[i], w, indent))
+			jsgen.Fprintf(w, "%sif (%s === undefined) { %s = %s; }\n", indent, variable, variable, conv.template(defaultValue))
+		}
+	}
+}
+
+// jsonPath returns the gjson selector of a simple JSONPath expression, for r.json().
+func (conv *converter) jsonPath(expression string, w io.Writer, indent string) string {
+	expression = strings.TrimSpace(expression)
+	if strings.ContainsAny(expression, "*?(") || strings.Contains(expression, "..") {
+		jsgen.Fprintf(w, "%s// WARNING: the JSONPath %q isn't converted, r.json() uses the GJSON syntax\n",
+			indent, expression)
+	}
+	selector := strings.TrimPrefix(strings.TrimPrefix(expression, "$"), ".")
+	selector = strings.NewReplacer("['", ".", "']", "", "[", ".", "]", "").Replace(selector)
+	return strings.TrimPrefix(selector, ".")
+}
+
+func (conv *converter) variable(name string) string {
+	if identifier.MatchString(name) {
+		return "v." + name
+	}
+	return fmt.Sprintf("v[%q]", name)
+}
+
+// template returns a JS string literal of the text, or a template literal with the
+// ${variables} and ${__functions()} of the text replaced with their values.
+//nolint:cyclop
+func (conv *converter) template(s string) string {
+	if !variableRef.MatchString(s) {
+		return fmt.Sprintf("%q", s)
+	}
+	var b strings.Builder
+	b.WriteString("`")
+	last := 0
+	for _, m := range variableRef.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(jsgen.EscapeTemplate(s[last:m[0]]))
+		last = m[1]
+		name := strings.TrimSpace(s[m[2]:m[3]])
+		f := functionCall.FindStringSubmatch(name)
+		if f == nil {
+			if identifier.MatchString(name) {
+				b.WriteString("${v." + name + "}")
+			} else {
+				b.WriteString(fmt.Sprintf("${v[%q]}", name))
+			}
+			continue
+		}
+		args := strings.Split(f[2], ",")
+		arg := func(i int) string {
+			if i < len(args) {
+				return strings.TrimSpace(args[i])
+			}
+			return ""
+		}
+		switch f[1] {
+		case "__P", "__property":
+			b.WriteString(fmt.Sprintf("${__ENV[%q] || %q}", arg(0), arg(1)))
+		case "__Random":
+			b.WriteString(fmt.Sprintf("${Math.floor(%s + Math.random() * (%s - %s + 1))}", arg(0), arg(1), arg(0)))
+		case "__time":
+			b.WriteString("${Date.now()}")
+		case "__UUID":
+			conv.usesFaker = true
+			b.WriteString("${faker.uuid()}")
+		case "__threadNum":
+			b.WriteString("${__VU}")
+		default:
+			conv.unsupported[f[1]] = true
+			b.WriteString(jsgen.EscapeTemplate(s[m[0]:m[1]]))
+		}
+	}
+	b.WriteString(jsgen.EscapeTemplate(s[last:]))
+	b.WriteString("`")
+	return b.String()
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
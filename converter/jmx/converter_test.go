@@ -0,0 +1,117 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package jmx
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib"
+)
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	src, err := ioutil.ReadFile("testdata/plan.jmx")
+	require.NoError(t, err)
+	expected, err := ioutil.ReadFile("testdata/plan.js")
+	require.NoError(t, err)
+
+	assert.True(t, IsTestPlan(src))
+	plan, err := Decode(bytes.NewReader(src))
+	require.NoError(t, err)
+	assert.Equal(t, "Shop load test", plan.Name)
+
+	script, err := Convert(plan, lib.Options{}, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), script)
+
+	_, err = Convert(plan, lib.Options{}, false, true)
+	assert.EqualError(t, err, "return on failed check requires --enable-status-code-checks")
+}
+
+const threadGroupPlan = `<jmeterTestPlan><hashTree>
+	<TestPlan testname="Plan"/>
+	<hashTree>
+		<ThreadGroup testname="Users">
+			<elementProp name="ThreadGroup.main_controller" elementType="LoopController">
+				<stringProp name="LoopController.loops">%s</stringProp>
+			</elementProp>
+			<stringProp name="ThreadGroup.num_threads">5</stringProp>
+			<stringProp name="ThreadGroup.ramp_time">%s</stringProp>
+			<boolProp name="ThreadGroup.scheduler">%s</boolProp>
+			<stringProp name="ThreadGroup.duration">%s</stringProp>
+		</ThreadGroup>
+		<hashTree/>
+	</hashTree>
+</hashTree></jmeterTestPlan>`
+
+func TestThreadGroupScenario(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		loops, rampUp, scheduler, duration string
+		expected                           scenario
+	}{
+		{"3", "0", "false", "", scenario{Executor: "per-vu-iterations", VUs: 5, Iterations: 3, Exec: "users"}},
+		{"-1", "0", "true", "90", scenario{Executor: "constant-vus", VUs: 5, Duration: "1m30s", Exec: "users"}},
+		{"-1", "0", "false", "", scenario{Executor: "constant-vus", VUs: 5, Duration: "1m", Exec: "users"}},
+		{"1", "7200", "true", "3600", scenario{Executor: "constant-vus", VUs: 5, Duration: "1h", Exec: "users"}},
+		{"-1", "60", "true", "600", scenario{
+			Executor: "ramping-vus", StartVUs: new(int64), Exec: "users",
+			Stages: []stage{{Duration: "1m", Target: 5}, {Duration: "9m", Target: 5}},
+		}},
+	}
+	for _, tc := range testCases {
+		plan, err := Decode(strings.NewReader(fmt.Sprintf(threadGroupPlan, tc.loops, tc.rampUp, tc.scheduler, tc.duration)))
+		require.NoError(t, err)
+		tg := plan.Children[0]
+		assert.Equal(t, tc.expected, threadGroupScenario(tg, "users", &bytes.Buffer{}), tc)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]string{
+		`<testPlan/>`:                                         "invalid JMeter test plan, the root element has to be jmeterTestPlan",
+		`<jmeterTestPlan></jmeterTestPlan>`:                   "invalid JMeter test plan, it doesn't have a hashTree",
+		`<jmeterTestPlan><hashTree/></jmeterTestPlan>`:        "invalid JMeter test plan, it doesn't have a TestPlan element",
+		`<jmeterTestPlan><hashTree><ThreadGroup/></hashTree>`: "invalid JMeter test plan: XML syntax error on line 1: unexpected EOF",
+		``: "invalid JMeter test plan: the document doesn't have a root element",
+	}
+	for src, expected := range testCases {
+		_, err := Decode(strings.NewReader(src))
+		require.Error(t, err, src)
+		assert.Equal(t, expected, err.Error(), src)
+	}
+
+	plan, err := Decode(strings.NewReader(`<jmeterTestPlan><hashTree><TestPlan/><hashTree/></hashTree></jmeterTestPlan>`))
+	require.NoError(t, err)
+	_, err = Convert(plan, lib.Options{}, false, false)
+	assert.EqualError(t, err, "the JMeter test plan doesn't have any enabled thread groups")
+
+	assert.False(t, IsTestPlan([]byte(`{"log": {"entries": []}}`)))
+	assert.False(t, IsTestPlan([]byte(`<?xml version="1.0"?><project/>`)))
+}
@@ -0,0 +1,253 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package jmx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var propertyRef = regexp.MustCompile(`^\$\{__P\([^,)]*,([^)]*)\)\}$`) //nolint:gochecknoglobals
+
+// Element is an element of a JMeter test plan, like a thread group, a sampler or an assertion,
+// with its properties and the elements of its hash tree.
+type Element struct {
+	// Type is the name of the XML element, like ThreadGroup or HTTPSamplerProxy.
+	Type string
+	// Name is the name given to the element in JMeter.
+	Name    string
+	Enabled bool
+	// Children are the elements of the hash tree of the element, which it controls or applies to.
+	Children []*Element
+
+	props *xmlNode
+}
+
+// TestPlan is a parsed JMeter test plan.
+type TestPlan struct {
+	*Element
+}
+
+type xmlNode struct {
+	name     string
+	attrs    map[string]string
+	children []*xmlNode
+	text     string
+}
+
+// Decode reads a JMeter .jmx test plan.
+func Decode(r io.Reader) (*TestPlan, error) {
+	root, err := parseXML(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JMeter test plan: %w", err)
+	}
+	if root.name != "jmeterTestPlan" {
+		return nil, errors.New("invalid JMeter test plan, the root element has to be jmeterTestPlan")
+	}
+	var tree *xmlNode
+	for _, c := range root.children {
+		if c.name == "hashTree" {
+			tree = c
+			break
+		}
+	}
+	if tree == nil {
+		return nil, errors.New("invalid JMeter test plan, it doesn't have a hashTree")
+	}
+	elements := hashTree(tree)
+	if len(elements) == 0 || elements[0].Type != "TestPlan" {
+		return nil, errors.New("invalid JMeter test plan, it doesn't have a TestPlan element")
+	}
+	return &TestPlan{elements[0]}, nil
+}
+
+// IsTestPlan returns whether the data looks like a JMeter test plan, for detecting the format of
+// the files to convert.
+func IsTestPlan(data []byte) bool {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := t.(xml.StartElement); ok {
+			return start.Name.Local == "jmeterTestPlan"
+		}
+	}
+}
+
+func parseXML(r io.Reader) (*xmlNode, error) {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	var stack []*xmlNode
+	var root *xmlNode
+	for {
+		t, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name.Local, attrs: make(map[string]string, len(t.Attr))}
+			for _, a := range t.Attr {
+				n.attrs[a.Name.Local] = a.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("the document doesn't have a root element")
+	}
+	return root, nil
+}
+
+// hashTree returns the elements of a hash tree, in which each element is followed by the hash
+// tree of its children.
+func hashTree(tree *xmlNode) []*Element {
+	var elements []*Element
+	for _, c := range tree.children {
+		if c.name == "hashTree" {
+			if len(elements) > 0 {
+				last := elements[len(elements)-1]
+				last.Children = append(last.Children, hashTree(c)...)
+			}
+			continue
+		}
+		elements = append(elements, &Element{
+			Type:    c.name,
+			Name:    c.attrs["testname"],
+			Enabled: c.attrs["enabled"] != "false",
+			props:   c,
+		})
+	}
+	return elements
+}
+
+// Class returns the GUI class of the element, which tells apart the elements of the same type.
+func (e *Element) Class() string {
+	return e.props.attrs["guiclass"]
+}
+
+// Prop returns the value of a string, bool, int or long property of the element.
+func (e *Element) Prop(name string) string {
+	return e.props.prop(name)
+}
+
+// BoolProp returns the value of a bool property of the element.
+func (e *Element) BoolProp(name string) bool {
+	return e.props.prop(name) == "true"
+}
+
+// IntProp returns the value of a number property of the element, or of the default value of a
+// ${__P(name,number)} property reference, or the default value if it's neither.
+func (e *Element) IntProp(name string, defaultValue int64) int64 {
+	s := strings.TrimSpace(e.props.prop(name))
+	if m := propertyRef.FindStringSubmatch(s); m != nil {
+		s = strings.TrimSpace(m[1])
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return v
+}
+
+// ElementProp returns an element property of the element, these hold the nested configuration,
+// like the loop controller of a thread group.
+func (e *Element) ElementProp(name string) *Element {
+	if n := e.props.child(name, "elementProp"); n != nil {
+		return &Element{Type: n.attrs["elementType"], Name: n.attrs["name"], Enabled: true, props: n}
+	}
+	return nil
+}
+
+// CollectionProp returns the elements and the values of a collection property of the element.
+func (e *Element) CollectionProp(name string) ([]*Element, []string) {
+	n := e.props.child(name, "collectionProp")
+	if n == nil {
+		return nil, nil
+	}
+	var elements []*Element
+	var values []string
+	for _, c := range n.children {
+		if c.name == "elementProp" {
+			elements = append(elements, &Element{
+				Type: c.attrs["elementType"], Name: c.attrs["name"], Enabled: true, props: c,
+			})
+		} else {
+			values = append(values, c.text)
+		}
+	}
+	return elements, values
+}
+
+// Arguments returns the arguments of the Arguments element property of the element, which hold
+// the HTTP parameters of a sampler and the user defined variables.
+func (e *Element) Arguments(name string) []*Element {
+	args := e.ElementProp(name)
+	if args == nil {
+		return nil
+	}
+	elements, _ := args.CollectionProp("Arguments.arguments")
+	return elements
+}
+
+func (n *xmlNode) prop(name string) string {
+	for _, c := range n.children {
+		if c.attrs["name"] == name && strings.HasSuffix(c.name, "Prop") && c.name != "elementProp" &&
+			c.name != "collectionProp" {
+			return c.text
+		}
+	}
+	return ""
+}
+
+func (n *xmlNode) child(name, kind string) *xmlNode {
+	for _, c := range n.children {
+		if c.name == kind && c.attrs["name"] == name {
+			return c
+		}
+	}
+	return nil
+}
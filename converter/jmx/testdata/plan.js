@@ -0,0 +1,155 @@
+import { group, check, sleep } from 'k6';
+import http from 'k6/http';
+import { SharedArray } from 'k6/data';
+
+// Shop load test
+// Converted from a JMeter test plan.
+
+export let options = {
+    scenarios: {
+        "browse": {
+            "executor": "ramping-vus",
+            "startTime": "10s",
+            "startVUs": 0,
+            "stages": [
+                {
+                    "duration": "30s",
+                    "target": 50
+                },
+                {
+                    "duration": "4m30s",
+                    "target": 50
+                }
+            ],
+            "exec": "browse"
+        },
+        "healthCheck": {
+            "executor": "per-vu-iterations",
+            "vus": 2,
+            "iterations": 10,
+            "exec": "healthCheck"
+        }
+    },
+};
+
+// The user defined variables, they can be overridden with environment variables
+const vars = {
+	host: __ENV.host || "shop.example.com",
+};
+
+// CSV Data Set Config "Users"
+const users = new SharedArray("data/users.csv", function() {
+	return parseCSV(open("data/users.csv"), ",", ["username", "password"], true);
+});
+
+// parseCSV returns the rows of a CSV file as objects with the names as keys, or the values of
+// the first line when there aren't any names. Quoted values aren't supported.
+function parseCSV(text, delimiter, names, ignoreFirstLine) {
+	const lines = text.split(/\r?\n/).filter((line) => line !== "");
+	if (!names) {
+		names = lines.shift().split(delimiter);
+	} else if (ignoreFirstLine) {
+		lines.shift();
+	}
+	return lines.map((line) => {
+		const values = line.split(delimiter);
+		const row = {};
+		names.forEach((name, i) => { row[name.trim()] = values[i]; });
+		return row;
+	});
+}
+
+// Thread Group "Browse"
+export function browse() {
+	const v = Object.assign({}, vars, users[(__VU - 1 + __ITER) % users.length]);
+	let res, body;
+
+	// Search products
+	sleep((1000 + Math.random() * 2000) / 1000);
+	res = http.get(`https://${v.host}/search?q=red+shoes&page=${Math.floor(1 + Math.random() * (5 - 1 + 1))}`, {
+		headers: {
+			"Accept": "application/json",
+		},
+		tags: { name: "Search products" },
+	});
+	check(res, {
+		"Status 200": (r) => String(r.status) === "200",
+		"No errors": (r) => !(r.body.includes("error")) && !(r.body.includes("exception")),
+	});
+	v.productId = res.json("results.0.id");
+	if (v.productId === undefined) { v.productId = "1"; }
+
+	group("Checkout", function() {
+		// Login
+		sleep((1000 + Math.random() * 2000) / 1000);
+		body = {
+			"user": `${v.username}`,
+			"password": `${v.password}`,
+		};
+		res = http.post(`https://${v.host}/login`, body, {
+			headers: {
+				"Accept": "application/json",
+			},
+			tags: { name: "Login" },
+		});
+		check(res, {"status is not an error": (r) => r.status < 400});
+		v.token = (res.body.match(new RegExp("\"token\":\"([^\"]+)\"")) || [])[1] || "NOT_FOUND";
+
+		// Add items
+		for (let i = 0; i < 3; i++) {
+			// Add to cart
+			sleep((1000 + Math.random() * 2000) / 1000);
+			body = `{"product": ${v.productId}, "quantity": 1}`;
+			res = http.post(`https://${v.host}/cart`, body, {
+				headers: {
+					"Accept": "application/json",
+					"Authorization": `Bearer ${v.token}`,
+					"Content-Type": "application/json",
+				},
+				tags: { name: "Add to cart" },
+			});
+			check(res, {
+				"Fast enough": (r) => r.timings.duration <= 500,
+				"status is not an error": (r) => r.status < 400,
+			});
+		}
+	});
+
+	// WARNING: the JSR223PostProcessor "Log" isn't converted, port its script by hand:
+	//   log.info("done")
+}
+
+// Thread Group "Health check"
+// WARNING: the ramp-up of 1s isn't converted, the VUs start at once.
+export function healthCheck() {
+	const v = Object.assign({}, vars, users[(__VU - 1 + __ITER) % users.length]);
+	let res, body;
+
+	// Health
+	sleep(1.5);
+	res = http.get("http://status.example.com:8080/health", {
+		headers: {
+			"Accept": "application/json",
+		},
+		tags: { name: "Health" },
+	});
+	check(res, {
+		"Not empty": (r) => r.body.length > 0,
+		"status is not an error": (r) => r.status < 400,
+	});
+}
+
+// setUp Thread Group
+export function setup() {
+	const v = Object.assign({}, vars);
+	let res, body;
+
+	// Reset catalog
+	res = http.post(`https://${v.host}/admin/reset`, null, {
+		headers: {
+			"Accept": "application/json",
+		},
+		tags: { name: "Reset catalog" },
+	});
+	check(res, {"status is not an error": (r) => r.status < 400});
+}
@@ -0,0 +1,430 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package openapi converts OpenAPI 3 documents to k6 script skeletons, with a request with example
+// values for each operation, grouped by their tags.
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.k6.io/k6/converter/internal/jsgen"
+	"go.k6.io/k6/lib"
+)
+
+var methods = []string{"get", "post", "put", "patch", "delete", "head", "options", "trace"} //nolint:gochecknoglobals
+
+type operation struct {
+	method, path string
+	op           map[string]interface{}
+	params       []map[string]interface{}
+}
+
+// Convert returns a k6 script with a request for each of the operations of the document.
+//nolint:funlen
+func Convert(
+	d *Document, options lib.Options, minSleep, maxSleep uint, enableChecks, returnOnFailedCheck bool,
+) (result string, convertErr error) {
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+
+	if returnOnFailedCheck && !enableChecks {
+		return "", fmt.Errorf("return on failed check requires --enable-status-code-checks")
+	}
+
+	groups, order := d.operations()
+	schemes := d.usedSecuritySchemes(groups)
+
+	imports := "group, sleep"
+	if enableChecks {
+		imports = "group, check, sleep"
+	}
+	jsgen.Fprintf(w, "import { %s } from 'k6';\n", imports)
+	jsgen.Fprint(w, "import http from 'k6/http';\n")
+	for _, s := range schemes {
+		if s.basic {
+			jsgen.Fprint(w, "import encoding from 'k6/encoding';\n")
+			break
+		}
+	}
+	jsgen.Fprint(w, "\n")
+
+	if title := str(d.root, "info", "title"); title != "" {
+		jsgen.Fprintf(w, "// %s\n", title)
+	}
+	if version := str(d.root, "info", "version"); version != "" {
+		jsgen.Fprintf(w, "// Version: %s\n", version)
+	}
+	jsgen.Fprint(w, "// Generated from an OpenAPI document, replace the example values with real ones.\n")
+
+	jsgen.Fprint(w, "\nexport let options = {\n")
+	options.ForEachSpecified("json", func(key string, val interface{}) {
+		if valJSON, err := json.MarshalIndent(val, "    ", "    "); err != nil {
+			convertErr = err
+		} else {
+			jsgen.Fprintf(w, "    %s: %s,\n", key, valJSON)
+		}
+	})
+	if convertErr != nil {
+		return "", convertErr
+	}
+	jsgen.Fprint(w, "};\n\n")
+
+	jsgen.Fprintf(w, "const BASE_URL = __ENV.BASE_URL || %q;\n", d.BaseURL())
+	if len(schemes) > 0 {
+		jsgen.Fprint(w, "\n// The credentials of the security schemes, from the environment variables\n")
+	}
+	for _, s := range schemes {
+		for _, v := range s.variables {
+			jsgen.Fprintf(w, "const %s = __ENV.%s || %q;\n", v, v, "<"+strings.ToLower(strings.ReplaceAll(v, "_", " "))+">")
+		}
+	}
+
+	jsgen.Fprint(w, "\nexport default function() {\n")
+	for _, tag := range order {
+		jsgen.Fprintf(w, "\tgroup(%q, function() {\n", tag)
+		jsgen.Fprint(w, "\t\tlet res, body;\n")
+		for i, o := range groups[tag] {
+			if i > 0 {
+				jsgen.Fprint(w, "\n")
+			}
+			d.writeRequest(w, o, schemes, enableChecks, returnOnFailedCheck)
+		}
+		jsgen.Fprint(w, "\t});\n\n")
+	}
+	jsgen.Fprintf(w, "\t// Random sleep between %ds and %ds\n", minSleep, maxSleep)
+	jsgen.Fprintf(w, "\tsleep(Math.floor(Math.random()*%d+%d));\n", maxSleep-minSleep, minSleep)
+	jsgen.Fprint(w, "}\n")
+
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// operations returns the operations by their first tag, in the order of the tags of the document
+// and then by path and by method.
+func (d *Document) operations() (map[string][]operation, []string) {
+	groups := make(map[string][]operation)
+	paths := object(d.root, "paths")
+	for _, path := range sortedKeys(paths) {
+		item := d.resolve(paths[path])
+		for _, method := range methods {
+			op := object(item, method)
+			if op == nil {
+				continue
+			}
+			tag := "default"
+			if tags, ok := op["tags"].([]interface{}); ok && len(tags) > 0 {
+				if t, ok := tags[0].(string); ok {
+					tag = t
+				}
+			}
+			groups[tag] = append(groups[tag], operation{
+				method: method,
+				path:   path,
+				op:     op,
+				params: d.parameters(item, op),
+			})
+		}
+	}
+
+	var order []string
+	seen := make(map[string]bool)
+	tags, _ := d.root["tags"].([]interface{})
+	for _, t := range tags {
+		if name := str(t, "name"); groups[name] != nil && !seen[name] {
+			seen[name] = true
+			order = append(order, name)
+		}
+	}
+	remaining := make([]string, 0, len(groups))
+	for name := range groups {
+		if !seen[name] {
+			remaining = append(remaining, name)
+		}
+	}
+	sort.Strings(remaining)
+	return groups, append(order, remaining...)
+}
+
+// parameters returns the parameters of the path item and of the operation, which override them.
+func (d *Document) parameters(item, op map[string]interface{}) []map[string]interface{} {
+	var result []map[string]interface{}
+	index := make(map[string]int)
+	for _, source := range []map[string]interface{}{item, op} {
+		params, _ := source["parameters"].([]interface{})
+		for _, p := range params {
+			param := d.resolve(p)
+			if param == nil {
+				continue
+			}
+			key := str(param, "in") + ":" + str(param, "name")
+			if i, ok := index[key]; ok {
+				result[i] = param
+				continue
+			}
+			index[key] = len(result)
+			result = append(result, param)
+		}
+	}
+	return result
+}
+
+// securityScheme is a security scheme of the document, with the names of the script constants
+// of its credentials.
+type securityScheme struct {
+	name      string
+	scheme    map[string]interface{}
+	variables []string
+	basic     bool
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9]+`) //nolint:gochecknoglobals
+
+func (d *Document) usedSecuritySchemes(groups map[string][]operation) []*securityScheme {
+	definitions := object(d.root, "components", "securitySchemes")
+	used := make(map[string]*securityScheme)
+	for _, operations := range groups {
+		for _, o := range operations {
+			for name := range d.security(o.op) {
+				if _, ok := used[name]; ok {
+					continue
+				}
+				scheme := d.resolve(definitions[name])
+				if scheme == nil {
+					continue
+				}
+				constant := strings.Trim(strings.ToUpper(nonIdentifier.ReplaceAllString(name, "_")), "_")
+				s := &securityScheme{name: name, scheme: scheme}
+				switch {
+				case str(scheme, "type") == "http" && strings.EqualFold(str(scheme, "scheme"), "basic"):
+					s.basic = true
+					s.variables = []string{constant + "_USERNAME", constant + "_PASSWORD"}
+				case str(scheme, "type") == "apiKey":
+					s.variables = []string{constant}
+				default:
+					s.variables = []string{constant + "_TOKEN"}
+				}
+				used[name] = s
+			}
+		}
+	}
+	result := make([]*securityScheme, 0, len(used))
+	for _, s := range used {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result
+}
+
+// security returns the security schemes of the first security requirement of the operation, or
+// of the document if the operation doesn't have its own.
+func (d *Document) security(op map[string]interface{}) map[string]interface{} {
+	requirements, ok := op["security"].([]interface{})
+	if !ok {
+		requirements, _ = d.root["security"].([]interface{})
+	}
+	if len(requirements) == 0 {
+		return nil
+	}
+	return object(requirements[0])
+}
+
+//nolint:funlen,gocognit,cyclop
+func (d *Document) writeRequest(
+	w io.Writer, o operation, schemes []*securityScheme, enableChecks, returnOnFailedCheck bool,
+) {
+	method := strings.ToUpper(o.method)
+	summary := str(o.op, "summary")
+	if summary == "" {
+		summary = str(o.op, "operationId")
+	}
+	if summary != "" {
+		jsgen.Fprintf(w, "\t\t// %s %s: %s\n", method, o.path, strings.Join(strings.Fields(summary), " "))
+	} else {
+		jsgen.Fprintf(w, "\t\t// %s %s\n", method, o.path)
+	}
+
+	path := o.path
+	query := url.Values{}
+	var headers, cookies []string
+	for _, p := range o.params {
+		name := str(p, "name")
+		value := d.parameterExample(p)
+		switch str(p, "in") {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(value))
+		case "query":
+			if p["required"] == true || value != "" && value != "string" {
+				query.Set(name, value)
+			}
+		case "header":
+			if p["required"] == true {
+				headers = append(headers, fmt.Sprintf("%q: %q", name, value))
+			}
+		case "cookie":
+			if p["required"] == true {
+				cookies = append(cookies, fmt.Sprintf("%q: %q", name, value))
+			}
+		}
+	}
+	target := jsgen.EscapeTemplate(path)
+	if encoded := query.Encode(); encoded != "" {
+		target += "?" + jsgen.EscapeTemplate(encoded)
+	}
+
+	security := d.security(o.op)
+	for _, s := range schemes {
+		if _, ok := security[s.name]; !ok {
+			continue
+		}
+		switch {
+		case s.basic:
+			headers = append(headers, fmt.Sprintf(
+				"\"Authorization\": `Basic ${encoding.b64encode(%s + \":\" + %s)}`", s.variables[0], s.variables[1]))
+		case str(s.scheme, "type") == "apiKey":
+			name := str(s.scheme, "name")
+			switch str(s.scheme, "in") {
+			case "query":
+				separator := "?"
+				if strings.Contains(target, "?") {
+					separator = "&"
+				}
+				target += separator + jsgen.EscapeTemplate(url.QueryEscape(name)) + "=${" + s.variables[0] + "}"
+			case "cookie":
+				cookies = append(cookies, fmt.Sprintf("%q: %s", name, s.variables[0]))
+			default:
+				headers = append(headers, fmt.Sprintf("%q: %s", name, s.variables[0]))
+			}
+		default:
+			headers = append(headers, fmt.Sprintf("\"Authorization\": `Bearer ${%s}`", s.variables[0]))
+		}
+	}
+
+	body := "null"
+	if requestBody := d.resolve(o.op["requestBody"]); requestBody != nil {
+		content := object(requestBody, "content")
+		if contentType, media := pickMediaType(content); contentType != "" {
+			example := d.mediaExample(media)
+			exampleJSON, _ := json.MarshalIndent(example, "\t\t", "\t")
+			switch {
+			case strings.Contains(contentType, "json"):
+				jsgen.Fprintf(w, "\t\tbody = %s;\n", exampleJSON)
+				body = "JSON.stringify(body)"
+				headers = append(headers, fmt.Sprintf("\"Content-Type\": %q", contentType))
+			case contentType == "application/x-www-form-urlencoded" || contentType == "multipart/form-data":
+				// k6 encodes the objects as forms, and sets the content type with the boundary
+				jsgen.Fprintf(w, "\t\tbody = %s;\n", exampleJSON)
+				body = "body"
+			default:
+				if s, ok := example.(string); ok {
+					jsgen.Fprintf(w, "\t\tbody = %q;\n", s)
+				} else {
+					jsgen.Fprintf(w, "\t\tbody = %q;\n", string(exampleJSON))
+				}
+				body = "body"
+				headers = append(headers, fmt.Sprintf("\"Content-Type\": %q", contentType))
+			}
+		}
+	}
+
+	var params []string
+	if len(headers) > 0 {
+		params = append(params, fmt.Sprintf("headers: {\n\t\t\t\t%s,\n\t\t\t}", strings.Join(headers, ",\n\t\t\t\t")))
+	}
+	if len(cookies) > 0 {
+		params = append(params, fmt.Sprintf("cookies: {\n\t\t\t\t%s,\n\t\t\t}", strings.Join(cookies, ",\n\t\t\t\t")))
+	}
+	params = append(params, fmt.Sprintf("tags: { name: %q }", method+" "+o.path))
+	paramsJS := fmt.Sprintf("{\n\t\t\t%s,\n\t\t}", strings.Join(params, ",\n\t\t\t"))
+
+	address := "`${BASE_URL}" + target + "`"
+	switch o.method {
+	case "get", "head":
+		jsgen.Fprintf(w, "\t\tres = http.%s(%s, %s);\n", o.method, address, paramsJS)
+	case "post", "put", "patch", "options":
+		jsgen.Fprintf(w, "\t\tres = http.%s(%s, %s, %s);\n", o.method, address, body, paramsJS)
+	case "delete":
+		jsgen.Fprintf(w, "\t\tres = http.del(%s, %s, %s);\n", address, body, paramsJS)
+	default:
+		jsgen.Fprintf(w, "\t\tres = http.request(%q, %s, %s, %s);\n", method, address, body, paramsJS)
+	}
+
+	if status := expectedStatus(o.op); enableChecks && status != "" {
+		if returnOnFailedCheck {
+			jsgen.Fprintf(w, "\t\tif (!check(res, {\"status is %s\": (r) => r.status === %s })) { return };\n", status, status)
+		} else {
+			jsgen.Fprintf(w, "\t\tcheck(res, {\"status is %s\": (r) => r.status === %s });\n", status, status)
+		}
+	}
+}
+
+// pickMediaType returns the JSON media type of the content if it has one, or the first one.
+func pickMediaType(content map[string]interface{}) (string, interface{}) {
+	types := sortedKeys(content)
+	for _, t := range types {
+		if strings.Contains(t, "json") {
+			return t, content[t]
+		}
+	}
+	if len(types) == 0 {
+		return "", nil
+	}
+	return types[0], content[types[0]]
+}
+
+func (d *Document) mediaExample(media interface{}) interface{} {
+	m := object(media)
+	if e, ok := m["example"]; ok {
+		return e
+	}
+	if examples := object(m, "examples"); len(examples) > 0 {
+		return d.resolve(examples[sortedKeys(examples)[0]])["value"]
+	}
+	return d.example(m["schema"], 0)
+}
+
+// expectedStatus returns the lowest documented success status of the operation.
+func expectedStatus(op map[string]interface{}) string {
+	for _, status := range sortedKeys(object(op, "responses")) {
+		if len(status) == 3 && status[0] == '2' && strings.Trim(status, "0123456789") == "" {
+			return status
+		}
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
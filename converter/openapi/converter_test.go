@@ -0,0 +1,107 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package openapi
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib"
+)
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	src, err := ioutil.ReadFile("testdata/petstore.yaml")
+	require.NoError(t, err)
+	expected, err := ioutil.ReadFile("testdata/petstore.js")
+	require.NoError(t, err)
+
+	assert.True(t, IsDocument(src))
+	doc, err := Decode(bytes.NewReader(src))
+	require.NoError(t, err)
+	assert.Equal(t, "https://petstore.example.com/v1", doc.BaseURL())
+
+	script, err := Convert(doc, lib.Options{}, 20, 40, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), script)
+
+	_, err = Convert(doc, lib.Options{}, 20, 40, false, true)
+	assert.EqualError(t, err, "return on failed check requires --enable-status-code-checks")
+}
+
+func TestExample(t *testing.T) {
+	t.Parallel()
+	doc, err := Decode(strings.NewReader(`
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths: {}
+components:
+  schemas:
+    Base:
+      type: object
+      properties:
+        id: {type: integer, readOnly: true}
+        name: {type: string, example: rex}
+    Pet:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+        - type: object
+          properties:
+            kind: {type: string, enum: [dog, cat]}
+            tags: {type: array, items: {type: string, format: email}}
+            born: {type: string, format: date}
+            weight: {type: number, default: 2.5}
+`))
+	require.NoError(t, err)
+
+	pet := doc.example(map[string]interface{}{"$ref": "#/components/schemas/Pet"}, 0)
+	assert.Equal(t, map[string]interface{}{
+		"name":   "rex",
+		"kind":   "dog",
+		"tags":   []interface{}{"user@example.com"},
+		"born":   "2021-01-01",
+		"weight": 2.5,
+	}, pet)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]string{
+		`swagger: "2.0"`:           "swagger 2.0 documents aren't supported, convert them to OpenAPI 3 first",
+		`info: {title: t}`:         "invalid OpenAPI document, the 'openapi' version 3 property is missing",
+		`openapi: 2.5.0`:           "invalid OpenAPI document, the 'openapi' version 3 property is missing",
+		"openapi: [3\n  - x":       "",
+		`{"log": {"entries": []}}`: "invalid OpenAPI document, the 'openapi' version 3 property is missing",
+	}
+	for src, expected := range testCases {
+		_, err := Decode(strings.NewReader(src))
+		require.Error(t, err, src)
+		if expected != "" {
+			assert.Equal(t, expected, err.Error(), src)
+		}
+	}
+	assert.False(t, IsDocument([]byte(`{"log": {"entries": []}}`)))
+}
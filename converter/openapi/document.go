@@ -0,0 +1,271 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// Document is a parsed OpenAPI 3 document, as the generic values of encoding/json.
+type Document struct {
+	root map[string]interface{}
+}
+
+// Decode reads an OpenAPI 3 document in JSON or YAML.
+func Decode(r io.Reader) (*Document, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(b, &root); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	if _, ok := root["swagger"]; ok {
+		return nil, errors.New("swagger 2.0 documents aren't supported, convert them to OpenAPI 3 first")
+	}
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, errors.New("invalid OpenAPI document, the 'openapi' version 3 property is missing")
+	}
+	return &Document{root: root}, nil
+}
+
+// IsDocument returns whether the data looks like an OpenAPI or Swagger document, for detecting the
+// format of the files to convert.
+func IsDocument(data []byte) bool {
+	b, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return false
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(b, &root); err != nil {
+		return false
+	}
+	_, openapi := root["openapi"]
+	_, swagger := root["swagger"]
+	return openapi || swagger
+}
+
+func object(v interface{}, keys ...string) map[string]interface{} {
+	for _, k := range keys {
+		m, _ := v.(map[string]interface{})
+		v = m[k]
+	}
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func str(v interface{}, keys ...string) string {
+	if len(keys) > 0 {
+		m := object(v, keys[:len(keys)-1]...)
+		v = m[keys[len(keys)-1]]
+	}
+	s, _ := v.(string)
+	return s
+}
+
+// resolve returns the value of the $ref of an object, or the object if it isn't a reference.
+func (d *Document) resolve(v interface{}) map[string]interface{} {
+	for i := 0; i < 32; i++ {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return m
+		}
+		var current interface{} = d.root
+		for _, token := range strings.Split(ref[2:], "/") {
+			if t, err := url.PathUnescape(token); err == nil {
+				token = t
+			}
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			current = object(current)[token]
+		}
+		v = current
+	}
+	return nil
+}
+
+// BaseURL returns the URL of the first server, with the default values of its variables.
+func (d *Document) BaseURL() string {
+	servers, _ := d.root["servers"].([]interface{})
+	if len(servers) == 0 {
+		return ""
+	}
+	server := object(servers[0])
+	u := str(server, "url")
+	for name, variable := range object(server, "variables") {
+		u = strings.ReplaceAll(u, "{"+name+"}", str(variable, "default"))
+	}
+	return strings.TrimSuffix(u, "/")
+}
+
+// example returns an example value of a schema, its example or default value if it has one.
+//nolint:gocognit,cyclop
+func (d *Document) example(v interface{}, depth int) interface{} {
+	schema := d.resolve(v)
+	if schema == nil || depth > 8 {
+		return nil
+	}
+	for _, key := range []string{"example", "default"} {
+		if e, ok := schema[key]; ok {
+			return e
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		subschemas, ok := schema[key].([]interface{})
+		if !ok || len(subschemas) == 0 {
+			continue
+		}
+		if key != "allOf" {
+			return d.example(subschemas[0], depth+1)
+		}
+		merged := map[string]interface{}{}
+		for _, sub := range subschemas {
+			if e, ok := d.example(sub, depth+1).(map[string]interface{}); ok {
+				for k, v := range e {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+
+	t, _ := schema["type"].(string)
+	if t == "" {
+		if types, ok := schema["type"].([]interface{}); ok && len(types) > 0 {
+			t, _ = types[0].(string)
+		} else if _, ok := schema["properties"]; ok {
+			t = "object"
+		} else if _, ok := schema["items"]; ok {
+			t = "array"
+		}
+	}
+	switch t {
+	case "object":
+		result := map[string]interface{}{}
+		for name, property := range object(schema, "properties") {
+			if p := d.resolve(property); p != nil && p["readOnly"] == true {
+				continue
+			}
+			result[name] = d.example(property, depth+1)
+		}
+		return result
+	case "array":
+		if item := d.example(schema["items"], depth+1); item != nil {
+			return []interface{}{item}
+		}
+		return []interface{}{}
+	case "integer":
+		if min, ok := schema["minimum"].(float64); ok {
+			return min
+		}
+		return 1
+	case "number":
+		if min, ok := schema["minimum"].(float64); ok {
+			return min
+		}
+		return 1.5
+	case "boolean":
+		return true
+	case "string":
+		return stringExample(str(schema, "format"))
+	default:
+		return nil
+	}
+}
+
+func stringExample(format string) string {
+	switch format {
+	case "date-time":
+		return "2021-01-01T00:00:00Z"
+	case "date":
+		return "2021-01-01"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	case "uri", "url":
+		return "https://example.com"
+	case "ipv4":
+		return "192.0.2.1"
+	case "ipv6":
+		return "2001:db8::1"
+	case "byte":
+		return "c3RyaW5n"
+	case "password":
+		return "password"
+	default:
+		return "string"
+	}
+}
+
+// parameterExample returns the example value of a parameter as a string.
+func (d *Document) parameterExample(p map[string]interface{}) string {
+	var v interface{}
+	if e, ok := p["example"]; ok {
+		v = e
+	} else if examples := object(p, "examples"); len(examples) > 0 {
+		for _, name := range sortedKeys(examples) {
+			v = d.resolve(examples[name])["value"]
+			break
+		}
+	} else {
+		v = d.example(p["schema"], 0)
+	}
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case nil:
+		return ""
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, e := range v {
+			values[i] = fmt.Sprint(e)
+		}
+		return strings.Join(values, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
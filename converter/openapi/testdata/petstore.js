@@ -0,0 +1,90 @@
+import { group, check, sleep } from 'k6';
+import http from 'k6/http';
+import encoding from 'k6/encoding';
+
+// Pet Store
+// Version: 1.0.0
+// Generated from an OpenAPI document, replace the example values with real ones.
+
+export let options = {
+};
+
+const BASE_URL = __ENV.BASE_URL || "https://petstore.example.com/v1";
+
+// The credentials of the security schemes, from the environment variables
+const API_KEY = __ENV.API_KEY || "<api key>";
+const BASIC_USERNAME = __ENV.BASIC_USERNAME || "<basic username>";
+const BASIC_PASSWORD = __ENV.BASIC_PASSWORD || "<basic password>";
+const BEARER_TOKEN = __ENV.BEARER_TOKEN || "<bearer token>";
+
+export default function() {
+	group("pets", function() {
+		let res, body;
+		// GET /pets: List all pets
+		res = http.get(`${BASE_URL}/pets?limit=10`, {
+			headers: {
+				"X-Request-Id": "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+				"X-API-Key": API_KEY,
+			},
+			tags: { name: "GET /pets" },
+		});
+		check(res, {"status is 200": (r) => r.status === 200 });
+
+		// POST /pets: Create a pet
+		body = {
+			"born": "2021-01-01",
+			"name": "Rex",
+			"owner": {
+				"email": "user@example.com",
+				"verified": true
+			},
+			"tags": [
+				"string"
+			]
+		};
+		res = http.post(`${BASE_URL}/pets`, JSON.stringify(body), {
+			headers: {
+				"Authorization": `Bearer ${BEARER_TOKEN}`,
+				"Content-Type": "application/json",
+			},
+			tags: { name: "POST /pets" },
+		});
+		check(res, {"status is 201": (r) => r.status === 201 });
+
+		// DELETE /pets/{petId}: deletePet
+		res = http.del(`${BASE_URL}/pets/p%201`, null, {
+			tags: { name: "DELETE /pets/{petId}" },
+		});
+		check(res, {"status is 204": (r) => r.status === 204 });
+	});
+
+	group("store", function() {
+		let res, body;
+		// PUT /store/orders
+		body = {
+			"quantity": 1
+		};
+		res = http.put(`${BASE_URL}/store/orders`, body, {
+			headers: {
+				"Authorization": `Basic ${encoding.b64encode(BASIC_USERNAME + ":" + BASIC_PASSWORD)}`,
+			},
+			tags: { name: "PUT /store/orders" },
+		});
+		check(res, {"status is 200": (r) => r.status === 200 });
+	});
+
+	group("default", function() {
+		let res, body;
+		// GET /health
+		res = http.get(`${BASE_URL}/health`, {
+			headers: {
+				"X-API-Key": API_KEY,
+			},
+			tags: { name: "GET /health" },
+		});
+		check(res, {"status is 200": (r) => r.status === 200 });
+	});
+
+	// Random sleep between 20s and 40s
+	sleep(Math.floor(Math.random()*20+20));
+}
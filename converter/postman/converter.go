@@ -0,0 +1,449 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package postman converts Postman collections to k6 scripts, with a group for each folder and
+// the collection and environment variables as script constants.
+package postman
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.k6.io/k6/converter/internal/jsgen"
+	"go.k6.io/k6/lib"
+)
+
+//nolint:gochecknoglobals
+var (
+	variableRef   = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+	identifier    = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+	expectsStatus = regexp.MustCompile(`(?:to\.have\.status|response\.code\)\.to\.(?:eql|equal))\((\d{3})\)`)
+
+	// the JS expressions of the dynamic variables of Postman that have an equivalent
+	dynamicVariables = map[string]string{
+		"$guid":                "faker.uuid()",
+		"$randomUUID":          "faker.uuid()",
+		"$timestamp":           "Math.floor(Date.now() / 1000)",
+		"$isoTimestamp":        "new Date().toISOString()",
+		"$randomInt":           "faker.integer(0, 1000)",
+		"$randomBoolean":       "faker.boolean()",
+		"$randomFirstName":     "faker.firstName()",
+		"$randomLastName":      "faker.lastName()",
+		"$randomFullName":      "faker.name()",
+		"$randomUserName":      "faker.userName()",
+		"$randomEmail":         "faker.email()",
+		"$randomExampleEmail":  "faker.email()",
+		"$randomPhoneNumber":   "faker.phone()",
+		"$randomCompanyName":   "faker.company()",
+		"$randomStreetAddress": "faker.streetAddress()",
+		"$randomCity":          "faker.city()",
+		"$randomCountry":       "faker.country()",
+		"$randomIP":            "faker.ipv4()",
+		"$randomUrl":           "faker.url()",
+		"$randomWord":          "faker.word()",
+		"$randomLoremSentence": "faker.sentence()",
+	}
+)
+
+type converter struct {
+	enableChecks        bool
+	returnOnFailedCheck bool
+
+	// the collection and environment variables, in the order they were defined
+	values    map[string]string
+	variables []string
+	// the variables that are used by the requests but aren't defined
+	undefined map[string]bool
+	// the dynamic variables that don't have an equivalent
+	unsupported map[string]bool
+
+	usesEncoding, usesFaker bool
+}
+
+// Convert returns a k6 script with the requests of the collection, the values of the variables of
+// the environment, if there's one, override the ones of the collection.
+//nolint:funlen
+func Convert(
+	c *Collection, env *Environment, options lib.Options, minSleep, maxSleep uint,
+	enableChecks, returnOnFailedCheck bool,
+) (result string, convertErr error) {
+	if returnOnFailedCheck && !enableChecks {
+		return "", fmt.Errorf("return on failed check requires --enable-status-code-checks")
+	}
+
+	conv := &converter{
+		enableChecks:        enableChecks,
+		returnOnFailedCheck: returnOnFailedCheck,
+		values:              make(map[string]string),
+		undefined:           make(map[string]bool),
+		unsupported:         make(map[string]bool),
+	}
+	for _, v := range c.Variable {
+		if !v.Disabled {
+			conv.define(v.Key, v.Value)
+		}
+	}
+	if env != nil {
+		for _, v := range env.Values {
+			if v.Enabled == nil || *v.Enabled {
+				conv.define(v.Key, v.Value)
+			}
+		}
+	}
+
+	// the requests are written first, to know the variables and the modules that they use
+	var requests bytes.Buffer
+	conv.writeScriptWarning(&requests, 1, "collection", c.Event)
+	if requests.Len() > 0 {
+		jsgen.Fprint(&requests, "\n")
+	}
+	conv.writeItems(&requests, c.Item, 1, c.Auth)
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+
+	imports := "group, sleep"
+	if enableChecks {
+		imports = "group, check, sleep"
+	}
+	jsgen.Fprintf(w, "import { %s } from 'k6';\n", imports)
+	jsgen.Fprint(w, "import http from 'k6/http';\n")
+	if conv.usesEncoding {
+		jsgen.Fprint(w, "import encoding from 'k6/encoding';\n")
+	}
+	if conv.usesFaker {
+		jsgen.Fprint(w, "import faker from 'k6/faker';\n")
+	}
+	jsgen.Fprint(w, "\n")
+
+	if c.Info.Name != "" {
+		jsgen.Fprintf(w, "// %s\n", c.Info.Name)
+	}
+	if env != nil && env.Name != "" {
+		jsgen.Fprintf(w, "// Converted from a Postman collection, with the %q environment.\n", env.Name)
+	} else {
+		jsgen.Fprint(w, "// Converted from a Postman collection.\n")
+	}
+	if len(conv.unsupported) > 0 {
+		jsgen.Fprintf(w, "// WARNING: the dynamic variables %s aren't supported, replace them by hand.\n",
+			strings.Join(sortedKeys(conv.unsupported), ", "))
+	}
+
+	jsgen.Fprint(w, "\nexport let options = {\n")
+	options.ForEachSpecified("json", func(key string, val interface{}) {
+		if valJSON, err := json.MarshalIndent(val, "    ", "    "); err != nil {
+			convertErr = err
+		} else {
+			jsgen.Fprintf(w, "    %s: %s,\n", key, valJSON)
+		}
+	})
+	if convertErr != nil {
+		return "", convertErr
+	}
+	jsgen.Fprint(w, "};\n\n")
+
+	names := append(conv.variables, sortedKeys(conv.undefined)...) //nolint:gocritic
+	if len(names) > 0 {
+		jsgen.Fprint(w, "// The variables of the collection, they can be overridden with environment variables\n")
+		jsgen.Fprint(w, "const vars = {\n")
+		for _, name := range names {
+			if identifier.MatchString(name) {
+				jsgen.Fprintf(w, "\t%s: __ENV.%s || %q,\n", name, name, conv.values[name])
+			} else {
+				jsgen.Fprintf(w, "\t%q: __ENV[%q] || %q,\n", name, name, conv.values[name])
+			}
+		}
+		jsgen.Fprint(w, "};\n\n")
+	}
+
+	jsgen.Fprint(w, "export default function() {\n")
+	jsgen.Fprint(w, "\tlet res, body;\n\n")
+	jsgen.Fprint(w, requests.String())
+	jsgen.Fprintf(w, "\t// Random sleep between %ds and %ds\n", minSleep, maxSleep)
+	jsgen.Fprintf(w, "\tsleep(Math.floor(Math.random()*%d+%d));\n", maxSleep-minSleep, minSleep)
+	jsgen.Fprint(w, "}\n")
+
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (conv *converter) define(name, value string) {
+	if _, ok := conv.values[name]; !ok {
+		conv.variables = append(conv.variables, name)
+	}
+	conv.values[name] = value
+}
+
+// writeItems writes the requests of the items, and a group for each folder with its requests.
+func (conv *converter) writeItems(w io.Writer, items []*Item, depth int, auth *Auth) {
+	indent := strings.Repeat("\t", depth)
+	for _, item := range items {
+		if !item.IsFolder() {
+			conv.writeRequest(w, item, depth, auth)
+			jsgen.Fprint(w, "\n")
+			continue
+		}
+		folderAuth := auth
+		if item.Auth != nil {
+			folderAuth = item.Auth
+		}
+		jsgen.Fprintf(w, "%sgroup(%q, function() {\n", indent, item.Name)
+		conv.writeScriptWarning(w, depth+1, "folder", item.Event)
+		var content bytes.Buffer
+		conv.writeItems(&content, item.Item, depth+1, folderAuth)
+		// no blank line after the last request of the group
+		jsgen.Fprint(w, strings.TrimSuffix(content.String(), "\n"))
+		jsgen.Fprintf(w, "%s});\n\n", indent)
+	}
+}
+
+// writeScriptWarning writes the scripts that can't be converted as comments, so they can be
+// ported by hand.
+func (conv *converter) writeScriptWarning(w io.Writer, depth int, of string, events []Event) {
+	indent := strings.Repeat("\t", depth)
+	for _, e := range events {
+		code := e.Script.Code()
+		if e.Disabled || code == "" {
+			continue
+		}
+		kind := "pre-request"
+		if e.Listen == "test" {
+			kind = "test"
+		}
+		jsgen.Fprintf(w, "%s// WARNING: the %s script of the %s isn't converted, port it by hand:\n", indent, kind, of)
+		for _, line := range strings.Split(code, "\n") {
+			jsgen.Fprintf(w, "%s//   %s\n", indent, strings.TrimRight(line, " \t\r"))
+		}
+	}
+}
+
+//nolint:funlen,gocognit,cyclop
+func (conv *converter) writeRequest(w io.Writer, item *Item, depth int, auth *Auth) {
+	indent := strings.Repeat("\t", depth)
+	req := item.Request
+	if req.Auth != nil {
+		auth = req.Auth
+	}
+	method := strings.ToUpper(req.Method)
+
+	jsgen.Fprintf(w, "%s// %s\n", indent, strings.Join(strings.Fields(item.Name), " "))
+	conv.writeScriptWarning(w, depth, "request", item.Event)
+
+	address := req.URL.String()
+	for _, v := range req.URL.Variable {
+		address = strings.ReplaceAll(address, "/:"+v.Key, "/"+v.Value)
+	}
+	if !strings.Contains(address, "://") && !strings.HasPrefix(address, "{{") {
+		// Postman defaults to http like browsers do
+		address = "http://" + address
+	}
+
+	var headers []string
+	hasContentType := false
+	for _, h := range req.Header {
+		if h.Disabled {
+			continue
+		}
+		if strings.EqualFold(h.Key, "Content-Type") {
+			hasContentType = true
+		}
+		headers = append(headers, fmt.Sprintf("%s: %s", conv.template(h.Key), conv.template(h.Value)))
+	}
+
+	if auth != nil {
+		switch auth.Type {
+		case "noauth", "":
+		case "bearer":
+			headers = append(headers, fmt.Sprintf(`"Authorization": %s`,
+				conv.template("Bearer "+auth.Attribute("token"))))
+		case "basic":
+			conv.usesEncoding = true
+			headers = append(headers, fmt.Sprintf(`"Authorization": "Basic " + encoding.b64encode(%s)`,
+				conv.template(auth.Attribute("username")+":"+auth.Attribute("password"))))
+		case "apikey":
+			key, value := auth.Attribute("key"), auth.Attribute("value")
+			if auth.Attribute("in") == "query" {
+				separator := "?"
+				if strings.Contains(address, "?") {
+					separator = "&"
+				}
+				address += separator + key + "=" + value
+			} else {
+				headers = append(headers, fmt.Sprintf("%s: %s", conv.template(key), conv.template(value)))
+			}
+		default:
+			jsgen.Fprintf(w, "%s// WARNING: the %s auth isn't converted, add its credentials by hand\n", indent, auth.Type)
+		}
+	}
+
+	body := "null"
+	if b := req.Body; b != nil && !b.Disabled { //nolint:nestif
+		switch b.Mode {
+		case "raw":
+			if b.Raw != "" {
+				jsgen.Fprintf(w, "%sbody = %s;\n", indent, conv.template(b.Raw))
+				body = "body"
+				if !hasContentType && b.Options != nil && b.Options.Raw != nil && b.Options.Raw.Language == "json" {
+					headers = append(headers, `"Content-Type": "application/json"`)
+				}
+			}
+		case "urlencoded", "formdata":
+			fields := b.URLEncoded
+			if b.Mode == "formdata" {
+				fields = b.FormData
+			}
+			var values []string
+			for _, f := range fields {
+				if f.Disabled {
+					continue
+				}
+				if f.Type == "file" {
+					jsgen.Fprintf(w, "%s// WARNING: the %q file field isn't converted, add it with http.file() by hand\n",
+						indent, f.Key)
+					continue
+				}
+				values = append(values, fmt.Sprintf("%s\t%s: %s,\n", indent, conv.template(f.Key), conv.template(f.Value)))
+			}
+			// k6 encodes the objects as forms, and sets the multipart content type with the boundary
+			jsgen.Fprintf(w, "%sbody = {\n%s%s};\n", indent, strings.Join(values, ""), indent)
+			body = "body"
+		case "graphql":
+			if b.GraphQL != nil {
+				variables := strings.TrimSpace(b.GraphQL.Variables)
+				if variables == "" {
+					variables = "{}"
+				}
+				jsgen.Fprintf(w, "%sbody = JSON.stringify({\n%s\tquery: %s,\n%s\tvariables: JSON.parse(%s),\n%s});\n",
+					indent, indent, conv.template(b.GraphQL.Query), indent, conv.template(variables), indent)
+				body = "body"
+				if !hasContentType {
+					headers = append(headers, `"Content-Type": "application/json"`)
+				}
+			}
+		case "file":
+			jsgen.Fprintf(w, "%s// WARNING: the file body isn't converted, open() it in the init context by hand\n", indent)
+		}
+	}
+
+	var params []string
+	if len(headers) > 0 {
+		params = append(params, fmt.Sprintf("headers: {\n%s\t\t%s,\n%s\t}",
+			indent, strings.Join(headers, ",\n"+indent+"\t\t"), indent))
+	}
+	params = append(params, fmt.Sprintf("tags: { name: %q }", item.Name))
+	paramsJS := fmt.Sprintf("{\n%s\t%s,\n%s}", indent, strings.Join(params, ",\n"+indent+"\t"), indent)
+
+	target := conv.template(address)
+	switch {
+	case (method == "GET" || method == "HEAD") && body == "null":
+		jsgen.Fprintf(w, "%sres = http.%s(%s, %s);\n", indent, strings.ToLower(method), target, paramsJS)
+	case method == "POST" || method == "PUT" || method == "PATCH" || method == "OPTIONS":
+		jsgen.Fprintf(w, "%sres = http.%s(%s, %s, %s);\n", indent, strings.ToLower(method), target, body, paramsJS)
+	case method == "DELETE":
+		jsgen.Fprintf(w, "%sres = http.del(%s, %s, %s);\n", indent, target, body, paramsJS)
+	default:
+		jsgen.Fprintf(w, "%sres = http.request(%q, %s, %s, %s);\n", indent, method, target, body, paramsJS)
+	}
+
+	if status := expectedStatus(item); conv.enableChecks && status != "" {
+		if conv.returnOnFailedCheck {
+			jsgen.Fprintf(w, "%sif (!check(res, {\"status is %s\": (r) => r.status === %s })) { return };\n",
+				indent, status, status)
+		} else {
+			jsgen.Fprintf(w, "%scheck(res, {\"status is %s\": (r) => r.status === %s });\n", indent, status, status)
+		}
+	}
+}
+
+// expectedStatus returns the status that the test script of the request checks for, or the one
+// of its first saved example response.
+func expectedStatus(item *Item) string {
+	for _, e := range item.Event {
+		if e.Listen != "test" || e.Disabled {
+			continue
+		}
+		if m := expectsStatus.FindStringSubmatch(e.Script.Code()); m != nil {
+			return m[1]
+		}
+	}
+	for _, r := range item.Response {
+		if r.Code != 0 {
+			return fmt.Sprint(r.Code)
+		}
+	}
+	return ""
+}
+
+// template returns a JS string literal of the text, or a template literal with the {{variables}}
+// of the text replaced with their values.
+func (conv *converter) template(s string) string {
+	if !variableRef.MatchString(s) {
+		return fmt.Sprintf("%q", s)
+	}
+	var b strings.Builder
+	b.WriteString("`")
+	last := 0
+	for _, m := range variableRef.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(jsgen.EscapeTemplate(s[last:m[0]]))
+		last = m[1]
+		name := strings.TrimSpace(s[m[2]:m[3]])
+		if strings.HasPrefix(name, "$") {
+			expression, ok := dynamicVariables[name]
+			if !ok {
+				conv.unsupported["{{"+name+"}}"] = true
+				b.WriteString(jsgen.EscapeTemplate(s[m[0]:m[1]]))
+				continue
+			}
+			if strings.HasPrefix(expression, "faker.") {
+				conv.usesFaker = true
+			}
+			b.WriteString("${" + expression + "}")
+			continue
+		}
+		if _, ok := conv.values[name]; !ok {
+			conv.undefined[name] = true
+		}
+		if identifier.MatchString(name) {
+			b.WriteString("${vars." + name + "}")
+		} else {
+			b.WriteString(fmt.Sprintf("${vars[%q]}", name))
+		}
+	}
+	b.WriteString(jsgen.EscapeTemplate(s[last:]))
+	b.WriteString("`")
+	return b.String()
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,108 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package postman
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib"
+)
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	src, err := ioutil.ReadFile("testdata/collection.json")
+	require.NoError(t, err)
+	expected, err := ioutil.ReadFile("testdata/collection.js")
+	require.NoError(t, err)
+
+	assert.True(t, IsCollection(src))
+	c, err := Decode(bytes.NewReader(src))
+	require.NoError(t, err)
+	envFile, err := os.Open("testdata/staging.postman_environment.json")
+	require.NoError(t, err)
+	defer func() { _ = envFile.Close() }()
+	env, err := DecodeEnvironment(envFile)
+	require.NoError(t, err)
+
+	script, err := Convert(c, env, lib.Options{}, 20, 40, true, true)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), script)
+
+	_, err = Convert(c, env, lib.Options{}, 20, 40, false, true)
+	assert.EqualError(t, err, "return on failed check requires --enable-status-code-checks")
+}
+
+func TestConvertWithoutEnvironment(t *testing.T) {
+	t.Parallel()
+	c, err := Decode(strings.NewReader(`{
+		"info": {"name": "Minimal", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+		"item": [{"name": "Home", "request": {"method": "DELETE", "url": "{{host}}/"}}]
+	}`))
+	require.NoError(t, err)
+
+	script, err := Convert(c, nil, lib.Options{}, 1, 2, false, false)
+	require.NoError(t, err)
+	assert.Contains(t, script, "// Converted from a Postman collection.\n")
+	assert.Contains(t, script, "\thost: __ENV.host || \"\",\n")
+	assert.Contains(t, script, "res = http.del(`${vars.host}/`, null, {\n")
+	assert.NotContains(t, script, "check(")
+}
+
+func TestURL(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]string{
+		`"https://example.com/a?b=c"`: "https://example.com/a?b=c",
+		`{"raw": "{{base}}/x"}`:       "{{base}}/x",
+		`{"protocol": "https", "host": ["api", "example", "com"], "port": "8443", "path": ["v1", "users"],
+		  "query": [{"key": "a", "value": "1"}, {"key": "b", "value": "2", "disabled": true}]}`: "https://api.example.com:8443/v1/users?a=1",
+		`{"host": "{{base}}", "path": "/users/"}`: "{{base}}/users",
+	}
+	for src, expected := range testCases {
+		var u URL
+		require.NoError(t, u.UnmarshalJSON([]byte(src)), src)
+		assert.Equal(t, expected, u.String(), src)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]string{
+		`{"info": {"schema": "https://schema.getpostman.com/json/collection/v2.0.0/collection.json"}}`: "only the Postman collections in the v2.1 format are supported, " +
+			"export the collection again as Collection v2.1",
+		`{"id": "1", "name": "v1", "requests": []}`: "only the Postman collections in the v2.1 format are supported, " +
+			"export the collection again as Collection v2.1",
+		`{"info": `: "invalid Postman collection: unexpected EOF",
+	}
+	for src, expected := range testCases {
+		_, err := Decode(strings.NewReader(src))
+		require.Error(t, err, src)
+		assert.Equal(t, expected, err.Error(), src)
+	}
+	assert.False(t, IsCollection([]byte(`{"openapi": "3.0.0", "info": {"title": "t"}}`)))
+	assert.False(t, IsCollection([]byte(`{"log": {"entries": []}}`)))
+}
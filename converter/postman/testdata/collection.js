@@ -0,0 +1,120 @@
+import { group, check, sleep } from 'k6';
+import http from 'k6/http';
+import encoding from 'k6/encoding';
+import faker from 'k6/faker';
+
+// Shop API
+// Converted from a Postman collection, with the "Staging" environment.
+// WARNING: the dynamic variables {{$randomColor}} aren't supported, replace them by hand.
+
+export let options = {
+};
+
+// The variables of the collection, they can be overridden with environment variables
+const vars = {
+	baseUrl: __ENV.baseUrl || "https://staging.shop.example.com",
+	token: __ENV.token || "collection-token",
+	"page-size": __ENV["page-size"] || "20",
+	user: __ENV.user || "tester",
+	apiKey: __ENV.apiKey || "",
+	stars: __ENV.stars || "",
+};
+
+export default function() {
+	let res, body;
+
+	// WARNING: the pre-request script of the collection isn't converted, port it by hand:
+	//   pm.variables.set("start", Date.now());
+
+	// Status
+	res = http.get(`${vars.baseUrl}/status`, {
+		headers: {
+			"Authorization": `Bearer ${vars.token}`,
+		},
+		tags: { name: "Status" },
+	});
+
+	group("Products", function() {
+		// List products
+		// WARNING: the test script of the request isn't converted, port it by hand:
+		//   pm.test("Status code is 200", function () {
+		//       pm.response.to.have.status(200);
+		//   });
+		res = http.get(`${vars.baseUrl}/products?limit=${vars["page-size"]}`, {
+			headers: {
+				"Accept": "application/json",
+				"Authorization": `Bearer ${vars.token}`,
+			},
+			tags: { name: "List products" },
+		});
+		if (!check(res, {"status is 200": (r) => r.status === 200 })) { return };
+
+		// Get product
+		res = http.get(`${vars.baseUrl}/products/42`, {
+			headers: {
+				"Authorization": `Bearer ${vars.token}`,
+			},
+			tags: { name: "Get product" },
+		});
+		if (!check(res, {"status is 200": (r) => r.status === 200 })) { return };
+
+		group("Reviews", function() {
+			// Add review
+			// WARNING: the pre-request script of the request isn't converted, port it by hand:
+			//   const stars = 5;
+			//   pm.variables.set("stars", stars);
+			body = `{
+	"id": "${faker.uuid()}",
+	"stars": ${vars.stars},
+	"at": ${Math.floor(Date.now() / 1000)},
+	"color": "{{$randomColor}}"
+}`;
+			res = http.post(`${vars.baseUrl}/products/42/reviews`, body, {
+				headers: {
+					"Authorization": `Bearer ${vars.token}`,
+					"Content-Type": "application/json",
+				},
+				tags: { name: "Add review" },
+			});
+			if (!check(res, {"status is 201": (r) => r.status === 201 })) { return };
+		});
+	});
+
+	group("Account", function() {
+		// Login
+		body = {
+			"user": `${vars.user}`,
+			"remember": "true",
+		};
+		res = http.post(`${vars.baseUrl}/login`, body, {
+			headers: {
+				"Authorization": "Basic " + encoding.b64encode(`${vars.user}:secret`),
+			},
+			tags: { name: "Login" },
+		});
+
+		// Upload avatar
+		// WARNING: the "file" file field isn't converted, add it with http.file() by hand
+		body = {
+			"name": "avatar",
+		};
+		res = http.put(`${vars.baseUrl}/account/avatar`, body, {
+			tags: { name: "Upload avatar" },
+		});
+
+		// Search
+		body = JSON.stringify({
+			query: "query { orders(first: 5) { id } }",
+			variables: JSON.parse("{}"),
+		});
+		res = http.post(`http://shop.example.com/graphql?api_key=${vars.apiKey}`, body, {
+			headers: {
+				"Content-Type": "application/json",
+			},
+			tags: { name: "Search" },
+		});
+	});
+
+	// Random sleep between 20s and 40s
+	sleep(Math.floor(Math.random()*20+20));
+}
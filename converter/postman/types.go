@@ -0,0 +1,352 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package postman
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Collection is the top level object of a Postman collection, in the v2.1 format.
+type Collection struct {
+	// Info has the name and the schema of the collection.
+	Info Info `json:"info"`
+	// Item is the list of the requests and the folders of the collection.
+	Item []*Item `json:"item"`
+	// Event has the pre-request and test scripts of the collection.
+	Event []Event `json:"event,omitempty"`
+	// Variable is the list of the collection variables.
+	Variable []Variable `json:"variable,omitempty"`
+	// Auth is the authentication of the requests that don't set their own.
+	Auth *Auth `json:"auth,omitempty"`
+}
+
+// Info describes the collection.
+type Info struct {
+	PostmanID   string      `json:"_postman_id,omitempty"`
+	Name        string      `json:"name"`
+	Description Description `json:"description,omitempty"`
+	// Schema is the URL of the JSON schema of the collection format.
+	Schema string `json:"schema"`
+}
+
+// Item is a request or, when it has items of its own, a folder.
+type Item struct {
+	Name        string      `json:"name"`
+	Description Description `json:"description,omitempty"`
+	// Item is the list of the requests and the folders of a folder.
+	Item []*Item `json:"item,omitempty"`
+	// Request is the request of the item, folders don't have one.
+	Request *Request `json:"request,omitempty"`
+	// Response is the list of the saved example responses of the request.
+	Response []Response `json:"response,omitempty"`
+	Event    []Event    `json:"event,omitempty"`
+	Auth     *Auth      `json:"auth,omitempty"`
+}
+
+// IsFolder returns whether the item is a folder instead of a request.
+func (i *Item) IsFolder() bool {
+	return i.Request == nil
+}
+
+// Request is an HTTP request, Postman allows it to be just its URL.
+type Request struct {
+	Method      string      `json:"method"`
+	URL         URL         `json:"url"`
+	Header      []KeyValue  `json:"header,omitempty"`
+	Body        *Body       `json:"body,omitempty"`
+	Auth        *Auth       `json:"auth,omitempty"`
+	Description Description `json:"description,omitempty"`
+}
+
+// UnmarshalJSON decodes a request object or a URL string.
+func (r *Request) UnmarshalJSON(data []byte) error {
+	var raw string
+	if json.Unmarshal(data, &raw) == nil {
+		*r = Request{Method: "GET", URL: URL{Raw: raw}}
+		return nil
+	}
+	type request Request
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return err
+	}
+	*r = Request(req)
+	if r.Method == "" {
+		r.Method = "GET"
+	}
+	return nil
+}
+
+// URL is the URL of a request, Postman allows it to be just the raw string.
+type URL struct {
+	Raw      string          `json:"raw"`
+	Protocol string          `json:"protocol,omitempty"`
+	Host     json.RawMessage `json:"host,omitempty"`
+	Port     string          `json:"port,omitempty"`
+	Path     json.RawMessage `json:"path,omitempty"`
+	Query    []KeyValue      `json:"query,omitempty"`
+	Variable []KeyValue      `json:"variable,omitempty"`
+}
+
+// UnmarshalJSON decodes a URL object or a raw URL string.
+func (u *URL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if json.Unmarshal(data, &raw) == nil {
+		*u = URL{Raw: raw}
+		return nil
+	}
+	type url URL
+	var v url
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*u = URL(v)
+	return nil
+}
+
+// String returns the raw URL, or the URL built from its parts when the raw one is missing.
+func (u URL) String() string {
+	if u.Raw != "" {
+		return u.Raw
+	}
+	var b strings.Builder
+	if u.Protocol != "" {
+		b.WriteString(u.Protocol + "://")
+	}
+	b.WriteString(strings.Join(segments(u.Host), "."))
+	if u.Port != "" {
+		b.WriteString(":" + u.Port)
+	}
+	if path := segments(u.Path); len(path) > 0 {
+		b.WriteString("/" + strings.Join(path, "/"))
+	}
+	separator := "?"
+	for _, q := range u.Query {
+		if q.Disabled {
+			continue
+		}
+		b.WriteString(separator + q.Key + "=" + q.Value)
+		separator = "&"
+	}
+	return b.String()
+}
+
+// segments returns the segments of a host or a path, which can be a string or a list of them.
+func segments(data json.RawMessage) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return []string{strings.Trim(s, "/")}
+	}
+	var list []interface{}
+	_ = json.Unmarshal(data, &list)
+	result := make([]string, 0, len(list))
+	for _, v := range list {
+		switch v := v.(type) {
+		case string:
+			result = append(result, v)
+		case map[string]interface{}:
+			result = append(result, fmt.Sprint(v["value"]))
+		}
+	}
+	return result
+}
+
+// KeyValue is a header, a query parameter, a form field or an auth attribute.
+type KeyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+	// Type is "text" or "file" for the form fields.
+	Type string `json:"type,omitempty"`
+	// Src is the path of the file of the file form fields.
+	Src json.RawMessage `json:"src,omitempty"`
+}
+
+// UnmarshalJSON decodes a key-value pair, with any non-string values as their JSON.
+func (kv *KeyValue) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Key      string          `json:"key"`
+		Value    json.RawMessage `json:"value"`
+		Disabled bool            `json:"disabled"`
+		Type     string          `json:"type"`
+		Src      json.RawMessage `json:"src"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*kv = KeyValue{Key: v.Key, Value: rawString(v.Value), Disabled: v.Disabled, Type: v.Type, Src: v.Src}
+	return nil
+}
+
+// Body is the body of a request, the mode is one of raw, urlencoded, formdata, file or graphql.
+type Body struct {
+	Mode       string     `json:"mode"`
+	Raw        string     `json:"raw,omitempty"`
+	URLEncoded []KeyValue `json:"urlencoded,omitempty"`
+	FormData   []KeyValue `json:"formdata,omitempty"`
+	File       *struct {
+		Src string `json:"src"`
+	} `json:"file,omitempty"`
+	GraphQL *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql,omitempty"`
+	Options *struct {
+		Raw *struct {
+			Language string `json:"language"`
+		} `json:"raw,omitempty"`
+	} `json:"options,omitempty"`
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// Auth is the authentication of a request, a folder or a collection. In the v2.1 format the
+// attributes of each type are lists of key-value pairs.
+type Auth struct {
+	Type   string     `json:"type"`
+	Basic  []KeyValue `json:"basic,omitempty"`
+	Bearer []KeyValue `json:"bearer,omitempty"`
+	APIKey []KeyValue `json:"apikey,omitempty"`
+}
+
+// Attribute returns the value of an attribute of the auth type.
+func (a *Auth) Attribute(key string) string {
+	var attributes []KeyValue
+	switch a.Type {
+	case "basic":
+		attributes = a.Basic
+	case "bearer":
+		attributes = a.Bearer
+	case "apikey":
+		attributes = a.APIKey
+	}
+	for _, kv := range attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return ""
+}
+
+// Event is a script that runs before a request ("prerequest") or after it ("test").
+type Event struct {
+	Listen   string `json:"listen"`
+	Script   Script `json:"script"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// Script is the source of an event script, as a list of lines.
+type Script struct {
+	Exec Lines `json:"exec"`
+}
+
+// Lines is a list of lines of source, which Postman allows to be a single string.
+type Lines []string
+
+// UnmarshalJSON decodes a list of lines or a string.
+func (l *Lines) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*l = strings.Split(s, "\n")
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return err
+	}
+	*l = lines
+	return nil
+}
+
+// Code returns the source of the script, or an empty string if it's only whitespace.
+func (s Script) Code() string {
+	return strings.TrimSpace(strings.Join(s.Exec, "\n"))
+}
+
+// Response is a saved example response of a request.
+type Response struct {
+	Name string `json:"name"`
+	Code int    `json:"code"`
+}
+
+// Variable is a collection variable.
+type Variable struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// UnmarshalJSON decodes a variable, with any non-string values as their JSON.
+func (v *Variable) UnmarshalJSON(data []byte) error {
+	var kv KeyValue
+	if err := json.Unmarshal(data, &kv); err != nil {
+		return err
+	}
+	*v = Variable{Key: kv.Key, Value: kv.Value, Disabled: kv.Disabled}
+	return nil
+}
+
+// Environment is a Postman environment, with the values of the variables of the collection.
+type Environment struct {
+	Name   string `json:"name"`
+	Values []struct {
+		Key     string `json:"key"`
+		Value   string `json:"value"`
+		Enabled *bool  `json:"enabled,omitempty"`
+	} `json:"values"`
+}
+
+// Description is the description of a collection, a folder or a request, which can be a string or
+// an object with the content.
+type Description string
+
+// UnmarshalJSON decodes a description string or object.
+func (d *Description) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*d = Description(s)
+		return nil
+	}
+	var v struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*d = Description(v.Content)
+	return nil
+}
+
+// rawString returns the JSON string as it is, and any other JSON value as its source.
+func rawString(data json.RawMessage) string {
+	if len(data) == 0 || string(data) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return s
+	}
+	return string(data)
+}
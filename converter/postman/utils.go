@@ -0,0 +1,65 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package postman
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Decode reads a Postman collection in the v2.1 format.
+func Decode(r io.Reader) (*Collection, error) {
+	var c Collection
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, fmt.Errorf("invalid Postman collection: %w", err)
+	}
+	if !strings.Contains(c.Info.Schema, "/v2.1") {
+		return nil, errors.New("only the Postman collections in the v2.1 format are supported, " +
+			"export the collection again as Collection v2.1")
+	}
+	return &c, nil
+}
+
+// DecodeEnvironment reads a Postman environment.
+func DecodeEnvironment(r io.Reader) (*Environment, error) {
+	var e Environment
+	if err := json.NewDecoder(r).Decode(&e); err != nil {
+		return nil, fmt.Errorf("invalid Postman environment: %w", err)
+	}
+	return &e, nil
+}
+
+// IsCollection returns whether the data looks like a Postman collection, of any version, for
+// detecting the format of the files to convert.
+func IsCollection(data []byte) bool {
+	var c struct {
+		Info *struct {
+			Schema string `json:"schema"`
+		} `json:"info"`
+	}
+	if json.Unmarshal(data, &c) != nil || c.Info == nil {
+		return false
+	}
+	return strings.Contains(c.Info.Schema, "schema.getpostman.com")
+}
@@ -0,0 +1,388 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package recorder implements the HTTP(S) proxy of k6 record, which records the traffic that
+// goes through it as a HAR log that the HAR converter turns into a script.
+package recorder
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/converter/har"
+	"go.k6.io/k6/lib/consts"
+)
+
+//nolint:gochecknoglobals
+var (
+	// the headers of a connection that aren't forwarded
+	hopHeaders = []string{
+		"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Proxy-Connection",
+		"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+	}
+	staticExtensions = map[string]bool{
+		".css": true, ".js": true, ".mjs": true, ".map": true, ".png": true, ".jpg": true, ".jpeg": true,
+		".gif": true, ".svg": true, ".ico": true, ".webp": true, ".avif": true, ".bmp": true, ".woff": true,
+		".woff2": true, ".ttf": true, ".otf": true, ".eot": true, ".mp3": true, ".mp4": true, ".webm": true,
+	}
+	staticMimeTypes = []string{"image/", "font/", "audio/", "video/", "text/css", "javascript"}
+)
+
+// Recorder is an HTTP proxy that records the requests and the responses that go through it. It
+// intercepts the HTTPS connections with the certificates of its CA.
+type Recorder struct {
+	ca        *CA
+	transport http.RoundTripper
+	idleGap   time.Duration
+	logger    logrus.FieldLogger
+
+	mutex       sync.Mutex
+	entries     []*har.Entry
+	pages       []har.Page
+	lastStarted time.Time
+}
+
+// New returns a recorder that sends the requests with the transport. A new page, which becomes a
+// group of the script, starts with each navigation of a browser, and with each request after
+// idleGap without requests.
+func New(ca *CA, transport http.RoundTripper, idleGap time.Duration, logger logrus.FieldLogger) *Recorder {
+	return &Recorder{ca: ca, transport: transport, idleGap: idleGap, logger: logger}
+}
+
+// ServeHTTP proxies the request, CONNECT requests start the interception of an HTTPS connection.
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodConnect {
+		r.intercept(w, req)
+		return
+	}
+	if !req.URL.IsAbs() {
+		http.Error(w, "k6 record is an HTTP proxy, the requests have to be sent through it", http.StatusBadRequest)
+		return
+	}
+	resp, err := r.roundTrip(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	for name, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// intercept accepts the tunnel of the CONNECT request and serves the requests of the TLS
+// connection of the client, with a certificate for the host signed by the CA.
+func (r *Recorder) intercept(w http.ResponseWriter, req *http.Request) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "the connection can't be intercepted", http.StatusInternalServerError)
+		return
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err = io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n"); err != nil {
+		return
+	}
+
+	host := req.URL.Host
+	hostname, _, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname = host
+	}
+	tlsConn := tls.Server(conn, &tls.Config{ //nolint:gosec
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName != "" {
+				return r.ca.Certificate(hello.ServerName)
+			}
+			return r.ca.Certificate(hostname)
+		},
+		NextProtos: []string{"http/1.1"},
+	})
+	if err = tlsConn.Handshake(); err != nil {
+		r.logger.WithError(err).Debugf("TLS handshake with the client of %s failed", host)
+		return
+	}
+
+	reader := bufio.NewReader(tlsConn)
+	for {
+		tunnelReq, err := http.ReadRequest(reader)
+		if err != nil {
+			return
+		}
+		tunnelReq.URL.Scheme = "https"
+		tunnelReq.URL.Host = host
+		resp, err := r.roundTrip(tunnelReq)
+		if err != nil {
+			resp = &http.Response{
+				StatusCode: http.StatusBadGateway,
+				ProtoMajor: 1, ProtoMinor: 1,
+				Header: http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+				Body:   ioutil.NopCloser(strings.NewReader(err.Error())),
+			}
+		}
+		err = resp.Write(tlsConn)
+		_ = resp.Body.Close()
+		if err != nil || tunnelReq.Close || resp.Close {
+			return
+		}
+	}
+}
+
+// roundTrip sends the request and records it with its response, the body of the returned response
+// is the recorded one.
+func (r *Recorder) roundTrip(req *http.Request) (*http.Response, error) {
+	started := time.Now()
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = req.Body.Close()
+
+	outReq := req.Clone(req.Context())
+	outReq.RequestURI = ""
+	outReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+	outReq.ContentLength = int64(len(body))
+	removeHopHeaders(outReq.Header)
+	// the transport asks for compressed responses and decompresses them, so the bodies are recorded
+	// as text
+	outReq.Header.Del("Accept-Encoding")
+
+	resp, err := r.transport.RoundTrip(outReq)
+	if err != nil {
+		r.record(req, body, nil, nil, started)
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	removeHopHeaders(resp.Header)
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Set("Content-Length", fmt.Sprint(len(respBody)))
+	resp.ContentLength = int64(len(respBody))
+	resp.TransferEncoding = nil
+	resp.Uncompressed = false
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	r.record(req, body, resp, respBody, started)
+	return resp, nil
+}
+
+func (r *Recorder) record(req *http.Request, body []byte, resp *http.Response, respBody []byte, started time.Time) {
+	elapsed := float32(time.Since(started).Seconds() * 1000)
+	entry := &har.Entry{
+		StartedDateTime: started,
+		Time:            elapsed,
+		Request:         harRequest(req, body),
+		Cache:           &har.Cache{},
+		Timings:         &har.Timings{Wait: elapsed},
+	}
+	if resp != nil {
+		entry.Response = harResponse(resp, respBody)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.pages) == 0 || isNavigation(req) || started.Sub(r.lastStarted) > r.idleGap {
+		r.pages = append(r.pages, har.Page{
+			StartedDateTime: started,
+			ID:              fmt.Sprintf("page_%d", len(r.pages)+1),
+			Title:           entry.Request.URL,
+		})
+	}
+	r.lastStarted = started
+	entry.Pageref = r.pages[len(r.pages)-1].ID
+	entry.ID = fmt.Sprint(len(r.entries) + 1)
+	r.entries = append(r.entries, entry)
+	r.logger.Debugf("Recorded %s %s", entry.Request.Method, entry.Request.URL)
+}
+
+// HAR returns the HAR log of the recorded requests. The requests of the static assets, like
+// images, stylesheets and fonts, are left out unless includeStatic is true.
+func (r *Recorder) HAR(includeStatic bool) har.HAR {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	log := &har.Log{
+		Version: "1.2",
+		Creator: &har.Creator{Name: "k6 record", Version: consts.Version},
+		Entries: []*har.Entry{},
+	}
+	pages := make(map[string]bool)
+	for _, e := range r.entries {
+		if !includeStatic && IsStatic(e) {
+			continue
+		}
+		pages[e.Pageref] = true
+		log.Entries = append(log.Entries, e)
+	}
+	for _, page := range r.pages {
+		if pages[page.ID] {
+			log.Pages = append(log.Pages, page)
+		}
+	}
+	return har.HAR{Log: log}
+}
+
+// IsStatic returns whether the entry is the request of a static asset, by the extension of its
+// path or by the type of its response.
+func IsStatic(e *har.Entry) bool {
+	if u, err := url.Parse(e.Request.URL); err == nil && staticExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return true
+	}
+	if e.Response == nil || e.Response.Content == nil {
+		return false
+	}
+	mimeType := strings.ToLower(e.Response.Content.MimeType)
+	for _, t := range staticMimeTypes {
+		if strings.Contains(mimeType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNavigation returns whether the request is the navigation of a browser to a page.
+func isNavigation(req *http.Request) bool {
+	if mode := req.Header.Get("Sec-Fetch-Mode"); mode != "" {
+		return mode == "navigate" && req.Header.Get("Sec-Fetch-Dest") != "iframe"
+	}
+	return req.Method == http.MethodGet && strings.HasPrefix(req.Header.Get("Accept"), "text/html")
+}
+
+func removeHopHeaders(header http.Header) {
+	for _, name := range strings.Split(header.Get("Connection"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			header.Del(name)
+		}
+	}
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+}
+
+func harHeaders(header http.Header) []har.Header {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headers := make([]har.Header, 0, len(header))
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers = append(headers, har.Header{Name: name, Value: value})
+		}
+	}
+	return headers
+}
+
+func harRequest(req *http.Request, body []byte) *har.Request {
+	header := req.Header.Clone()
+	removeHopHeaders(header)
+	if req.Host != "" && header.Get("Host") == "" {
+		header.Set("Host", req.Host)
+	}
+	hr := &har.Request{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: req.Proto,
+		Cookies:     []har.Cookie{},
+		Headers:     harHeaders(header),
+		QueryString: []har.QueryString{},
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+	}
+	for _, c := range req.Cookies() {
+		hr.Cookies = append(hr.Cookies, har.Cookie{Name: c.Name, Value: c.Value})
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			hr.QueryString = append(hr.QueryString, har.QueryString{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(hr.QueryString, func(i, j int) bool { return hr.QueryString[i].Name < hr.QueryString[j].Name })
+	if len(body) > 0 {
+		hr.PostData = &har.PostData{MimeType: req.Header.Get("Content-Type"), Text: string(body)}
+		if strings.HasPrefix(hr.PostData.MimeType, "application/x-www-form-urlencoded") {
+			if form, err := url.ParseQuery(string(body)); err == nil {
+				for name, values := range form {
+					for _, value := range values {
+						hr.PostData.Params = append(hr.PostData.Params, har.Param{Name: name, Value: value})
+					}
+				}
+				sort.SliceStable(hr.PostData.Params, func(i, j int) bool {
+					return hr.PostData.Params[i].Name < hr.PostData.Params[j].Name
+				})
+			}
+		}
+	}
+	return hr
+}
+
+func harResponse(resp *http.Response, body []byte) *har.Response {
+	hr := &har.Response{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []har.Cookie{},
+		Headers:     harHeaders(resp.Header),
+		Content:     &har.Content{Size: int64(len(body)), MimeType: resp.Header.Get("Content-Type")},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+	}
+	for _, c := range resp.Cookies() {
+		hr.Cookies = append(hr.Cookies, har.Cookie{
+			Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain, HTTPOnly: c.HttpOnly, Secure: c.Secure,
+		})
+	}
+	if utf8.Valid(body) {
+		hr.Content.Text = string(body)
+	} else {
+		hr.Content.Text = base64.StdEncoding.EncodeToString(body)
+		hr.Content.Encoding = "base64"
+	}
+	return hr
+}
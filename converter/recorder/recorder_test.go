@@ -0,0 +1,170 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package recorder
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/converter/har"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/testutils"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+	backend := http.NewServeMux()
+	backend.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><img src=\"/logo.png\"></html>"))
+	})
+	backend.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte{0x89, 'P', 'N', 'G', 0xff})
+	})
+	backend.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"user": "` + r.PostFormValue("user") + string(body) + `"}`))
+	})
+	tlsBackend := httptest.NewTLSServer(backend)
+	defer tlsBackend.Close()
+	plainBackend := httptest.NewServer(backend)
+	defer plainBackend.Close()
+
+	ca, err := NewCA()
+	require.NoError(t, err)
+	transport := tlsBackend.Client().Transport.(*http.Transport).Clone()
+	rec := New(ca, transport, time.Hour, testutils.NewLogger(t))
+	proxy := httptest.NewServer(rec)
+	defer proxy.Close()
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(ca.CertificatePEM()))
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "127.0.0.1"}, //nolint:gosec
+	}}
+
+	get := func(u string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_, _ = ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		return resp
+	}
+	resp := get(tlsBackend.URL + "/")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = client.Get(tlsBackend.URL + "/logo.png")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	get(plainBackend.URL + "/")
+	resp, err = client.Post(plainBackend.URL+"/login", "application/x-www-form-urlencoded", strings.NewReader("user=admin"))
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, `{"user": "user=admin"}`, string(body))
+
+	h := rec.HAR(false)
+	require.Len(t, h.Log.Entries, 3)
+	require.Len(t, h.Log.Pages, 2)
+	assert.Equal(t, tlsBackend.URL+"/", h.Log.Entries[0].Request.URL)
+	assert.Equal(t, "page_1", h.Log.Entries[0].Pageref)
+	assert.Equal(t, "page_2", h.Log.Entries[1].Pageref)
+	assert.Equal(t, "page_2", h.Log.Entries[2].Pageref)
+	assert.Equal(t, "page_2", h.Log.Pages[1].ID)
+
+	login := h.Log.Entries[2]
+	assert.Equal(t, http.MethodPost, login.Request.Method)
+	require.NotNil(t, login.Request.PostData)
+	assert.Equal(t, "user=admin", login.Request.PostData.Text)
+	assert.Equal(t, []har.Param{{Name: "user", Value: "admin"}}, login.Request.PostData.Params)
+	assert.Equal(t, 200, login.Response.Status)
+	assert.Equal(t, "application/json", login.Response.Content.MimeType)
+
+	all := rec.HAR(true)
+	require.Len(t, all.Log.Entries, 4)
+	assert.Equal(t, "base64", all.Log.Entries[1].Response.Content.Encoding)
+
+	script, err := har.Convert(h, lib.Options{}, 1, 2, true, false, 500, true, false, nil, nil)
+	require.NoError(t, err)
+	assert.Contains(t, script, "res = http.post(\""+plainBackend.URL+"/login\",\n\t\t\"user=admin\"")
+	assert.NotContains(t, script, "logo.png")
+}
+
+func TestIsStatic(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]bool{
+		"https://example.com/app.js?v=1":    true,
+		"https://example.com/img/LOGO.PNG":  true,
+		"https://example.com/fonts/a.woff2": true,
+		"https://example.com/api/users":     false,
+		"https://example.com/":              false,
+	}
+	for u, expected := range testCases {
+		e := &har.Entry{Request: &har.Request{URL: u}}
+		assert.Equal(t, expected, IsStatic(e), u)
+	}
+	e := &har.Entry{
+		Request:  &har.Request{URL: "https://example.com/avatar"},
+		Response: &har.Response{Content: &har.Content{MimeType: "image/jpeg"}},
+	}
+	assert.True(t, IsStatic(e))
+}
+
+func TestLoadCA(t *testing.T) {
+	t.Parallel()
+	ca, err := NewCA()
+	require.NoError(t, err)
+	loaded, err := LoadCA(ca.CertificatePEM(), ca.KeyPEM())
+	require.NoError(t, err)
+	cert, err := loaded.Certificate("example.com")
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.CertificatePEM())
+	_, err = leaf.Verify(x509.VerifyOptions{DNSName: "example.com", Roots: roots})
+	assert.NoError(t, err)
+	same, err := loaded.Certificate("example.com")
+	require.NoError(t, err)
+	assert.Same(t, cert, same)
+
+	_, err = LoadCA(cert.Certificate[0], ca.KeyPEM())
+	assert.Error(t, err)
+}
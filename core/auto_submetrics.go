@@ -0,0 +1,146 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"regexp"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/stats"
+)
+
+const defaultAutoSubmetricsLimit = 100
+
+// autoSubmetrics creates the sub-metrics of the autoSubmetrics option, for the tag values of the
+// samples of its metrics. It's guarded by the MetricsLock of the Engine.
+type autoSubmetrics struct {
+	metrics    map[string]bool
+	tags       []string
+	include    []*regexp.Regexp
+	exclude    []*regexp.Regexp
+	thresholds []string
+	limit      int
+
+	// the sub-metrics with thresholds in the options, which the Engine creates itself
+	declared map[string]bool
+	// the number of the sub-metrics of each metric and tag
+	counts map[[2]string]int
+}
+
+func newAutoSubmetrics(conf lib.AutoSubmetrics, declared map[string][]*stats.Submetric) (*autoSubmetrics, error) {
+	as := &autoSubmetrics{
+		metrics:    make(map[string]bool),
+		tags:       conf.Tags,
+		thresholds: conf.Thresholds,
+		limit:      defaultAutoSubmetricsLimit,
+		declared:   make(map[string]bool),
+		counts:     make(map[[2]string]int),
+	}
+	for _, submetrics := range declared {
+		for _, sm := range submetrics {
+			as.declared[sm.Name] = true
+		}
+	}
+	metrics := conf.Metrics
+	if len(metrics) == 0 {
+		metrics = []string{"http_req_duration"}
+	}
+	for _, name := range metrics {
+		as.metrics[name] = true
+	}
+	if conf.Limit.Valid {
+		as.limit = int(conf.Limit.Int64)
+	}
+	for _, pattern := range conf.Include {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		as.include = append(as.include, re)
+	}
+	for _, pattern := range conf.Exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		as.exclude = append(as.exclude, re)
+	}
+	// the thresholds are parsed once here for the errors, each sub-metric has its own copy
+	if _, err := stats.NewThresholds(as.thresholds); err != nil {
+		return nil, err
+	}
+	return as, nil
+}
+
+func (as *autoSubmetrics) matches(value string) bool {
+	for _, re := range as.exclude {
+		if re.MatchString(value) {
+			return false
+		}
+	}
+	if len(as.include) == 0 {
+		return true
+	}
+	for _, re := range as.include {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// add creates the sub-metrics of the tag values of the sample that don't have one yet, so the
+// sample is added to them like to the other sub-metrics of the metric.
+func (as *autoSubmetrics) add(metrics map[string]*stats.Metric, m *stats.Metric, sample stats.Sample) {
+	if !as.metrics[m.Name] || sample.Tags == nil {
+		return
+	}
+	for _, tag := range as.tags {
+		value, ok := sample.Tags.Get(tag)
+		if !ok || value == "" || !as.matches(value) {
+			continue
+		}
+		// the name isn't parsed, since the tag values like URLs can have any characters
+		name := m.Name + "{" + tag + ":" + value + "}"
+		if _, ok := metrics[name]; ok || as.declared[name] {
+			continue
+		}
+		key := [2]string{m.Name, tag}
+		if as.counts[key] >= as.limit {
+			continue
+		}
+		as.counts[key]++
+
+		sm := &stats.Submetric{
+			Name:   name,
+			Parent: m.Name,
+			Suffix: tag + ":" + value,
+			Tags:   stats.IntoSampleTags(&map[string]string{tag: value}),
+		}
+		sm.Metric = sample.Metric.Derive(name)
+		sm.Metric.Sub = *sm
+		if len(as.thresholds) > 0 {
+			sm.Metric.Thresholds, _ = stats.NewThresholds(as.thresholds)
+		}
+		metrics[name] = sm.Metric
+		m.Submetrics = append(m.Submetrics, sm)
+	}
+}
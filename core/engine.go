@@ -23,6 +23,9 @@ package core
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -32,6 +35,7 @@ import (
 
 	"go.k6.io/k6/errext"
 	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/events"
 	"go.k6.io/k6/lib/metrics"
 	"go.k6.io/k6/output"
 	"go.k6.io/k6/stats"
@@ -67,14 +71,41 @@ type Engine struct {
 	Metrics     map[string]*stats.Metric
 	MetricsLock sync.Mutex
 
+	// Only set if the summaryTimeSeriesInterval option is enabled, guarded
+	// by MetricsLock as well.
+	TimeSeries *stats.TimeSeries
+
 	Samples chan stats.SampleContainer
 
 	// Assigned to metrics upon first received sample.
 	thresholds map[string]stats.Thresholds
 	submetrics map[string][]*stats.Submetric
 
+	// Only set if the autoSubmetrics option is.
+	autoSubmetrics *autoSubmetrics
+	// The sub-metrics of http_req_errors for each error_class, for the errors of the summary.
+	errorClassSubmetrics *autoSubmetrics
+
+	// Caps the distinct tag values, if the maxTagValues option is set.
+	tagLimiter *stats.TagCardinalityLimiter
+
+	// Only set if the metrics option is.
+	metricsFilter *metricsFilter
+
+	// The tags that are set during the test run, like with the REST API, which override the ones
+	// of the staticTags option.
+	runtimeTags map[string]string
+	// Only set if there are static or runtime tags, or the staticMetadata option is set.
+	staticTags *staticTags
+
+	// The dropped samples of the outputs with limited buffers, only used by emitMetrics.
+	outputDroppedSamples map[output.Output]uint64
+
 	// Are thresholds tainted?
 	thresholdsTainted bool
+
+	// The bus of the events of the test run, it's nil if there are no subscribers.
+	events *events.Bus
 }
 
 // NewEngine instantiates a new Engine, without doing any heavy initialization.
@@ -98,6 +129,28 @@ func NewEngine(
 		logger:         logger.WithField("component", "engine"),
 	}
 
+	if opts.MaxTagValues.Int64 > 0 {
+		e.tagLimiter = stats.NewTagCardinalityLimiter(int(opts.MaxTagValues.Int64), func(metric, tag string) {
+			e.logger.Warnf(
+				"The metric '%s' exceeded the limit of %d distinct values for the tag '%s', all new values "+
+					"will be replaced with '%s'; consider not using high-cardinality values like IDs as tags",
+				metric, opts.MaxTagValues.Int64, tag, stats.TagValueOverflow,
+			)
+		})
+	}
+
+	if opts.Metrics != nil && (len(opts.Metrics.Include) > 0 || len(opts.Metrics.Exclude) > 0) {
+		e.metricsFilter = newMetricsFilter(*opts.Metrics)
+	}
+
+	e.updateStaticTags()
+
+	if interval := time.Duration(opts.SummaryTimeSeriesInterval.Duration); interval > 0 {
+		e.TimeSeries = stats.NewTimeSeries(interval)
+	}
+
+	e.executionState.SetMetricValueFunc(e.getMetricValue)
+
 	e.thresholds = opts.Thresholds
 	e.submetrics = make(map[string][]*stats.Submetric)
 	for name := range e.thresholds {
@@ -123,9 +176,44 @@ func NewEngine(
 		}
 	}
 
+	var err error
+	if opts.AutoSubmetrics != nil {
+		if e.autoSubmetrics, err = newAutoSubmetrics(*opts.AutoSubmetrics, e.submetrics); err != nil {
+			return nil, err
+		}
+	}
+	e.errorClassSubmetrics, err = newAutoSubmetrics(lib.AutoSubmetrics{
+		Metrics: []string{metrics.HTTPReqErrors.Name},
+		Tags:    []string{"error_class"},
+	}, e.submetrics)
+	if err != nil {
+		return nil, err
+	}
+
 	return e, nil
 }
 
+// DisableAutoSubmetrics drops the submetrics that the Engine creates on its
+// own, e.g. http_req_duration{expected_response:true} and the error classes
+// of http_req_errors, so that only the ones
+// with thresholds are tracked. It has to be called before Run().
+func (e *Engine) DisableAutoSubmetrics() {
+	e.errorClassSubmetrics = nil
+	for parent, submetrics := range e.submetrics {
+		kept := submetrics[:0]
+		for _, sm := range submetrics {
+			if _, ok := e.thresholds[sm.Name]; ok {
+				kept = append(kept, sm)
+			}
+		}
+		if len(kept) == 0 {
+			delete(e.submetrics, parent)
+			continue
+		}
+		e.submetrics[parent] = kept
+	}
+}
+
 // StartOutputs spins up all configured outputs, giving the thresholds to any
 // that can accept them. And if some output fails, stop the already started
 // ones. This may take some time, since some outputs make initial network
@@ -169,6 +257,74 @@ func (e *Engine) stopOutputs(upToID int) {
 	}
 }
 
+// updateStaticTags merges the tags of the options and the runtime tags, it has to be called with
+// the MetricsLock, except before the test run starts.
+func (e *Engine) updateStaticTags() {
+	tags := e.Options.StaticTags
+	if len(e.runtimeTags) > 0 {
+		tags = make(map[string]string, len(e.Options.StaticTags)+len(e.runtimeTags))
+		for k, v := range e.Options.StaticTags {
+			tags[k] = v
+		}
+		for k, v := range e.runtimeTags {
+			tags[k] = v
+		}
+	}
+	e.staticTags = nil
+	if len(tags) > 0 || len(e.Options.StaticMetadata) > 0 {
+		e.staticTags = newStaticTags(tags, e.Options.StaticMetadata)
+	}
+}
+
+// UpdateTags sets and removes the runtime tags, which the outputs get in the samples from now
+// on, like the static tags, and override them.
+func (e *Engine) UpdateTags(set map[string]string, unset []string) error {
+	for k := range set {
+		if k == "" {
+			return errors.New("the names of the tags can't be empty")
+		}
+	}
+
+	e.MetricsLock.Lock()
+	defer e.MetricsLock.Unlock()
+
+	// the map is replaced, since the staticTags keep using the old one
+	tags := make(map[string]string, len(e.runtimeTags)+len(set))
+	for k, v := range e.runtimeTags {
+		tags[k] = v
+	}
+	for k, v := range set {
+		tags[k] = v
+	}
+	for _, k := range unset {
+		delete(tags, k)
+	}
+	e.runtimeTags = tags
+	e.updateStaticTags()
+	return nil
+}
+
+// RuntimeTags returns the tags that were set during the test run.
+func (e *Engine) RuntimeTags() map[string]string {
+	e.MetricsLock.Lock()
+	defer e.MetricsLock.Unlock()
+	return e.copyRuntimeTags()
+}
+
+func (e *Engine) copyRuntimeTags() map[string]string {
+	tags := make(map[string]string, len(e.runtimeTags))
+	for k, v := range e.runtimeTags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// SetEvents sets the bus that the engine publishes the events of the failing thresholds and of
+// the aborted test run to. It has to be called before the test run starts.
+func (e *Engine) SetEvents(bus *events.Bus) {
+	e.events = bus
+}
+
 // Init is used to initialize the execution scheduler and all metrics processing
 // in the engine. The first is a costly operation, since it initializes all of
 // the planned VUs and could potentially take a long time.
@@ -256,10 +412,16 @@ func (e *Engine) startBackgroundProcesses(
 			if err != nil {
 				e.logger.WithError(err).Debug("run: execution scheduler returned an error")
 				var serr errext.Exception
-				if errors.As(err, &serr) {
+				var abortErr *errext.AbortTest
+				if errors.As(err, &abortErr) {
+					e.setRunStatus(lib.RunStatusAbortedUser)
+					e.publishAbort(string(lib.AbortReasonScript), err)
+				} else if errors.As(err, &serr) {
 					e.setRunStatus(lib.RunStatusAbortedScriptError)
+					e.publishAbort("script_error", err)
 				} else {
 					e.setRunStatus(lib.RunStatusAbortedSystem)
+					e.publishAbort("system", err)
 				}
 			} else {
 				e.logger.Debug("run: execution scheduler terminated")
@@ -268,14 +430,23 @@ func (e *Engine) startBackgroundProcesses(
 		case <-runCtx.Done():
 			e.logger.Debug("run: context expired; exiting...")
 			e.setRunStatus(lib.RunStatusAbortedUser)
+			reason := e.executionState.GetAbortReason()
+			if reason == "" {
+				reason = lib.AbortReasonUser
+			}
+			e.publishAbort(string(reason), nil)
 		case <-e.stopChan:
+			e.executionState.SetAbortReason(lib.AbortReasonUser)
 			runSubCancel()
 			e.logger.Debug("run: stopped by user; exiting...")
 			e.setRunStatus(lib.RunStatusAbortedUser)
+			e.publishAbort(string(lib.AbortReasonUser), nil)
 		case <-thresholdAbortChan:
 			e.logger.Debug("run: stopped by thresholds; exiting...")
+			e.executionState.SetAbortReason(lib.AbortReasonThreshold)
 			runSubCancel()
 			e.setRunStatus(lib.RunStatusAbortedThreshold)
+			e.publishAbort(string(lib.AbortReasonThreshold), nil)
 		}
 	}()
 
@@ -366,6 +537,49 @@ func (e *Engine) processMetrics(globalCtx context.Context, processMetricsAfterRu
 	}
 }
 
+// getOutputBufferSamples returns the samples of the depth and the drops of the buffers of the
+// outputs with limits, and warns about the new drops.
+func (e *Engine) getOutputBufferSamples(now time.Time) []stats.SampleContainer {
+	var samples stats.Samples
+	for _, out := range e.outputs {
+		withStats, ok := out.(output.WithBufferStats)
+		if !ok {
+			continue
+		}
+		bufferStats, ok := withStats.BufferStats()
+		if !ok {
+			continue
+		}
+		if e.outputDroppedSamples == nil {
+			e.outputDroppedSamples = make(map[output.Output]uint64)
+		}
+		dropped := bufferStats.Dropped - e.outputDroppedSamples[out]
+		e.outputDroppedSamples[out] = bufferStats.Dropped
+		if dropped > 0 {
+			logger := e.logger.WithField("output", out.Description())
+			if bufferStats.Err != nil {
+				logger = logger.WithError(bufferStats.Err)
+			}
+			logger.Warnf("The output fell behind and dropped %d samples", dropped)
+		}
+
+		tagsMap := e.Options.RunTags.CloneTags()
+		tagsMap["output"] = out.Description()
+		tags := stats.IntoSampleTags(&tagsMap)
+		samples = append(samples,
+			stats.Sample{
+				Metric: metrics.OutputBufferSamples, Tags: tags, Time: now,
+				Value: float64(bufferStats.Buffered + bufferStats.Spilled),
+			},
+			stats.Sample{Metric: metrics.OutputDroppedSamples, Tags: tags, Time: now, Value: float64(dropped)},
+		)
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	return []stats.SampleContainer{samples}
+}
+
 func (e *Engine) setRunStatus(status lib.RunStatus) {
 	for _, out := range e.outputs {
 		if statUpdOut, ok := out.(output.WithRunStatusUpdates); ok {
@@ -374,6 +588,15 @@ func (e *Engine) setRunStatus(status lib.RunStatus) {
 	}
 }
 
+// publishAbort publishes the event of the aborted test run, with the reason and the error.
+func (e *Engine) publishAbort(reason string, err error) {
+	data := map[string]interface{}{"reason": reason}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	e.events.Publish(events.TestAbort, data)
+}
+
 func (e *Engine) IsTainted() bool {
 	return e.thresholdsTainted
 }
@@ -429,6 +652,7 @@ func (e *Engine) emitMetrics() {
 		Tags: e.Options.RunTags,
 		Time: t,
 	}})
+	e.processSamples(e.getOutputBufferSamples(t))
 }
 
 func (e *Engine) processThresholds() (shouldAbort bool) {
@@ -442,6 +666,7 @@ func (e *Engine) processThresholds() (shouldAbort bool) {
 		if len(m.Thresholds.Thresholds) == 0 {
 			continue
 		}
+		wasTainted := m.Tainted.Bool
 		m.Tainted = null.BoolFrom(false)
 
 		e.logger.WithField("m", m.Name).Debug("running thresholds")
@@ -458,11 +683,79 @@ func (e *Engine) processThresholds() (shouldAbort bool) {
 				shouldAbort = true
 			}
 		}
+		if wasTainted != m.Tainted.Bool {
+			e.publishThresholdCrossed(m)
+		}
 	}
 
 	return shouldAbort
 }
 
+// publishThresholdCrossed publishes the event of the thresholds of the metric that started or
+// stopped failing, with the thresholds that fail.
+func (e *Engine) publishThresholdCrossed(m *stats.Metric) {
+	failed := []string{}
+	for _, th := range m.Thresholds.Thresholds {
+		if th.LastFailed {
+			failed = append(failed, th.Source)
+		}
+	}
+	e.events.Publish(events.ThresholdCrossed, map[string]interface{}{
+		"metric":     m.Name,
+		"failed":     m.Tainted.Bool,
+		"thresholds": failed,
+	})
+}
+
+// defaultMetricAggregations are the aggregations of the metric values for the scripts when they
+// don't specify one.
+//nolint:gochecknoglobals
+var defaultMetricAggregations = map[stats.MetricType]string{
+	stats.Counter:    "count",
+	stats.Gauge:      "value",
+	stats.Rate:       "rate",
+	stats.Trend:      "avg",
+	stats.Throughput: "rate",
+	stats.Histogram:  "avg",
+}
+
+// getMetricValue returns the current aggregated value of a metric for the scripts, with the same
+// aggregations as the thresholds, and any percentile like p(99.9) for the trends.
+func (e *Engine) getMetricValue(name, aggregation string) (float64, bool, error) {
+	e.MetricsLock.Lock()
+	defer e.MetricsLock.Unlock()
+
+	m, ok := e.Metrics[name]
+	if !ok {
+		return 0, false, nil
+	}
+	if aggregation == "" {
+		aggregation = defaultMetricAggregations[m.Type]
+	}
+	if strings.HasPrefix(aggregation, "p(") && strings.HasSuffix(aggregation, ")") {
+		if sink, ok := m.Sink.(interface{ P(float64) float64 }); ok {
+			pct, err := strconv.ParseFloat(aggregation[2:len(aggregation)-1], 64)
+			if err != nil || pct < 0 || pct > 100 {
+				return 0, false, fmt.Errorf("invalid percentile '%s' of the metric '%s'", aggregation, name)
+			}
+			return sink.P(pct / 100), true, nil
+		}
+	}
+
+	values := m.Sink.Format(e.executionState.GetCurrentTestRunDuration())
+	value, ok := values[aggregation]
+	if !ok {
+		aggregations := make([]string, 0, len(values))
+		for k := range values {
+			aggregations = append(aggregations, k)
+		}
+		sort.Strings(aggregations)
+		return 0, false, fmt.Errorf("the metric '%s' doesn't have the '%s' aggregation, it has %s",
+			name, aggregation, strings.Join(aggregations, ", "))
+	}
+	return value, true, nil
+}
+
 func (e *Engine) processSamplesForMetrics(sampleContainers []stats.SampleContainer) {
 	for _, sampleContainer := range sampleContainers {
 		samples := sampleContainer.GetSamples()
@@ -474,12 +767,21 @@ func (e *Engine) processSamplesForMetrics(sampleContainers []stats.SampleContain
 		for _, sample := range samples {
 			m, ok := e.Metrics[sample.Metric.Name]
 			if !ok {
-				m = stats.New(sample.Metric.Name, sample.Metric.Type, sample.Metric.Contains)
+				m = sample.Metric.Derive(sample.Metric.Name)
 				m.Thresholds = e.thresholds[m.Name]
 				m.Submetrics = e.submetrics[m.Name]
 				e.Metrics[m.Name] = m
 			}
+			if e.autoSubmetrics != nil {
+				e.autoSubmetrics.add(e.Metrics, m, sample)
+			}
+			if e.errorClassSubmetrics != nil {
+				e.errorClassSubmetrics.add(e.Metrics, m, sample)
+			}
 			m.Sink.Add(sample)
+			if e.TimeSeries != nil {
+				e.TimeSeries.Add(m, sample)
+			}
 
 			for _, sm := range m.Submetrics {
 				if !sample.Tags.Contains(sm.Tags) {
@@ -487,12 +789,15 @@ func (e *Engine) processSamplesForMetrics(sampleContainers []stats.SampleContain
 				}
 
 				if sm.Metric == nil {
-					sm.Metric = stats.New(sm.Name, sample.Metric.Type, sample.Metric.Contains)
+					sm.Metric = sample.Metric.Derive(sm.Name)
 					sm.Metric.Sub = *sm
 					sm.Metric.Thresholds = e.thresholds[sm.Name]
 					e.Metrics[sm.Name] = sm.Metric
 				}
 				sm.Metric.Sink.Add(sample)
+				if e.TimeSeries != nil {
+					e.TimeSeries.Add(sm.Metric, sample)
+				}
 			}
 		}
 	}
@@ -507,11 +812,21 @@ func (e *Engine) processSamples(sampleContainers []stats.SampleContainer) {
 	e.MetricsLock.Lock()
 	defer e.MetricsLock.Unlock()
 
+	if e.tagLimiter != nil {
+		e.tagLimiter.Apply(sampleContainers)
+	}
+
 	// TODO: run this and the below code in goroutines?
 	if !(e.runtimeOptions.NoSummary.Bool && e.runtimeOptions.NoThresholds.Bool) {
 		e.processSamplesForMetrics(sampleContainers)
 	}
 
+	if e.metricsFilter != nil {
+		sampleContainers = e.metricsFilter.apply(sampleContainers)
+	}
+	if e.staticTags != nil {
+		sampleContainers = e.staticTags.apply(sampleContainers)
+	}
 	for _, out := range e.outputs {
 		out.AddMetricSamples(sampleContainers)
 	}
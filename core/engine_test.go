@@ -38,8 +38,10 @@ import (
 	"go.k6.io/k6/errext"
 	"go.k6.io/k6/js"
 	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/events"
 	"go.k6.io/k6/lib/executor"
 	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/netext/httpext"
 	"go.k6.io/k6/lib/testutils"
 	"go.k6.io/k6/lib/testutils/httpmultibin"
 	"go.k6.io/k6/lib/testutils/minirunner"
@@ -54,7 +56,7 @@ const isWindows = runtime.GOOS == "windows"
 
 // Wrapper around NewEngine that applies a logger and manages the options.
 func newTestEngine( //nolint:golint
-	t *testing.T, runCtx context.Context, runner lib.Runner, outputs []output.Output, opts lib.Options,
+	t testing.TB, runCtx context.Context, runner lib.Runner, outputs []output.Output, opts lib.Options,
 ) (engine *Engine, run func() error, wait func()) {
 	if runner == nil {
 		runner = &minirunner.MiniRunner{}
@@ -278,6 +280,364 @@ func TestEngine_processSamples(t *testing.T) {
 		assert.IsType(t, &stats.GaugeSink{}, e.Metrics["my_metric"].Sink)
 		assert.IsType(t, &stats.GaugeSink{}, e.Metrics["my_metric{a:1}"].Sink)
 	})
+	t.Run("max tag values", func(t *testing.T) {
+		t.Parallel()
+		mockOutput := mockoutput.New()
+		e, _, wait := newTestEngine(t, nil, nil, []output.Output{mockOutput}, lib.Options{
+			MaxTagValues: null.IntFrom(2),
+		})
+		defer wait()
+
+		for _, vu := range []string{"1", "2", "3", "4"} {
+			e.processSamples([]stats.SampleContainer{stats.Sample{
+				Metric: metric, Value: 1, Tags: stats.IntoSampleTags(&map[string]string{"vu": vu}),
+			}})
+		}
+
+		vus := []string{}
+		for _, s := range mockOutput.Samples {
+			vu, _ := s.Tags.Get("vu")
+			vus = append(vus, vu)
+		}
+		assert.Equal(t, []string{"1", "2", stats.TagValueOverflow, stats.TagValueOverflow}, vus)
+	})
+}
+
+func TestEngineDisableAutoSubmetrics(t *testing.T) {
+	t.Parallel()
+	ths, err := stats.NewThresholds([]string{`1+1==2`})
+	require.NoError(t, err)
+
+	e, _, wait := newTestEngine(t, nil, nil, nil, lib.Options{
+		SystemTags: &stats.DefaultSystemTagSet,
+		Thresholds: map[string]stats.Thresholds{
+			"my_metric{a:1}": ths,
+		},
+	})
+	defer wait()
+
+	require.Len(t, e.submetrics["http_req_duration"], 1)
+	require.Len(t, e.submetrics["my_metric"], 1)
+
+	e.DisableAutoSubmetrics()
+	assert.NotContains(t, e.submetrics, "http_req_duration")
+	require.Len(t, e.submetrics["my_metric"], 1)
+	assert.Equal(t, "my_metric{a:1}", e.submetrics["my_metric"][0].Name)
+}
+
+// BenchmarkEngineProcessSamples measures the cost of the submetrics that the
+// engine tracks on its own, which the high-throughput performance profile
+// disables.
+func BenchmarkEngineProcessSamples(b *testing.B) {
+	metric := stats.New("http_req_duration", stats.Trend, stats.Time)
+	tags := stats.IntoSampleTags(&map[string]string{
+		"method": "GET", "status": "200", "expected_response": "true", "url": "http://example.com",
+	})
+	samples := make([]stats.SampleContainer, 1000)
+	for i := range samples {
+		samples[i] = stats.Sample{Metric: metric, Value: float64(i), Tags: tags, Time: time.Now()}
+	}
+
+	for _, disable := range []bool{false, true} {
+		disable := disable
+		b.Run(fmt.Sprintf("disableAutoSubmetrics=%t", disable), func(b *testing.B) {
+			e, _, wait := newTestEngine(b, nil, nil, nil, lib.Options{SystemTags: &stats.DefaultSystemTagSet})
+			defer wait()
+			if disable {
+				e.DisableAutoSubmetrics()
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				e.processSamples(samples)
+			}
+		})
+	}
+}
+
+func TestEngineAutoSubmetrics(t *testing.T) {
+	t.Parallel()
+	ths, err := stats.NewThresholds([]string{`p(95)<100`})
+	require.NoError(t, err)
+	e, _, wait := newTestEngine(t, nil, nil, nil, lib.Options{
+		Thresholds: map[string]stats.Thresholds{"http_req_duration{name:https://example.com/a}": ths},
+		AutoSubmetrics: &lib.AutoSubmetrics{
+			Tags:       []string{"name", "group"},
+			Exclude:    []string{`/static/`},
+			Thresholds: []string{`p(95)<200`},
+			Limit:      null.IntFrom(2),
+		},
+	})
+	defer wait()
+
+	sample := func(m *stats.Metric, value float64, tags map[string]string) stats.SampleContainer {
+		return stats.Sample{Metric: m, Value: value, Time: time.Now(), Tags: stats.IntoSampleTags(&tags)}
+	}
+	other := stats.New("other", stats.Trend)
+	e.processSamples([]stats.SampleContainer{
+		sample(metrics.HTTPReqDuration, 50, map[string]string{"name": "https://example.com/a", "group": "::login"}),
+		sample(metrics.HTTPReqDuration, 250, map[string]string{"name": "https://example.com/b,c", "group": "::login"}),
+		sample(metrics.HTTPReqDuration, 50, map[string]string{"name": "https://example.com/static/x.png"}),
+		sample(metrics.HTTPReqDuration, 50, map[string]string{"name": "https://example.com/d"}),
+		sample(other, 50, map[string]string{"name": "https://example.com/a"}),
+	})
+
+	// the sub-metric with a threshold in the options keeps it
+	a := e.Metrics["http_req_duration{name:https://example.com/a}"]
+	require.NotNil(t, a)
+	assert.Equal(t, []string{`p(95)<100`}, sourcesOf(a.Thresholds))
+	b := e.Metrics["http_req_duration{name:https://example.com/b,c}"]
+	require.NotNil(t, b)
+	assert.Equal(t, []string{`p(95)<200`}, sourcesOf(b.Thresholds))
+	assert.Equal(t, map[string]string{"name": "https://example.com/b,c"}, b.Sub.Tags.CloneTags())
+	assert.Equal(t, uint64(1), b.Sink.(*stats.TrendSink).Count)
+	group := e.Metrics["http_req_duration{group:::login}"]
+	require.NotNil(t, group)
+	assert.Equal(t, uint64(2), group.Sink.(*stats.TrendSink).Count)
+
+	assert.NotContains(t, e.Metrics, "http_req_duration{name:https://example.com/static/x.png}")
+	assert.NotContains(t, e.Metrics, "other{name:https://example.com/a}")
+	// over the limit of the name tag, which the declared sub-metric doesn't count against
+	assert.Contains(t, e.Metrics, "http_req_duration{name:https://example.com/d}")
+	e.processSamples([]stats.SampleContainer{
+		sample(metrics.HTTPReqDuration, 50, map[string]string{"name": "https://example.com/e"}),
+	})
+	assert.NotContains(t, e.Metrics, "http_req_duration{name:https://example.com/e}")
+
+	assert.False(t, e.processThresholds())
+	assert.True(t, b.Tainted.Bool)
+	assert.False(t, a.Tainted.Bool)
+}
+
+func TestEngineErrorClassSubmetrics(t *testing.T) {
+	t.Parallel()
+	tags := stats.IntoSampleTags(&map[string]string{"error_class": "tcp.reset"})
+	e, _, wait := newTestEngine(t, nil, nil, nil, lib.Options{})
+	defer wait()
+	e.processSamples([]stats.SampleContainer{
+		stats.Sample{Metric: metrics.HTTPReqErrors, Value: 1, Time: time.Now(), Tags: tags},
+	})
+	assert.Contains(t, e.Metrics, "http_req_errors{error_class:tcp.reset}")
+
+	e, _, wait = newTestEngine(t, nil, nil, nil, lib.Options{})
+	defer wait()
+	e.DisableAutoSubmetrics()
+	e.processSamples([]stats.SampleContainer{
+		stats.Sample{Metric: metrics.HTTPReqErrors, Value: 1, Time: time.Now(), Tags: tags},
+	})
+	assert.NotContains(t, e.Metrics, "http_req_errors{error_class:tcp.reset}")
+}
+
+func TestEngineMetricsFilter(t *testing.T) {
+	t.Parallel()
+	mockOutput := mockoutput.New()
+	e, _, wait := newTestEngine(t, nil, nil, []output.Output{mockOutput}, lib.Options{
+		Metrics: &lib.MetricsFilter{Include: []string{"http_req*", "iterations"}, Exclude: []string{"http_req_blocked"}},
+	})
+	defer wait()
+
+	now := time.Now()
+	trail := stats.ConnectedSamples{Samples: []stats.Sample{
+		{Metric: metrics.HTTPReqs, Value: 1, Time: now},
+		{Metric: metrics.HTTPReqDuration, Value: 10, Time: now},
+	}}
+	e.processSamples([]stats.SampleContainer{
+		trail,
+		stats.Samples{
+			{Metric: metrics.Iterations, Value: 1, Time: now},
+			{Metric: metrics.DataSent, Value: 100, Time: now},
+			{Metric: metrics.HTTPReqBlocked, Value: 1, Time: now},
+		},
+		stats.Sample{Metric: metrics.IterationDuration, Value: 1, Time: now},
+	})
+
+	// the containers without dropped samples are kept as they are
+	require.Len(t, mockOutput.SampleContainers, 2)
+	assert.Equal(t, trail, mockOutput.SampleContainers[0])
+	names := []string{}
+	for _, sample := range mockOutput.Samples {
+		names = append(names, sample.Metric.Name)
+	}
+	assert.Equal(t, []string{"http_reqs", "http_req_duration", "iterations"}, names)
+
+	// the dropped metrics are still in the summary
+	assert.Contains(t, e.Metrics, metrics.DataSent.Name)
+	assert.Contains(t, e.Metrics, metrics.IterationDuration.Name)
+}
+
+func TestEngineStaticTags(t *testing.T) {
+	t.Parallel()
+	mockOutput := mockoutput.New()
+	e, _, wait := newTestEngine(t, nil, nil, []output.Output{mockOutput}, lib.Options{
+		StaticTags:     map[string]string{"build": "123", "env": "staging"},
+		StaticMetadata: map[string]string{"commit": "abc"},
+	})
+	defer wait()
+
+	now := time.Now()
+	tags := stats.NewSampleTags(map[string]string{"env": "prod", "name": "home"})
+	connected := stats.ConnectedSamples{Samples: []stats.Sample{
+		{Metric: metrics.HTTPReqs, Value: 1, Time: now, Tags: tags, Metadata: map[string]string{"trace_id": "1"}},
+		{Metric: metrics.HTTPReqDuration, Value: 10, Time: now, Tags: tags},
+	}, Tags: tags}
+	trail := &httpext.Trail{Tags: tags, Samples: []stats.Sample{
+		{Metric: metrics.HTTPReqs, Value: 1, Time: now, Tags: tags},
+	}}
+	e.processSamples([]stats.SampleContainer{
+		connected,
+		stats.Sample{Metric: metrics.Iterations, Value: 1, Time: now},
+		trail,
+	})
+
+	require.Len(t, mockOutput.SampleContainers, 3)
+	require.IsType(t, stats.ConnectedSamples{}, mockOutput.SampleContainers[0])
+	require.Len(t, mockOutput.Samples, 4)
+	expected := map[string]string{"build": "123", "env": "prod", "name": "home"}
+	assert.Equal(t, expected, mockOutput.Samples[0].Tags.CloneTags())
+	assert.Same(t, mockOutput.Samples[0].Tags, mockOutput.Samples[1].Tags)
+	assert.Equal(t, map[string]string{"commit": "abc", "trace_id": "1"}, mockOutput.Samples[0].Metadata)
+	assert.Equal(t, map[string]string{"commit": "abc"}, mockOutput.Samples[1].Metadata)
+	assert.Equal(t, map[string]string{"build": "123", "env": "staging"}, mockOutput.Samples[2].Tags.CloneTags())
+	// the tags of the containers, which the cloud output uses, are the same as the ones of their samples
+	assert.Same(t, mockOutput.Samples[0].Tags, mockOutput.SampleContainers[0].(stats.ConnectedSamples).Tags)
+	assert.Same(t, mockOutput.Samples[0].Tags, trail.Tags)
+	assert.Same(t, mockOutput.Samples[0].Tags, mockOutput.Samples[3].Tags)
+
+	// the summary has the original tags
+	assert.Equal(t, map[string]string{"env": "prod", "name": "home"}, tags.CloneTags())
+}
+
+func TestEngineRuntimeTags(t *testing.T) {
+	t.Parallel()
+	mockOutput := mockoutput.New()
+	e, _, wait := newTestEngine(t, nil, nil, []output.Output{mockOutput}, lib.Options{
+		StaticTags: map[string]string{"build": "123", "phase": "baseline"},
+	})
+	defer wait()
+
+	emit := func() map[string]string {
+		mockOutput.Samples = nil
+		e.processSamples([]stats.SampleContainer{
+			stats.Sample{Metric: metrics.Iterations, Value: 1, Time: time.Now()},
+		})
+		require.Len(t, mockOutput.Samples, 1)
+		return mockOutput.Samples[0].Tags.CloneTags()
+	}
+
+	require.NoError(t, e.UpdateTags(map[string]string{"phase": "deployment", "canary": "v2"}, nil))
+	assert.Equal(t, map[string]string{"build": "123", "phase": "deployment", "canary": "v2"}, emit())
+	assert.Equal(t, map[string]string{"phase": "deployment", "canary": "v2"}, e.RuntimeTags())
+
+	require.NoError(t, e.UpdateTags(nil, []string{"phase", "canary"}))
+	assert.Equal(t, map[string]string{"build": "123", "phase": "baseline"}, emit())
+	assert.Empty(t, e.RuntimeTags())
+
+	assert.Error(t, e.UpdateTags(map[string]string{"": "empty"}, nil))
+}
+
+type bufferedOutput struct {
+	*mockoutput.MockOutput
+	stats output.SampleBufferStats
+}
+
+func (o *bufferedOutput) BufferStats() (output.SampleBufferStats, bool) {
+	return o.stats, true
+}
+
+func TestEngineOutputBufferSamples(t *testing.T) {
+	t.Parallel()
+	out := &bufferedOutput{MockOutput: mockoutput.New()}
+	e, _, wait := newTestEngine(t, nil, nil, []output.Output{out, mockoutput.New()}, lib.Options{})
+	defer wait()
+
+	out.stats = output.SampleBufferStats{Buffered: 10, Spilled: 5, Dropped: 3}
+	samples := e.getOutputBufferSamples(time.Now())
+	require.Len(t, samples, 1)
+	require.Len(t, samples[0].GetSamples(), 2)
+	depth, dropped := samples[0].GetSamples()[0], samples[0].GetSamples()[1]
+	assert.Equal(t, metrics.OutputBufferSamples, depth.Metric)
+	assert.Equal(t, 15.0, depth.Value)
+	assert.Equal(t, map[string]string{"output": "mock"}, depth.Tags.CloneTags())
+	assert.Equal(t, 3.0, dropped.Value)
+
+	// the drops are counted since the last samples
+	out.stats.Dropped = 4
+	samples = e.getOutputBufferSamples(time.Now())
+	assert.Equal(t, 1.0, samples[0].GetSamples()[1].Value)
+}
+
+func sourcesOf(ths stats.Thresholds) []string {
+	sources := make([]string, len(ths.Thresholds))
+	for i, th := range ths.Thresholds {
+		sources[i] = th.Source
+	}
+	return sources
+}
+
+func TestEngineTimeSeries(t *testing.T) {
+	t.Parallel()
+	ths, err := stats.NewThresholds([]string{`1+1==2`})
+	require.NoError(t, err)
+	metric := stats.New("my_metric", stats.Counter)
+	tags := stats.IntoSampleTags(&map[string]string{"a": "1"})
+	now := time.Now()
+
+	e, _, wait := newTestEngine(t, nil, nil, nil, lib.Options{})
+	assert.Nil(t, e.TimeSeries)
+	wait()
+
+	e, _, wait = newTestEngine(t, nil, nil, nil, lib.Options{
+		SummaryTimeSeriesInterval: types.NullDurationFrom(time.Second),
+		Thresholds:                map[string]stats.Thresholds{"my_metric{a:1}": ths},
+	})
+	defer wait()
+	require.NotNil(t, e.TimeSeries)
+
+	e.processSamples([]stats.SampleContainer{
+		stats.Sample{Metric: metric, Value: 1, Time: now, Tags: tags},
+		stats.Sample{Metric: metric, Value: 1, Time: now.Add(2 * time.Second)},
+	})
+	assert.Len(t, e.TimeSeries.Get("my_metric"), 2)
+	assert.Len(t, e.TimeSeries.Get("my_metric{a:1}"), 1)
+}
+
+func TestEngineMetricValues(t *testing.T) {
+	t.Parallel()
+	e, _, wait := newTestEngine(t, nil, nil, nil, lib.Options{})
+	defer wait()
+
+	trend := stats.New("my_trend", stats.Trend)
+	counter := stats.New("my_counter", stats.Counter)
+	var samples []stats.SampleContainer
+	for i := 1; i <= 100; i++ {
+		samples = append(samples, stats.Sample{Metric: trend, Value: float64(i), Time: time.Now()})
+	}
+	samples = append(samples, stats.Sample{Metric: counter, Value: 3, Time: time.Now()})
+	e.processSamples(samples)
+
+	state := e.ExecutionScheduler.GetState()
+	value, ok, err := state.GetMetricValue("my_trend", "p(95)")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.InDelta(t, 95.05, value, 0.001)
+	value, _, err = state.GetMetricValue("my_trend", "p(99.9)")
+	require.NoError(t, err)
+	assert.InDelta(t, 99.901, value, 0.001)
+	value, _, err = state.GetMetricValue("my_trend", "")
+	require.NoError(t, err)
+	assert.Equal(t, 50.5, value)
+	value, _, err = state.GetMetricValue("my_counter", "")
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, value)
+
+	_, ok, err = state.GetMetricValue("other_metric", "avg")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, _, err = state.GetMetricValue("my_trend", "p(101)")
+	assert.EqualError(t, err, "invalid percentile 'p(101)' of the metric 'my_trend'")
+	_, _, err = state.GetMetricValue("my_counter", "p(95)")
+	assert.EqualError(t, err, "the metric 'my_counter' doesn't have the 'p(95)' aggregation, it has count, rate")
 }
 
 func TestEngineThresholdsWillAbort(t *testing.T) {
@@ -376,6 +736,41 @@ func TestEngine_processThresholds(t *testing.T) {
 	}
 }
 
+func TestEngineThresholdCrossedEvents(t *testing.T) {
+	t.Parallel()
+	metric := stats.New("my_metric", stats.Gauge)
+	ths, err := stats.NewThresholds([]string{"value<2"})
+	require.NoError(t, err)
+	e, _, wait := newTestEngine(t, nil, nil, nil, lib.Options{
+		Thresholds: map[string]stats.Thresholds{"my_metric": ths},
+	})
+	defer wait()
+
+	var published []events.Event
+	bus := events.NewBus("run-1")
+	bus.Subscribe(func(ev events.Event) { published = append(published, ev) })
+	e.SetEvents(bus)
+
+	process := func(value float64) {
+		e.processSamples([]stats.SampleContainer{stats.Sample{Metric: metric, Value: value, Time: time.Now()}})
+		e.processThresholds()
+	}
+	process(1)
+	assert.Empty(t, published)
+	process(3)
+	process(4)
+	require.Len(t, published, 1)
+	assert.Equal(t, events.ThresholdCrossed, published[0].Type)
+	assert.Equal(t, map[string]interface{}{
+		"metric": "my_metric", "failed": true, "thresholds": []string{"value<2"},
+	}, published[0].Data)
+	process(1)
+	require.Len(t, published, 2)
+	assert.Equal(t, map[string]interface{}{
+		"metric": "my_metric", "failed": false, "thresholds": []string{},
+	}, published[1].Data)
+}
+
 func getMetricSum(mo *mockoutput.MockOutput, name string) (result float64) {
 	for _, sc := range mo.SampleContainers {
 		for _, s := range sc.GetSamples() {
@@ -765,8 +1160,8 @@ func TestSetupException(t *testing.T) {
 		require.Error(t, err)
 		var exception errext.Exception
 		require.ErrorAs(t, err, &exception)
-		require.Equal(t, "Error: baz\n\tat baz (file:///bar.js:7:8(4))\n"+
-			"\tat file:///bar.js:4:5(3)\n\tat setup (file:///script.js:7:204(4))\n",
+		require.Equal(t, "Error: baz\n\tat baz (file:///bar.js:7:17(4))\n"+
+			"\tat file:///bar.js:4:12(3)\n\tat setup (file:///script.js:4:6(4))\n",
 			err.Error())
 	}
 }
@@ -813,7 +1208,7 @@ func TestVuInitException(t *testing.T) {
 
 	var exception errext.Exception
 	require.ErrorAs(t, err, &exception)
-	assert.Equal(t, "Error: oops in 2\n\tat file:///script.js:10:8(32)\n", err.Error())
+	assert.Equal(t, "Error: oops in 2\n\tat file:///script.js:10:10(42)\n", err.Error())
 
 	var errWithHint errext.HasHint
 	require.ErrorAs(t, err, &errWithHint)
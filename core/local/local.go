@@ -31,6 +31,7 @@ import (
 
 	"go.k6.io/k6/errext"
 	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/events"
 	"go.k6.io/k6/stats"
 	"go.k6.io/k6/ui/pb"
 )
@@ -48,6 +49,7 @@ type ExecutionScheduler struct {
 	maxDuration     time.Duration // cached value derived from the execution plan
 	maxPossibleVUs  uint64        // cached value derived from the execution plan
 	state           *lib.ExecutionState
+	events          *events.Bus
 }
 
 // Check to see if we implement the lib.ExecutionScheduler interface
@@ -117,6 +119,32 @@ func (e *ExecutionScheduler) GetRunner() lib.Runner {
 	return e.runner
 }
 
+// SetEvents sets the bus that the scheduler publishes the events of the finished scenarios to.
+// It has to be called before the test run starts.
+func (e *ExecutionScheduler) SetEvents(bus *events.Bus) {
+	e.events = bus
+}
+
+// publishScenarioEnd publishes the event of the finished scenario, with its iterations if
+// the executor counts them, and its error.
+func (e *ExecutionScheduler) publishScenarioEnd(executor lib.Executor, err error) {
+	config := executor.GetConfig()
+	data := map[string]interface{}{
+		"scenario": config.GetName(),
+		"executor": config.GetType(),
+	}
+	if observable, ok := executor.(lib.ObservableExecutor); ok {
+		st := observable.GetStats()
+		data["iterations"] = st.Iterations
+		data["iterationErrors"] = st.IterationErrors
+		data["interruptedIterations"] = st.InterruptedIterations
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	e.events.Publish(events.ScenarioEnd, data)
+}
+
 // GetState returns a pointer to the execution state struct for the local
 // execution scheduler. It's guaranteed to be initialized and present, though
 // see the documentation in lib/execution.go for caveats about its usage. The
@@ -289,7 +317,8 @@ func (e *ExecutionScheduler) Init(ctx context.Context, samplesOut chan<- stats.S
 // configured startTime for the specific executor and then running its Run()
 // method.
 func (e *ExecutionScheduler) runExecutor(
-	runCtx context.Context, runResults chan<- error, engineOut chan<- stats.SampleContainer, executor lib.Executor,
+	globalCtx, runCtx context.Context, runResults chan<- error, engineOut chan<- stats.SampleContainer,
+	executor lib.Executor,
 ) {
 	executorConfig := executor.GetConfig()
 	executorStartTime := executorConfig.GetStartTime()
@@ -332,9 +361,54 @@ func (e *ExecutionScheduler) runExecutor(
 	} else {
 		executorLogger.WithField("error", err).Errorf("Executor error")
 	}
+
+	if teardownFn := executorConfig.GetTeardown(); teardownFn != "" && !e.options.NoTeardown.Bool {
+		executorLogger.Debugf("Running %s()", teardownFn)
+		// Like teardown(), this is run with the global context, so it isn't
+		// interrupted by aborts caused by thresholds or Ctrl+C.
+		teardownErr := e.runner.ScenarioTeardown(globalCtx, engineOut, executorConfig.GetName(), teardownFn)
+		if teardownErr != nil {
+			executorLogger.WithField("error", teardownErr).Debugf("%s() aborted by error", teardownFn)
+			if err == nil {
+				err = teardownErr
+			}
+		}
+	}
+	e.publishScenarioEnd(executor, err)
 	runResults <- err
 }
 
+// runScenarioSetups runs the setup functions of the scenarios one after the
+// other, right after setup() and before any executor is started. This way the
+// time they take isn't part of the scenarios, which still start at their
+// startTime, like with setup(). Each of them is bounded by the setupTimeout.
+func (e *ExecutionScheduler) runScenarioSetups(ctx context.Context, engineOut chan<- stats.SampleContainer) error {
+	if e.options.NoSetup.Bool {
+		return nil
+	}
+	for _, executor := range e.executors {
+		executorConfig := executor.GetConfig()
+		setupFn := executorConfig.GetSetup()
+		if setupFn == "" {
+			continue
+		}
+
+		executorLogger := e.logger.WithField("executor", executorConfig.GetName())
+		executorLogger.Debugf("Running %s()", setupFn)
+		e.initProgress.Modify(pb.WithConstProgress(1, setupFn+"()"))
+		executor.GetProgress().Modify(
+			pb.WithStatus(pb.Running),
+			pb.WithConstProgress(0, setupFn+"()"),
+		)
+		if err := e.runner.ScenarioSetup(ctx, engineOut, executorConfig.GetName(), setupFn); err != nil {
+			executorLogger.WithField("error", err).Debugf("%s() aborted by error", setupFn)
+			e.publishScenarioEnd(executor, err)
+			return err
+		}
+	}
+	return nil
+}
+
 // Run the ExecutionScheduler, funneling all generated metric samples through the supplied
 // out channel.
 func (e *ExecutionScheduler) Run(globalCtx, runCtx context.Context, engineOut chan<- stats.SampleContainer) error {
@@ -365,6 +439,7 @@ func (e *ExecutionScheduler) Run(globalCtx, runCtx context.Context, engineOut ch
 	runCtx = lib.WithExecutionState(runCtx, e.state)
 	runSubCtx, cancel := context.WithCancel(runCtx)
 	defer cancel() // just in case, and to shut up go vet...
+	e.state.SetAbortRunFunc(cancel)
 
 	// Run setup() before any executors, if it's not disabled
 	if !e.options.NoSetup.Bool {
@@ -373,16 +448,25 @@ func (e *ExecutionScheduler) Run(globalCtx, runCtx context.Context, engineOut ch
 		e.initProgress.Modify(pb.WithConstProgress(1, "setup()"))
 		if err := e.runner.Setup(runSubCtx, engineOut); err != nil {
 			logger.WithField("error", err).Debug("setup() aborted by error")
+			if abortErr := e.state.GetAbortError(); abortErr != nil {
+				return abortErr
+			}
 			return err
 		}
 	}
+	if err := e.runScenarioSetups(runSubCtx, engineOut); err != nil {
+		if abortErr := e.state.GetAbortError(); abortErr != nil {
+			return abortErr
+		}
+		return err
+	}
 	e.initProgress.Modify(pb.WithHijack(e.getRunStats))
 
 	// Start all executors at their particular startTime in a separate goroutine...
 	logger.Debug("Start all executors...")
 	e.state.SetExecutionStatus(lib.ExecutionStatusRunning)
 	for _, exec := range e.executors {
-		go e.runExecutor(runSubCtx, runResults, engineOut, exec)
+		go e.runExecutor(globalCtx, runSubCtx, runResults, engineOut, exec)
 	}
 
 	// Wait for all executors to finish
@@ -396,6 +480,9 @@ func (e *ExecutionScheduler) Run(globalCtx, runCtx context.Context, engineOut ch
 		}
 	}
 
+	// All VUs are idle and won't be activated again, so their lifetime is over
+	e.state.StopVUs()
+
 	// Run teardown() after all executors are done, if it's not disabled
 	if !e.options.NoTeardown.Bool {
 		logger.Debug("Running teardown()")
@@ -410,6 +497,10 @@ func (e *ExecutionScheduler) Run(globalCtx, runCtx context.Context, engineOut ch
 		}
 	}
 
+	// The executors were stopped by the script, their errors are the consequences
+	if abortErr := e.state.GetAbortError(); abortErr != nil {
+		return abortErr
+	}
 	return firstErr
 }
 
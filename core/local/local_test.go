@@ -29,6 +29,7 @@ import (
 	"net/url"
 	"reflect"
 	"runtime"
+	"strconv"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -39,6 +40,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"gopkg.in/guregu/null.v3"
 
+	"go.k6.io/k6/errext"
+	"go.k6.io/k6/errext/exitcodes"
 	"go.k6.io/k6/js"
 	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/executor"
@@ -273,6 +276,94 @@ func TestExecutionSchedulerRunEnv(t *testing.T) {
 	}
 }
 
+func TestExecutionSchedulerAbortTest(t *testing.T) {
+	t.Parallel()
+
+	scriptTemplate := `
+	import exec from "k6/execution";
+	import { Counter } from "k6/metrics";
+
+	let teardowns = new Counter("teardowns");
+
+	export let options = {
+		scenarios: {
+			long: { executor: "constant-vus", vus: 2, duration: "10s", gracefulStop: "0s" },
+		},
+		setupTimeout: "10s",
+		teardownTimeout: "10s",
+	};
+
+	export function setup() {
+		%[1]s
+	}
+
+	export default function () {
+		%[2]s
+	}
+
+	export function teardown() {
+		teardowns.add(1);
+	}`
+
+	testCases := []struct {
+		name, setup, iteration string
+		reason                 string
+		code                   errext.ExitCode
+		teardown               bool
+	}{
+		{"setup", `exec.abortTest("no test data", 3); throw new Error("after the abort");`, ``, "no test data", 3, false},
+		{"iteration", ``, `if (__VU === 2) { exec.abortTest(); }`, "", exitcodes.ScriptAborted, true},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			logger := logrus.New()
+			logger.SetOutput(testutils.NewTestOutput(t))
+			runner, err := js.New(logger, &loader.SourceData{
+				URL:  &url.URL{Path: "/script.js"},
+				Data: []byte(fmt.Sprintf(scriptTemplate, tc.setup, tc.iteration)),
+			}, nil, lib.RuntimeOptions{})
+			require.NoError(t, err)
+
+			execScheduler, err := NewExecutionScheduler(runner, logger)
+			require.NoError(t, err)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var runErr error
+			var teardowns float64
+			done := make(chan struct{})
+			samples := make(chan stats.SampleContainer)
+			go func() {
+				assert.NoError(t, execScheduler.Init(ctx, samples))
+				runErr = execScheduler.Run(ctx, ctx, samples)
+				close(done)
+			}()
+			start := time.Now()
+		loop:
+			for {
+				select {
+				case sample := <-samples:
+					if s, ok := sample.(stats.Sample); ok && s.Metric.Name == "teardowns" {
+						teardowns += s.Value
+					}
+				case <-done:
+					break loop
+				}
+			}
+			assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+
+			var abortErr *errext.AbortTest
+			require.True(t, errors.As(runErr, &abortErr), "%v", runErr)
+			assert.Equal(t, tc.reason, abortErr.Reason)
+			assert.Equal(t, tc.code, abortErr.ExitCode())
+			assert.Equal(t, lib.AbortReasonScript, execScheduler.GetState().GetAbortReason())
+			assert.Equal(t, tc.teardown, teardowns == 1)
+		})
+	}
+}
+
 func TestExecutionSchedulerSystemTags(t *testing.T) {
 	t.Parallel()
 	tb := httpmultibin.NewHTTPMultiBin(t)
@@ -773,6 +864,246 @@ func TestExecutionSchedulerSetupTeardownRun(t *testing.T) {
 	})
 }
 
+func TestExecutionSchedulerScenarioSetupTeardown(t *testing.T) {
+	t.Parallel()
+	script := []byte(`
+	import { Counter } from "k6/metrics";
+	let seen = new Counter("seen");
+
+	export let options = {
+		scenarios: {
+			with_setup: {
+				executor: "per-vu-iterations",
+				vus: 1,
+				iterations: 1,
+				exec: "run",
+				setup: "scenarioSetup",
+				teardown: "scenarioTeardown",
+			},
+			without_setup: {
+				executor: "per-vu-iterations",
+				vus: 1,
+				iterations: 1,
+				exec: "run",
+			},
+		}
+	}
+
+	export function setup() { return { v: "global" }; }
+	export function scenarioSetup(data) { return { v: "scenario-" + data.v }; }
+	export function run(data) { seen.add(1, { v: data.v }); }
+	export function scenarioTeardown(data) {
+		if (data.v !== "scenario-global") {
+			throw new Error("unexpected data " + data.v);
+		}
+		seen.add(1, { v: "teardown" });
+	}`)
+
+	logger := logrus.New()
+	logger.SetOutput(testutils.NewTestOutput(t))
+	runner, err := js.New(logger, &loader.SourceData{
+		URL: &url.URL{Path: "/script.js"}, Data: script,
+	}, nil, lib.RuntimeOptions{})
+	require.NoError(t, err)
+	require.NoError(t, runner.SetOptions(runner.GetOptions().Apply(lib.Options{
+		SetupTimeout:    types.NullDurationFrom(4 * time.Second),
+		TeardownTimeout: types.NullDurationFrom(4 * time.Second),
+	})))
+
+	execScheduler, err := NewExecutionScheduler(runner, logger)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	samples := make(chan stats.SampleContainer)
+	go func() {
+		assert.NoError(t, execScheduler.Init(ctx, samples))
+		assert.NoError(t, execScheduler.Run(ctx, ctx, samples))
+		close(done)
+	}()
+
+	var seen []string
+	for {
+		select {
+		case sampleContainer := <-samples:
+			for _, s := range sampleContainer.GetSamples() {
+				if s.Metric.Name == "seen" {
+					v, _ := s.Tags.Get("v")
+					seen = append(seen, v)
+				}
+			}
+		case <-done:
+			assert.ElementsMatch(t, []string{"scenario-global", "global", "teardown"}, seen)
+			return
+		}
+	}
+}
+
+func TestExecutionSchedulerScenarioSetupStartTime(t *testing.T) {
+	t.Parallel()
+	script := []byte(`
+	import { sleep } from "k6";
+	import { Gauge } from "k6/metrics";
+	let started = new Gauge("started");
+
+	export let options = {
+		scenarios: {
+			early: {
+				executor: "per-vu-iterations",
+				vus: 1,
+				iterations: 1,
+				exec: "runEarly",
+			},
+			late: {
+				executor: "per-vu-iterations",
+				vus: 1,
+				iterations: 1,
+				exec: "runLate",
+				startTime: "500ms",
+				setup: "slowSetup",
+			},
+		}
+	}
+
+	export function slowSetup() {
+		sleep(1);
+		return { done: Date.now() };
+	}
+	export function runEarly() {
+		started.add(Date.now(), { started: "early" });
+	}
+	export function runLate(data) {
+		started.add(Date.now(), { started: "late", setup_done: String(data.done) });
+	}`)
+
+	logger := logrus.New()
+	logger.SetOutput(testutils.NewTestOutput(t))
+	runner, err := js.New(logger, &loader.SourceData{
+		URL: &url.URL{Path: "/script.js"}, Data: script,
+	}, nil, lib.RuntimeOptions{})
+	require.NoError(t, err)
+	require.NoError(t, runner.SetOptions(runner.GetOptions().Apply(lib.Options{
+		SetupTimeout: types.NullDurationFrom(4 * time.Second),
+	})))
+
+	execScheduler, err := NewExecutionScheduler(runner, logger)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	samples := make(chan stats.SampleContainer)
+	go func() {
+		assert.NoError(t, execScheduler.Init(ctx, samples))
+		assert.NoError(t, execScheduler.Run(ctx, ctx, samples))
+		close(done)
+	}()
+
+	startedAt := map[string]float64{}
+	var setupDone float64
+	for {
+		select {
+		case sampleContainer := <-samples:
+			for _, s := range sampleContainer.GetSamples() {
+				if s.Metric.Name != "started" {
+					continue
+				}
+				scenario, _ := s.Tags.Get("started")
+				startedAt[scenario] = s.Value
+				if v, _ := s.Tags.Get("setup_done"); v != "" {
+					setupDone, err = strconv.ParseFloat(v, 64)
+					require.NoError(t, err)
+				}
+			}
+		case <-done:
+			require.Len(t, startedAt, 2)
+			// The second of setup time isn't added to the startTime of the
+			// late scenario, since its setup is run before both scenarios.
+			assert.GreaterOrEqual(t, startedAt["early"], setupDone)
+			assert.InDelta(t, 500, startedAt["late"]-startedAt["early"], 300)
+			return
+		}
+	}
+}
+
+func TestExecutionSchedulerVULifecycleFunctions(t *testing.T) {
+	t.Parallel()
+	script := []byte(`
+	import { Counter } from "k6/metrics";
+	let calls = new Counter("calls");
+
+	export let options = {
+		scenarios: {
+			first: {
+				executor: "per-vu-iterations",
+				vus: 1,
+				iterations: 2,
+				maxDuration: "200ms",
+				gracefulStop: "0s",
+				exec: "runFirst",
+			},
+			second: {
+				executor: "per-vu-iterations",
+				vus: 1,
+				iterations: 2,
+				startTime: "300ms",
+				exec: "runSecond",
+			},
+		}
+	}
+
+	export function onVUStart() { calls.add(1, { fn: "start" }); }
+	export function runFirst() { calls.add(1, { fn: "first" }); }
+	export function runSecond() { calls.add(1, { fn: "second" }); }
+	export function onVUStop() { calls.add(1, { fn: "stop" }); }
+	export function teardown() { calls.add(1, { fn: "teardown" }); }`)
+
+	logger := logrus.New()
+	logger.SetOutput(testutils.NewTestOutput(t))
+	runner, err := js.New(logger, &loader.SourceData{
+		URL: &url.URL{Path: "/script.js"}, Data: script,
+	}, nil, lib.RuntimeOptions{})
+	require.NoError(t, err)
+	require.NoError(t, runner.SetOptions(runner.GetOptions().Apply(lib.Options{
+		TeardownTimeout: types.NullDurationFrom(4 * time.Second),
+	})))
+
+	execScheduler, err := NewExecutionScheduler(runner, logger)
+	require.NoError(t, err)
+	// both scenarios are executed by the same VU
+	require.Equal(t, uint64(1), lib.GetMaxPlannedVUs(execScheduler.GetExecutionPlan()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	samples := make(chan stats.SampleContainer)
+	go func() {
+		assert.NoError(t, execScheduler.Init(ctx, samples))
+		assert.NoError(t, execScheduler.Run(ctx, ctx, samples))
+		close(done)
+	}()
+
+	var calls []string
+	for {
+		select {
+		case sampleContainer := <-samples:
+			for _, s := range sampleContainer.GetSamples() {
+				if s.Metric.Name == "calls" {
+					fn, _ := s.Tags.Get("fn")
+					calls = append(calls, fn)
+				}
+			}
+		case <-done:
+			assert.Equal(t, []string{"start", "first", "first", "second", "second", "stop", "teardown"}, calls)
+			return
+		}
+	}
+}
+
 func TestExecutionSchedulerStages(t *testing.T) {
 	t.Parallel()
 	testdata := map[string]struct {
@@ -0,0 +1,69 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/stats"
+)
+
+// metricsFilter drops the samples of the metrics of the metrics option before they are sent to
+// the outputs. It's guarded by the MetricsLock of the Engine.
+type metricsFilter struct {
+	patterns *lib.MetricPatterns
+}
+
+func newMetricsFilter(conf lib.MetricsFilter) *metricsFilter {
+	return &metricsFilter{patterns: lib.NewMetricPatterns(conf.Include, conf.Exclude)}
+}
+
+func (f *metricsFilter) isAllowed(metric string) bool {
+	return f.patterns.Match(metric)
+}
+
+// apply returns the containers with only the samples of the allowed metrics. A container loses
+// its type only when some of its samples are dropped, the untouched ones stay as they are.
+func (f *metricsFilter) apply(containers []stats.SampleContainer) []stats.SampleContainer {
+	result := make([]stats.SampleContainer, 0, len(containers))
+	for _, sc := range containers {
+		samples := sc.GetSamples()
+		dropped := 0
+		for _, sample := range samples {
+			if !f.isAllowed(sample.Metric.Name) {
+				dropped++
+			}
+		}
+		switch dropped {
+		case 0:
+			result = append(result, sc)
+		case len(samples):
+		default:
+			kept := make(stats.Samples, 0, len(samples)-dropped)
+			for _, sample := range samples {
+				if f.isAllowed(sample.Metric.Name) {
+					kept = append(kept, sample)
+				}
+			}
+			result = append(result, kept)
+		}
+	}
+	return result
+}
@@ -0,0 +1,119 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"go.k6.io/k6/lib/netext"
+	"go.k6.io/k6/lib/netext/httpext"
+	"go.k6.io/k6/stats"
+)
+
+// staticTags adds the static tags and metadata of the options, and the runtime tags, to the samples
+// before they are sent to the outputs, the values of the samples take precedence. It's guarded by
+// the MetricsLock of the Engine.
+type staticTags struct {
+	tags     map[string]string
+	metadata map[string]string
+	empty    *stats.SampleTags
+}
+
+func newStaticTags(tags, metadata map[string]string) *staticTags {
+	return &staticTags{
+		tags:     tags,
+		metadata: metadata,
+		empty:    stats.NewSampleTags(tags),
+	}
+}
+
+// apply updates the samples of the containers in place, instead of flattening them, so the
+// containers keep their types. The tags of the connected containers are updated too, since
+// outputs like the cloud one use them instead of the ones of the samples. The same tags get the
+// same merged tags, so the outputs can still compare them by their pointers.
+func (st *staticTags) apply(containers []stats.SampleContainer) []stats.SampleContainer {
+	merged := make(map[*stats.SampleTags]*stats.SampleTags)
+	for i, sc := range containers {
+		switch c := sc.(type) {
+		case stats.Sample:
+			containers[i] = st.sample(c, merged)
+			continue
+		case stats.ConnectedSamples:
+			c.Tags = st.mergeTags(c.Tags, merged)
+			containers[i] = c
+		case *httpext.Trail:
+			c.Tags = st.mergeTags(c.Tags, merged)
+		case *netext.NetTrail:
+			c.Tags = st.mergeTags(c.Tags, merged)
+		}
+		samples := sc.GetSamples()
+		for j := range samples {
+			samples[j] = st.sample(samples[j], merged)
+		}
+	}
+	return containers
+}
+
+// mergeTags returns the tags with the static tags that they don't have.
+func (st *staticTags) mergeTags(
+	tags *stats.SampleTags, merged map[*stats.SampleTags]*stats.SampleTags,
+) *stats.SampleTags {
+	if len(st.tags) == 0 {
+		return tags
+	}
+	if result, ok := merged[tags]; ok {
+		return result
+	}
+	if tags.IsEmpty() {
+		merged[tags] = st.empty
+		return st.empty
+	}
+	tagsMap := tags.CloneTags()
+	for k, v := range st.tags {
+		if _, ok := tagsMap[k]; !ok {
+			tagsMap[k] = v
+		}
+	}
+	result := stats.IntoSampleTags(&tagsMap)
+	merged[tags] = result
+	return result
+}
+
+func (st *staticTags) sample(
+	sample stats.Sample, merged map[*stats.SampleTags]*stats.SampleTags,
+) stats.Sample {
+	sample.Tags = st.mergeTags(sample.Tags, merged)
+
+	if len(st.metadata) > 0 {
+		if len(sample.Metadata) == 0 {
+			// the outputs don't change the metadata, so it can be shared
+			sample.Metadata = st.metadata
+		} else {
+			metadata := make(map[string]string, len(st.metadata)+len(sample.Metadata))
+			for k, v := range st.metadata {
+				metadata[k] = v
+			}
+			for k, v := range sample.Metadata {
+				metadata[k] = v
+			}
+			sample.Metadata = metadata
+		}
+	}
+	return sample
+}
@@ -0,0 +1,42 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package errext
+
+// AbortTest is the error of a test run that was aborted by the script, it has the exit code that
+// the script chose for k6.
+type AbortTest struct {
+	Reason string
+	Code   ExitCode
+}
+
+var _ HasExitCode = &AbortTest{}
+
+func (a *AbortTest) Error() string {
+	if a.Reason == "" {
+		return "the test was aborted by the script"
+	}
+	return "the test was aborted by the script: " + a.Reason
+}
+
+// ExitCode returns the exit code the script chose.
+func (a *AbortTest) ExitCode() ExitCode {
+	return a.Code
+}
@@ -36,4 +36,7 @@ const (
 	ExternalAbort            errext.ExitCode = 105
 	CannotStartRESTAPI       errext.ExitCode = 106
 	ScriptException          errext.ExitCode = 107
+	DryRunFailed             errext.ExitCode = 108
+	ScriptAborted            errext.ExitCode = 109 // the default exit code of exec.abortTest()
+	PerformanceRegression    errext.ExitCode = 110 // k6 compare found regressions
 )
@@ -37,6 +37,7 @@ import (
 
 	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/js/compiler"
+	"go.k6.io/k6/js/eventloop"
 	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/consts"
 	"go.k6.io/k6/loader"
@@ -67,6 +68,9 @@ type BundleInstance struct {
 	env map[string]string
 
 	exports map[string]goja.Callable
+
+	// runs the callbacks of the promises and the timers of the runtime
+	eventLoop *eventloop.EventLoop
 }
 
 // NewBundle creates a new bundle from a source file and a filesystem.
@@ -97,7 +101,7 @@ func NewBundle(
 		CompatibilityMode: compatMode,
 		exports:           make(map[string]goja.Callable),
 	}
-	if err = bundle.instantiate(logger, rt, bundle.BaseInitContext, 0); err != nil {
+	if _, err = bundle.instantiate(logger, rt, bundle.BaseInitContext, 0); err != nil {
 		return nil, err
 	}
 
@@ -155,7 +159,7 @@ func NewBundleFromArchive(logger logrus.FieldLogger, arc *lib.Archive, rtOpts li
 		exports:           make(map[string]goja.Callable),
 	}
 
-	if err = bundle.instantiate(logger, rt, bundle.BaseInitContext, 0); err != nil {
+	if _, err = bundle.instantiate(logger, rt, bundle.BaseInitContext, 0); err != nil {
 		return nil, err
 	}
 
@@ -225,6 +229,8 @@ func (b *Bundle) getExports(logger logrus.FieldLogger, rt *goja.Runtime, options
 			return errors.New("exported 'setup' must be a function")
 		case consts.TeardownFn:
 			return errors.New("exported 'teardown' must be a function")
+		case consts.VUStartFn, consts.VUStopFn, consts.TestAbortFn:
+			return fmt.Errorf("exported '%s' must be a function", k)
 		}
 	}
 
@@ -245,15 +251,17 @@ func (b *Bundle) Instantiate(logger logrus.FieldLogger, vuID uint64) (bi *Bundle
 	// runtime, but no state, to allow module-provided types to function within the init context.
 	rt := goja.New()
 	init := newBoundInitContext(b.BaseInitContext, ctxPtr, rt)
-	if err := b.instantiate(logger, rt, init, vuID); err != nil {
+	loop, err := b.instantiate(logger, rt, init, vuID)
+	if err != nil {
 		return nil, err
 	}
 
 	bi = &BundleInstance{
-		Runtime: rt,
-		Context: ctxPtr,
-		exports: make(map[string]goja.Callable),
-		env:     b.RuntimeOptions.Env,
+		Runtime:   rt,
+		Context:   ctxPtr,
+		exports:   make(map[string]goja.Callable),
+		env:       b.RuntimeOptions.Env,
+		eventLoop: loop,
 	}
 
 	// Grab any exported functions that could be executed. These were
@@ -283,7 +291,9 @@ func (b *Bundle) Instantiate(logger logrus.FieldLogger, vuID uint64) (bi *Bundle
 
 // Instantiates the bundle into an existing runtime. Not public because it also messes with a bunch
 // of other things, will potentially thrash data and makes a mess in it if the operation fails.
-func (b *Bundle) instantiate(logger logrus.FieldLogger, rt *goja.Runtime, init *InitContext, vuID uint64) error {
+func (b *Bundle) instantiate(
+	logger logrus.FieldLogger, rt *goja.Runtime, init *InitContext, vuID uint64,
+) (*eventloop.EventLoop, error) {
 	rt.SetParserOptions(parser.WithDisableSourceMaps)
 	rt.SetFieldNameMapper(common.FieldNameMapper{})
 	rt.SetRandSource(common.NewRandSource())
@@ -302,8 +312,23 @@ func (b *Bundle) instantiate(logger logrus.FieldLogger, rt *goja.Runtime, init *
 	rt.Set("__VU", vuID)
 	rt.Set("console", common.Bind(rt, newConsole(logger), init.ctxPtr))
 
+	loop, err := eventloop.New(rt)
+	if err != nil {
+		return nil, err
+	}
+	// the VUs replace these with the timers of the loop
+	common.BindToGlobal(rt, timersInInitContext(rt))
+
 	if init.compatibilityMode == lib.CompatibilityModeExtended {
 		rt.Set("global", rt.GlobalObject())
+		// the generator and async functions that Babel transformed need it
+		regenerator, err := compiler.RegeneratorRuntime()
+		if err != nil {
+			return nil, err
+		}
+		if _, err = rt.RunProgram(regenerator); err != nil {
+			return nil, err
+		}
 	}
 
 	// TODO: get rid of the unused ctxPtr, use a real external context (so we
@@ -316,17 +341,35 @@ func (b *Bundle) instantiate(logger logrus.FieldLogger, rt *goja.Runtime, init *
 	ctx := common.WithInitEnv(context.Background(), initenv)
 	*init.ctxPtr = common.WithRuntime(ctx, rt)
 	unbindInit := common.BindToGlobal(rt, common.Bind(rt, init, init.ctxPtr))
-	if _, err := rt.RunProgram(b.Program); err != nil {
+	err = loop.Start(context.Background(), func() error {
+		_, err := rt.RunProgram(b.Program)
+		return err
+	})
+	if err != nil {
 		var exception *goja.Exception
 		if errors.As(err, &exception) {
 			err = &scriptException{inner: exception}
 		}
-		return err
+		return nil, abortTestError(err)
 	}
 	unbindInit()
 	*init.ctxPtr = nil
 
 	rt.SetRandSource(common.NewRandSource())
 
-	return nil
+	return loop, nil
+}
+
+// timersInInitContext returns the timer functions of the init context, which
+// only throw an error, since nothing can wait for the timers there.
+func timersInInitContext(rt *goja.Runtime) map[string]interface{} {
+	timers := make(map[string]interface{})
+	for _, name := range []string{"setTimeout", "clearTimeout", "setInterval", "clearInterval"} {
+		msg := fmt.Sprintf(`The "%s()" function isn't available in the init stage (i.e. the global scope), `+
+			`see https://k6.io/docs/using-k6/test-life-cycle for more information`, name)
+		timers[name] = func() {
+			common.Throw(rt, errors.New(msg))
+		}
+	}
+	return timers
 }
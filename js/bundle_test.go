@@ -129,6 +129,28 @@ func TestNewBundle(t *testing.T) {
 			assert.Equal(t, "file:///", b.BaseInitContext.pwd.String())
 		}
 	})
+	t.Run("TypeScript", func(t *testing.T) {
+		t.Parallel()
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "/lib.ts", []byte(`
+			export interface Result { status: number }
+			export function ok(r: Result): boolean { return r.status === 200; }
+		`), 0o644))
+		b, err := getSimpleBundle(t, "/script.ts", `
+			import { Options } from "k6/options";
+			import { ok, Result } from "./lib.ts";
+			export const options: Options = { vus: 3 };
+			export default function(): void {
+				if (!ok({ status: 200 } as Result)) { throw new Error("not ok"); }
+			}`, fs)
+		require.NoError(t, err)
+		assert.Equal(t, null.IntFrom(3), b.Options.VUs)
+
+		bi, err := b.Instantiate(testutils.NewLogger(t), 0)
+		require.NoError(t, err)
+		_, err = bi.exports[consts.DefaultFn](goja.Undefined())
+		assert.NoError(t, err)
+	})
 	t.Run("CompatibilityMode", func(t *testing.T) {
 		t.Parallel()
 		t.Run("Extended/ok/global", func(t *testing.T) {
@@ -153,6 +175,17 @@ func TestNewBundle(t *testing.T) {
 				`module.exports.default = function() {};`, rtOpts)
 			assert.NoError(t, err)
 		})
+		t.Run("Base/ok/Modules", func(t *testing.T) {
+			t.Parallel()
+			rtOpts := lib.RuntimeOptions{
+				CompatibilityMode: null.StringFrom(lib.CompatibilityModeBase.String()),
+			}
+			_, err := getSimpleBundle(t, "/script.js", `
+				import { sleep } from "k6";
+				export let options = { vus: 2 };
+				export default function() { sleep(0); };`, rtOpts)
+			assert.NoError(t, err)
+		})
 		t.Run("Base/err", func(t *testing.T) {
 			t.Parallel()
 			testCases := []struct {
@@ -165,16 +198,16 @@ func TestNewBundle(t *testing.T) {
 					"InvalidCompat", "es1", `export default function() {};`,
 					`invalid compatibility mode "es1". Use: "extended", "base"`,
 				},
-				// ES2015 modules are not supported
+				// ES2015 features other than modules are not supported
 				{
-					"Modules", "base", `export default function() {};`,
-					"file:///script.js: Line 1:1 Unexpected reserved word",
+					"TemplateLiterals", "base", "export default function() {\n\treturn `hi!`; };",
+					"file:///script.js: Line 2:9 Unexpected token ILLEGAL (and 4 more errors)",
 				},
-				// Promises are not supported
+				// async functions need Babel, Promises are there in both modes though
 				{
-					"Promise", "base",
-					`module.exports.default = function() {}; new Promise(function(resolve, reject){});`,
-					"ReferenceError: Promise is not defined\n\tat file:///script.js:1:45(4)\n",
+					"AsyncFunction", "base",
+					`module.exports.default = async function() { await new Promise(function(resolve) {}); };`,
+					"file:///script.js: Line 1:32 Unexpected token function (and 4 more errors)",
 				},
 			}
 
@@ -492,11 +525,40 @@ func getArchive(tb testing.TB, data string, rtOpts lib.RuntimeOptions) (*lib.Arc
 	return b.makeArchive(), nil
 }
 
+func TestBundleESMStackTrace(t *testing.T) {
+	t.Parallel()
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/lib.js", []byte(`export function check(fn) {
+	fn();
+}
+export default function() { throw new Error("oops"); }
+`), 0o644))
+	script := `import fail, { check } from "./lib.js";
+export default function() {}
+check(fail);
+`
+	// the ES modules are transformed without Babel in both modes, with the positions of the source
+	for _, mode := range []lib.CompatibilityMode{lib.CompatibilityModeBase, lib.CompatibilityModeExtended} {
+		mode := mode
+		t.Run(mode.String(), func(t *testing.T) {
+			t.Parallel()
+			rtOpts := lib.RuntimeOptions{CompatibilityMode: null.StringFrom(mode.String())}
+			_, err := getSimpleBundle(t, "/script.js", script, fs, rtOpts)
+			require.Error(t, err)
+			assert.Equal(t, "Error: oops\n"+
+				"\tat file:///lib.js:5:35(4)\n"+
+				"\tat check (file:///lib.js:3:4(3))\n"+
+				"\tat file:///script.js:3:6(37)\n", err.Error())
+		})
+	}
+}
+
 func TestNewBundleFromArchive(t *testing.T) {
 	t.Parallel()
 
 	es5Code := `module.exports.options = { vus: 12345 }; module.exports.default = function() { return "hi!" };`
-	es6Code := `export let options = { vus: 12345 }; export default function() { return "hi!"; };`
+	// template literals aren't supported by goja, so this needs the extended compatibility mode
+	es6Code := "export let options = { vus: 12345 }; export default function() { return `hi!`; };"
 	baseCompatModeRtOpts := lib.RuntimeOptions{CompatibilityMode: null.StringFrom(lib.CompatibilityModeBase.String())}
 	extCompatModeRtOpts := lib.RuntimeOptions{CompatibilityMode: null.StringFrom(lib.CompatibilityModeExtended.String())}
 
@@ -529,7 +591,7 @@ func TestNewBundleFromArchive(t *testing.T) {
 
 		checkArchive(t, arc, lib.RuntimeOptions{}, "") // default options
 		checkArchive(t, arc, extCompatModeRtOpts, "")
-		checkArchive(t, arc, baseCompatModeRtOpts, "Unexpected reserved word")
+		checkArchive(t, arc, baseCompatModeRtOpts, "Unexpected token ILLEGAL")
 	})
 
 	t.Run("es6_script_explicit", func(t *testing.T) {
@@ -540,7 +602,7 @@ func TestNewBundleFromArchive(t *testing.T) {
 
 		checkArchive(t, arc, lib.RuntimeOptions{}, "")
 		checkArchive(t, arc, extCompatModeRtOpts, "")
-		checkArchive(t, arc, baseCompatModeRtOpts, "Unexpected reserved word")
+		checkArchive(t, arc, baseCompatModeRtOpts, "Unexpected token ILLEGAL")
 	})
 
 	t.Run("es5_script_with_extended", func(t *testing.T) {
@@ -579,7 +641,7 @@ func TestNewBundleFromArchive(t *testing.T) {
 		arc.CompatibilityMode = "blah"                                           // intentionally break the archive
 		checkArchive(t, arc, lib.RuntimeOptions{}, "invalid compatibility mode") // fails when it uses the archive one
 		checkArchive(t, arc, extCompatModeRtOpts, "")                            // works when I force the compat mode
-		checkArchive(t, arc, baseCompatModeRtOpts, "Unexpected reserved word")   // failes because of ES6
+		checkArchive(t, arc, baseCompatModeRtOpts, "Unexpected token ILLEGAL")   // failes because of ES6
 	})
 
 	t.Run("script_options_dont_overwrite_metadata", func(t *testing.T) {
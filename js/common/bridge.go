@@ -65,7 +65,14 @@ func FieldName(t reflect.Type, f reflect.StructField) string {
 		if tag == "-" {
 			return ""
 		}
-		return tag
+		// and the options after a comma aren't part of the name, see Bind() for them
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			if i > 0 {
+				return tag[:i]
+			}
+		} else {
+			return tag
+		}
 	}
 
 	if exception, ok := fieldNameExceptions[f.Name]; ok {
@@ -82,22 +89,25 @@ func FieldName(t reflect.Type, f reflect.StructField) string {
 var methodNameExceptions = map[string]string{
 	"JSON": "json",
 	"HTML": "html",
+	"XML":  "xml",
 	"URL":  "url",
 	"OCSP": "ocsp",
+	"UUID": "uuid",
+	"IPv4": "ipv4",
 }
 
 // MethodName Returns the JS name for an exported method. The first letter of the method's name is
 // lowercased, otherwise it is unaltered.
 func MethodName(t reflect.Type, m reflect.Method) string {
+	if exception, ok := methodNameExceptions[m.Name]; ok {
+		return exception
+	}
+
 	// A field with a name beginning with an X is a constructor, and just gets the prefix stripped.
 	// Note: They also get some special treatment from Bridge(), see further down.
 	if m.Name[0] == 'X' {
 		return m.Name[1:]
 	}
-
-	if exception, ok := methodNameExceptions[m.Name]; ok {
-		return exception
-	}
 	// Lowercase the first character of the method name.
 	return strings.ToLower(m.Name[0:1]) + m.Name[1:]
 }
@@ -255,7 +265,7 @@ func Bind(rt *goja.Runtime, v interface{}, ctxPtr *context.Context) map[string]i
 
 		// X-Prefixed methods are assumed to be constructors; use a closure to wrap them in a
 		// pure-JS function to allow them to be `new`d. (This is an awful hack...)
-		if meth.Name[0] == 'X' {
+		if _, isException := methodNameExceptions[meth.Name]; meth.Name[0] == 'X' && !isException {
 			wrapperV, _ := rt.RunProgram(constructWrap)
 			wrapper, _ := goja.AssertFunction(wrapperV)
 			v, _ := wrapper(goja.Undefined(), rt.ToValue(fn.Interface()))
@@ -273,9 +283,16 @@ func Bind(rt *goja.Runtime, v interface{}, ctxPtr *context.Context) map[string]i
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
 		name := FieldName(typ, field)
-		if name != "" {
-			exports[name] = val.Field(i).Interface()
+		if name == "" {
+			continue
+		}
+		// The values of the fields tagged with `js:",bind"` are bound as well, so their methods
+		// can get the context too, for objects like crypto.subtle.
+		if tag := field.Tag.Get("js"); strings.HasSuffix(tag, ",bind") {
+			exports[name] = Bind(rt, val.Field(i).Interface(), ctxPtr)
+			continue
 		}
+		exports[name] = val.Field(i).Interface()
 	}
 
 	return exports
@@ -41,6 +41,11 @@ type bridgeTestFieldsType struct {
 	unexportedTag  string `js:"unexported"`
 }
 
+type bridgeTestBoundFieldType struct {
+	Inner *bridgeTestContextType `js:"inner,bind"`
+	Other *bridgeTestContextType `js:",bind"`
+}
+
 type bridgeTestMethodsType struct{}
 
 func (bridgeTestMethodsType) ExportedFn() {}
@@ -231,6 +236,9 @@ func (bridgeTestConstructorType) XConstructor() bridgeTestConstructorSpawnedType
 	return bridgeTestConstructorSpawnedType{}
 }
 
+// XML starts with an X, but it's one of the exceptions and not a constructor
+func (bridgeTestConstructorType) XML() string { return "" }
+
 func TestFieldNameMapper(t *testing.T) {
 	testdata := []struct {
 		Typ     reflect.Type
@@ -254,6 +262,7 @@ func TestFieldNameMapper(t *testing.T) {
 		}, nil},
 		{reflect.TypeOf(bridgeTestConstructorType{}), nil, map[string]string{
 			"XConstructor": "Constructor",
+			"XML":          "xml",
 		}},
 	}
 	for _, data := range testdata {
@@ -321,6 +330,15 @@ func TestBind(t *testing.T) {
 				}
 			})
 		}},
+		{"BoundField", bridgeTestBoundFieldType{&bridgeTestContextType{}, &bridgeTestContextType{}}, func(t *testing.T, obj interface{}, rt *goja.Runtime) {
+			_, err := rt.RunString(`obj.inner.context()`)
+			assert.Contains(t, err.Error(), "context() can only be called from within default()")
+
+			*ctxPtr = context.Background()
+			defer func() { *ctxPtr = nil }()
+			_, err = rt.RunString(`obj.inner.context(); obj.other.context()`)
+			assert.NoError(t, err)
+		}},
 		{"Methods", bridgeTestMethodsType{}, func(t *testing.T, obj interface{}, rt *goja.Runtime) {
 			t.Run("unexportedFn", func(t *testing.T) {
 				_, err := rt.RunString(`obj.unexportedFn()`)
@@ -24,6 +24,8 @@ import (
 	"context"
 
 	"github.com/dop251/goja"
+
+	"go.k6.io/k6/js/eventloop"
 )
 
 type ctxKey int
@@ -31,6 +33,7 @@ type ctxKey int
 const (
 	ctxKeyRuntime ctxKey = iota
 	ctxKeyInitEnv
+	ctxKeyEventLoop
 )
 
 // WithRuntime attaches the given goja runtime to the context.
@@ -60,3 +63,17 @@ func GetInitEnv(ctx context.Context) *InitEnvironment {
 	}
 	return v.(*InitEnvironment)
 }
+
+// WithEventLoop attaches the given event loop to the context.
+func WithEventLoop(ctx context.Context, loop *eventloop.EventLoop) context.Context {
+	return context.WithValue(ctx, ctxKeyEventLoop, loop)
+}
+
+// GetEventLoop retrieves the attached event loop from the given context.
+func GetEventLoop(ctx context.Context) *eventloop.EventLoop {
+	v := ctx.Value(ctxKeyEventLoop)
+	if v == nil {
+		return nil
+	}
+	return v.(*eventloop.EventLoop)
+}
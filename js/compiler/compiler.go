@@ -22,6 +22,7 @@ package compiler
 
 import (
 	_ "embed" // we need this for embedding Babel
+	"fmt"
 	"sync"
 	"time"
 
@@ -51,7 +52,7 @@ var (
 			// "transform-es2015-shorthand-properties", // in goja
 			// "transform-es2015-duplicate-keys", // in goja
 			// []interface{}{"transform-es2015-computed-properties", map[string]interface{}{"loose": false}}, // in goja
-			// "transform-es2015-for-of", // in goja
+			"transform-es2015-for-of", // in goja, but the regenerator needs it for the loops in generator and async functions
 			// "transform-es2015-sticky-regex", // in goja
 			// "transform-es2015-unicode-regex", // in goja
 			// "check-es2015-constants", // in goja
@@ -62,14 +63,16 @@ var (
 			// "transform-es2015-typeof-symbol", // in goja
 			// all the other module plugins are just dropped
 			[]interface{}{"transform-es2015-modules-commonjs", map[string]interface{}{"loose": false}},
-			// "transform-regenerator", // Doesn't really work unless regeneratorRuntime is also added
+			// needs the regeneratorRuntime, see RegeneratorRuntime()
+			"transform-regenerator",
 
 			// es2016 https://github.com/babel/babel/blob/v6.26.0/packages/babel-preset-es2016/src/index.js
 			"transform-exponentiation-operator",
 
 			// es2017 https://github.com/babel/babel/blob/v6.26.0/packages/babel-preset-es2017/src/index.js
 			// "syntax-trailing-function-commas", // in goja
-			// "transform-async-to-generator", // Doesn't really work unless regeneratorRuntime is also added
+			// the async functions become generator functions that need the regeneratorRuntime and Promise
+			"transform-async-to-generator",
 		},
 		"ast":           false,
 		"sourceMaps":    false,
@@ -123,11 +126,36 @@ func (c *Compiler) Transform(src, filename string) (code string, srcmap *SourceM
 	return
 }
 
-// Compile the program in the given CompatibilityMode, wrapping it between pre and post code
+// Compile the program in the given CompatibilityMode, wrapping it between pre and post code.
+// TypeScript modules, with a .ts, .mts or .cts extension, have their types erased first.
 func (c *Compiler) Compile(src, filename, pre, post string,
+	strict bool, compatMode lib.CompatibilityMode) (*goja.Program, string, error) {
+	if isTypeScript(filename) {
+		code, err := stripTypes(src)
+		if err != nil {
+			return nil, src, fmt.Errorf("%s: %w", filename, err)
+		}
+		src = code
+	}
+	return c.compile(src, filename, pre, post, strict, compatMode)
+}
+
+func (c *Compiler) compile(src, filename, pre, post string,
 	strict bool, compatMode lib.CompatibilityMode) (*goja.Program, string, error) {
 	code := pre + src + post
+	// ES modules that only need their imports and exports to be transformed are
+	// compiled without Babel, with the positions of their source. In the extended
+	// mode, the ones that still don't compile are transformed by Babel after all.
+	esm, positions, isESM, err := transformESM(src)
+	if err != nil {
+		c.logger.WithError(err).Debug("The ES module can't be transformed without Babel")
+	} else if isESM {
+		code = pre + esm + post
+	}
 	ast, err := parser.ParseFile(nil, filename, code, 0, parser.WithDisableSourceMaps)
+	if err == nil && isESM {
+		restoreESMPositions(ast, filename, pre, src, esm, post, positions)
+	}
 	if err != nil {
 		if compatMode == lib.CompatibilityModeExtended {
 			code, _, err = c.Transform(src, filename)
@@ -135,7 +163,7 @@ func (c *Compiler) Compile(src, filename, pre, post string,
 				return nil, code, err
 			}
 			// the compatibility mode "decreases" here as we shouldn't transform twice
-			return c.Compile(code, filename, pre, post, strict, lib.CompatibilityModeBase)
+			return c.compile(code, filename, pre, post, strict, lib.CompatibilityModeBase)
 		}
 		return nil, code, err
 	}
@@ -157,7 +185,7 @@ func (c *Compiler) Compile(src, filename, pre, post string,
 				return nil, code, err
 			}
 			// the compatibility mode "decreases" here as we shouldn't transform twice
-			return c.Compile(code, filename, pre, post, strict, lib.CompatibilityModeBase)
+			return c.compile(code, filename, pre, post, strict, lib.CompatibilityModeBase)
 		}
 		return nil, code, err
 	}
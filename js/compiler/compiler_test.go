@@ -138,6 +138,34 @@ func TestCompile(t *testing.T) {
 			}
 		})
 
+		t.Run("Generators", func(t *testing.T) {
+			pgm, _, err := c.Compile(`
+				function* gen(n) {
+					try {
+						for (const i of [1, 2]) {
+							yield i * n;
+						}
+						yield* [3, 4];
+						throw new Error("oops");
+					} catch (e) {
+						yield e.message;
+					} finally {
+						yield "finally";
+					}
+				}
+				Array.from(gen(10)).join(",");
+			`, "script.js", "", "", true, lib.CompatibilityModeExtended)
+			require.NoError(t, err)
+			regenerator, err := RegeneratorRuntime()
+			require.NoError(t, err)
+			rt := goja.New()
+			_, err = rt.RunProgram(regenerator)
+			require.NoError(t, err)
+			v, err := rt.RunProgram(pgm)
+			require.NoError(t, err)
+			assert.Equal(t, "10,20,3,4,oops,finally", v.Export())
+		})
+
 		t.Run("Invalid", func(t *testing.T) {
 			_, _, err := c.Compile(`1+(=>2)()`, "script.js", "", "", true, lib.CompatibilityModeExtended)
 			assert.IsType(t, &goja.Exception{}, err)
@@ -0,0 +1,960 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+)
+
+// The ES module import and export statements are rewritten into CommonJS
+// directly, so that scripts that don't use anything else that goja doesn't
+// support can be executed without Babel, in both compatibility modes.
+// Exports are defined as getters at the start of the module and the imported
+// names are replaced with the properties of the imported modules wherever
+// they are used, so both are live bindings like in ES modules, and hoisted
+// functions can be used in circular imports. The replacements change the
+// columns after them, so the transform also returns the map of the offsets of
+// the transformed module to the ones of the source, with which the parsed
+// program gets the lines and the columns of the source back, for the error
+// messages and the stack traces.
+//
+// Anything that isn't recognized returns an error, in which case the source is
+// compiled as it is. That includes the imported names being declared again in
+// a nested scope, since the scopes aren't tracked.
+
+var errUnexpectedEOF = errors.New("unexpected end of the module")
+
+type esmTokenKind int
+
+const (
+	esmIdent esmTokenKind = iota + 1 // identifiers and keywords
+	esmString
+	esmTemplate // a template literal or a part of it between substitutions
+	esmRegexp
+	esmNumber
+	esmPunct
+)
+
+type esmToken struct {
+	kind       esmTokenKind
+	text       string
+	start, end int
+	// whether there is a new line between the previous token and this one
+	newline bool
+	// the nesting of parentheses, brackets and braces the token is in
+	depth int
+}
+
+func (t esmToken) is(kind esmTokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+// keywords after which a slash starts a regular expression literal
+var regexpKeywords = map[string]bool{ //nolint:gochecknoglobals
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true, "delete": true,
+	"void": true, "throw": true, "case": true, "do": true, "else": true, "yield": true, "await": true,
+}
+
+type esmLexer struct {
+	src    string
+	pos    int
+	depth  int
+	tokens []esmToken
+	// the depths at which the currently open template substitutions started
+	templates []int
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || r == '$' || r == '\\' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r) || r == '\u200c' || r == '\u200d'
+}
+
+// skipSpace skips the whitespace and the comments and returns whether there
+// was a new line in them.
+func (l *esmLexer) skipSpace() (bool, error) {
+	newline := false
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == '\n':
+			newline = true
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\v' || c == '\f':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			end := strings.IndexByte(l.src[l.pos:], '\n')
+			if end < 0 {
+				l.pos = len(l.src)
+			} else {
+				l.pos += end
+			}
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return newline, errors.New("unterminated comment")
+			}
+			newline = newline || strings.Contains(l.src[l.pos:l.pos+2+end], "\n")
+			l.pos += end + 4
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			if r == '\u2028' || r == '\u2029' {
+				newline = true
+			} else if !unicode.IsSpace(r) && r != '\ufeff' {
+				return newline, nil
+			}
+			l.pos += size
+		default:
+			return newline, nil
+		}
+	}
+	return newline, nil
+}
+
+func (l *esmLexer) regexpAllowed() bool {
+	if len(l.tokens) == 0 {
+		return true
+	}
+	prev := l.tokens[len(l.tokens)-1]
+	switch prev.kind {
+	case esmPunct:
+		return prev.text != ")" && prev.text != "]" && prev.text != "}"
+	case esmIdent:
+		return regexpKeywords[prev.text]
+	default:
+		return false
+	}
+}
+
+// scanQuoted scans until the unescaped closing character and returns the
+// position after it.
+func (l *esmLexer) scanQuoted(start int, closing byte) (int, error) {
+	inClass := false
+	for i := start; i < len(l.src); i++ {
+		switch c := l.src[i]; {
+		case c == '\\':
+			i++
+		case c == '\n':
+			return 0, errors.New("unterminated literal")
+		case closing == '/' && c == '[':
+			inClass = true
+		case closing == '/' && c == ']':
+			inClass = false
+		case c == closing && !inClass:
+			return i + 1, nil
+		}
+	}
+	return 0, errors.New("unterminated literal")
+}
+
+// scanTemplate scans a template literal from the current position, until its
+// end or the start of a substitution.
+func (l *esmLexer) scanTemplate() error {
+	for i := l.pos; i < len(l.src); i++ {
+		switch l.src[i] {
+		case '\\':
+			i++
+		case '`':
+			l.pos = i + 1
+			return nil
+		case '$':
+			if i+1 < len(l.src) && l.src[i+1] == '{' {
+				l.templates = append(l.templates, l.depth)
+				l.depth++
+				l.pos = i + 2
+				return nil
+			}
+		}
+	}
+	return errors.New("unterminated template literal")
+}
+
+func (l *esmLexer) scanToken() (esmToken, error) {
+	tok := esmToken{start: l.pos, depth: l.depth}
+	c := l.src[l.pos]
+	r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+	var err error
+	switch {
+	case isIdentStart(r):
+		tok.kind = esmIdent
+		l.pos += size
+		for l.pos < len(l.src) {
+			r, size = utf8.DecodeRuneInString(l.src[l.pos:])
+			if !isIdentPart(r) {
+				break
+			}
+			l.pos += size
+		}
+	case c >= '0' && c <= '9' || c == '.' && l.pos+1 < len(l.src) && l.src[l.pos+1] >= '0' && l.src[l.pos+1] <= '9':
+		tok.kind = esmNumber
+		for l.pos++; l.pos < len(l.src); l.pos++ {
+			c = l.src[l.pos]
+			if (c == '+' || c == '-') && (l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E') &&
+				!strings.HasPrefix(strings.ToLower(l.src[tok.start:]), "0x") {
+				continue
+			}
+			if c != '.' && c != '_' && !unicode.IsLetter(rune(c)) && !unicode.IsDigit(rune(c)) {
+				break
+			}
+		}
+	case c == '"' || c == '\'':
+		tok.kind = esmString
+		l.pos, err = l.scanQuoted(l.pos+1, c)
+	case c == '`':
+		tok.kind = esmTemplate
+		l.pos++
+		err = l.scanTemplate()
+	case c == '/' && l.regexpAllowed():
+		tok.kind = esmRegexp
+		if l.pos, err = l.scanQuoted(l.pos+1, '/'); err == nil {
+			for l.pos < len(l.src) && isIdentPart(rune(l.src[l.pos])) {
+				l.pos++
+			}
+		}
+	default:
+		tok.kind = esmPunct
+		l.pos++
+		switch c {
+		case '(', '[', '{':
+			l.depth++
+		case ')', ']', '}':
+			l.depth--
+			tok.depth = l.depth
+			if n := len(l.templates); c == '}' && n > 0 && l.templates[n-1] == l.depth {
+				l.templates = l.templates[:n-1]
+				tok.kind = esmTemplate
+				err = l.scanTemplate()
+			}
+		}
+	}
+	if err != nil {
+		return tok, err
+	}
+	tok.end = l.pos
+	tok.text = l.src[tok.start:tok.end]
+	return tok, nil
+}
+
+func lexESM(src string) ([]esmToken, error) {
+	l := &esmLexer{src: src}
+	for {
+		newline, err := l.skipSpace()
+		if err != nil {
+			return nil, err
+		}
+		if l.pos >= len(l.src) {
+			return l.tokens, nil
+		}
+		tok, err := l.scanToken()
+		if err != nil {
+			return nil, err
+		}
+		tok.newline = newline
+		l.tokens = append(l.tokens, tok)
+	}
+}
+
+// esmSegment is a part of the transformed module that starts at out: either a
+// part of the source copied from src, or the replacement of the source at src.
+type esmSegment struct {
+	out, src int
+	copied   bool
+}
+
+// esmPositions maps the offsets of the transformed module to the offsets of its
+// source, the parts of the replacements are at the start of what they replaced.
+type esmPositions []esmSegment
+
+func (p esmPositions) sourceOffset(out int) int {
+	i := sort.Search(len(p), func(i int) bool { return p[i].out > out }) - 1
+	if i < 0 {
+		return out
+	}
+	if p[i].copied {
+		return p[i].src + out - p[i].out
+	}
+	return p[i].src
+}
+
+type esmExport struct {
+	name, value string
+}
+
+// esmEdit replaces the source between start and end with text.
+type esmEdit struct {
+	start, end int
+	text       string
+}
+
+type esmTransformer struct {
+	src     string
+	tokens  []esmToken
+	pos     int
+	edits   []esmEdit
+	exports []esmExport
+	modules int
+	// the expressions that the imported names are replaced with
+	imports map[string]string
+}
+
+// transformESM rewrites the import and export statements of the ES module in
+// src into CommonJS, and returns the positions of the transformed module in the
+// source. It returns false if src doesn't have any of them.
+func transformESM(src string) (string, esmPositions, bool, error) {
+	tokens, err := lexESM(src)
+	if err != nil {
+		return "", nil, false, err
+	}
+	t := &esmTransformer{src: src, tokens: tokens, imports: make(map[string]string)}
+	found := false
+	for ; t.pos < len(t.tokens); t.pos++ {
+		tok := t.tokens[t.pos]
+		if tok.depth != 0 || tok.kind != esmIdent || (tok.text != "import" && tok.text != "export") ||
+			!t.isStatementStart() {
+			continue
+		}
+		var transformed bool
+		if tok.text == "import" {
+			transformed, err = t.transformImport()
+		} else {
+			transformed, err = t.transformExport()
+		}
+		if err != nil {
+			return "", nil, false, fmt.Errorf("line %d: %w", 1+strings.Count(src[:tok.start], "\n"), err)
+		}
+		found = found || transformed
+	}
+	if !found {
+		return src, nil, false, nil
+	}
+	if err := t.replaceImportedNames(); err != nil {
+		return "", nil, false, err
+	}
+
+	var out strings.Builder
+	out.WriteString(`Object.defineProperty(exports, "__esModule", { value: true });`)
+	for _, export := range t.exports {
+		value := export.value
+		if imported, ok := t.imports[value]; ok {
+			value = imported
+		}
+		fmt.Fprintf(&out, " Object.defineProperty(exports, %s, { enumerable: true, get: function() { return %s; } });",
+			strconv.Quote(export.name), value)
+	}
+	positions := esmPositions{{out: 0, src: 0}}
+	copied := 0
+	for _, edit := range t.edits {
+		positions = append(positions, esmSegment{out: out.Len(), src: copied, copied: true})
+		out.WriteString(t.src[copied:edit.start])
+		positions = append(positions, esmSegment{out: out.Len(), src: edit.start})
+		out.WriteString(edit.text)
+		copied = edit.end
+	}
+	positions = append(positions, esmSegment{out: out.Len(), src: copied, copied: true})
+	out.WriteString(t.src[copied:])
+	return out.String(), positions, true, nil
+}
+
+func (t *esmTransformer) isStatementStart() bool {
+	if t.pos == 0 {
+		return true
+	}
+	prev := t.tokens[t.pos-1]
+	if prev.kind == esmPunct && (prev.text == ";" || prev.text == "}") {
+		return true
+	}
+	return t.tokens[t.pos].newline && !prev.is(esmPunct, ".")
+}
+
+// replace replaces the source between the start of the token at from and the
+// end of the token at to, keeping the new lines in it. Shorter replacements on
+// a single line are padded, so the columns after them stay the same too.
+func (t *esmTransformer) replace(from, to int, text string) {
+	start, end := t.tokens[from].start, t.tokens[to].end
+	if lines := strings.Count(t.src[start:end], "\n"); lines > 0 {
+		text += strings.Repeat("\n", lines)
+	} else if len(text) < end-start {
+		text += strings.Repeat(" ", end-start-len(text))
+	}
+	t.edits = append(t.edits, esmEdit{start: start, end: end, text: text})
+}
+
+func (t *esmTransformer) token(offset int) (esmToken, error) {
+	if t.pos+offset >= len(t.tokens) {
+		return esmToken{}, errUnexpectedEOF
+	}
+	return t.tokens[t.pos+offset], nil
+}
+
+// expect advances to the next token, which has to be of the given kind and,
+// if it's not empty, have the given text.
+func (t *esmTransformer) expect(kind esmTokenKind, text string) (esmToken, error) {
+	t.pos++
+	tok, err := t.token(0)
+	if err != nil {
+		return tok, err
+	}
+	if tok.kind != kind || (text != "" && tok.text != text) {
+		return tok, fmt.Errorf("unexpected '%s' in the module statement", tok.text)
+	}
+	return tok, nil
+}
+
+func (t *esmTransformer) newModuleVar() string {
+	t.modules++
+	return fmt.Sprintf("__esm_%d", t.modules)
+}
+
+// parseSpecifiers parses the specifiers between braces in import and export
+// statements and returns the pairs of the original names and their aliases.
+func (t *esmTransformer) parseSpecifiers() ([][2]string, error) {
+	var specifiers [][2]string
+	for {
+		t.pos++
+		tok, err := t.token(0)
+		if err != nil {
+			return nil, err
+		}
+		if tok.is(esmPunct, "}") {
+			return specifiers, nil
+		}
+		if tok.kind != esmIdent {
+			return nil, fmt.Errorf("unexpected '%s' in the module statement", tok.text)
+		}
+		specifier := [2]string{tok.text, tok.text}
+		next, err := t.token(1)
+		if err != nil {
+			return nil, err
+		}
+		if next.is(esmIdent, "as") {
+			t.pos++
+			alias, err := t.expect(esmIdent, "")
+			if err != nil {
+				return nil, err
+			}
+			specifier[1] = alias.text
+		}
+		specifiers = append(specifiers, specifier)
+
+		t.pos++
+		if tok, err = t.token(0); err != nil {
+			return nil, err
+		}
+		if tok.is(esmPunct, "}") {
+			return specifiers, nil
+		}
+		if !tok.is(esmPunct, ",") {
+			return nil, fmt.Errorf("unexpected '%s' in the module statement", tok.text)
+		}
+	}
+}
+
+func (t *esmTransformer) parseFrom() (string, error) {
+	if _, err := t.expect(esmIdent, "from"); err != nil {
+		return "", err
+	}
+	tok, err := t.expect(esmString, "")
+	return tok.text, err
+}
+
+func (t *esmTransformer) transformImport() (bool, error) {
+	start := t.pos
+	next, err := t.token(1)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case next.is(esmPunct, "("):
+		return false, nil // dynamic imports are left to Babel
+	case next.is(esmPunct, "."):
+		return false, errors.New("import.meta is not supported")
+	case next.kind == esmString:
+		t.pos++
+		t.replace(start, t.pos, "require("+next.text+")")
+		return true, nil
+	}
+
+	var defaultName, namespace string
+	var specifiers [][2]string
+	hasBindings := true
+	if next.kind == esmIdent {
+		t.pos++
+		defaultName = next.text
+		if next, err = t.token(1); err != nil {
+			return false, err
+		}
+		hasBindings = next.is(esmPunct, ",")
+		if hasBindings {
+			t.pos++
+			if next, err = t.token(1); err != nil {
+				return false, err
+			}
+		}
+	}
+	if hasBindings {
+		if namespace, specifiers, err = t.parseImportBindings(next); err != nil {
+			return false, err
+		}
+	}
+
+	spec, err := t.parseFrom()
+	if err != nil {
+		return false, err
+	}
+	module := t.newModuleVar()
+	declarations := []string{module + " = require(" + spec + ")"}
+	if defaultName != "" {
+		// CommonJS modules, like the k6 ones, are imported as the default
+		interop := module + "_default"
+		declarations = append(declarations, fmt.Sprintf("%s = %s && %s.__esModule ? %s : { default: %s }",
+			interop, module, module, module, module))
+		t.imports[defaultName] = interop + ".default"
+	}
+	if namespace != "" {
+		declarations = append(declarations, namespace+" = "+module)
+	}
+	for _, specifier := range specifiers {
+		t.imports[specifier[1]] = module + "." + specifier[0]
+	}
+	t.replace(start, t.pos, "var "+strings.Join(declarations, ", "))
+	return true, nil
+}
+
+// parseImportBindings parses either the namespace import or the named imports
+// of an import statement, starting with the next token.
+func (t *esmTransformer) parseImportBindings(next esmToken) (string, [][2]string, error) {
+	t.pos++
+	switch {
+	case next.is(esmPunct, "*"):
+		if _, err := t.expect(esmIdent, "as"); err != nil {
+			return "", nil, err
+		}
+		tok, err := t.expect(esmIdent, "")
+		return tok.text, nil, err
+	case next.is(esmPunct, "{"):
+		specifiers, err := t.parseSpecifiers()
+		return "", specifiers, err
+	default:
+		return "", nil, fmt.Errorf("unexpected '%s' in the import statement", next.text)
+	}
+}
+
+func (t *esmTransformer) export(name, value string) {
+	t.exports = append(t.exports, esmExport{name: name, value: value})
+}
+
+// isDeclarationEnd returns whether a declaration continues past the token at
+// pos: a new statement starts after a new line after a complete expression.
+func (t *esmTransformer) isDeclarationEnd(pos int) bool {
+	tok := t.tokens[pos]
+	if tok.is(esmPunct, ";") {
+		return true
+	}
+	if !tok.newline || pos == 0 {
+		return false
+	}
+	prev := t.tokens[pos-1]
+	prevEnds := prev.kind != esmPunct || prev.text == ")" || prev.text == "]" || prev.text == "}"
+	return prevEnds && tok.kind != esmPunct
+}
+
+// exportDeclarations exports the names declared by the var, let or const
+// declaration that starts at the current token.
+func (t *esmTransformer) exportDeclarations() error {
+	depth := t.tokens[t.pos].depth
+	expectName := true
+	for t.pos++; t.pos < len(t.tokens); t.pos++ {
+		tok := t.tokens[t.pos]
+		if expectName {
+			if tok.kind != esmIdent {
+				return errors.New("destructuring exports are not supported")
+			}
+			t.export(tok.text, tok.text)
+			expectName = false
+			continue
+		}
+		if tok.depth != depth {
+			continue
+		}
+		if tok.is(esmPunct, ",") {
+			expectName = true
+			continue
+		}
+		if t.isDeclarationEnd(t.pos) {
+			break
+		}
+	}
+	t.pos--
+	return nil
+}
+
+func (t *esmTransformer) transformExport() (bool, error) { //nolint:funlen,cyclop
+	start := t.pos
+	next, err := t.token(1)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case next.is(esmIdent, "default"):
+		t.pos++
+		if next, err = t.token(1); err != nil {
+			return false, err
+		}
+		switch {
+		case next.is(esmIdent, "function"):
+			offset := 2
+			if tok, err := t.token(offset); err == nil && tok.is(esmPunct, "*") {
+				offset++
+			}
+			name, err := t.token(offset)
+			if err != nil {
+				return false, err
+			}
+			if name.kind == esmIdent {
+				t.replace(start, t.pos, "")
+				t.export("default", name.text)
+				return true, nil
+			}
+			// anonymous functions stay anonymous in the stack traces, but
+			// unlike in ES modules, they aren't hoisted
+			t.replace(start, t.pos, "exports.default =")
+		case next.is(esmIdent, "class"):
+			name, err := t.token(2)
+			if err != nil {
+				return false, err
+			}
+			if name.kind != esmIdent || name.text == "extends" {
+				return false, errors.New("anonymous default class exports are not supported")
+			}
+			t.replace(start, t.pos, "")
+			t.export("default", name.text)
+		case next.is(esmIdent, "async"):
+			return false, errors.New("async functions are not supported")
+		default:
+			t.replace(start, t.pos, "exports.default =")
+		}
+		return true, nil
+
+	case next.is(esmIdent, "function"), next.is(esmIdent, "class"):
+		offset := 2
+		if tok, err := t.token(offset); err == nil && tok.is(esmPunct, "*") {
+			offset++
+		}
+		name, err := t.token(offset)
+		if err != nil {
+			return false, err
+		}
+		if name.kind != esmIdent {
+			return false, fmt.Errorf("unexpected '%s' in the export statement", name.text)
+		}
+		t.replace(start, start, "")
+		t.export(name.text, name.text)
+		return true, nil
+
+	case next.is(esmIdent, "var"), next.is(esmIdent, "let"), next.is(esmIdent, "const"):
+		t.replace(start, start, "")
+		t.pos++
+		return true, t.exportDeclarations()
+
+	case next.is(esmPunct, "{"):
+		t.pos++
+		specifiers, err := t.parseSpecifiers()
+		if err != nil {
+			return false, err
+		}
+		replacement, prefix := "", ""
+		if tok, err := t.token(1); err == nil && tok.is(esmIdent, "from") {
+			spec, err := t.parseFrom()
+			if err != nil {
+				return false, err
+			}
+			module := t.newModuleVar()
+			replacement, prefix = "var "+module+" = require("+spec+")", module+"."
+		}
+		for _, specifier := range specifiers {
+			t.export(specifier[1], prefix+specifier[0])
+		}
+		t.replace(start, t.pos, replacement)
+		return true, nil
+
+	case next.is(esmPunct, "*"):
+		t.pos++
+		if tok, err := t.token(1); err == nil && tok.is(esmIdent, "as") {
+			t.pos++
+			name, err := t.expect(esmIdent, "")
+			if err != nil {
+				return false, err
+			}
+			spec, err := t.parseFrom()
+			if err != nil {
+				return false, err
+			}
+			module := t.newModuleVar()
+			t.export(name.text, module)
+			t.replace(start, t.pos, "var "+module+" = require("+spec+")")
+			return true, nil
+		}
+		spec, err := t.parseFrom()
+		if err != nil {
+			return false, err
+		}
+		// the explicit exports are defined first and take precedence
+		t.replace(start, t.pos, "(function(m) { Object.keys(m).forEach(function(k) { "+
+			"if (k !== \"default\" && !(k in exports)) { Object.defineProperty(exports, k, "+
+			"{ enumerable: true, get: function() { return m[k]; } }); } }); })(require("+spec+"))")
+		return true, nil
+	}
+
+	return false, fmt.Errorf("unexpected '%s' in the export statement", next.text)
+}
+
+// at returns the token at pos, or an empty token if there isn't one.
+func (t *esmTransformer) at(pos int) esmToken {
+	if pos < 0 || pos >= len(t.tokens) {
+		return esmToken{}
+	}
+	return t.tokens[pos]
+}
+
+// isArrow returns whether the tokens at pos are the => of an arrow function.
+func (t *esmTransformer) isArrow(pos int) bool {
+	return t.at(pos).is(esmPunct, "=") && t.at(pos+1).is(esmPunct, ">") && t.at(pos).end == t.at(pos+1).start
+}
+
+// nesting returns the positions of the parentheses, brackets, braces or
+// template substitutions that each token is directly in, or -1 for the top
+// level ones, and the positions of their closing tokens.
+func (t *esmTransformer) nesting() ([]int, map[int]int) {
+	parents := make([]int, len(t.tokens))
+	closers := make(map[int]int)
+	stack := []int{-1}
+	for i, tok := range t.tokens {
+		closes := (tok.kind == esmPunct && strings.ContainsAny(tok.text, ")]}")) ||
+			(tok.kind == esmTemplate && strings.HasPrefix(tok.text, "}"))
+		if closes && len(stack) > 1 {
+			closers[stack[len(stack)-1]] = i
+			stack = stack[:len(stack)-1]
+		}
+		parents[i] = stack[len(stack)-1]
+		if (tok.kind == esmPunct && strings.ContainsAny(tok.text, "([{")) ||
+			(tok.kind == esmTemplate && strings.HasSuffix(tok.text, "${")) {
+			stack = append(stack, i)
+		}
+	}
+	return parents, closers
+}
+
+func isDeclarationKeyword(tok esmToken) bool {
+	return tok.kind == esmIdent && (tok.text == "var" || tok.text == "let" || tok.text == "const")
+}
+
+// isBinding returns whether the identifier at pos is declared there, as a
+// variable, a function or class name, or a parameter.
+func (t *esmTransformer) isBinding(pos int, parents []int, closers map[int]int) bool {
+	prev := t.at(pos - 1)
+	if prev.kind == esmIdent {
+		switch prev.text {
+		case "var", "let", "const", "function", "class":
+			return true
+		}
+	}
+	if (prev.is(esmPunct, "*") && t.at(pos-2).is(esmIdent, "function")) || t.isArrow(pos+1) {
+		return true
+	}
+
+	// destructuring patterns are declared by their outermost brackets
+	outer := pos
+	for parents[outer] >= 0 && (t.at(parents[outer]).is(esmPunct, "{") || t.at(parents[outer]).is(esmPunct, "[")) {
+		outer = parents[outer]
+	}
+	prev = t.at(outer - 1)
+	if isDeclarationKeyword(prev) {
+		return true
+	}
+	if prev.is(esmPunct, ",") {
+		depth := t.tokens[outer].depth
+		for i := outer - 2; i >= 0 && t.tokens[i].depth >= depth; i-- {
+			if t.tokens[i].depth > depth {
+				continue
+			}
+			if t.tokens[i].is(esmPunct, ";") {
+				break
+			}
+			if isDeclarationKeyword(t.tokens[i]) {
+				return true
+			}
+		}
+	}
+
+	// the parameters of functions, methods, arrow functions and catch clauses
+	paren := parents[outer]
+	if paren < 0 || !t.at(paren).is(esmPunct, "(") {
+		return false
+	}
+	closer, ok := closers[paren]
+	if !ok {
+		return false
+	}
+	if t.isArrow(closer + 1) {
+		return true
+	}
+	if !t.at(closer+1).is(esmPunct, "{") {
+		return false
+	}
+	if before := t.at(paren - 1); before.kind == esmIdent {
+		switch before.text {
+		case "if", "while", "for", "switch", "with":
+			return false
+		}
+	}
+	return true
+}
+
+// replaceImportedNames replaces the uses of the imported names with the
+// properties of the imported modules, so they are live bindings.
+func (t *esmTransformer) replaceImportedNames() error {
+	if len(t.imports) == 0 {
+		return nil
+	}
+	parents, closers := t.nesting()
+	statements := t.edits
+	var edits []esmEdit
+	for i, tok := range t.tokens {
+		for len(statements) > 0 && statements[0].end <= tok.start {
+			statements = statements[1:]
+		}
+		value, ok := t.imports[tok.text]
+		if !ok || tok.kind != esmIdent || (len(statements) > 0 && statements[0].start <= tok.start) {
+			continue
+		}
+		prev, next := t.at(i-1), t.at(i+1)
+		if prev.is(esmPunct, ".") {
+			continue
+		}
+		inObject := parents[i] >= 0 && t.at(parents[i]).is(esmPunct, "{")
+		afterSeparator := prev.is(esmPunct, "{") || prev.is(esmPunct, ",")
+		if inObject && afterSeparator && next.is(esmPunct, ":") {
+			continue // a property name
+		}
+		if closer, ok := closers[i+1]; inObject && ok && next.is(esmPunct, "(") && t.at(closer+1).is(esmPunct, "{") {
+			continue // a method name
+		}
+		if t.isBinding(i, parents, closers) {
+			return fmt.Errorf("line %d: the imported '%s' is declared again, which is not supported",
+				1+strings.Count(t.src[:tok.start], "\n"), tok.text)
+		}
+		if inObject && afterSeparator && (next.is(esmPunct, "}") || next.is(esmPunct, ",")) {
+			value = tok.text + ": " + value // a shorthand property
+		}
+		edits = append(edits, esmEdit{start: tok.start, end: tok.end, text: value})
+	}
+	t.edits = append(t.edits, edits...)
+	sort.Slice(t.edits, func(i, j int) bool { return t.edits[i].start < t.edits[j].start })
+	return nil
+}
+
+// restoreESMPositions changes the positions of the program parsed from the
+// transformed module, wrapped between pre and post, to the positions in the
+// source, and makes them refer to the source wrapped between them instead.
+func restoreESMPositions(prg *ast.Program, filename, pre, src, esm, post string, positions esmPositions) {
+	sourceIdx := func(idx file.Idx) file.Idx {
+		offset := int(idx) - prg.File.Base()
+		switch {
+		case offset < len(pre):
+		case offset < len(pre)+len(esm):
+			offset = len(pre) + positions.sourceOffset(offset-len(pre))
+		default:
+			offset += len(src) - len(esm)
+		}
+		return file.Idx(offset + prg.File.Base())
+	}
+	r := &idxRestorer{sourceIdx: sourceIdx, visited: make(map[idxRestorerKey]bool)}
+	r.restore(reflect.ValueOf(prg))
+	prg.File = file.NewFile(filename, pre+src+post, prg.File.Base())
+}
+
+//nolint:gochecknoglobals
+var (
+	idxType  = reflect.TypeOf(file.Idx(0))
+	fileType = reflect.TypeOf((*file.File)(nil))
+)
+
+type idxRestorerKey struct {
+	t   reflect.Type
+	ptr uintptr
+}
+
+// idxRestorer walks the nodes of a program and changes all of their positions,
+// once, since some of the nodes are referenced more than once, like the
+// declarations that are both in the bodies and in the lists of declarations.
+type idxRestorer struct {
+	sourceIdx func(file.Idx) file.Idx
+	visited   map[idxRestorerKey]bool
+}
+
+func (r *idxRestorer) restore(v reflect.Value) {
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.Ptr:
+		if v.IsNil() || v.Type() == fileType {
+			return
+		}
+		key := idxRestorerKey{t: v.Type(), ptr: v.Pointer()}
+		if r.visited[key] {
+			return
+		}
+		r.visited[key] = true
+		r.restore(v.Elem())
+	case reflect.Interface:
+		if !v.IsNil() {
+			r.restore(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			r.restore(v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			if field.Type() == idxType {
+				if idx := file.Idx(field.Int()); idx > 0 {
+					field.SetInt(int64(r.sourceIdx(idx)))
+				}
+				continue
+			}
+			r.restore(field)
+		}
+	}
+}
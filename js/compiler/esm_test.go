@@ -0,0 +1,198 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/testutils"
+)
+
+func TestTransformESMUnchanged(t *testing.T) {
+	t.Parallel()
+
+	for _, src := range []string{
+		``,
+		`module.exports.default = function() {};`,
+		`var s = "export default 1"; var t = ` + "`import ${'x'} from`" + `;`,
+		"// export default 1\n/* import x from 'y' */",
+		`var o = { import: 1, export: 2 }; o.export = o.import;`,
+		`var r = /export default/g;`,
+	} {
+		code, _, ok, err := transformESM(src)
+		require.NoError(t, err, src)
+		assert.False(t, ok, src)
+		assert.Equal(t, src, code)
+	}
+}
+
+func TestTransformESMUnsupported(t *testing.T) {
+	t.Parallel()
+
+	for _, src := range []string{
+		`export const { a, b } = obj;`,
+		`export default class {}`,
+		`export default async function() {}`,
+		"import.meta.url;",
+		`import { a from "b";`,
+		`var s = "unterminated`,
+		`import { a } from "b"; function f(a) { return a; }`,
+		`import { a } from "b"; var x = 1, a = 2;`,
+		`import { a } from "b"; var f = a => a;`,
+		`import a from "b"; try {} catch ({ a }) {}`,
+	} {
+		_, _, _, err := transformESM(src)
+		assert.Error(t, err, src)
+	}
+}
+
+func TestTransformESMImportedNames(t *testing.T) {
+	t.Parallel()
+
+	code, _, ok, err := transformESM(`import a, { b as c } from "m";
+var o = { a: 1, c, a() {} }; o.a = a(c);`)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Contains(t, code, `var o = { a: 1, c: __esm_1.b, a() {} }; o.a = __esm_1_default.default(__esm_1.b);`)
+}
+
+func TestTransformESMLines(t *testing.T) {
+	t.Parallel()
+
+	src := strings.Join([]string{
+		`import {`,
+		`  check,`,
+		`  sleep`,
+		`} from "k6";`,
+		`export default function() {`,
+		`  throw new Error("line 6");`,
+		`}`,
+	}, "\n")
+	code, _, ok, err := transformESM(src)
+	require.NoError(t, err)
+	require.True(t, ok)
+	lines := strings.Split(code, "\n")
+	require.Len(t, lines, 7)
+	assert.Equal(t, `  throw new Error("line 6");`, lines[5])
+}
+
+func TestCompileESM(t *testing.T) {
+	t.Parallel()
+
+	modules := map[string]string{
+		"lib.js": `
+			export let counter = 1;
+			export function increment() { counter++; }
+			export default function() { return "lib default"; }
+			export { increment as inc };
+		`,
+		"star.js": `
+			export * from "lib.js";
+			export * as ns from "lib.js";
+			export { default as libDefault, counter } from "lib.js";
+		`,
+	}
+	src := `
+		import "side.js";
+		import libDefault, { counter, increment as incr } from "lib.js";
+		import * as lib from "lib.js";
+		import cjs, { value } from "cjs.js";
+		import * as star from "star.js";
+
+		export const before = counter, after = (incr(), lib.counter)
+		export let options = {
+			vus: 10,
+		}
+		export default function() { return [libDefault(), cjs.value, value, star.inc === lib.inc, star.ns.counter, { counter }.counter]; }
+		export var s = "a" + /b/.source +
+			String(after);
+	`
+
+	c := New(testutils.NewLogger(t))
+	rt := goja.New()
+	cache := map[string]goja.Value{
+		"cjs.js":  rt.ToValue(map[string]interface{}{"value": 42}),
+		"side.js": rt.NewObject(),
+	}
+	require.NoError(t, rt.Set("require", func(name string) goja.Value {
+		if v, ok := cache[name]; ok {
+			return v
+		}
+		pgm, _, err := c.Compile(modules[name], name, "(function(exports){", "})", true, lib.CompatibilityModeBase)
+		require.NoError(t, err)
+		fn, err := rt.RunProgram(pgm)
+		require.NoError(t, err)
+		call, _ := goja.AssertFunction(fn)
+		exports := rt.NewObject()
+		cache[name] = exports
+		_, err = call(goja.Undefined(), exports)
+		require.NoError(t, err)
+		return exports
+	}))
+	exports := rt.NewObject()
+	require.NoError(t, rt.Set("exports", exports))
+
+	pgm, code, err := c.Compile(src, "script.js", "", "", true, lib.CompatibilityModeBase)
+	require.NoError(t, err)
+	assert.NotContains(t, code, "use strict") // not transformed by Babel
+	_, err = rt.RunProgram(pgm)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), exports.Get("before").Export())
+	assert.Equal(t, int64(2), exports.Get("after").Export())
+	assert.Equal(t, map[string]interface{}{"vus": int64(10)}, exports.Get("options").Export())
+	assert.Equal(t, "ab2", exports.Get("s").Export())
+	assert.True(t, exports.Get("__esModule").ToBoolean())
+	assert.Equal(t, []string{"before", "after", "options", "s", "default"}, exports.Keys())
+
+	fn, ok := goja.AssertFunction(exports.Get("default"))
+	require.True(t, ok)
+	v, err := fn(goja.Undefined())
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"lib default", int64(42), int64(42), true, int64(2), int64(2)}, v.Export())
+
+	star := cache["star.js"].ToObject(rt)
+	assert.ElementsMatch(t, []string{"ns", "libDefault", "counter", "increment", "inc"}, star.Keys())
+}
+
+func TestCompileESMExtended(t *testing.T) {
+	t.Parallel()
+
+	c := New(testutils.NewLogger(t))
+	// the ES modules that goja supports aren't transformed by Babel in the extended mode either
+	_, code, err := c.Compile(`import { a } from "m"; export default function() { return a; }`,
+		"script.js", "", "", true, lib.CompatibilityModeExtended)
+	require.NoError(t, err)
+	assert.NotContains(t, code, "use strict")
+	assert.Contains(t, code, "return __esm_1.a;")
+
+	// and the ones that it doesn't still are
+	_, code, err = c.Compile(`import { a } from "m"; export default async function() { await a; }`,
+		"script.js", "", "", true, lib.CompatibilityModeExtended)
+	require.NoError(t, err)
+	assert.Contains(t, code, "use strict")
+}
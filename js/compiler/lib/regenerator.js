@@ -0,0 +1,351 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// The regeneratorRuntime that the generator functions, and so the async
+// functions, that Babel transformed need.
+//
+// Babel turns the body of a generator function into a state machine, an inner
+// function that's called with a context every time the generator is resumed.
+// It switches on context.next, runs until the next yield, where it returns the
+// yielded value, and it otherwise returns the result of the context methods
+// that complete the function or a try statement. The try statements are
+// described by lists of [tryLoc, catchLoc, finallyLoc, afterLoc] locations.
+var regeneratorRuntime = (function () {
+	"use strict";
+
+	// returned by the inner function when it has to be called again right away
+	var Continue = {};
+
+	var SuspendedStart = 0, SuspendedYield = 1, Executing = 2, Completed = 3;
+
+	function Generator() {}
+	function GeneratorFunction() {}
+	function GeneratorFunctionPrototype() {}
+
+	var Gp = Generator.prototype;
+	GeneratorFunctionPrototype.prototype = Gp;
+	GeneratorFunction.prototype = GeneratorFunctionPrototype;
+	Object.defineProperty(Gp, "constructor", { value: GeneratorFunctionPrototype, configurable: true });
+	Object.defineProperty(GeneratorFunctionPrototype, "constructor", { value: GeneratorFunction, configurable: true });
+
+	function normal() {
+		return { type: "normal" };
+	}
+
+	function Context(tryLocsList) {
+		this.tryEntries = [{ tryLoc: "root", completion: normal() }];
+		for (var i = 0; i < tryLocsList.length; i++) {
+			var locs = tryLocsList[i];
+			this.tryEntries.push({
+				tryLoc: locs[0], catchLoc: locs[1], finallyLoc: locs[2], afterLoc: locs[3], completion: normal(),
+			});
+		}
+		this.prev = 0;
+		this.next = 0;
+		this.sent = this._sent = undefined;
+		this.done = false;
+		this.delegate = null;
+		this.rval = undefined;
+	}
+
+	Context.prototype = {
+		constructor: Context,
+
+		stop: function () {
+			this.done = true;
+			var root = this.tryEntries[0].completion;
+			if (root.type === "throw") {
+				throw root.arg;
+			}
+			return this.rval;
+		},
+
+		// the innermost try statement with a finally block that wasn't run yet
+		pendingFinally: function () {
+			for (var i = this.tryEntries.length - 1; i > 0; i--) {
+				var entry = this.tryEntries[i];
+				if (entry.finallyLoc !== undefined && entry.tryLoc <= this.prev && this.prev < entry.finallyLoc) {
+					return entry;
+				}
+			}
+			return undefined;
+		},
+
+		dispatchException: function (exception) {
+			if (this.done) {
+				throw exception;
+			}
+			for (var i = this.tryEntries.length - 1; i >= 0; i--) {
+				var entry = this.tryEntries[i];
+				if (entry.tryLoc === "root") {
+					// nothing catches it, so the function completes by throwing it
+					entry.completion = { type: "throw", arg: exception };
+					this.next = "end";
+					return;
+				}
+				if (entry.tryLoc > this.prev) {
+					continue;
+				}
+				if (entry.catchLoc !== undefined && this.prev < entry.catchLoc) {
+					entry.completion = { type: "throw", arg: exception };
+					this.next = entry.catchLoc;
+					return;
+				}
+				if (entry.finallyLoc !== undefined && this.prev < entry.finallyLoc) {
+					entry.completion = { type: "throw", arg: exception };
+					this.next = entry.finallyLoc;
+					return;
+				}
+			}
+		},
+
+		abrupt: function (type, arg) {
+			var entry = this.pendingFinally();
+			if (entry && (type === "break" || type === "continue") && entry.tryLoc <= arg && arg <= entry.finallyLoc) {
+				// the jump doesn't leave the try statement
+				entry = undefined;
+			}
+			var record = { type: type, arg: arg };
+			if (entry) {
+				entry.completion = record;
+				this.next = entry.finallyLoc;
+				return Continue;
+			}
+			return this.complete(record);
+		},
+
+		complete: function (record, afterLoc) {
+			switch (record.type) {
+			case "throw":
+				throw record.arg;
+			case "break":
+			case "continue":
+				this.next = record.arg;
+				break;
+			case "return":
+				this.rval = record.arg;
+				this.next = "end";
+				break;
+			default:
+				if (afterLoc !== undefined) {
+					this.next = afterLoc;
+				}
+			}
+			return Continue;
+		},
+
+		finish: function (finallyLoc) {
+			for (var i = this.tryEntries.length - 1; i > 0; i--) {
+				var entry = this.tryEntries[i];
+				if (entry.finallyLoc === finallyLoc) {
+					var record = entry.completion;
+					entry.completion = normal();
+					return this.complete(record, entry.afterLoc);
+				}
+			}
+		},
+
+		"catch": function (tryLoc) {
+			for (var i = this.tryEntries.length - 1; i > 0; i--) {
+				var entry = this.tryEntries[i];
+				if (entry.tryLoc === tryLoc && entry.completion.type === "throw") {
+					var thrown = entry.completion.arg;
+					entry.completion = normal();
+					return thrown;
+				}
+			}
+			throw new Error("illegal catch attempt");
+		},
+
+		delegateYield: function (iterable, resultName, nextLoc) {
+			this.delegate = { iterator: values(iterable), resultName: resultName, nextLoc: nextLoc };
+			return Continue;
+		},
+	};
+
+	// resumeDelegate passes the method of a resumption to the iterator of a
+	// yield*, it returns the result to yield, or undefined when the generator
+	// itself has to continue as the context says.
+	function resumeDelegate(context, method, arg) {
+		var delegate = context.delegate;
+		var fn = delegate.iterator[method];
+		if (fn === undefined) {
+			context.delegate = null;
+			if (method === "throw") {
+				return { method: "throw", arg: new TypeError("The iterator does not provide a 'throw' method") };
+			}
+			return { method: method, arg: arg };
+		}
+		var result;
+		try {
+			result = fn.call(delegate.iterator, arg);
+		} catch (e) {
+			context.delegate = null;
+			return { method: "throw", arg: e };
+		}
+		if (!result.done) {
+			return { yielded: result };
+		}
+		context.delegate = null;
+		if (method === "return") {
+			return { method: "return", arg: result.value };
+		}
+		context[delegate.resultName] = result.value;
+		context.next = delegate.nextLoc;
+		return { method: "next", arg: undefined };
+	}
+
+	function makeInvoke(innerFn, self, context) {
+		var state = SuspendedStart;
+
+		return function invoke(method, arg) {
+			if (state === Executing) {
+				throw new TypeError("Generator is already running");
+			}
+			if (state === Completed) {
+				if (method === "throw") {
+					throw arg;
+				}
+				return { value: method === "return" ? arg : undefined, done: true };
+			}
+
+			if (context.delegate) {
+				var resumed = resumeDelegate(context, method, arg);
+				if (resumed.yielded) {
+					return resumed.yielded;
+				}
+				method = resumed.method;
+				arg = resumed.arg;
+			}
+			switch (method) {
+			case "next":
+				context.sent = context._sent = arg;
+				break;
+			case "throw":
+				if (state === SuspendedStart) {
+					state = Completed;
+					throw arg;
+				}
+				context.dispatchException(arg);
+				break;
+			case "return":
+				context.abrupt("return", arg);
+				break;
+			}
+
+			for (;;) {
+				state = Executing;
+				var result;
+				try {
+					result = innerFn.call(self, context);
+				} catch (e) {
+					if (context.done) {
+						state = Completed;
+						throw e;
+					}
+					context.dispatchException(e);
+					continue;
+				}
+				state = context.done ? Completed : SuspendedYield;
+				if (result !== Continue) {
+					return { value: result, done: context.done };
+				}
+				if (context.delegate) {
+					resumed = resumeDelegate(context, "next", undefined);
+					if (resumed.yielded) {
+						state = SuspendedYield;
+						return resumed.yielded;
+					}
+					if (resumed.method === "throw") {
+						context.dispatchException(resumed.arg);
+					}
+				}
+			}
+		};
+	}
+
+	Object.defineProperty(Gp, "next", { value: function (arg) { return this._invoke("next", arg); }, configurable: true, writable: true });
+	Object.defineProperty(Gp, "throw", { value: function (arg) { return this._invoke("throw", arg); }, configurable: true, writable: true });
+	Object.defineProperty(Gp, "return", { value: function (arg) { return this._invoke("return", arg); }, configurable: true, writable: true });
+	Object.defineProperty(Gp, "toString", { value: function () { return "[object Generator]"; }, configurable: true, writable: true });
+	if (typeof Symbol === "function" && Symbol.iterator) {
+		Object.defineProperty(Gp, Symbol.iterator, { value: function () { return this; }, configurable: true, writable: true });
+	}
+
+	function values(iterable) {
+		if (iterable && typeof Symbol === "function" && typeof iterable[Symbol.iterator] === "function") {
+			return iterable[Symbol.iterator]();
+		}
+		if (iterable && typeof iterable.next === "function") {
+			return iterable;
+		}
+		if (iterable && typeof iterable.length === "number") {
+			var i = 0;
+			return {
+				next: function () {
+					return i < iterable.length ? { value: iterable[i++], done: false } : { value: undefined, done: true };
+				},
+			};
+		}
+		throw new TypeError(typeof iterable + " is not iterable");
+	}
+
+	return {
+		mark: function (genFun) {
+			Object.setPrototypeOf(genFun, GeneratorFunctionPrototype);
+			genFun.prototype = Object.create(Gp);
+			return genFun;
+		},
+
+		isGeneratorFunction: function (genFun) {
+			var ctor = typeof genFun === "function" && genFun.constructor;
+			return ctor === GeneratorFunction || (ctor && ctor.name === "GeneratorFunction") || false;
+		},
+
+		wrap: function (innerFn, outerFn, self, tryLocsList) {
+			var proto = outerFn && outerFn.prototype instanceof Generator ? outerFn.prototype : Gp;
+			var generator = Object.create(proto);
+			Object.defineProperty(generator, "_invoke", {
+				value: makeInvoke(innerFn, self, new Context(tryLocsList || [])),
+			});
+			return generator;
+		},
+
+		// the keys of an object for a for-in loop in a generator function
+		keys: function (object) {
+			var keys = [];
+			for (var key in object) {
+				keys.push(key);
+			}
+			keys.reverse();
+			return function next() {
+				while (keys.length) {
+					var key = keys.pop();
+					if (key in object) {
+						return { value: key, done: false };
+					}
+				}
+				return { value: undefined, done: true };
+			};
+		},
+
+		values: values,
+	};
+})();
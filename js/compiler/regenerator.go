@@ -0,0 +1,48 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package compiler
+
+import (
+	_ "embed" // we need this for embedding the regeneratorRuntime
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+//go:embed lib/regenerator.js
+var regeneratorSrc string //nolint:gochecknoglobals
+
+var (
+	onceRegenerator      sync.Once     // nolint:gochecknoglobals
+	globalRegenerator    *goja.Program // nolint:gochecknoglobals
+	globalRegeneratorErr error         // nolint:gochecknoglobals
+)
+
+// RegeneratorRuntime returns the program that defines the regeneratorRuntime
+// global, which the generator and async functions that Babel transformed need.
+// It has to be run before any code that was compiled in the extended mode.
+func RegeneratorRuntime() (*goja.Program, error) {
+	onceRegenerator.Do(func() {
+		globalRegenerator, globalRegeneratorErr = goja.Compile(
+			"<internal/k6/compiler/lib/regenerator.js>", regeneratorSrc, false)
+	})
+	return globalRegenerator, globalRegeneratorErr
+}
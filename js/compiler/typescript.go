@@ -0,0 +1,1202 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package compiler
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// TypeScript modules are transpiled by erasing their types, i.e. replacing the
+// type annotations, the type-only declarations and the imports that are only
+// used as types with spaces. Nothing else is moved, so the lines and columns of
+// the JavaScript that is left are the same as in the TypeScript source, and the
+// error messages and stack traces point to the original code without the need
+// for a source map. Erased statements and class members are replaced with an
+// empty statement, so the automatic semicolon insertion isn't affected.
+//
+// The TypeScript features that need code to be generated, like enums,
+// namespaces, parameter properties and decorators, aren't supported.
+
+// isTypeScript returns whether the module with the given filename or URL has
+// to be transpiled from TypeScript.
+func isTypeScript(filename string) bool {
+	switch path.Ext(filename) {
+	case ".ts", ".mts", ".cts":
+		return true
+	default:
+		return false
+	}
+}
+
+type tsScope int
+
+const (
+	tsScopeOther tsScope = iota
+	tsScopeParams
+	tsScopeClass
+)
+
+type tsEdit struct {
+	start, end int
+	// what the start of the erased source is replaced with
+	text string
+}
+
+type tsStripper struct {
+	src    string
+	tokens []esmToken
+	pos    int
+	edits  []tsEdit
+	// the kinds of the parentheses, brackets and braces, by their depth
+	scopes []tsScope
+	// the depths at which there are variable declarations with typed bindings
+	decls map[int]bool
+	// the parentheses known to be parameter lists and the braces known to be
+	// class bodies, before the walk gets to them
+	params, classes map[int]bool
+	memberStart     bool
+}
+
+//nolint:gochecknoglobals
+var (
+	tsAccessModifiers = map[string]bool{
+		"public": true, "private": true, "protected": true, "readonly": true, "override": true,
+	}
+	tsControlKeywords = map[string]bool{
+		"if": true, "for": true, "while": true, "switch": true, "with": true, "await": true,
+	}
+)
+
+func tsUnsupported(what string) error {
+	return fmt.Errorf("TypeScript %s aren't supported, only types can be erased", what)
+}
+
+// stripTypes returns the JavaScript code of the TypeScript module in src.
+func stripTypes(src string) (string, error) {
+	tokens, err := lexESM(src)
+	if err != nil {
+		return "", err
+	}
+	s := &tsStripper{
+		src: src, tokens: tokens,
+		decls: make(map[int]bool), params: make(map[int]bool), classes: make(map[int]bool),
+	}
+	for s.pos < len(s.tokens) {
+		if err = s.next(); err != nil {
+			line := 1 + strings.Count(src[:s.tok(s.pos).start], "\n")
+			return "", fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return elideImports(s.output())
+}
+
+// tok returns the token at i, or an empty one at the end of the module.
+func (s *tsStripper) tok(i int) esmToken {
+	if i < 0 || i >= len(s.tokens) {
+		if i >= len(s.tokens) {
+			return esmToken{start: len(s.src), end: len(s.src), newline: true}
+		}
+		return esmToken{}
+	}
+	return s.tokens[i]
+}
+
+func (s *tsStripper) punct(i int, text string) bool {
+	return s.tok(i).is(esmPunct, text)
+}
+
+func (s *tsStripper) ident(i int, text string) bool {
+	return s.tok(i).is(esmIdent, text)
+}
+
+// operator returns whether the punctuators from i are the given operator.
+func (s *tsStripper) operator(i int, op string) bool {
+	for k := 0; k < len(op); k++ {
+		if !s.punct(i+k, op[k:k+1]) || (k > 0 && s.tok(i+k).start != s.tok(i+k-1).end) {
+			return false
+		}
+	}
+	return true
+}
+
+// matching returns the index of the token that closes the one at i.
+func (s *tsStripper) matching(i int) int {
+	depth := s.tokens[i].depth
+	for k := i + 1; k < len(s.tokens); k++ {
+		if s.tokens[k].depth <= depth {
+			return k
+		}
+	}
+	return -1
+}
+
+// endsExpression returns whether the token at i can be the end of an
+// expression, so it can be followed by a type assertion.
+func (s *tsStripper) endsExpression(i int) bool {
+	tok := s.tok(i)
+	switch tok.kind {
+	case esmIdent:
+		return !regexpKeywords[tok.text] || s.punct(i-1, ".")
+	case esmString, esmNumber, esmRegexp:
+		return true
+	case esmTemplate:
+		return strings.HasSuffix(tok.text, "`")
+	case esmPunct:
+		return tok.text == ")" || tok.text == "]" || tok.text == "}"
+	default:
+		return false
+	}
+}
+
+// startsExpression returns whether an expression can start at i, where a
+// `<` can only be the start of a type assertion or generic arrow function.
+func (s *tsStripper) startsExpression(i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := s.tok(i - 1)
+	switch prev.kind {
+	case esmPunct:
+		return prev.text != ")" && prev.text != "]" && prev.text != "}"
+	case esmIdent:
+		return regexpKeywords[prev.text]
+	default:
+		return false
+	}
+}
+
+func (s *tsStripper) isStatementStart(i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := s.tok(i - 1)
+	if prev.kind == esmPunct && (prev.text == ";" || prev.text == "}" || prev.text == "{") {
+		return true
+	}
+	return s.tok(i).newline && !prev.is(esmPunct, ".")
+}
+
+// followedOnLine returns whether the token after i is an identifier on the
+// same line, like the names of declarations after TypeScript keywords.
+func (s *tsStripper) followedOnLine(i int) bool {
+	next := s.tok(i + 1)
+	return next.kind == esmIdent && !next.newline
+}
+
+// erase replaces the tokens from the one at from to the one before to.
+func (s *tsStripper) erase(from, to int, text string) {
+	if to <= from {
+		return
+	}
+	s.edits = append(s.edits, tsEdit{start: s.tok(from).start, end: s.tok(to - 1).end, text: text})
+}
+
+// eraseStatement erases a statement or class member together with the
+// semicolon after it, if there is one, and moves to the following token.
+func (s *tsStripper) eraseStatement(from, to int) {
+	if s.punct(to, ";") {
+		to++
+	}
+	s.erase(from, to, ";")
+	s.pos = to
+}
+
+func (s *tsStripper) output() string {
+	// the enclosing edits go first, so the ones inside them are skipped
+	sort.Slice(s.edits, func(i, j int) bool {
+		if s.edits[i].start != s.edits[j].start {
+			return s.edits[i].start < s.edits[j].start
+		}
+		return s.edits[i].end > s.edits[j].end
+	})
+	var b strings.Builder
+	copied := 0
+	for _, edit := range s.edits {
+		if edit.start < copied {
+			continue // already erased together with an enclosing statement
+		}
+		b.WriteString(s.src[copied:edit.start])
+		b.WriteString(edit.text)
+		skip := len(edit.text)
+		for _, r := range s.src[edit.start:edit.end] {
+			switch {
+			case r == '\n' || r == '\r' || r == '\t' || r == '\u2028' || r == '\u2029':
+				b.WriteRune(r)
+			case skip > 0:
+				skip--
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		copied = edit.end
+	}
+	b.WriteString(s.src[copied:])
+	return b.String()
+}
+
+func (s *tsStripper) next() error {
+	tok := s.tokens[s.pos]
+	if s.inClass(tok) && (s.memberStart || tok.newline && s.endsExpression(s.pos-1)) {
+		s.memberStart = false
+		restart, err := s.classMember()
+		if err != nil || restart || s.pos >= len(s.tokens) {
+			s.memberStart = restart
+			return err
+		}
+		tok = s.tokens[s.pos]
+	}
+	s.memberStart = false
+
+	switch tok.kind {
+	case esmPunct:
+		return s.punctuator(tok)
+	case esmTemplate:
+		if strings.HasSuffix(tok.text, "${") {
+			s.setScope(tok.depth, tsScopeOther)
+		}
+	case esmIdent:
+		if !s.punct(s.pos-1, ".") {
+			return s.keyword(tok)
+		}
+	}
+	s.pos++
+	return nil
+}
+
+func (s *tsStripper) setScope(depth int, scope tsScope) {
+	for len(s.scopes) < depth {
+		s.scopes = append(s.scopes, tsScopeOther)
+	}
+	s.scopes = append(s.scopes[:depth], scope)
+}
+
+func (s *tsStripper) inClass(tok esmToken) bool {
+	return tok.depth > 0 && tok.depth <= len(s.scopes) && s.scopes[tok.depth-1] == tsScopeClass
+}
+
+func (s *tsStripper) punctuator(tok esmToken) error {
+	switch tok.text {
+	case "(", "[", "{":
+		scope := tsScopeOther
+		switch {
+		case tok.text == "(" && (s.params[s.pos] || s.isParamList(s.pos)):
+			scope = tsScopeParams
+		case tok.text == "{" && s.classes[s.pos]:
+			scope = tsScopeClass
+			s.memberStart = true
+		}
+		s.setScope(tok.depth, scope)
+		s.pos++
+		if scope == tsScopeParams {
+			return s.param()
+		}
+		return nil
+	case ")", "]", "}":
+		delete(s.decls, tok.depth+1)
+		s.pos++
+		if tok.depth >= len(s.scopes) {
+			return nil
+		}
+		switch s.scopes[tok.depth] {
+		case tsScopeParams:
+			if s.punct(s.pos, ":") {
+				return s.eraseType(s.pos, s.pos+1)
+			}
+		case tsScopeOther:
+			if tok.text == "}" && s.inClass(tok) {
+				s.memberStart = true
+			}
+		}
+		return nil
+	case ",":
+		s.pos++
+		if s.decls[tok.depth] {
+			return s.binding()
+		}
+		if tok.depth > 0 && tok.depth <= len(s.scopes) && s.scopes[tok.depth-1] == tsScopeParams {
+			return s.param()
+		}
+		return nil
+	case ";":
+		delete(s.decls, tok.depth)
+		s.memberStart = s.inClass(tok)
+	case "<":
+		if s.startsExpression(s.pos) || s.tok(s.pos-1).kind == esmIdent {
+			if end := s.skipTypeArgs(s.pos); end > 0 && (s.startsExpression(s.pos) || s.isTypeArgsEnd(end)) {
+				s.erase(s.pos, end, "")
+				s.pos = end
+				return nil
+			}
+		}
+	case "!":
+		// the non-null assertions, but not the != and !== operators
+		if !tok.newline && s.endsExpression(s.pos-1) && !s.operator(s.pos, "!=") {
+			s.erase(s.pos, s.pos+1, "")
+		}
+	case "@":
+		return tsUnsupported("decorators")
+	}
+	s.pos++
+	return nil
+}
+
+// isTypeArgsEnd returns whether the type arguments ending before the token at
+// i are followed by the arguments of a call or something else that makes them
+// type arguments and not comparisons.
+func (s *tsStripper) isTypeArgsEnd(i int) bool {
+	tok := s.tok(i)
+	return tok.is(esmPunct, "(") || tok.is(esmPunct, "{") || tok.kind == esmTemplate ||
+		tok.is(esmIdent, "extends") || tok.is(esmIdent, "implements")
+}
+
+// isParamList returns whether the parentheses at i are the parameters of a
+// function, a method or an arrow function.
+func (s *tsStripper) isParamList(i int) bool {
+	closing := s.matching(i)
+	if closing < 0 {
+		return false
+	}
+	after := closing + 1
+	if s.operator(after, "=>") {
+		return true
+	}
+	if s.punct(after, ":") {
+		after = s.skipType(after+1, true)
+		if after > 0 && s.operator(after, "=>") {
+			return true
+		}
+	}
+	if !s.punct(after, "{") {
+		return false
+	}
+	// a method or a function with a body, but not a statement like if (x) {
+	switch prev := s.tok(i - 1); prev.kind {
+	case esmIdent:
+		return !tsControlKeywords[prev.text] && !regexpKeywords[prev.text]
+	case esmString, esmNumber:
+		return true
+	case esmPunct:
+		return prev.text == "]" || prev.text == ">" || prev.text == "*"
+	default:
+		return false
+	}
+}
+
+// param erases the type of the parameter starting at the current token.
+func (s *tsStripper) param() error {
+	tok := s.tok(s.pos)
+	next := s.tok(s.pos + 1)
+	if tok.kind == esmIdent && tsAccessModifiers[tok.text] &&
+		(next.kind == esmIdent || next.is(esmPunct, "{") || next.is(esmPunct, "[")) {
+		return tsUnsupported("parameter properties")
+	}
+	if tok.is(esmIdent, "this") && next.is(esmPunct, ":") {
+		end := s.skipType(s.pos+2, true)
+		if end < 0 {
+			return fmt.Errorf("unexpected '%s' in the type", s.tok(s.pos+2).text)
+		}
+		if s.punct(end, ",") {
+			end++
+		}
+		s.erase(s.pos, end, "")
+		s.pos = end
+		return s.param()
+	}
+	if s.operator(s.pos, "...") {
+		s.pos += 3
+	}
+	if !s.skipBinding() {
+		return nil
+	}
+	if s.punct(s.pos, "?") {
+		s.erase(s.pos, s.pos+1, "")
+		s.pos++
+	}
+	if s.punct(s.pos, ":") {
+		return s.eraseType(s.pos, s.pos+1)
+	}
+	return nil
+}
+
+// binding erases the types of the variable declaration binding starting at
+// the current token.
+func (s *tsStripper) binding() error {
+	if !s.skipBinding() {
+		return nil
+	}
+	if s.punct(s.pos, "!") && s.punct(s.pos+1, ":") {
+		s.erase(s.pos, s.pos+1, "")
+		s.pos++
+	}
+	if s.punct(s.pos, ":") {
+		return s.eraseType(s.pos, s.pos+1)
+	}
+	return nil
+}
+
+// skipBinding moves after the identifier or the destructuring pattern at the
+// current token and returns whether there was one.
+func (s *tsStripper) skipBinding() bool {
+	tok := s.tok(s.pos)
+	switch {
+	case tok.kind == esmIdent:
+		s.pos++
+	case tok.is(esmPunct, "{") || tok.is(esmPunct, "["):
+		end := s.matching(s.pos)
+		if end < 0 {
+			return false
+		}
+		s.pos = end + 1
+	default:
+		return false
+	}
+	return true
+}
+
+// eraseType erases the tokens from the one at from to the end of the type
+// starting at typeStart and moves after it.
+func (s *tsStripper) eraseType(from, typeStart int) error {
+	end := s.skipType(typeStart, true)
+	if end < 0 {
+		return fmt.Errorf("unexpected '%s' in the type", s.tok(typeStart).text)
+	}
+	s.erase(from, end, "")
+	s.pos = end
+	return nil
+}
+
+//nolint:funlen,gocyclo,cyclop
+func (s *tsStripper) keyword(tok esmToken) error {
+	stmt := s.isStatementStart(s.pos)
+	next := s.tok(s.pos + 1)
+	switch tok.text {
+	case "let", "const", "var":
+		if tok.text == "const" && next.is(esmIdent, "enum") {
+			return tsUnsupported("enums")
+		}
+		if next.kind == esmIdent || next.is(esmPunct, "{") || next.is(esmPunct, "[") {
+			s.decls[tok.depth] = true
+			s.pos++
+			return s.binding()
+		}
+	case "in", "of":
+		delete(s.decls, tok.depth)
+	case "function":
+		return s.function(s.pos)
+	case "class":
+		if next.kind == esmIdent || next.is(esmPunct, "{") {
+			return s.class()
+		}
+	case "abstract":
+		if next.is(esmIdent, "class") {
+			s.erase(s.pos, s.pos+1, "")
+		}
+	case "as", "satisfies":
+		if !tok.newline && s.pos > 0 && s.endsExpression(s.pos-1) {
+			if tok.text == "as" && next.is(esmIdent, "const") {
+				s.erase(s.pos, s.pos+2, "")
+				s.pos += 2
+				return nil
+			}
+			return s.eraseType(s.pos, s.pos+1)
+		}
+	case "import":
+		if stmt && !next.is(esmPunct, "(") && !next.is(esmPunct, ".") {
+			return s.importStatement(s.pos)
+		}
+	case "export":
+		if stmt {
+			return s.exportStatement(s.pos)
+		}
+	case "type", "interface", "declare", "enum", "namespace", "module":
+		if stmt && s.followedOnLine(s.pos) || tok.text == "module" && stmt && next.kind == esmString {
+			return s.declaration(s.pos, s.pos)
+		}
+	}
+	s.pos++
+	return nil
+}
+
+// declaration erases the TypeScript declaration starting with the keyword at
+// i, which is a part of the statement starting at stmt.
+func (s *tsStripper) declaration(stmt, i int) error {
+	var end int
+	switch s.tok(i).text {
+	case "type":
+		end = s.skipTypeAlias(i)
+	case "interface":
+		end = s.skipToBlockEnd(i)
+	case "declare":
+		end = s.skipDeclare(i + 1)
+	case "enum":
+		return tsUnsupported("enums")
+	case "namespace", "module":
+		if s.punct(i+2, "{") || s.punct(i+2, ".") {
+			return tsUnsupported("namespaces")
+		}
+		s.pos = i + 1
+		return nil
+	}
+	if end < 0 {
+		return fmt.Errorf("unexpected '%s' in the TypeScript declaration", s.tok(s.pos).text)
+	}
+	s.eraseStatement(stmt, end)
+	return nil
+}
+
+// skipTypeAlias returns the index after the type alias declaration at i.
+func (s *tsStripper) skipTypeAlias(i int) int {
+	i += 2
+	if s.punct(i, "<") {
+		if i = s.skipTypeArgs(i); i < 0 {
+			return -1
+		}
+	}
+	if !s.punct(i, "=") {
+		return -1
+	}
+	return s.skipType(i+1, true)
+}
+
+// skipToBlockEnd returns the index after the closing brace of the first block
+// after i, or of the semicolon if there isn't a block before it.
+func (s *tsStripper) skipToBlockEnd(i int) int {
+	depth := s.tok(i).depth
+	for k := i; k < len(s.tokens); k++ {
+		if s.tokens[k].depth == depth && s.tokens[k].is(esmPunct, ";") {
+			return k
+		}
+		if s.tokens[k].depth == depth && s.tokens[k].is(esmPunct, "{") {
+			if end := s.matching(k); end > 0 {
+				return end + 1
+			}
+			return -1
+		}
+	}
+	return -1
+}
+
+// skipDeclare returns the index after the ambient declaration at i.
+func (s *tsStripper) skipDeclare(i int) int {
+	switch s.tok(i).text {
+	case "const", "let", "var":
+		for {
+			i++
+			if s.tok(i).kind != esmIdent {
+				return -1
+			}
+			if i++; s.punct(i, ":") {
+				if i = s.skipType(i+1, true); i < 0 {
+					return -1
+				}
+			}
+			if !s.punct(i, ",") {
+				return i
+			}
+		}
+	case "function":
+		i += 2
+		if s.punct(i, "<") {
+			if i = s.skipTypeArgs(i); i < 0 {
+				return -1
+			}
+		}
+		if !s.punct(i, "(") {
+			return -1
+		}
+		if i = s.matching(i) + 1; s.punct(i, ":") {
+			return s.skipType(i+1, true)
+		}
+		return i
+	case "type":
+		return s.skipTypeAlias(i)
+	default:
+		return s.skipToBlockEnd(i)
+	}
+}
+
+// function handles the function declaration or expression at i, erasing it
+// if it's an overload signature without a body.
+func (s *tsStripper) function(i int) error {
+	stmt := i
+	for stmt > 0 && !s.tok(stmt).newline &&
+		(s.ident(stmt-1, "export") || s.ident(stmt-1, "default") || s.ident(stmt-1, "async")) {
+		stmt--
+	}
+	i++
+	if s.punct(i, "*") {
+		i++
+	}
+	if s.tok(i).kind == esmIdent {
+		i++
+	}
+	if s.punct(i, "<") {
+		end := s.skipTypeArgs(i)
+		if end < 0 {
+			return fmt.Errorf("unexpected '%s' in the type parameters", s.tok(i).text)
+		}
+		s.erase(i, end, "")
+		i = end
+	}
+	if !s.punct(i, "(") {
+		s.pos = i
+		return nil
+	}
+	s.params[i] = true
+	end := s.matching(i) + 1
+	if end > 0 && s.punct(end, ":") {
+		end = s.skipType(end+1, true)
+	}
+	if end > 0 && !s.punct(end, "{") && s.isStatementStart(stmt) {
+		s.eraseStatement(stmt, end)
+		return nil
+	}
+	s.pos = i
+	return nil
+}
+
+// class handles the header of the class declaration or expression at the
+// current token.
+func (s *tsStripper) class() error {
+	s.pos++
+	if tok := s.tok(s.pos); tok.kind == esmIdent && tok.text != "extends" && tok.text != "implements" {
+		s.pos++
+	}
+	if s.punct(s.pos, "<") {
+		end := s.skipTypeArgs(s.pos)
+		if end < 0 {
+			return fmt.Errorf("unexpected '%s' in the type parameters", s.tok(s.pos).text)
+		}
+		s.erase(s.pos, end, "")
+		s.pos = end
+	}
+	depth := s.tok(s.pos).depth
+	for k := s.pos; k < len(s.tokens); k++ {
+		tok := s.tokens[k]
+		if tok.depth != depth {
+			continue
+		}
+		if tok.is(esmIdent, "implements") {
+			body := k
+			for body < len(s.tokens) && !(s.tokens[body].depth == depth && s.tokens[body].is(esmPunct, "{")) {
+				body++
+			}
+			s.erase(k, body, "")
+			s.classes[body] = true
+			return nil
+		}
+		if tok.is(esmPunct, "{") {
+			s.classes[k] = true
+			return nil
+		}
+	}
+	return nil
+}
+
+func isMemberNameStart(tok esmToken) bool {
+	switch tok.kind {
+	case esmIdent, esmString, esmNumber:
+		return true
+	case esmPunct:
+		return tok.text == "[" || tok.text == "#" || tok.text == "*"
+	default:
+		return false
+	}
+}
+
+// classMember erases the types of the class member starting at the current
+// token and returns whether the walk is at the start of the next member, after
+// the whole member or its type was erased.
+//
+//nolint:funlen,gocyclo,cyclop
+func (s *tsStripper) classMember() (bool, error) {
+	start := s.pos
+	erase := false
+	for s.tok(s.pos).kind == esmIdent && isMemberNameStart(s.tok(s.pos+1)) {
+		tok := s.tok(s.pos)
+		if tok.text == "declare" || tok.text == "abstract" {
+			erase = true
+		} else if tsAccessModifiers[tok.text] {
+			s.erase(s.pos, s.pos+1, "")
+		} else if tok.text != "static" && tok.text != "async" && tok.text != "get" &&
+			tok.text != "set" && tok.text != "accessor" {
+			break
+		}
+		s.pos++
+	}
+	if s.punct(s.pos, "*") {
+		s.pos++
+	}
+
+	switch tok := s.tok(s.pos); {
+	case tok.is(esmPunct, "["):
+		end := s.matching(s.pos)
+		if end < 0 {
+			return false, errUnexpectedEOF
+		}
+		if s.tok(s.pos+1).kind == esmIdent && s.punct(s.pos+2, ":") {
+			// an index signature
+			if !s.punct(end+1, ":") {
+				return false, fmt.Errorf("unexpected '%s' in the index signature", s.tok(end+1).text)
+			}
+			if end = s.skipType(end+2, true); end < 0 {
+				return false, fmt.Errorf("unexpected '%s' in the index signature", s.tok(s.pos).text)
+			}
+			s.eraseStatement(start, end)
+			return true, nil
+		}
+		s.pos = end + 1
+	case tok.is(esmPunct, "#"):
+		s.pos += 2
+	case tok.kind == esmIdent || tok.kind == esmString || tok.kind == esmNumber:
+		s.pos++
+	default:
+		s.pos = start
+		return false, nil
+	}
+
+	if s.punct(s.pos, "?") || s.punct(s.pos, "!") && !s.operator(s.pos, "!=") {
+		s.erase(s.pos, s.pos+1, "")
+		s.pos++
+	}
+	end := s.pos
+	switch {
+	case s.punct(s.pos, "<") || s.punct(s.pos, "("):
+		if s.punct(end, "<") {
+			if end = s.skipTypeArgs(end); end < 0 {
+				return false, fmt.Errorf("unexpected '%s' in the type parameters", s.tok(s.pos).text)
+			}
+		}
+		if !s.punct(end, "(") {
+			return false, fmt.Errorf("unexpected '%s' in the method", s.tok(end).text)
+		}
+		typeParams := s.pos
+		params := end
+		if end = s.matching(end) + 1; end > 0 && s.punct(end, ":") {
+			end = s.skipType(end+1, true)
+		}
+		if end < 0 {
+			return false, fmt.Errorf("unexpected '%s' in the method", s.tok(params).text)
+		}
+		if erase || !s.punct(end, "{") {
+			// abstract methods and overload signatures
+			s.eraseStatement(start, end)
+			return true, nil
+		}
+		s.erase(typeParams, params, "")
+		s.params[params] = true
+		s.pos = params
+	case s.punct(s.pos, ":"):
+		if end = s.skipType(s.pos+1, true); end < 0 {
+			return false, fmt.Errorf("unexpected '%s' in the type", s.tok(s.pos+1).text)
+		}
+		if erase || !s.punct(end, "=") {
+			// fields that are only declared for their types aren't defined,
+			// like when TypeScript doesn't use define semantics for them
+			s.eraseStatement(start, end)
+			return true, nil
+		}
+		s.erase(s.pos, end, "")
+		s.pos = end
+		return false, nil
+	case erase:
+		s.eraseStatement(start, s.pos)
+		return true, nil
+	}
+	return false, nil
+}
+
+// importStatement handles the import statement at i, erasing it if it only
+// imports types.
+func (s *tsStripper) importStatement(i int) error {
+	next := s.tok(i + 1)
+	switch {
+	case next.is(esmIdent, "type") && (s.tok(i+2).kind == esmIdent && !s.ident(i+2, "from") ||
+		s.punct(i+2, "{") || s.punct(i+2, "*")):
+		if s.punct(i+3, "=") {
+			s.eraseStatement(i, s.skipImportEquals(i+4))
+			return nil
+		}
+		s.eraseStatement(i, s.skipModuleSpecifier(i))
+		return nil
+	case next.kind == esmIdent && s.punct(i+2, "=") && !s.operator(i+2, "=>"):
+		// import x = require("x") is the same as a constant
+		s.erase(i, i+1, "const ")
+		s.pos = i + 1
+		return nil
+	}
+	for k := i + 1; k < len(s.tokens) && s.tokens[k].kind != esmString; k++ {
+		if s.punct(k, "{") {
+			return s.typeSpecifiers(k)
+		}
+	}
+	s.pos = s.skipModuleSpecifier(i)
+	return nil
+}
+
+// skipImportEquals returns the index after the reference in an import
+// equals declaration at i.
+func (s *tsStripper) skipImportEquals(i int) int {
+	for s.tok(i).kind == esmIdent || s.punct(i, ".") {
+		i++
+	}
+	if s.punct(i, "(") {
+		return s.matching(i) + 1
+	}
+	return i
+}
+
+// skipModuleSpecifier returns the index after the module specifier of the
+// import or export statement at i.
+func (s *tsStripper) skipModuleSpecifier(i int) int {
+	depth := s.tok(i).depth
+	for k := i + 1; k < len(s.tokens); k++ {
+		if s.tokens[k].depth == depth && s.tokens[k].kind == esmString {
+			return k + 1
+		}
+	}
+	return len(s.tokens)
+}
+
+// typeSpecifiers erases the type-only specifiers between the braces at i and
+// moves after them.
+func (s *tsStripper) typeSpecifiers(i int) error {
+	end := s.matching(i)
+	if end < 0 {
+		return errUnexpectedEOF
+	}
+	for k := i + 1; k < end; k++ {
+		// `type as x` imports or exports something named type, `type as` and
+		// `type as as x` something named as
+		if !s.ident(k, "type") || s.tok(k+1).kind != esmIdent || !(s.punct(k-1, "{") || s.punct(k-1, ",")) ||
+			s.ident(k+1, "as") && s.tok(k+2).kind == esmIdent && !s.ident(k+2, "as") {
+			continue
+		}
+		to := k + 2
+		if s.ident(to, "as") {
+			to += 2
+		}
+		if s.punct(to, ",") {
+			to++
+		}
+		s.erase(k, to, "")
+		k = to - 1
+	}
+	s.pos = end + 1
+	return nil
+}
+
+// exportStatement handles the export statement at i.
+func (s *tsStripper) exportStatement(i int) error {
+	next := s.tok(i + 1)
+	switch {
+	case next.is(esmIdent, "type") && (s.punct(i+2, "{") || s.punct(i+2, "*")):
+		if s.punct(i+2, "{") {
+			end := s.matching(i + 2)
+			if end < 0 {
+				return errUnexpectedEOF
+			}
+			if end++; s.ident(end, "from") {
+				end += 2
+			}
+			s.eraseStatement(i, end)
+			return nil
+		}
+		s.eraseStatement(i, s.skipModuleSpecifier(i))
+		return nil
+	case next.kind == esmIdent && (next.text == "type" || next.text == "interface" || next.text == "declare" ||
+		next.text == "enum" || next.text == "namespace" || next.text == "module") && s.followedOnLine(i+1):
+		return s.declaration(i, i+1)
+	case next.is(esmIdent, "default") && s.ident(i+2, "interface") && s.followedOnLine(i+2):
+		return s.declaration(i, i+2)
+	case next.is(esmIdent, "const") && s.ident(i+2, "enum"):
+		return tsUnsupported("enums")
+	case next.is(esmIdent, "as") && s.ident(i+2, "namespace"):
+		s.eraseStatement(i, i+4)
+		return nil
+	case next.is(esmPunct, "=") || next.is(esmIdent, "import") && s.punct(i+3, "="):
+		return tsUnsupported("export assignments")
+	case next.is(esmPunct, "{"):
+		return s.typeSpecifiers(i + 1)
+	case next.is(esmPunct, "*"):
+		s.pos = s.skipModuleSpecifier(i)
+		return nil
+	}
+	s.pos = i + 1
+	return nil
+}
+
+// skipTypeArgs returns the index after the type parameters or arguments
+// between the angle brackets at i, or -1 if they aren't types.
+func (s *tsStripper) skipTypeArgs(i int) int {
+	for {
+		i++
+		if s.punct(i, ">") {
+			return i + 1
+		}
+		if tok := s.tok(i); (tok.text == "const" || tok.text == "in" || tok.text == "out") &&
+			tok.kind == esmIdent && s.tok(i+1).kind == esmIdent {
+			i++
+		}
+		if i = s.skipType(i, true); i < 0 {
+			return -1
+		}
+		if s.ident(i, "extends") {
+			if i = s.skipType(i+1, true); i < 0 {
+				return -1
+			}
+		}
+		if s.punct(i, "=") && !s.operator(i, "=>") {
+			if i = s.skipType(i+1, true); i < 0 {
+				return -1
+			}
+		}
+		if s.punct(i, ">") {
+			return i + 1
+		}
+		if !s.punct(i, ",") {
+			return -1
+		}
+	}
+}
+
+// skipType returns the index after the type starting at i, or -1 if there
+// isn't one.
+func (s *tsStripper) skipType(i int, conditional bool) int {
+	if s.punct(i, "|") || s.punct(i, "&") {
+		i++
+	}
+	for {
+		if i = s.skipTypeOperand(i); i < 0 {
+			return -1
+		}
+		op := s.tok(i)
+		if !(op.is(esmPunct, "|") || op.is(esmPunct, "&")) || s.operator(i, op.text+op.text) || s.operator(i, op.text+"=") {
+			break
+		}
+		i++
+	}
+	if conditional && s.ident(i, "extends") && !s.tok(i).newline {
+		if j := s.skipType(i+1, false); j > 0 && s.punct(j, "?") {
+			if j = s.skipType(j+1, true); j > 0 && s.punct(j, ":") {
+				return s.skipType(j+1, true)
+			}
+			return -1
+		}
+	}
+	return i
+}
+
+//nolint:funlen,gocyclo,cyclop
+func (s *tsStripper) skipTypeOperand(i int) int {
+	for {
+		tok := s.tok(i)
+		if tok.kind != esmIdent || !s.startsType(i+1) ||
+			tok.text != "keyof" && tok.text != "unique" && tok.text != "readonly" && tok.text != "infer" {
+			break
+		}
+		i++
+	}
+
+	tok := s.tok(i)
+	switch {
+	case tok.is(esmPunct, "(") || tok.is(esmPunct, "<"):
+		if tok.text == "<" {
+			if i = s.skipTypeArgs(i); i < 0 || !s.punct(i, "(") {
+				return -1
+			}
+		}
+		if i = s.matching(i) + 1; i <= 0 {
+			return -1
+		}
+		if s.operator(i, "=>") {
+			return s.skipType(i+2, true)
+		}
+	case tok.is(esmIdent, "new") || tok.is(esmIdent, "abstract") && s.ident(i+1, "new"):
+		if tok.text == "abstract" {
+			i++
+		}
+		return s.skipTypeOperand(i + 1)
+	case tok.is(esmPunct, "{") || tok.is(esmPunct, "["):
+		if i = s.matching(i) + 1; i <= 0 {
+			return -1
+		}
+	case tok.is(esmPunct, "-") && s.tok(i+1).kind == esmNumber:
+		i += 2
+	case tok.kind == esmString || tok.kind == esmNumber:
+		i++
+	case tok.kind == esmTemplate:
+		for strings.HasSuffix(s.tok(i).text, "${") {
+			if i = s.skipType(i+1, true); i < 0 || s.tok(i).kind != esmTemplate {
+				return -1
+			}
+		}
+		i++
+	case tok.is(esmIdent, "typeof"):
+		return s.skipTypeOperand(i + 1)
+	case tok.kind == esmIdent:
+		if tok.text == "asserts" && s.followedOnLine(i) {
+			i++
+		}
+		if s.ident(i+1, "is") && !s.tok(i+1).newline {
+			return s.skipType(i+2, true)
+		}
+		if tok.text == "import" && s.punct(i+1, "(") {
+			i = s.matching(i + 1)
+		}
+		for i++; s.punct(i, ".") && s.tok(i+1).kind == esmIdent; i += 2 {
+		}
+		if s.punct(i, "<") && !s.tok(i).newline {
+			if i = s.skipTypeArgs(i); i < 0 {
+				return -1
+			}
+		}
+	default:
+		return -1
+	}
+
+	// array types and indexed access types
+	for s.punct(i, "[") && !s.tok(i).newline {
+		if i = s.matching(i) + 1; i <= 0 {
+			return -1
+		}
+	}
+	return i
+}
+
+// startsType returns whether a type can start at i, so the identifier before
+// it is a type operator and not the name of a type.
+func (s *tsStripper) startsType(i int) bool {
+	tok := s.tok(i)
+	switch tok.kind {
+	case esmIdent, esmString, esmNumber, esmTemplate:
+		return true
+	case esmPunct:
+		return tok.text == "(" || tok.text == "[" || tok.text == "{"
+	default:
+		return false
+	}
+}
+
+type tsImportBinding struct {
+	from, to int
+	local    string
+}
+
+// elideImports erases the import statements, or the bindings in them, that
+// are only used as types and so aren't used anywhere else after the types
+// are erased, like the TypeScript compiler does.
+func elideImports(src string) (string, error) {
+	tokens, err := lexESM(src)
+	if err != nil {
+		return "", err
+	}
+	s := &tsStripper{src: src, tokens: tokens}
+	type importStatement struct {
+		from, to   int
+		defaultImp *tsImportBinding
+		// the namespace import, or the braces of the named imports
+		group *tsImportBinding
+		named []tsImportBinding
+	}
+	var imports []importStatement
+	used := make(map[string]bool)
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.kind != esmIdent || s.punct(i-1, ".") {
+			continue
+		}
+		if tok.text != "import" || tok.depth != 0 || !s.isStatementStart(i) ||
+			s.punct(i+1, "(") || s.punct(i+1, ".") || s.tok(i+1).kind == esmString {
+			used[tok.text] = true
+			continue
+		}
+		imp := importStatement{from: i}
+		k := i + 1
+		if s.tok(k).kind == esmIdent && !s.ident(k, "from") || s.ident(k, "from") && s.ident(k+1, "from") {
+			imp.defaultImp = &tsImportBinding{from: k, to: k + 1, local: s.tok(k).text}
+			if k++; s.punct(k, ",") {
+				k++
+			}
+		}
+		switch {
+		case s.punct(k, "*") && s.ident(k+1, "as"):
+			imp.group = &tsImportBinding{from: k, to: k + 3, local: s.tok(k + 2).text}
+		case s.punct(k, "{"):
+			end := s.matching(k)
+			if end < 0 {
+				return src, nil
+			}
+			imp.group = &tsImportBinding{from: k, to: end + 1}
+			for j := k + 1; j < end; j++ {
+				binding := tsImportBinding{from: j, local: s.tok(j).text}
+				if s.ident(j+1, "as") {
+					j += 2
+					binding.local = s.tok(j).text
+				}
+				binding.to = j + 1
+				if s.punct(j+1, ",") {
+					j++
+				}
+				imp.named = append(imp.named, binding)
+			}
+		}
+		imp.to = s.skipModuleSpecifier(i)
+		imports = append(imports, imp)
+		i = imp.to - 1
+	}
+
+	for _, imp := range imports {
+		usedNamed := 0
+		for _, binding := range imp.named {
+			if used[binding.local] {
+				usedNamed++
+			}
+		}
+		defaultUsed := imp.defaultImp != nil && used[imp.defaultImp.local]
+		groupUsed := imp.group != nil && (imp.group.local != "" && used[imp.group.local] || usedNamed > 0)
+		switch {
+		case !defaultUsed && !groupUsed:
+			s.pos = imp.from
+			s.eraseStatement(imp.from, imp.to)
+		case !defaultUsed && imp.defaultImp != nil:
+			s.erase(imp.defaultImp.from, imp.group.from, "")
+		case !groupUsed && imp.group != nil:
+			s.erase(imp.defaultImp.to, imp.group.to, "")
+		}
+		if !groupUsed {
+			continue
+		}
+		for _, binding := range imp.named {
+			if !used[binding.local] {
+				to := binding.to
+				if s.punct(to, ",") {
+					to++
+				}
+				s.erase(binding.from, to, "")
+			}
+		}
+	}
+	return s.output(), nil
+}
@@ -0,0 +1,194 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package compiler
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/testutils"
+)
+
+func TestStripTypes(t *testing.T) {
+	t.Parallel()
+
+	// the erased types are left as spaces that don't matter for the tests
+	spaces := regexp.MustCompile(`\s+`)
+	punct := regexp.MustCompile(` ?([(),.;:]) ?`)
+	normalize := func(code string) string {
+		return strings.TrimSpace(punct.ReplaceAllString(spaces.ReplaceAllString(code, " "), "$1"))
+	}
+
+	tests := []struct{ ts, js string }{
+		{`let a: number = 1, b: Array<string>, c!: Map<string, () => void>;`, `let a = 1, b, c;`},
+		{`const { a, b }: { a: number; b?: string } = obj;`, `const { a, b } = obj;`},
+		{`for (let i: number = 0; i < n; i++) {}`, `for (let i = 0; i < n; i++) {}`},
+		{`function f<T extends object = {}>(a: T, b?: string, ...c: number[]): Promise<T> { return a; }`,
+			`function f(a, b, ...c) { return a; }`},
+		{`function f(a: string): void;
+function f(a: number): void;
+function f(a: any) {}`, `; ; function f(a) {}`},
+		{`export function f(this: Window, a: string) {}`, `export function f(a) {}`},
+		{`const f = async (a: number, { b }: Opts = {}): Promise<void> => {};`, `const f = async (a, { b } = {}) => {};`},
+		{`const g = <T,>(x: T): T => x;`, `const g = (x) => x;`},
+		{`x = a ? (b) : c;`, `x = a ? (b) : c;`},
+		{`let v = (a as any) as Foo<Bar>[], w = y satisfies Z, u = [1] as const;`, `let v = (a), w = y, u = [1];`},
+		{`let n = a!.b!.c! + d!; if (a != b && a!==c) {}`, `let n = a.b.c + d; if (a != b && a!==c) {}`},
+		{`let r = <string>foo; let m = new Map<string, number>(); f<T>(1); a < b && c > (d);`,
+			`let r = foo; let m = new Map(); f(1); a < b && c > (d);`},
+		{`type A<T> = { a: T } | [T, ...T[]] | ((x: T) => void) | keyof typeof obj | ` + "`a${string}`" + `;
+interface B extends A<number> { b(): void; [key: string]: any }
+declare const c: number;
+declare module "m" { export const x: number; }
+declare global { interface Window { k6: any } }
+let d = 1;`, `; ; ; ; ; let d = 1;`},
+		{`type Cond<T> = T extends string ? "s" : T extends (infer U)[] ? U : never; let x = 1`, `; let x = 1`},
+		{`abstract class A<T> extends B<T> implements C, D<T> {
+	private readonly a: number = 1;
+	protected b?: string;
+	declare c: number;
+	static d: Map<string, number>
+	e!: T
+	#f: boolean = false;
+	[key: string]: any;
+	constructor(a: number) { super(); }
+	abstract g(): void;
+	h(a: string): void;
+	h(a: any): void {}
+	get i(): number { return 1; }
+	public async *j<U>(u: U): AsyncGenerator<U> {}
+	k = (x: number): number => x;
+}`, `class A extends B {
+	a = 1;
+	;
+	;
+	;
+	;
+	#f = false;
+	;
+	constructor(a) { super(); }
+	;
+	;
+	h(a) {}
+	get i() { return 1; }
+	async *j(u) {}
+	k = (x) => x;
+}`},
+		{`class P { name: string; private q?: string; r; s = 1 }`, `class P { ; ; r; s = 1 }`},
+		{`import type { A } from "a"; import type B from "b"; import { type C, d, type E as F } from "c";
+export type { G } from "g"; export { type H, d }; export type I = number; export interface J {}
+export declare const k: number; export default interface L {}
+import m = require("m");
+export abstract class N {}`, `; ; import { d, } from "c";
+; export { d }; ; ;
+; ;
+const m = require("m");
+export class N {}`},
+		{`import { Options } from "k6/options"; import check, { sleep, group } from "k6"; import * as ns from "ns";
+export const options: Options = {}; export default function() { sleep(1); }`,
+			`; import { sleep, } from "k6"; ;
+export const options = {}; export default function() { sleep(1); }`},
+		{`try {} catch (e: unknown) { throw e as Error; }`, `try {} catch (e) { throw e; }`},
+		{`const o = { m(a: number): string { return ""; }, type: 1, as: 2, n: x as number };`,
+			`const o = { m(a) { return ""; }, type: 1, as: 2, n: x };`},
+	}
+	for _, test := range tests {
+		code, err := stripTypes(test.ts)
+		require.NoError(t, err, test.ts)
+		assert.Equal(t, normalize(test.js), normalize(code), test.ts)
+
+		// the lines and columns of everything that is left stay the same
+		tsLines, jsLines := strings.Split(test.ts, "\n"), strings.Split(code, "\n")
+		require.Len(t, jsLines, len(tsLines), test.ts)
+		for i := range tsLines {
+			assert.Equal(t, utf8.RuneCountInString(tsLines[i]), utf8.RuneCountInString(jsLines[i]), test.ts)
+		}
+	}
+}
+
+func TestStripTypesUnsupported(t *testing.T) {
+	t.Parallel()
+
+	for src, expected := range map[string]string{
+		`enum E { A, B }`:                               "line 1: TypeScript enums aren't supported",
+		"let a = 1;\nconst enum E { A }":                "line 2: TypeScript enums aren't supported",
+		`namespace N { export const a = 1; }`:           "line 1: TypeScript namespaces aren't supported",
+		`class A { constructor(private a: number) {} }`: "line 1: TypeScript parameter properties aren't supported",
+		`@decorator class A {}`:                         "line 1: TypeScript decorators aren't supported",
+		`export = foo;`:                                 "line 1: TypeScript export assignments aren't supported",
+	} {
+		_, err := stripTypes(src)
+		require.Error(t, err, src)
+		assert.Contains(t, err.Error(), expected, src)
+	}
+}
+
+func TestCompileTypeScript(t *testing.T) {
+	t.Parallel()
+
+	src := strings.Join([]string{
+		`interface Result { value: number }`,
+		`function compute(a: number, b?: number): Result {`,
+		`  return { value: a + (b || 0) };`,
+		`}`,
+		`export let value: number = compute(1, 2).value;`,
+		`export function fail(message: string): never {`,
+		`  throw new Error(message as string);`,
+		`}`,
+	}, "\n")
+	for _, compatMode := range []lib.CompatibilityMode{lib.CompatibilityModeBase, lib.CompatibilityModeExtended} {
+		compatMode := compatMode
+		t.Run(compatMode.String(), func(t *testing.T) {
+			t.Parallel()
+			c := New(testutils.NewLogger(t))
+			rt := goja.New()
+			exports := rt.NewObject()
+			require.NoError(t, rt.Set("exports", exports))
+			pgm, _, err := c.Compile(src, "file:///script.ts", "", "", true, compatMode)
+			require.NoError(t, err)
+			_, err = rt.RunProgram(pgm)
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), exports.Get("value").Export())
+
+			fail, ok := goja.AssertFunction(exports.Get("fail"))
+			require.True(t, ok)
+			_, err = fail(goja.Undefined(), rt.ToValue("oops"))
+			require.Error(t, err)
+			// the stack trace points to the TypeScript source
+			assert.Contains(t, err.Error(), "at fail (file:///script.ts:7:9(")
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := New(testutils.NewLogger(t)).Compile(
+			"let a = 1;\nenum E { A }", "file:///script.ts", "", "", true, lib.CompatibilityModeExtended)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "file:///script.ts: line 2: TypeScript enums aren't supported")
+	})
+}
@@ -22,21 +22,35 @@ package js
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/log"
 )
 
+// consoleStopTimeout is how long closing a console waits for its remote logs to be pushed.
+const consoleStopTimeout = 5 * time.Second
+
 // console represents a JS console implemented as a logrus.Logger.
 type console struct {
 	logger logrus.FieldLogger
+	// level is the least severe level of the messages that are logged, the others are dropped
+	// before being formatted.
+	level logrus.Level
+	// stopOutput stops the output of the console, if it has to be stopped.
+	stopOutput func()
 }
 
 // Creates a console with the standard logrus logger.
 func newConsole(logger logrus.FieldLogger) *console {
-	return &console{logger.WithField("source", "console")}
+	return &console{logger: logger.WithField("source", "console"), level: logrus.TraceLevel}
 }
 
 // Creates a console logger with its output set to the file at the provided `filepath`.
@@ -50,7 +64,173 @@ func newFileConsole(filepath string, formatter logrus.Formatter) (*console, erro
 	l.SetOutput(f)
 	l.SetFormatter(formatter)
 
-	return &console{l}, nil
+	return &console{logger: l, level: logrus.TraceLevel, stopOutput: func() { _ = f.Close() }}, nil
+}
+
+// newConsoleFromOutput creates the console for the --console-output option, which is either the
+// path of a file or one of:
+//   - none, the messages are discarded
+//   - log[,level=<level>], the messages go to the k6 logs
+//   - file=<path>[,maxSize=<size>][,maxBackups=<count>][,level=<level>], a file that is rotated
+//     when it would get larger than the size, like 10MB
+//   - loki[=<url>][,...], the messages are pushed to Loki, with the options of --log-output loki
+func newConsoleFromOutput(
+	line string, logger *logrus.Logger, fallbackLogger logrus.FieldLogger,
+) (*console, error) {
+	key := strings.SplitN(strings.SplitN(line, ",", 2)[0], "=", 2)[0]
+	switch key {
+	case "none":
+		if line != "none" {
+			return nil, fmt.Errorf("the none console output doesn't have options, but is `%s`", line)
+		}
+		l := logrus.New()
+		l.SetOutput(ioutil.Discard)
+		return &console{logger: l, level: logrus.PanicLevel}, nil
+	case "log":
+		opts, err := parseConsoleOutputArgs(line, "level")
+		if err != nil {
+			return nil, err
+		}
+		c := newConsole(logger)
+		return c, c.setLevel(opts["level"])
+	case "file":
+		return newRotatingFileConsole(line, logger.Formatter)
+	case "loki":
+		return newLokiConsole(line, fallbackLogger)
+	default:
+		return newFileConsole(line, logger.Formatter)
+	}
+}
+
+// parseConsoleOutputArgs returns the values of the key=value options of the console output, the
+// first option is the kind of output and the others have to be one of the allowed keys.
+func parseConsoleOutputArgs(line string, allowed ...string) (map[string]string, error) {
+	opts := make(map[string]string)
+	parts := strings.Split(line, ",")
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("console output option `%s` has no value", part)
+		}
+		known := false
+		for _, k := range allowed {
+			known = known || k == kv[0]
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown console output option `%s`", kv[0])
+		}
+		opts[kv[0]] = kv[1]
+	}
+	if kv := strings.SplitN(parts[0], "=", 2); len(kv) == 2 {
+		opts[kv[0]] = kv[1]
+	}
+	return opts, nil
+}
+
+func newRotatingFileConsole(line string, formatter logrus.Formatter) (*console, error) {
+	opts, err := parseConsoleOutputArgs(line, "maxSize", "maxBackups", "level")
+	if err != nil {
+		return nil, err
+	}
+	if opts["file"] == "" {
+		return nil, fmt.Errorf("the file console output should be in the form `file=path` but is `%s`", line)
+	}
+	var maxSize int64
+	if v, ok := opts["maxSize"]; ok {
+		if maxSize, err = parseConsoleSize(v); err != nil {
+			return nil, err
+		}
+	}
+	maxBackups := 1
+	if v, ok := opts["maxBackups"]; ok {
+		if maxBackups, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("couldn't parse the console output maxBackups as a number %w", err)
+		}
+	}
+
+	f, err := log.NewRotatingFile(opts["file"], maxSize, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+	l := logrus.New()
+	l.SetOutput(f)
+	l.SetFormatter(formatter)
+
+	c := &console{logger: l, level: logrus.TraceLevel, stopOutput: func() { _ = f.Close() }}
+	if err := c.setLevel(opts["level"]); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseConsoleSize parses a size in bytes, with an optional KB, MB or GB suffix.
+func parseConsoleSize(s string) (int64, error) {
+	multiplier := int64(1)
+	for suffix, m := range map[string]int64{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if strings.HasSuffix(strings.ToUpper(s), suffix) {
+			s, multiplier = s[:len(s)-len(suffix)], m
+			break
+		}
+	}
+	size, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("the console output maxSize has to be a positive size like 10MB, is `%s`", s)
+	}
+	return size * multiplier, nil
+}
+
+// newLokiConsole creates a console that pushes the messages to Loki, until it's closed.
+func newLokiConsole(line string, fallbackLogger logrus.FieldLogger) (*console, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	hook, err := log.LokiFromConfigLine(ctx, fallbackLogger, line, stopped)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	l.AddHook(hook)
+	levels := hook.Levels()
+	l.SetLevel(levels[len(levels)-1])
+
+	return &console{
+		logger: l,
+		level:  l.Level,
+		stopOutput: func() {
+			cancel()
+			select {
+			case <-stopped:
+			case <-time.After(consoleStopTimeout):
+				fallbackLogger.Errorf("The Loki console output didn't stop in %s", consoleStopTimeout)
+			}
+		},
+	}, nil
+}
+
+// setLevel sets the least severe level of the logged messages, if it isn't empty.
+func (c *console) setLevel(level string) error {
+	if level == "" {
+		return nil
+	}
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("unknown console output level %s", level)
+	}
+	c.level = lvl
+	if l, ok := c.logger.(*logrus.Logger); ok {
+		l.SetLevel(lvl)
+	}
+	return nil
+}
+
+// stop stops the output of the console, it isn't a method of the JS console since it's
+// unexported.
+func (c *console) stop() {
+	if c.stopOutput != nil {
+		c.stopOutput()
+	}
 }
 
 func (c console) log(ctx *context.Context, level logrus.Level, msgobj goja.Value, args ...goja.Value) {
@@ -61,6 +241,9 @@ func (c console) log(ctx *context.Context, level logrus.Level, msgobj goja.Value
 		default:
 		}
 	}
+	if level > c.level {
+		return
+	}
 
 	msg := msgobj.String()
 	if len(args) > 0 {
@@ -72,15 +255,25 @@ func (c console) log(ctx *context.Context, level logrus.Level, msgobj goja.Value
 
 		msg = strings.Join(strs, " ")
 	}
+	// the context of the VU is in the entries, so the formatters can add its fields, like the
+	// JSON logs of k6 do
+	logger := c.logger
+	if ctx != nil && *ctx != nil {
+		if l, ok := logger.(interface {
+			WithContext(context.Context) *logrus.Entry
+		}); ok {
+			logger = l.WithContext(*ctx)
+		}
+	}
 	switch level { //nolint:exhaustive
 	case logrus.DebugLevel:
-		c.logger.Debug(msg)
+		logger.Debug(msg)
 	case logrus.InfoLevel:
-		c.logger.Info(msg)
+		logger.Info(msg)
 	case logrus.WarnLevel:
-		c.logger.Warn(msg)
+		logger.Warn(msg)
 	case logrus.ErrorLevel:
-		c.logger.Error(msg)
+		logger.Error(msg)
 	}
 }
 
@@ -24,8 +24,12 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/dop251/goja"
@@ -33,6 +37,7 @@ import (
 	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/guregu/null.v3"
 
 	"go.k6.io/k6/js/common"
@@ -49,7 +54,7 @@ func TestConsoleContext(t *testing.T) {
 
 	ctxPtr := new(context.Context)
 	logger, hook := logtest.NewNullLogger()
-	rt.Set("console", common.Bind(rt, &console{logger}, ctxPtr))
+	rt.Set("console", common.Bind(rt, &console{logger: logger, level: logrus.TraceLevel}, ctxPtr))
 
 	_, err := rt.RunString(`console.log("a")`)
 	assert.NoError(t, err)
@@ -298,3 +303,100 @@ func TestFileConsole(t *testing.T) {
 		})
 	}
 }
+
+func TestConsoleFromOutput(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "k6-console-")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	run := func(t *testing.T, c *console) {
+		rt := goja.New()
+		rt.SetFieldNameMapper(common.FieldNameMapper{})
+		rt.Set("console", common.Bind(rt, c, new(context.Context)))
+		_, err := rt.RunString(`console.debug("a"); console.info("b"); console.warn("c"); console.error("d")`)
+		require.NoError(t, err)
+		c.stop()
+	}
+
+	t.Run("log", func(t *testing.T) {
+		t.Parallel()
+		logger, hook := logtest.NewNullLogger()
+		logger.SetLevel(logrus.DebugLevel)
+		c, err := newConsoleFromOutput("log,level=warn", logger, logger)
+		require.NoError(t, err)
+		run(t, c)
+		entries := hook.AllEntries()
+		require.Len(t, entries, 2)
+		assert.Equal(t, "c", entries[0].Message)
+		assert.Equal(t, "console", entries[0].Data["source"])
+		assert.Equal(t, logrus.DebugLevel, logger.Level)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		t.Parallel()
+		logger, hook := logtest.NewNullLogger()
+		c, err := newConsoleFromOutput("none", logger, logger)
+		require.NoError(t, err)
+		run(t, c)
+		assert.Empty(t, hook.AllEntries())
+	})
+
+	t.Run("file", func(t *testing.T) {
+		t.Parallel()
+		logger, _ := logtest.NewNullLogger()
+		logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+		path := filepath.Join(dir, "console.log")
+		c, err := newConsoleFromOutput("file="+path+",maxSize=1KB,maxBackups=2,level=error", logger, logger)
+		require.NoError(t, err)
+		run(t, c)
+		data, err := ioutil.ReadFile(path) //nolint:gosec
+		require.NoError(t, err)
+		assert.Equal(t, "level=error msg=d\n", string(data))
+	})
+
+	t.Run("loki", func(t *testing.T) {
+		t.Parallel()
+		var (
+			mu     sync.Mutex
+			bodies []string
+		)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			bodies = append(bodies, string(body))
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer srv.Close()
+		logger, _ := logtest.NewNullLogger()
+		c, err := newConsoleFromOutput("loki="+srv.URL+",pushPeriod=1h,level=warn", logger, logger)
+		require.NoError(t, err)
+		run(t, c)
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, bodies, 1)
+		assert.Contains(t, bodies[0], `"c"`)
+		assert.NotContains(t, bodies[0], `"b"`)
+	})
+
+	invalid := []string{
+		"none,level=info",
+		"log,level=loud",
+		"log,file=a",
+		"file=",
+		"file=" + filepath.Join(dir, "a.log") + ",maxSize=big",
+		"file=" + filepath.Join(dir, "a.log") + ",maxBackups=-1",
+		"file=" + filepath.Join(dir, "a.log") + ",maxSize",
+		"loki=http://localhost:3100,unknown=1",
+	}
+	for _, line := range invalid {
+		line := line
+		t.Run(line, func(t *testing.T) {
+			t.Parallel()
+			logger, _ := logtest.NewNullLogger()
+			_, err := newConsoleFromOutput(line, logger, logger)
+			assert.Error(t, err)
+		})
+	}
+}
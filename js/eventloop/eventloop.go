@@ -0,0 +1,233 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package eventloop implements the event loop of a VU's JS runtime, which
+// runs the reactions of promises, the callbacks of timers and the callbacks of
+// asynchronous operations, one at a time, on the goroutine of the VU.
+package eventloop
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// EventLoop runs the callbacks of a single goja runtime. All of its methods,
+// except RegisterCallback and the functions it returns, have to be called on
+// the goroutine that is using the runtime.
+type EventLoop struct {
+	rt *goja.Runtime
+
+	lock       sync.Mutex
+	queue      []func() error
+	registered int
+	generation int
+	wakeup     chan struct{}
+
+	// these are only used on the loop
+	jobs       []goja.Callable
+	rejections []rejection
+	timers     map[int64]*timer
+	lastTimer  int64
+
+	capability goja.Callable
+	inspect    goja.Callable
+}
+
+// New creates a new event loop for the runtime, and defines the Promise and
+// queueMicrotask globals that use it. The timers aren't defined, see Timers().
+func New(rt *goja.Runtime) (*EventLoop, error) {
+	e := &EventLoop{
+		rt:     rt,
+		wakeup: make(chan struct{}, 1),
+		timers: make(map[int64]*timer),
+	}
+	if err := e.definePromise(); err != nil {
+		return nil, err
+	}
+	err := rt.Set("queueMicrotask", func(call goja.FunctionCall) goja.Value {
+		fn, ok := goja.AssertFunction(call.Argument(0))
+		if !ok {
+			panic(rt.NewTypeError("The callback provided as parameter 1 is not a function"))
+		}
+		e.jobs = append(e.jobs, fn)
+		return goja.Undefined()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// RegisterCallback reserves a callback for an asynchronous operation, and the
+// loop doesn't finish until it's run. The returned function enqueues the
+// callback when the operation is done, which can be from any goroutine, and it
+// has to be called exactly once, later calls are ignored. The callback itself
+// is run on the loop, so it can use the runtime, for example to settle a
+// promise, and an error returned from it stops the loop.
+//
+// The callbacks that are enqueued after the loop was stopped by an error or a
+// done context are dropped.
+func (e *EventLoop) RegisterCallback() func(func() error) {
+	e.lock.Lock()
+	e.registered++
+	generation := e.generation
+	e.lock.Unlock()
+
+	var once sync.Once
+	return func(callback func() error) {
+		once.Do(func() {
+			e.lock.Lock()
+			defer e.lock.Unlock()
+			if generation != e.generation {
+				return
+			}
+			e.registered--
+			e.queue = append(e.queue, callback)
+			select {
+			case e.wakeup <- struct{}{}:
+			default:
+			}
+		})
+	}
+}
+
+// Start runs firstCallback and then everything that it caused, like promise
+// reactions, timers and other registered callbacks, until there is nothing
+// left to run or wait for. It returns the first error returned by a callback,
+// the error of the context if it's done before the loop is, or an error for a
+// promise that was rejected without being handled.
+func (e *EventLoop) Start(ctx context.Context, firstCallback func() error) error {
+	if err := e.run(ctx, firstCallback); err != nil {
+		e.reset()
+		return err
+	}
+	return e.unhandledRejection()
+}
+
+// Call calls fn with the arguments on the loop with Start, and returns what it
+// returned. If that's a promise, the value it was fulfilled with is returned
+// instead, or an error if it was rejected. The value returned by fn is also
+// returned if the loop encounters an error.
+func (e *EventLoop) Call(ctx context.Context, fn goja.Callable, args ...goja.Value) (goja.Value, error) {
+	var result goja.Value
+	err := e.Start(ctx, func() error {
+		var err error
+		result, err = fn(goja.Undefined(), args...)
+		if err == nil {
+			// calling inspect marks the returned promise as handled
+			_, err = e.inspectPromise(result)
+		}
+		return err
+	})
+	if err != nil {
+		return result, err
+	}
+
+	state, err := e.inspectPromise(result)
+	if err != nil || state == nil {
+		return result, err
+	}
+	switch state.Get("state").String() {
+	case "fulfilled":
+		return state.Get("value"), nil
+	case "rejected":
+		return result, rejectionError(state.Get("value"))
+	default:
+		// it can't be settled anymore, since nothing is left on the loop
+		return goja.Undefined(), nil
+	}
+}
+
+func (e *EventLoop) run(ctx context.Context, callback func() error) error {
+	for callback != nil {
+		if err := callback(); err != nil {
+			return err
+		}
+		if err := e.runJobs(); err != nil {
+			return err
+		}
+
+		var err error
+		if callback, err = e.next(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *EventLoop) runJobs() error {
+	for len(e.jobs) > 0 {
+		job := e.jobs[0]
+		e.jobs[0] = nil
+		e.jobs = e.jobs[1:]
+		if _, err := job(goja.Undefined()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// next waits for the next enqueued callback, it returns nil when there are no
+// registered callbacks left.
+func (e *EventLoop) next(ctx context.Context) (func() error, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		e.lock.Lock()
+		if len(e.queue) > 0 {
+			callback := e.queue[0]
+			e.queue[0] = nil
+			e.queue = e.queue[1:]
+			e.lock.Unlock()
+			return callback, nil
+		}
+		registered := e.registered
+		e.lock.Unlock()
+
+		if registered == 0 {
+			return nil, nil
+		}
+		select {
+		case <-e.wakeup:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// reset drops everything that is still on the loop, so it can be started
+// again after an error.
+func (e *EventLoop) reset() {
+	e.lock.Lock()
+	e.generation++
+	e.registered = 0
+	e.queue = nil
+	e.lock.Unlock()
+
+	e.jobs = nil
+	e.rejections = nil
+	for id, t := range e.timers {
+		t.timer.Stop()
+		delete(e.timers, id)
+	}
+}
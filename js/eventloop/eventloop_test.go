@@ -0,0 +1,258 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package eventloop
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLoop(t *testing.T) (*goja.Runtime, *EventLoop) {
+	rt := goja.New()
+	loop, err := New(rt)
+	require.NoError(t, err)
+	for name, fn := range loop.Timers() {
+		require.NoError(t, rt.Set(name, fn))
+	}
+	return rt, loop
+}
+
+func runScript(loop *EventLoop, rt *goja.Runtime, src string) error {
+	return loop.Start(context.Background(), func() error {
+		_, err := rt.RunString(src)
+		return err
+	})
+}
+
+func TestEventLoopOrder(t *testing.T) {
+	t.Parallel()
+	rt, loop := newTestLoop(t)
+	require.NoError(t, runScript(loop, rt, `
+		var log = [];
+		setTimeout(function(a, b) { log.push("timeout 20 " + a + b); }, 20, 1, 2);
+		setTimeout(function() {
+			log.push("timeout 0");
+			Promise.resolve().then(function() { log.push("microtask of timeout 0"); });
+		});
+		Promise.resolve(1).then(function(v) { log.push("then " + v); return v + 1; })
+			.then(function(v) { log.push("then " + v); });
+		queueMicrotask(function() { log.push("microtask"); });
+		new Promise(function(resolve) { log.push("executor"); resolve(); })
+			.finally(function() { log.push("finally"); });
+		log.push("sync");
+	`))
+
+	assert.Equal(t, []interface{}{
+		"executor", "sync", "then 1", "microtask", "finally", "then 2",
+		"timeout 0", "microtask of timeout 0", "timeout 20 12",
+	}, rt.Get("log").Export())
+}
+
+func TestEventLoopTimers(t *testing.T) {
+	t.Parallel()
+
+	t.Run("interval", func(t *testing.T) {
+		t.Parallel()
+		rt, loop := newTestLoop(t)
+		require.NoError(t, runScript(loop, rt, `
+			var count = 0;
+			var id = setInterval(function() {
+				if (++count === 3) {
+					clearInterval(id);
+				}
+			}, 1);
+		`))
+		assert.Equal(t, int64(3), rt.Get("count").ToInteger())
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		t.Parallel()
+		rt, loop := newTestLoop(t)
+		start := time.Now()
+		require.NoError(t, runScript(loop, rt, `
+			var called = false;
+			var id = setTimeout(function() { called = true; }, 10000);
+			setTimeout(function() { clearTimeout(id); }, 1);
+		`))
+		assert.False(t, rt.Get("called").ToBoolean())
+		assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		rt, loop := newTestLoop(t)
+		err := runScript(loop, rt, `
+			var called = false;
+			setTimeout(function() { throw new Error("oops"); }, 1);
+			setTimeout(function() { called = true; }, 50);
+		`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Error: oops")
+
+		// the loop was reset and the second timer is gone
+		require.NoError(t, runScript(loop, rt, `setTimeout(function() {}, 100);`))
+		assert.False(t, rt.Get("called").ToBoolean())
+	})
+}
+
+func TestEventLoopRegisterCallback(t *testing.T) {
+	t.Parallel()
+	rt, loop := newTestLoop(t)
+	require.NoError(t, rt.Set("later", func(value goja.Value) *goja.Object {
+		promise, resolve, _ := loop.NewPromise()
+		enqueue := loop.RegisterCallback()
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			enqueue(func() error {
+				resolve(value.Export().(int64) * 2)
+				return nil
+			})
+			// only the first call matters
+			enqueue(func() error { return errors.New("called twice") })
+		}()
+		return promise
+	}))
+
+	fn, err := rt.RunString(`(function() {
+		return Promise.all([later(1), later(2), 3]).then(function(values) {
+			return values.join(",");
+		});
+	})`)
+	require.NoError(t, err)
+	callable, _ := goja.AssertFunction(fn)
+	v, err := loop.Call(context.Background(), callable)
+	require.NoError(t, err)
+	assert.Equal(t, "2,4,3", v.String())
+}
+
+func TestEventLoopCall(t *testing.T) {
+	t.Parallel()
+	rt, loop := newTestLoop(t)
+	call := func(src string) (goja.Value, error) {
+		fn, err := rt.RunString(src)
+		require.NoError(t, err)
+		callable, _ := goja.AssertFunction(fn)
+		return loop.Call(context.Background(), callable)
+	}
+
+	v, err := call(`(function() { return 1; })`)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), v.Export())
+
+	v, err = call(`(function() {
+		return new Promise(function(resolve) { setTimeout(resolve, 1, "done"); });
+	})`)
+	require.NoError(t, err)
+	assert.Equal(t, "done", v.Export())
+
+	_, err = call(`(function() { return Promise.reject(new Error("rejected")); })`)
+	require.Error(t, err)
+	assert.Equal(t, "Error: rejected", err.Error())
+
+	_, err = call(`(function() { throw new Error("thrown"); })`)
+	require.Error(t, err)
+	var exception *goja.Exception
+	assert.True(t, errors.As(err, &exception))
+}
+
+func TestEventLoopUnhandledRejection(t *testing.T) {
+	t.Parallel()
+	rt, loop := newTestLoop(t)
+
+	err := runScript(loop, rt, `Promise.reject(new Error("first")); Promise.reject("second");`)
+	var rejection *UnhandledRejectionError
+	require.True(t, errors.As(err, &rejection))
+	assert.Equal(t, "Uncaught (in promise) Error: first", err.Error())
+
+	// handled later on, but still before the loop was done
+	require.NoError(t, runScript(loop, rt, `
+		var p = Promise.reject(new Error("handled"));
+		setTimeout(function() { p.catch(function() {}); }, 1);
+	`))
+}
+
+func TestEventLoopContextDone(t *testing.T) {
+	t.Parallel()
+	rt, loop := newTestLoop(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := loop.Start(ctx, func() error {
+		_, err := rt.RunString(`setTimeout(function() {}, 10000);`)
+		return err
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// nothing is left from the previous run
+	require.NoError(t, runScript(loop, rt, `1`))
+}
+
+func TestPromise(t *testing.T) {
+	t.Parallel()
+	rt, loop := newTestLoop(t)
+	require.NoError(t, runScript(loop, rt, `
+		var results = {};
+		function record(name) {
+			return function(v) { results[name] = v; };
+		}
+		function thenable(value) {
+			return { then: function(resolve) { resolve(value); } };
+		}
+		Promise.resolve(thenable(1)).then(record("thenable"));
+		Promise.all([]).then(record("all empty"));
+		Promise.all([Promise.resolve(1), Promise.reject("x")]).catch(record("all rejected"));
+		Promise.allSettled([1, Promise.reject("y")]).then(function(r) {
+			results.allSettled = r.map(function(s) { return s.status + ":" + (s.value || s.reason); }).join(",");
+		});
+		Promise.any([Promise.reject("a"), Promise.resolve("b")]).then(record("any"));
+		Promise.any([Promise.reject("a")]).catch(function(e) {
+			results.anyRejected = e.name + ":" + e.errors.join(",");
+		});
+		Promise.race([new Promise(function() {}), Promise.resolve("r")]).then(record("race"));
+		var p = new Promise(function(resolve) { resolve(); });
+		var cycle = p.then(function() { return cycle; });
+		cycle.catch(function(e) { results.cycle = e instanceof TypeError; });
+		Promise.reject("f").finally(function() { results.finally = true; }).catch(record("after finally"));
+		results.tag = Object.prototype.toString.call(p);
+		try { Promise(function() {}); } catch (e) { results.withoutNew = e instanceof TypeError; }
+	`))
+
+	assert.Equal(t, map[string]interface{}{
+		"thenable":      int64(1),
+		"all empty":     []interface{}{},
+		"all rejected":  "x",
+		"allSettled":    "fulfilled:1,rejected:y",
+		"any":           "b",
+		"anyRejected":   "AggregateError:a",
+		"race":          "r",
+		"cycle":         true,
+		"finally":       true,
+		"after finally": "f",
+		"tag":           "[object Promise]",
+		"withoutNew":    true,
+	}, rt.Get("results").Export())
+}
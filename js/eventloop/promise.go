@@ -0,0 +1,154 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package eventloop
+
+import (
+	_ "embed" // we need this for embedding the Promise implementation
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+//go:embed promise.js
+var promiseSrc string //nolint:gochecknoglobals
+
+var (
+	promiseProgram     *goja.Program //nolint:gochecknoglobals
+	promiseProgramErr  error         //nolint:gochecknoglobals
+	oncePromiseProgram sync.Once     //nolint:gochecknoglobals
+)
+
+// UnhandledRejectionError is returned by the loop when a promise was rejected
+// and nothing handled that by the time there was nothing left to run.
+type UnhandledRejectionError struct {
+	Reason goja.Value
+}
+
+func (e *UnhandledRejectionError) Error() string {
+	return fmt.Sprintf("Uncaught (in promise) %s", describe(e.Reason))
+}
+
+type rejection struct {
+	promise *goja.Object
+	reason  goja.Value
+}
+
+func (e *EventLoop) definePromise() error {
+	oncePromiseProgram.Do(func() {
+		promiseProgram, promiseProgramErr = goja.Compile("<internal/k6/js/eventloop/promise.js>", promiseSrc, true)
+	})
+	if promiseProgramErr != nil {
+		return promiseProgramErr
+	}
+
+	v, err := e.rt.RunProgram(promiseProgram)
+	if err != nil {
+		return err
+	}
+	factory, _ := goja.AssertFunction(v)
+	v, err = factory(goja.Undefined(), e.rt.ToValue(e.enqueueJob), e.rt.ToValue(e.trackRejection))
+	if err != nil {
+		return err
+	}
+	obj := v.ToObject(e.rt)
+	e.capability, _ = goja.AssertFunction(obj.Get("capability"))
+	e.inspect, _ = goja.AssertFunction(obj.Get("inspect"))
+	return e.rt.Set("Promise", obj.Get("Promise"))
+}
+
+func (e *EventLoop) enqueueJob(job goja.Callable) {
+	e.jobs = append(e.jobs, job)
+}
+
+func (e *EventLoop) trackRejection(promise *goja.Object, reason goja.Value, handled bool) {
+	if !handled {
+		e.rejections = append(e.rejections, rejection{promise: promise, reason: reason})
+		return
+	}
+	for i, r := range e.rejections {
+		if r.promise == promise {
+			e.rejections = append(e.rejections[:i], e.rejections[i+1:]...)
+			return
+		}
+	}
+}
+
+func (e *EventLoop) unhandledRejection() error {
+	if len(e.rejections) == 0 {
+		return nil
+	}
+	reason := e.rejections[0].reason
+	e.rejections = nil
+	return &UnhandledRejectionError{Reason: reason}
+}
+
+// NewPromise returns a new pending promise, with the functions that fulfill or
+// reject it. Those have to be called on the loop, usually from a callback that
+// was registered with RegisterCallback, and only the first call matters.
+func (e *EventLoop) NewPromise() (promise *goja.Object, resolve func(interface{}), reject func(interface{})) {
+	v, err := e.capability(goja.Undefined())
+	if err != nil {
+		// this can only happen if the runtime was interrupted
+		panic(err)
+	}
+	capability := v.ToObject(e.rt)
+	resolveFn, _ := goja.AssertFunction(capability.Get("resolve"))
+	rejectFn, _ := goja.AssertFunction(capability.Get("reject"))
+	settle := func(fn goja.Callable) func(interface{}) {
+		return func(value interface{}) {
+			_, _ = fn(goja.Undefined(), e.rt.ToValue(value))
+		}
+	}
+	return capability.Get("promise").ToObject(e.rt), settle(resolveFn), settle(rejectFn)
+}
+
+// inspectPromise returns an object with the state and the value of the promise
+// and marks it as handled, or nil if v isn't a promise.
+func (e *EventLoop) inspectPromise(v goja.Value) (*goja.Object, error) {
+	if v == nil {
+		return nil, nil
+	}
+	state, err := e.inspect(goja.Undefined(), v)
+	if err != nil || goja.IsUndefined(state) {
+		return nil, err
+	}
+	return state.ToObject(e.rt), nil
+}
+
+// rejectionError returns the error a promise was rejected with, or a new one
+// with the reason if it isn't an error thrown from Go code.
+func rejectionError(reason goja.Value) error {
+	if reason != nil {
+		if err, ok := reason.Export().(error); ok {
+			return err
+		}
+	}
+	return errors.New(describe(reason))
+}
+
+func describe(v goja.Value) string {
+	if v == nil {
+		return "undefined"
+	}
+	return v.String()
+}
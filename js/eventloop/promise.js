@@ -0,0 +1,338 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// An ES2015 (and later) compatible Promise, since goja doesn't have one. The
+// reactions of the promises are run as jobs that are enqueued on the event
+// loop with enqueueJob, and trackRejection keeps the loop informed about the
+// rejected promises that don't have a handler yet.
+(function (enqueueJob, trackRejection) {
+	"use strict";
+
+	var PENDING = 0, FULFILLED = 1, REJECTED = 2;
+	var stateNames = ["pending", "fulfilled", "rejected"];
+
+	// the internal slots of the promises
+	var states = new WeakMap();
+
+	function isObject(value) {
+		return value !== null && (typeof value === "object" || typeof value === "function");
+	}
+
+	function stateOf(promise) {
+		var state = isObject(promise) ? states.get(promise) : undefined;
+		if (state === undefined) {
+			throw new TypeError("Method Promise.prototype.then called on incompatible receiver " + String(promise));
+		}
+		return state;
+	}
+
+	function define(object, methods) {
+		Object.keys(methods).forEach(function (name) {
+			Object.defineProperty(object, name, {
+				value: methods[name], writable: true, enumerable: false, configurable: true,
+			});
+		});
+	}
+
+	function runReaction(reaction, kind, value) {
+		enqueueJob(function () {
+			var handler = kind === FULFILLED ? reaction.onFulfilled : reaction.onRejected;
+			if (typeof handler !== "function") {
+				if (kind === FULFILLED) {
+					reaction.resolve(value);
+				} else {
+					reaction.reject(value);
+				}
+				return;
+			}
+			var result;
+			try {
+				result = handler(value);
+			} catch (e) {
+				reaction.reject(e);
+				return;
+			}
+			reaction.resolve(result);
+		});
+	}
+
+	function settle(promise, state, kind, value) {
+		if (state.kind !== PENDING) {
+			return;
+		}
+		var reactions = state.reactions;
+		state.kind = kind;
+		state.value = value;
+		state.reactions = undefined;
+		if (kind === REJECTED && !state.handled) {
+			trackRejection(promise, value, false);
+		}
+		reactions.forEach(function (reaction) {
+			runReaction(reaction, kind, value);
+		});
+	}
+
+	function resolvingFunctions(promise, state) {
+		var alreadyResolved = false;
+		return {
+			resolve: function (resolution) {
+				if (alreadyResolved) {
+					return;
+				}
+				alreadyResolved = true;
+				if (resolution === promise) {
+					settle(promise, state, REJECTED, new TypeError("Chaining cycle detected for promise #<Promise>"));
+					return;
+				}
+				if (!isObject(resolution)) {
+					settle(promise, state, FULFILLED, resolution);
+					return;
+				}
+				var then;
+				try {
+					then = resolution.then;
+				} catch (e) {
+					settle(promise, state, REJECTED, e);
+					return;
+				}
+				if (typeof then !== "function") {
+					settle(promise, state, FULFILLED, resolution);
+					return;
+				}
+				// thenables are followed in a job of their own, like the spec says
+				enqueueJob(function () {
+					var fns = resolvingFunctions(promise, state);
+					try {
+						then.call(resolution, fns.resolve, fns.reject);
+					} catch (e) {
+						fns.reject(e);
+					}
+				});
+			},
+			reject: function (reason) {
+				if (alreadyResolved) {
+					return;
+				}
+				alreadyResolved = true;
+				settle(promise, state, REJECTED, reason);
+			},
+		};
+	}
+
+	function Promise(executor) {
+		if (!(this instanceof Promise) || states.has(this)) {
+			throw new TypeError("Promise constructor cannot be invoked without 'new'");
+		}
+		if (typeof executor !== "function") {
+			throw new TypeError("Promise resolver is not a function");
+		}
+		var state = { kind: PENDING, value: undefined, reactions: [], handled: false };
+		states.set(this, state);
+		var fns = resolvingFunctions(this, state);
+		try {
+			executor(fns.resolve, fns.reject);
+		} catch (e) {
+			fns.reject(e);
+		}
+	}
+
+	function newCapability(C) {
+		var resolve, reject;
+		var promise = new C(function (res, rej) {
+			if (resolve !== undefined || reject !== undefined) {
+				throw new TypeError("Promise executor has already been invoked with non-undefined arguments");
+			}
+			resolve = res;
+			reject = rej;
+		});
+		if (typeof resolve !== "function" || typeof reject !== "function") {
+			throw new TypeError("Promise resolve or reject function is not callable");
+		}
+		return { promise: promise, resolve: resolve, reject: reject };
+	}
+
+	function constructorOf(promise) {
+		var C = promise.constructor;
+		return typeof C === "function" ? C : Promise;
+	}
+
+	define(Promise.prototype, {
+		then: function (onFulfilled, onRejected) {
+			var state = stateOf(this);
+			var capability = newCapability(constructorOf(this));
+			var reaction = {
+				onFulfilled: onFulfilled,
+				onRejected: onRejected,
+				resolve: capability.resolve,
+				reject: capability.reject,
+			};
+			if (state.kind === PENDING) {
+				state.reactions.push(reaction);
+			} else {
+				if (state.kind === REJECTED && !state.handled) {
+					trackRejection(this, state.value, true);
+				}
+				runReaction(reaction, state.kind, state.value);
+			}
+			state.handled = true;
+			return capability.promise;
+		},
+		"catch": function (onRejected) {
+			return this.then(undefined, onRejected);
+		},
+		"finally": function (onFinally) {
+			if (typeof onFinally !== "function") {
+				return this.then(onFinally, onFinally);
+			}
+			var C = constructorOf(this);
+			return this.then(function (value) {
+				return C.resolve(onFinally()).then(function () {
+					return value;
+				});
+			}, function (reason) {
+				return C.resolve(onFinally()).then(function () {
+					throw reason;
+				});
+			});
+		},
+	});
+	if (typeof Symbol === "function" && Symbol.toStringTag !== undefined) {
+		Object.defineProperty(Promise.prototype, Symbol.toStringTag, { value: "Promise", configurable: true });
+	}
+
+	// combine calls onItem with the promise of each item of the iterable, and
+	// rejects the returned promise if the iteration fails
+	function combine(C, iterable, onItem) {
+		var capability = newCapability(C);
+		try {
+			var items = Array.from(iterable);
+			onItem(capability, items.length, items.map(function (item) {
+				return C.resolve(item);
+			}));
+		} catch (e) {
+			capability.reject(e);
+		}
+		return capability.promise;
+	}
+
+	define(Promise, {
+		resolve: function (value) {
+			if (isObject(value) && states.has(value) && value.constructor === this) {
+				return value;
+			}
+			var capability = newCapability(this);
+			capability.resolve(value);
+			return capability.promise;
+		},
+		reject: function (reason) {
+			var capability = newCapability(this);
+			capability.reject(reason);
+			return capability.promise;
+		},
+		all: function (iterable) {
+			return combine(this, iterable, function (capability, remaining, promises) {
+				var values = new Array(remaining);
+				if (remaining === 0) {
+					capability.resolve(values);
+				}
+				promises.forEach(function (promise, i) {
+					promise.then(function (value) {
+						values[i] = value;
+						if (--remaining === 0) {
+							capability.resolve(values);
+						}
+					}, capability.reject);
+				});
+			});
+		},
+		allSettled: function (iterable) {
+			return combine(this, iterable, function (capability, remaining, promises) {
+				var results = new Array(remaining);
+				if (remaining === 0) {
+					capability.resolve(results);
+				}
+				promises.forEach(function (promise, i) {
+					var done = function (result) {
+						results[i] = result;
+						if (--remaining === 0) {
+							capability.resolve(results);
+						}
+					};
+					promise.then(function (value) {
+						done({ status: "fulfilled", value: value });
+					}, function (reason) {
+						done({ status: "rejected", reason: reason });
+					});
+				});
+			});
+		},
+		any: function (iterable) {
+			return combine(this, iterable, function (capability, remaining, promises) {
+				var errors = new Array(remaining);
+				var rejectAll = function () {
+					// goja doesn't have an AggregateError, so this is the closest thing to it
+					var err = new Error("All promises were rejected");
+					err.name = "AggregateError";
+					err.errors = errors;
+					capability.reject(err);
+				};
+				if (remaining === 0) {
+					rejectAll();
+				}
+				promises.forEach(function (promise, i) {
+					promise.then(capability.resolve, function (reason) {
+						errors[i] = reason;
+						if (--remaining === 0) {
+							rejectAll();
+						}
+					});
+				});
+			});
+		},
+		race: function (iterable) {
+			return combine(this, iterable, function (capability, remaining, promises) {
+				promises.forEach(function (promise) {
+					promise.then(capability.resolve, capability.reject);
+				});
+			});
+		},
+	});
+
+	return {
+		Promise: Promise,
+		// capability returns a new pending promise with the functions that settle it
+		capability: function () {
+			return newCapability(Promise);
+		},
+		// inspect returns the state of a promise and marks it as handled, or
+		// undefined for anything that isn't a promise
+		inspect: function (promise) {
+			var state = isObject(promise) ? states.get(promise) : undefined;
+			if (state === undefined) {
+				return undefined;
+			}
+			if (state.kind === REJECTED && !state.handled) {
+				trackRejection(promise, state.value, true);
+			}
+			state.handled = true;
+			return { state: stateNames[state.kind], value: state.value };
+		},
+	};
+})
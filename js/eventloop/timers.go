@@ -0,0 +1,114 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package eventloop
+
+import (
+	"math"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+type timer struct {
+	timer   *time.Timer
+	release func(func() error)
+}
+
+// Timers returns the setTimeout, clearTimeout, setInterval and clearInterval
+// functions of the loop, to be bound to the global object.
+func (e *EventLoop) Timers() map[string]interface{} {
+	return map[string]interface{}{
+		"setTimeout": func(call goja.FunctionCall) goja.Value {
+			return e.setTimer(call, false)
+		},
+		"clearTimeout": func(call goja.FunctionCall) goja.Value {
+			e.clearTimer(call.Argument(0))
+			return goja.Undefined()
+		},
+		"setInterval": func(call goja.FunctionCall) goja.Value {
+			return e.setTimer(call, true)
+		},
+		"clearInterval": func(call goja.FunctionCall) goja.Value {
+			e.clearTimer(call.Argument(0))
+			return goja.Undefined()
+		},
+	}
+}
+
+func (e *EventLoop) setTimer(call goja.FunctionCall, repeat bool) goja.Value {
+	fn, ok := goja.AssertFunction(call.Argument(0))
+	if !ok {
+		panic(e.rt.NewTypeError("The callback provided as parameter 1 is not a function"))
+	}
+	delay := call.Argument(1).ToFloat()
+	if math.IsNaN(delay) || delay < 0 {
+		delay = 0
+	}
+	var args []goja.Value
+	if len(call.Arguments) > 2 {
+		// the arguments are on the stack of the runtime, so they have to be copied
+		args = append(args, call.Arguments[2:]...)
+	}
+
+	e.lastTimer++
+	id := e.lastTimer
+	t := &timer{}
+	e.timers[id] = t
+
+	var schedule func()
+	schedule = func() {
+		t.release = e.RegisterCallback()
+		t.timer = time.AfterFunc(time.Duration(delay*float64(time.Millisecond)), func() {
+			t.release(func() error {
+				if e.timers[id] != t {
+					// it was cleared after it had already fired
+					return nil
+				}
+				if !repeat {
+					delete(e.timers, id)
+				}
+				if _, err := fn(goja.Undefined(), args...); err != nil {
+					return err
+				}
+				if repeat && e.timers[id] == t {
+					schedule()
+				}
+				return nil
+			})
+		})
+	}
+	schedule()
+
+	return e.rt.ToValue(id)
+}
+
+func (e *EventLoop) clearTimer(v goja.Value) {
+	id := v.ToInteger()
+	t, ok := e.timers[id]
+	if !ok {
+		return
+	}
+	delete(e.timers, id)
+	if t.timer.Stop() {
+		// it didn't fire, so the loop has to stop waiting for it
+		t.release(func() error { return nil })
+	}
+}
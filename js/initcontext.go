@@ -28,6 +28,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/dop251/goja"
 	"github.com/sirupsen/logrus"
@@ -46,6 +47,32 @@ type programWithSource struct {
 	module *goja.Object
 }
 
+// programCache keeps the compiled programs of the imported files by their URL,
+// so every file is loaded and compiled only once and then shared by the init
+// contexts of all VUs, even when it's imported only by some of them.
+type programCache struct {
+	programs map[string]programWithSource
+	mu       sync.RWMutex
+}
+
+func newProgramCache() *programCache {
+	return &programCache{programs: make(map[string]programWithSource)}
+}
+
+func (c *programCache) get(key string) (programWithSource, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	pgm, ok := c.programs[key]
+	return pgm, ok
+}
+
+func (c *programCache) set(key string, pgm programWithSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// the modules are specific to the runtime of the init context
+	c.programs[key] = programWithSource{pgm: pgm.pgm, src: pgm.src}
+}
+
 const openCantBeUsedOutsideInitContextMsg = `The "open()" function is only available in the init stage ` +
 	`(i.e. the global scope), see https://k6.io/docs/using-k6/test-life-cycle for more information`
 
@@ -66,6 +93,8 @@ type InitContext struct {
 
 	// Cache of loaded programs and files.
 	programs map[string]programWithSource
+	// Compiled programs shared with all init contexts of the bundle.
+	compiled *programCache
 
 	compatibilityMode lib.CompatibilityMode
 
@@ -86,6 +115,7 @@ func NewInitContext(
 		filesystems:       filesystems,
 		pwd:               pwd,
 		programs:          make(map[string]programWithSource),
+		compiled:          newProgramCache(),
 		compatibilityMode: compatMode,
 		logger:            logger,
 		modules:           modules.GetJSModules(),
@@ -93,16 +123,9 @@ func NewInitContext(
 }
 
 func newBoundInitContext(base *InitContext, ctxPtr *context.Context, rt *goja.Runtime) *InitContext {
-	// we don't copy the exports as otherwise they will be shared and we don't want this.
-	// this means that all the files will be executed again but once again only once per compilation
-	// of the main file.
-	programs := make(map[string]programWithSource, len(base.programs))
-	for key, program := range base.programs {
-		programs[key] = programWithSource{
-			src: program.src,
-			pgm: program.pgm,
-		}
-	}
+	// we don't share the exports as otherwise they will be shared and we don't want this.
+	// this means that all the files will be executed again, but they are compiled only once
+	// for all VUs, since the programs are taken from the shared cache.
 	return &InitContext{
 		runtime: rt,
 		ctxPtr:  ctxPtr,
@@ -111,7 +134,8 @@ func newBoundInitContext(base *InitContext, ctxPtr *context.Context, rt *goja.Ru
 		pwd:         base.pwd,
 		compiler:    base.compiler,
 
-		programs:          programs,
+		programs:          make(map[string]programWithSource),
+		compiled:          base.compiled,
 		compatibilityMode: base.compatibilityMode,
 		logger:            base.logger,
 		modules:           base.modules,
@@ -154,14 +178,24 @@ func (i *InitContext) requireModule(name string) (goja.Value, error) {
 func (i *InitContext) requireFile(name string) (goja.Value, error) {
 	// Resolve the file path, push the target directory as pwd to make relative imports work.
 	pwd := i.pwd
-	fileURL, err := loader.Resolve(pwd, name)
+	// the bare specifiers are resolved to the packages of the node_modules directories first
+	fileURL, err := loader.ResolveNodeModule(i.filesystems["file"], pwd, name)
 	if err != nil {
 		return nil, err
 	}
+	if fileURL == nil {
+		fileURL, err = loader.Resolve(pwd, name)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// First, check if we have a cached program already.
 	pgm, ok := i.programs[fileURL.String()]
-	if !ok || pgm.module == nil {
+	if !ok {
+		pgm, _ = i.compiled.get(fileURL.String())
+	}
+	if pgm.module == nil {
 		if filepath.IsAbs(name) && runtime.GOOS == "windows" {
 			i.logger.Warnf("'%s' was imported with an absolute path - this won't be cross-platform and won't work if"+
 				" you move the script between machines or run it with `k6 cloud`; if absolute paths are required,"+
@@ -188,6 +222,7 @@ func (i *InitContext) requireFile(name string) (goja.Value, error) {
 			if err != nil {
 				return goja.Undefined(), err
 			}
+			i.compiled.set(fileURL.String(), pgm)
 		}
 
 		i.programs[fileURL.String()] = pgm
@@ -136,7 +136,24 @@ func TestInitContextRequire(t *testing.T) {
 			fs := afero.NewMemMapFs()
 			assert.NoError(t, afero.WriteFile(fs, "/file.js", []byte(`throw new Error("aaaa")`), 0o755))
 			_, err := getSimpleBundle(t, "/script.js", `import "/file.js"; export default function() {}`, fs)
-			assert.EqualError(t, err, "Error: aaaa\n\tat file:///file.js:2:7(4)\n\tat reflect.methodValueCall (native)\n\tat file:///script.js:1:117(14)\n")
+			assert.EqualError(t, err, "Error: aaaa\n\tat file:///file.js:2:7(4)\n\tat reflect.methodValueCall (native)\n\tat file:///script.js:1:1(11)\n")
+		})
+		t.Run("NodeModules", func(t *testing.T) {
+			t.Parallel()
+			fs := afero.NewMemMapFs()
+			files := map[string]string{
+				"/node_modules/greet/package.json":   `{"name": "greet", "main": "lib/greet.js"}`,
+				"/node_modules/greet/lib/greet.js":   `var shout = require("@util/shout"); exports.hello = function(n) { return shout("hello " + n); };`,
+				"/node_modules/@util/shout/index.js": `module.exports = function(s) { return s.toUpperCase(); };`,
+			}
+			for name, data := range files {
+				require.NoError(t, afero.WriteFile(fs, name, []byte(data), 0o644))
+			}
+			_, err := getSimpleBundle(t, "/path/to/script.js", `
+				import { hello } from "greet";
+				if (hello("k6") !== "HELLO K6") { throw new Error("wrong greeting: " + hello("k6")); }
+				export default function() {}`, fs)
+			require.NoError(t, err)
 		})
 
 		imports := map[string]struct {
@@ -238,6 +255,35 @@ func TestInitContextRequire(t *testing.T) {
 			_, err = bi.exports[consts.DefaultFn](goja.Undefined())
 			assert.NoError(t, err)
 		})
+
+		t.Run("SharedPrograms", func(t *testing.T) {
+			t.Parallel()
+			logger := testutils.NewLogger(t)
+			fs := afero.NewMemMapFs()
+			assert.NoError(t, afero.WriteFile(fs, "/lib.js", []byte(`exports.vu = __VU;`), 0o644))
+			data := `
+				var vu = __VU > 0 ? require("./lib.js").vu : 0;
+				export default function() { return vu; };`
+			b, err := getSimpleBundle(t, "/script.js", data, fs)
+			require.NoError(t, err)
+			assert.NotContains(t, b.BaseInitContext.programs, "file:///lib.js")
+
+			bi, err := b.Instantiate(logger, 1)
+			require.NoError(t, err)
+			_, ok := b.BaseInitContext.compiled.get("file:///lib.js")
+			assert.True(t, ok)
+
+			// the second VU uses the program compiled by the first one
+			require.NoError(t, fs.Remove("/lib.js"))
+			bi2, err := b.Instantiate(logger, 2)
+			require.NoError(t, err)
+
+			for vuID, instance := range map[int64]*BundleInstance{1: bi, 2: bi2} {
+				v, err := instance.exports[consts.DefaultFn](goja.Undefined())
+				require.NoError(t, err)
+				assert.Equal(t, vuID, v.Export())
+			}
+		})
 	})
 }
 
@@ -41,7 +41,9 @@ import (
 	"go.k6.io/k6/js/common"
 )
 
-type Crypto struct{}
+type Crypto struct {
+	Subtle *SubtleCrypto `js:"subtle,bind"`
+}
 
 type Hasher struct {
 	ctx context.Context
@@ -50,7 +52,7 @@ type Hasher struct {
 }
 
 func New() *Crypto {
-	return &Crypto{}
+	return &Crypto{Subtle: &SubtleCrypto{}}
 }
 
 // RandomBytes returns random data of the given size.
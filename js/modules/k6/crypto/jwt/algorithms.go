@@ -0,0 +1,220 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+type family int
+
+const (
+	familyHMAC family = iota
+	familyRSA
+	familyPSS
+	familyECDSA
+)
+
+type algorithm struct {
+	family family
+	hash   crypto.Hash
+}
+
+//nolint:gochecknoglobals
+var algorithms = map[string]algorithm{
+	"HS256": {familyHMAC, crypto.SHA256},
+	"HS384": {familyHMAC, crypto.SHA384},
+	"HS512": {familyHMAC, crypto.SHA512},
+	"RS256": {familyRSA, crypto.SHA256},
+	"RS384": {familyRSA, crypto.SHA384},
+	"RS512": {familyRSA, crypto.SHA512},
+	"PS256": {familyPSS, crypto.SHA256},
+	"PS384": {familyPSS, crypto.SHA384},
+	"PS512": {familyPSS, crypto.SHA512},
+	"ES256": {familyECDSA, crypto.SHA256},
+	"ES384": {familyECDSA, crypto.SHA384},
+	"ES512": {familyECDSA, crypto.SHA512},
+}
+
+var errInvalidSignature = errors.New("the JWT signature is invalid")
+
+func isPEM(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN"))
+}
+
+func decodePEM(data []byte) (*pem.Block, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("the key isn't PEM encoded")
+	}
+	return block, nil
+}
+
+func (a algorithm) signingKey(data []byte) (interface{}, error) {
+	if a.family == familyHMAC {
+		return hmacSecret(data)
+	}
+	block, err := decodePEM(data)
+	if err != nil {
+		return nil, err
+	}
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return a.checkKey(key, true)
+}
+
+func (a algorithm) verifyingKey(data []byte) (interface{}, error) {
+	if a.family == familyHMAC {
+		return hmacSecret(data)
+	}
+	block, err := decodePEM(data)
+	if err != nil {
+		return nil, err
+	}
+	var key interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	return a.checkKey(key, false)
+}
+
+// hmacSecret returns the secret, unless it's a PEM key, so a token can't be signed with the
+// public key of an asymmetric algorithm and with an HMAC algorithm in its header.
+func hmacSecret(data []byte) (interface{}, error) {
+	if isPEM(data) {
+		return nil, errors.New("a PEM key can't be used with an HMAC algorithm")
+	}
+	if len(data) == 0 {
+		return nil, errors.New("the HMAC secret can't be empty")
+	}
+	return data, nil
+}
+
+func (a algorithm) checkKey(key interface{}, private bool) (interface{}, error) {
+	var ok bool
+	switch a.family {
+	case familyRSA, familyPSS:
+		if private {
+			_, ok = key.(*rsa.PrivateKey)
+		} else {
+			_, ok = key.(*rsa.PublicKey)
+		}
+	case familyECDSA:
+		if private {
+			_, ok = key.(*ecdsa.PrivateKey)
+		} else {
+			_, ok = key.(*ecdsa.PublicKey)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("a %T key can't be used with this JWT algorithm", key)
+	}
+	return key, nil
+}
+
+func (a algorithm) digest(data []byte) []byte {
+	h := a.hash.New()
+	_, _ = h.Write(data)
+	return h.Sum(nil)
+}
+
+func (a algorithm) sign(key interface{}, data []byte) ([]byte, error) {
+	switch a.family {
+	case familyHMAC:
+		mac := hmac.New(a.hash.New, key.([]byte))
+		_, _ = mac.Write(data)
+		return mac.Sum(nil), nil
+	case familyRSA:
+		return rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), a.hash, a.digest(data))
+	case familyPSS:
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+		return rsa.SignPSS(rand.Reader, key.(*rsa.PrivateKey), a.hash, a.digest(data), opts)
+	default:
+		k := key.(*ecdsa.PrivateKey)
+		r, s, err := ecdsa.Sign(rand.Reader, k, a.digest(data))
+		if err != nil {
+			return nil, err
+		}
+		// JWS uses the concatenated r and s values instead of the ASN.1 encoding
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+		return signature, nil
+	}
+}
+
+func (a algorithm) verify(key interface{}, data, signature []byte) error {
+	var valid bool
+	switch a.family {
+	case familyHMAC:
+		mac := hmac.New(a.hash.New, key.([]byte))
+		_, _ = mac.Write(data)
+		valid = hmac.Equal(mac.Sum(nil), signature)
+	case familyRSA:
+		valid = rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), a.hash, a.digest(data), signature) == nil
+	case familyPSS:
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}
+		valid = rsa.VerifyPSS(key.(*rsa.PublicKey), a.hash, a.digest(data), signature, opts) == nil
+	default:
+		k := key.(*ecdsa.PublicKey)
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) == 2*size {
+			r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+			valid = ecdsa.Verify(k, a.digest(data), r, s)
+		}
+	}
+	if !valid {
+		return errInvalidSignature
+	}
+	return nil
+}
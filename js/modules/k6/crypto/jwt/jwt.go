@@ -0,0 +1,325 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package jwt implements the k6/crypto/jwt module, which creates and verifies JSON Web Tokens.
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+)
+
+// JWT is the k6/crypto/jwt module.
+type JWT struct {
+	now func() time.Time
+}
+
+// SignOptions are the options of JWT.Sign. The durations are strings, like "1h", or numbers of
+// milliseconds, like the rest of the durations in k6.
+type SignOptions struct {
+	Algorithm   string                 `js:"algorithm"`
+	Header      map[string]interface{} `js:"header"`
+	KeyID       string                 `js:"keyid"`
+	ExpiresIn   interface{}            `js:"expiresIn"`
+	NotBefore   interface{}            `js:"notBefore"`
+	Issuer      string                 `js:"issuer"`
+	Subject     string                 `js:"subject"`
+	Audience    interface{}            `js:"audience"`
+	JWTID       string                 `js:"jwtid"`
+	NoTimestamp bool                   `js:"noTimestamp"`
+}
+
+// VerifyOptions are the options of JWT.Verify, see SignOptions for the durations.
+type VerifyOptions struct {
+	Algorithms       []string    `js:"algorithms"`
+	Issuer           string      `js:"issuer"`
+	Subject          string      `js:"subject"`
+	Audience         interface{} `js:"audience"`
+	ClockTolerance   interface{} `js:"clockTolerance"`
+	MaxAge           interface{} `js:"maxAge"`
+	IgnoreExpiration bool        `js:"ignoreExpiration"`
+	IgnoreNotBefore  bool        `js:"ignoreNotBefore"`
+}
+
+// Token is a decoded, but not verified, JSON Web Token.
+type Token struct {
+	Header    map[string]interface{} `js:"header"`
+	Payload   map[string]interface{} `js:"payload"`
+	Signature string                 `js:"signature"`
+}
+
+// New returns a new JWT module.
+func New() *JWT {
+	return &JWT{now: time.Now}
+}
+
+// Sign returns a signed token with the claims of the payload, and the ones from the options. The
+// key is the secret for the HMAC algorithms, or a PEM encoded private key for the other ones.
+func (j *JWT) Sign(payload map[string]interface{}, key interface{}, options SignOptions) (string, error) {
+	if options.Algorithm == "" {
+		options.Algorithm = "HS256"
+	}
+	alg, ok := algorithms[options.Algorithm]
+	if !ok {
+		return "", fmt.Errorf("unsupported JWT algorithm %q", options.Algorithm)
+	}
+	keyData, err := common.ToBytes(key)
+	if err != nil {
+		return "", err
+	}
+	signingKey, err := alg.signingKey(keyData)
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]interface{}{"typ": "JWT"}
+	for k, v := range options.Header {
+		header[k] = v
+	}
+	header["alg"] = options.Algorithm
+	if options.KeyID != "" {
+		header["kid"] = options.KeyID
+	}
+
+	claims, err := j.claims(payload, options)
+	if err != nil {
+		return "", err
+	}
+
+	encodedHeader, err := encodeSegment(header)
+	if err != nil {
+		return "", err
+	}
+	encodedClaims, err := encodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encodedHeader + "." + encodedClaims
+	signature, err := alg.sign(signingKey, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (j *JWT) claims(payload map[string]interface{}, options SignOptions) (map[string]interface{}, error) {
+	claims := make(map[string]interface{}, len(payload)+7)
+	for k, v := range payload {
+		claims[k] = v
+	}
+	now := j.now()
+	if !options.NoTimestamp {
+		claims["iat"] = now.Unix()
+	}
+	for name, v := range map[string]interface{}{"exp": options.ExpiresIn, "nbf": options.NotBefore} {
+		if v == nil {
+			continue
+		}
+		d, err := types.GetDurationValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s duration: %w", name, err)
+		}
+		claims[name] = now.Add(d).Unix()
+	}
+	for name, v := range map[string]string{"iss": options.Issuer, "sub": options.Subject, "jti": options.JWTID} {
+		if v != "" {
+			claims[name] = v
+		}
+	}
+	if options.Audience != nil {
+		claims["aud"] = options.Audience
+	}
+	return claims, nil
+}
+
+// Verify returns the claims of the token if it's valid. The key is the secret for the HMAC
+// algorithms, or a PEM encoded public key or certificate for the other ones. Only the algorithms
+// in the options are accepted, or all of the ones for the type of the key if there aren't any,
+// and PEM keys are never used as HMAC secrets.
+func (j *JWT) Verify(token string, key interface{}, options VerifyOptions) (map[string]interface{}, error) {
+	parts, decoded, err := decode(token)
+	if err != nil {
+		return nil, err
+	}
+	keyData, err := common.ToBytes(key)
+	if err != nil {
+		return nil, err
+	}
+
+	name, _ := decoded.Header["alg"].(string)
+	alg, ok := algorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", name)
+	}
+	verifyingKey, err := alg.verifyingKey(keyData)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed(name, options.Algorithms) {
+		return nil, fmt.Errorf("the JWT algorithm %q isn't allowed", name)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("the JWT signature isn't valid base64url")
+	}
+	if err = alg.verify(verifyingKey, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	if err = j.verifyClaims(decoded.Payload, options); err != nil {
+		return nil, err
+	}
+	return decoded.Payload, nil
+}
+
+func allowed(name string, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *JWT) verifyClaims(claims map[string]interface{}, options VerifyOptions) error {
+	now := j.now()
+	var tolerance time.Duration
+	if options.ClockTolerance != nil {
+		var err error
+		if tolerance, err = types.GetDurationValue(options.ClockTolerance); err != nil {
+			return fmt.Errorf("invalid clockTolerance: %w", err)
+		}
+	}
+
+	timeClaim := func(name string) (time.Time, bool, error) {
+		v, ok := claims[name]
+		if !ok {
+			return time.Time{}, false, nil
+		}
+		n, ok := v.(float64)
+		if !ok {
+			return time.Time{}, false, fmt.Errorf("the JWT %q claim isn't a number", name)
+		}
+		return time.Unix(int64(n), 0), true, nil
+	}
+
+	if exp, ok, err := timeClaim("exp"); err != nil {
+		return err
+	} else if ok && !options.IgnoreExpiration && !now.Before(exp.Add(tolerance)) {
+		return fmt.Errorf("the JWT expired at %s", exp.UTC().Format(time.RFC3339))
+	}
+	if nbf, ok, err := timeClaim("nbf"); err != nil {
+		return err
+	} else if ok && !options.IgnoreNotBefore && now.Add(tolerance).Before(nbf) {
+		return fmt.Errorf("the JWT isn't valid before %s", nbf.UTC().Format(time.RFC3339))
+	}
+	if options.MaxAge != nil {
+		maxAge, err := types.GetDurationValue(options.MaxAge)
+		if err != nil {
+			return fmt.Errorf("invalid maxAge: %w", err)
+		}
+		iat, ok, err := timeClaim("iat")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("the JWT doesn't have an \"iat\" claim for the maxAge")
+		}
+		if !now.Before(iat.Add(maxAge + tolerance)) {
+			return fmt.Errorf("the JWT is older than %s", maxAge)
+		}
+	}
+
+	for name, expected := range map[string]string{"iss": options.Issuer, "sub": options.Subject} {
+		if expected != "" && claims[name] != expected {
+			return fmt.Errorf("the JWT %q claim isn't %q", name, expected)
+		}
+	}
+	if options.Audience != nil && !matchAudience(claims["aud"], options.Audience) {
+		return errors.New("the JWT audience doesn't match")
+	}
+	return nil
+}
+
+// matchAudience returns whether any of the audiences of the token is one of the expected ones.
+func matchAudience(aud, expected interface{}) bool {
+	toList := func(v interface{}) []interface{} {
+		if list, ok := v.([]interface{}); ok {
+			return list
+		}
+		return []interface{}{v}
+	}
+	for _, a := range toList(aud) {
+		for _, e := range toList(expected) {
+			if a == e {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Decode returns the header and the payload of the token, without verifying it.
+func (*JWT) Decode(token string) (*Token, error) {
+	_, decoded, err := decode(token)
+	return decoded, err
+}
+
+func decode(token string) ([]string, *Token, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, errors.New("a JWT has to have 3 parts")
+	}
+	decoded := &Token{Signature: parts[2]}
+	if err := decodeSegment(parts[0], &decoded.Header); err != nil {
+		return nil, nil, fmt.Errorf("invalid JWT header: %w", err)
+	}
+	if err := decodeSegment(parts[1], &decoded.Payload); err != nil {
+		return nil, nil, fmt.Errorf("invalid JWT payload: %w", err)
+	}
+	return parts, decoded, nil
+}
+
+func encodeSegment(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
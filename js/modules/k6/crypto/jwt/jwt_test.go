@@ -0,0 +1,186 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/js/common"
+)
+
+const jwtIOToken = "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9." +
+	"eyJzdWIiOiIxMjM0NTY3ODkwIiwibmFtZSI6IkpvaG4gRG9lIiwiaWF0IjoxNTE2MjM5MDIyfQ." +
+	"SflKxwRJSMeKKF2QT4fwpMeJf36POk6yJV_adQssw5c"
+
+func makeRuntime(t *testing.T, now time.Time) *goja.Runtime {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	require.NoError(t, rt.Set("jwt", common.Bind(rt, &JWT{now: func() time.Time { return now }}, &ctx)))
+	return rt
+}
+
+func pemKeys(t *testing.T, key crypto.Signer) (string, string) {
+	private, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	public, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+}
+
+func TestJWTHMAC(t *testing.T) {
+	t.Parallel()
+	rt := makeRuntime(t, time.Unix(1516239022, 0))
+	require.NoError(t, rt.Set("token", jwtIOToken))
+
+	v, err := rt.RunString(`jwt.verify(token, "your-256-bit-secret").name`)
+	require.NoError(t, err)
+	assert.Equal(t, "John Doe", v.Export())
+
+	v, err = rt.RunString(`
+		var decoded = jwt.decode(token);
+		decoded.header.alg + " " + decoded.payload.sub + " " + decoded.signature;
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, "HS256 1234567890 SflKxwRJSMeKKF2QT4fwpMeJf36POk6yJV_adQssw5c", v.Export())
+
+	v, err = rt.RunString(`
+		var signed = jwt.sign({ name: "k6" }, "secret", { algorithm: "HS512", expiresIn: "1h", issuer: "test" });
+		var claims = jwt.verify(signed, "secret", { algorithms: ["HS512"], issuer: "test" });
+		[claims.name, claims.iss, claims.exp - claims.iat, jwt.decode(signed).header.typ].join(" ");
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, "k6 test 3600 JWT", v.Export())
+
+	_, err = rt.RunString(`jwt.verify(token, "wrong secret")`)
+	assert.Contains(t, err.Error(), "the JWT signature is invalid")
+	_, err = rt.RunString(`jwt.verify(token, "your-256-bit-secret", { algorithms: ["RS256"] })`)
+	assert.Contains(t, err.Error(), `the JWT algorithm "HS256" isn't allowed`)
+	_, err = rt.RunString(`jwt.sign({}, "secret", { algorithm: "none" })`)
+	assert.Contains(t, err.Error(), `unsupported JWT algorithm "none"`)
+	_, err = rt.RunString(`jwt.verify("a.b", "secret")`)
+	assert.Contains(t, err.Error(), "a JWT has to have 3 parts")
+}
+
+func TestJWTClaims(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1600000000, 0)
+	signer := makeRuntime(t, now)
+	token, err := signer.RunString(`jwt.sign({ aud: ["a", "b"] }, "secret", { expiresIn: 60000, notBefore: "10s", subject: "me" })`)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		now     time.Time
+		options string
+		err     string
+	}{
+		{name: "valid", now: now.Add(30 * time.Second)},
+		{name: "not yet valid", now: now, err: "the JWT isn't valid before 2020-09-13T12:26:50Z"},
+		{name: "not yet valid with tolerance", now: now, options: `{ clockTolerance: "10s" }`},
+		{name: "ignore not before", now: now, options: `{ ignoreNotBefore: true }`},
+		{name: "expired", now: now.Add(time.Minute), err: "the JWT expired at 2020-09-13T12:27:40Z"},
+		{name: "ignore expiration", now: now.Add(time.Hour), options: `{ ignoreExpiration: true }`},
+		{name: "max age", now: now.Add(30 * time.Second), options: `{ maxAge: "20s" }`, err: "the JWT is older than 20s"},
+		{name: "audience", now: now.Add(30 * time.Second), options: `{ audience: ["c", "b"] }`},
+		{name: "wrong audience", now: now.Add(30 * time.Second), options: `{ audience: "c" }`, err: "the JWT audience doesn't match"},
+		{name: "subject", now: now.Add(30 * time.Second), options: `{ subject: "me" }`},
+		{name: "wrong subject", now: now.Add(30 * time.Second), options: `{ subject: "you" }`, err: `the JWT "sub" claim isn't "you"`},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rt := makeRuntime(t, tc.now)
+			require.NoError(t, rt.Set("token", token))
+			options := tc.options
+			if options == "" {
+				options = "{}"
+			}
+			_, err := rt.RunString(`jwt.verify(token, "secret", ` + options + `)`)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
+
+func TestJWTAsymmetric(t *testing.T) {
+	t.Parallel()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rt := makeRuntime(t, time.Now())
+	rsaPrivate, rsaPublic := pemKeys(t, rsaKey)
+	ecPrivate, ecPublic := pemKeys(t, ecKey)
+	require.NoError(t, rt.Set("rsaPrivate", rsaPrivate))
+	require.NoError(t, rt.Set("rsaPublic", rsaPublic))
+	require.NoError(t, rt.Set("ecPrivate", ecPrivate))
+	require.NoError(t, rt.Set("ecPublic", ecPublic))
+
+	for _, alg := range []string{"RS256", "PS384", "ES256"} {
+		private, public := "rsaPrivate", "rsaPublic"
+		if alg[0] == 'E' {
+			private, public = "ecPrivate", "ecPublic"
+		}
+		require.NoError(t, rt.Set("alg", alg))
+		v, err := rt.RunString(`
+			var token = jwt.sign({ n: 1 }, ` + private + `, { algorithm: alg, keyid: "k1" });
+			jwt.decode(token).header.kid + " " + jwt.verify(token, ` + public + `).n;
+		`)
+		require.NoError(t, err, alg)
+		assert.Equal(t, "k1 1", v.Export(), alg)
+
+		_, err = rt.RunString(`jwt.verify(token.slice(0, -2) + "AA", ` + public + `)`)
+		require.Error(t, err, alg)
+		assert.Contains(t, err.Error(), "the JWT signature is invalid", alg)
+	}
+
+	// a token signed with the public key as an HMAC secret isn't accepted
+	_, err = rt.RunString(`jwt.sign({}, rsaPublic, { algorithm: "HS256" })`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a PEM key can't be used with an HMAC algorithm")
+	_, err = rt.RunString(`jwt.verify(jwt.sign({}, rsaPrivate, { algorithm: "ES256" }), ecPublic)`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "can't be used with this JWT algorithm")
+	_, err = rt.RunString(`jwt.sign({}, rsaPublic, { algorithm: "RS256" })`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid private key")
+}
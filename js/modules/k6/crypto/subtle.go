@@ -0,0 +1,425 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package crypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/dop251/goja"
+
+	"go.k6.io/k6/js/common"
+)
+
+// The names of the errors that the promises of SubtleCrypto are rejected with, the same as the
+// names of the DOMExceptions in the Web Crypto API.
+const (
+	errNameNotSupported  = "NotSupportedError"
+	errNameSyntax        = "SyntaxError"
+	errNameData          = "DataError"
+	errNameInvalidAccess = "InvalidAccessError"
+	errNameOperation     = "OperationError"
+)
+
+// WebCryptoError is the error that the promises of SubtleCrypto are rejected with.
+type WebCryptoError struct {
+	Name    string `js:"name"`
+	Message string `js:"message"`
+}
+
+func newWebCryptoError(name, format string, args ...interface{}) *WebCryptoError {
+	return &WebCryptoError{Name: name, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *WebCryptoError) Error() string {
+	return e.Name + ": " + e.Message
+}
+
+// SubtleCrypto implements a subset of the SubtleCrypto interface of the Web Crypto API, as
+// crypto.subtle. All of its methods return promises, like in browsers.
+type SubtleCrypto struct{}
+
+// algorithm is a normalized AlgorithmIdentifier, a string or an object with a name and parameters.
+type algorithm struct {
+	name   string
+	params *goja.Object
+}
+
+//nolint:gochecknoglobals
+var (
+	algorithmNames = map[string]string{}
+
+	hashes = map[string]crypto.Hash{
+		"SHA-1":   crypto.SHA1,
+		"SHA-256": crypto.SHA256,
+		"SHA-384": crypto.SHA384,
+		"SHA-512": crypto.SHA512,
+	}
+)
+
+//nolint:gochecknoinits
+func init() {
+	for _, name := range []string{
+		"SHA-1", "SHA-256", "SHA-384", "SHA-512",
+		"HMAC", "AES-GCM", "RSASSA-PKCS1-v1_5", "RSA-PSS", "ECDSA",
+	} {
+		algorithmNames[strings.ToUpper(name)] = name
+	}
+}
+
+func normalizeAlgorithm(rt *goja.Runtime, v goja.Value) (algorithm, error) {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return algorithm{}, newWebCryptoError(errNameSyntax, "an algorithm is required")
+	}
+	alg := algorithm{}
+	name := v
+	if obj, ok := v.(*goja.Object); ok {
+		alg.params = obj
+		name = obj.Get("name")
+		if name == nil {
+			return algorithm{}, newWebCryptoError(errNameSyntax, "the algorithm doesn't have a name")
+		}
+	} else {
+		alg.params = rt.NewObject()
+	}
+	var ok bool
+	if alg.name, ok = algorithmNames[strings.ToUpper(name.String())]; !ok {
+		return algorithm{}, newWebCryptoError(errNameNotSupported, "the algorithm %q isn't supported", name.String())
+	}
+	return alg, nil
+}
+
+// param returns a parameter of the algorithm, or nil if it's not there.
+func (a algorithm) param(name string) goja.Value {
+	v := a.params.Get(name)
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil
+	}
+	return v
+}
+
+// hash returns the algorithm of the hash parameter.
+func (a algorithm) hash(rt *goja.Runtime) (string, crypto.Hash, error) {
+	v := a.param("hash")
+	if v == nil {
+		return "", 0, newWebCryptoError(errNameSyntax, "%s needs a hash", a.name)
+	}
+	hashAlg, err := normalizeAlgorithm(rt, v)
+	if err != nil {
+		return "", 0, err
+	}
+	hash, ok := hashes[hashAlg.name]
+	if !ok {
+		return "", 0, newWebCryptoError(errNameNotSupported, "%s isn't a hash", hashAlg.name)
+	}
+	return hashAlg.name, hash, nil
+}
+
+// bytes returns the bytes of a BufferSource parameter of the algorithm.
+func (a algorithm) bytes(rt *goja.Runtime, name string) ([]byte, error) {
+	v := a.param(name)
+	if v == nil {
+		return nil, nil
+	}
+	return bufferSource(v)
+}
+
+// bufferSource returns the bytes of an ArrayBuffer, a typed array or a DataView, or a string, as
+// the rest of k6/crypto accepts strings too.
+func bufferSource(v goja.Value) ([]byte, error) {
+	if obj, ok := v.(*goja.Object); ok {
+		if buffer, ok := obj.Get("buffer").(*goja.Object); ok {
+			if ab, ok := buffer.Export().(goja.ArrayBuffer); ok {
+				offset, length := obj.Get("byteOffset").ToInteger(), obj.Get("byteLength").ToInteger()
+				return ab.Bytes()[offset : offset+length], nil
+			}
+		}
+	}
+	data, err := common.ToBytes(v.Export())
+	if err != nil {
+		return nil, newWebCryptoError(errNameData, "%s", err)
+	}
+	return data, nil
+}
+
+// settle returns a promise that is settled with the result of fn.
+func settle(ctx context.Context, fn func(rt *goja.Runtime) (interface{}, error)) *goja.Object {
+	rt := common.GetRuntime(ctx)
+	promise, resolve, reject := common.GetEventLoop(ctx).NewPromise()
+	if v, err := fn(rt); err != nil {
+		reject(err)
+	} else {
+		resolve(v)
+	}
+	return promise
+}
+
+// Digest returns a promise of the digest of the data, with SHA-1, SHA-256, SHA-384 or SHA-512.
+func (*SubtleCrypto) Digest(ctx context.Context, alg goja.Value, data goja.Value) *goja.Object {
+	return settle(ctx, func(rt *goja.Runtime) (interface{}, error) {
+		algorithm, err := normalizeAlgorithm(rt, alg)
+		if err != nil {
+			return nil, err
+		}
+		hash, ok := hashes[algorithm.name]
+		if !ok {
+			return nil, newWebCryptoError(errNameNotSupported, "%s can't be used for digests", algorithm.name)
+		}
+		input, err := bufferSource(data)
+		if err != nil {
+			return nil, err
+		}
+		h := hash.New()
+		_, _ = h.Write(input)
+		return rt.NewArrayBuffer(h.Sum(nil)), nil
+	})
+}
+
+// checkKey returns the key if it can be used with the algorithm for the usage.
+func checkKey(v goja.Value, alg algorithm, usage string) (*CryptoKey, error) {
+	key, ok := v.Export().(*CryptoKey)
+	if !ok {
+		return nil, newWebCryptoError(errNameInvalidAccess, "the key isn't a CryptoKey")
+	}
+	if key.Algorithm["name"] != alg.name {
+		return nil, newWebCryptoError(errNameInvalidAccess,
+			"the key is for %s, it can't be used with %s", key.Algorithm["name"], alg.name)
+	}
+	for _, u := range key.Usages {
+		if u == usage {
+			return key, nil
+		}
+	}
+	return nil, newWebCryptoError(errNameInvalidAccess, "the key can't be used to %s", usage)
+}
+
+// Sign returns a promise of the signature of the data, with HMAC, RSASSA-PKCS1-v1_5, RSA-PSS or
+// ECDSA. The ECDSA signatures are the concatenated r and s values, like in browsers.
+func (*SubtleCrypto) Sign(ctx context.Context, alg, keyV, data goja.Value) *goja.Object {
+	return settle(ctx, func(rt *goja.Runtime) (interface{}, error) {
+		algorithm, err := normalizeAlgorithm(rt, alg)
+		if err != nil {
+			return nil, err
+		}
+		key, err := checkKey(keyV, algorithm, "sign")
+		if err != nil {
+			return nil, err
+		}
+		input, err := bufferSource(data)
+		if err != nil {
+			return nil, err
+		}
+		signature, err := sign(rt, algorithm, key, input)
+		if err != nil {
+			return nil, err
+		}
+		return rt.NewArrayBuffer(signature), nil
+	})
+}
+
+// Verify returns a promise of whether the signature of the data is valid, see Sign.
+func (*SubtleCrypto) Verify(ctx context.Context, alg, keyV, signatureV, data goja.Value) *goja.Object {
+	return settle(ctx, func(rt *goja.Runtime) (interface{}, error) {
+		algorithm, err := normalizeAlgorithm(rt, alg)
+		if err != nil {
+			return nil, err
+		}
+		key, err := checkKey(keyV, algorithm, "verify")
+		if err != nil {
+			return nil, err
+		}
+		signature, err := bufferSource(signatureV)
+		if err != nil {
+			return nil, err
+		}
+		input, err := bufferSource(data)
+		if err != nil {
+			return nil, err
+		}
+		return verify(rt, algorithm, key, signature, input)
+	})
+}
+
+func digest(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	_, _ = h.Write(data)
+	return h.Sum(nil)
+}
+
+func pssOptions(alg algorithm, hash crypto.Hash) (*rsa.PSSOptions, error) {
+	saltLength := alg.param("saltLength")
+	if saltLength == nil {
+		return nil, newWebCryptoError(errNameSyntax, "RSA-PSS needs a saltLength")
+	}
+	return &rsa.PSSOptions{SaltLength: int(saltLength.ToInteger()), Hash: hash}, nil
+}
+
+func sign(rt *goja.Runtime, alg algorithm, key *CryptoKey, data []byte) ([]byte, error) {
+	switch k := key.key.(type) {
+	case []byte:
+		mac := hmac.New(key.hash.New, k)
+		_, _ = mac.Write(data)
+		return mac.Sum(nil), nil
+
+	case *rsa.PrivateKey:
+		if alg.name == "RSA-PSS" {
+			opts, err := pssOptions(alg, key.hash)
+			if err != nil {
+				return nil, err
+			}
+			return rsa.SignPSS(rand.Reader, k, key.hash, digest(key.hash, data), opts)
+		}
+		return rsa.SignPKCS1v15(rand.Reader, k, key.hash, digest(key.hash, data))
+
+	case *ecdsa.PrivateKey:
+		_, hash, err := alg.hash(rt)
+		if err != nil {
+			return nil, err
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest(hash, data))
+		if err != nil {
+			return nil, newWebCryptoError(errNameOperation, "%s", err)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+		return signature, nil
+
+	default:
+		return nil, newWebCryptoError(errNameInvalidAccess, "the key can't be used to sign")
+	}
+}
+
+func verify(rt *goja.Runtime, alg algorithm, key *CryptoKey, signature, data []byte) (bool, error) {
+	switch k := key.key.(type) {
+	case []byte:
+		mac := hmac.New(key.hash.New, k)
+		_, _ = mac.Write(data)
+		return hmac.Equal(mac.Sum(nil), signature), nil
+
+	case *rsa.PublicKey:
+		if alg.name == "RSA-PSS" {
+			opts, err := pssOptions(alg, key.hash)
+			if err != nil {
+				return false, err
+			}
+			return rsa.VerifyPSS(k, key.hash, digest(key.hash, data), signature, opts) == nil, nil
+		}
+		return rsa.VerifyPKCS1v15(k, key.hash, digest(key.hash, data), signature) == nil, nil
+
+	case *ecdsa.PublicKey:
+		_, hash, err := alg.hash(rt)
+		if err != nil {
+			return false, err
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false, nil
+		}
+		r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(k, digest(hash, data), r, s), nil
+
+	default:
+		return false, newWebCryptoError(errNameInvalidAccess, "the key can't be used to verify")
+	}
+}
+
+// Encrypt returns a promise of the data encrypted with AES-GCM, with the authentication tag at the
+// end, like in browsers.
+func (*SubtleCrypto) Encrypt(ctx context.Context, alg, keyV, data goja.Value) *goja.Object {
+	return settle(ctx, func(rt *goja.Runtime) (interface{}, error) {
+		return aesGCM(rt, alg, keyV, data, "encrypt")
+	})
+}
+
+// Decrypt returns a promise of the data decrypted with AES-GCM, see Encrypt.
+func (*SubtleCrypto) Decrypt(ctx context.Context, alg, keyV, data goja.Value) *goja.Object {
+	return settle(ctx, func(rt *goja.Runtime) (interface{}, error) {
+		return aesGCM(rt, alg, keyV, data, "decrypt")
+	})
+}
+
+func aesGCM(rt *goja.Runtime, alg, keyV, data goja.Value, usage string) (interface{}, error) {
+	algorithm, err := normalizeAlgorithm(rt, alg)
+	if err != nil {
+		return nil, err
+	}
+	key, err := checkKey(keyV, algorithm, usage)
+	if err != nil {
+		return nil, err
+	}
+	input, err := bufferSource(data)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := algorithm.bytes(rt, "iv")
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) == 0 {
+		return nil, newWebCryptoError(errNameOperation, "AES-GCM needs an iv")
+	}
+	additionalData, err := algorithm.bytes(rt, "additionalData")
+	if err != nil {
+		return nil, err
+	}
+	tagLength := int64(128)
+	if v := algorithm.param("tagLength"); v != nil {
+		tagLength = v.ToInteger()
+	}
+
+	block, err := aes.NewCipher(key.key.([]byte))
+	if err != nil {
+		return nil, newWebCryptoError(errNameOperation, "%s", err)
+	}
+	var gcm cipher.AEAD
+	switch {
+	case tagLength == 128:
+		gcm, err = cipher.NewGCMWithNonceSize(block, len(iv))
+	case len(iv) == 12 && tagLength >= 96 && tagLength <= 128 && tagLength%8 == 0:
+		gcm, err = cipher.NewGCMWithTagSize(block, int(tagLength/8))
+	default:
+		return nil, newWebCryptoError(errNameNotSupported,
+			"AES-GCM with a %d bytes iv and a tagLength of %d isn't supported", len(iv), tagLength)
+	}
+	if err != nil {
+		return nil, newWebCryptoError(errNameOperation, "%s", err)
+	}
+
+	if usage == "encrypt" {
+		return rt.NewArrayBuffer(gcm.Seal(nil, iv, input, additionalData)), nil
+	}
+	plaintext, err := gcm.Open(nil, iv, input, additionalData)
+	if err != nil {
+		return nil, newWebCryptoError(errNameOperation, "the data couldn't be decrypted")
+	}
+	return rt.NewArrayBuffer(plaintext), nil
+}
@@ -0,0 +1,364 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package crypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// CryptoKey is a key that was imported with SubtleCrypto.importKey.
+type CryptoKey struct {
+	Type        string                 `js:"type"`
+	Extractable bool                   `js:"extractable"`
+	Algorithm   map[string]interface{} `js:"algorithm"`
+	Usages      []string               `js:"usages"`
+
+	key  interface{}
+	hash crypto.Hash
+}
+
+//nolint:gochecknoglobals
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// ImportKey returns a promise of a CryptoKey for the key data, in the raw, pkcs8, spki or jwk
+// format. HMAC and AES-GCM keys can be imported from raw and jwk key data, and RSASSA-PKCS1-v1_5,
+// RSA-PSS and ECDSA keys from pkcs8, spki and jwk key data, as well as raw public ECDSA keys.
+func (*SubtleCrypto) ImportKey(
+	ctx context.Context, format string, keyData, alg goja.Value, extractable bool, usages []string,
+) *goja.Object {
+	return settle(ctx, func(rt *goja.Runtime) (interface{}, error) {
+		algorithm, err := normalizeAlgorithm(rt, alg)
+		if err != nil {
+			return nil, err
+		}
+		key := &CryptoKey{
+			Extractable: extractable,
+			Algorithm:   map[string]interface{}{"name": algorithm.name},
+			Usages:      usages,
+		}
+
+		switch algorithm.name {
+		case "HMAC":
+			err = importHMACKey(rt, key, algorithm, format, keyData)
+		case "AES-GCM":
+			err = importAESKey(rt, key, format, keyData)
+		case "RSASSA-PKCS1-v1_5", "RSA-PSS", "ECDSA":
+			err = importAsymmetricKey(rt, key, algorithm, format, keyData)
+		default:
+			err = newWebCryptoError(errNameNotSupported, "%s keys can't be imported", algorithm.name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = checkUsages(key); err != nil {
+			return nil, err
+		}
+		return key, nil
+	})
+}
+
+func checkUsages(key *CryptoKey) error {
+	var allowed []string
+	switch key.Type {
+	case "secret":
+		if key.Algorithm["name"] == "HMAC" {
+			allowed = []string{"sign", "verify"}
+		} else {
+			allowed = []string{"encrypt", "decrypt"}
+		}
+	case "private":
+		allowed = []string{"sign"}
+	case "public":
+		allowed = []string{"verify"}
+	}
+	for _, usage := range key.Usages {
+		ok := false
+		for _, a := range allowed {
+			ok = ok || usage == a
+		}
+		if !ok {
+			return newWebCryptoError(errNameSyntax,
+				"a %s %s key can't be used to %s", key.Type, key.Algorithm["name"], usage)
+		}
+	}
+	if key.Type != "public" && len(key.Usages) == 0 {
+		return newWebCryptoError(errNameSyntax, "the usages of a %s key can't be empty", key.Type)
+	}
+	return nil
+}
+
+// jwk returns the key data as a JSON Web Key object, checking that it has the key type.
+func jwk(rt *goja.Runtime, keyData goja.Value, kty string) (*goja.Object, error) {
+	obj, ok := keyData.(*goja.Object)
+	if !ok || obj.Get("kty") == nil || obj.Get("kty").String() != kty {
+		return nil, newWebCryptoError(errNameData, "the key data isn't a JWK with the %q key type", kty)
+	}
+	return obj, nil
+}
+
+// jwkBytes returns the decoded base64url value of a member of a JSON Web Key.
+func jwkBytes(obj *goja.Object, name string) ([]byte, error) {
+	v := obj.Get(name)
+	if v == nil || goja.IsUndefined(v) {
+		return nil, newWebCryptoError(errNameData, "the JWK doesn't have %q", name)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(v.String(), "="))
+	if err != nil {
+		return nil, newWebCryptoError(errNameData, "the %q of the JWK isn't valid base64url", name)
+	}
+	return b, nil
+}
+
+func jwkInt(obj *goja.Object, name string) (*big.Int, error) {
+	b, err := jwkBytes(obj, name)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func secretKeyData(rt *goja.Runtime, format string, keyData goja.Value) ([]byte, error) {
+	switch format {
+	case "raw":
+		return bufferSource(keyData)
+	case "jwk":
+		obj, err := jwk(rt, keyData, "oct")
+		if err != nil {
+			return nil, err
+		}
+		return jwkBytes(obj, "k")
+	default:
+		return nil, newWebCryptoError(errNameNotSupported, "secret keys can't be imported from %s", format)
+	}
+}
+
+func importHMACKey(rt *goja.Runtime, key *CryptoKey, alg algorithm, format string, keyData goja.Value) error {
+	hashName, hash, err := alg.hash(rt)
+	if err != nil {
+		return err
+	}
+	data, err := secretKeyData(rt, format, keyData)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return newWebCryptoError(errNameData, "HMAC keys can't be empty")
+	}
+	key.Type, key.key, key.hash = "secret", data, hash
+	key.Algorithm["hash"] = map[string]interface{}{"name": hashName}
+	key.Algorithm["length"] = len(data) * 8
+	return nil
+}
+
+func importAESKey(rt *goja.Runtime, key *CryptoKey, format string, keyData goja.Value) error {
+	data, err := secretKeyData(rt, format, keyData)
+	if err != nil {
+		return err
+	}
+	if l := len(data); l != 16 && l != 24 && l != 32 {
+		return newWebCryptoError(errNameData, "AES keys have to be 128, 192 or 256 bits long, not %d", l*8)
+	}
+	key.Type, key.key = "secret", data
+	key.Algorithm["length"] = len(data) * 8
+	return nil
+}
+
+func importAsymmetricKey(rt *goja.Runtime, key *CryptoKey, alg algorithm, format string, keyData goja.Value) error {
+	var (
+		parsed interface{}
+		err    error
+	)
+	if alg.name != "ECDSA" {
+		var hashName string
+		if hashName, key.hash, err = alg.hash(rt); err != nil {
+			return err
+		}
+		key.Algorithm["hash"] = map[string]interface{}{"name": hashName}
+	}
+
+	switch format {
+	case "pkcs8", "spki":
+		var data []byte
+		if data, err = bufferSource(keyData); err != nil {
+			return err
+		}
+		if format == "pkcs8" {
+			parsed, err = x509.ParsePKCS8PrivateKey(data)
+		} else {
+			parsed, err = x509.ParsePKIXPublicKey(data)
+		}
+		if err != nil {
+			return newWebCryptoError(errNameData, "the key data isn't a valid %s key: %s", format, err)
+		}
+	case "jwk":
+		if alg.name == "ECDSA" {
+			parsed, err = ecdsaJWK(rt, keyData)
+		} else {
+			parsed, err = rsaJWK(rt, keyData)
+		}
+	case "raw":
+		if alg.name != "ECDSA" {
+			return newWebCryptoError(errNameNotSupported, "%s keys can't be imported from raw", alg.name)
+		}
+		parsed, err = ecdsaRaw(rt, alg, keyData)
+	default:
+		return newWebCryptoError(errNameNotSupported, "keys can't be imported from %s", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	isECDSA := false
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Type = "private"
+		setRSAAlgorithm(key, &k.PublicKey)
+	case *rsa.PublicKey:
+		key.Type = "public"
+		setRSAAlgorithm(key, k)
+	case *ecdsa.PrivateKey:
+		key.Type, isECDSA = "private", true
+		key.Algorithm["namedCurve"] = k.Curve.Params().Name
+	case *ecdsa.PublicKey:
+		key.Type, isECDSA = "public", true
+		key.Algorithm["namedCurve"] = k.Curve.Params().Name
+	}
+	if key.Type == "" || isECDSA != (alg.name == "ECDSA") {
+		return newWebCryptoError(errNameData, "the key data isn't for a %s key", alg.name)
+	}
+
+	if isECDSA {
+		if curve := alg.param("namedCurve"); curve != nil && curve.String() != key.Algorithm["namedCurve"] {
+			return newWebCryptoError(errNameData,
+				"the key is on the %s curve, not %s", key.Algorithm["namedCurve"], curve.String())
+		}
+	}
+	key.key = parsed
+	return nil
+}
+
+func setRSAAlgorithm(key *CryptoKey, k *rsa.PublicKey) {
+	key.Algorithm["modulusLength"] = k.N.BitLen()
+	key.Algorithm["publicExponent"] = big.NewInt(int64(k.E)).Bytes()
+}
+
+func rsaJWK(rt *goja.Runtime, keyData goja.Value) (interface{}, error) {
+	obj, err := jwk(rt, keyData, "RSA")
+	if err != nil {
+		return nil, err
+	}
+	n, err := jwkInt(obj, "n")
+	if err != nil {
+		return nil, err
+	}
+	e, err := jwkInt(obj, "e")
+	if err != nil {
+		return nil, err
+	}
+	public := rsa.PublicKey{N: n, E: int(e.Int64())}
+	if d := obj.Get("d"); d == nil || goja.IsUndefined(d) {
+		return &public, nil
+	}
+
+	private := &rsa.PrivateKey{PublicKey: public}
+	if private.D, err = jwkInt(obj, "d"); err != nil {
+		return nil, err
+	}
+	p, err := jwkInt(obj, "p")
+	if err != nil {
+		return nil, err
+	}
+	q, err := jwkInt(obj, "q")
+	if err != nil {
+		return nil, err
+	}
+	private.Primes = []*big.Int{p, q}
+	if err = private.Validate(); err != nil {
+		return nil, newWebCryptoError(errNameData, "the JWK isn't a valid RSA key: %s", err)
+	}
+	private.Precompute()
+	return private, nil
+}
+
+func ecdsaJWK(rt *goja.Runtime, keyData goja.Value) (interface{}, error) {
+	obj, err := jwk(rt, keyData, "EC")
+	if err != nil {
+		return nil, err
+	}
+	curve, ok := curves[obj.Get("crv").String()]
+	if !ok {
+		return nil, newWebCryptoError(errNameNotSupported, "the %q curve isn't supported", obj.Get("crv").String())
+	}
+	x, err := jwkInt(obj, "x")
+	if err != nil {
+		return nil, err
+	}
+	y, err := jwkInt(obj, "y")
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, newWebCryptoError(errNameData, "the point of the JWK isn't on the %s curve", curve.Params().Name)
+	}
+	public := ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	if d := obj.Get("d"); d == nil || goja.IsUndefined(d) {
+		return &public, nil
+	}
+	private := &ecdsa.PrivateKey{PublicKey: public}
+	if private.D, err = jwkInt(obj, "d"); err != nil {
+		return nil, err
+	}
+	return private, nil
+}
+
+func ecdsaRaw(rt *goja.Runtime, alg algorithm, keyData goja.Value) (interface{}, error) {
+	name := alg.param("namedCurve")
+	if name == nil {
+		return nil, newWebCryptoError(errNameSyntax, "ECDSA needs a namedCurve")
+	}
+	curve, ok := curves[name.String()]
+	if !ok {
+		return nil, newWebCryptoError(errNameNotSupported, "the %q curve isn't supported", name.String())
+	}
+	data, err := bufferSource(keyData)
+	if err != nil {
+		return nil, err
+	}
+	x, y := elliptic.Unmarshal(curve, data) //nolint:staticcheck
+	if x == nil {
+		return nil, newWebCryptoError(errNameData, "the key data isn't a point on the %s curve", name.String())
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
@@ -0,0 +1,235 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package crypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/eventloop"
+)
+
+func newSubtleRuntime(t *testing.T) (*goja.Runtime, func(string) error) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loop, err := eventloop.New(rt)
+	require.NoError(t, err)
+	ctx := common.WithEventLoop(common.WithRuntime(context.Background(), rt), loop)
+	require.NoError(t, rt.Set("crypto", common.Bind(rt, New(), &ctx)))
+
+	return rt, func(src string) error {
+		return loop.Start(context.Background(), func() error {
+			_, err := rt.RunString(src)
+			return err
+		})
+	}
+}
+
+func TestSubtleDigest(t *testing.T) {
+	t.Parallel()
+	rt, run := newSubtleRuntime(t)
+	require.NoError(t, run(`
+		var results = {};
+		crypto.subtle.digest("SHA-256", "hello").then(function(d) {
+			results.string = crypto.hexEncode(d);
+		});
+		crypto.subtle.digest({ name: "sha-1" }, new Uint8Array([104, 101, 108, 108, 111]).subarray(1, 4)).then(function(d) {
+			results.view = crypto.hexEncode(d);
+		});
+		crypto.subtle.digest("MD5", "hello").catch(function(e) {
+			results.unsupported = e.name;
+		});
+	`))
+	assert.Equal(t, map[string]interface{}{
+		"string":      "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		"view":        "d027b4c247e6911ac060b71f7a4979b6e52e773b", // sha1("ell")
+		"unsupported": "NotSupportedError",
+	}, rt.Get("results").Export())
+}
+
+func TestSubtleHMAC(t *testing.T) {
+	t.Parallel()
+	rt, run := newSubtleRuntime(t)
+	require.NoError(t, run(`
+		var results = {};
+		var data = "The quick brown fox jumps over the lazy dog";
+		crypto.subtle.importKey("raw", "key", { name: "HMAC", hash: "SHA-256" }, false, ["sign", "verify"])
+			.then(function(key) {
+				results.algorithm = key.algorithm.name + "/" + key.algorithm.hash.name + "/" + key.algorithm.length;
+				results.type = key.type;
+				return crypto.subtle.sign("HMAC", key, data).then(function(signature) {
+					results.signature = crypto.hexEncode(signature);
+					return Promise.all([
+						crypto.subtle.verify("HMAC", key, signature, data),
+						crypto.subtle.verify("HMAC", key, signature, data + "."),
+					]);
+				});
+			})
+			.then(function(valid) { results.valid = valid; });
+		crypto.subtle.importKey("jwk", { kty: "oct", k: "a2V5" }, { name: "HMAC", hash: { name: "SHA-256" } }, false, ["verify"])
+			.then(function(key) { return crypto.subtle.sign("HMAC", key, data); })
+			.catch(function(e) { results.usage = e.name; });
+		crypto.subtle.importKey("raw", "key", { name: "HMAC", hash: "SHA-256" }, false, ["encrypt"])
+			.catch(function(e) { results.importUsage = e.name; });
+	`))
+	assert.Equal(t, map[string]interface{}{
+		"algorithm":   "HMAC/SHA-256/24",
+		"type":        "secret",
+		"signature":   "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		"valid":       []interface{}{true, false},
+		"usage":       "InvalidAccessError",
+		"importUsage": "SyntaxError",
+	}, rt.Get("results").Export())
+}
+
+func TestSubtleAESGCM(t *testing.T) {
+	t.Parallel()
+	rt, run := newSubtleRuntime(t)
+	require.NoError(t, run(`
+		var results = {};
+		var rawKey = new Uint8Array(16);
+		var iv = new Uint8Array(12);
+		crypto.subtle.importKey("raw", rawKey.buffer, "AES-GCM", false, ["encrypt", "decrypt"]).then(function(key) {
+			var alg = { name: "AES-GCM", iv: iv, additionalData: "aad" };
+			return crypto.subtle.encrypt(alg, key, "secret").then(function(ciphertext) {
+				results.length = ciphertext.byteLength;
+				return crypto.subtle.decrypt(alg, key, ciphertext).then(function(plaintext) {
+					results.plaintext = String.fromCharCode.apply(null, new Uint8Array(plaintext));
+					var tampered = new Uint8Array(ciphertext);
+					tampered[0] ^= 1;
+					return crypto.subtle.decrypt(alg, key, tampered);
+				});
+			}).catch(function(e) { results.tampered = e.name; }).then(function() {
+				return crypto.subtle.encrypt({ name: "AES-GCM", iv: iv, tagLength: 96 }, key, "");
+			}).then(function(ciphertext) {
+				results.shortTag = ciphertext.byteLength;
+			});
+		});
+		crypto.subtle.importKey("raw", new Uint8Array(10), "AES-GCM", false, ["encrypt"])
+			.catch(function(e) { results.badKey = e.name; });
+	`))
+	assert.Equal(t, map[string]interface{}{
+		"length":    int64(6 + 16),
+		"plaintext": "secret",
+		"tampered":  "OperationError",
+		"shortTag":  int64(12),
+		"badKey":    "DataError",
+	}, rt.Get("results").Export())
+}
+
+func TestSubtleAsymmetric(t *testing.T) {
+	t.Parallel()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rt, run := newSubtleRuntime(t)
+	for name, key := range map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey} {
+		private, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		public, err := x509.MarshalPKIXPublicKey(key.Public())
+		require.NoError(t, err)
+		require.NoError(t, rt.Set(name+"Private", rt.NewArrayBuffer(private)))
+		require.NoError(t, rt.Set(name+"Public", rt.NewArrayBuffer(public)))
+	}
+	require.NoError(t, rt.Set("ecRaw", rt.NewArrayBuffer(elliptic.Marshal(elliptic.P256(), ecKey.X, ecKey.Y))))
+
+	require.NoError(t, run(`
+		var results = {};
+		function roundTrip(name, alg, privateData, publicFormat, publicData) {
+			return Promise.all([
+				crypto.subtle.importKey("pkcs8", privateData, alg, false, ["sign"]),
+				crypto.subtle.importKey(publicFormat, publicData, alg, true, ["verify"]),
+			]).then(function(keys) {
+				return crypto.subtle.sign(alg, keys[0], "data").then(function(signature) {
+					return Promise.all([
+						crypto.subtle.verify(alg, keys[1], signature, "data"),
+						crypto.subtle.verify(alg, keys[1], signature, "other data"),
+						keys[1].type,
+					]);
+				});
+			}).then(function(r) { results[name] = r; }, function(e) { results[name] = e.name + ": " + e.message; });
+		}
+		roundTrip("pkcs1", { name: "RSASSA-PKCS1-v1_5", hash: "SHA-256" }, rsaPrivate, "spki", rsaPublic);
+		roundTrip("pss", { name: "RSA-PSS", hash: "SHA-384", saltLength: 32 }, rsaPrivate, "spki", rsaPublic);
+		roundTrip("ecdsa", { name: "ECDSA", hash: "SHA-256", namedCurve: "P-256" }, ecPrivate, "spki", ecPublic);
+		roundTrip("ecdsaRaw", { name: "ECDSA", hash: "SHA-512", namedCurve: "P-256" }, ecPrivate, "raw", ecRaw);
+		crypto.subtle.importKey("spki", rsaPublic, { name: "ECDSA", namedCurve: "P-256" }, false, ["verify"])
+			.catch(function(e) { results.mismatch = e.name; });
+		crypto.subtle.importKey("spki", ecPublic, { name: "ECDSA", namedCurve: "P-384" }, false, ["verify"])
+			.catch(function(e) { results.curve = e.name; });
+	`))
+	assert.Equal(t, map[string]interface{}{
+		"pkcs1":    []interface{}{true, false, "public"},
+		"pss":      []interface{}{true, false, "public"},
+		"ecdsa":    []interface{}{true, false, "public"},
+		"ecdsaRaw": []interface{}{true, false, "public"},
+		"mismatch": "DataError",
+		"curve":    "DataError",
+	}, rt.Get("results").Export())
+}
+
+func TestSubtleJWK(t *testing.T) {
+	t.Parallel()
+	rt, run := newSubtleRuntime(t)
+	// the example keys from RFC 7515, appendices A.2 and A.3
+	require.NoError(t, run(`
+		var results = {};
+		var rsa = {
+			kty: "RSA",
+			n: "ofgWCuLjybRlzo0tZWJjNiuSfb4p4fAkd_wWJcyQoTbji9k0l8W26mPddxHmfHQp-Vaw-4qPCJrcS2mJPMEzP1Pt0Bm4d4QlL-yRT-SFd2lZS-pCgNMsD1W_YpRPEwOWvG6b32690r2jZ47soMZo9wGzjb_7OMg0LOL-bSf63kpaSHSXndS5z5rexMdbBYUsLA9e-KXBdQOS-UTo7WTBEMa2R2CapHg665xsmtdVMTBQY4uDZlxvb3qCo5ZwKh9kG4LT6_I5IhlJH7aGhyxXFvUK-DWNmoudF8NAco9_h9iaGNj8q2ethFkMLs91kzk2PAcDTW9gb54h4FRWyuXpoQ",
+			e: "AQAB",
+		};
+		var ec = {
+			kty: "EC", crv: "P-256",
+			x: "f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU",
+			y: "x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0",
+		};
+		crypto.subtle.importKey("jwk", rsa, { name: "RSASSA-PKCS1-v1_5", hash: "SHA-256" }, false, ["verify"])
+			.then(function(key) {
+				results.rsa = key.type + "/" + key.algorithm.modulusLength;
+				return crypto.subtle.importKey("jwk", rsa, { name: "RSASSA-PKCS1-v1_5", hash: "SHA-256" }, false, ["sign"]);
+			})
+			.catch(function(e) { results.rsaSign = e.name; });
+		crypto.subtle.importKey("jwk", ec, { name: "ECDSA", namedCurve: "P-256" }, false, ["verify"])
+			.then(function(key) { results.ec = key.type + "/" + key.algorithm.namedCurve; });
+		crypto.subtle.importKey("jwk", rsa, { name: "ECDSA", namedCurve: "P-256" }, false, ["verify"])
+			.catch(function(e) { results.kty = e.name; });
+	`))
+	assert.Equal(t, map[string]interface{}{
+		"rsa":     "public/2048",
+		"rsaSign": "SyntaxError",
+		"ec":      "public/P-256",
+		"kty":     "DataError",
+	}, rt.Get("results").Export())
+}
@@ -33,6 +33,8 @@ import (
 
 type data struct {
 	shared sharedArrays
+
+	counters, queues, maps, feeds, readers, limiters sharedObjects
 }
 
 type sharedArrays struct {
@@ -83,6 +85,55 @@ func (d *data) XSharedArray(ctx context.Context, name string, call goja.Callable
 	return array.wrap(rt), nil
 }
 
+// XSharedCounter is a constructor returning an integer counter, identified by
+// the name, that is shared and can be atomically modified by all VUs
+func (d *data) XSharedCounter(ctx context.Context, name string) (goja.Value, error) {
+	if lib.GetState(ctx) != nil {
+		return nil, errors.New("new SharedCounter must be called in the init context")
+	}
+	if err := checkSharedName("SharedCounter", name); err != nil {
+		return nil, err
+	}
+
+	counter := d.counters.get(name, func() interface{} { return &sharedCounter{} }).(*sharedCounter)
+	return common.GetRuntime(ctx).ToValue(&SharedCounter{counter: counter}), nil
+}
+
+// XSharedQueue is a constructor returning a FIFO queue, identified by the
+// name, that is shared by all VUs. If the capacity is positive, pushing values
+// to the queue fails once it has that many values. The capacity of the first
+// constructor call with the name is used.
+func (d *data) XSharedQueue(ctx context.Context, name string, capacity int) (goja.Value, error) {
+	if lib.GetState(ctx) != nil {
+		return nil, errors.New("new SharedQueue must be called in the init context")
+	}
+	if err := checkSharedName("SharedQueue", name); err != nil {
+		return nil, err
+	}
+	if capacity < 0 {
+		return nil, errNegativeCapacity
+	}
+
+	queue := d.queues.get(name, func() interface{} { return &sharedQueue{capacity: capacity} }).(*sharedQueue)
+	rt := common.GetRuntime(ctx)
+	return rt.ToValue(&SharedQueue{rt: rt, queue: queue}), nil
+}
+
+// XSharedMap is a constructor returning a key-value store, identified by the
+// name, that is shared by all VUs
+func (d *data) XSharedMap(ctx context.Context, name string) (goja.Value, error) {
+	if lib.GetState(ctx) != nil {
+		return nil, errors.New("new SharedMap must be called in the init context")
+	}
+	if err := checkSharedName("SharedMap", name); err != nil {
+		return nil, err
+	}
+
+	m := d.maps.get(name, func() interface{} { return &sharedMap{items: make(map[string]string)} }).(*sharedMap)
+	rt := common.GetRuntime(ctx)
+	return rt.ToValue(&SharedMap{rt: rt, m: m}), nil
+}
+
 func getShareArrayFromCall(rt *goja.Runtime, call goja.Callable) sharedArray {
 	gojaValue, err := call(goja.Undefined())
 	if err != nil {
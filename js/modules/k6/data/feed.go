@@ -0,0 +1,152 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+// The ways the rows of a DataFeed can be handed out.
+const (
+	feedModeOnce       = "once"
+	feedModeRoundRobin = "round-robin"
+)
+
+var errFeedInInitContext = common.NewInitContextError("Using DataFeed's next() in the init context is not supported")
+
+// dataFeed hands out the rows of a SharedArray-like list to all VUs in the
+// instance. When the test is executed with an execution segment, every
+// instance only uses its own part of the rows, so across all instances every
+// row is used exactly once in the "once" mode.
+type dataFeed struct {
+	rows []string
+	mode string
+
+	rangeOnce  sync.Once
+	start, end int64
+
+	next uint64 // only accessed atomically
+}
+
+// setRange calculates which rows belong to the execution segment of the
+// instance, it has to be called before the rows are used.
+func (f *dataFeed) setRange(segment *lib.ExecutionSegment) {
+	f.rangeOnce.Do(func() {
+		f.start, f.end = segment.ScaleRange(int64(len(f.rows)))
+	})
+}
+
+// DataFeed is the JS wrapper of a dataFeed.
+type DataFeed struct {
+	feed  *dataFeed
+	parse goja.Callable
+}
+
+// Next returns the next row of the feed. In the "once" mode, every row is
+// returned only once across all VUs, and undefined is returned after all rows
+// have been used. In the "round-robin" mode, it starts again from the first
+// row.
+func (f DataFeed) Next(ctx context.Context) (goja.Value, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, errFeedInInitContext
+	}
+	f.feed.setRange(state.Options.ExecutionSegment)
+	size := uint64(f.feed.end - f.feed.start)
+	if size == 0 {
+		return goja.Undefined(), nil
+	}
+
+	i := atomic.AddUint64(&f.feed.next, 1) - 1
+	if f.feed.mode == feedModeRoundRobin {
+		i %= size
+	} else if i >= size {
+		return goja.Undefined(), nil
+	}
+
+	rt := common.GetRuntime(ctx)
+	return f.parse(goja.Undefined(), rt.ToValue(f.feed.rows[f.feed.start+int64(i)]))
+}
+
+// Size returns the number of rows that belong to this instance.
+func (f DataFeed) Size(ctx context.Context) (int64, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return int64(len(f.feed.rows)), nil
+	}
+	f.feed.setRange(state.Options.ExecutionSegment)
+	return f.feed.end - f.feed.start, nil
+}
+
+// Remaining returns how many rows haven't been used yet in the "once" mode,
+// and the number of rows in the "round-robin" mode.
+func (f DataFeed) Remaining(ctx context.Context) (int64, error) {
+	size, err := f.Size(ctx)
+	if err != nil || f.feed.mode == feedModeRoundRobin {
+		return size, err
+	}
+	used := int64(atomic.LoadUint64(&f.feed.next))
+	if used >= size {
+		return 0, nil
+	}
+	return size - used, nil
+}
+
+// XDataFeed is a constructor returning a feed of the rows returned by the
+// call, identified by the name, that hands them out to all VUs either exactly
+// once or in a round-robin fashion. Like with SharedArray, the call is only
+// executed once per instance.
+func (d *data) XDataFeed(
+	ctxPtr *context.Context, name string, call goja.Callable, options goja.Value,
+) (goja.Value, error) {
+	if lib.GetState(*ctxPtr) != nil {
+		return nil, errors.New("new DataFeed must be called in the init context")
+	}
+	if len(name) == 0 {
+		return nil, errors.New("empty name provided to DataFeed's constructor")
+	}
+
+	mode := feedModeOnce
+	rt := common.GetRuntime(*ctxPtr)
+	if options != nil && !goja.IsUndefined(options) && !goja.IsNull(options) {
+		if m := options.ToObject(rt).Get("mode"); m != nil && !goja.IsUndefined(m) {
+			mode = m.String()
+		}
+	}
+	if mode != feedModeOnce && mode != feedModeRoundRobin {
+		return nil, fmt.Errorf("invalid DataFeed mode '%s', use '%s' or '%s'", mode, feedModeOnce, feedModeRoundRobin)
+	}
+
+	feed := d.feeds.get(name, func() interface{} {
+		return &dataFeed{rows: getShareArrayFromCall(rt, call).arr, mode: mode}
+	}).(*dataFeed)
+	parse, _ := goja.AssertFunction(rt.GlobalObject().Get("JSON").ToObject(rt).Get("parse"))
+
+	return rt.ToValue(common.Bind(rt, DataFeed{feed: feed, parse: parse}, ctxPtr)), nil
+}
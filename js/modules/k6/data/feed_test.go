@@ -0,0 +1,149 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+const makeFeedScript = `
+var feed = new data.DataFeed("users", function() {
+	var rows = [];
+	for (var i = 0; i < 10; i++) {
+		rows.push({id: i});
+	}
+	return rows;
+}, %s);
+`
+
+// newFeedVU returns a runtime that has executed the init code of the script
+// and a function that switches it to the VU context with the given segment.
+func newFeedVU(
+	t *testing.T, moduleInstance interface{}, options string,
+) (*goja.Runtime, func(segment *lib.ExecutionSegment)) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	require.NoError(t, rt.Set("data", common.Bind(rt, moduleInstance, &ctx)))
+	_, err := rt.RunString(fmt.Sprintf(makeFeedScript, options))
+	require.NoError(t, err)
+
+	return rt, func(segment *lib.ExecutionSegment) {
+		ctx = lib.WithState(ctx, &lib.State{Options: lib.Options{ExecutionSegment: segment}})
+	}
+}
+
+func getFeedIDs(t *testing.T, rt *goja.Runtime, count int) []int64 {
+	var ids []int64
+	for i := 0; i < count; i++ {
+		v, err := rt.RunString(`var row = feed.next(); row === undefined ? -1 : row.id`)
+		require.NoError(t, err)
+		ids = append(ids, v.ToInteger())
+	}
+	return ids
+}
+
+func TestDataFeedOnce(t *testing.T) {
+	t.Parallel()
+
+	var ids []int64
+	for _, segmentStr := range []string{"0:1/3", "1/3:1"} {
+		segment, err := lib.NewExecutionSegmentFromString(segmentStr)
+		require.NoError(t, err)
+
+		// every instance has its own module instance
+		moduleInstance := New()
+		vu1, setVUContext1 := newFeedVU(t, moduleInstance, "undefined")
+		vu2, setVUContext2 := newFeedVU(t, moduleInstance, `{mode: "once"}`)
+		setVUContext1(segment)
+		setVUContext2(segment)
+
+		size, err := vu1.RunString(`feed.size()`)
+		require.NoError(t, err)
+		instanceIDs := append(getFeedIDs(t, vu1, 2), getFeedIDs(t, vu2, int(size.ToInteger()))...)
+		assert.Equal(t, []int64{-1, -1}, instanceIDs[len(instanceIDs)-2:])
+		remaining, err := vu2.RunString(`feed.remaining()`)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), remaining.ToInteger())
+
+		for _, id := range instanceIDs {
+			if id >= 0 {
+				ids = append(ids, id)
+			}
+		}
+	}
+	assert.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, ids)
+}
+
+func TestDataFeedRoundRobin(t *testing.T) {
+	t.Parallel()
+
+	segment, err := lib.NewExecutionSegmentFromString("1/2:1")
+	require.NoError(t, err)
+	vu, setVUContext := newFeedVU(t, New(), `{mode: "round-robin"}`)
+	setVUContext(segment)
+	assert.Equal(t, []int64{5, 6, 7, 8, 9, 5, 6}, getFeedIDs(t, vu, 7))
+
+	remaining, err := vu.RunString(`feed.remaining()`)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), remaining.ToInteger())
+}
+
+func TestDataFeedExceptions(t *testing.T) {
+	t.Parallel()
+
+	rt, err := newConfiguredRuntime(New())
+	require.NoError(t, err)
+	cases := map[string]struct {
+		code, err string
+	}{
+		"empty name": {
+			code: `new data.DataFeed("", function() {return []});`,
+			err:  "empty name provided to DataFeed's constructor",
+		},
+		"bad mode": {
+			code: `new data.DataFeed("bad", function() {return []}, {mode: "twice"});`,
+			err:  "invalid DataFeed mode 'twice'",
+		},
+		"next in init context": {
+			code: `new data.DataFeed("init", function() {return [1]}).next();`,
+			err:  "Using DataFeed's next() in the init context is not supported",
+		},
+	}
+
+	for name, testCase := range cases {
+		name, testCase := name, testCase
+		t.Run(name, func(t *testing.T) {
+			_, err := rt.RunString(testCase.code)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testCase.err)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"golang.org/x/time/rate"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/stats"
+)
+
+// rateLimiterUnits are the units of the rates of the RateLimiter, with their durations.
+//nolint:gochecknoglobals
+var rateLimiterUnits = map[string]time.Duration{
+	"rps": time.Second,
+	"rpm": time.Minute,
+	"rph": time.Hour,
+}
+
+// RateLimiter is the JS wrapper of a token bucket shared between all VUs.
+type RateLimiter struct {
+	limiter *rate.Limiter
+}
+
+// XRateLimiter is a constructor returning a token bucket rate limiter, shared by all VUs, that
+// allows the rate of requests in the unit, one of rps, rpm or rph. The options can have the burst,
+// the number of tokens the bucket can hold, which is 1 by default, and the name that identifies
+// the limiter, which is the rate and the unit by default. Like for the other shared objects, the
+// rate and the burst of the first constructor call with the name are used.
+func (d *data) XRateLimiter(
+	ctxPtr *context.Context, limit float64, unit string, opts goja.Value,
+) (interface{}, error) {
+	if lib.GetState(*ctxPtr) != nil {
+		return nil, errors.New("new RateLimiter must be called in the init context")
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("invalid RateLimiter rate %v, it has to be positive", limit)
+	}
+	unit = strings.ToLower(unit)
+	period, ok := rateLimiterUnits[unit]
+	if !ok {
+		return nil, fmt.Errorf("invalid RateLimiter unit '%s', it has to be one of rps, rpm or rph", unit)
+	}
+
+	rt := common.GetRuntime(*ctxPtr)
+	name, burst := fmt.Sprintf("%v%s", limit, unit), 1
+	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
+		obj := opts.ToObject(rt)
+		for _, k := range obj.Keys() {
+			switch k {
+			case "name":
+				name = obj.Get(k).String()
+				if err := checkSharedName("RateLimiter", name); err != nil {
+					return nil, err
+				}
+			case "burst":
+				burst = int(obj.Get(k).ToInteger())
+				if burst < 1 {
+					return nil, fmt.Errorf("invalid RateLimiter burst %d, it has to be at least 1", burst)
+				}
+			default:
+				return nil, fmt.Errorf("unknown RateLimiter option '%s'", k)
+			}
+		}
+	}
+
+	limiter := d.limiters.get(name, func() interface{} {
+		return rate.NewLimiter(rate.Limit(limit/period.Seconds()), burst)
+	}).(*rate.Limiter)
+	return common.Bind(rt, &RateLimiter{limiter: limiter}, ctxPtr), nil
+}
+
+// Wait blocks until the limiter allows the tokens, 1 if it's not specified, and returns how long it
+// waited in milliseconds. It returns early if the iteration is interrupted.
+func (l *RateLimiter) Wait(ctx context.Context, tokens goja.Value) (float64, error) {
+	n := 1
+	if tokens != nil && !goja.IsUndefined(tokens) {
+		n = int(tokens.ToInteger())
+	}
+	if n > l.limiter.Burst() {
+		return 0, fmt.Errorf("can't wait for %d tokens, the burst of the RateLimiter is %d", n, l.limiter.Burst())
+	}
+	start := time.Now()
+	if err := l.limiter.WaitN(ctx, n); err != nil && ctx.Err() == nil {
+		return 0, err
+	}
+	return stats.D(time.Since(start)), nil
+}
+
+// TryAcquire takes the tokens, 1 if it's not specified, if the limiter allows them now, and
+// returns whether it took them.
+func (l *RateLimiter) TryAcquire(tokens goja.Value) bool {
+	n := 1
+	if tokens != nil && !goja.IsUndefined(tokens) {
+		n = int(tokens.ToInteger())
+	}
+	return l.limiter.AllowN(time.Now(), n)
+}
+
+// Rate returns the rate of the limiter in requests per second.
+func (l *RateLimiter) Rate() float64 {
+	return float64(l.limiter.Limit())
+}
@@ -0,0 +1,107 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	moduleInstance := New()
+	newVU := func() *goja.Runtime {
+		rt := goja.New()
+		rt.SetFieldNameMapper(common.FieldNameMapper{})
+		ctx := common.WithRuntime(context.Background(), rt)
+		require.NoError(t, rt.Set("data", common.Bind(rt, moduleInstance, &ctx)))
+		_, err := rt.RunString(`var limiter = new data.RateLimiter(1200, "rpm", { name: "api" });`)
+		require.NoError(t, err)
+		ctx = lib.WithState(ctx, &lib.State{})
+		return rt
+	}
+
+	const vus, waits = 4, 3
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < vus; i++ {
+		rt := newVU()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < waits; j++ {
+				_, err := rt.RunString(`limiter.wait()`)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+	// 20 requests per second, the first one doesn't wait
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64((vus*waits-1)*50*time.Millisecond))
+
+	rt := newVU()
+	v, err := rt.RunString(`limiter.rate()`)
+	require.NoError(t, err)
+	assert.Equal(t, float64(20), v.ToFloat())
+	_, err = rt.RunString(`limiter.wait(2)`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "can't wait for 2 tokens, the burst of the RateLimiter is 1")
+
+	_, err = rt.RunString(`new data.RateLimiter(1, "rps")`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "new RateLimiter must be called in the init context")
+}
+
+func TestRateLimiterErrors(t *testing.T) {
+	t.Parallel()
+	rt, err := newConfiguredRuntime(New())
+	require.NoError(t, err)
+
+	_, err = rt.RunString(`
+	var burst = new data.RateLimiter(1, "rph", { burst: 2 });
+	if (!burst.tryAcquire() || !burst.tryAcquire() || burst.tryAcquire()) {
+		throw new Error("the burst wasn't respected");
+	}`)
+	require.NoError(t, err)
+
+	cases := map[string]string{
+		`new data.RateLimiter(0, "rps")`:                "invalid RateLimiter rate 0, it has to be positive",
+		`new data.RateLimiter(10, "rpd")`:               "invalid RateLimiter unit 'rpd', it has to be one of rps, rpm or rph",
+		`new data.RateLimiter(10, "rps", { burst: 0 })`: "invalid RateLimiter burst 0, it has to be at least 1",
+		`new data.RateLimiter(10, "rps", { name: "" })`: "empty name provided to RateLimiter's constructor",
+		`new data.RateLimiter(10, "rps", { other: 1 })`: "unknown RateLimiter option 'other'",
+	}
+	for code, msg := range cases {
+		_, err := rt.RunString(code)
+		require.Error(t, err, code)
+		assert.Contains(t, err.Error(), msg, code)
+	}
+}
@@ -0,0 +1,300 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/dop251/goja"
+	"github.com/spf13/afero"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/fsext"
+)
+
+// The supported formats of the files read by a FileReader.
+const (
+	readerFormatCSV   = "csv"
+	readerFormatJSONL = "jsonl"
+	readerFormatText  = "text"
+)
+
+type readerOptions struct {
+	format    string
+	header    bool
+	delimiter rune
+	loop      bool
+	shared    bool
+}
+
+// fileReader reads the records of a file one by one, so only a small buffer
+// of the file is kept in memory for every reader, instead of the whole file
+// for every VU like with open().
+type fileReader struct {
+	fs       afero.Fs
+	filename string
+	options  readerOptions
+
+	mu      sync.Mutex
+	file    afero.File
+	csv     *csv.Reader
+	lines   *bufio.Reader
+	columns []string
+}
+
+// record is a single record of the file, either the fields of a CSV record
+// or a single line.
+type record struct {
+	fields []string
+	line   string
+}
+
+func (r *fileReader) open() error {
+	file, err := r.fs.Open(r.filename)
+	if err != nil {
+		return err
+	}
+	r.file = file
+	r.columns = nil
+	if r.options.format != readerFormatCSV {
+		r.lines = bufio.NewReader(file)
+		return nil
+	}
+
+	r.csv = csv.NewReader(file)
+	r.csv.Comma = r.options.delimiter
+	r.csv.FieldsPerRecord = -1
+	if r.options.header {
+		r.columns, err = r.csv.Read()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fileReader) closeFile() {
+	if r.file != nil {
+		_ = r.file.Close()
+	}
+	r.file, r.csv, r.lines, r.columns = nil, nil, nil, nil
+}
+
+func (r *fileReader) readRecord() (record, error) {
+	if r.csv != nil {
+		fields, err := r.csv.Read()
+		return record{fields: fields}, err
+	}
+	for {
+		line, err := r.lines.ReadString('\n')
+		if errors.Is(err, io.EOF) && len(line) > 0 {
+			err = nil
+		}
+		if err != nil {
+			return record{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if r.options.format == readerFormatJSONL && strings.TrimSpace(line) == "" {
+			continue
+		}
+		return record{line: line}, nil
+	}
+}
+
+// next returns the next record of the file and the CSV header, if there is
+// one. If the end of the file is reached, false is returned, unless the reader
+// loops, in which case it starts again from the beginning of the file.
+func (r *fileReader) next() (record, []string, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for restarted := false; ; restarted = true {
+		if r.file == nil {
+			if err := r.open(); err != nil {
+				r.closeFile()
+				return record{}, nil, false, err
+			}
+		}
+		rec, err := r.readRecord()
+		if err == nil {
+			return rec, r.columns, true, nil
+		}
+		r.closeFile()
+		if !errors.Is(err, io.EOF) {
+			return record{}, nil, false, err
+		}
+		if !r.options.loop || restarted {
+			return record{}, nil, false, nil
+		}
+	}
+}
+
+// reset closes the file, so the next record is read from its beginning.
+func (r *fileReader) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeFile()
+}
+
+// FileReader is the JS wrapper of a fileReader.
+type FileReader struct {
+	rt     *goja.Runtime
+	reader *fileReader
+}
+
+// Next returns the next record of the file, or undefined after the last
+// one. CSV records are returned as arrays of strings, or as objects if the
+// file has a header, JSONL records are parsed and text lines are returned as
+// strings.
+func (r FileReader) Next() goja.Value {
+	rec, columns, ok, err := r.reader.next()
+	if err != nil {
+		common.Throw(r.rt, err)
+	}
+	if !ok {
+		return goja.Undefined()
+	}
+
+	switch r.reader.options.format {
+	case readerFormatCSV:
+		if columns == nil {
+			return r.rt.ToValue(rec.fields)
+		}
+		obj := r.rt.NewObject()
+		for i, column := range columns {
+			if i < len(rec.fields) {
+				_ = obj.Set(column, rec.fields[i])
+			}
+		}
+		return obj
+	case readerFormatJSONL:
+		return fromJSON(r.rt, rec.line)
+	default:
+		return r.rt.ToValue(rec.line)
+	}
+}
+
+// Reset starts reading the file from its beginning again. For shared readers,
+// this affects all VUs.
+func (r FileReader) Reset() {
+	r.reader.reset()
+}
+
+func parseReaderOptions(rt *goja.Runtime, filename string, options goja.Value) (readerOptions, error) {
+	opts := readerOptions{delimiter: ','}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		opts.format = readerFormatCSV
+	case ".jsonl", ".ndjson":
+		opts.format = readerFormatJSONL
+	default:
+		opts.format = readerFormatText
+	}
+	if options == nil || goja.IsUndefined(options) || goja.IsNull(options) {
+		return opts, nil
+	}
+
+	obj := options.ToObject(rt)
+	isSet := func(v goja.Value) bool { return v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) }
+	if v := obj.Get("format"); isSet(v) {
+		opts.format = v.String()
+	}
+	if opts.format != readerFormatCSV && opts.format != readerFormatJSONL && opts.format != readerFormatText {
+		return opts, fmt.Errorf("invalid FileReader format '%s', use '%s', '%s' or '%s'",
+			opts.format, readerFormatCSV, readerFormatJSONL, readerFormatText)
+	}
+	if v := obj.Get("delimiter"); isSet(v) {
+		delimiter := v.String()
+		if utf8.RuneCountInString(delimiter) != 1 {
+			return opts, fmt.Errorf("the FileReader delimiter has to be a single character, got '%s'", delimiter)
+		}
+		opts.delimiter, _ = utf8.DecodeRuneInString(delimiter)
+	}
+	opts.header = isSet(obj.Get("header")) && obj.Get("header").ToBoolean()
+	opts.loop = isSet(obj.Get("loop")) && obj.Get("loop").ToBoolean()
+	opts.shared = isSet(obj.Get("shared")) && obj.Get("shared").ToBoolean()
+	return opts, nil
+}
+
+// XFileReader is a constructor returning a reader of the file with the given
+// name, that reads it record by record instead of loading the whole file in
+// memory. By default, every VU reads the whole file on its own. Shared readers
+// of the same file are used by all VUs in the instance, so that every record
+// is read only once, and the options of the first constructor call with the
+// file are used.
+//
+// The file is read from the disk, bypassing the in-memory cache of the files
+// that are opened in the init context, so it isn't included in archives unless
+// it's also opened with open(). When an archive is executed, the file is read
+// from the files of the archive.
+func (d *data) XFileReader(ctx context.Context, filename string, options goja.Value) (goja.Value, error) {
+	if lib.GetState(ctx) != nil {
+		return nil, errors.New("new FileReader must be called in the init context")
+	}
+	if len(filename) == 0 {
+		return nil, errors.New("empty filename provided to FileReader's constructor")
+	}
+	initEnv := common.GetInitEnv(ctx)
+	if initEnv == nil {
+		return nil, errors.New("missing init environment")
+	}
+
+	rt := common.GetRuntime(ctx)
+	opts, err := parseReaderOptions(rt, filename, options)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := initEnv.FileSystems["file"]
+	if cachedFs, ok := fs.(fsext.CacheOnReadFs); ok {
+		fs = cachedFs.GetBaseFs()
+	}
+	absFilePath := initEnv.GetAbsFilePath(filename)
+	if isDir, _ := afero.IsDir(fs, absFilePath); isDir {
+		return nil, fmt.Errorf("FileReader can't be used with directories, path: %q", filename)
+	}
+	// Opening the file in the init context makes sure that it exists.
+	file, err := fs.Open(absFilePath)
+	if err != nil {
+		return nil, err
+	}
+	_ = file.Close()
+
+	newReader := func() interface{} {
+		return &fileReader{fs: fs, filename: absFilePath, options: opts}
+	}
+	var reader *fileReader
+	if opts.shared {
+		reader = d.readers.get(absFilePath, newReader).(*fileReader)
+	} else {
+		reader = newReader().(*fileReader)
+	}
+	return rt.ToValue(&FileReader{rt: rt, reader: reader}), nil
+}
@@ -0,0 +1,182 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/fsext"
+)
+
+func newReaderRuntime(t *testing.T, moduleInstance interface{}, fs afero.Fs) *goja.Runtime {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithInitEnv(context.Background(), &common.InitEnvironment{
+		FileSystems: map[string]afero.Fs{"file": fs},
+		CWD:         &url.URL{Path: "/path/to"},
+	})
+	ctx = common.WithRuntime(ctx, rt)
+	require.NoError(t, rt.Set("data", common.Bind(rt, moduleInstance, &ctx)))
+	return rt
+}
+
+func newReaderFs(t *testing.T) afero.Fs {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/path/to/users.csv", []byte("name,age\nalice,30\nbob,40\n"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/path/to/users.jsonl", []byte("{\"id\":1}\n\n{\"id\":2}"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/path/to/users.tsv", []byte("name\tage\nalice\t30\n"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/path/to/words.txt", []byte("foo\r\nbar\n"), 0o644))
+	return fs
+}
+
+func TestFileReader(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, script string
+		expected     interface{}
+	}{
+		{
+			name: "csv",
+			script: `var r = new data.FileReader("users.csv");
+				[r.next(), r.next(), r.next(), r.next()]`,
+			expected: []interface{}{
+				[]string{"name", "age"}, []string{"alice", "30"}, []string{"bob", "40"}, nil,
+			},
+		},
+		{
+			name: "csv header",
+			script: `var r = new data.FileReader("/path/to/users.csv", {header: true});
+				[r.next(), r.next(), r.next()]`,
+			expected: []interface{}{
+				map[string]interface{}{"name": "alice", "age": "30"},
+				map[string]interface{}{"name": "bob", "age": "40"},
+				nil,
+			},
+		},
+		{
+			name: "jsonl",
+			script: `var r = new data.FileReader("users.jsonl");
+				[r.next().id, r.next().id, r.next()]`,
+			expected: []interface{}{int64(1), int64(2), nil},
+		},
+		{
+			name: "text loop",
+			script: `var r = new data.FileReader("words.txt", {loop: true});
+				[r.next(), r.next(), r.next()]`,
+			expected: []interface{}{"foo", "bar", "foo"},
+		},
+		{
+			name: "reset",
+			script: `var r = new data.FileReader("words.txt", {format: "text"});
+				r.next(); r.reset();
+				[r.next()]`,
+			expected: []interface{}{"foo"},
+		},
+		{
+			name: "delimiter",
+			script: `var r = new data.FileReader("users.tsv", {format: "csv", delimiter: "\t", header: true});
+				[r.next()]`,
+			expected: []interface{}{map[string]interface{}{"name": "alice", "age": "30"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rt := newReaderRuntime(t, New(), newReaderFs(t))
+			v, err := rt.RunString(tc.script)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, v.Export())
+		})
+	}
+}
+
+func TestFileReaderNotCached(t *testing.T) {
+	t.Parallel()
+	cache := afero.NewMemMapFs()
+	rt := newReaderRuntime(t, New(), fsext.NewCacheOnReadFs(newReaderFs(t), cache, 0))
+	v, err := rt.RunString(`var r = new data.FileReader("words.txt"); [r.next()]`)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"foo"}, v.Export())
+
+	_, err = cache.Stat("/path/to/words.txt")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileReaderErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		`new data.FileReader("")`:                             "empty filename provided to FileReader's constructor",
+		`new data.FileReader("missing.csv")`:                  "file does not exist",
+		`new data.FileReader("/path/to")`:                     "FileReader can't be used with directories",
+		`new data.FileReader("users.csv", {format: "xml"})`:   "invalid FileReader format 'xml'",
+		`new data.FileReader("users.csv", {delimiter: ";;"})`: "the FileReader delimiter has to be a single character",
+	}
+	for script, errMsg := range testCases {
+		script, errMsg := script, errMsg
+		t.Run(script, func(t *testing.T) {
+			t.Parallel()
+			rt := newReaderRuntime(t, New(), newReaderFs(t))
+			_, err := rt.RunString(script)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), errMsg)
+		})
+	}
+}
+
+func TestFileReaderShared(t *testing.T) {
+	t.Parallel()
+
+	fs := newReaderFs(t)
+	moduleInstance := New()
+	rt1 := newReaderRuntime(t, moduleInstance, fs)
+	rt2 := newReaderRuntime(t, moduleInstance, fs)
+	rt3 := newReaderRuntime(t, moduleInstance, fs)
+
+	script := `var r = new data.FileReader("words.txt", {shared: true}); r.next()`
+	v, err := rt1.RunString(script)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", v.Export())
+	v, err = rt2.RunString(script)
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v.Export())
+
+	// not shared readers have their own position in the file
+	v, err = rt3.RunString(`new data.FileReader("words.txt").next()`)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", v.Export())
+
+	v, err = rt1.RunString(`r.next()`)
+	require.NoError(t, err)
+	assert.Nil(t, v.Export())
+}
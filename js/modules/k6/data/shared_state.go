@@ -0,0 +1,256 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dop251/goja"
+	"go.k6.io/k6/js/common"
+)
+
+var errNegativeCapacity = errors.New("the SharedQueue capacity can't be negative")
+
+// The writable shared objects keep their values as JSON, like the SharedArray,
+// so that the VUs can't share JS objects between their runtimes.
+
+// sharedObjects keeps all of the writable shared objects of a single type,
+// by their name, for all VUs in the instance.
+type sharedObjects struct {
+	data map[string]interface{}
+	mu   sync.Mutex
+}
+
+func (s *sharedObjects) get(name string, create func() interface{}) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		s.data = make(map[string]interface{})
+	}
+	obj, ok := s.data[name]
+	if !ok {
+		obj = create()
+		s.data[name] = obj
+	}
+	return obj
+}
+
+func toJSON(rt *goja.Runtime, val goja.Value) string {
+	if val == nil || goja.IsUndefined(val) {
+		return "null"
+	}
+	b, err := json.Marshal(val.Export())
+	if err != nil {
+		common.Throw(rt, err)
+	}
+	return string(b)
+}
+
+func fromJSON(rt *goja.Runtime, data string) goja.Value {
+	var v interface{}
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		common.Throw(rt, err)
+	}
+	return rt.ToValue(v)
+}
+
+// sharedCounter is an integer counter shared between all VUs.
+type sharedCounter struct {
+	value int64
+}
+
+// SharedCounter is the JS wrapper of a sharedCounter.
+type SharedCounter struct {
+	counter *sharedCounter
+}
+
+// Add atomically adds the delta, 1 if it's not specified, to the counter and
+// returns the new value. Since every call returns a different value, this can
+// be used to hand out unique IDs to the VUs.
+func (c SharedCounter) Add(delta goja.Value) int64 {
+	d := int64(1)
+	if delta != nil && !goja.IsUndefined(delta) {
+		d = delta.ToInteger()
+	}
+	return atomic.AddInt64(&c.counter.value, d)
+}
+
+// Get returns the current value of the counter.
+func (c SharedCounter) Get() int64 {
+	return atomic.LoadInt64(&c.counter.value)
+}
+
+// Set changes the value of the counter.
+func (c SharedCounter) Set(value int64) {
+	atomic.StoreInt64(&c.counter.value, value)
+}
+
+// CompareAndSwap changes the value of the counter to newValue only if it's
+// equal to oldValue, and returns whether it was changed.
+func (c SharedCounter) CompareAndSwap(oldValue, newValue int64) bool {
+	return atomic.CompareAndSwapInt64(&c.counter.value, oldValue, newValue)
+}
+
+// sharedQueue is a bounded FIFO queue shared between all VUs.
+type sharedQueue struct {
+	capacity int
+	items    []string
+	mu       sync.Mutex
+}
+
+// SharedQueue is the JS wrapper of a sharedQueue.
+type SharedQueue struct {
+	rt    *goja.Runtime
+	queue *sharedQueue
+}
+
+// Push adds the value to the end of the queue and returns true, or returns
+// false if the queue is full.
+func (q SharedQueue) Push(val goja.Value) bool {
+	data := toJSON(q.rt, val)
+	q.queue.mu.Lock()
+	defer q.queue.mu.Unlock()
+	if q.queue.capacity > 0 && len(q.queue.items) >= q.queue.capacity {
+		return false
+	}
+	q.queue.items = append(q.queue.items, data)
+	return true
+}
+
+// Pop removes and returns the value from the start of the queue, or returns
+// undefined if the queue is empty.
+func (q SharedQueue) Pop() goja.Value {
+	q.queue.mu.Lock()
+	if len(q.queue.items) == 0 {
+		q.queue.mu.Unlock()
+		return goja.Undefined()
+	}
+	data := q.queue.items[0]
+	q.queue.items[0] = ""
+	q.queue.items = q.queue.items[1:]
+	q.queue.mu.Unlock()
+	return fromJSON(q.rt, data)
+}
+
+// Size returns the number of values in the queue.
+func (q SharedQueue) Size() int {
+	q.queue.mu.Lock()
+	defer q.queue.mu.Unlock()
+	return len(q.queue.items)
+}
+
+// Capacity returns the maximum number of values in the queue, 0 means that
+// it's unbounded.
+func (q SharedQueue) Capacity() int {
+	return q.queue.capacity
+}
+
+// sharedMap is a key-value store shared between all VUs.
+type sharedMap struct {
+	items map[string]string
+	mu    sync.RWMutex
+}
+
+// SharedMap is the JS wrapper of a sharedMap.
+type SharedMap struct {
+	rt *goja.Runtime
+	m  *sharedMap
+}
+
+// Get returns the value of the key, or undefined if it isn't set.
+func (m SharedMap) Get(key string) goja.Value {
+	m.m.mu.RLock()
+	data, ok := m.m.items[key]
+	m.m.mu.RUnlock()
+	if !ok {
+		return goja.Undefined()
+	}
+	return fromJSON(m.rt, data)
+}
+
+// Set sets the value of the key.
+func (m SharedMap) Set(key string, val goja.Value) {
+	data := toJSON(m.rt, val)
+	m.m.mu.Lock()
+	defer m.m.mu.Unlock()
+	m.m.items[key] = data
+}
+
+// SetIfAbsent sets the value of the key only if it isn't set already, and
+// returns whether it was set. This way only one VU can claim a key.
+func (m SharedMap) SetIfAbsent(key string, val goja.Value) bool {
+	data := toJSON(m.rt, val)
+	m.m.mu.Lock()
+	defer m.m.mu.Unlock()
+	if _, ok := m.m.items[key]; ok {
+		return false
+	}
+	m.m.items[key] = data
+	return true
+}
+
+// Has returns whether the key is set.
+func (m SharedMap) Has(key string) bool {
+	m.m.mu.RLock()
+	defer m.m.mu.RUnlock()
+	_, ok := m.m.items[key]
+	return ok
+}
+
+// Delete removes the key and returns whether it was set.
+func (m SharedMap) Delete(key string) bool {
+	m.m.mu.Lock()
+	defer m.m.mu.Unlock()
+	_, ok := m.m.items[key]
+	delete(m.m.items, key)
+	return ok
+}
+
+// Keys returns the sorted keys of the map.
+func (m SharedMap) Keys() []string {
+	m.m.mu.RLock()
+	keys := make([]string, 0, len(m.m.items))
+	for key := range m.m.items {
+		keys = append(keys, key)
+	}
+	m.m.mu.RUnlock()
+	sort.Strings(keys)
+	return keys
+}
+
+// Size returns the number of keys in the map.
+func (m SharedMap) Size() int {
+	m.m.mu.RLock()
+	defer m.m.mu.RUnlock()
+	return len(m.m.items)
+}
+
+func checkSharedName(kind, name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("empty name provided to %s's constructor", kind)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedCounter(t *testing.T) {
+	t.Parallel()
+
+	const instances, iterations = 10, 100
+	moduleInstance := New()
+	var wg sync.WaitGroup
+	ids := make([][]int64, instances)
+	for i := 0; i < instances; i++ {
+		rt, err := newConfiguredRuntime(moduleInstance)
+		require.NoError(t, err)
+		_, err = rt.RunString(`var counter = new data.SharedCounter("ids");`)
+		require.NoError(t, err)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				v, err := rt.RunString(`counter.add()`)
+				if !assert.NoError(t, err) {
+					return
+				}
+				ids[i] = append(ids[i], v.ToInteger())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[int64]bool)
+	for _, vuIDs := range ids {
+		for _, id := range vuIDs {
+			assert.False(t, seen[id], "duplicate id %d", id)
+			seen[id] = true
+		}
+	}
+	assert.Len(t, seen, instances*iterations)
+
+	rt, err := newConfiguredRuntime(moduleInstance)
+	require.NoError(t, err)
+	_, err = rt.RunString(`
+	var counter = new data.SharedCounter("ids");
+	if (counter.get() !== 1000) {
+		throw new Error("bad value " + counter.get());
+	}
+	if (counter.add(-10) !== 990) {
+		throw new Error("bad add");
+	}
+	if (counter.compareAndSwap(1, 2) || !counter.compareAndSwap(990, 5)) {
+		throw new Error("bad compareAndSwap");
+	}
+	counter.set(42);
+	if (counter.get() !== 42) {
+		throw new Error("bad set");
+	}
+	if (new data.SharedCounter("other").get() !== 0) {
+		throw new Error("counters aren't separate");
+	}
+	`)
+	require.NoError(t, err)
+}
+
+func TestSharedQueue(t *testing.T) {
+	t.Parallel()
+
+	moduleInstance := New()
+	producer, err := newConfiguredRuntime(moduleInstance)
+	require.NoError(t, err)
+	_, err = producer.RunString(`
+	var queue = new data.SharedQueue("jobs", 2);
+	if (!queue.push({id: 1}) || !queue.push({id: 2})) {
+		throw new Error("push failed");
+	}
+	if (queue.push({id: 3})) {
+		throw new Error("push should fail when the queue is full");
+	}
+	`)
+	require.NoError(t, err)
+
+	consumer, err := newConfiguredRuntime(moduleInstance)
+	require.NoError(t, err)
+	_, err = consumer.RunString(`
+	var queue = new data.SharedQueue("jobs", 100);
+	if (queue.capacity() !== 2 || queue.size() !== 2) {
+		throw new Error("bad queue " + queue.capacity() + " " + queue.size());
+	}
+	var job = queue.pop();
+	if (job.id !== 1) {
+		throw new Error("bad job " + JSON.stringify(job));
+	}
+	job.id = 10; // this shouldn't affect the queue
+	if (queue.pop().id !== 2 || queue.pop() !== undefined || queue.size() !== 0) {
+		throw new Error("bad pop");
+	}
+	`)
+	require.NoError(t, err)
+
+	_, err = consumer.RunString(`new data.SharedQueue("bad", -1)`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the SharedQueue capacity can't be negative")
+}
+
+func TestSharedMap(t *testing.T) {
+	t.Parallel()
+
+	moduleInstance := New()
+	rt1, err := newConfiguredRuntime(moduleInstance)
+	require.NoError(t, err)
+	_, err = rt1.RunString(`
+	var sessions = new data.SharedMap("sessions");
+	sessions.set("user1", {token: "a"});
+	if (!sessions.setIfAbsent("user2", {token: "b"})) {
+		throw new Error("setIfAbsent failed");
+	}
+	`)
+	require.NoError(t, err)
+
+	rt2, err := newConfiguredRuntime(moduleInstance)
+	require.NoError(t, err)
+	_, err = rt2.RunString(`
+	var sessions = new data.SharedMap("sessions");
+	if (sessions.setIfAbsent("user2", {token: "c"})) {
+		throw new Error("setIfAbsent should fail for existing keys");
+	}
+	if (sessions.get("user2").token !== "b" || sessions.get("nope") !== undefined) {
+		throw new Error("bad get");
+	}
+	if (sessions.keys().join(",") !== "user1,user2" || sessions.size() !== 2) {
+		throw new Error("bad keys " + sessions.keys());
+	}
+	if (!sessions.delete("user1") || sessions.delete("user1") || sessions.has("user1")) {
+		throw new Error("bad delete");
+	}
+	`)
+	require.NoError(t, err)
+
+	_, err = rt2.RunString(`new data.SharedMap("")`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "empty name provided to SharedMap's constructor")
+}
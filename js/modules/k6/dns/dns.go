@@ -0,0 +1,338 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package dns implements the k6/net/dns module, which makes DNS queries over UDP, TCP, TLS
+// (DoT) or HTTPS (DoH), so the DNS servers can be load tested like any other service.
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+// ErrQueryInInitContext is returned when a query is made in the init context.
+var ErrQueryInInitContext = common.NewInitContextError("Making DNS queries in the init context is not supported")
+
+const defaultTimeout = 5 * time.Second
+
+// DNS is the k6/net/dns module.
+type DNS struct{}
+
+// New returns a new DNS module instance.
+func New() *DNS {
+	return &DNS{}
+}
+
+// Record is a resource record of the answer of a query.
+type Record struct {
+	Name string `js:"name"`
+	Type string `js:"type"`
+	TTL  uint32 `js:"ttl"`
+	// The address of the A and AAAA records, the target of the other ones and the text of the
+	// TXT records.
+	Data string `js:"data"`
+
+	// The fields of the MX and SRV records.
+	Priority uint16 `js:"priority"`
+	Weight   uint16 `js:"weight"`
+	Port     uint16 `js:"port"`
+	Target   string `js:"target"`
+}
+
+// Response is the result of a query, its error is set if the query couldn't be made.
+type Response struct {
+	Rcode         string   `js:"rcode"`
+	Authoritative bool     `js:"authoritative"`
+	Answers       []Record `js:"answers"`
+	Duration      float64  `js:"duration"`
+	Error         string   `js:"error"`
+}
+
+// queryParams are the params of a query.
+type queryParams struct {
+	server    string
+	proto     string
+	timeout   time.Duration
+	recursion bool
+	tags      map[string]string
+}
+
+// Query makes a query of the name and the record type, one of A, AAAA, SRV, TXT, CNAME, MX,
+// NS and PTR, to the server of the params, which are:
+//   - server: the address of the server, host:port or an https URL for DoH, it's required
+//   - protocol: udp, the default, tcp, dot or doh
+//   - timeout: 5s by default
+//   - recursion: whether the recursion is desired, true by default
+//   - tags: the tags of the metrics
+func (d *DNS) Query(ctx context.Context, name, rtype string, params map[string]interface{}) (*Response, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrQueryInInitContext
+	}
+	qtype, err := parseType(rtype)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = appendName(nil, name); err != nil {
+		return nil, err
+	}
+	p, err := parseQueryParams(state, params)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := state.CloneTags()
+	for k, v := range p.tags {
+		tags[k] = v
+	}
+	tags["name"], tags["type"], tags["proto"] = name, typeName(qtype), p.proto
+
+	start := time.Now()
+	msg, err := d.exchange(ctx, state, p, name, qtype)
+	end := time.Now()
+
+	resp := &Response{Duration: stats.D(end.Sub(start))}
+	if err != nil {
+		tags["error"] = err.Error()
+	} else {
+		resp.Rcode, resp.Authoritative, resp.Answers = rcodeName(msg.rcode), msg.authoritative, msg.answers
+		if resp.Answers == nil {
+			resp.Answers = []Record{}
+		}
+		tags["rcode"] = resp.Rcode
+	}
+	sampleTags := stats.IntoSampleTags(&tags)
+	stats.PushIfNotDone(ctx, state.Samples, stats.ConnectedSamples{
+		Samples: []stats.Sample{
+			{Metric: metrics.DNSLookups, Time: end, Tags: sampleTags, Value: 1},
+			{Metric: metrics.DNSLookupDuration, Time: end, Tags: sampleTags, Value: resp.Duration},
+		},
+		Tags: sampleTags,
+		Time: end,
+	})
+
+	if err != nil {
+		if state.Options.Throw.Bool {
+			return nil, err
+		}
+		state.Logger.WithField("error", err).Warn("DNS query failed")
+		resp.Error = err.Error()
+	}
+	return resp, nil
+}
+
+func parseQueryParams(state *lib.State, params map[string]interface{}) (queryParams, error) {
+	p := queryParams{proto: "udp", timeout: defaultTimeout, recursion: true}
+	for k, v := range params {
+		var ok bool
+		switch k {
+		case "server":
+			p.server, ok = v.(string)
+		case "protocol":
+			p.proto, ok = v.(string)
+			ok = ok && (p.proto == "udp" || p.proto == "tcp" || p.proto == "dot" || p.proto == "doh")
+		case "timeout":
+			var err error
+			p.timeout, err = types.GetDurationValue(v)
+			ok = err == nil && p.timeout > 0
+		case "recursion":
+			p.recursion, ok = v.(bool)
+		case "tags":
+			var raw map[string]interface{}
+			raw, ok = v.(map[string]interface{})
+			p.tags = make(map[string]string, len(raw))
+			for tk, tv := range raw {
+				p.tags[tk] = fmt.Sprint(tv)
+			}
+		default:
+			return p, fmt.Errorf("unknown DNS query param: %q", k)
+		}
+		if !ok {
+			return p, fmt.Errorf("invalid DNS query %s value: %v", k, v)
+		}
+	}
+	if p.server == "" {
+		return p, errors.New("the server of the DNS query is required")
+	}
+	if p.proto == "doh" {
+		if u, err := url.Parse(p.server); err != nil || u.Scheme != "https" && u.Scheme != "http" {
+			return p, fmt.Errorf("the DoH server has to be an https URL, got %q", p.server)
+		}
+	} else if _, _, err := net.SplitHostPort(p.server); err != nil {
+		port := "53"
+		if p.proto == "dot" {
+			port = "853"
+		}
+		p.server = net.JoinHostPort(p.server, port)
+	}
+	return p, nil
+}
+
+// exchange sends the query and returns the response, the UDP queries with truncated responses
+// are made again over TCP.
+func (d *DNS) exchange(
+	ctx context.Context, state *lib.State, p queryParams, name string, qtype uint16,
+) (*message, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var id uint16
+	if p.proto != "doh" {
+		// DoH queries use 0 for the HTTP caches, RFC 8484
+		id = uint16(rand.Intn(1 << 16)) //nolint:gosec
+	}
+	query, err := buildQuery(id, name, qtype, p.recursion)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []byte
+	switch p.proto {
+	case "udp":
+		raw, err = exchangeUDP(ctx, state, p.server, query)
+	case "tcp", "dot":
+		raw, err = exchangeStream(ctx, state, p.server, query, p.proto == "dot")
+	case "doh":
+		raw, err = exchangeHTTPS(ctx, state, p.server, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	msg, err := parseMessage(raw)
+	if err != nil {
+		return nil, err
+	}
+	if msg.id != id {
+		return nil, fmt.Errorf("the DNS response ID %d doesn't match the query ID %d", msg.id, id)
+	}
+	if msg.truncated && p.proto == "udp" {
+		p.proto = "tcp"
+		return d.exchange(ctx, state, p, name, qtype)
+	}
+	return msg, nil
+}
+
+func dial(ctx context.Context, state *lib.State, network, server string) (net.Conn, error) {
+	conn, err := state.Dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	return conn, nil
+}
+
+func exchangeUDP(ctx context.Context, state *lib.State, server string, query []byte) ([]byte, error) {
+	conn, err := dial(ctx, state, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err = conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxMsgLen)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// exchangeStream makes the query over TCP or TLS, the messages are prefixed with their length.
+func exchangeStream(ctx context.Context, state *lib.State, server string, query []byte, useTLS bool) ([]byte, error) {
+	conn, err := dial(ctx, state, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	if useTLS {
+		host, _, _ := net.SplitHostPort(server)
+		tlsConfig := state.TLSConfig.Clone()
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		tlsConfig.ServerName = host
+		tlsConn := tls.Client(conn, tlsConfig)
+		// the deadline of the connection from the context bounds the handshake too
+		if err = tlsConn.Handshake(); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err = conn.Write(append(appendUint16(nil, uint16(len(query))), query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err = io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err = io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// exchangeHTTPS makes the query with a POST request to the DoH server, RFC 8484.
+func exchangeHTTPS(ctx context.Context, state *lib.State, server string, query []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	if ua := state.Options.UserAgent; ua.Valid {
+		req.Header.Set("User-Agent", ua.String)
+	}
+	resp, err := state.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxMsgLen))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the DoH server responded with the status %d", resp.StatusCode)
+	}
+	return body, nil
+}
@@ -0,0 +1,269 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/netext"
+	"go.k6.io/k6/stats"
+)
+
+// testAnswer returns the response of the test servers to the query, the answers of the names
+// that start with big are truncated over UDP.
+func testAnswer(t *testing.T, query []byte, udp bool) []byte {
+	name, off, err := readName(query, headerLen)
+	require.NoError(t, err)
+	qtype := binary.BigEndian.Uint16(query[off:])
+
+	resp := append([]byte{}, query[:off+4]...)
+	flags := uint16(flagResponse | flagAuthoritative)
+	var answers [][]byte
+	switch {
+	case udp && name == "big.k6.test":
+		flags |= flagTruncated
+	case name == "missing.k6.test":
+		flags |= 3
+	case qtype == typeA:
+		answers = append(answers, []byte{127, 0, 0, 1}, []byte{127, 0, 0, 2})
+	case qtype == typeSRV:
+		srv := []byte{0, 10, 0, 5, 0x1f, 0x90}
+		srv, err = appendName(srv, "backend.k6.test")
+		require.NoError(t, err)
+		answers = append(answers, srv)
+	case qtype == typeTXT:
+		answers = append(answers, append([]byte{5}, "hello"...))
+	}
+	binary.BigEndian.PutUint16(resp[2:], flags)
+	binary.BigEndian.PutUint16(resp[6:], uint16(len(answers)))
+	for _, data := range answers {
+		resp = append(resp, 0xc0, headerLen) // the name of the question
+		resp = appendUint16(resp, qtype)
+		resp = appendUint16(resp, 1)
+		resp = append(resp, 0, 0, 0, 60)
+		resp = appendUint16(resp, uint16(len(data)))
+		resp = append(resp, data...)
+	}
+	return resp
+}
+
+// newTestServer starts a DNS server listening on UDP and TCP on the same port.
+func newTestServer(t *testing.T) string {
+	var (
+		tcp net.Listener
+		udp net.PacketConn
+		err error
+	)
+	for i := 0; i < 10; i++ {
+		tcp, err = net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		udp, err = net.ListenPacket("udp", tcp.Addr().String())
+		if err == nil {
+			break
+		}
+		_ = tcp.Close()
+	}
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = tcp.Close()
+		_ = udp.Close()
+	})
+
+	go func() {
+		buf := make([]byte, maxMsgLen)
+		for {
+			n, addr, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = udp.WriteTo(testAnswer(t, buf[:n], true), addr)
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			var length [2]byte
+			if _, err = io.ReadFull(conn, length[:]); err == nil {
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err = io.ReadFull(conn, query); err == nil {
+					resp := testAnswer(t, query, false)
+					_, _ = conn.Write(append(appendUint16(nil, uint16(len(resp))), resp...))
+				}
+			}
+			_ = conn.Close()
+		}
+	}()
+	return tcp.Addr().String()
+}
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	doh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, err := ioutil.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" || err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(testAnswer(t, query, false))
+	}))
+	t.Cleanup(doh.Close)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	rt.Set("dns", common.Bind(rt, New(), &ctx))
+	rt.Set("server", server)
+	rt.Set("dohServer", doh.URL+"/dns-query")
+
+	t.Run("InitContext", func(t *testing.T) {
+		_, err := rt.RunString(`dns.query("k6.test", "A", { server: server })`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), ErrQueryInInitContext.Error())
+	})
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{
+		Group:     root,
+		Dialer:    netext.NewDialer(net.Dialer{}, nil),
+		Transport: doh.Client().Transport,
+		Samples:   samples,
+		Logger:    logrus.New(),
+		Options: lib.Options{
+			SystemTags: stats.NewSystemTagSet(stats.TagName),
+			UserAgent:  null.StringFrom("k6-test"),
+		},
+	}
+	ctx = lib.WithState(ctx, state)
+
+	t.Run("A", func(t *testing.T) {
+		for _, proto := range []string{"udp", "tcp", "doh"} {
+			v, err := rt.RunString(`
+				var res = dns.query("k6.test", "A", {
+					server: "` + proto + `" == "doh" ? dohServer : server,
+					protocol: "` + proto + `",
+					tags: { tag: "value" },
+				});
+				if (res.rcode !== "NOERROR" || !res.authoritative) throw new Error("unexpected response: " + JSON.stringify(res));
+				res.answers.map(function(a) { return a.name + " " + a.type + " " + a.ttl + " " + a.data }).join(",")
+			`)
+			require.NoError(t, err, proto)
+			assert.Equal(t, "k6.test A 60 127.0.0.1,k6.test A 60 127.0.0.2", v.String(), proto)
+		}
+
+		var seen int
+		for _, container := range stats.GetBufferedSamples(samples) {
+			for _, sample := range container.GetSamples() {
+				tags := sample.Tags.CloneTags()
+				assert.Equal(t, "value", tags["tag"])
+				assert.Equal(t, "A", tags["type"])
+				assert.Equal(t, "NOERROR", tags["rcode"])
+				if sample.Metric == metrics.DNSLookups {
+					seen++
+				}
+			}
+		}
+		assert.Equal(t, 3, seen)
+	})
+
+	t.Run("SRVAndTXT", func(t *testing.T) {
+		v, err := rt.RunString(`
+			var srv = dns.query("_http._tcp.k6.test", "SRV", { server: server }).answers[0];
+			var txt = dns.query("k6.test", "TXT", { server: server, protocol: "tcp" }).answers[0];
+			[srv.priority, srv.weight, srv.port, srv.target, txt.data].join(" ")
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "10 5 8080 backend.k6.test hello", v.String())
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		v, err := rt.RunString(`dns.query("big.k6.test", "A", { server: server }).answers.length`)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), v.ToInteger())
+	})
+
+	t.Run("NXDOMAIN", func(t *testing.T) {
+		v, err := rt.RunString(`
+			var res = dns.query("missing.k6.test", "A", { server: server });
+			res.rcode + " " + res.answers.length
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "NXDOMAIN 0", v.String())
+	})
+
+	t.Run("InvalidParams", func(t *testing.T) {
+		testCases := map[string]string{
+			`dns.query("k6.test", "AXFR", { server: server })`:                  `unsupported DNS record type "AXFR"`,
+			`dns.query("k6.test", "A", {})`:                                     "the server of the DNS query is required",
+			`dns.query("k6.test", "A", { server: server, protocol: "quic" })`:   "invalid DNS query protocol value: quic",
+			`dns.query("k6.test", "A", { server: server, timeout: "-1s" })`:     "invalid DNS query timeout value: -1s",
+			`dns.query("k6.test", "A", { server: server, proto: "tcp" })`:       `unknown DNS query param: "proto"`,
+			`dns.query("k6.test", "A", { server: "1.1.1.1", protocol: "doh" })`: `the DoH server has to be an https URL, got "1.1.1.1"`,
+			`dns.query("bad..name", "A", { server: server })`:                   "invalid DNS name",
+		}
+		for script, expected := range testCases {
+			_, err := rt.RunString(script)
+			require.Error(t, err, script)
+			assert.Contains(t, err.Error(), expected, script)
+		}
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		closed := l.Addr().String()
+		require.NoError(t, l.Close())
+		rt.Set("closed", closed)
+
+		v, err := rt.RunString(`dns.query("k6.test", "A", { server: closed, protocol: "tcp" }).error`)
+		require.NoError(t, err)
+		assert.Contains(t, v.String(), "connection refused")
+
+		state.Options.Throw = null.BoolFrom(true)
+		defer func() { state.Options.Throw = null.Bool{} }()
+		_, err = rt.RunString(`dns.query("k6.test", "A", { server: closed, protocol: "tcp" })`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "connection refused")
+	})
+}
@@ -0,0 +1,273 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// The types of the DNS records, https://www.iana.org/assignments/dns-parameters
+const (
+	typeA     uint16 = 1
+	typeNS    uint16 = 2
+	typeCNAME uint16 = 5
+	typePTR   uint16 = 12
+	typeMX    uint16 = 15
+	typeTXT   uint16 = 16
+	typeAAAA  uint16 = 28
+	typeSRV   uint16 = 33
+
+	classINET uint16 = 1
+
+	headerLen = 12
+	maxMsgLen = 65535
+
+	flagResponse      = 1 << 15
+	flagAuthoritative = 1 << 10
+	flagTruncated     = 1 << 9
+	flagRecursion     = 1 << 8
+)
+
+//nolint:gochecknoglobals
+var typeNames = map[uint16]string{
+	typeA:     "A",
+	typeNS:    "NS",
+	typeCNAME: "CNAME",
+	typePTR:   "PTR",
+	typeMX:    "MX",
+	typeTXT:   "TXT",
+	typeAAAA:  "AAAA",
+	typeSRV:   "SRV",
+}
+
+//nolint:gochecknoglobals
+var rcodeNames = []string{"NOERROR", "FORMERR", "SERVFAIL", "NXDOMAIN", "NOTIMP", "REFUSED"}
+
+func typeName(t uint16) string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return "TYPE" + strconv.Itoa(int(t))
+}
+
+func parseType(name string) (uint16, error) {
+	name = strings.ToUpper(name)
+	for t, n := range typeNames {
+		if n == name {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported DNS record type %q", name)
+}
+
+func rcodeName(rcode int) string {
+	if rcode < len(rcodeNames) {
+		return rcodeNames[rcode]
+	}
+	return "RCODE" + strconv.Itoa(rcode)
+}
+
+// message is the part of a DNS response that the module uses.
+type message struct {
+	id            uint16
+	rcode         int
+	authoritative bool
+	truncated     bool
+	answers       []Record
+}
+
+// buildQuery returns the wire format of a query of the name and the type.
+func buildQuery(id uint16, name string, qtype uint16, recursion bool) ([]byte, error) {
+	var flags uint16
+	if recursion {
+		flags |= flagRecursion
+	}
+	b := make([]byte, headerLen, 512)
+	binary.BigEndian.PutUint16(b[0:], id)
+	binary.BigEndian.PutUint16(b[2:], flags)
+	binary.BigEndian.PutUint16(b[4:], 1) // one question
+	b, err := appendName(b, name)
+	if err != nil {
+		return nil, err
+	}
+	b = appendUint16(b, qtype)
+	return appendUint16(b, classINET), nil
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendName(b []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if len(name) > 253 {
+		return nil, fmt.Errorf("the DNS name %q is too long", name)
+	}
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, fmt.Errorf("invalid DNS name %q", name)
+			}
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+	}
+	return append(b, 0), nil
+}
+
+var errMalformed = errors.New("malformed DNS message")
+
+// parseMessage parses the header and the answers of a DNS response.
+func parseMessage(b []byte) (*message, error) {
+	if len(b) < headerLen {
+		return nil, errMalformed
+	}
+	flags := binary.BigEndian.Uint16(b[2:])
+	if flags&flagResponse == 0 {
+		return nil, errors.New("the DNS message isn't a response")
+	}
+	msg := &message{
+		id:            binary.BigEndian.Uint16(b),
+		rcode:         int(flags & 0xf),
+		authoritative: flags&flagAuthoritative != 0,
+		truncated:     flags&flagTruncated != 0,
+	}
+	questions := int(binary.BigEndian.Uint16(b[4:]))
+	answers := int(binary.BigEndian.Uint16(b[6:]))
+
+	off := headerLen
+	for i := 0; i < questions; i++ {
+		_, n, err := readName(b, off)
+		if err != nil {
+			return nil, err
+		}
+		off = n + 4 // type and class
+	}
+	for i := 0; i < answers; i++ {
+		rec, n, err := readRecord(b, off)
+		if err != nil {
+			return nil, err
+		}
+		off = n
+		msg.answers = append(msg.answers, rec)
+	}
+	return msg, nil
+}
+
+// readName reads the possibly compressed name at the offset, it returns the offset after it.
+func readName(b []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; ; {
+		if off >= len(b) {
+			return "", 0, errMalformed
+		}
+		l := int(b[off])
+		switch {
+		case l == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.Join(labels, "."), end, nil
+		case l&0xc0 == 0xc0:
+			if off+1 >= len(b) || jumps > 32 {
+				return "", 0, errMalformed
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(b[off:]) & 0x3fff)
+			jumps++
+		default:
+			if off+1+l > len(b) {
+				return "", 0, errMalformed
+			}
+			labels = append(labels, string(b[off+1:off+1+l]))
+			off += 1 + l
+		}
+	}
+}
+
+func readRecord(b []byte, off int) (Record, int, error) {
+	name, off, err := readName(b, off)
+	if err != nil {
+		return Record{}, 0, err
+	}
+	if off+10 > len(b) {
+		return Record{}, 0, errMalformed
+	}
+	rtype := binary.BigEndian.Uint16(b[off:])
+	rec := Record{Name: name, Type: typeName(rtype), TTL: binary.BigEndian.Uint32(b[off+4:])}
+	length := int(binary.BigEndian.Uint16(b[off+8:]))
+	off += 10
+	if off+length > len(b) {
+		return Record{}, 0, errMalformed
+	}
+	data := b[off : off+length]
+
+	switch rtype {
+	case typeA, typeAAAA:
+		if len(data) != net.IPv4len && len(data) != net.IPv6len {
+			return Record{}, 0, errMalformed
+		}
+		rec.Data = net.IP(data).String()
+	case typeNS, typeCNAME, typePTR:
+		rec.Data, _, err = readName(b, off)
+	case typeMX:
+		if length < 3 {
+			return Record{}, 0, errMalformed
+		}
+		rec.Priority = binary.BigEndian.Uint16(data)
+		rec.Target, _, err = readName(b, off+2)
+		rec.Data = fmt.Sprintf("%d %s", rec.Priority, rec.Target)
+	case typeSRV:
+		if length < 7 {
+			return Record{}, 0, errMalformed
+		}
+		rec.Priority = binary.BigEndian.Uint16(data)
+		rec.Weight = binary.BigEndian.Uint16(data[2:])
+		rec.Port = binary.BigEndian.Uint16(data[4:])
+		rec.Target, _, err = readName(b, off+6)
+		rec.Data = fmt.Sprintf("%d %d %d %s", rec.Priority, rec.Weight, rec.Port, rec.Target)
+	case typeTXT:
+		var parts []string
+		for i := 0; i < len(data); {
+			l := int(data[i])
+			if i+1+l > len(data) {
+				return Record{}, 0, errMalformed
+			}
+			parts = append(parts, string(data[i+1:i+1+l]))
+			i += 1 + l
+		}
+		rec.Data = strings.Join(parts, "")
+	default:
+		rec.Data = fmt.Sprintf("%x", data)
+	}
+	if err != nil {
+		return Record{}, 0, err
+	}
+	return rec, off + length, nil
+}
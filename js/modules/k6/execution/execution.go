@@ -0,0 +1,90 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package execution implements the k6/execution module, which lets scripts control the test run.
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dop251/goja"
+
+	"go.k6.io/k6/errext"
+	"go.k6.io/k6/errext/exitcodes"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+// Execution is the k6/execution module.
+type Execution struct {
+	Metrics *Metrics `js:"metrics,bind"`
+}
+
+// New returns a new Execution module instance.
+func New() *Execution {
+	return &Execution{Metrics: &Metrics{}}
+}
+
+// AbortTest stops the whole test run, e.g. when a precondition of the test fails in setup(), and
+// makes k6 exit with the exit code, 109 by default, and the reason as the error. The code after
+// the call doesn't run and the iterations of the other VUs are interrupted, but teardown() still
+// runs unless the test is aborted in setup() or in teardown() itself.
+func (*Execution) AbortTest(ctx context.Context, reason, exitCode goja.Value) (goja.Value, error) {
+	abortErr := &errext.AbortTest{Code: exitcodes.ScriptAborted}
+	if reason != nil && !goja.IsUndefined(reason) && !goja.IsNull(reason) {
+		abortErr.Reason = reason.String()
+	}
+	if exitCode != nil && !goja.IsUndefined(exitCode) && !goja.IsNull(exitCode) {
+		code := exitCode.ToInteger()
+		if code < 0 || code > 255 {
+			return nil, fmt.Errorf("invalid exit code %d, it has to be between 0 and 255", code)
+		}
+		abortErr.Code = errext.ExitCode(code)
+	}
+
+	// the runtime is interrupted first, so the error is the abort even without an execution state
+	common.GetRuntime(ctx).Interrupt(abortErr)
+	if executionState := lib.GetExecutionState(ctx); executionState != nil {
+		executionState.AbortTest(abortErr)
+	}
+	return goja.Undefined(), nil
+}
+
+// Metrics is the execution.metrics object, with the current values of the metrics of the test run.
+type Metrics struct{}
+
+// Get returns the current aggregated value of a metric, like the "p(95)" of http_req_duration,
+// for the scripts that adapt to the load of the system, or null if the metric doesn't have any
+// values yet. The aggregations are the ones of the thresholds, and the default one is the count
+// of the counters, the value of the gauges, the rate of the rates and the avg of the trends. The
+// values are aggregated periodically, so they lag a little behind the samples of the VUs.
+func (*Metrics) Get(ctx context.Context, metric, aggregation string) (interface{}, error) {
+	executionState := lib.GetExecutionState(ctx)
+	if executionState == nil {
+		return nil, errors.New("the values of the metrics are only available during the test run")
+	}
+	value, ok, err := executionState.GetMetricValue(metric, aggregation)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return value, nil
+}
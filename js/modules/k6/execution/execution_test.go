@@ -0,0 +1,116 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package execution
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/errext"
+	"go.k6.io/k6/errext/exitcodes"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+func TestAbortTest(t *testing.T) {
+	t.Parallel()
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	et, err := lib.NewExecutionTuple(nil, nil)
+	require.NoError(t, err)
+	executionState := lib.NewExecutionState(lib.Options{}, et, 0, 0)
+	aborted := false
+	executionState.SetAbortRunFunc(func() { aborted = true })
+	ctx := lib.WithExecutionState(common.WithRuntime(context.Background(), rt), executionState)
+	require.NoError(t, rt.Set("exec", common.Bind(rt, New(), &ctx)))
+
+	_, err = rt.RunString(`exec.abortTest("", 256)`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid exit code 256, it has to be between 0 and 255")
+	assert.False(t, aborted)
+
+	_, err = rt.RunString(`
+	try {
+		exec.abortTest("the precondition failed", 42);
+	} catch (e) {}
+	throw new Error("the script wasn't interrupted");`)
+	var interrupted *goja.InterruptedError
+	require.True(t, errors.As(err, &interrupted), "%v", err)
+	abortErr, ok := interrupted.Value().(*errext.AbortTest)
+	require.True(t, ok)
+	assert.Equal(t, "the test was aborted by the script: the precondition failed", abortErr.Error())
+	assert.Equal(t, errext.ExitCode(42), abortErr.ExitCode())
+	assert.True(t, aborted)
+	assert.Equal(t, abortErr, executionState.GetAbortError())
+	assert.Equal(t, lib.AbortReasonScript, executionState.GetAbortReason())
+
+	// only the first abort is kept
+	rt.ClearInterrupt()
+	_, err = rt.RunString(`exec.abortTest()`)
+	require.True(t, errors.As(err, &interrupted))
+	assert.Equal(t, exitcodes.ScriptAborted, interrupted.Value().(*errext.AbortTest).ExitCode())
+	assert.Equal(t, abortErr, executionState.GetAbortError())
+}
+
+func TestMetricsGet(t *testing.T) {
+	t.Parallel()
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	et, err := lib.NewExecutionTuple(nil, nil)
+	require.NoError(t, err)
+	executionState := lib.NewExecutionState(lib.Options{}, et, 0, 0)
+	executionState.SetMetricValueFunc(func(metric, aggregation string) (float64, bool, error) {
+		switch {
+		case metric == "http_req_duration" && aggregation == "p(95)":
+			return 250, true, nil
+		case metric == "http_req_duration":
+			return 0, false, errors.New("unknown aggregation")
+		default:
+			return 0, false, nil
+		}
+	})
+	ctx := common.WithRuntime(context.Background(), rt)
+	require.NoError(t, rt.Set("exec", common.Bind(rt, New(), &ctx)))
+
+	_, err = rt.RunString(`exec.metrics.get("http_req_duration", "p(95)")`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the values of the metrics are only available during the test run")
+
+	ctx = lib.WithExecutionState(ctx, executionState)
+	_, err = rt.RunString(`
+	var value = exec.metrics.get("http_req_duration", "p(95)");
+	if (value !== 250) {
+		throw new Error("wrong value: " + value);
+	}
+	if (exec.metrics.get("my_metric") !== null) {
+		throw new Error("the metric without values should be null");
+	}`)
+	require.NoError(t, err)
+
+	_, err = rt.RunString(`exec.metrics.get("http_req_duration", "p(x)")`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown aggregation")
+}
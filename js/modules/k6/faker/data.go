@@ -0,0 +1,97 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package faker
+
+//nolint:gochecknoglobals
+var (
+	firstNames = []string{
+		"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda", "William",
+		"Elizabeth", "David", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah",
+		"Charles", "Karen", "Daniel", "Nancy", "Matthew", "Lisa", "Anthony", "Betty", "Mark",
+		"Margaret", "Paul", "Sandra", "Steven", "Ashley", "Andrew", "Emily", "Kenneth", "Donna",
+		"Joshua", "Michelle", "Kevin", "Carol", "Brian", "Amanda", "George", "Melissa", "Timothy",
+		"Deborah", "Ronald", "Stephanie", "Jason", "Rebecca", "Ryan", "Laura", "Jacob", "Sharon",
+		"Gary", "Cynthia", "Nicholas", "Kathleen", "Eric", "Amy", "Jonathan", "Angela", "Stephen",
+		"Anna", "Larry", "Ruth", "Justin", "Brenda", "Scott", "Pamela", "Brandon", "Nicole",
+	}
+
+	lastNames = []string{
+		"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez",
+		"Martinez", "Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Thomas", "Taylor",
+		"Moore", "Jackson", "Martin", "Lee", "Perez", "Thompson", "White", "Harris", "Sanchez",
+		"Clark", "Ramirez", "Lewis", "Robinson", "Walker", "Young", "Allen", "King", "Wright",
+		"Scott", "Torres", "Nguyen", "Hill", "Flores", "Green", "Adams", "Nelson", "Baker", "Hall",
+		"Rivera", "Campbell", "Mitchell", "Carter", "Roberts", "Gomez", "Phillips", "Evans",
+		"Turner", "Diaz", "Parker", "Cruz", "Edwards", "Collins", "Reyes", "Stewart", "Morris",
+	}
+
+	// the second level domains that are reserved for documentation by RFC 2606
+	emailDomains = []string{"example.com", "example.net", "example.org"}
+
+	companySuffixes = []string{"Inc", "LLC", "Group", "Ltd", "and Sons", "Partners", "Corp", "Labs"}
+
+	streetNames = []string{
+		"Oak", "Maple", "Cedar", "Pine", "Elm", "Washington", "Lake", "Hill", "Park", "Main",
+		"Church", "Highland", "Sunset", "Jackson", "Lincoln", "Madison", "Franklin", "River",
+		"Spring", "Willow", "Meadow", "Forest", "Center", "Mill", "Chestnut", "Walnut", "Ridge",
+	}
+
+	streetSuffixes = []string{
+		"Street", "Avenue", "Road", "Lane", "Drive", "Court", "Boulevard", "Way", "Place", "Terrace",
+	}
+
+	cities = []string{
+		"Springfield", "Riverside", "Franklin", "Greenville", "Bristol", "Clinton", "Fairview",
+		"Salem", "Madison", "Georgetown", "Arlington", "Ashland", "Burlington", "Manchester",
+		"Milton", "Newport", "Oxford", "Dayton", "Jackson", "Kingston", "Lexington", "Marion",
+		"Mount Vernon", "Oakland", "Centerville", "Winchester", "Dover", "Hudson", "Auburn",
+	}
+
+	states = []string{
+		"Alabama", "Alaska", "Arizona", "Arkansas", "California", "Colorado", "Connecticut",
+		"Delaware", "Florida", "Georgia", "Hawaii", "Idaho", "Illinois", "Indiana", "Iowa", "Kansas",
+		"Kentucky", "Louisiana", "Maine", "Maryland", "Massachusetts", "Michigan", "Minnesota",
+		"Mississippi", "Missouri", "Montana", "Nebraska", "Nevada", "New Hampshire", "New Jersey",
+		"New Mexico", "New York", "North Carolina", "North Dakota", "Ohio", "Oklahoma", "Oregon",
+		"Pennsylvania", "Rhode Island", "South Carolina", "South Dakota", "Tennessee", "Texas",
+		"Utah", "Vermont", "Virginia", "Washington", "West Virginia", "Wisconsin", "Wyoming",
+	}
+
+	countries = []string{
+		"Argentina", "Australia", "Austria", "Belgium", "Brazil", "Bulgaria", "Canada", "Chile",
+		"Colombia", "Croatia", "Czechia", "Denmark", "Egypt", "Estonia", "Finland", "France",
+		"Germany", "Greece", "Hungary", "Iceland", "India", "Indonesia", "Ireland", "Israel",
+		"Italy", "Japan", "Kenya", "Latvia", "Lithuania", "Mexico", "Morocco", "Netherlands",
+		"New Zealand", "Nigeria", "Norway", "Peru", "Poland", "Portugal", "Romania", "Serbia",
+		"Singapore", "Slovakia", "Slovenia", "South Africa", "South Korea", "Spain", "Sweden",
+		"Switzerland", "Thailand", "Turkey", "Ukraine", "United Kingdom", "United States", "Vietnam",
+	}
+
+	loremWords = []string{
+		"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed", "do",
+		"eiusmod", "tempor", "incididunt", "ut", "labore", "et", "dolore", "magna", "aliqua", "enim",
+		"ad", "minim", "veniam", "quis", "nostrud", "exercitation", "ullamco", "laboris", "nisi",
+		"aliquip", "ex", "ea", "commodo", "consequat", "duis", "aute", "irure", "in",
+		"reprehenderit", "voluptate", "velit", "esse", "cillum", "eu", "fugiat", "nulla",
+		"pariatur", "excepteur", "sint", "occaecat", "cupidatat", "non", "proident", "sunt",
+		"culpa", "qui", "officia", "deserunt", "mollit", "anim", "id", "est", "laborum",
+	}
+)
@@ -0,0 +1,351 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package faker implements the k6/faker module, which generates realistic looking test data, like
+// names, emails, addresses and lorem ipsum text.
+package faker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+// RootModule is the global k6/faker module, it returns a Faker for each VU.
+type RootModule struct{}
+
+// New returns a new faker module.
+func New() *RootModule {
+	return &RootModule{}
+}
+
+// NewModuleInstancePerVU returns a Faker with a random seed for each VU.
+func (*RootModule) NewModuleInstancePerVU() interface{} {
+	return newFaker(time.Now().UnixNano(), false)
+}
+
+// Faker generates the data with a pseudo-random generator. When it's seeded with seed(), the
+// generator is reseeded for each iteration, with the seed, the VU ID and the iteration, so a VU
+// generates the same data in the same iteration in every test run.
+type Faker struct {
+	rng *rand.Rand
+
+	seed         int64
+	perIteration bool
+	vu           uint64
+	iteration    int64
+}
+
+func newFaker(seed int64, perIteration bool) *Faker {
+	return &Faker{rng: rand.New(rand.NewSource(seed)), seed: seed, perIteration: perIteration} //nolint:gosec
+}
+
+// XFaker is the Faker constructor (e.g. `new faker.Faker(seed)`), the returned generator has a
+// fixed seed, so it generates the same sequence of data regardless of the VU and the iteration.
+func (*Faker) XFaker(ctxPtr *context.Context, seed int64) interface{} {
+	return common.Bind(common.GetRuntime(*ctxPtr), newFaker(seed, false), ctxPtr)
+}
+
+// Seed makes the data of the VU deterministic, it's reseeded in each iteration with the seed.
+func (f *Faker) Seed(ctx context.Context, seed int64) {
+	f.seed = seed
+	f.perIteration = true
+	f.vu, f.iteration = iterationOf(ctx)
+	f.reseed()
+}
+
+func iterationOf(ctx context.Context) (uint64, int64) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return 0, -1
+	}
+	return state.VUIDGlobal, state.Iteration
+}
+
+func (f *Faker) reseed() {
+	var b [24]byte
+	binary.LittleEndian.PutUint64(b[:8], uint64(f.seed))
+	binary.LittleEndian.PutUint64(b[8:16], f.vu)
+	binary.LittleEndian.PutUint64(b[16:], uint64(f.iteration))
+	h := fnv.New64a()
+	_, _ = h.Write(b[:])
+	f.rng.Seed(int64(h.Sum64()))
+}
+
+// random returns the generator, reseeded if the faker is seeded and it's a new iteration.
+func (f *Faker) random(ctx context.Context) *rand.Rand {
+	if f.perIteration {
+		if vu, iteration := iterationOf(ctx); vu != f.vu || iteration != f.iteration {
+			f.vu, f.iteration = vu, iteration
+			f.reseed()
+		}
+	}
+	return f.rng
+}
+
+func pick(r *rand.Rand, values []string) string {
+	return values[r.Intn(len(values))]
+}
+
+// FirstName returns a first name.
+func (f *Faker) FirstName(ctx context.Context) string {
+	return pick(f.random(ctx), firstNames)
+}
+
+// LastName returns a last name.
+func (f *Faker) LastName(ctx context.Context) string {
+	return pick(f.random(ctx), lastNames)
+}
+
+// Name returns a first and a last name.
+func (f *Faker) Name(ctx context.Context) string {
+	r := f.random(ctx)
+	return pick(r, firstNames) + " " + pick(r, lastNames)
+}
+
+// UserName returns a user name made of a name and a number.
+func (f *Faker) UserName(ctx context.Context) string {
+	r := f.random(ctx)
+	separator := pick(r, []string{".", "_", ""})
+	return strings.ToLower(pick(r, firstNames)+separator+pick(r, lastNames)) + strconv.Itoa(r.Intn(100))
+}
+
+// Email returns an email address, at one of the domains that are reserved for examples by default,
+// or at the domain if one is passed.
+func (f *Faker) Email(ctx context.Context, domain ...string) string {
+	user := f.UserName(ctx)
+	if len(domain) > 0 && domain[0] != "" {
+		return user + "@" + domain[0]
+	}
+	return user + "@" + pick(f.random(ctx), emailDomains)
+}
+
+// Phone returns a phone number in the (###) ###-#### format.
+func (f *Faker) Phone(ctx context.Context) string {
+	return f.Pattern(ctx, "(###) ###-####")
+}
+
+// Company returns the name of a company.
+func (f *Faker) Company(ctx context.Context) string {
+	r := f.random(ctx)
+	return pick(r, lastNames) + " " + pick(r, companySuffixes)
+}
+
+// StreetAddress returns a house number and a street.
+func (f *Faker) StreetAddress(ctx context.Context) string {
+	r := f.random(ctx)
+	return strconv.Itoa(1+r.Intn(9999)) + " " + pick(r, streetNames) + " " + pick(r, streetSuffixes)
+}
+
+// City returns the name of a city.
+func (f *Faker) City(ctx context.Context) string {
+	return pick(f.random(ctx), cities)
+}
+
+// State returns the name of a US state.
+func (f *Faker) State(ctx context.Context) string {
+	return pick(f.random(ctx), states)
+}
+
+// ZipCode returns a five digit zip code.
+func (f *Faker) ZipCode(ctx context.Context) string {
+	return f.Pattern(ctx, "#####")
+}
+
+// Country returns the name of a country.
+func (f *Faker) Country(ctx context.Context) string {
+	return pick(f.random(ctx), countries)
+}
+
+// Address is a postal address.
+type Address struct {
+	Street  string `js:"street"`
+	City    string `js:"city"`
+	State   string `js:"state"`
+	ZipCode string `js:"zipCode"`
+	Country string `js:"country"`
+}
+
+// Address returns a postal address, its fields are generated as with the functions of the same names.
+func (f *Faker) Address(ctx context.Context) Address {
+	return Address{
+		Street:  f.StreetAddress(ctx),
+		City:    f.City(ctx),
+		State:   f.State(ctx),
+		ZipCode: f.ZipCode(ctx),
+		Country: f.Country(ctx),
+	}
+}
+
+// UUID returns a random (version 4) UUID. Unlike the UUIDs of crypto.randomUUID() these are
+// deterministic if the faker has a seed, so they may not be unique across the VUs.
+func (f *Faker) UUID(ctx context.Context) string {
+	var b [16]byte
+	_, _ = f.random(ctx).Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// IPv4 returns an IPv4 address.
+func (f *Faker) IPv4(ctx context.Context) string {
+	r := f.random(ctx)
+	return fmt.Sprintf("%d.%d.%d.%d", 1+r.Intn(254), r.Intn(256), r.Intn(256), 1+r.Intn(254))
+}
+
+// URL returns an HTTPS URL at one of the example domains.
+func (f *Faker) URL(ctx context.Context) string {
+	r := f.random(ctx)
+	return "https://" + pick(r, emailDomains) + "/" + pick(r, loremWords) + "/" + pick(r, loremWords)
+}
+
+// Word returns a lorem ipsum word.
+func (f *Faker) Word(ctx context.Context) string {
+	return pick(f.random(ctx), loremWords)
+}
+
+// Words returns the number of lorem ipsum words.
+func (f *Faker) Words(ctx context.Context, count int) []string {
+	r := f.random(ctx)
+	words := make([]string, count)
+	for i := range words {
+		words[i] = pick(r, loremWords)
+	}
+	return words
+}
+
+// Sentence returns a lorem ipsum sentence with the number of words, between 4 and 12 by default.
+func (f *Faker) Sentence(ctx context.Context, words ...int) string {
+	count := 4 + f.random(ctx).Intn(9)
+	if len(words) > 0 {
+		count = words[0]
+	}
+	if count <= 0 {
+		return ""
+	}
+	s := strings.Join(f.Words(ctx, count), " ")
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
+
+// Paragraph returns a lorem ipsum paragraph with the number of sentences, between 3 and 6 by default.
+func (f *Faker) Paragraph(ctx context.Context, sentences ...int) string {
+	count := 3 + f.random(ctx).Intn(4)
+	if len(sentences) > 0 {
+		count = sentences[0]
+	}
+	result := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		result = append(result, f.Sentence(ctx))
+	}
+	return strings.Join(result, " ")
+}
+
+// Integer returns an integer between min and max, both of them included.
+func (f *Faker) Integer(ctx context.Context, min, max int64) (int64, error) {
+	if min > max {
+		return 0, fmt.Errorf("the minimum %d is greater than the maximum %d", min, max)
+	}
+	return min + f.random(ctx).Int63n(max-min+1), nil
+}
+
+// Float returns a number between min and max, with the number of decimals if it's passed.
+func (f *Faker) Float(ctx context.Context, min, max float64, decimals ...int) (float64, error) {
+	if min > max {
+		return 0, fmt.Errorf("the minimum %v is greater than the maximum %v", min, max)
+	}
+	v := min + f.random(ctx).Float64()*(max-min)
+	if len(decimals) > 0 {
+		p := math.Pow10(decimals[0])
+		v = math.Min(math.Max(math.Round(v*p)/p, min), max)
+	}
+	return v, nil
+}
+
+// Boolean returns true or false.
+func (f *Faker) Boolean(ctx context.Context) bool {
+	return f.random(ctx).Intn(2) == 1
+}
+
+// Pick returns one of the elements of the array.
+func (f *Faker) Pick(ctx context.Context, array goja.Value) (goja.Value, error) {
+	obj, ok := array.(*goja.Object)
+	if !ok || obj.ClassName() != "Array" {
+		return nil, errors.New("pick() requires an array")
+	}
+	length := obj.Get("length").ToInteger()
+	if length == 0 {
+		return goja.Undefined(), nil
+	}
+	return obj.Get(strconv.FormatInt(f.random(ctx).Int63n(length), 10)), nil
+}
+
+// Date returns a date between the from and to dates, which are RFC 3339 dates and times or
+// YYYY-MM-DD dates, as an RFC 3339 string in UTC.
+func (f *Faker) Date(ctx context.Context, from, to string) (string, error) {
+	start, err := time.Parse(time.RFC3339, normalizeDate(from))
+	if err != nil {
+		return "", fmt.Errorf("invalid from date %q: %w", from, err)
+	}
+	end, err := time.Parse(time.RFC3339, normalizeDate(to))
+	if err != nil {
+		return "", fmt.Errorf("invalid to date %q: %w", to, err)
+	}
+	if end.Before(start) {
+		return "", fmt.Errorf("the date %s is before %s", to, from)
+	}
+	offset := time.Duration(f.random(ctx).Int63n(int64(end.Sub(start)) + 1))
+	return start.Add(offset).UTC().Format(time.RFC3339), nil
+}
+
+func normalizeDate(date string) string {
+	if len(date) == len("2006-01-02") {
+		return date + "T00:00:00Z"
+	}
+	return date
+}
+
+// Pattern replaces the # characters of the pattern with digits and the ? characters with letters.
+func (f *Faker) Pattern(ctx context.Context, pattern string) string {
+	r := f.random(ctx)
+	var b strings.Builder
+	for _, c := range pattern {
+		switch c {
+		case '#':
+			b.WriteByte(byte('0' + r.Intn(10)))
+		case '?':
+			b.WriteByte(byte('a' + r.Intn(26)))
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
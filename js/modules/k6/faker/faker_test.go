@@ -0,0 +1,129 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package faker
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+func makeRuntime() (*goja.Runtime, *lib.State) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	state := &lib.State{VUIDGlobal: 1, Iteration: 0}
+	ctx := common.WithRuntime(context.Background(), rt)
+	ctx = lib.WithState(ctx, state)
+	rt.Set("faker", common.Bind(rt, New().NewModuleInstancePerVU(), &ctx))
+	return rt, state
+}
+
+func TestFaker(t *testing.T) {
+	t.Parallel()
+	rt, _ := makeRuntime()
+
+	testCases := map[string]string{
+		`faker.firstName()`:                      `^[A-Z][a-z]+$`,
+		`faker.name()`:                           `^[A-Z][a-z]+ [A-Z][a-z]+$`,
+		`faker.userName()`:                       `^[a-z]+[._]?[a-z]+\d{1,2}$`,
+		`faker.email()`:                          `^[a-z._]+\d{1,2}@example\.(com|net|org)$`,
+		`faker.email("k6.io")`:                   `@k6\.io$`,
+		`faker.phone()`:                          `^\(\d{3}\) \d{3}-\d{4}$`,
+		`faker.company()`:                        `^[A-Z][a-z]+ [A-Za-z ]+$`,
+		`faker.streetAddress()`:                  `^\d{1,4} [A-Z][a-z]+ [A-Z][a-z]+$`,
+		`faker.zipCode()`:                        `^\d{5}$`,
+		`JSON.stringify(faker.address())`:        `^\{"street":".+","city":".+","state":".+","zipCode":"\d{5}","country":".+"\}$`,
+		`faker.uuid()`:                           `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+		`faker.ipv4()`:                           `^\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}$`,
+		`faker.url()`:                            `^https://example\.(com|net|org)/[a-z]+/[a-z]+$`,
+		`faker.words(3).join(" ")`:               `^[a-z]+ [a-z]+ [a-z]+$`,
+		`faker.sentence(2)`:                      `^[A-Z][a-z]* [a-z]+\.$`,
+		`faker.paragraph(2)`:                     `^[A-Z][a-z ]+\. [A-Z][a-z ]+\.$`,
+		`String(faker.integer(5, 5))`:            `^5$`,
+		`String(faker.float(1, 2, 2))`:           `^[12](\.\d{1,2})?$`,
+		`String(faker.boolean())`:                `^(true|false)$`,
+		`faker.pick(["a"])`:                      `^a$`,
+		`faker.date("2021-01-01", "2021-01-02")`: `^2021-01-0[12]T\d\d:\d\d:\d\dZ$`,
+		`faker.pattern("??-##")`:                 `^[a-z]{2}-\d{2}$`,
+	}
+	for code, pattern := range testCases {
+		v, err := rt.RunString(code)
+		require.NoError(t, err, code)
+		assert.Regexp(t, regexp.MustCompile(pattern), v.String(), code)
+	}
+
+	errors := map[string]string{
+		`faker.integer(2, 1)`:                    `the minimum 2 is greater than the maximum 1`,
+		`faker.pick("abc")`:                      `pick() requires an array`,
+		`faker.date("2021-02-01", "2021-01-01")`: `the date 2021-01-01 is before 2021-02-01`,
+		`faker.date("yesterday", "2021-01-01")`:  `invalid from date "yesterday"`,
+	}
+	for code, expected := range errors {
+		_, err := rt.RunString(code)
+		require.Error(t, err, code)
+		assert.Contains(t, err.Error(), expected, code)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	t.Parallel()
+	const generate = `[faker.name(), faker.uuid(), faker.integer(0, 1000000)].join()`
+
+	run := func(vu uint64, iterations ...int64) []string {
+		rt, state := makeRuntime()
+		state.VUIDGlobal = vu
+		_, err := rt.RunString(`faker.seed(42)`)
+		require.NoError(t, err)
+		result := make([]string, 0, len(iterations))
+		for _, iteration := range iterations {
+			state.Iteration = iteration
+			v, err := rt.RunString(generate)
+			require.NoError(t, err)
+			result = append(result, v.String())
+		}
+		return result
+	}
+
+	first, second := run(1, 0, 1, 0), run(1, 0, 1)
+	assert.Equal(t, first[:2], second)
+	// the data of an iteration doesn't depend on the data that was generated before it
+	assert.Equal(t, first[0], first[2])
+	assert.NotEqual(t, first[0], first[1])
+	assert.NotEqual(t, first[:2], run(2, 0, 1))
+
+	t.Run("Constructor", func(t *testing.T) {
+		t.Parallel()
+		rt, state := makeRuntime()
+		v, err := rt.RunString(`var f = new faker.Faker(7); f.name() + "," + f.uuid() + "," + f.integer(0, 1000000)`)
+		require.NoError(t, err)
+		state.Iteration = 1
+		w, err := rt.RunString(`f = new faker.Faker(7); f.name() + "," + f.uuid() + "," + f.integer(0, 1000000)`)
+		require.NoError(t, err)
+		assert.Equal(t, v.String(), w.String())
+	})
+}
@@ -0,0 +1,135 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package files implements the k6/files module, which allows scripts to write
+// files in the directory configured with the filesDir option.
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+// ErrFilesInInitContext is returned when files are written in the init context.
+var ErrFilesInInitContext = common.NewInitContextError("Writing files in the init context is not supported")
+
+var errFilesDirNotSet = errors.New("writing files is disabled, set the files directory with --files-dir")
+
+// Files is the k6/files module. A single instance is shared by all VUs,
+// so that their writes to the same file don't get interleaved.
+type Files struct {
+	mu sync.Mutex
+}
+
+// New returns a new Files module instance.
+func New() *Files {
+	return &Files{}
+}
+
+// AppendLine appends the line, followed by a new line, to the file with the
+// given name in the files directory.
+func (f *Files) AppendLine(ctx context.Context, name, line string) {
+	f.write(ctx, name, []byte(line+"\n"), os.O_APPEND)
+}
+
+// Append appends the data, which can be a string or an ArrayBuffer, to the
+// file with the given name in the files directory.
+func (f *Files) Append(ctx context.Context, name string, data interface{}) {
+	f.write(ctx, name, toBytes(ctx, data), os.O_APPEND)
+}
+
+// Write writes the data, which can be a string or an ArrayBuffer, to the file
+// with the given name in the files directory, replacing it if it exists.
+func (f *Files) Write(ctx context.Context, name string, data interface{}) {
+	f.write(ctx, name, toBytes(ctx, data), os.O_TRUNC)
+}
+
+func toBytes(ctx context.Context, data interface{}) []byte {
+	b, err := common.ToBytes(data)
+	if err != nil {
+		common.Throw(common.GetRuntime(ctx), err)
+	}
+	return b
+}
+
+func (f *Files) write(ctx context.Context, name string, data []byte, flag int) {
+	if err := f.writeFile(ctx, name, data, flag); err != nil {
+		common.Throw(common.GetRuntime(ctx), err)
+	}
+}
+
+func (f *Files) writeFile(ctx context.Context, name string, data []byte, flag int) error {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return ErrFilesInInitContext
+	}
+	if !state.Options.FilesDir.Valid || state.Options.FilesDir.String == "" {
+		return errFilesDirNotSet
+	}
+	path, err := resolvePath(state.Options.FilesDir.String, name)
+	if err != nil {
+		return err
+	}
+
+	if limiter := state.FilesLimit; limiter != nil {
+		for remaining := len(data); remaining > 0; remaining -= limiter.Burst() {
+			n := remaining
+			if n > limiter.Burst() {
+				n = limiter.Burst()
+			}
+			if err = limiter.WaitN(ctx, n); err != nil {
+				return err
+			}
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err = os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|flag, 0o640) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// resolvePath returns the path of the file with the given name in dir. Names
+// are always relative to dir, so that they can't point outside of it.
+func resolvePath(dir, name string) (string, error) {
+	cleaned := filepath.Clean(string(filepath.Separator) + name)
+	if strings.Trim(cleaned, string(filepath.Separator)) == "" {
+		return "", fmt.Errorf("invalid file name '%s'", name)
+	}
+	return filepath.Join(dir, cleaned), nil
+}
@@ -0,0 +1,116 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+func newRuntime(t *testing.T, state *lib.State) *goja.Runtime {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	if state != nil {
+		ctx = lib.WithState(ctx, state)
+	}
+	require.NoError(t, rt.Set("files", common.Bind(rt, New(), &ctx)))
+	return rt
+}
+
+func TestFilesWrite(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	rt := newRuntime(t, &lib.State{Options: lib.Options{FilesDir: null.StringFrom(dir)}})
+	_, err := rt.RunString(`
+		files.appendLine("ids.txt", "1");
+		files.appendLine("/ids.txt", "2");
+		files.append("sub/dir/blob.bin", new Uint8Array([104, 105]).buffer);
+		files.write("excerpt.json", '{"a":1}');
+		files.write("excerpt.json", '{"b":2}');
+		files.appendLine("../../escaped.txt", "x");
+	`)
+	require.NoError(t, err)
+
+	for name, expected := range map[string]string{
+		"ids.txt":          "1\n2\n",
+		"sub/dir/blob.bin": "hi",
+		"excerpt.json":     `{"b":2}`,
+		"escaped.txt":      "x\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name)) //nolint:gosec
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, string(data), name)
+	}
+}
+
+func TestFilesErrors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("init context", func(t *testing.T) {
+		t.Parallel()
+		_, err := newRuntime(t, nil).RunString(`files.appendLine("a.txt", "a")`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Writing files in the init context is not supported")
+	})
+	t.Run("no directory", func(t *testing.T) {
+		t.Parallel()
+		_, err := newRuntime(t, &lib.State{}).RunString(`files.appendLine("a.txt", "a")`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), errFilesDirNotSet.Error())
+	})
+	t.Run("invalid name", func(t *testing.T) {
+		t.Parallel()
+		state := &lib.State{Options: lib.Options{FilesDir: null.StringFrom(t.TempDir())}}
+		_, err := newRuntime(t, state).RunString(`files.write("..", "a")`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid file name '..'")
+	})
+}
+
+func TestFilesRateLimit(t *testing.T) {
+	t.Parallel()
+
+	limiter := rate.NewLimiter(rate.Limit(100), 100)
+	limiter.AllowN(time.Now(), 100)
+	rt := newRuntime(t, &lib.State{
+		Options:    lib.Options{FilesDir: null.StringFrom(t.TempDir())},
+		FilesLimit: limiter,
+	})
+
+	start := time.Now()
+	_, err := rt.RunString(`files.write("a.txt", "a".repeat(50))`)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
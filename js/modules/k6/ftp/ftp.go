@@ -0,0 +1,513 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package ftp implements the k6/net/ftp module, an FTP and FTPS client with the passive and the
+// active transfer modes, and the throughput metrics of the transfers.
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+var (
+	errConnectInInitContext = common.NewInitContextError("connecting to an FTP server in the init context is not supported")
+	errNotConnected         = errors.New("the FTP client isn't connected, call connect() first")
+)
+
+const defaultTimeout = 30 * time.Second
+
+// FTP is the k6/net/ftp module.
+type FTP struct{}
+
+// New returns a new FTP module instance.
+func New() *FTP {
+	return &FTP{}
+}
+
+// XClient represents the Client constructor (e.g. `new ftp.Client()`) and creates a new FTP
+// client, which can connect to a server and transfer files.
+func (*FTP) XClient(ctxPtr *context.Context) interface{} {
+	rt := common.GetRuntime(*ctxPtr)
+	return common.Bind(rt, &Client{}, ctxPtr)
+}
+
+// Client is an FTP client that can be used by the goja VM.
+type Client struct {
+	conn    net.Conn
+	text    *textproto.Conn
+	host    string
+	active  bool
+	timeout time.Duration
+	// the config of the TLS connections, nil without TLS
+	tlsConfig *tls.Config
+	tags      map[string]string
+}
+
+// TransferResult is the result of an upload or a download.
+type TransferResult struct {
+	Bytes    int     `js:"bytes"`
+	Duration float64 `js:"duration"`
+	// The bytes per second of the transfer.
+	Throughput float64 `js:"throughput"`
+}
+
+// Connect connects and logs in to the server at the address, host:port with the port 21 by
+// default, or 990 with the implicit TLS. The params are:
+//   - username and password: anonymous by default
+//   - tls: none, the default, explicit (AUTH TLS) or implicit
+//   - mode: passive, the default, or active
+//   - timeout: the timeout of the commands and the transfers, 30s by default
+//   - tags: the tags of the metrics of the client
+//
+//nolint:funlen,gocognit
+func (c *Client) Connect(ctxPtr *context.Context, addr string, params map[string]interface{}) (bool, error) {
+	state := lib.GetState(*ctxPtr)
+	if state == nil {
+		return false, errConnectInInitContext
+	}
+
+	username, password, security := "anonymous", "anonymous", "none"
+	c.timeout, c.active = defaultTimeout, false
+	for k, v := range params {
+		var (
+			ok  bool
+			err error
+		)
+		switch k {
+		case "username":
+			username, ok = v.(string)
+		case "password":
+			password, ok = v.(string)
+		case "tls":
+			security, ok = v.(string)
+			ok = ok && (security == "none" || security == "explicit" || security == "implicit")
+		case "mode":
+			var mode string
+			mode, ok = v.(string)
+			ok = ok && (mode == "passive" || mode == "active")
+			c.active = mode == "active"
+		case "timeout":
+			c.timeout, err = types.GetDurationValue(v)
+			ok = err == nil && c.timeout > 0
+		case "tags":
+			var raw map[string]interface{}
+			raw, ok = v.(map[string]interface{})
+			c.tags = make(map[string]string, len(raw))
+			for tk, tv := range raw {
+				c.tags[tk] = fmt.Sprint(tv)
+			}
+		default:
+			return false, fmt.Errorf("unknown connect param: %q", k)
+		}
+		if !ok {
+			return false, fmt.Errorf("invalid FTP %s value: %v", k, v)
+		}
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		port := "21"
+		if security == "implicit" {
+			port = "990"
+		}
+		addr = net.JoinHostPort(addr, port)
+	}
+	c.host, _, _ = net.SplitHostPort(addr)
+	if security != "none" {
+		c.tlsConfig = state.TLSConfig.Clone()
+		if c.tlsConfig == nil {
+			c.tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		c.tlsConfig.ServerName = c.host
+		// the servers usually require the data connections to resume the TLS session of the
+		// control connection
+		c.tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+	}
+
+	ctx, cancel := context.WithTimeout(*ctxPtr, c.timeout)
+	defer cancel()
+	conn, err := state.Dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return false, err
+	}
+	c.setConn(conn)
+	if err = c.login(security, username, password); err != nil {
+		_ = conn.Close()
+		c.conn, c.text = nil, nil
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *Client) setConn(conn net.Conn) {
+	c.conn, c.text = conn, textproto.NewConn(conn)
+}
+
+func (c *Client) login(security, username, password string) error {
+	c.deadline()
+	if security == "implicit" {
+		tlsConn := tls.Client(c.conn, c.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		c.setConn(tlsConn)
+	}
+	if _, _, err := c.text.ReadResponse(220); err != nil {
+		return err
+	}
+	if security == "explicit" {
+		if _, err := c.cmd(234, "AUTH TLS"); err != nil {
+			return err
+		}
+		tlsConn := tls.Client(c.conn, c.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		c.setConn(tlsConn)
+	}
+	if security != "none" {
+		if _, err := c.cmd(200, "PBSZ 0"); err != nil {
+			return err
+		}
+		if _, err := c.cmd(200, "PROT P"); err != nil {
+			return err
+		}
+	}
+
+	code, msg, err := c.cmdCode("USER %s", username)
+	switch {
+	case err != nil:
+		return err
+	case code == 331:
+		if _, err = c.cmd(230, "PASS %s", password); err != nil {
+			return err
+		}
+	case code != 230:
+		return &textproto.Error{Code: code, Msg: msg}
+	}
+	_, err = c.cmd(200, "TYPE I")
+	return err
+}
+
+// deadline sets the deadline of the next command of the control connection.
+func (c *Client) deadline() {
+	_ = c.conn.SetDeadline(time.Now().Add(c.timeout))
+}
+
+// cmdCode sends the command and returns the code and the message of the response.
+func (c *Client) cmdCode(format string, args ...interface{}) (int, string, error) {
+	c.deadline()
+	if _, err := c.text.Cmd(format, args...); err != nil {
+		return 0, "", err
+	}
+	return c.text.ReadResponse(0)
+}
+
+// cmd sends the command and returns the message of the response, which has to have the code.
+func (c *Client) cmd(expectCode int, format string, args ...interface{}) (string, error) {
+	c.deadline()
+	if _, err := c.text.Cmd(format, args...); err != nil {
+		return "", err
+	}
+	_, msg, err := c.text.ReadResponse(expectCode)
+	return msg, err
+}
+
+// transfer runs the command of a transfer on a new data connection, the transfer function
+// reads or writes the data.
+func (c *Client) transfer(
+	ctx context.Context, state *lib.State, command string, transfer func(net.Conn) error,
+) error {
+	if c.conn == nil {
+		return errNotConnected
+	}
+	var (
+		data net.Conn
+		err  error
+	)
+	if c.active {
+		data, err = c.activeConn(command)
+	} else {
+		data, err = c.passiveConn(ctx, state, command)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = data.Close() }()
+	_ = data.SetDeadline(time.Now().Add(c.timeout))
+	if c.tlsConfig != nil {
+		tlsConn := tls.Client(data, c.tlsConfig)
+		if err = tlsConn.Handshake(); err != nil {
+			return err
+		}
+		data = tlsConn
+	}
+	if err = transfer(data); err != nil {
+		return err
+	}
+	if err = data.Close(); err != nil {
+		return err
+	}
+	c.deadline()
+	_, _, err = c.text.ReadResponse(226)
+	return err
+}
+
+// passiveConn connects to the port of the EPSV or PASV response, on the host of the control
+// connection, and sends the command.
+func (c *Client) passiveConn(ctx context.Context, state *lib.State, command string) (net.Conn, error) {
+	var port int
+	msg, err := c.cmd(229, "EPSV")
+	if err == nil {
+		// Entering Extended Passive Mode (|||port|)
+		start, end := strings.Index(msg, "(|||"), strings.LastIndex(msg, "|)")
+		if start < 0 || end < start+4 {
+			return nil, fmt.Errorf("invalid EPSV response: %s", msg)
+		}
+		port, err = strconv.Atoi(msg[start+4 : end])
+	} else {
+		if msg, err = c.cmd(227, "PASV"); err != nil {
+			return nil, err
+		}
+		port, err = parsePASV(msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := state.Dialer.DialContext(ctx, "tcp", net.JoinHostPort(c.host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	if err = c.startTransfer(command); err != nil {
+		_ = data.Close()
+		return nil, err
+	}
+	return data, nil
+}
+
+// parsePASV returns the port of a PASV response, Entering Passive Mode (h1,h2,h3,h4,p1,p2).
+func parsePASV(msg string) (int, error) {
+	start, end := strings.Index(msg, "("), strings.LastIndex(msg, ")")
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("invalid PASV response: %s", msg)
+	}
+	parts := strings.Split(msg[start+1:end], ",")
+	if len(parts) != 6 {
+		return 0, fmt.Errorf("invalid PASV response: %s", msg)
+	}
+	p1, err1 := strconv.Atoi(parts[4])
+	p2, err2 := strconv.Atoi(parts[5])
+	if err1 != nil || err2 != nil {
+		return 0, fmt.Errorf("invalid PASV response: %s", msg)
+	}
+	return p1<<8 | p2, nil
+}
+
+// activeConn listens on the local address of the control connection, sends it with EPRT and
+// the command, and accepts the connection of the server.
+func (c *Client) activeConn(command string) (net.Conn, error) {
+	localIP := c.conn.LocalAddr().(*net.TCPAddr).IP //nolint:forcetypeassert
+	l, err := net.Listen("tcp", net.JoinHostPort(localIP.String(), "0"))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = l.Close() }()
+	port := l.Addr().(*net.TCPAddr).Port //nolint:forcetypeassert
+	proto := 1
+	if localIP.To4() == nil {
+		proto = 2
+	}
+	if _, err = c.cmd(200, "EPRT |%d|%s|%d|", proto, localIP, port); err != nil {
+		return nil, err
+	}
+	if err = c.startTransfer(command); err != nil {
+		return nil, err
+	}
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(accepted)
+			return
+		}
+		accepted <- conn
+	}()
+	select {
+	case conn, ok := <-accepted:
+		if !ok {
+			return nil, errors.New("the FTP server didn't connect for the transfer")
+		}
+		return conn, nil
+	case <-time.After(c.timeout):
+		return nil, errors.New("the FTP server didn't connect for the transfer before the timeout")
+	}
+}
+
+// startTransfer sends the command of a transfer, the server responds with 125 or 150 before the
+// transfer.
+func (c *Client) startTransfer(command string) error {
+	code, msg, err := c.cmdCode("%s", command)
+	if err != nil {
+		return err
+	}
+	if code != 125 && code != 150 {
+		return &textproto.Error{Code: code, Msg: msg}
+	}
+	return nil
+}
+
+// Upload stores the data, a string or an ArrayBuffer, as the remote file.
+func (c *Client) Upload(ctxPtr *context.Context, remotePath string, data interface{}) (*TransferResult, error) {
+	state := lib.GetState(*ctxPtr)
+	if state == nil {
+		return nil, errConnectInInitContext
+	}
+	b, err := common.ToBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	err = c.transfer(*ctxPtr, state, "STOR "+remotePath, func(conn net.Conn) error {
+		_, err := io.Copy(conn, bytes.NewReader(b))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.pushTransfer(*ctxPtr, state, "upload", len(b), start), nil
+}
+
+// Download returns the remote file as a string, or as an ArrayBuffer if binary is true.
+func (c *Client) Download(ctxPtr *context.Context, remotePath string, binary bool) (interface{}, error) {
+	state := lib.GetState(*ctxPtr)
+	if state == nil {
+		return nil, errConnectInInitContext
+	}
+	var b []byte
+	start := time.Now()
+	err := c.transfer(*ctxPtr, state, "RETR "+remotePath, func(conn net.Conn) error {
+		var err error
+		b, err = ioutil.ReadAll(conn)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.pushTransfer(*ctxPtr, state, "download", len(b), start)
+	if binary {
+		return common.GetRuntime(*ctxPtr).NewArrayBuffer(b), nil
+	}
+	return string(b), nil
+}
+
+// List returns the names of the files of the remote directory, the current one if it's empty.
+func (c *Client) List(ctxPtr *context.Context, remoteDir string) ([]string, error) {
+	state := lib.GetState(*ctxPtr)
+	if state == nil {
+		return nil, errConnectInInitContext
+	}
+	command := "NLST"
+	if remoteDir != "" {
+		command += " " + remoteDir
+	}
+	var b []byte
+	err := c.transfer(*ctxPtr, state, command, func(conn net.Conn) error {
+		var err error
+		b, err = ioutil.ReadAll(conn)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
+
+// Remove removes the remote file.
+func (c *Client) Remove(ctxPtr *context.Context, remotePath string) error {
+	if lib.GetState(*ctxPtr) == nil {
+		return errConnectInInitContext
+	}
+	if c.conn == nil {
+		return errNotConnected
+	}
+	_, err := c.cmd(250, "DELE %s", remotePath)
+	return err
+}
+
+// pushTransfer emits the duration and the throughput of a transfer and returns its result.
+func (c *Client) pushTransfer(
+	ctx context.Context, state *lib.State, op string, n int, start time.Time,
+) *TransferResult {
+	end := time.Now()
+	result := &TransferResult{Bytes: n, Duration: stats.D(end.Sub(start))}
+	if elapsed := end.Sub(start).Seconds(); elapsed > 0 {
+		result.Throughput = float64(n) / elapsed
+	}
+
+	tags := state.CloneTags()
+	for k, v := range c.tags {
+		tags[k] = v
+	}
+	tags["op"] = op
+	sampleTags := stats.IntoSampleTags(&tags)
+	stats.PushIfNotDone(ctx, state.Samples, stats.ConnectedSamples{
+		Samples: []stats.Sample{
+			{Metric: metrics.FTPTransferDuration, Time: end, Tags: sampleTags, Value: result.Duration},
+			{Metric: metrics.FTPTransferThroughput, Time: end, Tags: sampleTags, Value: result.Throughput},
+		},
+		Tags: sampleTags,
+		Time: end,
+	})
+	return result
+}
+
+// Close logs out and closes the connection.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	_, _ = c.cmd(221, "QUIT")
+	err := c.conn.Close()
+	c.conn, c.text = nil, nil
+	return err
+}
@@ -0,0 +1,310 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ftp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/netext"
+	"go.k6.io/k6/lib/testutils/httpmultibin"
+	"go.k6.io/k6/stats"
+)
+
+// testServer is an FTP server with the files in memory, it supports the explicit TLS and the
+// passive and active modes, without EPSV if noEPSV is set.
+type testServer struct {
+	addr      string
+	tlsConfig *tls.Config
+	noEPSV    bool
+	mu        sync.Mutex
+	files     map[string][]byte
+}
+
+func newTestServer(t *testing.T, tlsConfig *tls.Config, noEPSV bool) *testServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	srv := &testServer{addr: l.Addr().String(), tlsConfig: tlsConfig, noEPSV: noEPSV, files: map[string][]byte{}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return srv
+}
+
+//nolint:funlen,gocognit,cyclop
+func (s *testServer) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	text := textproto.NewConn(conn)
+	reply := func(code int, msg string) { _ = text.PrintfLine("%d %s", code, msg) }
+	var (
+		passive    net.Listener
+		activeAddr string
+		protected  bool
+		loggedIn   bool
+	)
+	dataConn := func() (net.Conn, error) {
+		var data net.Conn
+		var err error
+		if passive != nil {
+			data, err = passive.Accept()
+			_ = passive.Close()
+			passive = nil
+		} else {
+			data, err = net.Dial("tcp", activeAddr)
+		}
+		if err != nil || !protected {
+			return data, err
+		}
+		return tls.Server(data, s.tlsConfig), nil
+	}
+
+	reply(220, "ready")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			cmd, arg = line[:i], line[i+1:]
+		}
+		if !loggedIn && cmd != "USER" && cmd != "PASS" && cmd != "AUTH" && cmd != "PBSZ" && cmd != "PROT" {
+			reply(530, "not logged in")
+			continue
+		}
+		switch cmd {
+		case "AUTH":
+			reply(234, "AUTH TLS ok")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			conn, text = tlsConn, textproto.NewConn(tlsConn)
+		case "PBSZ", "TYPE":
+			reply(200, "ok")
+		case "PROT":
+			protected = arg == "P"
+			reply(200, "ok")
+		case "USER":
+			reply(331, "password required")
+		case "PASS":
+			loggedIn = arg == "secret"
+			if !loggedIn {
+				reply(530, "login incorrect")
+				continue
+			}
+			reply(230, "logged in")
+		case "EPSV", "PASV":
+			if cmd == "EPSV" && s.noEPSV {
+				reply(502, "not implemented")
+				continue
+			}
+			passive, _ = net.Listen("tcp", "127.0.0.1:0")
+			port := passive.Addr().(*net.TCPAddr).Port
+			if cmd == "EPSV" {
+				reply(229, fmt.Sprintf("Entering Extended Passive Mode (|||%d|)", port))
+			} else {
+				reply(227, fmt.Sprintf("Entering Passive Mode (127,0,0,1,%d,%d)", port>>8, port&0xff))
+			}
+		case "EPRT":
+			parts := strings.Split(arg, "|")
+			activeAddr = net.JoinHostPort(parts[2], parts[3])
+			reply(200, "ok")
+		case "STOR", "RETR", "NLST":
+			s.mu.Lock()
+			file, ok := s.files[arg]
+			var names []string
+			for name := range s.files {
+				names = append(names, name)
+			}
+			s.mu.Unlock()
+			if cmd == "RETR" && !ok {
+				reply(550, "no such file")
+				continue
+			}
+			reply(150, "opening the data connection")
+			data, err := dataConn()
+			if err != nil {
+				return
+			}
+			switch cmd {
+			case "STOR":
+				b, _ := ioutil.ReadAll(data)
+				s.mu.Lock()
+				s.files[arg] = b
+				s.mu.Unlock()
+			case "RETR":
+				_, _ = data.Write(file)
+			case "NLST":
+				sort.Strings(names)
+				_, _ = data.Write([]byte(strings.Join(names, "\r\n") + "\r\n"))
+			}
+			_ = data.Close()
+			reply(226, "transfer complete")
+		case "DELE":
+			s.mu.Lock()
+			_, ok := s.files[arg]
+			delete(s.files, arg)
+			s.mu.Unlock()
+			if !ok {
+				reply(550, "no such file")
+				continue
+			}
+			reply(250, "deleted")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			reply(502, "not implemented")
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	tlsSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(tlsSrv.Close)
+	plain := newTestServer(t, nil, false)
+	pasv := newTestServer(t, nil, true)
+	secure := newTestServer(t, tlsSrv.TLS, false)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	rt.Set("ftp", common.Bind(rt, New(), &ctx))
+
+	t.Run("InitContext", func(t *testing.T) {
+		rt.Set("server", plain.addr)
+		_, err := rt.RunString(`new ftp.Client().connect(server)`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), errConnectInInitContext.Error())
+	})
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{
+		Group:     root,
+		Dialer:    netext.NewDialer(net.Dialer{}, nil),
+		TLSConfig: httpmultibin.GetTLSClientConfig(t, tlsSrv),
+		Samples:   samples,
+		Logger:    logrus.New(),
+		Options:   lib.Options{SystemTags: stats.NewSystemTagSet(stats.TagName)},
+	}
+	ctx = lib.WithState(ctx, state)
+
+	testCases := []struct {
+		name   string
+		srv    *testServer
+		params string
+	}{
+		{"Passive", plain, `{}`},
+		{"PASV", pasv, `{}`},
+		{"Active", plain, `{ mode: "active" }`},
+		{"ExplicitTLS", secure, `{ tls: "explicit" }`},
+		{"ExplicitTLSActive", secure, `{ tls: "explicit", mode: "active" }`},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			rt.Set("server", tc.srv.addr)
+			v, err := rt.RunString(`
+				var params = ` + tc.params + `;
+				params.username = "k6";
+				params.password = "secret";
+				params.tags = { partner: "acme" };
+				var client = new ftp.Client();
+				client.connect(server, params);
+				var up = client.upload("orders.csv", "id,amount\n1,10\n");
+				var down = client.download("orders.csv");
+				var binary = client.download("orders.csv", true);
+				var names = client.list("");
+				client.remove("orders.csv");
+				client.close();
+				[up.bytes, up.throughput > 0, down, binary.byteLength, names.join(",")].join("|")
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, "15|true|id,amount\n1,10\n|15|orders.csv", v.String())
+			assert.Empty(t, tc.srv.files)
+
+			ops := map[string]int{}
+			for _, container := range stats.GetBufferedSamples(samples) {
+				for _, sample := range container.GetSamples() {
+					tags := sample.Tags.CloneTags()
+					assert.Equal(t, "acme", tags["partner"])
+					if sample.Metric == metrics.FTPTransferThroughput {
+						ops[tags["op"]]++
+					}
+				}
+			}
+			assert.Equal(t, map[string]int{"upload": 1, "download": 2}, ops)
+		})
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		rt.Set("server", plain.addr)
+		testCases := map[string]string{
+			`new ftp.Client().download("file")`:                                                                          "the FTP client isn't connected",
+			`new ftp.Client().connect(server, { username: "k6", password: "wrong" })`:                                    `530 "login incorrect"`,
+			`new ftp.Client().connect(server, { tls: "ssl" })`:                                                           "invalid FTP tls value: ssl",
+			`new ftp.Client().connect(server, { passive: true })`:                                                        `unknown connect param: "passive"`,
+			`var c = new ftp.Client(); c.connect(server, { username: "k6", password: "secret" }); c.download("missing")`: `550 "no such file"`,
+		}
+		for script, expected := range testCases {
+			_, err := rt.RunString(script)
+			require.Error(t, err, script)
+			assert.Contains(t, err.Error(), expected, script)
+		}
+	})
+}
+
+func TestParsePASV(t *testing.T) {
+	t.Parallel()
+	port, err := parsePASV("Entering Passive Mode (192,168,1,2," + strconv.Itoa(0x12) + ",52).")
+	require.NoError(t, err)
+	assert.Equal(t, 0x12<<8|52, port)
+	_, err = parsePASV("Entering Passive Mode")
+	assert.Error(t, err)
+}
@@ -22,18 +22,16 @@ package grpc
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dop251/goja"
-	"github.com/jhump/protoreflect/desc"
-	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -44,15 +42,14 @@ import (
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
-	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/dynamicpb"
 
 	//nolint: staticcheck
 	protoV1 "github.com/golang/protobuf/proto"
 
 	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/modules/k6/protobuf"
 	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/metrics"
 	"go.k6.io/k6/lib/types"
@@ -69,6 +66,10 @@ var (
 type Client struct {
 	mds  map[string]protoreflect.MethodDescriptor
 	conn *grpc.ClientConn
+
+	// the default metadata and retry policy of the calls, set by connect()
+	metadata metadata.MD
+	retry    retryPolicy
 }
 
 // XClient represents the Client constructor (e.g. `new grpc.Client()`) and
@@ -97,23 +98,6 @@ type Response struct {
 	Error    interface{}
 }
 
-func walkFileDescriptors(seen map[string]struct{}, fd *desc.FileDescriptor) []*descriptorpb.FileDescriptorProto {
-	fds := []*descriptorpb.FileDescriptorProto{}
-
-	if _, ok := seen[fd.GetName()]; ok {
-		return fds
-	}
-	seen[fd.GetName()] = struct{}{}
-	fds = append(fds, fd.AsFileDescriptorProto())
-
-	for _, dep := range fd.GetDependencies() {
-		deps := walkFileDescriptors(seen, dep)
-		fds = append(fds, deps...)
-	}
-
-	return fds
-}
-
 // Load will parse the given proto files and make the file descriptors available to request.
 func (c *Client) Load(ctxPtr *context.Context, importPaths []string, filenames ...string) ([]MethodInfo, error) {
 	if lib.GetState(*ctxPtr) != nil {
@@ -125,33 +109,7 @@ func (c *Client) Load(ctxPtr *context.Context, importPaths []string, filenames .
 		return nil, errors.New("missing init environment")
 	}
 
-	// If no import paths are specified, use the current working directory
-	if len(importPaths) == 0 {
-		importPaths = append(importPaths, initEnv.CWD.Path)
-	}
-
-	parser := protoparse.Parser{
-		ImportPaths:      importPaths,
-		InferImportPaths: false,
-		Accessor: protoparse.FileAccessor(func(filename string) (io.ReadCloser, error) {
-			absFilePath := initEnv.GetAbsFilePath(filename)
-			return initEnv.FileSystems["file"].Open(absFilePath)
-		}),
-	}
-
-	fds, err := parser.ParseFiles(filenames...)
-	if err != nil {
-		return nil, err
-	}
-
-	fdset := &descriptorpb.FileDescriptorSet{}
-
-	seen := make(map[string]struct{})
-	for _, fd := range fds {
-		fdset.File = append(fdset.File, walkFileDescriptors(seen, fd)...)
-	}
-
-	files, err := protodesc.NewFiles(fdset)
+	files, err := protobuf.ParseFiles(initEnv, importPaths, filenames...)
 	if err != nil {
 		return nil, err
 	}
@@ -214,17 +172,28 @@ func (c *Client) Connect(ctxPtr *context.Context, addr string, params map[string
 	}
 
 	isPlaintext, timeout := false, 60*time.Second
+	md, retry := metadata.New(nil), defaultRetryPolicy()
 
 	for k, v := range params {
+		var err error
 		switch k {
 		case "plaintext":
 			isPlaintext, _ = v.(bool)
 		case "timeout":
-			var err error
 			timeout, err = types.GetDurationValue(v)
 			if err != nil {
 				return false, fmt.Errorf("invalid timeout value: %w", err)
 			}
+		case "metadata":
+			md, err = parseMetadata(v, "metadata")
+			if err != nil {
+				return false, err
+			}
+		case "retry":
+			retry, err = parseRetryPolicy(retry, v)
+			if err != nil {
+				return false, err
+			}
 		default:
 			return false, fmt.Errorf("unknown connect param: %q", k)
 		}
@@ -288,6 +257,8 @@ func (c *Client) Connect(ctxPtr *context.Context, addr string, params map[string
 		return false, err
 	}
 
+	c.metadata, c.retry = md, retry
+
 	return true, nil
 }
 
@@ -321,22 +292,20 @@ func (c *Client) Invoke(ctxPtr *context.Context,
 
 	tags := state.CloneTags()
 	timeout := 60 * time.Second
+	var deadline time.Time
+	retry := c.retry
 
-	ctx = metadata.NewOutgoingContext(ctx, metadata.New(nil))
+	// the headers of the call replace the default metadata with the same keys
+	reqMD := c.metadata.Copy()
 	for k, v := range params {
 		switch k {
 		case "headers":
-			rawHeaders, ok := v.(map[string]interface{})
-			if !ok {
-				return nil, errors.New("headers must be an object with key-value pairs")
+			headers, err := parseMetadata(v, "headers")
+			if err != nil {
+				return nil, err
 			}
-			for hk, kv := range rawHeaders {
-				// TODO(rogchap): Should we manage a string slice?
-				strVal, ok := kv.(string)
-				if !ok {
-					return nil, fmt.Errorf("header %q value must be a string", hk)
-				}
-				ctx = metadata.AppendToOutgoingContext(ctx, hk, strVal)
+			for hk, hv := range headers {
+				reqMD[hk] = hv
 			}
 		case "tags":
 			rawTags, ok := v.(map[string]interface{})
@@ -356,6 +325,18 @@ func (c *Client) Invoke(ctxPtr *context.Context,
 			if err != nil {
 				return nil, fmt.Errorf("invalid timeout value: %w", err)
 			}
+		case "deadline":
+			var ok bool
+			deadline, ok = v.(time.Time)
+			if !ok {
+				return nil, errors.New("deadline must be a Date")
+			}
+		case "retry":
+			var err error
+			retry, err = parseRetryPolicy(retry, v)
+			if err != nil {
+				return nil, err
+			}
 		default:
 			return nil, fmt.Errorf("unknown param: %q", k)
 		}
@@ -377,6 +358,7 @@ func (c *Client) Invoke(ctxPtr *context.Context,
 		tags["name"] = method
 	}
 
+	ctx = metadata.NewOutgoingContext(ctx, reqMD)
 	ctx = withTags(ctx, tags)
 
 	reqdm := dynamicpb.NewMessage(md.Input())
@@ -390,12 +372,28 @@ func (c *Client) Invoke(ctxPtr *context.Context,
 		}
 	}
 
+	// the timeout and the deadline are of the whole call, including its retries
 	reqCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-
-	resp := dynamicpb.NewMessage(md.Output())
-	header, trailer := metadata.New(nil), metadata.New(nil)
-	err := c.conn.Invoke(reqCtx, method, reqdm, resp, grpc.Header(&header), grpc.Trailer(&trailer))
+	if !deadline.IsZero() {
+		var cancelDeadline context.CancelFunc
+		reqCtx, cancelDeadline = context.WithDeadline(reqCtx, deadline)
+		defer cancelDeadline()
+	}
+
+	var (
+		resp            *dynamicpb.Message
+		header, trailer metadata.MD
+		err             error
+	)
+	for attempt := int64(1); ; attempt++ {
+		resp = dynamicpb.NewMessage(md.Output())
+		header, trailer = metadata.New(nil), metadata.New(nil)
+		err = c.conn.Invoke(reqCtx, method, reqdm, resp, grpc.Header(&header), grpc.Trailer(&trailer))
+		if err == nil || !retry.retryable(attempt, status.Code(err)) || !retry.wait(reqCtx, attempt) {
+			break
+		}
+	}
 
 	var response Response
 	response.Headers = header
@@ -466,9 +464,9 @@ func (*Client) HandleConn(context.Context, grpcstats.ConnStats) {
 }
 
 // TagRPC implements the stats.Handler interface
-func (*Client) TagRPC(ctx context.Context, _ *grpcstats.RPCTagInfo) context.Context {
-	// noop
-	return ctx
+func (*Client) TagRPC(ctx context.Context, info *grpcstats.RPCTagInfo) context.Context {
+	// the method is needed for the debug logs of the payloads, which don't have it
+	return withMethod(ctx, info.FullMethodName)
 }
 
 // HandleRPC implements the stats.Handler interface
@@ -503,14 +501,23 @@ func (c *Client) HandleRPC(ctx context.Context, stat grpcstats.RPCStats) {
 	}
 
 	// (rogchap) Re-using --http-debug flag as gRPC is technically still HTTP
-	if state.Options.HTTPDebug.String != "" {
-		logger := state.Logger.WithField("source", "http-debug")
-		httpDebugOption := state.Options.HTTPDebug.String
-		debugStat(stat, logger, httpDebugOption)
+	// --grpc-debug takes precedence, since it also has the wire level
+	source, debugOption := "http-debug", state.Options.HTTPDebug.String
+	if state.Options.GRPCDebug.String != "" {
+		source, debugOption = "grpc-debug", state.Options.GRPCDebug.String
+	}
+	if debugOption != "" {
+		logger := state.Logger.WithFields(logrus.Fields{
+			"source": source, "vu": state.VUID, "iter": state.Iteration, "method": getMethod(ctx),
+		})
+		debugStat(stat, logger, debugOption)
 	}
 }
 
-func debugStat(stat grpcstats.RPCStats, logger logrus.FieldLogger, httpDebugOption string) {
+// debugStat logs the stat of a call, the payloads are logged with their sizes, and the decoded
+// messages with the "full" and "wire" options, the latter with a dump of the encoded bytes too.
+func debugStat(stat grpcstats.RPCStats, logger logrus.FieldLogger, debugOption string) {
+	withMessages := debugOption == "full" || debugOption == "wire"
 	switch s := stat.(type) {
 	case *grpcstats.OutHeader:
 		logger.Infof("Out Header:\nFull Method: %s\nRemote Address: %s\n%s\n",
@@ -520,9 +527,12 @@ func debugStat(stat grpcstats.RPCStats, logger logrus.FieldLogger, httpDebugOpti
 			logger.Infof("Out Trailer:\n%s\n", formatMetadata(s.Trailer))
 		}
 	case *grpcstats.OutPayload:
-		if httpDebugOption == "full" {
-			logger.Infof("Out Payload:\nWire Length: %d\nSent Time: %s\n%s\n\n",
-				s.WireLength, s.SentTime, formatPayload(s.Payload))
+		if withMessages {
+			logger.Infof("Out Payload:\nLength: %d\nWire Length: %d\nSent Time: %s\n%s\n%s\n",
+				s.Length, s.WireLength, s.SentTime, formatPayload(s.Payload), formatWire(s.Data, debugOption))
+		} else {
+			logger.Infof("Out Payload:\nLength: %d\nWire Length: %d\nSent Time: %s\n",
+				s.Length, s.WireLength, s.SentTime)
 		}
 	case *grpcstats.InHeader:
 		if len(s.Header) > 0 {
@@ -533,13 +543,29 @@ func debugStat(stat grpcstats.RPCStats, logger logrus.FieldLogger, httpDebugOpti
 			logger.Infof("In Trailer:\nWire Length: %d\n%s\n", s.WireLength, formatMetadata(s.Trailer))
 		}
 	case *grpcstats.InPayload:
-		if httpDebugOption == "full" {
-			logger.Infof("In Payload:\nWire Length: %d\nReceived Time: %s\n%s\n\n",
-				s.WireLength, s.RecvTime, formatPayload(s.Payload))
+		if withMessages {
+			logger.Infof("In Payload:\nLength: %d\nWire Length: %d\nReceived Time: %s\n%s\n%s\n",
+				s.Length, s.WireLength, s.RecvTime, formatPayload(s.Payload), formatWire(s.Data, debugOption))
+		} else {
+			logger.Infof("In Payload:\nLength: %d\nWire Length: %d\nReceived Time: %s\n",
+				s.Length, s.WireLength, s.RecvTime)
 		}
+	case *grpcstats.End:
+		st := status.Convert(s.Error)
+		logger.Infof("End:\nStatus: %s\nMessage: %s\nDuration: %s\n",
+			st.Code(), st.Message(), s.EndTime.Sub(s.BeginTime))
 	}
 }
 
+// formatWire returns the hex dump of the encoded message with the "wire" option.
+func formatWire(data []byte, debugOption string) string {
+	if debugOption != "wire" {
+		return ""
+	}
+
+	return "Wire Data:\n" + hex.Dump(data)
+}
+
 func formatMetadata(md metadata.MD) string {
 	var sb strings.Builder
 	for k, v := range md {
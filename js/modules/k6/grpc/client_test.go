@@ -27,6 +27,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/dop251/goja"
@@ -412,6 +413,125 @@ func TestClient(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("InvokeInvalidDeadline", func(t *testing.T) {
+		_, err := rt.RunString(`
+			client.invoke("grpc.testing.TestService/EmptyCall", {}, { deadline: "soon" })
+		`)
+		if !assert.Error(t, err) {
+			return
+		}
+		assert.Contains(t, err.Error(), "deadline must be a Date")
+	})
+
+	t.Run("InvokeInvalidRetry", func(t *testing.T) {
+		_, err := rt.RunString(`
+			client.invoke("grpc.testing.TestService/EmptyCall", {}, { retry: { maxAttempts: 0 } })
+		`)
+		if !assert.Error(t, err) {
+			return
+		}
+		assert.Contains(t, err.Error(), "retry maxAttempts must be a positive integer")
+
+		_, err = rt.RunString(`
+			client.invoke("grpc.testing.TestService/EmptyCall", {}, { retry: { codes: ["NOPE"] } })
+		`)
+		if !assert.Error(t, err) {
+			return
+		}
+		assert.Contains(t, err.Error(), "invalid retry status code NOPE")
+	})
+
+	t.Run("InvokeTimeoutExceeded", func(t *testing.T) {
+		tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+			<-ctx.Done()
+
+			return nil, ctx.Err()
+		}
+		_, err := rt.RunString(`
+			var resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { timeout: "10ms" })
+			if (resp.status !== grpc.StatusDeadlineExceeded) {
+				throw new Error("unexpected timeout status: " + resp.status)
+			}
+			resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { deadline: new Date(Date.now() + 10) })
+			if (resp.status !== grpc.StatusDeadlineExceeded) {
+				throw new Error("unexpected deadline status: " + resp.status)
+			}
+		`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("InvokeRetry", func(t *testing.T) {
+		var calls int64
+		tb.GRPCStub.EmptyCallFunc = func(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+			if atomic.AddInt64(&calls, 1)%3 != 0 {
+				return nil, status.Error(codes.Unavailable, "not yet")
+			}
+
+			return &grpc_testing.Empty{}, nil
+		}
+		_, err := rt.RunString(`
+			var resp = client.invoke("grpc.testing.TestService/EmptyCall", {})
+			if (resp.status !== grpc.StatusUnavailable) {
+				throw new Error("unexpected status without retries: " + resp.status)
+			}
+			resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, { retry: { maxAttempts: 3, backoff: 1 } })
+			if (resp.status !== grpc.StatusOK) {
+				throw new Error("unexpected status with retries: " + resp.status)
+			}
+		`)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), atomic.LoadInt64(&calls))
+	})
+
+	t.Run("ConnectInvalidMetadata", func(t *testing.T) {
+		_, err := rt.RunString(sr(`
+			client.connect("GRPCBIN_ADDR", { metadata: { "x-load-tester": 6 } });
+		`))
+		if !assert.Error(t, err) {
+			return
+		}
+		assert.Contains(t, err.Error(), "metadata \"x-load-tester\" value must be a string")
+	})
+
+	t.Run("ConnectMetadataAndRetry", func(t *testing.T) {
+		var calls int64
+		tb.GRPCStub.EmptyCallFunc = func(ctx context.Context, _ *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			if len(md["x-load-tester"]) != 1 || md["x-load-tester"][0] != "k6" {
+				return nil, status.Error(codes.FailedPrecondition, "missing the default metadata")
+			}
+			if len(md["authorization"]) != 1 || md["authorization"][0] != strings.Join(md["x-expected-auth"], "") {
+				return nil, status.Error(codes.Unauthenticated, "unexpected authorization")
+			}
+			if atomic.AddInt64(&calls, 1) == 1 {
+				return nil, status.Error(codes.DataLoss, "first")
+			}
+
+			return &grpc_testing.Empty{}, nil
+		}
+		_, err := rt.RunString(sr(`
+			client.close();
+			client.connect("GRPCBIN_ADDR", {
+				metadata: { "x-load-tester": "k6", "authorization": "Bearer default" },
+				retry: { maxAttempts: 2, backoff: "1ms", codes: [grpc.StatusDataLoss] },
+			});
+			var resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, {
+				headers: { "x-expected-auth": "Bearer default" },
+			});
+			if (resp.status !== grpc.StatusOK) {
+				throw new Error("unexpected status with the default metadata: " + resp.status)
+			}
+			resp = client.invoke("grpc.testing.TestService/EmptyCall", {}, {
+				headers: { "authorization": "Bearer call", "x-expected-auth": "Bearer call" },
+			});
+			if (resp.status !== grpc.StatusOK) {
+				throw new Error("unexpected status with the call headers: " + resp.status)
+			}
+		`))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), atomic.LoadInt64(&calls))
+	})
+
 	t.Run("LoadNotInit", func(t *testing.T) {
 		_, err := rt.RunString("client.load()")
 		if !assert.Error(t, err) {
@@ -504,3 +624,47 @@ func TestDebugStat(t *testing.T) {
 		})
 	}
 }
+
+func TestDebugStatOptions(t *testing.T) {
+	t.Parallel()
+
+	payload := &grpcstats.OutPayload{
+		Payload:    &grpc_testing.SimpleRequest{FillUsername: true},
+		Data:       []byte{0x08, 0x01},
+		Length:     2,
+		WireLength: 7,
+	}
+	tests := [...]struct {
+		option      string
+		stat        grpcstats.RPCStats
+		expected    []string
+		notExpected []string
+	}{
+		{"headers", payload, []string{"Length: 2", "Wire Length: 7"}, []string{"fill_username:", "Wire Data:"}},
+		{"full", payload, []string{"Length: 2", "fill_username:"}, []string{"Wire Data:"}},
+		{"wire", payload, []string{"fill_username:", "Wire Data:", "00000000  08 01"}, nil},
+		{
+			"headers",
+			&grpcstats.End{Error: status.Error(codes.Unavailable, "down")},
+			[]string{"Status: Unavailable", "Message: down"},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.option, func(t *testing.T) {
+			t.Parallel()
+			var b bytes.Buffer
+			logger := logrus.New()
+			logger.Out = &b
+
+			debugStat(tt.stat, logger.WithField("source", "test"), tt.option)
+			for _, e := range tt.expected {
+				assert.Contains(t, b.String(), e)
+			}
+			for _, e := range tt.notExpected {
+				assert.NotContains(t, b.String(), e)
+			}
+		})
+	}
+}
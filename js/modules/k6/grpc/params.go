@@ -0,0 +1,174 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"go.k6.io/k6/lib/types"
+)
+
+// parseMetadata returns the metadata of an object with key-value pairs, like the default
+// metadata of connect() or the headers of invoke().
+func parseMetadata(v interface{}, name string) (metadata.MD, error) {
+	raw, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object with key-value pairs", name)
+	}
+	md := metadata.New(nil)
+	for k, kv := range raw {
+		// TODO(rogchap): Should we manage a string slice?
+		strVal, ok := kv.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s %q value must be a string", name, k)
+		}
+		md.Append(k, strVal)
+	}
+
+	return md, nil
+}
+
+// retryPolicy is how the calls that fail with one of the retryable status codes are retried,
+// they are made only once unless maxAttempts is more than 1.
+type retryPolicy struct {
+	maxAttempts int64
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	codes       map[codes.Code]bool
+}
+
+func defaultRetryPolicy() retryPolicy {
+	return retryPolicy{
+		maxAttempts: 1,
+		backoff:     100 * time.Millisecond,
+		maxBackoff:  time.Second,
+		codes:       map[codes.Code]bool{codes.Unavailable: true},
+	}
+}
+
+// parseRetryPolicy returns the base policy with the fields set in the retry param, an object
+// like { maxAttempts: 3, backoff: "100ms", maxBackoff: "1s", codes: ["UNAVAILABLE"] }.
+//nolint: gocognit
+func parseRetryPolicy(base retryPolicy, v interface{}) (retryPolicy, error) {
+	raw, ok := v.(map[string]interface{})
+	if !ok {
+		return base, errors.New("retry must be an object with the retry policy")
+	}
+	policy := base
+	for k, rv := range raw {
+		var err error
+		switch k {
+		case "maxAttempts":
+			var n float64
+			switch val := rv.(type) {
+			case int64:
+				n = float64(val)
+			case float64:
+				n = val
+			default:
+				return base, fmt.Errorf("invalid retry maxAttempts value: %v", rv)
+			}
+			if n < 1 || n != math.Trunc(n) {
+				return base, fmt.Errorf("retry maxAttempts must be a positive integer, got %v", rv)
+			}
+			policy.maxAttempts = int64(n)
+		case "backoff":
+			policy.backoff, err = types.GetDurationValue(rv)
+			if err != nil {
+				return base, fmt.Errorf("invalid retry backoff value: %w", err)
+			}
+		case "maxBackoff":
+			policy.maxBackoff, err = types.GetDurationValue(rv)
+			if err != nil {
+				return base, fmt.Errorf("invalid retry maxBackoff value: %w", err)
+			}
+		case "codes":
+			list, ok := rv.([]interface{})
+			if !ok {
+				return base, errors.New("retry codes must be an array of status codes")
+			}
+			policy.codes = make(map[codes.Code]bool, len(list))
+			for _, cv := range list {
+				code, err := parseStatusCode(cv)
+				if err != nil {
+					return base, err
+				}
+				policy.codes[code] = true
+			}
+		default:
+			return base, fmt.Errorf("unknown retry param: %q", k)
+		}
+	}
+	if policy.backoff < 0 || policy.maxBackoff < 0 {
+		return base, errors.New("retry backoff values can't be negative")
+	}
+
+	return policy, nil
+}
+
+// parseStatusCode returns the status code of a number, like grpc.StatusUnavailable, or of
+// its name, like "UNAVAILABLE".
+func parseStatusCode(v interface{}) (codes.Code, error) {
+	var code codes.Code
+	b, err := json.Marshal(v)
+	if err == nil {
+		err = code.UnmarshalJSON(b)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid retry status code %v", v)
+	}
+
+	return code, nil
+}
+
+// retryable returns whether another attempt of a call that failed with the code can be made.
+func (p retryPolicy) retryable(attempt int64, code codes.Code) bool {
+	return attempt < p.maxAttempts && p.codes[code]
+}
+
+// wait waits for the exponential backoff of the attempt, it returns false when the context is
+// done before, since the call can't be retried anymore.
+func (p retryPolicy) wait(ctx context.Context, attempt int64) bool {
+	backoff := p.backoff
+	for i := int64(1); i < attempt && backoff < p.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.maxBackoff {
+		backoff = p.maxBackoff
+	}
+
+	t := time.NewTimer(backoff)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
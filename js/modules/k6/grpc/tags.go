@@ -30,6 +30,17 @@ func withTags(ctx context.Context, tags reqtags) context.Context {
 	return context.WithValue(ctx, ctxKeyTags{}, tags)
 }
 
+type ctxKeyMethod struct{}
+
+func withMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, ctxKeyMethod{}, method)
+}
+
+func getMethod(ctx context.Context) string {
+	method, _ := ctx.Value(ctxKeyMethod{}).(string)
+	return method
+}
+
 func getTags(ctx context.Context) reqtags {
 	v := ctx.Value(ctxKeyTags{})
 	if v == nil {
@@ -104,3 +104,46 @@ func (j HTTPCookieJar) Set(url, name, value string, opts goja.Value) (bool, erro
 	j.jar.SetCookies(u, []*http.Cookie{&c})
 	return true, nil
 }
+
+// ExportedCookie is a cookie of a jar export, with the URL it's sent to.
+type ExportedCookie struct {
+	URL   string `json:"url" js:"url"`
+	Name  string `json:"name" js:"name"`
+	Value string `json:"value" js:"value"`
+}
+
+// Export returns the cookies of the jar that are sent to the URLs, so they can be imported in
+// another jar, e.g. of a later iteration or of another VU.
+func (j HTTPCookieJar) Export(urls []string) ([]ExportedCookie, error) {
+	exported := []ExportedCookie{}
+	for _, url := range urls {
+		u, err := neturl.Parse(url)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range j.jar.Cookies(u) {
+			exported = append(exported, ExportedCookie{URL: url, Name: c.Name, Value: c.Value})
+		}
+	}
+	return exported, nil
+}
+
+// Import sets the cookies of an export in the jar, each cookie is set for its URL.
+func (j HTTPCookieJar) Import(cookies goja.Value) error {
+	rt := common.GetRuntime(*j.ctx)
+	var exported []ExportedCookie
+	if err := rt.ExportTo(cookies, &exported); err != nil {
+		return fmt.Errorf("invalid cookies, they have to be an array of { url, name, value } objects: %w", err)
+	}
+	for _, c := range exported {
+		u, err := neturl.Parse(c.URL)
+		if err != nil {
+			return err
+		}
+		if c.Name == "" {
+			return fmt.Errorf("the cookie for %s doesn't have a name", c.URL)
+		}
+		j.jar.SetCookies(u, []*http.Cookie{{Name: c.Name, Value: c.Value}})
+	}
+	return nil
+}
@@ -73,6 +73,7 @@ func (g *GlobalHTTP) NewModuleInstancePerVU() interface{} { // this here needs t
 		OCSP_REASON_AA_COMPROMISE:          netext.OCSP_REASON_AA_COMPROMISE,
 
 		responseCallback: defaultExpectedStatuses.match,
+		cache:            httpext.NewCache(),
 	}
 }
 
@@ -98,6 +99,8 @@ type HTTP struct {
 	OCSP_REASON_AA_COMPROMISE          string `js:"OCSP_REASON_AA_COMPROMISE"`
 
 	responseCallback func(int) bool
+	// The emulated browser cache of the VU, for the httpCache option and the cache param.
+	cache *httpext.Cache
 }
 
 // XCookieJar creates a new cookie jar object.
@@ -114,6 +117,12 @@ func (*HTTP) CookieJar(ctx context.Context) (*HTTPCookieJar, error) {
 	return &HTTPCookieJar{state.CookieJar, &ctx}, nil
 }
 
+// ClearCache removes the responses of the emulated browser cache of the VU, so the next requests
+// aren't conditional, like the requests of a new user.
+func (h *HTTP) ClearCache() {
+	h.cache.Clear()
+}
+
 // URL creates a new URL from the provided parts
 func (*HTTP) URL(parts []string, pieces ...string) (httpext.URL, error) {
 	var name, urlstr string
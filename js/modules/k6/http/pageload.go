@@ -0,0 +1,175 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/netext/httpext"
+	"go.k6.io/k6/stats"
+)
+
+// ErrPageLoadForbiddenInInitContext is used when a page load was made in the init context
+var ErrPageLoadForbiddenInInitContext = common.NewInitContextError(
+	"Using pageLoad in the init context is not supported")
+
+// PageLoadTiming is the start and the end of a request of a page load, in milliseconds from the
+// start of the page load.
+type PageLoadTiming struct {
+	ID    string  `js:"id"`
+	Start float64 `js:"start"`
+	End   float64 `js:"end"`
+}
+
+// PageLoadResult is the result of http.pageLoad().
+type PageLoadResult struct {
+	Duration  float64          `js:"duration"`
+	Responses []*Response      `js:"responses"`
+	Waterfall []PageLoadTiming `js:"waterfall"`
+}
+
+// PageLoad makes the requests of the resources of a page like a browser does. The resources are
+// batch requests, the ones with the object form can have an id and the ids of the resources they
+// depend on in dependsOn, and they are only requested after their dependencies have finished.
+// The in-flight requests are limited by the batch and batchPerHost params, which default to the
+// options with the same names. The duration of the whole page load is emitted as the
+// page_load_time metric, with the tags of the params, which are also the default tags of the
+// requests.
+//nolint:funlen,gocognit
+func (h *HTTP) PageLoad(ctx context.Context, resourcesV goja.Value, paramsV goja.Value) (*PageLoadResult, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrPageLoadForbiddenInInitContext
+	}
+	rt := common.GetRuntime(ctx)
+
+	globalLimit, perHostLimit := int(state.Options.Batch.Int64), int(state.Options.BatchPerHost.Int64)
+	tags := make(map[string]string)
+	if paramsV != nil && !goja.IsUndefined(paramsV) && !goja.IsNull(paramsV) {
+		params := paramsV.ToObject(rt)
+		for _, k := range params.Keys() {
+			switch k {
+			case "batch":
+				globalLimit = int(params.Get(k).ToInteger())
+			case "batchPerHost":
+				perHostLimit = int(params.Get(k).ToInteger())
+			case "tags":
+				tagsV := params.Get(k)
+				if goja.IsUndefined(tagsV) || goja.IsNull(tagsV) {
+					continue
+				}
+				tagObj := tagsV.ToObject(rt)
+				for _, key := range tagObj.Keys() {
+					tags[key] = tagObj.Get(key).String()
+				}
+			}
+		}
+	}
+
+	resources, ok := resourcesV.Export().([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid http.pageLoad() argument type %T", resourcesV.Export())
+	}
+	ids := make([]string, len(resources))
+	indexes := make(map[string]int, len(resources))
+	for i, res := range resources {
+		ids[i] = strconv.Itoa(i)
+		if data, ok := res.(map[string]interface{}); ok && data["id"] != nil {
+			ids[i] = fmt.Sprint(data["id"])
+		}
+		if _, ok := indexes[ids[i]]; ok {
+			return nil, fmt.Errorf("the id '%s' of the resources of http.pageLoad() isn't unique", ids[i])
+		}
+		indexes[ids[i]] = i
+	}
+
+	result := &PageLoadResult{
+		Responses: make([]*Response, len(resources)),
+		Waterfall: make([]PageLoadTiming, len(resources)),
+	}
+	requests := make([]httpext.PageLoadRequest, len(resources))
+	for i, res := range resources {
+		resp := httpext.NewResponse(ctx)
+		result.Responses[i] = h.responseFromHttpext(resp)
+		result.Waterfall[i].ID = ids[i]
+
+		parsedReq, err := h.parseBatchRequest(ctx, i, res)
+		if err != nil {
+			resp.Error = err.Error()
+			var k6e httpext.K6Error
+			if errors.As(err, &k6e) {
+				resp.ErrorCode = int(k6e.Code)
+			}
+			if state.Options.Throw.Bool {
+				return nil, err
+			}
+			state.Logger.WithField("error", err).Warn("A page load request failed")
+			return result, nil
+		}
+		for k, v := range tags {
+			if _, ok := parsedReq.Tags[k]; !ok {
+				parsedReq.Tags[k] = v
+			}
+		}
+		requests[i].BatchParsedHTTPRequest = httpext.BatchParsedHTTPRequest{
+			ParsedHTTPRequest: parsedReq,
+			Response:          resp,
+		}
+
+		data, _ := res.(map[string]interface{})
+		deps, _ := data["dependsOn"].([]interface{})
+		for _, dep := range deps {
+			index, ok := indexes[fmt.Sprint(dep)]
+			if !ok {
+				return nil, fmt.Errorf("unknown dependency '%v' of the resource '%s' of http.pageLoad()", dep, ids[i])
+			}
+			requests[i].DependsOn = append(requests[i].DependsOn, index)
+		}
+	}
+
+	if err := httpext.ValidatePageLoad(requests); err != nil {
+		return nil, err
+	}
+	duration, err := httpext.MakePageLoadRequests(ctx, requests, globalLimit, perHostLimit, processResponse)
+	for i, req := range requests {
+		result.Waterfall[i].Start = stats.D(req.Start)
+		result.Waterfall[i].End = stats.D(req.End)
+	}
+	result.Duration = stats.D(duration)
+
+	sampleTags := state.CloneTags()
+	for k, v := range tags {
+		sampleTags[k] = v
+	}
+	stats.PushIfNotDone(ctx, state.Samples, stats.Sample{
+		Metric: metrics.PageLoadTime, Time: time.Now(), Tags: stats.IntoSampleTags(&sampleTags), Value: result.Duration,
+	})
+	return result, err
+}
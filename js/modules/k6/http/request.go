@@ -107,17 +107,7 @@ func (h *HTTP) Request(ctx context.Context, method string, url goja.Value, args
 
 	req, err := h.parseRequest(ctx, method, url, body, params)
 	if err != nil {
-		if state.Options.Throw.Bool {
-			return nil, err
-		}
-		state.Logger.WithField("error", err).Warn("Request Failed")
-		r := httpext.NewResponse(ctx)
-		r.Error = err.Error()
-		var k6e httpext.K6Error
-		if errors.As(err, &k6e) {
-			r.ErrorCode = int(k6e.Code)
-		}
-		return &Response{Response: r}, nil
+		return h.failedRequest(ctx, err)
 	}
 
 	resp, err := httpext.MakeRequest(ctx, req)
@@ -128,6 +118,87 @@ func (h *HTTP) Request(ctx context.Context, method string, url goja.Value, args
 	return h.responseFromHttpext(resp), nil
 }
 
+// AsyncRequest makes an http request like Request, but it returns a promise that is resolved with
+// the response, or rejected with the error, and the VU doesn't wait for the request in the meantime.
+// The requests that are in flight are limited by the asyncRequests and asyncRequestsPerHost options.
+func (h *HTTP) AsyncRequest(ctx context.Context, method string, url goja.Value, args ...goja.Value) (*goja.Object, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrHTTPForbiddenInInitContext
+	}
+	loop := common.GetEventLoop(ctx)
+	promise, resolve, reject := loop.NewPromise()
+
+	var body interface{}
+	var params goja.Value
+
+	if len(args) > 0 {
+		body = args[0].Export()
+	}
+	if len(args) > 1 {
+		params = args[1]
+	}
+
+	req, err := h.parseRequest(ctx, method, url, body, params)
+	if err != nil {
+		resp, err := h.failedRequest(ctx, err)
+		if err != nil {
+			reject(err)
+		} else {
+			resolve(resp)
+		}
+		return promise, nil
+	}
+
+	// The VU keeps on running while the request is made, so it gets a copy of the state, with the
+	// tags that are current now.
+	reqState := *state
+	reqState.Tags = state.CloneTags()
+	reqCtx := lib.WithState(ctx, &reqState)
+
+	callback := loop.RegisterCallback()
+	go func() {
+		// the host slot is taken first, so the requests to a busy host don't hold up the others
+		var hostLimit lib.SlotLimiter
+		if state.AsyncRequestsPerHostLimit != nil {
+			hostLimit = state.AsyncRequestsPerHostLimit.Slot(req.URL.GetURL().Host)
+		}
+		hostLimit.Begin()
+		state.AsyncRequestsLimit.Begin()
+		resp, err := httpext.MakeRequest(reqCtx, req)
+		state.AsyncRequestsLimit.End()
+		hostLimit.End()
+
+		callback(func() error {
+			if err != nil {
+				reject(err)
+				return nil
+			}
+			processResponse(reqCtx, resp, req.ResponseType)
+			resolve(h.responseFromHttpext(resp))
+			return nil
+		})
+	}()
+	return promise, nil
+}
+
+// failedRequest returns the error of a request that couldn't be made if the throw option is
+// enabled, or a response with it otherwise.
+func (h *HTTP) failedRequest(ctx context.Context, err error) (*Response, error) {
+	state := lib.GetState(ctx)
+	if state.Options.Throw.Bool {
+		return nil, err
+	}
+	state.Logger.WithField("error", err).Warn("Request Failed")
+	r := httpext.NewResponse(ctx)
+	r.Error = err.Error()
+	var k6e httpext.K6Error
+	if errors.As(err, &k6e) {
+		r.ErrorCode = int(k6e.Code)
+	}
+	return &Response{Response: r}, nil
+}
+
 //TODO break this function up
 //nolint: gocyclo
 func (h *HTTP) parseRequest(
@@ -167,6 +238,9 @@ func (h *HTTP) parseRequest(
 	} else {
 		result.ResponseType = httpext.ResponseTypeText
 	}
+	if state.Options.HTTPCache.Bool {
+		result.Cache = h.cache
+	}
 
 	formatFormVal := func(v interface{}) string {
 		// TODO: handle/warn about unsupported/nested values
@@ -361,6 +435,19 @@ func (h *HTTP) parseRequest(
 				for _, key := range tagObj.Keys() {
 					result.Tags[key] = tagObj.Get(key).String()
 				}
+			case "metadata":
+				metadataV := params.Get(k)
+				if goja.IsUndefined(metadataV) || goja.IsNull(metadataV) {
+					continue
+				}
+				metadataObj := metadataV.ToObject(rt)
+				if metadataObj == nil {
+					continue
+				}
+				result.Metadata = make(map[string]string, len(metadataObj.Keys()))
+				for _, key := range metadataObj.Keys() {
+					result.Metadata[key] = metadataObj.Get(key).String()
+				}
 			case "auth":
 				result.Auth = params.Get(k).String()
 			case "timeout":
@@ -369,8 +456,47 @@ func (h *HTTP) parseRequest(
 					return nil, fmt.Errorf("invalid timeout value: %w", err)
 				}
 				result.Timeout = t
+			case "totalTimeout":
+				t, err := types.GetDurationValue(params.Get(k).Export())
+				if err != nil {
+					return nil, fmt.Errorf("invalid totalTimeout value: %w", err)
+				}
+				result.TotalTimeout = t
+			case "retries":
+				retries := params.Get(k).ToInteger()
+				if retries < 0 {
+					return nil, fmt.Errorf("invalid retries value: %d, it can't be negative", retries)
+				}
+				result.Retries = retries
 			case "throw":
 				result.Throw = params.Get(k).ToBoolean()
+			case "cache":
+				if params.Get(k).ToBoolean() {
+					result.Cache = h.cache
+				} else {
+					result.Cache = nil
+				}
+			case "chunkSize":
+				result.ChunkSize = params.Get(k).ToInteger()
+				if result.ChunkSize < 0 {
+					return nil, fmt.Errorf("invalid chunkSize value: %d", result.ChunkSize)
+				}
+			case "chunkInterval":
+				t, err := types.GetDurationValue(params.Get(k).Export())
+				if err != nil {
+					return nil, fmt.Errorf("invalid chunkInterval value: %w", err)
+				}
+				result.ChunkInterval = t
+			case "trailers":
+				trailersV := params.Get(k)
+				if goja.IsUndefined(trailersV) || goja.IsNull(trailersV) {
+					continue
+				}
+				trailersObj := trailersV.ToObject(rt)
+				result.Trailers = make(map[string]string, len(trailersObj.Keys()))
+				for _, key := range trailersObj.Keys() {
+					result.Trailers[key] = trailersObj.Get(key).String()
+				}
 			case "responseType":
 				responseType, err := httpext.ResponseTypeString(params.Get(k).String())
 				if err != nil {
@@ -25,6 +25,7 @@ import (
 	"compress/gzip"
 	"compress/zlib"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -34,6 +35,8 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -50,10 +53,12 @@ import (
 
 	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/js/compiler"
+	"go.k6.io/k6/js/eventloop"
 	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/metrics"
 	"go.k6.io/k6/lib/testutils"
 	"go.k6.io/k6/lib/testutils/httpmultibin"
+	"go.k6.io/k6/lib/types"
 	"go.k6.io/k6/stats"
 )
 
@@ -350,6 +355,138 @@ func TestRequestAndBatch(t *testing.T) {
 			logEntry := hook.LastEntry()
 			assert.Nil(t, logEntry)
 		})
+		t.Run("totalTimeout", func(t *testing.T) {
+			_, err := rt.RunString(sr(`
+				var res = http.get("HTTPBIN_URL/redirect/2", { timeout: "2s", totalTimeout: "5s" });
+				if (res.status != 200) { throw new Error("wrong status: " + res.status); }
+				if (res.budget.total != 5000) { throw new Error("wrong budget: " + res.budget.total); }
+				if (res.budget.consumed + res.budget.remaining != 5000) {
+					throw new Error("wrong consumed budget: " + res.budget.consumed);
+				}
+			`))
+			assert.NoError(t, err)
+		})
+		t.Run("totalTimeout exceeded", func(t *testing.T) {
+			_, err := rt.RunString(sr(`
+				http.get("HTTPBIN_URL/delay/10", { timeout: "5s", totalTimeout: "1s" });
+			`))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "request timeout")
+		})
+		t.Run("retries", func(t *testing.T) {
+			var attempts int64
+			tb.Mux.HandleFunc("/flaky", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt64(&attempts, 1) < 3 {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			_, err := rt.RunString(sr(`
+				var res = http.get("HTTPBIN_URL/flaky", { retries: 2, totalTimeout: "5s" });
+				if (res.status != 200) { throw new Error("wrong status: " + res.status); }
+			`))
+			assert.NoError(t, err)
+			assert.Equal(t, int64(3), atomic.LoadInt64(&attempts))
+
+			_, err = rt.RunString(`http.get("HTTPBIN_URL/flaky", { retries: -1 })`)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid retries value")
+		})
+		t.Run("no totalTimeout", func(t *testing.T) {
+			_, err := rt.RunString(sr(`
+				var res = http.get("HTTPBIN_URL/get");
+				if (res.budget.total !== 0) { throw new Error("unexpected budget: " + res.budget.total); }
+			`))
+			assert.NoError(t, err)
+		})
+	})
+	t.Run("Chunked", func(t *testing.T) {
+		tb.Mux.HandleFunc("/chunked-echo", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			w.Header().Set("X-Transfer-Encoding", strings.Join(r.TransferEncoding, ","))
+			w.Header().Set("X-Trailer", r.Trailer.Get("X-Checksum"))
+			for _, part := range bytes.SplitAfter(body, []byte(",")) {
+				_, _ = w.Write(part)
+				w.(http.Flusher).Flush()
+				time.Sleep(10 * time.Millisecond)
+			}
+		}))
+		_, err := rt.RunString(sr(`
+			var res = http.post("HTTPBIN_URL/chunked-echo", "a,b,c", {
+				chunkSize: 2, chunkInterval: "10ms", trailers: { "X-Checksum": "abc" },
+			});
+			if (res.body !== "a,b,c") { throw new Error("wrong body: " + res.body); }
+			if (res.headers["X-Transfer-Encoding"] !== "chunked") { throw new Error("the request wasn't chunked"); }
+			if (res.headers["X-Trailer"] !== "abc") { throw new Error("wrong trailer: " + res.headers["X-Trailer"]); }
+			if (res.chunks.length !== 3 || res.chunks[2].size !== 1 || res.chunks[2].time <= res.chunks[0].time) {
+				throw new Error("wrong chunks: " + JSON.stringify(res.chunks));
+			}
+		`))
+		assert.NoError(t, err)
+
+		_, err = rt.RunString(`http.post("HTTPBIN_URL/chunked-echo", "a", { chunkSize: -1 })`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid chunkSize value: -1")
+	})
+	t.Run("Cache", func(t *testing.T) {
+		tb.Mux.HandleFunc("/cached", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"abc"`)
+			if r.Header.Get("If-None-Match") == `"abc"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			_, _ = w.Write([]byte("fresh"))
+		}))
+		_, err := rt.RunString(sr(`
+			var statuses = [];
+			for (var i = 0; i < 2; i++) {
+				var res = http.get("HTTPBIN_URL/cached", { cache: true });
+				if (res.body !== "fresh") { throw new Error("wrong body: " + res.body); }
+				statuses.push(res.status);
+			}
+			statuses.push(http.get("HTTPBIN_URL/cached").status);
+			http.clearCache();
+			statuses.push(http.get("HTTPBIN_URL/cached", { cache: true }).status);
+			if (statuses.join(",") !== "200,304,200,200") { throw new Error("wrong statuses: " + statuses); }
+		`))
+		assert.NoError(t, err)
+	})
+	t.Run("PageLoad", func(t *testing.T) {
+		_, err := rt.RunString(sr(`
+			var page = http.pageLoad([
+				{ id: "doc", url: "HTTPBIN_URL/get" },
+				{ id: "css", url: "HTTPBIN_URL/delay/0", dependsOn: ["doc"] },
+				{ id: "img", method: "POST", url: "HTTPBIN_URL/post", body: "x", dependsOn: ["doc", "css"] },
+				"HTTPBIN_URL/headers",
+			], { batchPerHost: 1, tags: { page: "home" } });
+			if (page.responses.length !== 4 || page.responses[2].status !== 200) {
+				throw new Error("wrong responses: " + JSON.stringify(page.responses));
+			}
+			if (page.waterfall[2].id !== "img" || page.waterfall[3].id !== "3") {
+				throw new Error("wrong ids: " + JSON.stringify(page.waterfall));
+			}
+			if (page.waterfall[2].start < page.waterfall[1].end || page.duration < page.waterfall[2].end) {
+				throw new Error("wrong waterfall: " + JSON.stringify(page));
+			}
+		`))
+		assert.NoError(t, err)
+		var pageLoads int
+		for _, container := range stats.GetBufferedSamples(samples) {
+			for _, sample := range container.GetSamples() {
+				if sample.Metric == metrics.PageLoadTime {
+					pageLoads++
+					assert.Equal(t, "home", sample.Tags.CloneTags()["page"])
+				}
+			}
+		}
+		assert.Equal(t, 1, pageLoads)
+
+		_, err = rt.RunString(sr(`http.pageLoad([{ id: "a", url: "HTTPBIN_URL/get", dependsOn: ["a"] }])`))
+		assert.Contains(t, err.Error(), "circular dependencies")
+		_, err = rt.RunString(sr(`http.pageLoad([{ url: "HTTPBIN_URL/get", dependsOn: ["b"] }])`))
+		assert.Contains(t, err.Error(), "unknown dependency 'b' of the resource '0'")
 	})
 	t.Run("UserAgent", func(t *testing.T) {
 		_, err := rt.RunString(sr(`
@@ -951,6 +1088,33 @@ func TestRequestAndBatch(t *testing.T) {
 				assert.NoError(t, err)
 				assertRequestMetricsEmitted(t, stats.GetBufferedSamples(samples), "GET", sr("HTTPBIN_URL/cookies"), "", 200, "")
 			})
+
+			t.Run("exportImport", func(t *testing.T) {
+				cookieJar, err := cookiejar.New(nil)
+				assert.NoError(t, err)
+				state.CookieJar = cookieJar
+				_, err = rt.RunString(sr(`
+				var jar = http.cookieJar();
+				jar.set("HTTPBIN_URL/cookies", "session", "abc");
+				jar.set("HTTPBIN_URL/cookies", "theme", "dark");
+				var exported = JSON.parse(JSON.stringify(jar.export(["HTTPBIN_URL/cookies"])));
+				if (exported.length != 2 || exported[0].url != "HTTPBIN_URL/cookies") {
+					throw new Error("wrong export: " + JSON.stringify(exported));
+				}
+				var other = new http.CookieJar();
+				other.import(exported);
+				var res = http.request("GET", "HTTPBIN_URL/cookies", null, { jar: other });
+				if (res.json().session != "abc" || res.json().theme != "dark") {
+					throw new Error("wrong cookies: " + res.body);
+				}
+				`))
+				assert.NoError(t, err)
+				assertRequestMetricsEmitted(t, stats.GetBufferedSamples(samples), "GET", sr("HTTPBIN_URL/cookies"), "", 200, "")
+
+				_, err = rt.RunString(`new http.CookieJar().import([{ url: "http://example.com" }])`)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "the cookie for http://example.com doesn't have a name")
+			})
 		})
 
 		t.Run("auth", func(t *testing.T) {
@@ -1114,6 +1278,23 @@ func TestRequestAndBatch(t *testing.T) {
 				}
 			})
 
+			t.Run("metadata", func(t *testing.T) {
+				_, err := rt.RunString(sr(`
+				var res = http.request("GET", "HTTPBIN_URL/headers", null, { metadata: { trace_id: "abc" } });
+				if (res.status != 200) { throw new Error("wrong status: " + res.status); }
+				`))
+				assert.NoError(t, err)
+				bufSamples := stats.GetBufferedSamples(samples)
+				assertRequestMetricsEmitted(t, bufSamples, "GET", sr("HTTPBIN_URL/headers"), "", 200, "")
+				for _, sampleC := range bufSamples {
+					for _, sample := range sampleC.GetSamples() {
+						assert.Equal(t, map[string]string{"trace_id": "abc"}, sample.Metadata)
+						_, ok := sample.Tags.Get("trace_id")
+						assert.False(t, ok)
+					}
+				}
+			})
+
 			t.Run("tags-precedence", func(t *testing.T) {
 				oldTags := state.Tags
 				defer func() { state.Tags = oldTags }()
@@ -1147,6 +1328,26 @@ func TestRequestAndBatch(t *testing.T) {
 					}
 				}
 			})
+			t.Run("url-groups", func(t *testing.T) {
+				oldOpts := state.Options
+				defer func() { state.Options = oldOpts }()
+				group, err := types.NewURLGroup("", "", "/status/{code}")
+				require.NoError(t, err)
+				state.Options.URLGroups = types.URLGroups{group}
+
+				_, err = rt.RunString(sr(`
+				var res = http.get("HTTPBIN_URL/status/200?a=1");
+				if (res.status != 200) { throw new Error("wrong status: " + res.status); }
+				res = http.get("HTTPBIN_URL/status/200", { tags: { name: "myName" } });
+				if (res.status != 200) { throw new Error("wrong status: " + res.status); }
+				`))
+				assert.NoError(t, err)
+
+				bufSamples := stats.GetBufferedSamples(samples)
+				assertRequestMetricsEmitted(t, bufSamples, "GET", sr("HTTPBIN_URL/status/200?a=1"),
+					sr("HTTPBIN_URL/status/{code}"), 200, "")
+				assertRequestMetricsEmitted(t, bufSamples, "GET", sr("HTTPBIN_URL/status/200"), "myName", 200, "")
+			})
 		})
 	})
 
@@ -2202,7 +2403,7 @@ func TestRedirectMetricTags(t *testing.T) {
 
 	checkTags := func(sc stats.SampleContainer, expTags map[string]string) {
 		allSamples := sc.GetSamples()
-		assert.Len(t, allSamples, 9)
+		assert.Len(t, allSamples, 11)
 		for _, s := range allSamples {
 			assert.Equal(t, expTags, s.Tags.CloneTags())
 		}
@@ -2425,3 +2626,80 @@ func TestBinaryResponseWithStatus0(t *testing.T) {
 	`)
 	require.NoError(t, err)
 }
+
+func TestAsyncRequest(t *testing.T) {
+	t.Parallel()
+	tb, state, _, rt, ctx := newRuntime(t)
+	sr := tb.Replacer.Replace
+
+	var inFlight, maxInFlight int64
+	var lock sync.Mutex
+	tb.Mux.HandleFunc("/async", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		lock.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+		_, _ = fmt.Fprint(w, r.URL.Query().Get("n"))
+	}))
+
+	loop, err := eventloop.New(rt)
+	require.NoError(t, err)
+	*ctx = common.WithEventLoop(*ctx, loop)
+	state.AsyncRequestsLimit = lib.NewSlotLimiter(2)
+	state.AsyncRequestsPerHostLimit = lib.NewMultiSlotLimiter(6)
+
+	run := func(src string) error {
+		return loop.Start(*ctx, func() error {
+			_, err := rt.RunString(sr(src))
+			return err
+		})
+	}
+
+	t.Run("limits", func(t *testing.T) {
+		require.NoError(t, run(`
+			var bodies;
+			var requests = [1, 2, 3, 4, 5].map(function(n) {
+				return http.asyncRequest("GET", "HTTPBIN_URL/async?n=" + n);
+			});
+			var sync = http.get("HTTPBIN_URL/async?n=sync");
+			Promise.all(requests).then(function(responses) {
+				bodies = responses.map(function(r) { return r.status + ":" + r.body; }).join(",");
+			});
+		`))
+		assert.Equal(t, "200:1,200:2,200:3,200:4,200:5", rt.Get("bodies").String())
+		assert.Equal(t, int64(3), maxInFlight) // the two async requests and the synchronous one
+	})
+
+	t.Run("chained", func(t *testing.T) {
+		require.NoError(t, run(`
+			var chained;
+			http.asyncRequest("GET", "HTTPBIN_URL/async?n=2").then(function(res) {
+				return http.asyncRequest("POST", "HTTPBIN_URL/post", { n: res.body });
+			}).then(function(res) {
+				chained = res.json().form.n[0];
+			});
+		`))
+		assert.Equal(t, "2", rt.Get("chained").String())
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		require.NoError(t, run(`
+			var rejected;
+			http.asyncRequest("GET", "HTTPBIN_URL/async", null, { timeout: "invalid" }).catch(function(e) {
+				rejected = String(e);
+			});
+		`))
+		assert.Contains(t, rt.Get("rejected").String(), "invalid")
+
+		err := run(`http.asyncRequest("GET", "http://127.0.0.1:1/");`)
+		var rejection *eventloop.UnhandledRejectionError
+		require.True(t, errors.As(err, &rejection))
+		assert.Contains(t, err.Error(), "connection refused")
+	})
+}
@@ -33,6 +33,7 @@ import (
 
 	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/js/modules/k6/html"
+	"go.k6.io/k6/js/modules/k6/xml"
 	"go.k6.io/k6/lib/netext/httpext"
 )
 
@@ -88,6 +89,24 @@ func (res *Response) HTML(selector ...string) html.Selection {
 	return sel
 }
 
+// XML parses the body as XML and returns the document as an xml.Selection, or the nodes that the
+// XPath expression selects from it.
+func (res *Response) XML(xpath ...string) xml.Selection {
+	body, err := common.ToString(res.Body)
+	if err != nil {
+		common.Throw(common.GetRuntime(res.GetCtx()), err)
+	}
+
+	sel, err := xml.XML{}.ParseXML(res.GetCtx(), body)
+	if err != nil {
+		common.Throw(common.GetRuntime(res.GetCtx()), err)
+	}
+	if len(xpath) > 0 {
+		sel = sel.Find(xpath[0])
+	}
+	return sel
+}
+
 // JSON parses the body of a response as JSON and returns it to the goja VM.
 func (res *Response) JSON(selector ...string) goja.Value {
 	rt := common.GetRuntime(res.GetCtx())
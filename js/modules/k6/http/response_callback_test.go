@@ -122,6 +122,8 @@ func TestResponseCallbackInAction(t *testing.T) {
 		metrics.HTTPReqWaiting,
 		metrics.HTTPReqSending,
 		metrics.HTTPReqTLSHandshaking,
+		metrics.HTTPRespHeaderBytes,
+		metrics.HTTPRespHeaderCount,
 	}
 
 	allHTTPMetrics := append(HTTPMetricsWithoutFailed, metrics.HTTPReqFailed)
@@ -322,6 +324,8 @@ func TestResponseCallbackBatch(t *testing.T) {
 		metrics.HTTPReqWaiting,
 		metrics.HTTPReqSending,
 		metrics.HTTPReqTLSHandshaking,
+		metrics.HTTPRespHeaderBytes,
+		metrics.HTTPRespHeaderCount,
 	}
 
 	allHTTPMetrics := append(HTTPMetricsWithoutFailed, metrics.HTTPReqFailed)
@@ -436,6 +440,8 @@ func TestResponseCallbackInActionWithoutPassedTag(t *testing.T) {
 		metrics.HTTPReqSending,
 		metrics.HTTPReqWaiting,
 		metrics.HTTPReqTLSHandshaking,
+		metrics.HTTPRespHeaderBytes,
+		metrics.HTTPRespHeaderCount,
 	}
 	deleteSystemTag(state, stats.TagExpectedResponse.String())
 	httpModule := new(GlobalHTTP).NewModuleInstancePerVU().(*HTTP)
@@ -500,6 +506,8 @@ func TestDigestWithResponseCallback(t *testing.T) {
 		metrics.HTTPReqSending,
 		metrics.HTTPReqWaiting,
 		metrics.HTTPReqTLSHandshaking,
+		metrics.HTTPRespHeaderBytes,
+		metrics.HTTPRespHeaderCount,
 	}
 	_, err := rt.RunString(fmt.Sprintf(`
 		var res = http.get(%q,  { auth: "digest" });
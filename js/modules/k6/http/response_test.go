@@ -184,6 +184,30 @@ func TestResponse(t *testing.T) {
 			assertRequestMetricsEmitted(t, stats.GetBufferedSamples(samples), "GET", sr("HTTPBIN_URL/html"), "", 200, "::my group")
 		})
 	})
+	t.Run("Xml", func(t *testing.T) {
+		_, err := rt.RunString(sr(`
+			var res = http.request("GET", "HTTPBIN_URL/xml");
+			if (res.status != 200) { throw new Error("wrong status: " + res.status); }
+			var title = res.xml().find("/slideshow/@title").text();
+			if (title != "Sample Slide Show") { throw new Error("wrong title: " + title); }
+			var count = res.xml().evaluate("count(//slide)");
+			if (count != 2) { throw new Error("wrong number of slides: " + count); }
+		`))
+		assert.NoError(t, err)
+
+		t.Run("shorthand", func(t *testing.T) {
+			_, err := rt.RunString(`
+				var slide = res.xml("//slide[title='Overview']");
+				if (slide.attr("type") != "all") { throw new Error("wrong type: " + slide.xml()); }
+			`)
+			assert.NoError(t, err)
+		})
+
+		t.Run("Invalid", func(t *testing.T) {
+			_, err := rt.RunString(sr(`http.request("GET", "HTTPBIN_URL/get").xml();`))
+			assert.Contains(t, err.Error(), "the XML document doesn't have a root element")
+		})
+	})
 	t.Run("Json", func(t *testing.T) {
 		_, err := rt.RunString(sr(`
 			var res = http.request("GET", "HTTPBIN_URL/get?a=1&b=2");
@@ -24,6 +24,7 @@ package k6
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/rand"
 	"sync/atomic"
 	"time"
@@ -33,6 +34,7 @@ import (
 	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/types"
 	"go.k6.io/k6/stats"
 )
 
@@ -45,6 +47,9 @@ var ErrGroupInInitContext = common.NewInitContextError("Using group() in the ini
 // ErrCheckInInitContext is returned when check() are using in the init context.
 var ErrCheckInInitContext = common.NewInitContextError("Using check() in the init context is not supported")
 
+// ErrPaceInInitContext is returned when pace() are using in the init context.
+var ErrPaceInInitContext = common.NewInitContextError("Using pace() in the init context is not supported")
+
 // New returns a new module Struct.
 func New() *K6 {
 	return &K6{}
@@ -65,6 +70,90 @@ func (*K6) Sleep(ctx context.Context, secs float64) {
 	}
 }
 
+// Pace sleeps until the iteration has lasted the target duration, so the iterations of the VU
+// start at a steady pace regardless of how long their requests take. The target is a number of
+// seconds, like for sleep(), a duration string like "5s", or an object with either a duration or
+// the rate of iterations per timeUnit (1s by default) for the VU, e.g. { rate: 2, timeUnit: "1m" }.
+//
+// The time that it sleeps is emitted as the pacing_delay metric and the iterations that already
+// took longer than the target are counted by the pacing_overruns metric.
+func (*K6) Pace(ctx context.Context, target goja.Value) (goja.Value, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return goja.Undefined(), ErrPaceInInitContext
+	}
+	duration, err := paceDuration(common.GetRuntime(ctx), target)
+	if err != nil {
+		return goja.Undefined(), err
+	}
+
+	now := time.Now()
+	delay := state.IterationStartTime.Add(duration).Sub(now)
+	tags := state.CloneTags()
+	sampleTags := stats.IntoSampleTags(&tags)
+	if delay <= 0 {
+		stats.PushIfNotDone(ctx, state.Samples, stats.Sample{
+			Time: now, Metric: metrics.PacingOverruns, Tags: sampleTags, Value: 1,
+		})
+		delay = 0
+	}
+	stats.PushIfNotDone(ctx, state.Samples, stats.Sample{
+		Time: now, Metric: metrics.PacingDelay, Tags: sampleTags, Value: stats.D(delay),
+	})
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+	return goja.Undefined(), nil
+}
+
+func paceDuration(rt *goja.Runtime, target goja.Value) (time.Duration, error) {
+	if target == nil || goja.IsUndefined(target) || goja.IsNull(target) {
+		return 0, errors.New("pace() requires a target duration or rate")
+	}
+	var duration time.Duration
+	switch v := target.Export().(type) {
+	case int64:
+		duration = time.Duration(v) * time.Second
+	case float64:
+		duration = time.Duration(v * float64(time.Second))
+	case string:
+		d, err := types.ParseExtendedDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid pace() duration %q: %w", v, err)
+		}
+		duration = d
+	case map[string]interface{}:
+		obj := target.ToObject(rt)
+		if d := obj.Get("duration"); d != nil && !goja.IsUndefined(d) {
+			return paceDuration(rt, d)
+		}
+		rate := obj.Get("rate")
+		if rate == nil || goja.IsUndefined(rate) || rate.ToFloat() <= 0 {
+			return 0, errors.New("pace() requires either a duration or a positive rate")
+		}
+		timeUnit := time.Second
+		if u := obj.Get("timeUnit"); u != nil && !goja.IsUndefined(u) {
+			d, err := types.GetDurationValue(u.Export())
+			if err != nil {
+				return 0, fmt.Errorf("invalid pace() timeUnit: %w", err)
+			}
+			timeUnit = d
+		}
+		duration = time.Duration(float64(timeUnit) / rate.ToFloat())
+	default:
+		return 0, fmt.Errorf("invalid pace() target %s", target)
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("the pace() duration has to be positive, not %s", duration)
+	}
+	return duration, nil
+}
+
 // RandomSeed sets the seed to the random generator used for this VU.
 func (*K6) RandomSeed(ctx context.Context, seed int64) {
 	randSource := rand.New(rand.NewSource(seed)).Float64 //nolint:gosec
@@ -92,6 +92,90 @@ func TestSleep(t *testing.T) {
 	})
 }
 
+func TestPace(t *testing.T) {
+	t.Parallel()
+	setupPaceTest := func() (*goja.Runtime, *lib.State, chan stats.SampleContainer) {
+		rt := goja.New()
+		samples := make(chan stats.SampleContainer, 1000)
+		state := &lib.State{Samples: samples, Tags: map[string]string{}, IterationStartTime: time.Now()}
+
+		ctx := context.Background()
+		ctx = lib.WithState(ctx, state)
+		ctx = common.WithRuntime(ctx, rt)
+		require.NoError(t, rt.Set("k6", common.Bind(rt, New(), &ctx)))
+		return rt, state, samples
+	}
+
+	testdata := map[string]time.Duration{
+		`0.3`:                           300 * time.Millisecond,
+		`"300ms"`:                       300 * time.Millisecond,
+		`{ duration: "300ms" }`:         300 * time.Millisecond,
+		`{ rate: 200, timeUnit: "1m" }`: 300 * time.Millisecond,
+		`{ rate: 4, timeUnit: 1200 }`:   300 * time.Millisecond,
+	}
+	for target, d := range testdata {
+		target, d := target, d
+		t.Run(target, func(t *testing.T) {
+			t.Parallel()
+			rt, state, samples := setupPaceTest()
+			state.IterationStartTime = time.Now().Add(-100 * time.Millisecond)
+			startTime := time.Now()
+			_, err := rt.RunString(`k6.pace(` + target + `)`)
+			elapsed := time.Since(startTime)
+			require.NoError(t, err)
+			assert.True(t, elapsed >= d-100*time.Millisecond, "did not sleep long enough: %s", elapsed)
+			assert.True(t, elapsed < d, "slept for too long: %s", elapsed)
+
+			bufSamples := stats.GetBufferedSamples(samples)
+			require.Len(t, bufSamples, 1)
+			sample := bufSamples[0].GetSamples()[0]
+			assert.Equal(t, metrics.PacingDelay, sample.Metric)
+			assert.InDelta(t, float64(d-100*time.Millisecond)/float64(time.Millisecond), sample.Value, 50)
+		})
+	}
+
+	t.Run("Overrun", func(t *testing.T) {
+		t.Parallel()
+		rt, state, samples := setupPaceTest()
+		state.IterationStartTime = time.Now().Add(-time.Second)
+		_, err := rt.RunString(`k6.pace("500ms")`)
+		require.NoError(t, err)
+
+		bufSamples := stats.GetBufferedSamples(samples)
+		require.Len(t, bufSamples, 2)
+		assert.Equal(t, metrics.PacingOverruns, bufSamples[0].GetSamples()[0].Metric)
+		assert.Equal(t, metrics.PacingDelay, bufSamples[1].GetSamples()[0].Metric)
+		assert.Equal(t, 0.0, bufSamples[1].GetSamples()[0].Value)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+		rt, _, _ := setupPaceTest()
+		testdata := map[string]string{
+			``:            `pace() requires a target duration or rate`,
+			`"soon"`:      `invalid pace() duration "soon"`,
+			`-1`:          `the pace() duration has to be positive, not -1s`,
+			`{}`:          `pace() requires either a duration or a positive rate`,
+			`{ rate: 0 }`: `pace() requires either a duration or a positive rate`,
+			`true`:        `invalid pace() target true`,
+		}
+		for target, expected := range testdata {
+			_, err := rt.RunString(`k6.pace(` + target + `)`)
+			require.Error(t, err, target)
+			assert.Contains(t, err.Error(), expected, target)
+		}
+	})
+
+	t.Run("InitContext", func(t *testing.T) {
+		t.Parallel()
+		rt := goja.New()
+		ctx := common.WithRuntime(context.Background(), rt)
+		require.NoError(t, rt.Set("k6", common.Bind(rt, New(), &ctx)))
+		_, err := rt.RunString(`k6.pace(1)`)
+		assert.Contains(t, err.Error(), ErrPaceInInitContext.Error())
+	})
+}
+
 func TestRandSeed(t *testing.T) {
 	t.Parallel()
 	rt := goja.New()
@@ -0,0 +1,187 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package mail
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapClient is a minimal IMAP client, RFC 3501, with the commands the polling uses.
+type imapClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+func newIMAPClient(conn net.Conn) (*imapClient, error) {
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		return nil, fmt.Errorf("unexpected IMAP greeting: %s", greeting)
+	}
+	return c, nil
+}
+
+func (c *imapClient) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readResponse reads a response line with its literals, the {n} counts are kept and followed by
+// the literals.
+func (c *imapClient) readResponse() (string, error) {
+	var b strings.Builder
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(line)
+		start := strings.LastIndexByte(line, '{')
+		if !strings.HasSuffix(line, "}") || start < 0 {
+			return b.String(), nil
+		}
+		n, err := strconv.Atoi(line[start+1 : len(line)-1])
+		if err != nil || n < 0 {
+			return b.String(), nil //nolint:nilerr // it isn't a literal
+		}
+		literal := make([]byte, n)
+		if _, err = io.ReadFull(c.r, literal); err != nil {
+			return "", err
+		}
+		b.WriteString("\r\n")
+		b.Write(literal)
+	}
+}
+
+// cmd runs the command and returns its untagged responses, it fails if the command isn't OK.
+func (c *imapClient) cmd(format string, args ...interface{}) ([]string, error) {
+	c.tag++
+	tag := "k" + strconv.Itoa(c.tag)
+	if _, err := fmt.Fprintf(c.conn, tag+" "+format+"\r\n", args...); err != nil {
+		return nil, err
+	}
+	var untagged []string
+	for {
+		line, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case strings.HasPrefix(line, tag+" "):
+			status := strings.TrimPrefix(line, tag+" ")
+			if !strings.HasPrefix(status, "OK") {
+				return nil, fmt.Errorf("IMAP %s failed: %s", strings.Fields(format)[0], status)
+			}
+			return untagged, nil
+		case strings.HasPrefix(line, "* "):
+			untagged = append(untagged, strings.TrimPrefix(line, "* "))
+		}
+	}
+}
+
+func (c *imapClient) startTLS(config *tls.Config) error {
+	if _, err := c.cmd("STARTTLS"); err != nil {
+		return err
+	}
+	tlsConn := tls.Client(c.conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	c.conn, c.r = tlsConn, bufio.NewReader(tlsConn)
+	return nil
+}
+
+func (c *imapClient) login(username, password string) error {
+	_, err := c.cmd("LOGIN %s %s", quote(username), quote(password))
+	return err
+}
+
+// examine selects the mailbox in the read-only mode, so the polled mails aren't marked as seen.
+func (c *imapClient) examine(mailbox string) error {
+	_, err := c.cmd("EXAMINE %s", quote(mailbox))
+	return err
+}
+
+func (c *imapClient) noop() error {
+	_, err := c.cmd("NOOP")
+	return err
+}
+
+// findSent searches the mail with the message ID, and returns the time it was sent at.
+func (c *imapClient) findSent(messageID string) (time.Time, bool, error) {
+	responses, err := c.cmd("SEARCH HEADER Message-ID %s", quote(messageID))
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	var seq string
+	for _, r := range responses {
+		if fields := strings.Fields(r); len(fields) > 1 && strings.EqualFold(fields[0], "SEARCH") {
+			seq = fields[1]
+		}
+	}
+	if seq == "" {
+		return time.Time{}, false, nil
+	}
+
+	responses, err = c.cmd("FETCH %s (BODY.PEEK[HEADER.FIELDS (%s)])", seq, sentHeader)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	for _, r := range responses {
+		i := strings.Index(r, "\r\n")
+		if i < 0 {
+			continue
+		}
+		header, err := textproto.NewReader(bufio.NewReader(strings.NewReader(r[i+2:]))).ReadMIMEHeader()
+		if err != nil && !errors.Is(err, io.EOF) {
+			continue
+		}
+		if sent, err := time.Parse(time.RFC3339Nano, header.Get(sentHeader)); err == nil {
+			return sent, true, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("the mail %s doesn't have the %s header", messageID, sentHeader)
+}
+
+func (c *imapClient) close() {
+	_, _ = c.cmd("LOGOUT")
+	_ = c.conn.Close()
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
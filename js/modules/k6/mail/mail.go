@@ -0,0 +1,514 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package mail implements the k6/net/mail module, which sends mails with SMTP and polls the
+// mailboxes with IMAP, so the delivery latency of mail pipelines can be load tested.
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+// ErrMailInInitContext is returned when mails are sent or polled in the init context.
+var ErrMailInInitContext = common.NewInitContextError("Using mail in the init context is not supported")
+
+// sentHeader is the header with the time a mail was sent at, the delivery latency is measured
+// from it, so the mails can be polled by other VUs than the ones that sent them.
+const sentHeader = "X-K6-Sent"
+
+const (
+	defaultTimeout     = 10 * time.Second
+	defaultPollTimeout = 30 * time.Second
+	defaultInterval    = time.Second
+)
+
+// Mail is the k6/net/mail module.
+type Mail struct{}
+
+// New returns a new Mail module instance.
+func New() *Mail {
+	return &Mail{}
+}
+
+// SendResult is the result of the sending of a mail, its error is set if it couldn't be sent.
+type SendResult struct {
+	MessageID string  `js:"messageId"`
+	Duration  float64 `js:"duration"`
+	Error     string  `js:"error"`
+}
+
+// PollResult is the result of the polling of a mailbox for a mail.
+type PollResult struct {
+	Found bool `js:"found"`
+	// The time from the sending of the mail to its polling, in ms.
+	Latency  float64 `js:"latency"`
+	Duration float64 `js:"duration"`
+	Error    string  `js:"error"`
+}
+
+// connParams are the params of the connections to the SMTP and IMAP servers.
+type connParams struct {
+	server   string
+	security string
+	username string
+	password string
+	timeout  time.Duration
+	tags     map[string]string
+}
+
+// message are the params of a mail.
+type message struct {
+	from    string
+	to      []string
+	subject string
+	body    string
+	headers map[string]string
+}
+
+// Send sends a mail with SMTP, its params are:
+//   - server: the address of the server, host:port, it's required
+//   - security: starttls, which upgrades the connection when the server supports it and is the
+//     default, tls or none
+//   - username and password: the credentials for the PLAIN authentication
+//   - from, to, subject, body and headers: the mail, from and to are required
+//   - timeout: 10s by default
+//   - tags: the tags of the metrics
+func (m *Mail) Send(ctx context.Context, params map[string]interface{}) (*SendResult, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrMailInInitContext
+	}
+	p, msg, err := parseSendParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SendResult{MessageID: newMessageID()}
+	start := time.Now()
+	err = sendMail(ctx, state, p, msg, result.MessageID, start)
+	end := time.Now()
+	result.Duration = stats.D(end.Sub(start))
+
+	tags := sampleTags(state, p, "smtp", err)
+	stats.PushIfNotDone(ctx, state.Samples, stats.ConnectedSamples{
+		Samples: []stats.Sample{
+			{Metric: metrics.MailSent, Time: end, Tags: tags, Value: 1},
+			{Metric: metrics.MailSendDuration, Time: end, Tags: tags, Value: result.Duration},
+		},
+		Tags: tags,
+		Time: end,
+	})
+	if err != nil {
+		if state.Options.Throw.Bool {
+			return nil, err
+		}
+		state.Logger.WithField("error", err).Warn("Sending the mail failed")
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
+// Poll polls a mailbox with IMAP until it has the mail with the message ID, its params are:
+//   - server: the address of the server, host:port, it's required
+//   - security: tls, the default, starttls or none
+//   - username and password: the credentials of the mailbox, they're required
+//   - mailbox: INBOX by default
+//   - messageId: the message ID returned by send, it's required
+//   - timeout: how long the mailbox is polled, 30s by default
+//   - interval: the time between the polls, 1s by default
+//   - tags: the tags of the metrics
+func (m *Mail) Poll(ctx context.Context, params map[string]interface{}) (*PollResult, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrMailInInitContext
+	}
+	p, mailbox, messageID, interval, err := parsePollParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PollResult{}
+	start := time.Now()
+	sent, err := pollMail(ctx, state, p, mailbox, messageID, interval)
+	end := time.Now()
+	result.Duration = stats.D(end.Sub(start))
+	if err == nil && !sent.IsZero() {
+		result.Found = true
+		result.Latency = stats.D(end.Sub(sent))
+		tags := sampleTags(state, p, "imap", nil)
+		stats.PushIfNotDone(ctx, state.Samples, stats.Sample{
+			Metric: metrics.MailDeliveryLatency, Time: end, Tags: tags, Value: result.Latency,
+		})
+	}
+	if err != nil {
+		if state.Options.Throw.Bool {
+			return nil, err
+		}
+		state.Logger.WithField("error", err).Warn("Polling the mailbox failed")
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
+func sampleTags(state *lib.State, p connParams, proto string, err error) *stats.SampleTags {
+	tags := state.CloneTags()
+	for k, v := range p.tags {
+		tags[k] = v
+	}
+	tags["proto"] = proto
+	if err != nil {
+		tags["error"] = err.Error()
+	}
+	return stats.IntoSampleTags(&tags)
+}
+
+func newMessageID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@k6>"
+}
+
+// parseConnParam parses the param of the connections, it returns false if the key isn't one.
+func parseConnParam(p *connParams, k string, v interface{}) (bool, error) {
+	var ok bool
+	switch k {
+	case "server":
+		p.server, ok = v.(string)
+	case "security":
+		p.security, ok = v.(string)
+		ok = ok && (p.security == "starttls" || p.security == "tls" || p.security == "none")
+	case "username":
+		p.username, ok = v.(string)
+	case "password":
+		p.password, ok = v.(string)
+	case "timeout":
+		var err error
+		p.timeout, err = types.GetDurationValue(v)
+		ok = err == nil && p.timeout > 0
+	case "tags":
+		var raw map[string]interface{}
+		raw, ok = v.(map[string]interface{})
+		p.tags = make(map[string]string, len(raw))
+		for tk, tv := range raw {
+			p.tags[tk] = fmt.Sprint(tv)
+		}
+	default:
+		return false, nil
+	}
+	if !ok {
+		return true, fmt.Errorf("invalid mail %s value: %v", k, v)
+	}
+	return true, nil
+}
+
+// checkServer checks the server of the params, and adds the default port of the security if
+// it doesn't have one.
+func checkServer(p *connParams, tlsPort, plainPort string) error {
+	if p.server == "" {
+		return errors.New("the mail server is required")
+	}
+	if _, _, err := net.SplitHostPort(p.server); err != nil {
+		port := plainPort
+		if p.security == "tls" {
+			port = tlsPort
+		}
+		p.server = net.JoinHostPort(p.server, port)
+	}
+	return nil
+}
+
+func parseSendParams(params map[string]interface{}) (connParams, message, error) {
+	p := connParams{security: "starttls", timeout: defaultTimeout}
+	var msg message
+	for k, v := range params {
+		if isConn, err := parseConnParam(&p, k, v); isConn {
+			if err != nil {
+				return p, msg, err
+			}
+			continue
+		}
+		var ok bool
+		switch k {
+		case "from":
+			msg.from, ok = v.(string)
+		case "to":
+			msg.to, ok = toStrings(v)
+		case "subject":
+			msg.subject, ok = v.(string)
+		case "body":
+			msg.body, ok = v.(string)
+		case "headers":
+			var raw map[string]interface{}
+			raw, ok = v.(map[string]interface{})
+			msg.headers = make(map[string]string, len(raw))
+			for hk, hv := range raw {
+				msg.headers[hk] = fmt.Sprint(hv)
+			}
+		default:
+			return p, msg, fmt.Errorf("unknown mail param: %q", k)
+		}
+		if !ok {
+			return p, msg, fmt.Errorf("invalid mail %s value: %v", k, v)
+		}
+	}
+	if err := checkServer(&p, "465", "25"); err != nil {
+		return p, msg, err
+	}
+	if msg.from == "" || len(msg.to) == 0 {
+		return p, msg, errors.New("the from and to addresses of the mail are required")
+	}
+	return p, msg, nil
+}
+
+func toStrings(v interface{}) ([]string, bool) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, true
+	case []interface{}:
+		result := make([]string, len(v))
+		for i, s := range v {
+			var ok bool
+			if result[i], ok = s.(string); !ok {
+				return nil, false
+			}
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+func parsePollParams(
+	params map[string]interface{},
+) (p connParams, mailbox, messageID string, interval time.Duration, err error) {
+	p = connParams{security: "tls", timeout: defaultPollTimeout}
+	mailbox, interval = "INBOX", defaultInterval
+	for k, v := range params {
+		var isConn bool
+		if isConn, err = parseConnParam(&p, k, v); isConn {
+			if err != nil {
+				return
+			}
+			continue
+		}
+		var ok bool
+		switch k {
+		case "mailbox":
+			mailbox, ok = v.(string)
+		case "messageId":
+			messageID, ok = v.(string)
+		case "interval":
+			interval, err = types.GetDurationValue(v)
+			ok = err == nil && interval > 0
+		default:
+			err = fmt.Errorf("unknown mail param: %q", k)
+			return
+		}
+		if !ok {
+			err = fmt.Errorf("invalid mail %s value: %v", k, v)
+			return
+		}
+	}
+	if err = checkServer(&p, "993", "143"); err != nil {
+		return
+	}
+	switch {
+	case p.username == "" || p.password == "":
+		err = errors.New("the username and the password of the mailbox are required")
+	case messageID == "":
+		err = errors.New("the message ID of the polled mail is required")
+	}
+	return
+}
+
+// dial connects to the server with the dialer of the VU, the tls security is handled here and
+// the starttls one by the protocols.
+func dial(ctx context.Context, state *lib.State, p connParams) (net.Conn, error) {
+	conn, err := state.Dialer.DialContext(ctx, "tcp", p.server)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if p.security != "tls" {
+		return conn, nil
+	}
+	// the deadline of the connection from the context bounds the handshake too
+	tlsConn := tls.Client(conn, tlsConfig(state, p))
+	if err = tlsConn.Handshake(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func tlsConfig(state *lib.State, p connParams) *tls.Config {
+	config := state.TLSConfig.Clone()
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	config.ServerName, _, _ = net.SplitHostPort(p.server)
+	return config
+}
+
+func sendMail(
+	ctx context.Context, state *lib.State, p connParams, msg message, messageID string, sent time.Time,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	conn, err := dial(ctx, state, p)
+	if err != nil {
+		return err
+	}
+	host, _, _ := net.SplitHostPort(p.server)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && p.security == "starttls" {
+		if err = client.StartTLS(tlsConfig(state, p)); err != nil {
+			return err
+		}
+	}
+	if p.username != "" {
+		if err = client.Auth(smtp.PlainAuth("", p.username, p.password, host)); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(msg.from); err != nil {
+		return err
+	}
+	for _, to := range msg.to {
+		if err = client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(buildMessage(msg, messageID, sent)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage returns the mail with its headers, the custom ones are sorted.
+func buildMessage(msg message, messageID string, sent time.Time) []byte {
+	var b strings.Builder
+	header := func(k, v string) {
+		b.WriteString(k + ": " + strings.NewReplacer("\r", "", "\n", "").Replace(v) + "\r\n")
+	}
+	header("From", msg.from)
+	header("To", strings.Join(msg.to, ", "))
+	header("Subject", msg.subject)
+	header("Date", sent.Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	header(sentHeader, sent.UTC().Format(time.RFC3339Nano))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	keys := make([]string, 0, len(msg.headers))
+	for k := range msg.headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		header(k, msg.headers[k])
+	}
+	b.WriteString("\r\n")
+	body := strings.ReplaceAll(strings.ReplaceAll(msg.body, "\r\n", "\n"), "\n", "\r\n")
+	b.WriteString(body)
+	if !strings.HasSuffix(body, "\r\n") {
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String())
+}
+
+// pollMail polls the mailbox until it has the mail, it returns the time the mail was sent at,
+// or the zero time if the mail isn't found before the timeout.
+func pollMail(
+	ctx context.Context, state *lib.State, p connParams, mailbox, messageID string, interval time.Duration,
+) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	conn, err := dial(ctx, state, p)
+	if err != nil {
+		return time.Time{}, err
+	}
+	client, err := newIMAPClient(conn)
+	if err != nil {
+		_ = conn.Close()
+		return time.Time{}, err
+	}
+	defer client.close()
+
+	if p.security == "starttls" {
+		if err = client.startTLS(tlsConfig(state, p)); err != nil {
+			return time.Time{}, err
+		}
+	}
+	if err = client.login(p.username, p.password); err != nil {
+		return time.Time{}, err
+	}
+	if err = client.examine(mailbox); err != nil {
+		return time.Time{}, err
+	}
+	for {
+		sent, found, err := client.findSent(messageID)
+		if err == nil && !found {
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-time.After(interval):
+				err = client.noop()
+			}
+		}
+		switch {
+		case err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded):
+			// the mail wasn't delivered before the timeout, the connection times out with it
+			return time.Time{}, nil
+		case err != nil || found:
+			return sent, err
+		}
+	}
+}
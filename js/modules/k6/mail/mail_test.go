@@ -0,0 +1,329 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package mail
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/netext"
+	"go.k6.io/k6/stats"
+)
+
+// testMailbox is the mailbox of the test servers, the SMTP server adds the mails to it and the
+// IMAP server serves them.
+type testMailbox struct {
+	mu    sync.Mutex
+	mails []string
+	auth  []string
+}
+
+func (m *testMailbox) add(mail string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mails = append(m.mails, mail)
+}
+
+func (m *testMailbox) get() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.mails...)
+}
+
+func serve(t *testing.T, handle func(conn net.Conn)) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				handle(conn)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func newSMTPServer(t *testing.T, mailbox *testMailbox) string {
+	return serve(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = fmt.Fprint(conn, s+"\r\n") }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch cmd := strings.ToUpper(strings.Fields(line + " ")[0]); cmd {
+			case "EHLO":
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case "AUTH":
+				creds, _ := base64.StdEncoding.DecodeString(strings.Fields(line)[2])
+				mailbox.mu.Lock()
+				mailbox.auth = append(mailbox.auth, string(creds))
+				mailbox.mu.Unlock()
+				reply("235 OK")
+			case "MAIL", "RCPT":
+				if strings.Contains(line, "rejected@") {
+					reply("no such user")
+					continue
+				}
+				reply("250 OK")
+			case "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				mailbox.add(data.String())
+				reply("250 OK")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 unknown")
+			}
+		}
+	})
+}
+
+func newIMAPServer(t *testing.T, mailbox *testMailbox) string {
+	return serve(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = fmt.Fprint(conn, s+"\r\n") }
+		reply("* OK IMAP ready")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			tag, cmd := fields[0], strings.ToUpper(fields[1])
+			switch cmd {
+			case "LOGIN":
+				if fields[3] != `"secret"` {
+					reply(tag + " NO invalid credentials")
+					continue
+				}
+			case "EXAMINE":
+				reply(fmt.Sprintf("* %d EXISTS", len(mailbox.get())))
+			case "SEARCH":
+				id := strings.Trim(fields[4], `"`)
+				result := "* SEARCH"
+				for i, mail := range mailbox.get() {
+					if strings.Contains(mail, "Message-ID: "+id+"\r\n") {
+						result += fmt.Sprintf(" %d", i+1)
+					}
+				}
+				reply(result)
+			case "FETCH":
+				var seq int
+				_, _ = fmt.Sscan(fields[2], &seq)
+				mail := mailbox.get()[seq-1]
+				var header string
+				for _, h := range strings.Split(mail, "\r\n") {
+					if strings.HasPrefix(h, sentHeader+": ") {
+						header = h + "\r\n\r\n"
+					}
+				}
+				reply(fmt.Sprintf("* %d FETCH (BODY[HEADER.FIELDS (%s)] {%d}", seq, sentHeader, len(header)))
+				_, _ = fmt.Fprint(conn, header+")\r\n")
+			case "LOGOUT":
+				reply("* BYE")
+				reply(tag + " OK LOGOUT completed")
+				return
+			}
+			reply(tag + " OK " + cmd + " completed")
+		}
+	})
+}
+
+func TestMail(t *testing.T) {
+	t.Parallel()
+
+	mailbox := &testMailbox{}
+	smtpServer, imapServer := newSMTPServer(t, mailbox), newIMAPServer(t, mailbox)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	rt.Set("mail", common.Bind(rt, New(), &ctx))
+	rt.Set("smtpServer", smtpServer)
+	rt.Set("imapServer", imapServer)
+
+	t.Run("InitContext", func(t *testing.T) {
+		_, err := rt.RunString(`mail.send({ server: smtpServer, from: "k6@k6.test", to: "user@k6.test" })`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), ErrMailInInitContext.Error())
+	})
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{
+		Group:   root,
+		Dialer:  netext.NewDialer(net.Dialer{}, nil),
+		Samples: samples,
+		Logger:  logrus.New(),
+		Options: lib.Options{SystemTags: stats.NewSystemTagSet(stats.TagName)},
+	}
+	ctx = lib.WithState(ctx, state)
+
+	t.Run("SendAndPoll", func(t *testing.T) {
+		v, err := rt.RunString(`
+			var sent = mail.send({
+				server: smtpServer,
+				security: "none",
+				username: "k6",
+				password: "secret",
+				from: "k6@k6.test",
+				to: ["user@k6.test", "other@k6.test"],
+				subject: "Order confirmation",
+				body: "Thanks\nfor the order",
+				headers: { "X-Order": 42 },
+				tags: { pipeline: "orders" },
+			});
+			if (sent.error) throw new Error(sent.error);
+			var polled = mail.poll({
+				server: imapServer,
+				security: "none",
+				username: "k6",
+				password: "secret",
+				messageId: sent.messageId,
+				interval: "10ms",
+				tags: { pipeline: "orders" },
+			});
+			if (!polled.found || polled.latency <= 0 || polled.error) throw new Error(JSON.stringify(polled));
+			sent.messageId
+		`)
+		require.NoError(t, err)
+
+		mails := mailbox.get()
+		require.Len(t, mails, 1)
+		assert.Contains(t, mails[0], "Message-ID: "+v.String()+"\r\n")
+		assert.Contains(t, mails[0], "To: user@k6.test, other@k6.test\r\n")
+		assert.Contains(t, mails[0], "Subject: Order confirmation\r\n")
+		assert.Contains(t, mails[0], "X-Order: 42\r\n")
+		assert.True(t, strings.HasSuffix(mails[0], "\r\n\r\nThanks\r\nfor the order\r\n"), mails[0])
+		assert.Equal(t, []string{"\x00k6\x00secret"}, mailbox.auth)
+
+		seen := map[*stats.Metric]int{}
+		for _, container := range stats.GetBufferedSamples(samples) {
+			for _, sample := range container.GetSamples() {
+				seen[sample.Metric]++
+				tags := sample.Tags.CloneTags()
+				assert.Equal(t, "orders", tags["pipeline"])
+			}
+		}
+		assert.Equal(t, map[*stats.Metric]int{
+			metrics.MailSent: 1, metrics.MailSendDuration: 1, metrics.MailDeliveryLatency: 1,
+		}, seen)
+	})
+
+	t.Run("PollNotFound", func(t *testing.T) {
+		start := time.Now()
+		v, err := rt.RunString(`
+			var polled = mail.poll({
+				server: imapServer,
+				security: "none",
+				username: "k6",
+				password: "secret",
+				messageId: "<missing@k6>",
+				timeout: "100ms",
+				interval: "10ms",
+			});
+			polled.found + " " + polled.error
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "false ", v.String())
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		v, err := rt.RunString(`
+			mail.send({ server: smtpServer, security: "none", from: "k6@k6.test", to: "rejected@k6.test" }).error
+		`)
+		require.NoError(t, err)
+		assert.Contains(t, v.String(), "no such user")
+
+		v, err = rt.RunString(`
+			mail.poll({ server: imapServer, security: "none", username: "k6", password: "wrong", messageId: "<id@k6>" }).error
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "IMAP LOGIN failed: NO invalid credentials", v.String())
+
+		state.Options.Throw = null.BoolFrom(true)
+		defer func() { state.Options.Throw = null.Bool{} }()
+		_, err = rt.RunString(`
+			mail.send({ server: smtpServer, security: "none", from: "k6@k6.test", to: "rejected@k6.test" })
+		`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no such user")
+	})
+
+	t.Run("InvalidParams", func(t *testing.T) {
+		testCases := map[string]string{
+			`mail.send({ from: "k6@k6.test", to: "user@k6.test" })`:                 "the mail server is required",
+			`mail.send({ server: smtpServer, to: "user@k6.test" })`:                 "the from and to addresses of the mail are required",
+			`mail.send({ server: smtpServer, from: "k6@k6.test", to: [1] })`:        "invalid mail to value: [1]",
+			`mail.send({ server: smtpServer, security: "ssl" })`:                    "invalid mail security value: ssl",
+			`mail.send({ server: smtpServer, attachments: [] })`:                    `unknown mail param: "attachments"`,
+			`mail.poll({ server: imapServer, messageId: "<id@k6>" })`:               "the username and the password of the mailbox are required",
+			`mail.poll({ server: imapServer, username: "k6", password: "secret" })`: "the message ID of the polled mail is required",
+			`mail.poll({ server: imapServer, interval: "0s" })`:                     "invalid mail interval value: 0s",
+		}
+		for script, expected := range testCases {
+			_, err := rt.RunString(script)
+			require.Error(t, err, script)
+			assert.Contains(t, err.Error(), expected, script)
+		}
+	})
+}
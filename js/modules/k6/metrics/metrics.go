@@ -50,6 +50,14 @@ type Metric struct {
 var ErrMetricsAddInInitContext = common.NewInitContextError("Adding to metrics in the init context is not supported")
 
 func newMetric(ctxPtr *context.Context, name string, t stats.MetricType, isTime []bool) (interface{}, error) {
+	return newMetricWith(ctxPtr, name, isTime, func(valueType stats.ValueType) (*stats.Metric, error) {
+		return stats.New(name, t, valueType), nil
+	})
+}
+
+func newMetricWith(
+	ctxPtr *context.Context, name string, isTime []bool, create func(stats.ValueType) (*stats.Metric, error),
+) (interface{}, error) {
 	if lib.GetState(*ctxPtr) != nil {
 		return nil, errors.New("metrics must be declared in the init context")
 	}
@@ -64,20 +72,47 @@ func newMetric(ctxPtr *context.Context, name string, t stats.MetricType, isTime
 		valueType = stats.Time
 	}
 
+	metric, err := create(valueType)
+	if err != nil {
+		return nil, common.NewInitContextError(err.Error())
+	}
+
 	rt := common.GetRuntime(*ctxPtr)
-	bound := common.Bind(rt, Metric{stats.New(name, t, valueType)}, ctxPtr)
+	bound := common.Bind(rt, Metric{metric}, ctxPtr)
 	o := rt.NewObject()
-	err := o.DefineDataProperty("name", rt.ToValue(name), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	err = o.DefineDataProperty("name", rt.ToValue(name), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_TRUE)
 	if err != nil {
 		return nil, err
 	}
 	if err = o.Set("add", rt.ToValue(bound["add"])); err != nil {
 		return nil, err
 	}
+	if err = o.Set("addWithMetadata", rt.ToValue(bound["addWithMetadata"])); err != nil {
+		return nil, err
+	}
 	return o, nil
 }
 
+// Add emits a new sample for the metric with the given value, with all of the
+// given maps merged into the VU tags.
 func (m Metric) Add(ctx context.Context, v goja.Value, addTags ...map[string]string) (bool, error) {
+	return m.add(ctx, v, nil, addTags)
+}
+
+// AddWithMetadata is like Add, but it also attaches the given metadata to the
+// sample. The metadata isn't indexed like the tags, see stats.Sample.
+func (m Metric) AddWithMetadata(
+	ctx context.Context, v goja.Value, metadata map[string]string, addTags ...map[string]string,
+) (bool, error) {
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+	return m.add(ctx, v, metadata, addTags)
+}
+
+func (m Metric) add(
+	ctx context.Context, v goja.Value, metadata map[string]string, addTags []map[string]string,
+) (bool, error) {
 	state := lib.GetState(ctx)
 	if state == nil {
 		return false, ErrMetricsAddInInitContext
@@ -95,7 +130,9 @@ func (m Metric) Add(ctx context.Context, v goja.Value, addTags ...map[string]str
 		vfloat = 1.0
 	}
 
-	sample := stats.Sample{Time: time.Now(), Metric: m.metric, Value: vfloat, Tags: stats.IntoSampleTags(&tags)}
+	sample := stats.Sample{
+		Time: time.Now(), Metric: m.metric, Value: vfloat, Tags: stats.IntoSampleTags(&tags), Metadata: metadata,
+	}
 	stats.PushIfNotDone(ctx, state.Samples, sample)
 	return true, nil
 }
@@ -121,3 +158,20 @@ func (*Metrics) XTrend(ctx *context.Context, name string, isTime ...bool) (inter
 func (*Metrics) XRate(ctx *context.Context, name string, isTime ...bool) (interface{}, error) {
 	return newMetric(ctx, name, stats.Rate, isTime)
 }
+
+// XThroughput creates a metric that reports the number of events per second,
+// calculated over consecutive wall-clock windows.
+func (*Metrics) XThroughput(ctx *context.Context, name string, isTime ...bool) (interface{}, error) {
+	return newMetric(ctx, name, stats.Throughput, isTime)
+}
+
+// XHistogram creates a metric that counts the added values in buckets with the
+// given upper bounds, or in the default buckets if none are given.
+func (*Metrics) XHistogram(ctx *context.Context, name string, buckets []float64, isTime ...bool) (interface{}, error) {
+	return newMetricWith(ctx, name, isTime, func(valueType stats.ValueType) (*stats.Metric, error) {
+		if buckets == nil {
+			return stats.New(name, stats.Histogram, valueType), nil
+		}
+		return stats.NewHistogram(name, buckets, valueType)
+	})
+}
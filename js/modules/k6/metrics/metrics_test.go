@@ -37,10 +37,11 @@ import (
 func TestMetrics(t *testing.T) {
 	t.Parallel()
 	types := map[string]stats.MetricType{
-		"Counter": stats.Counter,
-		"Gauge":   stats.Gauge,
-		"Trend":   stats.Trend,
-		"Rate":    stats.Rate,
+		"Counter":    stats.Counter,
+		"Gauge":      stats.Gauge,
+		"Trend":      stats.Trend,
+		"Rate":       stats.Rate,
+		"Throughput": stats.Throughput,
 	}
 	values := map[string]struct {
 		JS    string
@@ -136,6 +137,39 @@ func TestMetrics(t *testing.T) {
 											assert.Equal(t, "my_metric", sample.Metric.Name)
 											assert.Equal(t, mtyp, sample.Metric.Type)
 											assert.Equal(t, valueType, sample.Metric.Contains)
+											assert.Nil(t, sample.Metadata)
+										}
+									})
+									t.Run("MultipleTags", func(t *testing.T) {
+										_, err := rt.RunString(fmt.Sprintf(`m.add(%v, {a:1}, {b:2})`, val.JS))
+										assert.NoError(t, err)
+										bufSamples := stats.GetBufferedSamples(samples)
+										if assert.Len(t, bufSamples, 1) {
+											sample, ok := bufSamples[0].(stats.Sample)
+											require.True(t, ok)
+
+											assert.Equal(t, map[string]string{
+												"group": g.Path,
+												"a":     "1",
+												"b":     "2",
+											}, sample.Tags.CloneTags())
+											assert.Nil(t, sample.Metadata)
+										}
+									})
+									t.Run("Metadata", func(t *testing.T) {
+										_, err := rt.RunString(fmt.Sprintf(`m.addWithMetadata(%v, {trace_id:"abc"}, {a:1})`, val.JS))
+										assert.NoError(t, err)
+										bufSamples := stats.GetBufferedSamples(samples)
+										if assert.Len(t, bufSamples, 1) {
+											sample, ok := bufSamples[0].(stats.Sample)
+											require.True(t, ok)
+
+											assert.Equal(t, sample.Value, val.Float)
+											assert.Equal(t, map[string]string{
+												"group": g.Path,
+												"a":     "1",
+											}, sample.Tags.CloneTags())
+											assert.Equal(t, map[string]string{"trace_id": "abc"}, sample.Metadata)
 										}
 									})
 								})
@@ -148,6 +182,41 @@ func TestMetrics(t *testing.T) {
 	}
 }
 
+func TestHistogramMetric(t *testing.T) {
+	t.Parallel()
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+
+	ctxPtr := new(context.Context)
+	*ctxPtr = common.WithRuntime(context.Background(), rt)
+	require.NoError(t, rt.Set("metrics", common.Bind(rt, New(), ctxPtr)))
+
+	_, err := rt.RunString(`
+		var def = new metrics.Histogram("default_histogram");
+		var custom = new metrics.Histogram("custom_histogram", [500, 100, 1000], true);
+	`)
+	require.NoError(t, err)
+
+	samples := make(chan stats.SampleContainer, 1000)
+	*ctxPtr = lib.WithState(*ctxPtr, &lib.State{Samples: samples, Tags: map[string]string{}})
+	_, err = rt.RunString(`def.add(1); custom.add(150);`)
+	require.NoError(t, err)
+
+	bufSamples := stats.GetBufferedSamples(samples)
+	require.Len(t, bufSamples, 2)
+	def := bufSamples[0].(stats.Sample).Metric
+	assert.Equal(t, stats.Histogram, def.Type)
+	assert.Equal(t, stats.DefaultHistogramBuckets, def.Buckets)
+	custom := bufSamples[1].(stats.Sample).Metric
+	assert.Equal(t, stats.Time, custom.Contains)
+	assert.Equal(t, []float64{100, 500, 1000}, custom.Buckets)
+
+	*ctxPtr = common.WithRuntime(context.Background(), rt)
+	_, err = rt.RunString(`new metrics.Histogram("bad_histogram", [])`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "histogram metric 'bad_histogram' needs at least one bucket")
+}
+
 func TestMetricNames(t *testing.T) {
 	t.Parallel()
 	testMap := map[string]bool{
@@ -0,0 +1,216 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package protobuf implements the k6/protobuf module, which encodes and decodes Protocol Buffers
+// messages with the definitions of .proto files, for payloads that aren't sent with the gRPC client.
+package protobuf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/dop251/goja"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+// Protobuf is the k6/protobuf module.
+type Protobuf struct{}
+
+// New returns a new Protobuf module.
+func New() *Protobuf {
+	return &Protobuf{}
+}
+
+// Load parses the .proto files and returns a Schema with their message types. The import paths
+// are the directories of the imported files, the current working directory by default.
+func (*Protobuf) Load(ctx context.Context, importPaths []string, filenames ...string) (*Schema, error) {
+	if lib.GetState(ctx) != nil {
+		return nil, errors.New("load must be called in the init context")
+	}
+
+	initEnv := common.GetInitEnv(ctx)
+	if initEnv == nil {
+		return nil, errors.New("missing init environment")
+	}
+
+	files, err := ParseFiles(initEnv, importPaths, filenames...)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{rt: common.GetRuntime(ctx), files: files}, nil
+}
+
+// ParseFiles parses the .proto files, which are opened from the file system of the init environment,
+// and returns the registry of them and of their dependencies.
+func ParseFiles(
+	initEnv *common.InitEnvironment, importPaths []string, filenames ...string,
+) (*protoregistry.Files, error) {
+	// If no import paths are specified, use the current working directory
+	if len(importPaths) == 0 {
+		importPaths = append(importPaths, initEnv.CWD.Path)
+	}
+
+	parser := protoparse.Parser{
+		ImportPaths:      importPaths,
+		InferImportPaths: false,
+		Accessor: protoparse.FileAccessor(func(filename string) (io.ReadCloser, error) {
+			absFilePath := initEnv.GetAbsFilePath(filename)
+			return initEnv.FileSystems["file"].Open(absFilePath)
+		}),
+	}
+
+	fds, err := parser.ParseFiles(filenames...)
+	if err != nil {
+		return nil, err
+	}
+
+	fdset := &descriptorpb.FileDescriptorSet{}
+
+	seen := make(map[string]struct{})
+	for _, fd := range fds {
+		fdset.File = append(fdset.File, walkFileDescriptors(seen, fd)...)
+	}
+
+	return protodesc.NewFiles(fdset)
+}
+
+func walkFileDescriptors(seen map[string]struct{}, fd *desc.FileDescriptor) []*descriptorpb.FileDescriptorProto {
+	fds := []*descriptorpb.FileDescriptorProto{}
+
+	if _, ok := seen[fd.GetName()]; ok {
+		return fds
+	}
+	seen[fd.GetName()] = struct{}{}
+	fds = append(fds, fd.AsFileDescriptorProto())
+
+	for _, dep := range fd.GetDependencies() {
+		deps := walkFileDescriptors(seen, dep)
+		fds = append(fds, deps...)
+	}
+
+	return fds
+}
+
+// Schema holds the message types of the loaded .proto files.
+type Schema struct {
+	rt    *goja.Runtime
+	files *protoregistry.Files
+}
+
+// Messages returns the full names of the message types, for example "package.Message".
+func (s *Schema) Messages() []string {
+	var names []string
+	s.files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		names = appendMessages(names, fd.Messages())
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+func appendMessages(names []string, mds protoreflect.MessageDescriptors) []string {
+	for i := 0; i < mds.Len(); i++ {
+		md := mds.Get(i)
+		if md.IsMapEntry() {
+			continue
+		}
+		names = append(names, string(md.FullName()))
+		names = appendMessages(names, md.Messages())
+	}
+	return names
+}
+
+// Encode returns the binary encoding of the object as the message type, as an ArrayBuffer. The
+// object has the fields of the message with the names of the proto3 JSON mapping.
+func (s *Schema) Encode(messageType string, object goja.Value) (goja.ArrayBuffer, error) {
+	msg, err := s.newMessage(messageType)
+	if err != nil {
+		return goja.ArrayBuffer{}, err
+	}
+	if object != nil && !goja.IsUndefined(object) && !goja.IsNull(object) {
+		b, err := object.ToObject(s.rt).MarshalJSON()
+		if err != nil {
+			return goja.ArrayBuffer{}, fmt.Errorf("unable to serialise the object: %w", err)
+		}
+		if err := protojson.Unmarshal(b, msg); err != nil {
+			return goja.ArrayBuffer{}, fmt.Errorf("unable to serialise the object to %s: %w", messageType, err)
+		}
+	}
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return goja.ArrayBuffer{}, err
+	}
+	return s.rt.NewArrayBuffer(b), nil
+}
+
+// Decode returns the object of the binary encoding of the message type, which is an ArrayBuffer or
+// a string. Fields that don't have a value have their default values, as in the gRPC responses.
+func (s *Schema) Decode(messageType string, data interface{}) (map[string]interface{}, error) {
+	msg, err := s.newMessage(messageType)
+	if err != nil {
+		return nil, err
+	}
+	b, err := common.ToBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := proto.Unmarshal(b, msg); err != nil {
+		return nil, fmt.Errorf("unable to decode %s: %w", messageType, err)
+	}
+
+	// Like the gRPC client, marshal the dynamic message to JSON and back, so the object has the
+	// values of the fields as properties, including the zero values.
+	raw, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	object := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func (s *Schema) newMessage(messageType string) (*dynamicpb.Message, error) {
+	d, err := s.files.FindDescriptorByName(protoreflect.FullName(messageType))
+	if err != nil {
+		return nil, fmt.Errorf("the message type %q isn't defined in the loaded files", messageType)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q isn't a message type", messageType)
+	}
+	return dynamicpb.NewMessage(md), nil
+}
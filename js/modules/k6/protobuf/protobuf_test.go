@@ -0,0 +1,168 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package protobuf
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+const testProto = `syntax = "proto3";
+
+package test;
+
+import "common/money.proto";
+
+message Order {
+	enum Status {
+		PENDING = 0;
+		SHIPPED = 1;
+	}
+	message Item {
+		string sku = 1;
+		uint32 quantity = 2;
+	}
+	string id = 1;
+	Status status = 2;
+	repeated Item items = 3;
+	map<string, string> labels = 4;
+	common.Money total = 5;
+	bytes signature = 6;
+}
+`
+
+const testMoneyProto = `syntax = "proto3";
+
+package common;
+
+message Money {
+	string currency = 1;
+	int64 cents = 2;
+}
+`
+
+func makeRuntime(t *testing.T) (*goja.Runtime, *context.Context) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/protos/order.proto", []byte(testProto), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/protos/common/money.proto", []byte(testMoneyProto), 0o644))
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	ctx = common.WithInitEnv(ctx, &common.InitEnvironment{
+		Logger:      logrus.New(),
+		CWD:         &url.URL{Path: "/"},
+		FileSystems: map[string]afero.Fs{"file": fs},
+	})
+	rt.Set("protobuf", common.Bind(rt, New(), &ctx))
+	return rt, &ctx
+}
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+	rt, ctx := makeRuntime(t)
+
+	_, err := rt.RunString(`var schema = protobuf.load(["/protos"], "order.proto");`)
+	require.NoError(t, err)
+
+	// the schema can be used after the init context
+	*ctx = lib.WithState(*ctx, &lib.State{})
+
+	t.Run("Messages", func(t *testing.T) {
+		v, err := rt.RunString(`schema.messages().join()`)
+		require.NoError(t, err)
+		assert.Equal(t, "common.Money,test.Order,test.Order.Item", v.Export())
+	})
+
+	t.Run("EncodeDecode", func(t *testing.T) {
+		v, err := rt.RunString(`
+			var order = {
+				id: "o-1",
+				status: "SHIPPED",
+				items: [{ sku: "a", quantity: 2 }],
+				labels: { channel: "web" },
+				total: { currency: "EUR", cents: "1999" },
+				signature: "AQI=",
+			};
+			var encoded = schema.encode("test.Order", order);
+			if (!(encoded instanceof ArrayBuffer)) throw new Error("not an ArrayBuffer");
+			JSON.stringify(schema.decode("test.Order", encoded));
+		`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"id": "o-1",
+			"status": "SHIPPED",
+			"items": [{"sku": "a", "quantity": 2}],
+			"labels": {"channel": "web"},
+			"total": {"currency": "EUR", "cents": "1999"},
+			"signature": "AQI="
+		}`, v.String())
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		v, err := rt.RunString(`
+			var encoded = schema.encode("test.Order.Item", {});
+			var item = schema.decode("test.Order.Item", encoded);
+			encoded.byteLength + ":" + item.sku + ":" + item.quantity;
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "0::0", v.Export())
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		testCases := map[string]string{
+			`schema.encode("test.Missing", {})`:        `the message type "test.Missing" isn't defined in the loaded files`,
+			`schema.encode("test.Order.Status", {})`:   `"test.Order.Status" isn't a message type`,
+			`schema.encode("test.Order", { nope: 1 })`: `unable to serialise the object to test.Order`,
+			`schema.decode("test.Order", "\xff\xff")`:  `unable to decode test.Order`,
+			`schema.decode("test.Order", 1)`:           `invalid type int64, expected string, []byte or ArrayBuffer`,
+			`protobuf.load([], "order.proto")`:         `load must be called in the init context`,
+		}
+		for code, expected := range testCases {
+			_, err := rt.RunString(code)
+			require.Error(t, err, code)
+			assert.Contains(t, err.Error(), expected, code)
+		}
+	})
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Parallel()
+	rt, _ := makeRuntime(t)
+
+	_, err := rt.RunString(`protobuf.load(["/protos"], "missing.proto")`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "file does not exist")
+
+	_, err = rt.RunString(`protobuf.load([], "protos/order.proto")`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "common/money.proto")
+}
@@ -0,0 +1,290 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package resilience implements the k6/resilience module, with the circuit breakers of the
+// clients that are tested against degrading backends.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+// ErrCallInInitContext is returned when a circuit breaker is called in the init context.
+var ErrCallInInitContext = common.NewInitContextError("Calling circuit breakers in the init context is not supported")
+
+// The states of a circuit breaker.
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+// Resilience is the k6/resilience module.
+type Resilience struct{}
+
+// New returns a new Resilience module instance.
+func New() *Resilience {
+	return &Resilience{}
+}
+
+// BreakerOptions are the options of a circuit breaker.
+type BreakerOptions struct {
+	// The failure rate of the calls in the window that opens the breaker.
+	FailureRate float64
+	// The minimum number of calls in the window before the breaker can open.
+	MinRequests int
+	// The rolling window of the calls since the previous state change.
+	Window time.Duration
+	// How long the breaker stays open before it lets probe calls through.
+	OpenDuration time.Duration
+	// The number of successful probe calls that close a half-open breaker.
+	HalfOpenRequests int
+	// Whether the result of a call is a failure, by default the results with a status property
+	// that is 0 or at least 500, like the responses of failed HTTP requests.
+	IsFailure goja.Callable
+}
+
+type outcome struct {
+	time   time.Time
+	failed bool
+}
+
+// CircuitBreaker stops the calls of a VU to a backend while too many of them fail. A closed breaker
+// lets all calls through and opens when the failure rate of the calls in the window reaches the
+// threshold. An open breaker rejects the calls until the open duration passes, then it's half-open
+// and lets the calls through as probes, until enough of them succeed and it closes or one of them
+// fails and it opens again.
+type CircuitBreaker struct {
+	name    string
+	opts    BreakerOptions
+	now     func() time.Time
+	state   string
+	changed time.Time
+	window  []outcome
+	probes  int
+}
+
+// XCircuitBreaker is a constructor returning a circuit breaker with the name, which is the breaker
+// tag of its metrics, and the options.
+func (*Resilience) XCircuitBreaker(ctxPtr *context.Context, name string, opts goja.Value) (interface{}, error) {
+	rt := common.GetRuntime(*ctxPtr)
+	if name == "" {
+		return nil, errors.New("empty name provided to CircuitBreaker's constructor")
+	}
+	options, err := parseBreakerOptions(rt, opts)
+	if err != nil {
+		return nil, err
+	}
+	return common.Bind(rt, newCircuitBreaker(name, options, time.Now), ctxPtr), nil
+}
+
+func newCircuitBreaker(name string, opts BreakerOptions, now func() time.Time) *CircuitBreaker {
+	return &CircuitBreaker{name: name, opts: opts, now: now, state: StateClosed, changed: now()}
+}
+
+//nolint:cyclop
+func parseBreakerOptions(rt *goja.Runtime, opts goja.Value) (BreakerOptions, error) {
+	options := BreakerOptions{
+		FailureRate:      0.5,
+		MinRequests:      10,
+		Window:           10 * time.Second,
+		OpenDuration:     30 * time.Second,
+		HalfOpenRequests: 1,
+	}
+	if opts == nil || goja.IsUndefined(opts) || goja.IsNull(opts) {
+		return options, nil
+	}
+	obj := opts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		v := obj.Get(k)
+		var err error
+		switch k {
+		case "failureRate":
+			options.FailureRate = v.ToFloat()
+			if options.FailureRate <= 0 || options.FailureRate > 1 {
+				return options, fmt.Errorf("invalid failureRate %v, it has to be between 0 and 1", v)
+			}
+		case "minRequests":
+			options.MinRequests = int(v.ToInteger())
+			if options.MinRequests < 1 {
+				return options, fmt.Errorf("invalid minRequests %v, it has to be at least 1", v)
+			}
+		case "window":
+			options.Window, err = types.GetDurationValue(v.Export())
+		case "openDuration":
+			options.OpenDuration, err = types.GetDurationValue(v.Export())
+		case "halfOpenRequests":
+			options.HalfOpenRequests = int(v.ToInteger())
+			if options.HalfOpenRequests < 1 {
+				return options, fmt.Errorf("invalid halfOpenRequests %v, it has to be at least 1", v)
+			}
+		case "isFailure":
+			var ok bool
+			if options.IsFailure, ok = goja.AssertFunction(v); !ok {
+				return options, errors.New("isFailure has to be a function")
+			}
+		default:
+			return options, fmt.Errorf("unknown CircuitBreaker option '%s'", k)
+		}
+		if err != nil {
+			return options, fmt.Errorf("invalid %s: %w", k, err)
+		}
+	}
+	return options, nil
+}
+
+// Call calls the function if the breaker lets it through and records whether it failed, a thrown
+// exception is a failure and is thrown again. If the breaker rejects the call, the fallback
+// function is called instead, or an error is thrown if there isn't one.
+func (cb *CircuitBreaker) Call(ctx context.Context, fn goja.Callable, fallback goja.Value) (goja.Value, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrCallInInitContext
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("the circuit breaker '%s' requires a function to call", cb.name)
+	}
+
+	if cb.state == StateOpen && cb.now().Sub(cb.changed) >= cb.opts.OpenDuration {
+		cb.transition(ctx, state, StateHalfOpen)
+	}
+	if cb.state == StateOpen {
+		tags := cb.tags(state)
+		stats.PushIfNotDone(ctx, state.Samples, stats.Sample{
+			Time: cb.now(), Metric: metrics.CircuitBreakerRejections, Tags: stats.IntoSampleTags(&tags), Value: 1,
+		})
+		if fb, ok := goja.AssertFunction(fallback); ok {
+			return fb(goja.Undefined())
+		}
+		return nil, fmt.Errorf("the circuit breaker '%s' is open", cb.name)
+	}
+
+	result, err := fn(goja.Undefined())
+	failed := err != nil
+	if !failed {
+		if failed, err = cb.isFailure(result); err != nil {
+			return nil, err
+		}
+	}
+	cb.record(ctx, state, failed)
+	return result, err
+}
+
+func (cb *CircuitBreaker) isFailure(result goja.Value) (bool, error) {
+	if cb.opts.IsFailure != nil {
+		v, err := cb.opts.IsFailure(goja.Undefined(), result)
+		if err != nil {
+			return false, err
+		}
+		return v.ToBoolean(), nil
+	}
+	obj, ok := result.(*goja.Object)
+	if !ok {
+		return false, nil
+	}
+	status := obj.Get("status")
+	if status == nil || goja.IsUndefined(status) || goja.IsNull(status) {
+		return false, nil
+	}
+	code := status.ToInteger()
+	return code == 0 || code >= 500, nil
+}
+
+func (cb *CircuitBreaker) record(ctx context.Context, state *lib.State, failed bool) {
+	now := cb.now()
+	if cb.state == StateHalfOpen {
+		if failed {
+			cb.transition(ctx, state, StateOpen)
+			return
+		}
+		if cb.probes++; cb.probes >= cb.opts.HalfOpenRequests {
+			cb.transition(ctx, state, StateClosed)
+		}
+		return
+	}
+
+	cb.window = append(cb.window, outcome{time: now, failed: failed})
+	start := 0
+	for start < len(cb.window) && now.Sub(cb.window[start].time) > cb.opts.Window {
+		start++
+	}
+	cb.window = cb.window[start:]
+	if len(cb.window) < cb.opts.MinRequests {
+		return
+	}
+	failures := 0
+	for _, o := range cb.window {
+		if o.failed {
+			failures++
+		}
+	}
+	if float64(failures)/float64(len(cb.window)) >= cb.opts.FailureRate {
+		cb.transition(ctx, state, StateOpen)
+	}
+}
+
+// transition changes the state of the breaker and emits the change as a circuit_breaker_transitions
+// sample, tagged with the previous and the new state.
+func (cb *CircuitBreaker) transition(ctx context.Context, state *lib.State, to string) {
+	from := cb.state
+	if from == to {
+		return
+	}
+	cb.state, cb.changed, cb.window, cb.probes = to, cb.now(), nil, 0
+	if state == nil {
+		return
+	}
+	tags := cb.tags(state)
+	tags["from"], tags["to"] = from, to
+	stats.PushIfNotDone(ctx, state.Samples, stats.Sample{
+		Time: cb.changed, Metric: metrics.CircuitBreakerTransitions, Tags: stats.IntoSampleTags(&tags), Value: 1,
+	})
+}
+
+func (cb *CircuitBreaker) tags(state *lib.State) map[string]string {
+	tags := state.CloneTags()
+	tags["breaker"] = cb.name
+	return tags
+}
+
+// State returns the current state of the breaker, closed, open or half-open.
+func (cb *CircuitBreaker) State() string {
+	if cb.state == StateOpen && cb.now().Sub(cb.changed) >= cb.opts.OpenDuration {
+		return StateHalfOpen
+	}
+	return cb.state
+}
+
+// Reset closes the breaker and forgets the recorded calls.
+func (cb *CircuitBreaker) Reset(ctx context.Context) {
+	cb.transition(ctx, lib.GetState(ctx), StateClosed)
+	cb.window, cb.probes = nil, 0
+}
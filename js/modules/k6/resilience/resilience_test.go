@@ -0,0 +1,138 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package resilience
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/stats"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	require.NoError(t, rt.Set("resilience", common.Bind(rt, New(), &ctx)))
+
+	t.Run("InitContext", func(t *testing.T) {
+		_, err := rt.RunString(`
+		var breaker = new resilience.CircuitBreaker("api", {
+			failureRate: 0.5, minRequests: 4, window: "10s", openDuration: "30s", halfOpenRequests: 2,
+		});
+		breaker.call(function() { return { status: 200 }; });`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Calling circuit breakers in the init context is not supported")
+
+		cases := map[string]string{
+			`new resilience.CircuitBreaker("")`:                           "empty name provided to CircuitBreaker's constructor",
+			`new resilience.CircuitBreaker("a", { failureRate: 2 })`:      "invalid failureRate 2, it has to be between 0 and 1",
+			`new resilience.CircuitBreaker("a", { minRequests: 0 })`:      "invalid minRequests 0, it has to be at least 1",
+			`new resilience.CircuitBreaker("a", { window: "abc" })`:       "invalid window",
+			`new resilience.CircuitBreaker("a", { isFailure: 1 })`:        "isFailure has to be a function",
+			`new resilience.CircuitBreaker("a", { retries: 1 })`:          "unknown CircuitBreaker option 'retries'",
+			`new resilience.CircuitBreaker("a", { halfOpenRequests: 0 })`: "invalid halfOpenRequests 0, it has to be at least 1",
+		}
+		for code, msg := range cases {
+			_, err := rt.RunString(code)
+			require.Error(t, err, code)
+			assert.Contains(t, err.Error(), msg, code)
+		}
+	})
+
+	samples := make(chan stats.SampleContainer, 100)
+	ctx = lib.WithState(ctx, &lib.State{Samples: samples, Tags: map[string]string{}})
+	now := time.Now()
+	cb := newCircuitBreaker("api", BreakerOptions{
+		FailureRate: 0.5, MinRequests: 4, Window: 10 * time.Second, OpenDuration: 30 * time.Second, HalfOpenRequests: 2,
+	}, func() time.Time { return now })
+	require.NoError(t, rt.Set("breaker", common.Bind(rt, cb, &ctx)))
+	run := func(code string) goja.Value {
+		v, err := rt.RunString(code)
+		require.NoError(t, err)
+		return v
+	}
+
+	// 2 failures of 4 calls, one of them an exception
+	run(`
+	breaker.call(function() { return { status: 200 }; });
+	breaker.call(function() { return { status: 503 }; });
+	breaker.call(function() { return { status: 201 }; });`)
+	assert.Equal(t, StateClosed, cb.State())
+	_, err := rt.RunString(`breaker.call(function() { throw new Error("connection refused"); });`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.Equal(t, StateOpen, cb.State())
+
+	assert.Equal(t, "fallback", run(`breaker.call(function() { return "call"; }, function() { return "fallback"; })`).String())
+	_, err = rt.RunString(`breaker.call(function() { return "call"; })`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the circuit breaker 'api' is open")
+
+	// the probes of the half-open breaker, the failed one opens it again
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, StateHalfOpen, run(`breaker.state()`).String())
+	run(`breaker.call(function() { return { status: 0 }; })`)
+	assert.Equal(t, StateOpen, cb.State())
+	now = now.Add(30 * time.Second)
+	run(`breaker.call(function() { return { status: 200 }; }); breaker.call(function() { return { status: 200 }; });`)
+	assert.Equal(t, StateClosed, cb.State())
+
+	var transitions []string
+	var rejections float64
+	for _, container := range stats.GetBufferedSamples(samples) {
+		for _, sample := range container.GetSamples() {
+			tags := sample.Tags.CloneTags()
+			assert.Equal(t, "api", tags["breaker"])
+			switch sample.Metric {
+			case metrics.CircuitBreakerTransitions:
+				transitions = append(transitions, tags["from"]+">"+tags["to"])
+			case metrics.CircuitBreakerRejections:
+				rejections += sample.Value
+			}
+		}
+	}
+	assert.Equal(t, []string{
+		"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed",
+	}, transitions)
+	assert.Equal(t, float64(2), rejections)
+
+	t.Run("IsFailure", func(t *testing.T) {
+		run(`var custom = new resilience.CircuitBreaker("custom", {
+			minRequests: 1, failureRate: 0.6, isFailure: function(res) { return res !== "ok"; },
+		});
+		custom.call(function() { return "ok"; });`)
+		assert.Equal(t, StateClosed, run(`custom.state()`).String())
+		run(`custom.call(function() { return "not ok"; }); custom.call(function() { return "not ok"; });`)
+		assert.Equal(t, StateOpen, run(`custom.state()`).String())
+		run(`custom.reset()`)
+		assert.Equal(t, StateClosed, run(`custom.state()`).String())
+	})
+}
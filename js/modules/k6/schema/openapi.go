@@ -0,0 +1,243 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// operation is an operation of an OpenAPI 3 or Swagger 2 document.
+type operation struct {
+	method, path string
+	pattern      *regexp.Regexp
+	params       int
+	responses    map[string]interface{}
+}
+
+// Name returns the method and the path template of the operation, e.g. "GET /users/{id}".
+func (o *operation) Name() string {
+	return o.method + " " + o.path
+}
+
+type apiDocument struct {
+	root       map[string]interface{}
+	swagger    bool
+	basePaths  []*regexp.Regexp
+	operations []*operation
+}
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"} //nolint:gochecknoglobals
+
+func newAPIDocument(doc interface{}) (*apiDocument, error) {
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("the OpenAPI document has to be an object")
+	}
+	d := &apiDocument{root: root}
+	switch {
+	case root["openapi"] != nil:
+		servers, _ := root["servers"].([]interface{})
+		for _, server := range servers {
+			if s, ok := server.(map[string]interface{}); ok {
+				if u, ok := s["url"].(string); ok {
+					d.basePaths = append(d.basePaths, pathPattern(serverBasePath(u), true))
+				}
+			}
+		}
+	case root["swagger"] != nil:
+		d.swagger = true
+		if basePath, ok := root["basePath"].(string); ok {
+			d.basePaths = append(d.basePaths, pathPattern(strings.TrimSuffix(basePath, "/"), true))
+		}
+	default:
+		return nil, errors.New("the document doesn't have an openapi or a swagger version field")
+	}
+
+	paths, ok := root["paths"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("the OpenAPI document doesn't have paths")
+	}
+	for path, item := range paths {
+		item, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		params := strings.Count(path, "{")
+		pattern := pathPattern(path, false)
+		for _, method := range methods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			responses, _ := op["responses"].(map[string]interface{})
+			d.operations = append(d.operations, &operation{
+				method:    strings.ToUpper(method),
+				path:      path,
+				pattern:   pattern,
+				params:    params,
+				responses: responses,
+			})
+		}
+	}
+	// The paths without parameters take precedence over the templated ones, as in the specification.
+	sort.SliceStable(d.operations, func(i, j int) bool {
+		if d.operations[i].params != d.operations[j].params {
+			return d.operations[i].params < d.operations[j].params
+		}
+		return d.operations[i].path < d.operations[j].path
+	})
+	return d, nil
+}
+
+// serverBasePath returns the path of a server URL.
+func serverBasePath(server string) string {
+	path := server
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+		if j := strings.Index(path, "/"); j >= 0 {
+			path = path[j:]
+		} else {
+			path = ""
+		}
+	}
+	return strings.TrimSuffix(path, "/")
+}
+
+// pathPattern returns the regular expression of a path template, its {parameters} match any
+// segment. The pattern of a prefix matches the beginning of the paths.
+func pathPattern(template string, prefix bool) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for template != "" {
+		start := strings.Index(template, "{")
+		end := strings.Index(template, "}")
+		if start < 0 || end < start {
+			b.WriteString(regexp.QuoteMeta(template))
+			break
+		}
+		b.WriteString(regexp.QuoteMeta(template[:start]) + "[^/]+")
+		template = template[end+1:]
+	}
+	if !prefix {
+		b.WriteString("/?$")
+	}
+	return regexp.MustCompile(b.String())
+}
+
+// find returns the operation of the method and the URL.
+func (d *apiDocument) find(method, rawURL string) (*operation, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	path := u.Path
+	candidates := make([]string, 0, len(d.basePaths)+1)
+	for _, base := range d.basePaths {
+		if loc := base.FindStringIndex(path); loc != nil && loc[1] > 0 {
+			if rest := path[loc[1]:]; rest == "" || rest[0] == '/' {
+				candidates = append(candidates, "/"+strings.TrimPrefix(rest, "/"))
+			}
+		}
+	}
+	candidates = append(candidates, path)
+	method = strings.ToUpper(method)
+	for _, candidate := range candidates {
+		for _, op := range d.operations {
+			if op.method == method && op.pattern.MatchString(candidate) {
+				return op, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("the OpenAPI document doesn't have an operation for %s %s", method, path)
+}
+
+// responseSchema returns the schema of the response with the status and the content type of the
+// operation, and whether the response has a body to validate.
+func (d *apiDocument) responseSchema(op *operation, status int, contentType string) (interface{}, bool, error) {
+	response, ok := op.responses[strconv.Itoa(status)]
+	if !ok {
+		response, ok = op.responses[strconv.Itoa(status/100)+"XX"]
+	}
+	if !ok {
+		response, ok = op.responses["default"]
+	}
+	if !ok {
+		return nil, false, fmt.Errorf("the status %d isn't a documented response of %s", status, op.Name())
+	}
+	r, ok := response.(map[string]interface{})
+	if !ok {
+		return nil, false, nil
+	}
+	if ref, ok := r["$ref"].(string); ok {
+		resolved, err := newValidator(d.root).resolve(ref)
+		if err != nil {
+			return nil, false, err
+		}
+		if r, ok = resolved.(map[string]interface{}); !ok {
+			return nil, false, nil
+		}
+	}
+
+	if d.swagger {
+		schema, ok := r["schema"]
+		return schema, ok, nil
+	}
+
+	content, ok := r["content"].(map[string]interface{})
+	if !ok || len(content) == 0 {
+		return nil, false, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	media, ok := content[mediaType]
+	if !ok {
+		if media, ok = content[strings.SplitN(mediaType, "/", 2)[0]+"/*"]; !ok {
+			media, ok = content["*/*"]
+		}
+	}
+	if !ok {
+		types := make([]string, 0, len(content))
+		for t := range content {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		return nil, false, fmt.Errorf("the content type %q isn't a documented response of %s, it has to be %s",
+			contentType, op.Name(), strings.Join(types, " or "))
+	}
+	m, ok := media.(map[string]interface{})
+	if !ok {
+		return nil, false, nil
+	}
+	schema, ok := m["schema"]
+	if !ok || !strings.Contains(mediaType, "json") && mediaType != "*/*" {
+		return nil, false, nil
+	}
+	return schema, true, nil
+}
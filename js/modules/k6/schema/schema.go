@@ -0,0 +1,289 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package schema implements the k6/schema module, which validates values and HTTP responses against
+// JSON Schemas and OpenAPI documents and records the results as checks.
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/ghodss/yaml"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/modules/k6"
+)
+
+// Schema is the k6/schema module.
+type Schema struct{}
+
+// New returns a new Schema module.
+func New() *Schema {
+	return &Schema{}
+}
+
+// ValidationResult is the result of a validation.
+type ValidationResult struct {
+	Valid  bool              `js:"valid"`
+	Errors []ValidationError `js:"errors"`
+	// Operation is the OpenAPI operation of the response, e.g. "GET /users/{id}".
+	Operation string `js:"operation"`
+}
+
+func newResult(errs []ValidationError) ValidationResult {
+	if errs == nil {
+		errs = []ValidationError{}
+	}
+	return ValidationResult{Valid: len(errs) == 0, Errors: errs}
+}
+
+// Options are the options of the JSONSchema and OpenAPI constructors.
+type Options struct {
+	// Name is the name of the checks, instead of the default ones.
+	Name string `js:"name"`
+}
+
+// JSONSchema validates values with a JSON Schema.
+type JSONSchema struct {
+	schema    interface{}
+	options   Options
+	validator *validator
+}
+
+// XJSONSchema is the JSONSchema constructor (e.g. `new schema.JSONSchema(definition)`), the schema
+// is an object or a JSON string.
+func (*Schema) XJSONSchema(ctxPtr *context.Context, definition goja.Value, options Options) (interface{}, error) {
+	doc, err := parseDocument(definition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	switch doc.(type) {
+	case map[string]interface{}, bool:
+	default:
+		return nil, fmt.Errorf("the JSON Schema has to be an object or a boolean, not %s", typeOf(doc))
+	}
+	if options.Name == "" {
+		options.Name = "response matches the JSON Schema"
+	}
+	s := &JSONSchema{schema: doc, options: options, validator: newValidator(doc)}
+	return common.Bind(common.GetRuntime(*ctxPtr), s, ctxPtr), nil
+}
+
+// Validate validates a value, like the parsed JSON of a response.
+func (s *JSONSchema) Validate(value goja.Value) (ValidationResult, error) {
+	v, err := normalize(value)
+	if err != nil {
+		return ValidationResult{}, err
+	}
+	return s.validate(v), nil
+}
+
+func (s *JSONSchema) validate(v interface{}) ValidationResult {
+	return newResult(s.validator.validate(s.schema, v))
+}
+
+// ValidateResponse validates the JSON body of an HTTP response.
+func (s *JSONSchema) ValidateResponse(ctx context.Context, res goja.Value) (ValidationResult, error) {
+	r, err := responseOf(common.GetRuntime(ctx), res)
+	if err != nil {
+		return ValidationResult{}, err
+	}
+	v, errs := r.json()
+	if errs != nil {
+		return newResult(errs), nil
+	}
+	return s.validate(v), nil
+}
+
+// Check validates the JSON body of an HTTP response and records the result as a check, with the
+// name of the options and the extra tags. It returns whether the response is valid, like check().
+func (s *JSONSchema) Check(ctx context.Context, res goja.Value, tags ...goja.Value) (bool, error) {
+	result, err := s.ValidateResponse(ctx, res)
+	if err != nil {
+		return false, err
+	}
+	return record(ctx, res, s.options.Name, result, tags)
+}
+
+// OpenAPI validates HTTP responses with the operations of an OpenAPI 3 or Swagger 2 document.
+type OpenAPI struct {
+	doc       *apiDocument
+	options   Options
+	validator *validator
+}
+
+// XOpenAPI is the OpenAPI constructor (e.g. `new schema.OpenAPI(open("openapi.yaml"))`), the
+// document is an object or a JSON or YAML string.
+func (*Schema) XOpenAPI(ctxPtr *context.Context, definition goja.Value, options Options) (interface{}, error) {
+	doc, err := parseDocument(definition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	api, err := newAPIDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	o := &OpenAPI{doc: api, options: options, validator: newValidator(doc)}
+	return common.Bind(common.GetRuntime(*ctxPtr), o, ctxPtr), nil
+}
+
+// Validate validates an HTTP response with the operation of its request: its status has to be one
+// of the documented responses and its JSON body has to match the schema of the response.
+func (o *OpenAPI) Validate(ctx context.Context, res goja.Value) (ValidationResult, error) {
+	r, err := responseOf(common.GetRuntime(ctx), res)
+	if err != nil {
+		return ValidationResult{}, err
+	}
+	op, err := o.doc.find(r.method, r.url)
+	if err != nil {
+		return newResult([]ValidationError{{Message: err.Error()}}), nil
+	}
+	result := o.validateOperation(op, r)
+	result.Operation = op.Name()
+	return result, nil
+}
+
+func (o *OpenAPI) validateOperation(op *operation, r *response) ValidationResult {
+	schema, hasBody, err := o.doc.responseSchema(op, r.status, r.contentType)
+	if err != nil {
+		return newResult([]ValidationError{{Message: err.Error()}})
+	}
+	if !hasBody {
+		return newResult(nil)
+	}
+	v, errs := r.json()
+	if errs != nil {
+		return newResult(errs)
+	}
+	return newResult(o.validator.validate(schema, v))
+}
+
+// Check validates an HTTP response and records the result as a check, named after the operation
+// by default, e.g. "GET /users/{id} matches the OpenAPI document", so the summary shows the results
+// of each operation. It returns whether the response is valid, like check().
+func (o *OpenAPI) Check(ctx context.Context, res goja.Value, tags ...goja.Value) (bool, error) {
+	result, err := o.Validate(ctx, res)
+	if err != nil {
+		return false, err
+	}
+	name := o.options.Name
+	if name == "" {
+		name = "response matches an OpenAPI operation"
+		if result.Operation != "" {
+			name = result.Operation + " matches the OpenAPI document"
+		}
+	}
+	return record(ctx, res, name, result, tags)
+}
+
+// record records the result as a check, like check() does.
+func record(ctx context.Context, res goja.Value, name string, result ValidationResult, tags []goja.Value) (bool, error) {
+	rt := common.GetRuntime(ctx)
+	checks := rt.NewObject()
+	if err := checks.Set(name, result.Valid); err != nil {
+		return false, err
+	}
+	return k6.New().Check(ctx, res, checks, tags...)
+}
+
+// response is the fields of an HTTP response of k6/http that are validated.
+type response struct {
+	status      int
+	contentType string
+	body        string
+	method, url string
+}
+
+func responseOf(rt *goja.Runtime, value goja.Value) (*response, error) {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil, fmt.Errorf("a response is required")
+	}
+	obj := value.ToObject(rt)
+	r := &response{status: int(obj.Get("status").ToInteger())}
+	if body := obj.Get("body"); body != nil && !goja.IsUndefined(body) && !goja.IsNull(body) {
+		b, err := common.ToString(body.Export())
+		if err != nil {
+			return nil, fmt.Errorf("can't validate the body of the response: %w", err)
+		}
+		r.body = b
+	}
+	if headers := obj.Get("headers"); headers != nil && !goja.IsUndefined(headers) && !goja.IsNull(headers) {
+		h := headers.ToObject(rt)
+		for _, k := range h.Keys() {
+			if strings.EqualFold(k, "Content-Type") {
+				r.contentType = h.Get(k).String()
+			}
+		}
+	}
+	if u := obj.Get("url"); u != nil && !goja.IsUndefined(u) {
+		r.url = u.String()
+	}
+	r.method = "GET"
+	if req := obj.Get("request"); req != nil && !goja.IsUndefined(req) && !goja.IsNull(req) {
+		if m := req.ToObject(rt).Get("method"); m != nil && !goja.IsUndefined(m) {
+			r.method = m.String()
+		}
+	}
+	return r, nil
+}
+
+func (r *response) json() (interface{}, []ValidationError) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(r.body), &v); err != nil {
+		return nil, []ValidationError{{Message: "the body isn't valid JSON: " + err.Error()}}
+	}
+	return v, nil
+}
+
+// parseDocument returns a JSON or YAML string, or an object, with the types of encoding/json.
+func parseDocument(definition goja.Value) (interface{}, error) {
+	if s, ok := definition.Export().(string); ok {
+		b, err := yaml.YAMLToJSON([]byte(s))
+		if err != nil {
+			return nil, err
+		}
+		var doc interface{}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+	return normalize(definition)
+}
+
+// normalize returns the value with the types of encoding/json.
+func normalize(value goja.Value) (interface{}, error) {
+	if value == nil || goja.IsUndefined(value) {
+		return nil, nil
+	}
+	b, err := json.Marshal(value.Export())
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,267 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package schema
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/stats"
+)
+
+func makeRuntime(t *testing.T) (*goja.Runtime, *lib.State, chan stats.SampleContainer) {
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{
+		Group:   root,
+		Samples: samples,
+		Tags:    map[string]string{},
+		Options: lib.Options{SystemTags: stats.NewSystemTagSet(stats.TagCheck)},
+	}
+	ctx := common.WithRuntime(context.Background(), rt)
+	ctx = lib.WithState(ctx, state)
+	require.NoError(t, rt.Set("schema", common.Bind(rt, New(), &ctx)))
+	_, err = rt.RunString(`
+		function response(method, url, status, body, contentType) {
+			return {
+				request: { method: method },
+				url: url,
+				status: status,
+				headers: { "Content-Type": contentType || "application/json; charset=utf-8" },
+				body: typeof body === "string" ? body : JSON.stringify(body),
+			};
+		}
+	`)
+	require.NoError(t, err)
+	return rt, state, samples
+}
+
+func checkSamples(t *testing.T, samples chan stats.SampleContainer) map[string]float64 {
+	result := make(map[string]float64)
+	for _, container := range stats.GetBufferedSamples(samples) {
+		for _, sample := range container.GetSamples() {
+			if sample.Metric == metrics.Checks {
+				name, _ := sample.Tags.Get("check")
+				result[name] = sample.Value
+			}
+		}
+	}
+	return result
+}
+
+func TestJSONSchema(t *testing.T) {
+	t.Parallel()
+	rt, state, samples := makeRuntime(t)
+
+	_, err := rt.RunString(`
+		var user = new schema.JSONSchema({
+			type: "object",
+			required: ["id", "email"],
+			properties: { id: { type: "integer" }, email: { type: "string", format: "email" } },
+		});
+	`)
+	require.NoError(t, err)
+
+	t.Run("Validate", func(t *testing.T) {
+		v, err := rt.RunString(`JSON.stringify(user.validate({ id: 1.5, email: "a@b.c" }))`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"valid": false, "errors": [{"path": "/id", "message": "expected integer, got number"}],
+			"operation": ""}`, v.String())
+
+		v, err = rt.RunString(`user.validate({ id: 1, email: "a@b.c" }).valid`)
+		require.NoError(t, err)
+		assert.Equal(t, true, v.Export())
+
+		v, err = rt.RunString(`new schema.JSONSchema('{"type": "string"}').validate("a").valid`)
+		require.NoError(t, err)
+		assert.Equal(t, true, v.Export())
+	})
+
+	t.Run("Check", func(t *testing.T) {
+		v, err := rt.RunString(`
+			user.check(response("GET", "http://x/users/1", 200, { id: 1, email: "a@b.c" })) &&
+			!user.check(response("GET", "http://x/users/1", 200, "not json")) &&
+			!new schema.JSONSchema({ type: "array" }, { name: "users" }).check(response("GET", "http://x/users", 200, {}))
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, true, v.Export())
+		assert.Equal(t, map[string]float64{"response matches the JSON Schema": 0, "users": 0}, checkSamples(t, samples))
+		check, err := state.Group.Check("response matches the JSON Schema")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), check.Passes)
+		assert.Equal(t, int64(1), check.Fails)
+
+		v, err = rt.RunString(`user.validateResponse(response("GET", "http://x/users/1", 200, "not json")).errors[0].message`)
+		require.NoError(t, err)
+		assert.Contains(t, v.String(), "the body isn't valid JSON")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := rt.RunString(`new schema.JSONSchema(1)`)
+		assert.Contains(t, err.Error(), "the JSON Schema has to be an object or a boolean, not integer")
+		_, err = rt.RunString(`new schema.JSONSchema("{")`)
+		assert.Contains(t, err.Error(), "invalid JSON Schema")
+	})
+}
+
+const testOpenAPI = `
+openapi: 3.0.3
+servers:
+  - url: https://api.example.com/{version}
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                type: array
+                items: { $ref: "#/components/schemas/User" }
+  /users/me:
+    get:
+      responses:
+        "200": { $ref: "#/components/responses/User" }
+  /users/{id}:
+    get:
+      responses:
+        "200": { $ref: "#/components/responses/User" }
+        4XX:
+          content:
+            application/problem+json:
+              schema: { type: object, required: [title] }
+    delete:
+      responses:
+        "204": { description: deleted }
+  /files/{name}.txt:
+    get:
+      responses:
+        default:
+          content:
+            text/plain: { schema: { type: string } }
+components:
+  responses:
+    User:
+      description: a user
+      content:
+        application/json:
+          schema: { $ref: "#/components/schemas/User" }
+  schemas:
+    User:
+      type: object
+      required: [id]
+      properties:
+        id: { type: integer }
+        manager: { $ref: "#/components/schemas/User", nullable: true }
+`
+
+func TestOpenAPI(t *testing.T) {
+	t.Parallel()
+	rt, _, samples := makeRuntime(t)
+	require.NoError(t, rt.Set("doc", testOpenAPI))
+	_, err := rt.RunString(`var api = new schema.OpenAPI(doc);`)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		response, operation string
+		errors              []string
+	}{
+		{`response("GET", "https://api.example.com/v1/users", 200, [{ id: 1, manager: null }])`, "GET /users", nil},
+		{`response("GET", "https://api.example.com/v1/users/", 200, [{ id: "1" }])`, "GET /users", []string{
+			"/0/id: expected integer, got string",
+		}},
+		{`response("GET", "https://api.example.com/v1/users/me", 200, { id: 1 })`, "GET /users/me", nil},
+		{`response("GET", "https://api.example.com/v1/users/7", 200, { manager: { id: 2 } })`, "GET /users/{id}",
+			[]string{`the required property "id" is missing`}},
+		{`response("GET", "https://api.example.com/v1/users/7", 404, {}, "application/problem+json")`,
+			"GET /users/{id}", []string{`the required property "title" is missing`}},
+		{`response("GET", "https://api.example.com/v1/users/7", 404, {}, "text/html")`, "GET /users/{id}", []string{
+			`the content type "text/html" isn't a documented response of GET /users/{id}, it has to be application/problem+json`,
+		}},
+		{`response("GET", "https://api.example.com/v1/users/7", 500, "")`, "GET /users/{id}", []string{
+			"the status 500 isn't a documented response of GET /users/{id}",
+		}},
+		{`response("DELETE", "https://api.example.com/v1/users/7", 204, "")`, "DELETE /users/{id}", nil},
+		{`response("GET", "https://api.example.com/v1/files/a%20b.txt", 200, "text", "text/plain")`,
+			"GET /files/{name}.txt", nil},
+		{`response("GET", "https://api.example.com/users/7", 200, { id: 1 })`, "GET /users/{id}", nil},
+		{`response("POST", "https://api.example.com/v1/users", 201, {})`, "", []string{
+			"the OpenAPI document doesn't have an operation for POST /v1/users",
+		}},
+	}
+	for _, tc := range testCases {
+		v, err := rt.RunString(`api.validate(` + tc.response + `)`)
+		require.NoError(t, err, tc.response)
+		result, ok := v.Export().(ValidationResult)
+		require.True(t, ok)
+		var errs []string
+		for _, e := range result.Errors {
+			errs = append(errs, e.Error())
+		}
+		assert.Equal(t, tc.errors, errs, tc.response)
+		assert.Equal(t, tc.errors == nil, result.Valid, tc.response)
+		assert.Equal(t, tc.operation, result.Operation, tc.response)
+	}
+
+	t.Run("Check", func(t *testing.T) {
+		v, err := rt.RunString(`
+			api.check(response("GET", "https://api.example.com/v1/users/me", 200, { id: 1 })) &&
+			!api.check(response("PUT", "https://api.example.com/v1/users/me", 200, {}))
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, true, v.Export())
+		assert.Equal(t, map[string]float64{
+			"GET /users/me matches the OpenAPI document": 1,
+			"response matches an OpenAPI operation":      0,
+		}, checkSamples(t, samples))
+	})
+
+	t.Run("Swagger", func(t *testing.T) {
+		v, err := rt.RunString(`
+			var swagger = new schema.OpenAPI({
+				swagger: "2.0",
+				basePath: "/api",
+				paths: { "/items": { get: { responses: { 200: { schema: { type: "array" } } } } } },
+			});
+			swagger.validate(response("GET", "http://localhost/api/items", 200, {})).errors[0].message
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "expected array, got object", v.String())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := rt.RunString(`new schema.OpenAPI({ paths: {} })`)
+		assert.Contains(t, err.Error(), "the document doesn't have an openapi or a swagger version field")
+		_, err = rt.RunString(`new schema.OpenAPI("openapi: [")`)
+		assert.Contains(t, err.Error(), "invalid OpenAPI document")
+	})
+}
@@ -0,0 +1,541 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError is a value that doesn't match its schema.
+type ValidationError struct {
+	// Path is the JSON pointer of the value, e.g. /items/0/id, empty for the root value.
+	Path    string `js:"path"`
+	Message string `js:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// validator validates the values against the JSON Schema keywords of draft 4 to 2020-12 that don't
+// require loading other documents, and the nullable keyword of OpenAPI 3.0. The references are
+// JSON pointers in the root document, like #/definitions/user or #/components/schemas/User.
+type validator struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+func newValidator(root interface{}) *validator {
+	return &validator{root: root, patterns: make(map[string]*regexp.Regexp)}
+}
+
+// validate returns the errors of the value, which has the types of encoding/json.
+func (v *validator) validate(schema, value interface{}) []ValidationError {
+	var errs []ValidationError
+	v.check(schema, value, "", &errs, 0)
+	return errs
+}
+
+func (v *validator) valid(schema, value interface{}, depth int) bool {
+	var errs []ValidationError
+	v.check(schema, value, "", &errs, depth)
+	return len(errs) == 0
+}
+
+const maxRefDepth = 256
+
+//nolint:gocognit,funlen,gocyclo,cyclop
+func (v *validator) check(schema, value interface{}, path string, errs *[]ValidationError, depth int) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		if allowed, isBool := schema.(bool); isBool && !allowed {
+			fail("no value is allowed")
+		}
+		return
+	}
+
+	if value == nil {
+		if nullable, _ := s["nullable"].(bool); nullable {
+			return
+		}
+	}
+
+	if ref, ok := s["$ref"].(string); ok {
+		if depth > maxRefDepth {
+			fail("too many nested references at %s", ref)
+			return
+		}
+		resolved, err := v.resolve(ref)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		v.check(resolved, value, path, errs, depth+1)
+		// Before draft 2019-09 the other keywords are ignored next to $ref, but they are usually
+		// just descriptions, so check them anyway.
+	}
+
+	if t, ok := s["type"]; ok {
+		var types []string
+		switch t := t.(type) {
+		case string:
+			types = []string{t}
+		case []interface{}:
+			for _, tt := range t {
+				if str, ok := tt.(string); ok {
+					types = append(types, str)
+				}
+			}
+		}
+		matches := false
+		for _, t := range types {
+			if hasType(value, t) {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			fail("expected %s, got %s", strings.Join(types, " or "), typeOf(value))
+			return
+		}
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if equal(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("the value has to be one of %s", marshal(enum))
+		}
+	}
+	if c, ok := s["const"]; ok && !equal(c, value) {
+		fail("the value has to be %s", marshal(c))
+	}
+
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		subschemas, ok := s[keyword].([]interface{})
+		if !ok {
+			continue
+		}
+		matched := 0
+		for _, sub := range subschemas {
+			if keyword == "allOf" {
+				v.check(sub, value, path, errs, depth+1)
+			} else if v.valid(sub, value, depth+1) {
+				matched++
+			}
+		}
+		switch {
+		case keyword == "anyOf" && matched == 0:
+			fail("the value doesn't match any of the anyOf schemas")
+		case keyword == "oneOf" && matched != 1:
+			fail("the value matches %d of the oneOf schemas instead of exactly one", matched)
+		}
+	}
+	if not, ok := s["not"]; ok && v.valid(not, value, depth+1) {
+		fail("the value matches the not schema")
+	}
+	if cond, ok := s["if"]; ok {
+		if v.valid(cond, value, depth+1) {
+			if then, ok := s["then"]; ok {
+				v.check(then, value, path, errs, depth+1)
+			}
+		} else if els, ok := s["else"]; ok {
+			v.check(els, value, path, errs, depth+1)
+		}
+	}
+
+	switch value := value.(type) {
+	case float64:
+		v.checkNumber(s, value, fail)
+	case string:
+		v.checkString(s, value, fail)
+	case []interface{}:
+		v.checkArray(s, value, path, errs, depth)
+	case map[string]interface{}:
+		v.checkObject(s, value, path, errs, depth)
+	}
+}
+
+func (v *validator) checkNumber(s map[string]interface{}, value float64, fail func(string, ...interface{})) {
+	if m, ok := s["multipleOf"].(float64); ok && m > 0 {
+		if q := value / m; math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("%v isn't a multiple of %v", value, m)
+		}
+	}
+	// The exclusive limits are booleans in draft 4 and OpenAPI 3.0 and numbers afterwards.
+	exclusiveMin, exclusiveMax := s["exclusiveMinimum"], s["exclusiveMaximum"]
+	if min, ok := s["minimum"].(float64); ok {
+		if exclusiveMin == true && value <= min {
+			fail("%v has to be greater than %v", value, min)
+		} else if value < min {
+			fail("%v is less than the minimum %v", value, min)
+		}
+	}
+	if max, ok := s["maximum"].(float64); ok {
+		if exclusiveMax == true && value >= max {
+			fail("%v has to be less than %v", value, max)
+		} else if value > max {
+			fail("%v is greater than the maximum %v", value, max)
+		}
+	}
+	if min, ok := exclusiveMin.(float64); ok && value <= min {
+		fail("%v has to be greater than %v", value, min)
+	}
+	if max, ok := exclusiveMax.(float64); ok && value >= max {
+		fail("%v has to be less than %v", value, max)
+	}
+}
+
+func (v *validator) checkString(s map[string]interface{}, value string, fail func(string, ...interface{})) {
+	length := float64(utf8.RuneCountInString(value))
+	if min, ok := s["minLength"].(float64); ok && length < min {
+		fail("the string is shorter than %v characters", min)
+	}
+	if max, ok := s["maxLength"].(float64); ok && length > max {
+		fail("the string is longer than %v characters", max)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		re, err := v.regexp(pattern)
+		if err != nil {
+			fail("%s", err)
+		} else if !re.MatchString(value) {
+			fail("%q doesn't match the pattern %q", value, pattern)
+		}
+	}
+	if format, ok := s["format"].(string); ok && !checkFormat(format, value) {
+		fail("%q isn't a valid %s", value, format)
+	}
+}
+
+//nolint:gocognit,cyclop
+func (v *validator) checkArray(
+	s map[string]interface{}, value []interface{}, path string, errs *[]ValidationError, depth int,
+) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	length := float64(len(value))
+	if min, ok := s["minItems"].(float64); ok && length < min {
+		fail("the array has fewer than %v items", min)
+	}
+	if max, ok := s["maxItems"].(float64); ok && length > max {
+		fail("the array has more than %v items", max)
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+		for i := range value {
+			for j := 0; j < i; j++ {
+				if equal(value[i], value[j]) {
+					fail("the items %d and %d are equal", j, i)
+				}
+			}
+		}
+	}
+
+	// prefixItems in 2020-12, items as an array before it
+	prefix, _ := s["prefixItems"].([]interface{})
+	rest := s["items"]
+	if tuple, ok := rest.([]interface{}); ok {
+		prefix, rest = tuple, s["additionalItems"]
+	}
+	for i, item := range value {
+		itemPath := path + "/" + strconv.Itoa(i)
+		switch {
+		case i < len(prefix):
+			v.check(prefix[i], item, itemPath, errs, depth+1)
+		case rest != nil:
+			v.check(rest, item, itemPath, errs, depth+1)
+		}
+	}
+
+	if contains, ok := s["contains"]; ok {
+		matched := 0
+		for _, item := range value {
+			if v.valid(contains, item, depth+1) {
+				matched++
+			}
+		}
+		min, max := 1.0, math.Inf(1)
+		if m, ok := s["minContains"].(float64); ok {
+			min = m
+		}
+		if m, ok := s["maxContains"].(float64); ok {
+			max = m
+		}
+		if float64(matched) < min || float64(matched) > max {
+			fail("the array has %d items that match the contains schema", matched)
+		}
+	}
+}
+
+//nolint:gocognit,funlen,cyclop
+func (v *validator) checkObject(
+	s map[string]interface{}, value map[string]interface{}, path string, errs *[]ValidationError, depth int,
+) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	length := float64(len(value))
+	if min, ok := s["minProperties"].(float64); ok && length < min {
+		fail("the object has fewer than %v properties", min)
+	}
+	if max, ok := s["maxProperties"].(float64); ok && length > max {
+		fail("the object has more than %v properties", max)
+	}
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, ok := value[name]; !ok {
+					fail("the required property %q is missing", name)
+				}
+			}
+		}
+	}
+	if dependent, ok := s["dependentRequired"].(map[string]interface{}); ok {
+		for name, required := range dependent {
+			if _, ok := value[name]; !ok {
+				continue
+			}
+			list, _ := required.([]interface{})
+			for _, r := range list {
+				if r, ok := r.(string); ok {
+					if _, ok := value[r]; !ok {
+						fail("the property %q is required with %q", r, name)
+					}
+				}
+			}
+		}
+	}
+
+	properties, _ := s["properties"].(map[string]interface{})
+	patternProperties, _ := s["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := s["additionalProperties"]
+	propertyNames, hasPropertyNames := s["propertyNames"]
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propertyPath := path + "/" + escapePointer(name)
+		if hasPropertyNames && !v.valid(propertyNames, name, depth+1) {
+			fail("the property name %q doesn't match the propertyNames schema", name)
+		}
+		matched := false
+		if sub, ok := properties[name]; ok {
+			matched = true
+			v.check(sub, value[name], propertyPath, errs, depth+1)
+		}
+		for pattern, sub := range patternProperties {
+			re, err := v.regexp(pattern)
+			if err != nil {
+				fail("%s", err)
+				continue
+			}
+			if re.MatchString(name) {
+				matched = true
+				v.check(sub, value[name], propertyPath, errs, depth+1)
+			}
+		}
+		if !matched && hasAdditional {
+			if additional == false {
+				fail("the property %q isn't allowed", name)
+			} else {
+				v.check(additional, value[name], propertyPath, errs, depth+1)
+			}
+		}
+	}
+}
+
+// resolve returns the schema of the reference, a JSON pointer in the root document.
+func (v *validator) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("the reference %q isn't supported, only the references in the same document are", ref)
+	}
+	pointer, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference %q: %w", ref, err)
+	}
+	current := v.root
+	if pointer == "" {
+		return current, nil
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch c := current.(type) {
+		case map[string]interface{}:
+			next, ok := c[token]
+			if !ok {
+				return nil, fmt.Errorf("the reference %q doesn't exist", ref)
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, fmt.Errorf("the reference %q doesn't exist", ref)
+			}
+			current = c[i]
+		default:
+			return nil, fmt.Errorf("the reference %q doesn't exist", ref)
+		}
+	}
+	return current, nil
+}
+
+func (v *validator) regexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := v.patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	v.patterns[pattern] = re
+	return re, nil
+}
+
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+func hasType(value interface{}, t string) bool {
+	switch t {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	default:
+		return false
+	}
+}
+
+func typeOf(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func marshal(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+//nolint:gochecknoglobals
+var (
+	emailRegexp    = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	uuidRegexp     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hostnameRegexp = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+)
+
+// checkFormat checks the common formats, the unknown formats are valid, as the specification allows.
+func checkFormat(format, value string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339Nano, value)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", value)
+		if err != nil {
+			_, err = time.Parse("15:04:05.999999999Z07:00", value)
+		}
+		return err == nil
+	case "email":
+		return emailRegexp.MatchString(value)
+	case "uuid":
+		return uuidRegexp.MatchString(value)
+	case "hostname":
+		return len(value) <= 253 && hostnameRegexp.MatchString(value)
+	case "ipv4":
+		ip := net.ParseIP(value)
+		return ip != nil && ip.To4() != nil && !strings.Contains(value, ":")
+	case "ipv6":
+		ip := net.ParseIP(value)
+		return ip != nil && strings.Contains(value, ":")
+	case "uri":
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != ""
+	case "regex":
+		_, err := regexp.Compile(value)
+		return err == nil
+	default:
+		return true
+	}
+}
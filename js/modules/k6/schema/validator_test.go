@@ -0,0 +1,106 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidator(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		schema, value string
+		errors        []string
+	}{
+		{`true`, `1`, nil},
+		{`false`, `1`, []string{"no value is allowed"}},
+		{`{"type": "integer"}`, `1.5`, []string{"expected integer, got number"}},
+		{`{"type": ["string", "null"]}`, `null`, nil},
+		{`{"type": "string", "nullable": true}`, `null`, nil},
+		{`{"enum": ["a", 1]}`, `"b"`, []string{`the value has to be one of ["a",1]`}},
+		{`{"const": {"a": 1}}`, `{"a": 1}`, nil},
+		{`{"minimum": 1, "maximum": 3, "multipleOf": 2}`, `3`, []string{"3 isn't a multiple of 2"}},
+		{`{"minimum": 1, "exclusiveMinimum": true}`, `1`, []string{"1 has to be greater than 1"}},
+		{`{"exclusiveMaximum": 10}`, `10`, []string{"10 has to be less than 10"}},
+		{`{"minLength": 2, "maxLength": 3, "pattern": "^a"}`, `"bcde"`, []string{
+			"the string is longer than 3 characters", `"bcde" doesn't match the pattern "^a"`,
+		}},
+		{`{"minLength": 2}`, `"éé"`, nil},
+		{`{"format": "date-time"}`, `"2021-05-04T10:00:00Z"`, nil},
+		{`{"format": "date"}`, `"2021-13-04"`, []string{`"2021-13-04" isn't a valid date`}},
+		{`{"format": "email"}`, `"nope"`, []string{`"nope" isn't a valid email`}},
+		{`{"format": "uuid"}`, `"3fa85f64-5717-4562-b3fc-2c963f66afa6"`, nil},
+		{`{"format": "ipv4"}`, `"::1"`, []string{`"::1" isn't a valid ipv4`}},
+		{`{"format": "custom"}`, `"anything"`, nil},
+		{`{"items": {"type": "integer"}, "minItems": 1, "uniqueItems": true}`, `[1, "a", 1]`, []string{
+			"the items 0 and 2 are equal", `/1: expected integer, got string`,
+		}},
+		{`{"items": [{"type": "string"}], "additionalItems": false}`, `["a", 1]`, []string{"/1: no value is allowed"}},
+		{`{"prefixItems": [{"type": "string"}], "items": {"type": "integer"}}`, `["a", 1, 2]`, nil},
+		{`{"contains": {"const": 2}, "maxContains": 1}`, `[2, 2]`, []string{
+			"the array has 2 items that match the contains schema",
+		}},
+		{`{"required": ["id"], "properties": {"id": {"type": "integer"}}, "additionalProperties": false}`,
+			`{"name": "x"}`, []string{`the required property "id" is missing`, `the property "name" isn't allowed`}},
+		{`{"patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": {"type": "integer"}}`,
+			`{"x-a": "b", "c": 1, "d": "e"}`, []string{"/d: expected integer, got string"}},
+		{`{"propertyNames": {"maxLength": 1}, "maxProperties": 1}`, `{"ab": 1, "c": 2}`, []string{
+			"the object has more than 1 properties", `the property name "ab" doesn't match the propertyNames schema`,
+		}},
+		{`{"dependentRequired": {"a": ["b"]}}`, `{"a": 1}`, []string{`the property "b" is required with "a"`}},
+		{`{"properties": {"a/b": {"type": "string"}}}`, `{"a/b": 1}`, []string{"/a~1b: expected string, got integer"}},
+		{`{"allOf": [{"type": "integer"}, {"minimum": 5}]}`, `4`, []string{"4 is less than the minimum 5"}},
+		{`{"anyOf": [{"type": "integer"}, {"type": "boolean"}]}`, `"a"`, []string{
+			"the value doesn't match any of the anyOf schemas",
+		}},
+		{`{"oneOf": [{"type": "integer"}, {"minimum": 0}]}`, `1`, []string{
+			"the value matches 2 of the oneOf schemas instead of exactly one",
+		}},
+		{`{"not": {"type": "null"}}`, `null`, []string{"the value matches the not schema"}},
+		{`{"if": {"minimum": 10}, "then": {"multipleOf": 10}, "else": {"maximum": 5}}`, `7`, []string{
+			"7 is greater than the maximum 5",
+		}},
+		{`{"definitions": {"id": {"type": "integer"}}, "items": {"$ref": "#/definitions/id"}}`, `[1, "2"]`, []string{
+			"/1: expected integer, got string",
+		}},
+		{`{"$defs": {"node": {"type": "object", "properties": {"next": {"$ref": "#/$defs/node"}}}}, "$ref": "#/$defs/node"}`,
+			`{"next": {"next": 1}}`, []string{"/next/next: expected object, got integer"}},
+		{`{"$ref": "#/definitions/missing"}`, `1`, []string{`the reference "#/definitions/missing" doesn't exist`}},
+		{`{"$ref": "other.json#/a"}`, `1`, []string{
+			`the reference "other.json#/a" isn't supported, only the references in the same document are`,
+		}},
+		{`{"$ref": "#"}`, `1`, []string{"too many nested references at #"}},
+	}
+	for _, tc := range testCases {
+		var schema, value interface{}
+		require.NoError(t, json.Unmarshal([]byte(tc.schema), &schema), tc.schema)
+		require.NoError(t, json.Unmarshal([]byte(tc.value), &value), tc.value)
+		var errs []string
+		for _, err := range newValidator(schema).validate(schema, value) {
+			errs = append(errs, err.Error())
+		}
+		assert.Equal(t, tc.errors, errs, "%s with %s", tc.schema, tc.value)
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package secrets implements the k6/secrets module, which gives scripts the
+// secrets of the sources set with --secret-source.
+package secrets
+
+import (
+	"context"
+	"errors"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+// ErrSecretsInInitContext is returned when secrets are got in the init context,
+// which is also run for the options and the archives, before the secret
+// sources are set.
+var ErrSecretsInInitContext = common.NewInitContextError("Getting secrets in the init context is not supported")
+
+var errNoSecretSources = errors.New("there aren't any secret sources, set them with --secret-source")
+
+// Secrets is the k6/secrets module.
+type Secrets struct{}
+
+// New returns a new Secrets module instance.
+func New() *Secrets {
+	return &Secrets{}
+}
+
+// Get returns the value of the secret with the given name, from the first of
+// the secret sources that has it.
+func (*Secrets) Get(ctx context.Context, name string) (string, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return "", ErrSecretsInInitContext
+	}
+	if state.Secrets == nil {
+		return "", errNoSecretSources
+	}
+	return state.Secrets.Get(name)
+}
@@ -0,0 +1,69 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/secrets"
+)
+
+func newRuntime(t *testing.T, state *lib.State) *goja.Runtime {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	if state != nil {
+		ctx = lib.WithState(ctx, state)
+	}
+	require.NoError(t, rt.Set("secrets", common.Bind(rt, New(), &ctx)))
+	return rt
+}
+
+func TestSecretsGet(t *testing.T) {
+	t.Parallel()
+	store := secrets.NewStore(secrets.NewEnvSource(map[string]string{"K6_SECRET_TOKEN": "s3cr3t"}))
+	rt := newRuntime(t, &lib.State{Secrets: store})
+	v, err := rt.RunString(`secrets.get("TOKEN")`)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", v.String())
+
+	_, err = rt.RunString(`secrets.get("MISSING")`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "secret not found: 'MISSING' isn't in any of the secret sources")
+}
+
+func TestSecretsGetWithoutSources(t *testing.T) {
+	t.Parallel()
+	_, err := newRuntime(t, &lib.State{}).RunString(`secrets.get("TOKEN")`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), errNoSecretSources.Error())
+
+	_, err = newRuntime(t, nil).RunString(`secrets.get("TOKEN")`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), ErrSecretsInInitContext.Error())
+}
@@ -0,0 +1,287 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ssh
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// The SFTP packets and flags the client uses, version 3 of the protocol,
+// https://tools.ietf.org/html/draft-ietf-secsh-filexfer-02
+const (
+	sftpInit    = 1
+	sftpVersion = 2
+	sftpOpen    = 3
+	sftpClose   = 4
+	sftpRead    = 5
+	sftpWrite   = 6
+	sftpRemove  = 13
+	sftpStatus  = 101
+	sftpHandle  = 102
+	sftpData    = 103
+
+	sftpFlagRead  = 0x01
+	sftpFlagWrite = 0x02
+	sftpFlagCreat = 0x08
+	sftpFlagTrunc = 0x10
+
+	sftpStatusOK  = 0
+	sftpStatusEOF = 1
+
+	sftpChunkSize = 32 * 1024
+	// the limit of the packets of the servers, the data packets are smaller
+	sftpMaxPacket = 256 * 1024
+)
+
+// sftpClient is a minimal SFTP client that reads, writes and removes files, the requests are
+// made one at a time.
+type sftpClient struct {
+	session *ssh.Session
+	w       io.WriteCloser
+	r       io.Reader
+	id      uint32
+}
+
+func newSFTPClient(conn *ssh.Client) (*sftpClient, error) {
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	c := &sftpClient{session: session}
+	if c.w, err = session.StdinPipe(); err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	if c.r, err = session.StdoutPipe(); err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	if err = session.RequestSubsystem("sftp"); err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+
+	if err = c.send(sftpInit, appendUint32(nil, 3)); err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	typ, _, err := c.recv()
+	if err == nil && typ != sftpVersion {
+		err = fmt.Errorf("unexpected SFTP packet %d instead of the version", typ)
+	}
+	if err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendString(b []byte, s []byte) []byte {
+	return append(appendUint32(b, uint32(len(s))), s...)
+}
+
+func (c *sftpClient) send(typ byte, payload []byte) error {
+	packet := appendUint32(make([]byte, 0, 5+len(payload)), uint32(1+len(payload)))
+	packet = append(append(packet, typ), payload...)
+	_, err := c.w.Write(packet)
+	return err
+}
+
+func (c *sftpClient) recv() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length < 1 || length > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("invalid SFTP packet length %d", length)
+	}
+	payload := make([]byte, length-1)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[4], payload, nil
+}
+
+// request sends a request with a new ID and returns the type and the payload of its response,
+// without the ID.
+func (c *sftpClient) request(typ byte, payload []byte) (byte, []byte, error) {
+	c.id++
+	if err := c.send(typ, append(appendUint32(nil, c.id), payload...)); err != nil {
+		return 0, nil, err
+	}
+	respType, resp, err := c.recv()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(resp) < 4 || binary.BigEndian.Uint32(resp) != c.id {
+		return 0, nil, errors.New("unexpected SFTP response ID")
+	}
+	return respType, resp[4:], nil
+}
+
+// sftpError is the error of a status response.
+type sftpError struct {
+	code uint32
+	msg  string
+}
+
+func (e *sftpError) Error() string {
+	return fmt.Sprintf("SFTP error %d: %s", e.code, e.msg)
+}
+
+func readString(b []byte) ([]byte, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, errors.New("malformed SFTP packet")
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < n {
+		return nil, nil, errors.New("malformed SFTP packet")
+	}
+	return b[4 : 4+n], b[4+n:], nil
+}
+
+// status returns the error of the status response, nil if it's OK.
+func status(typ byte, resp []byte) error {
+	if typ != sftpStatus {
+		return fmt.Errorf("unexpected SFTP packet %d", typ)
+	}
+	if len(resp) < 4 {
+		return errors.New("malformed SFTP packet")
+	}
+	code := binary.BigEndian.Uint32(resp)
+	if code == sftpStatusOK {
+		return nil
+	}
+	msg, _, _ := readString(resp[4:])
+	return &sftpError{code: code, msg: string(msg)}
+}
+
+func (c *sftpClient) open(path string, flags uint32) ([]byte, error) {
+	payload := appendUint32(appendString(nil, []byte(path)), flags)
+	payload = appendUint32(payload, 0) // no attributes
+	typ, resp, err := c.request(sftpOpen, payload)
+	if err != nil {
+		return nil, err
+	}
+	if typ != sftpHandle {
+		err = status(typ, resp)
+		if err == nil {
+			err = fmt.Errorf("unexpected SFTP packet %d instead of the handle", typ)
+		}
+		return nil, fmt.Errorf("couldn't open %s: %w", path, err)
+	}
+	handle, _, err := readString(resp)
+	return handle, err
+}
+
+func (c *sftpClient) closeHandle(handle []byte) error {
+	typ, resp, err := c.request(sftpClose, appendString(nil, handle))
+	if err != nil {
+		return err
+	}
+	return status(typ, resp)
+}
+
+func (c *sftpClient) writeFile(path string, data []byte) error {
+	handle, err := c.open(path, sftpFlagWrite|sftpFlagCreat|sftpFlagTrunc)
+	if err != nil {
+		return err
+	}
+	for offset := 0; offset < len(data); offset += sftpChunkSize {
+		end := offset + sftpChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		payload := appendString(nil, handle)
+		payload = appendUint32(appendUint32(payload, uint32(uint64(offset)>>32)), uint32(offset))
+		payload = appendString(payload, data[offset:end])
+		typ, resp, err := c.request(sftpWrite, payload)
+		if err == nil {
+			err = status(typ, resp)
+		}
+		if err != nil {
+			_ = c.closeHandle(handle)
+			return fmt.Errorf("couldn't write %s: %w", path, err)
+		}
+	}
+	return c.closeHandle(handle)
+}
+
+func (c *sftpClient) readFile(path string) ([]byte, error) {
+	handle, err := c.open(path, sftpFlagRead)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	for {
+		payload := appendString(nil, handle)
+		payload = appendUint32(appendUint32(payload, uint32(uint64(len(data))>>32)), uint32(len(data)))
+		payload = appendUint32(payload, sftpChunkSize)
+		typ, resp, err := c.request(sftpRead, payload)
+		if err != nil {
+			return nil, err
+		}
+		if typ == sftpData {
+			chunk, _, err := readString(resp)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, chunk...)
+			continue
+		}
+		err = status(typ, resp)
+		var sftpErr *sftpError
+		if errors.As(err, &sftpErr) && sftpErr.code == sftpStatusEOF {
+			return data, c.closeHandle(handle)
+		}
+		if err == nil {
+			err = fmt.Errorf("unexpected SFTP packet %d instead of the data", typ)
+		}
+		_ = c.closeHandle(handle)
+		return nil, fmt.Errorf("couldn't read %s: %w", path, err)
+	}
+}
+
+func (c *sftpClient) remove(path string) error {
+	typ, resp, err := c.request(sftpRemove, appendString(nil, []byte(path)))
+	if err != nil {
+		return err
+	}
+	if err = status(typ, resp); err != nil {
+		return fmt.Errorf("couldn't remove %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *sftpClient) close() error {
+	_ = c.w.Close()
+	return c.session.Close()
+}
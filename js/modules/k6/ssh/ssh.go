@@ -0,0 +1,516 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package ssh implements the k6/net/ssh module, which runs commands over SSH and transfers files
+// over SFTP, so the infrastructure workflows can be part of the iterations.
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+var (
+	errConnectInInitContext = common.NewInitContextError("connecting to an SSH server in the init context is not supported")
+	errNotConnected         = errors.New("the SSH client isn't connected, call connect() first")
+)
+
+const defaultTimeout = 10 * time.Second
+
+// SSH is the k6/net/ssh module.
+type SSH struct{}
+
+// New returns a new SSH module instance.
+func New() *SSH {
+	return &SSH{}
+}
+
+// XClient represents the Client constructor (e.g. `new ssh.Client()`) and creates a new SSH
+// client, which can connect to a server, run commands and transfer files.
+func (*SSH) XClient(ctxPtr *context.Context) interface{} {
+	rt := common.GetRuntime(*ctxPtr)
+	return common.Bind(rt, &Client{}, ctxPtr)
+}
+
+// Client is an SSH client that can be used by the goja VM.
+type Client struct {
+	conn *ssh.Client
+	// the connection to the jump host, if the client connected through one
+	jump *ssh.Client
+	sftp *sftpClient
+	tags map[string]string
+}
+
+// RunResult is the result of a command.
+type RunResult struct {
+	Stdout   string  `js:"stdout"`
+	Stderr   string  `js:"stderr"`
+	ExitCode int     `js:"exitCode"`
+	Duration float64 `js:"duration"`
+}
+
+// authParams are the params of the authentication to a server.
+type authParams struct {
+	server     string
+	username   string
+	password   string
+	privateKey string
+	passphrase string
+	hostKey    string
+	// skips the verification of the host key of the server, instead of requiring it
+	insecureSkipHostKeyVerify bool
+}
+
+func parseAuthParam(p *authParams, k string, v interface{}) (bool, error) {
+	var ok bool
+	switch k {
+	case "username":
+		p.username, ok = v.(string)
+	case "password":
+		p.password, ok = v.(string)
+	case "privateKey":
+		p.privateKey, ok = v.(string)
+	case "passphrase":
+		p.passphrase, ok = v.(string)
+	case "hostKey":
+		p.hostKey, ok = v.(string)
+	case "insecureSkipHostKeyVerify":
+		p.insecureSkipHostKeyVerify, ok = v.(bool)
+	default:
+		return false, nil
+	}
+	if !ok {
+		return true, fmt.Errorf("invalid SSH %s value: %v", k, v)
+	}
+	return true, nil
+}
+
+// clientConfig returns the config of the connection, the host key is required unless its
+// verification is explicitly skipped.
+func (p authParams) clientConfig(timeout time.Duration) (*ssh.ClientConfig, error) {
+	if p.username == "" {
+		return nil, errors.New("the SSH username is required")
+	}
+	config := &ssh.ClientConfig{User: p.username, Timeout: timeout}
+	if p.privateKey != "" {
+		var (
+			signer ssh.Signer
+			err    error
+		)
+		if p.passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(p.privateKey), []byte(p.passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(p.privateKey))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid SSH private key: %w", err)
+		}
+		config.Auth = append(config.Auth, ssh.PublicKeys(signer))
+	}
+	if p.password != "" {
+		config.Auth = append(config.Auth, ssh.Password(p.password))
+	}
+	if len(config.Auth) == 0 {
+		return nil, errors.New("the SSH password or private key is required")
+	}
+	switch {
+	case p.hostKey != "":
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(p.hostKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SSH host key: %w", err)
+		}
+		config.HostKeyCallback = ssh.FixedHostKey(key)
+	case p.insecureSkipHostKeyVerify:
+		config.HostKeyCallback = ssh.InsecureIgnoreHostKey() //nolint:gosec
+	default:
+		return nil, errors.New("the SSH hostKey is required, or insecureSkipHostKeyVerify to not verify it")
+	}
+	return config, nil
+}
+
+func withDefaultPort(server string) string {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		return net.JoinHostPort(server, "22")
+	}
+	return server
+}
+
+// Connect connects to the server at the address, host:port with the port 22 by default. The
+// params are:
+//   - username, and password or privateKey with its passphrase: the authentication, required
+//   - hostKey: the host key of the server, in the authorized_keys format, required
+//   - insecureSkipHostKeyVerify: don't verify the host key of the server, instead of hostKey
+//   - jump: the server, the authentication and the host key of a jump host (a bastion) the
+//     connection goes through
+//   - timeout: the timeout of the connection, 10s by default
+//   - tags: the tags of the metrics of the client
+//
+//nolint:funlen
+func (c *Client) Connect(ctxPtr *context.Context, addr string, params map[string]interface{}) (bool, error) {
+	state := lib.GetState(*ctxPtr)
+	if state == nil {
+		return false, errConnectInInitContext
+	}
+
+	p, timeout := authParams{server: withDefaultPort(addr)}, defaultTimeout
+	var jump *authParams
+	for k, v := range params {
+		isAuth, err := parseAuthParam(&p, k, v)
+		if isAuth {
+			if err != nil {
+				return false, err
+			}
+			continue
+		}
+		switch k {
+		case "timeout":
+			timeout, err = types.GetDurationValue(v)
+			if err != nil {
+				return false, fmt.Errorf("invalid timeout value: %w", err)
+			}
+		case "tags":
+			raw, ok := v.(map[string]interface{})
+			if !ok {
+				return false, fmt.Errorf("invalid SSH tags value: %v", v)
+			}
+			c.tags = make(map[string]string, len(raw))
+			for tk, tv := range raw {
+				c.tags[tk] = fmt.Sprint(tv)
+			}
+		case "jump":
+			if jump, err = parseJumpParams(v); err != nil {
+				return false, err
+			}
+		default:
+			return false, fmt.Errorf("unknown connect param: %q", k)
+		}
+	}
+	config, err := p.clientConfig(timeout)
+	if err != nil {
+		return false, err
+	}
+	var jumpConfig *ssh.ClientConfig
+	if jump != nil {
+		if jumpConfig, err = jump.clientConfig(timeout); err != nil {
+			return false, fmt.Errorf("jump host: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(*ctxPtr, timeout)
+	defer cancel()
+	start := time.Now()
+	if jump != nil {
+		c.jump, err = dial(ctx, state, jump.server, jumpConfig, nil)
+		if err != nil {
+			return false, fmt.Errorf("couldn't connect to the jump host: %w", err)
+		}
+	}
+	c.conn, err = dial(ctx, state, p.server, config, c.jump)
+	if err != nil {
+		c.closeJump()
+		return false, err
+	}
+	end := time.Now()
+
+	tags := c.sampleTags(state, nil)
+	stats.PushIfNotDone(ctx, state.Samples, stats.Sample{
+		Metric: metrics.SSHConnectDuration, Time: end, Tags: tags, Value: stats.D(end.Sub(start)),
+	})
+	return true, nil
+}
+
+func parseJumpParams(v interface{}) (*authParams, error) {
+	raw, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid SSH jump value: %v", v)
+	}
+	jump := &authParams{}
+	for k, v := range raw {
+		if k == "server" {
+			if jump.server, ok = v.(string); !ok {
+				return nil, fmt.Errorf("invalid SSH jump server value: %v", v)
+			}
+			continue
+		}
+		if isAuth, err := parseAuthParam(jump, k, v); !isAuth || err != nil {
+			if err == nil {
+				err = fmt.Errorf("unknown jump param: %q", k)
+			}
+			return nil, err
+		}
+	}
+	if jump.server == "" {
+		return nil, errors.New("the SSH jump server is required")
+	}
+	jump.server = withDefaultPort(jump.server)
+	return jump, nil
+}
+
+// dial connects to the server with the dialer of the VU, or through the jump host.
+func dial(
+	ctx context.Context, state *lib.State, server string, config *ssh.ClientConfig, jump *ssh.Client,
+) (*ssh.Client, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+	if jump != nil {
+		conn, err = jump.Dial("tcp", server)
+	} else {
+		conn, err = state.Dialer.DialContext(ctx, "tcp", server)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the handshake is bounded by the connect timeout, the deadline is removed after it
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, server, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// Run runs the command in a new session, the params are:
+//   - stdin: the input of the command
+//   - env: the environment variables, the server has to accept them
+//   - timeout: the command is stopped and the run fails after it, none by default
+func (c *Client) Run(ctxPtr *context.Context, command string, params map[string]interface{}) (*RunResult, error) {
+	state := lib.GetState(*ctxPtr)
+	if state == nil {
+		return nil, errConnectInInitContext
+	}
+	if c.conn == nil {
+		return nil, errNotConnected
+	}
+
+	var (
+		stdin   []byte
+		env     map[string]string
+		timeout time.Duration
+		err     error
+	)
+	for k, v := range params {
+		switch k {
+		case "stdin":
+			if stdin, err = common.ToBytes(v); err != nil {
+				return nil, fmt.Errorf("invalid stdin value: %w", err)
+			}
+		case "env":
+			raw, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid env value: %v", v)
+			}
+			env = make(map[string]string, len(raw))
+			for ek, ev := range raw {
+				env[ek] = fmt.Sprint(ev)
+			}
+		case "timeout":
+			if timeout, err = types.GetDurationValue(v); err != nil {
+				return nil, fmt.Errorf("invalid timeout value: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown run param: %q", k)
+		}
+	}
+
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = session.Close() }()
+	for k, v := range env {
+		if err = session.Setenv(k, v); err != nil {
+			return nil, fmt.Errorf("couldn't set the environment variable %s: %w", k, err)
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	session.Stdin, session.Stdout, session.Stderr = bytes.NewReader(stdin), &stdout, &stderr
+
+	ctx := *ctxPtr
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- session.Run(command) }()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		_ = session.Signal(ssh.SIGKILL)
+		_ = session.Close()
+		return nil, fmt.Errorf("the command was stopped: %w", ctx.Err())
+	}
+	end := time.Now()
+
+	result := &RunResult{Stdout: stdout.String(), Stderr: stderr.String(), Duration: stats.D(end.Sub(start))}
+	var exitErr *ssh.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitStatus()
+	case err != nil:
+		return nil, err
+	}
+
+	tags := c.sampleTags(state, map[string]string{"exit_code": strconv.Itoa(result.ExitCode)})
+	stats.PushIfNotDone(ctx, state.Samples, stats.ConnectedSamples{
+		Samples: []stats.Sample{
+			{Metric: metrics.SSHCommands, Time: end, Tags: tags, Value: 1},
+			{Metric: metrics.SSHCommandDuration, Time: end, Tags: tags, Value: result.Duration},
+		},
+		Tags: tags,
+		Time: end,
+	})
+	return result, nil
+}
+
+// Upload writes the data, a string or an ArrayBuffer, to the remote file over SFTP, the file is
+// created or truncated.
+func (c *Client) Upload(ctxPtr *context.Context, remotePath string, data interface{}) (float64, error) {
+	state := lib.GetState(*ctxPtr)
+	if state == nil {
+		return 0, errConnectInInitContext
+	}
+	b, err := common.ToBytes(data)
+	if err != nil {
+		return 0, err
+	}
+	client, err := c.getSFTP()
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	if err = client.writeFile(remotePath, b); err != nil {
+		return 0, err
+	}
+	return c.pushTransfer(*ctxPtr, state, "upload", start), nil
+}
+
+// Download returns the content of the remote file over SFTP, as a string or as an ArrayBuffer
+// if binary is true.
+func (c *Client) Download(ctxPtr *context.Context, remotePath string, binary bool) (interface{}, error) {
+	state := lib.GetState(*ctxPtr)
+	if state == nil {
+		return nil, errConnectInInitContext
+	}
+	client, err := c.getSFTP()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	b, err := client.readFile(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	c.pushTransfer(*ctxPtr, state, "download", start)
+	if binary {
+		return common.GetRuntime(*ctxPtr).NewArrayBuffer(b), nil
+	}
+	return string(b), nil
+}
+
+// Remove removes the remote file over SFTP.
+func (c *Client) Remove(ctxPtr *context.Context, remotePath string) error {
+	if lib.GetState(*ctxPtr) == nil {
+		return errConnectInInitContext
+	}
+	client, err := c.getSFTP()
+	if err != nil {
+		return err
+	}
+	return client.remove(remotePath)
+}
+
+func (c *Client) getSFTP() (*sftpClient, error) {
+	if c.conn == nil {
+		return nil, errNotConnected
+	}
+	if c.sftp == nil {
+		client, err := newSFTPClient(c.conn)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't start the SFTP session: %w", err)
+		}
+		c.sftp = client
+	}
+	return c.sftp, nil
+}
+
+// pushTransfer emits the duration of a transfer and returns it.
+func (c *Client) pushTransfer(ctx context.Context, state *lib.State, op string, start time.Time) float64 {
+	end := time.Now()
+	duration := stats.D(end.Sub(start))
+	stats.PushIfNotDone(ctx, state.Samples, stats.Sample{
+		Metric: metrics.SFTPTransferDuration, Time: end, Tags: c.sampleTags(state, map[string]string{"op": op}),
+		Value: duration,
+	})
+	return duration
+}
+
+func (c *Client) sampleTags(state *lib.State, extra map[string]string) *stats.SampleTags {
+	tags := state.CloneTags()
+	for k, v := range c.tags {
+		tags[k] = v
+	}
+	for k, v := range extra {
+		tags[k] = v
+	}
+	return stats.IntoSampleTags(&tags)
+}
+
+// Close closes the SFTP session and the connection.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	if c.sftp != nil {
+		_ = c.sftp.close()
+		c.sftp = nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	c.closeJump()
+	return err
+}
+
+func (c *Client) closeJump() {
+	if c.jump != nil {
+		_ = c.jump.Close()
+		c.jump = nil
+	}
+}
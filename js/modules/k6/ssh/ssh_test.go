@@ -0,0 +1,367 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ssh
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/netext"
+	"go.k6.io/k6/stats"
+)
+
+// testServer is an SSH server with a few fake commands, an in-memory SFTP subsystem and the
+// forwarding of the connections for the jump hosts.
+type testServer struct {
+	addr    string
+	hostKey string
+	mu      sync.Mutex
+	files   map[string][]byte
+}
+
+func newTestServer(t *testing.T) *testServer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "k6" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, errors.New("invalid credentials")
+		},
+	}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	srv := &testServer{
+		addr:    l.Addr().String(),
+		hostKey: string(ssh.MarshalAuthorizedKey(signer.PublicKey())),
+		files:   map[string][]byte{},
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn, config)
+		}
+	}()
+	return srv
+}
+
+func (s *testServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newCh := range chans {
+		switch newCh.ChannelType() {
+		case "session":
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				return
+			}
+			go s.session(ch, reqs)
+		case "direct-tcpip":
+			var target struct {
+				Host       string
+				Port       uint32
+				OriginHost string
+				OriginPort uint32
+			}
+			_ = ssh.Unmarshal(newCh.ExtraData(), &target)
+			dst, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+			if err != nil {
+				_ = newCh.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			ch, reqs, err := newCh.Accept()
+			if err != nil {
+				return
+			}
+			go ssh.DiscardRequests(reqs)
+			go func() { _, _ = io.Copy(ch, dst); _ = ch.Close() }()
+			go func() { _, _ = io.Copy(dst, ch); _ = dst.Close() }()
+		default:
+			_ = newCh.Reject(ssh.UnknownChannelType, "unsupported")
+		}
+	}
+}
+
+// session runs the commands echo, cat, printenv and exit, and the sftp subsystem.
+func (s *testServer) session(ch ssh.Channel, reqs <-chan *ssh.Request) {
+	defer func() { _ = ch.Close() }()
+	env := map[string]string{}
+	for req := range reqs {
+		var payload struct{ Value string }
+		switch req.Type {
+		case "env":
+			var kv struct{ Name, Value string }
+			_ = ssh.Unmarshal(req.Payload, &kv)
+			env[kv.Name] = kv.Value
+			_ = req.Reply(true, nil)
+		case "exec":
+			_ = ssh.Unmarshal(req.Payload, &payload)
+			_ = req.Reply(true, nil)
+			args := strings.Fields(payload.Value)
+			code := 0
+			switch args[0] {
+			case "echo":
+				_, _ = io.WriteString(ch, strings.Join(args[1:], " ")+"\n")
+			case "cat":
+				_, _ = io.Copy(ch, ch)
+			case "printenv":
+				_, _ = io.WriteString(ch, env[args[1]]+"\n")
+			case "exit":
+				_, _ = io.WriteString(ch.Stderr(), "failed\n")
+				code, _ = strconv.Atoi(args[1])
+			}
+			_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(code)}))
+			return
+		case "subsystem":
+			_ = ssh.Unmarshal(req.Payload, &payload)
+			_ = req.Reply(payload.Value == "sftp", nil)
+			go ssh.DiscardRequests(reqs)
+			s.sftp(ch)
+			return
+		default:
+			_ = req.Reply(false, nil)
+		}
+	}
+}
+
+// sftp serves the requests of the client, the files are in memory and the handles are their
+// paths.
+func (s *testServer) sftp(ch ssh.Channel) {
+	reply := func(typ byte, id uint32, payload []byte) {
+		b := appendUint32(nil, uint32(5+len(payload)))
+		b = appendUint32(append(b, typ), id)
+		_, _ = ch.Write(append(b, payload...))
+	}
+	statusReply := func(id, code uint32) {
+		reply(sftpStatus, id, appendString(appendString(appendUint32(nil, code), []byte("status")), nil))
+	}
+	for {
+		var header [5]byte
+		if _, err := io.ReadFull(ch, header[:]); err != nil {
+			return
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[:])-1)
+		if _, err := io.ReadFull(ch, payload); err != nil {
+			return
+		}
+		if header[4] == sftpInit {
+			_, _ = ch.Write(append(appendUint32(nil, 5), sftpVersion, 0, 0, 0, 3))
+			continue
+		}
+		id := binary.BigEndian.Uint32(payload)
+		path, rest, _ := readString(payload[4:])
+		s.mu.Lock()
+		switch header[4] {
+		case sftpOpen:
+			flags := binary.BigEndian.Uint32(rest)
+			if _, ok := s.files[string(path)]; !ok && flags&sftpFlagCreat == 0 {
+				statusReply(id, 2)
+				break
+			}
+			if flags&sftpFlagTrunc != 0 {
+				s.files[string(path)] = nil
+			}
+			reply(sftpHandle, id, appendString(nil, path))
+		case sftpRead:
+			offset := binary.BigEndian.Uint64(rest)
+			length := uint64(binary.BigEndian.Uint32(rest[8:]))
+			data := s.files[string(path)]
+			if offset >= uint64(len(data)) {
+				statusReply(id, sftpStatusEOF)
+				break
+			}
+			if offset+length > uint64(len(data)) {
+				length = uint64(len(data)) - offset
+			}
+			reply(sftpData, id, appendString(nil, data[offset:offset+length]))
+		case sftpWrite:
+			offset := binary.BigEndian.Uint64(rest)
+			data, _, _ := readString(rest[8:])
+			file := s.files[string(path)]
+			for uint64(len(file)) < offset+uint64(len(data)) {
+				file = append(file, 0)
+			}
+			copy(file[offset:], data)
+			s.files[string(path)] = file
+			statusReply(id, sftpStatusOK)
+		case sftpRemove:
+			if _, ok := s.files[string(path)]; !ok {
+				statusReply(id, 2)
+				break
+			}
+			delete(s.files, string(path))
+			statusReply(id, sftpStatusOK)
+		default:
+			statusReply(id, sftpStatusOK)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	rt.Set("ssh", common.Bind(rt, New(), &ctx))
+	rt.Set("server", srv.addr)
+	rt.Set("hostKey", srv.hostKey)
+
+	t.Run("InitContext", func(t *testing.T) {
+		_, err := rt.RunString(`
+			var client = new ssh.Client();
+			client.connect(server, { username: "k6", password: "secret" });
+		`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), errConnectInInitContext.Error())
+	})
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{
+		Group:   root,
+		Dialer:  netext.NewDialer(net.Dialer{}, nil),
+		Samples: samples,
+		Logger:  logrus.New(),
+		Options: lib.Options{SystemTags: stats.NewSystemTagSet(stats.TagName)},
+	}
+	ctx = lib.WithState(ctx, state)
+
+	t.Run("Run", func(t *testing.T) {
+		v, err := rt.RunString(`
+			client.connect(server, { username: "k6", password: "secret", hostKey: hostKey, tags: { host: "a" } });
+			var echo = client.run("echo hello k6");
+			var cat = client.run("cat", { stdin: "from stdin" });
+			var env = client.run("printenv STAGE", { env: { STAGE: "load" } });
+			var failed = client.run("exit 3");
+			[echo.stdout, cat.stdout, env.stdout, failed.exitCode, failed.stderr].join("|")
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "hello k6\n|from stdin|load\n|3|failed\n", v.String())
+
+		seen := map[string]int{}
+		for _, container := range stats.GetBufferedSamples(samples) {
+			for _, sample := range container.GetSamples() {
+				tags := sample.Tags.CloneTags()
+				assert.Equal(t, "a", tags["host"])
+				seen[sample.Metric.Name+tags["exit_code"]]++
+			}
+		}
+		assert.Equal(t, map[string]int{
+			metrics.SSHConnectDuration.Name: 1,
+			metrics.SSHCommands.Name + "0":  3, metrics.SSHCommandDuration.Name + "0": 3,
+			metrics.SSHCommands.Name + "3": 1, metrics.SSHCommandDuration.Name + "3": 1,
+		}, seen)
+	})
+
+	t.Run("SFTP", func(t *testing.T) {
+		v, err := rt.RunString(`
+			var data = "";
+			for (var i = 0; i < 10000; i++) { data += "line " + i + "\n"; }
+			client.upload("/tmp/artifact", data);
+			var downloaded = client.download("/tmp/artifact");
+			var binary = client.download("/tmp/artifact", true);
+			client.remove("/tmp/artifact");
+			[downloaded === data, binary.byteLength].join(" ")
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "true 98890", v.String())
+		assert.Empty(t, srv.files)
+
+		_, err = rt.RunString(`client.download("/tmp/missing")`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "couldn't open /tmp/missing: SFTP error 2")
+		_, err = rt.RunString(`client.close()`)
+		require.NoError(t, err)
+	})
+
+	t.Run("Jump", func(t *testing.T) {
+		v, err := rt.RunString(`
+			var jumped = new ssh.Client();
+			jumped.connect(server, {
+				username: "k6",
+				password: "secret",
+				insecureSkipHostKeyVerify: true,
+				jump: { server: server, username: "k6", password: "secret", hostKey: hostKey },
+			});
+			var res = jumped.run("echo jumped");
+			jumped.close();
+			res.stdout
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "jumped\n", v.String())
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		testCases := map[string]string{
+			`new ssh.Client().run("echo")`:                                                       "the SSH client isn't connected",
+			`new ssh.Client().connect(server, { password: "secret" })`:                           "the SSH username is required",
+			`new ssh.Client().connect(server, { username: "k6" })`:                               "the SSH password or private key is required",
+			`new ssh.Client().connect(server, { username: "k6", password: "secret" })`:           "the SSH hostKey is required",
+			`new ssh.Client().connect(server, { username: "k6", password: "wrong", hostKey })`:   "unable to authenticate",
+			`new ssh.Client().connect(server, { username: "k6", privateKey: "invalid" })`:        "invalid SSH private key",
+			`new ssh.Client().connect(server, { username: "k6", password: "secret", port: 22 })`: `unknown connect param: "port"`,
+			`new ssh.Client().connect(server, { username: "k6", password: "secret", jump: {} })`: "the SSH jump server is required",
+			`new ssh.Client().connect(server, {
+				username: "k6", password: "secret",
+				hostKey: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl",
+			})`: "host key mismatch",
+		}
+		for script, expected := range testCases {
+			_, err := rt.RunString(script)
+			require.Error(t, err, script)
+			assert.Contains(t, err.Error(), expected, script)
+		}
+	})
+}
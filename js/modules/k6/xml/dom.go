@@ -0,0 +1,283 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package xml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+type nodeType int
+
+const (
+	documentNode nodeType = iota
+	elementNode
+	attributeNode
+	textNode
+	commentNode
+	procInstNode
+)
+
+// node is a node of a parsed XML document. The attributes of an element are nodes too, so XPath
+// expressions can select them.
+type node struct {
+	typ      nodeType
+	prefix   string
+	local    string
+	space    string // the namespace URI
+	data     string // the value of attributes, text, comments and processing instructions
+	parent   *node
+	children []*node
+	attrs    []*node
+	nsDecls  []xml.Attr // the namespace declarations of an element, as they were in the document
+	order    int        // the position of the node in the document order
+}
+
+func (n *node) name() string {
+	if n.prefix != "" {
+		return n.prefix + ":" + n.local
+	}
+	return n.local
+}
+
+// stringValue returns the string-value of the node, as defined by XPath.
+func (n *node) stringValue() string {
+	switch n.typ {
+	case documentNode, elementNode:
+		var b strings.Builder
+		var walk func(*node)
+		walk = func(n *node) {
+			for _, c := range n.children {
+				switch c.typ {
+				case textNode:
+					b.WriteString(c.data)
+				case elementNode:
+					walk(c)
+				default:
+				}
+			}
+		}
+		walk(n)
+		return b.String()
+	default:
+		return n.data
+	}
+}
+
+func (n *node) root() *node {
+	for n.parent != nil {
+		n = n.parent
+	}
+	return n
+}
+
+// lookupNamespace returns the URI of a namespace prefix that is declared in the scope of the node.
+func (n *node) lookupNamespace(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return "http://www.w3.org/XML/1998/namespace", true
+	}
+	for ; n != nil; n = n.parent {
+		for _, d := range n.nsDecls {
+			if (prefix == "" && d.Name.Space == "" && d.Name.Local == "xmlns") ||
+				(d.Name.Space == "xmlns" && d.Name.Local == prefix) {
+				return d.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (n *node) attr(name string) (*node, bool) {
+	prefix, local := splitName(name)
+	for _, a := range n.attrs {
+		if a.local == local && (prefix == "" || a.prefix == prefix) {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+func splitName(name string) (string, string) {
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// parse parses an XML document and returns its document node.
+func parse(src string) (*node, error) {
+	d := xml.NewDecoder(strings.NewReader(src))
+	d.Strict = true
+	d.CharsetReader = charsetReader
+	doc := &node{typ: documentNode}
+	current := doc
+	order := 1
+	add := func(n *node) {
+		n.parent = current
+		n.order = order
+		order++
+		current.children = append(current.children, n)
+	}
+
+	for {
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			if current != doc {
+				return nil, fmt.Errorf("<%s> isn't closed", current.name())
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &node{typ: elementNode, prefix: t.Name.Space, local: t.Name.Local}
+			add(el)
+			var attrs []xml.Attr
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+					el.nsDecls = append(el.nsDecls, a)
+				} else {
+					attrs = append(attrs, a)
+				}
+			}
+			var ok bool
+			if el.space, ok = el.lookupNamespace(el.prefix); !ok && el.prefix != "" {
+				return nil, fmt.Errorf("the namespace prefix %q of <%s> isn't declared", el.prefix, el.name())
+			}
+			for _, a := range attrs {
+				attr := &node{typ: attributeNode, prefix: a.Name.Space, local: a.Name.Local, data: a.Value, parent: el}
+				if attr.prefix != "" {
+					if attr.space, ok = el.lookupNamespace(attr.prefix); !ok {
+						return nil, fmt.Errorf("the namespace prefix %q of the %s attribute isn't declared",
+							attr.prefix, attr.name())
+					}
+				}
+				attr.order = order
+				order++
+				el.attrs = append(el.attrs, attr)
+			}
+			current = el
+		case xml.EndElement:
+			// RawToken() doesn't check that the elements are balanced
+			if current == doc || t.Name.Space != current.prefix || t.Name.Local != current.local {
+				name := (&node{prefix: t.Name.Space, local: t.Name.Local}).name()
+				return nil, fmt.Errorf("unexpected </%s>", name)
+			}
+			current = current.parent
+		case xml.CharData:
+			if current == doc {
+				continue // whitespace around the root element
+			}
+			if last := len(current.children) - 1; last >= 0 && current.children[last].typ == textNode {
+				// CDATA sections and entities can split the text
+				current.children[last].data += string(t)
+				continue
+			}
+			add(&node{typ: textNode, data: string(t)})
+		case xml.Comment:
+			add(&node{typ: commentNode, data: string(t)})
+		case xml.ProcInst:
+			if t.Target != "xml" {
+				add(&node{typ: procInstNode, local: t.Target, data: string(t.Inst)})
+			}
+		default:
+		}
+	}
+
+	for _, c := range doc.children {
+		if c.typ == elementNode {
+			return doc, nil
+		}
+	}
+	return nil, errors.New("the XML document doesn't have a root element")
+}
+
+// charsetReader returns a reader that converts the declared encoding to UTF-8, which is only needed
+// for ISO-8859-1, since US-ASCII is a subset of UTF-8.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "latin1":
+		b, err := ioutil.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return strings.NewReader(string(runes)), nil
+	default:
+		return nil, fmt.Errorf("the %q encoding isn't supported", charset)
+	}
+}
+
+// serialize writes the node as XML.
+func serialize(b *strings.Builder, n *node) {
+	switch n.typ {
+	case documentNode:
+		for _, c := range n.children {
+			serialize(b, c)
+		}
+	case elementNode:
+		b.WriteString("<" + n.name())
+		for _, d := range n.nsDecls {
+			name := d.Name.Local
+			if d.Name.Space != "" {
+				name = d.Name.Space + ":" + name
+			}
+			writeAttr(b, name, d.Value)
+		}
+		for _, a := range n.attrs {
+			writeAttr(b, a.name(), a.data)
+		}
+		if len(n.children) == 0 {
+			b.WriteString("/>")
+			return
+		}
+		b.WriteString(">")
+		for _, c := range n.children {
+			serialize(b, c)
+		}
+		b.WriteString("</" + n.name() + ">")
+	case attributeNode:
+		b.WriteString(n.data)
+	case textNode:
+		_ = xml.EscapeText(b, []byte(n.data))
+	case commentNode:
+		b.WriteString("<!--" + n.data + "-->")
+	case procInstNode:
+		b.WriteString("<?" + n.local + " " + n.data + "?>")
+	}
+}
+
+func writeAttr(b *strings.Builder, name, value string) {
+	b.WriteString(" " + name + `="`)
+	_ = xml.EscapeText(b, []byte(value))
+	b.WriteString(`"`)
+}
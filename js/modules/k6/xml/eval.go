@@ -0,0 +1,602 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package xml
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The values of XPath expressions are node-sets ([]*node), strings, numbers (float64) and booleans.
+
+type evalContext struct {
+	node       *node
+	position   int
+	size       int
+	namespaces map[string]string
+}
+
+func (c *evalContext) with(n *node, position, size int) *evalContext {
+	return &evalContext{node: n, position: position, size: size, namespaces: c.namespaces}
+}
+
+func (c *evalContext) namespace(prefix string) (string, error) {
+	if uri, ok := c.namespaces[prefix]; ok {
+		return uri, nil
+	}
+	if uri, ok := c.node.root().declaredNamespace(prefix); ok {
+		return uri, nil
+	}
+	return "", fmt.Errorf("the XPath namespace prefix %q isn't declared", prefix)
+}
+
+// declaredNamespace returns the URI of the first declaration of the prefix in the document.
+func (n *node) declaredNamespace(prefix string) (string, bool) {
+	if uri, ok := n.lookupNamespace(prefix); ok {
+		return uri, true
+	}
+	for _, c := range n.children {
+		if c.typ == elementNode {
+			if uri, ok := c.declaredNamespace(prefix); ok {
+				return uri, true
+			}
+		}
+	}
+	return "", false
+}
+
+// evaluate returns the value of the expression for the node.
+func evaluate(e expr, n *node, namespaces map[string]string) (interface{}, error) {
+	return e.eval(&evalContext{node: n, position: 1, size: 1, namespaces: namespaces})
+}
+
+func (e *literalExpr) eval(*evalContext) (interface{}, error) {
+	return e.value, nil
+}
+
+func (e *negateExpr) eval(c *evalContext) (interface{}, error) {
+	v, err := e.operand.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	return -toNumber(v), nil
+}
+
+func (e *binaryExpr) eval(c *evalContext) (interface{}, error) {
+	left, err := e.left.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	// and and or don't evaluate their right operand if they don't have to
+	switch e.op {
+	case "and":
+		if !toBoolean(left) {
+			return false, nil
+		}
+	case "or":
+		if toBoolean(left) {
+			return true, nil
+		}
+	}
+	right, err := e.right.eval(c)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "and", "or":
+		return toBoolean(right), nil
+	case "|":
+		l, lok := left.([]*node)
+		r, rok := right.([]*node)
+		if !lok || !rok {
+			return nil, fmt.Errorf("the operands of | have to be node-sets")
+		}
+		return sortNodes(append(append([]*node{}, l...), r...)), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return compare(e.op, left, right), nil
+	}
+
+	l, r := toNumber(left), toNumber(right)
+	switch e.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "div":
+		return l / r, nil
+	default: // mod
+		return math.Mod(l, r), nil
+	}
+}
+
+func compare(op string, a, b interface{}) bool {
+	if nodes, ok := a.([]*node); ok {
+		if bb, ok := b.(bool); ok {
+			return compareAtomic(op, len(nodes) > 0, bb)
+		}
+		for _, n := range nodes {
+			if compare(op, n.stringValue(), b) {
+				return true
+			}
+		}
+		return false
+	}
+	if nodes, ok := b.([]*node); ok {
+		if ab, ok := a.(bool); ok {
+			return compareAtomic(op, ab, len(nodes) > 0)
+		}
+		for _, n := range nodes {
+			if compare(op, a, n.stringValue()) {
+				return true
+			}
+		}
+		return false
+	}
+	return compareAtomic(op, a, b)
+}
+
+func compareAtomic(op string, a, b interface{}) bool {
+	if op == "=" || op == "!=" {
+		var equal bool
+		_, aBool := a.(bool)
+		_, bBool := b.(bool)
+		_, aNum := a.(float64)
+		_, bNum := b.(float64)
+		switch {
+		case aBool || bBool:
+			equal = toBoolean(a) == toBoolean(b)
+		case aNum || bNum:
+			equal = toNumber(a) == toNumber(b)
+		default:
+			equal = toString(a) == toString(b)
+		}
+		return equal == (op == "=")
+	}
+	x, y := toNumber(a), toNumber(b)
+	switch op {
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	default:
+		return x >= y
+	}
+}
+
+func (e *filterExpr) eval(c *evalContext) (interface{}, error) {
+	v, err := e.primary.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	nodes, ok := v.([]*node)
+	if !ok {
+		return nil, fmt.Errorf("predicates can only be used with node-sets")
+	}
+	for _, predicate := range e.predicates {
+		if nodes, err = filter(c, nodes, predicate); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+func filter(c *evalContext, nodes []*node, predicate expr) ([]*node, error) {
+	var result []*node
+	for i, n := range nodes {
+		v, err := predicate.eval(c.with(n, i+1, len(nodes)))
+		if err != nil {
+			return nil, err
+		}
+		var matches bool
+		if position, ok := v.(float64); ok {
+			matches = position == float64(i+1)
+		} else {
+			matches = toBoolean(v)
+		}
+		if matches {
+			result = append(result, n)
+		}
+	}
+	return result, nil
+}
+
+func (e *pathExpr) eval(c *evalContext) (interface{}, error) {
+	nodes := []*node{c.node}
+	switch {
+	case e.start != nil:
+		v, err := e.start.eval(c)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if nodes, ok = v.([]*node); !ok {
+			return nil, fmt.Errorf("a location path can only be used after a node-set")
+		}
+	case e.absolute:
+		nodes = []*node{c.node.root()}
+	}
+
+	for _, s := range e.steps {
+		var result []*node
+		for _, n := range nodes {
+			selected, err := s.eval(c, n)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, selected...)
+		}
+		nodes = sortNodes(result)
+	}
+	return nodes, nil
+}
+
+func (s *step) eval(c *evalContext, n *node) ([]*node, error) {
+	var candidates []*node
+	switch s.axis {
+	case "child":
+		candidates = n.children
+	case "attribute":
+		candidates = n.attrs
+	case "self":
+		candidates = []*node{n}
+	case "parent":
+		if n.parent != nil {
+			candidates = []*node{n.parent}
+		}
+	case "descendant", "descendant-or-self":
+		if s.axis == "descendant-or-self" {
+			candidates = append(candidates, n)
+		}
+		var walk func(*node)
+		walk = func(n *node) {
+			for _, child := range n.children {
+				candidates = append(candidates, child)
+				walk(child)
+			}
+		}
+		walk(n)
+	case "ancestor", "ancestor-or-self":
+		if s.axis == "ancestor-or-self" {
+			candidates = append(candidates, n)
+		}
+		for p := n.parent; p != nil; p = p.parent {
+			candidates = append(candidates, p)
+		}
+	case "following-sibling", "preceding-sibling":
+		if n.parent == nil || n.typ == attributeNode {
+			break
+		}
+		siblings := n.parent.children
+		for i, sibling := range siblings {
+			if sibling != n {
+				continue
+			}
+			if s.axis == "following-sibling" {
+				candidates = siblings[i+1:]
+			} else {
+				// the reverse axes are in reverse document order for the predicates
+				for j := i - 1; j >= 0; j-- {
+					candidates = append(candidates, siblings[j])
+				}
+			}
+			break
+		}
+	}
+
+	var selected []*node
+	for _, candidate := range candidates {
+		ok, err := s.test.matches(c, candidate, s.axis == "attribute")
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			selected = append(selected, candidate)
+		}
+	}
+	for _, predicate := range s.predicates {
+		var err error
+		if selected, err = filter(c, selected, predicate); err != nil {
+			return nil, err
+		}
+	}
+	return selected, nil
+}
+
+func (t nodeTest) matches(c *evalContext, n *node, attributeAxis bool) (bool, error) {
+	switch t.kind {
+	case "node":
+		return true, nil
+	case "text":
+		return n.typ == textNode, nil
+	case "comment":
+		return n.typ == commentNode, nil
+	case "processing-instruction":
+		return n.typ == procInstNode && (t.local == "" || t.local == n.local), nil
+	}
+
+	principal := elementNode
+	if attributeAxis {
+		principal = attributeNode
+	}
+	if n.typ != principal || (t.local != "*" && t.local != n.local) {
+		return false, nil
+	}
+	if t.prefix == "" {
+		return true, nil
+	}
+	uri, err := c.namespace(t.prefix)
+	return uri == n.space, err
+}
+
+func sortNodes(nodes []*node) []*node {
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].order < nodes[j].order })
+	result := nodes[:0]
+	for i, n := range nodes {
+		if i == 0 || n != nodes[i-1] {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+func toBoolean(v interface{}) bool {
+	switch v := v.(type) {
+	case []*node:
+		return len(v) > 0
+	case string:
+		return v != ""
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case bool:
+		return v
+	default:
+		return false
+	}
+}
+
+//nolint:gochecknoglobals
+var numberPattern = regexp.MustCompile(`^\s*-?(\d+(\.\d*)?|\.\d+)\s*$`)
+
+func toNumber(v interface{}) float64 {
+	switch v := v.(type) {
+	case []*node:
+		return toNumber(toString(v))
+	case string:
+		if !numberPattern.MatchString(v) {
+			return math.NaN()
+		}
+		n, _ := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	default:
+		return math.NaN()
+	}
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case []*node:
+		if len(v) == 0 {
+			return ""
+		}
+		return v[0].stringValue()
+	case string:
+		return v
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN"
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		case v == 0:
+			return "0"
+		default:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		return ""
+	}
+}
+
+type function func(c *evalContext, args []interface{}) (interface{}, error)
+
+//nolint:gochecknoglobals
+var functions map[string]struct {
+	minArgs, maxArgs int
+	fn               function
+}
+
+//nolint:gochecknoinits,funlen
+func init() {
+	nodeName := func(name func(*node) string) function {
+		return func(c *evalContext, args []interface{}) (interface{}, error) {
+			n := c.node
+			if len(args) > 0 {
+				nodes, ok := args[0].([]*node)
+				if !ok {
+					return nil, fmt.Errorf("the argument has to be a node-set")
+				}
+				if len(nodes) == 0 {
+					return "", nil
+				}
+				n = nodes[0]
+			}
+			return name(n), nil
+		}
+	}
+	stringArg := func(c *evalContext, args []interface{}) string {
+		if len(args) == 0 {
+			return c.node.stringValue()
+		}
+		return toString(args[0])
+	}
+
+	functions = map[string]struct {
+		minArgs, maxArgs int
+		fn               function
+	}{
+		"last":     {0, 0, func(c *evalContext, _ []interface{}) (interface{}, error) { return float64(c.size), nil }},
+		"position": {0, 0, func(c *evalContext, _ []interface{}) (interface{}, error) { return float64(c.position), nil }},
+		"count": {1, 1, func(_ *evalContext, args []interface{}) (interface{}, error) {
+			nodes, ok := args[0].([]*node)
+			if !ok {
+				return nil, fmt.Errorf("the argument of count() has to be a node-set")
+			}
+			return float64(len(nodes)), nil
+		}},
+		"name":          {0, 1, nodeName(func(n *node) string { return n.name() })},
+		"local-name":    {0, 1, nodeName(func(n *node) string { return n.local })},
+		"namespace-uri": {0, 1, nodeName(func(n *node) string { return n.space })},
+		"string": {0, 1, func(c *evalContext, args []interface{}) (interface{}, error) {
+			return stringArg(c, args), nil
+		}},
+		"concat": {2, -1, func(_ *evalContext, args []interface{}) (interface{}, error) {
+			var b strings.Builder
+			for _, arg := range args {
+				b.WriteString(toString(arg))
+			}
+			return b.String(), nil
+		}},
+		"starts-with": {2, 2, func(_ *evalContext, args []interface{}) (interface{}, error) {
+			return strings.HasPrefix(toString(args[0]), toString(args[1])), nil
+		}},
+		"contains": {2, 2, func(_ *evalContext, args []interface{}) (interface{}, error) {
+			return strings.Contains(toString(args[0]), toString(args[1])), nil
+		}},
+		"substring-before": {2, 2, func(_ *evalContext, args []interface{}) (interface{}, error) {
+			s, sep := toString(args[0]), toString(args[1])
+			if i := strings.Index(s, sep); i >= 0 {
+				return s[:i], nil
+			}
+			return "", nil
+		}},
+		"substring-after": {2, 2, func(_ *evalContext, args []interface{}) (interface{}, error) {
+			s, sep := toString(args[0]), toString(args[1])
+			if i := strings.Index(s, sep); i >= 0 {
+				return s[i+len(sep):], nil
+			}
+			return "", nil
+		}},
+		"substring": {2, 3, func(_ *evalContext, args []interface{}) (interface{}, error) {
+			s := []rune(toString(args[0]))
+			start := math.Floor(toNumber(args[1]) + 0.5)
+			end := math.Inf(1)
+			if len(args) == 3 {
+				end = start + math.Floor(toNumber(args[2])+0.5)
+			}
+			var b strings.Builder
+			for i, r := range s {
+				if p := float64(i + 1); p >= start && p < end {
+					b.WriteRune(r)
+				}
+			}
+			return b.String(), nil
+		}},
+		"string-length": {0, 1, func(c *evalContext, args []interface{}) (interface{}, error) {
+			return float64(utf8.RuneCountInString(stringArg(c, args))), nil
+		}},
+		"normalize-space": {0, 1, func(c *evalContext, args []interface{}) (interface{}, error) {
+			return strings.Join(strings.Fields(stringArg(c, args)), " "), nil
+		}},
+		"translate": {3, 3, func(_ *evalContext, args []interface{}) (interface{}, error) {
+			from, to := []rune(toString(args[1])), []rune(toString(args[2]))
+			return strings.Map(func(r rune) rune {
+				for i, f := range from {
+					if f == r {
+						if i < len(to) {
+							return to[i]
+						}
+						return -1
+					}
+				}
+				return r
+			}, toString(args[0])), nil
+		}},
+		"boolean": {1, 1, func(_ *evalContext, args []interface{}) (interface{}, error) { return toBoolean(args[0]), nil }},
+		"not":     {1, 1, func(_ *evalContext, args []interface{}) (interface{}, error) { return !toBoolean(args[0]), nil }},
+		"true":    {0, 0, func(*evalContext, []interface{}) (interface{}, error) { return true, nil }},
+		"false":   {0, 0, func(*evalContext, []interface{}) (interface{}, error) { return false, nil }},
+		"number": {0, 1, func(c *evalContext, args []interface{}) (interface{}, error) {
+			if len(args) == 0 {
+				return toNumber(c.node.stringValue()), nil
+			}
+			return toNumber(args[0]), nil
+		}},
+		"sum": {1, 1, func(_ *evalContext, args []interface{}) (interface{}, error) {
+			nodes, ok := args[0].([]*node)
+			if !ok {
+				return nil, fmt.Errorf("the argument of sum() has to be a node-set")
+			}
+			var sum float64
+			for _, n := range nodes {
+				sum += toNumber(n.stringValue())
+			}
+			return sum, nil
+		}},
+		"floor": {1, 1, func(_ *evalContext, args []interface{}) (interface{}, error) {
+			return math.Floor(toNumber(args[0])), nil
+		}},
+		"ceiling": {1, 1, func(_ *evalContext, args []interface{}) (interface{}, error) {
+			return math.Ceil(toNumber(args[0])), nil
+		}},
+		"round": {1, 1, func(_ *evalContext, args []interface{}) (interface{}, error) {
+			return math.Floor(toNumber(args[0]) + 0.5), nil
+		}},
+	}
+}
+
+func (e *callExpr) eval(c *evalContext) (interface{}, error) {
+	f := functions[e.name]
+	if len(e.args) < f.minArgs || (f.maxArgs >= 0 && len(e.args) > f.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments for %s()", e.name)
+	}
+	args := make([]interface{}, len(e.args))
+	for i, arg := range e.args {
+		var err error
+		if args[i], err = arg.eval(c); err != nil {
+			return nil, err
+		}
+	}
+	return f.fn(c, args)
+}
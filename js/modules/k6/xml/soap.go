@@ -0,0 +1,135 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package xml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	soap11Namespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// SoapOptions are the options of XML.SoapEnvelope.
+type SoapOptions struct {
+	// Version is "1.1", the default, or "1.2".
+	Version string `js:"version"`
+	// Prefix is the namespace prefix of the envelope elements, "soap" by default.
+	Prefix string `js:"prefix"`
+	// Header is the XML of the children of the header element, without one if it's empty.
+	Header string `js:"header"`
+	// Namespaces are declared on the envelope, so the header and the body can use them.
+	Namespaces map[string]string `js:"namespaces"`
+}
+
+// SoapFault is the fault of a SOAP response.
+type SoapFault struct {
+	Code   string `js:"code"`
+	Reason string `js:"reason"`
+	Actor  string `js:"actor"`
+	Detail string `js:"detail"`
+}
+
+// SoapEnvelope returns a SOAP envelope with the body, which is the XML of the children of the
+// body element.
+func (XML) SoapEnvelope(body string, options SoapOptions) (string, error) {
+	namespace := soap11Namespace
+	switch options.Version {
+	case "", "1.1":
+	case "1.2":
+		namespace = soap12Namespace
+	default:
+		return "", fmt.Errorf("unsupported SOAP version %q, it has to be 1.1 or 1.2", options.Version)
+	}
+	prefix := options.Prefix
+	if prefix == "" {
+		prefix = "soap"
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString("<" + prefix + ":Envelope")
+	writeAttr(&b, "xmlns:"+prefix, namespace)
+	prefixes := make([]string, 0, len(options.Namespaces))
+	for p := range options.Namespaces {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	for _, p := range prefixes {
+		name := "xmlns"
+		if p != "" {
+			name += ":" + p
+		}
+		writeAttr(&b, name, options.Namespaces[p])
+	}
+	b.WriteString(">")
+	if options.Header != "" {
+		b.WriteString("<" + prefix + ":Header>" + options.Header + "</" + prefix + ":Header>")
+	}
+	b.WriteString("<" + prefix + ":Body>" + body + "</" + prefix + ":Body>")
+	b.WriteString("</" + prefix + ":Envelope>")
+	return b.String(), nil
+}
+
+// Escape returns the text escaped for XML, for building bodies with strings.
+func (XML) Escape(text string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
+
+// SoapFault returns the fault of the SOAP 1.1 or 1.2 envelope in the selection, or nil if it
+// doesn't have one.
+func (s Selection) SoapFault() *SoapFault {
+	namespaces := map[string]string{"s11": soap11Namespace, "s12": soap12Namespace}
+	if fault := s.Find("//s11:Envelope/s11:Body/s11:Fault", namespaces); fault.Size() > 0 {
+		return &SoapFault{
+			Code:   strings.TrimSpace(fault.Find("faultcode").Text()),
+			Reason: strings.TrimSpace(fault.Find("faultstring").Text()),
+			Actor:  strings.TrimSpace(fault.Find("faultactor").Text()),
+			Detail: innerXML(fault.Find("detail")),
+		}
+	}
+	if fault := s.Find("//s12:Envelope/s12:Body/s12:Fault", namespaces); fault.Size() > 0 {
+		return &SoapFault{
+			Code:   strings.TrimSpace(fault.Find("s12:Code/s12:Value", namespaces).Text()),
+			Reason: strings.TrimSpace(fault.Find("s12:Reason/s12:Text", namespaces).First().Text()),
+			Actor:  strings.TrimSpace(fault.Find("s12:Role", namespaces).Text()),
+			Detail: innerXML(fault.Find("s12:Detail", namespaces)),
+		}
+	}
+	return nil
+}
+
+func innerXML(s Selection) string {
+	if s.Size() == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, c := range s.nodes[0].children {
+		serialize(&b, c)
+	}
+	return strings.TrimSpace(b.String())
+}
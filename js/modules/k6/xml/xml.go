@@ -0,0 +1,237 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package xml implements the k6/xml module, which parses XML documents, queries them with XPath
+// and builds SOAP envelopes.
+package xml
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dop251/goja"
+
+	"go.k6.io/k6/js/common"
+)
+
+// XML is the k6/xml module.
+type XML struct{}
+
+// New returns a new XML module.
+func New() *XML {
+	return &XML{}
+}
+
+// ParseXML parses an XML document and returns a Selection with its document node.
+func (XML) ParseXML(ctx context.Context, src string) (Selection, error) {
+	doc, err := parse(src)
+	if err != nil {
+		return Selection{}, err
+	}
+	return Selection{rt: common.GetRuntime(ctx), nodes: []*node{doc}}, nil
+}
+
+// Selection is a list of the nodes of an XML document, in the document order.
+type Selection struct {
+	rt    *goja.Runtime
+	nodes []*node
+}
+
+func (s Selection) with(nodes []*node) Selection {
+	return Selection{rt: s.rt, nodes: nodes}
+}
+
+func (s Selection) throw(err error) {
+	common.Throw(s.rt, err)
+}
+
+// Find returns the nodes that the XPath expression selects from any of the nodes of the selection.
+// The namespaces are an optional map of the prefixes in the expression to their URIs.
+func (s Selection) Find(xpath string, namespaces ...map[string]string) Selection {
+	e, err := compile(xpath)
+	if err != nil {
+		s.throw(err)
+	}
+	var ns map[string]string
+	if len(namespaces) > 0 {
+		ns = namespaces[0]
+	}
+	var result []*node
+	for _, n := range s.nodes {
+		v, err := evaluate(e, n, ns)
+		if err != nil {
+			s.throw(err)
+		}
+		nodes, ok := v.([]*node)
+		if !ok {
+			s.throw(fmt.Errorf("the XPath expression %q doesn't select nodes, see evaluate()", xpath))
+		}
+		result = append(result, nodes...)
+	}
+	return s.with(sortNodes(result))
+}
+
+// Evaluate returns the value of the XPath expression for the first node of the selection, as a
+// string, a number, a boolean or a Selection, for example for "count(//item)".
+func (s Selection) Evaluate(xpath string, namespaces ...map[string]string) goja.Value {
+	e, err := compile(xpath)
+	if err != nil {
+		s.throw(err)
+	}
+	if len(s.nodes) == 0 {
+		return goja.Undefined()
+	}
+	var ns map[string]string
+	if len(namespaces) > 0 {
+		ns = namespaces[0]
+	}
+	v, err := evaluate(e, s.nodes[0], ns)
+	if err != nil {
+		s.throw(err)
+	}
+	if nodes, ok := v.([]*node); ok {
+		return s.rt.ToValue(s.with(nodes))
+	}
+	return s.rt.ToValue(v)
+}
+
+// Size returns the number of nodes in the selection.
+func (s Selection) Size() int {
+	return len(s.nodes)
+}
+
+// Text returns the combined text of the nodes.
+func (s Selection) Text() string {
+	var b strings.Builder
+	for _, n := range s.nodes {
+		b.WriteString(n.stringValue())
+	}
+	return b.String()
+}
+
+// Attr returns the value of an attribute of the first node, or the default value if it doesn't
+// have one. The name can have a namespace prefix, as it's in the document.
+func (s Selection) Attr(name string, def ...goja.Value) goja.Value {
+	if len(s.nodes) > 0 {
+		if a, ok := s.nodes[0].attr(name); ok {
+			return s.rt.ToValue(a.data)
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	return goja.Undefined()
+}
+
+// Name returns the qualified name of the first node, with the prefix it has in the document.
+func (s Selection) Name() goja.Value {
+	if len(s.nodes) == 0 || s.nodes[0].local == "" {
+		return goja.Undefined()
+	}
+	return s.rt.ToValue(s.nodes[0].name())
+}
+
+// LocalName returns the name of the first node without the namespace prefix.
+func (s Selection) LocalName() goja.Value {
+	if len(s.nodes) == 0 || s.nodes[0].local == "" {
+		return goja.Undefined()
+	}
+	return s.rt.ToValue(s.nodes[0].local)
+}
+
+// NamespaceURI returns the namespace URI of the first node.
+func (s Selection) NamespaceURI() goja.Value {
+	if len(s.nodes) == 0 {
+		return goja.Undefined()
+	}
+	return s.rt.ToValue(s.nodes[0].space)
+}
+
+// XML returns the first node as an XML string.
+func (s Selection) XML() goja.Value {
+	if len(s.nodes) == 0 {
+		return goja.Undefined()
+	}
+	var b strings.Builder
+	serialize(&b, s.nodes[0])
+	return s.rt.ToValue(b.String())
+}
+
+// Eq returns a selection with the node at the index, negative indexes count from the end.
+func (s Selection) Eq(index int) Selection {
+	if index < 0 {
+		index += len(s.nodes)
+	}
+	if index < 0 || index >= len(s.nodes) {
+		return s.with(nil)
+	}
+	return s.with(s.nodes[index : index+1])
+}
+
+// First returns a selection with the first node.
+func (s Selection) First() Selection {
+	return s.Eq(0)
+}
+
+// Last returns a selection with the last node.
+func (s Selection) Last() Selection {
+	return s.Eq(-1)
+}
+
+// ToArray returns a selection for each of the nodes.
+func (s Selection) ToArray() []Selection {
+	result := make([]Selection, len(s.nodes))
+	for i := range s.nodes {
+		result[i] = s.Eq(i)
+	}
+	return result
+}
+
+// Each calls the function with the index and a selection for each of the nodes.
+func (s Selection) Each(v goja.Value) Selection {
+	fn, ok := goja.AssertFunction(v)
+	if !ok {
+		s.throw(fmt.Errorf("the argument to each() must be a function"))
+	}
+	for i := range s.nodes {
+		if _, err := fn(v, s.rt.ToValue(i), s.rt.ToValue(s.Eq(i))); err != nil {
+			s.throw(err)
+		}
+	}
+	return s
+}
+
+// Map returns an array with the results of calling the function with the index and a selection
+// for each of the nodes.
+func (s Selection) Map(v goja.Value) []goja.Value {
+	fn, ok := goja.AssertFunction(v)
+	if !ok {
+		s.throw(fmt.Errorf("the argument to map() must be a function"))
+	}
+	result := make([]goja.Value, len(s.nodes))
+	for i := range s.nodes {
+		var err error
+		if result[i], err = fn(v, s.rt.ToValue(i), s.rt.ToValue(s.Eq(i))); err != nil {
+			s.throw(err)
+		}
+	}
+	return result
+}
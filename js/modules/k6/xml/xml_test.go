@@ -0,0 +1,126 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package xml
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/js/common"
+)
+
+func makeRuntime() *goja.Runtime {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := context.Background()
+	ctx = common.WithRuntime(ctx, rt)
+	rt.Set("xml", common.Bind(rt, New(), &ctx))
+	return rt
+}
+
+func TestSelection(t *testing.T) {
+	t.Parallel()
+	rt := makeRuntime()
+	require.NoError(t, rt.Set("src", testXPathDoc))
+	_, err := rt.RunString(`var doc = xml.parseXML(src);`)
+	require.NoError(t, err)
+
+	testCases := map[string]interface{}{
+		`doc.find("//book").size()`:                                                                        int64(3),
+		`doc.find("//book").eq(-1).attr("id")`:                                                             "3",
+		`doc.find("//book").first().attr("missing", "none")`:                                               "none",
+		`doc.find("//book").last().find("title").text()`:                                                   "A & B",
+		`doc.find("//x:rating").name()`:                                                                    "x:rating",
+		`doc.find("//x:rating").localName()`:                                                               "rating",
+		`doc.find("//e:rating", {e: "urn:extra"}).namespaceURI()`:                                          "urn:extra",
+		`doc.find("//book[2]").xml()`:                                                                      `<book id="2" lang="de"><title>Rust</title><price>20</price></book>`,
+		`doc.find("//book").map(function(i, b) { return b.attr("id") }).join()`:                            "1,2,3",
+		`doc.find("//book").toArray().length`:                                                              int64(3),
+		`doc.evaluate("count(//book)")`:                                                                    int64(3),
+		`doc.evaluate("//book[1]/title").text()`:                                                           "Go",
+		`doc.find("//missing").name()`:                                                                     nil,
+		`var ids = []; doc.find("//book").each(function(i, b) { ids.push(i + b.attr("id")) }); ids.join()`: "01,12,23",
+	}
+	for code, expected := range testCases {
+		v, err := rt.RunString(code)
+		require.NoError(t, err, code)
+		assert.Equal(t, expected, v.Export(), code)
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+		rt := makeRuntime()
+		_, err := rt.RunString(`xml.parseXML("<a>")`)
+		assert.Contains(t, err.Error(), "<a> isn't closed")
+		_, err = rt.RunString(`xml.parseXML("<a/>").find("count(//a)")`)
+		assert.Contains(t, err.Error(), `the XPath expression "count(//a)" doesn't select nodes, see evaluate()`)
+	})
+}
+
+func TestSoap(t *testing.T) {
+	t.Parallel()
+	t.Run("Envelope", func(t *testing.T) {
+		t.Parallel()
+		rt := makeRuntime()
+		v, err := rt.RunString(`xml.soapEnvelope("<m:Get><m:Name>" + xml.escape("a<b") + "</m:Name></m:Get>", {
+			namespaces: {m: "urn:m"},
+			header: "<m:Token>t</m:Token>",
+		})`)
+		require.NoError(t, err)
+		assert.Equal(t, `<?xml version="1.0" encoding="utf-8"?>`+"\n"+
+			`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:m="urn:m">`+
+			`<soap:Header><m:Token>t</m:Token></soap:Header>`+
+			`<soap:Body><m:Get><m:Name>a&lt;b</m:Name></m:Get></soap:Body></soap:Envelope>`, v.Export())
+
+		v, err = rt.RunString(`xml.soapEnvelope("<a/>", {version: "1.2", prefix: "env"})`)
+		require.NoError(t, err)
+		assert.Equal(t, `<?xml version="1.0" encoding="utf-8"?>`+"\n"+
+			`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body><a/></env:Body></env:Envelope>`,
+			v.Export())
+
+		_, err = rt.RunString(`xml.soapEnvelope("", {version: "2"})`)
+		assert.Contains(t, err.Error(), `unsupported SOAP version "2", it has to be 1.1 or 1.2`)
+	})
+
+	t.Run("Fault", func(t *testing.T) {
+		t.Parallel()
+		rt := makeRuntime()
+		v, err := rt.RunString(`xml.parseXML('<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>' +
+			'<s:Fault><faultcode>s:Client</faultcode><faultstring>Bad</faultstring><detail><e>1</e></detail></s:Fault>' +
+			'</s:Body></s:Envelope>').soapFault()`)
+		require.NoError(t, err)
+		assert.Equal(t, &SoapFault{Code: "s:Client", Reason: "Bad", Detail: "<e>1</e>"}, v.Export())
+
+		v, err = rt.RunString(`xml.parseXML('<e:Envelope xmlns:e="http://www.w3.org/2003/05/soap-envelope"><e:Body><e:Fault>' +
+			'<e:Code><e:Value>e:Receiver</e:Value></e:Code><e:Reason><e:Text xml:lang="en">Down</e:Text></e:Reason>' +
+			'</e:Fault></e:Body></e:Envelope>').soapFault()`)
+		require.NoError(t, err)
+		assert.Equal(t, &SoapFault{Code: "e:Receiver", Reason: "Down"}, v.Export())
+
+		v, err = rt.RunString(`xml.parseXML("<a/>").soapFault()`)
+		require.NoError(t, err)
+		assert.Nil(t, v.Export())
+	})
+}
@@ -0,0 +1,505 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package xml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// This is an XPath 1.0 implementation, without variables and the following and preceding axes.
+// The only deliberate difference from the specification is that a name test without a prefix,
+// like "Body", matches the local name of elements in any namespace, so documents with a default
+// namespace can be queried without declaring it. A name test with a prefix, like "soap:Body",
+// matches the namespace of the prefix, from the namespaces given to the query or the ones that are
+// declared in the document.
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokName     // a QName, or a name test like "prefix:*" or "*"
+	tokOperator // everything else, including the operator names
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	num  float64
+}
+
+func isNameStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isNameChar(r rune) bool {
+	return isNameStart(r) || r == '-' || r == '.' || unicode.IsDigit(r)
+}
+
+//nolint:funlen,gocognit,cyclop
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	src := []rune(expr)
+	// an operand before a * or an operator name makes them operators, see section 3.7 of the spec
+	afterOperand := func() bool {
+		if len(tokens) == 0 {
+			return false
+		}
+		last := tokens[len(tokens)-1]
+		if last.kind != tokOperator {
+			return true
+		}
+		return last.val == ")" || last.val == "]" || last.val == "." || last.val == ".."
+	}
+	readName := func(i int) int {
+		for i < len(src) && isNameChar(src[i]) {
+			i++
+		}
+		return i
+	}
+
+	for i := 0; i < len(src); {
+		r := src[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != r {
+				end++
+			}
+			if end == len(src) {
+				return nil, fmt.Errorf("unterminated string in %q", expr)
+			}
+			tokens = append(tokens, token{kind: tokString, val: string(src[i+1 : end])})
+			i = end + 1
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(src) && unicode.IsDigit(src[i+1])):
+			end := i
+			for end < len(src) && (unicode.IsDigit(src[end]) || src[end] == '.') {
+				end++
+			}
+			n, err := strconv.ParseFloat(string(src[i:end]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q in %q", string(src[i:end]), expr)
+			}
+			tokens = append(tokens, token{kind: tokNumber, num: n})
+			i = end
+		case r == '*':
+			if afterOperand() {
+				tokens = append(tokens, token{kind: tokOperator, val: "*"})
+			} else {
+				tokens = append(tokens, token{kind: tokName, val: "*"})
+			}
+			i++
+		case isNameStart(r):
+			end := readName(i)
+			name := string(src[i:end])
+			if afterOperand() && (name == "and" || name == "or" || name == "mod" || name == "div") {
+				tokens = append(tokens, token{kind: tokOperator, val: name})
+				i = end
+				continue
+			}
+			if end+1 < len(src) && src[end] == ':' && src[end+1] != ':' {
+				switch {
+				case src[end+1] == '*':
+					name += ":*"
+					end += 2
+				case isNameStart(src[end+1]):
+					next := readName(end + 1)
+					name += ":" + string(src[end+1:next])
+					end = next
+				default:
+					return nil, fmt.Errorf("invalid name %q in %q", name+":", expr)
+				}
+			}
+			tokens = append(tokens, token{kind: tokName, val: name})
+			i = end
+		default:
+			op := string(r)
+			if i+1 < len(src) {
+				switch two := string(src[i : i+2]); two {
+				case "//", "!=", "<=", ">=", "::", "..":
+					op = two
+				}
+			}
+			if !strings.Contains("/|+-=!<>()[],.@:", op[:1]) || op == "!" || op == ":" {
+				return nil, fmt.Errorf("unexpected %q in %q", op, expr)
+			}
+			tokens = append(tokens, token{kind: tokOperator, val: op})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF}), nil
+}
+
+type expr interface {
+	eval(c *evalContext) (interface{}, error)
+}
+
+type (
+	binaryExpr struct {
+		op          string
+		left, right expr
+	}
+	negateExpr struct {
+		operand expr
+	}
+	literalExpr struct {
+		value interface{}
+	}
+	callExpr struct {
+		name string
+		args []expr
+	}
+	filterExpr struct {
+		primary    expr
+		predicates []expr
+	}
+	// pathExpr is a location path, relative to the result of start if it's not nil
+	pathExpr struct {
+		start    expr
+		absolute bool
+		steps    []step
+	}
+	step struct {
+		axis       string
+		test       nodeTest
+		predicates []expr
+	}
+	nodeTest struct {
+		kind   string // "name", "node", "text", "comment" or "processing-instruction"
+		prefix string
+		local  string // "*" for any name
+	}
+)
+
+type parser struct {
+	expr   string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(vals ...string) bool {
+	t := p.peek()
+	if t.kind != tokOperator {
+		return false
+	}
+	for _, v := range vals {
+		if t.val == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(val string) error {
+	if !p.isOp(val) {
+		return p.errorf("expected %q", val)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	at := "the end"
+	switch t.kind {
+	case tokEOF:
+	case tokNumber:
+		at = strconv.FormatFloat(t.num, 'f', -1, 64)
+	default:
+		at = strconv.Quote(t.val)
+	}
+	return fmt.Errorf("invalid XPath expression %q: %s at %s", p.expr, fmt.Sprintf(format, args...), at)
+}
+
+// compile parses an XPath expression.
+func compile(src string) (expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{expr: src, tokens: tokens}
+	e, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected token")
+	}
+	return e, nil
+}
+
+//nolint:gochecknoglobals
+var precedence = [][]string{
+	{"or"},
+	{"and"},
+	{"=", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "div", "mod"},
+}
+
+func (p *parser) parseBinary(level int) (expr, error) {
+	if level == len(precedence) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(precedence[level]...) {
+		op := p.next().val
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.isOp("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negateExpr{operand: operand}, nil
+	}
+	left, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("|") {
+		p.next()
+		right, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "|", left: left, right: right}
+	}
+	return left, nil
+}
+
+func isNodeType(name string) bool {
+	return name == "node" || name == "text" || name == "comment" || name == "processing-instruction"
+}
+
+// startsStep returns whether the next token starts a location step.
+func (p *parser) startsStep() bool {
+	t := p.peek()
+	switch t.kind {
+	case tokName:
+		next := p.tokens[p.pos+1]
+		isCall := next.kind == tokOperator && next.val == "("
+		return !isCall || isNodeType(t.val)
+	case tokOperator:
+		return t.val == "." || t.val == ".." || t.val == "@"
+	default:
+		return false
+	}
+}
+
+func (p *parser) parsePath() (expr, error) {
+	path := &pathExpr{}
+	switch {
+	case p.isOp("/"):
+		p.next()
+		path.absolute = true
+		if !p.startsStep() {
+			return path, nil
+		}
+	case p.isOp("//"):
+		p.next()
+		path.absolute = true
+		path.steps = append(path.steps, descendantOrSelf())
+	case p.startsStep():
+	default:
+		primary, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		predicates, err := p.parsePredicates()
+		if err != nil {
+			return nil, err
+		}
+		if len(predicates) > 0 {
+			primary = &filterExpr{primary: primary, predicates: predicates}
+		}
+		if !p.isOp("/", "//") {
+			return primary, nil
+		}
+		path.start = primary
+		if p.next().val == "//" {
+			path.steps = append(path.steps, descendantOrSelf())
+		}
+	}
+
+	for {
+		s, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+		path.steps = append(path.steps, s)
+		if !p.isOp("/", "//") {
+			return path, nil
+		}
+		if p.next().val == "//" {
+			path.steps = append(path.steps, descendantOrSelf())
+		}
+	}
+}
+
+func descendantOrSelf() step {
+	return step{axis: "descendant-or-self", test: nodeTest{kind: "node"}}
+}
+
+//nolint:gochecknoglobals
+var axes = map[string]bool{
+	"ancestor": true, "ancestor-or-self": true, "attribute": true, "child": true, "descendant": true,
+	"descendant-or-self": true, "following-sibling": true, "parent": true, "preceding-sibling": true,
+	"self": true,
+}
+
+func (p *parser) parseStep() (step, error) {
+	switch {
+	case p.isOp("."):
+		p.next()
+		return step{axis: "self", test: nodeTest{kind: "node"}}, nil
+	case p.isOp(".."):
+		p.next()
+		return step{axis: "parent", test: nodeTest{kind: "node"}}, nil
+	}
+
+	s := step{axis: "child"}
+	if p.isOp("@") {
+		p.next()
+		s.axis = "attribute"
+	} else if next := p.tokens[p.pos+1]; p.peek().kind == tokName && next.kind == tokOperator && next.val == "::" {
+		s.axis = p.next().val
+		p.next()
+		if !axes[s.axis] {
+			return s, fmt.Errorf("invalid XPath expression %q: the %s axis isn't supported", p.expr, s.axis)
+		}
+	}
+
+	t := p.next()
+	if t.kind != tokName {
+		p.pos--
+		return s, p.errorf("expected a node test")
+	}
+	if isNodeType(t.val) && p.isOp("(") {
+		p.next()
+		if t.val == "processing-instruction" && p.peek().kind == tokString {
+			s.test.local = p.next().val
+		}
+		if err := p.expect(")"); err != nil {
+			return s, err
+		}
+		s.test.kind = t.val
+	} else {
+		s.test.kind = "name"
+		s.test.prefix, s.test.local = splitName(t.val)
+	}
+
+	var err error
+	s.predicates, err = p.parsePredicates()
+	return s, err
+}
+
+func (p *parser) parsePredicates() ([]expr, error) {
+	var predicates []expr
+	for p.isOp("[") {
+		p.next()
+		e, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect("]"); err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, e)
+	}
+	return predicates, nil
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokString:
+		p.next()
+		return &literalExpr{value: t.val}, nil
+	case t.kind == tokNumber:
+		p.next()
+		return &literalExpr{value: t.num}, nil
+	case p.isOp("("):
+		p.next()
+		e, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	case t.kind == tokName:
+		p.next()
+		if _, ok := functions[t.val]; !ok {
+			p.pos--
+			return nil, p.errorf("unknown function")
+		}
+		call := &callExpr{name: t.val}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		for !p.isOp(")") {
+			if len(call.args) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			arg, err := p.parseBinary(0)
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+		}
+		p.next()
+		return call, nil
+	default:
+		return nil, p.errorf("unexpected token")
+	}
+}
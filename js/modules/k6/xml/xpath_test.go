@@ -0,0 +1,166 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package xml
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testXPathDoc = `<?xml version="1.0"?>
+<library xmlns="urn:library" xmlns:x="urn:extra">
+	<!-- the books -->
+	<book id="1" lang="en"><title>Go</title><price>10.5</price><x:rating>4</x:rating></book>
+	<book id="2" lang="de"><title>Rust</title><price>20</price></book>
+	<book id="3"><title><![CDATA[A & B]]></title><price>5</price><x:rating>2</x:rating></book>
+	<?render fast?>
+</library>`
+
+func TestXPath(t *testing.T) {
+	t.Parallel()
+	doc, err := parse(testXPathDoc)
+	require.NoError(t, err)
+
+	// node-sets are compared as the space separated string-values of their nodes
+	testCases := map[string]interface{}{
+		"/library/book/title":                           "Go Rust A & B",
+		"//title":                                       "Go Rust A & B",
+		"//book[2]/title":                               "Rust",
+		"//book[last()]/@id":                            "3",
+		"//book[position() < 3]/@id":                    "1 2",
+		"//book[@lang='de']/title":                      "Rust",
+		"//book[not(@lang)]/title":                      "A & B",
+		"//book[price > 10]/title":                      "Go Rust",
+		"//book[title = 'Go' or title = 'Rust']/@id":    "1 2",
+		"//book[x:rating]/@id":                          "1 3",
+		"//x:rating":                                    "4 2",
+		"//*[local-name() = 'rating'][1]":               "4 2",
+		"(//x:rating)[1]":                               "4",
+		"//title[. = 'Rust']/../@id":                    "2",
+		"//price/parent::book/@id":                      "1 2 3",
+		"//book[1]/following-sibling::book/@id":         "2 3",
+		"//book[3]/preceding-sibling::book[1]/@id":      "2",
+		"//title[text() = 'Go']/ancestor::*/@id":        "1",
+		"//book[1]/@*":                                  "1 en",
+		"//book[1]/title | //book[2]/price":             "Go 20",
+		"//comment()":                                   " the books ",
+		"//processing-instruction('render')":            "fast",
+		"count(//book)":                                 float64(3),
+		"sum(//price)":                                  35.5,
+		"sum(//price) div count(//price) * 2":           35.5 / 3 * 2,
+		"7 mod 3 - -1":                                  float64(2),
+		"string(//book[1]/price * 2)":                   "21",
+		"concat(//book[1]/title, '-', //book[2]/title)": "Go-Rust",
+		"substring('12345', 2, 3)":                      "234",
+		"substring-before('a=b', '=')":                  "a",
+		"substring-after('a=b', '=')":                   "b",
+		"normalize-space('  a   b ')":                   "a b",
+		"translate('abc', 'abc', 'AB')":                 "AB",
+		"string-length(//book[2]/title)":                float64(4),
+		"starts-with(//book[1]/title, 'G')":             true,
+		"contains(//book[3]/title, '&')":                true,
+		"round(2.5) + floor(1.9) + ceiling(1.1)":        float64(6),
+		"namespace-uri(//x:rating)":                     "urn:extra",
+		"name(/*)":                                      "library",
+		"//book[1]/title = 'Rust'":                      false,
+		"//book/title = 'Rust'":                         true,
+		"//book/title != 'Rust'":                        true,
+		"boolean(//missing)":                            false,
+		"number('abc')":                                 math.NaN(),
+	}
+
+	for expression, expected := range testCases {
+		expression, expected := expression, expected
+		t.Run(expression, func(t *testing.T) {
+			t.Parallel()
+			e, err := compile(expression)
+			require.NoError(t, err)
+			v, err := evaluate(e, doc, nil)
+			require.NoError(t, err)
+			if nodes, ok := v.([]*node); ok {
+				values := make([]string, len(nodes))
+				for i, n := range nodes {
+					values[i] = n.stringValue()
+				}
+				v = strings.Join(values, " ")
+			}
+			if f, ok := expected.(float64); ok && math.IsNaN(f) {
+				assert.True(t, math.IsNaN(v.(float64)))
+				return
+			}
+			assert.Equal(t, expected, v)
+		})
+	}
+}
+
+func TestXPathErrors(t *testing.T) {
+	t.Parallel()
+	doc, err := parse(testXPathDoc)
+	require.NoError(t, err)
+
+	testCases := map[string]string{
+		"//book[":         `invalid XPath expression "//book[": unexpected token at the end`,
+		"//book[1":        `invalid XPath expression "//book[1": expected "]" at the end`,
+		"unknown()":       `invalid XPath expression "unknown()": unknown function at "unknown"`,
+		"following::book": `invalid XPath expression "following::book": the following axis isn't supported`,
+		"'unterminated":   `unterminated string in "'unterminated"`,
+		"//y:book":        `the XPath namespace prefix "y" isn't declared`,
+		"count(1)":        `the argument of count() has to be a node-set`,
+		"concat('a')":     `wrong number of arguments for concat()`,
+		"//book ! 1":      `unexpected "!" in "//book ! 1"`,
+		"1 | //book":      `the operands of | have to be node-sets`,
+		"//book/title)":   `invalid XPath expression "//book/title)": unexpected token at ")"`,
+	}
+	for expression, expected := range testCases {
+		expression, expected := expression, expected
+		t.Run(expression, func(t *testing.T) {
+			t.Parallel()
+			e, err := compile(expression)
+			if err == nil {
+				_, err = evaluate(e, doc, nil)
+			}
+			require.Error(t, err)
+			assert.Equal(t, expected, err.Error())
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]string{
+		"":             "the XML document doesn't have a root element",
+		"<a>":          "<a> isn't closed",
+		"<a></b>":      "unexpected </b>",
+		"<a/></a>":     "unexpected </a>",
+		"<x:a/>":       `the namespace prefix "x" of <x:a> isn't declared`,
+		`<a x:b="1"/>`: `the namespace prefix "x" of the x:b attribute isn't declared`,
+		`<?xml version="1.0" encoding="koi8-r"?><a/>`: `xml: opening charset "koi8-r": the "koi8-r" encoding isn't supported`,
+	}
+	for src, expected := range testCases {
+		_, err := parse(src)
+		require.Error(t, err, src)
+		assert.Equal(t, expected, err.Error(), src)
+	}
+}
@@ -27,14 +27,27 @@ import (
 
 	"go.k6.io/k6/js/modules/k6"
 	"go.k6.io/k6/js/modules/k6/crypto"
+	"go.k6.io/k6/js/modules/k6/crypto/jwt"
 	"go.k6.io/k6/js/modules/k6/crypto/x509"
 	"go.k6.io/k6/js/modules/k6/data"
+	"go.k6.io/k6/js/modules/k6/dns"
 	"go.k6.io/k6/js/modules/k6/encoding"
+	"go.k6.io/k6/js/modules/k6/execution"
+	"go.k6.io/k6/js/modules/k6/faker"
+	"go.k6.io/k6/js/modules/k6/files"
+	"go.k6.io/k6/js/modules/k6/ftp"
 	"go.k6.io/k6/js/modules/k6/grpc"
 	"go.k6.io/k6/js/modules/k6/html"
 	"go.k6.io/k6/js/modules/k6/http"
+	"go.k6.io/k6/js/modules/k6/mail"
 	"go.k6.io/k6/js/modules/k6/metrics"
+	"go.k6.io/k6/js/modules/k6/protobuf"
+	"go.k6.io/k6/js/modules/k6/resilience"
+	"go.k6.io/k6/js/modules/k6/schema"
+	"go.k6.io/k6/js/modules/k6/secrets"
+	"go.k6.io/k6/js/modules/k6/ssh"
 	"go.k6.io/k6/js/modules/k6/ws"
+	"go.k6.io/k6/js/modules/k6/xml"
 )
 
 const extPrefix string = "k6/x/"
@@ -78,14 +91,27 @@ func GetJSModules() map[string]interface{} {
 	result := map[string]interface{}{
 		"k6":             k6.New(),
 		"k6/crypto":      crypto.New(),
+		"k6/crypto/jwt":  jwt.New(),
 		"k6/crypto/x509": x509.New(),
 		"k6/data":        data.New(),
 		"k6/encoding":    encoding.New(),
+		"k6/execution":   execution.New(),
+		"k6/faker":       faker.New(),
+		"k6/files":       files.New(),
+		"k6/net/dns":     dns.New(),
+		"k6/net/ftp":     ftp.New(),
 		"k6/net/grpc":    grpc.New(),
+		"k6/net/mail":    mail.New(),
+		"k6/net/ssh":     ssh.New(),
 		"k6/html":        html.New(),
 		"k6/http":        http.New(),
 		"k6/metrics":     metrics.New(),
+		"k6/protobuf":    protobuf.New(),
+		"k6/resilience":  resilience.New(),
+		"k6/schema":      schema.New(),
+		"k6/secrets":     secrets.New(),
 		"k6/ws":          ws.New(),
+		"k6/xml":         xml.New(),
 	}
 
 	mx.Lock()
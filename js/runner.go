@@ -31,9 +31,11 @@ import (
 	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"os"
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dop251/goja"
@@ -42,6 +44,7 @@ import (
 	"github.com/spf13/afero"
 	"golang.org/x/net/http2"
 	"golang.org/x/time/rate"
+	"gopkg.in/guregu/null.v3"
 
 	"go.k6.io/k6/errext"
 	"go.k6.io/k6/errext/exitcodes"
@@ -49,6 +52,7 @@ import (
 	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/consts"
 	"go.k6.io/k6/lib/netext"
+	"go.k6.io/k6/lib/secrets"
 	"go.k6.io/k6/lib/types"
 	"go.k6.io/k6/loader"
 	"go.k6.io/k6/stats"
@@ -67,9 +71,16 @@ type Runner struct {
 	// TODO: Remove ActualResolver, it's a hack to simplify mocking in tests.
 	ActualResolver netext.MultiResolver
 	RPSLimit       *rate.Limiter
+	FilesLimit     *rate.Limiter
+	Secrets        *secrets.Store
 
 	console   *console
 	setupData []byte
+
+	// The data returned by the setup functions of the scenarios that have
+	// them, with nil values meaning undefined.
+	scenarioSetupData   map[string][]byte
+	scenarioSetupDataMx sync.RWMutex
 }
 
 // New returns a new Runner for the provide source
@@ -173,6 +184,17 @@ func (r *Runner) newVU(idLocal, idGlobal uint64, samplesOut chan<- stats.SampleC
 		BlockedHostnames: r.Bundle.Options.BlockedHostnames.Trie,
 		Hosts:            r.Bundle.Options.Hosts,
 	}
+	if keepAlive := r.Bundle.Options.TCPKeepAlive; keepAlive.Valid {
+		// net.Dialer uses its default period for 0, the probes are disabled with a negative one
+		dialer.Dialer.KeepAlive = time.Duration(keepAlive.Duration)
+		if dialer.Dialer.KeepAlive == 0 {
+			dialer.Dialer.KeepAlive = -1
+		}
+	}
+	dialer.Dialer.Control = netext.SocketOptions{
+		DSCP:      int(r.Bundle.Options.DSCP.Int64),
+		ReusePort: r.Bundle.Options.TCPReusePort.Bool,
+	}.Control()
 	if r.Bundle.Options.LocalIPs.Valid {
 		var ipIndex uint64
 		if idLocal > 0 {
@@ -198,8 +220,22 @@ func (r *Runner) newVU(idLocal, idGlobal uint64, samplesOut chan<- stats.SampleC
 		DisableKeepAlives:   r.Bundle.Options.NoConnectionReuse.Bool,
 		MaxIdleConns:        int(r.Bundle.Options.Batch.Int64),
 		MaxIdleConnsPerHost: int(r.Bundle.Options.BatchPerHost.Int64),
+		IdleConnTimeout:     time.Duration(r.Bundle.Options.IdleConnTimeout.Duration),
+	}
+	if r.Bundle.Options.MaxIdleConnsPerHost.Valid {
+		transport.MaxIdleConnsPerHost = int(r.Bundle.Options.MaxIdleConnsPerHost.Int64)
+		// the idle connections of all the hosts are limited too, so they don't cap the ones per host
+		if transport.MaxIdleConns < transport.MaxIdleConnsPerHost {
+			transport.MaxIdleConns = transport.MaxIdleConnsPerHost
+		}
+	}
+	maxHeaderBytes := r.Bundle.Options.MaxResponseHeaderBytes.Int64
+	if maxHeaderBytes > 0 {
+		transport.MaxResponseHeaderBytes = maxHeaderBytes
+	}
+	if h2Transport, h2Err := http2.ConfigureTransports(transport); h2Err == nil && maxHeaderBytes > 0 {
+		h2Transport.MaxHeaderListSize = uint32(maxHeaderBytes)
 	}
-	_ = http2.ConfigureTransport(transport)
 
 	cookieJar, err := cookiejar.New(nil)
 	if err != nil {
@@ -220,6 +256,8 @@ func (r *Runner) newVU(idLocal, idGlobal uint64, samplesOut chan<- stats.SampleC
 		BPool:          bpool.NewBufferPool(100),
 		Samples:        samplesOut,
 		scenarioIter:   make(map[string]uint64),
+
+		scenarioSetupData: make(map[string]goja.Value),
 	}
 
 	vu.state = &lib.State{
@@ -230,14 +268,20 @@ func (r *Runner) newVU(idLocal, idGlobal uint64, samplesOut chan<- stats.SampleC
 		TLSConfig:  vu.TLSConfig,
 		CookieJar:  cookieJar,
 		RPSLimit:   vu.Runner.RPSLimit,
+		FilesLimit: vu.Runner.FilesLimit,
+		Secrets:    vu.Runner.Secrets,
 		BPool:      vu.BPool,
 		VUID:       vu.ID,
 		VUIDGlobal: vu.IDGlobal,
 		Samples:    vu.Samples,
 		Tags:       vu.Runner.Bundle.Options.RunTags.CloneTags(),
 		Group:      r.defaultGroup,
+
+		AsyncRequestsLimit:        lib.NewSlotLimiter(int(vu.Runner.Bundle.Options.AsyncRequests.Int64)),
+		AsyncRequestsPerHostLimit: lib.NewMultiSlotLimiter(int(vu.Runner.Bundle.Options.AsyncRequestsPerHost.Int64)),
 	}
 	vu.Runtime.Set("console", common.Bind(vu.Runtime, vu.Console, vu.Context))
+	common.BindToGlobal(vu.Runtime, vu.eventLoop.Timers())
 
 	// This is here mostly so if someone tries they get a nice message
 	// instead of "Value is not an object: undefined  ..."
@@ -299,6 +343,82 @@ func (r *Runner) Teardown(ctx context.Context, out chan<- stats.SampleContainer)
 	return err
 }
 
+// ScenarioSetup runs the fn setup function of the scenario, with the data
+// returned by the test-wide setup() as its argument, and saves its result for
+// the VU iterations and the teardown function of the scenario.
+func (r *Runner) ScenarioSetup(
+	ctx context.Context, out chan<- stats.SampleContainer, scenario, fn string,
+) error {
+	setupCtx, setupCancel := context.WithTimeout(ctx, r.getTimeoutFor(consts.SetupFn))
+	defer setupCancel()
+
+	arg, err := unmarshalSetupData(r.setupData)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling setup data for %s() from JSON: %w", fn, err)
+	}
+	v, err := r.runPart(setupCtx, out, fn, arg)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if !goja.IsUndefined(v) {
+		data, err = json.Marshal(v.Export())
+		if err != nil {
+			return fmt.Errorf("error marshaling %s() data to JSON: %w", fn, err)
+		}
+	}
+
+	r.scenarioSetupDataMx.Lock()
+	defer r.scenarioSetupDataMx.Unlock()
+	if r.scenarioSetupData == nil {
+		r.scenarioSetupData = make(map[string][]byte)
+	}
+	r.scenarioSetupData[scenario] = data
+	return nil
+}
+
+// ScenarioTeardown runs the fn teardown function of the scenario, with the
+// data returned by its setup function as its argument, or the test-wide
+// setup data if the scenario doesn't have one.
+func (r *Runner) ScenarioTeardown(
+	ctx context.Context, out chan<- stats.SampleContainer, scenario, fn string,
+) error {
+	teardownCtx, teardownCancel := context.WithTimeout(ctx, r.getTimeoutFor(consts.TeardownFn))
+	defer teardownCancel()
+
+	data, ok := r.getScenarioSetupData(scenario)
+	if !ok {
+		data = r.setupData
+	}
+	arg, err := unmarshalSetupData(data)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling setup data for %s() from JSON: %w", fn, err)
+	}
+	_, err = r.runPart(teardownCtx, out, fn, arg)
+	return err
+}
+
+// getScenarioSetupData returns the data returned by the setup function of the
+// scenario and true, or false if the scenario doesn't have one.
+func (r *Runner) getScenarioSetupData(scenario string) ([]byte, bool) {
+	r.scenarioSetupDataMx.RLock()
+	defer r.scenarioSetupDataMx.RUnlock()
+	data, ok := r.scenarioSetupData[scenario]
+	return data, ok
+}
+
+func unmarshalSetupData(data []byte) (interface{}, error) {
+	if data == nil {
+		return goja.Undefined(), nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
 func (r *Runner) GetDefaultGroup() *lib.Group {
 	return r.defaultGroup
 }
@@ -341,6 +461,7 @@ func (r *Runner) HandleSummary(ctx context.Context, summary *lib.Summary) (map[s
 		}
 	}
 	ctx = common.WithRuntime(ctx, vu.Runtime)
+	ctx = common.WithEventLoop(ctx, vu.eventLoop)
 	ctx = lib.WithState(ctx, vu.state)
 	ctx, cancel := context.WithTimeout(ctx, r.getTimeoutFor(consts.HandleSummaryFn))
 	defer cancel()
@@ -391,15 +512,28 @@ func (r *Runner) SetOptions(opts lib.Options) error {
 	if rps := opts.RPS; rps.Valid {
 		r.RPSLimit = rate.NewLimiter(rate.Limit(rps.Int64), 1)
 	}
+	r.FilesLimit = nil
+	if filesRate := opts.FilesRate; filesRate.Valid && filesRate.Int64 > 0 {
+		// the burst is a second worth of bytes, larger writes are waited for in chunks
+		r.FilesLimit = rate.NewLimiter(rate.Limit(filesRate.Int64), int(filesRate.Int64))
+	}
+
+	if err := r.setSecretSources(opts.SecretSources); err != nil {
+		return err
+	}
 
 	// TODO: validate that all exec values are either nil or valid exported methods (or HTTP requests in the future)
 
 	if opts.ConsoleOutput.Valid {
-		c, err := newFileConsole(opts.ConsoleOutput.String, r.Logger.Formatter)
+		c, err := newConsoleFromOutput(opts.ConsoleOutput.String, r.Logger, r.Logger)
 		if err != nil {
 			return err
 		}
 
+		if l, ok := c.logger.(*logrus.Logger); ok && r.Secrets != nil {
+			r.Secrets.RedactLogs(l)
+		}
+		r.console.stop()
 		r.console = c
 	}
 
@@ -420,6 +554,35 @@ func (r *Runner) SetOptions(opts lib.Options) error {
 	return nil
 }
 
+// Close stops the console output of the runner, for the outputs like Loki that have to push
+// the last messages.
+func (r *Runner) Close() error {
+	r.console.stop()
+	return nil
+}
+
+// setSecretSources sets the sources of the secrets of the VUs. The store is
+// created with the first sources, and its values are redacted from the logs
+// from then on, even if the sources are removed by a later call.
+func (r *Runner) setSecretSources(specs []string) error {
+	if len(specs) == 0 {
+		if r.Secrets != nil {
+			r.Secrets.SetSources()
+		}
+		return nil
+	}
+	sources, err := secrets.NewSources(specs, afero.NewOsFs(), os.Environ())
+	if err != nil {
+		return err
+	}
+	if r.Secrets == nil {
+		r.Secrets = secrets.NewStore()
+		r.Secrets.RedactLogs(r.Logger)
+	}
+	r.Secrets.SetSources(sources...)
+	return nil
+}
+
 func (r *Runner) setResolver(dns types.DNSConfig) error {
 	ttl, err := parseTTL(dns.TTL.String)
 	if err != nil {
@@ -478,6 +641,7 @@ func (r *Runner) runPart(ctx context.Context, out chan<- stats.SampleContainer,
 	}
 
 	ctx = common.WithRuntime(ctx, vu.Runtime)
+	ctx = common.WithEventLoop(ctx, vu.eventLoop)
 	ctx = lib.WithState(ctx, vu.state)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -544,16 +708,24 @@ type VU struct {
 	Samples chan<- stats.SampleContainer
 
 	setupData goja.Value
+	// the per-VU copies of the data returned by the scenario setup functions
+	scenarioSetupData map[string]goja.Value
 
 	state *lib.State
 	// count of iterations executed by this VU in each scenario
 	scenarioIter map[string]uint64
+	// the noCookiesReset option of the scenario the VU is activated for
+	noCookiesReset null.Bool
+	// Whether onVUStart() was called, it's only accessed while the VU is
+	// running an iteration or being stopped, so it needs no locking.
+	started bool
 }
 
 // Verify that interfaces are implemented
 var (
 	_ lib.ActiveVU      = &ActiveVU{}
 	_ lib.InitializedVU = &VU{}
+	_ lib.StoppableVU   = &VU{}
 )
 
 // ActiveVU holds a VU and its activation parameters
@@ -608,8 +780,12 @@ func (u *VU) Activate(params *lib.VUActivationParams) lib.ActiveVU {
 	if opts.SystemTags.Has(stats.TagScenario) {
 		u.state.Tags["scenario"] = params.Scenario
 	}
+	u.state.NoConnectionReuse = params.NoConnectionReuse
+	u.noCookiesReset = params.NoCookiesReset
+	u.Dialer.SetNetworkShaping(params.Network)
 
 	ctx := common.WithRuntime(params.RunContext, u.Runtime)
+	ctx = common.WithEventLoop(ctx, u.eventLoop)
 	ctx = lib.WithState(ctx, u.state)
 	params.RunContext = ctx
 	*u.Context = ctx
@@ -664,17 +840,17 @@ func (u *ActiveVU) RunOnce() error {
 		<-u.busy // unlock deactivation again
 	}()
 
-	// Unmarshall the setupData only the first time for each VU so that VUs are isolated but we
-	// still don't use too much CPU in the middle test
-	if u.setupData == nil {
-		if u.Runner.setupData != nil {
-			var data interface{}
-			if err := json.Unmarshal(u.Runner.setupData, &data); err != nil {
-				return fmt.Errorf("error unmarshaling setup data for the iteration from JSON: %w", err)
+	setupData, err := u.getSetupData()
+	if err != nil {
+		return err
+	}
+
+	if !u.started {
+		u.started = true
+		if fn, ok := u.exports[consts.VUStartFn]; ok {
+			if _, _, _, err = u.runFn(u.RunContext, false, fn); err != nil {
+				return err
 			}
-			u.setupData = u.Runtime.ToValue(data)
-		} else {
-			u.setupData = goja.Undefined()
 		}
 	}
 
@@ -690,7 +866,7 @@ func (u *ActiveVU) RunOnce() error {
 	}
 
 	// Call the exported function.
-	_, isFullIteration, totalTime, err := u.runFn(u.RunContext, true, fn, u.setupData)
+	_, isFullIteration, totalTime, err := u.runFn(u.RunContext, true, fn, setupData)
 
 	// If MinIterationDuration is specified and the iteration wasn't canceled
 	// and was less than it, sleep for the remainder
@@ -707,10 +883,89 @@ func (u *ActiveVU) RunOnce() error {
 	return err
 }
 
+// Stop calls the onTestAbort() function of the script, if the test run was
+// aborted, and its onVUStop() function, once at the end of the test run, after
+// the last activation of the VU. Since there's no run context anymore, they
+// are executed with a new one, limited by the teardownTimeout option. Nothing
+// is called if the VU never executed any iterations.
+func (u *VU) Stop(abortReason lib.AbortReason) {
+	abortFn, hasAbortFn := u.exports[consts.TestAbortFn]
+	stopFn, hasStopFn := u.exports[consts.VUStopFn]
+	if !u.started || (!hasAbortFn && !hasStopFn) {
+		return
+	}
+	u.started = false
+
+	u.Runtime.ClearInterrupt()
+	ctx, cancel := context.WithTimeout(context.Background(), u.Runner.getTimeoutFor(consts.TeardownFn))
+	ctx = common.WithRuntime(ctx, u.Runtime)
+	ctx = common.WithEventLoop(ctx, u.eventLoop)
+	ctx = lib.WithState(ctx, u.state)
+	*u.Context = ctx
+	interrupted := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		u.Runtime.Interrupt(context.Canceled)
+		close(interrupted)
+	}()
+	defer func() {
+		cancel()
+		<-interrupted // so the VU isn't interrupted after Stop() returns
+	}()
+
+	if hasAbortFn && abortReason != "" {
+		if _, _, _, err := u.runFn(ctx, false, abortFn, u.Runtime.ToValue(string(abortReason))); err != nil {
+			u.state.Logger.WithError(err).Warnf("%s() failed", consts.TestAbortFn)
+		}
+	}
+	if hasStopFn {
+		info := map[string]interface{}{"aborted": abortReason != ""}
+		if _, _, _, err := u.runFn(ctx, false, stopFn, u.Runtime.ToValue(info)); err != nil {
+			u.state.Logger.WithError(err).Warnf("%s() failed", consts.VUStopFn)
+		}
+	}
+}
+
+// getSetupData returns the setup data for the iterations of the current
+// scenario, i.e. the data returned by its own setup function if it has one, or
+// the test-wide setup data otherwise.
+func (u *ActiveVU) getSetupData() (goja.Value, error) {
+	// Unmarshall the setupData only the first time for each VU so that VUs are isolated but we
+	// still don't use too much CPU in the middle test
+	if data, ok := u.Runner.getScenarioSetupData(u.scenarioName); ok {
+		if v, ok := u.scenarioSetupData[u.scenarioName]; ok {
+			return v, nil
+		}
+		v, err := unmarshalSetupData(data)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshaling setup data for the iteration from JSON: %w", err)
+		}
+		u.scenarioSetupData[u.scenarioName] = u.Runtime.ToValue(v)
+		return u.scenarioSetupData[u.scenarioName], nil
+	}
+
+	if u.setupData == nil {
+		if u.Runner.setupData != nil {
+			var data interface{}
+			if err := json.Unmarshal(u.Runner.setupData, &data); err != nil {
+				return nil, fmt.Errorf("error unmarshaling setup data for the iteration from JSON: %w", err)
+			}
+			u.setupData = u.Runtime.ToValue(data)
+		} else {
+			u.setupData = goja.Undefined()
+		}
+	}
+	return u.setupData, nil
+}
+
 func (u *VU) runFn(
 	ctx context.Context, isDefault bool, fn goja.Callable, args ...goja.Value,
 ) (v goja.Value, isFullIteration bool, t time.Duration, err error) {
-	if !u.Runner.Bundle.Options.NoCookiesReset.ValueOrZero() {
+	noCookiesReset := u.Runner.Bundle.Options.NoCookiesReset
+	if u.noCookiesReset.Valid {
+		noCookiesReset = u.noCookiesReset
+	}
+	if !noCookiesReset.ValueOrZero() {
 		u.state.CookieJar, err = cookiejar.New(nil)
 		if err != nil {
 			return goja.Undefined(), false, time.Duration(0), err
@@ -739,12 +994,15 @@ func (u *VU) runFn(
 	}()
 
 	startTime := time.Now()
-	v, err = fn(goja.Undefined(), args...) // Actually run the JS script
+	u.state.IterationStartTime = startTime
+	// Actually run the JS script, and everything that it left on the event loop
+	v, err = u.eventLoop.Call(ctx, fn, args...)
 	endTime := time.Now()
 	var exception *goja.Exception
 	if errors.As(err, &exception) {
 		err = &scriptException{inner: exception}
 	}
+	err = abortTestError(err)
 
 	select {
 	case <-ctx.Done():
@@ -777,6 +1035,18 @@ func (u *ActiveVU) incrIteration() {
 	}
 }
 
+// abortTestError returns the error of the abortTest() of the script, if it interrupted the runtime,
+// or the error as it is.
+func abortTestError(err error) error {
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		if abortErr, ok := interrupted.Value().(*errext.AbortTest); ok {
+			return abortErr
+		}
+	}
+	return err
+}
+
 type scriptException struct {
 	inner *goja.Exception
 }
@@ -33,6 +33,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -1223,6 +1224,47 @@ func TestVUIntegrationCookiesNoReset(t *testing.T) {
 	}
 }
 
+func TestVUIntegrationScenarioCookiesNoReset(t *testing.T) {
+	t.Parallel()
+	tb := httpmultibin.NewHTTPMultiBin(t)
+
+	r, err := getSimpleRunner(t, "/script.js", tb.Replacer.Replace(`
+			var http = require("k6/http");
+			exports.default = function() {
+				if (__ITER == 0) {
+					http.get("HTTPBIN_URL/cookies/set?k1=v1");
+					return;
+				}
+				var res = http.get("HTTPBIN_URL/cookies");
+				if (__ENV.KEEP == "true" && res.json().k1 != "v1") { throw new Error("no cookies: " + res.body); }
+				if (__ENV.KEEP == "false" && res.json().k1 !== undefined) { throw new Error("cookies: " + res.body); }
+			}
+		`))
+	require.NoError(t, err)
+	r.SetOptions(lib.Options{
+		Throw:          null.BoolFrom(true),
+		MaxRedirects:   null.IntFrom(10),
+		Hosts:          tb.Dialer.Hosts,
+		NoCookiesReset: null.BoolFrom(true),
+	})
+
+	// the option of the scenario overrides the global one
+	for _, keep := range []bool{true, false} {
+		initVU, err := r.NewVU(1, 1, make(chan stats.SampleContainer, 100))
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		vu := initVU.Activate(&lib.VUActivationParams{
+			RunContext:     ctx,
+			Env:            map[string]string{"KEEP": fmt.Sprint(keep)},
+			NoCookiesReset: null.BoolFrom(keep),
+		})
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, vu.RunOnce(), keep)
+		}
+		cancel()
+	}
+}
+
 func TestVUIntegrationVUID(t *testing.T) {
 	t.Parallel()
 	r1, err := getSimpleRunner(t, "/script.js", `
@@ -2021,3 +2063,158 @@ func TestMinIterationDurationIsCancellable(t *testing.T) {
 		require.NoError(t, err)
 	}
 }
+
+func TestVULifecycleFunctions(t *testing.T) {
+	t.Parallel()
+	r, err := getSimpleRunner(t, "/script.js", `
+		var Counter = require("k6/metrics").Counter;
+		var calls = new Counter("calls");
+		var session = null;
+		exports.options = { teardownTimeout: "5s" };
+		exports.onVUStart = function() {
+			session = "session-" + __VU;
+			calls.add(1, { fn: "start" });
+		};
+		exports.default = function() { calls.add(1, { fn: "iteration", session: session }); };
+		exports.onTestAbort = function(reason) { calls.add(1, { fn: "abort", reason: reason }); };
+		exports.onVUStop = function(info) {
+			calls.add(1, { fn: "stop", session: session, aborted: String(info.aborted) });
+		};
+	`)
+	require.NoError(t, err)
+
+	samples := make(chan stats.SampleContainer, 100)
+	initVU, err := r.NewVU(1, 1, samples)
+	require.NoError(t, err)
+
+	// the VU is activated for two scenarios, but it's only started and
+	// stopped once, at the end of the test run
+	for _, scenario := range []string{"first", "second"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		deactivated := make(chan struct{})
+		vu := initVU.Activate(&lib.VUActivationParams{
+			RunContext:         ctx,
+			Scenario:           scenario,
+			DeactivateCallback: func(lib.InitializedVU) { close(deactivated) },
+		})
+		require.NoError(t, vu.RunOnce())
+		cancel()
+		select {
+		case <-deactivated:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the VU wasn't deactivated")
+		}
+	}
+	stoppable, ok := initVU.(lib.StoppableVU)
+	require.True(t, ok)
+	stoppable.Stop(lib.AbortReasonThreshold)
+	stoppable.Stop(lib.AbortReasonThreshold)
+
+	var calls []map[string]string
+	for _, sc := range stats.GetBufferedSamples(samples) {
+		for _, s := range sc.GetSamples() {
+			if s.Metric.Name == "calls" {
+				tags := s.Tags.CloneTags()
+				delete(tags, "group")
+				calls = append(calls, tags)
+			}
+		}
+	}
+	assert.Equal(t, []map[string]string{
+		{"fn": "start"},
+		{"fn": "iteration", "session": "session-1"},
+		{"fn": "iteration", "session": "session-1"},
+		{"fn": "abort", "reason": "threshold"},
+		{"fn": "stop", "session": "session-1", "aborted": "true"},
+	}, calls)
+}
+
+func TestVUAsync(t *testing.T) {
+	t.Parallel()
+	r1, err := getSimpleRunner(t, "/script.js", `
+		let initTimerErr;
+		try { setTimeout(() => {}, 1); } catch (e) { initTimerErr = e; }
+		const sleep = (ms, value) => new Promise((resolve) => setTimeout(resolve, ms, value));
+		export const options = { setupTimeout: "5s" };
+
+		export async function setup() {
+			return await sleep(1, "data");
+		}
+
+		export default async function(data) {
+			if (initTimerErr === undefined) {
+				throw new Error("setTimeout() worked in the init context");
+			}
+			const values = await Promise.all([sleep(20, 1), sleep(10, 2), check()]);
+			let sum = 0;
+			for (const v of values) {
+				sum += await v;
+			}
+			if (data !== "data" || sum !== 3) {
+				throw new Error("unexpected " + data + " " + sum);
+			}
+			try {
+				await Promise.reject(new Error("rejected"));
+			} catch (e) {
+				throw new Error("caught " + e.message);
+			}
+		}
+		`, lib.RuntimeOptions{CompatibilityMode: null.StringFrom("extended")})
+	require.NoError(t, err)
+
+	r2, err := NewFromArchive(testutils.NewLogger(t), r1.MakeArchive(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	testdata := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range testdata {
+		r := r
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			samples := make(chan stats.SampleContainer, 100)
+			require.NoError(t, r.Setup(ctx, samples))
+			vu, err := r.newVU(1, 1, samples)
+			require.NoError(t, err)
+			vu.Runtime.Set("check", func() int {
+				assert.NotNil(t, common.GetEventLoop(*vu.Context))
+				return 0
+			})
+
+			activeVU := vu.Activate(&lib.VUActivationParams{RunContext: ctx})
+			err = activeVU.RunOnce()
+			require.Error(t, err)
+			assert.Equal(t, "Error: caught rejected", err.Error())
+		})
+	}
+}
+
+func TestVUSecrets(t *testing.T) {
+	t.Parallel()
+	secretsFile := filepath.Join(t.TempDir(), "secrets.json")
+	require.NoError(t, ioutil.WriteFile(secretsFile, []byte(`{"token": "s3cr3t-token"}`), 0o600))
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	hook := testutils.SimpleLogrusHook{HookedLevels: []logrus.Level{logrus.InfoLevel}}
+	logger.AddHook(&hook)
+
+	r, err := getSimpleRunner(t, "/script.js", `
+		var secrets = require("k6/secrets");
+		exports.default = function() {
+			console.log("the token is " + secrets.get("token"));
+		}`, logger)
+	require.NoError(t, err)
+	require.NoError(t, r.SetOptions(r.GetOptions().Apply(lib.Options{SecretSources: []string{"file=" + secretsFile}})))
+
+	initVU, err := r.NewVU(1, 1, make(chan stats.SampleContainer, 100))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	vu := initVU.Activate(&lib.VUActivationParams{RunContext: ctx})
+	require.NoError(t, vu.RunOnce())
+
+	entries := hook.Drain()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "the token is ***", entries[0].Message)
+}
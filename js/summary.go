@@ -24,6 +24,7 @@ import (
 	_ "embed" // this is used to embed the contents of summary.js
 	"fmt"
 	"io"
+	"strconv"
 	"time"
 
 	"github.com/dop251/goja"
@@ -66,6 +67,16 @@ func metricValueGetter(summaryTrendStats []string) func(stats.Sink, time.Duratio
 			result = sink.Format(t)
 			result["passes"] = float64(sink.Trues)
 			result["fails"] = float64(sink.Total - sink.Trues)
+		case *stats.ThroughputSink:
+			result = sink.Format(t)
+		case *stats.HistogramSink:
+			result = sink.Format(t)
+			// The bucket counts are exported as cumulative, like in Prometheus
+			var cumulative uint64
+			for i, bound := range sink.Buckets {
+				cumulative += sink.Counts[i]
+				result["le("+strconv.FormatFloat(bound, 'f', -1, 64)+")"] = float64(cumulative)
+			}
 		case *stats.TrendSink:
 			result = make(map[string]float64, len(summaryTrendStats))
 			for _, col := range summaryTrendStats {
@@ -88,11 +99,15 @@ func summarizeMetricsToObject(data *lib.Summary, options lib.Options) map[string
 		"summaryTimeUnit":   options.SummaryTimeUnit.String,
 		"noColor":           data.NoColor, // TODO: move to the (runtime) options
 	}
-	m["state"] = map[string]interface{}{
+	state := map[string]interface{}{
 		"isStdOutTTY":       data.UIState.IsStdOutTTY,
 		"isStdErrTTY":       data.UIState.IsStdErrTTY,
 		"testRunDurationMs": float64(data.TestRunDuration) / float64(time.Millisecond),
 	}
+	if data.TimeSeries != nil {
+		state["timeSeriesIntervalMs"] = float64(data.TimeSeries.Interval()) / float64(time.Millisecond)
+	}
+	m["state"] = state
 
 	getMetricValues := metricValueGetter(options.SummaryTrendStats)
 
@@ -113,13 +128,45 @@ func summarizeMetricsToObject(data *lib.Summary, options lib.Options) map[string
 			}
 			metricData["thresholds"] = thresholds
 		}
+
+		if data.TimeSeries != nil {
+			metricData["timeSeries"] = exportTimeSeries(data.TimeSeries, name, getMetricValues)
+		}
 		metricsData[name] = metricData
 	}
 	m["metrics"] = metricsData
 
+	if data.Instance != nil {
+		m["instance"] = map[string]interface{}{
+			"execution_segment":      data.Instance.ExecutionSegment,
+			"iterations":             data.Instance.Iterations,
+			"interrupted_iterations": data.Instance.InterruptedIterations,
+			"dropped_iterations":     data.Instance.DroppedIterations,
+			"iteration_errors":       data.Instance.IterationErrors,
+			"vu_seconds":             data.Instance.VUSeconds,
+		}
+	}
+
 	return m
 }
 
+// exportTimeSeries returns the aggregated intervals of the metric, with their
+// start times as Unix milliseconds, so they can be directly passed to `new
+// Date()` in JS.
+func exportTimeSeries(
+	ts *stats.TimeSeries, name string, getMetricValues func(stats.Sink, time.Duration) map[string]float64,
+) []map[string]interface{} {
+	points := ts.Get(name)
+	result := make([]map[string]interface{}, len(points))
+	for i, point := range points {
+		result[i] = map[string]interface{}{
+			"time":   float64(point.Time.UnixNano()) / float64(time.Millisecond),
+			"values": getMetricValues(point.Sink, ts.Interval()),
+		}
+	}
+	return result
+}
+
 func exportGroup(group *lib.Group) map[string]interface{} {
 	subGroups := make([]map[string]interface{}, len(group.OrderedGroups))
 	for i, subGroup := range group.OrderedGroups {
@@ -236,6 +236,18 @@ function nonTrendMetricValueForSum(metric, timeUnit) {
         succMark + ' ' + metric.values.passes,
         failMark + ' ' + metric.values.fails,
       ]
+    case 'throughput':
+      return [
+        humanizeValue(metric.values.count, metric, timeUnit),
+        humanizeValue(metric.values.rate, metric, timeUnit) + '/s',
+        'peak=' + humanizeValue(metric.values.peak, metric, timeUnit) + '/s',
+      ]
+    case 'histogram':
+      return [
+        'avg=' + humanizeValue(metric.values.avg, metric, timeUnit),
+        'p(90)=' + humanizeValue(metric.values['p(90)'], metric, timeUnit),
+        'p(95)=' + humanizeValue(metric.values['p(95)'], metric, timeUnit),
+      ]
     default:
       return ['[no data]']
   }
@@ -257,6 +269,9 @@ function summarizeMetrics(options, data, decorate) {
   var numTrendColumns = options.summaryTrendStats.length
   var trendColMaxLens = new Array(numTrendColumns).fill(0)
   forEach(data.metrics, function (name, metric) {
+    if (isErrorClassMetric(name)) {
+      return // they are in the errors table
+    }
     names.push(name)
     // When calculating widths for metrics, account for the indentation on submetrics.
     var displayName = indentForMetric(name) + displayNameForMetric(name)
@@ -371,6 +386,75 @@ function summarizeMetrics(options, data, decorate) {
   return result
 }
 
+var errorClassPrefix = 'http_req_errors{error_class:'
+
+function isErrorClassMetric(name) {
+  return name.indexOf(errorClassPrefix) === 0
+}
+
+// summarizeErrors returns the table of the http_req_errors by their error_class, with the
+// classes under their kinds, like the tcp.reset class under tcp, and their share of all the
+// errors.
+function summarizeErrors(options, data, decorate) {
+  var total = data.metrics.http_req_errors ? data.metrics.http_req_errors.values.count : 0
+  var kinds = {}
+  forEach(data.metrics, function (name, metric) {
+    if (!isErrorClassMetric(name)) {
+      return
+    }
+    var errorClass = name.substring(errorClassPrefix.length, name.length - 1)
+    var dot = errorClass.indexOf('.')
+    var kind = dot >= 0 ? errorClass.substring(0, dot) : errorClass
+    if (!kinds.hasOwnProperty(kind)) {
+      kinds[kind] = { count: 0, causes: {} }
+    }
+    kinds[kind].count += metric.values.count
+    if (dot >= 0) {
+      kinds[kind].causes[errorClass.substring(dot + 1)] = metric.values.count
+    }
+  })
+  if (!total || Object.keys(kinds).length === 0) {
+    return []
+  }
+
+  var rows = []
+  Object.keys(kinds)
+    .sort()
+    .forEach(function (kind) {
+      rows.push({ name: kind, count: kinds[kind].count })
+      Object.keys(kinds[kind].causes)
+        .sort()
+        .forEach(function (cause) {
+          rows.push({ name: '  ' + cause, count: kinds[kind].causes[cause] })
+        })
+    })
+  var nameLenMax = 0
+  var countLenMax = 0
+  rows.forEach(function (row) {
+    nameLenMax = Math.max(nameLenMax, strWidth(row.name))
+    countLenMax = Math.max(countLenMax, strWidth(row.count.toString()))
+  })
+
+  var indent = options.indent + '  '
+  var result = ['', indent + '  ' + decorate('errors by class', palette.bold)]
+  rows.forEach(function (row) {
+    var count = row.count.toString()
+    var share = toFixedNoTrailingZeros((row.count / total) * 100, 2) + '%'
+    result.push(
+      indent +
+        '  ' +
+        row.name +
+        decorate('.'.repeat(nameLenMax - strWidth(row.name) + 3) + ':', palette.faint) +
+        ' ' +
+        decorate(count, palette.cyan) +
+        ' '.repeat(countLenMax - strWidth(count)) +
+        ' ' +
+        decorate(share, palette.cyan, palette.faint)
+    )
+  })
+  return result
+}
+
 function generateTextSummary(data, options) {
   var mergedOpts = Object.assign({}, defaultOptions, data.options, options)
   var lines = []
@@ -396,6 +480,8 @@ function generateTextSummary(data, options) {
 
   Array.prototype.push.apply(lines, summarizeMetrics(mergedOpts, data, decorate))
 
+  Array.prototype.push.apply(lines, summarizeErrors(mergedOpts, data, decorate))
+
   return lines.join('\n')
 }
 
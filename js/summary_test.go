@@ -95,6 +95,41 @@ func TestTextSummary(t *testing.T) {
 	}
 }
 
+func TestTextSummaryErrors(t *testing.T) {
+	t.Parallel()
+	summary := createTestSummary(t)
+	errorsMetric := stats.New("http_req_errors", stats.Counter)
+	for errorClass, count := range map[string]int{"dns.no_such_host": 1, "http.5xx": 4, "http.4xx": 2, "other": 1} {
+		sub := errorsMetric.Derive("http_req_errors{error_class:" + errorClass + "}")
+		for i := 0; i < count; i++ {
+			errorsMetric.Sink.Add(stats.Sample{Value: 1})
+			sub.Sink.Add(stats.Sample{Value: 1})
+		}
+		summary.Metrics[sub.Name] = sub
+	}
+	summary.Metrics[errorsMetric.Name] = errorsMetric
+
+	runner, err := getSimpleRunner(t, "/script.js", `exports.default = function() {};`,
+		lib.RuntimeOptions{CompatibilityMode: null.NewString("base", true)})
+	require.NoError(t, err)
+	result, err := runner.HandleSummary(context.Background(), summary)
+	require.NoError(t, err)
+	summaryOut, err := ioutil.ReadAll(result["stdout"])
+	require.NoError(t, err)
+
+	assert.Contains(t, string(summaryOut), "     http_req_errors...: 8      8/s\n")
+	assert.NotContains(t, string(summaryOut), "{ error_class:")
+	assert.Contains(t, string(summaryOut), `
+     errors by class
+     dns..............: 1 12.5%
+       no_such_host...: 1 12.5%
+     http.............: 6 75%
+       4xx............: 2 25%
+       5xx............: 4 50%
+     other............: 1 12.5%
+`)
+}
+
 func createTestMetrics(t *testing.T) (map[string]*stats.Metric, *lib.Group) {
 	metrics := make(map[string]*stats.Metric)
 	gaugeMetric := stats.New("vus", stats.Gauge)
@@ -490,3 +525,63 @@ func TestExceptionInHandleSummaryFallsBackToTextSummary(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, errMsg, "intentional error")
 }
+
+func TestMetricValueGetterHistogram(t *testing.T) {
+	t.Parallel()
+	m, err := stats.NewHistogram("my_histogram", []float64{10, 100})
+	require.NoError(t, err)
+	for _, v := range []float64{1, 5, 50, 500} {
+		m.Sink.Add(stats.Sample{Metric: m, Value: v})
+	}
+
+	values := metricValueGetter([]string{"avg"})(m.Sink, time.Second)
+	assert.Equal(t, 4.0, values["count"])
+	assert.Equal(t, 2.0, values["le(10)"])
+	assert.Equal(t, 3.0, values["le(100)"])
+	assert.Equal(t, 500.0, values["max"])
+}
+
+func TestSummarizeMetricsToObjectInstance(t *testing.T) {
+	t.Parallel()
+	summary := createTestSummary(t)
+	data := summarizeMetricsToObject(summary, lib.Options{})
+	assert.NotContains(t, data, "instance")
+
+	summary.Instance = &lib.InstanceExecutionReport{ExecutionSegment: "0:1/2", Iterations: 10, VUSeconds: 1.5}
+	data = summarizeMetricsToObject(summary, lib.Options{})
+	require.Contains(t, data, "instance")
+	instance, ok := data["instance"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "0:1/2", instance["execution_segment"])
+	assert.Equal(t, uint64(10), instance["iterations"])
+	assert.Equal(t, 1.5, instance["vu_seconds"])
+}
+
+func TestSummarizeMetricsToObjectTimeSeries(t *testing.T) {
+	t.Parallel()
+	summary := createTestSummary(t)
+	data := summarizeMetricsToObject(summary, lib.Options{})
+	assert.NotContains(t, data["metrics"].(map[string]interface{})["http_reqs"], "timeSeries")
+
+	start := time.Unix(100, 0)
+	summary.TimeSeries = stats.NewTimeSeries(time.Second)
+	httpReqs := summary.Metrics["http_reqs"]
+	for _, offset := range []time.Duration{0, 500 * time.Millisecond, 2 * time.Second} {
+		summary.TimeSeries.Add(httpReqs, stats.Sample{Metric: httpReqs, Time: start.Add(offset), Value: 1})
+	}
+
+	data = summarizeMetricsToObject(summary, lib.Options{SummaryTrendStats: []string{"avg"}})
+	state, ok := data["state"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, 1000.0, state["timeSeriesIntervalMs"])
+
+	metric, ok := data["metrics"].(map[string]interface{})["http_reqs"].(map[string]interface{})
+	require.True(t, ok)
+	points, ok := metric["timeSeries"].([]map[string]interface{})
+	require.True(t, ok)
+	require.Len(t, points, 2)
+	assert.Equal(t, 100000.0, points[0]["time"])
+	assert.Equal(t, map[string]float64{"count": 2, "rate": 2}, points[0]["values"])
+	assert.Equal(t, 102000.0, points[1]["time"])
+	assert.Equal(t, map[string]float64{"count": 1, "rate": 1}, points[1]["values"])
+}
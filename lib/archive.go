@@ -93,6 +93,9 @@ type Archive struct {
 
 	K6Version string `json:"k6version"`
 	Goos      string `json:"goos"`
+
+	// The encryption of the files, nil if they aren't encrypted.
+	Encryption *ArchiveEncryption `json:"encryption,omitempty"`
 }
 
 func (arc *Archive) getFs(name string) afero.Fs {
@@ -271,14 +274,27 @@ func (arc *Archive) Write(out io.Writer) error {
 		return err
 	}
 
+	// the files of the archives that were read without decrypting them are already encrypted
+	encrypt := func(data, _ []byte) ([]byte, error) { return data, nil }
+	if arc.Encryption != nil && arc.Encryption.key != nil {
+		encrypt = arc.Encryption.encrypt
+	}
+	dataID, err := archiveDataID(metaArc.FilenameURL)
+	if err != nil {
+		return err
+	}
+	data, err := encrypt(arc.Data, dataID)
+	if err != nil {
+		return err
+	}
 	_ = w.WriteHeader(&tar.Header{
 		Name:     "data",
 		Mode:     0644,
-		Size:     int64(len(arc.Data)),
+		Size:     int64(len(data)),
 		ModTime:  now,
 		Typeflag: tar.TypeReg,
 	})
-	if _, err = w.Write(arc.Data); err != nil {
+	if _, err = w.Write(data); err != nil {
 		return err
 	}
 	for _, name := range [...]string{"file", "https"} {
@@ -315,7 +331,11 @@ func (arc *Archive) Write(out io.Writer) error {
 			}
 
 			paths = append(paths, normalizedPath)
-			files[normalizedPath], err = afero.ReadFile(filesystem, filePath)
+			data, err := afero.ReadFile(filesystem, filePath)
+			if err != nil {
+				return err
+			}
+			files[normalizedPath], err = encrypt(data, archiveFileID(name, normalizedPath))
 			return err
 		})
 
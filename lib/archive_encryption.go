@@ -0,0 +1,285 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+	"golang.org/x/crypto/pbkdf2"
+
+	"go.k6.io/k6/lib/fsext"
+	"go.k6.io/k6/lib/secrets"
+)
+
+const (
+	archiveCipher        = "aes-256-gcm"
+	archiveKDF           = "pbkdf2-sha256"
+	archiveKDFIterations = 200000
+	// archives with more iterations are rejected, so they can't make k6 hang
+	archiveKDFMaxIterations = 10 * archiveKDFIterations
+	archiveKeySize          = 32
+)
+
+// ErrArchiveDecryption is returned when the files of an encrypted archive can't
+// be decrypted with the given passphrase.
+var ErrArchiveDecryption = errors.New("couldn't decrypt the archive, the passphrase is wrong or the archive is corrupted")
+
+// ArchiveEncryption describes how the files of an archive are encrypted. The
+// files, including the main script, are encrypted with a key derived from a
+// passphrase, and the metadata.json is left in plaintext, so the archives can
+// still be inspected.
+type ArchiveEncryption struct {
+	Cipher     string `json:"cipher"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+
+	key []byte
+}
+
+func (e *ArchiveEncryption) deriveKey(passphrase string) error {
+	if e.Cipher != archiveCipher || e.KDF != archiveKDF {
+		return fmt.Errorf("unsupported archive encryption %s with %s", e.Cipher, e.KDF)
+	}
+	if e.Iterations <= 0 || e.Iterations > archiveKDFMaxIterations || len(e.Salt) == 0 {
+		return errors.New("invalid archive encryption parameters")
+	}
+	e.key = pbkdf2.Key([]byte(passphrase), e.Salt, e.Iterations, archiveKeySize, sha256.New)
+	return nil
+}
+
+func (e *ArchiveEncryption) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// archiveFileID returns the additional data of the encryption of the file at the path of the
+// filesystem, so that the encrypted files can't be swapped between paths undetected.
+func archiveFileID(fsName, filePath string) []byte {
+	return []byte(fsName + ":" + NormalizeAndAnonymizePath(filePath))
+}
+
+// archiveDataID returns the additional data of the encryption of the data of the archive, the
+// one of the main script.
+func archiveDataID(filenameURL *url.URL) ([]byte, error) {
+	scheme, pathOnFs := getURLPathOnFs(filenameURL)
+	pathOnFs, err := url.PathUnescape(pathOnFs)
+	if err != nil {
+		return nil, err
+	}
+	return archiveFileID(scheme, pathOnFs), nil
+}
+
+// encrypt returns the nonce followed by the sealed data, authenticated with the
+// additional data.
+func (e *ArchiveEncryption) encrypt(data, additionalData []byte) ([]byte, error) {
+	aead, err := e.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, data, additionalData), nil
+}
+
+func (e *ArchiveEncryption) decrypt(data, additionalData []byte) ([]byte, error) {
+	aead, err := e.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, ErrArchiveDecryption
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrArchiveDecryption
+	}
+	return plain, nil
+}
+
+// Encrypt makes Write encrypt the files of the archive with a key derived from
+// the passphrase.
+func (arc *Archive) Encrypt(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("the passphrase of the archive encryption can't be empty")
+	}
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+	e := &ArchiveEncryption{Cipher: archiveCipher, KDF: archiveKDF, Iterations: archiveKDFIterations, Salt: salt}
+	if err := e.deriveKey(passphrase); err != nil {
+		return err
+	}
+	arc.Encryption = e
+	return nil
+}
+
+// Decrypt decrypts the files of an archive read by ReadArchive, it does
+// nothing if the archive isn't encrypted.
+func (arc *Archive) Decrypt(passphrase string) error {
+	e := arc.Encryption
+	if e == nil {
+		return nil
+	}
+	if err := e.deriveKey(passphrase); err != nil {
+		return err
+	}
+	dataID, err := archiveDataID(arc.FilenameURL)
+	if err != nil {
+		return err
+	}
+	data, err := e.decrypt(arc.Data, dataID)
+	if err != nil {
+		return err
+	}
+
+	for fsName, filesystem := range arc.Filesystems {
+		err := fsext.Walk(filesystem, afero.FilePathSeparator, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			encrypted, err := afero.ReadFile(filesystem, filePath)
+			if err != nil {
+				return err
+			}
+			plain, err := e.decrypt(encrypted, archiveFileID(fsName, filePath))
+			if err != nil {
+				return fmt.Errorf("%w: %s", err, filePath)
+			}
+			if err := afero.WriteFile(filesystem, filePath, plain, info.Mode()); err != nil {
+				return err
+			}
+			return filesystem.Chtimes(filePath, info.ModTime(), info.ModTime())
+		})
+		if err != nil {
+			return err
+		}
+	}
+	arc.Data = data
+	arc.Encryption = nil
+	return nil
+}
+
+// RedactEnv removes the environment variables with names that match any of the
+// patterns, in the syntax of path.Match, and replaces their values in the
+// strings of the options with secrets.Redacted, so the tokens that the options
+// got from them aren't in the archive. The values in the systemEnv are replaced
+// too, for the options that were set from system environment variables that
+// aren't in the archive. Like in the logs, the values shorter than
+// secrets.MinRedactLength aren't replaced, since they would mangle unrelated
+// options. It returns the names of the removed variables.
+func (arc *Archive) RedactEnv(patterns []string, systemEnv map[string]string) ([]string, error) {
+	var (
+		names  []string
+		values []string
+	)
+	matches := func(name string) (bool, error) {
+		for _, pattern := range patterns {
+			if ok, err := path.Match(pattern, name); ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+	for name, value := range arc.Env {
+		ok, err := matches(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of the environment variables to redact: %w", err)
+		}
+		if ok {
+			names = append(names, name)
+			delete(arc.Env, name)
+			if len(value) >= secrets.MinRedactLength {
+				values = append(values, value)
+			}
+		}
+	}
+	for name, value := range systemEnv {
+		if ok, _ := matches(name); ok && len(value) >= secrets.MinRedactLength {
+			values = append(values, value)
+		}
+	}
+	sort.Strings(names)
+	// the longest values first, so the values that contain others are replaced entirely
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	data, err := json.Marshal(arc.Options)
+	if err != nil {
+		return nil, err
+	}
+	var options interface{}
+	if err := json.Unmarshal(data, &options); err != nil {
+		return nil, err
+	}
+	if data, err = json.Marshal(redactJSONStrings(options, values)); err != nil {
+		return nil, err
+	}
+	var redacted Options
+	if err := json.Unmarshal(data, &redacted); err != nil {
+		return nil, err
+	}
+	arc.Options = redacted
+	return names, nil
+}
+
+// redactJSONStrings replaces the values in the strings of the decoded JSON. The
+// keys are kept, since they are names, like the ones of the scenarios and of the
+// thresholds, and renaming them could merge two of them.
+func redactJSONStrings(v interface{}, values []string) interface{} {
+	switch v := v.(type) {
+	case string:
+		for _, value := range values {
+			v = strings.ReplaceAll(v, value, secrets.Redacted)
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = redactJSONStrings(v[i], values)
+		}
+		return v
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = redactJSONStrings(value, values)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/consts"
+	"go.k6.io/k6/stats"
+)
+
+func TestArchiveEncryptionParameters(t *testing.T) {
+	t.Parallel()
+	for _, iterations := range []int{0, -1, archiveKDFMaxIterations + 1} {
+		e := &ArchiveEncryption{Cipher: archiveCipher, KDF: archiveKDF, Iterations: iterations, Salt: []byte("salt")}
+		assert.EqualError(t, e.deriveKey("passphrase"), "invalid archive encryption parameters")
+	}
+}
+
+func TestArchiveEncryption(t *testing.T) {
+	t.Parallel()
+	newArchive := func() *Archive {
+		return &Archive{
+			Type:        "js",
+			K6Version:   consts.Version,
+			Options:     Options{VUs: null.IntFrom(10), SystemTags: &stats.DefaultSystemTagSet},
+			FilenameURL: &url.URL{Scheme: "file", Path: "/path/to/a.js"},
+			Data:        []byte(`// a contents`),
+			PwdURL:      &url.URL{Scheme: "file", Path: "/path/to"},
+			Filesystems: map[string]afero.Fs{
+				"file": makeMemMapFs(t, map[string][]byte{
+					"/path/to/a.js":      []byte(`// a contents`),
+					"/path/to/users.csv": []byte("user,password\nadmin,hunter22\n"),
+				}),
+				"https": makeMemMapFs(t, map[string][]byte{
+					"/cdnjs.com/libraries/Faker": []byte(`// faker contents`),
+				}),
+			},
+		}
+	}
+
+	arc := newArchive()
+	require.NoError(t, arc.Encrypt("passphrase"))
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, arc.Write(buf))
+	written := buf.Bytes()
+
+	r := tar.NewReader(bytes.NewReader(written))
+	for hdr, err := r.Next(); err == nil; hdr, err = r.Next() {
+		data, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		if hdr.Name != "metadata.json" {
+			assert.NotContains(t, string(data), "contents", hdr.Name)
+			assert.NotContains(t, string(data), "hunter22", hdr.Name)
+		}
+	}
+
+	read, err := ReadArchive(bytes.NewReader(written))
+	require.NoError(t, err)
+	require.NotNil(t, read.Encryption)
+	assert.Equal(t, "aes-256-gcm", read.Encryption.Cipher)
+	assert.True(t, errors.Is(read.Decrypt("wrong"), ErrArchiveDecryption))
+
+	// the encrypted files can't be moved to other paths
+	read, err = ReadArchive(bytes.NewReader(written))
+	require.NoError(t, err)
+	moved, err := afero.ReadFile(read.Filesystems["file"], "/path/to/users.csv")
+	require.NoError(t, err)
+	require.NoError(t, afero.WriteFile(read.Filesystems["file"], "/path/to/other.csv", moved, 0644))
+	assert.True(t, errors.Is(read.Decrypt("passphrase"), ErrArchiveDecryption))
+
+	read, err = ReadArchive(bytes.NewReader(written))
+	require.NoError(t, err)
+	// the archives that weren't decrypted are written with the same encryption
+	buf = bytes.NewBuffer(nil)
+	require.NoError(t, read.Write(buf))
+	read, err = ReadArchive(buf)
+	require.NoError(t, err)
+
+	require.NoError(t, read.Decrypt("passphrase"))
+	assert.Nil(t, read.Encryption)
+	assert.Equal(t, []byte(`// a contents`), read.Data)
+	expected := newArchive()
+	diffMapFilesystems(t, expected.Filesystems, read.Filesystems)
+}
+
+func TestArchiveRedactEnv(t *testing.T) {
+	t.Parallel()
+	arc := &Archive{
+		Env: map[string]string{"API_TOKEN": "s3cr3t", "DB_TOKEN": "d8", "HOST": "example.com"},
+		Options: Options{
+			VUs: null.IntFrom(10),
+			External: map[string]json.RawMessage{
+				"loadimpact": json.RawMessage(`{"token": "s3cr3t", "name": "test with d8"}`),
+				"s3cr3t":     json.RawMessage(`{"name": "s3cr3t"}`),
+			},
+			UserAgent: null.StringFrom("k6 at example.com"),
+		},
+	}
+	names, err := arc.RedactEnv([]string{"*_TOKEN", "CLOUD_*"}, map[string]string{"CLOUD_PASSWORD": "example"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"API_TOKEN", "DB_TOKEN"}, names)
+	assert.Equal(t, map[string]string{"HOST": "example.com"}, arc.Env)
+	assert.Equal(t, null.IntFrom(10), arc.Options.VUs)
+	assert.JSONEq(t, `{"token": "***", "name": "test with d8"}`, string(arc.Options.External["loadimpact"]))
+	assert.JSONEq(t, `{"name": "***"}`, string(arc.Options.External["s3cr3t"]))
+	assert.Equal(t, null.StringFrom("k6 at ***.com"), arc.Options.UserAgent)
+
+	_, err = arc.RedactEnv([]string{"["}, nil)
+	assert.Error(t, err)
+}
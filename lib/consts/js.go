@@ -27,4 +27,9 @@ const (
 	SetupFn         = "setup"
 	TeardownFn      = "teardown"
 	HandleSummaryFn = "handleSummary"
+
+	// VU lifecycle functions
+	VUStartFn   = "onVUStart"
+	VUStopFn    = "onVUStop"
+	TestAbortFn = "onTestAbort"
 )
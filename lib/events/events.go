@@ -0,0 +1,114 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package events is the bus of the events of a test run, like its start, its thresholds that
+// fail and its end, which the notifiers like the webhooks send to other services.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"go.k6.io/k6/lib/types"
+)
+
+// Type is the type of an event.
+type Type string
+
+// The types of the events of a test run.
+const (
+	// TestStart is published when the test run starts, after the VUs are initialized.
+	TestStart Type = "test.start"
+	// ThresholdCrossed is published when the thresholds of a metric start or stop failing.
+	ThresholdCrossed Type = "threshold.crossed"
+	// ScenarioEnd is published when a scenario finishes, with its error if it has one.
+	ScenarioEnd Type = "scenario.end"
+	// TestAbort is published when the test run is aborted, with the reason.
+	TestAbort Type = "test.abort"
+	// TestEnd is published when the test run ends, with its summary.
+	TestEnd Type = "test.end"
+)
+
+// AllTypes are the types of all the events.
+var AllTypes = []Type{TestStart, ThresholdCrossed, ScenarioEnd, TestAbort, TestEnd} //nolint:gochecknoglobals
+
+// Event is an event of a test run, the data depends on its type.
+type Event struct {
+	Type  Type        `json:"type"`
+	Time  time.Time   `json:"time"`
+	RunID string      `json:"runId"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// TestEndData is the data of the TestEnd events, the end-of-test summary of the metrics.
+type TestEndData struct {
+	Duration         types.Duration           `json:"duration"`
+	ThresholdsFailed bool                     `json:"thresholdsFailed"`
+	Metrics          map[string]MetricSummary `json:"metrics"`
+}
+
+// MetricSummary is a metric in the end-of-test summary, with the values of its sink and whether
+// each of its thresholds passed, by their sources. The values of the rates also have their
+// passes and fails.
+type MetricSummary struct {
+	Type       string             `json:"type"`
+	Contains   string             `json:"contains"`
+	Values     map[string]float64 `json:"values"`
+	Thresholds map[string]bool    `json:"thresholds,omitempty"`
+}
+
+// Handler handles the published events, it's called synchronously by the publisher, so it
+// mustn't block, the notifiers queue the events instead.
+type Handler func(Event)
+
+// Bus delivers the published events to its subscribers. A nil Bus is valid and drops the
+// events, so the publishers don't have to check whether there are subscribers.
+type Bus struct {
+	runID string
+
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewBus returns a bus of the events of the test run with the ID.
+func NewBus(runID string) *Bus {
+	return &Bus{runID: runID}
+}
+
+// Subscribe adds the handler of the events published after it.
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish delivers the event with the type and the data to all the subscribers.
+func (b *Bus) Publish(t Type, data interface{}) {
+	if b == nil {
+		return
+	}
+	e := Event{Type: t, Time: time.Now(), RunID: b.runID, Data: data}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, h := range b.handlers {
+		h(e)
+	}
+}
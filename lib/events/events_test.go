@@ -0,0 +1,127 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/lib/types"
+)
+
+func TestBus(t *testing.T) {
+	t.Parallel()
+	var nilBus *Bus
+	nilBus.Publish(TestStart, nil)
+
+	bus := NewBus("run-1")
+	var first, second []Event
+	bus.Subscribe(func(e Event) { first = append(first, e) })
+	bus.Publish(TestStart, map[string]interface{}{"vus": 1})
+	bus.Subscribe(func(e Event) { second = append(second, e) })
+	bus.Publish(TestEnd, nil)
+
+	require.Len(t, first, 2)
+	require.Len(t, second, 1)
+	assert.Equal(t, TestStart, first[0].Type)
+	assert.Equal(t, "run-1", first[0].RunID)
+	assert.Equal(t, map[string]interface{}{"vus": 1}, first[0].Data)
+	assert.Equal(t, TestEnd, second[0].Type)
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+	var (
+		mu       sync.Mutex
+		received []Event
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var e Event
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		mu.Lock()
+		received = append(received, e)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	_, err := NewWebhook(srv.URL, []Type{"test.unknown"}, testutils.NewLogger(t))
+	require.Error(t, err)
+
+	w, err := NewWebhook(srv.URL, []Type{TestStart, TestEnd}, testutils.NewLogger(t))
+	require.NoError(t, err)
+	bus := NewBus("run-1")
+	bus.Subscribe(w.Handle)
+	bus.Publish(TestStart, nil)
+	bus.Publish(ScenarioEnd, map[string]string{"scenario": "default"})
+	bus.Publish(TestEnd, map[string]bool{"thresholdsFailed": true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, w.Close(ctx))
+	// the events after the close are dropped
+	bus.Publish(TestEnd, nil)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 2)
+	assert.Equal(t, TestStart, received[0].Type)
+	assert.Equal(t, "run-1", received[0].RunID)
+	assert.Equal(t, TestEnd, received[1].Type)
+	assert.Equal(t, map[string]interface{}{"thresholdsFailed": true}, received[1].Data)
+}
+
+func TestTestEndDataJSON(t *testing.T) {
+	t.Parallel()
+	data, err := json.Marshal(TestEndData{
+		Duration:         types.Duration(90 * time.Second),
+		ThresholdsFailed: true,
+		Metrics: map[string]MetricSummary{
+			"checks": {Type: "rate", Contains: "default", Values: map[string]float64{"rate": 0.5, "passes": 1, "fails": 1}},
+			"http_req_duration": {
+				Type: "trend", Contains: "time", Values: map[string]float64{"p(95)": 250},
+				Thresholds: map[string]bool{"p(95)<200": false},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"duration": "1m30s",
+		"thresholdsFailed": true,
+		"metrics": {
+			"checks": {"type": "rate", "contains": "default", "values": {"rate": 0.5, "passes": 1, "fails": 1}},
+			"http_req_duration": {
+				"type": "trend", "contains": "time", "values": {"p(95)": 250},
+				"thresholds": {"p(95)<200": false}
+			}
+		}
+	}`, string(data))
+}
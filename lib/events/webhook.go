@@ -0,0 +1,166 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/lib/consts"
+)
+
+const (
+	// webhookQueueSize is the amount of events that are queued for every webhook, the others
+	// are dropped while it's slow.
+	webhookQueueSize = 100
+	// webhookTimeout is the timeout of the requests of the webhooks.
+	webhookTimeout = 10 * time.Second
+)
+
+// Webhook POSTs the events to a URL, as JSON objects, one per request. The requests are sent
+// in the background and in order, so the publishers aren't blocked.
+type Webhook struct {
+	url    string
+	types  map[Type]bool
+	client *http.Client
+	logger logrus.FieldLogger
+
+	queue chan Event
+	done  chan struct{}
+
+	mu      sync.Mutex
+	closed  bool
+	dropped int
+}
+
+// NewWebhook returns a webhook that POSTs the events of the types to the URL, or all events if
+// types is empty. It has to be closed so the queued events are sent.
+func NewWebhook(url string, types []Type, logger logrus.FieldLogger) (*Webhook, error) {
+	w := &Webhook{
+		url:    url,
+		types:  make(map[Type]bool, len(types)),
+		client: &http.Client{Timeout: webhookTimeout},
+		logger: logger.WithField("webhook", url),
+		queue:  make(chan Event, webhookQueueSize),
+		done:   make(chan struct{}),
+	}
+	for _, t := range types {
+		if !isValidType(t) {
+			return nil, fmt.Errorf("unknown event type '%s'", t)
+		}
+		w.types[t] = true
+	}
+	go w.loop()
+	return w, nil
+}
+
+func isValidType(t Type) bool {
+	for _, valid := range AllTypes {
+		if t == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle queues the event if it's one of the types of the webhook, it's the Handler of the bus.
+func (w *Webhook) Handle(e Event) {
+	if len(w.types) > 0 && !w.types[e.Type] {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	select {
+	case w.queue <- e:
+	default:
+		w.dropped++
+	}
+}
+
+func (w *Webhook) loop() {
+	defer close(w.done)
+	for e := range w.queue {
+		if err := w.send(e); err != nil {
+			w.logger.WithError(err).Warnf("Couldn't send the %s event to the webhook", e.Type)
+		}
+	}
+}
+
+func (w *Webhook) send(e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "k6/"+consts.Version)
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("the webhook responded with the status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Close sends the queued events and stops the webhook, it waits for them until the context is
+// done.
+func (w *Webhook) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	dropped := w.dropped
+	w.mu.Unlock()
+
+	if dropped > 0 {
+		w.logger.Warnf("Dropped %d events because the webhook was too slow", dropped)
+	}
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("the webhook %s didn't send the queued events: %w", w.url, ctx.Err())
+	}
+}
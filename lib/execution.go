@@ -212,6 +212,12 @@ type ExecutionState struct {
 	// simplification of the used atomic arithmetic operations.
 	activeVUs *int64
 
+	// The accumulated time all VUs have been active, up until the last change
+	// of the active VUs count. Both are only modified behind activeVUsTimeLock.
+	activeVUsTimeLock       sync.Mutex
+	activeVUsTime           time.Duration
+	activeVUsTimeLastChange time.Time
+
 	// The total number of full (i.e uninterrupted) iterations that have been
 	// completed so far.
 	fullIterationsCount *uint64
@@ -223,6 +229,10 @@ type ExecutionState struct {
 	// API, etc.
 	interruptedIterationsCount *uint64
 
+	// The total number of iterations that ended with an error, both full and
+	// interrupted ones.
+	iterationErrorsCount *uint64
+
 	// A machine-readable indicator in which the current state of the test
 	// execution is currently stored. Useful for the REST API and external
 	// observability of the k6 test run progress.
@@ -267,8 +277,38 @@ type ExecutionState struct {
 	pauseStateLock      sync.RWMutex
 	totalPausedDuration time.Duration // only modified behind the lock
 	resumeNotify        chan struct{}
+
+	// Why the test run was aborted before its normal end, if it was. Only the
+	// first reason is kept, since an abort usually causes others, e.g. the
+	// context cancellations.
+	abortReasonLock sync.RWMutex
+	abortReason     AbortReason
+	// The error of the script that aborted the test, and the function that stops the test run
+	// for it, both behind the abortReasonLock.
+	abortErr   error
+	abortRunFn func()
+
+	// The function that returns the current aggregated values of the metrics, set by the Engine,
+	// which aggregates them. It's set before the test run starts, so it isn't behind a lock.
+	metricValueFn MetricValueFunc
 }
 
+// MetricValueFunc returns the current aggregated value of a metric, e.g. the "p(95)" of
+// http_req_duration, and false if the metric doesn't have any values yet.
+type MetricValueFunc func(metric, aggregation string) (float64, bool, error)
+
+// AbortReason describes why a test run was aborted before its normal end.
+type AbortReason string
+
+// The possible abort reasons, they are also passed to the onTestAbort()
+// function of scripts.
+const (
+	AbortReasonThreshold AbortReason = "threshold" // a threshold with abortOnFail has failed
+	AbortReasonSignal    AbortReason = "signal"    // k6 has received SIGINT or SIGTERM
+	AbortReasonUser      AbortReason = "user"      // the test was stopped via the REST API
+	AbortReasonScript    AbortReason = "script"    // the script aborted the test with abortTest()
+)
+
 // NewExecutionState initializes all of the pointers in the ExecutionState
 // with zeros. It also makes sure that the initial state is unpaused, by
 // setting resumeNotify to an already closed channel.
@@ -291,6 +331,7 @@ func NewExecutionState(options Options, et *ExecutionTuple, maxPlannedVUs, maxPo
 		activeVUs:                  new(int64),
 		fullIterationsCount:        new(uint64),
 		interruptedIterationsCount: new(uint64),
+		iterationErrorsCount:       new(uint64),
 		startTime:                  new(int64),
 		endTime:                    new(int64),
 		currentPauseTime:           new(int64),
@@ -342,9 +383,34 @@ func (es *ExecutionState) GetCurrentlyActiveVUsCount() int64 {
 //
 // IMPORTANT: for UI/information purposes only, don't use for synchronization.
 func (es *ExecutionState) ModCurrentlyActiveVUsCount(mod int64) int64 {
+	es.activeVUsTimeLock.Lock()
+	defer es.activeVUsTimeLock.Unlock()
+
+	now := time.Now()
+	es.activeVUsTime = es.getActiveVUsTime(now)
+	es.activeVUsTimeLastChange = now
 	return atomic.AddInt64(es.activeVUs, mod)
 }
 
+// GetActiveVUsTime returns the total time all VUs have been active so far,
+// i.e. the number of VU-seconds used by the test run.
+//
+// IMPORTANT: for UI/information purposes only, don't use for synchronization.
+func (es *ExecutionState) GetActiveVUsTime() time.Duration {
+	es.activeVUsTimeLock.Lock()
+	defer es.activeVUsTimeLock.Unlock()
+	return es.getActiveVUsTime(time.Now())
+}
+
+// getActiveVUsTime should only be called behind the activeVUsTimeLock.
+func (es *ExecutionState) getActiveVUsTime(now time.Time) time.Duration {
+	if es.activeVUsTimeLastChange.IsZero() {
+		return es.activeVUsTime
+	}
+	active := time.Duration(atomic.LoadInt64(es.activeVUs))
+	return es.activeVUsTime + active*now.Sub(es.activeVUsTimeLastChange)
+}
+
 // GetFullIterationCount returns the total of full (i.e uninterrupted) iterations
 // that have been completed so far.
 //
@@ -377,6 +443,22 @@ func (es *ExecutionState) AddInterruptedIterations(count uint64) uint64 {
 	return atomic.AddUint64(es.interruptedIterationsCount, count)
 }
 
+// GetIterationErrorCount returns the total number of iterations that ended
+// with an error so far.
+//
+// IMPORTANT: for UI/information purposes only, don't use for synchronization.
+func (es *ExecutionState) GetIterationErrorCount() uint64 {
+	return atomic.LoadUint64(es.iterationErrorsCount)
+}
+
+// AddIterationErrors increments the number of iterations that ended with an
+// error by the provided amount.
+//
+// IMPORTANT: for UI/information purposes only, don't use for synchronization.
+func (es *ExecutionState) AddIterationErrors(count uint64) uint64 {
+	return atomic.AddUint64(es.iterationErrorsCount, count)
+}
+
 // SetExecutionStatus changes the current execution status to the supplied value
 // and returns the current value.
 func (es *ExecutionState) SetExecutionStatus(newStatus ExecutionStatus) (oldStatus ExecutionStatus) {
@@ -412,6 +494,70 @@ func (es *ExecutionState) MarkEnded() {
 	es.SetExecutionStatus(ExecutionStatusEnded)
 }
 
+// SetAbortReason saves why the test run is aborted. It should be called before
+// the test run context is cancelled, so that the VUs can see it when they are
+// interrupted. Only the first reason is kept.
+func (es *ExecutionState) SetAbortReason(reason AbortReason) {
+	es.abortReasonLock.Lock()
+	defer es.abortReasonLock.Unlock()
+	if es.abortReason == "" {
+		es.abortReason = reason
+	}
+}
+
+// GetAbortReason returns why the test run was aborted, or an empty string if
+// it wasn't.
+func (es *ExecutionState) GetAbortReason() AbortReason {
+	es.abortReasonLock.RLock()
+	defer es.abortReasonLock.RUnlock()
+	return es.abortReason
+}
+
+// SetAbortRunFunc sets the function that stops the test run when it's aborted with AbortTest().
+func (es *ExecutionState) SetAbortRunFunc(fn func()) {
+	es.abortReasonLock.Lock()
+	defer es.abortReasonLock.Unlock()
+	es.abortRunFn = fn
+}
+
+// AbortTest stops the test run because of the error, like when the script aborts the test, and
+// keeps the error for the execution scheduler to return it. Only the first error is kept.
+func (es *ExecutionState) AbortTest(err error) {
+	es.SetAbortReason(AbortReasonScript)
+	es.abortReasonLock.Lock()
+	if es.abortErr == nil {
+		es.abortErr = err
+	}
+	abortRun := es.abortRunFn
+	es.abortReasonLock.Unlock()
+	if abortRun != nil {
+		abortRun()
+	}
+}
+
+// GetAbortError returns the error the test was aborted with by AbortTest(), or nil.
+func (es *ExecutionState) GetAbortError() error {
+	es.abortReasonLock.RLock()
+	defer es.abortReasonLock.RUnlock()
+	return es.abortErr
+}
+
+// SetMetricValueFunc sets the function that returns the current aggregated values of the
+// metrics for GetMetricValue(). It has to be called before the test run starts.
+func (es *ExecutionState) SetMetricValueFunc(fn MetricValueFunc) {
+	es.metricValueFn = fn
+}
+
+// GetMetricValue returns the current aggregated value of a metric, and false if the metric
+// doesn't have any values yet. The values are only available when the metrics are aggregated
+// locally, by the Engine.
+func (es *ExecutionState) GetMetricValue(metric, aggregation string) (float64, bool, error) {
+	if es.metricValueFn == nil {
+		return 0, false, errors.New("the values of the metrics aren't available in this test run")
+	}
+	return es.metricValueFn(metric, aggregation)
+}
+
 // HasStarted returns true if the test has actually started executing.
 // It will return false while a test is in the init phase, or if it has
 // been initially paused. But if will return true if a test is paused
@@ -614,3 +760,31 @@ func (es *ExecutionState) ReturnVU(vu InitializedVU, wasActive bool) {
 		es.ModCurrentlyActiveVUsCount(-1)
 	}
 }
+
+// StopVUs concurrently stops all of the VUs in the buffer that implement
+// StoppableVU and waits for them, they are put back in the buffer afterwards.
+// It should only be called after all executors have finished, when all VUs
+// have been returned and none of them will be activated again.
+func (es *ExecutionState) StopVUs() {
+	abortReason := es.GetAbortReason()
+	vus := make([]InitializedVU, 0, len(es.vus))
+	for len(es.vus) > 0 {
+		vus = append(vus, <-es.vus)
+	}
+
+	wg := sync.WaitGroup{}
+	for _, vu := range vus {
+		if stoppable, ok := vu.(StoppableVU); ok {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				stoppable.Stop(abortReason)
+			}()
+		}
+	}
+	wg.Wait()
+
+	for _, vu := range vus {
+		es.vus <- vu
+	}
+}
@@ -288,6 +288,24 @@ func (es *ExecutionSegment) Scale(value int64) int64 {
 	return roundUp(toValue).Int64()
 }
 
+// ScaleRange returns the [start, end) range of the items from 0 to value that
+// belong to the execution segment. The ranges of the segments in a sequence
+// don't overlap and cover all items, and end - start is always equal to
+// Scale(value).
+func (es *ExecutionSegment) ScaleRange(value int64) (start, end int64) {
+	if es == nil { // no execution segment, i.e. 100%
+		return 0, value
+	}
+
+	fromValue := big.NewRat(value, 1)
+	fromValue.Mul(fromValue, es.from)
+
+	toValue := big.NewRat(value, 1)
+	toValue.Mul(toValue, es.to)
+
+	return roundUp(fromValue).Int64(), roundUp(toValue).Int64()
+}
+
 // InPlaceScaleRat scales rational numbers in-place - it changes the passed
 // argument (and also returns it, to allow for chaining, like many other big.Rat
 // methods).
@@ -476,6 +476,36 @@ func TestExecutionSegmentScaleConsistency(t *testing.T) {
 	}
 }
 
+func TestExecutionSegmentScaleRange(t *testing.T) {
+	t.Parallel()
+
+	seed := time.Now().UnixNano()
+	r := rand.New(rand.NewSource(seed))
+	t.Logf("Random source seeded with %d\n", seed)
+
+	var nilEs *ExecutionSegment
+	start, end := nilEs.ScaleRange(10)
+	assert.Equal(t, int64(0), start)
+	assert.Equal(t, int64(10), end)
+
+	const numTests = 10
+	for i := 0; i < numTests; i++ {
+		scale := rand.Int31n(99) + 2
+		seq := generateRandomSequence(t, r.Int63n(9)+2, 100, r)
+
+		t.Run(fmt.Sprintf("%d_%s", scale, seq), func(t *testing.T) {
+			var prevEnd int64
+			for _, segment := range seq {
+				start, end := segment.ScaleRange(int64(scale))
+				assert.Equal(t, prevEnd, start)
+				assert.Equal(t, segment.Scale(int64(scale)), end-start)
+				prevEnd = end
+			}
+			assert.Equal(t, int64(scale), prevEnd)
+		})
+	}
+}
+
 // Ensure that the sum of scaling all execution segments in
 // the same sequence with scaling factor M results in M itself.
 func TestExecutionTupleScaleConsistency(t *testing.T) {
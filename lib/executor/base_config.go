@@ -21,6 +21,7 @@
 package executor
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
@@ -28,6 +29,7 @@ import (
 
 	"gopkg.in/guregu/null.v3"
 
+	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/consts"
 	"go.k6.io/k6/lib/types"
 )
@@ -47,10 +49,23 @@ type BaseConfig struct {
 	StartTime    types.NullDuration `json:"startTime"`
 	GracefulStop types.NullDuration `json:"gracefulStop"`
 	Env          map[string]string  `json:"env"`
-	Exec         null.String        `json:"exec"` // function name, externally validated
+	Exec         null.String        `json:"exec"`     // function name, externally validated
+	Setup        null.String        `json:"setup"`    // function name, externally validated
+	Teardown     null.String        `json:"teardown"` // function name, externally validated
 	Tags         map[string]string  `json:"tags"`
 
-	// TODO: future extensions like distribution, others?
+	// Disable keep-alive connections for the HTTP requests of the scenario.
+	NoConnectionReuse null.Bool `json:"noConnectionReuse"`
+	// Keep the cookies of the VUs between the iterations of the scenario, the global
+	// noCookiesReset option is used if it isn't set.
+	NoCookiesReset null.Bool `json:"noCookiesReset"`
+
+	// The shaping of the network traffic of the scenario's VUs, e.g. for simulating mobile users.
+	Network *lib.NetworkShaping `json:"network,omitempty"`
+
+	// The extension options of the scenario, like the ones of options.ext, e.g. the load zones
+	// distribution of its VUs in the cloud with ext.loadimpact.distribution.
+	Ext map[string]json.RawMessage `json:"ext,omitempty"`
 }
 
 // NewBaseConfig returns a default base config with the default values
@@ -75,6 +90,12 @@ func (bc BaseConfig) Validate() (errors []error) {
 	if bc.Exec.Valid && bc.Exec.String == "" {
 		errors = append(errors, fmt.Errorf("exec value cannot be empty"))
 	}
+	if bc.Setup.Valid && bc.Setup.String == "" {
+		errors = append(errors, fmt.Errorf("setup value cannot be empty"))
+	}
+	if bc.Teardown.Valid && bc.Teardown.String == "" {
+		errors = append(errors, fmt.Errorf("teardown value cannot be empty"))
+	}
 	if bc.Type == "" {
 		errors = append(errors, fmt.Errorf("missing or empty type field"))
 	}
@@ -85,6 +106,9 @@ func (bc BaseConfig) Validate() (errors []error) {
 	if bc.GracefulStop.Duration < 0 {
 		errors = append(errors, fmt.Errorf("the gracefulStop timeout can't be negative"))
 	}
+	if bc.Network != nil {
+		errors = append(errors, bc.Network.Validate()...)
+	}
 	return errors
 }
 
@@ -129,11 +153,46 @@ func (bc BaseConfig) GetExec() string {
 	return exec
 }
 
+// GetSetup returns the name of the function that should be executed once
+// before the scenario starts, if any. It's run before the start of the whole
+// test, so it doesn't delay the scenario past its startTime.
+func (bc BaseConfig) GetSetup() string {
+	return bc.Setup.ValueOrZero()
+}
+
+// GetTeardown returns the name of the function that should be executed once
+// after the scenario ends, if any.
+func (bc BaseConfig) GetTeardown() string {
+	return bc.Teardown.ValueOrZero()
+}
+
 // GetTags returns any custom tags configured for the executor.
 func (bc BaseConfig) GetTags() map[string]string {
 	return bc.Tags
 }
 
+// GetNoConnectionReuse returns whether the HTTP requests of the scenario don't use keep-alive
+// connections.
+func (bc BaseConfig) GetNoConnectionReuse() bool {
+	return bc.NoConnectionReuse.Bool
+}
+
+// GetNoCookiesReset returns whether the cookies of the VUs are kept between the iterations of
+// the scenario, it isn't valid if the scenario doesn't set it.
+func (bc BaseConfig) GetNoCookiesReset() null.Bool {
+	return bc.NoCookiesReset
+}
+
+// GetNetwork returns the shaping of the network traffic of the scenario, nil if there isn't any.
+func (bc BaseConfig) GetNetwork() *lib.NetworkShaping {
+	return bc.Network
+}
+
+// GetExt returns the extension options of the scenario.
+func (bc BaseConfig) GetExt() map[string]json.RawMessage {
+	return bc.Ext
+}
+
 // IsDistributable returns true since by default all executors could be run in
 // a distributed manner.
 func (bc BaseConfig) IsDistributable() bool {
@@ -145,6 +204,12 @@ func (bc BaseConfig) getBaseInfo(facts ...string) string {
 	if bc.Exec.Valid {
 		facts = append(facts, fmt.Sprintf("exec: %s", bc.Exec.String))
 	}
+	if bc.Setup.Valid {
+		facts = append(facts, fmt.Sprintf("setup: %s", bc.Setup.String))
+	}
+	if bc.Teardown.Valid {
+		facts = append(facts, fmt.Sprintf("teardown: %s", bc.Teardown.String))
+	}
 	if bc.StartTime.Duration > 0 {
 		facts = append(facts, fmt.Sprintf("startTime: %s", bc.StartTime.Duration))
 	}
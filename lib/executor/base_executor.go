@@ -24,6 +24,8 @@ import (
 	"context"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -43,6 +45,17 @@ type BaseExecutor struct {
 	iterSegIndex   *lib.SegmentedIndex
 	logger         *logrus.Entry
 	progress       *pb.ProgressBar
+
+	activeVUs             *int64
+	iterations            *uint64
+	interruptedIterations *uint64
+	iterationErrors       *uint64
+
+	runStateMx sync.Mutex
+	startTime  time.Time
+	running    bool
+	stopped    bool
+	stopRun    func()
 }
 
 // NewBaseExecutor returns an initialized BaseExecutor
@@ -54,6 +67,12 @@ func NewBaseExecutor(config lib.ExecutorConfig, es *lib.ExecutionState, logger *
 		logger:         logger,
 		iterSegIndexMx: new(sync.Mutex),
 		iterSegIndex:   segIdx,
+
+		activeVUs:             new(int64),
+		iterations:            new(uint64),
+		interruptedIterations: new(uint64),
+		iterationErrors:       new(uint64),
+
 		progress: pb.New(
 			pb.WithLeft(config.GetName),
 			pb.WithLogger(logger),
@@ -90,6 +109,61 @@ func (bs *BaseExecutor) GetProgress() *pb.ProgressBar {
 	return bs.progress
 }
 
+// GetStats returns the live execution statistics of the executor.
+func (bs *BaseExecutor) GetStats() lib.ExecutorStats {
+	bs.runStateMx.Lock()
+	defer bs.runStateMx.Unlock()
+	return lib.ExecutorStats{
+		StartTime:             bs.startTime,
+		Running:               bs.running,
+		Stopped:               bs.stopped,
+		ActiveVUs:             atomic.LoadInt64(bs.activeVUs),
+		Iterations:            atomic.LoadUint64(bs.iterations),
+		InterruptedIterations: atomic.LoadUint64(bs.interruptedIterations),
+		IterationErrors:       atomic.LoadUint64(bs.iterationErrors),
+	}
+}
+
+// Stop interrupts all of the iterations of the executor and makes its Run()
+// return, without affecting any other executors. If the executor hasn't
+// started yet, it will not run any iterations when its time comes. Stopping an
+// already finished executor doesn't do anything.
+func (bs *BaseExecutor) Stop() {
+	bs.runStateMx.Lock()
+	defer bs.runStateMx.Unlock()
+	if !bs.startTime.IsZero() && !bs.running {
+		return
+	}
+	bs.stopped = true
+	if bs.stopRun != nil {
+		bs.logger.Debug("Stopping the executor...")
+		bs.stopRun()
+	}
+}
+
+// startRun should be called at the start of the executors' Run() methods. It
+// returns a context that will be cancelled by Stop() and a function that has
+// to be called when Run() is finished.
+func (bs *BaseExecutor) startRun(parentCtx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parentCtx)
+
+	bs.runStateMx.Lock()
+	defer bs.runStateMx.Unlock()
+	bs.startTime = time.Now()
+	bs.running = true
+	bs.stopRun = cancel
+	if bs.stopped {
+		cancel()
+	}
+
+	return ctx, func() {
+		cancel()
+		bs.runStateMx.Lock()
+		bs.running = false
+		bs.runStateMx.Unlock()
+	}
+}
+
 // getMetricTags returns a tag set that can be used to emit metrics by the
 // executor. The VU ID is optional.
 func (bs *BaseExecutor) getMetricTags(vuID *uint64) *stats.SampleTags {
@@ -76,3 +76,38 @@ func TestConstantVUsRun(t *testing.T) {
 	})
 	assert.Equal(t, uint64(50), totalIters)
 }
+
+func TestConstantVUsStop(t *testing.T) {
+	t.Parallel()
+	et, err := lib.NewExecutionTuple(nil, nil)
+	require.NoError(t, err)
+	es := lib.NewExecutionState(lib.Options{}, et, 10, 50)
+	ctx, cancel, executor, _ := setupExecutor(
+		t, getTestConstantVUsConfig(), es,
+		simpleRunner(func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+			case <-time.After(50 * time.Millisecond):
+			}
+			return nil
+		}),
+	)
+	defer cancel()
+
+	observable, ok := executor.(lib.ObservableExecutor)
+	require.True(t, ok)
+	assert.True(t, observable.GetStats().StartTime.IsZero())
+
+	time.AfterFunc(200*time.Millisecond, executor.(lib.StoppableExecutor).Stop)
+	start := time.Now()
+	require.NoError(t, executor.Run(ctx, nil))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	stats := observable.GetStats()
+	assert.False(t, stats.StartTime.IsZero())
+	assert.False(t, stats.Running)
+	assert.True(t, stats.Stopped)
+	assert.Zero(t, stats.ActiveVUs)
+	assert.Greater(t, stats.Iterations, uint64(0))
+	assert.NotZero(t, stats.InterruptedIterations)
+}
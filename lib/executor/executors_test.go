@@ -450,3 +450,25 @@ func TestConfigMapParsingAndValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestArchiveRedactEnvScenarios(t *testing.T) {
+	t.Parallel()
+	scenarios, err := lib.GetParsedExecutorConfig("s3cr3t", constantVUsType, []byte(
+		`{"executor": "constant-vus", "vus": 1, "duration": "10s", "env": {"TOKEN": "s3cr3t"}}`,
+	))
+	require.NoError(t, err)
+	arc := &lib.Archive{
+		// "vus" would break the executor type, if it was redacted
+		Env:     map[string]string{"API_TOKEN": "s3cr3t", "SHORT_TOKEN": "vus"},
+		Options: lib.Options{Scenarios: lib.ScenarioConfigs{"s3cr3t": scenarios}},
+	}
+	_, err = arc.RedactEnv([]string{"*_TOKEN"}, nil)
+	require.NoError(t, err)
+
+	require.Contains(t, arc.Options.Scenarios, "s3cr3t")
+	config, ok := arc.Options.Scenarios["s3cr3t"].(ConstantVUsConfig)
+	require.True(t, ok)
+	assert.Equal(t, "s3cr3t", config.GetName())
+	assert.Equal(t, null.IntFrom(1), config.VUs)
+	assert.Equal(t, map[string]string{"TOKEN": "***"}, config.GetEnv())
+}
@@ -25,10 +25,9 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sync/atomic"
 	"time"
 
-	"github.com/sirupsen/logrus"
-
 	"go.k6.io/k6/errext"
 	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/types"
@@ -77,15 +76,17 @@ func validateStages(stages []Stage) []error {
 }
 
 // getIterationRunner is a helper function that returns an iteration executor
-// closure. It takes care of updating the execution state statistics and
-// warning messages. And returns whether a full iteration was finished or not
+// closure. It takes care of updating the execution state and executor
+// statistics and warning messages. And returns whether a full iteration was
+// finished or not
 //
 // TODO: emit the end-of-test iteration metrics here (https://github.com/k6io/k6/issues/1250)
-func getIterationRunner(
-	executionState *lib.ExecutionState, logger *logrus.Entry,
-) func(context.Context, lib.ActiveVU) bool {
+func getIterationRunner(bs *BaseExecutor) func(context.Context, lib.ActiveVU) bool {
+	executionState, logger := bs.executionState, bs.logger
 	return func(ctx context.Context, vu lib.ActiveVU) bool {
+		atomic.AddInt64(bs.activeVUs, 1)
 		err := vu.RunOnce()
+		atomic.AddInt64(bs.activeVUs, -1)
 
 		// TODO: track (non-ramp-down) errors from script iterations as a metric,
 		// and have a default threshold that will abort the script when the error
@@ -95,9 +96,12 @@ func getIterationRunner(
 		case <-ctx.Done():
 			// Don't log errors or emit iterations metrics from cancelled iterations
 			executionState.AddInterruptedIterations(1)
+			atomic.AddUint64(bs.interruptedIterations, 1)
 			return false
 		default:
 			if err != nil {
+				executionState.AddIterationErrors(1)
+				atomic.AddUint64(bs.iterationErrors, 1)
 				var exception errext.Exception
 				if errors.As(err, &exception) {
 					// TODO don't count this as a full iteration?
@@ -110,6 +114,7 @@ func getIterationRunner(
 
 			// TODO: move emission of end-of-iteration metrics here?
 			executionState.AddFullIterations(1)
+			atomic.AddUint64(bs.iterations, 1)
 			return true
 		}
 	}
@@ -233,6 +238,9 @@ func getVUActivationParams(
 		Exec:                     conf.GetExec(),
 		Env:                      conf.GetEnv(),
 		Tags:                     conf.GetTags(),
+		NoConnectionReuse:        conf.GetNoConnectionReuse(),
+		NoCookiesReset:           conf.GetNoCookiesReset(),
+		Network:                  conf.GetNetwork(),
 		DeactivateCallback:       deactivateCallback,
 		GetNextIterationCounters: nextIterationCounters,
 	}
@@ -498,6 +498,9 @@ var _ lib.Executor = &RampingVUs{}
 // and see what happens)... :/ so maybe see how it can be split?
 // nolint:funlen,gocognit
 func (vlv RampingVUs) Run(parentCtx context.Context, out chan<- stats.SampleContainer) (err error) {
+	parentCtx, finishRun := vlv.startRun(parentCtx)
+	defer finishRun()
+
 	rawExecutionSteps := vlv.config.getRawExecutionSteps(vlv.executionState.ExecutionTuple, true)
 	regularDuration, isFinal := lib.GetEndOffset(rawExecutionSteps)
 	if !isFinal {
@@ -544,7 +547,7 @@ func (vlv RampingVUs) Run(parentCtx context.Context, out chan<- stats.SampleCont
 
 	// Actually schedule the VUs and iterations, likely the most complicated
 	// executor among all of them...
-	runIteration := getIterationRunner(vlv.executionState, vlv.logger)
+	runIteration := getIterationRunner(vlv.BaseExecutor)
 	getVU := func() (lib.InitializedVU, error) {
 		initVU, err := vlv.executionState.GetPlannedVU(vlv.logger, false)
 		if err != nil {
@@ -185,6 +185,9 @@ func (si *SharedIterations) Init(ctx context.Context) error {
 // the configured VUs.
 // nolint:funlen
 func (si SharedIterations) Run(parentCtx context.Context, out chan<- stats.SampleContainer) (err error) {
+	parentCtx, finishRun := si.startRun(parentCtx)
+	defer finishRun()
+
 	numVUs := si.config.GetVUs(si.executionState.ExecutionTuple)
 	iterations := si.et.ScaleInt64(si.config.Iterations.Int64)
 	duration := time.Duration(si.config.MaxDuration.Duration)
@@ -232,7 +235,7 @@ func (si SharedIterations) Run(parentCtx context.Context, out chan<- stats.Sampl
 	}()
 
 	regDurationDone := regDurationCtx.Done()
-	runIteration := getIterationRunner(si.executionState, si.logger)
+	runIteration := getIterationRunner(si.BaseExecutor)
 
 	maxDurationCtx = lib.WithScenarioState(maxDurationCtx, &lib.ScenarioState{
 		Name:       si.config.Name,
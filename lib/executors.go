@@ -93,7 +93,13 @@ type ExecutorConfig interface {
 	//
 	// TODO: use interface{} so plain http requests can be specified?
 	GetExec() string
+	// The names of the functions that should be executed once before and
+	// after the scenario, if any were specified.
+	GetSetup() string
+	GetTeardown() string
 	GetTags() map[string]string
+	// The extension options of the scenario, like the ones of options.ext.
+	GetExt() map[string]json.RawMessage
 
 	// Calculates the VU requirements in different stages of the executor's
 	// execution, including any extensions caused by waiting for iterations to
@@ -145,6 +151,36 @@ type LiveUpdatableExecutor interface {
 	UpdateConfig(ctx context.Context, newConfig interface{}) error
 }
 
+// StoppableExecutor should be implemented by the executors that can be stopped
+// individually in the middle of the test execution, without stopping the
+// whole test run.
+type StoppableExecutor interface {
+	Stop()
+}
+
+// ExecutorStats contains live execution statistics for a single executor.
+type ExecutorStats struct {
+	// StartTime is zero if the executor hasn't started yet.
+	StartTime time.Time
+	Running   bool
+	Stopped   bool
+
+	// ActiveVUs is the number of VUs currently running an iteration.
+	ActiveVUs             int64
+	Iterations            uint64
+	InterruptedIterations uint64
+	// IterationErrors is the number of the full iterations that ended with
+	// an error, like an uncaught exception.
+	IterationErrors uint64
+}
+
+// ObservableExecutor should be implemented by the executors that keep track of
+// their own execution statistics, in addition to the global ones kept in the
+// ExecutionState.
+type ObservableExecutor interface {
+	GetStats() ExecutorStats
+}
+
 // ExecutorConfigConstructor is a simple function that returns a concrete
 // Config instance with the specified name and all default values correctly
 // initialized
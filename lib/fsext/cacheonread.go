@@ -30,6 +30,7 @@ import (
 // that is used as cache
 type CacheOnReadFs struct {
 	afero.Fs
+	base  afero.Fs
 	cache afero.Fs
 }
 
@@ -37,6 +38,7 @@ type CacheOnReadFs struct {
 func NewCacheOnReadFs(base, layer afero.Fs, cacheTime time.Duration) afero.Fs {
 	return CacheOnReadFs{
 		Fs:    afero.NewCacheOnReadFs(base, layer, cacheTime),
+		base:  base,
 		cache: layer,
 	}
 }
@@ -45,3 +47,8 @@ func NewCacheOnReadFs(base, layer afero.Fs, cacheTime time.Duration) afero.Fs {
 func (c CacheOnReadFs) GetCachingFs() afero.Fs {
 	return c.cache
 }
+
+// GetBaseFs returns the afero.Fs that is cached, files read directly from it aren't cached
+func (c CacheOnReadFs) GetBaseFs() afero.Fs {
+	return c.base
+}
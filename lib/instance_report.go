@@ -0,0 +1,60 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/stats"
+)
+
+// InstanceExecutionReport contains the execution statistics of a single k6
+// instance, which is useful for diagnosing the separate instances of a test
+// run that was split with execution segments.
+type InstanceExecutionReport struct {
+	ExecutionSegment      string  `json:"execution_segment"`
+	Iterations            uint64  `json:"iterations"`
+	InterruptedIterations uint64  `json:"interrupted_iterations"`
+	DroppedIterations     uint64  `json:"dropped_iterations"`
+	IterationErrors       uint64  `json:"iteration_errors"`
+	VUSeconds             float64 `json:"vu_seconds"`
+}
+
+// NewInstanceExecutionReport creates a new report from the current state of
+// the execution and the dropped_iterations metric, if it was emitted.
+func NewInstanceExecutionReport(
+	es *ExecutionState, engineMetrics map[string]*stats.Metric,
+) *InstanceExecutionReport {
+	report := &InstanceExecutionReport{
+		Iterations:            es.GetFullIterationCount(),
+		InterruptedIterations: es.GetPartialIterationCount(),
+		IterationErrors:       es.GetIterationErrorCount(),
+		VUSeconds:             es.GetActiveVUsTime().Seconds(),
+	}
+	if es.ExecutionTuple != nil {
+		report.ExecutionSegment = es.ExecutionTuple.Segment.String()
+	}
+	if m, ok := engineMetrics[metrics.DroppedIterations.Name]; ok {
+		if sink, ok := m.Sink.(*stats.CounterSink); ok {
+			report.DroppedIterations = uint64(sink.Value)
+		}
+	}
+	return report
+}
@@ -0,0 +1,63 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/stats"
+)
+
+func TestNewInstanceExecutionReport(t *testing.T) {
+	t.Parallel()
+	segment, err := NewExecutionSegmentFromString("1/2:1")
+	require.NoError(t, err)
+	et, err := NewExecutionTuple(segment, nil)
+	require.NoError(t, err)
+	es := NewExecutionState(Options{}, et, 10, 10)
+
+	es.AddFullIterations(5)
+	es.AddInterruptedIterations(2)
+	es.AddIterationErrors(1)
+	es.ModCurrentlyActiveVUsCount(+2)
+	time.Sleep(50 * time.Millisecond)
+	es.ModCurrentlyActiveVUsCount(-2)
+
+	dropped := metrics.DroppedIterations.Derive(metrics.DroppedIterations.Name)
+	dropped.Sink.Add(stats.Sample{Value: 3})
+	report := NewInstanceExecutionReport(es, map[string]*stats.Metric{dropped.Name: dropped})
+
+	assert.Equal(t, "1/2:1", report.ExecutionSegment)
+	assert.Equal(t, uint64(5), report.Iterations)
+	assert.Equal(t, uint64(2), report.InterruptedIterations)
+	assert.Equal(t, uint64(1), report.IterationErrors)
+	assert.Equal(t, uint64(3), report.DroppedIterations)
+	assert.GreaterOrEqual(t, report.VUSeconds, 0.1)
+	assert.Less(t, report.VUSeconds, 1.0)
+
+	// The VU time doesn't grow while there are no active VUs
+	assert.Equal(t, report.VUSeconds, es.GetActiveVUsTime().Seconds())
+}
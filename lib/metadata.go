@@ -0,0 +1,161 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	uuid "github.com/nu7hatch/gouuid"
+
+	"go.k6.io/k6/lib/consts"
+	"go.k6.io/k6/stats"
+)
+
+// TestRunMetadata identifies a single test run, so that its results can be
+// told apart from the results of other test runs in shared backends and the
+// test run can be reproduced later.
+type TestRunMetadata struct {
+	RunID            string            `json:"run_id"`
+	ScriptName       string            `json:"script_name"`
+	ScriptHash       string            `json:"script_hash"`
+	K6Version        string            `json:"k6_version"`
+	OptionsDigest    string            `json:"options_digest"`
+	StartTime        *time.Time        `json:"start_time,omitempty"`
+	ExecutionSegment string            `json:"execution_segment,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+}
+
+// NewTestRunMetadata generates a new unique run ID and returns the metadata
+// for a test run of the given script with the given consolidated options.
+// The run tags of the options are used as the instance labels. The start time
+// is only set by MarkStarted(), when the test run actually starts.
+func NewTestRunMetadata(scriptName string, scriptData []byte, opts Options) (*TestRunMetadata, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate the test run ID: %w", err)
+	}
+
+	optsJSON, err := json.Marshal(opts)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't serialize the options: %w", err)
+	}
+
+	scriptHash := sha256.Sum256(scriptData)
+	optsHash := sha256.Sum256(optsJSON)
+	md := &TestRunMetadata{
+		RunID:         id.String(),
+		ScriptName:    scriptName,
+		ScriptHash:    hex.EncodeToString(scriptHash[:]),
+		K6Version:     consts.Version,
+		OptionsDigest: hex.EncodeToString(optsHash[:]),
+	}
+	if opts.ExecutionSegment != nil {
+		md.ExecutionSegment = opts.ExecutionSegment.String()
+	}
+	if !opts.RunTags.IsEmpty() {
+		md.Labels = opts.RunTags.CloneTags()
+	}
+
+	return md, nil
+}
+
+// WithRunIDTag returns the options with the run ID in the run_id tag of their run tags, if the
+// run_id system tag is enabled, so that the samples get it when they are created, like the other
+// run tags. A run_id tag of the run tags takes precedence.
+func (md *TestRunMetadata) WithRunIDTag(opts Options) Options {
+	if !opts.SystemTags.Has(stats.TagRunID) {
+		return opts
+	}
+	tags := opts.RunTags.CloneTags()
+	if _, ok := tags[stats.TagRunID.String()]; !ok {
+		tags[stats.TagRunID.String()] = md.RunID
+	}
+	opts.RunTags = stats.IntoSampleTags(&tags)
+	return opts
+}
+
+// MarkStarted sets the start time of the test run to now, it has to be called
+// right before the test run starts, after the VUs are initialized.
+func (md *TestRunMetadata) MarkStarted() {
+	now := time.Now()
+	md.StartTime = &now
+}
+
+// TestRunManifest describes a finished test run, with its metadata, its consolidated options and
+// its result, so that the results of the test run in the outputs can be joined back with the
+// exact test definition by the run ID.
+type TestRunManifest struct {
+	*TestRunMetadata
+	Options  Options   `json:"options"`
+	EndTime  time.Time `json:"end_time"`
+	ExitCode int       `json:"exit_code"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// NewTestRunManifest returns the manifest of the test run with the given metadata and options,
+// which ended now with the given exit code and error. The options of the manifest don't have
+// their secrets, since it's sent to the outputs.
+func NewTestRunManifest(md *TestRunMetadata, opts Options, exitCode int, err error) *TestRunManifest {
+	manifest := &TestRunManifest{
+		TestRunMetadata: md,
+		Options:         optionsWithoutSecrets(opts),
+		EndTime:         time.Now(),
+		ExitCode:        exitCode,
+	}
+	if err != nil {
+		manifest.Error = err.Error()
+	}
+	return manifest
+}
+
+// optionsWithoutSecrets returns a copy of the options without the private keys of the TLS client
+// certificates and without the cloud token of the ext options.
+func optionsWithoutSecrets(opts Options) Options {
+	if opts.TLSAuth != nil {
+		tlsAuth := make([]*TLSAuth, len(opts.TLSAuth))
+		for i, auth := range opts.TLSAuth {
+			tlsAuth[i] = &TLSAuth{TLSAuthFields: auth.TLSAuthFields}
+			tlsAuth[i].Key = ""
+		}
+		opts.TLSAuth = tlsAuth
+	}
+
+	var cloudConf map[string]json.RawMessage
+	if err := json.Unmarshal(opts.External["loadimpact"], &cloudConf); err != nil || cloudConf["token"] == nil {
+		return opts
+	}
+	delete(cloudConf, "token")
+	cloudJSON, err := json.Marshal(cloudConf)
+	if err != nil {
+		return opts
+	}
+	external := make(map[string]json.RawMessage, len(opts.External))
+	for k, v := range opts.External {
+		external[k] = v
+	}
+	external["loadimpact"] = cloudJSON
+	opts.External = external
+	return opts
+}
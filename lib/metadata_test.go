@@ -0,0 +1,129 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/consts"
+	"go.k6.io/k6/stats"
+)
+
+func TestNewTestRunMetadata(t *testing.T) {
+	t.Parallel()
+
+	opts := Options{
+		VUs:     null.IntFrom(10),
+		RunTags: stats.IntoSampleTags(&map[string]string{"instance": "eu-1"}),
+	}
+	md1, err := NewTestRunMetadata("file:///script.js", []byte("export default function() {}"), opts)
+	require.NoError(t, err)
+	md2, err := NewTestRunMetadata("file:///script.js", []byte("export default function() {}"), opts)
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, md1.RunID)
+	assert.NotEqual(t, md1.RunID, md2.RunID)
+	assert.Equal(t, "file:///script.js", md1.ScriptName)
+	assert.Equal(t, consts.Version, md1.K6Version)
+	assert.Len(t, md1.ScriptHash, 64)
+	assert.Equal(t, md1.ScriptHash, md2.ScriptHash)
+	assert.Equal(t, md1.OptionsDigest, md2.OptionsDigest)
+	assert.Equal(t, map[string]string{"instance": "eu-1"}, md1.Labels)
+	assert.Empty(t, md1.ExecutionSegment)
+	assert.Nil(t, md1.StartTime)
+	md1.MarkStarted()
+	require.NotNil(t, md1.StartTime)
+	assert.False(t, md1.StartTime.IsZero())
+
+	segment, err := NewExecutionSegmentFromString("0:1/2")
+	require.NoError(t, err)
+	md3, err := NewTestRunMetadata("file:///script.js", []byte("export default function() {}"),
+		opts.Apply(Options{ExecutionSegment: segment}))
+	require.NoError(t, err)
+	assert.NotEqual(t, md1.OptionsDigest, md3.OptionsDigest)
+	assert.Equal(t, "0:1/2", md3.ExecutionSegment)
+}
+
+func TestWithRunIDTag(t *testing.T) {
+	t.Parallel()
+
+	md := &TestRunMetadata{RunID: "some-id"}
+	systemTags := stats.DefaultSystemTagSet
+	opts := md.WithRunIDTag(Options{
+		SystemTags: &systemTags,
+		RunTags:    stats.IntoSampleTags(&map[string]string{"instance": "eu-1"}),
+	})
+	assert.Equal(t, map[string]string{"instance": "eu-1", "run_id": "some-id"}, opts.RunTags.CloneTags())
+	opts = md.WithRunIDTag(Options{SystemTags: &systemTags})
+	assert.Equal(t, map[string]string{"run_id": "some-id"}, opts.RunTags.CloneTags())
+
+	// the run_id tag of the run tags takes precedence
+	opts = md.WithRunIDTag(Options{
+		SystemTags: &systemTags,
+		RunTags:    stats.IntoSampleTags(&map[string]string{"run_id": "custom"}),
+	})
+	assert.Equal(t, map[string]string{"run_id": "custom"}, opts.RunTags.CloneTags())
+
+	// the run_id tag can be disabled like the other system tags
+	systemTags &^= stats.TagRunID
+	assert.Nil(t, md.WithRunIDTag(Options{SystemTags: &systemTags}).RunTags)
+}
+
+func TestNewTestRunManifest(t *testing.T) {
+	t.Parallel()
+
+	opts := Options{VUs: null.IntFrom(10)}
+	md, err := NewTestRunMetadata("file:///script.js", []byte("export default function() {}"), opts)
+	require.NoError(t, err)
+
+	md.MarkStarted()
+	manifest := NewTestRunManifest(md, opts, 99, errors.New("some thresholds have failed"))
+	assert.False(t, manifest.EndTime.Before(*md.StartTime))
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	var fromJSON map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &fromJSON))
+	assert.Equal(t, md.RunID, fromJSON["run_id"])
+	assert.Equal(t, md.ScriptHash, fromJSON["script_hash"])
+	assert.Equal(t, float64(99), fromJSON["exit_code"])
+	assert.Equal(t, "some thresholds have failed", fromJSON["error"])
+	assert.Equal(t, float64(10), fromJSON["options"].(map[string]interface{})["vus"])
+
+	assert.Empty(t, NewTestRunManifest(md, opts, 0, nil).Error)
+
+	// the secrets of the options aren't in the manifest
+	tlsAuth := &TLSAuth{TLSAuthFields: TLSAuthFields{Cert: "cert", Key: "key", Domains: []string{"example.com"}}}
+	opts = Options{
+		TLSAuth:  []*TLSAuth{tlsAuth},
+		External: map[string]json.RawMessage{"loadimpact": json.RawMessage(`{"name":"test","token":"secret"}`)},
+	}
+	manifest = NewTestRunManifest(md, opts, 0, nil)
+	assert.Equal(t, TLSAuthFields{Cert: "cert", Domains: []string{"example.com"}}, manifest.Options.TLSAuth[0].TLSAuthFields)
+	assert.JSONEq(t, `{"name":"test"}`, string(manifest.Options.External["loadimpact"]))
+	assert.Equal(t, "key", tlsAuth.Key)
+	assert.JSONEq(t, `{"name":"test","token":"secret"}`, string(opts.External["loadimpact"]))
+}
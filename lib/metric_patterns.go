@@ -0,0 +1,74 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"fmt"
+	"path"
+)
+
+// MetricPatterns matches the names of the metrics with glob patterns: a name matches if it
+// matches any of the include patterns, or there aren't any, and none of the exclude ones. The
+// results are cached, so it isn't safe for concurrent use.
+type MetricPatterns struct {
+	include, exclude []string
+	matches          map[string]bool
+}
+
+// NewMetricPatterns returns the matcher of the include and exclude patterns, which have to be
+// checked with ValidateMetricPatterns() before.
+func NewMetricPatterns(include, exclude []string) *MetricPatterns {
+	return &MetricPatterns{include: include, exclude: exclude, matches: make(map[string]bool)}
+}
+
+// Match returns whether the name of the metric matches the patterns.
+func (mp *MetricPatterns) Match(name string) bool {
+	if matches, ok := mp.matches[name]; ok {
+		return matches
+	}
+	matches := len(mp.include) == 0
+	for _, pattern := range mp.include {
+		if ok, _ := path.Match(pattern, name); ok {
+			matches = true
+			break
+		}
+	}
+	for _, pattern := range mp.exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			matches = false
+			break
+		}
+	}
+	mp.matches[name] = matches
+	return matches
+}
+
+// ValidateMetricPatterns returns an error for the first of the patterns that isn't a valid glob.
+func ValidateMetricPatterns(patterns ...[]string) error {
+	for _, list := range patterns {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid metrics pattern '%s': %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricPatterns(t *testing.T) {
+	t.Parallel()
+
+	all := NewMetricPatterns(nil, nil)
+	assert.True(t, all.Match("http_reqs"))
+
+	mp := NewMetricPatterns([]string{"http_req_*", "vus"}, []string{"http_req_blocked"})
+	assert.True(t, mp.Match("http_req_duration"))
+	assert.True(t, mp.Match("vus"))
+	assert.False(t, mp.Match("http_req_blocked"))
+	assert.False(t, mp.Match("data_sent"))
+	assert.False(t, NewMetricPatterns(nil, []string{"data_*"}).Match("data_sent"))
+
+	assert.NoError(t, ValidateMetricPatterns([]string{"http_req_*"}, nil))
+	assert.EqualError(t, ValidateMetricPatterns(nil, []string{"[a"}),
+		"invalid metrics pattern '[a': syntax error in pattern")
+}
@@ -37,8 +37,10 @@ var (
 	Errors            = stats.New("errors", stats.Counter)
 
 	// Runner-emitted.
-	Checks        = stats.New("checks", stats.Rate)
-	GroupDuration = stats.New("group_duration", stats.Trend, stats.Time)
+	Checks         = stats.New("checks", stats.Rate)
+	GroupDuration  = stats.New("group_duration", stats.Trend, stats.Time)
+	PacingDelay    = stats.New("pacing_delay", stats.Trend, stats.Time)
+	PacingOverruns = stats.New("pacing_overruns", stats.Counter)
 
 	// HTTP-related.
 	HTTPReqs              = stats.New("http_reqs", stats.Counter)
@@ -50,6 +52,34 @@ var (
 	HTTPReqSending        = stats.New("http_req_sending", stats.Trend, stats.Time)
 	HTTPReqWaiting        = stats.New("http_req_waiting", stats.Trend, stats.Time)
 	HTTPReqReceiving      = stats.New("http_req_receiving", stats.Trend, stats.Time)
+	HTTPRespHeaderBytes   = stats.New("http_resp_header_bytes", stats.Trend, stats.Data)
+	HTTPRespHeaderCount   = stats.New("http_resp_header_count", stats.Trend)
+
+	// The failed requests, with an error or an error status, tagged with the error_class of the
+	// error, like "dns.no_such_host" or "http.5xx".
+	HTTPReqErrors = stats.New("http_req_errors", stats.Counter)
+
+	// HTTP connection pool, only with the httpConnPoolMetrics option.
+	HTTPReqConnWait = stats.New("http_req_conn_wait", stats.Trend, stats.Time)
+	HTTPConnsOpen   = stats.New("http_conns_open", stats.Trend)
+	HTTPConnsIdle   = stats.New("http_conns_idle", stats.Trend)
+
+	// The rate of the cacheable requests that were revalidated from the cache, only with the
+	// httpCache option or the cache param.
+	HTTPCacheHits = stats.New("http_cache_hits", stats.Rate)
+
+	// The duration of the page loads of http.pageLoad(), from the start of the first request to the
+	// end of the last one.
+	PageLoadTime = stats.New("page_load_time", stats.Trend, stats.Time)
+
+	// The state changes of the circuit breakers of k6/resilience and the calls they rejected.
+	CircuitBreakerTransitions = stats.New("circuit_breaker_transitions", stats.Counter)
+	CircuitBreakerRejections  = stats.New("circuit_breaker_rejections", stats.Counter)
+
+	// The samples waiting in the buffers of the outputs, in memory and on disk, and the samples
+	// the outputs dropped, tagged with the output, only with the outputBufferMaxSamples option.
+	OutputBufferSamples  = stats.New("output_buffer_samples", stats.Gauge)
+	OutputDroppedSamples = stats.New("output_dropped_samples", stats.Counter)
 
 	// Websocket-related
 	WSSessions         = stats.New("ws_sessions", stats.Counter)
@@ -62,6 +92,25 @@ var (
 	// gRPC-related
 	GRPCReqDuration = stats.New("grpc_req_duration", stats.Trend, stats.Time)
 
+	// DNS-related
+	DNSLookups        = stats.New("dns_lookups", stats.Counter)
+	DNSLookupDuration = stats.New("dns_lookup_duration", stats.Trend, stats.Time)
+
+	// Mail-related
+	MailSent            = stats.New("mail_sent", stats.Counter)
+	MailSendDuration    = stats.New("mail_send_duration", stats.Trend, stats.Time)
+	MailDeliveryLatency = stats.New("mail_delivery_latency", stats.Trend, stats.Time)
+
+	// SSH-related
+	SSHConnectDuration   = stats.New("ssh_connect_duration", stats.Trend, stats.Time)
+	SSHCommands          = stats.New("ssh_commands", stats.Counter)
+	SSHCommandDuration   = stats.New("ssh_command_duration", stats.Trend, stats.Time)
+	SFTPTransferDuration = stats.New("sftp_transfer_duration", stats.Trend, stats.Time)
+
+	// FTP-related, the throughput is in bytes per second
+	FTPTransferDuration   = stats.New("ftp_transfer_duration", stats.Trend, stats.Time)
+	FTPTransferThroughput = stats.New("ftp_transfer_throughput", stats.Trend)
+
 	// Network-related; used for future protocols as well.
 	DataSent     = stats.New("data_sent", stats.Counter, stats.Data)
 	DataReceived = stats.New("data_received", stats.Counter, stats.Data)
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -46,6 +47,15 @@ type Dialer struct {
 
 	BytesRead    int64
 	BytesWritten int64
+
+	// the open connections and the requests using them by "host:port", for the connection pool
+	// metrics of the HTTP transport
+	poolMu    sync.Mutex
+	openConns map[string]int64
+	busyConns map[string]int64
+
+	// the *networkShaper of the current scenario of the VU, it's nil without shaping
+	shaper atomic.Value
 }
 
 // NewDialer constructs a new Dialer with the given DNS resolver.
@@ -86,10 +96,90 @@ func (d *Dialer) DialContext(ctx context.Context, proto, addr string) (net.Conn,
 	if err != nil {
 		return nil, err
 	}
-	conn = &Conn{conn, &d.BytesRead, &d.BytesWritten}
+	if s := d.getNetworkShaper(); s != nil && s.latency > 0 {
+		// the round trip of the TCP handshake
+		t := time.NewTimer(s.latency)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			_ = conn.Close()
+			return nil, ctx.Err()
+		}
+	}
+	d.addOpenConns(addr, 1)
+	shapingCtx, cancelShaping := context.WithCancel(context.Background())
+	conn = &Conn{
+		Conn: conn, BytesRead: &d.BytesRead, BytesWritten: &d.BytesWritten,
+		onClose: func() {
+			cancelShaping()
+			d.addOpenConns(addr, -1)
+		},
+		shaper: d.getNetworkShaper,
+		ctx:    shapingCtx,
+	}
 	return conn, err
 }
 
+// SetNetworkShaping sets the shaping of the traffic of all the connections of the dialer, the
+// ones that are already open too, nil disables it.
+func (d *Dialer) SetNetworkShaping(s *lib.NetworkShaping) {
+	d.shaper.Store(newNetworkShaper(s))
+}
+
+func (d *Dialer) getNetworkShaper() *networkShaper {
+	s, _ := d.shaper.Load().(*networkShaper)
+	return s
+}
+
+func (d *Dialer) addOpenConns(addr string, n int64) {
+	d.poolMu.Lock()
+	defer d.poolMu.Unlock()
+	if d.openConns == nil {
+		d.openConns = make(map[string]int64)
+	}
+	d.openConns[addr] += n
+	if d.openConns[addr] <= 0 {
+		delete(d.openConns, addr)
+	}
+}
+
+// TrackRequest counts the request to the address, as "host:port", as using one of the open
+// connections to it until the returned function is called.
+func (d *Dialer) TrackRequest(addr string) (done func()) {
+	d.poolMu.Lock()
+	defer d.poolMu.Unlock()
+	if d.busyConns == nil {
+		d.busyConns = make(map[string]int64)
+	}
+	d.busyConns[addr]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.poolMu.Lock()
+			defer d.poolMu.Unlock()
+			d.busyConns[addr]--
+			if d.busyConns[addr] <= 0 {
+				delete(d.busyConns, addr)
+			}
+		})
+	}
+}
+
+// ConnPoolStats returns how many connections to the address, as "host:port", are open and how
+// many of them aren't used by any request. With HTTP/2 several requests use the same connection,
+// so the idle ones are approximate.
+func (d *Dialer) ConnPoolStats(addr string) (open, idle int64) {
+	d.poolMu.Lock()
+	defer d.poolMu.Unlock()
+	open = d.openConns[addr]
+	if idle = open - d.busyConns[addr]; idle < 0 {
+		idle = 0
+	}
+	return open, idle
+}
+
 // GetTrail creates a new NetTrail instance with the Dialer
 // sent and received data metrics and the supplied times and tags.
 // TODO: Refactor this according to
@@ -249,20 +339,51 @@ type Conn struct {
 	net.Conn
 
 	BytesRead, BytesWritten *int64
+
+	onClose   func()
+	closeOnce sync.Once
+
+	shaper    func() *networkShaper
+	direction int32
+	// done when the connection is closed, for the waits of the shaping
+	ctx context.Context
 }
 
-func (c *Conn) Read(b []byte) (int, error) {
-	n, err := c.Conn.Read(b)
+func (c *Conn) Read(b []byte) (n int, err error) {
+	if s := c.getShaper(); s != nil {
+		n, err = c.shapedRead(s, b)
+	} else {
+		n, err = c.Conn.Read(b)
+	}
 	if n > 0 {
 		atomic.AddInt64(c.BytesRead, int64(n))
 	}
 	return n, err
 }
 
-func (c *Conn) Write(b []byte) (int, error) {
-	n, err := c.Conn.Write(b)
+// Close closes the connection, it isn't counted as open by the dialer anymore.
+func (c *Conn) Close() error {
+	if c.onClose != nil {
+		c.closeOnce.Do(c.onClose)
+	}
+	return c.Conn.Close()
+}
+
+func (c *Conn) Write(b []byte) (n int, err error) {
+	if s := c.getShaper(); s != nil {
+		n, err = c.shapedWrite(s, b)
+	} else {
+		n, err = c.Conn.Write(b)
+	}
 	if n > 0 {
 		atomic.AddInt64(c.BytesWritten, int64(n))
 	}
 	return n, err
 }
+
+func (c *Conn) getShaper() *networkShaper {
+	if c.shaper == nil {
+		return nil
+	}
+	return c.shaper()
+}
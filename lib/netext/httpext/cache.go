@@ -0,0 +1,246 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpext
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// maxCacheSize is the size of the bodies that the cache of a VU keeps, the least recently used
+// responses are evicted when it's full, like from the cache of a browser.
+const maxCacheSize = 64 << 20
+
+// Cache emulates the cache of a browser for the requests of a VU. It keeps the validators, the
+// headers and the body of the GET responses with an ETag or a Last-Modified header, and the next
+// requests for the same URLs, with the same values of the headers in the Vary header, are sent as
+// conditional requests. The cached responses are always revalidated, regardless of their
+// freshness, and a 304 response gets the cached headers and body.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// the entries, the most recently used first
+	lru     *list.List
+	size    int
+	maxSize int
+}
+
+type cacheEntry struct {
+	key                string
+	etag, lastModified string
+	header             http.Header
+	body               []byte
+	// the values of the request headers in the Vary header of the response
+	vary map[string]string
+}
+
+// matches returns whether the request has the values of the headers the response varies by.
+func (e *cacheEntry) matches(req *http.Request) bool {
+	for name, value := range e.vary {
+		if strings.Join(req.Header.Values(name), ", ") != value {
+			return false
+		}
+	}
+	return true
+}
+
+// NewCache returns a new empty cache.
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]*list.Element), lru: list.New(), maxSize: maxCacheSize}
+}
+
+// Clear removes all the cached responses, like for a new visit of a user.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.size = 0
+}
+
+// Len returns the number of cached responses.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *Cache) get(key string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*cacheEntry)
+}
+
+func (c *Cache) set(entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(entry.key)
+	if len(entry.body) > c.maxSize {
+		return
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.size += len(entry.body)
+	for c.size > c.maxSize {
+		c.removeLocked(c.lru.Back().Value.(*cacheEntry).key)
+	}
+}
+
+func (c *Cache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(key)
+}
+
+func (c *Cache) removeLocked(key string) {
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		c.size -= len(elem.Value.(*cacheEntry).body)
+	}
+}
+
+// isCacheable returns whether the response of the request can be served from the cache, the
+// requests with their own conditional or range headers are sent as they are.
+func isCacheable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	for _, h := range []string{"If-None-Match", "If-Modified-Since", "Range"} {
+		if req.Header.Get(h) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func hasCacheControl(header http.Header, directive string) bool {
+	for _, v := range header.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(d), directive) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// conditionalRequest returns the request with the validators of the cached response, if there is one.
+func (c *Cache) conditionalRequest(req *http.Request) (*http.Request, *cacheEntry) {
+	entry := c.get(req.URL.String())
+	if entry == nil || !entry.matches(req) {
+		return req, nil
+	}
+	req = req.Clone(req.Context())
+	if entry.etag != "" {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+	if entry.lastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.lastModified)
+	}
+	return req, entry
+}
+
+// handleResponse serves a 304 response of a conditional request from the cached entry, and makes
+// the body of the other GET responses with validators be cached once it's read. It returns
+// whether the response was served from the cache.
+func (c *Cache) handleResponse(req *http.Request, resp *http.Response, entry *cacheEntry) bool {
+	key := req.URL.String()
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+		for k, vs := range entry.header {
+			if _, ok := resp.Header[k]; !ok {
+				resp.Header[k] = vs
+			}
+		}
+		if req.Method == http.MethodHead {
+			resp.Body = http.NoBody
+		} else {
+			resp.Body = ioutil.NopCloser(bytes.NewReader(entry.body))
+		}
+		return true
+	}
+
+	if req.Method != http.MethodGet {
+		return false
+	}
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") ||
+		hasCacheControl(resp.Header, "no-store") || resp.Header.Get("Vary") == "*" {
+		if resp.StatusCode != http.StatusNotModified {
+			c.remove(key)
+		}
+		return false
+	}
+	vary := make(map[string]string)
+	for _, v := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+				vary[name] = strings.Join(req.Header.Values(name), ", ")
+			}
+		}
+	}
+	resp.Body = &cachingBody{
+		ReadCloser: resp.Body,
+		maxSize:    c.maxSize,
+		onEOF: func(body []byte) {
+			c.set(&cacheEntry{
+				key: key, etag: etag, lastModified: lastModified, header: resp.Header.Clone(), body: body, vary: vary,
+			})
+		},
+	}
+	return false
+}
+
+// cachingBody is the body of a response that is cached when it's read until the end, unless it's
+// bigger than the cache.
+type cachingBody struct {
+	io.ReadCloser
+	buf     bytes.Buffer
+	maxSize int
+	onEOF   func([]byte)
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.onEOF != nil && b.buf.Len()+n > b.maxSize {
+		b.onEOF = nil
+		b.buf = bytes.Buffer{}
+	}
+	if b.onEOF != nil {
+		b.buf.Write(p[:n])
+	}
+	if err == io.EOF && b.onEOF != nil {
+		b.onEOF(b.buf.Bytes())
+		b.onEOF = nil
+	}
+	return n, err
+}
@@ -0,0 +1,131 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpext
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"go.k6.io/k6/stats"
+)
+
+// ResponseChunk is a part of a chunked response body as a read of the body received it, the time
+// is the milliseconds since the response headers were received. The reads don't follow the
+// chunks of the transfer encoding, which net/http doesn't expose: a read can return a part of a
+// chunk or the parts of several chunks that were already buffered, so the sizes and the times
+// only show how the body arrived.
+type ResponseChunk struct {
+	Size int     `json:"size"`
+	Time float64 `json:"time"`
+}
+
+// chunkedBody is a request body that is sent in chunks of the size, with the interval between
+// them, the transport flushes each chunk of the chunked requests.
+type chunkedBody struct {
+	ctx      context.Context
+	data     []byte
+	size     int
+	interval time.Duration
+	// the bytes left of the current chunk, a new chunk starts after the interval when it's 0
+	left  int
+	first bool
+}
+
+func newChunkedBody(ctx context.Context, data []byte, size int, interval time.Duration) *chunkedBody {
+	return &chunkedBody{ctx: ctx, data: data, size: size, interval: interval, first: true}
+}
+
+func (b *chunkedBody) Read(p []byte) (int, error) {
+	if len(b.data) == 0 {
+		return 0, io.EOF
+	}
+	if b.left == 0 {
+		if !b.first && b.interval > 0 {
+			t := time.NewTimer(b.interval)
+			select {
+			case <-t.C:
+			case <-b.ctx.Done():
+				t.Stop()
+				return 0, b.ctx.Err()
+			}
+		}
+		b.first = false
+		b.left = b.size
+		if b.left > len(b.data) {
+			b.left = len(b.data)
+		}
+	}
+	n := copy(p, b.data[:b.left])
+	b.data, b.left = b.data[n:], b.left-n
+	return n, nil
+}
+
+func (b *chunkedBody) Close() error {
+	return nil
+}
+
+// setChunkedBody makes the request send the body with the chunked transfer encoding, and the
+// trailers after it.
+func setChunkedBody(ctx context.Context, req *http.Request, data []byte, preq *ParsedHTTPRequest) {
+	size := int(preq.ChunkSize)
+	if size <= 0 {
+		size = len(data)
+	}
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return newChunkedBody(ctx, data, size, preq.ChunkInterval), nil
+	}
+	req.Body, _ = req.GetBody()
+	if len(preq.Trailers) > 0 {
+		req.Trailer = make(http.Header, len(preq.Trailers))
+		for k, v := range preq.Trailers {
+			req.Trailer.Set(k, v)
+		}
+	}
+}
+
+// chunkTimingBody records the sizes and the times of the reads of a chunked response body, not
+// of its encoded chunks.
+type chunkTimingBody struct {
+	io.ReadCloser
+	start  time.Time
+	chunks *[]ResponseChunk
+}
+
+func (b chunkTimingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		*b.chunks = append(*b.chunks, ResponseChunk{Size: n, Time: stats.D(time.Since(b.start))})
+	}
+	return n, err
+}
+
+func isChunked(res *http.Response) bool {
+	for _, te := range res.TransferEncoding {
+		if te == "chunked" {
+			return true
+		}
+	}
+	return false
+}
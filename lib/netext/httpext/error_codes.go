@@ -29,6 +29,7 @@ import (
 	"net/url"
 	"os"
 	"runtime"
+	"strings"
 	"syscall"
 
 	"golang.org/x/net/http2"
@@ -63,7 +64,7 @@ const (
 	tcpDialUnknownErrnoCode  errCode = 1213
 	tcpResetByPeerErrorCode  errCode = 1220
 	// TLS errors
-	defaultTLSErrorCode           errCode = 1300 //nolint:deadcode,varcheck // this is here to save the number
+	defaultTLSErrorCode           errCode = 1300
 	tlsHeaderErrorCode            errCode = 1301
 	x509UnknownAuthorityErrorCode errCode = 1310
 	x509HostnameErrorCode         errCode = 1311
@@ -84,7 +85,8 @@ const (
 
 	// Custom k6 content errors, i.e. when the magic fails
 	// defaultContentError errCode = 1700 // reserved for future use
-	responseDecompressionErrorCode errCode = 1701
+	responseDecompressionErrorCode   errCode = 1701
+	responseHeadersTooLargeErrorCode errCode = 1702
 )
 
 const (
@@ -103,6 +105,8 @@ const (
 	x509UnknownAuthority        = "x509: unknown authority"
 	requestTimeoutErrorCodeMsg  = "request timeout"
 	invalidURLErrorCodeMsg      = "invalid URL"
+
+	responseHeadersTooLargeErrorCodeMsg = "response headers too large"
 )
 
 func http2ErrCodeOffset(code http2.ErrCode) errCode {
@@ -220,6 +224,79 @@ func errorCodeForError(err error) (errCode, string) {
 	}
 }
 
+// errorClass returns the class of an error code in the hierarchy of the error_class tag, the
+// kind of the error and after a dot its cause, like "tcp.reset" or "http.5xx". The timeouts
+// are a class of their own, whether they happen when dialing or waiting for the response.
+//nolint:cyclop
+func errorClass(code errCode) string {
+	switch {
+	case code == requestTimeoutErrorCode:
+		return "timeout.request"
+	case code == tcpDialTimeoutErrorCode:
+		return "timeout.dial"
+	case code == invalidURLErrorCode:
+		return "other.invalid_url"
+	case code == defaultNetNonTCPErrorCode:
+		return "other.network"
+	case code == dnsNoSuchHostErrorCode:
+		return "dns.no_such_host"
+	case code == blackListedIPErrorCode, code == blockedHostnameErrorCode:
+		return "dns.blocked"
+	case code >= defaultDNSErrorCode && code < defaultTCPErrorCode:
+		return "dns.other"
+	case code == tcpBrokenPipeErrorCode:
+		return "tcp.broken_pipe"
+	case code == tcpResetByPeerErrorCode:
+		return "tcp.reset"
+	case code == tcpDialRefusedErrorCode:
+		return "tcp.refused"
+	case code >= tcpDialErrorCode && code < tcpResetByPeerErrorCode:
+		return "tcp.dial"
+	case code >= defaultTCPErrorCode && code < defaultTLSErrorCode:
+		return "tcp.other"
+	case code == x509UnknownAuthorityErrorCode, code == x509HostnameErrorCode:
+		return "tls.certificate"
+	case code >= defaultTLSErrorCode && code < 1400:
+		return "tls.other"
+	case code >= 1400 && code < 1500:
+		return "http.4xx"
+	case code >= 1500 && code < 1600:
+		return "http.5xx"
+	case code >= unknownHTTP2GoAwayErrorCode && code < unknownHTTP2StreamErrorCode:
+		return "http2.goaway"
+	case code >= unknownHTTP2StreamErrorCode && code < unknownHTTP2ConnectionErrorCode:
+		return "http2.stream"
+	case code >= unknownHTTP2ConnectionErrorCode && code < 1700:
+		return "http2.connection"
+	case code >= 1700 && code < 1800:
+		return "content"
+	default:
+		return "other"
+	}
+}
+
+// The messages of the errors that net/http and x/net/http2 return when the
+// response headers exceed the limit of the transport. Neither package exports
+// these errors, as values or as types, so the message of the error that the
+// transport itself returned is compared with them.
+const (
+	h1ResponseHeadersTooLargePrefix = "net/http: server response headers exceeded "
+	h2ResponseHeaderListTooLargeMsg = "http2: response header list larger than advertised limit"
+)
+
+// isResponseHeadersTooLargeError checks whether the error was returned because
+// the response headers exceeded the MaxResponseHeaderBytes of the transport.
+func isResponseHeadersTooLargeError(err error) bool {
+	if err == nil {
+		return false
+	}
+	for inner := errors.Unwrap(err); inner != nil; inner = errors.Unwrap(err) {
+		err = inner
+	}
+	msg := err.Error()
+	return strings.HasPrefix(msg, h1ResponseHeadersTooLargePrefix) || msg == h2ResponseHeaderListTooLargeMsg
+}
+
 // K6Error is a helper struct that enhances Go errors with custom k6-specific
 // error-codes and more user-readable error messages.
 type K6Error struct {
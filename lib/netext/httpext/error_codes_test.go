@@ -400,3 +400,45 @@ func getHTTP2ServerWithCustomConnContext(t *testing.T) *httpmultibin.HTTPMultiBi
 		HTTPTransport:   transport,
 	}
 }
+
+func TestResponseHeadersTooLargeError(t *testing.T) {
+	t.Parallel()
+	h1Err := errors.New("net/http: server response headers exceeded 1024 bytes; aborted")
+	h2Err := errors.New("http2: response header list larger than advertised limit")
+
+	assert.True(t, isResponseHeadersTooLargeError(h1Err))
+	assert.True(t, isResponseHeadersTooLargeError(h2Err))
+	assert.True(t, isResponseHeadersTooLargeError(fmt.Errorf("round trip: %w", h2Err)))
+	assert.False(t, isResponseHeadersTooLargeError(nil))
+	assert.False(t, isResponseHeadersTooLargeError(fmt.Errorf("round trip: %s", h1Err)))
+	assert.False(t, isResponseHeadersTooLargeError(errors.New("the server response headers exceeded the limit")))
+}
+
+func TestErrorClass(t *testing.T) {
+	t.Parallel()
+	testTable := map[errCode]string{
+		defaultErrorCode:                "other",
+		invalidURLErrorCode:             "other.invalid_url",
+		requestTimeoutErrorCode:         "timeout.request",
+		tcpDialTimeoutErrorCode:         "timeout.dial",
+		dnsNoSuchHostErrorCode:          "dns.no_such_host",
+		blockedHostnameErrorCode:        "dns.blocked",
+		defaultDNSErrorCode:             "dns.other",
+		tcpDialRefusedErrorCode:         "tcp.refused",
+		tcpDialUnknownErrnoCode:         "tcp.dial",
+		tcpResetByPeerErrorCode:         "tcp.reset",
+		tcpBrokenPipeErrorCode:          "tcp.broken_pipe",
+		netUnknownErrnoErrorCode:        "tcp.other",
+		x509HostnameErrorCode:           "tls.certificate",
+		tlsHeaderErrorCode:              "tls.other",
+		1404:                            "http.4xx",
+		1503:                            "http.5xx",
+		unknownHTTP2GoAwayErrorCode + 2: "http2.goaway",
+		unknownHTTP2StreamErrorCode:     "http2.stream",
+		unknownHTTP2ConnectionErrorCode: "http2.connection",
+		responseDecompressionErrorCode:  "content",
+	}
+	for code, class := range testTable {
+		assert.Equal(t, class, errorClass(code), "%d", code)
+	}
+}
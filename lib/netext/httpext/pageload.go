@@ -0,0 +1,150 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.k6.io/k6/lib"
+)
+
+// PageLoadRequest is a request of a page load, with the requests that have to finish before it's
+// started, like the resources that a browser discovers in the documents it loads.
+type PageLoadRequest struct {
+	BatchParsedHTTPRequest
+	// The indexes of the requests this one depends on.
+	DependsOn []int
+	// The start and the end of the request, from the start of the page load.
+	Start, End time.Duration
+}
+
+// ErrPageLoadCycle is returned when the requests of a page load have circular dependencies.
+var ErrPageLoadCycle = errors.New("the requests of the page load have circular dependencies")
+
+// ValidatePageLoad returns an error if the dependencies of the requests can't be satisfied.
+func ValidatePageLoad(requests []PageLoadRequest) error {
+	pending := make([]int, len(requests))
+	dependents := make([][]int, len(requests))
+	var ready []int
+	for i, req := range requests {
+		for _, dep := range req.DependsOn {
+			if dep == i {
+				return ErrPageLoadCycle
+			}
+			if dep < 0 || dep >= len(requests) {
+				return fmt.Errorf("invalid dependency %d of the request %d of the page load", dep, i)
+			}
+			dependents[dep] = append(dependents[dep], i)
+		}
+		pending[i] = len(req.DependsOn)
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	done := 0
+	for len(ready) > 0 {
+		i := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		done++
+		for _, d := range dependents[i] {
+			if pending[d]--; pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	if done < len(requests) {
+		return ErrPageLoadCycle
+	}
+	return nil
+}
+
+// MakePageLoadRequests makes the requests of a page load like a browser does, each request is
+// started once all of its dependencies have finished, with at most globalLimit requests in flight
+// and at most perHostLimit requests to each host, 0 meaning no limit. It returns the duration of
+// the whole page load and the first error of the requests; the start and the end of each request
+// and its response are recorded in the requests.
+func MakePageLoadRequests(
+	ctx context.Context,
+	requests []PageLoadRequest,
+	globalLimit, perHostLimit int,
+	processResponse func(context.Context, *Response, ResponseType),
+) (time.Duration, error) {
+	if err := ValidatePageLoad(requests); err != nil {
+		return 0, err
+	}
+
+	globalLimiter := lib.NewSlotLimiter(globalLimit)
+	perHostLimiter := lib.NewMultiSlotLimiter(perHostLimit)
+	type result struct {
+		index int
+		err   error
+	}
+	results := make(chan result, len(requests))
+	start := time.Now()
+
+	makeRequest := func(i int) {
+		req := &requests[i]
+		if hl := perHostLimiter.Slot(req.URL.GetURL().Host); hl != nil {
+			hl.Begin()
+			defer hl.End()
+		}
+		globalLimiter.Begin()
+		defer globalLimiter.End()
+
+		req.Start = time.Since(start)
+		resp, err := MakeRequest(ctx, req.ParsedHTTPRequest)
+		req.End = time.Since(start)
+		if resp != nil {
+			processResponse(ctx, resp, req.ParsedHTTPRequest.ResponseType)
+			*req.Response = *resp
+		}
+		results <- result{i, err}
+	}
+
+	pending := make([]int, len(requests))
+	dependents := make([][]int, len(requests))
+	for i, req := range requests {
+		pending[i] = len(req.DependsOn)
+		for _, dep := range req.DependsOn {
+			dependents[dep] = append(dependents[dep], i)
+		}
+		if pending[i] == 0 {
+			go makeRequest(i)
+		}
+	}
+
+	var firstErr error
+	for range requests {
+		res := <-results
+		if res.err != nil && firstErr == nil {
+			firstErr = res.err
+		}
+		for _, d := range dependents[res.index] {
+			if pending[d]--; pending[d] == 0 {
+				go makeRequest(d)
+			}
+		}
+	}
+	return time.Since(start), firstErr
+}
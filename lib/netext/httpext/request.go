@@ -63,6 +63,8 @@ type ParsedHTTPRequest struct {
 	Body             *bytes.Buffer
 	Req              *http.Request
 	Timeout          time.Duration
+	TotalTimeout     time.Duration
+	Retries          int64
 	Auth             string
 	Throw            bool
 	ResponseType     ResponseType
@@ -72,6 +74,16 @@ type ParsedHTTPRequest struct {
 	ActiveJar        *cookiejar.Jar
 	Cookies          map[string]*HTTPRequestCookie
 	Tags             map[string]string
+	Metadata         map[string]string
+
+	// The body is sent with the chunked transfer encoding if ChunkSize or Trailers are set, in
+	// chunks of ChunkSize bytes with the ChunkInterval pause between them.
+	ChunkSize     int64
+	ChunkInterval time.Duration
+	Trailers      map[string]string
+
+	// The cache of the VU for the conditional requests, nil if the responses aren't cached.
+	Cache *Cache
 }
 
 // Matches non-compliant io.Closer implementations (e.g. zstd.Decoder)
@@ -186,6 +198,10 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 		}
 	}
 
+	if preq.Body != nil && (preq.ChunkSize > 0 || len(preq.Trailers) > 0) {
+		setChunkedBody(ctx, preq.Req, preq.Body.Bytes(), preq)
+	}
+
 	tags := state.CloneTags()
 	// Override any global tags with request-specific ones.
 	for k, v := range preq.Tags {
@@ -207,8 +223,21 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 	}
 
 	tracerTransport := newTransport(ctx, state, tags, preq.ResponseCallback)
+	tracerTransport.metadata = preq.Metadata
+	tracerTransport.cache = preq.Cache
 	var transport http.RoundTripper = tracerTransport
 
+	// With a totalTimeout, the whole request is bound by it and the regular
+	// timeout is applied to every single redirect and retry instead.
+	requestTimeout := preq.Timeout
+	if preq.TotalTimeout > 0 {
+		requestTimeout = preq.TotalTimeout
+		transport = attemptTimeoutTransport{originalTransport: transport, timeout: preq.Timeout}
+	}
+	if preq.Retries > 0 {
+		transport = retryTransport{originalTransport: transport, retries: preq.Retries}
+	}
+
 	// Combine tags with common log fields
 	combinedLogFields := map[string]interface{}{"source": "http-debug", "vu": state.VUID, "iter": state.Iteration}
 	for k, v := range tags {
@@ -280,9 +309,10 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 		},
 	}
 
-	reqCtx, cancelFunc := context.WithTimeout(ctx, preq.Timeout)
+	reqCtx, cancelFunc := context.WithTimeout(ctx, requestTimeout)
 	defer cancelFunc()
 	mreq := preq.Req.WithContext(reqCtx)
+	startTime := time.Now()
 	res, resErr := client.Do(mreq)
 
 	// TODO(imiric): It would be safer to check for a writeable
@@ -295,12 +325,28 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 	}
 
 	if resErr == nil {
+		// the chunks are an empty array for the responses that aren't chunked, like the trailers
+		resp.Chunks = []ResponseChunk{}
+		if isChunked(res) {
+			res.Body = chunkTimingBody{ReadCloser: res.Body, start: time.Now(), chunks: &resp.Chunks}
+		}
 		resp.Body, resErr = readResponseBody(state, preq.ResponseType, res, resErr)
 		if resErr != nil && errors.Is(resErr, context.DeadlineExceeded) {
 			// TODO This can be more specific that the timeout happened in the middle of the reading of the body
 			resErr = NewK6Error(requestTimeoutErrorCode, requestTimeoutErrorCodeMsg, resErr)
 		}
 	}
+	if preq.TotalTimeout > 0 {
+		consumed := time.Since(startTime)
+		if consumed > preq.TotalTimeout {
+			consumed = preq.TotalTimeout
+		}
+		resp.Budget = ResponseTimeBudget{
+			Total:     stats.D(preq.TotalTimeout),
+			Consumed:  stats.D(consumed),
+			Remaining: stats.D(preq.TotalTimeout - consumed),
+		}
+	}
 	finishedReq := tracerTransport.processLastSavedRequest(wrapDecompressionError(resErr))
 	if finishedReq != nil {
 		updateK6Response(resp, finishedReq)
@@ -327,6 +373,14 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 			resp.Headers[k] = strings.Join(vs, ", ")
 		}
 
+		// Trailers are only available after the whole body has been read
+		resp.Trailers = make(map[string]string, len(res.Trailer))
+		for k, vs := range res.Trailer {
+			if len(vs) > 0 {
+				resp.Trailers[k] = strings.Join(vs, ", ")
+			}
+		}
+
 		resCookies := res.Cookies()
 		resp.Cookies = make(map[string][]*HTTPCookie, len(resCookies))
 		for _, c := range resCookies {
@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
@@ -31,6 +32,9 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -43,6 +47,7 @@ import (
 
 	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/lib/netext"
 	"go.k6.io/k6/stats"
 )
 
@@ -299,6 +304,75 @@ func TestMakeRequestTimeoutInTheMiddle(t *testing.T) {
 	}
 }
 
+func TestMakeRequestErrorClass(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	withoutErrorClass := stats.DefaultSystemTagSet &^ stats.TagErrorClass
+	for name, tc := range map[string]struct {
+		responseCallback func(int) bool
+		systemTags       stats.SystemTagSet
+	}{
+		"no response callback": {nil, stats.DefaultSystemTagSet},
+		"unexpected response":  {func(status int) bool { return status == 200 }, stats.DefaultSystemTagSet},
+		"expected response":    {func(status int) bool { return status == 503 }, stats.DefaultSystemTagSet},
+		"no error_class tag":   {nil, withoutErrorClass},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			samples := make(chan stats.SampleContainer, 10)
+			state := &lib.State{
+				Options: lib.Options{
+					RunTags:    &stats.SampleTags{},
+					SystemTags: &tc.systemTags,
+				},
+				Transport: srv.Client().Transport,
+				Samples:   samples,
+				Logger:    logrus.New(),
+				BPool:     bpool.NewBufferPool(100),
+			}
+			ctx := lib.WithState(context.Background(), state)
+			req, _ := http.NewRequest("GET", srv.URL, nil)
+			_, err := MakeRequest(ctx, &ParsedHTTPRequest{
+				Req:              req,
+				URL:              &URL{u: req.URL, URL: srv.URL},
+				Body:             new(bytes.Buffer),
+				Timeout:          10 * time.Second,
+				ResponseCallback: tc.responseCallback,
+			})
+			require.NoError(t, err)
+
+			all := stats.GetBufferedSamples(samples)
+			require.Len(t, all, 1)
+			var errorSamples []stats.Sample
+			for _, sample := range all[0].GetSamples() {
+				if sample.Metric == metrics.HTTPReqErrors {
+					errorSamples = append(errorSamples, sample)
+				}
+			}
+			if name == "expected response" {
+				require.Empty(t, errorSamples)
+				return
+			}
+			require.Len(t, errorSamples, 1)
+			assert.Equal(t, metrics.HTTPReqErrors, errorSamples[0].Metric)
+			assert.Equal(t, 1.0, errorSamples[0].Value)
+			tags := errorSamples[0].Tags.CloneTags()
+			if name == "no error_class tag" {
+				assert.NotContains(t, tags, "error_class")
+			} else {
+				assert.Equal(t, "http.5xx", tags["error_class"])
+			}
+			assert.Equal(t, "1503", tags["error_code"])
+			assert.Equal(t, srv.URL, tags["name"])
+		})
+	}
+}
+
 func BenchmarkWrapDecompressionError(b *testing.B) {
 	err := errors.New("error")
 	b.ResetTimer()
@@ -490,3 +564,595 @@ func TestMakeRequestTimeoutInTheBegining(t *testing.T) {
 		assert.Equal(t, expTags, s.Tags.CloneTags())
 	}
 }
+
+func TestMakeRequestTotalTimeout(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		if n, _ := strconv.Atoi(r.URL.Query().Get("n")); n > 0 {
+			http.Redirect(w, r, fmt.Sprintf("/?n=%d", n-1), http.StatusFound)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	defer srv.Close()
+
+	makeRequest := func(t *testing.T, timeout, totalTimeout time.Duration) *Response {
+		samples := make(chan stats.SampleContainer, 10)
+		state := &lib.State{
+			Options: lib.Options{
+				RunTags:    &stats.SampleTags{},
+				SystemTags: &stats.DefaultSystemTagSet,
+			},
+			Transport: srv.Client().Transport,
+			Samples:   samples,
+			Logger:    logrus.New(),
+			BPool:     bpool.NewBufferPool(100),
+		}
+		ctx := lib.WithState(context.Background(), state)
+		req, _ := http.NewRequest("GET", srv.URL+"/?n=3", nil)
+		preq := &ParsedHTTPRequest{
+			Req:          req,
+			URL:          &URL{u: req.URL, URL: srv.URL},
+			Body:         new(bytes.Buffer),
+			Redirects:    null.IntFrom(10),
+			Timeout:      timeout,
+			TotalTimeout: totalTimeout,
+			Throw:        true,
+		}
+		res, err := MakeRequest(ctx, preq)
+		if err != nil {
+			assert.Contains(t, err.Error(), "request timeout")
+			return nil
+		}
+		return res
+	}
+
+	t.Run("within budget", func(t *testing.T) {
+		res := makeRequest(t, 150*time.Millisecond, 2*time.Second)
+		require.NotNil(t, res)
+		assert.Equal(t, 200, res.Status)
+		assert.Equal(t, float64(2000), res.Budget.Total)
+		assert.GreaterOrEqual(t, res.Budget.Consumed, float64(200))
+		assert.InDelta(t, res.Budget.Total, res.Budget.Consumed+res.Budget.Remaining, 0.001)
+	})
+	t.Run("budget exceeded", func(t *testing.T) {
+		assert.Nil(t, makeRequest(t, 150*time.Millisecond, 120*time.Millisecond))
+	})
+	t.Run("attempt timeout", func(t *testing.T) {
+		assert.Nil(t, makeRequest(t, 20*time.Millisecond, 2*time.Second))
+	})
+	t.Run("no budget", func(t *testing.T) {
+		res := makeRequest(t, 2*time.Second, 0)
+		require.NotNil(t, res)
+		assert.Equal(t, 200, res.Status)
+		assert.Equal(t, ResponseTimeBudget{}, res.Budget)
+	})
+}
+
+func TestMakeRequestRetries(t *testing.T) {
+	t.Parallel()
+	makeRequest := func(
+		t *testing.T, req *http.Request, failures, retries int64, totalTimeout time.Duration,
+	) (*Response, int64, error) {
+		var attempts int64
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, "payload", string(body))
+			if atomic.AddInt64(&attempts, 1) <= failures {
+				time.Sleep(50 * time.Millisecond)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		samples := make(chan stats.SampleContainer, 50)
+		state := &lib.State{
+			Options: lib.Options{
+				RunTags:    &stats.SampleTags{},
+				SystemTags: &stats.DefaultSystemTagSet,
+			},
+			Transport: srv.Client().Transport,
+			Samples:   samples,
+			Logger:    logrus.New(),
+			BPool:     bpool.NewBufferPool(100),
+		}
+		ctx := lib.WithState(context.Background(), state)
+		req.URL, _ = url.Parse(srv.URL)
+		preq := &ParsedHTTPRequest{
+			Req:          req,
+			URL:          &URL{u: req.URL, URL: srv.URL},
+			Body:         bytes.NewBufferString("payload"),
+			Timeout:      time.Second,
+			TotalTimeout: totalTimeout,
+			Retries:      retries,
+			Throw:        true,
+		}
+		res, err := MakeRequest(ctx, preq)
+		close(samples)
+		var trails int64
+		for sc := range samples {
+			if _, ok := sc.(*Trail); ok {
+				trails++
+			}
+		}
+		assert.Equal(t, atomic.LoadInt64(&attempts), trails, "every attempt is emitted")
+		return res, atomic.LoadInt64(&attempts), err
+	}
+
+	t.Run("retried", func(t *testing.T) {
+		t.Parallel()
+		req, _ := http.NewRequest("PUT", "", bytes.NewBufferString("payload"))
+		res, attempts, err := makeRequest(t, req, 2, 2, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 200, res.Status)
+		assert.Equal(t, int64(3), attempts)
+	})
+	t.Run("out of retries", func(t *testing.T) {
+		t.Parallel()
+		req, _ := http.NewRequest("PUT", "", bytes.NewBufferString("payload"))
+		res, attempts, err := makeRequest(t, req, 2, 1, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 503, res.Status)
+		assert.Equal(t, int64(2), attempts)
+	})
+	t.Run("out of budget", func(t *testing.T) {
+		t.Parallel()
+		req, _ := http.NewRequest("PUT", "", bytes.NewBufferString("payload"))
+		_, attempts, err := makeRequest(t, req, 10, 10, 120*time.Millisecond)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "request timeout")
+		assert.Less(t, attempts, int64(4))
+	})
+	t.Run("not idempotent", func(t *testing.T) {
+		t.Parallel()
+		req, _ := http.NewRequest("POST", "", bytes.NewBufferString("payload"))
+		res, attempts, err := makeRequest(t, req, 2, 2, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 503, res.Status)
+		assert.Equal(t, int64(1), attempts)
+	})
+	t.Run("idempotency key", func(t *testing.T) {
+		t.Parallel()
+		req, _ := http.NewRequest("POST", "", bytes.NewBufferString("payload"))
+		req.Header.Set("Idempotency-Key", "abc")
+		res, attempts, err := makeRequest(t, req, 1, 1, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 200, res.Status)
+		assert.Equal(t, int64(2), attempts)
+	})
+}
+
+func TestRetryBackoff(t *testing.T) {
+	t.Parallel()
+	for attempt, limit := range []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond,
+		1600 * time.Millisecond, 2 * time.Second, 2 * time.Second, 2 * time.Second,
+	} {
+		for i := 0; i < 10; i++ {
+			backoff := retryBackoff(int64(attempt))
+			assert.GreaterOrEqual(t, int64(backoff), int64(limit/2))
+			assert.Less(t, int64(backoff), int64(limit))
+		}
+	}
+}
+
+func TestMakeRequestResponseHeaders(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large" {
+			w.Header().Set("X-Large", strings.Repeat("a", 2048))
+		}
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Header().Set("X-Multi", "a")
+		w.Header().Add("X-Multi", "b")
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte("body"))
+		w.Header().Set("X-Checksum", "abc")
+	}))
+	defer srv.Close()
+
+	transport, ok := srv.Client().Transport.(*http.Transport)
+	require.True(t, ok)
+	transport = transport.Clone()
+	transport.MaxResponseHeaderBytes = 1024
+
+	makeRequest := func(path string) (*Response, []stats.Sample, error) {
+		samples := make(chan stats.SampleContainer, 10)
+		state := &lib.State{
+			Options: lib.Options{
+				RunTags:    &stats.SampleTags{},
+				SystemTags: &stats.DefaultSystemTagSet,
+			},
+			Transport: transport,
+			Samples:   samples,
+			Logger:    logrus.New(),
+			BPool:     bpool.NewBufferPool(100),
+		}
+		ctx := lib.WithState(context.Background(), state)
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		preq := &ParsedHTTPRequest{
+			Req:     req,
+			URL:     &URL{u: req.URL, URL: srv.URL + path},
+			Body:    new(bytes.Buffer),
+			Timeout: 10 * time.Second,
+		}
+		res, err := MakeRequest(ctx, preq)
+		close(samples)
+		var allSamples []stats.Sample
+		for sc := range samples {
+			allSamples = append(allSamples, sc.GetSamples()...)
+		}
+		return res, allSamples, err
+	}
+
+	t.Run("metrics and trailers", func(t *testing.T) {
+		res, allSamples, err := makeRequest("/")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"X-Checksum": "abc"}, res.Trailers)
+		assert.Equal(t, "a, b", res.Headers["X-Multi"])
+
+		var seenBytes, seenCount bool
+		for _, s := range allSamples {
+			switch s.Metric {
+			case metrics.HTTPRespHeaderBytes:
+				seenBytes = true
+				assert.Greater(t, s.Value, 0.0)
+			case metrics.HTTPRespHeaderCount:
+				seenCount = true
+				// X-Multi twice, Content-Type and Date, net/http moves
+				// the Trailer header to the response trailers
+				assert.Equal(t, 4.0, s.Value)
+			}
+		}
+		assert.True(t, seenBytes)
+		assert.True(t, seenCount)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		_, allSamples, err := makeRequest("/large")
+		require.NoError(t, err)
+		for _, s := range allSamples {
+			assert.NotEqual(t, metrics.HTTPRespHeaderBytes, s.Metric)
+			if s.Metric == metrics.HTTPReqs {
+				errorCode, ok := s.Tags.Get("error_code")
+				assert.True(t, ok)
+				assert.Equal(t, "1702", errorCode)
+			}
+		}
+	})
+}
+
+func TestMakeRequestChunked(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reads []string
+		buf := make([]byte, 1024)
+		for {
+			n, err := r.Body.Read(buf)
+			if n > 0 {
+				reads = append(reads, string(buf[:n]))
+			}
+			if err != nil {
+				break
+			}
+		}
+		w.Header().Set("X-Transfer-Encoding", strings.Join(r.TransferEncoding, ","))
+		w.Header().Set("X-Reads", strings.Join(reads, "|"))
+		w.Header().Set("X-Trailer", r.Trailer.Get("X-Checksum"))
+		flusher, ok := w.(http.Flusher)
+		require.True(t, ok)
+		for _, part := range []string{"first", "second", "third"} {
+			_, _ = w.Write([]byte(part))
+			flusher.Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	state := &lib.State{
+		Options: lib.Options{
+			RunTags:    &stats.SampleTags{},
+			SystemTags: &stats.DefaultSystemTagSet,
+		},
+		Transport: srv.Client().Transport,
+		Samples:   make(chan stats.SampleContainer, 10),
+		Logger:    logrus.New(),
+		BPool:     bpool.NewBufferPool(100),
+	}
+	ctx := lib.WithState(context.Background(), state)
+	req, _ := http.NewRequest("POST", srv.URL, nil)
+	preq := &ParsedHTTPRequest{
+		Req:           req,
+		URL:           &URL{u: req.URL, URL: srv.URL},
+		Body:          bytes.NewBufferString("aaabbbcc"),
+		Timeout:       10 * time.Second,
+		ChunkSize:     3,
+		ChunkInterval: 20 * time.Millisecond,
+		Trailers:      map[string]string{"X-Checksum": "abc"},
+	}
+	start := time.Now()
+	res, err := MakeRequest(ctx, preq)
+	require.NoError(t, err)
+	assert.Greater(t, int64(time.Since(start)), int64(40*time.Millisecond))
+	assert.Equal(t, "chunked", res.Headers["X-Transfer-Encoding"])
+	assert.Equal(t, "aaa|bbb|cc", res.Headers["X-Reads"])
+	assert.Equal(t, "abc", res.Headers["X-Trailer"])
+	assert.Equal(t, "firstsecondthird", res.Body)
+
+	require.Len(t, res.Chunks, 3)
+	for i, size := range []int{5, 6, 5} {
+		assert.Equal(t, size, res.Chunks[i].Size)
+		if i > 0 {
+			assert.Greater(t, res.Chunks[i].Time, res.Chunks[i-1].Time+10)
+		}
+	}
+}
+
+func TestMakeRequestCache(t *testing.T) {
+	t.Parallel()
+	var conditional []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditional = append(conditional, r.Header.Get("If-None-Match")+"|"+r.Header.Get("If-Modified-Since"))
+		if r.URL.Path == "/no-store" {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		w.Header().Set("Content-Type", "text/plain")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("cached body"))
+	}))
+	defer srv.Close()
+
+	samples := make(chan stats.SampleContainer, 10)
+	state := &lib.State{
+		Options: lib.Options{
+			RunTags:    &stats.SampleTags{},
+			SystemTags: &stats.DefaultSystemTagSet,
+		},
+		Transport: srv.Client().Transport,
+		Samples:   samples,
+		Logger:    logrus.New(),
+		BPool:     bpool.NewBufferPool(100),
+	}
+	ctx := lib.WithState(context.Background(), state)
+	cache := NewCache()
+	get := func(path string) (*Response, float64) {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		preq := &ParsedHTTPRequest{
+			Req:          req,
+			URL:          &URL{u: req.URL, URL: srv.URL + path},
+			Timeout:      10 * time.Second,
+			ResponseType: ResponseTypeText,
+			Cache:        cache,
+		}
+		res, err := MakeRequest(ctx, preq)
+		require.NoError(t, err)
+		hits := -1.0
+		for _, sample := range (<-samples).GetSamples() {
+			if sample.Metric == metrics.HTTPCacheHits {
+				hits = sample.Value
+			}
+		}
+		return res, hits
+	}
+
+	res, hits := get("/")
+	assert.Equal(t, 200, res.Status)
+	assert.Equal(t, float64(0), hits)
+	assert.Equal(t, 1, cache.Len())
+
+	res, hits = get("/")
+	assert.Equal(t, http.StatusNotModified, res.Status)
+	assert.Equal(t, "cached body", res.Body)
+	assert.Equal(t, "text/plain", res.Headers["Content-Type"])
+	assert.Equal(t, float64(1), hits)
+
+	_, hits = get("/no-store")
+	assert.Equal(t, float64(0), hits)
+	_, hits = get("/no-store")
+	assert.Equal(t, float64(0), hits)
+	assert.Equal(t, 1, cache.Len())
+
+	cache.Clear()
+	res, hits = get("/")
+	assert.Equal(t, 200, res.Status)
+	assert.Equal(t, float64(0), hits)
+	assert.Equal(t, []string{
+		"|", `"v1"|Wed, 21 Oct 2015 07:28:00 GMT`, "|", "|", "|",
+	}, conditional)
+}
+
+func TestCacheEvictionAndVary(t *testing.T) {
+	t.Parallel()
+	cache := NewCache()
+	cache.maxSize = 10
+	cache.set(&cacheEntry{key: "/a", etag: `"a"`, body: []byte("aaaa")})
+	cache.set(&cacheEntry{key: "/b", etag: `"b"`, body: []byte("bbbb")})
+	require.NotNil(t, cache.get("/a"))
+	cache.set(&cacheEntry{key: "/c", etag: `"c"`, body: []byte("cccc")})
+	assert.Equal(t, 2, cache.Len())
+	assert.Nil(t, cache.get("/b"))
+	cache.set(&cacheEntry{key: "/d", etag: `"d"`, body: []byte("too big to be cached")})
+	assert.Nil(t, cache.get("/d"))
+	assert.Equal(t, 2, cache.Len())
+
+	req, err := http.NewRequest("GET", "http://example.com/", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Language", "en")
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Etag": {`"v1"`}, "Vary": {"accept-language"}},
+		Body:       ioutil.NopCloser(strings.NewReader("hello")),
+	}
+	require.False(t, cache.handleResponse(req, resp, nil))
+	_, err = ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	condReq, entry := cache.conditionalRequest(req)
+	require.NotNil(t, entry)
+	assert.Equal(t, `"v1"`, condReq.Header.Get("If-None-Match"))
+
+	req.Header.Set("Accept-Language", "de")
+	condReq, entry = cache.conditionalRequest(req)
+	assert.Nil(t, entry)
+	assert.Empty(t, condReq.Header.Get("If-None-Match"))
+}
+
+func TestMakePageLoadRequests(t *testing.T) {
+	t.Parallel()
+	var inFlight, maxInFlight int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			m := atomic.LoadInt64(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt64(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer srv.Close()
+
+	state := &lib.State{
+		Options: lib.Options{
+			RunTags:    &stats.SampleTags{},
+			SystemTags: &stats.DefaultSystemTagSet,
+		},
+		Transport: srv.Client().Transport,
+		Samples:   make(chan stats.SampleContainer, 10),
+		Logger:    logrus.New(),
+		BPool:     bpool.NewBufferPool(100),
+	}
+	ctx := lib.WithState(context.Background(), state)
+	newRequests := func(deps ...[]int) []PageLoadRequest {
+		requests := make([]PageLoadRequest, len(deps))
+		for i := range deps {
+			u := fmt.Sprintf("%s/%d", srv.URL, i)
+			req, _ := http.NewRequest("GET", u, nil)
+			requests[i] = PageLoadRequest{
+				BatchParsedHTTPRequest: BatchParsedHTTPRequest{
+					ParsedHTTPRequest: &ParsedHTTPRequest{
+						Req: req, URL: &URL{u: req.URL, URL: u}, Timeout: 10 * time.Second, ResponseType: ResponseTypeText,
+					},
+					Response: new(Response),
+				},
+				DependsOn: deps[i],
+			}
+		}
+		return requests
+	}
+	processResponse := func(context.Context, *Response, ResponseType) {}
+
+	// the document, then three resources with at most two in flight, then one that needs two of them
+	requests := newRequests(nil, []int{0}, []int{0}, []int{0}, []int{1, 2})
+	duration, err := MakePageLoadRequests(ctx, requests, 2, 2, processResponse)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&maxInFlight))
+	assert.Greater(t, int64(duration), int64(60*time.Millisecond))
+	for i, req := range requests {
+		assert.Equal(t, fmt.Sprintf("/%d", i), req.Response.Body)
+		for _, dep := range req.DependsOn {
+			assert.GreaterOrEqual(t, int64(req.Start), int64(requests[dep].End))
+		}
+	}
+
+	_, err = MakePageLoadRequests(ctx, newRequests([]int{1}, []int{0}), 0, 0, processResponse)
+	assert.Equal(t, ErrPageLoadCycle, err)
+	_, err = MakePageLoadRequests(ctx, newRequests(nil, []int{1}), 0, 0, processResponse)
+	assert.Equal(t, ErrPageLoadCycle, err)
+	_, err = MakePageLoadRequests(ctx, newRequests([]int{2}), 0, 0, processResponse)
+	assert.EqualError(t, err, "invalid dependency 2 of the request 0 of the page load")
+}
+
+func TestMakeRequestConnPool(t *testing.T) {
+	t.Parallel()
+	var closeRequests int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Close {
+			atomic.AddInt64(&closeRequests, 1)
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	dialer := netext.NewDialer(net.Dialer{}, nil)
+	transport := &http.Transport{DialContext: dialer.DialContext}
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	makeRequest := func(poolMetrics, noConnectionReuse bool) map[*stats.Metric]float64 {
+		samples := make(chan stats.SampleContainer, 10)
+		state := &lib.State{
+			Options: lib.Options{
+				RunTags:             &stats.SampleTags{},
+				SystemTags:          &stats.DefaultSystemTagSet,
+				HTTPConnPoolMetrics: null.BoolFrom(poolMetrics),
+			},
+			Transport:         transport,
+			Dialer:            dialer,
+			Samples:           samples,
+			Logger:            logrus.New(),
+			BPool:             bpool.NewBufferPool(100),
+			NoConnectionReuse: noConnectionReuse,
+		}
+		ctx := lib.WithState(context.Background(), state)
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		preq := &ParsedHTTPRequest{
+			Req:     req,
+			URL:     &URL{u: req.URL, URL: srv.URL},
+			Body:    new(bytes.Buffer),
+			Timeout: 10 * time.Second,
+		}
+		_, err := MakeRequest(ctx, preq)
+		require.NoError(t, err)
+		close(samples)
+		values := make(map[*stats.Metric]float64)
+		for sc := range samples {
+			for _, s := range sc.GetSamples() {
+				values[s.Metric] = s.Value
+			}
+		}
+		return values
+	}
+
+	values := makeRequest(false, false)
+	assert.NotContains(t, values, metrics.HTTPConnsOpen)
+
+	for i := 0; i < 2; i++ {
+		values = makeRequest(true, false)
+		assert.Equal(t, 1.0, values[metrics.HTTPConnsOpen])
+		assert.Equal(t, 0.0, values[metrics.HTTPConnsIdle])
+		assert.Contains(t, values, metrics.HTTPReqConnWait)
+	}
+	open, idle := dialer.ConnPoolStats(addr)
+	assert.Equal(t, int64(1), open)
+	assert.Equal(t, int64(1), idle)
+	assert.Equal(t, int64(0), atomic.LoadInt64(&closeRequests))
+
+	makeRequest(true, true)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&closeRequests))
+}
+
+func TestConnPoolAddr(t *testing.T) {
+	t.Parallel()
+	proxyURL, err := url.Parse("http://proxy.example.com:3128")
+	require.NoError(t, err)
+	newTransport := func(proxy func(*http.Request) (*url.URL, error)) *transport {
+		return &transport{state: &lib.State{Transport: &http.Transport{Proxy: proxy}}}
+	}
+	req, err := http.NewRequest("GET", "https://example.com/path", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "example.com:443", newTransport(nil).connPoolAddr(req))
+	// the connections are dialed to the proxy
+	assert.Equal(t, "proxy.example.com:3128", newTransport(http.ProxyURL(proxyURL)).connPoolAddr(req))
+}
@@ -64,6 +64,15 @@ type ResponseTimings struct {
 	Receiving      float64 `json:"receiving"`
 }
 
+// ResponseTimeBudget describes how much of the totalTimeout of a request, in
+// milliseconds, was consumed by all of its redirects and retries. All of the
+// values are 0 if the request didn't have a totalTimeout.
+type ResponseTimeBudget struct {
+	Total     float64 `json:"total"`
+	Consumed  float64 `json:"consumed"`
+	Remaining float64 `json:"remaining"`
+}
+
 // HTTPCookie is a representation of an http cookies used in the Response object
 type HTTPCookie struct {
 	Name, Value, Domain, Path string
@@ -83,9 +92,12 @@ type Response struct {
 	StatusText     string                   `json:"status_text"`
 	Proto          string                   `json:"proto"`
 	Headers        map[string]string        `json:"headers"`
+	Trailers       map[string]string        `json:"trailers"`
+	Chunks         []ResponseChunk          `json:"chunks"`
 	Cookies        map[string][]*HTTPCookie `json:"cookies"`
 	Body           interface{}              `json:"body"`
 	Timings        ResponseTimings          `json:"timings"`
+	Budget         ResponseTimeBudget       `json:"budget"`
 	TLSVersion     string                   `json:"tls_version"`
 	TLSCipherSuite string                   `json:"tls_cipher_suite"`
 	OCSP           netext.OCSP              `json:"ocsp"`
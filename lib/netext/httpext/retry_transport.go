@@ -0,0 +1,119 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpext
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+const (
+	retryBackoffBase = 100 * time.Millisecond
+	retryBackoffMax  = 2 * time.Second
+)
+
+// retryTransport retries the round trips of the idempotent requests that
+// failed without a response or with a 5xx one, up to the given number of
+// times, with a jittered exponential backoff between the attempts. Like in
+// net/http, the requests with other methods are only retried if they have an
+// Idempotency-Key or X-Idempotency-Key header. It's below the redirects, so
+// every redirect is retried on its own, and the retries stop when the context
+// of the whole logical request is done, e.g. when its totalTimeout is
+// consumed. Every attempt is measured and emitted as a separate request.
+type retryTransport struct {
+	originalTransport http.RoundTripper
+	retries           int64
+}
+
+// RoundTrip makes the request and retries it while it fails, the response of
+// the last attempt is returned.
+func (t retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isIdempotent(req) {
+		return t.originalTransport.RoundTrip(req)
+	}
+	for attempt := int64(0); ; attempt++ {
+		resp, err := t.originalTransport.RoundTrip(req)
+		if attempt >= t.retries || req.Context().Err() != nil || !shouldRetry(resp, err) {
+			return resp, err
+		}
+		// the body of the request has to be sent again, which isn't possible
+		// if it can't be rewound, like with the paced chunked bodies
+		var body io.ReadCloser
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			var bodyErr error
+			if body, bodyErr = req.GetBody(); bodyErr != nil {
+				return resp, err
+			}
+		}
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		timer := time.NewTimer(retryBackoff(attempt))
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			if body != nil {
+				_ = body.Close()
+			}
+			// the budget of the request was consumed while waiting
+			return nil, NewK6Error(requestTimeoutErrorCode, requestTimeoutErrorCodeMsg, req.Context().Err())
+		case <-timer.C:
+		}
+		if body != nil {
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// retryBackoff returns how long to wait before the retry after the given
+// attempt, a random duration between the half and the whole of an exponential
+// backoff, so the VUs that fail at the same time don't retry at the same time.
+func retryBackoff(attempt int64) time.Duration {
+	backoff := retryBackoffMax
+	if attempt < 5 {
+		backoff = retryBackoffBase << uint(attempt)
+		if backoff > retryBackoffMax {
+			backoff = retryBackoffMax
+		}
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2))) //nolint:gosec
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= 500
+}
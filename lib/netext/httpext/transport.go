@@ -41,7 +41,10 @@ type transport struct {
 	ctx              context.Context
 	state            *lib.State
 	tags             map[string]string
+	metadata         map[string]string
 	responseCallback func(int) bool
+	// The cache of the VU, the responses aren't cached if it's nil.
+	cache *Cache
 
 	lastRequest     *unfinishedRequest
 	lastRequestLock *sync.Mutex
@@ -55,6 +58,21 @@ type unfinishedRequest struct {
 	request  *http.Request
 	response *http.Response
 	err      error
+
+	// the address of the connection pool and the end of the request's use of it, only with
+	// the httpConnPoolMetrics option
+	poolAddr string
+	poolDone func()
+
+	// whether the request could be served from the cache and whether it was
+	cacheable, cacheHit bool
+}
+
+// connPoolTracker is implemented by the dialers that keep track of their open connections and
+// the requests that use them, like netext.Dialer, for the connection pool metrics.
+type connPoolTracker interface {
+	TrackRequest(addr string) (done func())
+	ConnPoolStats(addr string) (open, idle int64)
 }
 
 // finishedRequest is produced once the request has been finalized; it is
@@ -119,6 +137,9 @@ func (t *transport) measureAndEmitMetrics(unfReq *unfinishedRequest) *finishedRe
 		}
 		if setName {
 			tags["name"] = cleanURL
+			if name, ok := t.state.Options.URLGroups.Group(unfReq.request.URL, cleanURL); ok {
+				tags["name"] = name
+			}
 		}
 	}
 
@@ -186,6 +207,7 @@ func (t *transport) measureAndEmitMetrics(unfReq *unfinishedRequest) *finishedRe
 	}
 
 	finalTags := stats.IntoSampleTags(&tags)
+	trail.Metadata = t.metadata
 	trail.SaveSamples(finalTags)
 	if t.responseCallback != nil {
 		trail.Failed.Valid = true
@@ -195,14 +217,107 @@ func (t *transport) measureAndEmitMetrics(unfReq *unfinishedRequest) *finishedRe
 		trail.Samples = append(trail.Samples,
 			stats.Sample{
 				Metric: metrics.HTTPReqFailed, Time: trail.EndTime, Tags: finalTags, Value: failed,
+				Metadata: t.metadata,
 			},
 		)
 	}
+	if unfReq.err == nil {
+		headerBytes, headerCount := headerSize(unfReq.response.Header)
+		trail.Samples = append(trail.Samples,
+			stats.Sample{
+				Metric: metrics.HTTPRespHeaderBytes, Time: trail.EndTime, Tags: finalTags, Value: float64(headerBytes),
+				Metadata: t.metadata,
+			},
+			stats.Sample{
+				Metric: metrics.HTTPRespHeaderCount, Time: trail.EndTime, Tags: finalTags, Value: float64(headerCount),
+				Metadata: t.metadata,
+			},
+		)
+	}
+	if unfReq.poolDone != nil {
+		trail.Samples = append(trail.Samples, t.connPoolSamples(unfReq, trail, finalTags)...)
+	}
+	if unfReq.cacheable && unfReq.err == nil {
+		var hit float64
+		if unfReq.cacheHit {
+			hit = 1
+		}
+		trail.Samples = append(trail.Samples, stats.Sample{
+			Metric: metrics.HTTPCacheHits, Time: trail.EndTime, Tags: finalTags, Value: hit, Metadata: t.metadata,
+		})
+	}
+	// the error classes are only in the tags of http_req_errors, so they don't split the time
+	// series of the other metrics, and like for http_req_failed the expected responses of the
+	// responseCallback aren't errors; the sample is in the trail, like the one of http_req_failed,
+	// so that each request still emits a single sample container
+	code := result.errorCode
+	if unfReq.err == nil && unfReq.response.StatusCode >= 400 {
+		code = errCode(1000 + unfReq.response.StatusCode)
+	}
+	if code != 0 && (t.responseCallback == nil || failed == 1) {
+		errorTags := finalTags.CloneTags()
+		if enabledTags.Has(stats.TagErrorClass) {
+			errorTags["error_class"] = errorClass(code)
+		}
+		trail.Samples = append(trail.Samples, stats.Sample{
+			Metric: metrics.HTTPReqErrors, Time: trail.EndTime, Tags: stats.IntoSampleTags(&errorTags), Value: 1,
+			Metadata: t.metadata,
+		})
+	}
 	stats.PushIfNotDone(t.ctx, t.state.Samples, trail)
 
 	return result
 }
 
+// connPoolSamples returns the samples of the connection pool of the request's host, while the
+// request still counts as using one of its connections, and ends its use of it.
+func (t *transport) connPoolSamples(unfReq *unfinishedRequest, trail *Trail, tags *stats.SampleTags) []stats.Sample {
+	defer unfReq.poolDone()
+	open, idle := t.state.Dialer.(connPoolTracker).ConnPoolStats(unfReq.poolAddr)
+
+	// the part of the blocked time that wasn't spent establishing a new connection
+	wait := trail.Blocked - trail.Connecting - trail.TLSHandshaking
+	if wait < 0 {
+		wait = 0
+	}
+	return []stats.Sample{
+		{Metric: metrics.HTTPReqConnWait, Time: trail.EndTime, Tags: tags, Value: stats.D(wait), Metadata: t.metadata},
+		{Metric: metrics.HTTPConnsOpen, Time: trail.EndTime, Tags: tags, Value: float64(open), Metadata: t.metadata},
+		{Metric: metrics.HTTPConnsIdle, Time: trail.EndTime, Tags: tags, Value: float64(idle), Metadata: t.metadata},
+	}
+}
+
+// connPoolAddr returns the "host:port" address that the transport dials for the connections of
+// the request, the one of the proxy if the request goes through one.
+func (t *transport) connPoolAddr(req *http.Request) string {
+	u := req.URL
+	if httpTransport, ok := t.state.Transport.(*http.Transport); ok && httpTransport.Proxy != nil {
+		if proxyURL, err := httpTransport.Proxy(req); err == nil && proxyURL != nil {
+			u = proxyURL
+		}
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// headerSize returns the size of the headers in their HTTP/1.1 wire format,
+// i.e. "Key: value\r\n" for every value, and the number of header lines.
+func headerSize(header http.Header) (size, count int) {
+	for k, vs := range header {
+		for _, v := range vs {
+			size += len(k) + len(v) + 4
+			count++
+		}
+	}
+	return size, count
+}
+
 func (t *transport) saveCurrentRequest(currentRequest *unfinishedRequest) {
 	t.lastRequestLock.Lock()
 	unprocessedRequest := t.lastRequest
@@ -239,9 +354,25 @@ func (t *transport) processLastSavedRequest(lastErr error) *finishedRequest {
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	t.processLastSavedRequest(nil)
 
+	var cacheEntry *cacheEntry
+	cacheable := t.cache != nil && isCacheable(req)
+	if cacheable {
+		req, cacheEntry = t.cache.conditionalRequest(req)
+	}
+
 	ctx := req.Context()
 	tracer := &Tracer{}
 	reqWithTracer := req.WithContext(httptrace.WithClientTrace(ctx, tracer.Trace()))
+	if t.state.NoConnectionReuse {
+		reqWithTracer.Close = true
+	}
+
+	var poolAddr string
+	var poolDone func()
+	if tracker, ok := t.state.Dialer.(connPoolTracker); ok && t.state.Options.HTTPConnPoolMetrics.Bool {
+		poolAddr = t.connPoolAddr(req)
+		poolDone = tracker.TrackRequest(poolAddr)
+	}
 	resp, err := t.state.Transport.RoundTrip(reqWithTracer)
 
 	var netError net.Error
@@ -252,6 +383,13 @@ func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		} else {
 			err = NewK6Error(requestTimeoutErrorCode, requestTimeoutErrorCodeMsg, netError)
 		}
+	} else if isResponseHeadersTooLargeError(err) {
+		err = NewK6Error(responseHeadersTooLargeErrorCode, responseHeadersTooLargeErrorCodeMsg, err)
+	}
+
+	var cacheHit bool
+	if cacheable && err == nil {
+		cacheHit = t.cache.handleResponse(req, resp, cacheEntry)
 	}
 
 	t.saveCurrentRequest(&unfinishedRequest{
@@ -260,6 +398,11 @@ func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		request:  req,
 		response: resp,
 		err:      err,
+		poolAddr: poolAddr,
+		poolDone: poolDone,
+
+		cacheable: cacheable,
+		cacheHit:  cacheHit,
 	})
 
 	return resp, err
@@ -0,0 +1,149 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"go.k6.io/k6/lib"
+)
+
+// minRetransmissionTimeout is the minimum delay of the simulated packet losses, the minimum
+// TCP retransmission timeout of Linux.
+const minRetransmissionTimeout = 200 * time.Millisecond
+
+// networkShaper shapes the traffic of the connections of a dialer, the rates are limited with
+// token buckets that are shared by all the connections, like the bandwidth of a network.
+type networkShaper struct {
+	download, upload *rate.Limiter
+	latency          time.Duration
+	packetLoss       float64
+}
+
+func newNetworkShaper(s *lib.NetworkShaping) *networkShaper {
+	if s == nil {
+		return nil
+	}
+	shaper := &networkShaper{
+		download:   newRateLimiter(s.DownloadKbps.Int64),
+		upload:     newRateLimiter(s.UploadKbps.Int64),
+		latency:    time.Duration(s.Latency.Duration),
+		packetLoss: s.PacketLoss.Float64,
+	}
+	if shaper.download == nil && shaper.upload == nil && shaper.latency <= 0 && shaper.packetLoss <= 0 {
+		return nil
+	}
+	return shaper
+}
+
+// newRateLimiter returns the limiter of the kilobits per second, it allows bursts of 50ms of
+// data, so the traffic is smooth but the reads and writes aren't too small.
+func newRateLimiter(kbps int64) *rate.Limiter {
+	if kbps <= 0 {
+		return nil
+	}
+	bytesPerSec := kbps * 1000 / 8
+	burst := bytesPerSec / 20
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSec), int(burst))
+}
+
+// chunk returns the part of the buffer that can be read or written at once with the limiter.
+func chunk(b []byte, limiter *rate.Limiter) []byte {
+	if limiter != nil && len(b) > limiter.Burst() {
+		return b[:limiter.Burst()]
+	}
+	return b
+}
+
+// delay waits for the rate limit of the bytes and for the simulated packet loss.
+func (c *Conn) delay(s *networkShaper, limiter *rate.Limiter, n int) {
+	if limiter != nil && n > 0 {
+		_ = limiter.WaitN(c.shapingContext(), n)
+	}
+	if s.packetLoss > 0 && rand.Float64() < s.packetLoss { //nolint:gosec
+		rto := 2 * s.latency
+		if rto < minRetransmissionTimeout {
+			rto = minRetransmissionTimeout
+		}
+		c.sleep(rto)
+	}
+}
+
+// shapingContext returns the context of the waits of the shaping. The connections outlive the
+// requests that dial them, so the waits end when the connection is closed, like the HTTP
+// transport does when the context of the request using it is done.
+func (c *Conn) shapingContext() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+func (c *Conn) sleep(d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-c.shapingContext().Done():
+	}
+}
+
+// Connection directions, for adding the latency when a connection starts reading after writing,
+// i.e. once for each round trip.
+const (
+	dirNone int32 = iota
+	dirRead
+	dirWrite
+)
+
+func (c *Conn) shapedRead(s *networkShaper, b []byte) (int, error) {
+	n, err := c.Conn.Read(chunk(b, s.download))
+	if n > 0 {
+		if atomic.SwapInt32(&c.direction, dirRead) == dirWrite && s.latency > 0 {
+			c.sleep(s.latency)
+		}
+		c.delay(s, s.download, n)
+	}
+	return n, err
+}
+
+func (c *Conn) shapedWrite(s *networkShaper, b []byte) (int, error) {
+	atomic.StoreInt32(&c.direction, dirWrite)
+	var written int
+	for written < len(b) {
+		part := chunk(b[written:], s.upload)
+		c.delay(s, s.upload, len(part))
+		n, err := c.Conn.Write(part)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,112 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/types"
+)
+
+func TestNetworkShaping(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	data := make([]byte, 10000)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				buf := make([]byte, 1)
+				if _, err := io.ReadFull(conn, buf); err == nil {
+					_, _ = conn.Write(data)
+				}
+			}()
+		}
+	}()
+
+	roundTrip := func(t *testing.T, shaping *lib.NetworkShaping) time.Duration {
+		dialer := NewDialer(net.Dialer{}, nil)
+		dialer.SetNetworkShaping(shaping)
+		start := time.Now()
+		conn, err := dialer.DialContext(context.Background(), "tcp", listener.Addr().String())
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+		_, err = conn.Write([]byte{1})
+		require.NoError(t, err)
+		received, err := ioutil.ReadAll(conn)
+		require.NoError(t, err)
+		assert.Len(t, received, len(data))
+		assert.Equal(t, int64(len(data)), dialer.BytesRead)
+		return time.Since(start)
+	}
+
+	t.Run("none", func(t *testing.T) {
+		t.Parallel()
+		assert.Less(t, int64(roundTrip(t, nil)), int64(200*time.Millisecond))
+		assert.Nil(t, newNetworkShaper(&lib.NetworkShaping{}))
+	})
+	t.Run("download", func(t *testing.T) {
+		t.Parallel()
+		// 10KB at 20KB/s, with the first 50ms burst
+		took := roundTrip(t, &lib.NetworkShaping{DownloadKbps: null.IntFrom(160)})
+		assert.GreaterOrEqual(t, int64(took), int64(400*time.Millisecond))
+	})
+	t.Run("latency", func(t *testing.T) {
+		t.Parallel()
+		// the TCP handshake and the request-response round trip
+		took := roundTrip(t, &lib.NetworkShaping{Latency: types.NullDurationFrom(100 * time.Millisecond)})
+		assert.GreaterOrEqual(t, int64(took), int64(200*time.Millisecond))
+	})
+	t.Run("packet loss", func(t *testing.T) {
+		t.Parallel()
+		took := roundTrip(t, &lib.NetworkShaping{PacketLoss: null.FloatFrom(1)})
+		assert.GreaterOrEqual(t, int64(took), int64(2*minRetransmissionTimeout))
+	})
+	t.Run("closed", func(t *testing.T) {
+		t.Parallel()
+		dialer := NewDialer(net.Dialer{}, nil)
+		dialer.SetNetworkShaping(&lib.NetworkShaping{UploadKbps: null.IntFrom(8)})
+		conn, err := dialer.DialContext(context.Background(), "tcp", listener.Addr().String())
+		require.NoError(t, err)
+		// the writes that wait for the rate limit end when the connection is closed
+		time.AfterFunc(100*time.Millisecond, func() { _ = conn.Close() })
+		start := time.Now()
+		_, _ = conn.Write(make([]byte, 1000))
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	})
+}
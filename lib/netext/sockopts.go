@@ -0,0 +1,53 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"syscall"
+)
+
+// SocketOptions are the options of the sockets of the connections, they're set before the
+// connections are established.
+type SocketOptions struct {
+	// The Differentiated Services Code Point of the IP packets, from 0 to 63, the traffic
+	// class of IPv6 packets, it isn't set if it's 0.
+	DSCP int
+	// Set SO_REUSEPORT, so several sockets can be bound to the same local address and port.
+	ReusePort bool
+}
+
+// Control returns the function for net.Dialer.Control that sets the options, or nil if there
+// isn't any option to set.
+func (o SocketOptions) Control() func(network, address string, c syscall.RawConn) error {
+	if o.DSCP == 0 && !o.ReusePort {
+		return nil
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = setSocketOptions(fd, network, o)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"fmt"
+
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+)
+
+// NetworkShaping is the shaping of the network traffic of the VUs of a scenario, for simulating
+// the users of slower networks, like the mobile ones, alongside the other users.
+type NetworkShaping struct {
+	// The rate caps of the received and sent data, in kilobits per second.
+	DownloadKbps null.Int `json:"downloadKbps"`
+	UploadKbps   null.Int `json:"uploadKbps"`
+
+	// The latency that is added to each round trip of the connections.
+	Latency types.NullDuration `json:"latency"`
+
+	// The probability, from 0 to 1, that a read or write of the connections is delayed as if
+	// its packets were lost and retransmitted.
+	PacketLoss null.Float `json:"packetLoss"`
+}
+
+// Validate checks that the rates and the latency aren't negative and the packet loss is a
+// probability.
+func (n NetworkShaping) Validate() (errors []error) {
+	if n.DownloadKbps.Int64 < 0 {
+		errors = append(errors, fmt.Errorf("the network downloadKbps can't be negative"))
+	}
+	if n.UploadKbps.Int64 < 0 {
+		errors = append(errors, fmt.Errorf("the network uploadKbps can't be negative"))
+	}
+	if n.Latency.Duration < 0 {
+		errors = append(errors, fmt.Errorf("the network latency can't be negative"))
+	}
+	if n.PacketLoss.Float64 < 0 || n.PacketLoss.Float64 > 1 {
+		errors = append(errors, fmt.Errorf("the network packetLoss must be between 0 and 1"))
+	}
+	return errors
+}
@@ -0,0 +1,47 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+)
+
+func TestNetworkShapingValidate(t *testing.T) {
+	t.Parallel()
+	assert.Empty(t, NetworkShaping{
+		DownloadKbps: null.IntFrom(750),
+		UploadKbps:   null.IntFrom(250),
+		Latency:      types.NullDurationFrom(300 * time.Millisecond),
+		PacketLoss:   null.FloatFrom(0.01),
+	}.Validate())
+	assert.Len(t, NetworkShaping{
+		DownloadKbps: null.IntFrom(-1),
+		UploadKbps:   null.IntFrom(-1),
+		Latency:      types.NullDurationFrom(-time.Second),
+		PacketLoss:   null.FloatFrom(1.5),
+	}.Validate(), 4)
+}
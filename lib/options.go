@@ -23,9 +23,11 @@ package lib
 import (
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"reflect"
+	"regexp"
 	"strconv"
 
 	"go.k6.io/k6/lib/types"
@@ -275,6 +277,62 @@ func ParseCIDR(s string) (*IPNet, error) {
 	return &parsedIPNet, nil
 }
 
+// AutoSubmetrics configures the sub-metrics that are created automatically for each value of
+// some tags, like a http_req_duration{name:...} sub-metric of each URL, so the summary has their
+// values without declaring a threshold for each of them.
+type AutoSubmetrics struct {
+	// The metrics with sub-metrics, http_req_duration by default.
+	Metrics []string `json:"metrics"`
+	// The tags with a sub-metric for each of their values, e.g. "name" or "group".
+	Tags []string `json:"tags"`
+	// Patterns of the tag values with sub-metrics, all of them by default, and of the tag values
+	// without them, which take precedence.
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+	// The thresholds of all the sub-metrics.
+	Thresholds []string `json:"thresholds"`
+	// The maximum number of sub-metrics of a metric and a tag, 100 by default, the values after
+	// it don't have a sub-metric.
+	Limit null.Int `json:"limit"`
+}
+
+// Validate returns the errors of the patterns and the thresholds.
+func (as AutoSubmetrics) Validate() []error {
+	var errs []error
+	for _, pattern := range append(append([]string{}, as.Include...), as.Exclude...) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("invalid autoSubmetrics pattern '%s': %w", pattern, err))
+		}
+	}
+	if _, err := stats.NewThresholds(as.Thresholds); err != nil {
+		errs = append(errs, fmt.Errorf("invalid autoSubmetrics thresholds: %w", err))
+	}
+	if len(as.Tags) == 0 {
+		errs = append(errs, errors.New("the autoSubmetrics option has to have at least one tag"))
+	}
+	if as.Limit.Valid && as.Limit.Int64 < 1 {
+		errs = append(errs, fmt.Errorf("the autoSubmetrics limit has to be positive, got %d", as.Limit.Int64))
+	}
+	return errs
+}
+
+// MetricsFilter configures the metrics whose samples are sent to the outputs, they are still in
+// the end-of-test summary and their thresholds are still evaluated.
+type MetricsFilter struct {
+	// Glob patterns of the metrics that are sent, all of them by default, and of the metrics that
+	// aren't sent, which take precedence.
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+}
+
+// Validate returns the error of the first invalid pattern.
+func (mf MetricsFilter) Validate() []error {
+	if err := ValidateMetricPatterns(mf.Include, mf.Exclude); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
 type Options struct {
 	// Should the test start in a paused state?
 	Paused null.Bool `json:"paused" envconfig:"K6_PAUSED"`
@@ -305,12 +363,28 @@ type Options struct {
 	// Limit HTTP requests per second.
 	RPS null.Int `json:"rps" envconfig:"K6_RPS"`
 
+	// Directory in which scripts can write files with k6/files, writing files
+	// is disabled if it's not set. Like ConsoleOutput, it can't be set from the
+	// script, so scripts can't write outside of the directory they were given.
+	FilesDir null.String `json:"-" envconfig:"K6_FILES_DIR"`
+
+	// Limit the bytes written per second with k6/files.
+	FilesRate null.Int `json:"filesRate" envconfig:"K6_FILES_RATE"`
+
+	// Sources of the secrets of k6/secrets, in the name[=argument] format of
+	// secrets.NewStoreFromSpecs. They can't be set from the script and they
+	// aren't in the archives, so the secrets stay on the machine running k6.
+	SecretSources []string `json:"-" envconfig:"K6_SECRET_SOURCES"`
+
 	// DNS handling configuration.
 	DNS types.DNSConfig `json:"dns" envconfig:"K6_DNS"`
 
 	// How many HTTP redirects do we follow?
 	MaxRedirects null.Int `json:"maxRedirects" envconfig:"K6_MAX_REDIRECTS"`
 
+	// Limit on the size of the HTTP response headers, larger responses fail.
+	MaxResponseHeaderBytes null.Int `json:"maxResponseHeaderBytes" envconfig:"K6_MAX_RESPONSE_HEADER_BYTES"`
+
 	// Default User Agent string for HTTP requests.
 	UserAgent null.String `json:"userAgent" envconfig:"K6_USER_AGENT"`
 
@@ -318,9 +392,17 @@ type Options struct {
 	Batch        null.Int `json:"batch" envconfig:"K6_BATCH"`
 	BatchPerHost null.Int `json:"batchPerHost" envconfig:"K6_BATCH_PER_HOST"`
 
+	// How many requests made with http.asyncRequest() can a VU have in flight, in total and per host?
+	AsyncRequests        null.Int `json:"asyncRequests" envconfig:"K6_ASYNC_REQUESTS"`
+	AsyncRequestsPerHost null.Int `json:"asyncRequestsPerHost" envconfig:"K6_ASYNC_REQUESTS_PER_HOST"`
+
 	// Should all HTTP requests and responses be logged (excluding body)?
 	HTTPDebug null.String `json:"httpDebug" envconfig:"K6_HTTP_DEBUG"`
 
+	// Should all gRPC calls be logged with their metadata and message sizes? The messages are
+	// included as text with "full", and also as the encoded protobuf bytes with "wire".
+	GRPCDebug null.String `json:"grpcDebug" envconfig:"K6_GRPC_DEBUG"`
+
 	// Accept invalid or untrusted TLS certificates.
 	InsecureSkipTLSVerify null.Bool `json:"insecureSkipTLSVerify" envconfig:"K6_INSECURE_SKIP_TLS_VERIFY"`
 
@@ -354,6 +436,19 @@ type Options struct {
 	// errors about running out of file handles or sockets, or being unable to bind addresses.
 	NoVUConnectionReuse null.Bool `json:"noVUConnectionReuse" envconfig:"K6_NO_VU_CONNECTION_REUSE"`
 
+	// How many idle connections are kept for each host, batchPerHost by default, and how long
+	// they are kept before they're closed, indefinitely by default.
+	MaxIdleConnsPerHost null.Int           `json:"maxIdleConnsPerHost" envconfig:"K6_MAX_IDLE_CONNS_PER_HOST"`
+	IdleConnTimeout     types.NullDuration `json:"idleConnTimeout" envconfig:"K6_IDLE_CONN_TIMEOUT"`
+
+	// Emit the metrics of the connection pools, the open and idle connections to the host of
+	// each request and the time it waited for a connection.
+	HTTPConnPoolMetrics null.Bool `json:"httpConnPoolMetrics" envconfig:"K6_HTTP_CONN_POOL_METRICS"`
+
+	// Emulate the cache of a browser, each VU keeps the validators of the responses and revalidates
+	// them with conditional requests.
+	HTTPCache null.Bool `json:"httpCache" envconfig:"K6_HTTP_CACHE"`
+
 	// MinIterationDuration can be used to force VUs to pause between iterations if a specific
 	// iteration is shorter than the specified value.
 	MinIterationDuration types.NullDuration `json:"minIterationDuration" envconfig:"K6_MIN_ITERATION_DURATION"`
@@ -368,6 +463,10 @@ type Options struct {
 	// Summary time unit for summary metrics (response times) in CLI output
 	SummaryTimeUnit null.String `json:"summaryTimeUnit" envconfig:"K6_SUMMARY_TIME_UNIT"`
 
+	// If set, the metrics are also aggregated in intervals of this length and
+	// passed to handleSummary() as time series
+	SummaryTimeSeriesInterval types.NullDuration `json:"summaryTimeSeriesInterval" envconfig:"K6_SUMMARY_TIME_SERIES_INTERVAL"`
+
 	// Which system tags to include with metrics ("method", "vu" etc.)
 	// Use pointer for identifying whether user provide any tag or not.
 	SystemTags *stats.SystemTagSet `json:"systemTags" envconfig:"K6_SYSTEM_TAGS"`
@@ -378,17 +477,44 @@ type Options struct {
 	// Buffer size of the channel for metric samples; 0 means unbuffered
 	MetricSamplesBufferSize null.Int `json:"metricSamplesBufferSize" envconfig:"K6_METRIC_SAMPLES_BUFFER_SIZE"`
 
+	// Maximum number of distinct values for every tag of every metric; 0 means unlimited
+	MaxTagValues null.Int `json:"maxTagValues" envconfig:"K6_MAX_TAG_VALUES"`
+
+	// The rules that group the URLs with IDs in the name tag of the HTTP requests
+	URLGroups types.URLGroups `json:"urlGroups" ignored:"true"`
+
+	// The sub-metrics that are created automatically for the values of some tags
+	AutoSubmetrics *AutoSubmetrics `json:"autoSubmetrics" ignored:"true"`
+
+	// The metrics whose samples are sent to the outputs
+	Metrics *MetricsFilter `json:"metrics" ignored:"true"`
+
+	// The static tags and metadata that are added to all of the samples sent to the outputs, like
+	// the build number or the git SHA of the tested version, without overriding their own values
+	StaticTags     map[string]string `json:"staticTags" ignored:"true"`
+	StaticMetadata map[string]string `json:"staticMetadata" ignored:"true"`
+
 	// Do not reset cookies after a VU iteration
 	NoCookiesReset null.Bool `json:"noCookiesReset" envconfig:"K6_NO_COOKIES_RESET"`
 
 	// Discard Http Responses Body
 	DiscardResponseBodies null.Bool `json:"discardResponseBodies" envconfig:"K6_DISCARD_RESPONSE_BODIES"`
 
-	// Redirect console logging to a file
+	// Redirect console logging to a file, a rotated file, Loki, the k6 logs or nowhere
 	ConsoleOutput null.String `json:"-" envconfig:"K6_CONSOLE_OUTPUT"`
 
 	// Specify client IP ranges and/or CIDR from which VUs will make requests
 	LocalIPs types.NullIPPool `json:"-" envconfig:"K6_LOCAL_IPS"`
+
+	// The period of the TCP keep-alive probes of the connections, 30s by default, 0 disables them.
+	TCPKeepAlive types.NullDuration `json:"tcpKeepAlive" envconfig:"K6_TCP_KEEP_ALIVE"`
+
+	// The DSCP marking of the IP packets of the connections, from 0 to 63.
+	DSCP null.Int `json:"dscp" envconfig:"K6_DSCP"`
+
+	// Set SO_REUSEPORT on the sockets of the connections, for binding many of them to the
+	// local IPs.
+	TCPReusePort null.Bool `json:"tcpReusePort" envconfig:"K6_TCP_REUSE_PORT"`
 }
 
 // Returns the result of overwriting any fields with any that are set on the argument.
@@ -462,9 +588,21 @@ func (o Options) Apply(opts Options) Options {
 	if opts.RPS.Valid {
 		o.RPS = opts.RPS
 	}
+	if opts.FilesDir.Valid {
+		o.FilesDir = opts.FilesDir
+	}
+	if opts.FilesRate.Valid {
+		o.FilesRate = opts.FilesRate
+	}
+	if opts.SecretSources != nil {
+		o.SecretSources = opts.SecretSources
+	}
 	if opts.MaxRedirects.Valid {
 		o.MaxRedirects = opts.MaxRedirects
 	}
+	if opts.MaxResponseHeaderBytes.Valid {
+		o.MaxResponseHeaderBytes = opts.MaxResponseHeaderBytes
+	}
 	if opts.UserAgent.Valid {
 		o.UserAgent = opts.UserAgent
 	}
@@ -474,9 +612,18 @@ func (o Options) Apply(opts Options) Options {
 	if opts.BatchPerHost.Valid {
 		o.BatchPerHost = opts.BatchPerHost
 	}
+	if opts.AsyncRequests.Valid {
+		o.AsyncRequests = opts.AsyncRequests
+	}
+	if opts.AsyncRequestsPerHost.Valid {
+		o.AsyncRequestsPerHost = opts.AsyncRequestsPerHost
+	}
 	if opts.HTTPDebug.Valid {
 		o.HTTPDebug = opts.HTTPDebug
 	}
+	if opts.GRPCDebug.Valid {
+		o.GRPCDebug = opts.GRPCDebug
+	}
 	if opts.InsecureSkipTLSVerify.Valid {
 		o.InsecureSkipTLSVerify = opts.InsecureSkipTLSVerify
 	}
@@ -510,6 +657,18 @@ func (o Options) Apply(opts Options) Options {
 	if opts.NoVUConnectionReuse.Valid {
 		o.NoVUConnectionReuse = opts.NoVUConnectionReuse
 	}
+	if opts.MaxIdleConnsPerHost.Valid {
+		o.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout.Valid {
+		o.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	if opts.HTTPConnPoolMetrics.Valid {
+		o.HTTPConnPoolMetrics = opts.HTTPConnPoolMetrics
+	}
+	if opts.HTTPCache.Valid {
+		o.HTTPCache = opts.HTTPCache
+	}
 	if opts.MinIterationDuration.Valid {
 		o.MinIterationDuration = opts.MinIterationDuration
 	}
@@ -525,6 +684,9 @@ func (o Options) Apply(opts Options) Options {
 	if opts.SummaryTimeUnit.Valid {
 		o.SummaryTimeUnit = opts.SummaryTimeUnit
 	}
+	if opts.SummaryTimeSeriesInterval.Valid {
+		o.SummaryTimeSeriesInterval = opts.SummaryTimeSeriesInterval
+	}
 	if opts.SystemTags != nil {
 		o.SystemTags = opts.SystemTags
 	}
@@ -534,6 +696,24 @@ func (o Options) Apply(opts Options) Options {
 	if opts.MetricSamplesBufferSize.Valid {
 		o.MetricSamplesBufferSize = opts.MetricSamplesBufferSize
 	}
+	if opts.MaxTagValues.Valid {
+		o.MaxTagValues = opts.MaxTagValues
+	}
+	if opts.URLGroups != nil {
+		o.URLGroups = opts.URLGroups
+	}
+	if opts.AutoSubmetrics != nil {
+		o.AutoSubmetrics = opts.AutoSubmetrics
+	}
+	if opts.Metrics != nil {
+		o.Metrics = opts.Metrics
+	}
+	if opts.StaticTags != nil {
+		o.StaticTags = opts.StaticTags
+	}
+	if opts.StaticMetadata != nil {
+		o.StaticMetadata = opts.StaticMetadata
+	}
 	if opts.DiscardResponseBodies.Valid {
 		o.DiscardResponseBodies = opts.DiscardResponseBodies
 	}
@@ -543,6 +723,15 @@ func (o Options) Apply(opts Options) Options {
 	if opts.LocalIPs.Valid {
 		o.LocalIPs = opts.LocalIPs
 	}
+	if opts.TCPKeepAlive.Valid {
+		o.TCPKeepAlive = opts.TCPKeepAlive
+	}
+	if opts.DSCP.Valid {
+		o.DSCP = opts.DSCP
+	}
+	if opts.TCPReusePort.Valid {
+		o.TCPReusePort = opts.TCPReusePort
+	}
 	if opts.DNS.TTL.Valid {
 		o.DNS.TTL = opts.DNS.TTL
 	}
@@ -575,6 +764,27 @@ func (o Options) Validate() []error {
 					o.ExecutionSegment, o.ExecutionSegmentSequence))
 		}
 	}
+	if o.MaxTagValues.Valid && o.MaxTagValues.Int64 < 0 {
+		errors = append(errors, fmt.Errorf("the maxTagValues value can't be negative, got %d", o.MaxTagValues.Int64))
+	}
+	if o.DSCP.Valid && (o.DSCP.Int64 < 0 || o.DSCP.Int64 > 63) {
+		errors = append(errors, fmt.Errorf("the dscp value must be between 0 and 63, got %d", o.DSCP.Int64))
+	}
+	if o.TCPKeepAlive.Valid && o.TCPKeepAlive.Duration < 0 {
+		errors = append(errors, fmt.Errorf("the tcpKeepAlive value can't be negative, got %s", o.TCPKeepAlive))
+	}
+	if o.AutoSubmetrics != nil {
+		errors = append(errors, o.AutoSubmetrics.Validate()...)
+	}
+	if o.Metrics != nil {
+		errors = append(errors, o.Metrics.Validate()...)
+	}
+	if _, ok := o.StaticTags[""]; ok {
+		errors = append(errors, fmt.Errorf("the static tags can't have an empty name"))
+	}
+	if _, ok := o.StaticMetadata[""]; ok {
+		errors = append(errors, fmt.Errorf("the static metadata can't have an empty key"))
+	}
 	return append(errors, o.Scenarios.Validate()...)
 }
 
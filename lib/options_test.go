@@ -90,11 +90,27 @@ func TestOptions(t *testing.T) {
 		assert.True(t, opts.RPS.Valid)
 		assert.Equal(t, int64(12345), opts.RPS.Int64)
 	})
+	t.Run("Files", func(t *testing.T) {
+		opts := Options{}.Apply(Options{FilesDir: null.StringFrom("/tmp/out"), FilesRate: null.IntFrom(1024)})
+		assert.Equal(t, null.StringFrom("/tmp/out"), opts.FilesDir)
+		assert.Equal(t, null.IntFrom(1024), opts.FilesRate)
+	})
+	t.Run("SecretSources", func(t *testing.T) {
+		opts := Options{}.Apply(Options{SecretSources: []string{"env", "file=secrets.json"}})
+		assert.Equal(t, []string{"env", "file=secrets.json"}, opts.SecretSources)
+		opts = opts.Apply(Options{})
+		assert.Equal(t, []string{"env", "file=secrets.json"}, opts.SecretSources)
+	})
 	t.Run("MaxRedirects", func(t *testing.T) {
 		opts := Options{}.Apply(Options{MaxRedirects: null.IntFrom(12345)})
 		assert.True(t, opts.MaxRedirects.Valid)
 		assert.Equal(t, int64(12345), opts.MaxRedirects.Int64)
 	})
+	t.Run("MaxResponseHeaderBytes", func(t *testing.T) {
+		opts := Options{}.Apply(Options{MaxResponseHeaderBytes: null.IntFrom(4096)})
+		assert.True(t, opts.MaxResponseHeaderBytes.Valid)
+		assert.Equal(t, int64(4096), opts.MaxResponseHeaderBytes.Int64)
+	})
 	t.Run("UserAgent", func(t *testing.T) {
 		opts := Options{}.Apply(Options{UserAgent: null.StringFrom("foo")})
 		assert.True(t, opts.UserAgent.Valid)
@@ -110,11 +126,26 @@ func TestOptions(t *testing.T) {
 		assert.True(t, opts.BatchPerHost.Valid)
 		assert.Equal(t, int64(12345), opts.BatchPerHost.Int64)
 	})
+	t.Run("AsyncRequests", func(t *testing.T) {
+		opts := Options{}.Apply(Options{AsyncRequests: null.IntFrom(12345)})
+		assert.True(t, opts.AsyncRequests.Valid)
+		assert.Equal(t, int64(12345), opts.AsyncRequests.Int64)
+	})
+	t.Run("AsyncRequestsPerHost", func(t *testing.T) {
+		opts := Options{}.Apply(Options{AsyncRequestsPerHost: null.IntFrom(12345)})
+		assert.True(t, opts.AsyncRequestsPerHost.Valid)
+		assert.Equal(t, int64(12345), opts.AsyncRequestsPerHost.Int64)
+	})
 	t.Run("HTTPDebug", func(t *testing.T) {
 		opts := Options{}.Apply(Options{HTTPDebug: null.StringFrom("foo")})
 		assert.True(t, opts.HTTPDebug.Valid)
 		assert.Equal(t, "foo", opts.HTTPDebug.String)
 	})
+	t.Run("GRPCDebug", func(t *testing.T) {
+		opts := Options{}.Apply(Options{GRPCDebug: null.StringFrom("wire")})
+		assert.True(t, opts.GRPCDebug.Valid)
+		assert.Equal(t, "wire", opts.GRPCDebug.String)
+	})
 	t.Run("InsecureSkipTLSVerify", func(t *testing.T) {
 		opts := Options{}.Apply(Options{InsecureSkipTLSVerify: null.BoolFrom(true)})
 		assert.True(t, opts.InsecureSkipTLSVerify.Valid)
@@ -293,6 +324,26 @@ func TestOptions(t *testing.T) {
 		assert.True(t, opts.NoConnectionReuse.Valid)
 		assert.True(t, opts.NoConnectionReuse.Bool)
 	})
+	t.Run("MaxIdleConnsPerHost", func(t *testing.T) {
+		opts := Options{}.Apply(Options{MaxIdleConnsPerHost: null.IntFrom(12)})
+		assert.True(t, opts.MaxIdleConnsPerHost.Valid)
+		assert.Equal(t, int64(12), opts.MaxIdleConnsPerHost.Int64)
+	})
+	t.Run("IdleConnTimeout", func(t *testing.T) {
+		opts := Options{}.Apply(Options{IdleConnTimeout: types.NullDurationFrom(30 * time.Second)})
+		assert.True(t, opts.IdleConnTimeout.Valid)
+		assert.Equal(t, types.Duration(30*time.Second), opts.IdleConnTimeout.Duration)
+	})
+	t.Run("HTTPConnPoolMetrics", func(t *testing.T) {
+		opts := Options{}.Apply(Options{HTTPConnPoolMetrics: null.BoolFrom(true)})
+		assert.True(t, opts.HTTPConnPoolMetrics.Valid)
+		assert.True(t, opts.HTTPConnPoolMetrics.Bool)
+	})
+	t.Run("HTTPCache", func(t *testing.T) {
+		opts := Options{}.Apply(Options{HTTPCache: null.BoolFrom(true)})
+		assert.True(t, opts.HTTPCache.Valid)
+		assert.True(t, opts.HTTPCache.Bool)
+	})
 	t.Run("NoVUConnectionReuse", func(t *testing.T) {
 		opts := Options{}.Apply(Options{NoVUConnectionReuse: null.BoolFrom(true)})
 		assert.True(t, opts.NoVUConnectionReuse.Valid)
@@ -413,11 +464,68 @@ func TestOptions(t *testing.T) {
 		opts := Options{}.Apply(Options{SummaryTrendStats: stats})
 		assert.Equal(t, stats, opts.SummaryTrendStats)
 	})
+	t.Run("SummaryTimeSeriesInterval", func(t *testing.T) {
+		opts := Options{}.Apply(Options{SummaryTimeSeriesInterval: types.NullDurationFrom(10 * time.Second)})
+		assert.True(t, opts.SummaryTimeSeriesInterval.Valid)
+		assert.Equal(t, types.Duration(10*time.Second), opts.SummaryTimeSeriesInterval.Duration)
+	})
 	t.Run("RunTags", func(t *testing.T) {
 		tags := stats.IntoSampleTags(&map[string]string{"myTag": "hello"})
 		opts := Options{}.Apply(Options{RunTags: tags})
 		assert.Equal(t, tags, opts.RunTags)
 	})
+	t.Run("MaxTagValues", func(t *testing.T) {
+		opts := Options{}.Apply(Options{MaxTagValues: null.IntFrom(100)})
+		assert.True(t, opts.MaxTagValues.Valid)
+		assert.Equal(t, int64(100), opts.MaxTagValues.Int64)
+		assert.Empty(t, opts.Validate())
+		assert.Len(t, Options{MaxTagValues: null.IntFrom(-1)}.Validate(), 1)
+	})
+	t.Run("URLGroups", func(t *testing.T) {
+		var fromJSON Options
+		require.NoError(t, json.Unmarshal([]byte(`{"urlGroups": [{"template": "/users/{id}"}]}`), &fromJSON))
+		opts := Options{}.Apply(fromJSON)
+		require.Len(t, opts.URLGroups, 1)
+		assert.Equal(t, "/users/{id}", opts.URLGroups[0].Template)
+		assert.Error(t, json.Unmarshal([]byte(`{"urlGroups": [{"pattern": "("}]}`), &fromJSON))
+	})
+	t.Run("AutoSubmetrics", func(t *testing.T) {
+		auto := &AutoSubmetrics{Tags: []string{"name"}, Exclude: []string{"^https://cdn\\."}, Thresholds: []string{"p(95)<500"}}
+		opts := Options{}.Apply(Options{AutoSubmetrics: auto})
+		assert.Equal(t, auto, opts.AutoSubmetrics)
+		assert.Empty(t, opts.Validate())
+
+		var fromJSON Options
+		require.NoError(t, json.Unmarshal([]byte(`{"autoSubmetrics": {"tags": ["group"], "limit": 0}}`), &fromJSON))
+		assert.Equal(t, []string{"group"}, fromJSON.AutoSubmetrics.Tags)
+		assert.Len(t, fromJSON.Validate(), 1)
+		assert.Len(t, Options{AutoSubmetrics: &AutoSubmetrics{Include: []string{"("}, Thresholds: []string{"p(95)<"}}}.Validate(), 3)
+	})
+	t.Run("Metrics", func(t *testing.T) {
+		filter := &MetricsFilter{Include: []string{"http_req_*"}, Exclude: []string{"http_req_blocked"}}
+		opts := Options{}.Apply(Options{Metrics: filter})
+		assert.Equal(t, filter, opts.Metrics)
+		assert.Empty(t, opts.Validate())
+
+		var fromJSON Options
+		require.NoError(t, json.Unmarshal([]byte(`{"metrics": {"exclude": ["data_*", "[a"]}}`), &fromJSON))
+		assert.Equal(t, []string{"data_*", "[a"}, fromJSON.Metrics.Exclude)
+		assert.Len(t, fromJSON.Validate(), 1)
+	})
+	t.Run("StaticTags", func(t *testing.T) {
+		opts := Options{}.Apply(Options{
+			StaticTags:     map[string]string{"build": "123"},
+			StaticMetadata: map[string]string{"commit": "abc"},
+		})
+		assert.Equal(t, map[string]string{"build": "123"}, opts.StaticTags)
+		assert.Equal(t, map[string]string{"commit": "abc"}, opts.StaticMetadata)
+		assert.Empty(t, opts.Validate())
+
+		var fromJSON Options
+		require.NoError(t, json.Unmarshal([]byte(`{"staticTags": {"": "x"}, "staticMetadata": {"a": "b"}}`), &fromJSON))
+		assert.Equal(t, map[string]string{"a": "b"}, fromJSON.StaticMetadata)
+		assert.Len(t, fromJSON.Validate(), 1)
+	})
 	t.Run("DiscardResponseBodies", func(t *testing.T) {
 		opts := Options{}.Apply(Options{DiscardResponseBodies: null.BoolFrom(true)})
 		assert.True(t, opts.DiscardResponseBodies.Valid)
@@ -429,6 +537,23 @@ func TestOptions(t *testing.T) {
 		opts := Options{}.Apply(Options{LocalIPs: types.NullIPPool{Pool: clientIPRanges, Valid: true}})
 		assert.NotNil(t, opts.LocalIPs)
 	})
+	t.Run("TCPKeepAlive", func(t *testing.T) {
+		opts := Options{}.Apply(Options{TCPKeepAlive: types.NullDurationFrom(10 * time.Second)})
+		assert.True(t, opts.TCPKeepAlive.Valid)
+		assert.Equal(t, types.Duration(10*time.Second), opts.TCPKeepAlive.Duration)
+	})
+	t.Run("DSCP", func(t *testing.T) {
+		opts := Options{}.Apply(Options{DSCP: null.IntFrom(46)})
+		assert.True(t, opts.DSCP.Valid)
+		assert.Equal(t, int64(46), opts.DSCP.Int64)
+		assert.Empty(t, opts.Validate())
+		assert.Len(t, Options{DSCP: null.IntFrom(64)}.Validate(), 1)
+	})
+	t.Run("TCPReusePort", func(t *testing.T) {
+		opts := Options{}.Apply(Options{TCPReusePort: null.BoolFrom(true)})
+		assert.True(t, opts.TCPReusePort.Valid)
+		assert.True(t, opts.TCPReusePort.Bool)
+	})
 }
 
 func TestOptionsEnv(t *testing.T) {
@@ -25,6 +25,8 @@ import (
 	"io"
 	"time"
 
+	"gopkg.in/guregu/null.v3"
+
 	"go.k6.io/k6/stats"
 )
 
@@ -48,6 +50,17 @@ type InitializedVU interface {
 	GetID() uint64
 }
 
+// StoppableVU is an InitializedVU that has to be stopped once at the end of
+// the test run, after its last activation, e.g. so it can release the
+// resources it has acquired over all of its iterations.
+type StoppableVU interface {
+	InitializedVU
+
+	// Stop the VU, with the reason why the test run was aborted, or an empty
+	// one if it ended normally.
+	Stop(abortReason AbortReason)
+}
+
 // VUActivationParams are supplied by each executor when it retrieves a VU from
 // the buffer pool and activates it for use.
 type VUActivationParams struct {
@@ -55,6 +68,9 @@ type VUActivationParams struct {
 	DeactivateCallback       func(InitializedVU)
 	Env, Tags                map[string]string
 	Exec, Scenario           string
+	NoConnectionReuse        bool
+	NoCookiesReset           null.Bool
+	Network                  *NetworkShaping
 	GetNextIterationCounters func() (uint64, uint64)
 }
 
@@ -88,6 +104,15 @@ type Runner interface {
 	// Runs post-test teardown, if applicable.
 	Teardown(ctx context.Context, out chan<- stats.SampleContainer) error
 
+	// Runs the fn setup function of a scenario, after setup() and before any
+	// scenario starts.
+	// Its result is passed to the VU iterations of that scenario, instead of
+	// the data returned by the test-wide setup().
+	ScenarioSetup(ctx context.Context, out chan<- stats.SampleContainer, scenario, fn string) error
+
+	// Runs the fn teardown function of a scenario, after the scenario ends.
+	ScenarioTeardown(ctx context.Context, out chan<- stats.SampleContainer, scenario, fn string) error
+
 	// Returns the default (root) Group.
 	GetDefaultGroup() *Group
 
@@ -118,4 +143,12 @@ type Summary struct {
 	TestRunDuration time.Duration // TODO: use lib.ExecutionState-based interface instead?
 	NoColor         bool          // TODO: drop this when noColor is part of the (runtime) options
 	UIState         UIState
+
+	// Instance is only set when the test is executed with an execution
+	// segment, i.e. when this is only one of the instances of the test run.
+	Instance *InstanceExecutionReport
+
+	// TimeSeries is only set when the summaryTimeSeriesInterval option is
+	// enabled.
+	TimeSeries *stats.TimeSeries
 }
@@ -0,0 +1,186 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package secrets
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const awsService = "secretsmanager"
+
+// awsSource gets the secrets with the GetSecretValue action of AWS Secrets
+// Manager, the names of the secrets are their names or ARNs.
+type awsSource struct {
+	client   *http.Client
+	endpoint string
+	region   string
+
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+
+	now func() time.Time
+}
+
+// NewAWSSource returns a Source of AWS Secrets Manager in the region, or in the
+// one of AWS_REGION or AWS_DEFAULT_REGION, with the credentials of the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN variables of
+// the env. The endpoint can be replaced with AWS_ENDPOINT_URL_SECRETS_MANAGER.
+func NewAWSSource(client *http.Client, region string, env map[string]string) (Source, error) {
+	if region == "" {
+		region = env["AWS_REGION"]
+	}
+	if region == "" {
+		region = env["AWS_DEFAULT_REGION"]
+	}
+	if region == "" {
+		return nil, errors.New("the aws secret source needs a region, like aws=us-east-1, or AWS_REGION")
+	}
+	s := &awsSource{
+		client:          client,
+		endpoint:        env["AWS_ENDPOINT_URL_SECRETS_MANAGER"],
+		region:          region,
+		accessKeyID:     env["AWS_ACCESS_KEY_ID"],
+		secretAccessKey: env["AWS_SECRET_ACCESS_KEY"],
+		sessionToken:    env["AWS_SESSION_TOKEN"],
+		now:             time.Now,
+	}
+	if s.accessKeyID == "" || s.secretAccessKey == "" {
+		return nil, errors.New("the aws secret source needs the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY " +
+			"environment variables")
+	}
+	if s.endpoint == "" {
+		s.endpoint = "https://" + awsService + "." + region + ".amazonaws.com"
+	}
+	return s, nil
+}
+
+func (s *awsSource) Get(name string) (string, error) {
+	payload, err := json.Marshal(map[string]string{"SecretId": name})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	s.sign(req, payload)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("couldn't get the secret from AWS Secrets Manager: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var body struct {
+		Type         string `json:"__type"`
+		Message      string `json:"message"`
+		SecretString *string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid response of AWS Secrets Manager, the status is %s: %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// the type can be prefixed with the namespace, like namespace#ResourceNotFoundException
+		if strings.HasSuffix(body.Type, "ResourceNotFoundException") {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("AWS Secrets Manager returned %s: %s %s", resp.Status, body.Type, body.Message)
+	}
+	if body.SecretString == nil {
+		return "", fmt.Errorf("the AWS secret '%s' doesn't have a string value", name)
+	}
+	return *body.SecretString, nil
+}
+
+// sign adds the AWS Signature Version 4 of the request to its headers.
+func (s *awsSource) sign(req *http.Request, payload []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		headers[strings.ToLower(key)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, canonicalQuery(req.URL.Query()), canonicalHeaders.String(), signedHeaders, sha256Hex(payload),
+	}, "\n")
+
+	scope := date + "/" + s.region + "/" + awsService + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), date)
+	for _, part := range []string{s.region, awsService, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func canonicalQuery(query url.Values) string {
+	// Encode sorts by the keys, but AWS wants %20 instead of + for the spaces
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,207 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package secrets implements the sources of the secrets that scripts get with
+// the k6/secrets module, so that the credentials of a test don't have to be in
+// the script, in its archive or in the __ENV variables, and it redacts the
+// values of the secrets from the logs.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// ErrNotFound is returned by the sources that don't have a secret.
+var ErrNotFound = errors.New("secret not found")
+
+// Redacted replaces the values of the secrets in the logs.
+const Redacted = "***"
+
+// MinRedactLength is the length of the shortest values that are redacted, the
+// shorter ones would mangle unrelated log messages.
+const MinRedactLength = 4
+
+// Source is a source of secrets, like the environment variables, a file or a
+// secrets manager.
+type Source interface {
+	// Get returns the value of the secret, or an error wrapping ErrNotFound
+	// if the source doesn't have it.
+	Get(name string) (string, error)
+}
+
+// Store gets the secrets from its sources, in order, and caches them, so each
+// secret is fetched only once per test. It's a logrus.Hook that redacts the
+// values of all the secrets it returned from the log entries.
+type Store struct {
+	sources []Source
+
+	mu     sync.RWMutex
+	cache  map[string]string
+	values []string
+}
+
+var _ logrus.Hook = &Store{}
+
+// NewStore returns a Store with the given sources.
+func NewStore(sources ...Source) *Store {
+	return &Store{sources: sources, cache: make(map[string]string)}
+}
+
+// NewSources returns the sources of the given specs, in the name[=argument]
+// format:
+//   - env: the environment variables with the K6_SECRET_ prefix
+//   - file=path: a JSON object or a file with NAME=value lines
+//   - vault=path: the secret at the path of HashiCorp Vault, at VAULT_ADDR with VAULT_TOKEN
+//   - aws[=region]: AWS Secrets Manager, with the credentials of the AWS_* variables
+//
+// The environ are the environment variables in the KEY=value format.
+func NewSources(specs []string, fs afero.Fs, environ []string) ([]Source, error) {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	sources := make([]Source, 0, len(specs))
+	for _, spec := range specs {
+		name, arg := spec, ""
+		if i := strings.IndexByte(spec, '='); i >= 0 {
+			name, arg = spec[:i], spec[i+1:]
+		}
+		var (
+			source Source
+			err    error
+		)
+		switch name {
+		case "env":
+			source = NewEnvSource(env)
+		case "file":
+			source, err = NewFileSource(fs, arg)
+		case "vault":
+			source, err = NewVaultSource(client, env["VAULT_ADDR"], env["VAULT_TOKEN"], arg)
+		case "aws":
+			source, err = NewAWSSource(client, arg, env)
+		default:
+			err = fmt.Errorf("unknown secret source '%s', it has to be env, file, vault or aws", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// SetSources replaces the sources of the store and clears its cache, the
+// values that were already returned are still redacted.
+func (s *Store) SetSources(sources ...Source) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sources = sources
+	s.cache = make(map[string]string)
+}
+
+// Get returns the value of the secret from the first source that has it.
+func (s *Store) Get(name string) (string, error) {
+	s.mu.RLock()
+	value, ok := s.cache[name]
+	s.mu.RUnlock()
+	if ok {
+		return value, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value, ok := s.cache[name]; ok {
+		return value, nil
+	}
+	for _, source := range s.sources {
+		value, err := source.Get(name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("couldn't get the secret '%s': %w", name, err)
+		}
+		s.cache[name] = value
+		if len(value) >= MinRedactLength {
+			s.values = append(s.values, value)
+			// the longest values first, so the secrets that contain others are redacted entirely
+			sort.Slice(s.values, func(i, j int) bool { return len(s.values[i]) > len(s.values[j]) })
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("%w: '%s' isn't in any of the secret sources", ErrNotFound, name)
+}
+
+// Redact replaces the values of the secrets that were returned in the text.
+func (s *Store) Redact(text string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, value := range s.values {
+		text = strings.ReplaceAll(text, value, Redacted)
+	}
+	return text
+}
+
+// RedactLogs adds the store as the first hook of the logger, so the values are
+// redacted before the other hooks, like the ones of the log outputs, get the
+// entries.
+func (s *Store) RedactLogs(logger *logrus.Logger) {
+	old := logger.ReplaceHooks(make(logrus.LevelHooks))
+	hooks := make(logrus.LevelHooks, len(old))
+	for _, level := range s.Levels() {
+		hooks[level] = append([]logrus.Hook{s}, old[level]...)
+	}
+	logger.ReplaceHooks(hooks)
+}
+
+// Levels implements logrus.Hook, the values are redacted from all the levels.
+func (s *Store) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook, it redacts the message and the string and error
+// fields of the entry.
+func (s *Store) Fire(entry *logrus.Entry) error {
+	entry.Message = s.Redact(entry.Message)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = s.Redact(v)
+		case error:
+			if msg := v.Error(); s.Redact(msg) != msg {
+				entry.Data[key] = errors.New(s.Redact(msg))
+			}
+		}
+	}
+	return nil
+}
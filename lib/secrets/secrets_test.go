@@ -0,0 +1,228 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	counting := sourceFunc(func(name string) (string, error) {
+		calls++
+		if name == "broken" {
+			return "", errors.New("unavailable")
+		}
+		return "", ErrNotFound
+	})
+	store := NewStore(counting, NewEnvSource(map[string]string{"K6_SECRET_TOKEN": "s3cr3t-token", "TOKEN": "public"}))
+
+	value, err := store.Get("TOKEN")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t-token", value)
+	value, err = store.Get("TOKEN")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t-token", value)
+	assert.Equal(t, 1, calls)
+
+	_, err = store.Get("MISSING")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = store.Get("broken")
+	assert.EqualError(t, err, "couldn't get the secret 'broken': unavailable")
+
+	store.SetSources()
+	_, err = store.Get("TOKEN")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.Equal(t, "Bearer ***", store.Redact("Bearer s3cr3t-token"))
+}
+
+func TestStoreRedactsLogs(t *testing.T) {
+	t.Parallel()
+	store := NewStore(NewEnvSource(map[string]string{
+		"K6_SECRET_PASSWORD": "hunter22", "K6_SECRET_LONG": "hunter22-and-more", "K6_SECRET_PIN": "123",
+	}))
+	for _, name := range []string{"PASSWORD", "LONG", "PIN"} {
+		_, err := store.Get(name)
+		require.NoError(t, err)
+	}
+
+	logger, hook := test.NewNullLogger()
+	store.RedactLogs(logger)
+	logger.WithField("auth", "user:hunter22").WithError(errors.New("denied for hunter22-and-more")).
+		Info("logged in with hunter22 and 123")
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "logged in with *** and 123", entry.Message)
+	assert.Equal(t, "user:***", entry.Data["auth"])
+	assert.EqualError(t, entry.Data[logrus.ErrorKey].(error), "denied for ***")
+}
+
+func TestFileSource(t *testing.T) {
+	t.Parallel()
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/secrets.json", []byte(`{"user": "admin", "password": "p=ss"}`), 0o600))
+	require.NoError(t, afero.WriteFile(fs, "/secrets.env", []byte("# comment\n\nUSER=admin\nPASSWORD=p=ss\n"), 0o600))
+	require.NoError(t, afero.WriteFile(fs, "/invalid.env", []byte("USER\n"), 0o600))
+
+	for _, path := range []string{"/secrets.json", "/secrets.env"} {
+		source, err := NewFileSource(fs, path)
+		require.NoError(t, err, path)
+		value, err := source.Get(strings.ToUpper("password"))
+		if path == "/secrets.json" {
+			assert.Equal(t, ErrNotFound, err)
+			value, err = source.Get("password")
+		}
+		require.NoError(t, err, path)
+		assert.Equal(t, "p=ss", value, path)
+	}
+
+	_, err := NewFileSource(fs, "/invalid.env")
+	assert.EqualError(t, err, "the line 1 of the secrets file /invalid.env isn't in the NAME=value format")
+	_, err = NewFileSource(fs, "/missing.json")
+	assert.Error(t, err)
+}
+
+func TestVaultSource(t *testing.T) {
+	t.Parallel()
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/k6":
+			_, _ = w.Write([]byte(`{"data": {"data": {"password": "hunter22", "port": 5432}, "metadata": {"version": 1}}}`))
+		case "/v1/kv/k6":
+			_, _ = w.Write([]byte(`{"data": {"password": "hunter23"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	sources, err := NewSources([]string{"vault=secret/data/k6", "vault=/kv/k6", "vault=missing"}, nil,
+		[]string{"VAULT_ADDR=" + srv.URL + "/", "VAULT_TOKEN=root"})
+	require.NoError(t, err)
+	require.Len(t, sources, 3)
+
+	value, err := sources[0].Get("password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter22", value)
+	value, err = sources[0].Get("port")
+	require.NoError(t, err)
+	assert.Equal(t, "5432", value)
+	_, err = sources[0].Get("user")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, 1, requests)
+
+	value, err = sources[1].Get("password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter23", value)
+
+	_, err = sources[2].Get("password")
+	assert.Contains(t, err.Error(), "404 Not Found")
+
+	_, err = NewSources([]string{"vault=secret/data/k6"}, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestAWSSource(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		assert.Equal(t, "session", r.Header.Get("X-Amz-Security-Token"))
+		assert.Equal(t, "20211014T101500Z", r.Header.Get("X-Amz-Date"))
+		auth := r.Header.Get("Authorization")
+		assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 "+
+			"Credential=AKID/20211014/eu-west-1/secretsmanager/aws4_request, "+
+			"SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target, Signature="), auth)
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var input struct{ SecretId string } //nolint:golint,revive,stylecheck
+		require.NoError(t, json.Unmarshal(body, &input))
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		if input.SecretId != "prod/db" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type": "ResourceNotFoundException", "message": "not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"Name": "prod/db", "SecretString": "hunter22"}`))
+	}))
+	defer srv.Close()
+
+	source, err := NewAWSSource(srv.Client(), "", map[string]string{
+		"AWS_DEFAULT_REGION":               "eu-west-1",
+		"AWS_ACCESS_KEY_ID":                "AKID",
+		"AWS_SECRET_ACCESS_KEY":            "secret",
+		"AWS_SESSION_TOKEN":                "session",
+		"AWS_ENDPOINT_URL_SECRETS_MANAGER": srv.URL,
+	})
+	require.NoError(t, err)
+	source.(*awsSource).now = func() time.Time { return time.Date(2021, 10, 14, 10, 15, 0, 0, time.UTC) }
+
+	value, err := source.Get("prod/db")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter22", value)
+	_, err = source.Get("prod/other")
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = NewAWSSource(srv.Client(), "eu-west-1", nil)
+	assert.Error(t, err)
+}
+
+func TestNewSources(t *testing.T) {
+	t.Parallel()
+	_, err := NewSources([]string{"keychain"}, nil, nil)
+	assert.EqualError(t, err, "unknown secret source 'keychain', it has to be env, file, vault or aws")
+	_, err = NewSources([]string{"file"}, nil, nil)
+	assert.Error(t, err)
+
+	sources, err := NewSources([]string{"env"}, nil, []string{"K6_SECRET_A=b=c", "OTHER=d"})
+	require.NoError(t, err)
+	value, err := sources[0].Get("A")
+	require.NoError(t, err)
+	assert.Equal(t, "b=c", value)
+	_, err = sources[0].Get("OTHER")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+type sourceFunc func(name string) (string, error)
+
+func (f sourceFunc) Get(name string) (string, error) {
+	return f(name)
+}
@@ -0,0 +1,100 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// EnvPrefix is the prefix of the environment variables of the env source,
+// the K6_SECRET_DB_PASSWORD variable is the secret DB_PASSWORD. These variables
+// aren't in __ENV even with --include-system-env-vars.
+const EnvPrefix = "K6_SECRET_"
+
+type envSource map[string]string
+
+// NewEnvSource returns a Source of the environment variables with EnvPrefix.
+func NewEnvSource(env map[string]string) Source {
+	source := make(envSource)
+	for key, value := range env {
+		if strings.HasPrefix(key, EnvPrefix) {
+			source[strings.TrimPrefix(key, EnvPrefix)] = value
+		}
+	}
+	return source
+}
+
+func (s envSource) Get(name string) (string, error) {
+	if value, ok := s[name]; ok {
+		return value, nil
+	}
+	return "", ErrNotFound
+}
+
+type fileSource map[string]string
+
+// NewFileSource returns a Source of the secrets in a file, which is a JSON
+// object with string values or a file with NAME=value lines, where the empty
+// lines and the ones starting with # are ignored.
+func NewFileSource(fs afero.Fs, path string) (Source, error) {
+	if path == "" {
+		return nil, fmt.Errorf("the file secret source needs a path, like file=secrets.json")
+	}
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the secrets file: %w", err)
+	}
+
+	source := make(fileSource)
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, (*map[string]string)(&source)); err != nil {
+			return nil, fmt.Errorf("the secrets file %s isn't a JSON object with string values: %w", path, err)
+		}
+		return source, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.IndexByte(line, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("the line %d of the secrets file %s isn't in the NAME=value format", n, path)
+		}
+		source[strings.TrimSpace(line[:i])] = line[i+1:]
+	}
+	return source, scanner.Err()
+}
+
+func (s fileSource) Get(name string) (string, error) {
+	if value, ok := s[name]; ok {
+		return value, nil
+	}
+	return "", ErrNotFound
+}
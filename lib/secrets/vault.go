@@ -0,0 +1,114 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// vaultSource gets the secrets from the keys of a secret of HashiCorp Vault,
+// which is read once, the first time one of them is needed.
+type vaultSource struct {
+	client *http.Client
+	url    string
+	token  string
+
+	once sync.Once
+	data map[string]string
+	err  error
+}
+
+// NewVaultSource returns a Source of the keys of the Vault secret at the path,
+// like secret/data/k6 for the k6 secret of the KV version 2 engine mounted at
+// secret, or secret/k6 with the version 1 engine.
+func NewVaultSource(client *http.Client, addr, token, path string) (Source, error) {
+	if addr == "" || token == "" {
+		return nil, errors.New("the vault secret source needs the VAULT_ADDR and VAULT_TOKEN environment variables")
+	}
+	if path == "" {
+		return nil, errors.New("the vault secret source needs the path of the secret, like vault=secret/data/k6")
+	}
+	return &vaultSource{
+		client: client,
+		url:    strings.TrimSuffix(addr, "/") + "/v1/" + strings.TrimPrefix(path, "/"),
+		token:  token,
+	}, nil
+}
+
+func (s *vaultSource) Get(name string) (string, error) {
+	s.once.Do(func() { s.data, s.err = s.read() })
+	if s.err != nil {
+		return "", s.err
+	}
+	if value, ok := s.data[name]; ok {
+		return value, nil
+	}
+	return "", ErrNotFound
+}
+
+func (s *vaultSource) read() (map[string]string, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the Vault secret: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("couldn't read the Vault secret %s, the status is %s", s.url, resp.Status)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid response of Vault: %w", err)
+	}
+	fields := body.Data
+	// the KV version 2 secrets are in the data of the data, next to the metadata
+	if _, ok := fields["metadata"]; ok {
+		if inner, ok := fields["data"]; ok {
+			fields = nil
+			if err := json.Unmarshal(inner, &fields); err != nil {
+				return nil, fmt.Errorf("invalid response of Vault: %w", err)
+			}
+		}
+	}
+
+	data := make(map[string]string, len(fields))
+	for key, raw := range fields {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			// the values that aren't strings are returned as JSON
+			value = string(raw)
+		}
+		data[key] = value
+	}
+	return data, nil
+}
@@ -26,11 +26,13 @@ import (
 	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"time"
 
 	"github.com/oxtoacart/bpool"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
 
+	"go.k6.io/k6/lib/secrets"
 	"go.k6.io/k6/stats"
 )
 
@@ -59,6 +61,14 @@ type State struct {
 
 	// Rate limits.
 	RPSLimit *rate.Limiter
+	// Limits the bytes per second written with k6/files.
+	FilesLimit *rate.Limiter
+	// Limit the in-flight requests of http.asyncRequest(), in total and per host.
+	AsyncRequestsLimit        SlotLimiter
+	AsyncRequestsPerHostLimit *MultiSlotLimiter
+
+	// The secrets of k6/secrets, nil if there aren't any secret sources.
+	Secrets *secrets.Store
 
 	// Sample channel, possibly buffered
 	Samples chan<- stats.SampleContainer
@@ -69,8 +79,12 @@ type State struct {
 
 	VUID, VUIDGlobal uint64
 	Iteration        int64
-	Tags             map[string]string
+	// The time when the current iteration started, for pacing the iterations.
+	IterationStartTime time.Time
+	Tags               map[string]string
 	// These will be assigned on VU activation.
+	// Whether the HTTP requests of the current scenario don't use keep-alive connections.
+	NoConnectionReuse bool
 	// Returns the iteration number of this VU in the current scenario.
 	GetScenarioVUIter func() uint64
 	// Returns the iteration number across all VUs in the current scenario
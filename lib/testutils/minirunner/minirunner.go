@@ -46,6 +46,9 @@ type MiniRunner struct {
 
 	SetupData []byte
 
+	ScenarioSetupFn    func(ctx context.Context, out chan<- stats.SampleContainer, scenario, fn string) error
+	ScenarioTeardownFn func(ctx context.Context, out chan<- stats.SampleContainer, scenario, fn string) error
+
 	Group   *lib.Group
 	Options lib.Options
 }
@@ -96,6 +99,23 @@ func (r MiniRunner) Teardown(ctx context.Context, out chan<- stats.SampleContain
 	return nil
 }
 
+// ScenarioSetup calls the supplied mock scenario setup function, if present.
+func (r MiniRunner) ScenarioSetup(ctx context.Context, out chan<- stats.SampleContainer, scenario, fn string) error {
+	if r.ScenarioSetupFn != nil {
+		return r.ScenarioSetupFn(ctx, out, scenario, fn)
+	}
+	return nil
+}
+
+// ScenarioTeardown calls the supplied mock scenario teardown function, if
+// present.
+func (r MiniRunner) ScenarioTeardown(ctx context.Context, out chan<- stats.SampleContainer, scenario, fn string) error {
+	if r.ScenarioTeardownFn != nil {
+		return r.ScenarioTeardownFn(ctx, out, scenario, fn)
+	}
+	return nil
+}
+
 // GetDefaultGroup returns the default group.
 func (r MiniRunner) GetDefaultGroup() *lib.Group {
 	if r.Group == nil {
@@ -0,0 +1,152 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// URLGroup is a rule of the urlGroups option, which groups the URLs with IDs and the like in the
+// name tag of the requests, e.g. https://example.com/users/123 as https://example.com/users/{id},
+// so the metrics don't have a time series for each ID.
+type URLGroup struct {
+	// A regular expression, the parts of the URLs that match it are replaced with the name,
+	// which can reference the groups of the expression like ${1}.
+	Pattern string `json:"pattern,omitempty"`
+	Name    string `json:"name,omitempty"`
+	// Or an OpenAPI path template like /users/{id}, the URLs with the paths that match it are
+	// grouped as their scheme and host with the template. A template with a scheme and a host is
+	// matched with the whole URL, without the query.
+	Template string `json:"template,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// NewURLGroup returns a URLGroup with either the pattern and the name or the template.
+func NewURLGroup(pattern, name, template string) (URLGroup, error) {
+	g := URLGroup{Pattern: pattern, Name: name, Template: template}
+	var err error
+	switch {
+	case pattern != "" && template != "":
+		return g, errors.New("a URL group has to have either a pattern or a template, not both")
+	case pattern != "":
+		if name == "" {
+			return g, fmt.Errorf("the URL group with the pattern '%s' doesn't have a name", pattern)
+		}
+		if g.re, err = regexp.Compile(pattern); err != nil {
+			return g, fmt.Errorf("invalid URL group pattern '%s': %w", pattern, err)
+		}
+	case template != "":
+		if g.re, err = regexp.Compile(templateRegexp(template)); err != nil {
+			return g, fmt.Errorf("invalid URL group template '%s': %w", template, err)
+		}
+	default:
+		return g, errors.New("a URL group has to have a pattern or a template")
+	}
+	return g, nil
+}
+
+// templateRegexp returns the regular expression of the paths or URLs that match a template,
+// where each {param} is a path segment.
+func templateRegexp(template string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for template != "" {
+		start := strings.IndexByte(template, '{')
+		end := -1
+		if start >= 0 {
+			end = strings.IndexByte(template[start:], '}')
+		}
+		if end < 0 {
+			b.WriteString(regexp.QuoteMeta(template))
+			break
+		}
+		b.WriteString(regexp.QuoteMeta(template[:start]))
+		b.WriteString("[^/]+")
+		template = template[start+end+1:]
+	}
+	b.WriteString("/?$")
+	return b.String()
+}
+
+// UnmarshalJSON parses and validates a URLGroup.
+func (g *URLGroup) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		Pattern  string `json:"pattern"`
+		Name     string `json:"name"`
+		Template string `json:"template"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	group, err := NewURLGroup(fields.Pattern, fields.Name, fields.Template)
+	if err != nil {
+		return err
+	}
+	*g = group
+	return nil
+}
+
+// Group returns the group of the URL and true, or false if the URL doesn't match the rule.
+// The cleanURL is the URL without the user credentials.
+func (g URLGroup) Group(u *url.URL, cleanURL string) (string, bool) {
+	if g.re == nil {
+		return "", false
+	}
+	if g.Template == "" {
+		if !g.re.MatchString(cleanURL) {
+			return "", false
+		}
+		return g.re.ReplaceAllString(cleanURL, g.Name), true
+	}
+	if strings.HasPrefix(g.Template, "/") {
+		if !g.re.MatchString(u.EscapedPath()) {
+			return "", false
+		}
+		return u.Scheme + "://" + u.Host + g.Template, true
+	}
+	withoutQuery := cleanURL
+	if i := strings.IndexAny(withoutQuery, "?#"); i >= 0 {
+		withoutQuery = withoutQuery[:i]
+	}
+	if !g.re.MatchString(withoutQuery) {
+		return "", false
+	}
+	return g.Template, true
+}
+
+// URLGroups are the rules of the urlGroups option, the first one that matches a URL is used.
+type URLGroups []URLGroup
+
+// Group returns the group of the URL from the first rule that matches it, or false if none does.
+func (gs URLGroups) Group(u *url.URL, cleanURL string) (string, bool) {
+	for _, g := range gs {
+		if name, ok := g.Group(u, cleanURL); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,76 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLGroups(t *testing.T) {
+	t.Parallel()
+	var groups URLGroups
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"pattern": "/[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}", "name": "/{uuid}"},
+		{"template": "/users/{id}/orders/{orderId}"},
+		{"template": "https://api.example.com/items/{id}"},
+		{"pattern": "^(https://shop\\.example\\.com)/p/\\d+", "name": "${1}/p/{id}"}
+	]`), &groups))
+
+	cases := map[string]string{
+		"https://example.com/users/123/orders/abc":                          "https://example.com/users/{id}/orders/{orderId}",
+		"https://example.com/users/123/orders/abc/":                         "https://example.com/users/{id}/orders/{orderId}",
+		"https://example.com/users/123/orders/abc?page=2":                   "https://example.com/users/{id}/orders/{orderId}",
+		"https://api.example.com/items/42?fields=name":                      "https://api.example.com/items/{id}",
+		"https://example.com/sessions/5f0c8a36-1c1d-4a3b-9e0f-6b8a7c9d0e1f": "https://example.com/sessions/{uuid}",
+		"https://shop.example.com/p/777?ref=home":                           "https://shop.example.com/p/{id}?ref=home",
+		"https://example.com/users/123":                                     "",
+		"https://example.com/users/123/orders/abc/items":                    "",
+		"https://other.example.com/items/42":                                "",
+	}
+	for rawURL, expected := range cases {
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		name, ok := groups.Group(u, rawURL)
+		assert.Equal(t, expected != "", ok, rawURL)
+		assert.Equal(t, expected, name, rawURL)
+	}
+
+	data, err := json.Marshal(groups[:2])
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"pattern": "/[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}", "name": "/{uuid}"},
+		{"template": "/users/{id}/orders/{orderId}"}
+	]`, string(data))
+
+	for _, invalid := range []string{
+		`[{"pattern": "("}]`,
+		`[{"pattern": "/users/\\d+"}]`,
+		`[{"pattern": "/users", "name": "users", "template": "/users"}]`,
+		`[{}]`,
+	} {
+		assert.Error(t, json.Unmarshal([]byte(invalid), &groups), invalid)
+	}
+}
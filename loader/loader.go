@@ -80,8 +80,9 @@ type noSchemeRemoteModuleResolutionError struct {
 func (n noSchemeRemoteModuleResolutionError) Error() string {
 	return fmt.Sprintf(
 		`Module specifier "%s" was tried to be loaded as remote module by prepending "https://" to it, `+
-			`which didn't work. If you are trying to import a nodejs module, this is not supported `+
-			`as k6 is _not_ nodejs based. Please read https://k6.io/docs/using-k6/modules for more information. `+
+			`which didn't work. If you are trying to import a nodejs module, only the pure JavaScript packages `+
+			`in a node_modules directory of the script, or of its parents, are supported as k6 is _not_ nodejs based. `+
+			`Please read https://k6.io/docs/using-k6/modules for more information. `+
 			`Remote resolution error: "%s"`, n.moduleSpecifier, n.err)
 }
 
@@ -223,6 +224,11 @@ func Load(
 		var result *SourceData
 		result, err = loadRemoteURL(logger, finalModuleSpecifierURL)
 		if err == nil {
+			if locked, ok := filesystems[scheme].(*lockedFs); ok {
+				if err = locked.lock.check(logger, remoteModuleURL(pathOnFs), result.Data); err != nil {
+					return nil, err
+				}
+			}
 			result.URL = moduleSpecifier
 			// TODO maybe make an afero.Fs which makes request directly and than use CacheOnReadFs
 			// on top of as with the `file` scheme fs
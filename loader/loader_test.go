@@ -204,6 +204,27 @@ func TestLoad(t *testing.T) {
 		})
 	})
 
+	t.Run("Locked", func(t *testing.T) {
+		root, err := url.Parse("file:///")
+		require.NoError(t, err)
+		moduleSpecifier := sr("HTTPSBIN_URL/robots.txt")
+		moduleSpecifierURL, err := loader.Resolve(root, moduleSpecifier)
+		require.NoError(t, err)
+
+		lock := &loader.Lockfile{Version: 1, Modules: map[string]loader.LockedModule{
+			moduleSpecifier: {Integrity: loader.Integrity([]byte("User-agent: *\nDisallow: /deny\n"))},
+		}}
+		filesystems := map[string]afero.Fs{"https": lock.LockFs(afero.NewMemMapFs())}
+		_, err = loader.Load(logger, filesystems, moduleSpecifierURL, moduleSpecifier)
+		require.NoError(t, err)
+
+		lock.Modules[moduleSpecifier] = loader.LockedModule{Integrity: loader.Integrity([]byte("changed"))}
+		filesystems = map[string]afero.Fs{"https": lock.LockFs(afero.NewMemMapFs())}
+		_, err = loader.Load(logger, filesystems, moduleSpecifierURL, moduleSpecifier)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the remote module "+moduleSpecifier+" has the integrity")
+	})
+
 	const responseStr = "export function fn() {\r\n    return 1234;\r\n}"
 	tb.Mux.HandleFunc("/raw/something", func(w http.ResponseWriter, r *http.Request) {
 		if _, ok := r.URL.Query()["_k6"]; ok {
@@ -0,0 +1,217 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package loader
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"go.k6.io/k6/lib/fsext"
+)
+
+const (
+	// LockfileName is the name of the lockfile of the remote modules, it's in the directory of the
+	// main script.
+	LockfileName = "k6.lock"
+	// VendorDirName is the name of the directory with the vendored remote modules, next to the
+	// lockfile.
+	VendorDirName = "vendor"
+
+	lockfileVersion = 1
+)
+
+// Lockfile has the integrity hashes of the remote modules of a script, so its runs fail instead
+// of running other code when the modules change. The modules can be vendored in the vendor
+// directory next to it, then they are loaded from there instead of being downloaded.
+type Lockfile struct {
+	Version int                     `json:"version"`
+	Modules map[string]LockedModule `json:"modules"`
+}
+
+// LockedModule is a remote module of a Lockfile.
+type LockedModule struct {
+	// Integrity is the hash of the module, like in the integrity attribute of the HTML scripts.
+	Integrity string `json:"integrity"`
+}
+
+// Integrity returns the sha256 integrity hash of the data.
+func Integrity(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// NewLockfile returns the lockfile of the remote modules that were loaded in the https filesystem.
+func NewLockfile(httpsFs afero.Fs) (*Lockfile, error) {
+	lock := &Lockfile{Version: lockfileVersion, Modules: make(map[string]LockedModule)}
+	err := walkRemoteModules(httpsFs, func(moduleURL string, data []byte) error {
+		lock.Modules[moduleURL] = LockedModule{Integrity: Integrity(data)}
+		return nil
+	})
+	return lock, err
+}
+
+// ReadLockfile reads the lockfile of the directory, it returns nil if there isn't one.
+func ReadLockfile(fs afero.Fs, dir string) (*Lockfile, error) {
+	data, err := afero.ReadFile(fs, filepath.Join(dir, LockfileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lock := &Lockfile{}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", LockfileName, err)
+	}
+	if lock.Version != lockfileVersion {
+		return nil, fmt.Errorf("unsupported version %d of %s", lock.Version, LockfileName)
+	}
+	return lock, nil
+}
+
+// Write writes the lockfile in the directory.
+func (l *Lockfile) Write(fs afero.Fs, dir string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return afero.WriteFile(fs, filepath.Join(dir, LockfileName), append(data, '\n'), 0o644)
+}
+
+// LoadVendored copies the vendored modules of the lockfile from the vendor directory in dir to the
+// https filesystem, where the loader finds them before downloading them. It returns the number of
+// modules that were vendored.
+func (l *Lockfile) LoadVendored(fs afero.Fs, dir string, httpsFs afero.Fs) (int, error) {
+	vendored := 0
+	for moduleURL, module := range l.Modules {
+		modulePath := remoteModulePath(moduleURL)
+		data, err := afero.ReadFile(fs, filepath.Join(dir, VendorDirName, filepath.FromSlash(modulePath)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return vendored, err
+		}
+		if integrity := Integrity(data); integrity != module.Integrity {
+			return vendored, fmt.Errorf("the vendored module %s has the integrity %s instead of %s of %s",
+				moduleURL, integrity, module.Integrity, LockfileName)
+		}
+		if err := afero.WriteFile(httpsFs, filepath.FromSlash("/"+modulePath), data, 0o644); err != nil {
+			return vendored, err
+		}
+		vendored++
+	}
+	return vendored, nil
+}
+
+// LockFs returns the https filesystem to use instead of httpsFs in the runs of the script of the
+// lockfile. Load checks that the remote modules it downloads in it have the integrity hashes of
+// the lockfile, before they are compiled. The modules that aren't in the lockfile are only logged,
+// so a new import doesn't break the runs before the lockfile is updated.
+func (l *Lockfile) LockFs(httpsFs afero.Fs) afero.Fs {
+	if locked, ok := httpsFs.(*lockedFs); ok {
+		httpsFs = locked.Fs
+	}
+	return &lockedFs{Fs: httpsFs, lock: l}
+}
+
+type lockedFs struct {
+	afero.Fs
+	lock *Lockfile
+}
+
+// check checks the downloaded data of the remote module against the lockfile.
+func (l *Lockfile) check(logger logrus.FieldLogger, moduleURL string, data []byte) error {
+	module, ok := l.Modules[moduleURL]
+	if !ok {
+		logger.Warnf("The remote module %s isn't in %s, update it with k6 vendor", moduleURL, LockfileName)
+		return nil
+	}
+	if integrity := Integrity(data); integrity != module.Integrity {
+		return fmt.Errorf("the remote module %s has the integrity %s instead of %s of %s, it changed since "+
+			"it was written", moduleURL, integrity, module.Integrity, LockfileName)
+	}
+	return nil
+}
+
+// Vendor writes the remote modules that were loaded in the https filesystem in the vendor
+// directory in dir.
+func Vendor(httpsFs afero.Fs, fs afero.Fs, dir string) error {
+	return walkRemoteModules(httpsFs, func(moduleURL string, data []byte) error {
+		target := filepath.Join(dir, VendorDirName, filepath.FromSlash(remoteModulePath(moduleURL)))
+		if err := fs.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		return afero.WriteFile(fs, target, data, 0o644)
+	})
+}
+
+// walkRemoteModules calls fn with the URLs and the contents of the modules in the https
+// filesystem, in the order of their URLs.
+func walkRemoteModules(httpsFs afero.Fs, fn func(moduleURL string, data []byte) error) error {
+	modules := make(map[string][]byte)
+	err := fsext.Walk(httpsFs, afero.FilePathSeparator, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := afero.ReadFile(httpsFs, filePath)
+		if err != nil {
+			return err
+		}
+		modules[remoteModuleURL(filePath)] = data
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	urls := make([]string, 0, len(modules))
+	for moduleURL := range modules {
+		urls = append(urls, moduleURL)
+	}
+	sort.Strings(urls)
+	for _, moduleURL := range urls {
+		if err := fn(moduleURL, modules[moduleURL]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remoteModulePath returns the path of the module in the https filesystem, without the leading
+// slash, which is also its path in the vendor directory.
+func remoteModulePath(moduleURL string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(moduleURL, "https://")), "/")
+}
+
+// remoteModuleURL returns the URL of the module at the path in the https filesystem.
+func remoteModuleURL(filePath string) string {
+	return "https://" + strings.TrimPrefix(filepath.ToSlash(filePath), "/")
+}
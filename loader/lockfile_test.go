@@ -0,0 +1,102 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package loader
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockfile(t *testing.T) {
+	t.Parallel()
+	utils := []byte("export function randomItem(a) { return a[0]; }")
+	httpsFs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(httpsFs, filepath.FromSlash("/jslib.k6.io/k6-utils/1.1.0/index.js"), utils, 0o644))
+	require.NoError(t, afero.WriteFile(httpsFs, filepath.FromSlash("/github.com/k6io/lib/util.js"), []byte("1"), 0o644))
+
+	lock, err := NewLockfile(httpsFs)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]LockedModule{
+		"https://jslib.k6.io/k6-utils/1.1.0/index.js": {Integrity: Integrity(utils)},
+		"https://github.com/k6io/lib/util.js":         {Integrity: "sha256-a4ayc/80/OGda4BO/1o/V0etpOqiLx1JwB5S3beHW0s="},
+	}, lock.Modules)
+
+	fs := afero.NewMemMapFs()
+	dir := filepath.FromSlash("/project")
+	require.NoError(t, lock.Write(fs, dir))
+	require.NoError(t, Vendor(httpsFs, fs, dir))
+	read, err := ReadLockfile(fs, dir)
+	require.NoError(t, err)
+	assert.Equal(t, lock, read)
+
+	vendored, err := afero.ReadFile(fs, filepath.FromSlash("/project/vendor/jslib.k6.io/k6-utils/1.1.0/index.js"))
+	require.NoError(t, err)
+	assert.Equal(t, utils, vendored)
+
+	// the runs load the vendored modules before downloading them
+	runFs := afero.NewMemMapFs()
+	n, err := read.LoadVendored(fs, dir, runFs)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	loaded, err := afero.ReadFile(runFs, filepath.FromSlash("/jslib.k6.io/k6-utils/1.1.0/index.js"))
+	require.NoError(t, err)
+	assert.Equal(t, utils, loaded)
+
+	logger, hook := test.NewNullLogger()
+	require.NoError(t, read.check(logger, "https://jslib.k6.io/k6-utils/1.1.0/index.js", utils))
+	assert.Empty(t, hook.Entries)
+
+	require.NoError(t, read.check(logger, "https://jslib.k6.io/new.js", []byte("2")))
+	require.Len(t, hook.Entries, 1)
+	assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+	assert.Contains(t, hook.LastEntry().Message, "https://jslib.k6.io/new.js")
+
+	err = read.check(logger, "https://github.com/k6io/lib/util.js", []byte("3"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "https://github.com/k6io/lib/util.js")
+
+	require.NoError(t, afero.WriteFile(fs, filepath.FromSlash("/project/vendor/github.com/k6io/lib/util.js"),
+		[]byte("changed"), 0o644))
+	_, err = read.LoadVendored(fs, dir, afero.NewMemMapFs())
+	assert.Error(t, err)
+}
+
+func TestReadLockfile(t *testing.T) {
+	t.Parallel()
+	fs := afero.NewMemMapFs()
+	lock, err := ReadLockfile(fs, "/")
+	require.NoError(t, err)
+	assert.Nil(t, lock)
+
+	require.NoError(t, afero.WriteFile(fs, "/k6.lock", []byte(`{"version": 2, "modules": {}}`), 0o644))
+	_, err = ReadLockfile(fs, "/")
+	assert.EqualError(t, err, "unsupported version 2 of k6.lock")
+
+	require.NoError(t, afero.WriteFile(fs, "/k6.lock", []byte(`{`), 0o644))
+	_, err = ReadLockfile(fs, "/")
+	assert.Error(t, err)
+}
@@ -0,0 +1,201 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// nodeModulesConditions are the conditions of the exports of the package.json files that k6
+// supports, in order of preference.
+var nodeModulesConditions = []string{"k6", "import", "require", "default"} //nolint:gochecknoglobals
+
+// packageJSON has the fields of the package.json files that are used for resolving the modules.
+type packageJSON struct {
+	Main    string          `json:"main"`
+	Exports json.RawMessage `json:"exports"`
+	Gypfile bool            `json:"gypfile"`
+}
+
+// IsBareSpecifier returns true for the module specifiers that are the names of packages, like
+// lodash or @scope/package/sub/module.js, instead of paths or URLs.
+func IsBareSpecifier(moduleSpecifier string) bool {
+	if moduleSpecifier == "" || moduleSpecifier[0] == '.' || moduleSpecifier[0] == '/' ||
+		filepath.IsAbs(moduleSpecifier) || strings.Contains(moduleSpecifier, "://") {
+		return false
+	}
+	_, loader, _ := pickLoader(moduleSpecifier)
+	return loader == nil
+}
+
+// ResolveNodeModule resolves a bare specifier to a file of a package in a node_modules directory,
+// in the directory of pwd or in the closest of its parents that has the package, like Node.js does.
+// It returns nil if there isn't such a package, so the specifier can be resolved as before. Only
+// pure JavaScript packages are supported, the ones with native addons return an error.
+func ResolveNodeModule(fs afero.Fs, pwd *url.URL, moduleSpecifier string) (*url.URL, error) {
+	if pwd.Scheme != "file" || !IsBareSpecifier(moduleSpecifier) {
+		return nil, nil
+	}
+	name, subpath := moduleSpecifier, ""
+	parts := strings.SplitN(moduleSpecifier, "/", 3)
+	switch {
+	case strings.HasPrefix(moduleSpecifier, "@") && len(parts) >= 2:
+		name = parts[0] + "/" + parts[1]
+		if len(parts) == 3 {
+			subpath = parts[2]
+		}
+	case len(parts) >= 2:
+		name = parts[0]
+		subpath = strings.SplitN(moduleSpecifier, "/", 2)[1]
+	}
+
+	for dir := path.Clean("/" + pwd.Path); ; dir = path.Dir(dir) {
+		pkgDir := path.Join(dir, "node_modules", name)
+		if info, err := fs.Stat(filepath.FromSlash(pkgDir)); err == nil && info.IsDir() {
+			resolved, err := resolvePackageFile(fs, pkgDir, moduleSpecifier, subpath)
+			if err != nil {
+				return nil, err
+			}
+			return &url.URL{Scheme: "file", Path: resolved}, nil
+		}
+		if dir == "/" {
+			return nil, nil
+		}
+	}
+}
+
+// resolvePackageFile returns the file of the package in the directory with the subpath, or its
+// main file if the subpath is empty.
+func resolvePackageFile(fs afero.Fs, pkgDir, moduleSpecifier, subpath string) (string, error) {
+	pkg, err := readPackageJSON(fs, pkgDir)
+	if err != nil {
+		return "", err
+	}
+	if pkg.Gypfile {
+		return "", fmt.Errorf("the package of '%s' has native addons, only pure JavaScript packages can be imported",
+			moduleSpecifier)
+	}
+	if _, err := fs.Stat(filepath.FromSlash(path.Join(pkgDir, "binding.gyp"))); err == nil {
+		return "", fmt.Errorf("the package of '%s' has native addons, only pure JavaScript packages can be imported",
+			moduleSpecifier)
+	}
+
+	target := subpath
+	if export, ok := pkg.export(subpath); ok {
+		target = export
+	} else if subpath == "" {
+		target = pkg.Main
+	}
+	if file, ok := resolveFile(fs, path.Join(pkgDir, target)); ok {
+		return file, nil
+	}
+	return "", fmt.Errorf("the module '%s' isn't in its package in %s", moduleSpecifier, pkgDir)
+}
+
+// export returns the target of the exports of the package.json for the subpath, the exports can be
+// a string, the conditions or a map of the subpaths to them.
+func (pkg packageJSON) export(subpath string) (string, bool) {
+	if len(pkg.Exports) == 0 {
+		return "", false
+	}
+	var exports interface{}
+	if err := json.Unmarshal(pkg.Exports, &exports); err != nil {
+		return "", false
+	}
+	if m, ok := exports.(map[string]interface{}); ok {
+		isSubpaths := false
+		for key := range m {
+			isSubpaths = strings.HasPrefix(key, ".")
+			break
+		}
+		if isSubpaths {
+			exports = m["./"+subpath]
+			if subpath == "" {
+				exports = m["."]
+			}
+		} else if subpath != "" {
+			return "", false
+		}
+	} else if subpath != "" {
+		return "", false
+	}
+	return exportTarget(exports)
+}
+
+func exportTarget(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case map[string]interface{}:
+		for _, condition := range nodeModulesConditions {
+			if target, ok := exportTarget(v[condition]); ok {
+				return target, true
+			}
+		}
+	}
+	return "", false
+}
+
+func readPackageJSON(fs afero.Fs, dir string) (packageJSON, error) {
+	var pkg packageJSON
+	data, err := afero.ReadFile(fs, filepath.FromSlash(path.Join(dir, "package.json")))
+	if os.IsNotExist(err) {
+		return pkg, nil
+	}
+	if err != nil {
+		return pkg, err
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return pkg, fmt.Errorf("invalid %s: %w", path.Join(dir, "package.json"), err)
+	}
+	return pkg, nil
+}
+
+// resolveFile returns the file of the path, like Node.js does: the file itself, with the .js
+// extension, or the main file or the index.js file if it's a directory.
+func resolveFile(fs afero.Fs, p string) (string, bool) {
+	for _, candidate := range []string{p, p + ".js"} {
+		if info, err := fs.Stat(filepath.FromSlash(candidate)); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	if info, err := fs.Stat(filepath.FromSlash(p)); err != nil || !info.IsDir() {
+		return "", false
+	}
+	if pkg, err := readPackageJSON(fs, p); err == nil && pkg.Main != "" && path.Clean(pkg.Main) != "." {
+		if file, ok := resolveFile(fs, path.Join(p, pkg.Main)); ok {
+			return file, true
+		}
+	}
+	index := path.Join(p, "index.js")
+	if info, err := fs.Stat(filepath.FromSlash(index)); err == nil && !info.IsDir() {
+		return index, true
+	}
+	return "", false
+}
@@ -0,0 +1,114 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package loader
+
+import (
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsBareSpecifier(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]bool{
+		"lodash":                        true,
+		"@scope/pkg/sub.js":             true,
+		"./lib.js":                      false,
+		"/abs/lib.js":                   false,
+		"https://jslib.k6.io/index.js":  false,
+		"github.com/k6io/k6/samples.js": false,
+		"":                              false,
+	}
+	for specifier, expected := range testCases {
+		assert.Equal(t, expected, IsBareSpecifier(specifier), specifier)
+	}
+}
+
+func TestResolveNodeModule(t *testing.T) {
+	t.Parallel()
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/project/node_modules/main/package.json":       `{"main": "dist/main"}`,
+		"/project/node_modules/main/dist/main.js":       ``,
+		"/project/node_modules/index/index.js":          ``,
+		"/project/node_modules/index/lib/sub/index.js":  ``,
+		"/project/node_modules/exports/package.json":    `{"main": "old.js", "exports": {".": {"node": "./node.js", "import": "./esm.js"}, "./util": "./src/util.js"}}`,
+		"/project/node_modules/exports/esm.js":          ``,
+		"/project/node_modules/exports/src/util.js":     ``,
+		"/project/node_modules/string/package.json":     `{"exports": "./lib/string.js"}`,
+		"/project/node_modules/string/lib/string.js":    ``,
+		"/project/node_modules/@scope/pkg/package.json": `{}`,
+		"/project/node_modules/@scope/pkg/index.js":     ``,
+		"/project/node_modules/@scope/pkg/helpers.js":   ``,
+		"/project/tests/node_modules/main/index.js":     ``,
+		"/project/node_modules/native/package.json":     `{"gypfile": true}`,
+		"/project/node_modules/native/index.js":         ``,
+		"/project/node_modules/broken/package.json":     `{`,
+		"/project/node_modules/nomain/package.json":     `{"main": "missing.js"}`,
+		"/project/node_modules/pkgdir/package.json":     `{}`,
+		"/project/node_modules/pkgdir/sub/package.json": `{"main": "./entry.js"}`,
+		"/project/node_modules/pkgdir/sub/entry.js":     ``,
+	}
+	for name, data := range files {
+		require.NoError(t, afero.WriteFile(fs, filepath.FromSlash(name), []byte(data), 0o644))
+	}
+
+	testCases := []struct {
+		pwd, specifier, expected string
+	}{
+		{"/project/", "main", "/project/node_modules/main/dist/main.js"},
+		{"/project/tests/sub/", "main", "/project/tests/node_modules/main/index.js"},
+		{"/project/tests/sub/", "index", "/project/node_modules/index/index.js"},
+		{"/project/", "index/lib/sub", "/project/node_modules/index/lib/sub/index.js"},
+		{"/project/", "exports", "/project/node_modules/exports/esm.js"},
+		{"/project/", "exports/util", "/project/node_modules/exports/src/util.js"},
+		{"/project/", "string", "/project/node_modules/string/lib/string.js"},
+		{"/project/", "@scope/pkg", "/project/node_modules/@scope/pkg/index.js"},
+		{"/project/", "@scope/pkg/helpers", "/project/node_modules/@scope/pkg/helpers.js"},
+		{"/project/", "pkgdir/sub", "/project/node_modules/pkgdir/sub/entry.js"},
+		{"/project/", "missing", ""},
+		{"/project/", "./main", ""},
+		{"/", "main", ""},
+	}
+	for _, tc := range testCases {
+		u, err := ResolveNodeModule(fs, &url.URL{Scheme: "file", Path: tc.pwd}, tc.specifier)
+		require.NoError(t, err, tc.specifier)
+		if tc.expected == "" {
+			assert.Nil(t, u, tc.specifier)
+			continue
+		}
+		require.NotNil(t, u, tc.specifier)
+		assert.Equal(t, "file://"+tc.expected, u.String(), tc.specifier)
+	}
+
+	for _, specifier := range []string{"native", "broken", "nomain", "exports/missing"} {
+		_, err := ResolveNodeModule(fs, &url.URL{Scheme: "file", Path: "/project/"}, specifier)
+		assert.Error(t, err, specifier)
+	}
+
+	u, err := ResolveNodeModule(fs, &url.URL{Scheme: "https", Host: "example.com", Path: "/project/"}, "main")
+	require.NoError(t, err)
+	assert.Nil(t, u)
+}
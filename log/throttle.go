@@ -0,0 +1,206 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// throttleWindow is the period in which at most the limit of identical messages are logged.
+const throttleWindow = time.Second
+
+// suppressedCount is the value of the suppressed field of the summaries, which aren't throttled.
+type suppressedCount int
+
+// Throttle drops the messages that are logged more times than its limit in a second, like the
+// same warning of thousands of VUs. The messages with the same level and text are identical,
+// regardless of their fields, and instead of the dropped ones there is a "suppressed X similar
+// messages" summary after each second.
+//
+// The messages are dropped before both the output and the hooks of the logger, like the ones of
+// Loki and of the outputs. The throttle is the only hook of the logger, it calls the other hooks
+// itself, the ones that are added later too, and it wraps the formatter, which formats the
+// dropped entries as nothing.
+type Throttle struct {
+	logger *logrus.Logger
+	limit  int
+
+	mu       sync.Mutex
+	hooks    logrus.LevelHooks
+	messages map[throttleKey]*throttledMessage
+	dropped  map[*logrus.Entry]bool
+}
+
+type throttleKey struct {
+	level   logrus.Level
+	message string
+}
+
+type throttledMessage struct {
+	count      int
+	suppressed int
+}
+
+// NewThrottle installs a throttle of up to limit identical messages per second in the logger,
+// the hooks and the formatter of the logger have to be set before.
+func NewThrottle(logger *logrus.Logger, limit int) *Throttle {
+	t := &Throttle{
+		logger:   logger,
+		limit:    limit,
+		hooks:    make(logrus.LevelHooks),
+		messages: make(map[throttleKey]*throttledMessage),
+		dropped:  make(map[*logrus.Entry]bool),
+	}
+	t.adoptHooks()
+	logger.SetFormatter(&throttleFormatter{throttle: t, formatter: logger.Formatter})
+	return t
+}
+
+// adoptHooks moves the hooks of the logger to the throttle, the ones before the throttle in the
+// hooks of the logger are called before the ones of the throttle, and the others after them.
+// It has to be called with the lock, or before the throttle is used.
+func (t *Throttle) adoptHooks() {
+	own := make(logrus.LevelHooks)
+	own.Add(t)
+	old := t.logger.ReplaceHooks(own)
+	for _, level := range logrus.AllLevels {
+		before, after := old[level], []logrus.Hook(nil)
+		for i, hook := range old[level] {
+			if hook == t {
+				before, after = old[level][:i], old[level][i+1:]
+				break
+			}
+		}
+		if len(before) == 0 && len(after) == 0 {
+			continue
+		}
+		hooks := make([]logrus.Hook, 0, len(before)+len(t.hooks[level])+len(after))
+		hooks = append(hooks, before...)
+		hooks = append(hooks, t.hooks[level]...)
+		t.hooks[level] = append(hooks, after...)
+	}
+}
+
+// Levels implements logrus.Hook, all of the entries are throttled.
+func (t *Throttle) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook, it calls the other hooks with the entry, unless it's over the
+// limit. The hooks that were added to the logger since the last entry are adopted, but they
+// aren't called with this entry, since the logger calls them itself.
+func (t *Throttle) Fire(entry *logrus.Entry) error {
+	t.mu.Lock()
+	hooks := t.hooks[entry.Level]
+	t.adoptHooks()
+	drop := false
+	if _, ok := entry.Data["suppressed"].(suppressedCount); !ok {
+		key := throttleKey{level: entry.Level, message: entry.Message}
+		msg, ok := t.messages[key]
+		if !ok {
+			msg = &throttledMessage{}
+			t.messages[key] = msg
+		}
+		msg.count++
+		if msg.count > t.limit {
+			msg.suppressed++
+			t.dropped[entry] = true
+			drop = true
+		}
+	}
+	t.mu.Unlock()
+
+	if drop {
+		return nil
+	}
+	for _, hook := range hooks {
+		if err := hook.Fire(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isDropped returns whether the entry was dropped by Fire, and forgets it.
+func (t *Throttle) isDropped(entry *logrus.Entry) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	dropped := t.dropped[entry]
+	delete(t.dropped, entry)
+	return dropped
+}
+
+// Run logs the summaries of the dropped messages every second, until the context is done.
+func (t *Throttle) Run(ctx context.Context) {
+	ticker := time.NewTicker(throttleWindow)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.Flush()
+		}
+	}
+}
+
+// Flush logs the summaries of the messages that were dropped since the last flush and starts a
+// new window, it has to be called before k6 exits.
+func (t *Throttle) Flush() {
+	t.mu.Lock()
+	var summaries []*logrus.Entry
+	for key, msg := range t.messages {
+		if msg.suppressed == 0 {
+			continue
+		}
+		summaries = append(summaries, &logrus.Entry{
+			Data:    logrus.Fields{"suppressed": suppressedCount(msg.suppressed)},
+			Level:   key.level,
+			Message: fmt.Sprintf("suppressed %d similar messages: %s", msg.suppressed, key.message),
+		})
+	}
+	t.messages = make(map[throttleKey]*throttledMessage)
+	t.mu.Unlock()
+
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Message < summaries[j].Message })
+	for _, summary := range summaries {
+		t.logger.WithFields(summary.Data).Log(summary.Level, summary.Message)
+	}
+}
+
+// throttleFormatter formats the entries that the throttle dropped as nothing.
+type throttleFormatter struct {
+	throttle  *Throttle
+	formatter logrus.Formatter
+}
+
+func (f *throttleFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if f.throttle.isDropped(entry) {
+		return nil, nil
+	}
+	return f.formatter.Format(entry)
+}
@@ -0,0 +1,103 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingHook struct {
+	messages []string
+}
+
+func (h *recordingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *recordingHook) Fire(entry *logrus.Entry) error {
+	h.messages = append(h.messages, entry.Message)
+	return nil
+}
+
+func TestThrottle(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+	before := &recordingHook{}
+	logger.AddHook(before)
+	throttle := NewThrottle(logger, 2)
+	logger.Info("adds the next hook")
+	after := &recordingHook{}
+	logger.AddHook(after)
+
+	lines := func() []string {
+		defer buf.Reset()
+		return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	}
+
+	for i := 0; i < 5; i++ {
+		logger.WithField("vu", i).Warn("Request Failed")
+	}
+	logger.Warn("another")
+	logger.Error("Request Failed")
+	assert.Equal(t, []string{
+		`level=info msg="adds the next hook"`,
+		`level=warning msg="Request Failed" vu=0`,
+		`level=warning msg="Request Failed" vu=1`,
+		`level=warning msg=another`,
+		`level=error msg="Request Failed"`,
+	}, lines())
+
+	throttle.Flush()
+	logger.Warn("Request Failed")
+	assert.Equal(t, []string{
+		`level=warning msg="suppressed 3 similar messages: Request Failed" suppressed=3`,
+		`level=warning msg="Request Failed"`,
+	}, lines())
+
+	logger.Warn("Request Failed")
+	logger.Warn("Request Failed")
+	throttle.Flush()
+	assert.Equal(t, []string{
+		`level=warning msg="Request Failed"`,
+		`level=warning msg="suppressed 1 similar messages: Request Failed" suppressed=1`,
+	}, lines())
+
+	throttle.Flush()
+	assert.Empty(t, buf.String())
+
+	// the hooks get the same messages as the output, the one that was added after the throttle
+	// gets them too since it was added
+	expected := []string{
+		"Request Failed", "Request Failed", "another", "Request Failed",
+		"suppressed 3 similar messages: Request Failed", "Request Failed",
+		"Request Failed", "suppressed 1 similar messages: Request Failed",
+	}
+	assert.Equal(t, append([]string{"adds the next hook"}, expected...), before.messages)
+	assert.Equal(t, expected, after.messages)
+}
@@ -0,0 +1,147 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package output
+
+import (
+	"sort"
+	"strings"
+
+	"go.k6.io/k6/stats"
+)
+
+// NewSink returns the sink of the values of a metric of the given type for the outputs that
+// aggregate the samples: the trends and the histograms keep all of their values, and the
+// counters and the throughputs are summed.
+func NewSink(t stats.MetricType) stats.Sink {
+	switch t {
+	case stats.Gauge:
+		return &stats.GaugeSink{}
+	case stats.Rate:
+		return &stats.RateSink{}
+	case stats.Trend, stats.Histogram:
+		return &stats.TrendSink{}
+	default:
+		return &stats.CounterSink{}
+	}
+}
+
+// FilterTags returns the tags without the empty ones and without the ones in the blocklist.
+func FilterTags(tags *stats.SampleTags, blocklist stats.TagSet) map[string]string {
+	filtered := tags.CloneTags()
+	for k, v := range filtered {
+		if v == "" || blocklist[k] {
+			delete(filtered, k)
+		}
+	}
+	return filtered
+}
+
+// AggregatedSeries are the values of the samples of a metric with the same tags.
+type AggregatedSeries struct {
+	Metric *stats.Metric
+	// The filtered tags of the samples, nil when the series doesn't have any.
+	Tags map[string]string
+	Sink stats.Sink
+	// Key is the same for the series of the same metric and tags in all of the aggregations,
+	// e.g. for keeping the totals of the counters.
+	Key string
+}
+
+// Aggregator groups the samples in series by their metrics and their tags, for the outputs that
+// send the values of intervals instead of the samples. It isn't safe for concurrent use.
+type Aggregator struct {
+	// TagBlocklist are the tags that the series don't have, besides the empty ones.
+	TagBlocklist stats.TagSet
+	// WithoutTags aggregates all of the samples of a metric in a single series.
+	WithoutTags bool
+	// SinkFor returns the sink of the series of a metric, the one of NewSink() if it's nil.
+	SinkFor func(*stats.Metric) stats.Sink
+
+	series map[string]*AggregatedSeries
+}
+
+// Add adds the samples of the containers to their series.
+func (a *Aggregator) Add(containers ...stats.SampleContainer) {
+	if a.series == nil {
+		a.series = make(map[string]*AggregatedSeries)
+	}
+	for _, sc := range containers {
+		for _, sample := range sc.GetSamples() {
+			var tags map[string]string
+			if !a.WithoutTags {
+				tags = FilterTags(sample.Tags, a.TagBlocklist)
+			}
+			key := seriesKey(sample.Metric.Name, tags)
+			s, ok := a.series[key]
+			if !ok {
+				s = &AggregatedSeries{Metric: sample.Metric, Sink: a.newSink(sample.Metric), Key: key}
+				if len(tags) > 0 {
+					s.Tags = tags
+				}
+				a.series[key] = s
+			}
+			s.Sink.Add(sample)
+		}
+	}
+}
+
+func (a *Aggregator) newSink(m *stats.Metric) stats.Sink {
+	if a.SinkFor != nil {
+		return a.SinkFor(m)
+	}
+	return NewSink(m.Type)
+}
+
+// Series returns the series of all of the added samples, sorted by the names of their metrics
+// and then by their tags, so the series of each metric are together.
+func (a *Aggregator) Series() []*AggregatedSeries {
+	keys := make([]string, 0, len(a.series))
+	for key := range a.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	series := make([]*AggregatedSeries, len(keys))
+	for i, key := range keys {
+		series[i] = a.series[key]
+	}
+	return series
+}
+
+// AggregateSamples returns the series of the samples, with the tags filtered by the blocklist.
+func AggregateSamples(containers []stats.SampleContainer, tagBlocklist stats.TagSet) []*AggregatedSeries {
+	a := &Aggregator{TagBlocklist: tagBlocklist}
+	a.Add(containers...)
+	return a.Series()
+}
+
+func seriesKey(name string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		b.WriteString("\x00" + k + "=" + tags[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,69 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/stats"
+)
+
+func TestAggregator(t *testing.T) {
+	t.Parallel()
+	reqs := stats.New("http_reqs", stats.Counter)
+	duration := stats.New("http_req_duration", stats.Trend)
+	get := stats.NewSampleTags(map[string]string{"method": "GET", "vu": "1", "empty": ""})
+	post := stats.NewSampleTags(map[string]string{"method": "POST", "vu": "2"})
+	now := time.Now()
+	samples := []stats.SampleContainer{stats.Samples{
+		{Metric: reqs, Value: 1, Time: now, Tags: get},
+		{Metric: reqs, Value: 1, Time: now, Tags: post},
+		{Metric: reqs, Value: 1, Time: now, Tags: get},
+		{Metric: duration, Value: 100, Time: now, Tags: get},
+		{Metric: duration, Value: 300, Time: now},
+	}}
+
+	series := AggregateSamples(samples, stats.TagSet{"vu": true})
+	require.Len(t, series, 4)
+	assert.Equal(t, duration, series[0].Metric)
+	assert.Nil(t, series[0].Tags)
+	assert.Equal(t, []float64{300}, series[0].Sink.(*stats.TrendSink).Values)
+	assert.Equal(t, map[string]string{"method": "GET"}, series[1].Tags)
+	assert.Equal(t, reqs, series[2].Metric)
+	assert.Equal(t, map[string]string{"method": "GET"}, series[2].Tags)
+	assert.Equal(t, 2.0, series[2].Sink.(*stats.CounterSink).Value)
+	assert.Equal(t, map[string]string{"method": "POST"}, series[3].Tags)
+	assert.Equal(t, AggregateSamples(samples[:1], stats.TagSet{"vu": true})[2].Key, series[2].Key)
+
+	a := &Aggregator{
+		WithoutTags: true,
+		SinkFor:     func(m *stats.Metric) stats.Sink { return &stats.GaugeSink{} },
+	}
+	a.Add(samples...)
+	series = a.Series()
+	require.Len(t, series, 2)
+	assert.Nil(t, series[1].Tags)
+	assert.Equal(t, 1.0, series[1].Sink.(*stats.GaugeSink).Value)
+}
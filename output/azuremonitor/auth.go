@@ -0,0 +1,64 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package azuremonitor
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.k6.io/k6/output"
+)
+
+// The AAD resource of the custom metrics of Azure Monitor.
+const monitoringResource = "https://monitoring.azure.com/"
+
+// tokenSource returns the access tokens for the custom metrics API, the configured access token
+// or the tokens of the AAD application, which it gets with the client credentials flow. It's only
+// used by the flushing goroutine.
+type tokenSource struct {
+	config Config
+	cache  *output.TokenCache
+}
+
+func newTokenSource(config Config, client *http.Client, now func() time.Time) *tokenSource {
+	return &tokenSource{
+		config: config,
+		cache:  &output.TokenCache{Name: "AAD access token", Client: client, Now: now},
+	}
+}
+
+func (ts *tokenSource) accessToken() (string, error) {
+	if ts.config.AccessToken.String != "" {
+		return ts.config.AccessToken.String, nil
+	}
+	tokenURL := strings.TrimSuffix(ts.config.AuthorityURL.String, "/") + "/" +
+		url.PathEscape(ts.config.TenantID.String) + "/oauth2/token"
+	return ts.cache.Token(tokenURL, func(time.Time) (url.Values, error) {
+		return url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {ts.config.ClientID.String},
+			"client_secret": {ts.config.ClientSecret.String},
+			"resource":      {monitoringResource},
+		}, nil
+	})
+}
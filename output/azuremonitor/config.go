@@ -0,0 +1,224 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package azuremonitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+// Config is the config for the azure-monitor output
+type Config struct {
+	// The Azure resource that the custom metrics are published for, like
+	// /subscriptions/{id}/resourceGroups/{group}/providers/Microsoft.Compute/virtualMachines/{vm},
+	// and its region, or the URL of the metrics endpoint for the sovereign clouds.
+	ResourceID null.String `json:"resourceId" envconfig:"K6_AZURE_MONITOR_RESOURCE_ID"`
+	Region     null.String `json:"region" envconfig:"K6_AZURE_MONITOR_REGION"`
+	URL        null.String `json:"url" envconfig:"K6_AZURE_MONITOR_URL"`
+	Namespace  null.String `json:"namespace" envconfig:"K6_AZURE_MONITOR_NAMESPACE"`
+
+	// The AAD application that has the Monitoring Metrics Publisher role for the resource, or an
+	// access token for the https://monitoring.azure.com/ resource, like from az account get-access-token.
+	TenantID     null.String `json:"tenantId" envconfig:"K6_AZURE_MONITOR_TENANT_ID"`
+	ClientID     null.String `json:"clientId" envconfig:"K6_AZURE_MONITOR_CLIENT_ID"`
+	ClientSecret null.String `json:"clientSecret" envconfig:"K6_AZURE_MONITOR_CLIENT_SECRET"`
+	AccessToken  null.String `json:"accessToken" envconfig:"K6_AZURE_MONITOR_ACCESS_TOKEN"`
+	AuthorityURL null.String `json:"authorityUrl" envconfig:"K6_AZURE_MONITOR_AUTHORITY_URL"`
+
+	FlushInterval types.NullDuration `json:"flushInterval" envconfig:"K6_AZURE_MONITOR_FLUSH_INTERVAL"`
+	Timeout       types.NullDuration `json:"timeout" envconfig:"K6_AZURE_MONITOR_TIMEOUT"`
+	TagBlocklist  stats.TagSet       `json:"tagBlocklist" envconfig:"K6_AZURE_MONITOR_TAG_BLOCKLIST"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Namespace:     null.StringFrom("k6"),
+		AuthorityURL:  null.StringFrom("https://login.microsoftonline.com"),
+		FlushInterval: types.NullDurationFrom(time.Minute),
+		Timeout:       types.NullDurationFrom(10 * time.Second),
+		TagBlocklist:  (stats.TagVU | stats.TagIter | stats.TagURL).Map(),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.ResourceID.Valid {
+		c.ResourceID = cfg.ResourceID
+	}
+	if cfg.Region.Valid {
+		c.Region = cfg.Region
+	}
+	if cfg.URL.Valid {
+		c.URL = cfg.URL
+	}
+	if cfg.Namespace.Valid {
+		c.Namespace = cfg.Namespace
+	}
+	if cfg.TenantID.Valid {
+		c.TenantID = cfg.TenantID
+	}
+	if cfg.ClientID.Valid {
+		c.ClientID = cfg.ClientID
+	}
+	if cfg.ClientSecret.Valid {
+		c.ClientSecret = cfg.ClientSecret
+	}
+	if cfg.AccessToken.Valid {
+		c.AccessToken = cfg.AccessToken
+	}
+	if cfg.AuthorityURL.Valid {
+		c.AuthorityURL = cfg.AuthorityURL
+	}
+	if cfg.FlushInterval.Valid {
+		c.FlushInterval = cfg.FlushInterval
+	}
+	if cfg.Timeout.Valid {
+		c.Timeout = cfg.Timeout
+	}
+	if cfg.TagBlocklist != nil {
+		c.TagBlocklist = cfg.TagBlocklist
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config, like in
+// --out azure-monitor=region=westeurope,namespace=checkout, the tagBlocklist key can be repeated.
+// The clientSecret and the accessToken aren't accepted here, like the other credentials of the
+// outputs they have to be in the environment variables or in the JSON config.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	for _, pair := range strings.Split(arg, ",") {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			return c, fmt.Errorf("couldn't parse %q as argument for azure-monitor output", arg)
+		}
+		switch r[0] {
+		case "resourceId":
+			c.ResourceID = null.StringFrom(r[1])
+		case "region":
+			c.Region = null.StringFrom(r[1])
+		case "url":
+			c.URL = null.StringFrom(r[1])
+		case "namespace":
+			c.Namespace = null.StringFrom(r[1])
+		case "tenantId":
+			c.TenantID = null.StringFrom(r[1])
+		case "clientId":
+			c.ClientID = null.StringFrom(r[1])
+		case "authorityUrl":
+			c.AuthorityURL = null.StringFrom(r[1])
+		case "flushInterval":
+			if err := c.FlushInterval.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "timeout":
+			if err := c.Timeout.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "tagBlocklist":
+			if c.TagBlocklist == nil {
+				c.TagBlocklist = make(stats.TagSet)
+			}
+			c.TagBlocklist[r[1]] = true
+		case "clientSecret", "accessToken":
+			return c, fmt.Errorf("the azure-monitor %s can't be in the argument, use an environment variable instead", r[0])
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for azure-monitor output", r[0])
+		}
+	}
+
+	return c, nil
+}
+
+// Validate returns an error if any config value is invalid.
+func (c Config) Validate() error {
+	if !strings.HasPrefix(c.ResourceID.String, "/subscriptions/") {
+		return fmt.Errorf("the azure-monitor output needs the ID of the resource, like " +
+			"/subscriptions/{id}/resourceGroups/{group}/providers/{provider}/{type}/{name}")
+	}
+	if c.Region.String == "" && c.URL.String == "" {
+		return fmt.Errorf("the azure-monitor output needs the region of the resource, like westeurope")
+	}
+	if c.AccessToken.String == "" &&
+		(c.TenantID.String == "" || c.ClientID.String == "" || c.ClientSecret.String == "") {
+		return fmt.Errorf("the azure-monitor output needs the tenantId, the clientId and the clientSecret " +
+			"of an AAD application, or an accessToken")
+	}
+	if c.Namespace.String == "" {
+		return fmt.Errorf("the azure-monitor namespace can't be empty")
+	}
+	if c.FlushInterval.Duration <= 0 {
+		return fmt.Errorf("the azure-monitor flushInterval should be positive, but it's %s", c.FlushInterval)
+	}
+	if c.Timeout.Duration <= 0 {
+		return fmt.Errorf("the azure-monitor timeout should be positive, but it's %s", c.Timeout)
+	}
+	return nil
+}
+
+// metricsURL returns the URL of the custom metrics of the resource.
+func (c Config) metricsURL() string {
+	base := c.URL.String
+	if base == "" {
+		base = "https://" + c.Region.String + ".monitoring.azure.com"
+	}
+	return strings.TrimSuffix(base, "/") + c.ResourceID.String + "/metrics"
+}
+
+// GetConsolidatedConfig combines {default config values + JSON config +
+// environment vars + arg config values}, and returns the final result.
+func GetConsolidatedConfig(jsonRawConf json.RawMessage, env map[string]string, arg string) (Config, error) {
+	result := NewConfig()
+	if jsonRawConf != nil {
+		jsonConf := Config{}
+		if err := json.Unmarshal(jsonRawConf, &jsonConf); err != nil {
+			return result, err
+		}
+		result = result.Apply(jsonConf)
+	}
+
+	envConfig := Config{}
+	if err := envconfig.Process("", &envConfig); err != nil {
+		// TODO: get rid of envconfig and actually use the env parameter...
+		return result, err
+	}
+	result = result.Apply(envConfig)
+
+	if arg != "" {
+		argConf, err := ParseArg(arg)
+		if err != nil {
+			return result, err
+		}
+		result = result.Apply(argConf)
+	}
+
+	return result, result.Validate()
+}
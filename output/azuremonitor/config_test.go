@@ -0,0 +1,105 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package azuremonitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+const testResourceID = "/subscriptions/1234/resourceGroups/loadtests/providers/Microsoft.Web/sites/checkout"
+
+func TestParseArg(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		config      Config
+		expectedErr bool
+	}{
+		"region=westeurope,namespace=checkout,flushInterval=30s": {
+			config: Config{
+				Region:        null.StringFrom("westeurope"),
+				Namespace:     null.StringFrom("checkout"),
+				FlushInterval: types.NullDurationFrom(30 * time.Second),
+			},
+		},
+		"resourceId=" + testResourceID + ",tenantId=t,clientId=c,tagBlocklist=name": {
+			config: Config{
+				ResourceID:   null.StringFrom(testResourceID),
+				TenantID:     null.StringFrom("t"),
+				ClientID:     null.StringFrom("c"),
+				TagBlocklist: stats.TagSet{"name": true},
+			},
+		},
+		"clientSecret=secret": {expectedErr: true},
+		"accessToken=token":   {expectedErr: true},
+		"westeurope":          {expectedErr: true},
+		"foo=bar":             {expectedErr: true},
+		"timeout=never":       {expectedErr: true},
+	}
+
+	for arg, tc := range cases {
+		arg, tc := arg, tc
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+			config, err := ParseArg(arg)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.config, config)
+		})
+	}
+}
+
+func TestGetConsolidatedConfig(t *testing.T) {
+	t.Parallel()
+	jsonConf := []byte(`{"resourceId": "` + testResourceID + `", "tenantId": "t", "clientId": "c", "clientSecret": "s"}`)
+	config, err := GetConsolidatedConfig(jsonConf, nil, "region=westeurope")
+	require.NoError(t, err)
+	expected := NewConfig()
+	expected.ResourceID = null.StringFrom(testResourceID)
+	expected.Region = null.StringFrom("westeurope")
+	expected.TenantID = null.StringFrom("t")
+	expected.ClientID = null.StringFrom("c")
+	expected.ClientSecret = null.StringFrom("s")
+	assert.Equal(t, expected, config)
+	assert.Equal(t, "https://westeurope.monitoring.azure.com"+testResourceID+"/metrics", config.metricsURL())
+
+	invalid := map[string]string{
+		`{"region": "westeurope", "accessToken": "x"}`:                                            "",
+		`{"resourceId": "` + testResourceID + `", "accessToken": "x"}`:                            "",
+		`{"resourceId": "` + testResourceID + `", "region": "westeurope"}`:                        "",
+		`{"resourceId": "` + testResourceID + `", "clientId": "c", "region": "west"}`:             "",
+		`{"resourceId": "` + testResourceID + `", "accessToken": "x", "url": "http://localhost"}`: "flushInterval=0s",
+	}
+	for conf, arg := range invalid {
+		_, err := GetConsolidatedConfig([]byte(conf), nil, arg)
+		assert.Error(t, err, conf)
+	}
+}
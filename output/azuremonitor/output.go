@@ -0,0 +1,276 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package azuremonitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+// Azure Monitor limits the custom metrics to 10 dimensions.
+const maxDimensions = 10
+
+// Output publishes the metrics to Azure Monitor as custom metrics of a resource, with the
+// minimum, the maximum, the sum and the count of the values of each interval, and with the tags
+// of the samples as their dimensions.
+type Output struct {
+	output.SampleBuffer
+
+	config          Config
+	logger          logrus.FieldLogger
+	client          *http.Client
+	tokens          *tokenSource
+	periodicFlusher *output.PeriodicFlusher
+	intervalStart   time.Time
+	warnedMetrics   map[string]bool
+	now             func() time.Time
+}
+
+var _ output.Output = new(Output)
+
+// New returns a new azure-monitor output
+func New(params output.Params) (output.Output, error) {
+	return newOutput(params)
+}
+
+func newOutput(params output.Params) (*Output, error) {
+	conf, err := GetConsolidatedConfig(params.JSONConfig, params.Environment, params.ConfigArgument)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: time.Duration(conf.Timeout.Duration)}
+	return &Output{
+		config:        conf,
+		logger:        params.Logger.WithFields(logrus.Fields{"output": "azure-monitor"}),
+		client:        client,
+		tokens:        newTokenSource(conf, client, time.Now),
+		warnedMetrics: make(map[string]bool),
+		now:           time.Now,
+	}, nil
+}
+
+// Description returns a human-readable description of the output.
+func (o *Output) Description() string {
+	return fmt.Sprintf("azure-monitor (%s)", o.config.ResourceID.String)
+}
+
+// Start gets the first access token, so that the misconfigured credentials fail the test run
+// before it starts, and starts the goroutine for the flushing of the metrics.
+func (o *Output) Start() error {
+	o.logger.Debug("Starting...")
+	if _, err := o.tokens.accessToken(); err != nil {
+		return err
+	}
+	o.intervalStart = o.now()
+	pf, err := output.NewDrainingPeriodicFlusher(
+		time.Duration(o.config.FlushInterval.Duration), o.flushMetrics, o.HasSpilledSamples)
+	if err != nil {
+		return err
+	}
+	o.logger.Debug("Started!")
+	o.periodicFlusher = pf
+	return nil
+}
+
+// Stop flushes any remaining metrics and stops the goroutine.
+func (o *Output) Stop() error {
+	o.logger.Debug("Stopping...")
+	defer o.logger.Debug("Stopped!")
+	o.periodicFlusher.Stop()
+	return nil
+}
+
+// The body of the custom metrics requests, see
+// https://docs.microsoft.com/azure/azure-monitor/essentials/metrics-store-custom-rest-api
+type (
+	customMetric struct {
+		Time string     `json:"time"`
+		Data metricData `json:"data"`
+	}
+	metricData struct {
+		BaseData baseData `json:"baseData"`
+	}
+	baseData struct {
+		Metric    string   `json:"metric"`
+		Namespace string   `json:"namespace"`
+		DimNames  []string `json:"dimNames,omitempty"`
+		Series    []series `json:"series"`
+	}
+	series struct {
+		DimValues []string `json:"dimValues,omitempty"`
+		Min       float64  `json:"min"`
+		Max       float64  `json:"max"`
+		Sum       float64  `json:"sum"`
+		Count     int64    `json:"count"`
+	}
+)
+
+func (s *series) add(value float64) {
+	if s.Count == 0 || value < s.Min {
+		s.Min = value
+	}
+	if s.Count == 0 || value > s.Max {
+		s.Max = value
+	}
+	s.Sum += value
+	s.Count++
+}
+
+// aggregate returns a custom metric for each metric of the samples, with a series for each
+// combination of the values of its dimensions. All of the series of a metric have the same
+// dimensions, the tags that aren't in some samples have empty values.
+func (o *Output) aggregate(containers []stats.SampleContainer, start time.Time) []customMetric {
+	type metricSeries struct {
+		dims   map[string]bool
+		tags   []map[string]string
+		values []float64
+	}
+	byMetric := make(map[string]*metricSeries)
+	for _, sc := range containers {
+		for _, sample := range sc.GetSamples() {
+			ms, ok := byMetric[sample.Metric.Name]
+			if !ok {
+				ms = &metricSeries{dims: make(map[string]bool)}
+				byMetric[sample.Metric.Name] = ms
+			}
+			tags := output.FilterTags(sample.Tags, o.config.TagBlocklist)
+			for k := range tags {
+				ms.dims[k] = true
+			}
+			ms.tags = append(ms.tags, tags)
+			ms.values = append(ms.values, sample.Value)
+		}
+	}
+
+	names := make([]string, 0, len(byMetric))
+	for name := range byMetric {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := make([]customMetric, 0, len(names))
+	for _, name := range names {
+		ms := byMetric[name]
+		dimNames := make([]string, 0, len(ms.dims))
+		for dim := range ms.dims {
+			dimNames = append(dimNames, dim)
+		}
+		sort.Strings(dimNames)
+		if len(dimNames) > maxDimensions {
+			if !o.warnedMetrics[name] {
+				o.warnedMetrics[name] = true
+				o.logger.Warnf("The metric '%s' has more than %d tags, only %s are sent as its dimensions, "+
+					"use the tagBlocklist option for choosing them", name, maxDimensions,
+					strings.Join(dimNames[:maxDimensions], ", "))
+			}
+			dimNames = dimNames[:maxDimensions]
+		}
+
+		bySeries := make(map[string]*series)
+		var keys []string
+		for i, tags := range ms.tags {
+			dimValues := make([]string, len(dimNames))
+			for j, dim := range dimNames {
+				dimValues[j] = tags[dim]
+			}
+			key := strings.Join(dimValues, "\x00")
+			s, ok := bySeries[key]
+			if !ok {
+				s = &series{DimValues: dimValues}
+				bySeries[key] = s
+				keys = append(keys, key)
+			}
+			s.add(ms.values[i])
+		}
+		sort.Strings(keys)
+
+		data := baseData{Metric: name, Namespace: o.config.Namespace.String, DimNames: dimNames}
+		for _, key := range keys {
+			data.Series = append(data.Series, *bySeries[key])
+		}
+		metrics = append(metrics, customMetric{
+			Time: start.UTC().Format(time.RFC3339),
+			Data: metricData{BaseData: data},
+		})
+	}
+	return metrics
+}
+
+func (o *Output) flushMetrics() {
+	samples := o.GetBufferedSamples()
+	start := o.intervalStart
+	o.intervalStart = o.now()
+	if len(samples) == 0 {
+		return
+	}
+	metrics := o.aggregate(samples, start)
+	failed := 0
+	for _, m := range metrics {
+		if err := o.send(m); err != nil {
+			failed++
+			o.logger.WithError(err).Debugf("Couldn't publish the metric %s", m.Data.BaseData.Metric)
+		}
+	}
+	if failed > 0 {
+		o.logger.Warnf("Couldn't publish %d out of %d metrics to Azure Monitor, "+
+			"enable verbose logging with --verbose to see the errors", failed, len(metrics))
+	}
+}
+
+// send publishes a single custom metric, since the API accepts only one metric per request.
+func (o *Output) send(m customMetric) error {
+	token, err := o.tokens.accessToken()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, o.config.metricsURL(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
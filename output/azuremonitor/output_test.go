@@ -0,0 +1,126 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package azuremonitor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+func TestOutput(t *testing.T) {
+	t.Parallel()
+	var (
+		mu         sync.Mutex
+		tokenCalls int
+		published  []customMetric
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tenant/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+		assert.Equal(t, "secret", r.PostForm.Get("client_secret"))
+		assert.Equal(t, monitoringResource, r.PostForm.Get("resource"))
+		mu.Lock()
+		tokenCalls++
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"token_type": "Bearer", "expires_in": "3599", "access_token": "token"}`))
+	})
+	mux.HandleFunc(testResourceID+"/metrics", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		var m customMetric
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&m))
+		mu.Lock()
+		published = append(published, m)
+		mu.Unlock()
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	o, err := newOutput(output.Params{
+		Logger: testutils.NewLogger(t),
+		JSONConfig: []byte(`{"resourceId": "` + testResourceID + `", "tenantId": "tenant", ` +
+			`"clientId": "client", "clientSecret": "secret", "url": "` + srv.URL + `/", ` +
+			`"authorityUrl": "` + srv.URL + `"}`),
+		ConfigArgument: "flushInterval=1h",
+	})
+	require.NoError(t, err)
+	o.now = func() time.Time { return time.Date(2021, time.February, 24, 13, 37, 0, 0, time.UTC) }
+	require.NoError(t, o.Start())
+
+	duration := stats.New("http_req_duration", stats.Trend)
+	vus := stats.New("vus", stats.Gauge)
+	get := stats.NewSampleTags(map[string]string{"method": "GET", "vu": "1"})
+	post := stats.NewSampleTags(map[string]string{"method": "POST", "status": "201"})
+	now := time.Now()
+	o.AddMetricSamples([]stats.SampleContainer{stats.Samples{
+		{Metric: duration, Value: 100, Time: now, Tags: get},
+		{Metric: duration, Value: 300, Time: now, Tags: get},
+		{Metric: duration, Value: 50, Time: now, Tags: post},
+		{Metric: vus, Value: 5, Time: now},
+	}})
+	require.NoError(t, o.Stop())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, tokenCalls)
+	assert.Equal(t, []customMetric{
+		{Time: "2021-02-24T13:37:00Z", Data: metricData{BaseData: baseData{
+			Metric: "http_req_duration", Namespace: "k6", DimNames: []string{"method", "status"},
+			Series: []series{
+				{DimValues: []string{"GET", ""}, Min: 100, Max: 300, Sum: 400, Count: 2},
+				{DimValues: []string{"POST", "201"}, Min: 50, Max: 50, Sum: 50, Count: 1},
+			},
+		}}},
+		{Time: "2021-02-24T13:37:00Z", Data: metricData{BaseData: baseData{
+			Metric: "vus", Namespace: "k6",
+			Series: []series{{Min: 5, Max: 5, Sum: 5, Count: 1}},
+		}}},
+	}, published)
+}
+
+func TestOutputInvalidCredentials(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+	}))
+	defer srv.Close()
+
+	o, err := newOutput(output.Params{
+		Logger: testutils.NewLogger(t),
+		JSONConfig: []byte(`{"resourceId": "` + testResourceID + `", "region": "westeurope", ` +
+			`"tenantId": "tenant", "clientId": "client", "clientSecret": "secret", "authorityUrl": "` + srv.URL + `"}`),
+	})
+	require.NoError(t, err)
+	assert.EqualError(t, o.Start(), `couldn't get the AAD access token, status 401: {"error": "invalid_client"}`)
+}
@@ -45,6 +45,20 @@ func toMicroSecond(t time.Time) int64 {
 	return t.UnixNano() / 1000
 }
 
+// cloudMetricType returns the metric type that is sent to the cloud, which only knows the
+// original four metric types. Throughputs are sent as counters and histograms as trends, the
+// backend calculates their aggregates from the raw values anyway.
+func cloudMetricType(t stats.MetricType) stats.MetricType {
+	switch t {
+	case stats.Throughput:
+		return stats.Counter
+	case stats.Histogram:
+		return stats.Trend
+	default:
+		return t
+	}
+}
+
 // Sample is the generic struct that contains all types of data that we send to the cloud.
 //easyjson:json
 type Sample struct {
@@ -53,6 +53,7 @@ type Output struct {
 
 	executionPlan []lib.ExecutionStep
 	duration      int64 // in seconds
+	runMetadata   *lib.TestRunMetadata
 	thresholds    map[string][]*stats.Threshold
 	client        *MetricsClient
 
@@ -62,6 +63,12 @@ type Output struct {
 	bufferHTTPTrails []*httpext.Trail
 	bufferSamples    []*Sample
 
+	// aggrExcludedNames are the names of the HTTP requests that aren't aggregated
+	aggrExcludedNames map[string]bool
+
+	// spill keeps the samples that couldn't be pushed, it's nil if it's disabled
+	spill *metricsSpill
+
 	logger logrus.FieldLogger
 	opts   lib.Options
 
@@ -142,13 +149,23 @@ func newOutput(params output.Params) (*Output, error) {
 			conf.MaxMetricSamplesPerPackage.Int64)
 	}
 
-	apiClient := cloudapi.NewClient(logger, conf.Token.String, conf.Host.String, consts.Version)
+	apiClient, err := cloudapi.NewClientFromConfig(logger, conf, consts.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	var spill *metricsSpill
+	if conf.MetricSpillMaxSize.Int64 > 0 {
+		spill = newMetricsSpill(conf.MetricSpillMaxSize.Int64)
+	}
 
 	return &Output{
 		config:        conf,
+		spill:         spill,
 		client:        NewMetricsClient(apiClient, logger, conf.Host.String, conf.NoCompress.Bool),
 		executionPlan: params.ExecutionPlan,
 		duration:      int64(duration / time.Second),
+		runMetadata:   params.RunMetadata,
 		opts:          params.ScriptOptions,
 		aggrBuckets:   map[int64]map[[3]string]aggregationBucket{},
 		logger:        logger,
@@ -204,6 +221,7 @@ func (out *Output) Start() error {
 		VUsMax:     int64(maxVUs),
 		Thresholds: thresholds,
 		Duration:   out.duration,
+		Metadata:   out.runMetadata,
 	}
 
 	response, err := out.client.CreateTestRun(testRun)
@@ -231,6 +249,11 @@ func (out *Output) Start() error {
 }
 
 func (out *Output) startBackgroundProcesses() {
+	out.aggrExcludedNames = make(map[string]bool, len(out.config.AggregationExcludedNames))
+	for _, name := range out.config.AggregationExcludedNames {
+		out.aggrExcludedNames[name] = true
+	}
+
 	aggregationPeriod := time.Duration(out.config.AggregationPeriod.Duration)
 	// If enabled, start periodically aggregating the collected HTTP trails
 	if aggregationPeriod > 0 {
@@ -287,6 +310,7 @@ func (out *Output) Stop() error {
 	out.logger.Debug("Aggregation stopped, stopping metric emission...")
 	close(out.stopOutput)
 	out.outputDone.Wait()
+	out.closeMetricsSpill()
 	out.logger.Debug("Metric emission stopped, calling cloud API...")
 	err := out.testFinished()
 	if err != nil {
@@ -360,8 +384,8 @@ func (out *Output) AddMetricSamples(sampleContainers []stats.SampleContainer) {
 		switch sc := sampleContainer.(type) {
 		case *httpext.Trail:
 			sc = useCloudTags(sc)
-			// Check if aggregation is enabled,
-			if out.config.AggregationPeriod.Duration > 0 {
+			// Check if aggregation is enabled, and not disabled for the request
+			if out.config.AggregationPeriod.Duration > 0 && !out.isAggregationExcluded(sc) {
 				newHTTPTrails = append(newHTTPTrails, sc)
 			} else {
 				newSamples = append(newSamples, NewSampleFromTrail(sc))
@@ -393,7 +417,7 @@ func (out *Output) AddMetricSamples(sampleContainers []stats.SampleContainer) {
 					Type:   DataTypeSingle,
 					Metric: sample.Metric.Name,
 					Data: &SampleDataSingle{
-						Type:  sample.Metric.Type,
+						Type:  cloudMetricType(sample.Metric.Type),
 						Time:  toMicroSecond(sample.Time),
 						Tags:  sample.Tags,
 						Value: sample.Value,
@@ -411,6 +435,14 @@ func (out *Output) AddMetricSamples(sampleContainers []stats.SampleContainer) {
 	}
 }
 
+func (out *Output) isAggregationExcluded(trail *httpext.Trail) bool {
+	if len(out.aggrExcludedNames) == 0 {
+		return false
+	}
+	name, _ := trail.Tags.Get("name")
+	return out.aggrExcludedNames[name]
+}
+
 //nolint:funlen,nestif,gocognit
 func (out *Output) aggregateHTTPTrails(waitPeriod time.Duration) {
 	out.bufferMutex.Lock()
@@ -608,6 +640,7 @@ func (out *Output) pushMetrics() {
 	out.bufferMutex.Lock()
 	if len(out.bufferSamples) == 0 {
 		out.bufferMutex.Unlock()
+		out.replaySpilledMetrics()
 		return
 	}
 	buffer := out.bufferSamples
@@ -654,6 +687,7 @@ func (out *Output) pushMetrics() {
 
 	close(ch)
 
+	failed := false
 	for _, job := range jobs {
 		err := <-job.done
 		if err != nil {
@@ -663,15 +697,73 @@ func (out *Output) pushMetrics() {
 					out.engineStopFunc(err)
 				}
 				close(out.stopSendingMetrics)
+				failed = true
 				break
 			}
+			failed = true
 			out.logger.WithError(err).Warn("Failed to send metrics to cloud")
+			out.spillMetrics(job.samples)
 		}
 	}
 	out.logger.WithFields(logrus.Fields{
 		"samples": count,
 		"t":       time.Since(start),
 	}).Debug("Pushing metrics to cloud finished")
+	if !failed {
+		out.replaySpilledMetrics()
+	}
+}
+
+// spillMetrics keeps the samples that couldn't be pushed in the spill file, if it's enabled.
+func (out *Output) spillMetrics(pkg []*Sample) {
+	if out.spill == nil {
+		return
+	}
+	ok, err := out.spill.write(pkg)
+	switch {
+	case err != nil:
+		out.logger.WithError(err).Warn("Failed to keep the metrics that couldn't be sent locally")
+	case !ok:
+		out.logger.Warnf("The local file of the metrics that couldn't be sent reached its maximum size of "+
+			"%d bytes, %d samples were dropped", out.config.MetricSpillMaxSize.Int64, out.spill.dropped)
+	default:
+		out.logger.Debugf("Kept %d samples locally for sending them later", len(pkg))
+	}
+}
+
+// replaySpilledMetrics pushes the samples of the spill file, after the connection to the cloud
+// works again.
+func (out *Output) replaySpilledMetrics() {
+	if out.spill == nil || out.spill.pending == 0 {
+		return
+	}
+	pushed, err := out.spill.replay(func(pkg []*Sample) error {
+		return out.client.PushMetric(out.referenceID, pkg)
+	})
+	if pushed > 0 {
+		out.logger.Infof("Sent %d samples that couldn't be sent before to the cloud", pushed)
+	}
+	if err != nil {
+		out.logger.WithError(err).Debug("Failed to send the samples kept locally to the cloud, retrying later")
+	}
+}
+
+// closeMetricsSpill tries to push the samples of the spill file a last time and removes it.
+func (out *Output) closeMetricsSpill() {
+	if out.spill == nil {
+		return
+	}
+	select {
+	case <-out.stopSendingMetrics:
+	default:
+		out.replaySpilledMetrics()
+	}
+	if lost := out.spill.pending + out.spill.dropped; lost > 0 {
+		out.logger.Warnf("%d samples couldn't be sent to the cloud", lost)
+	}
+	if err := out.spill.close(); err != nil {
+		out.logger.WithError(err).Debug("Failed to remove the local file of the metrics that couldn't be sent")
+	}
 }
 
 func (out *Output) testFinished() error {
@@ -853,3 +853,29 @@ func TestPublishMetric(t *testing.T) {
 
 	assert.Nil(t, err)
 }
+
+func TestCloudOutputAggregationExcludedNames(t *testing.T) {
+	t.Parallel()
+	config := cloudapi.NewConfig()
+	config.AggregationPeriod = types.NullDurationFrom(time.Second)
+	out := &Output{
+		config:            config,
+		referenceID:       "123",
+		aggrExcludedNames: map[string]bool{"excluded": true},
+		logger:            testutils.NewLogger(t),
+	}
+	newTrail := func(name string) *httpext.Trail {
+		return &httpext.Trail{
+			EndTime: time.Now(),
+			Tags:    stats.IntoSampleTags(&map[string]string{"name": name, "url": name}),
+		}
+	}
+	out.AddMetricSamples([]stats.SampleContainer{newTrail("aggregated"), newTrail("excluded")})
+	require.Len(t, out.bufferHTTPTrails, 1)
+	name, _ := out.bufferHTTPTrails[0].Tags.Get("name")
+	assert.Equal(t, "aggregated", name)
+	require.Len(t, out.bufferSamples, 1)
+	assert.Equal(t, "http_req_li_all", out.bufferSamples[0].Metric)
+	name, _ = out.bufferSamples[0].Data.(*SampleDataMap).Tags.Get("name")
+	assert.Equal(t, "excluded", name)
+}
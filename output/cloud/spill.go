@@ -0,0 +1,86 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+
+	"github.com/mailru/easyjson"
+
+	"go.k6.io/k6/output"
+)
+
+// metricsSpill is the local file where the packages of samples that couldn't be pushed to the
+// cloud, during network outages, are kept until they can be pushed again, a package on each line.
+// The packages that don't fit in the file are dropped.
+type metricsSpill struct {
+	file *output.SpillFile
+	// pending is the number of the samples that weren't pushed yet, and dropped the ones of the
+	// packages that didn't fit in the file
+	pending, dropped int
+}
+
+func newMetricsSpill(maxSize int64) *metricsSpill {
+	return &metricsSpill{file: output.NewSpillFile("", "k6-cloud-metrics-*.jsonl", maxSize)}
+}
+
+// write adds a package of samples at the end of the file, it returns false if the package doesn't
+// fit in it and is dropped.
+func (s *metricsSpill) write(pkg []*Sample) (bool, error) {
+	data, err := easyjson.Marshal(samples(pkg))
+	if err != nil {
+		return false, err
+	}
+	written, err := s.file.Push(data)
+	if err != nil {
+		return false, err
+	}
+	if written == 0 {
+		s.dropped += len(pkg)
+		return false, nil
+	}
+	s.pending += len(pkg)
+	return true, nil
+}
+
+// replay pushes the packages of the file in the order they were written, until push fails for
+// one of them, which is kept with the following ones for the next replay.
+func (s *metricsSpill) replay(push func([]*Sample) error) (int, error) {
+	pushed := 0
+	_, err := s.file.Pop(0, func(line []byte) error {
+		var pkg []*Sample
+		if err := json.Unmarshal(line, &pkg); err != nil {
+			return err
+		}
+		if err := push(pkg); err != nil {
+			return err
+		}
+		s.pending -= len(pkg)
+		pushed += len(pkg)
+		return nil
+	})
+	return pushed, err
+}
+
+// close removes the file, with the packages that weren't pushed.
+func (s *metricsSpill) close() error {
+	return s.file.Close()
+}
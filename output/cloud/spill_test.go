@@ -0,0 +1,112 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloud
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/stats"
+)
+
+func TestMetricsSpill(t *testing.T) {
+	t.Parallel()
+	newPkg := func(values ...float64) []*Sample {
+		pkg := make([]*Sample, len(values))
+		for i, v := range values {
+			pkg[i] = &Sample{Type: DataTypeSingle, Metric: "vus", Data: &SampleDataSingle{
+				Type: stats.Gauge, Time: int64(i), Tags: stats.IntoSampleTags(&map[string]string{"a": "b"}), Value: v,
+			}}
+		}
+		return pkg
+	}
+	values := func(pkg []*Sample) []float64 {
+		result := make([]float64, len(pkg))
+		for i, s := range pkg {
+			result[i] = s.Data.(*SampleDataSingle).Value
+		}
+		return result
+	}
+
+	spill := newMetricsSpill(1024)
+	for _, pkg := range [][]*Sample{newPkg(1, 2), newPkg(3)} {
+		ok, err := spill.write(pkg)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	assert.Equal(t, 3, spill.pending)
+
+	var pushed [][]float64
+	errPush := errors.New("offline")
+	pushFirst := func(pkg []*Sample) error {
+		if len(pushed) > 0 {
+			return errPush
+		}
+		pushed = append(pushed, values(pkg))
+		return nil
+	}
+	n, err := spill.replay(pushFirst)
+	assert.ErrorIs(t, err, errPush)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 1, spill.pending)
+
+	ok, err := spill.write(newPkg(4))
+	require.NoError(t, err)
+	require.True(t, ok)
+	n, err = spill.replay(func(pkg []*Sample) error {
+		pushed = append(pushed, values(pkg))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, [][]float64{{1, 2}, {3}, {4}}, pushed)
+	assert.Equal(t, 0, spill.pending)
+	assert.Equal(t, int64(0), spill.file.Size())
+
+	ok, err = spill.write(newPkg(make([]float64, 100)...))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 100, spill.dropped)
+
+	// the space of the replayed packages is reused, even if some of them are still in the file
+	_, err = spill.write(newPkg(0))
+	require.NoError(t, err)
+	for i := 1; i < 20; i++ {
+		ok, err = spill.write(newPkg(float64(i)))
+		require.NoError(t, err)
+		require.True(t, ok)
+		replayed := false
+		_, err = spill.replay(func([]*Sample) error {
+			if replayed {
+				return errPush
+			}
+			replayed = true
+			return nil
+		})
+		require.ErrorIs(t, err, errPush)
+	}
+	assert.Equal(t, 1, spill.pending)
+	require.NoError(t, spill.close())
+	assert.Equal(t, 0, spill.file.Len())
+}
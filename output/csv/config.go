@@ -37,6 +37,9 @@ type Config struct {
 	// Samples.
 	FileName     null.String        `json:"file_name" envconfig:"K6_CSV_FILENAME"`
 	SaveInterval types.NullDuration `json:"save_interval" envconfig:"K6_CSV_SAVE_INTERVAL"`
+	// SaveMetadata adds a "metadata" column after the extra tags, it's
+	// disabled by default to not change the columns of the existing files.
+	SaveMetadata null.Bool `json:"save_metadata" envconfig:"K6_CSV_SAVE_METADATA"`
 }
 
 // NewConfig creates a new Config instance with default values for some fields.
@@ -55,6 +58,9 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.SaveInterval.Valid {
 		c.SaveInterval = cfg.SaveInterval
 	}
+	if cfg.SaveMetadata.Valid {
+		c.SaveMetadata = cfg.SaveMetadata
+	}
 	return c
 }
 
@@ -82,6 +88,10 @@ func ParseArg(arg string) (Config, error) {
 			}
 		case "file_name":
 			c.FileName = null.StringFrom(r[1])
+		case "save_metadata":
+			if err := c.SaveMetadata.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
 		default:
 			return c, fmt.Errorf("unknown key %q as argument for csv output", r[0])
 		}
@@ -100,6 +100,14 @@ func TestParseArg(t *testing.T) {
 		"filename=test.csv,save_interval=5s": {
 			expectedErr: true,
 		},
+		"save_metadata=true": {
+			config: Config{
+				SaveMetadata: null.BoolFrom(true),
+			},
+		},
+		"save_metadata=maybe": {
+			expectedErr: true,
+		},
 	}
 
 	for arg, testCase := range cases {
@@ -116,6 +124,7 @@ func TestParseArg(t *testing.T) {
 			}
 			assert.Equal(t, testCase.config.FileName.String, config.FileName.String)
 			assert.Equal(t, testCase.config.SaveInterval.String(), config.SaveInterval.String())
+			assert.Equal(t, testCase.config.SaveMetadata, config.SaveMetadata)
 		})
 	}
 }
@@ -54,6 +54,7 @@ type Output struct {
 	ignoredTags  []string
 	row          []string
 	saveInterval time.Duration
+	saveMetadata bool
 }
 
 // New Creates new instance of CSV output
@@ -83,6 +84,11 @@ func newOutput(params output.Params) (*Output, error) {
 
 	saveInterval := time.Duration(config.SaveInterval.Duration)
 	fname := config.FileName.String
+	saveMetadata := config.SaveMetadata.Bool
+	rowLen := 3 + len(resTags) + 1
+	if saveMetadata {
+		rowLen++
+	}
 
 	logger := params.Logger.WithFields(logrus.Fields{
 		"output":   "csv",
@@ -95,8 +101,9 @@ func newOutput(params output.Params) (*Output, error) {
 			resTags:      resTags,
 			ignoredTags:  ignoredTags,
 			csvWriter:    stdoutWriter,
-			row:          make([]string, 3+len(resTags)+1),
+			row:          make([]string, rowLen),
 			saveInterval: saveInterval,
+			saveMetadata: saveMetadata,
 			closeFn:      func() error { return nil },
 			logger:       logger,
 			params:       params,
@@ -112,8 +119,9 @@ func newOutput(params output.Params) (*Output, error) {
 		fname:        fname,
 		resTags:      resTags,
 		ignoredTags:  ignoredTags,
-		row:          make([]string, 3+len(resTags)+1),
+		row:          make([]string, rowLen),
 		saveInterval: saveInterval,
+		saveMetadata: saveMetadata,
 		logger:       logger,
 		params:       params,
 	}
@@ -148,13 +156,16 @@ func (o *Output) Start() error {
 	o.logger.Debug("Starting...")
 
 	header := MakeHeader(o.resTags)
+	if o.saveMetadata {
+		header = append(header, "metadata")
+	}
 	err := o.csvWriter.Write(header)
 	if err != nil {
 		o.logger.WithField("filename", o.fname).Error("CSV: Error writing column names to file")
 	}
 	o.csvWriter.Flush()
 
-	pf, err := output.NewPeriodicFlusher(o.saveInterval, o.flushMetrics)
+	pf, err := output.NewDrainingPeriodicFlusher(o.saveInterval, o.flushMetrics, o.HasSpilledSamples)
 	if err != nil {
 		return err
 	}
@@ -182,7 +193,7 @@ func (o *Output) flushMetrics() {
 		for _, sc := range samples {
 			for _, sample := range sc.GetSamples() {
 				sample := sample
-				row := SampleToRow(&sample, o.resTags, o.ignoredTags, o.row)
+				row := o.sampleToRow(&sample)
 				err := o.csvWriter.Write(row)
 				if err != nil {
 					o.logger.WithField("filename", o.fname).Error("CSV: Error writing to file")
@@ -193,6 +204,17 @@ func (o *Output) flushMetrics() {
 	}
 }
 
+// sampleToRow converts the sample into the reused row, with the metadata in the
+// last column when it's enabled.
+func (o *Output) sampleToRow(sample *stats.Sample) []string {
+	if !o.saveMetadata {
+		return SampleToRow(sample, o.resTags, o.ignoredTags, o.row)
+	}
+	SampleToRow(sample, o.resTags, o.ignoredTags, o.row[:len(o.row)-1])
+	o.row[len(o.row)-1] = joinMetadata(sample.Metadata)
+	return o.row
+}
+
 // MakeHeader creates list of column names for csv file
 func MakeHeader(tags []string) []string {
 	tags = append(tags, "extra_tags")
@@ -239,6 +261,30 @@ func SampleToRow(sample *stats.Sample, resTags []string, ignoredTags []string, r
 	return row
 }
 
+// joinMetadata formats the sample metadata the same way as the extra tags, but
+// with sorted keys, since it usually contains just a few values.
+func joinMetadata(metadata map[string]string) string {
+	if len(metadata) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(metadata[k])
+	}
+	return b.String()
+}
+
 // IsStringInSlice returns whether the string is contained within a string slice
 func IsStringInSlice(slice []string, str string) bool {
 	if index := sort.SearchStrings(slice, str); index == len(slice) || slice[index] != str {
@@ -301,6 +301,43 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestRunWithMetadata(t *testing.T) {
+	t.Parallel()
+
+	mem := afero.NewMemMapFs()
+	output, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		FS:             mem,
+		ConfigArgument: "file_name=test,save_metadata=true",
+		ScriptOptions: lib.Options{
+			SystemTags: stats.NewSystemTagSet(stats.TagCheck),
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, output.Start())
+	output.AddMetricSamples([]stats.SampleContainer{
+		stats.Sample{
+			Time:     time.Unix(1562324643, 0),
+			Metric:   stats.New("my_metric", stats.Gauge),
+			Value:    1,
+			Tags:     stats.NewSampleTags(map[string]string{"check": "val1", "url": "val2"}),
+			Metadata: map[string]string{"trace_id": "abc", "request_id": "123"},
+		},
+		stats.Sample{
+			Time:   time.Unix(1562324644, 0),
+			Metric: stats.New("my_metric", stats.Gauge),
+			Value:  1,
+			Tags:   stats.NewSampleTags(map[string]string{"check": "val1"}),
+		},
+	})
+	require.NoError(t, output.Stop())
+
+	assert.Equal(t, "metric_name,timestamp,metric_value,check,extra_tags,metadata\n"+
+		"my_metric,1562324643,1.000000,val1,url=val2,request_id=123&trace_id=abc\n"+
+		"my_metric,1562324644,1.000000,val1,,\n", readUnCompressedFile("test", mem))
+}
+
 func sortExtraTagsForTest(t *testing.T, input string) string {
 	t.Helper()
 	r := csv.NewReader(strings.NewReader(input))
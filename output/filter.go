@@ -0,0 +1,171 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package output
+
+import (
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/stats"
+)
+
+// Filter selects the samples that an output receives, like only the trends for a time series
+// database and all of the samples for a file. The samples have to match all of the set fields.
+type Filter struct {
+	// Glob patterns of the metrics that are sent, all of them by default, and of the metrics that
+	// aren't sent, which take precedence.
+	Metrics        []string `json:"metrics"`
+	ExcludeMetrics []string `json:"excludeMetrics"`
+	// The types of the metrics that are sent, all of them by default.
+	Types []stats.MetricType `json:"types"`
+	// The tags the samples have to have, with these values.
+	Tags map[string]string `json:"tags"`
+}
+
+// Validate returns an error if any of the patterns is invalid.
+func (f Filter) Validate() error {
+	return lib.ValidateMetricPatterns(f.Metrics, f.ExcludeMetrics)
+}
+
+// NewFilteredOutput returns an output that passes only the samples that match the filter to the
+// wrapped output. It passes on the thresholds, the test run stop callback, the run status
+// updates, the run manifest and the buffer stats to and from the outputs that have them.
+func NewFilteredOutput(out Output, filter Filter) Output {
+	return &filteredOutput{
+		Output:  out,
+		filter:  filter,
+		names:   lib.NewMetricPatterns(filter.Metrics, filter.ExcludeMetrics),
+		allowed: make(map[*stats.Metric]bool),
+	}
+}
+
+type filteredOutput struct {
+	Output
+	filter  Filter
+	names   *lib.MetricPatterns
+	allowed map[*stats.Metric]bool
+}
+
+var (
+	_ WithThresholds       = &filteredOutput{}
+	_ WithTestRunStop      = &filteredOutput{}
+	_ WithRunStatusUpdates = &filteredOutput{}
+	_ WithBufferStats      = &filteredOutput{}
+	_ WithRunStart         = &filteredOutput{}
+	_ WithRunManifest      = &filteredOutput{}
+)
+
+func (fo *filteredOutput) SetThresholds(thresholds map[string]stats.Thresholds) {
+	if out, ok := fo.Output.(WithThresholds); ok {
+		out.SetThresholds(thresholds)
+	}
+}
+
+func (fo *filteredOutput) SetTestRunStopCallback(callback func(error)) {
+	if out, ok := fo.Output.(WithTestRunStop); ok {
+		out.SetTestRunStopCallback(callback)
+	}
+}
+
+func (fo *filteredOutput) SetRunStatus(latestStatus lib.RunStatus) {
+	if out, ok := fo.Output.(WithRunStatusUpdates); ok {
+		out.SetRunStatus(latestStatus)
+	}
+}
+
+func (fo *filteredOutput) SetRunStart(md *lib.TestRunMetadata) {
+	if out, ok := fo.Output.(WithRunStart); ok {
+		out.SetRunStart(md)
+	}
+}
+
+func (fo *filteredOutput) SetRunManifest(manifest *lib.TestRunManifest) {
+	if out, ok := fo.Output.(WithRunManifest); ok {
+		out.SetRunManifest(manifest)
+	}
+}
+
+func (fo *filteredOutput) BufferStats() (SampleBufferStats, bool) {
+	if out, ok := fo.Output.(WithBufferStats); ok {
+		return out.BufferStats()
+	}
+	return SampleBufferStats{}, false
+}
+
+// isAllowedMetric returns whether the name and the type of the metric match the filter. The
+// results are cached by metric, since AddMetricSamples() is never called concurrently.
+func (fo *filteredOutput) isAllowedMetric(m *stats.Metric) bool {
+	if allowed, ok := fo.allowed[m]; ok {
+		return allowed
+	}
+	allowed := fo.names.Match(m.Name)
+	if allowed && len(fo.filter.Types) > 0 {
+		allowed = false
+		for _, t := range fo.filter.Types {
+			if m.Type == t {
+				allowed = true
+				break
+			}
+		}
+	}
+	fo.allowed[m] = allowed
+	return allowed
+}
+
+func (fo *filteredOutput) isAllowed(sample stats.Sample) bool {
+	if !fo.isAllowedMetric(sample.Metric) {
+		return false
+	}
+	for key, value := range fo.filter.Tags {
+		if v, ok := sample.Tags.Get(key); !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// AddMetricSamples passes the containers without any dropped samples as they are, so the wrapped
+// output still gets the HTTP trails it aggregates, and the rest as plain samples.
+func (fo *filteredOutput) AddMetricSamples(containers []stats.SampleContainer) {
+	result := make([]stats.SampleContainer, 0, len(containers))
+	for _, sc := range containers {
+		samples := sc.GetSamples()
+		var kept stats.Samples
+		for i, sample := range samples {
+			if fo.isAllowed(sample) {
+				if kept != nil {
+					kept = append(kept, sample)
+				}
+				continue
+			}
+			if kept == nil {
+				kept = append(make(stats.Samples, 0, len(samples)), samples[:i]...)
+			}
+		}
+		switch {
+		case kept == nil:
+			result = append(result, sc)
+		case len(kept) > 0:
+			result = append(result, kept)
+		}
+	}
+	if len(result) > 0 {
+		fo.Output.AddMetricSamples(result)
+	}
+}
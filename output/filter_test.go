@@ -0,0 +1,107 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/stats"
+)
+
+type recordingOutput struct {
+	SampleBuffer
+	thresholds map[string]stats.Thresholds
+	status     lib.RunStatus
+}
+
+func (o *recordingOutput) Description() string { return "recording" }
+func (o *recordingOutput) Start() error        { return nil }
+func (o *recordingOutput) Stop() error         { return nil }
+
+func (o *recordingOutput) SetThresholds(thresholds map[string]stats.Thresholds) {
+	o.thresholds = thresholds
+}
+
+func (o *recordingOutput) SetRunStatus(status lib.RunStatus) {
+	o.status = status
+}
+
+func TestFilteredOutput(t *testing.T) {
+	t.Parallel()
+	inner := &recordingOutput{}
+	out := NewFilteredOutput(inner, Filter{
+		Metrics:        []string{"http_req*", "custom"},
+		ExcludeMetrics: []string{"http_req_blocked"},
+		Types:          []stats.MetricType{stats.Trend, stats.Counter},
+		Tags:           map[string]string{"scenario": "browse"},
+	})
+	assert.Equal(t, "recording", out.Description())
+
+	ths := map[string]stats.Thresholds{"custom": {}}
+	out.(WithThresholds).SetThresholds(ths)
+	assert.Equal(t, ths, inner.thresholds)
+	out.(WithRunStatusUpdates).SetRunStatus(lib.RunStatusFinished)
+	assert.Equal(t, lib.RunStatusFinished, inner.status)
+	// the wrapped output doesn't take the callback, so it's dropped
+	out.(WithTestRunStop).SetTestRunStopCallback(func(error) {})
+
+	now := time.Now()
+	browse := stats.IntoSampleTags(&map[string]string{"scenario": "browse"})
+	login := stats.IntoSampleTags(&map[string]string{"scenario": "login"})
+	duration := stats.New("http_req_duration", stats.Trend)
+	kept := stats.ConnectedSamples{Samples: []stats.Sample{
+		{Metric: duration, Time: now, Value: 1, Tags: browse},
+		{Metric: stats.New("http_reqs", stats.Counter), Time: now, Value: 1, Tags: browse},
+	}}
+	out.AddMetricSamples([]stats.SampleContainer{
+		kept,
+		stats.Samples{
+			{Metric: stats.New("http_req_blocked", stats.Trend), Time: now, Value: 1, Tags: browse},
+			{Metric: duration, Time: now, Value: 2, Tags: login},
+			{Metric: stats.New("custom", stats.Trend), Time: now, Value: 3, Tags: browse},
+			{Metric: stats.New("custom_gauge", stats.Gauge), Time: now, Value: 4, Tags: browse},
+			{Metric: duration, Time: now, Value: 5},
+		},
+		stats.Sample{Metric: stats.New("http_req_failed", stats.Rate), Time: now, Value: 1, Tags: browse},
+	})
+
+	buffered := inner.GetBufferedSamples()
+	require.Len(t, buffered, 2)
+	assert.Equal(t, kept, buffered[0])
+	samples := buffered[1].GetSamples()
+	require.Len(t, samples, 1)
+	assert.Equal(t, 3.0, samples[0].Value)
+
+	// nothing is passed on when all of the samples are dropped
+	out.AddMetricSamples([]stats.SampleContainer{stats.Sample{Metric: duration, Time: now, Value: 6}})
+	assert.Nil(t, inner.GetBufferedSamples())
+}
+
+func TestFilterValidate(t *testing.T) {
+	t.Parallel()
+	assert.NoError(t, Filter{Metrics: []string{"http_*"}}.Validate())
+	assert.Error(t, Filter{ExcludeMetrics: []string{"[a"}}.Validate())
+}
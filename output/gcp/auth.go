@@ -0,0 +1,139 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gcp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.k6.io/k6/output"
+)
+
+const (
+	// The OAuth 2.0 scope of the writing of the metrics.
+	monitoringWriteScope = "https://www.googleapis.com/auth/monitoring.write"
+	defaultTokenURI      = "https://oauth2.googleapis.com/token"
+	jwtBearerGrantType   = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+)
+
+// serviceAccountKey is the JSON key file of a service account, with the fields that are needed
+// for getting its access tokens.
+type serviceAccountKey struct {
+	Type        string `json:"type"`
+	ProjectID   string `json:"project_id"`
+	PrivateKey  string `json:"private_key"`
+	ClientEmail string `json:"client_email"`
+	TokenURI    string `json:"token_uri"`
+
+	key *rsa.PrivateKey
+}
+
+func parseServiceAccountKey(data []byte) (*serviceAccountKey, error) {
+	sa := &serviceAccountKey{}
+	if err := json.Unmarshal(data, sa); err != nil {
+		return nil, fmt.Errorf("invalid service account key file: %w", err)
+	}
+	if sa.Type != "service_account" {
+		return nil, fmt.Errorf("the credentials have the type '%s', only the keys of the service accounts "+
+			"are supported", sa.Type)
+	}
+	if sa.ClientEmail == "" {
+		return nil, errors.New("the service account key doesn't have a client_email")
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = defaultTokenURI
+	}
+
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return nil, errors.New("the private_key of the service account key isn't a PEM block")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid private_key of the service account key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the private_key of the service account key isn't an RSA key")
+	}
+	sa.key = key
+	return sa, nil
+}
+
+// assertion returns the JWT signed with the key of the service account that is exchanged for
+// an access token, see https://developers.google.com/identity/protocols/oauth2/service-account
+func (sa *serviceAccountKey) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   sa.ClientEmail,
+		"scope": monitoringWriteScope,
+		"aud":   sa.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, sa.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// tokenSource returns the access tokens for the Cloud Monitoring API, the configured access token
+// or the tokens of the service account. It's only used by the flushing goroutine.
+type tokenSource struct {
+	token   string
+	account *serviceAccountKey
+	cache   *output.TokenCache
+}
+
+func (ts *tokenSource) accessToken() (string, error) {
+	if ts.account == nil {
+		return ts.token, nil
+	}
+	return ts.cache.Token(ts.account.TokenURI, func(now time.Time) (url.Values, error) {
+		assertion, err := ts.account.assertion(now)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't sign the service account assertion: %w", err)
+		}
+		return url.Values{"grant_type": {jwtBearerGrantType}, "assertion": {assertion}}, nil
+	})
+}
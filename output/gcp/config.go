@@ -0,0 +1,250 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+// Cloud Monitoring accepts a point of a time series at most every 5 seconds.
+const minFlushInterval = 5 * time.Second
+
+// Config is the config for the gcp output
+type Config struct {
+	// The project of the metrics, by default the one of the service account, and the credentials
+	// of the service account, or an access token, like from gcloud auth print-access-token.
+	ProjectID       null.String `json:"projectId" envconfig:"K6_GCP_PROJECT_ID"`
+	CredentialsFile null.String `json:"credentialsFile" envconfig:"K6_GCP_CREDENTIALS_FILE"`
+	AccessToken     null.String `json:"accessToken" envconfig:"K6_GCP_ACCESS_TOKEN"`
+	URL             null.String `json:"url" envconfig:"K6_GCP_URL"`
+
+	// The type prefix of the metrics and the labels of the generic_task monitored resource, which
+	// tell the time series of the different k6 instances apart.
+	MetricPrefix null.String `json:"metricPrefix" envconfig:"K6_GCP_METRIC_PREFIX"`
+	Location     null.String `json:"location" envconfig:"K6_GCP_LOCATION"`
+	Namespace    null.String `json:"namespace" envconfig:"K6_GCP_NAMESPACE"`
+	Job          null.String `json:"job" envconfig:"K6_GCP_JOB"`
+	TaskID       null.String `json:"taskId" envconfig:"K6_GCP_TASK_ID"`
+
+	FlushInterval types.NullDuration `json:"flushInterval" envconfig:"K6_GCP_FLUSH_INTERVAL"`
+	Timeout       types.NullDuration `json:"timeout" envconfig:"K6_GCP_TIMEOUT"`
+	TagBlocklist  stats.TagSet       `json:"tagBlocklist" envconfig:"K6_GCP_TAG_BLOCKLIST"`
+	// The names of the labels of the tags, the other tags have the same names with the characters
+	// that aren't allowed in the labels replaced.
+	LabelMapping map[string]string `json:"labelMapping" envconfig:"K6_GCP_LABEL_MAPPING"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	taskID, err := os.Hostname()
+	if err != nil {
+		taskID = "k6"
+	}
+	return Config{
+		URL:           null.StringFrom("https://monitoring.googleapis.com"),
+		MetricPrefix:  null.StringFrom("custom.googleapis.com/k6/"),
+		Location:      null.StringFrom("global"),
+		Namespace:     null.StringFrom("k6"),
+		Job:           null.StringFrom("k6"),
+		TaskID:        null.StringFrom(fmt.Sprintf("%s-%d", taskID, os.Getpid())),
+		FlushInterval: types.NullDurationFrom(time.Minute),
+		Timeout:       types.NullDurationFrom(10 * time.Second),
+		TagBlocklist:  (stats.TagVU | stats.TagIter | stats.TagURL).Map(),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.ProjectID.Valid {
+		c.ProjectID = cfg.ProjectID
+	}
+	if cfg.CredentialsFile.Valid {
+		c.CredentialsFile = cfg.CredentialsFile
+	}
+	if cfg.AccessToken.Valid {
+		c.AccessToken = cfg.AccessToken
+	}
+	if cfg.URL.Valid {
+		c.URL = cfg.URL
+	}
+	if cfg.MetricPrefix.Valid {
+		c.MetricPrefix = cfg.MetricPrefix
+	}
+	if cfg.Location.Valid {
+		c.Location = cfg.Location
+	}
+	if cfg.Namespace.Valid {
+		c.Namespace = cfg.Namespace
+	}
+	if cfg.Job.Valid {
+		c.Job = cfg.Job
+	}
+	if cfg.TaskID.Valid {
+		c.TaskID = cfg.TaskID
+	}
+	if cfg.FlushInterval.Valid {
+		c.FlushInterval = cfg.FlushInterval
+	}
+	if cfg.Timeout.Valid {
+		c.Timeout = cfg.Timeout
+	}
+	if cfg.TagBlocklist != nil {
+		c.TagBlocklist = cfg.TagBlocklist
+	}
+	if cfg.LabelMapping != nil {
+		c.LabelMapping = cfg.LabelMapping
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config, like in
+// --out gcp=projectId=my-project,job=checkout, the tagBlocklist key can be repeated and the
+// labelMapping key is tag:label, like labelMapping=name:endpoint. The accessToken key is rejected,
+// a token is only taken from K6_GCP_ACCESS_TOKEN or the JSON config.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	for _, pair := range strings.Split(arg, ",") {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			return c, fmt.Errorf("couldn't parse %q as argument for gcp output", arg)
+		}
+		switch r[0] {
+		case "projectId":
+			c.ProjectID = null.StringFrom(r[1])
+		case "credentialsFile":
+			c.CredentialsFile = null.StringFrom(r[1])
+		case "url":
+			c.URL = null.StringFrom(r[1])
+		case "metricPrefix":
+			c.MetricPrefix = null.StringFrom(r[1])
+		case "location":
+			c.Location = null.StringFrom(r[1])
+		case "namespace":
+			c.Namespace = null.StringFrom(r[1])
+		case "job":
+			c.Job = null.StringFrom(r[1])
+		case "taskId":
+			c.TaskID = null.StringFrom(r[1])
+		case "flushInterval":
+			if err := c.FlushInterval.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "timeout":
+			if err := c.Timeout.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "tagBlocklist":
+			if c.TagBlocklist == nil {
+				c.TagBlocklist = make(stats.TagSet)
+			}
+			c.TagBlocklist[r[1]] = true
+		case "labelMapping":
+			mapping := strings.SplitN(r[1], ":", 2)
+			if len(mapping) != 2 || mapping[0] == "" || mapping[1] == "" {
+				return c, fmt.Errorf("the gcp labelMapping has to be tag:label, not %q", r[1])
+			}
+			if c.LabelMapping == nil {
+				c.LabelMapping = make(map[string]string)
+			}
+			c.LabelMapping[mapping[0]] = mapping[1]
+		case "accessToken":
+			return c, fmt.Errorf("the gcp accessToken can't be in the argument, use K6_GCP_ACCESS_TOKEN instead")
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for gcp output", r[0])
+		}
+	}
+
+	return c, nil
+}
+
+// Validate returns an error if any config value is invalid. The project can still be empty,
+// since it can be the one of the service account.
+func (c Config) Validate() error {
+	if c.AccessToken.String == "" && c.CredentialsFile.String == "" {
+		return fmt.Errorf("the gcp output needs the credentialsFile of a service account, " +
+			"or GOOGLE_APPLICATION_CREDENTIALS, or an accessToken")
+	}
+	if c.AccessToken.String != "" && c.ProjectID.String == "" {
+		return fmt.Errorf("the gcp output needs the projectId with an accessToken")
+	}
+	if !strings.HasSuffix(c.MetricPrefix.String, "/") {
+		return fmt.Errorf("the gcp metricPrefix has to end with /, like custom.googleapis.com/k6/")
+	}
+	for tag, label := range c.LabelMapping {
+		if !isValidLabel(label) {
+			return fmt.Errorf("invalid gcp label %q of the tag %q, it has to be lowercase letters, "+
+				"digits and underscores, starting with a letter", label, tag)
+		}
+	}
+	if time.Duration(c.FlushInterval.Duration) < minFlushInterval {
+		return fmt.Errorf("the gcp flushInterval should be at least %s, but it's %s", minFlushInterval, c.FlushInterval)
+	}
+	if c.Timeout.Duration <= 0 {
+		return fmt.Errorf("the gcp timeout should be positive, but it's %s", c.Timeout)
+	}
+	return nil
+}
+
+// GetConsolidatedConfig combines {default config values + JSON config +
+// environment vars + arg config values}, and returns the final result. The credentials file is
+// the one of GOOGLE_APPLICATION_CREDENTIALS if no other credentials are set.
+func GetConsolidatedConfig(jsonRawConf json.RawMessage, env map[string]string, arg string) (Config, error) {
+	result := NewConfig()
+	if jsonRawConf != nil {
+		jsonConf := Config{}
+		if err := json.Unmarshal(jsonRawConf, &jsonConf); err != nil {
+			return result, err
+		}
+		result = result.Apply(jsonConf)
+	}
+
+	envConfig := Config{}
+	if err := envconfig.Process("", &envConfig); err != nil {
+		// TODO: get rid of envconfig and actually use the env parameter...
+		return result, err
+	}
+	result = result.Apply(envConfig)
+
+	if arg != "" {
+		argConf, err := ParseArg(arg)
+		if err != nil {
+			return result, err
+		}
+		result = result.Apply(argConf)
+	}
+
+	if !result.CredentialsFile.Valid && !result.AccessToken.Valid && env["GOOGLE_APPLICATION_CREDENTIALS"] != "" {
+		result.CredentialsFile = null.StringFrom(env["GOOGLE_APPLICATION_CREDENTIALS"])
+	}
+
+	return result, result.Validate()
+}
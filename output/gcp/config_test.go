@@ -0,0 +1,103 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+func TestParseArg(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		config      Config
+		expectedErr bool
+	}{
+		"projectId=my-project,job=checkout,flushInterval=30s": {
+			config: Config{
+				ProjectID:     null.StringFrom("my-project"),
+				Job:           null.StringFrom("checkout"),
+				FlushInterval: types.NullDurationFrom(30 * time.Second),
+			},
+		},
+		"credentialsFile=key.json,tagBlocklist=name,tagBlocklist=method,labelMapping=name:endpoint": {
+			config: Config{
+				CredentialsFile: null.StringFrom("key.json"),
+				TagBlocklist:    stats.TagSet{"name": true, "method": true},
+				LabelMapping:    map[string]string{"name": "endpoint"},
+			},
+		},
+		"accessToken=token":     {expectedErr: true},
+		"labelMapping=endpoint": {expectedErr: true},
+		"my-project":            {expectedErr: true},
+		"foo=bar":               {expectedErr: true},
+		"timeout=never":         {expectedErr: true},
+	}
+
+	for arg, tc := range cases {
+		arg, tc := arg, tc
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+			config, err := ParseArg(arg)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.config, config)
+		})
+	}
+}
+
+func TestGetConsolidatedConfig(t *testing.T) {
+	t.Parallel()
+	env := map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": "/etc/gcp/key.json"}
+	config, err := GetConsolidatedConfig([]byte(`{"projectId": "my-project"}`), env, "location=europe-west1")
+	require.NoError(t, err)
+	expected := NewConfig()
+	expected.ProjectID = null.StringFrom("my-project")
+	expected.Location = null.StringFrom("europe-west1")
+	expected.CredentialsFile = null.StringFrom("/etc/gcp/key.json")
+	assert.Equal(t, expected, config)
+
+	config, err = GetConsolidatedConfig([]byte(`{"credentialsFile": "key.json"}`), env, "")
+	require.NoError(t, err)
+	assert.Equal(t, "key.json", config.CredentialsFile.String)
+
+	invalid := map[string]string{
+		`{}`:                              "",
+		`{"accessToken": "x"}`:            "",
+		`{"credentialsFile": "key.json"}`: "metricPrefix=custom.googleapis.com/k6",
+		`{"credentialsFile": "key.json", "x": 1}`:                               "flushInterval=1s",
+		`{"credentialsFile": "key.json", "labelMapping": {"name": "Endpoint"}}`: "",
+	}
+	for conf, arg := range invalid {
+		_, err := GetConsolidatedConfig([]byte(conf), nil, arg)
+		assert.Error(t, err, conf)
+	}
+}
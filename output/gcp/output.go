@@ -0,0 +1,520 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+const (
+	// Cloud Monitoring accepts at most 200 time series in a request and 30 labels in a metric.
+	maxSeriesPerRequest = 200
+	maxLabels           = 30
+	maxLabelKeyLength   = 100
+	maxLabelValueLength = 1024
+
+	// The buckets of the distributions of the trends, from 1 to about 10^7, so milliseconds
+	// from 1ms to about 3 hours.
+	bucketsCount  = 40
+	bucketsGrowth = 1.5
+	bucketsScale  = 1
+)
+
+// Output publishes the metrics to Cloud Monitoring as custom metrics, with the tags of the samples
+// as their labels. The counters are cumulative from the start of the test, the gauges and the
+// rates are the values of each interval, and the trends are the distributions of each interval.
+type Output struct {
+	output.SampleBuffer
+
+	config          Config
+	logger          logrus.FieldLogger
+	client          *http.Client
+	tokens          *tokenSource
+	projectID       string
+	periodicFlusher *output.PeriodicFlusher
+	start           time.Time
+	// The labels of the metric descriptors that were created, and the totals of the counters.
+	descriptors   map[string]map[string]bool
+	counters      map[string]float64
+	warnedMetrics map[string]bool
+	now           func() time.Time
+}
+
+var _ output.Output = new(Output)
+
+// New returns a new gcp output
+func New(params output.Params) (output.Output, error) {
+	return newOutput(params)
+}
+
+func newOutput(params output.Params) (*Output, error) {
+	conf, err := GetConsolidatedConfig(params.JSONConfig, params.Environment, params.ConfigArgument)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: time.Duration(conf.Timeout.Duration)}
+	tokens := &tokenSource{
+		token: conf.AccessToken.String,
+		cache: &output.TokenCache{Name: "service account access token", Client: client, Now: time.Now},
+	}
+	projectID := conf.ProjectID.String
+	if conf.AccessToken.String == "" {
+		fs := params.FS
+		if fs == nil {
+			fs = afero.NewOsFs()
+		}
+		data, err := afero.ReadFile(fs, conf.CredentialsFile.String)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the gcp credentials file: %w", err)
+		}
+		if tokens.account, err = parseServiceAccountKey(data); err != nil {
+			return nil, err
+		}
+		if projectID == "" {
+			projectID = tokens.account.ProjectID
+		}
+	}
+	if projectID == "" {
+		return nil, errors.New("the gcp output needs the projectId, the service account key doesn't have one")
+	}
+
+	return &Output{
+		config:        conf,
+		logger:        params.Logger.WithFields(logrus.Fields{"output": "gcp"}),
+		client:        client,
+		tokens:        tokens,
+		projectID:     projectID,
+		descriptors:   make(map[string]map[string]bool),
+		counters:      make(map[string]float64),
+		warnedMetrics: make(map[string]bool),
+		now:           time.Now,
+	}, nil
+}
+
+// Description returns a human-readable description of the output.
+func (o *Output) Description() string {
+	return fmt.Sprintf("gcp (%s)", o.projectID)
+}
+
+// Start gets the first access token, so that the misconfigured credentials fail the test run
+// before it starts, and starts the goroutine for the flushing of the metrics.
+func (o *Output) Start() error {
+	o.logger.Debug("Starting...")
+	if _, err := o.tokens.accessToken(); err != nil {
+		return err
+	}
+	o.start = o.now()
+	pf, err := output.NewDrainingPeriodicFlusher(
+		time.Duration(o.config.FlushInterval.Duration), o.flushMetrics, o.HasSpilledSamples)
+	if err != nil {
+		return err
+	}
+	o.logger.Debug("Started!")
+	o.periodicFlusher = pf
+	return nil
+}
+
+// Stop flushes any remaining metrics and stops the goroutine.
+func (o *Output) Stop() error {
+	o.logger.Debug("Stopping...")
+	defer o.logger.Debug("Stopped!")
+	o.periodicFlusher.Stop()
+	return nil
+}
+
+// The bodies of the Cloud Monitoring API requests, see
+// https://cloud.google.com/monitoring/api/ref_v3/rest/v3/projects.timeSeries
+type (
+	metricDescriptor struct {
+		Type        string            `json:"type"`
+		MetricKind  string            `json:"metricKind"`
+		ValueType   string            `json:"valueType"`
+		Unit        string            `json:"unit,omitempty"`
+		Description string            `json:"description,omitempty"`
+		DisplayName string            `json:"displayName,omitempty"`
+		Labels      []labelDescriptor `json:"labels,omitempty"`
+	}
+	labelDescriptor struct {
+		Key       string `json:"key"`
+		ValueType string `json:"valueType"`
+	}
+	timeSeries struct {
+		Metric   metric   `json:"metric"`
+		Resource resource `json:"resource"`
+		Points   []point  `json:"points"`
+	}
+	metric struct {
+		Type   string            `json:"type"`
+		Labels map[string]string `json:"labels,omitempty"`
+	}
+	resource struct {
+		Type   string            `json:"type"`
+		Labels map[string]string `json:"labels"`
+	}
+	point struct {
+		Interval interval   `json:"interval"`
+		Value    typedValue `json:"value"`
+	}
+	interval struct {
+		StartTime string `json:"startTime,omitempty"`
+		EndTime   string `json:"endTime"`
+	}
+	typedValue struct {
+		DoubleValue       *float64      `json:"doubleValue,omitempty"`
+		DistributionValue *distribution `json:"distributionValue,omitempty"`
+	}
+	distribution struct {
+		Count                 int64         `json:"count"`
+		Mean                  float64       `json:"mean"`
+		SumOfSquaredDeviation float64       `json:"sumOfSquaredDeviation"`
+		BucketOptions         bucketOptions `json:"bucketOptions"`
+		BucketCounts          []int64       `json:"bucketCounts"`
+	}
+	bucketOptions struct {
+		ExponentialBuckets exponentialBuckets `json:"exponentialBuckets"`
+	}
+	exponentialBuckets struct {
+		NumFiniteBuckets int     `json:"numFiniteBuckets"`
+		GrowthFactor     float64 `json:"growthFactor"`
+		Scale            float64 `json:"scale"`
+	}
+)
+
+// isValidLabel returns whether the key can be the key of a label of a metric.
+func isValidLabel(key string) bool {
+	if key == "" || len(key) > maxLabelKeyLength || key[0] < 'a' || key[0] > 'z' {
+		return false
+	}
+	for _, c := range key {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// labelKey returns the key of the label of a tag, the mapped one or the name of the tag in
+// lowercase with the characters that aren't allowed replaced with underscores.
+func (o *Output) labelKey(tag string) string {
+	if label, ok := o.config.LabelMapping[tag]; ok {
+		return label
+	}
+	key := []byte(strings.ToLower(tag))
+	for i, c := range key {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			key[i] = '_'
+		}
+	}
+	if len(key) == 0 || key[0] < 'a' || key[0] > 'z' {
+		key = append([]byte("k6_"), key...)
+	}
+	if len(key) > maxLabelKeyLength {
+		key = key[:maxLabelKeyLength]
+	}
+	return string(key)
+}
+
+// metricType returns the type of the custom metric of a k6 metric.
+func (o *Output) metricType(name string) string {
+	path := []byte(name)
+	for i, c := range path {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' {
+			path[i] = '_'
+		}
+	}
+	return o.config.MetricPrefix.String + string(path)
+}
+
+func metricKindAndValueType(m *stats.Metric) (string, string) {
+	switch m.Type {
+	case stats.Counter, stats.Throughput:
+		return "CUMULATIVE", "DOUBLE"
+	case stats.Trend, stats.Histogram:
+		return "GAUGE", "DISTRIBUTION"
+	default:
+		return "GAUGE", "DOUBLE"
+	}
+}
+
+func metricUnit(m *stats.Metric) string {
+	switch {
+	case m.Type == stats.Rate:
+		return "1"
+	case m.Contains == stats.Time:
+		return "ms"
+	case m.Contains == stats.Data:
+		return "By"
+	default:
+		return "1"
+	}
+}
+
+// aggregated is a time series of an interval, with the tags of its samples as labels.
+type aggregated struct {
+	*output.AggregatedSeries
+	labels map[string]string
+}
+
+// aggregate aggregates the samples by the metrics and their tags, and returns the series of each
+// metric sorted by the names of the metrics.
+func (o *Output) aggregate(containers []stats.SampleContainer) []*aggregated {
+	series := output.AggregateSamples(containers, o.config.TagBlocklist)
+	result := make([]*aggregated, len(series))
+	for i, s := range series {
+		labels := make(map[string]string, len(s.Tags))
+		for tag, value := range s.Tags {
+			if len(value) > maxLabelValueLength {
+				value = value[:maxLabelValueLength]
+			}
+			labels[o.labelKey(tag)] = value
+		}
+		result[i] = &aggregated{AggregatedSeries: s, labels: labels}
+	}
+	return result
+}
+
+func newDistribution(values []float64) *distribution {
+	d := &distribution{
+		Count: int64(len(values)),
+		BucketOptions: bucketOptions{ExponentialBuckets: exponentialBuckets{
+			NumFiniteBuckets: bucketsCount, GrowthFactor: bucketsGrowth, Scale: bucketsScale,
+		}},
+		BucketCounts: make([]int64, bucketsCount+2),
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	d.Mean = sum / float64(len(values))
+	last := 0
+	for _, v := range values {
+		d.SumOfSquaredDeviation += (v - d.Mean) * (v - d.Mean)
+		// the bucket 0 is the underflow one, the bucket i is [scale*growth^(i-1), scale*growth^i)
+		bucket := 0
+		if v >= bucketsScale {
+			bucket = int(math.Floor(math.Log(v/bucketsScale)/math.Log(bucketsGrowth))) + 1
+			if bucket > bucketsCount {
+				bucket = bucketsCount + 1
+			}
+		}
+		d.BucketCounts[bucket]++
+		if bucket > last {
+			last = bucket
+		}
+	}
+	// the missing trailing buckets are empty
+	d.BucketCounts = d.BucketCounts[:last+1]
+	return d
+}
+
+// timeSeries returns the time series of the values of an interval, the counters have the
+// totals since the start of the test.
+func (o *Output) timeSeries(s *aggregated, end time.Time) timeSeries {
+	ts := timeSeries{
+		Metric: metric{Type: o.metricType(s.Metric.Name), Labels: s.labels},
+		Resource: resource{Type: "generic_task", Labels: map[string]string{
+			"project_id": o.projectID,
+			"location":   o.config.Location.String,
+			"namespace":  o.config.Namespace.String,
+			"job":        o.config.Job.String,
+			"task_id":    o.config.TaskID.String,
+		}},
+	}
+	p := point{Interval: interval{EndTime: end.UTC().Format(time.RFC3339Nano)}}
+	switch sink := s.Sink.(type) {
+	case *stats.CounterSink:
+		o.counters[s.Key] += sink.Value
+		total := o.counters[s.Key]
+		p.Interval.StartTime = o.start.UTC().Format(time.RFC3339Nano)
+		p.Value.DoubleValue = &total
+	case *stats.GaugeSink:
+		p.Value.DoubleValue = &sink.Value
+	case *stats.RateSink:
+		rate := float64(sink.Trues) / float64(sink.Total)
+		p.Value.DoubleValue = &rate
+	case *stats.TrendSink:
+		p.Value.DistributionValue = newDistribution(sink.Values)
+	}
+	ts.Points = []point{p}
+	return ts
+}
+
+// ensureDescriptor creates the descriptor of the custom metric of a k6 metric, or updates it
+// when the metric has labels that aren't in it. It returns the labels of the descriptor.
+func (o *Output) ensureDescriptor(m *stats.Metric, series []*aggregated) (map[string]bool, error) {
+	labels, ok := o.descriptors[m.Name]
+	missing := false
+	for _, s := range series {
+		for label := range s.labels {
+			if !labels[label] {
+				missing = true
+			}
+		}
+	}
+	if ok && !missing {
+		return labels, nil
+	}
+
+	all := make(map[string]bool, len(labels))
+	for label := range labels {
+		all[label] = true
+	}
+	for _, s := range series {
+		for label := range s.labels {
+			all[label] = true
+		}
+	}
+	keys := make([]string, 0, len(all))
+	for label := range all {
+		keys = append(keys, label)
+	}
+	sort.Strings(keys)
+	if len(keys) > maxLabels {
+		if !o.warnedMetrics[m.Name] {
+			o.warnedMetrics[m.Name] = true
+			o.logger.Warnf("The metric '%s' has more than %d tags, only %s are sent as its labels, "+
+				"use the tagBlocklist option for choosing them", m.Name, maxLabels, strings.Join(keys[:maxLabels], ", "))
+		}
+		keys = keys[:maxLabels]
+	}
+
+	kind, valueType := metricKindAndValueType(m)
+	descriptor := metricDescriptor{
+		Type:        o.metricType(m.Name),
+		MetricKind:  kind,
+		ValueType:   valueType,
+		Unit:        metricUnit(m),
+		Description: fmt.Sprintf("The k6 %s metric %s", m.Type, m.Name),
+		DisplayName: m.Name,
+	}
+	created := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		descriptor.Labels = append(descriptor.Labels, labelDescriptor{Key: key, ValueType: "STRING"})
+		created[key] = true
+	}
+	if err := o.post("metricDescriptors", descriptor, http.StatusConflict); err != nil {
+		return nil, fmt.Errorf("couldn't create the metric descriptor of %s: %w", m.Name, err)
+	}
+	o.descriptors[m.Name] = created
+	return created, nil
+}
+
+func (o *Output) flushMetrics() {
+	samples := o.GetBufferedSamples()
+	if len(samples) == 0 {
+		return
+	}
+	end := o.now()
+	all := o.aggregate(samples)
+
+	var series []timeSeries
+	failed := 0
+	for i := 0; i < len(all); {
+		// the series are sorted by the metrics, so the series of each metric are together
+		j := i
+		for j < len(all) && all[j].Metric.Name == all[i].Metric.Name {
+			j++
+		}
+		labels, err := o.ensureDescriptor(all[i].Metric, all[i:j])
+		if err != nil {
+			failed += j - i
+			o.logger.WithError(err).Debug("Couldn't publish the metric")
+		} else {
+			for _, s := range all[i:j] {
+				for label := range s.labels {
+					if !labels[label] {
+						delete(s.labels, label)
+					}
+				}
+				series = append(series, o.timeSeries(s, end))
+			}
+		}
+		i = j
+	}
+
+	for len(series) > 0 {
+		n := len(series)
+		if n > maxSeriesPerRequest {
+			n = maxSeriesPerRequest
+		}
+		if err := o.post("timeSeries", map[string][]timeSeries{"timeSeries": series[:n]}); err != nil {
+			failed += n
+			o.logger.WithError(err).Debug("Couldn't publish the time series")
+		}
+		series = series[n:]
+	}
+	if failed > 0 {
+		o.logger.Warnf("Couldn't publish %d out of %d time series to Cloud Monitoring, "+
+			"enable verbose logging with --verbose to see the errors", failed, len(all))
+	}
+}
+
+// post sends a request to the API of the project, the statuses besides the 2xx ones that are
+// successful can be in okStatuses.
+func (o *Output) post(collection string, body interface{}, okStatuses ...int) error {
+	token, err := o.tokens.accessToken()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	u := strings.TrimSuffix(o.config.URL.String, "/") + "/v3/projects/" + url.PathEscape(o.projectID) + "/" + collection
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	for _, status := range okStatuses {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
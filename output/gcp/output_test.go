@@ -0,0 +1,249 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gcp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+// newTestServiceAccount returns the key file of a service account with a new key and the
+// token URI of the server.
+func newTestServiceAccount(t *testing.T, tokenURI string) ([]byte, *rsa.PublicKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "my-project",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email": "k6@my-project.iam.gserviceaccount.com",
+		"token_uri":    tokenURI,
+	})
+	require.NoError(t, err)
+	return data, &key.PublicKey
+}
+
+func verifyAssertion(t *testing.T, assertion string, key *rsa.PublicKey) map[string]interface{} {
+	parts := strings.Split(assertion, ".")
+	require.Len(t, parts, 3)
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	require.NoError(t, rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature))
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &claims))
+	return claims
+}
+
+//nolint:funlen
+func TestOutput(t *testing.T) {
+	t.Parallel()
+	var (
+		mu          sync.Mutex
+		key         *rsa.PublicKey
+		tokenCalls  int
+		descriptors []metricDescriptor
+		published   []timeSeries
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, jwtBearerGrantType, r.PostForm.Get("grant_type"))
+		claims := verifyAssertion(t, r.PostForm.Get("assertion"), key)
+		assert.Equal(t, "k6@my-project.iam.gserviceaccount.com", claims["iss"])
+		assert.Equal(t, monitoringWriteScope, claims["scope"])
+		mu.Lock()
+		tokenCalls++
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"access_token": "token", "expires_in": 3599, "token_type": "Bearer"}`))
+	})
+	mux.HandleFunc("/v3/projects/my-project/metricDescriptors", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		var d metricDescriptor
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&d))
+		mu.Lock()
+		descriptors = append(descriptors, d)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/v3/projects/my-project/timeSeries", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		var body struct {
+			TimeSeries []timeSeries `json:"timeSeries"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		mu.Lock()
+		published = append(published, body.TimeSeries...)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fs := afero.NewMemMapFs()
+	var keyFile []byte
+	keyFile, key = newTestServiceAccount(t, srv.URL+"/token")
+	require.NoError(t, afero.WriteFile(fs, "/key.json", keyFile, 0o600))
+
+	o, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		FS:             fs,
+		JSONConfig:     []byte(`{"url": "` + srv.URL + `", "taskId": "task", "labelMapping": {"name": "endpoint"}}`),
+		ConfigArgument: "credentialsFile=/key.json,flushInterval=1h",
+	})
+	require.NoError(t, err)
+	start := time.Date(2021, time.February, 24, 13, 37, 0, 0, time.UTC)
+	o.now = func() time.Time { return start }
+	require.NoError(t, o.Start())
+	assert.Equal(t, "gcp (my-project)", o.Description())
+
+	reqs := stats.New("http_reqs", stats.Counter)
+	duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+	get := stats.NewSampleTags(map[string]string{"name": "home", "vu": "1", "expected-response": "true"})
+	now := time.Now()
+	o.AddMetricSamples([]stats.SampleContainer{stats.Samples{
+		{Metric: reqs, Value: 1, Time: now, Tags: get},
+		{Metric: reqs, Value: 1, Time: now, Tags: get},
+		{Metric: duration, Value: 0.5, Time: now, Tags: get},
+		{Metric: duration, Value: 2, Time: now, Tags: get},
+	}})
+	o.now = func() time.Time { return start.Add(time.Minute) }
+	o.flushMetrics()
+	o.AddMetricSamples([]stats.SampleContainer{stats.Samples{{Metric: reqs, Value: 3, Time: now, Tags: get}}})
+	o.now = func() time.Time { return start.Add(2 * time.Minute) }
+	require.NoError(t, o.Stop())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, tokenCalls)
+	labels := []labelDescriptor{{Key: "endpoint", ValueType: "STRING"}, {Key: "expected_response", ValueType: "STRING"}}
+	assert.Equal(t, []metricDescriptor{
+		{
+			Type: "custom.googleapis.com/k6/http_req_duration", MetricKind: "GAUGE", ValueType: "DISTRIBUTION",
+			Unit: "ms", Description: "The k6 trend metric http_req_duration", DisplayName: "http_req_duration",
+			Labels: labels,
+		},
+		{
+			Type: "custom.googleapis.com/k6/http_reqs", MetricKind: "CUMULATIVE", ValueType: "DOUBLE",
+			Unit: "1", Description: "The k6 counter metric http_reqs", DisplayName: "http_reqs", Labels: labels,
+		},
+	}, descriptors)
+
+	require.Len(t, published, 3)
+	expectedLabels := map[string]string{"endpoint": "home", "expected_response": "true"}
+	for _, ts := range published {
+		assert.Equal(t, expectedLabels, ts.Metric.Labels)
+		assert.Equal(t, resource{Type: "generic_task", Labels: map[string]string{
+			"project_id": "my-project", "location": "global", "namespace": "k6", "job": "k6", "task_id": "task",
+		}}, ts.Resource)
+		require.Len(t, ts.Points, 1)
+	}
+
+	assert.Equal(t, "custom.googleapis.com/k6/http_req_duration", published[0].Metric.Type)
+	assert.Equal(t, interval{EndTime: "2021-02-24T13:38:00Z"}, published[0].Points[0].Interval)
+	assert.Equal(t, &distribution{
+		Count: 2, Mean: 1.25, SumOfSquaredDeviation: 1.125,
+		BucketOptions: bucketOptions{ExponentialBuckets: exponentialBuckets{
+			NumFiniteBuckets: bucketsCount, GrowthFactor: bucketsGrowth, Scale: bucketsScale,
+		}},
+		BucketCounts: []int64{1, 0, 1},
+	}, published[0].Points[0].Value.DistributionValue)
+
+	// the counters are cumulative from the start of the test
+	for i, expected := range []float64{2, 5} {
+		ts := published[i+1]
+		assert.Equal(t, "custom.googleapis.com/k6/http_reqs", ts.Metric.Type)
+		assert.Equal(t, "2021-02-24T13:37:00Z", ts.Points[0].Interval.StartTime)
+		require.NotNil(t, ts.Points[0].Value.DoubleValue)
+		assert.Equal(t, expected, *ts.Points[0].Value.DoubleValue)
+	}
+	assert.Equal(t, "2021-02-24T13:39:00Z", published[2].Points[0].Interval.EndTime)
+}
+
+func TestOutputInvalidCredentials(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": "invalid_grant"}`))
+	}))
+	defer srv.Close()
+
+	fs := afero.NewMemMapFs()
+	keyFile, _ := newTestServiceAccount(t, srv.URL)
+	require.NoError(t, afero.WriteFile(fs, "/key.json", keyFile, 0o600))
+	o, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		FS:             fs,
+		ConfigArgument: "credentialsFile=/key.json",
+	})
+	require.NoError(t, err)
+	err = o.Start()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_grant")
+
+	_, err = newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		FS:             fs,
+		ConfigArgument: "credentialsFile=/missing.json",
+	})
+	assert.Error(t, err)
+
+	require.NoError(t, afero.WriteFile(fs, "/user.json", []byte(`{"type": "authorized_user"}`), 0o600))
+	_, err = newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		FS:             fs,
+		ConfigArgument: "credentialsFile=/user.json",
+	})
+	assert.Error(t, err)
+}
+
+func TestNewDistribution(t *testing.T) {
+	t.Parallel()
+	d := newDistribution([]float64{0, 1, 1.4, 1.5, 3, 1e9})
+	assert.Equal(t, int64(6), d.Count)
+	counts := make([]int64, bucketsCount+2)
+	counts[0], counts[1], counts[2], counts[3], counts[bucketsCount+1] = 1, 2, 1, 1, 1
+	assert.Equal(t, counts, d.BucketCounts)
+}
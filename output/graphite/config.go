@@ -0,0 +1,185 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package graphite
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+// The protocols of the Carbon daemon of Graphite.
+const (
+	protocolPlaintext = "plaintext"
+	protocolPickle    = "pickle"
+)
+
+// Config is the config for the graphite output
+type Config struct {
+	Addr          null.String        `json:"addr" envconfig:"K6_GRAPHITE_ADDR"`
+	Protocol      null.String        `json:"protocol" envconfig:"K6_GRAPHITE_PROTOCOL"`
+	Prefix        null.String        `json:"prefix" envconfig:"K6_GRAPHITE_PREFIX"`
+	FlushInterval types.NullDuration `json:"flushInterval" envconfig:"K6_GRAPHITE_FLUSH_INTERVAL"`
+	Timeout       types.NullDuration `json:"timeout" envconfig:"K6_GRAPHITE_TIMEOUT"`
+	EnableTags    null.Bool          `json:"enableTags" envconfig:"K6_GRAPHITE_ENABLE_TAGS"`
+	TagBlocklist  stats.TagSet       `json:"tagBlocklist" envconfig:"K6_GRAPHITE_TAG_BLOCKLIST"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Addr:          null.StringFrom("localhost:2003"),
+		Protocol:      null.StringFrom(protocolPlaintext),
+		Prefix:        null.StringFrom("k6."),
+		FlushInterval: types.NullDurationFrom(10 * time.Second),
+		Timeout:       types.NullDurationFrom(5 * time.Second),
+		EnableTags:    null.BoolFrom(false),
+		TagBlocklist:  (stats.TagVU | stats.TagIter | stats.TagURL).Map(),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Addr.Valid {
+		c.Addr = cfg.Addr
+	}
+	if cfg.Protocol.Valid {
+		c.Protocol = cfg.Protocol
+	}
+	if cfg.Prefix.Valid {
+		c.Prefix = cfg.Prefix
+	}
+	if cfg.FlushInterval.Valid {
+		c.FlushInterval = cfg.FlushInterval
+	}
+	if cfg.Timeout.Valid {
+		c.Timeout = cfg.Timeout
+	}
+	if cfg.EnableTags.Valid {
+		c.EnableTags = cfg.EnableTags
+	}
+	if cfg.TagBlocklist != nil {
+		c.TagBlocklist = cfg.TagBlocklist
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config. The first value can be the address
+// without a key, like in --out graphite=localhost:2004,protocol=pickle, and the tagBlocklist key
+// can be repeated.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	for i, pair := range strings.Split(arg, ",") {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			if i == 0 {
+				c.Addr = null.StringFrom(pair)
+				continue
+			}
+			return c, fmt.Errorf("couldn't parse %q as argument for graphite output", arg)
+		}
+		switch r[0] {
+		case "addr":
+			c.Addr = null.StringFrom(r[1])
+		case "protocol":
+			c.Protocol = null.StringFrom(r[1])
+		case "prefix":
+			c.Prefix = null.StringFrom(r[1])
+		case "flushInterval":
+			if err := c.FlushInterval.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "timeout":
+			if err := c.Timeout.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "enableTags":
+			if err := c.EnableTags.UnmarshalText([]byte(r[1])); err != nil {
+				return c, fmt.Errorf("enableTags must be true or false, not %s", r[1])
+			}
+		case "tagBlocklist":
+			if c.TagBlocklist == nil {
+				c.TagBlocklist = make(stats.TagSet)
+			}
+			c.TagBlocklist[r[1]] = true
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for graphite output", r[0])
+		}
+	}
+
+	return c, nil
+}
+
+// Validate returns an error if any config value is invalid.
+func (c Config) Validate() error {
+	if c.Addr.String == "" {
+		return fmt.Errorf("the graphite addr can't be empty")
+	}
+	if c.Protocol.String != protocolPlaintext && c.Protocol.String != protocolPickle {
+		return fmt.Errorf("invalid graphite protocol %q, it has to be %s or %s",
+			c.Protocol.String, protocolPlaintext, protocolPickle)
+	}
+	if c.FlushInterval.Duration <= 0 {
+		return fmt.Errorf("the graphite flushInterval should be positive, but it's %s", c.FlushInterval)
+	}
+	if c.Timeout.Duration <= 0 {
+		return fmt.Errorf("the graphite timeout should be positive, but it's %s", c.Timeout)
+	}
+	return nil
+}
+
+// GetConsolidatedConfig combines {default config values + JSON config +
+// environment vars + arg config values}, and returns the final result.
+func GetConsolidatedConfig(jsonRawConf json.RawMessage, env map[string]string, arg string) (Config, error) {
+	result := NewConfig()
+	if jsonRawConf != nil {
+		jsonConf := Config{}
+		if err := json.Unmarshal(jsonRawConf, &jsonConf); err != nil {
+			return result, err
+		}
+		result = result.Apply(jsonConf)
+	}
+
+	envConfig := Config{}
+	if err := envconfig.Process("", &envConfig); err != nil {
+		// TODO: get rid of envconfig and actually use the env parameter...
+		return result, err
+	}
+	result = result.Apply(envConfig)
+
+	if arg != "" {
+		argConf, err := ParseArg(arg)
+		if err != nil {
+			return result, err
+		}
+		result = result.Apply(argConf)
+	}
+
+	return result, result.Validate()
+}
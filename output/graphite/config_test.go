@@ -0,0 +1,93 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package graphite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+func TestParseArg(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		config      Config
+		expectedErr bool
+	}{
+		"localhost:2004,protocol=pickle": {
+			config: Config{Addr: null.StringFrom("localhost:2004"), Protocol: null.StringFrom("pickle")},
+		},
+		"addr=graphite:2003,prefix=loadtests.k6.,flushInterval=1m,timeout=1s": {
+			config: Config{
+				Addr:          null.StringFrom("graphite:2003"),
+				Prefix:        null.StringFrom("loadtests.k6."),
+				FlushInterval: types.NullDurationFrom(time.Minute),
+				Timeout:       types.NullDurationFrom(time.Second),
+			},
+		},
+		"enableTags=true,tagBlocklist=vu,tagBlocklist=name": {
+			config: Config{
+				EnableTags:   null.BoolFrom(true),
+				TagBlocklist: stats.TagSet{"vu": true, "name": true},
+			},
+		},
+		"enableTags=yes":      {expectedErr: true},
+		"localhost:2003,foo":  {expectedErr: true},
+		"foo=bar":             {expectedErr: true},
+		"flushInterval=never": {expectedErr: true},
+	}
+
+	for arg, tc := range cases {
+		arg, tc := arg, tc
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+			config, err := ParseArg(arg)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.config, config)
+		})
+	}
+}
+
+func TestGetConsolidatedConfig(t *testing.T) {
+	t.Parallel()
+	config, err := GetConsolidatedConfig([]byte(`{"prefix": "app.", "enableTags": true}`), nil, "graphite:2004")
+	require.NoError(t, err)
+	expected := NewConfig()
+	expected.Addr = null.StringFrom("graphite:2004")
+	expected.Prefix = null.StringFrom("app.")
+	expected.EnableTags = null.BoolFrom(true)
+	assert.Equal(t, expected, config)
+
+	for _, arg := range []string{"protocol=udp", "flushInterval=0s", "timeout=-1s", "addr="} {
+		_, err := GetConsolidatedConfig(nil, nil, arg)
+		assert.Error(t, err, arg)
+	}
+}
@@ -0,0 +1,206 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package graphite
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+// Output aggregates the metrics in intervals and sends them to the Carbon daemon of Graphite.
+type Output struct {
+	output.SampleBuffer
+
+	config          Config
+	logger          logrus.FieldLogger
+	periodicFlusher *output.PeriodicFlusher
+	conn            net.Conn
+	now             func() time.Time
+}
+
+var _ output.Output = new(Output)
+
+// New returns a new graphite output
+func New(params output.Params) (output.Output, error) {
+	return newOutput(params)
+}
+
+func newOutput(params output.Params) (*Output, error) {
+	conf, err := GetConsolidatedConfig(params.JSONConfig, params.Environment, params.ConfigArgument)
+	if err != nil {
+		return nil, err
+	}
+	return &Output{
+		config: conf,
+		logger: params.Logger.WithFields(logrus.Fields{"output": "graphite"}),
+		now:    time.Now,
+	}, nil
+}
+
+// Description returns a human-readable description of the output.
+func (o *Output) Description() string {
+	return fmt.Sprintf("graphite (%s, %s)", o.config.Addr.String, o.config.Protocol.String)
+}
+
+// Start starts the goroutine for the flushing of the metrics, the connection to Carbon is made
+// with the first flush, and again after any errors.
+func (o *Output) Start() error {
+	o.logger.Debug("Starting...")
+	pf, err := output.NewDrainingPeriodicFlusher(
+		time.Duration(o.config.FlushInterval.Duration), o.flushMetrics, o.HasSpilledSamples)
+	if err != nil {
+		return err
+	}
+	o.logger.Debug("Started!")
+	o.periodicFlusher = pf
+	return nil
+}
+
+// Stop flushes any remaining metrics and stops the goroutine.
+func (o *Output) Stop() error {
+	o.logger.Debug("Stopping...")
+	defer o.logger.Debug("Stopped!")
+	o.periodicFlusher.Stop()
+	if o.conn != nil {
+		return o.conn.Close()
+	}
+	return nil
+}
+
+// sanitize replaces the characters that can't be in the nodes of the Graphite paths or in the
+// values of the tags.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '.', ';', '~', '=', '\t', '\n', '\r':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// seriesTags returns the tags of the series in the format of the Graphite tagged series, like
+// ";method=GET;status=200".
+func seriesTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteByte(';')
+		b.WriteString(sanitize(k))
+		b.WriteByte('=')
+		b.WriteString(sanitize(tags[k]))
+	}
+	return b.String()
+}
+
+// aggregate returns the points of the samples, which are aggregated by their metrics and their
+// tags if they're enabled, all of them at the given time.
+func (o *Output) aggregate(containers []stats.SampleContainer, now time.Time) []point {
+	aggregator := &output.Aggregator{TagBlocklist: o.config.TagBlocklist, WithoutTags: !o.config.EnableTags.Bool}
+	aggregator.Add(containers...)
+	series := aggregator.Series()
+
+	timestamp := now.Unix()
+	points := make([]point, 0, len(series))
+	for _, s := range series {
+		base := o.config.Prefix.String + sanitize(s.Metric.Name)
+		tags := seriesTags(s.Tags)
+		add := func(suffix string, value float64) {
+			points = append(points, point{path: base + suffix + tags, value: value, timestamp: timestamp})
+		}
+		switch sink := s.Sink.(type) {
+		case *stats.CounterSink:
+			add("", sink.Value)
+		case *stats.GaugeSink:
+			add("", sink.Value)
+		case *stats.RateSink:
+			add("", float64(sink.Trues)/float64(sink.Total))
+		case *stats.TrendSink:
+			sink.Calc()
+			add(".avg", sink.Avg)
+			add(".min", sink.Min)
+			add(".med", sink.Med)
+			add(".max", sink.Max)
+			add(".p90", sink.P(0.90))
+			add(".p95", sink.P(0.95))
+			add(".count", float64(sink.Count))
+		}
+	}
+	return points
+}
+
+func (o *Output) flushMetrics() {
+	samples := o.GetBufferedSamples()
+	if len(samples) == 0 {
+		return
+	}
+	start := time.Now()
+	points := o.aggregate(samples, o.now())
+	if len(points) == 0 {
+		return
+	}
+	if err := o.send(points); err != nil {
+		o.logger.WithError(err).Errorf("Couldn't send %d points to Graphite", len(points))
+		return
+	}
+	o.logger.WithField("t", time.Since(start)).WithField("points", len(points)).Debug("Wrote metrics to Graphite")
+}
+
+// send writes the points to Carbon, it connects first if it isn't connected, and it closes the
+// connection after any errors, so the next flush connects again.
+func (o *Output) send(points []point) error {
+	timeout := time.Duration(o.config.Timeout.Duration)
+	if o.conn == nil {
+		conn, err := net.DialTimeout("tcp", o.config.Addr.String, timeout)
+		if err != nil {
+			return err
+		}
+		o.conn = conn
+	}
+
+	var data []byte
+	if o.config.Protocol.String == protocolPickle {
+		data = encodePickle(points)
+	} else {
+		data = encodePlaintext(points)
+	}
+	err := o.conn.SetWriteDeadline(time.Now().Add(timeout))
+	if err == nil {
+		_, err = o.conn.Write(data)
+	}
+	if err != nil {
+		_ = o.conn.Close()
+		o.conn = nil
+	}
+	return err
+}
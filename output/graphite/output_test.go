@@ -0,0 +1,151 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package graphite
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+func TestOutput(t *testing.T) {
+	t.Parallel()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = l.Close() }()
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		var lines []string
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		received <- lines
+	}()
+
+	o, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		ConfigArgument: l.Addr().String() + ",enableTags=true,flushInterval=1h",
+	})
+	require.NoError(t, err)
+	o.now = func() time.Time { return time.Unix(1614173820, 0) }
+	require.NoError(t, o.Start())
+
+	reqs := stats.New("http_reqs", stats.Counter)
+	duration := stats.New("http_req_duration", stats.Trend)
+	checks := stats.New("checks", stats.Rate)
+	vus := stats.New("vus", stats.Gauge)
+	get := stats.NewSampleTags(map[string]string{"method": "GET", "name": "home page", "vu": "1"})
+	post := stats.NewSampleTags(map[string]string{"method": "POST", "vu": "2"})
+	now := time.Now()
+	o.AddMetricSamples([]stats.SampleContainer{stats.Samples{
+		{Metric: reqs, Value: 1, Time: now, Tags: get},
+		{Metric: reqs, Value: 1, Time: now, Tags: get},
+		{Metric: reqs, Value: 1, Time: now, Tags: post},
+		{Metric: duration, Value: 100, Time: now},
+		{Metric: duration, Value: 300, Time: now},
+		{Metric: checks, Value: 1, Time: now},
+		{Metric: checks, Value: 0, Time: now},
+		{Metric: vus, Value: 5, Time: now},
+		{Metric: vus, Value: 10, Time: now},
+	}})
+	require.NoError(t, o.Stop())
+
+	select {
+	case lines := <-received:
+		assert.Equal(t, []string{
+			"k6.checks 0.5 1614173820",
+			"k6.http_req_duration.avg 200 1614173820",
+			"k6.http_req_duration.min 100 1614173820",
+			"k6.http_req_duration.med 200 1614173820",
+			"k6.http_req_duration.max 300 1614173820",
+			"k6.http_req_duration.p90 280 1614173820",
+			"k6.http_req_duration.p95 290 1614173820",
+			"k6.http_req_duration.count 2 1614173820",
+			"k6.http_reqs;method=GET;name=home_page 2 1614173820",
+			"k6.http_reqs;method=POST 1 1614173820",
+			"k6.vus 10 1614173820",
+		}, lines)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the metrics weren't sent")
+	}
+}
+
+func TestOutputWithoutTags(t *testing.T) {
+	t.Parallel()
+	o, err := newOutput(output.Params{Logger: testutils.NewLogger(t), ConfigArgument: "prefix=app."})
+	require.NoError(t, err)
+	reqs := stats.New("http_reqs", stats.Counter)
+	points := o.aggregate([]stats.SampleContainer{stats.Samples{
+		{Metric: reqs, Value: 1, Tags: stats.NewSampleTags(map[string]string{"method": "GET"})},
+		{Metric: reqs, Value: 2, Tags: stats.NewSampleTags(map[string]string{"method": "POST"})},
+	}}, time.Unix(10, 0))
+	assert.Equal(t, []point{{path: "app.http_reqs", value: 3, timestamp: 10}}, points)
+}
+
+func TestOutputConnectionError(t *testing.T) {
+	t.Parallel()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	o, err := newOutput(output.Params{Logger: testutils.NewLogger(t), ConfigArgument: addr + ",timeout=1s"})
+	require.NoError(t, err)
+	assert.Error(t, o.send([]point{{path: "k6.vus", value: 1, timestamp: 10}}))
+	assert.Nil(t, o.conn)
+}
+
+func TestEncodePickle(t *testing.T) {
+	t.Parallel()
+	data := encodePickle([]point{{path: "k6.vus", value: 1.5, timestamp: 1614173820}})
+	require.True(t, len(data) > 4)
+	assert.Equal(t, uint32(len(data)-4), binary.BigEndian.Uint32(data[:4]))
+	assert.Equal(t, []byte{
+		0x80, 2, ']', '(',
+		'X', 6, 0, 0, 0, 'k', '6', '.', 'v', 'u', 's',
+		'J', 0x7c, 0x56, 0x36, 0x60,
+		'G', 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+		0x86, 0x86, 'e', '.',
+	}, data[4:])
+
+	points := make([]point, picklePointsPerMessage+1)
+	for i := range points {
+		points[i] = point{path: "k6.vus", value: 1, timestamp: 10}
+	}
+	data = encodePickle(points)
+	first := binary.BigEndian.Uint32(data[:4])
+	second := binary.BigEndian.Uint32(data[4+first : 8+first])
+	assert.Equal(t, len(data), int(8+first+second))
+}
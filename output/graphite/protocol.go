@@ -0,0 +1,108 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package graphite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+// The points of a single message of the pickle protocol, Carbon limits the size of the messages.
+const picklePointsPerMessage = 500
+
+// point is a single value of a metric path at a time, in seconds.
+type point struct {
+	path      string
+	value     float64
+	timestamp int64
+}
+
+// encodePlaintext returns the points in the plaintext protocol, a line per point.
+func encodePlaintext(points []point) []byte {
+	var buf bytes.Buffer
+	for _, p := range points {
+		buf.WriteString(p.path)
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatFloat(p.value, 'f', -1, 64))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatInt(p.timestamp, 10))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// encodePickle returns the points in the pickle protocol, as messages with at most
+// picklePointsPerMessage points each. Each message is the length of its payload as a 4 byte
+// big-endian integer, and the payload is the pickle (protocol 2) of the list of the
+// (path, (timestamp, value)) tuples.
+func encodePickle(points []point) []byte {
+	var buf bytes.Buffer
+	for start := 0; start < len(points); start += picklePointsPerMessage {
+		end := start + picklePointsPerMessage
+		if end > len(points) {
+			end = len(points)
+		}
+		payload := picklePoints(points[start:end])
+		var header [4]byte
+		binary.BigEndian.PutUint32(header[:], uint32(len(payload)))
+		buf.Write(header[:])
+		buf.Write(payload)
+	}
+	return buf.Bytes()
+}
+
+// The opcodes of the pickle protocol 2 that the payload is made of.
+const (
+	pickleProto      = 0x80
+	pickleEmptyList  = ']'
+	pickleMark       = '('
+	pickleAppends    = 'e'
+	pickleBinUnicode = 'X'
+	pickleBinInt     = 'J'
+	pickleBinFloat   = 'G'
+	pickleTuple2     = 0x86
+	pickleStop       = '.'
+)
+
+func picklePoints(points []point) []byte {
+	var buf bytes.Buffer
+	var scratch [8]byte
+	buf.Write([]byte{pickleProto, 2, pickleEmptyList, pickleMark})
+	for _, p := range points {
+		buf.WriteByte(pickleBinUnicode)
+		binary.LittleEndian.PutUint32(scratch[:4], uint32(len(p.path)))
+		buf.Write(scratch[:4])
+		buf.WriteString(p.path)
+
+		buf.WriteByte(pickleBinInt)
+		binary.LittleEndian.PutUint32(scratch[:4], uint32(int32(p.timestamp)))
+		buf.Write(scratch[:4])
+		buf.WriteByte(pickleBinFloat)
+		binary.BigEndian.PutUint64(scratch[:], math.Float64bits(p.value))
+		buf.Write(scratch[:])
+
+		buf.Write([]byte{pickleTuple2, pickleTuple2})
+	}
+	buf.Write([]byte{pickleAppends, pickleStop})
+	return buf.Bytes()
+}
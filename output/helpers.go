@@ -32,10 +32,85 @@ import (
 // used by most outputs, since we generally want to flush metric samples to the
 // remote service asynchronously. We want to do it only every several seconds,
 // and we don't want to block the Engine in the meantime.
+//
+// By default, the buffer grows without a limit when an output falls behind. With
+// SetBufferLimits(), the samples over the limit spill to an on-disk queue, or are
+// dropped, and each flush gets at most the limit of the samples from the queue.
 type SampleBuffer struct {
 	sync.Mutex
 	buffer []stats.SampleContainer
 	maxLen int
+
+	limits   SampleBufferLimits
+	buffered int
+	spill    *spillQueue
+	dropped  uint64
+	spillErr error
+}
+
+// SampleBufferLimits are the limits of a SampleBuffer.
+type SampleBufferLimits struct {
+	// The maximum number of the samples in memory.
+	MaxSamples int
+	// The directory of the on-disk queue of the samples over MaxSamples, they are dropped if it's
+	// empty. The samples from the queue are plain samples, without the types of their containers.
+	SpillDir string
+	// The maximum size of the on-disk queue in bytes, the samples after it are dropped. 0 means
+	// unlimited.
+	MaxSpillBytes int64
+}
+
+// SampleBufferStats are the depth of a SampleBuffer and the samples it dropped.
+type SampleBufferStats struct {
+	Buffered     int    // the samples in memory
+	Spilled      int    // the samples in the on-disk queue
+	SpilledBytes int64  // the size of the on-disk queue
+	Dropped      uint64 // all of the samples that were dropped
+	Err          error  // the last error of the on-disk queue
+}
+
+// WithBufferStats is an output with the stats of its SampleBuffer, or of another buffer.
+type WithBufferStats interface {
+	Output
+	BufferStats() (SampleBufferStats, bool)
+}
+
+// SetBufferLimits limits the samples the buffer keeps in memory. It has to be called before any
+// samples are added.
+func (sc *SampleBuffer) SetBufferLimits(limits SampleBufferLimits) error {
+	if limits.MaxSamples <= 0 {
+		return fmt.Errorf("the maximum number of the buffered samples should be positive but was %d",
+			limits.MaxSamples)
+	}
+	sc.Lock()
+	defer sc.Unlock()
+	sc.limits = limits
+	if limits.SpillDir != "" {
+		sc.spill = newSpillQueue(limits.SpillDir, limits.MaxSpillBytes)
+	}
+	return nil
+}
+
+// BufferStats returns the stats of the buffer, if it has limits.
+func (sc *SampleBuffer) BufferStats() (SampleBufferStats, bool) {
+	sc.Lock()
+	defer sc.Unlock()
+	if sc.limits.MaxSamples <= 0 {
+		return SampleBufferStats{}, false
+	}
+	result := SampleBufferStats{Buffered: sc.buffered, Dropped: sc.dropped, Err: sc.spillErr}
+	if sc.spill != nil {
+		result.Spilled, result.SpilledBytes = sc.spill.pending(), sc.spill.size()
+	}
+	return result, true
+}
+
+// HasSpilledSamples returns whether there are samples in the on-disk queue, which the next
+// GetBufferedSamples() returns.
+func (sc *SampleBuffer) HasSpilledSamples() bool {
+	sc.Lock()
+	defer sc.Unlock()
+	return sc.spill != nil && sc.spill.pending() > 0
 }
 
 // AddMetricSamples adds the given metric samples to the internal buffer.
@@ -44,8 +119,31 @@ func (sc *SampleBuffer) AddMetricSamples(samples []stats.SampleContainer) {
 		return
 	}
 	sc.Lock()
-	sc.buffer = append(sc.buffer, samples...)
-	sc.Unlock()
+	defer sc.Unlock()
+	if sc.limits.MaxSamples <= 0 {
+		sc.buffer = append(sc.buffer, samples...)
+		return
+	}
+
+	for _, container := range samples {
+		containerSamples := container.GetSamples()
+		// the samples after the spilled ones are spilled too, so they keep their order
+		spilling := sc.spill != nil && sc.spill.pending() > 0
+		if !spilling && sc.buffered+len(containerSamples) <= sc.limits.MaxSamples {
+			sc.buffer = append(sc.buffer, container)
+			sc.buffered += len(containerSamples)
+			continue
+		}
+		if sc.spill == nil {
+			sc.dropped += uint64(len(containerSamples))
+			continue
+		}
+		pushed, err := sc.spill.push(containerSamples)
+		if err != nil {
+			sc.spillErr = err
+		}
+		sc.dropped += uint64(len(containerSamples) - pushed)
+	}
 }
 
 // GetBufferedSamples returns the currently buffered metric samples and makes a
@@ -56,6 +154,13 @@ func (sc *SampleBuffer) GetBufferedSamples() []stats.SampleContainer {
 	defer sc.Unlock()
 
 	buffered, bufferedLen := sc.buffer, len(sc.buffer)
+	if sc.spill != nil && sc.spill.pending() > 0 {
+		if spilled := sc.popSpilledSamples(); len(spilled) > 0 {
+			buffered = append(buffered, spilled)
+			bufferedLen = len(buffered)
+		}
+	}
+	sc.buffered = 0
 	if bufferedLen == 0 {
 		return nil
 	}
@@ -69,12 +174,26 @@ func (sc *SampleBuffer) GetBufferedSamples() []stats.SampleContainer {
 	return buffered
 }
 
+// popSpilledSamples returns up to the limit of the samples from the on-disk queue, all of its
+// samples are dropped if it can't be read.
+func (sc *SampleBuffer) popSpilledSamples() stats.Samples {
+	pending := sc.spill.pending()
+	samples, err := sc.spill.pop(sc.limits.MaxSamples)
+	if err != nil {
+		sc.spillErr = err
+		sc.dropped += uint64(pending - len(samples))
+		_ = sc.spill.close()
+	}
+	return samples
+}
+
 // PeriodicFlusher is a small helper for asynchronously flushing buffered metric
 // samples on regular intervals. The biggest benefit is having a Stop() method
 // that waits for one last flush before it returns.
 type PeriodicFlusher struct {
 	period        time.Duration
 	flushCallback func()
+	pending       func() bool
 	stop          chan struct{}
 	stopped       chan struct{}
 	once          *sync.Once
@@ -89,6 +208,9 @@ func (pf *PeriodicFlusher) run() {
 			pf.flushCallback()
 		case <-pf.stop:
 			pf.flushCallback()
+			for pf.pending != nil && pf.pending() {
+				pf.flushCallback()
+			}
 			close(pf.stopped)
 			return
 		}
@@ -107,6 +229,15 @@ func (pf *PeriodicFlusher) Stop() {
 
 // NewPeriodicFlusher creates a new PeriodicFlusher and starts its goroutine.
 func NewPeriodicFlusher(period time.Duration, flushCallback func()) (*PeriodicFlusher, error) {
+	return NewDrainingPeriodicFlusher(period, flushCallback, nil)
+}
+
+// NewDrainingPeriodicFlusher creates a new PeriodicFlusher whose last flush is repeated while
+// pending returns true, like with the HasSpilledSamples() of a SampleBuffer, and starts its
+// goroutine.
+func NewDrainingPeriodicFlusher(
+	period time.Duration, flushCallback func(), pending func() bool,
+) (*PeriodicFlusher, error) {
 	if period <= 0 {
 		return nil, fmt.Errorf("metric flush period should be positive but was %s", period)
 	}
@@ -114,6 +245,7 @@ func NewPeriodicFlusher(period time.Duration, flushCallback func()) (*PeriodicFl
 	pf := &PeriodicFlusher{
 		period:        period,
 		flushCallback: flushCallback,
+		pending:       pending,
 		stop:          make(chan struct{}),
 		stopped:       make(chan struct{}),
 		once:          &sync.Once{},
@@ -21,6 +21,7 @@
 package output
 
 import (
+	"io/ioutil"
 	"math/rand"
 	"sync"
 	"testing"
@@ -121,6 +122,116 @@ loop:
 	}
 }
 
+func TestSampleBufferLimits(t *testing.T) {
+	t.Parallel()
+	metric := stats.New("my_metric", stats.Trend)
+	tags := stats.IntoSampleTags(&map[string]string{"url": "https://example.com"})
+	samples := func(values ...float64) stats.Samples {
+		result := make(stats.Samples, len(values))
+		for i, v := range values {
+			result[i] = stats.Sample{Time: time.Unix(int64(v), 0).UTC(), Metric: metric, Value: v, Tags: tags}
+		}
+		return result
+	}
+	valuesOf := func(containers []stats.SampleContainer) []float64 {
+		var values []float64
+		for _, sc := range containers {
+			for _, sample := range sc.GetSamples() {
+				assert.Same(t, metric, sample.Metric)
+				values = append(values, sample.Value)
+			}
+		}
+		return values
+	}
+
+	t.Run("Drop", func(t *testing.T) {
+		t.Parallel()
+		buffer := SampleBuffer{}
+		_, ok := buffer.BufferStats()
+		assert.False(t, ok)
+		assert.Error(t, buffer.SetBufferLimits(SampleBufferLimits{}))
+		require.NoError(t, buffer.SetBufferLimits(SampleBufferLimits{MaxSamples: 3}))
+
+		buffer.AddMetricSamples([]stats.SampleContainer{samples(1, 2), samples(3, 4), samples(5)})
+		bufferStats, ok := buffer.BufferStats()
+		require.True(t, ok)
+		assert.Equal(t, SampleBufferStats{Buffered: 3, Dropped: 2}, bufferStats)
+		assert.Equal(t, []float64{1, 2, 5}, valuesOf(buffer.GetBufferedSamples()))
+		assert.False(t, buffer.HasSpilledSamples())
+	})
+
+	t.Run("Spill", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		buffer := SampleBuffer{}
+		require.NoError(t, buffer.SetBufferLimits(SampleBufferLimits{MaxSamples: 2, SpillDir: dir}))
+
+		buffer.AddMetricSamples([]stats.SampleContainer{samples(1, 2), samples(3), samples(4, 5, 6)})
+		// the samples after the spilled ones are spilled too, so they keep their order
+		buffer.AddMetricSamples([]stats.SampleContainer{samples(7)})
+		bufferStats, _ := buffer.BufferStats()
+		assert.Equal(t, 2, bufferStats.Buffered)
+		assert.Equal(t, 5, bufferStats.Spilled)
+		assert.Positive(t, bufferStats.SpilledBytes)
+		assert.True(t, buffer.HasSpilledSamples())
+
+		assert.Equal(t, []float64{1, 2, 3, 4}, valuesOf(buffer.GetBufferedSamples()))
+		buffered := buffer.GetBufferedSamples()
+		assert.Equal(t, []float64{5, 6}, valuesOf(buffered))
+		assert.Equal(t, "https://example.com", buffered[0].GetSamples()[0].Tags.CloneTags()["url"])
+		assert.Equal(t, time.Unix(5, 0).UTC(), buffered[0].GetSamples()[0].Time)
+		assert.Equal(t, []float64{7}, valuesOf(buffer.GetBufferedSamples()))
+
+		// the file is removed when the queue is empty
+		assert.False(t, buffer.HasSpilledSamples())
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, files)
+		buffer.AddMetricSamples([]stats.SampleContainer{samples(8)})
+		assert.Equal(t, []float64{8}, valuesOf(buffer.GetBufferedSamples()))
+	})
+
+	t.Run("SpillLimit", func(t *testing.T) {
+		t.Parallel()
+		buffer := SampleBuffer{}
+		dir := t.TempDir()
+		require.NoError(t, buffer.SetBufferLimits(SampleBufferLimits{
+			MaxSamples: 1, SpillDir: dir, MaxSpillBytes: 200,
+		}))
+		buffer.AddMetricSamples([]stats.SampleContainer{samples(1), samples(2, 3, 4, 5)})
+		bufferStats, _ := buffer.BufferStats()
+		assert.Equal(t, 2, bufferStats.Spilled)
+		assert.Equal(t, uint64(2), bufferStats.Dropped)
+
+		// the popped samples free their space in the queue, and its file is compacted
+		assert.Equal(t, []float64{1, 2}, valuesOf(buffer.GetBufferedSamples()))
+		buffer.AddMetricSamples([]stats.SampleContainer{samples(6, 7)})
+		bufferStats, _ = buffer.BufferStats()
+		assert.Equal(t, 2, bufferStats.Spilled)
+		assert.Equal(t, uint64(3), bufferStats.Dropped)
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		assert.Equal(t, bufferStats.SpilledBytes, files[0].Size())
+		assert.Equal(t, []float64{3}, valuesOf(buffer.GetBufferedSamples()))
+		assert.Equal(t, []float64{6}, valuesOf(buffer.GetBufferedSamples()))
+	})
+
+	t.Run("DrainingFlusher", func(t *testing.T) {
+		t.Parallel()
+		buffer := SampleBuffer{}
+		require.NoError(t, buffer.SetBufferLimits(SampleBufferLimits{MaxSamples: 1, SpillDir: t.TempDir()}))
+		var flushed []float64
+		f, err := NewDrainingPeriodicFlusher(time.Hour, func() {
+			flushed = append(flushed, valuesOf(buffer.GetBufferedSamples())...)
+		}, buffer.HasSpilledSamples)
+		require.NoError(t, err)
+		buffer.AddMetricSamples([]stats.SampleContainer{samples(1), samples(2), samples(3)})
+		f.Stop()
+		assert.Equal(t, []float64{1, 2, 3}, flushed)
+	})
+}
+
 func TestPeriodicFlusherBasics(t *testing.T) {
 	t.Parallel()
 
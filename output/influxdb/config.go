@@ -45,12 +45,26 @@ type Config struct {
 	PushInterval     types.NullDuration `json:"pushInterval,omitempty" envconfig:"K6_INFLUXDB_PUSH_INTERVAL"`
 	ConcurrentWrites null.Int           `json:"concurrentWrites,omitempty" envconfig:"K6_INFLUXDB_CONCURRENT_WRITES"`
 
+	// Non-blocking writes, in batches of BatchSize points or on each FlushInterval.
+	NonBlocking   null.Bool          `json:"nonBlocking,omitempty" envconfig:"K6_INFLUXDB_NON_BLOCKING"`
+	BatchSize     null.Int           `json:"batchSize,omitempty" envconfig:"K6_INFLUXDB_BATCH_SIZE"`
+	FlushInterval types.NullDuration `json:"flushInterval,omitempty" envconfig:"K6_INFLUXDB_FLUSH_INTERVAL"`
+
 	// Samples.
 	DB           null.String `json:"db" envconfig:"K6_INFLUXDB_DB"`
 	Precision    null.String `json:"precision,omitempty" envconfig:"K6_INFLUXDB_PRECISION"`
 	Retention    null.String `json:"retention,omitempty" envconfig:"K6_INFLUXDB_RETENTION"`
 	Consistency  null.String `json:"consistency,omitempty" envconfig:"K6_INFLUXDB_CONSISTENCY"`
 	TagsAsFields []string    `json:"tagsAsFields,omitempty" envconfig:"K6_INFLUXDB_TAGS_AS_FIELDS"`
+
+	// Round the timestamps to the precision, ns, us, ms or s, instead of truncating them.
+	RoundTimestamps null.Bool `json:"roundTimestamps,omitempty" envconfig:"K6_INFLUXDB_ROUND_TIMESTAMPS"`
+
+	// The creation of the database when the output starts, with the duration of its retention
+	// policy, infinite by default, and of its shard groups.
+	CreateDatabase    null.Bool          `json:"createDatabase,omitempty" envconfig:"K6_INFLUXDB_CREATE_DATABASE"`
+	RetentionDuration types.NullDuration `json:"retentionDuration,omitempty" envconfig:"K6_INFLUXDB_RETENTION_DURATION"`
+	ShardDuration     types.NullDuration `json:"shardDuration,omitempty" envconfig:"K6_INFLUXDB_SHARD_DURATION"`
 }
 
 // NewConfig creates a new InfluxDB output config with some default values.
@@ -61,6 +75,10 @@ func NewConfig() Config {
 		TagsAsFields:     []string{"vu", "iter", "url"},
 		ConcurrentWrites: null.NewInt(10, false),
 		PushInterval:     types.NewNullDuration(time.Second, false),
+		NonBlocking:      null.NewBool(false, false),
+		BatchSize:        null.NewInt(5000, false),
+		FlushInterval:    types.NewNullDuration(time.Second, false),
+		CreateDatabase:   null.NewBool(true, false),
 	}
 	return c
 }
@@ -87,6 +105,9 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.Precision.Valid {
 		c.Precision = cfg.Precision
 	}
+	if cfg.RoundTimestamps.Valid {
+		c.RoundTimestamps = cfg.RoundTimestamps
+	}
 	if cfg.Retention.Valid {
 		c.Retention = cfg.Retention
 	}
@@ -103,6 +124,24 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.ConcurrentWrites.Valid {
 		c.ConcurrentWrites = cfg.ConcurrentWrites
 	}
+	if cfg.NonBlocking.Valid {
+		c.NonBlocking = cfg.NonBlocking
+	}
+	if cfg.BatchSize.Valid {
+		c.BatchSize = cfg.BatchSize
+	}
+	if cfg.FlushInterval.Valid {
+		c.FlushInterval = cfg.FlushInterval
+	}
+	if cfg.CreateDatabase.Valid {
+		c.CreateDatabase = cfg.CreateDatabase
+	}
+	if cfg.RetentionDuration.Valid {
+		c.RetentionDuration = cfg.RetentionDuration
+	}
+	if cfg.ShardDuration.Valid {
+		c.ShardDuration = cfg.ShardDuration
+	}
 	return c
 }
 
@@ -170,6 +209,13 @@ func ParseURL(text string) (Config, error) {
 			c.PayloadSize = null.IntFrom(int64(size))
 		case "precision":
 			c.Precision = null.StringFrom(vs[0])
+		case "roundTimestamps":
+			var round bool
+			round, err = strconv.ParseBool(vs[0])
+			if err != nil {
+				return c, fmt.Errorf("roundTimestamps must be true or false, not %s", vs[0])
+			}
+			c.RoundTimestamps = null.BoolFrom(round)
 		case "retention":
 			c.Retention = null.StringFrom(vs[0])
 		case "consistency":
@@ -189,6 +235,42 @@ func ParseURL(text string) (Config, error) {
 			c.ConcurrentWrites = null.IntFrom(int64(writes))
 		case "tagsAsFields":
 			c.TagsAsFields = vs
+		case "nonBlocking":
+			var nonBlocking bool
+			nonBlocking, err = strconv.ParseBool(vs[0])
+			if err != nil {
+				return c, fmt.Errorf("nonBlocking must be true or false, not %s", vs[0])
+			}
+			c.NonBlocking = null.BoolFrom(nonBlocking)
+		case "batchSize":
+			var size int
+			size, err = strconv.Atoi(vs[0])
+			if err != nil {
+				return c, err
+			}
+			c.BatchSize = null.IntFrom(int64(size))
+		case "flushInterval":
+			err = c.FlushInterval.UnmarshalText([]byte(vs[0]))
+			if err != nil {
+				return c, err
+			}
+		case "createDatabase":
+			var create bool
+			create, err = strconv.ParseBool(vs[0])
+			if err != nil {
+				return c, fmt.Errorf("createDatabase must be true or false, not %s", vs[0])
+			}
+			c.CreateDatabase = null.BoolFrom(create)
+		case "retentionDuration":
+			err = c.RetentionDuration.UnmarshalText([]byte(vs[0]))
+			if err != nil {
+				return c, err
+			}
+		case "shardDuration":
+			err = c.ShardDuration.UnmarshalText([]byte(vs[0]))
+			if err != nil {
+				return c, err
+			}
 		default:
 			return c, fmt.Errorf("unknown query parameter: %s", k)
 		}
@@ -22,9 +22,12 @@ package influxdb
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
 )
 
 func TestParseURL(t *testing.T) {
@@ -46,6 +49,17 @@ func TestParseURL(t *testing.T) {
 		"?insecure=ture":   {Config{}, "insecure must be true or false, not ture"},
 		"?payload_size=69": {Config{PayloadSize: null.IntFrom(69)}, ""},
 		"?payload_size=a":  {Config{}, "strconv.Atoi: parsing \"a\": invalid syntax"},
+		"?nonBlocking=true&batchSize=100&flushInterval=500ms": {Config{
+			NonBlocking:   null.BoolFrom(true),
+			BatchSize:     null.IntFrom(100),
+			FlushInterval: types.NullDurationFrom(500 * time.Millisecond),
+		}, ""},
+		"?nonBlocking=1x": {Config{}, "nonBlocking must be true or false, not 1x"},
+		"?createDatabase=false&retentionDuration=720h&shardDuration=24h": {Config{
+			CreateDatabase:    null.BoolFrom(false),
+			RetentionDuration: types.NullDurationFrom(720 * time.Hour),
+			ShardDuration:     types.NullDurationFrom(24 * time.Hour),
+		}, ""},
 	}
 	for str, data := range testdata {
 		t.Run(str, func(t *testing.T) {
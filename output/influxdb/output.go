@@ -21,18 +21,28 @@
 package influxdb
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	client "github.com/influxdata/influxdb1-client/v2"
 	"github.com/sirupsen/logrus"
 
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/types"
 	"go.k6.io/k6/output"
 	"go.k6.io/k6/stats"
 )
 
+// The measurements that the test run metadata point and the test run manifest point are written to.
+const (
+	metadataMeasurement = "k6_test_run"
+	manifestMeasurement = "k6_test_run_manifest"
+)
+
 // FieldKind defines Enum for tag-to-field type conversion
 type FieldKind int
 
@@ -61,8 +71,18 @@ type Output struct {
 	logger      logrus.FieldLogger
 	semaphoreCh chan struct{}
 	fieldKinds  map[string]FieldKind
+
+	// Only set with the nonBlocking option.
+	writeAPI *writeAPI
+	// The precision the timestamps are rounded to, only set with the roundTimestamps option.
+	roundTo time.Duration
+	// The precision the timestamps are truncated to, only set for microseconds, since the
+	// client writes them as nanoseconds.
+	truncateTo time.Duration
 }
 
+var _ output.WithBufferStats = &Output{}
+
 // New returns new influxdb output
 func New(params output.Params) (output.Output, error) {
 	return newOutput(params)
@@ -81,6 +101,23 @@ func newOutput(params output.Params) (*Output, error) {
 	if conf.ConcurrentWrites.Int64 <= 0 {
 		return nil, errors.New("influxdb's ConcurrentWrites must be a positive number")
 	}
+	if conf.NonBlocking.Bool && conf.BatchSize.Int64 <= 0 {
+		return nil, errors.New("influxdb's BatchSize must be a positive number")
+	}
+	if conf.NonBlocking.Bool && conf.FlushInterval.Duration <= 0 {
+		return nil, errors.New("influxdb's FlushInterval must be a positive duration")
+	}
+	precision, err := precisionDuration(conf.Precision.String)
+	if err != nil {
+		return nil, err
+	}
+	var roundTo, truncateTo time.Duration
+	switch {
+	case conf.RoundTimestamps.Bool:
+		roundTo = precision
+	case isMicroseconds(conf.Precision.String):
+		truncateTo = precision
+	}
 	fldKinds, err := MakeFieldKinds(conf)
 	return &Output{
 		params: params,
@@ -92,6 +129,8 @@ func newOutput(params output.Params) (*Output, error) {
 		BatchConf:   batchConf,
 		semaphoreCh: make(chan struct{}, conf.ConcurrentWrites.Int64),
 		fieldKinds:  fldKinds,
+		roundTo:     roundTo,
+		truncateTo:  truncateTo,
 	}, err
 }
 
@@ -148,12 +187,19 @@ func (o *Output) batchFromSamples(containers []stats.SampleContainer) (client.Ba
 				cache[sample.Tags] = cacheItem{tags, values}
 			}
 			values["value"] = sample.Value
+			ts := sample.Time
+			if o.roundTo > 0 {
+				ts = ts.Round(o.roundTo)
+			}
+			if o.truncateTo > 0 {
+				ts = ts.Truncate(o.truncateTo)
+			}
 			var p *client.Point
 			p, err = client.NewPoint(
 				sample.Metric.Name,
 				tags,
 				values,
-				sample.Time,
+				ts,
 			)
 			if err != nil {
 				return nil, fmt.Errorf("couldn't make point from sample: %w", err)
@@ -165,6 +211,147 @@ func (o *Output) batchFromSamples(containers []stats.SampleContainer) (client.Ba
 	return batch, nil
 }
 
+// SetRunStart writes a single point with the test run metadata, at the start time of the test run.
+func (o *Output) SetRunStart(md *lib.TestRunMetadata) {
+	if err := o.writeRunMetadata(md); err != nil {
+		o.logger.WithError(err).Warn("Couldn't write the test run metadata")
+	}
+}
+
+// writeRunMetadata writes the point with the test run metadata, tagged with its run_id, which is
+// also the run_id tag of the other points while the run_id system tag is enabled.
+func (o *Output) writeRunMetadata(md *lib.TestRunMetadata) error {
+	batch, err := client.NewBatchPoints(o.BatchConf)
+	if err != nil {
+		return fmt.Errorf("couldn't make a batch: %w", err)
+	}
+
+	fields := map[string]interface{}{
+		"script_hash":    md.ScriptHash,
+		"options_digest": md.OptionsDigest,
+	}
+
+	startTime := time.Now()
+	if md.StartTime != nil {
+		startTime = *md.StartTime
+	}
+	p, err := client.NewPoint(metadataMeasurement, runMetadataTags(md), fields, startTime)
+	if err != nil {
+		return fmt.Errorf("couldn't make point from the test run metadata: %w", err)
+	}
+	batch.AddPoint(p)
+
+	return o.Client.Write(batch)
+}
+
+func runMetadataTags(md *lib.TestRunMetadata) map[string]string {
+	tags := make(map[string]string, len(md.Labels)+4)
+	for k, v := range md.Labels {
+		tags[k] = v
+	}
+	tags["run_id"] = md.RunID
+	tags["script_name"] = md.ScriptName
+	tags["k6_version"] = md.K6Version
+	if md.ExecutionSegment != "" {
+		tags["execution_segment"] = md.ExecutionSegment
+	}
+	return tags
+}
+
+// SetRunManifest writes a single point with the end of the test run, its exit code and its
+// options, with the same tags as the test run metadata point.
+func (o *Output) SetRunManifest(manifest *lib.TestRunManifest) {
+	if manifest.TestRunMetadata == nil {
+		return
+	}
+	if err := o.writeRunManifest(manifest); err != nil {
+		o.logger.WithError(err).Error("InfluxDB: Couldn't write the test run manifest")
+	}
+}
+
+func (o *Output) writeRunManifest(manifest *lib.TestRunManifest) error {
+	batch, err := client.NewBatchPoints(o.BatchConf)
+	if err != nil {
+		return fmt.Errorf("couldn't make a batch: %w", err)
+	}
+
+	options, err := json.Marshal(manifest.Options)
+	if err != nil {
+		return fmt.Errorf("couldn't serialize the options: %w", err)
+	}
+	fields := map[string]interface{}{
+		"script_hash":    manifest.ScriptHash,
+		"options_digest": manifest.OptionsDigest,
+		"options":        string(options),
+		"exit_code":      manifest.ExitCode,
+	}
+	// the test run didn't start if it failed while the VUs were initialized
+	if manifest.StartTime != nil {
+		fields["duration"] = manifest.EndTime.Sub(*manifest.StartTime).Seconds()
+	}
+	if manifest.Error != "" {
+		fields["error"] = manifest.Error
+	}
+
+	p, err := client.NewPoint(manifestMeasurement, runMetadataTags(manifest.TestRunMetadata), fields, manifest.EndTime)
+	if err != nil {
+		return fmt.Errorf("couldn't make point from the test run manifest: %w", err)
+	}
+	batch.AddPoint(p)
+
+	return o.Client.Write(batch)
+}
+
+// createDatabaseQuery returns the query that creates the database, with its retention policy if
+// its duration or the duration of its shard groups is set.
+func (o *Output) createDatabaseQuery() string {
+	query := "CREATE DATABASE " + strconv.Quote(o.BatchConf.Database)
+	if !o.Config.RetentionDuration.Valid && !o.Config.ShardDuration.Valid {
+		return query
+	}
+	query += " WITH DURATION " + influxQLDuration(o.Config.RetentionDuration)
+	if o.Config.ShardDuration.Valid {
+		query += " SHARD DURATION " + influxQLDuration(o.Config.ShardDuration)
+	}
+	if o.Config.Retention.String != "" {
+		query += " NAME " + strconv.Quote(o.Config.Retention.String)
+	}
+	return query
+}
+
+// influxQLDuration returns the duration in seconds, since InfluxQL doesn't support the format of
+// the Go durations, or INF for 0.
+func influxQLDuration(d types.NullDuration) string {
+	if d.Duration <= 0 {
+		return "INF"
+	}
+	return strconv.FormatInt(int64(time.Duration(d.Duration)/time.Second), 10) + "s"
+}
+
+// createDatabase tries to create the database if it doesn't exist. Failure to do so is USUALLY
+// harmless; it usually means we're either a non-admin user to an existing DB or connecting over
+// UDP. It's a warning if the user isn't allowed to do it, or if the retention was configured.
+func (o *Output) createDatabase() {
+	res, err := o.Client.Query(client.NewQuery(o.createDatabaseQuery(), "", ""))
+	if err == nil && res != nil {
+		err = res.Error()
+	}
+	if err == nil {
+		return
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "authoriz") || strings.Contains(msg, "privilege"):
+		o.logger.WithError(err).Warnf("InfluxDB: The user isn't allowed to create the database '%s', "+
+			"create it beforehand or disable its creation with the createDatabase option", o.BatchConf.Database)
+	case o.Config.RetentionDuration.Valid || o.Config.ShardDuration.Valid:
+		o.logger.WithError(err).Warnf("InfluxDB: Couldn't create the database '%s' with its retention policy",
+			o.BatchConf.Database)
+	default:
+		o.logger.WithError(err).Debug("InfluxDB: Couldn't create database; most likely harmless")
+	}
+}
+
 // Description returns a human-readable description of the output.
 func (o *Output) Description() string {
 	return fmt.Sprintf("InfluxDBv1 (%s)", o.Config.Addr.String)
@@ -174,14 +361,17 @@ func (o *Output) Description() string {
 // metric flushing. If gzip encoding is specified, it also handles that.
 func (o *Output) Start() error {
 	o.logger.Debug("Starting...")
-	// Try to create the database if it doesn't exist. Failure to do so is USUALLY harmless; it
-	// usually means we're either a non-admin user to an existing DB or connecting over UDP.
-	_, err := o.Client.Query(client.NewQuery("CREATE DATABASE "+o.BatchConf.Database, "", ""))
-	if err != nil {
-		o.logger.WithError(err).Debug("InfluxDB: Couldn't create database; most likely harmless")
+	if o.Config.CreateDatabase.Bool {
+		o.createDatabase()
+	}
+
+	if o.Config.NonBlocking.Bool {
+		o.writeAPI = newWriteAPI(o.Client, o.BatchConf, int(o.Config.BatchSize.Int64),
+			time.Duration(o.Config.FlushInterval.Duration), o.onWriteError)
 	}
 
-	pf, err := output.NewPeriodicFlusher(time.Duration(o.Config.PushInterval.Duration), o.flushMetrics)
+	pf, err := output.NewDrainingPeriodicFlusher(
+		time.Duration(o.Config.PushInterval.Duration), o.flushMetrics, o.HasSpilledSamples)
 	if err != nil {
 		return err //nolint:wrapcheck
 	}
@@ -196,11 +386,35 @@ func (o *Output) Stop() error {
 	o.logger.Debug("Stopping...")
 	defer o.logger.Debug("Stopped!")
 	o.periodicFlusher.Stop()
+	if o.writeAPI != nil {
+		o.writeAPI.Close()
+	}
 	return nil
 }
 
+// BufferStats returns the stats of the SampleBuffer, with the points that the non-blocking
+// writes dropped.
+func (o *Output) BufferStats() (output.SampleBufferStats, bool) {
+	bufferStats, ok := o.SampleBuffer.BufferStats()
+	if o.writeAPI == nil {
+		return bufferStats, ok
+	}
+	bufferStats.Dropped += o.writeAPI.Dropped()
+	return bufferStats, true
+}
+
+// onWriteError is the error callback of the non-blocking writes, it's called once for each batch
+// that couldn't be written.
+func (o *Output) onWriteError(err error, points int) {
+	o.logger.WithError(err).WithField("points", points).Error("Couldn't write stats, the points were dropped")
+}
+
 func (o *Output) flushMetrics() {
 	samples := o.GetBufferedSamples()
+	if o.writeAPI != nil {
+		o.queueMetrics(samples)
+		return
+	}
 
 	o.semaphoreCh <- struct{}{}
 	defer func() {
@@ -223,3 +437,26 @@ func (o *Output) flushMetrics() {
 	t := time.Since(startTime)
 	o.logger.WithField("t", t).Debug("Batch written!")
 }
+
+// queueMetrics queues the points of the samples in the non-blocking writeAPI.
+func (o *Output) queueMetrics(samples []stats.SampleContainer) {
+	if len(samples) == 0 {
+		return
+	}
+	batch, err := o.batchFromSamples(samples)
+	if err != nil {
+		o.logger.WithError(err).Error("Couldn't create batch from samples")
+		return
+	}
+	dropped := 0
+	for _, p := range batch.Points() {
+		if !o.writeAPI.WritePoint(p) {
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		o.logger.WithField("points", dropped).Error("Couldn't queue stats, the queue of the writes is full " +
+			"and the points were dropped")
+	}
+	o.logger.WithField("points", len(batch.Points())-dropped).Debug("Queued!")
+}
@@ -26,11 +26,18 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	client "github.com/influxdata/influxdb1-client/v2"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
 
+	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/testutils"
 	"go.k6.io/k6/output"
 	"go.k6.io/k6/stats"
@@ -140,6 +147,112 @@ func TestOutput(t *testing.T) {
 	})
 }
 
+func TestOutputRunMetadata(t *testing.T) {
+	t.Parallel()
+
+	lines := make(chan string, 10)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &http.Server{
+		Handler: http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/write" {
+				b, _ := io.ReadAll(r.Body)
+				lines <- string(b)
+			}
+			rw.WriteHeader(204)
+		}),
+		MaxHeaderBytes: 4096,
+	}
+	defer func() {
+		_ = s.Shutdown(context.Background())
+	}()
+	go func() {
+		_ = s.Serve(l)
+	}()
+
+	startTime := time.Unix(1614173820, 0)
+	md := &lib.TestRunMetadata{
+		RunID:         "some-id",
+		ScriptName:    "file:///script.js",
+		ScriptHash:    "abc",
+		K6Version:     "0.33.0",
+		OptionsDigest: "def",
+		StartTime:     &startTime,
+		Labels:        map[string]string{"instance": "eu-1"},
+	}
+	o, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		ConfigArgument: "http://" + l.Addr().String(),
+		RunMetadata:    md,
+	})
+	require.NoError(t, err)
+	require.NoError(t, o.Start())
+	o.SetRunStart(md)
+	require.NoError(t, o.Stop())
+
+	select {
+	case line := <-lines:
+		require.Equal(t, `k6_test_run,instance=eu-1,k6_version=0.33.0,run_id=some-id,script_name=file:///script.js `+
+			`options_digest="def",script_hash="abc" 1614173820000000000`+"\n", line)
+	default:
+		t.Fatal("the test run metadata wasn't written")
+	}
+}
+
+func TestOutputRunManifest(t *testing.T) {
+	t.Parallel()
+
+	lines := make(chan string, 10)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &http.Server{
+		Handler: http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/write" {
+				b, _ := io.ReadAll(r.Body)
+				lines <- string(b)
+			}
+			rw.WriteHeader(204)
+		}),
+		MaxHeaderBytes: 4096,
+	}
+	defer func() {
+		_ = s.Shutdown(context.Background())
+	}()
+	go func() {
+		_ = s.Serve(l)
+	}()
+
+	o, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		ConfigArgument: "http://" + l.Addr().String(),
+	})
+	require.NoError(t, err)
+	startTime := time.Unix(1614173820, 0)
+	o.SetRunManifest(&lib.TestRunManifest{
+		TestRunMetadata: &lib.TestRunMetadata{
+			RunID:      "some-id",
+			ScriptName: "file:///script.js",
+			ScriptHash: "abc",
+			K6Version:  "0.33.0",
+			StartTime:  &startTime,
+		},
+		Options:  lib.Options{VUs: null.IntFrom(10)},
+		EndTime:  time.Unix(1614173880, 0),
+		ExitCode: 99,
+		Error:    "some thresholds have failed",
+	})
+
+	select {
+	case line := <-lines:
+		require.True(t, strings.HasPrefix(line, `k6_test_run_manifest,k6_version=0.33.0,run_id=some-id,`+
+			`script_name=file:///script.js duration=60,error="some thresholds have failed",exit_code=99i,`), line)
+		assert.Contains(t, line, `\"vus\":10`)
+		assert.True(t, strings.HasSuffix(line, ` 1614173880000000000`+"\n"), line)
+	default:
+		t.Fatal("the test run manifest wasn't written")
+	}
+}
+
 func TestExtractTagsToValues(t *testing.T) {
 	t.Parallel()
 	o, err := newOutput(output.Params{
@@ -162,3 +275,143 @@ func TestExtractTagsToValues(t *testing.T) {
 	require.Equal(t, 3.14, values["floatField"])
 	require.Equal(t, int64(12345), values["intField"])
 }
+
+func TestOutputNonBlocking(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var requests, points int
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/write" {
+			rw.WriteHeader(204)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		requests++
+		points += bytes.Count(b, []byte("\n"))
+		rw.WriteHeader(204)
+	}))
+	defer srv.Close()
+
+	o, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		ConfigArgument: srv.URL + "?nonBlocking=true&batchSize=4&flushInterval=1h&pushInterval=10ms",
+	})
+	require.NoError(t, err)
+	require.NoError(t, o.Start())
+
+	samples := make(stats.Samples, 10)
+	for i := range samples {
+		samples[i] = stats.Sample{Metric: stats.New("testGauge", stats.Gauge), Time: time.Now(), Value: float64(i)}
+	}
+	o.AddMetricSamples([]stats.SampleContainer{samples})
+	require.NoError(t, o.Stop())
+
+	// two full batches, and the rest when it's stopped
+	mu.Lock()
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, 10, points)
+	fail = true
+	mu.Unlock()
+
+	failing := newWriteAPI(o.Client, o.BatchConf, 4, time.Hour, func(err error, points int) {
+		assert.Error(t, err)
+		assert.Equal(t, 2, points)
+	})
+	p, err := client.NewPoint("testGauge", nil, map[string]interface{}{"value": 1.0}, time.Now())
+	require.NoError(t, err)
+	assert.True(t, failing.WritePoint(p))
+	assert.True(t, failing.WritePoint(p))
+	failing.Close()
+	assert.Equal(t, uint64(2), failing.Dropped())
+
+	full := &writeAPI{points: make(chan *client.Point, 1)}
+	assert.True(t, full.WritePoint(p))
+	assert.False(t, full.WritePoint(p))
+	assert.Equal(t, uint64(1), full.Dropped())
+}
+
+func TestOutputNonBlockingConfig(t *testing.T) {
+	t.Parallel()
+	_, err := New(output.Params{Logger: testutils.NewLogger(t), ConfigArgument: "?nonBlocking=true&batchSize=0"})
+	require.EqualError(t, err, "influxdb's BatchSize must be a positive number")
+}
+
+func TestOutputCreateDatabase(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Query", func(t *testing.T) {
+		t.Parallel()
+		testCases := map[string]string{
+			"":                                    `CREATE DATABASE "k6"`,
+			"?retentionDuration=168h":             `CREATE DATABASE "k6" WITH DURATION 604800s`,
+			"?shardDuration=1h":                   `CREATE DATABASE "k6" WITH DURATION INF SHARD DURATION 3600s`,
+			"?retentionDuration=24h&retention=rp": `CREATE DATABASE "k6" WITH DURATION 86400s NAME "rp"`,
+		}
+		for query, expected := range testCases {
+			o, err := newOutput(output.Params{Logger: testutils.NewLogger(t), ConfigArgument: query})
+			require.NoError(t, err)
+			assert.Equal(t, expected, o.createDatabaseQuery(), query)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Parallel()
+		var queries []string
+		var mu sync.Mutex
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/query" {
+				mu.Lock()
+				queries = append(queries, r.URL.Query().Get("q"))
+				mu.Unlock()
+			}
+			rw.WriteHeader(204)
+		}))
+		defer srv.Close()
+
+		for _, arg := range []string{"?shardDuration=2h", "?createDatabase=false"} {
+			o, err := newOutput(output.Params{Logger: testutils.NewLogger(t), ConfigArgument: srv.URL + arg})
+			require.NoError(t, err)
+			require.NoError(t, o.Start())
+			require.NoError(t, o.Stop())
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []string{`CREATE DATABASE "k6" WITH DURATION INF SHARD DURATION 7200s`}, queries)
+	})
+}
+
+func TestOutputPrecision(t *testing.T) {
+	t.Parallel()
+	sampleTime := time.Unix(1614173820, 123600700)
+	samples := []stats.SampleContainer{stats.Sample{
+		Metric: stats.New("testGauge", stats.Gauge), Time: sampleTime, Value: 1,
+	}}
+	testCases := map[string]string{
+		"?precision=ms":                      "testGauge value=1 1614173820123",
+		"?precision=ms&roundTimestamps=true": "testGauge value=1 1614173820124",
+		"?precision=s&roundTimestamps=true":  "testGauge value=1 1614173820",
+		"?roundTimestamps=true":              "testGauge value=1 1614173820123600700",
+		"?precision=u":                       "testGauge value=1 1614173820123600000",
+		"?precision=us":                      "testGauge value=1 1614173820123600000",
+		"?precision=µs&roundTimestamps=true": "testGauge value=1 1614173820123601000",
+	}
+	for arg, expected := range testCases {
+		o, err := newOutput(output.Params{Logger: testutils.NewLogger(t), ConfigArgument: arg})
+		require.NoError(t, err)
+		batch, err := o.batchFromSamples(samples)
+		require.NoError(t, err)
+		require.Len(t, batch.Points(), 1)
+		assert.Equal(t, expected, batch.Points()[0].PrecisionString(batch.Precision()), arg)
+	}
+
+	_, err := newOutput(output.Params{Logger: testutils.NewLogger(t), ConfigArgument: "?precision=d"})
+	require.EqualError(t, err, "invalid InfluxDB precision 'd', it has to be ns, u, us, µs, ms, s, m or h")
+}
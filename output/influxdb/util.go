@@ -23,6 +23,7 @@ package influxdb
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	client "github.com/influxdata/influxdb1-client/v2"
 	"gopkg.in/guregu/null.v3"
@@ -51,14 +52,45 @@ func MakeBatchConfig(conf Config) client.BatchPointsConfig {
 	if !conf.DB.Valid || conf.DB.String == "" {
 		conf.DB = null.StringFrom("k6")
 	}
+	precision := conf.Precision.String
+	if isMicroseconds(precision) {
+		// the client doesn't write microseconds correctly, so their points are written as
+		// nanoseconds, which the output truncates to microseconds
+		precision = "ns"
+	}
 	return client.BatchPointsConfig{
-		Precision:        conf.Precision.String,
+		Precision:        precision,
 		Database:         conf.DB.String,
 		RetentionPolicy:  conf.Retention.String,
 		WriteConsistency: conf.Consistency.String,
 	}
 }
 
+// precisionDuration returns the duration of a write precision, the default precision is ns.
+func precisionDuration(precision string) (time.Duration, error) {
+	switch precision {
+	case "", "ns":
+		return time.Nanosecond, nil
+	case "u", "us", "µs":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	default:
+		return 0, fmt.Errorf("invalid InfluxDB precision '%s', it has to be ns, u, us, µs, ms, s, m or h", precision)
+	}
+}
+
+// isMicroseconds returns whether the precision is one of the names of microseconds.
+func isMicroseconds(precision string) bool {
+	return precision == "u" || precision == "us" || precision == "µs"
+}
+
 func checkDuplicatedTypeDefinitions(fieldKinds map[string]FieldKind, tag string) error {
 	if _, found := fieldKinds[tag]; found {
 		return fmt.Errorf("a tag name (%s) shows up more than once in InfluxDB field type configurations", tag)
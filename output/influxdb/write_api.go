@@ -0,0 +1,135 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package influxdb
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	client "github.com/influxdata/influxdb1-client/v2"
+)
+
+// writeAPIQueuedBatches is how many batches of points the writeAPI queues, before it drops the
+// new points.
+const writeAPIQueuedBatches = 10
+
+// writeAPI writes points in batches in the background, like the non-blocking WriteAPI of the
+// InfluxDB v2 client. WritePoint() never blocks, the points are written when there is a full
+// batch of them or on each flush interval. The points that couldn't be queued are dropped and
+// reported by WritePoint(), the batches that couldn't be written are dropped and reported to the
+// error callback.
+type writeAPI struct {
+	client        client.Client
+	batchConf     client.BatchPointsConfig
+	batchSize     int
+	flushInterval time.Duration
+	onError       func(err error, points int)
+
+	points    chan *client.Point
+	done      chan struct{}
+	closeOnce sync.Once
+	dropped   uint64
+}
+
+func newWriteAPI(
+	cl client.Client, batchConf client.BatchPointsConfig, batchSize int, flushInterval time.Duration,
+	onError func(err error, points int),
+) *writeAPI {
+	w := &writeAPI{
+		client:        cl,
+		batchConf:     batchConf,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		onError:       onError,
+		points:        make(chan *client.Point, batchSize*writeAPIQueuedBatches),
+		done:          make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// WritePoint queues the point, or drops it if the queue is full and returns false.
+func (w *writeAPI) WritePoint(p *client.Point) bool {
+	select {
+	case w.points <- p:
+		return true
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+		return false
+	}
+}
+
+// Dropped returns how many points were dropped.
+func (w *writeAPI) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Close writes the queued points and waits for the writing to finish.
+func (w *writeAPI) Close() {
+	w.closeOnce.Do(func() {
+		close(w.points)
+	})
+	<-w.done
+}
+
+func (w *writeAPI) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*client.Point, 0, w.batchSize)
+	write := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := w.write(batch); err != nil {
+			atomic.AddUint64(&w.dropped, uint64(len(batch)))
+			w.onError(err, len(batch))
+		}
+		batch = make([]*client.Point, 0, w.batchSize)
+	}
+	for {
+		select {
+		case p, ok := <-w.points:
+			if !ok {
+				write()
+				return
+			}
+			batch = append(batch, p)
+			if len(batch) >= w.batchSize {
+				write()
+			}
+		case <-ticker.C:
+			write()
+		}
+	}
+}
+
+func (w *writeAPI) write(points []*client.Point) error {
+	batch, err := client.NewBatchPoints(w.batchConf)
+	if err != nil {
+		return fmt.Errorf("couldn't make a batch: %w", err)
+	}
+	batch.AddPoints(points)
+	return w.client.Write(batch)
+}
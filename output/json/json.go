@@ -25,10 +25,12 @@ import (
 	stdlibjson "encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"go.k6.io/k6/lib"
 	"go.k6.io/k6/output"
 	"go.k6.io/k6/stats"
 )
@@ -45,10 +47,12 @@ type Output struct {
 
 	logger      logrus.FieldLogger
 	filename    string
+	encoderLock sync.Mutex
 	encoder     *stdlibjson.Encoder
 	closeFn     func() error
 	seenMetrics map[string]struct{}
 	thresholds  map[string][]*stats.Threshold
+	runManifest *lib.TestRunManifest
 }
 
 // New returns a new JSON output.
@@ -104,7 +108,7 @@ func (o *Output) Start() error {
 
 	o.encoder.SetEscapeHTML(false)
 
-	pf, err := output.NewPeriodicFlusher(flushPeriod, o.flushMetrics)
+	pf, err := output.NewDrainingPeriodicFlusher(flushPeriod, o.flushMetrics, o.HasSpilledSamples)
 	if err != nil {
 		return err
 	}
@@ -119,9 +123,30 @@ func (o *Output) Stop() error {
 	o.logger.Debug("Stopping...")
 	defer o.logger.Debug("Stopped!")
 	o.periodicFlusher.Stop()
+	if o.runManifest != nil {
+		if err := o.encoder.Encode(wrapRunManifest(o.runManifest)); err != nil {
+			o.logger.WithError(err).Error("Test run manifest couldn't be marshalled to JSON")
+		}
+	}
 	return o.closeFn()
 }
 
+// SetRunStart writes the metadata of the test run, with its start time, before the samples of the
+// test run.
+func (o *Output) SetRunStart(md *lib.TestRunMetadata) {
+	o.encoderLock.Lock()
+	defer o.encoderLock.Unlock()
+	if err := o.encoder.Encode(wrapMetadata(md)); err != nil {
+		o.logger.WithError(err).Error("Test run metadata couldn't be marshalled to JSON")
+	}
+}
+
+// SetRunManifest receives the manifest of the test run, which is written as the last line when
+// the output is stopped.
+func (o *Output) SetRunManifest(manifest *lib.TestRunManifest) {
+	o.runManifest = manifest
+}
+
 // SetThresholds receives the thresholds before the output is Start()-ed.
 func (o *Output) SetThresholds(thresholds map[string]stats.Thresholds) {
 	ths := make(map[string][]*stats.Threshold)
@@ -135,6 +160,8 @@ func (o *Output) flushMetrics() {
 	samples := o.GetBufferedSamples()
 	start := time.Now()
 	var count int
+	o.encoderLock.Lock()
+	defer o.encoderLock.Unlock()
 	for _, sc := range samples {
 		samples := sc.GetSamples()
 		count += len(samples)
@@ -25,6 +25,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -32,6 +33,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"go.k6.io/k6/lib"
 	"go.k6.io/k6/lib/testutils"
 	"go.k6.io/k6/output"
 	"go.k6.io/k6/stats"
@@ -69,7 +71,10 @@ func generateTestMetricSamples(t *testing.T) ([]stats.SampleContainer, func(io.R
 			{Time: time2, Metric: metric2, Value: float64(3), Tags: connTags},
 			{Time: time2, Metric: metric1, Value: float64(4), Tags: connTags},
 		}, Time: time2, Tags: connTags},
-		stats.Sample{Time: time3, Metric: metric2, Value: float64(5), Tags: stats.NewSampleTags(map[string]string{"tag3": "val3"})},
+		stats.Sample{
+			Time: time3, Metric: metric2, Value: float64(5), Tags: stats.NewSampleTags(map[string]string{"tag3": "val3"}),
+			Metadata: map[string]string{"trace_id": "abc"},
+		},
 	}
 	expected := []string{
 		`{"type":"Metric","data":{"name":"my_metric1","type":"gauge","contains":"default","tainted":null,"thresholds":["rate<0.01","p(99)<250"],"submetrics":null,"sub":{"name":"","parent":"","suffix":"","tags":null}},"metric":"my_metric1"}`,
@@ -78,7 +83,7 @@ func generateTestMetricSamples(t *testing.T) ([]stats.SampleContainer, func(io.R
 		`{"type":"Metric","data":{"name":"my_metric2","type":"counter","contains":"data","tainted":null,"thresholds":[],"submetrics":null,"sub":{"name":"","parent":"","suffix":"","tags":null}},"metric":"my_metric2"}`,
 		`{"type":"Point","data":{"time":"2021-02-24T13:37:20Z","value":3,"tags":{"key":"val"}},"metric":"my_metric2"}`,
 		`{"type":"Point","data":{"time":"2021-02-24T13:37:20Z","value":4,"tags":{"key":"val"}},"metric":"my_metric1"}`,
-		`{"type":"Point","data":{"time":"2021-02-24T13:37:30Z","value":5,"tags":{"tag3":"val3"}},"metric":"my_metric2"}`,
+		`{"type":"Point","data":{"time":"2021-02-24T13:37:30Z","value":5,"tags":{"tag3":"val3"},"metadata":{"trace_id":"abc"}},"metric":"my_metric2"}`,
 	}
 
 	return samples, getValidator(t, expected)
@@ -104,6 +109,69 @@ func TestJsonOutputStdout(t *testing.T) {
 	validateResults(stdout)
 }
 
+func TestJsonOutputRunMetadata(t *testing.T) {
+	t.Parallel()
+
+	stdout := new(bytes.Buffer)
+	md := &lib.TestRunMetadata{
+		RunID:         "4b0c4ca3-0b57-4a7e-6e0c-8b4a3e4e1d6c",
+		ScriptName:    "file:///script.js",
+		ScriptHash:    "abc",
+		K6Version:     "0.33.0",
+		OptionsDigest: "def",
+		Labels:        map[string]string{"instance": "eu-1"},
+	}
+	out, err := New(output.Params{
+		Logger:      testutils.NewLogger(t),
+		StdOut:      stdout,
+		RunMetadata: md,
+	})
+	require.NoError(t, err)
+
+	setThresholds(t, out)
+	require.NoError(t, out.Start())
+	startTime := time.Date(2021, time.February, 24, 13, 37, 0, 0, time.UTC)
+	md.StartTime = &startTime
+	out.(output.WithRunStart).SetRunStart(md)
+
+	samples, _ := generateTestMetricSamples(t)
+	out.AddMetricSamples(samples)
+	require.NoError(t, out.Stop())
+
+	firstLine, err := stdout.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, `{"type":"Metadata","data":{"run_id":"4b0c4ca3-0b57-4a7e-6e0c-8b4a3e4e1d6c",`+
+		`"script_name":"file:///script.js","script_hash":"abc","k6_version":"0.33.0","options_digest":"def",`+
+		`"start_time":"2021-02-24T13:37:00Z","labels":{"instance":"eu-1"}}}`+"\n", firstLine)
+}
+
+func TestJsonOutputRunManifest(t *testing.T) {
+	t.Parallel()
+
+	stdout := new(bytes.Buffer)
+	out, err := New(output.Params{
+		Logger: testutils.NewLogger(t),
+		StdOut: stdout,
+	})
+	require.NoError(t, err)
+
+	setThresholds(t, out)
+	require.NoError(t, out.Start())
+	samples, _ := generateTestMetricSamples(t)
+	out.AddMetricSamples(samples)
+	out.(output.WithRunManifest).SetRunManifest(&lib.TestRunManifest{
+		TestRunMetadata: &lib.TestRunMetadata{RunID: "some-id"},
+		EndTime:         time.Unix(1614173880, 0).UTC(),
+		ExitCode:        99,
+	})
+	require.NoError(t, out.Stop())
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.True(t, len(lines) > 1)
+	assert.True(t, strings.HasPrefix(lines[len(lines)-1], `{"type":"RunManifest","data":{"run_id":"some-id",`))
+	assert.Contains(t, lines[len(lines)-1], `"end_time":"2021-02-24T13:38:00Z","exit_code":99}}`)
+}
+
 func TestJsonOutputFileError(t *testing.T) {
 	t.Parallel()
 
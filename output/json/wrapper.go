@@ -23,6 +23,7 @@ package json
 import (
 	"time"
 
+	"go.k6.io/k6/lib"
 	"go.k6.io/k6/stats"
 )
 
@@ -36,16 +37,18 @@ type Envelope struct {
 
 // Sample is the data format for metric sample data in the JSON file.
 type Sample struct {
-	Time  time.Time         `json:"time"`
-	Value float64           `json:"value"`
-	Tags  *stats.SampleTags `json:"tags"`
+	Time     time.Time         `json:"time"`
+	Value    float64           `json:"value"`
+	Tags     *stats.SampleTags `json:"tags"`
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 func newJSONSample(sample stats.Sample) Sample {
 	return Sample{
-		Time:  sample.Time,
-		Value: sample.Value,
-		Tags:  sample.Tags,
+		Time:     sample.Time,
+		Value:    sample.Value,
+		Tags:     sample.Tags,
+		Metadata: sample.Metadata,
 	}
 }
 
@@ -59,6 +62,20 @@ func WrapSample(sample stats.Sample) Envelope {
 	}
 }
 
+func wrapMetadata(md *lib.TestRunMetadata) *Envelope {
+	return &Envelope{
+		Type: "Metadata",
+		Data: md,
+	}
+}
+
+func wrapRunManifest(manifest *lib.TestRunManifest) *Envelope {
+	return &Envelope{
+		Type: "RunManifest",
+		Data: manifest,
+	}
+}
+
 func wrapMetric(metric *stats.Metric) *Envelope {
 	if metric == nil {
 		return nil
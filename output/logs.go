@@ -0,0 +1,116 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package output
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogCollector is a logrus hook that collects the logs of k6 for an output that sends them with
+// its flushes. The logs of the output itself, with its name in their output field, aren't
+// collected, so that the errors of the sending don't cause more logs to send.
+type LogCollector struct {
+	name     string
+	levels   []logrus.Level
+	limit    int
+	hookable *logrus.Logger
+
+	mu      sync.Mutex
+	active  bool
+	entries []*logrus.Entry
+	dropped int
+}
+
+// NewLogCollector returns a collector of the logs of the logger with the given levels for the
+// output with the given name. When limit logs are waiting to be taken, the next ones are dropped.
+func NewLogCollector(logger logrus.FieldLogger, name string, levels []logrus.Level, limit int) *LogCollector {
+	c := &LogCollector{name: name, levels: levels, limit: limit}
+	switch l := logger.(type) {
+	case *logrus.Logger:
+		c.hookable = l
+	case *logrus.Entry:
+		c.hookable = l.Logger
+	}
+	return c
+}
+
+// Start adds the collector as a hook of the logger, it returns false if the logger doesn't
+// support hooks.
+func (c *LogCollector) Start() bool {
+	if c.hookable == nil {
+		return false
+	}
+	c.mu.Lock()
+	c.active = true
+	c.mu.Unlock()
+	c.hookable.AddHook(c)
+	return true
+}
+
+// Stop stops the collecting, since the hooks can't be removed from the logger.
+func (c *LogCollector) Stop() {
+	c.mu.Lock()
+	c.active = false
+	c.mu.Unlock()
+}
+
+// Take returns the collected logs, and how many logs were dropped since the last call.
+func (c *LogCollector) Take() ([]*logrus.Entry, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, dropped := c.entries, c.dropped
+	c.entries, c.dropped = nil, 0
+	return entries, dropped
+}
+
+// Levels implements logrus.Hook.
+func (c *LogCollector) Levels() []logrus.Level {
+	return c.levels
+}
+
+// Fire implements logrus.Hook, it keeps a copy of the entry, since the logger can reuse it.
+func (c *LogCollector) Fire(entry *logrus.Entry) error {
+	if entry.Data["output"] == c.name {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active {
+		return nil
+	}
+	if len(c.entries) >= c.limit {
+		c.dropped++
+		return nil
+	}
+	data := make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	c.entries = append(c.entries, &logrus.Entry{
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+		Data:    data,
+	})
+	return nil
+}
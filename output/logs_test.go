@@ -0,0 +1,59 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package output
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogCollector(t *testing.T) {
+	t.Parallel()
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	c := NewLogCollector(logger.WithField("some", "field"), "test", []logrus.Level{logrus.ErrorLevel}, 2)
+	logger.Error("before the start")
+	require.True(t, c.Start())
+
+	logger.WithField("output", "test").Error("the output's own error")
+	logger.Warn("not collected")
+	logger.WithField("url", "http://example.com").Error("first")
+	logger.Error("second")
+	logger.Error("third")
+	entries, dropped := c.Take()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "first", entries[0].Message)
+	assert.Equal(t, logrus.Fields{"url": "http://example.com"}, entries[0].Data)
+	assert.Equal(t, "second", entries[1].Message)
+	assert.Equal(t, 1, dropped)
+
+	c.Stop()
+	logger.Error("after the stop")
+	entries, dropped = c.Take()
+	assert.Empty(t, entries)
+	assert.Zero(t, dropped)
+
+	assert.False(t, NewLogCollector(logrus.FieldLogger(nil), "test", logrus.AllLevels, 1).Start())
+}
@@ -0,0 +1,234 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// The types of the MQTT 3.1.1 control packets that the client uses, see
+// http://docs.oasis-open.org/mqtt/mqtt/v3.1.1/mqtt-v3.1.1.html
+const (
+	packetConnect    = 1
+	packetConnack    = 2
+	packetPublish    = 3
+	packetPuback     = 4
+	packetPingreq    = 12
+	packetDisconnect = 14
+)
+
+// The reasons of the refused connections, by the return codes of the CONNACK packets.
+var connackErrors = map[byte]string{ //nolint:gochecknoglobals
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// client is a minimal MQTT 3.1.1 client that only publishes messages with the QoS 0 or 1, so
+// the output doesn't need a whole MQTT library. It's only used by the flushing goroutine.
+type client struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	timeout  time.Duration
+	packetID uint16
+}
+
+// dial connects to the broker and sends the CONNECT packet of a clean session.
+func dial(conf Config) (*client, error) {
+	addr, secure, err := conf.brokerAddress()
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(conf.Timeout.Duration)
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if secure {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: conf.Insecure.Bool, //nolint:gosec
+		})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &client{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}
+	if err := c.connect(conf); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
+
+// writePacket writes a packet with its fixed header, the remaining length is a variable length
+// integer of 7 bits per byte.
+func (c *client) writePacket(header byte, body []byte) error {
+	packet := []byte{header}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	packet = append(packet, body...)
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+// readPacket reads the next packet and returns its type and body.
+func (c *client) readPacket() (byte, []byte, error) {
+	if err := c.conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, nil, err
+	}
+	header, err := c.reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		digit, err := c.reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, errors.New("invalid MQTT packet length")
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header >> 4, body, nil
+}
+
+func (c *client) connect(conf Config) error {
+	var flags byte = 0x02 // clean session
+	if conf.Username.String != "" {
+		flags |= 0x80
+	}
+	if conf.Password.String != "" {
+		flags |= 0x40
+	}
+	keepAlive := uint16(time.Duration(conf.KeepAlive.Duration) / time.Second)
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags, byte(keepAlive>>8), byte(keepAlive))
+	body = appendString(body, conf.ClientID.String)
+	if conf.Username.String != "" {
+		body = appendString(body, conf.Username.String)
+	}
+	if conf.Password.String != "" {
+		body = appendString(body, conf.Password.String)
+	}
+	if err := c.writePacket(packetConnect<<4, body); err != nil {
+		return err
+	}
+
+	packetType, resp, err := c.readPacket()
+	if err != nil {
+		return fmt.Errorf("couldn't read the MQTT CONNACK: %w", err)
+	}
+	if packetType != packetConnack || len(resp) != 2 {
+		return fmt.Errorf("unexpected MQTT packet of type %d instead of the CONNACK", packetType)
+	}
+	if resp[1] != 0 {
+		reason, ok := connackErrors[resp[1]]
+		if !ok {
+			reason = fmt.Sprintf("return code %d", resp[1])
+		}
+		return fmt.Errorf("the MQTT broker refused the connection: %s", reason)
+	}
+	return nil
+}
+
+// publish publishes a message, with the QoS 1 it waits for the PUBACK of the broker.
+func (c *client) publish(topic string, payload []byte, qos byte, retain bool) error {
+	header := byte(packetPublish<<4) | qos<<1
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(nil, topic)
+	if qos > 0 {
+		c.packetID++
+		if c.packetID == 0 {
+			c.packetID++ // the packet identifier 0 is invalid
+		}
+		body = append(body, byte(c.packetID>>8), byte(c.packetID))
+	}
+	body = append(body, payload...)
+	if err := c.writePacket(header, body); err != nil {
+		return err
+	}
+	if qos == 0 {
+		return nil
+	}
+
+	for {
+		packetType, resp, err := c.readPacket()
+		if err != nil {
+			return fmt.Errorf("couldn't read the MQTT PUBACK: %w", err)
+		}
+		// the other packets are the responses of the pings
+		if packetType == packetPuback && len(resp) == 2 && binary.BigEndian.Uint16(resp) == c.packetID {
+			return nil
+		}
+	}
+}
+
+// ping sends a PINGREQ, so the broker doesn't close the connection when nothing is published
+// during the keep alive. The PINGRESP isn't waited for.
+func (c *client) ping() error {
+	return c.writePacket(packetPingreq<<4, nil)
+}
+
+func (c *client) close() error {
+	_ = c.writePacket(packetDisconnect<<4, nil)
+	return c.conn.Close()
+}
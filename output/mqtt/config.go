@@ -0,0 +1,269 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+// The default ports of the broker URLs without a port, by their schemes.
+var defaultPorts = map[string]string{ //nolint:gochecknoglobals
+	"tcp":   "1883",
+	"mqtt":  "1883",
+	"ssl":   "8883",
+	"tls":   "8883",
+	"mqtts": "8883",
+}
+
+// Config is the config for the mqtt output
+type Config struct {
+	// The broker is like tcp://localhost:1883, or ssl://broker:8883 for TLS.
+	Broker   null.String `json:"broker" envconfig:"K6_MQTT_BROKER"`
+	Insecure null.Bool   `json:"insecure" envconfig:"K6_MQTT_INSECURE"`
+	ClientID null.String `json:"clientId" envconfig:"K6_MQTT_CLIENT_ID"`
+	Username null.String `json:"username" envconfig:"K6_MQTT_USERNAME"`
+	Password null.String `json:"password" envconfig:"K6_MQTT_PASSWORD"`
+
+	// The messages are published with the QoS 0, at most once, or 1, at least once.
+	Topic     null.String        `json:"topic" envconfig:"K6_MQTT_TOPIC"`
+	QoS       null.Int           `json:"qos" envconfig:"K6_MQTT_QOS"`
+	Retain    null.Bool          `json:"retain" envconfig:"K6_MQTT_RETAIN"`
+	KeepAlive types.NullDuration `json:"keepAlive" envconfig:"K6_MQTT_KEEP_ALIVE"`
+
+	FlushInterval types.NullDuration `json:"flushInterval" envconfig:"K6_MQTT_FLUSH_INTERVAL"`
+	Timeout       types.NullDuration `json:"timeout" envconfig:"K6_MQTT_TIMEOUT"`
+	TagBlocklist  stats.TagSet       `json:"tagBlocklist" envconfig:"K6_MQTT_TAG_BLOCKLIST"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	return Config{
+		Broker:        null.StringFrom("tcp://localhost:1883"),
+		Insecure:      null.BoolFrom(false),
+		ClientID:      null.StringFrom(fmt.Sprintf("k6-%s-%d", host, os.Getpid())),
+		Topic:         null.StringFrom("k6/metrics"),
+		QoS:           null.IntFrom(0),
+		Retain:        null.BoolFrom(false),
+		KeepAlive:     types.NullDurationFrom(time.Minute),
+		FlushInterval: types.NullDurationFrom(10 * time.Second),
+		Timeout:       types.NullDurationFrom(10 * time.Second),
+		TagBlocklist:  (stats.TagVU | stats.TagIter | stats.TagURL).Map(),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Broker.Valid {
+		c.Broker = cfg.Broker
+	}
+	if cfg.Insecure.Valid {
+		c.Insecure = cfg.Insecure
+	}
+	if cfg.ClientID.Valid {
+		c.ClientID = cfg.ClientID
+	}
+	if cfg.Username.Valid {
+		c.Username = cfg.Username
+	}
+	if cfg.Password.Valid {
+		c.Password = cfg.Password
+	}
+	if cfg.Topic.Valid {
+		c.Topic = cfg.Topic
+	}
+	if cfg.QoS.Valid {
+		c.QoS = cfg.QoS
+	}
+	if cfg.Retain.Valid {
+		c.Retain = cfg.Retain
+	}
+	if cfg.KeepAlive.Valid {
+		c.KeepAlive = cfg.KeepAlive
+	}
+	if cfg.FlushInterval.Valid {
+		c.FlushInterval = cfg.FlushInterval
+	}
+	if cfg.Timeout.Valid {
+		c.Timeout = cfg.Timeout
+	}
+	if cfg.TagBlocklist != nil {
+		c.TagBlocklist = cfg.TagBlocklist
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config, like in
+// --out mqtt=broker=ssl://broker:8883,topic=site/k6,qos=1, the tagBlocklist key can be repeated.
+// The password of the broker is an error here, it's only read from K6_MQTT_PASSWORD.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	for _, pair := range strings.Split(arg, ",") {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			return c, fmt.Errorf("couldn't parse %q as argument for mqtt output", arg)
+		}
+		switch r[0] {
+		case "broker":
+			c.Broker = null.StringFrom(r[1])
+		case "insecure", "retain":
+			v, err := strconv.ParseBool(r[1])
+			if err != nil {
+				return c, fmt.Errorf("%s value must be true or false, not %q", r[0], r[1])
+			}
+			if r[0] == "insecure" {
+				c.Insecure = null.BoolFrom(v)
+			} else {
+				c.Retain = null.BoolFrom(v)
+			}
+		case "clientId":
+			c.ClientID = null.StringFrom(r[1])
+		case "username":
+			c.Username = null.StringFrom(r[1])
+		case "topic":
+			c.Topic = null.StringFrom(r[1])
+		case "qos":
+			v, err := strconv.ParseInt(r[1], 10, 64)
+			if err != nil {
+				return c, fmt.Errorf("qos value must be a number, not %q", r[1])
+			}
+			c.QoS = null.IntFrom(v)
+		case "keepAlive":
+			if err := c.KeepAlive.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "flushInterval":
+			if err := c.FlushInterval.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "timeout":
+			if err := c.Timeout.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "tagBlocklist":
+			if c.TagBlocklist == nil {
+				c.TagBlocklist = make(stats.TagSet)
+			}
+			c.TagBlocklist[r[1]] = true
+		case "password":
+			return c, fmt.Errorf("the mqtt password can't be in the argument, use K6_MQTT_PASSWORD instead")
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for mqtt output", r[0])
+		}
+	}
+
+	return c, nil
+}
+
+// brokerAddress returns the address of the broker and whether the connection is with TLS.
+func (c Config) brokerAddress() (string, bool, error) {
+	u, err := url.Parse(c.Broker.String)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid mqtt broker %q: %w", c.Broker.String, err)
+	}
+	port, ok := defaultPorts[u.Scheme]
+	if !ok || u.Hostname() == "" {
+		return "", false, fmt.Errorf("invalid mqtt broker %q, it has to be like tcp://host:1883 or ssl://host:8883",
+			c.Broker.String)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	secure := u.Scheme == "ssl" || u.Scheme == "tls" || u.Scheme == "mqtts"
+	return net.JoinHostPort(u.Hostname(), port), secure, nil
+}
+
+// Validate returns an error if any config value is invalid.
+func (c Config) Validate() error {
+	if _, _, err := c.brokerAddress(); err != nil {
+		return err
+	}
+	if c.Topic.String == "" || strings.ContainsAny(c.Topic.String, "+#") {
+		return fmt.Errorf("invalid mqtt topic %q, it can't be empty or have wildcards", c.Topic.String)
+	}
+	if c.QoS.Int64 != 0 && c.QoS.Int64 != 1 {
+		return fmt.Errorf("invalid mqtt qos %d, it has to be 0 or 1", c.QoS.Int64)
+	}
+	if c.Password.String != "" && c.Username.String == "" {
+		return fmt.Errorf("the mqtt password needs the username")
+	}
+	if c.KeepAlive.Duration < 0 || time.Duration(c.KeepAlive.Duration) > 0xffff*time.Second {
+		return fmt.Errorf("the mqtt keepAlive should be between 0 and 18h12m15s, but it's %s", c.KeepAlive)
+	}
+	if c.KeepAlive.Duration > 0 && c.KeepAlive.Duration < c.FlushInterval.Duration {
+		return fmt.Errorf("the mqtt keepAlive should be 0 or at least the flushInterval %s, but it's %s",
+			c.FlushInterval, c.KeepAlive)
+	}
+	if c.FlushInterval.Duration <= 0 {
+		return fmt.Errorf("the mqtt flushInterval should be positive, but it's %s", c.FlushInterval)
+	}
+	if c.Timeout.Duration <= 0 {
+		return fmt.Errorf("the mqtt timeout should be positive, but it's %s", c.Timeout)
+	}
+	return nil
+}
+
+// GetConsolidatedConfig combines {default config values + JSON config +
+// environment vars + arg config values}, and returns the final result.
+func GetConsolidatedConfig(jsonRawConf json.RawMessage, env map[string]string, arg string) (Config, error) {
+	result := NewConfig()
+	if jsonRawConf != nil {
+		jsonConf := Config{}
+		if err := json.Unmarshal(jsonRawConf, &jsonConf); err != nil {
+			return result, err
+		}
+		result = result.Apply(jsonConf)
+	}
+
+	envConfig := Config{}
+	if err := envconfig.Process("", &envConfig); err != nil {
+		// TODO: get rid of envconfig and actually use the env parameter...
+		return result, err
+	}
+	result = result.Apply(envConfig)
+
+	if arg != "" {
+		argConf, err := ParseArg(arg)
+		if err != nil {
+			return result, err
+		}
+		result = result.Apply(argConf)
+	}
+
+	return result, result.Validate()
+}
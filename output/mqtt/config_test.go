@@ -0,0 +1,111 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package mqtt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+func TestParseArg(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		config      Config
+		expectedErr bool
+	}{
+		"broker=ssl://broker:8883,topic=site/1/k6,qos=1,retain=true": {
+			config: Config{
+				Broker: null.StringFrom("ssl://broker:8883"),
+				Topic:  null.StringFrom("site/1/k6"),
+				QoS:    null.IntFrom(1),
+				Retain: null.BoolFrom(true),
+			},
+		},
+		"clientId=agent-1,username=k6,insecure=true,keepAlive=0s,tagBlocklist=name,flushInterval=1m": {
+			config: Config{
+				ClientID:      null.StringFrom("agent-1"),
+				Username:      null.StringFrom("k6"),
+				Insecure:      null.BoolFrom(true),
+				KeepAlive:     types.NullDurationFrom(0),
+				TagBlocklist:  stats.TagSet{"name": true},
+				FlushInterval: types.NullDurationFrom(time.Minute),
+			},
+		},
+		"password=secret":       {expectedErr: true},
+		"qos=high":              {expectedErr: true},
+		"retain=maybe":          {expectedErr: true},
+		"tcp://localhost:1883":  {expectedErr: true},
+		"foo=bar":               {expectedErr: true},
+		"flushInterval=forever": {expectedErr: true},
+	}
+
+	for arg, tc := range cases {
+		arg, tc := arg, tc
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+			config, err := ParseArg(arg)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.config, config)
+		})
+	}
+}
+
+func TestGetConsolidatedConfig(t *testing.T) {
+	t.Parallel()
+	config, err := GetConsolidatedConfig([]byte(`{"broker": "mqtts://broker", "username": "k6"}`), nil, "topic=k6/edge")
+	require.NoError(t, err)
+	expected := NewConfig()
+	expected.Broker = null.StringFrom("mqtts://broker")
+	expected.Username = null.StringFrom("k6")
+	expected.Topic = null.StringFrom("k6/edge")
+	assert.Equal(t, expected, config)
+
+	addr, secure, err := config.brokerAddress()
+	require.NoError(t, err)
+	assert.Equal(t, "broker:8883", addr)
+	assert.True(t, secure)
+
+	invalid := map[string]string{
+		`{"broker": "http://broker"}`: "",
+		`{"broker": "tcp://"}`:        "",
+		`{"topic": "k6/#"}`:           "",
+		`{"qos": 2}`:                  "",
+		`{"password": "secret"}`:      "",
+		`{"keepAlive": "10s"}`:        "flushInterval=1m",
+		`{"keepAlive": "100h"}`:       "",
+		`{}`:                          "timeout=0s",
+	}
+	for conf, arg := range invalid {
+		_, err := GetConsolidatedConfig([]byte(conf), nil, arg)
+		assert.Error(t, err, conf)
+	}
+}
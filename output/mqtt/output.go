@@ -0,0 +1,202 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+// Output publishes the metrics to a topic of an MQTT broker, as a JSON message with the metrics
+// of each interval aggregated by their names and tags, with the same values as in the end of
+// test summary.
+type Output struct {
+	output.SampleBuffer
+
+	config          Config
+	logger          logrus.FieldLogger
+	client          *client
+	periodicFlusher *output.PeriodicFlusher
+	intervalStart   time.Time
+	now             func() time.Time
+}
+
+var _ output.Output = new(Output)
+
+// New returns a new mqtt output
+func New(params output.Params) (output.Output, error) {
+	return newOutput(params)
+}
+
+func newOutput(params output.Params) (*Output, error) {
+	conf, err := GetConsolidatedConfig(params.JSONConfig, params.Environment, params.ConfigArgument)
+	if err != nil {
+		return nil, err
+	}
+	return &Output{
+		config: conf,
+		logger: params.Logger.WithFields(logrus.Fields{"output": "mqtt"}),
+		now:    time.Now,
+	}, nil
+}
+
+// Description returns a human-readable description of the output.
+func (o *Output) Description() string {
+	return fmt.Sprintf("mqtt (%s, topic %s)", o.config.Broker.String, o.config.Topic.String)
+}
+
+// Start connects to the broker and starts the goroutine for the flushing of the metrics. The
+// test doesn't fail when the broker is unavailable, since the output connects again on each
+// flush, like when the connection of an edge device is lost.
+func (o *Output) Start() error {
+	o.logger.Debug("Starting...")
+	if err := o.connect(); err != nil {
+		o.logger.WithError(err).Warn("Couldn't connect to the MQTT broker, it will be retried on each flush")
+	}
+	o.intervalStart = o.now()
+	pf, err := output.NewDrainingPeriodicFlusher(
+		time.Duration(o.config.FlushInterval.Duration), o.flushMetrics, o.HasSpilledSamples)
+	if err != nil {
+		return err
+	}
+	o.logger.Debug("Started!")
+	o.periodicFlusher = pf
+	return nil
+}
+
+// Stop flushes any remaining metrics, stops the goroutine and disconnects from the broker.
+func (o *Output) Stop() error {
+	o.logger.Debug("Stopping...")
+	defer o.logger.Debug("Stopped!")
+	o.periodicFlusher.Stop()
+	if o.client != nil {
+		return o.client.close()
+	}
+	return nil
+}
+
+// The JSON messages of the metrics.
+type (
+	message struct {
+		ClientID  string          `json:"clientId"`
+		Timestamp string          `json:"timestamp"`
+		Interval  float64         `json:"interval"`
+		Metrics   []metricMessage `json:"metrics"`
+	}
+	metricMessage struct {
+		Name   string             `json:"name"`
+		Type   string             `json:"type"`
+		Tags   map[string]string  `json:"tags,omitempty"`
+		Values map[string]float64 `json:"values"`
+	}
+)
+
+// aggregate returns the metrics of the samples, aggregated by their names and tags.
+func (o *Output) aggregate(containers []stats.SampleContainer, interval time.Duration) []metricMessage {
+	series := output.AggregateSamples(containers, o.config.TagBlocklist)
+	metrics := make([]metricMessage, 0, len(series))
+	for _, s := range series {
+		values := s.Sink.Format(interval)
+		switch sink := s.Sink.(type) {
+		case *stats.TrendSink:
+			values["count"] = float64(sink.Count)
+		case *stats.RateSink:
+			values["passes"], values["fails"] = float64(sink.Trues), float64(sink.Total-sink.Trues)
+		}
+		metrics = append(metrics, metricMessage{
+			Name:   s.Metric.Name,
+			Type:   s.Metric.Type.String(),
+			Tags:   s.Tags,
+			Values: values,
+		})
+	}
+	return metrics
+}
+
+func (o *Output) flushMetrics() {
+	samples := o.GetBufferedSamples()
+	start, end := o.intervalStart, o.now()
+	o.intervalStart = end
+	if len(samples) == 0 {
+		// the broker closes the idle connections after one and a half keep alive intervals
+		if o.client != nil && o.config.KeepAlive.Duration > 0 {
+			if err := o.client.ping(); err != nil {
+				o.disconnect()
+			}
+		}
+		return
+	}
+
+	interval := end.Sub(start)
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+	payload, err := json.Marshal(message{
+		ClientID:  o.config.ClientID.String,
+		Timestamp: end.UTC().Format(time.RFC3339Nano),
+		Interval:  interval.Seconds(),
+		Metrics:   o.aggregate(samples, interval),
+	})
+	if err != nil {
+		o.logger.WithError(err).Error("Couldn't encode the metrics")
+		return
+	}
+	if err := o.publish(payload); err != nil {
+		o.logger.WithError(err).Errorf("Couldn't publish the metrics to the MQTT topic %s", o.config.Topic.String)
+	}
+}
+
+func (o *Output) connect() error {
+	c, err := dial(o.config)
+	if err != nil {
+		return err
+	}
+	o.client = c
+	return nil
+}
+
+func (o *Output) disconnect() {
+	if o.client != nil {
+		_ = o.client.conn.Close()
+		o.client = nil
+	}
+}
+
+// publish publishes a message, it connects first if it isn't connected, and it closes the
+// connection after any errors, so the next flush connects again.
+func (o *Output) publish(payload []byte) error {
+	if o.client == nil {
+		if err := o.connect(); err != nil {
+			return err
+		}
+	}
+	err := o.client.publish(o.config.Topic.String, payload, byte(o.config.QoS.Int64), o.config.Retain.Bool)
+	if err != nil {
+		o.disconnect()
+	}
+	return err
+}
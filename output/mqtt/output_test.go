@@ -0,0 +1,193 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+type publishedMessage struct {
+	topic   string
+	payload []byte
+}
+
+// testBroker is a broker that accepts the connections of the clients with the password, and
+// acknowledges the messages with the QoS 1. It reuses the packet functions of the client.
+type testBroker struct {
+	t        *testing.T
+	listener net.Listener
+
+	mu       sync.Mutex
+	connects [][]byte
+	messages []publishedMessage
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := &testBroker{t: t, listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	c := &client{conn: conn, reader: bufio.NewReader(conn), timeout: 5 * time.Second}
+	packetType, body, err := c.readPacket()
+	if err != nil || !assert.Equal(b.t, byte(packetConnect), packetType) {
+		return
+	}
+	b.mu.Lock()
+	b.connects = append(b.connects, body)
+	b.mu.Unlock()
+	var code byte
+	if body[7]&0x40 == 0 {
+		code = 4 // the password is required
+	}
+	if c.writePacket(packetConnack<<4, []byte{0, code}) != nil || code != 0 {
+		return
+	}
+
+	for {
+		packetType, body, err := c.readPacket()
+		if err != nil || packetType == packetDisconnect {
+			return
+		}
+		if packetType != packetPublish {
+			continue
+		}
+		// the messages of the test have the QoS 1, so they have a packet identifier
+		topicLength := int(binary.BigEndian.Uint16(body))
+		topic := string(body[2 : 2+topicLength])
+		packetID := body[2+topicLength : 4+topicLength]
+		b.mu.Lock()
+		b.messages = append(b.messages, publishedMessage{topic: topic, payload: body[4+topicLength:]})
+		b.mu.Unlock()
+		if c.writePacket(packetPuback<<4, packetID) != nil {
+			return
+		}
+	}
+}
+
+func TestOutput(t *testing.T) {
+	t.Parallel()
+	broker := newTestBroker(t)
+	defer func() { _ = broker.listener.Close() }()
+
+	o, err := newOutput(output.Params{
+		Logger: testutils.NewLogger(t),
+		JSONConfig: []byte(`{"broker": "tcp://` + broker.listener.Addr().String() + `", ` +
+			`"clientId": "agent-1", "username": "k6", "password": "secret"}`),
+		ConfigArgument: "topic=site/1/k6,qos=1,flushInterval=1h,keepAlive=0s",
+	})
+	require.NoError(t, err)
+	start := time.Date(2021, time.February, 24, 13, 37, 0, 0, time.UTC)
+	o.now = func() time.Time { return start }
+	require.NoError(t, o.Start())
+	assert.Equal(t, "mqtt (tcp://"+broker.listener.Addr().String()+", topic site/1/k6)", o.Description())
+
+	reqs := stats.New("http_reqs", stats.Counter)
+	duration := stats.New("http_req_duration", stats.Trend)
+	checks := stats.New("checks", stats.Rate)
+	tags := stats.NewSampleTags(map[string]string{"method": "GET", "vu": "1"})
+	now := time.Now()
+	o.AddMetricSamples([]stats.SampleContainer{stats.Samples{
+		{Metric: reqs, Value: 1, Time: now, Tags: tags},
+		{Metric: reqs, Value: 1, Time: now, Tags: tags},
+		{Metric: duration, Value: 100, Time: now, Tags: tags},
+		{Metric: duration, Value: 200, Time: now, Tags: tags},
+		{Metric: checks, Value: 1, Time: now},
+		{Metric: checks, Value: 0, Time: now},
+	}})
+	o.now = func() time.Time { return start.Add(10 * time.Second) }
+	require.NoError(t, o.Stop())
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	require.Len(t, broker.connects, 1)
+	connect := broker.connects[0]
+	assert.Equal(t, []byte{0, 4, 'M', 'Q', 'T', 'T', 4, 0xc2, 0, 0}, connect[:10])
+	assert.Equal(t, "\x00\x07agent-1\x00\x02k6\x00\x06secret", string(connect[10:]))
+
+	require.Len(t, broker.messages, 1)
+	assert.Equal(t, "site/1/k6", broker.messages[0].topic)
+	var msg message
+	require.NoError(t, json.Unmarshal(broker.messages[0].payload, &msg))
+	assert.Equal(t, message{
+		ClientID:  "agent-1",
+		Timestamp: "2021-02-24T13:37:10Z",
+		Interval:  10,
+		Metrics: []metricMessage{
+			{Name: "checks", Type: "rate", Values: map[string]float64{"rate": 0.5, "passes": 1, "fails": 1}},
+			{
+				Name: "http_req_duration", Type: "trend", Tags: map[string]string{"method": "GET"},
+				Values: map[string]float64{
+					"min": 100, "max": 200, "avg": 150, "med": 150, "p(90)": 190, "p(95)": 195, "count": 2,
+				},
+			},
+			{
+				Name: "http_reqs", Type: "counter", Tags: map[string]string{"method": "GET"},
+				Values: map[string]float64{"count": 2, "rate": 0.2},
+			},
+		},
+	}, msg)
+}
+
+func TestOutputRefusedConnection(t *testing.T) {
+	t.Parallel()
+	broker := newTestBroker(t)
+	defer func() { _ = broker.listener.Close() }()
+
+	o, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		JSONConfig:     []byte(`{"broker": "tcp://` + broker.listener.Addr().String() + `"}`),
+		ConfigArgument: "flushInterval=1h,keepAlive=0s",
+	})
+	require.NoError(t, err)
+	err = o.connect()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad user name or password")
+
+	// the test doesn't fail when the broker is unavailable
+	require.NoError(t, o.Start())
+	require.NoError(t, o.Stop())
+}
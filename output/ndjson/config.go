@@ -0,0 +1,156 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ndjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/types"
+)
+
+// Config is the config for the ndjson output
+type Config struct {
+	Target         null.String        `json:"target" envconfig:"K6_NDJSON_TARGET"`
+	SampleRate     null.Float         `json:"sampleRate" envconfig:"K6_NDJSON_SAMPLE_RATE"`
+	Metrics        []string           `json:"metrics" envconfig:"K6_NDJSON_METRICS"`
+	ExcludeMetrics []string           `json:"excludeMetrics" envconfig:"K6_NDJSON_EXCLUDE_METRICS"`
+	FlushInterval  types.NullDuration `json:"flushInterval" envconfig:"K6_NDJSON_FLUSH_INTERVAL"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Target:        null.StringFrom("stdout"),
+		SampleRate:    null.FloatFrom(1),
+		FlushInterval: types.NullDurationFrom(200 * time.Millisecond),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Target.Valid {
+		c.Target = cfg.Target
+	}
+	if cfg.SampleRate.Valid {
+		c.SampleRate = cfg.SampleRate
+	}
+	if cfg.Metrics != nil {
+		c.Metrics = cfg.Metrics
+	}
+	if cfg.ExcludeMetrics != nil {
+		c.ExcludeMetrics = cfg.ExcludeMetrics
+	}
+	if cfg.FlushInterval.Valid {
+		c.FlushInterval = cfg.FlushInterval
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config. The first value can be the target
+// without a key, like in --out ndjson=stdout,sampleRate=0.1, and the metrics keys can be repeated.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	for i, pair := range strings.Split(arg, ",") {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			if i == 0 {
+				c.Target = null.StringFrom(pair)
+				continue
+			}
+			return c, fmt.Errorf("couldn't parse %q as argument for ndjson output", arg)
+		}
+		switch r[0] {
+		case "target":
+			c.Target = null.StringFrom(r[1])
+		case "sampleRate":
+			rate, err := strconv.ParseFloat(r[1], 64)
+			if err != nil {
+				return c, fmt.Errorf("invalid sampleRate %q: %w", r[1], err)
+			}
+			c.SampleRate = null.FloatFrom(rate)
+		case "metrics":
+			c.Metrics = append(c.Metrics, r[1])
+		case "excludeMetrics":
+			c.ExcludeMetrics = append(c.ExcludeMetrics, r[1])
+		case "flushInterval":
+			if err := c.FlushInterval.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for ndjson output", r[0])
+		}
+	}
+
+	return c, nil
+}
+
+// Validate returns an error if any config value is invalid.
+func (c Config) Validate() error {
+	if c.SampleRate.Float64 <= 0 || c.SampleRate.Float64 > 1 {
+		return fmt.Errorf("the ndjson sampleRate should be more than 0 and at most 1, but it's %g", c.SampleRate.Float64)
+	}
+	if c.FlushInterval.Duration <= 0 {
+		return fmt.Errorf("the ndjson flushInterval should be positive, but it's %s", c.FlushInterval)
+	}
+	if err := lib.ValidateMetricPatterns(c.Metrics, c.ExcludeMetrics); err != nil {
+		return fmt.Errorf("the ndjson metrics are invalid: %w", err)
+	}
+	return nil
+}
+
+// GetConsolidatedConfig combines {default config values + JSON config +
+// environment vars + arg config values}, and returns the final result.
+func GetConsolidatedConfig(jsonRawConf json.RawMessage, env map[string]string, arg string) (Config, error) {
+	result := NewConfig()
+	if jsonRawConf != nil {
+		jsonConf := Config{}
+		if err := json.Unmarshal(jsonRawConf, &jsonConf); err != nil {
+			return result, err
+		}
+		result = result.Apply(jsonConf)
+	}
+
+	envConfig := Config{}
+	if err := envconfig.Process("", &envConfig); err != nil {
+		// TODO: get rid of envconfig and actually use the env parameter...
+		return result, err
+	}
+	result = result.Apply(envConfig)
+
+	if arg != "" {
+		argConf, err := ParseArg(arg)
+		if err != nil {
+			return result, err
+		}
+		result = result.Apply(argConf)
+	}
+
+	return result, result.Validate()
+}
@@ -0,0 +1,93 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ndjson
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+)
+
+func TestParseArg(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		config      Config
+		expectedErr bool
+	}{
+		"stdout": {
+			config: Config{Target: null.StringFrom("stdout")},
+		},
+		"stderr,sampleRate=0.1,metrics=http_req_*,metrics=checks,excludeMetrics=http_req_blocked": {
+			config: Config{
+				Target:         null.StringFrom("stderr"),
+				SampleRate:     null.FloatFrom(0.1),
+				Metrics:        []string{"http_req_*", "checks"},
+				ExcludeMetrics: []string{"http_req_blocked"},
+			},
+		},
+		"target=samples.ndjson,flushInterval=1s": {
+			config: Config{
+				Target:        null.StringFrom("samples.ndjson"),
+				FlushInterval: types.NullDurationFrom(1 * time.Second),
+			},
+		},
+		"sampleRate=abc":      {expectedErr: true},
+		"stdout,metrics":      {expectedErr: true},
+		"foo=bar":             {expectedErr: true},
+		"flushInterval=never": {expectedErr: true},
+	}
+
+	for arg, tc := range cases {
+		arg, tc := arg, tc
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+			config, err := ParseArg(arg)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.config, config)
+		})
+	}
+}
+
+func TestGetConsolidatedConfig(t *testing.T) {
+	t.Parallel()
+	config, err := GetConsolidatedConfig([]byte(`{"sampleRate": 0.5, "metrics": ["checks"]}`), nil, "stderr")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Target:        null.StringFrom("stderr"),
+		SampleRate:    null.FloatFrom(0.5),
+		Metrics:       []string{"checks"},
+		FlushInterval: types.NullDurationFrom(200 * time.Millisecond),
+	}, config)
+
+	for _, arg := range []string{"sampleRate=0", "sampleRate=1.5", "flushInterval=0s", "metrics=[a"} {
+		_, err := GetConsolidatedConfig(nil, nil, arg)
+		assert.Error(t, err, arg)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package ndjson implements a lightweight output that streams the metric samples as
+// newline-delimited JSON, one compact object per line, for piping them into other processes.
+// Unlike the json output, the samples can be filtered by their metrics and sampled.
+package ndjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+// line is a sample in the stream.
+type line struct {
+	Metric string            `json:"metric"`
+	Type   stats.MetricType  `json:"type"`
+	Time   time.Time         `json:"time"`
+	Value  float64           `json:"value"`
+	Tags   *stats.SampleTags `json:"tags,omitempty"`
+}
+
+// Output writes the metric samples to the standard output, the standard error or a file.
+type Output struct {
+	output.SampleBuffer
+
+	config          Config
+	params          output.Params
+	logger          logrus.FieldLogger
+	periodicFlusher *output.PeriodicFlusher
+
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	closeFn func() error
+	random  func() float64
+	// the metrics and excludeMetrics patterns, matched only in AddMetricSamples()
+	metrics *lib.MetricPatterns
+}
+
+// New creates an instance of the ndjson output
+func New(params output.Params) (output.Output, error) {
+	return newOutput(params)
+}
+
+func newOutput(params output.Params) (*Output, error) {
+	config, err := GetConsolidatedConfig(params.JSONConfig, params.Environment, params.ConfigArgument)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Output{
+		config:  config,
+		params:  params,
+		logger:  params.Logger.WithFields(logrus.Fields{"output": "ndjson", "target": config.Target.String}),
+		random:  rand.New(rand.NewSource(time.Now().UnixNano())).Float64, //nolint:gosec
+		metrics: lib.NewMetricPatterns(config.Metrics, config.ExcludeMetrics),
+	}, nil
+}
+
+// Description returns a human-readable description of the output.
+func (o *Output) Description() string {
+	if o.config.SampleRate.Float64 < 1 {
+		return fmt.Sprintf("ndjson (%s, %g%% sampled)", o.config.Target.String, o.config.SampleRate.Float64*100)
+	}
+	return fmt.Sprintf("ndjson (%s)", o.config.Target.String)
+}
+
+// Start opens the target and starts the goroutine for the flushing of the samples.
+func (o *Output) Start() error {
+	o.logger.Debug("Starting...")
+
+	var w io.Writer
+	switch o.config.Target.String {
+	case "", "-", "stdout":
+		w, o.closeFn = o.params.StdOut, func() error { return nil }
+	case "stderr":
+		w, o.closeFn = o.params.StdErr, func() error { return nil }
+	default:
+		file, err := o.params.FS.Create(o.config.Target.String)
+		if err != nil {
+			return err
+		}
+		w, o.closeFn = file, file.Close
+	}
+	o.writer = bufio.NewWriter(w)
+	o.encoder = json.NewEncoder(o.writer)
+	o.encoder.SetEscapeHTML(false)
+
+	pf, err := output.NewDrainingPeriodicFlusher(
+		time.Duration(o.config.FlushInterval.Duration), o.flushMetrics, o.HasSpilledSamples)
+	if err != nil {
+		return err
+	}
+	o.logger.Debug("Started!")
+	o.periodicFlusher = pf
+	return nil
+}
+
+// Stop flushes any remaining samples and closes the target.
+func (o *Output) Stop() error {
+	o.logger.Debug("Stopping...")
+	defer o.logger.Debug("Stopped!")
+	o.periodicFlusher.Stop()
+	return o.closeFn()
+}
+
+// AddMetricSamples buffers only the sampled samples of the allowed metrics, so the filtered out
+// samples don't take any memory until the next flush.
+func (o *Output) AddMetricSamples(containers []stats.SampleContainer) {
+	var kept stats.Samples
+	for _, sc := range containers {
+		for _, sample := range sc.GetSamples() {
+			if !o.metrics.Match(sample.Metric.Name) {
+				continue
+			}
+			if o.config.SampleRate.Float64 < 1 && o.random() >= o.config.SampleRate.Float64 {
+				continue
+			}
+			kept = append(kept, sample)
+		}
+	}
+	if len(kept) > 0 {
+		o.SampleBuffer.AddMetricSamples([]stats.SampleContainer{kept})
+	}
+}
+
+func (o *Output) flushMetrics() {
+	var count int
+	for _, sc := range o.GetBufferedSamples() {
+		for _, sample := range sc.GetSamples() {
+			count++
+			err := o.encoder.Encode(line{
+				Metric: sample.Metric.Name,
+				Type:   sample.Metric.Type,
+				Time:   sample.Time,
+				Value:  sample.Value,
+				Tags:   sample.Tags,
+			})
+			if err != nil {
+				o.logger.WithError(err).Error("Sample couldn't be marshalled to JSON")
+			}
+		}
+	}
+	if count == 0 {
+		return
+	}
+	if err := o.writer.Flush(); err != nil {
+		o.logger.WithError(err).Error("Couldn't write the samples")
+	}
+}
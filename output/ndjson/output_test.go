@@ -0,0 +1,107 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ndjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+func readLines(t *testing.T, data string) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, l := range strings.Split(strings.TrimSpace(data), "\n") {
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(l), &decoded), l)
+		lines = append(lines, decoded)
+	}
+	return lines
+}
+
+func TestOutputFilters(t *testing.T) {
+	t.Parallel()
+	var stdout bytes.Buffer
+	out, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		StdOut:         &stdout,
+		ConfigArgument: "stdout,metrics=http_req_*,excludeMetrics=http_req_blocked",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ndjson (stdout)", out.Description())
+	require.NoError(t, out.Start())
+
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	tags := stats.IntoSampleTags(&map[string]string{"status": "200"})
+	out.AddMetricSamples([]stats.SampleContainer{stats.Samples{
+		{Time: now, Metric: stats.New("http_req_duration", stats.Trend), Value: 12.5, Tags: tags},
+		{Time: now, Metric: stats.New("http_req_blocked", stats.Trend), Value: 1},
+		{Time: now, Metric: stats.New("iterations", stats.Counter), Value: 1},
+	}})
+	require.NoError(t, out.Stop())
+
+	assert.Equal(t, `{"metric":"http_req_duration","type":"trend","time":"2021-06-01T10:00:00Z","value":12.5,`+
+		`"tags":{"status":"200"}}`+"\n", stdout.String())
+}
+
+func TestOutputSampling(t *testing.T) {
+	t.Parallel()
+	fs := afero.NewMemMapFs()
+	out, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		FS:             fs,
+		ConfigArgument: "target=samples.ndjson,sampleRate=0.25",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ndjson (samples.ndjson, 25% sampled)", out.Description())
+	values := []float64{0.1, 0.3, 0.2, 0.9}
+	out.random = func() float64 {
+		v := values[0]
+		values = values[1:]
+		return v
+	}
+	require.NoError(t, out.Start())
+
+	metric := stats.New("my_counter", stats.Counter)
+	var samples stats.Samples
+	for i := 1; i <= 4; i++ {
+		samples = append(samples, stats.Sample{Time: time.Now(), Metric: metric, Value: float64(i)})
+	}
+	out.AddMetricSamples([]stats.SampleContainer{samples})
+	require.NoError(t, out.Stop())
+
+	data, err := afero.ReadFile(fs, "samples.ndjson")
+	require.NoError(t, err)
+	lines := readLines(t, string(data))
+	require.Len(t, lines, 2)
+	assert.Equal(t, 1.0, lines[0]["value"])
+	assert.Equal(t, 3.0, lines[1]["value"])
+	assert.Equal(t, "counter", lines[1]["type"])
+}
@@ -0,0 +1,184 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package newrelic
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+// The endpoints of the Metric API in the New Relic regions.
+var regionURLs = map[string]string{ //nolint:gochecknoglobals
+	"us": "https://metric-api.newrelic.com/metric/v1",
+	"eu": "https://metric-api.eu.newrelic.com/metric/v1",
+}
+
+// Config is the config for the newrelic output
+type Config struct {
+	APIKey        null.String        `json:"apiKey" envconfig:"K6_NEWRELIC_API_KEY"`
+	Region        null.String        `json:"region" envconfig:"K6_NEWRELIC_REGION"`
+	URL           null.String        `json:"url" envconfig:"K6_NEWRELIC_URL"`
+	Prefix        null.String        `json:"prefix" envconfig:"K6_NEWRELIC_PREFIX"`
+	FlushInterval types.NullDuration `json:"flushInterval" envconfig:"K6_NEWRELIC_FLUSH_INTERVAL"`
+	Timeout       types.NullDuration `json:"timeout" envconfig:"K6_NEWRELIC_TIMEOUT"`
+	TagBlocklist  stats.TagSet       `json:"tagBlocklist" envconfig:"K6_NEWRELIC_TAG_BLOCKLIST"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Region:        null.StringFrom("us"),
+		Prefix:        null.StringFrom("k6."),
+		FlushInterval: types.NullDurationFrom(10 * time.Second),
+		Timeout:       types.NullDurationFrom(10 * time.Second),
+		TagBlocklist:  (stats.TagVU | stats.TagIter).Map(),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.APIKey.Valid {
+		c.APIKey = cfg.APIKey
+	}
+	if cfg.Region.Valid {
+		c.Region = cfg.Region
+	}
+	if cfg.URL.Valid {
+		c.URL = cfg.URL
+	}
+	if cfg.Prefix.Valid {
+		c.Prefix = cfg.Prefix
+	}
+	if cfg.FlushInterval.Valid {
+		c.FlushInterval = cfg.FlushInterval
+	}
+	if cfg.Timeout.Valid {
+		c.Timeout = cfg.Timeout
+	}
+	if cfg.TagBlocklist != nil {
+		c.TagBlocklist = cfg.TagBlocklist
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config, like in
+// --out newrelic=region=eu,prefix=checkout., the tagBlocklist key can be repeated. The license
+// key is too sensitive for the command line, so the apiKey key is refused.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	for _, pair := range strings.Split(arg, ",") {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			return c, fmt.Errorf("couldn't parse %q as argument for newrelic output", arg)
+		}
+		switch r[0] {
+		case "region":
+			c.Region = null.StringFrom(r[1])
+		case "url":
+			c.URL = null.StringFrom(r[1])
+		case "prefix":
+			c.Prefix = null.StringFrom(r[1])
+		case "flushInterval":
+			if err := c.FlushInterval.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "timeout":
+			if err := c.Timeout.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "tagBlocklist":
+			if c.TagBlocklist == nil {
+				c.TagBlocklist = make(stats.TagSet)
+			}
+			c.TagBlocklist[r[1]] = true
+		case "apiKey":
+			return c, fmt.Errorf("the newrelic apiKey can't be in the argument, use K6_NEWRELIC_API_KEY instead")
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for newrelic output", r[0])
+		}
+	}
+
+	return c, nil
+}
+
+// Validate returns an error if any config value is invalid.
+func (c Config) Validate() error {
+	if c.APIKey.String == "" {
+		return fmt.Errorf("the newrelic output needs the API key, set it with K6_NEWRELIC_API_KEY")
+	}
+	if _, ok := regionURLs[c.Region.String]; !ok && c.URL.String == "" {
+		return fmt.Errorf("invalid newrelic region %q, it has to be us or eu", c.Region.String)
+	}
+	if c.FlushInterval.Duration <= 0 {
+		return fmt.Errorf("the newrelic flushInterval should be positive, but it's %s", c.FlushInterval)
+	}
+	if c.Timeout.Duration <= 0 {
+		return fmt.Errorf("the newrelic timeout should be positive, but it's %s", c.Timeout)
+	}
+	return nil
+}
+
+// endpoint returns the URL of the Metric API, the one of the region unless the URL is set.
+func (c Config) endpoint() string {
+	if c.URL.String != "" {
+		return c.URL.String
+	}
+	return regionURLs[c.Region.String]
+}
+
+// GetConsolidatedConfig combines {default config values + JSON config +
+// environment vars + arg config values}, and returns the final result.
+func GetConsolidatedConfig(jsonRawConf json.RawMessage, env map[string]string, arg string) (Config, error) {
+	result := NewConfig()
+	if jsonRawConf != nil {
+		jsonConf := Config{}
+		if err := json.Unmarshal(jsonRawConf, &jsonConf); err != nil {
+			return result, err
+		}
+		result = result.Apply(jsonConf)
+	}
+
+	envConfig := Config{}
+	if err := envconfig.Process("", &envConfig); err != nil {
+		// TODO: get rid of envconfig and actually use the env parameter...
+		return result, err
+	}
+	result = result.Apply(envConfig)
+
+	if arg != "" {
+		argConf, err := ParseArg(arg)
+		if err != nil {
+			return result, err
+		}
+		result = result.Apply(argConf)
+	}
+
+	return result, result.Validate()
+}
@@ -0,0 +1,96 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package newrelic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+func TestParseArg(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		config      Config
+		expectedErr bool
+	}{
+		"region=eu,prefix=checkout.,flushInterval=30s": {
+			config: Config{
+				Region:        null.StringFrom("eu"),
+				Prefix:        null.StringFrom("checkout."),
+				FlushInterval: types.NullDurationFrom(30 * time.Second),
+			},
+		},
+		"url=http://localhost:8080/metric/v1,timeout=1s,tagBlocklist=url,tagBlocklist=name": {
+			config: Config{
+				URL:          null.StringFrom("http://localhost:8080/metric/v1"),
+				Timeout:      types.NullDurationFrom(time.Second),
+				TagBlocklist: stats.TagSet{"url": true, "name": true},
+			},
+		},
+		"apiKey=secret":       {expectedErr: true},
+		"eu":                  {expectedErr: true},
+		"foo=bar":             {expectedErr: true},
+		"flushInterval=never": {expectedErr: true},
+	}
+
+	for arg, tc := range cases {
+		arg, tc := arg, tc
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+			config, err := ParseArg(arg)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.config, config)
+		})
+	}
+}
+
+func TestGetConsolidatedConfig(t *testing.T) {
+	t.Parallel()
+	config, err := GetConsolidatedConfig([]byte(`{"apiKey": "secret", "prefix": "app."}`), nil, "region=eu")
+	require.NoError(t, err)
+	expected := NewConfig()
+	expected.APIKey = null.StringFrom("secret")
+	expected.Region = null.StringFrom("eu")
+	expected.Prefix = null.StringFrom("app.")
+	assert.Equal(t, expected, config)
+	assert.Equal(t, "https://metric-api.eu.newrelic.com/metric/v1", config.endpoint())
+
+	_, err = GetConsolidatedConfig(nil, nil, "region=eu")
+	assert.EqualError(t, err, "the newrelic output needs the API key, set it with K6_NEWRELIC_API_KEY")
+	for _, arg := range []string{"region=ap", "flushInterval=0s", "timeout=-1s"} {
+		_, err := GetConsolidatedConfig([]byte(`{"apiKey": "secret"}`), nil, arg)
+		assert.Error(t, err, arg)
+	}
+	config, err = GetConsolidatedConfig([]byte(`{"apiKey": "secret"}`), nil, "region=gov,url=http://localhost/v1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost/v1", config.endpoint())
+}
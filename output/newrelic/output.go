@@ -0,0 +1,210 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package newrelic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+// The metrics in a single request, the Metric API limits the size of the payloads.
+const metricsPerRequest = 10000
+
+// Output sends the metrics, aggregated in intervals, to the Metric API of New Relic as
+// dimensional metrics, with the tags of the samples as their attributes.
+type Output struct {
+	output.SampleBuffer
+
+	config          Config
+	logger          logrus.FieldLogger
+	client          *http.Client
+	periodicFlusher *output.PeriodicFlusher
+	intervalStart   time.Time
+	now             func() time.Time
+}
+
+var _ output.Output = new(Output)
+
+// New returns a new newrelic output
+func New(params output.Params) (output.Output, error) {
+	return newOutput(params)
+}
+
+func newOutput(params output.Params) (*Output, error) {
+	conf, err := GetConsolidatedConfig(params.JSONConfig, params.Environment, params.ConfigArgument)
+	if err != nil {
+		return nil, err
+	}
+	return &Output{
+		config: conf,
+		logger: params.Logger.WithFields(logrus.Fields{"output": "newrelic"}),
+		client: &http.Client{Timeout: time.Duration(conf.Timeout.Duration)},
+		now:    time.Now,
+	}, nil
+}
+
+// Description returns a human-readable description of the output.
+func (o *Output) Description() string {
+	return fmt.Sprintf("newrelic (%s)", o.config.endpoint())
+}
+
+// Start starts the goroutine for the flushing of the metrics.
+func (o *Output) Start() error {
+	o.logger.Debug("Starting...")
+	o.intervalStart = o.now()
+	pf, err := output.NewDrainingPeriodicFlusher(
+		time.Duration(o.config.FlushInterval.Duration), o.flushMetrics, o.HasSpilledSamples)
+	if err != nil {
+		return err
+	}
+	o.logger.Debug("Started!")
+	o.periodicFlusher = pf
+	return nil
+}
+
+// Stop flushes any remaining metrics and stops the goroutine.
+func (o *Output) Stop() error {
+	o.logger.Debug("Stopping...")
+	defer o.logger.Debug("Stopped!")
+	o.periodicFlusher.Stop()
+	return nil
+}
+
+// The data of the Metric API, see https://docs.newrelic.com/docs/data-apis/ingest-apis/metric-api/
+type (
+	metricsPayload struct {
+		Common  commonBlock `json:"common"`
+		Metrics []metric    `json:"metrics"`
+	}
+	commonBlock struct {
+		Timestamp  int64 `json:"timestamp"`
+		IntervalMs int64 `json:"interval.ms"`
+	}
+	metric struct {
+		Name       string            `json:"name"`
+		Type       string            `json:"type"`
+		Value      interface{}       `json:"value"`
+		Attributes map[string]string `json:"attributes,omitempty"`
+	}
+	summaryValue struct {
+		Count float64 `json:"count"`
+		Sum   float64 `json:"sum"`
+		Min   float64 `json:"min"`
+		Max   float64 `json:"max"`
+	}
+)
+
+// aggregate returns the metrics of the samples, aggregated by their names and tags, which are
+// their attributes. The counters are counts, the trends are summaries, and the gauges and the
+// rates are gauges.
+func (o *Output) aggregate(containers []stats.SampleContainer) []metric {
+	series := output.AggregateSamples(containers, o.config.TagBlocklist)
+	metrics := make([]metric, 0, len(series))
+	for _, s := range series {
+		m := metric{Name: o.config.Prefix.String + s.Metric.Name, Attributes: s.Tags}
+		switch sink := s.Sink.(type) {
+		case *stats.GaugeSink:
+			m.Type, m.Value = "gauge", sink.Value
+		case *stats.RateSink:
+			m.Type, m.Value = "gauge", float64(sink.Trues)/float64(sink.Total)
+		case *stats.TrendSink:
+			m.Type = "summary"
+			m.Value = summaryValue{Count: float64(sink.Count), Sum: sink.Sum, Min: sink.Min, Max: sink.Max}
+		case *stats.CounterSink:
+			m.Type, m.Value = "count", sink.Value
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+func (o *Output) flushMetrics() {
+	samples := o.GetBufferedSamples()
+	start, end := o.intervalStart, o.now()
+	o.intervalStart = end
+	if len(samples) == 0 {
+		return
+	}
+	metrics := o.aggregate(samples)
+	common := commonBlock{
+		Timestamp:  start.UnixNano() / int64(time.Millisecond),
+		IntervalMs: end.Sub(start).Milliseconds(),
+	}
+	if common.IntervalMs <= 0 {
+		common.IntervalMs = 1
+	}
+	for i := 0; i < len(metrics); i += metricsPerRequest {
+		j := i + metricsPerRequest
+		if j > len(metrics) {
+			j = len(metrics)
+		}
+		if err := o.send(metricsPayload{Common: common, Metrics: metrics[i:j]}); err != nil {
+			o.logger.WithError(err).Errorf("Couldn't send %d metrics to New Relic", j-i)
+			continue
+		}
+		o.logger.WithField("metrics", j-i).Debug("Sent metrics to New Relic")
+	}
+}
+
+// send posts the gzipped payload to the Metric API, which accepts it with a 202 status.
+func (o *Output) send(payload metricsPayload) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode([]metricsPayload{payload}); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, o.config.endpoint(), &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Api-Key", o.config.APIKey.String)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	switch {
+	case resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("the API key was rejected: %s", strings.TrimSpace(string(body)))
+	case resp.StatusCode/100 != 2:
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
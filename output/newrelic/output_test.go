@@ -0,0 +1,117 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package newrelic
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+func TestOutput(t *testing.T) {
+	t.Parallel()
+	payloads := make(chan []map[string]interface{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("Api-Key"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		var payload []map[string]interface{}
+		require.NoError(t, json.NewDecoder(gz).Decode(&payload))
+		payloads <- payload
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	o, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		JSONConfig:     []byte(`{"apiKey": "secret"}`),
+		ConfigArgument: "url=" + srv.URL + ",flushInterval=1h",
+	})
+	require.NoError(t, err)
+	times := []time.Time{time.Unix(1614173820, 0), time.Unix(1614173830, 0)}
+	o.now = func() time.Time {
+		now := times[0]
+		times = times[1:]
+		return now
+	}
+	require.NoError(t, o.Start())
+
+	reqs := stats.New("http_reqs", stats.Counter)
+	duration := stats.New("http_req_duration", stats.Trend)
+	checks := stats.New("checks", stats.Rate)
+	get := stats.NewSampleTags(map[string]string{"method": "GET", "vu": "1"})
+	now := time.Now()
+	o.AddMetricSamples([]stats.SampleContainer{stats.Samples{
+		{Metric: reqs, Value: 1, Time: now, Tags: get},
+		{Metric: reqs, Value: 1, Time: now, Tags: get},
+		{Metric: duration, Value: 100, Time: now, Tags: get},
+		{Metric: duration, Value: 300, Time: now, Tags: get},
+		{Metric: checks, Value: 1, Time: now},
+		{Metric: checks, Value: 0, Time: now},
+	}})
+	require.NoError(t, o.Stop())
+
+	require.Len(t, payloads, 1)
+	payload := <-payloads
+	require.Len(t, payload, 1)
+	assert.Equal(t, map[string]interface{}{"timestamp": 1614173820000.0, "interval.ms": 10000.0}, payload[0]["common"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "k6.checks", "type": "gauge", "value": 0.5},
+		map[string]interface{}{
+			"name": "k6.http_req_duration", "type": "summary",
+			"value":      map[string]interface{}{"count": 2.0, "sum": 400.0, "min": 100.0, "max": 300.0},
+			"attributes": map[string]interface{}{"method": "GET"},
+		},
+		map[string]interface{}{
+			"name": "k6.http_reqs", "type": "count", "value": 2.0,
+			"attributes": map[string]interface{}{"method": "GET"},
+		},
+	}, payload[0]["metrics"])
+}
+
+func TestOutputRejectedAPIKey(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("invalid key\n"))
+	}))
+	defer srv.Close()
+
+	o, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		JSONConfig:     []byte(`{"apiKey": "secret"}`),
+		ConfigArgument: "url=" + srv.URL,
+	})
+	require.NoError(t, err)
+	err = o.send(metricsPayload{Metrics: []metric{{Name: "k6.vus", Type: "gauge", Value: 1}}})
+	assert.EqualError(t, err, "the API key was rejected: invalid key")
+}
@@ -0,0 +1,153 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+)
+
+// The chat services that the messages can be sent to.
+const (
+	providerSlack = "slack"
+	providerTeams = "teams"
+)
+
+// Config is the config for the notify output
+type Config struct {
+	Provider null.String        `json:"provider" envconfig:"K6_NOTIFY_PROVIDER"`
+	URL      null.String        `json:"url" envconfig:"K6_NOTIFY_URL"`
+	Title    null.String        `json:"title" envconfig:"K6_NOTIFY_TITLE"`
+	Trends   []string           `json:"trends" envconfig:"K6_NOTIFY_TRENDS"`
+	Timeout  types.NullDuration `json:"timeout" envconfig:"K6_NOTIFY_TIMEOUT"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Provider: null.StringFrom(providerSlack),
+		Trends:   []string{"http_req_duration", "iteration_duration"},
+		Timeout:  types.NullDurationFrom(10 * time.Second),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Provider.Valid {
+		c.Provider = cfg.Provider
+	}
+	if cfg.URL.Valid {
+		c.URL = cfg.URL
+	}
+	if cfg.Title.Valid {
+		c.Title = cfg.Title
+	}
+	if cfg.Trends != nil {
+		c.Trends = cfg.Trends
+	}
+	if cfg.Timeout.Valid {
+		c.Timeout = cfg.Timeout
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config, like in
+// --out notify=provider=teams,trend=http_req_duration, the trend key can be repeated. The URL
+// can't be in the argument, since the incoming webhooks of Slack and Teams are secrets.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	for _, pair := range strings.Split(arg, ",") {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			return c, fmt.Errorf("couldn't parse %q as argument for notify output", arg)
+		}
+		switch r[0] {
+		case "provider":
+			c.Provider = null.StringFrom(r[1])
+		case "title":
+			c.Title = null.StringFrom(r[1])
+		case "trend":
+			c.Trends = append(c.Trends, r[1])
+		case "timeout":
+			if err := c.Timeout.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "url":
+			return c, fmt.Errorf("the notify url can't be in the argument, use K6_NOTIFY_URL instead")
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for notify output", r[0])
+		}
+	}
+
+	return c, nil
+}
+
+// Validate returns an error if any config value is invalid.
+func (c Config) Validate() error {
+	if c.Provider.String != providerSlack && c.Provider.String != providerTeams {
+		return fmt.Errorf("invalid notify provider %q, it has to be slack or teams", c.Provider.String)
+	}
+	if c.URL.String == "" {
+		return fmt.Errorf("the notify output needs the URL of the webhook, set it with K6_NOTIFY_URL")
+	}
+	if c.Timeout.Duration <= 0 {
+		return fmt.Errorf("the notify timeout should be positive, but it's %s", c.Timeout)
+	}
+	return nil
+}
+
+// GetConsolidatedConfig combines {default config values + JSON config +
+// environment vars + arg config values}, and returns the final result.
+func GetConsolidatedConfig(jsonRawConf json.RawMessage, env map[string]string, arg string) (Config, error) {
+	result := NewConfig()
+	if jsonRawConf != nil {
+		jsonConf := Config{}
+		if err := json.Unmarshal(jsonRawConf, &jsonConf); err != nil {
+			return result, err
+		}
+		result = result.Apply(jsonConf)
+	}
+
+	envConfig := Config{}
+	if err := envconfig.Process("", &envConfig); err != nil {
+		// TODO: get rid of envconfig and actually use the env parameter...
+		return result, err
+	}
+	result = result.Apply(envConfig)
+
+	if arg != "" {
+		argConf, err := ParseArg(arg)
+		if err != nil {
+			return result, err
+		}
+		result = result.Apply(argConf)
+	}
+
+	return result, result.Validate()
+}
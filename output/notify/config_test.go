@@ -0,0 +1,88 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+)
+
+func TestParseArg(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		config      Config
+		expectedErr bool
+	}{
+		"provider=teams,title=Checkout,timeout=5s": {
+			config: Config{
+				Provider: null.StringFrom("teams"),
+				Title:    null.StringFrom("Checkout"),
+				Timeout:  types.NullDurationFrom(5 * time.Second),
+			},
+		},
+		"trend=http_req_duration,trend=http_req_waiting": {
+			config: Config{Trends: []string{"http_req_duration", "http_req_waiting"}},
+		},
+		"url=https://hooks.slack.com/services/secret": {expectedErr: true},
+		"teams":         {expectedErr: true},
+		"foo=bar":       {expectedErr: true},
+		"timeout=never": {expectedErr: true},
+	}
+
+	for arg, tc := range cases {
+		arg, tc := arg, tc
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+			config, err := ParseArg(arg)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.config, config)
+		})
+	}
+}
+
+func TestGetConsolidatedConfig(t *testing.T) {
+	t.Parallel()
+	config, err := GetConsolidatedConfig([]byte(`{"url": "http://localhost/hook", "trends": ["http_req_waiting"]}`),
+		nil, "provider=teams")
+	require.NoError(t, err)
+	expected := NewConfig()
+	expected.Provider = null.StringFrom("teams")
+	expected.URL = null.StringFrom("http://localhost/hook")
+	expected.Trends = []string{"http_req_waiting"}
+	assert.Equal(t, expected, config)
+
+	_, err = GetConsolidatedConfig(nil, nil, "provider=teams")
+	assert.EqualError(t, err, "the notify output needs the URL of the webhook, set it with K6_NOTIFY_URL")
+	for _, arg := range []string{"provider=discord", "timeout=0s"} {
+		_, err := GetConsolidatedConfig([]byte(`{"url": "http://localhost/hook"}`), nil, arg)
+		assert.Error(t, err, arg)
+	}
+}
@@ -0,0 +1,392 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package notify implements an output that sends the end-of-test summary of the test run, with
+// its thresholds, its checks and the percentiles of its main trends, to a Slack or a Microsoft
+// Teams incoming webhook when it ends, colored by whether it passed.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/consts"
+	"go.k6.io/k6/lib/events"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+// The colors of the messages of the test runs that passed and that failed.
+const (
+	passColor = "2EB886"
+	failColor = "E01E5A"
+)
+
+// Output sends the end-of-test summary of the TestEnd event to the webhook when the test run
+// ends, it doesn't need the samples of the metrics.
+type Output struct {
+	config    Config
+	logger    logrus.FieldLogger
+	client    *http.Client
+	title     string
+	runID     string
+	startTime time.Time
+	now       func() time.Time
+
+	mutex    sync.Mutex
+	testEnd  *events.TestEndData
+	manifest *lib.TestRunManifest
+}
+
+var (
+	_ output.WithEvents      = new(Output)
+	_ output.WithRunManifest = new(Output)
+)
+
+// New returns a new notify output
+func New(params output.Params) (output.Output, error) {
+	return newOutput(params)
+}
+
+func newOutput(params output.Params) (*Output, error) {
+	conf, err := GetConsolidatedConfig(params.JSONConfig, params.Environment, params.ConfigArgument)
+	if err != nil {
+		return nil, err
+	}
+	o := &Output{
+		config: conf,
+		logger: params.Logger.WithFields(logrus.Fields{"output": "notify"}),
+		client: &http.Client{Timeout: time.Duration(conf.Timeout.Duration)},
+		title:  conf.Title.String,
+		now:    time.Now,
+	}
+	if params.RunMetadata != nil {
+		o.runID = params.RunMetadata.RunID
+		if o.title == "" {
+			o.title = "k6 test " + params.RunMetadata.ScriptName
+		}
+	}
+	if o.title == "" {
+		o.title = "k6 test"
+	}
+	return o, nil
+}
+
+// Description returns a human-readable description of the output.
+func (o *Output) Description() string {
+	return fmt.Sprintf("notify (%s)", o.config.Provider.String)
+}
+
+// SetEvents subscribes to the events of the test run, for the summary of its TestEnd event.
+func (o *Output) SetEvents(bus *events.Bus) {
+	bus.Subscribe(o.handleEvent)
+}
+
+func (o *Output) handleEvent(e events.Event) {
+	if e.Type != events.TestEnd {
+		return
+	}
+	data, ok := e.Data.(events.TestEndData)
+	if !ok {
+		o.logger.Debugf("Unexpected data of the %s event: %T", e.Type, e.Data)
+		return
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.testEnd = &data
+}
+
+// SetRunManifest receives the manifest of the test run, so that the summary has its error and
+// the test run fails if it was aborted.
+func (o *Output) SetRunManifest(manifest *lib.TestRunManifest) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.manifest = manifest
+}
+
+// Start only keeps the start time, for the duration of the test runs that don't have a summary.
+func (o *Output) Start() error {
+	o.startTime = o.now()
+	return nil
+}
+
+// AddMetricSamples drops the samples, the summary already has the values of the metrics.
+func (o *Output) AddMetricSamples(_ []stats.SampleContainer) {}
+
+// Stop sends the summary of the test run to the webhook.
+func (o *Output) Stop() error {
+	o.logger.Debug("Stopping...")
+	defer o.logger.Debug("Stopped!")
+
+	o.mutex.Lock()
+	s := o.summarize()
+	o.mutex.Unlock()
+
+	var payload interface{}
+	if o.config.Provider.String == providerTeams {
+		payload = newTeamsMessage(s)
+	} else {
+		payload = newSlackMessage(s)
+	}
+	if err := o.send(payload); err != nil {
+		return fmt.Errorf("couldn't send the summary to %s: %w", o.config.Provider.String, err)
+	}
+	return nil
+}
+
+type fact struct {
+	name, value string
+}
+
+// summary is the summary of the test run, which is formatted for the provider of the webhook.
+type summary struct {
+	title  string
+	passed bool
+	runID  string
+	facts  []fact
+}
+
+// summarize has to be called with the mutex held. Without the TestEnd event, e.g. when the
+// engine failed, the summary only has the status and the duration.
+func (o *Output) summarize() summary {
+	s := summary{title: o.title, passed: true, runID: o.runID}
+	status := "passed"
+	duration := o.now().Sub(o.startTime)
+
+	var checkFacts, thresholdFacts, trendFacts []fact
+	if end := o.testEnd; end != nil {
+		duration = time.Duration(end.Duration)
+		if end.ThresholdsFailed {
+			s.passed = false
+			status = "failed, some thresholds have failed"
+		}
+
+		names := make([]string, 0, len(end.Metrics))
+		for name := range end.Metrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if m := end.Metrics[name]; len(m.Thresholds) > 0 {
+				thresholdFacts = append(thresholdFacts, fact{name, formatThresholds(m.Thresholds)})
+			}
+		}
+		for _, name := range o.config.Trends {
+			if m, ok := end.Metrics[name]; ok && m.Type == stats.Trend.String() {
+				trendFacts = append(trendFacts, fact{name, formatTrend(m)})
+			}
+		}
+		if m, ok := end.Metrics[metrics.Checks.Name]; ok {
+			passes, fails := m.Values["passes"], m.Values["fails"]
+			if total := passes + fails; total > 0 {
+				checkFacts = append(checkFacts, fact{"Checks", fmt.Sprintf("%s%% passed (%s of %s)",
+					formatFloat(passes/total*100), formatFloat(passes), formatFloat(total))})
+			}
+		}
+	}
+	if o.manifest != nil && o.manifest.ExitCode != 0 {
+		s.passed = false
+		status = fmt.Sprintf("failed with the exit code %d", o.manifest.ExitCode)
+		if o.manifest.Error != "" {
+			status += ", " + o.manifest.Error
+		}
+	}
+
+	s.facts = append(s.facts, fact{"Status", status}, fact{"Duration", duration.Round(time.Millisecond).String()})
+	s.facts = append(s.facts, checkFacts...)
+	s.facts = append(s.facts, thresholdFacts...)
+	s.facts = append(s.facts, trendFacts...)
+	return s
+}
+
+// formatThresholds returns the thresholds sorted by their sources, marked by whether they passed.
+func formatThresholds(thresholds map[string]bool) string {
+	sources := make([]string, 0, len(thresholds))
+	for source := range thresholds {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	results := make([]string, len(sources))
+	for i, source := range sources {
+		mark := "✓"
+		if !thresholds[source] {
+			mark = "✗"
+		}
+		results[i] = mark + " " + source
+	}
+	return strings.Join(results, ", ")
+}
+
+// The values of the trends in the summary, if the summary of their metrics has them.
+var trendValues = []string{"avg", "med", "p(90)", "p(95)"} //nolint:gochecknoglobals
+
+// formatTrend returns the average, the median and the main percentiles of the trend.
+func formatTrend(m events.MetricSummary) string {
+	parts := make([]string, 0, len(trendValues))
+	for _, name := range trendValues {
+		if v, ok := m.Values[name]; ok {
+			parts = append(parts, name+"="+formatValue(m.Contains, v))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatValue returns the value with its unit, the time values are in milliseconds.
+func formatValue(contains string, v float64) string {
+	switch contains {
+	case stats.Time.String():
+		if v >= 1000 {
+			return formatFloat(v/1000) + "s"
+		}
+		return formatFloat(v) + "ms"
+	case stats.Data.String():
+		return formatFloat(v) + "B"
+	default:
+		return formatFloat(v)
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// The incoming webhook messages of Slack, with a legacy attachment, since only they have colors,
+// see https://api.slack.com/reference/messaging/attachments
+type (
+	slackMessage struct {
+		Text        string            `json:"text"`
+		Attachments []slackAttachment `json:"attachments"`
+	}
+	slackAttachment struct {
+		Fallback string       `json:"fallback"`
+		Color    string       `json:"color"`
+		Fields   []slackField `json:"fields"`
+		Footer   string       `json:"footer,omitempty"`
+	}
+	slackField struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+	}
+)
+
+// The incoming webhook messages of Microsoft Teams, see
+// https://docs.microsoft.com/en-us/outlook/actionable-messages/message-card-reference
+type (
+	teamsMessage struct {
+		Type       string         `json:"@type"`
+		Context    string         `json:"@context"`
+		ThemeColor string         `json:"themeColor"`
+		Summary    string         `json:"summary"`
+		Title      string         `json:"title"`
+		Sections   []teamsSection `json:"sections"`
+	}
+	teamsSection struct {
+		Facts []teamsFact `json:"facts"`
+	}
+	teamsFact struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+)
+
+func (s summary) headline() string {
+	if s.passed {
+		return s.title + " passed"
+	}
+	return s.title + " failed"
+}
+
+func (s summary) color() string {
+	if s.passed {
+		return passColor
+	}
+	return failColor
+}
+
+func newSlackMessage(s summary) slackMessage {
+	attachment := slackAttachment{
+		Fallback: s.headline(),
+		Color:    "#" + s.color(),
+		Fields:   make([]slackField, 0, len(s.facts)),
+	}
+	if s.runID != "" {
+		attachment.Footer = "Run ID " + s.runID
+	}
+	for _, f := range s.facts {
+		attachment.Fields = append(attachment.Fields, slackField{Title: f.name, Value: f.value})
+	}
+	return slackMessage{Text: s.headline(), Attachments: []slackAttachment{attachment}}
+}
+
+func newTeamsMessage(s summary) teamsMessage {
+	facts := make([]teamsFact, 0, len(s.facts)+1)
+	for _, f := range s.facts {
+		facts = append(facts, teamsFact{Name: f.name, Value: f.value})
+	}
+	if s.runID != "" {
+		facts = append(facts, teamsFact{Name: "Run ID", Value: s.runID})
+	}
+	return teamsMessage{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		ThemeColor: s.color(),
+		Summary:    s.headline(),
+		Title:      s.headline(),
+		Sections:   []teamsSection{{Facts: facts}},
+	}
+}
+
+// send posts the message to the webhook.
+func (o *Output) send(payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, o.config.URL.String, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "k6/"+consts.Version)
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
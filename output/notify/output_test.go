@@ -0,0 +1,173 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/errext"
+	"go.k6.io/k6/errext/exitcodes"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/events"
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+// runOutput runs the notify output with the provider against a test webhook, with the TestEnd
+// event of a short test run, and returns the message it sent.
+func runOutput(t *testing.T, provider string, manifest *lib.TestRunManifest) map[string]interface{} {
+	messages := make(chan map[string]interface{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var message map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+		messages <- message
+	}))
+	defer srv.Close()
+
+	o, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		JSONConfig:     []byte(`{"url": "` + srv.URL + `"}`),
+		ConfigArgument: "provider=" + provider,
+		RunMetadata:    &lib.TestRunMetadata{RunID: "run-1", ScriptName: "script.js"},
+	})
+	require.NoError(t, err)
+	bus := events.NewBus("run-1")
+	o.SetEvents(bus)
+	require.NoError(t, o.Start())
+
+	o.AddMetricSamples([]stats.SampleContainer{stats.Sample{
+		Metric: stats.New("vus", stats.Gauge), Value: 1, Time: time.Now(),
+	}})
+	bus.Publish(events.TestEnd, events.TestEndData{
+		Duration:         types.Duration(10 * time.Second),
+		ThresholdsFailed: true,
+		Metrics: map[string]events.MetricSummary{
+			"http_req_duration": {
+				Type: "trend", Contains: "time",
+				Values:     map[string]float64{"avg": 583.333, "min": 100, "med": 150, "max": 1500, "p(90)": 1230, "p(95)": 1360},
+				Thresholds: map[string]bool{"p(95)<200": false},
+			},
+			"http_reqs{status:500}": {
+				Type: "counter", Contains: "default",
+				Values:     map[string]float64{"count": 1, "rate": 0.1},
+				Thresholds: map[string]bool{"count<2": true},
+			},
+			"checks": {
+				Type: "rate", Contains: "default",
+				Values: map[string]float64{"rate": 2.0 / 3, "passes": 2, "fails": 1},
+			},
+			"vus": {Type: "gauge", Contains: "default", Values: map[string]float64{"value": 1}},
+		},
+	})
+	if manifest != nil {
+		o.SetRunManifest(manifest)
+	}
+	require.NoError(t, o.Stop())
+
+	require.Len(t, messages, 1)
+	return <-messages
+}
+
+func TestOutputSlack(t *testing.T) {
+	t.Parallel()
+	message := runOutput(t, "slack", nil)
+	assert.Equal(t, map[string]interface{}{
+		"text": "k6 test script.js failed",
+		"attachments": []interface{}{map[string]interface{}{
+			"fallback": "k6 test script.js failed",
+			"color":    "#E01E5A",
+			"footer":   "Run ID run-1",
+			"fields": []interface{}{
+				map[string]interface{}{"title": "Status", "value": "failed, some thresholds have failed"},
+				map[string]interface{}{"title": "Duration", "value": "10s"},
+				map[string]interface{}{"title": "Checks", "value": "66.67% passed (2 of 3)"},
+				map[string]interface{}{"title": "http_req_duration", "value": "✗ p(95)<200"},
+				map[string]interface{}{"title": "http_reqs{status:500}", "value": "✓ count<2"},
+				map[string]interface{}{
+					"title": "http_req_duration",
+					"value": "avg=583.33ms med=150ms p(90)=1.23s p(95)=1.36s",
+				},
+			},
+		}},
+	}, message)
+}
+
+func TestOutputTeams(t *testing.T) {
+	t.Parallel()
+	message := runOutput(t, "teams", nil)
+	assert.Equal(t, "MessageCard", message["@type"])
+	assert.Equal(t, "E01E5A", message["themeColor"])
+	assert.Equal(t, "k6 test script.js failed", message["title"])
+	sections, ok := message["sections"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, sections, 1)
+	facts, ok := sections[0].(map[string]interface{})["facts"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, facts, 7)
+	assert.Equal(t, map[string]interface{}{"name": "Status", "value": "failed, some thresholds have failed"}, facts[0])
+	assert.Equal(t, map[string]interface{}{"name": "Run ID", "value": "run-1"}, facts[6])
+}
+
+func TestOutputAborted(t *testing.T) {
+	t.Parallel()
+	abortErr := errext.WithExitCodeIfNone(errors.New("aborted by the script"), exitcodes.ScriptAborted)
+	message := runOutput(t, "slack", lib.NewTestRunManifest(&lib.TestRunMetadata{}, lib.Options{},
+		int(exitcodes.ScriptAborted), abortErr))
+	attachments, ok := message["attachments"].([]interface{})
+	require.True(t, ok)
+	fields, ok := attachments[0].(map[string]interface{})["fields"].([]interface{})
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"title": "Status", "value": "failed with the exit code 109, aborted by the script",
+	}, fields[0])
+}
+
+func TestOutputPassed(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message slackMessage
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+		assert.Equal(t, "k6 test passed", message.Text)
+		assert.Equal(t, "#"+passColor, message.Attachments[0].Color)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid_payload\n"))
+	}))
+	defer srv.Close()
+
+	o, err := newOutput(output.Params{
+		Logger:     testutils.NewLogger(t),
+		JSONConfig: []byte(`{"url": "` + srv.URL + `"}`),
+	})
+	require.NoError(t, err)
+	require.NoError(t, o.Start())
+	assert.EqualError(t, o.Stop(), "couldn't send the summary to slack: unexpected status 400: invalid_payload")
+}
@@ -0,0 +1,194 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package otlplogs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+// Config is the config for the otlp-logs output
+type Config struct {
+	// The OTLP/HTTP endpoint of the logs, and the headers of the requests, like the ones of the
+	// authentication of the backend.
+	Endpoint    null.String       `json:"endpoint" envconfig:"K6_OTLP_LOGS_ENDPOINT"`
+	Headers     map[string]string `json:"headers" envconfig:"K6_OTLP_LOGS_HEADERS"`
+	ServiceName null.String       `json:"serviceName" envconfig:"K6_OTLP_LOGS_SERVICE_NAME"`
+
+	// The records of the failed checks and of the script errors, the errors are the logs of k6
+	// with the error level.
+	Checks null.Bool `json:"checks" envconfig:"K6_OTLP_LOGS_CHECKS"`
+	Errors null.Bool `json:"errors" envconfig:"K6_OTLP_LOGS_ERRORS"`
+
+	FlushInterval types.NullDuration `json:"flushInterval" envconfig:"K6_OTLP_LOGS_FLUSH_INTERVAL"`
+	Timeout       types.NullDuration `json:"timeout" envconfig:"K6_OTLP_LOGS_TIMEOUT"`
+	TagBlocklist  stats.TagSet       `json:"tagBlocklist" envconfig:"K6_OTLP_LOGS_TAG_BLOCKLIST"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Endpoint:      null.StringFrom("http://localhost:4318/v1/logs"),
+		ServiceName:   null.StringFrom("k6"),
+		Checks:        null.BoolFrom(true),
+		Errors:        null.BoolFrom(true),
+		FlushInterval: types.NullDurationFrom(5 * time.Second),
+		Timeout:       types.NullDurationFrom(10 * time.Second),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Endpoint.Valid {
+		c.Endpoint = cfg.Endpoint
+	}
+	if cfg.Headers != nil {
+		c.Headers = cfg.Headers
+	}
+	if cfg.ServiceName.Valid {
+		c.ServiceName = cfg.ServiceName
+	}
+	if cfg.Checks.Valid {
+		c.Checks = cfg.Checks
+	}
+	if cfg.Errors.Valid {
+		c.Errors = cfg.Errors
+	}
+	if cfg.FlushInterval.Valid {
+		c.FlushInterval = cfg.FlushInterval
+	}
+	if cfg.Timeout.Valid {
+		c.Timeout = cfg.Timeout
+	}
+	if cfg.TagBlocklist != nil {
+		c.TagBlocklist = cfg.TagBlocklist
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config, like in
+// --out otlp-logs=endpoint=http://collector:4318/v1/logs,errors=false, the tagBlocklist key can
+// be repeated. The headers can't be in the argument, since they usually have the credentials,
+// so they are in K6_OTLP_LOGS_HEADERS, like Authorization:Bearer token.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	for _, pair := range strings.Split(arg, ",") {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			return c, fmt.Errorf("couldn't parse %q as argument for otlp-logs output", arg)
+		}
+		switch r[0] {
+		case "endpoint":
+			c.Endpoint = null.StringFrom(r[1])
+		case "serviceName":
+			c.ServiceName = null.StringFrom(r[1])
+		case "checks", "errors":
+			v, err := strconv.ParseBool(r[1])
+			if err != nil {
+				return c, fmt.Errorf("%s value must be true or false, not %q", r[0], r[1])
+			}
+			if r[0] == "checks" {
+				c.Checks = null.BoolFrom(v)
+			} else {
+				c.Errors = null.BoolFrom(v)
+			}
+		case "flushInterval":
+			if err := c.FlushInterval.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "timeout":
+			if err := c.Timeout.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "tagBlocklist":
+			if c.TagBlocklist == nil {
+				c.TagBlocklist = make(stats.TagSet)
+			}
+			c.TagBlocklist[r[1]] = true
+		case "headers":
+			return c, fmt.Errorf("the otlp-logs headers can't be in the argument, use K6_OTLP_LOGS_HEADERS instead")
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for otlp-logs output", r[0])
+		}
+	}
+
+	return c, nil
+}
+
+// Validate returns an error if any config value is invalid.
+func (c Config) Validate() error {
+	u, err := url.Parse(c.Endpoint.String)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid otlp-logs endpoint %q, it has to be like http://localhost:4318/v1/logs",
+			c.Endpoint.String)
+	}
+	if !c.Checks.Bool && !c.Errors.Bool {
+		return fmt.Errorf("the otlp-logs output doesn't have anything to send with both checks and errors disabled")
+	}
+	if c.FlushInterval.Duration <= 0 {
+		return fmt.Errorf("the otlp-logs flushInterval should be positive, but it's %s", c.FlushInterval)
+	}
+	if c.Timeout.Duration <= 0 {
+		return fmt.Errorf("the otlp-logs timeout should be positive, but it's %s", c.Timeout)
+	}
+	return nil
+}
+
+// GetConsolidatedConfig combines {default config values + JSON config +
+// environment vars + arg config values}, and returns the final result.
+func GetConsolidatedConfig(jsonRawConf json.RawMessage, env map[string]string, arg string) (Config, error) {
+	result := NewConfig()
+	if jsonRawConf != nil {
+		jsonConf := Config{}
+		if err := json.Unmarshal(jsonRawConf, &jsonConf); err != nil {
+			return result, err
+		}
+		result = result.Apply(jsonConf)
+	}
+
+	envConfig := Config{}
+	if err := envconfig.Process("", &envConfig); err != nil {
+		// TODO: get rid of envconfig and actually use the env parameter...
+		return result, err
+	}
+	result = result.Apply(envConfig)
+
+	if arg != "" {
+		argConf, err := ParseArg(arg)
+		if err != nil {
+			return result, err
+		}
+		result = result.Apply(argConf)
+	}
+
+	return result, result.Validate()
+}
@@ -0,0 +1,99 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package otlplogs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+func TestParseArg(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		config      Config
+		expectedErr bool
+	}{
+		"endpoint=https://collector:4318/v1/logs,serviceName=checkout,errors=false": {
+			config: Config{
+				Endpoint:    null.StringFrom("https://collector:4318/v1/logs"),
+				ServiceName: null.StringFrom("checkout"),
+				Errors:      null.BoolFrom(false),
+			},
+		},
+		"checks=false,tagBlocklist=url,flushInterval=1s": {
+			config: Config{
+				Checks:        null.BoolFrom(false),
+				TagBlocklist:  stats.TagSet{"url": true},
+				FlushInterval: types.NullDurationFrom(time.Second),
+			},
+		},
+		"headers=Authorization:token": {expectedErr: true},
+		"checks=some":                 {expectedErr: true},
+		"http://collector:4318":       {expectedErr: true},
+		"foo=bar":                     {expectedErr: true},
+		"timeout=never":               {expectedErr: true},
+	}
+
+	for arg, tc := range cases {
+		arg, tc := arg, tc
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+			config, err := ParseArg(arg)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.config, config)
+		})
+	}
+}
+
+func TestGetConsolidatedConfig(t *testing.T) {
+	t.Parallel()
+	jsonConf := []byte(`{"headers": {"Authorization": "Bearer token"}, "serviceName": "checkout"}`)
+	config, err := GetConsolidatedConfig(jsonConf, nil, "errors=false")
+	require.NoError(t, err)
+	expected := NewConfig()
+	expected.Headers = map[string]string{"Authorization": "Bearer token"}
+	expected.ServiceName = null.StringFrom("checkout")
+	expected.Errors = null.BoolFrom(false)
+	assert.Equal(t, expected, config)
+
+	invalid := map[string]string{
+		`{"endpoint": "collector:4318"}`:     "",
+		`{"endpoint": "grpc://collector"}`:   "",
+		`{"checks": false, "errors": false}`: "",
+		`{"flushInterval": "0s"}`:            "",
+		`{}`:                                 "timeout=0s",
+	}
+	for conf, arg := range invalid {
+		_, err := GetConsolidatedConfig([]byte(conf), nil, arg)
+		assert.Error(t, err, conf)
+	}
+}
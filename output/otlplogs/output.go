@@ -0,0 +1,325 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package otlplogs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/lib/consts"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+const (
+	// The errors are dropped when more than maxBufferedRecords are waiting for the flushing, so
+	// that a script that fails too much doesn't use all of the memory.
+	maxBufferedRecords   = 10000
+	maxRecordsPerRequest = 1000
+
+	// The severity numbers of the OTLP log records.
+	severityWarn  = 13
+	severityError = 17
+	severityFatal = 21
+)
+
+// Output sends the failed checks and the script errors as OTLP log records, so that the failures
+// are in the same backend as the metrics and the traces. The records are correlated with the
+// traces by the trace_id and the span_id of the metadata or the tags of the samples, and of the
+// fields of the logs.
+type Output struct {
+	output.SampleBuffer
+
+	config          Config
+	logger          logrus.FieldLogger
+	client          *http.Client
+	resource        resource
+	periodicFlusher *output.PeriodicFlusher
+	errors          *output.LogCollector
+	now             func() time.Time
+}
+
+var _ output.Output = new(Output)
+
+// New returns a new otlp-logs output
+func New(params output.Params) (output.Output, error) {
+	return newOutput(params)
+}
+
+func newOutput(params output.Params) (*Output, error) {
+	conf, err := GetConsolidatedConfig(params.JSONConfig, params.Environment, params.ConfigArgument)
+	if err != nil {
+		return nil, err
+	}
+	res := resource{Attributes: []keyValue{
+		stringAttribute("service.name", conf.ServiceName.String),
+		stringAttribute("service.version", consts.Version),
+	}}
+	if params.RunMetadata != nil {
+		res.Attributes = append(res.Attributes, stringAttribute("k6.run_id", params.RunMetadata.RunID))
+	}
+	errorLevels := []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+	return &Output{
+		config:   conf,
+		logger:   params.Logger.WithFields(logrus.Fields{"output": "otlp-logs"}),
+		client:   &http.Client{Timeout: time.Duration(conf.Timeout.Duration)},
+		resource: res,
+		errors:   output.NewLogCollector(params.Logger, "otlp-logs", errorLevels, maxBufferedRecords),
+		now:      time.Now,
+	}, nil
+}
+
+// Description returns a human-readable description of the output.
+func (o *Output) Description() string {
+	return fmt.Sprintf("otlp-logs (%s)", o.config.Endpoint.String)
+}
+
+// Start adds the hook of the script errors, if they are enabled, and starts the goroutine for
+// the flushing of the records.
+func (o *Output) Start() error {
+	o.logger.Debug("Starting...")
+	if o.config.Errors.Bool && !o.errors.Start() {
+		o.logger.Warn("The script errors can't be sent as OTLP logs, since the logger doesn't support hooks")
+	}
+	pf, err := output.NewDrainingPeriodicFlusher(
+		time.Duration(o.config.FlushInterval.Duration), o.flushMetrics, o.HasSpilledSamples)
+	if err != nil {
+		return err
+	}
+	o.logger.Debug("Started!")
+	o.periodicFlusher = pf
+	return nil
+}
+
+// Stop stops collecting the errors, since the hooks can't be removed from the logger, and
+// flushes any remaining records and stops the goroutine.
+func (o *Output) Stop() error {
+	o.logger.Debug("Stopping...")
+	defer o.logger.Debug("Stopped!")
+	o.errors.Stop()
+	o.periodicFlusher.Stop()
+	return nil
+}
+
+// The OTLP/HTTP JSON encoding of the logs, see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/logs/v1/logs.proto
+type (
+	logsData struct {
+		ResourceLogs []resourceLogs `json:"resourceLogs"`
+	}
+	resourceLogs struct {
+		Resource  resource    `json:"resource"`
+		ScopeLogs []scopeLogs `json:"scopeLogs"`
+	}
+	resource struct {
+		Attributes []keyValue `json:"attributes"`
+	}
+	scopeLogs struct {
+		Scope      scope       `json:"scope"`
+		LogRecords []logRecord `json:"logRecords"`
+	}
+	scope struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	logRecord struct {
+		TimeUnixNano         string     `json:"timeUnixNano"`
+		ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+		SeverityNumber       int        `json:"severityNumber"`
+		SeverityText         string     `json:"severityText"`
+		Body                 anyValue   `json:"body"`
+		Attributes           []keyValue `json:"attributes,omitempty"`
+		TraceID              string     `json:"traceId,omitempty"`
+		SpanID               string     `json:"spanId,omitempty"`
+	}
+	keyValue struct {
+		Key   string   `json:"key"`
+		Value anyValue `json:"value"`
+	}
+	anyValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+func stringAttribute(key, value string) keyValue {
+	return keyValue{Key: key, Value: anyValue{StringValue: value}}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// sortedAttributes returns the attributes of the values sorted by their keys, after the
+// attribute of the name of the event.
+func sortedAttributes(event string, values map[string]string) []keyValue {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]keyValue, 0, len(keys)+1)
+	attrs = append(attrs, stringAttribute("event.name", event))
+	for _, k := range keys {
+		attrs = append(attrs, stringAttribute(k, values[k]))
+	}
+	return attrs
+}
+
+func isHexID(id string, length int) bool {
+	if len(id) != length {
+		return false
+	}
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// correlate sets the trace and the span of a record, the first valid ones of the sources.
+func (r *logRecord) correlate(sources ...map[string]string) {
+	for _, source := range sources {
+		if id := strings.ToLower(source["trace_id"]); r.TraceID == "" && isHexID(id, 32) {
+			r.TraceID = id
+		}
+		if id := strings.ToLower(source["span_id"]); r.SpanID == "" && isHexID(id, 16) {
+			r.SpanID = id
+		}
+	}
+}
+
+// checkRecord returns the record of a failed check, with the tags of the sample as attributes.
+func (o *Output) checkRecord(sample stats.Sample, observed time.Time) logRecord {
+	tags := sample.Tags.CloneTags()
+	for tag := range tags {
+		if o.config.TagBlocklist[tag] {
+			delete(tags, tag)
+		}
+	}
+	r := logRecord{
+		TimeUnixNano:         unixNano(sample.Time),
+		ObservedTimeUnixNano: unixNano(observed),
+		SeverityNumber:       severityWarn,
+		SeverityText:         "WARN",
+		Body:                 anyValue{StringValue: fmt.Sprintf("The check '%s' failed", tags["check"])},
+		Attributes:           sortedAttributes("k6.check.failed", tags),
+	}
+	r.correlate(sample.Metadata, tags)
+	return r
+}
+
+// errorRecord returns the record of an error log, with its fields as attributes.
+func errorRecord(entry *logrus.Entry) logRecord {
+	fields := make(map[string]string, len(entry.Data))
+	for k, v := range entry.Data {
+		fields[k] = fmt.Sprint(v)
+	}
+	r := logRecord{
+		TimeUnixNano:         unixNano(entry.Time),
+		ObservedTimeUnixNano: unixNano(entry.Time),
+		SeverityNumber:       severityError,
+		SeverityText:         "ERROR",
+		Body:                 anyValue{StringValue: entry.Message},
+		Attributes:           sortedAttributes("k6.error", fields),
+	}
+	if entry.Level < logrus.ErrorLevel {
+		r.SeverityNumber, r.SeverityText = severityFatal, "FATAL"
+	}
+	r.correlate(fields)
+	return r
+}
+
+func (o *Output) flushMetrics() {
+	samples := o.GetBufferedSamples()
+	errors, dropped := o.errors.Take()
+	if dropped > 0 {
+		o.logger.Warnf("Dropped %d script errors, since more than %d were waiting for sending them",
+			dropped, maxBufferedRecords)
+	}
+	records := make([]logRecord, 0, len(errors))
+	for _, entry := range errors {
+		records = append(records, errorRecord(entry))
+	}
+	if o.config.Checks.Bool {
+		observed := o.now()
+		for _, sc := range samples {
+			for _, sample := range sc.GetSamples() {
+				if sample.Metric.Name == metrics.Checks.Name && sample.Value == 0 {
+					records = append(records, o.checkRecord(sample, observed))
+				}
+			}
+		}
+	}
+
+	for len(records) > 0 {
+		n := len(records)
+		if n > maxRecordsPerRequest {
+			n = maxRecordsPerRequest
+		}
+		if err := o.send(records[:n]); err != nil {
+			o.logger.WithError(err).Errorf("Couldn't send %d OTLP log records", n)
+		}
+		records = records[n:]
+	}
+}
+
+func (o *Output) send(records []logRecord) error {
+	body, err := json.Marshal(logsData{ResourceLogs: []resourceLogs{{
+		Resource: o.resource,
+		ScopeLogs: []scopeLogs{{
+			Scope:      scope{Name: "k6", Version: consts.Version},
+			LogRecords: records,
+		}},
+	}}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, o.config.Endpoint.String, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range o.config.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package otlplogs
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/consts"
+	"go.k6.io/k6/lib/metrics"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+func TestOutput(t *testing.T) {
+	t.Parallel()
+	var (
+		mu        sync.Mutex
+		requests  []logsData
+		authorize []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/logs", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var data logsData
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&data))
+		mu.Lock()
+		requests = append(requests, data)
+		authorize = append(authorize, r.Header.Get("Authorization"))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	o, err := newOutput(output.Params{
+		Logger:         logger,
+		JSONConfig:     []byte(`{"endpoint": "` + srv.URL + `/v1/logs", "headers": {"Authorization": "Bearer token"}}`),
+		ConfigArgument: "flushInterval=1h,tagBlocklist=vu",
+		RunMetadata:    &lib.TestRunMetadata{RunID: "run-1"},
+	})
+	require.NoError(t, err)
+	observed := time.Unix(1614173830, 0)
+	o.now = func() time.Time { return observed }
+	require.NoError(t, o.Start())
+
+	logger.Warn("not an error")
+	logger.WithError(errors.New("boom")).WithField("trace_id", "0AF7651916CD43DD8448EB211C80319C").
+		Error("The iteration failed")
+
+	tags := stats.NewSampleTags(map[string]string{"check": "status is 200", "group": "", "vu": "1"})
+	now := time.Unix(1614173820, 0)
+	o.AddMetricSamples([]stats.SampleContainer{stats.Samples{
+		{Metric: metrics.Checks, Value: 1, Time: now, Tags: tags},
+		{Metric: metrics.Checks, Value: 0, Time: now, Tags: tags, Metadata: map[string]string{
+			"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736", "span_id": "00f067aa0ba902b7",
+		}},
+		{Metric: metrics.HTTPReqs, Value: 1, Time: now, Tags: tags},
+	}})
+	require.NoError(t, o.Stop())
+	logger.Error("not sent after the stop")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, requests, 1)
+	assert.Equal(t, []string{"Bearer token"}, authorize)
+	require.Len(t, requests[0].ResourceLogs, 1)
+	rl := requests[0].ResourceLogs[0]
+	assert.Equal(t, resource{Attributes: []keyValue{
+		stringAttribute("service.name", "k6"),
+		stringAttribute("service.version", consts.Version),
+		stringAttribute("k6.run_id", "run-1"),
+	}}, rl.Resource)
+	require.Len(t, rl.ScopeLogs, 1)
+	assert.Equal(t, scope{Name: "k6", Version: consts.Version}, rl.ScopeLogs[0].Scope)
+
+	records := rl.ScopeLogs[0].LogRecords
+	require.Len(t, records, 2)
+	assert.Equal(t, "The iteration failed", records[0].Body.StringValue)
+	assert.Equal(t, severityError, records[0].SeverityNumber)
+	assert.Equal(t, []keyValue{
+		stringAttribute("event.name", "k6.error"),
+		stringAttribute("error", "boom"),
+		stringAttribute("trace_id", "0AF7651916CD43DD8448EB211C80319C"),
+	}, records[0].Attributes)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", records[0].TraceID)
+	assert.Empty(t, records[0].SpanID)
+
+	assert.Equal(t, logRecord{
+		TimeUnixNano:         "1614173820000000000",
+		ObservedTimeUnixNano: "1614173830000000000",
+		SeverityNumber:       severityWarn,
+		SeverityText:         "WARN",
+		Body:                 anyValue{StringValue: "The check 'status is 200' failed"},
+		Attributes: []keyValue{
+			stringAttribute("event.name", "k6.check.failed"),
+			stringAttribute("check", "status is 200"),
+			stringAttribute("group", ""),
+		},
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+	}, records[1])
+}
+
+func TestCorrelate(t *testing.T) {
+	t.Parallel()
+	r := logRecord{}
+	r.correlate(
+		map[string]string{"trace_id": "not-hex", "span_id": "00f067aa0ba902b7"},
+		map[string]string{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736", "span_id": "ffffffffffffffff"},
+	)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", r.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", r.SpanID)
+}
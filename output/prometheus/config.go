@@ -0,0 +1,243 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+const (
+	// modeServe exposes the /metrics endpoint for the scraping, modePush pushes the metrics
+	// to a Pushgateway.
+	modeServe = "serve"
+	modePush  = "push"
+)
+
+// Config is the config for the prometheus output
+type Config struct {
+	Mode null.String `json:"mode" envconfig:"K6_PROMETHEUS_MODE"`
+	// The address of the /metrics endpoint in the serve mode.
+	Address null.String `json:"address" envconfig:"K6_PROMETHEUS_ADDRESS"`
+
+	// The Pushgateway of the push mode, the metrics are in the group of the job and the
+	// instance, which is deleted at the end of the test with DeleteOnStop.
+	PushgatewayURL null.String `json:"pushgatewayUrl" envconfig:"K6_PROMETHEUS_PUSHGATEWAY_URL"`
+	Job            null.String `json:"job" envconfig:"K6_PROMETHEUS_JOB"`
+	Instance       null.String `json:"instance" envconfig:"K6_PROMETHEUS_INSTANCE"`
+	Username       null.String `json:"username" envconfig:"K6_PROMETHEUS_USERNAME"`
+	Password       null.String `json:"password" envconfig:"K6_PROMETHEUS_PASSWORD"`
+	DeleteOnStop   null.Bool   `json:"deleteOnStop" envconfig:"K6_PROMETHEUS_DELETE_ON_STOP"`
+
+	Namespace     null.String        `json:"namespace" envconfig:"K6_PROMETHEUS_NAMESPACE"`
+	FlushInterval types.NullDuration `json:"flushInterval" envconfig:"K6_PROMETHEUS_FLUSH_INTERVAL"`
+	Timeout       types.NullDuration `json:"timeout" envconfig:"K6_PROMETHEUS_TIMEOUT"`
+	TagBlocklist  stats.TagSet       `json:"tagBlocklist" envconfig:"K6_PROMETHEUS_TAG_BLOCKLIST"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	return Config{
+		Mode:           null.StringFrom(modeServe),
+		Address:        null.StringFrom("localhost:5656"),
+		PushgatewayURL: null.StringFrom("http://localhost:9091"),
+		Job:            null.StringFrom("k6"),
+		Instance:       null.StringFrom(host),
+		DeleteOnStop:   null.BoolFrom(false),
+		Namespace:      null.StringFrom("k6_"),
+		FlushInterval:  types.NullDurationFrom(5 * time.Second),
+		Timeout:        types.NullDurationFrom(10 * time.Second),
+		TagBlocklist:   (stats.TagVU | stats.TagIter | stats.TagURL).Map(),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Mode.Valid {
+		c.Mode = cfg.Mode
+	}
+	if cfg.Address.Valid {
+		c.Address = cfg.Address
+	}
+	if cfg.PushgatewayURL.Valid {
+		c.PushgatewayURL = cfg.PushgatewayURL
+	}
+	if cfg.Job.Valid {
+		c.Job = cfg.Job
+	}
+	if cfg.Instance.Valid {
+		c.Instance = cfg.Instance
+	}
+	if cfg.Username.Valid {
+		c.Username = cfg.Username
+	}
+	if cfg.Password.Valid {
+		c.Password = cfg.Password
+	}
+	if cfg.DeleteOnStop.Valid {
+		c.DeleteOnStop = cfg.DeleteOnStop
+	}
+	if cfg.Namespace.Valid {
+		c.Namespace = cfg.Namespace
+	}
+	if cfg.FlushInterval.Valid {
+		c.FlushInterval = cfg.FlushInterval
+	}
+	if cfg.Timeout.Valid {
+		c.Timeout = cfg.Timeout
+	}
+	if cfg.TagBlocklist != nil {
+		c.TagBlocklist = cfg.TagBlocklist
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config, like in
+// --out prometheus=address=0.0.0.0:5656 or --out prometheus=mode=push,job=checkout, the
+// tagBlocklist key can be repeated. The basic auth password of the Pushgateway has to be in
+// K6_PROMETHEUS_PASSWORD instead, the command line is visible to the other users.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	for _, pair := range strings.Split(arg, ",") {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			return c, fmt.Errorf("couldn't parse %q as argument for prometheus output", arg)
+		}
+		switch r[0] {
+		case "mode":
+			c.Mode = null.StringFrom(r[1])
+		case "address":
+			c.Address = null.StringFrom(r[1])
+		case "pushgatewayUrl":
+			c.PushgatewayURL = null.StringFrom(r[1])
+		case "job":
+			c.Job = null.StringFrom(r[1])
+		case "instance":
+			c.Instance = null.StringFrom(r[1])
+		case "username":
+			c.Username = null.StringFrom(r[1])
+		case "deleteOnStop":
+			v, err := strconv.ParseBool(r[1])
+			if err != nil {
+				return c, fmt.Errorf("deleteOnStop value must be true or false, not %q", r[1])
+			}
+			c.DeleteOnStop = null.BoolFrom(v)
+		case "namespace":
+			c.Namespace = null.StringFrom(r[1])
+		case "flushInterval":
+			if err := c.FlushInterval.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "timeout":
+			if err := c.Timeout.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "tagBlocklist":
+			if c.TagBlocklist == nil {
+				c.TagBlocklist = make(stats.TagSet)
+			}
+			c.TagBlocklist[r[1]] = true
+		case "password":
+			return c, fmt.Errorf("the prometheus password can't be in the argument, use K6_PROMETHEUS_PASSWORD instead")
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for prometheus output", r[0])
+		}
+	}
+
+	return c, nil
+}
+
+// Validate returns an error if any config value is invalid.
+func (c Config) Validate() error {
+	switch c.Mode.String {
+	case modeServe:
+		if _, _, err := net.SplitHostPort(c.Address.String); err != nil {
+			return fmt.Errorf("invalid prometheus address %q: %w", c.Address.String, err)
+		}
+	case modePush:
+		u, err := url.Parse(c.PushgatewayURL.String)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid prometheus pushgatewayUrl %q", c.PushgatewayURL.String)
+		}
+		if c.Job.String == "" {
+			return fmt.Errorf("the prometheus push mode needs the job")
+		}
+	default:
+		return fmt.Errorf("invalid prometheus mode %q, it has to be %s or %s", c.Mode.String, modeServe, modePush)
+	}
+	if c.Namespace.String != "" && !isValidMetricName(c.Namespace.String) {
+		return fmt.Errorf("invalid prometheus namespace %q, it has to be a valid metric name prefix", c.Namespace.String)
+	}
+	if c.FlushInterval.Duration <= 0 {
+		return fmt.Errorf("the prometheus flushInterval should be positive, but it's %s", c.FlushInterval)
+	}
+	if c.Timeout.Duration <= 0 {
+		return fmt.Errorf("the prometheus timeout should be positive, but it's %s", c.Timeout)
+	}
+	return nil
+}
+
+// GetConsolidatedConfig combines {default config values + JSON config +
+// environment vars + arg config values}, and returns the final result.
+func GetConsolidatedConfig(jsonRawConf json.RawMessage, env map[string]string, arg string) (Config, error) {
+	result := NewConfig()
+	if jsonRawConf != nil {
+		jsonConf := Config{}
+		if err := json.Unmarshal(jsonRawConf, &jsonConf); err != nil {
+			return result, err
+		}
+		result = result.Apply(jsonConf)
+	}
+
+	envConfig := Config{}
+	if err := envconfig.Process("", &envConfig); err != nil {
+		// TODO: get rid of envconfig and actually use the env parameter...
+		return result, err
+	}
+	result = result.Apply(envConfig)
+
+	if arg != "" {
+		argConf, err := ParseArg(arg)
+		if err != nil {
+			return result, err
+		}
+		result = result.Apply(argConf)
+	}
+
+	return result, result.Validate()
+}
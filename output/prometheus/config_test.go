@@ -0,0 +1,104 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+func TestParseArg(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		config      Config
+		expectedErr bool
+	}{
+		"address=0.0.0.0:9090,namespace=loadtest_,tagBlocklist=name": {
+			config: Config{
+				Address:      null.StringFrom("0.0.0.0:9090"),
+				Namespace:    null.StringFrom("loadtest_"),
+				TagBlocklist: stats.TagSet{"name": true},
+			},
+		},
+		"mode=push,pushgatewayUrl=http://gateway:9091,job=checkout,instance=agent-1,deleteOnStop=true,flushInterval=1m": {
+			config: Config{
+				Mode:           null.StringFrom("push"),
+				PushgatewayURL: null.StringFrom("http://gateway:9091"),
+				Job:            null.StringFrom("checkout"),
+				Instance:       null.StringFrom("agent-1"),
+				DeleteOnStop:   null.BoolFrom(true),
+				FlushInterval:  types.NullDurationFrom(time.Minute),
+			},
+		},
+		"password=secret":     {expectedErr: true},
+		"deleteOnStop=always": {expectedErr: true},
+		"localhost:9090":      {expectedErr: true},
+		"foo=bar":             {expectedErr: true},
+		"timeout=never":       {expectedErr: true},
+	}
+
+	for arg, tc := range cases {
+		arg, tc := arg, tc
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+			config, err := ParseArg(arg)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.config, config)
+		})
+	}
+}
+
+func TestGetConsolidatedConfig(t *testing.T) {
+	t.Parallel()
+	config, err := GetConsolidatedConfig([]byte(`{"mode": "push", "username": "k6"}`), nil, "job=checkout")
+	require.NoError(t, err)
+	expected := NewConfig()
+	expected.Mode = null.StringFrom("push")
+	expected.Username = null.StringFrom("k6")
+	expected.Job = null.StringFrom("checkout")
+	assert.Equal(t, expected, config)
+
+	invalid := map[string]string{
+		`{"mode": "pull"}`:                           "",
+		`{"address": "localhost"}`:                   "",
+		`{"mode": "push", "pushgatewayUrl": "gw"}`:   "",
+		`{"mode": "push", "job": ""}`:                "",
+		`{"namespace": "k6-"}`:                       "",
+		`{"flushInterval": "0s"}`:                    "",
+		`{"mode": "push", "address": "localhost"}`:   "timeout=-1s",
+		`{"pushgatewayUrl": "ftp://gateway:9091/x"}`: "mode=push",
+	}
+	for conf, arg := range invalid {
+		_, err := GetConsolidatedConfig([]byte(conf), nil, arg)
+		assert.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,197 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package prometheus
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+// isValidMetricName returns whether the name can be the name of a Prometheus metric.
+func isValidMetricName(name string) bool {
+	for i, c := range name {
+		if !isNameChar(c, i == 0) && c != ':' {
+			return false
+		}
+	}
+	return name != ""
+}
+
+func isNameChar(c rune, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+// sanitizeName replaces the characters that aren't allowed in the names of the metrics and the
+// labels with underscores.
+func sanitizeName(name string) string {
+	runes := []rune(name)
+	for i, c := range runes {
+		if !isNameChar(c, false) {
+			runes[i] = '_'
+		}
+	}
+	if len(runes) == 0 || (runes[0] >= '0' && runes[0] <= '9') {
+		return "_" + string(runes)
+	}
+	return string(runes)
+}
+
+type labelPair struct {
+	name, value string
+}
+
+// tagLabels returns the labels of the tags of a series, sorted by their names.
+func tagLabels(tags map[string]string) []labelPair {
+	labels := make([]labelPair, 0, len(tags))
+	for tag, value := range tags {
+		name := sanitizeName(tag)
+		if strings.HasPrefix(name, "__") || name == "le" {
+			// the labels starting with __ are reserved, and le is the one of the buckets
+			name = "k6_" + strings.TrimLeft(name, "_")
+		}
+		labels = append(labels, labelPair{name: name, value: value})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+	return labels
+}
+
+// registry aggregates the samples since the start of the test in the series of the exposition,
+// the trends and the histograms are Prometheus histograms with the buckets of the Histogram
+// metrics, or the default ones. It isn't safe for concurrent use.
+type registry struct {
+	namespace  string
+	aggregator *output.Aggregator
+}
+
+func newRegistry(namespace string, blocklist stats.TagSet) *registry {
+	return &registry{namespace: namespace, aggregator: &output.Aggregator{
+		TagBlocklist: blocklist,
+		SinkFor: func(m *stats.Metric) stats.Sink {
+			if m.Type == stats.Trend || m.Type == stats.Histogram {
+				return stats.NewHistogramSink(m.Buckets)
+			}
+			return output.NewSink(m.Type)
+		},
+	}}
+}
+
+func (r *registry) add(sample stats.Sample) {
+	r.aggregator.Add(sample)
+}
+
+// family returns the name, the help and the type of the metric in the exposition.
+func (r *registry) family(m *stats.Metric) (name, help, typ string) {
+	name = r.namespace + sanitizeName(m.Name)
+	help = "The k6 " + m.Type.String() + " metric " + m.Name
+	switch m.Type {
+	case stats.Counter, stats.Throughput:
+		return name + "_total", help, "counter"
+	case stats.Trend, stats.Histogram:
+		return name, help, "histogram"
+	default:
+		return name, help, "gauge"
+	}
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`) //nolint:gochecknoglobals
+
+// writeSample writes a line of the exposition, the extra label is the le of the buckets.
+func writeSample(w *bufio.Writer, name string, labels []labelPair, extra *labelPair, value float64) {
+	_, _ = w.WriteString(name)
+	if len(labels) > 0 || extra != nil {
+		_ = w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				_ = w.WriteByte(',')
+			}
+			_, _ = w.WriteString(l.name + `="` + labelValueEscaper.Replace(l.value) + `"`)
+		}
+		if extra != nil {
+			if len(labels) > 0 {
+				_ = w.WriteByte(',')
+			}
+			_, _ = w.WriteString(extra.name + `="` + extra.value + `"`)
+		}
+		_ = w.WriteByte('}')
+	}
+	_, _ = w.WriteString(" " + formatValue(value) + "\n")
+}
+
+// write writes the metrics in the text exposition format, see
+// https://prometheus.io/docs/instrumenting/exposition_formats/
+func (r *registry) write(out io.Writer) error {
+	// the series are sorted by the names of the k6 metrics, the metrics of the exposition are
+	// sorted by their own names
+	series := r.aggregator.Series()
+	sort.SliceStable(series, func(i, j int) bool {
+		nameI, _, _ := r.family(series[i].Metric)
+		nameJ, _, _ := r.family(series[j].Metric)
+		return nameI < nameJ
+	})
+
+	w := bufio.NewWriter(out)
+	for i, s := range series {
+		name, help, typ := r.family(s.Metric)
+		if i == 0 || series[i-1].Metric.Name != s.Metric.Name {
+			_, _ = w.WriteString("# HELP " + name + " " + help + "\n# TYPE " + name + " " + typ + "\n")
+		}
+		labels := tagLabels(s.Tags)
+		switch sink := s.Sink.(type) {
+		case *stats.CounterSink:
+			writeSample(w, name, labels, nil, sink.Value)
+		case *stats.GaugeSink:
+			writeSample(w, name, labels, nil, sink.Value)
+		case *stats.RateSink:
+			writeSample(w, name, labels, nil, float64(sink.Trues)/float64(sink.Total))
+		case *stats.HistogramSink:
+			// the buckets of the exposition are cumulative
+			var cumulative uint64
+			for j, bound := range sink.Buckets {
+				cumulative += sink.Counts[j]
+				writeSample(w, name+"_bucket", labels, &labelPair{"le", formatValue(bound)}, float64(cumulative))
+			}
+			writeSample(w, name+"_bucket", labels, &labelPair{"le", "+Inf"}, float64(sink.Count))
+			writeSample(w, name+"_sum", labels, nil, sink.Sum)
+			writeSample(w, name+"_count", labels, nil, float64(sink.Count))
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,117 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package prometheus
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/stats"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	t.Parallel()
+	r := newRegistry("k6_", stats.TagSet{"vu": true})
+	reqs := stats.New("http_reqs", stats.Counter)
+	vus := stats.New("vus", stats.Gauge)
+	checks := stats.New("checks", stats.Rate)
+	duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+	sizes := stats.New("my.sizes", stats.Histogram)
+	sizes.Buckets = []float64{10, 100}
+	events := stats.New("events", stats.Throughput)
+
+	get := stats.NewSampleTags(map[string]string{"method": "GET", "vu": "1", "le": "x", "__name": "y"})
+	post := stats.NewSampleTags(map[string]string{"method": "POST", "name": "a \"quoted\"\nname"})
+	now := time.Now()
+	for _, sample := range []stats.Sample{
+		{Metric: reqs, Value: 1, Tags: get},
+		{Metric: reqs, Value: 1, Tags: get},
+		{Metric: reqs, Value: 1, Tags: post},
+		{Metric: vus, Value: 3},
+		{Metric: vus, Value: 5},
+		{Metric: checks, Value: 1},
+		{Metric: checks, Value: 0},
+		{Metric: checks, Value: 1},
+		{Metric: checks, Value: 1},
+		{Metric: duration, Value: 7},
+		{Metric: duration, Value: 120},
+		{Metric: sizes, Value: 5},
+		{Metric: sizes, Value: 500},
+		{Metric: events, Value: 2},
+		{Metric: events, Value: 3},
+	} {
+		sample.Time = now
+		r.add(sample)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, r.write(&buf))
+	assert.Equal(t, `# HELP k6_checks The k6 rate metric checks
+# TYPE k6_checks gauge
+k6_checks 0.75
+# HELP k6_events_total The k6 throughput metric events
+# TYPE k6_events_total counter
+k6_events_total 5
+# HELP k6_http_req_duration The k6 trend metric http_req_duration
+# TYPE k6_http_req_duration histogram
+k6_http_req_duration_bucket{le="5"} 0
+k6_http_req_duration_bucket{le="10"} 1
+k6_http_req_duration_bucket{le="25"} 1
+k6_http_req_duration_bucket{le="50"} 1
+k6_http_req_duration_bucket{le="100"} 1
+k6_http_req_duration_bucket{le="250"} 2
+k6_http_req_duration_bucket{le="500"} 2
+k6_http_req_duration_bucket{le="1000"} 2
+k6_http_req_duration_bucket{le="2500"} 2
+k6_http_req_duration_bucket{le="5000"} 2
+k6_http_req_duration_bucket{le="10000"} 2
+k6_http_req_duration_bucket{le="+Inf"} 2
+k6_http_req_duration_sum 127
+k6_http_req_duration_count 2
+# HELP k6_http_reqs_total The k6 counter metric http_reqs
+# TYPE k6_http_reqs_total counter
+k6_http_reqs_total{k6_le="x",k6_name="y",method="GET"} 2
+k6_http_reqs_total{method="POST",name="a \"quoted\"\nname"} 1
+# HELP k6_my_sizes The k6 histogram metric my.sizes
+# TYPE k6_my_sizes histogram
+k6_my_sizes_bucket{le="10"} 1
+k6_my_sizes_bucket{le="100"} 1
+k6_my_sizes_bucket{le="+Inf"} 2
+k6_my_sizes_sum 505
+k6_my_sizes_count 2
+# HELP k6_vus The k6 gauge metric vus
+# TYPE k6_vus gauge
+k6_vus 5
+`, buf.String())
+}
+
+func TestSanitizeName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "http_req_duration", sanitizeName("http_req_duration"))
+	assert.Equal(t, "my_metric_name", sanitizeName("my.metric-name"))
+	assert.Equal(t, "_1st", sanitizeName("1st"))
+	assert.True(t, isValidMetricName("k6:"))
+	assert.False(t, isValidMetricName("1k6_"))
+}
@@ -0,0 +1,214 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/output"
+)
+
+// The content type of the text exposition format.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Output aggregates the metrics since the start of the test and exposes them in the Prometheus
+// text format, on a /metrics endpoint for the scraping, or by pushing them to a Pushgateway
+// after each flush.
+type Output struct {
+	output.SampleBuffer
+
+	config          Config
+	logger          logrus.FieldLogger
+	client          *http.Client
+	periodicFlusher *output.PeriodicFlusher
+	server          *http.Server
+
+	mutex    sync.Mutex
+	registry *registry
+}
+
+var _ output.Output = new(Output)
+
+// New returns a new prometheus output
+func New(params output.Params) (output.Output, error) {
+	return newOutput(params)
+}
+
+func newOutput(params output.Params) (*Output, error) {
+	conf, err := GetConsolidatedConfig(params.JSONConfig, params.Environment, params.ConfigArgument)
+	if err != nil {
+		return nil, err
+	}
+	return &Output{
+		config:   conf,
+		logger:   params.Logger.WithFields(logrus.Fields{"output": "prometheus"}),
+		client:   &http.Client{Timeout: time.Duration(conf.Timeout.Duration)},
+		registry: newRegistry(conf.Namespace.String, conf.TagBlocklist),
+	}, nil
+}
+
+// Description returns a human-readable description of the output.
+func (o *Output) Description() string {
+	if o.config.Mode.String == modePush {
+		return fmt.Sprintf("prometheus (push to %s)", o.config.PushgatewayURL.String)
+	}
+	return fmt.Sprintf("prometheus (http://%s/metrics)", o.config.Address.String)
+}
+
+// Start starts the HTTP server of the /metrics endpoint in the serve mode, and the goroutine
+// for the flushing of the metrics.
+func (o *Output) Start() error {
+	o.logger.Debug("Starting...")
+	if o.config.Mode.String == modeServe {
+		listener, err := net.Listen("tcp", o.config.Address.String)
+		if err != nil {
+			return fmt.Errorf("couldn't start the prometheus metrics server: %w", err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/metrics", o.handleMetrics)
+		o.server = &http.Server{Handler: mux}
+		go func() {
+			if err := o.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+				o.logger.WithError(err).Error("Prometheus metrics server error")
+			}
+		}()
+		o.logger.Infof("The metrics are available for the scraping at http://%s/metrics", listener.Addr())
+	}
+
+	pf, err := output.NewDrainingPeriodicFlusher(
+		time.Duration(o.config.FlushInterval.Duration), o.flushMetrics, o.HasSpilledSamples)
+	if err != nil {
+		return err
+	}
+	o.logger.Debug("Started!")
+	o.periodicFlusher = pf
+	return nil
+}
+
+// Stop flushes any remaining metrics and stops the goroutine, then it deletes the group of the
+// Pushgateway with deleteOnStop, or it shuts down the HTTP server.
+func (o *Output) Stop() error {
+	o.logger.Debug("Stopping...")
+	defer o.logger.Debug("Stopped!")
+	o.periodicFlusher.Stop()
+
+	if o.config.Mode.String == modePush {
+		if o.config.DeleteOnStop.Bool {
+			return o.request(http.MethodDelete, nil)
+		}
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return o.server.Shutdown(ctx)
+}
+
+func (o *Output) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	var buf bytes.Buffer
+	o.mutex.Lock()
+	err := o.registry.write(&buf)
+	o.mutex.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (o *Output) flushMetrics() {
+	samples := o.GetBufferedSamples()
+	if len(samples) == 0 {
+		return
+	}
+	var buf bytes.Buffer
+	o.mutex.Lock()
+	for _, sc := range samples {
+		for _, sample := range sc.GetSamples() {
+			o.registry.add(sample)
+		}
+	}
+	if o.config.Mode.String == modePush {
+		_ = o.registry.write(&buf)
+	}
+	o.mutex.Unlock()
+
+	if o.config.Mode.String == modePush {
+		// the whole group is replaced, since all of the values are since the start of the test
+		if err := o.request(http.MethodPut, buf.Bytes()); err != nil {
+			o.logger.WithError(err).Error("Couldn't push the metrics to the Pushgateway")
+		}
+	}
+}
+
+// groupLabel returns a label of the path of the group, the values with slashes are encoded in
+// base64, see https://github.com/prometheus/pushgateway#url
+func groupLabel(name, value string) string {
+	if value == "" || strings.Contains(value, "/") {
+		return name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return name + "/" + url.PathEscape(value)
+}
+
+func (o *Output) groupURL() string {
+	u := strings.TrimSuffix(o.config.PushgatewayURL.String, "/") + "/metrics/" + groupLabel("job", o.config.Job.String)
+	if o.config.Instance.String != "" {
+		u += "/" + groupLabel("instance", o.config.Instance.String)
+	}
+	return u
+}
+
+// request sends a request for the group of the Pushgateway.
+func (o *Output) request(method string, body []byte) error {
+	req, err := http.NewRequest(method, o.groupURL(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if o.config.Username.String != "" {
+		req.SetBasicAuth(o.config.Username.String, o.config.Password.String)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
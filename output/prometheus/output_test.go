@@ -0,0 +1,104 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package prometheus
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+func TestOutputServe(t *testing.T) {
+	t.Parallel()
+	o, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		ConfigArgument: "address=127.0.0.1:0,flushInterval=1h",
+	})
+	require.NoError(t, err)
+	require.NoError(t, o.Start())
+
+	o.AddMetricSamples([]stats.SampleContainer{stats.Sample{
+		Metric: stats.New("vus", stats.Gauge), Value: 5, Time: time.Now(),
+	}})
+	o.flushMetrics()
+
+	rec := httptest.NewRecorder()
+	o.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, contentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "# HELP k6_vus The k6 gauge metric vus\n# TYPE k6_vus gauge\nk6_vus 5\n", rec.Body.String())
+	require.NoError(t, o.Stop())
+}
+
+func TestOutputPush(t *testing.T) {
+	t.Parallel()
+	type request struct {
+		method, path, body, user, password string
+	}
+	var (
+		mu       sync.Mutex
+		requests []request
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		user, password, _ := r.BasicAuth()
+		mu.Lock()
+		requests = append(requests, request{r.Method, r.URL.EscapedPath(), string(body), user, password})
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	o, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		JSONConfig:     []byte(`{"pushgatewayUrl": "` + srv.URL + `/", "username": "k6", "password": "secret"}`),
+		ConfigArgument: "mode=push,job=checkout,instance=site/1,deleteOnStop=true,flushInterval=1h",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "prometheus (push to "+srv.URL+"/)", o.Description())
+	require.NoError(t, o.Start())
+
+	reqs := stats.New("http_reqs", stats.Counter)
+	o.AddMetricSamples([]stats.SampleContainer{stats.Sample{Metric: reqs, Value: 1, Time: time.Now()}})
+	o.flushMetrics()
+	o.AddMetricSamples([]stats.SampleContainer{stats.Sample{Metric: reqs, Value: 2, Time: time.Now()}})
+	require.NoError(t, o.Stop())
+
+	mu.Lock()
+	defer mu.Unlock()
+	path := "/metrics/job/checkout/instance@base64/c2l0ZS8x"
+	header := "# HELP k6_http_reqs_total The k6 counter metric http_reqs\n# TYPE k6_http_reqs_total counter\n"
+	assert.Equal(t, []request{
+		{http.MethodPut, path, header + "k6_http_reqs_total 1\n", "k6", "secret"},
+		{http.MethodPut, path, header + "k6_http_reqs_total 3\n", "k6", "secret"},
+		{http.MethodDelete, path, "", "k6", "secret"},
+	}, requests)
+}
@@ -0,0 +1,246 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"go.k6.io/k6/stats"
+)
+
+// SpillFile is a FIFO queue of lines in a temporary file, for keeping on the disk what an output
+// can't send yet. The queue is bounded by the size of its lines, the file is created on the first
+// push, compacted once the popped lines take at least half of it, and removed when the queue is
+// empty again. It isn't safe for concurrent use.
+type SpillFile struct {
+	dir, pattern string
+	maxBytes     int64
+
+	file                    *os.File
+	readOffset, writeOffset int64
+	lines                   int
+}
+
+// NewSpillFile returns an empty queue, with its file in dir, or in the default directory for
+// temporary files if it's empty, and named by pattern, like the one of ioutil.TempFile(). 0
+// maxBytes means that the queue is unlimited.
+func NewSpillFile(dir, pattern string, maxBytes int64) *SpillFile {
+	return &SpillFile{dir: dir, pattern: pattern, maxBytes: maxBytes}
+}
+
+// Push appends the lines, which can't contain newlines, until the next one doesn't fit in the
+// queue. It returns how many of them were appended.
+func (f *SpillFile) Push(lines ...[]byte) (int, error) {
+	var buf bytes.Buffer
+	pushed := 0
+	for _, line := range lines {
+		if f.maxBytes > 0 && f.Size()+int64(buf.Len()+len(line)+1) > f.maxBytes {
+			break
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		pushed++
+	}
+	if pushed == 0 {
+		return 0, nil
+	}
+	if f.file == nil {
+		file, err := ioutil.TempFile(f.dir, f.pattern)
+		if err != nil {
+			return 0, err
+		}
+		f.file = file
+	}
+	if _, err := f.file.WriteAt(buf.Bytes(), f.writeOffset); err != nil {
+		return 0, err
+	}
+	f.writeOffset += int64(buf.Len())
+	f.lines += pushed
+	return pushed, nil
+}
+
+// Pop calls fn with up to n of the oldest lines, or with all of them if n isn't positive, without
+// their newlines. A line is only removed from the queue when fn returns nil for it, the first
+// error of fn stops the pop and is returned. It returns how many lines were removed.
+func (f *SpillFile) Pop(n int, fn func(line []byte) error) (int, error) {
+	if f.lines == 0 {
+		return 0, nil
+	}
+	reader := bufio.NewReader(io.NewSectionReader(f.file, f.readOffset, f.Size()))
+	popped := 0
+	for (n <= 0 || popped < n) && f.lines > 0 {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return popped, err
+		}
+		if err = fn(line[:len(line)-1]); err != nil {
+			return popped, err
+		}
+		f.readOffset += int64(len(line))
+		f.lines--
+		popped++
+	}
+	if f.lines == 0 {
+		return popped, f.Close()
+	}
+	return popped, f.compact()
+}
+
+// compact moves the lines of the queue to the start of the file and truncates it, once the popped
+// lines take at least as much of it as the ones in the queue, so the file of a queue that's never
+// empty doesn't grow forever.
+func (f *SpillFile) compact() error {
+	size := f.Size()
+	if f.readOffset < size {
+		return nil
+	}
+	// the lines are after their new place, so each chunk is read before it's overwritten
+	buf := make([]byte, 64*1024)
+	for copied := int64(0); copied < size; {
+		n, err := f.file.ReadAt(buf[:minInt64(int64(len(buf)), size-copied)], f.readOffset+copied)
+		if err != nil {
+			return err
+		}
+		if _, err = f.file.WriteAt(buf[:n], copied); err != nil {
+			return err
+		}
+		copied += int64(n)
+	}
+	f.readOffset, f.writeOffset = 0, size
+	return f.file.Truncate(size)
+}
+
+// Len returns the number of the lines in the queue.
+func (f *SpillFile) Len() int {
+	return f.lines
+}
+
+// Size returns the size of the lines in the queue, with their newlines.
+func (f *SpillFile) Size() int64 {
+	return f.writeOffset - f.readOffset
+}
+
+// Close removes the file of the queue and discards the lines in it.
+func (f *SpillFile) Close() error {
+	f.readOffset, f.writeOffset, f.lines = 0, 0, 0
+	if f.file == nil {
+		return nil
+	}
+	name := f.file.Name()
+	err := f.file.Close()
+	f.file = nil
+	if removeErr := os.Remove(name); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// spilledSample is a sample in the on-disk queue, its metric is restored by its name.
+type spilledSample struct {
+	Metric   string            `json:"metric"`
+	Time     time.Time         `json:"time"`
+	Value    float64           `json:"value"`
+	Tags     *stats.SampleTags `json:"tags,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// spillQueue is the on-disk queue of the samples of a SampleBuffer, with a line of JSON for each
+// sample.
+type spillQueue struct {
+	file    *SpillFile
+	metrics map[string]*stats.Metric
+}
+
+func newSpillQueue(dir string, maxBytes int64) *spillQueue {
+	return &spillQueue{
+		file:    NewSpillFile(dir, "k6-samples-*.ndjson", maxBytes),
+		metrics: make(map[string]*stats.Metric),
+	}
+}
+
+// push appends the samples to the queue until it's full, it returns how many of them were
+// appended.
+func (q *spillQueue) push(samples []stats.Sample) (int, error) {
+	lines := make([][]byte, len(samples))
+	for i, sample := range samples {
+		line, err := json.Marshal(spilledSample{
+			Metric:   sample.Metric.Name,
+			Time:     sample.Time,
+			Value:    sample.Value,
+			Tags:     sample.Tags,
+			Metadata: sample.Metadata,
+		})
+		if err != nil {
+			return 0, err
+		}
+		lines[i] = line
+	}
+	pushed, err := q.file.Push(lines...)
+	for _, sample := range samples[:pushed] {
+		q.metrics[sample.Metric.Name] = sample.Metric
+	}
+	return pushed, err
+}
+
+// pop returns up to n of the oldest samples of the queue.
+func (q *spillQueue) pop(n int) ([]stats.Sample, error) {
+	samples := make([]stats.Sample, 0, n)
+	_, err := q.file.Pop(n, func(line []byte) error {
+		var s spilledSample
+		if err := json.Unmarshal(line, &s); err != nil {
+			return err
+		}
+		samples = append(samples, stats.Sample{
+			Metric:   q.metrics[s.Metric],
+			Time:     s.Time,
+			Value:    s.Value,
+			Tags:     s.Tags,
+			Metadata: s.Metadata,
+		})
+		return nil
+	})
+	return samples, err
+}
+
+func (q *spillQueue) pending() int {
+	return q.file.Len()
+}
+
+func (q *spillQueue) size() int64 {
+	return q.file.Size()
+}
+
+func (q *spillQueue) close() error {
+	return q.file.Close()
+}
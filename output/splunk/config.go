@@ -0,0 +1,263 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package splunk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+const (
+	// formatEvent sends each sample as an event, formatMetric sends them as the metric events of
+	// the metrics indexes.
+	formatEvent  = "event"
+	formatMetric = "metric"
+)
+
+// Config is the config for the splunk output
+type Config struct {
+	URL      null.String `json:"url" envconfig:"K6_SPLUNK_URL"`
+	Token    null.String `json:"token" envconfig:"K6_SPLUNK_TOKEN"`
+	Insecure null.Bool   `json:"insecure" envconfig:"K6_SPLUNK_INSECURE"`
+
+	// The metadata of the events, the empty index and host are the defaults of the token and the
+	// host of k6.
+	Index      null.String `json:"index" envconfig:"K6_SPLUNK_INDEX"`
+	Host       null.String `json:"host" envconfig:"K6_SPLUNK_HOST"`
+	Source     null.String `json:"source" envconfig:"K6_SPLUNK_SOURCE"`
+	SourceType null.String `json:"sourceType" envconfig:"K6_SPLUNK_SOURCE_TYPE"`
+	Format     null.String `json:"format" envconfig:"K6_SPLUNK_FORMAT"`
+
+	// The k6 logs are sent as the events of their own source type when Logs is enabled.
+	Logs           null.Bool   `json:"logs" envconfig:"K6_SPLUNK_LOGS"`
+	LogsLevel      null.String `json:"logsLevel" envconfig:"K6_SPLUNK_LOGS_LEVEL"`
+	LogsSourceType null.String `json:"logsSourceType" envconfig:"K6_SPLUNK_LOGS_SOURCE_TYPE"`
+
+	BatchSize     null.Int           `json:"batchSize" envconfig:"K6_SPLUNK_BATCH_SIZE"`
+	FlushInterval types.NullDuration `json:"flushInterval" envconfig:"K6_SPLUNK_FLUSH_INTERVAL"`
+	Timeout       types.NullDuration `json:"timeout" envconfig:"K6_SPLUNK_TIMEOUT"`
+	TagBlocklist  stats.TagSet       `json:"tagBlocklist" envconfig:"K6_SPLUNK_TAG_BLOCKLIST"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	host, _ := os.Hostname()
+	return Config{
+		URL:            null.StringFrom("https://localhost:8088"),
+		Insecure:       null.BoolFrom(false),
+		Host:           null.StringFrom(host),
+		Source:         null.StringFrom("k6"),
+		SourceType:     null.StringFrom("k6:metrics"),
+		Format:         null.StringFrom(formatEvent),
+		Logs:           null.BoolFrom(false),
+		LogsLevel:      null.StringFrom("info"),
+		LogsSourceType: null.StringFrom("k6:logs"),
+		BatchSize:      null.IntFrom(1000),
+		FlushInterval:  types.NullDurationFrom(time.Second),
+		Timeout:        types.NullDurationFrom(10 * time.Second),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.URL.Valid {
+		c.URL = cfg.URL
+	}
+	if cfg.Token.Valid {
+		c.Token = cfg.Token
+	}
+	if cfg.Insecure.Valid {
+		c.Insecure = cfg.Insecure
+	}
+	if cfg.Index.Valid {
+		c.Index = cfg.Index
+	}
+	if cfg.Host.Valid {
+		c.Host = cfg.Host
+	}
+	if cfg.Source.Valid {
+		c.Source = cfg.Source
+	}
+	if cfg.SourceType.Valid {
+		c.SourceType = cfg.SourceType
+	}
+	if cfg.Format.Valid {
+		c.Format = cfg.Format
+	}
+	if cfg.Logs.Valid {
+		c.Logs = cfg.Logs
+	}
+	if cfg.LogsLevel.Valid {
+		c.LogsLevel = cfg.LogsLevel
+	}
+	if cfg.LogsSourceType.Valid {
+		c.LogsSourceType = cfg.LogsSourceType
+	}
+	if cfg.BatchSize.Valid {
+		c.BatchSize = cfg.BatchSize
+	}
+	if cfg.FlushInterval.Valid {
+		c.FlushInterval = cfg.FlushInterval
+	}
+	if cfg.Timeout.Valid {
+		c.Timeout = cfg.Timeout
+	}
+	if cfg.TagBlocklist != nil {
+		c.TagBlocklist = cfg.TagBlocklist
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config, like in
+// --out splunk=url=https://splunk:8088,index=k6,logs=true, the tagBlocklist key can be repeated.
+// The HEC token is kept out of it, since the arguments end up in the shell history.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	for _, pair := range strings.Split(arg, ",") {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			return c, fmt.Errorf("couldn't parse %q as argument for splunk output", arg)
+		}
+		switch r[0] {
+		case "url":
+			c.URL = null.StringFrom(r[1])
+		case "insecure":
+			v, err := strconv.ParseBool(r[1])
+			if err != nil {
+				return c, fmt.Errorf("insecure value must be true or false, not %q", r[1])
+			}
+			c.Insecure = null.BoolFrom(v)
+		case "index":
+			c.Index = null.StringFrom(r[1])
+		case "host":
+			c.Host = null.StringFrom(r[1])
+		case "source":
+			c.Source = null.StringFrom(r[1])
+		case "sourceType":
+			c.SourceType = null.StringFrom(r[1])
+		case "format":
+			c.Format = null.StringFrom(r[1])
+		case "logs":
+			v, err := strconv.ParseBool(r[1])
+			if err != nil {
+				return c, fmt.Errorf("logs value must be true or false, not %q", r[1])
+			}
+			c.Logs = null.BoolFrom(v)
+		case "logsLevel":
+			c.LogsLevel = null.StringFrom(r[1])
+		case "logsSourceType":
+			c.LogsSourceType = null.StringFrom(r[1])
+		case "batchSize":
+			v, err := strconv.ParseInt(r[1], 10, 64)
+			if err != nil {
+				return c, fmt.Errorf("batchSize value must be a number, not %q", r[1])
+			}
+			c.BatchSize = null.IntFrom(v)
+		case "flushInterval":
+			if err := c.FlushInterval.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "timeout":
+			if err := c.Timeout.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "tagBlocklist":
+			if c.TagBlocklist == nil {
+				c.TagBlocklist = make(stats.TagSet)
+			}
+			c.TagBlocklist[r[1]] = true
+		case "token":
+			return c, fmt.Errorf("the splunk token can't be in the argument, use K6_SPLUNK_TOKEN instead")
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for splunk output", r[0])
+		}
+	}
+
+	return c, nil
+}
+
+// Validate returns an error if any config value is invalid.
+func (c Config) Validate() error {
+	if c.Token.String == "" {
+		return fmt.Errorf("the splunk output needs the HEC token, set it with K6_SPLUNK_TOKEN")
+	}
+	if c.URL.String == "" {
+		return fmt.Errorf("the splunk output needs the url of the HTTP Event Collector")
+	}
+	if c.Format.String != formatEvent && c.Format.String != formatMetric {
+		return fmt.Errorf("invalid splunk format %q, it has to be %s or %s", c.Format.String, formatEvent, formatMetric)
+	}
+	if _, err := logrus.ParseLevel(c.LogsLevel.String); err != nil {
+		return fmt.Errorf("invalid splunk logsLevel: %w", err)
+	}
+	if c.BatchSize.Int64 <= 0 {
+		return fmt.Errorf("the splunk batchSize should be positive, but it's %d", c.BatchSize.Int64)
+	}
+	if c.FlushInterval.Duration <= 0 {
+		return fmt.Errorf("the splunk flushInterval should be positive, but it's %s", c.FlushInterval)
+	}
+	if c.Timeout.Duration <= 0 {
+		return fmt.Errorf("the splunk timeout should be positive, but it's %s", c.Timeout)
+	}
+	return nil
+}
+
+// GetConsolidatedConfig combines {default config values + JSON config +
+// environment vars + arg config values}, and returns the final result.
+func GetConsolidatedConfig(jsonRawConf json.RawMessage, env map[string]string, arg string) (Config, error) {
+	result := NewConfig()
+	if jsonRawConf != nil {
+		jsonConf := Config{}
+		if err := json.Unmarshal(jsonRawConf, &jsonConf); err != nil {
+			return result, err
+		}
+		result = result.Apply(jsonConf)
+	}
+
+	envConfig := Config{}
+	if err := envconfig.Process("", &envConfig); err != nil {
+		// TODO: get rid of envconfig and actually use the env parameter...
+		return result, err
+	}
+	result = result.Apply(envConfig)
+
+	if arg != "" {
+		argConf, err := ParseArg(arg)
+		if err != nil {
+			return result, err
+		}
+		result = result.Apply(argConf)
+	}
+
+	return result, result.Validate()
+}
@@ -0,0 +1,102 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package splunk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+	"go.k6.io/k6/stats"
+)
+
+func TestParseArg(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		config      Config
+		expectedErr bool
+	}{
+		"url=https://splunk:8088,index=k6,sourceType=k6:test,batchSize=100": {
+			config: Config{
+				URL:        null.StringFrom("https://splunk:8088"),
+				Index:      null.StringFrom("k6"),
+				SourceType: null.StringFrom("k6:test"),
+				BatchSize:  null.IntFrom(100),
+			},
+		},
+		"logs=true,logsLevel=warning,insecure=true,format=metric,tagBlocklist=vu,flushInterval=5s": {
+			config: Config{
+				Logs:          null.BoolFrom(true),
+				LogsLevel:     null.StringFrom("warning"),
+				Insecure:      null.BoolFrom(true),
+				Format:        null.StringFrom("metric"),
+				TagBlocklist:  stats.TagSet{"vu": true},
+				FlushInterval: types.NullDurationFrom(5 * time.Second),
+			},
+		},
+		"token=secret":   {expectedErr: true},
+		"logs=maybe":     {expectedErr: true},
+		"batchSize=many": {expectedErr: true},
+		"splunk:8088":    {expectedErr: true},
+		"foo=bar":        {expectedErr: true},
+	}
+
+	for arg, tc := range cases {
+		arg, tc := arg, tc
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+			config, err := ParseArg(arg)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.config, config)
+		})
+	}
+}
+
+func TestGetConsolidatedConfig(t *testing.T) {
+	t.Parallel()
+	config, err := GetConsolidatedConfig([]byte(`{"token": "secret", "index": "loadtests"}`), nil, "logs=true")
+	require.NoError(t, err)
+	expected := NewConfig()
+	expected.Token = null.StringFrom("secret")
+	expected.Index = null.StringFrom("loadtests")
+	expected.Logs = null.BoolFrom(true)
+	assert.Equal(t, expected, config)
+
+	invalid := map[string]string{
+		`{}`:                                   "",
+		`{"token": "secret", "format": "raw"}`: "",
+		`{"token": "secret", "logsLevel": "loud"}`: "",
+		`{"token": "secret"}`:                      "batchSize=0",
+		`{"token": "secret", "url": ""}`:           "",
+	}
+	for conf, arg := range invalid {
+		_, err := GetConsolidatedConfig([]byte(conf), nil, arg)
+		assert.Error(t, err, conf)
+	}
+}
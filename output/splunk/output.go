@@ -0,0 +1,276 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package splunk
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+// The logs are dropped when more than maxBufferedLogs are waiting for the flushing, so that a
+// script that logs too much doesn't use all of the memory.
+const maxBufferedLogs = 10000
+
+// Output sends the samples to the Splunk HTTP Event Collector, as events with the metric, the
+// value and the tags of each sample, or as the metric events of the metrics indexes. It can also
+// send the logs of k6 as events of their own source type.
+type Output struct {
+	output.SampleBuffer
+
+	config          Config
+	logger          logrus.FieldLogger
+	client          *http.Client
+	periodicFlusher *output.PeriodicFlusher
+	logs            *output.LogCollector
+}
+
+var _ output.Output = new(Output)
+
+// New returns a new splunk output
+func New(params output.Params) (output.Output, error) {
+	return newOutput(params)
+}
+
+func newOutput(params output.Params) (*Output, error) {
+	conf, err := GetConsolidatedConfig(params.JSONConfig, params.Environment, params.ConfigArgument)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: conf.Insecure.Bool} //nolint:gosec
+	// the levels are sorted from panic to trace
+	level, _ := logrus.ParseLevel(conf.LogsLevel.String)
+	return &Output{
+		config: conf,
+		logger: params.Logger.WithFields(logrus.Fields{"output": "splunk"}),
+		client: &http.Client{Transport: transport, Timeout: time.Duration(conf.Timeout.Duration)},
+		logs:   output.NewLogCollector(params.Logger, "splunk", logrus.AllLevels[:level+1], maxBufferedLogs),
+	}, nil
+}
+
+// Description returns a human-readable description of the output.
+func (o *Output) Description() string {
+	if o.config.Logs.Bool {
+		return fmt.Sprintf("splunk (%s, with the logs)", o.config.URL.String)
+	}
+	return fmt.Sprintf("splunk (%s)", o.config.URL.String)
+}
+
+// Start adds the hook of the logs, if they are enabled, and starts the goroutine for the
+// flushing of the events.
+func (o *Output) Start() error {
+	o.logger.Debug("Starting...")
+	if o.config.Logs.Bool && !o.logs.Start() {
+		o.logger.Warn("The logs can't be sent to Splunk, since the logger doesn't support hooks")
+	}
+	pf, err := output.NewDrainingPeriodicFlusher(
+		time.Duration(o.config.FlushInterval.Duration), o.flushMetrics, o.HasSpilledSamples)
+	if err != nil {
+		return err
+	}
+	o.logger.Debug("Started!")
+	o.periodicFlusher = pf
+	return nil
+}
+
+// Stop stops collecting the logs, since the hooks can't be removed from the logger, and
+// flushes any remaining events and stops the goroutine.
+func (o *Output) Stop() error {
+	o.logger.Debug("Stopping...")
+	defer o.logger.Debug("Stopped!")
+	o.logs.Stop()
+	o.periodicFlusher.Stop()
+	return nil
+}
+
+// event is an event of the HTTP Event Collector, see
+// https://docs.splunk.com/Documentation/Splunk/latest/Data/FormateventsforHTTPEventCollector
+type event struct {
+	Time       float64                `json:"time"`
+	Host       string                 `json:"host,omitempty"`
+	Index      string                 `json:"index,omitempty"`
+	Source     string                 `json:"source,omitempty"`
+	SourceType string                 `json:"sourcetype,omitempty"`
+	Event      interface{}            `json:"event"`
+	Fields     map[string]interface{} `json:"fields,omitempty"`
+}
+
+type sampleEvent struct {
+	Metric string            `json:"metric"`
+	Type   string            `json:"type"`
+	Value  float64           `json:"value"`
+	Tags   map[string]string `json:"tags,omitempty"`
+}
+
+type logEvent struct {
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// epoch returns the time of an event, the seconds since the epoch with milliseconds precision.
+func epoch(t time.Time) float64 {
+	return float64(t.UnixNano()/int64(time.Millisecond)) / 1000
+}
+
+func (o *Output) newEvent(t time.Time, sourceType string, e interface{}) event {
+	return event{
+		Time:       epoch(t),
+		Host:       o.config.Host.String,
+		Index:      o.config.Index.String,
+		Source:     o.config.Source.String,
+		SourceType: sourceType,
+		Event:      e,
+	}
+}
+
+func (o *Output) sampleEvent(sample stats.Sample) event {
+	tags := sample.Tags.CloneTags()
+	for tag := range tags {
+		if o.config.TagBlocklist[tag] {
+			delete(tags, tag)
+		}
+	}
+	if o.config.Format.String == formatMetric {
+		// the multiple-metric format, the tags are the dimensions of the metric
+		e := o.newEvent(sample.Time, o.config.SourceType.String, "metric")
+		e.Fields = make(map[string]interface{}, len(tags)+1)
+		for tag, value := range tags {
+			e.Fields[tag] = value
+		}
+		e.Fields["metric_name:"+sample.Metric.Name] = sample.Value
+		return e
+	}
+	return o.newEvent(sample.Time, o.config.SourceType.String, sampleEvent{
+		Metric: sample.Metric.Name,
+		Type:   sample.Metric.Type.String(),
+		Value:  sample.Value,
+		Tags:   tags,
+	})
+}
+
+func (o *Output) logEvent(entry *logrus.Entry) event {
+	fields := make(map[string]interface{}, len(entry.Data))
+	for k, v := range entry.Data {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		fields[k] = v
+	}
+	return o.newEvent(entry.Time, o.config.LogsSourceType.String, logEvent{
+		Level:   entry.Level.String(),
+		Message: entry.Message,
+		Fields:  fields,
+	})
+}
+
+func (o *Output) flushMetrics() {
+	samples := o.GetBufferedSamples()
+	logs, dropped := o.logs.Take()
+	if dropped > 0 {
+		o.logger.Warnf("Dropped %d logs, since more than %d were waiting for sending them to Splunk",
+			dropped, maxBufferedLogs)
+	}
+
+	events := make([]event, 0, len(logs))
+	for _, entry := range logs {
+		events = append(events, o.logEvent(entry))
+	}
+	for _, sc := range samples {
+		for _, sample := range sc.GetSamples() {
+			events = append(events, o.sampleEvent(sample))
+		}
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	failed := 0
+	batchSize := int(o.config.BatchSize.Int64)
+	for start := 0; start < len(events); start += batchSize {
+		end := start + batchSize
+		if end > len(events) {
+			end = len(events)
+		}
+		if err := o.send(events[start:end]); err != nil {
+			failed += end - start
+			o.logger.WithError(err).Debug("Couldn't send the events")
+		}
+	}
+	if failed > 0 {
+		o.logger.Warnf("Couldn't send %d out of %d events to Splunk, "+
+			"enable verbose logging with --verbose to see the errors", failed, len(events))
+	}
+}
+
+func (o *Output) eventsURL() string {
+	u := strings.TrimSuffix(o.config.URL.String, "/")
+	if strings.Contains(u, "/services/collector") {
+		return u
+	}
+	return u + "/services/collector/event"
+}
+
+// send sends a batch of events in a single request, the events are concatenated in its body.
+func (o *Output) send(events []event) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, e := range events {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(http.MethodPost, o.eventsURL(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+o.config.Token.String)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	var hecErr struct {
+		Text string `json:"text"`
+		Code int    `json:"code"`
+	}
+	if json.Unmarshal(respBody, &hecErr) == nil && hecErr.Text != "" {
+		return fmt.Errorf("unexpected status %d: %s (code %d)", resp.StatusCode, hecErr.Text, hecErr.Code)
+	}
+	return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
@@ -0,0 +1,175 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package splunk
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+// newTestServer returns a server of the HTTP Event Collector that collects the events of each
+// request.
+func newTestServer(t *testing.T) (*httptest.Server, func() [][]map[string]interface{}) {
+	var (
+		mu       sync.Mutex
+		requests [][]map[string]interface{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/services/collector/event", r.URL.Path)
+		if r.Header.Get("Authorization") != "Splunk token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"text": "Invalid token", "code": 4}`))
+			return
+		}
+		var events []map[string]interface{}
+		decoder := json.NewDecoder(r.Body)
+		for {
+			var e map[string]interface{}
+			err := decoder.Decode(&e)
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			events = append(events, e)
+		}
+		mu.Lock()
+		requests = append(requests, events)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"text": "Success", "code": 0}`))
+	}))
+	return srv, func() [][]map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func TestOutput(t *testing.T) {
+	t.Parallel()
+	srv, requests := newTestServer(t)
+	defer srv.Close()
+
+	o, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		JSONConfig:     []byte(`{"token": "token", "url": "` + srv.URL + `", "host": "k6-host", "index": "loadtests"}`),
+		ConfigArgument: "batchSize=2,flushInterval=1h,tagBlocklist=vu",
+	})
+	require.NoError(t, err)
+	require.NoError(t, o.Start())
+
+	reqs := stats.New("http_reqs", stats.Counter)
+	tags := stats.NewSampleTags(map[string]string{"method": "GET", "vu": "1"})
+	now := time.Unix(1614173820, 123456789)
+	o.AddMetricSamples([]stats.SampleContainer{stats.Samples{
+		{Metric: reqs, Value: 1, Time: now, Tags: tags},
+		{Metric: reqs, Value: 2, Time: now, Tags: tags},
+		{Metric: reqs, Value: 3, Time: now, Tags: tags},
+	}})
+	require.NoError(t, o.Stop())
+
+	batches := requests()
+	require.Len(t, batches, 2)
+	assert.Len(t, batches[0], 2)
+	require.Len(t, batches[1], 1)
+	assert.Equal(t, map[string]interface{}{
+		"time":       1614173820.123,
+		"host":       "k6-host",
+		"index":      "loadtests",
+		"source":     "k6",
+		"sourcetype": "k6:metrics",
+		"event": map[string]interface{}{
+			"metric": "http_reqs",
+			"type":   "counter",
+			"value":  float64(3),
+			"tags":   map[string]interface{}{"method": "GET"},
+		},
+	}, batches[1][0])
+}
+
+func TestOutputMetricFormatAndLogs(t *testing.T) {
+	t.Parallel()
+	srv, requests := newTestServer(t)
+	defer srv.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	o, err := newOutput(output.Params{
+		Logger:         logger,
+		JSONConfig:     []byte(`{"token": "token", "url": "` + srv.URL + `/"}`),
+		ConfigArgument: "format=metric,logs=true,logsLevel=warning,flushInterval=1h",
+	})
+	require.NoError(t, err)
+	require.NoError(t, o.Start())
+
+	logger.Info("not sent")
+	logger.WithField("source", "console").Warn("sent")
+	o.AddMetricSamples([]stats.SampleContainer{stats.Sample{
+		Metric: stats.New("vus", stats.Gauge),
+		Value:  5,
+		Time:   time.Unix(1614173820, 0),
+		Tags:   stats.NewSampleTags(map[string]string{"scenario": "default"}),
+	}})
+	require.NoError(t, o.Stop())
+	logger.Warn("not sent after the stop")
+
+	batches := requests()
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 2)
+	logEvent, metricEvent := batches[0][0], batches[0][1]
+	assert.Equal(t, "k6:logs", logEvent["sourcetype"])
+	assert.Equal(t, map[string]interface{}{
+		"level":   "warning",
+		"message": "sent",
+		"fields":  map[string]interface{}{"source": "console"},
+	}, logEvent["event"])
+	assert.Equal(t, "k6:metrics", metricEvent["sourcetype"])
+	assert.Equal(t, "metric", metricEvent["event"])
+	assert.Equal(t, map[string]interface{}{"metric_name:vus": float64(5), "scenario": "default"}, metricEvent["fields"])
+}
+
+func TestOutputInvalidToken(t *testing.T) {
+	t.Parallel()
+	srv, _ := newTestServer(t)
+	defer srv.Close()
+
+	o, err := newOutput(output.Params{
+		Logger:     testutils.NewLogger(t),
+		JSONConfig: []byte(`{"token": "wrong", "url": "` + srv.URL + `"}`),
+	})
+	require.NoError(t, err)
+	err = o.send([]event{o.newEvent(time.Now(), "k6:metrics", "test")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid token (code 4)")
+}
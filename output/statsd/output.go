@@ -70,8 +70,10 @@ func (o *Output) dispatch(entry stats.Sample) error {
 	}
 
 	switch entry.Metric.Type {
-	case stats.Counter:
+	case stats.Counter, stats.Throughput:
 		return o.client.Count(entry.Metric.Name, int64(entry.Value), tagList, 1)
+	case stats.Histogram:
+		return o.client.Histogram(entry.Metric.Name, entry.Value, tagList, 1)
 	case stats.Trend:
 		return o.client.TimeInMilliseconds(entry.Metric.Name, entry.Value, tagList, 1)
 	case stats.Gauge:
@@ -131,7 +133,8 @@ func (o *Output) Start() error {
 		o.client.Namespace = namespace
 	}
 
-	pf, err := output.NewPeriodicFlusher(time.Duration(o.config.PushInterval.Duration), o.flushMetrics)
+	pf, err := output.NewDrainingPeriodicFlusher(
+		time.Duration(o.config.PushInterval.Duration), o.flushMetrics, o.HasSpilledSamples)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,100 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The lifetime of the tokens without an expires_in in the responses of the token endpoints.
+const defaultTokenLifetime = 5 * time.Minute
+
+// TokenCache gets the OAuth 2.0 access tokens of an output from a token endpoint, and keeps each
+// of them until a minute before it expires, so that it doesn't expire during the requests of the
+// output. It isn't safe for concurrent use.
+type TokenCache struct {
+	// Name is the name of the tokens in the errors, e.g. "AAD access token".
+	Name   string
+	Client *http.Client
+	// Now returns the current time, it's time.Now if it's nil.
+	Now func() time.Time
+
+	token   string
+	expires time.Time
+}
+
+func (tc *TokenCache) now() time.Time {
+	if tc.Now != nil {
+		return tc.Now()
+	}
+	return time.Now()
+}
+
+// Token returns the cached token if it isn't about to expire, or it posts the form returned by
+// newForm to the token endpoint for a new one. The expires_in of the responses can be a number
+// or a string.
+func (tc *TokenCache) Token(tokenURL string, newForm func(now time.Time) (url.Values, error)) (string, error) {
+	now := tc.now()
+	if tc.token != "" && now.Before(tc.expires) {
+		return tc.token, nil
+	}
+
+	form, err := newForm(now)
+	if err != nil {
+		return "", err
+	}
+	resp, err := tc.Client.PostForm(tokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("couldn't get the %s: %w", tc.Name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("couldn't get the %s: %w", tc.Name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("couldn't get the %s, status %d: %s",
+			tc.Name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || result.AccessToken == "" {
+		return "", fmt.Errorf("invalid %s response: %s", tc.Name, strings.TrimSpace(string(body)))
+	}
+	lifetime := defaultTokenLifetime
+	if expiresIn, err := strconv.ParseInt(result.ExpiresIn.String(), 10, 64); err == nil && expiresIn > 0 {
+		lifetime = time.Duration(expiresIn) * time.Second
+	}
+	tc.token = result.AccessToken
+	tc.expires = now.Add(lifetime - time.Minute)
+	return tc.token, nil
+}
@@ -0,0 +1,81 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package output
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenCache(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "client_credentials", r.PostFormValue("grant_type"))
+		// the v1 endpoint of AAD has a string expires_in
+		_, _ = w.Write([]byte(`{"expires_in": "3600", "access_token": "token"}`))
+	}))
+	defer srv.Close()
+
+	now := time.Unix(1614173820, 0)
+	tc := &TokenCache{Name: "test token", Client: srv.Client(), Now: func() time.Time { return now }}
+	newForm := func(time.Time) (url.Values, error) {
+		return url.Values{"grant_type": {"client_credentials"}}, nil
+	}
+	for i := 0; i < 2; i++ {
+		token, err := tc.Token(srv.URL, newForm)
+		require.NoError(t, err)
+		assert.Equal(t, "token", token)
+	}
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(59 * time.Minute)
+	_, err := tc.Token(srv.URL, newForm)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestTokenCacheErrors(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/invalid" {
+			_, _ = w.Write([]byte(`{"expires_in": 3600}`))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+	}))
+	defer srv.Close()
+
+	tc := &TokenCache{Name: "test token", Client: srv.Client()}
+	newForm := func(time.Time) (url.Values, error) { return url.Values{}, nil }
+	_, err := tc.Token(srv.URL, newForm)
+	assert.EqualError(t, err, `couldn't get the test token, status 401: {"error": "invalid_client"}`)
+	_, err = tc.Token(srv.URL+"/invalid", newForm)
+	assert.EqualError(t, err, `invalid test token response: {"expires_in": 3600}`)
+}
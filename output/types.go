@@ -29,6 +29,7 @@ import (
 	"github.com/spf13/afero"
 
 	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/events"
 	"go.k6.io/k6/stats"
 )
 
@@ -47,6 +48,7 @@ type Params struct {
 	ScriptOptions  lib.Options
 	RuntimeOptions lib.RuntimeOptions
 	ExecutionPlan  []lib.ExecutionStep
+	RunMetadata    *lib.TestRunMetadata
 }
 
 // An Output abstracts the process of funneling samples to an external storage
@@ -95,3 +97,24 @@ type WithRunStatusUpdates interface {
 	Output
 	SetRunStatus(latestStatus lib.RunStatus)
 }
+
+// WithRunStart is an output that emits the metadata of the test run when it starts, after the VUs
+// are initialized, since only then the metadata has its start time.
+type WithRunStart interface {
+	Output
+	SetRunStart(md *lib.TestRunMetadata)
+}
+
+// WithRunManifest is an output that receives the manifest of the test run when it ends, before
+// it's stopped.
+type WithRunManifest interface {
+	Output
+	SetRunManifest(manifest *lib.TestRunManifest)
+}
+
+// WithEvents is an output that subscribes to the events of the test run, e.g. to get the
+// end-of-test summary from the TestEnd event. It gets the bus before it's started.
+type WithEvents interface {
+	Output
+	SetEvents(bus *events.Bus)
+}
@@ -0,0 +1,134 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package webdashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+)
+
+// Config is the config for the web dashboard output
+type Config struct {
+	Host   null.String        `json:"host" envconfig:"K6_WEB_DASHBOARD_HOST"`
+	Port   null.Int           `json:"port" envconfig:"K6_WEB_DASHBOARD_PORT"`
+	Period types.NullDuration `json:"period" envconfig:"K6_WEB_DASHBOARD_PERIOD"`
+	Report null.String        `json:"report" envconfig:"K6_WEB_DASHBOARD_REPORT"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Host:   null.StringFrom("localhost"),
+		Port:   null.IntFrom(5665),
+		Period: types.NullDurationFrom(1 * time.Second),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Host.Valid {
+		c.Host = cfg.Host
+	}
+	if cfg.Port.Valid {
+		c.Port = cfg.Port
+	}
+	if cfg.Period.Valid {
+		c.Period = cfg.Period
+	}
+	if cfg.Report.Valid {
+		c.Report = cfg.Report
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	for _, pair := range strings.Split(arg, ",") {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			return c, fmt.Errorf("couldn't parse %q as argument for web-dashboard output", arg)
+		}
+		switch r[0] {
+		case "host":
+			c.Host = null.StringFrom(r[1])
+		case "port":
+			port, err := strconv.ParseInt(r[1], 10, 64)
+			if err != nil {
+				return c, fmt.Errorf("invalid port %q: %w", r[1], err)
+			}
+			c.Port = null.IntFrom(port)
+		case "period":
+			if err := c.Period.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "report":
+			c.Report = null.StringFrom(r[1])
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for web-dashboard output", r[0])
+		}
+	}
+
+	return c, nil
+}
+
+// GetConsolidatedConfig combines {default config values + JSON config +
+// environment vars + arg config values}, and returns the final result.
+func GetConsolidatedConfig(jsonRawConf json.RawMessage, env map[string]string, arg string) (Config, error) {
+	result := NewConfig()
+	if jsonRawConf != nil {
+		jsonConf := Config{}
+		if err := json.Unmarshal(jsonRawConf, &jsonConf); err != nil {
+			return result, err
+		}
+		result = result.Apply(jsonConf)
+	}
+
+	envConfig := Config{}
+	if err := envconfig.Process("", &envConfig); err != nil {
+		// TODO: get rid of envconfig and actually use the env parameter...
+		return result, err
+	}
+	result = result.Apply(envConfig)
+
+	if arg != "" {
+		argConf, err := ParseArg(arg)
+		if err != nil {
+			return result, err
+		}
+		result = result.Apply(argConf)
+	}
+
+	if result.Period.Duration <= 0 {
+		return result, fmt.Errorf("the web-dashboard period should be positive, but it's %s", result.Period)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,82 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package webdashboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"go.k6.io/k6/lib/types"
+)
+
+func TestParseArg(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		config      Config
+		expectedErr bool
+	}{
+		"port=1234": {
+			config: Config{Port: null.IntFrom(1234)},
+		},
+		"host=0.0.0.0,period=5s,report=report.html": {
+			config: Config{
+				Host:   null.StringFrom("0.0.0.0"),
+				Period: types.NullDurationFrom(5 * time.Second),
+				Report: null.StringFrom("report.html"),
+			},
+		},
+		"port=abc":    {expectedErr: true},
+		"report.html": {expectedErr: true},
+		"foo=bar":     {expectedErr: true},
+	}
+
+	for arg, tc := range cases {
+		arg, tc := arg, tc
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+			config, err := ParseArg(arg)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.config, config)
+		})
+	}
+}
+
+func TestGetConsolidatedConfig(t *testing.T) {
+	t.Parallel()
+	config, err := GetConsolidatedConfig([]byte(`{"host":"127.0.0.1","port":8080}`), nil, "port=9090")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Host:   null.StringFrom("127.0.0.1"),
+		Port:   null.IntFrom(9090),
+		Period: types.NullDurationFrom(1 * time.Second),
+	}, config)
+
+	_, err = GetConsolidatedConfig(nil, nil, "period=0s")
+	assert.Error(t, err)
+}
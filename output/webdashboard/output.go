@@ -0,0 +1,470 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package webdashboard implements an output that serves a local web page with
+// live charts of all metrics and the status of the thresholds.
+package webdashboard
+
+import (
+	"bytes"
+	_ "embed" // this is used to embed the contents of dashboard.html
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte //nolint:gochecknoglobals
+
+// reportDataMarker is replaced with the test run data in the downloadable
+// reports, so that they can be viewed without a running k6 instance.
+const reportDataMarker = "<!-- k6-report-data -->"
+
+// subscriberBufferSize is the amount of updates that are buffered for every
+// connected browser, after which updates are dropped for slow clients.
+const subscriberBufferSize = 10
+
+// maxHistoryPoints is the maximum amount of chart points that are kept. When
+// it's reached, every other point is dropped, so the charts still cover the
+// whole test run, with half of the previous resolution.
+const maxHistoryPoints = 1000
+
+type thresholdStatus struct {
+	Source string `json:"source"`
+	OK     bool   `json:"ok"`
+}
+
+type metricSnapshot struct {
+	Type       stats.MetricType   `json:"type"`
+	Contains   stats.ValueType    `json:"contains"`
+	Values     map[string]float64 `json:"values"`
+	Thresholds []thresholdStatus  `json:"thresholds,omitempty"`
+}
+
+// snapshot contains the latest values of all metrics.
+type snapshot struct {
+	Time    time.Time                 `json:"time"`
+	Elapsed float64                   `json:"elapsed"`
+	Metrics map[string]metricSnapshot `json:"metrics"`
+}
+
+// point contains the charted value of every metric at some point in time.
+type point struct {
+	Time   time.Time          `json:"time"`
+	Values map[string]float64 `json:"values"`
+}
+
+// report is all of the data needed to render the dashboard.
+type report struct {
+	Finished bool      `json:"finished"`
+	Snapshot *snapshot `json:"snapshot"`
+	History  []point   `json:"history"`
+}
+
+type update struct {
+	Finished bool      `json:"finished"`
+	Snapshot *snapshot `json:"snapshot"`
+	Point    point     `json:"point"`
+}
+
+// Output aggregates the metric samples and serves them to the web dashboard.
+type Output struct {
+	output.SampleBuffer
+
+	config          Config
+	logger          logrus.FieldLogger
+	fs              afero.Fs
+	periodicFlusher *output.PeriodicFlusher
+	server          *http.Server
+	listenAddr      net.Addr
+	startTime       time.Time
+
+	mutex       sync.Mutex
+	metrics     map[string]*stats.Metric
+	submetrics  map[string][]*stats.Submetric
+	thresholds  map[string]stats.Thresholds
+	lastCounts  map[string]float64
+	lastFlush   time.Time
+	latest      *snapshot
+	history     []point
+	finished    bool
+	subscribers map[chan []byte]struct{}
+}
+
+var _ output.WithThresholds = &Output{}
+
+// New creates an instance of the web dashboard output
+func New(params output.Params) (output.Output, error) {
+	return newOutput(params)
+}
+
+func newOutput(params output.Params) (*Output, error) {
+	config, err := GetConsolidatedConfig(params.JSONConfig, params.Environment, params.ConfigArgument)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Output{
+		config:      config,
+		logger:      params.Logger.WithFields(logrus.Fields{"output": "web-dashboard"}),
+		fs:          params.FS,
+		metrics:     make(map[string]*stats.Metric),
+		submetrics:  make(map[string][]*stats.Submetric),
+		thresholds:  make(map[string]stats.Thresholds),
+		lastCounts:  make(map[string]float64),
+		subscribers: make(map[chan []byte]struct{}),
+	}, nil
+}
+
+func (o *Output) address() string {
+	return net.JoinHostPort(o.config.Host.String, strconv.FormatInt(o.config.Port.Int64, 10))
+}
+
+// Description returns a human-readable description of the output.
+func (o *Output) Description() string {
+	return fmt.Sprintf("web-dashboard (http://%s)", o.address())
+}
+
+// SetThresholds receives the thresholds of the test run, so the dashboard can
+// show their status. They are copied, since the Engine runs its own ones.
+func (o *Output) SetThresholds(thresholds map[string]stats.Thresholds) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for name, ths := range thresholds {
+		data, err := json.Marshal(ths)
+		if err != nil {
+			o.logger.WithError(err).Warnf("Couldn't copy the thresholds of '%s'", name)
+			continue
+		}
+		var copied stats.Thresholds
+		if err := json.Unmarshal(data, &copied); err != nil {
+			o.logger.WithError(err).Warnf("Couldn't copy the thresholds of '%s'", name)
+			continue
+		}
+		o.thresholds[name] = copied
+
+		if strings.Contains(name, "{") {
+			parent, sm := stats.NewSubmetric(name)
+			o.submetrics[parent] = append(o.submetrics[parent], sm)
+		}
+	}
+}
+
+// Start starts the HTTP server of the dashboard and the goroutine that
+// aggregates the metric samples.
+func (o *Output) Start() error {
+	o.logger.Debug("Starting...")
+
+	listener, err := net.Listen("tcp", o.address())
+	if err != nil {
+		return fmt.Errorf("couldn't start the web dashboard server: %w", err)
+	}
+
+	o.startTime = time.Now()
+	o.lastFlush = o.startTime
+	o.listenAddr = listener.Addr()
+	o.server = &http.Server{Handler: o.handler()}
+	go func() {
+		if err := o.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			o.logger.WithError(err).Error("Web dashboard server error")
+		}
+	}()
+	o.logger.Infof("The web dashboard is available at http://%s", o.listenAddr)
+
+	pf, err := output.NewDrainingPeriodicFlusher(
+		time.Duration(o.config.Period.Duration), o.flushMetrics, o.HasSpilledSamples)
+	if err != nil {
+		return err
+	}
+	o.periodicFlusher = pf
+
+	o.logger.Debug("Started!")
+	return nil
+}
+
+// Stop flushes the remaining metrics and saves the report if one was
+// configured. The HTTP server isn't shut down, so the final state of the
+// dashboard and its report can still be downloaded until the k6 process exits.
+func (o *Output) Stop() error {
+	o.logger.Debug("Stopping...")
+	defer o.logger.Debug("Stopped!")
+	o.periodicFlusher.Stop()
+
+	o.mutex.Lock()
+	o.finished = true
+	o.broadcast(update{Finished: true, Snapshot: o.latest})
+	for sub := range o.subscribers {
+		close(sub)
+		delete(o.subscribers, sub)
+	}
+	o.mutex.Unlock()
+
+	if o.config.Report.String != "" {
+		data, err := o.reportHTML()
+		if err == nil {
+			err = afero.WriteFile(o.fs, o.config.Report.String, data, 0o644)
+		}
+		if err != nil {
+			o.logger.WithError(err).Error("Couldn't save the web dashboard report")
+		}
+	}
+	return nil
+}
+
+func (o *Output) addSample(sample stats.Sample) {
+	m, ok := o.metrics[sample.Metric.Name]
+	if !ok {
+		m = sample.Metric.Derive(sample.Metric.Name)
+		m.Thresholds = o.thresholds[m.Name]
+		m.Submetrics = o.submetrics[m.Name]
+		o.metrics[m.Name] = m
+	}
+	m.Sink.Add(sample)
+
+	for _, sm := range m.Submetrics {
+		if !sample.Tags.Contains(sm.Tags) {
+			continue
+		}
+		if sm.Metric == nil {
+			sm.Metric = sample.Metric.Derive(sm.Name)
+			sm.Metric.Sub = *sm
+			sm.Metric.Thresholds = o.thresholds[sm.Name]
+			o.metrics[sm.Name] = sm.Metric
+		}
+		sm.Metric.Sink.Add(sample)
+	}
+}
+
+// chartValue returns the value that is shown in the chart of the metric. For
+// counters, it's their rate since the previous flush.
+func (o *Output) chartValue(m *stats.Metric, values map[string]float64, sinceLast time.Duration) float64 {
+	switch m.Type {
+	case stats.Counter:
+		count := values["count"]
+		rate := 0.0
+		if sinceLast > 0 {
+			rate = (count - o.lastCounts[m.Name]) / sinceLast.Seconds()
+		}
+		o.lastCounts[m.Name] = count
+		return rate
+	case stats.Gauge:
+		return values["value"]
+	case stats.Trend, stats.Histogram:
+		return values["p(95)"]
+	default:
+		return values["rate"]
+	}
+}
+
+func (o *Output) flushMetrics() {
+	samples := o.GetBufferedSamples()
+	now := time.Now()
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for _, sc := range samples {
+		for _, sample := range sc.GetSamples() {
+			o.addSample(sample)
+		}
+	}
+
+	elapsed := now.Sub(o.startTime)
+	snap := &snapshot{Time: now, Elapsed: elapsed.Seconds(), Metrics: make(map[string]metricSnapshot, len(o.metrics))}
+	pt := point{Time: now, Values: make(map[string]float64, len(o.metrics))}
+	for name, m := range o.metrics {
+		values := finiteValues(m.Sink.Format(elapsed))
+		ms := metricSnapshot{Type: m.Type, Contains: m.Contains, Values: values}
+		if len(m.Thresholds.Thresholds) > 0 {
+			if _, err := m.Thresholds.Run(m.Sink, elapsed); err != nil {
+				o.logger.WithError(err).Debugf("Couldn't run the thresholds of '%s'", name)
+			}
+			for _, th := range m.Thresholds.Thresholds {
+				ms.Thresholds = append(ms.Thresholds, thresholdStatus{Source: th.Source, OK: !th.LastFailed})
+			}
+		}
+		snap.Metrics[name] = ms
+		if v := o.chartValue(m, values, now.Sub(o.lastFlush)); !math.IsNaN(v) && !math.IsInf(v, 0) {
+			pt.Values[name] = v
+		}
+	}
+	o.lastFlush = now
+	o.latest = snap
+	o.history = appendHistory(o.history, pt)
+	o.broadcast(update{Snapshot: snap, Point: pt})
+}
+
+// appendHistory appends the point to the history, after dropping every other
+// point of it if it has reached maxHistoryPoints.
+func appendHistory(history []point, pt point) []point {
+	if len(history) >= maxHistoryPoints {
+		kept := history[:0]
+		for i := 1; i < len(history); i += 2 {
+			kept = append(kept, history[i])
+		}
+		history = kept
+	}
+	return append(history, pt)
+}
+
+// finiteValues drops the NaN and infinite values, which can't be encoded in
+// JSON, e.g. the rate of a Rate metric without any samples.
+func finiteValues(values map[string]float64) map[string]float64 {
+	for k, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			delete(values, k)
+		}
+	}
+	return values
+}
+
+// broadcast has to be called with the mutex held.
+func (o *Output) broadcast(u update) {
+	data, err := json.Marshal(u)
+	if err != nil {
+		o.logger.WithError(err).Error("Couldn't encode the web dashboard update")
+		return
+	}
+	for sub := range o.subscribers {
+		select {
+		case sub <- data:
+		default:
+			// the browser is too slow, it will catch up with the next snapshot
+		}
+	}
+}
+
+// getReport has to be called with the mutex held.
+func (o *Output) getReport() report {
+	return report{Finished: o.finished, Snapshot: o.latest, History: o.history}
+}
+
+func (o *Output) reportHTML() ([]byte, error) {
+	o.mutex.Lock()
+	data, err := json.Marshal(o.getReport())
+	o.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	// json.Marshal escapes '<', '>' and '&', so the data is safe to embed in
+	// a script tag.
+	script := fmt.Sprintf("<script>window.k6Report = %s;</script>", data)
+	return bytes.Replace(dashboardHTML, []byte(reportDataMarker), []byte(script), 1), nil
+}
+
+func (o *Output) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(rw, r)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = rw.Write(dashboardHTML)
+	})
+	mux.HandleFunc("/data", func(rw http.ResponseWriter, r *http.Request) {
+		o.mutex.Lock()
+		data, err := json.Marshal(o.getReport())
+		o.mutex.Unlock()
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write(data)
+	})
+	mux.HandleFunc("/report", func(rw http.ResponseWriter, r *http.Request) {
+		data, err := o.reportHTML()
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		rw.Header().Set("Content-Disposition", `attachment; filename="k6-report.html"`)
+		_, _ = rw.Write(data)
+	})
+	mux.HandleFunc("/events", o.handleEvents)
+	return mux
+}
+
+// handleEvents streams the updates to the browser with server-sent events.
+// The first event contains the whole history of the test run.
+func (o *Output) handleEvents(rw http.ResponseWriter, r *http.Request) {
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		http.Error(rw, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	o.mutex.Lock()
+	history, err := json.Marshal(o.getReport())
+	var sub chan []byte
+	if !o.finished {
+		sub = make(chan []byte, subscriberBufferSize)
+		o.subscribers[sub] = struct{}{}
+	}
+	o.mutex.Unlock()
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		if sub == nil {
+			return
+		}
+		o.mutex.Lock()
+		delete(o.subscribers, sub)
+		o.mutex.Unlock()
+	}()
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	_, _ = fmt.Fprintf(rw, "event: history\ndata: %s\n\n", history)
+	flusher.Flush()
+	if sub == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data, ok := <-sub:
+			if !ok {
+				return
+			}
+			_, _ = fmt.Fprintf(rw, "event: update\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
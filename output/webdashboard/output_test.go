@@ -0,0 +1,124 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package webdashboard
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/output"
+	"go.k6.io/k6/stats"
+)
+
+func TestOutput(t *testing.T) {
+	t.Parallel()
+	fs := afero.NewMemMapFs()
+	out, err := newOutput(output.Params{
+		Logger:         testutils.NewLogger(t),
+		FS:             fs,
+		ConfigArgument: "host=127.0.0.1,port=0,period=50ms,report=report.html",
+	})
+	require.NoError(t, err)
+
+	ths, err := stats.NewThresholds([]string{"count>1", "count>100"})
+	require.NoError(t, err)
+	out.SetThresholds(map[string]stats.Thresholds{"my_counter{a:1}": ths})
+	require.NoError(t, out.Start())
+
+	srv := httptest.NewServer(out.handler())
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/events") //nolint:bodyclose,noctx
+	require.NoError(t, err)
+	defer func() { _ = res.Body.Close() }()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	counter := stats.New("my_counter", stats.Counter)
+	tags := stats.IntoSampleTags(&map[string]string{"a": "1"})
+	out.AddMetricSamples([]stats.SampleContainer{stats.Samples{
+		{Time: time.Now(), Metric: counter, Value: 1, Tags: tags},
+		{Time: time.Now(), Metric: counter, Value: 2, Tags: tags},
+		{Time: time.Now(), Metric: stats.New("my_rate", stats.Rate), Value: 1},
+	}})
+
+	reader := bufio.NewReader(res.Body)
+	events := map[string]int{}
+	var last update
+	for events["update"] == 0 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			events[strings.TrimPrefix(line, "event: ")]++
+		case strings.HasPrefix(line, "data: ") && events["update"] > 0:
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &last))
+		}
+	}
+	for last.Snapshot == nil || len(last.Snapshot.Metrics) == 0 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "data: ") {
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "data: ")), &last))
+		}
+	}
+	assert.Equal(t, 1, events["history"])
+
+	require.NoError(t, out.Stop())
+
+	sub := last.Snapshot.Metrics["my_counter{a:1}"]
+	assert.Equal(t, stats.Counter, sub.Type)
+	assert.Equal(t, 3.0, sub.Values["count"])
+	assert.Equal(t, []thresholdStatus{{"count>1", true}, {"count>100", false}}, sub.Thresholds)
+	assert.Equal(t, 1.0, last.Snapshot.Metrics["my_rate"].Values["rate"])
+
+	report, err := afero.ReadFile(fs, "report.html")
+	require.NoError(t, err)
+	assert.Contains(t, string(report), `window.k6Report = {"finished":true`)
+
+	// the server of the dashboard is kept running after the output is stopped
+	reportRes, err := http.Get("http://" + out.listenAddr.String() + "/report") //nolint:noctx
+	require.NoError(t, err)
+	defer func() { _ = reportRes.Body.Close() }()
+	assert.Equal(t, http.StatusOK, reportRes.StatusCode)
+}
+
+func TestAppendHistory(t *testing.T) {
+	t.Parallel()
+	var history []point
+	for i := 0; i < maxHistoryPoints+1; i++ {
+		history = appendHistory(history, point{Values: map[string]float64{"i": float64(i)}})
+	}
+	require.Len(t, history, maxHistoryPoints/2+1)
+	assert.Equal(t, 1.0, history[0].Values["i"])
+	assert.Equal(t, float64(maxHistoryPoints-1), history[len(history)-2].Values["i"])
+	assert.Equal(t, float64(maxHistoryPoints), history[len(history)-1].Values["i"])
+}
@@ -0,0 +1,23 @@
+import http from "k6/http";
+import faker from "k6/faker";
+import { check } from "k6";
+
+// With a seed each VU sends the same users in the same iterations of every test run
+faker.seed(1234);
+
+export default function() {
+    const user = {
+        id: faker.uuid(),
+        name: faker.name(),
+        email: faker.email(),
+        phone: faker.phone(),
+        address: faker.address(),
+        bio: faker.paragraph(2),
+        age: faker.integer(18, 99),
+    };
+
+    const res = http.post("https://httpbin.test.k6.io/post", JSON.stringify(user), {
+        headers: { "Content-Type": "application/json" },
+    });
+    check(res, { "status is 200": (r) => r.status === 200 });
+}
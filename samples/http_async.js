@@ -0,0 +1,27 @@
+import { check } from 'k6';
+import http from 'k6/http';
+
+export const options = {
+  // at most 4 requests of a VU are in flight at the same time, and 2 for each host
+  asyncRequests: 4,
+  asyncRequestsPerHost: 2,
+};
+
+export default async function() {
+  const main = await http.asyncRequest("GET", "http://test.k6.io");
+  check(main, {
+    "main page 200": res => res.status === 200,
+  });
+
+  // the pages that the main one links to are requested concurrently
+  const pages = await Promise.all([
+    http.asyncRequest("GET", "http://test.k6.io/pi.php"),
+    http.asyncRequest("GET", "http://test.k6.io/contacts.php"),
+    http.asyncRequest("GET", "http://test.k6.io/news.php"),
+  ]);
+
+  check(pages[0], {
+    "pi page 200": res => res.status === 200,
+    "pi page has right content": res => res.body === "3.14",
+  });
+};
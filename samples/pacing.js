@@ -0,0 +1,20 @@
+import http from "k6/http";
+import { pace } from "k6";
+
+export let options = {
+    vus: 5,
+    duration: "1m",
+    thresholds: {
+        // the iterations shouldn't take longer than the pacing target
+        pacing_overruns: ["count<10"],
+    },
+};
+
+export default function() {
+    http.get("https://test.k6.io/");
+    http.get("https://test.k6.io/contacts.php");
+
+    // Start the iterations of each VU every 5 seconds, however long the requests took,
+    // the same as pace({ rate: 12, timeUnit: "1m" })
+    pace("5s");
+}
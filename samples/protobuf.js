@@ -0,0 +1,22 @@
+import protobuf from 'k6/protobuf';
+import http from 'k6/http';
+import { check } from "k6";
+
+// The definitions can be shared with the gRPC client
+const schema = protobuf.load([], "./grpc_server/route_guide.proto");
+
+export default () => {
+    const body = schema.encode("main.Point", {
+        latitude: 410248224,
+        longitude: -747127767
+    });
+
+    const res = http.post("https://httpbin.test.k6.io/post", body, {
+        headers: { "Content-Type": "application/x-protobuf" },
+    });
+    check(res, { "status is 200": (r) => r.status === 200 });
+
+    // The decoded objects have all their fields, with the default values for the missing ones
+    const point = schema.decode("main.Point", body);
+    console.log(JSON.stringify(point));
+}
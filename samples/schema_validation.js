@@ -0,0 +1,27 @@
+import http from "k6/http";
+import { JSONSchema, OpenAPI } from "k6/schema";
+
+// The OpenAPI documents can be JSON or YAML
+const api = new OpenAPI(open("./openapi.yaml"));
+
+const crocodile = new JSONSchema({
+    type: "object",
+    required: ["id", "name", "sex"],
+    properties: {
+        id: { type: "integer" },
+        name: { type: "string", minLength: 1 },
+        sex: { enum: ["M", "F"] },
+        date_of_birth: { type: "string", format: "date" },
+    },
+}, { name: "crocodile schema" });
+
+export default function() {
+    // Records a check named "GET /public/crocodiles/{id}/ matches the OpenAPI document"
+    api.check(http.get("https://test-api.k6.io/public/crocodiles/1/"));
+
+    // Records a check named "crocodile schema" and logs the problems of the invalid responses
+    const res = http.get("https://test-api.k6.io/public/crocodiles/2/");
+    if (!crocodile.check(res)) {
+        console.warn(JSON.stringify(crocodile.validateResponse(res).errors));
+    }
+}
@@ -0,0 +1,24 @@
+import http from "k6/http";
+import { check } from "k6";
+import { soapEnvelope, escape } from "k6/xml";
+
+const ns = { m: "http://www.oorsprong.org/websamples.countryinfo" };
+
+export default function() {
+    // Build the SOAP 1.1 request, escaping the user provided values
+    const body = soapEnvelope(
+        `<m:CapitalCity><m:sCountryISOCode>${escape("NL")}</m:sCountryISOCode></m:CapitalCity>`,
+        { namespaces: ns }
+    );
+
+    let res = http.post("http://webservices.oorsprong.org/websamples.countryinfo/CountryInfoService.wso", body, {
+        headers: { "Content-Type": "text/xml; charset=utf-8" },
+    });
+
+    // Query the response with XPath, soapFault() is null for successful responses
+    check(res, {
+        "status is 200": (r) => r.status === 200,
+        "no fault": (r) => r.xml().soapFault() === null,
+        "capital is Amsterdam": (r) => r.xml().find("//m:CapitalCityResult", ns).text() === "Amsterdam",
+    });
+}
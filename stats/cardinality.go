@@ -0,0 +1,136 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+// TagValueOverflow is the value that replaces any tag value above the limit
+// configured in a TagCardinalityLimiter.
+const TagValueOverflow = "__overflow__"
+
+// TagCardinalityLimiter caps the number of distinct values every tag can have
+// for every metric. Once the limit for a metric and tag pair is reached, all
+// new values for that tag are replaced by TagValueOverflow, so they all end up
+// in a single bucket. Since submetrics are matched by their tag values, this
+// also caps the number of submetrics a single metric can have.
+//
+// It's not thread-safe, callers are expected to synchronize access to it.
+type TagCardinalityLimiter struct {
+	limit      int
+	values     map[string]map[string]map[string]struct{}
+	onOverflow func(metric, tag string)
+}
+
+// NewTagCardinalityLimiter returns a new TagCardinalityLimiter that allows at
+// most limit distinct values per metric and tag. The onOverflow callback, if
+// not nil, is called once for every metric and tag pair when its limit is
+// first exceeded.
+func NewTagCardinalityLimiter(limit int, onOverflow func(metric, tag string)) *TagCardinalityLimiter {
+	return &TagCardinalityLimiter{
+		limit:      limit,
+		values:     make(map[string]map[string]map[string]struct{}),
+		onOverflow: onOverflow,
+	}
+}
+
+// Limit returns the given tag set with all tag values over the limit replaced
+// by TagValueOverflow. If no tag values needed to be replaced, the same tag set
+// is returned.
+func (l *TagCardinalityLimiter) Limit(metric string, tags *SampleTags) *SampleTags {
+	if l.limit <= 0 || tags.IsEmpty() {
+		return tags
+	}
+
+	metricValues, ok := l.values[metric]
+	if !ok {
+		metricValues = make(map[string]map[string]struct{})
+		l.values[metric] = metricValues
+	}
+
+	var overflowed []string
+	for k, v := range tags.tags {
+		tagValues, ok := metricValues[k]
+		if !ok {
+			tagValues = make(map[string]struct{})
+			metricValues[k] = tagValues
+		}
+		if _, ok := tagValues[v]; ok {
+			continue
+		}
+		if len(tagValues) < l.limit {
+			tagValues[v] = struct{}{}
+			continue
+		}
+		if _, ok := tagValues[TagValueOverflow]; !ok {
+			// The overflow bucket doesn't count towards the limit
+			tagValues[TagValueOverflow] = struct{}{}
+			if l.onOverflow != nil {
+				l.onOverflow(metric, k)
+			}
+		}
+		if v != TagValueOverflow {
+			overflowed = append(overflowed, k)
+		}
+	}
+
+	if len(overflowed) == 0 {
+		return tags
+	}
+	newTags := tags.CloneTags()
+	for _, k := range overflowed {
+		newTags[k] = TagValueOverflow
+	}
+	return IntoSampleTags(&newTags)
+}
+
+// Apply limits the tags of all samples in the given sample containers. The
+// containers are modified in place, single Sample and ConnectedSamples values
+// are replaced in the slice with limited copies.
+func (l *TagCardinalityLimiter) Apply(sampleContainers []SampleContainer) {
+	if l.limit <= 0 {
+		return
+	}
+
+	for i, sc := range sampleContainers {
+		switch container := sc.(type) {
+		case Sample:
+			container.Tags = l.Limit(container.Metric.Name, container.Tags)
+			sampleContainers[i] = container
+		case ConnectedSamples:
+			// The container tags are usually shared with the samples, so
+			// keep them in sync with whatever the first sample gets.
+			if len(container.Samples) > 0 && container.Tags == container.Samples[0].Tags {
+				l.limitSamples(container.Samples)
+				container.Tags = container.Samples[0].Tags
+				sampleContainers[i] = container
+				continue
+			}
+			l.limitSamples(container.Samples)
+		default:
+			// Custom containers return their own underlying slice
+			l.limitSamples(sc.GetSamples())
+		}
+	}
+}
+
+func (l *TagCardinalityLimiter) limitSamples(samples []Sample) {
+	for i := range samples {
+		samples[i].Tags = l.Limit(samples[i].Metric.Name, samples[i].Tags)
+	}
+}
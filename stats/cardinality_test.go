@@ -0,0 +1,93 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagCardinalityLimiter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Limit", func(t *testing.T) {
+		t.Parallel()
+		var overflows []string
+		l := NewTagCardinalityLimiter(2, func(metric, tag string) {
+			overflows = append(overflows, metric+":"+tag)
+		})
+
+		tags := func(vu string) *SampleTags {
+			return IntoSampleTags(&map[string]string{"vu": vu, "method": "GET"})
+		}
+		t1, t2, t3, t4 := tags("1"), tags("2"), tags("3"), tags("4")
+		assert.Same(t, t1, l.Limit("m1", t1))
+		assert.Same(t, t2, l.Limit("m1", t2))
+		assert.Same(t, t1, l.Limit("m1", t1))
+
+		limited := l.Limit("m1", t3)
+		assert.Equal(t, map[string]string{"vu": TagValueOverflow, "method": "GET"}, limited.CloneTags())
+		assert.Equal(t, map[string]string{"vu": "3", "method": "GET"}, t3.CloneTags())
+		limited = l.Limit("m1", t4)
+		assert.Equal(t, map[string]string{"vu": TagValueOverflow, "method": "GET"}, limited.CloneTags())
+		assert.Same(t, limited, l.Limit("m1", limited))
+
+		// other metrics have their own limits
+		assert.Same(t, t3, l.Limit("m2", t3))
+		assert.Equal(t, []string{"m1:vu"}, overflows)
+	})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		t.Parallel()
+		l := NewTagCardinalityLimiter(0, nil)
+		for i := 0; i < 10; i++ {
+			tags := IntoSampleTags(&map[string]string{"iter": string(rune('a' + i))})
+			assert.Same(t, tags, l.Limit("m", tags))
+		}
+	})
+
+	t.Run("Apply", func(t *testing.T) {
+		t.Parallel()
+		m := New("m", Counter)
+		l := NewTagCardinalityLimiter(1, nil)
+		tags1 := IntoSampleTags(&map[string]string{"url": "/1"})
+		tags2 := IntoSampleTags(&map[string]string{"url": "/2"})
+		expected := map[string]string{"url": TagValueOverflow}
+
+		containers := []SampleContainer{
+			Sample{Metric: m, Tags: tags1},
+			Sample{Metric: m, Tags: tags2},
+			ConnectedSamples{Tags: tags2, Samples: []Sample{{Metric: m, Tags: tags2}}},
+			Samples{{Metric: m, Tags: tags2}},
+		}
+		l.Apply(containers)
+
+		require.Len(t, containers, 4)
+		assert.Same(t, tags1, containers[0].(Sample).Tags)
+		assert.Equal(t, expected, containers[1].(Sample).Tags.CloneTags())
+		cs := containers[2].(ConnectedSamples)
+		assert.Equal(t, expected, cs.Tags.CloneTags())
+		assert.Same(t, cs.Tags, cs.Samples[0].Tags)
+		assert.Equal(t, expected, containers[3].GetSamples()[0].Tags.CloneTags())
+	})
+}
@@ -32,6 +32,8 @@ var (
 	_ Sink = &GaugeSink{}
 	_ Sink = &TrendSink{}
 	_ Sink = &RateSink{}
+	_ Sink = &ThroughputSink{}
+	_ Sink = &HistogramSink{}
 	_ Sink = &DummySink{}
 )
 
@@ -177,6 +179,160 @@ func (r RateSink) Format(t time.Duration) map[string]float64 {
 	return map[string]float64{"rate": float64(r.Trues) / float64(r.Total)}
 }
 
+// DefaultThroughputWindow is the length of the wall-clock windows over which
+// ThroughputSink calculates the per-second rates.
+const DefaultThroughputWindow = time.Second
+
+// ThroughputSink counts events in consecutive wall-clock windows of the
+// sample times, unlike CounterSink, whose rate is relative to the whole test
+// run duration. Samples that arrive for an already closed window are still
+// counted, but they don't affect the per-window rates anymore.
+type ThroughputSink struct {
+	Window time.Duration
+	Count  float64
+	// Peak is the highest per-second rate of all closed windows.
+	Peak float64
+
+	first         time.Time
+	windowStart   time.Time
+	windowCount   float64
+	closedWindows int64
+}
+
+func (ts *ThroughputSink) window() time.Duration {
+	if ts.Window <= 0 {
+		return DefaultThroughputWindow
+	}
+	return ts.Window
+}
+
+func (ts *ThroughputSink) Add(s Sample) {
+	window := ts.window()
+	start := s.Time.Truncate(window)
+	ts.Count += s.Value
+
+	switch {
+	case ts.first.IsZero():
+		ts.first = start
+		ts.windowStart = start
+	case start.After(ts.windowStart):
+		ts.closeWindow()
+		// Empty windows in between lower the overall rate, but not the peak
+		ts.closedWindows += int64(start.Sub(ts.windowStart)/window) - 1
+		ts.windowStart = start
+	case start.Before(ts.windowStart):
+		return
+	}
+	ts.windowCount += s.Value
+}
+
+func (ts *ThroughputSink) closeWindow() {
+	if rate := ts.windowCount / ts.window().Seconds(); rate > ts.Peak {
+		ts.Peak = rate
+	}
+	ts.windowCount = 0
+	ts.closedWindows++
+}
+
+func (ts *ThroughputSink) Calc() {}
+
+// Format returns the total count, the average per-second rate over all of the
+// closed windows since the first sample and the peak per-second rate. The
+// current window is only used while there are no closed windows yet.
+func (ts *ThroughputSink) Format(t time.Duration) map[string]float64 {
+	rate, peak := 0.0, ts.Peak
+	if ts.closedWindows > 0 {
+		rate = (ts.Count - ts.windowCount) / (float64(ts.closedWindows) * ts.window().Seconds())
+	} else if !ts.first.IsZero() {
+		rate = ts.windowCount / ts.window().Seconds()
+		peak = rate
+	}
+	return map[string]float64{
+		"count": ts.Count,
+		"rate":  rate,
+		"peak":  peak,
+	}
+}
+
+// HistogramSink counts the values in buckets with explicit upper bounds. A
+// value is counted in the first bucket with a bound that is greater than or
+// equal to it, values above the last bound go to an overflow bucket.
+type HistogramSink struct {
+	Buckets []float64
+	// Counts has one more element than Buckets, for the overflow bucket.
+	Counts []uint64
+
+	Count    uint64
+	Min, Max float64
+	Sum, Avg float64
+}
+
+// NewHistogramSink returns a HistogramSink with the given sorted bucket upper
+// bounds, or with DefaultHistogramBuckets if none are given.
+func NewHistogramSink(buckets []float64) *HistogramSink {
+	if len(buckets) == 0 {
+		buckets = DefaultHistogramBuckets
+	}
+	return &HistogramSink{Buckets: buckets, Counts: make([]uint64, len(buckets)+1)}
+}
+
+func (h *HistogramSink) Add(s Sample) {
+	if h.Counts == nil {
+		*h = *NewHistogramSink(h.Buckets)
+	}
+	h.Counts[sort.SearchFloat64s(h.Buckets, s.Value)]++
+	h.Count++
+	h.Sum += s.Value
+	h.Avg = h.Sum / float64(h.Count)
+
+	if s.Value > h.Max || h.Count == 1 {
+		h.Max = s.Value
+	}
+	if s.Value < h.Min || h.Count == 1 {
+		h.Min = s.Value
+	}
+}
+
+// P estimates the given percentile by linear interpolation inside the bucket
+// that contains it. The lower bound of the first bucket and the upper bound of
+// the overflow bucket are the min and max values.
+func (h *HistogramSink) P(pct float64) float64 {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := pct * float64(h.Count)
+	var cumulative float64
+	for i, c := range h.Counts {
+		if c == 0 || cumulative+float64(c) < rank {
+			cumulative += float64(c)
+			continue
+		}
+		lower, upper := h.Min, h.Max
+		if i > 0 && h.Buckets[i-1] > lower {
+			lower = h.Buckets[i-1]
+		}
+		if i < len(h.Buckets) && h.Buckets[i] < upper {
+			upper = h.Buckets[i]
+		}
+		return lower + (upper-lower)*(rank-cumulative)/float64(c)
+	}
+	return h.Max
+}
+
+func (h *HistogramSink) Calc() {}
+
+func (h *HistogramSink) Format(t time.Duration) map[string]float64 {
+	return map[string]float64{
+		"count": float64(h.Count),
+		"min":   h.Min,
+		"max":   h.Max,
+		"avg":   h.Avg,
+		"sum":   h.Sum,
+		"p(90)": h.P(0.90),
+		"p(95)": h.P(0.95),
+	}
+}
+
 type DummySink map[string]float64
 
 func (d DummySink) Add(s Sample) {
@@ -263,6 +263,83 @@ func TestRateSink(t *testing.T) {
 	})
 }
 
+func TestThroughputSink(t *testing.T) {
+	start := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) Sample {
+		return Sample{Metric: &Metric{}, Value: 1.0, Time: start.Add(d)}
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		sink := ThroughputSink{}
+		assert.Equal(t, map[string]float64{"count": 0, "rate": 0, "peak": 0}, sink.Format(time.Second))
+	})
+	t.Run("single window", func(t *testing.T) {
+		sink := ThroughputSink{}
+		for i := 0; i < 5; i++ {
+			sink.Add(at(time.Duration(i) * 100 * time.Millisecond))
+		}
+		assert.Equal(t, map[string]float64{"count": 5, "rate": 5, "peak": 5}, sink.Format(time.Minute))
+	})
+	t.Run("windows", func(t *testing.T) {
+		sink := ThroughputSink{Window: time.Second}
+		for i := 0; i < 4; i++ {
+			sink.Add(at(100 * time.Millisecond))
+		}
+		sink.Add(at(1500 * time.Millisecond))
+		sink.Add(at(1600 * time.Millisecond))
+		// An empty window, followed by the current one
+		sink.Add(at(3100 * time.Millisecond))
+		// Late samples are only counted in the total
+		sink.Add(at(200 * time.Millisecond))
+
+		assert.Equal(t, int64(3), sink.closedWindows)
+		assert.Equal(t, map[string]float64{"count": 8, "rate": 7.0 / 3, "peak": 4}, sink.Format(time.Minute))
+	})
+}
+
+func TestHistogramSink(t *testing.T) {
+	samples := []float64{1, 5, 7, 10, 11, 50, 100, 101}
+
+	t.Run("add", func(t *testing.T) {
+		sink := NewHistogramSink([]float64{5, 10, 100})
+		for _, s := range samples {
+			sink.Add(Sample{Metric: &Metric{}, Value: s})
+		}
+		assert.Equal(t, []uint64{2, 2, 3, 1}, sink.Counts)
+		assert.Equal(t, uint64(8), sink.Count)
+		assert.Equal(t, 1.0, sink.Min)
+		assert.Equal(t, 101.0, sink.Max)
+		assert.Equal(t, 285.0, sink.Sum)
+		assert.Equal(t, 285.0/8, sink.Avg)
+	})
+	t.Run("default buckets", func(t *testing.T) {
+		sink := &HistogramSink{}
+		sink.Add(Sample{Metric: &Metric{}, Value: 7})
+		assert.Equal(t, DefaultHistogramBuckets, sink.Buckets)
+		assert.Equal(t, uint64(1), sink.Counts[1])
+	})
+	t.Run("percentiles", func(t *testing.T) {
+		sink := NewHistogramSink([]float64{10, 20})
+		assert.Equal(t, 0.0, sink.P(0.5))
+		for i := 1; i <= 20; i++ {
+			sink.Add(Sample{Metric: &Metric{}, Value: float64(i)})
+		}
+		assert.InDelta(t, 10.0, sink.P(0.5), 0.0001)
+		assert.InDelta(t, 18.0, sink.P(0.9), 0.0001)
+		assert.InDelta(t, 20.0, sink.P(1), 0.0001)
+	})
+	t.Run("format", func(t *testing.T) {
+		sink := NewHistogramSink([]float64{10, 20})
+		for i := 1; i <= 20; i++ {
+			sink.Add(Sample{Metric: &Metric{}, Value: float64(i)})
+		}
+		f := sink.Format(0)
+		assert.Equal(t, 20.0, f["count"])
+		assert.Equal(t, 210.0, f["sum"])
+		assert.InDelta(t, 19.0, f["p(95)"], 0.0001)
+	})
+}
+
 func TestDummySinkAddPanics(t *testing.T) {
 	assert.Panics(t, func() {
 		DummySink{}.Add(Sample{})
@@ -25,6 +25,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -39,17 +41,24 @@ const (
 	trendString   = "trend"
 	rateString    = "rate"
 
+	throughputString = "throughput"
+	histogramString  = "histogram"
+
 	defaultString = "default"
 	timeString    = "time"
 	dataString    = "data"
 )
 
-// Possible values for MetricType.
+// Possible values for MetricType. Outputs that only support the first four
+// types export Throughput metrics as counters and Histogram metrics as trends,
+// since the values of their samples are the same.
 const (
-	Counter = MetricType(iota) // A counter that sums its data points
-	Gauge                      // A gauge that displays the latest value
-	Trend                      // A trend, min/max/avg/med are interesting
-	Rate                       // A rate, displays % of values that aren't 0
+	Counter    = MetricType(iota) // A counter that sums its data points
+	Gauge                         // A gauge that displays the latest value
+	Trend                         // A trend, min/max/avg/med are interesting
+	Rate                          // A rate, displays % of values that aren't 0
+	Throughput                    // A throughput, displays events per second over wall-clock windows
+	Histogram                     // A histogram, counts values in explicit buckets
 )
 
 // Possible values for ValueType.
@@ -88,6 +97,10 @@ func (t MetricType) MarshalText() ([]byte, error) {
 		return []byte(trendString), nil
 	case Rate:
 		return []byte(rateString), nil
+	case Throughput:
+		return []byte(throughputString), nil
+	case Histogram:
+		return []byte(histogramString), nil
 	default:
 		return nil, ErrInvalidMetricType
 	}
@@ -104,6 +117,10 @@ func (t *MetricType) UnmarshalText(data []byte) error {
 		*t = Trend
 	case rateString:
 		*t = Rate
+	case throughputString:
+		*t = Throughput
+	case histogramString:
+		*t = Histogram
 	default:
 		return ErrInvalidMetricType
 	}
@@ -121,6 +138,10 @@ func (t MetricType) String() string {
 		return trendString
 	case Rate:
 		return rateString
+	case Throughput:
+		return throughputString
+	case Histogram:
+		return histogramString
 	default:
 		return "[INVALID]"
 	}
@@ -332,6 +353,14 @@ type Sample struct {
 	Time   time.Time
 	Tags   *SampleTags
 	Value  float64
+
+	// Metadata contains non-indexed key/values, like trace or request IDs.
+	// Unlike the tags, it's never used for sub-metrics and thresholds, so it
+	// can have unique values without any impact on the metrics processing.
+	// Outputs that can store it are free to do so, the rest should ignore it.
+	// Loki only receives the log entries through the log hook and the console,
+	// never the samples, so the metadata isn't pushed there.
+	Metadata map[string]string
 }
 
 // SampleContainer is a simple abstraction that allows sample
@@ -443,7 +472,15 @@ type Metric struct {
 	Thresholds Thresholds   `json:"thresholds"`
 	Submetrics []*Submetric `json:"submetrics"`
 	Sub        Submetric    `json:"sub,omitempty"`
-	Sink       Sink         `json:"-"`
+	// Buckets are the sorted upper bounds of the buckets of Histogram metrics.
+	Buckets []float64 `json:"buckets,omitempty"`
+	Sink    Sink      `json:"-"`
+}
+
+// DefaultHistogramBuckets are the bucket upper bounds used for Histogram
+// metrics that were created without explicit buckets.
+var DefaultHistogramBuckets = []float64{ //nolint:gochecknoglobals
+	5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000,
 }
 
 func New(name string, typ MetricType, t ...ValueType) *Metric {
@@ -451,20 +488,71 @@ func New(name string, typ MetricType, t ...ValueType) *Metric {
 	if len(t) > 0 {
 		vt = t[0]
 	}
-	var sink Sink
+	var buckets []float64
+	if typ == Histogram {
+		buckets = DefaultHistogramBuckets
+	}
+	sink := newSink(typ, buckets)
+	if sink == nil {
+		return nil
+	}
+	return &Metric{Name: name, Type: typ, Contains: vt, Buckets: buckets, Sink: sink}
+}
+
+// NewHistogram returns a new Histogram metric with the given bucket upper
+// bounds. Values above the last bucket are counted in an implicit overflow
+// bucket.
+func NewHistogram(name string, buckets []float64, t ...ValueType) (*Metric, error) {
+	if len(buckets) == 0 {
+		return nil, fmt.Errorf("histogram metric '%s' needs at least one bucket", name)
+	}
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+	for i, b := range sorted {
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			return nil, fmt.Errorf("histogram metric '%s' has an invalid bucket %f", name, b)
+		}
+		if i > 0 && sorted[i-1] == b {
+			return nil, fmt.Errorf("histogram metric '%s' has a duplicate bucket %f", name, b)
+		}
+	}
+
+	m := New(name, Histogram, t...)
+	m.Buckets = sorted
+	m.Sink = newSink(Histogram, sorted)
+	return m, nil
+}
+
+// Derive returns a new metric with the given name and the same type, value
+// type and sink configuration as m, but with an empty sink.
+func (m *Metric) Derive(name string) *Metric {
+	return &Metric{
+		Name:     name,
+		Type:     m.Type,
+		Contains: m.Contains,
+		Buckets:  m.Buckets,
+		Sink:     newSink(m.Type, m.Buckets),
+	}
+}
+
+func newSink(typ MetricType, buckets []float64) Sink {
 	switch typ {
 	case Counter:
-		sink = &CounterSink{}
+		return &CounterSink{}
 	case Gauge:
-		sink = &GaugeSink{}
+		return &GaugeSink{}
 	case Trend:
-		sink = &TrendSink{}
+		return &TrendSink{}
 	case Rate:
-		sink = &RateSink{}
+		return &RateSink{}
+	case Throughput:
+		return &ThroughputSink{Window: DefaultThroughputWindow}
+	case Histogram:
+		return NewHistogramSink(buckets)
 	default:
 		return nil
 	}
-	return &Metric{Name: name, Type: typ, Contains: vt, Sink: sink}
 }
 
 var unitMap = map[string][]interface{}{
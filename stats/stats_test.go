@@ -23,10 +23,12 @@ package stats
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNew(t *testing.T) {
@@ -35,10 +37,12 @@ func TestNew(t *testing.T) {
 		Type     MetricType
 		SinkType Sink
 	}{
-		"Counter": {Counter, &CounterSink{}},
-		"Gauge":   {Gauge, &GaugeSink{}},
-		"Trend":   {Trend, &TrendSink{}},
-		"Rate":    {Rate, &RateSink{}},
+		"Counter":    {Counter, &CounterSink{}},
+		"Gauge":      {Gauge, &GaugeSink{}},
+		"Trend":      {Trend, &TrendSink{}},
+		"Rate":       {Rate, &RateSink{}},
+		"Throughput": {Throughput, &ThroughputSink{}},
+		"Histogram":  {Histogram, &HistogramSink{}},
 	}
 
 	for name, data := range testdata {
@@ -52,6 +56,28 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewHistogram(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewHistogram("my_histogram", []float64{100, 10, 50}, Time)
+	require.NoError(t, err)
+	assert.Equal(t, Histogram, m.Type)
+	assert.Equal(t, Time, m.Contains)
+	assert.Equal(t, []float64{10, 50, 100}, m.Buckets)
+	assert.Equal(t, m.Buckets, m.Sink.(*HistogramSink).Buckets)
+
+	derived := m.Derive("my_histogram{a:1}")
+	assert.Equal(t, m.Buckets, derived.Buckets)
+	assert.Equal(t, m.Buckets, derived.Sink.(*HistogramSink).Buckets)
+
+	_, err = NewHistogram("my_histogram", nil)
+	assert.EqualError(t, err, "histogram metric 'my_histogram' needs at least one bucket")
+	_, err = NewHistogram("my_histogram", []float64{1, 2, 1})
+	assert.EqualError(t, err, "histogram metric 'my_histogram' has a duplicate bucket 1.000000")
+	_, err = NewHistogram("my_histogram", []float64{1, math.NaN()})
+	assert.Error(t, err)
+}
+
 func TestNewSubmetric(t *testing.T) {
 	t.Parallel()
 	testdata := map[string]struct {
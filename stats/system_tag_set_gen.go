@@ -7,7 +7,7 @@ import (
 	"fmt"
 )
 
-const _SystemTagSetName = "protosubprotostatusmethodurlnamegroupcheckerrorerror_codetls_versionscenarioserviceexpected_responseitervuocsp_statusip"
+const _SystemTagSetName = "protosubprotostatusmethodurlnamegroupcheckerrorerror_codetls_versionscenarioserviceexpected_responseitervuocsp_statusiprun_iderror_class"
 
 var _SystemTagSetMap = map[SystemTagSet]string{
 	1:      _SystemTagSetName[0:5],
@@ -28,6 +28,8 @@ var _SystemTagSetMap = map[SystemTagSet]string{
 	32768:  _SystemTagSetName[104:106],
 	65536:  _SystemTagSetName[106:117],
 	131072: _SystemTagSetName[117:119],
+	262144: _SystemTagSetName[119:125],
+	524288: _SystemTagSetName[125:136],
 }
 
 func (i SystemTagSet) String() string {
@@ -37,7 +39,7 @@ func (i SystemTagSet) String() string {
 	return fmt.Sprintf("SystemTagSet(%d)", i)
 }
 
-var _SystemTagSetValues = []SystemTagSet{1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024, 2048, 4096, 8192, 16384, 32768, 65536, 131072}
+var _SystemTagSetValues = []SystemTagSet{1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024, 2048, 4096, 8192, 16384, 32768, 65536, 131072, 262144, 524288}
 
 var _SystemTagSetNameToValueMap = map[string]SystemTagSet{
 	_SystemTagSetName[0:5]:     1,
@@ -58,6 +60,8 @@ var _SystemTagSetNameToValueMap = map[string]SystemTagSet{
 	_SystemTagSetName[104:106]: 32768,
 	_SystemTagSetName[106:117]: 65536,
 	_SystemTagSetName[117:119]: 131072,
+	_SystemTagSetName[119:125]: 262144,
+	_SystemTagSetName[125:136]: 524288,
 }
 
 // SystemTagSetString retrieves an enum value from the enum constants string name.
@@ -0,0 +1,92 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"sort"
+	"time"
+)
+
+// TimeSeriesPoint contains the aggregated values of a metric for the samples
+// with a time in [Time, Time+interval).
+type TimeSeriesPoint struct {
+	Time time.Time
+	Sink Sink
+}
+
+// TimeSeries aggregates the samples of every metric in fixed-length time
+// intervals, with a separate sink for every interval. Since every sink keeps
+// the same data as the end-of-test one, this roughly doubles the memory that
+// is needed for the metrics, especially for trends.
+//
+// It's not thread-safe, callers are expected to synchronize access to it.
+type TimeSeries struct {
+	interval time.Duration
+	metrics  map[string]map[int64]Sink
+}
+
+// NewTimeSeries returns a new TimeSeries with the given interval length, which
+// has to be positive.
+func NewTimeSeries(interval time.Duration) *TimeSeries {
+	return &TimeSeries{
+		interval: interval,
+		metrics:  make(map[string]map[int64]Sink),
+	}
+}
+
+// Interval returns the length of the aggregation intervals.
+func (ts *TimeSeries) Interval() time.Duration {
+	return ts.interval
+}
+
+// Add aggregates the sample in the interval of its time for the given metric,
+// which can also be a submetric of the sample's metric.
+func (ts *TimeSeries) Add(m *Metric, sample Sample) {
+	points, ok := ts.metrics[m.Name]
+	if !ok {
+		points = make(map[int64]Sink)
+		ts.metrics[m.Name] = points
+	}
+	key := sample.Time.Truncate(ts.interval).UnixNano()
+	sink, ok := points[key]
+	if !ok {
+		sink = newSink(m.Type, m.Buckets)
+		if sink == nil {
+			return
+		}
+		points[key] = sink
+	}
+	sink.Add(sample)
+}
+
+// Get returns the aggregated intervals of the metric with the given name,
+// sorted by their time. Intervals without any samples are omitted.
+func (ts *TimeSeries) Get(name string) []TimeSeriesPoint {
+	points := ts.metrics[name]
+	result := make([]TimeSeriesPoint, 0, len(points))
+	for key, sink := range points {
+		result = append(result, TimeSeriesPoint{Time: time.Unix(0, key), Sink: sink})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+	return result
+}
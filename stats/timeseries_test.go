@@ -0,0 +1,63 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeSeries(t *testing.T) {
+	t.Parallel()
+	trend := New("my_trend", Trend, Time)
+	sub := trend.Derive("my_trend{status:200}")
+	start := time.Unix(1000, 0)
+
+	ts := NewTimeSeries(10 * time.Second)
+	assert.Equal(t, 10*time.Second, ts.Interval())
+	assert.Empty(t, ts.Get("my_trend"))
+
+	// Out of order, the buckets should still be sorted
+	ts.Add(trend, Sample{Metric: trend, Time: start.Add(25 * time.Second), Value: 5})
+	ts.Add(trend, Sample{Metric: trend, Time: start.Add(1 * time.Second), Value: 1})
+	ts.Add(trend, Sample{Metric: trend, Time: start.Add(9 * time.Second), Value: 3})
+	ts.Add(sub, Sample{Metric: trend, Time: start.Add(9 * time.Second), Value: 3})
+
+	points := ts.Get("my_trend")
+	require.Len(t, points, 2)
+	assert.Equal(t, start, points[0].Time)
+	assert.Equal(t, start.Add(20*time.Second), points[1].Time)
+
+	first, ok := points[0].Sink.(*TrendSink)
+	require.True(t, ok)
+	assert.Equal(t, uint64(2), first.Count)
+	assert.Equal(t, 4.0, first.Sum)
+	second, ok := points[1].Sink.(*TrendSink)
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), second.Count)
+
+	subPoints := ts.Get("my_trend{status:200}")
+	require.Len(t, subPoints, 1)
+	assert.Equal(t, start, subPoints[0].Time)
+}
@@ -148,6 +148,18 @@ func (pb *ProgressBar) renderLeft(maxLen int) string {
 	return left
 }
 
+// Progress returns the current progress value, clamped between 0 and 1, and
+// the right-side text of the progressbar.
+func (pb *ProgressBar) Progress() (float64, []string) {
+	pb.mutex.RLock()
+	defer pb.mutex.RUnlock()
+	if pb.progress == nil {
+		return 0, nil
+	}
+	progress, right := pb.progress()
+	return Clampf(progress, 0, 1), right
+}
+
 // Modify changes the progressbar options in a thread-safe way.
 func (pb *ProgressBar) Modify(options ...ProgressBarOption) {
 	pb.mutex.Lock()